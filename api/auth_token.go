@@ -240,4 +240,7 @@ type TokenCreateRequest struct {
 	DisplayName     string            `json:"display_name"`
 	NumUses         int               `json:"num_uses"`
 	Renewable       *bool             `json:"renewable,omitempty"`
+	Type            string            `json:"type,omitempty"`
+	BoundCIDRs      []string          `json:"bound_cidrs,omitempty"`
+	EntityAlias     string            `json:"entity_alias,omitempty"`
 }
@@ -1,8 +1,14 @@
 package api
 
 import (
+	"bytes"
+	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
 	"net"
 	"net/http"
 	"net/url"
@@ -18,7 +24,6 @@ import (
 	"github.com/hashicorp/go-cleanhttp"
 	"github.com/hashicorp/go-rootcerts"
 	"github.com/hashicorp/vault/helper/parseutil"
-	"github.com/sethgrid/pester"
 )
 
 const EnvVaultAddress = "VAULT_ADDR"
@@ -32,6 +37,10 @@ const EnvVaultTLSServerName = "VAULT_TLS_SERVER_NAME"
 const EnvVaultWrapTTL = "VAULT_WRAP_TTL"
 const EnvVaultMaxRetries = "VAULT_MAX_RETRIES"
 const EnvVaultToken = "VAULT_TOKEN"
+const EnvVaultNamespace = "VAULT_NAMESPACE"
+
+// NamespaceHeaderName is the header selecting the namespace of a request
+const NamespaceHeaderName = "X-Vault-Namespace"
 
 // WrappingLookupFunc is a function that, given an HTTP verb and a path,
 // returns an optional string duration to be used for response wrapping (e.g.
@@ -54,10 +63,21 @@ type Config struct {
 
 	redirectSetup sync.Once
 
-	// MaxRetries controls the maximum number of times to retry when a 5xx error
-	// occurs. Set to 0 or less to disable retrying. Defaults to 0.
+	// MaxRetries controls the maximum number of times to retry an idempotent
+	// request (GET, HEAD, OPTIONS, LIST or DELETE) when a connection error or
+	// a 502, 503 or 504 error occurs. A failed read may still have been
+	// served, so that reads issuing dynamic secrets can issue them more than
+	// once when retried. Set to 0 or less to disable retrying. Defaults to 2.
 	MaxRetries int
 
+	// MinRetryWait and MaxRetryWait bound the wait before each retry, which
+	// doubles from MinRetryWait with each retry of a request and is jittered
+	// so that clients failing together don't retry together. A longer wait
+	// given by the Retry-After header of a 503 response is honored, up to
+	// MaxRetryWait. Default to 500ms and 5s.
+	MinRetryWait time.Duration
+	MaxRetryWait time.Duration
+
 	// Timeout is for setting custom timeout parameter in the HttpClient
 	Timeout time.Duration
 }
@@ -94,8 +114,11 @@ type TLSConfig struct {
 // setting the `VAULT_ADDR` environment variable.
 func DefaultConfig() *Config {
 	config := &Config{
-		Address:    "https://127.0.0.1:8200",
-		HttpClient: cleanhttp.DefaultClient(),
+		Address:      "https://127.0.0.1:8200",
+		HttpClient:   cleanhttp.DefaultClient(),
+		MaxRetries:   2,
+		MinRetryWait: 500 * time.Millisecond,
+		MaxRetryWait: 5 * time.Second,
 	}
 	config.HttpClient.Timeout = time.Second * 60
 	transport := config.HttpClient.Transport.(*http.Transport)
@@ -226,7 +249,7 @@ func (c *Config) ReadEnvironment() error {
 	}
 
 	if envMaxRetries != nil {
-		c.MaxRetries = int(*envMaxRetries) + 1
+		c.MaxRetries = int(*envMaxRetries)
 	}
 
 	if envClientTimeout != 0 {
@@ -242,8 +265,10 @@ type Client struct {
 	addr               *url.URL
 	config             *Config
 	token              string
+	namespace          string
 	headers            http.Header
 	wrappingLookupFunc WrappingLookupFunc
+	ctx                context.Context
 }
 
 // NewClient returns a new client for the given configuration.
@@ -277,6 +302,21 @@ func NewClient(c *Config) (*Client, error) {
 		}
 	}
 
+	// Connect to the unix socket of a unix listener given as
+	// unix:///path/to/socket
+	if u.Scheme == "unix" {
+		tp, ok := c.HttpClient.Transport.(*http.Transport)
+		if !ok {
+			return nil, fmt.Errorf("unix socket addresses require an *http.Transport")
+		}
+		socket := u.Path
+		tp.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socket)
+		}
+		u = &url.URL{Scheme: "http", Host: "localhost"}
+	}
+
 	redirFunc := func() {
 		// Ensure redirects are not automatically followed
 		// Note that this is sane for the API client as it has its own
@@ -284,9 +324,8 @@ func NewClient(c *Config) (*Client, error) {
 		// but in e.g. http_test actual redirect handling is necessary
 		c.HttpClient.CheckRedirect = func(req *http.Request, via []*http.Request) error {
 			// Returning this value causes the Go net library to not close the
-			// response body and to nil out the error. Otherwise pester tries
-			// three times on every redirect because it sees an error from this
-			// function (to prevent redirects) passing through to it.
+			// response body and to nil out the error, so that RawRequest
+			// follows the redirect itself rather than seeing an error.
 			return http.ErrUseLastResponse
 		}
 	}
@@ -302,6 +341,10 @@ func NewClient(c *Config) (*Client, error) {
 		client.SetToken(token)
 	}
 
+	if namespace := os.Getenv(EnvVaultNamespace); namespace != "" {
+		client.SetNamespace(namespace)
+	}
+
 	return client, nil
 }
 
@@ -355,11 +398,47 @@ func (c *Client) ClearToken() {
 	c.token = ""
 }
 
+// Namespace returns the namespace the requests of this client target. It
+// will return the empty string for the root namespace.
+func (c *Client) Namespace() string {
+	return c.namespace
+}
+
+// SetNamespace sets the namespace the future requests target, relative to
+// which their paths are resolved.
+func (c *Client) SetNamespace(namespace string) {
+	c.namespace = namespace
+}
+
+// ClearNamespace makes the future requests target the root namespace.
+func (c *Client) ClearNamespace() {
+	c.namespace = ""
+}
+
 // SetHeaders sets the headers to be used for future requests.
 func (c *Client) SetHeaders(headers http.Header) {
 	c.headers = headers
 }
 
+// WithContext returns a shallow copy of this client whose requests, made by
+// any of its methods, are bound to the given context: they are aborted,
+// including while waiting between retries, when the context is canceled or
+// its deadline expires. The copy shares the configuration of this client.
+func (c *Client) WithContext(ctx context.Context) *Client {
+	client := *c
+	client.ctx = ctx
+	return &client
+}
+
+// Context returns the context the requests of this client are bound to,
+// which is context.Background() unless set with WithContext.
+func (c *Client) Context() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+	return c.ctx
+}
+
 // Clone creates a copy of this client.
 func (c *Client) Clone() (*Client, error) {
 	return NewClient(c.config)
@@ -412,6 +491,14 @@ func (c *Client) NewRequest(method, requestPath string) *Request {
 	if c.headers != nil {
 		req.Headers = c.headers
 	}
+	if c.namespace != "" {
+		headers := make(http.Header, len(req.Headers)+1)
+		for header, vals := range req.Headers {
+			headers[header] = vals
+		}
+		headers.Set(NamespaceHeaderName, c.namespace)
+		req.Headers = headers
+	}
 
 	return req
 }
@@ -420,22 +507,70 @@ func (c *Client) NewRequest(method, requestPath string) *Request {
 // a Vault server not configured with this client. This is an advanced operation
 // that generally won't need to be called externally.
 func (c *Client) RawRequest(r *Request) (*Response, error) {
+	return c.RawRequestWithContext(c.Context(), r)
+}
+
+// RawRequestWithContext performs the raw request given, bound to the given
+// context rather than to the context of the client. Idempotent requests are
+// retried as configured by MaxRetries.
+func (c *Client) RawRequestWithContext(ctx context.Context, r *Request) (*Response, error) {
+	// The body is sent again on a retry or a redirect. JSON bodies are
+	// encoded again, while the other bodies are only buffered when the
+	// request can be retried, so that large bodies such as raft snapshots
+	// are streamed.
+	var body []byte
+	buffered := r.Body != nil && r.Obj == nil && idempotent(r.Method)
+	if buffered {
+		var err error
+		if body, err = ioutil.ReadAll(r.Body); err != nil {
+			return nil, err
+		}
+	}
+
 	redirectCount := 0
+	retryCount := 0
 START:
+	if buffered {
+		r.Body = bytes.NewReader(body)
+	} else if r.Body != nil && redirectCount+retryCount > 0 {
+		if r.Obj == nil {
+			return nil, fmt.Errorf("request body was streamed and cannot be sent again")
+		}
+		if err := r.ResetJSONBody(); err != nil {
+			return nil, err
+		}
+	}
 	req, err := r.ToHTTP()
 	if err != nil {
 		return nil, err
 	}
-
-	client := pester.NewExtendedClient(c.config.HttpClient)
-	client.Backoff = pester.LinearJitterBackoff
-	client.MaxRetries = c.config.MaxRetries
+	req = req.WithContext(ctx)
 
 	var result *Response
-	resp, err := client.Do(req)
+	resp, err := c.config.HttpClient.Do(req)
 	if resp != nil {
 		result = &Response{Response: resp}
 	}
+
+	if retryCount < c.config.MaxRetries && retryable(ctx, req.Method, resp, err) {
+		wait := c.config.retryWait(retryCount, resp)
+		if resp != nil {
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+
+		retryCount++
+		goto START
+	}
+
 	if err != nil {
 		if strings.Contains(err.Error(), "tls: oversized") {
 			err = fmt.Errorf(
@@ -466,14 +601,9 @@ START:
 			return result, fmt.Errorf("redirect would cause protocol downgrade")
 		}
 
-		// Update the request
+		// Update the request, whose body is reset at START
 		r.URL = respLoc
 
-		// Reset the request body if any
-		if err := r.ResetJSONBody(); err != nil {
-			return result, err
-		}
-
 		// Retry the request
 		redirectCount++
 		goto START
@@ -485,3 +615,89 @@ START:
 
 	return result, nil
 }
+
+// idempotent returns whether requests of the given method can be retried
+func idempotent(method string) bool {
+	switch method {
+	case "GET", "HEAD", "OPTIONS", "LIST", "DELETE":
+		return true
+	}
+	return false
+}
+
+// retryable returns whether a failed attempt of a request can be retried.
+// Only the idempotent requests are, on connection errors other than TLS
+// errors and on 502, 503 and 504 responses. Note that the failed attempt may
+// still have been served, such as behind a proxy timing out, so that reads
+// issuing dynamic secrets can issue them more than once; set MaxRetries to 0
+// to avoid it. Rate limited requests aren't retried, but returned with a
+// RateLimitError, so that callers can back off as they see fit.
+func retryable(ctx context.Context, method string, resp *http.Response, err error) bool {
+	if ctx.Err() != nil || !idempotent(method) {
+		return false
+	}
+
+	if err != nil {
+		if urlErr, ok := err.(*url.Error); ok {
+			switch urlErr.Err.(type) {
+			case x509.UnknownAuthorityError, x509.CertificateInvalidError, x509.HostnameError:
+				return false
+			}
+		}
+		return !strings.Contains(err.Error(), "tls: ")
+	}
+
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryWait returns how long to wait before the given retry of a request,
+// counted from 0
+func (c *Config) retryWait(retry int, resp *http.Response) time.Duration {
+	min, max := c.MinRetryWait, c.MaxRetryWait
+	if min <= 0 {
+		min = 500 * time.Millisecond
+	}
+	if max < min {
+		max = min
+	}
+
+	wait := min << uint(retry)
+	if wait > max || wait <= 0 {
+		wait = max
+	}
+	// The wait is drawn between its half and itself
+	wait = wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))
+
+	if resp != nil && resp.StatusCode == http.StatusServiceUnavailable {
+		if after := retryAfter(resp.Header); after > wait {
+			wait = after
+		}
+		if wait > max {
+			wait = max
+		}
+	}
+
+	return wait
+}
+
+// retryAfter returns the delay given by the Retry-After header, either in
+// seconds or as a date, or 0 if it is missing or invalid
+func retryAfter(h http.Header) time.Duration {
+	v := h.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+	if seconds, err := strconv.ParseUint(v, 10, 32); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
@@ -2,9 +2,15 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"io"
+	"io/ioutil"
+	"net"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -133,6 +139,43 @@ func TestClientRedirect(t *testing.T) {
 	}
 }
 
+func TestClientUnixSocket(t *testing.T) {
+	td, err := ioutil.TempDir("", "vault-api-unix")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(td)
+
+	socket := filepath.Join(td, "vault.sock")
+	ln, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go http.Serve(ln, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(req.URL.Path))
+	}))
+
+	config := DefaultConfig()
+	config.Address = "unix://" + socket
+	client, err := NewClient(config)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	resp, err := client.RawRequest(client.NewRequest("GET", "/v1/sys/health"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer resp.Body.Close()
+
+	var buf bytes.Buffer
+	io.Copy(&buf, resp.Body)
+	if buf.String() != "/v1/sys/health" {
+		t.Fatalf("bad: %s", buf.String())
+	}
+}
+
 func TestClientEnvSettings(t *testing.T) {
 	cwd, _ := os.Getwd()
 	oldCACert := os.Getenv(EnvVaultCACert)
@@ -169,6 +212,9 @@ func TestClientEnvSettings(t *testing.T) {
 	if tlsConfig.InsecureSkipVerify != true {
 		t.Fatalf("bad: %v", tlsConfig.InsecureSkipVerify)
 	}
+	if config.MaxRetries != 5 {
+		t.Fatalf("bad: %d", config.MaxRetries)
+	}
 }
 
 func TestClientTimeoutSetting(t *testing.T) {
@@ -213,3 +259,154 @@ func TestClientNonTransportRoundTripper(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestClientRetries(t *testing.T) {
+	var lock sync.Mutex
+	var attempts int
+	status := 503
+	handler := func(w http.ResponseWriter, req *http.Request) {
+		lock.Lock()
+		attempts++
+		n := attempts
+		lock.Unlock()
+
+		body, _ := ioutil.ReadAll(req.Body)
+		if req.URL.Path == "/body" && strings.TrimSpace(string(body)) != `{"foo":"bar"}` {
+			w.WriteHeader(400)
+			return
+		}
+		if n < 3 {
+			w.WriteHeader(status)
+			return
+		}
+		w.Write([]byte("test"))
+	}
+	config, ln := testHTTPServer(t, http.HandlerFunc(handler))
+	defer ln.Close()
+	config.MinRetryWait = 10 * time.Millisecond
+	config.MaxRetryWait = 20 * time.Millisecond
+
+	client, err := NewClient(config)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Idempotent requests are retried, with their JSON or raw body
+	for _, method := range []string{"GET", "DELETE"} {
+		attempts = 0
+		req := client.NewRequest(method, "/body")
+		if method == "GET" {
+			req.Body = strings.NewReader(`{"foo":"bar"}`)
+		} else if err := req.SetJSONBody(map[string]string{"foo": "bar"}); err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.RawRequest(req)
+		if err != nil {
+			t.Fatalf("%s: err: %s", method, err)
+		}
+		resp.Body.Close()
+		if attempts != 3 {
+			t.Fatalf("%s: bad: %d attempts", method, attempts)
+		}
+	}
+
+	// Past the maximum number of retries, the last error is returned
+	attempts = 0
+	client.SetMaxRetries(1)
+	resp, err := client.RawRequest(client.NewRequest("GET", "/"))
+	if err == nil || resp == nil || resp.StatusCode != 503 {
+		t.Fatalf("expected a 503 error, got: %v", err)
+	}
+	if attempts != 2 {
+		t.Fatalf("bad: %d attempts", attempts)
+	}
+
+	// Errors which may come from the backend aren't retried
+	attempts = 0
+	status = 500
+	client.SetMaxRetries(2)
+	req := client.NewRequest("GET", "/body")
+	if err := req.SetJSONBody(map[string]string{"foo": "bar"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.RawRequest(req); err == nil {
+		t.Fatal("expected an error")
+	}
+	if attempts != 1 {
+		t.Fatalf("bad: %d attempts", attempts)
+	}
+
+	// Writes aren't retried
+	attempts = 0
+	status = 503
+	if _, err := client.RawRequest(client.NewRequest("PUT", "/")); err == nil {
+		t.Fatal("expected an error")
+	}
+	if attempts != 1 {
+		t.Fatalf("bad: %d attempts", attempts)
+	}
+}
+
+func TestClientRateLimitError(t *testing.T) {
+	var attempts int
+	handler := func(w http.ResponseWriter, req *http.Request) {
+		attempts++
+		w.Header().Set("Retry-After", "7")
+		w.WriteHeader(429)
+		w.Write([]byte(`{"errors":["request rate limit exceeded"]}`))
+	}
+	config, ln := testHTTPServer(t, http.HandlerFunc(handler))
+	defer ln.Close()
+
+	client, err := NewClient(config)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	_, err = client.RawRequest(client.NewRequest("GET", "/"))
+	rlErr, ok := err.(*RateLimitError)
+	if !ok {
+		t.Fatalf("expected a rate limit error, got: %#v", err)
+	}
+	if rlErr.RetryAfter != 7*time.Second {
+		t.Fatalf("bad: %s", rlErr.RetryAfter)
+	}
+	if !strings.Contains(rlErr.Error(), "request rate limit exceeded") {
+		t.Fatalf("bad: %s", rlErr)
+	}
+	if attempts != 1 {
+		t.Fatalf("bad: %d attempts", attempts)
+	}
+}
+
+func TestClientWithContext(t *testing.T) {
+	handler := func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(503)
+	}
+	config, ln := testHTTPServer(t, http.HandlerFunc(handler))
+	defer ln.Close()
+	config.MaxRetries = 10
+	config.MinRetryWait = time.Minute
+	config.MaxRetryWait = time.Minute
+
+	client, err := NewClient(config)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The context aborts the wait between the retries
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = client.WithContext(ctx).Logical().Read("secret/foo")
+	if err != context.DeadlineExceeded {
+		t.Fatalf("expected the context error, got: %v", err)
+	}
+	if time.Since(start) > 10*time.Second {
+		t.Fatalf("the request wasn't aborted")
+	}
+
+	if client.Context() != context.Background() {
+		t.Fatal("the context of the original client changed")
+	}
+}
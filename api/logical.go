@@ -2,9 +2,11 @@ package api
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
+	"strconv"
 
 	"github.com/hashicorp/vault/helper/jsonutil"
 )
@@ -31,6 +33,10 @@ var (
 
 		return ""
 	}
+
+	// ErrNotModified is returned by ReadIfChanged when the key has not
+	// changed since the version the caller last read
+	ErrNotModified = errors.New("secret not modified")
 )
 
 // Logical is used to perform logical backend operations on Vault.
@@ -44,7 +50,40 @@ func (c *Client) Logical() *Logical {
 }
 
 func (c *Logical) Read(path string) (*Secret, error) {
+	return c.ReadWithData(path, nil)
+}
+
+// ReadWithData reads the path with the given query parameters, such as the
+// version to read of a key of a versioned kv mount
+func (c *Logical) ReadWithData(path string, data map[string][]string) (*Secret, error) {
+	r := c.c.NewRequest("GET", "/v1/"+path)
+	for k, v := range data {
+		for _, vv := range v {
+			r.Params.Add(k, vv)
+		}
+	}
+	resp, err := c.c.RawRequest(r)
+	if resp != nil {
+		defer resp.Body.Close()
+	}
+	if resp != nil && resp.StatusCode == 404 {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseSecret(resp.Body)
+}
+
+// ReadIfChanged reads a key from a versioned kv mount, given the path under
+// its "data/" prefix. If the key's current version is still knownVersion, no
+// data is transferred and ErrNotModified is returned.
+func (c *Logical) ReadIfChanged(path string, knownVersion int) (*Secret, error) {
 	r := c.c.NewRequest("GET", "/v1/"+path)
+	if knownVersion > 0 {
+		r.Params.Set("known_version", strconv.Itoa(knownVersion))
+	}
 	resp, err := c.c.RawRequest(r)
 	if resp != nil {
 		defer resp.Body.Close()
@@ -55,6 +94,9 @@ func (c *Logical) Read(path string) (*Secret, error) {
 	if err != nil {
 		return nil, err
 	}
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, ErrNotModified
+	}
 
 	return ParseSecret(resp.Body)
 }
@@ -100,6 +142,39 @@ func (c *Logical) Write(path string, data map[string]interface{}) (*Secret, erro
 	return nil, nil
 }
 
+// JSONMergePatch updates the fields of the resource at the path given in
+// data, applying it as a JSON merge patch (RFC 7396): the fields set to nil
+// are removed or reset, and the others are set to the given values.
+func (c *Logical) JSONMergePatch(path string, data map[string]interface{}) (*Secret, error) {
+	r := c.c.NewRequest("PATCH", "/v1/"+path)
+
+	// The headers of the request may be shared with the client
+	headers := make(http.Header, len(r.Headers)+1)
+	for header, vals := range r.Headers {
+		headers[header] = vals
+	}
+	headers.Set("Content-Type", "application/merge-patch+json")
+	r.Headers = headers
+
+	if err := r.SetJSONBody(data); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.c.RawRequest(r)
+	if resp != nil {
+		defer resp.Body.Close()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == 200 {
+		return ParseSecret(resp.Body)
+	}
+
+	return nil, nil
+}
+
 func (c *Logical) Delete(path string) (*Secret, error) {
 	r := c.c.NewRequest("DELETE", "/v1/"+path)
 	resp, err := c.c.RawRequest(r)
@@ -183,3 +258,37 @@ func (c *Logical) Unwrap(wrappingToken string) (*Secret, error) {
 
 	return wrappedSecret, nil
 }
+
+// UnwrapEntry unwraps a single named entry of a token wrapping multiple
+// entries. Each entry can only be unwrapped once.
+func (c *Logical) UnwrapEntry(wrappingToken, entry string) (*Secret, error) {
+	data := map[string]interface{}{
+		"entry": entry,
+	}
+	if wrappingToken != "" {
+		if c.c.Token() == "" {
+			c.c.SetToken(wrappingToken)
+		} else if wrappingToken != c.c.Token() {
+			data["token"] = wrappingToken
+		}
+	}
+
+	r := c.c.NewRequest("PUT", "/v1/sys/wrapping/unwrap")
+	if err := r.SetJSONBody(data); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.c.RawRequest(r)
+	if resp != nil {
+		defer resp.Body.Close()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == 200 {
+		return ParseSecret(resp.Body)
+	}
+
+	return nil, nil
+}
@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
+	"time"
 
 	"github.com/hashicorp/vault/helper/jsonutil"
 )
@@ -22,21 +24,35 @@ func (r *Response) DecodeJSON(out interface{}) error {
 }
 
 // Error returns an error response if there is one. If there is an error,
-// this will fully consume the response body, but will not close it. The
-// body must still be closed manually.
+// the response body is read, then replaced with a copy which can be read
+// again, such as to forward the response. The body must still be closed
+// manually. The error of a 429 response is a *RateLimitError.
 func (r *Response) Error() error {
-	// 200 to 399 are okay status codes. 429 is the code for health status of
-	// standby nodes.
-	if (r.StatusCode >= 200 && r.StatusCode < 400) || r.StatusCode == 429 {
+	// 200 to 399 are okay status codes.
+	if r.StatusCode >= 200 && r.StatusCode < 400 {
 		return nil
 	}
 
+	err := r.error()
+	if r.StatusCode == http.StatusTooManyRequests {
+		return &RateLimitError{
+			RetryAfter: retryAfter(r.Header),
+			err:        err,
+		}
+	}
+	return err
+}
+
+func (r *Response) error() error {
+
 	// We have an error. Let's copy the body into our own buffer first,
 	// so that if we can't decode JSON, we can at least copy it raw.
 	var bodyBuf bytes.Buffer
 	if _, err := io.Copy(&bodyBuf, r.Body); err != nil {
 		return err
 	}
+	r.Body.Close()
+	r.Body = ioutil.NopCloser(bytes.NewReader(bodyBuf.Bytes()))
 
 	// Decode the error response if we can. Note that we wrap the bodyBuf
 	// in a bytes.Reader here so that the JSON decoder doesn't move the
@@ -46,18 +62,18 @@ func (r *Response) Error() error {
 		// Ignore the decoding error and just drop the raw response
 		return fmt.Errorf(
 			"Error making API request.\n\n"+
-				"URL: %s %s\n"+
+				"URL: %s %s\n%s"+
 				"Code: %d. Raw Message:\n\n%s",
-			r.Request.Method, r.Request.URL.String(),
+			r.Request.Method, r.Request.URL.String(), r.requestIDLine(),
 			r.StatusCode, bodyBuf.String())
 	}
 
 	var errBody bytes.Buffer
 	errBody.WriteString(fmt.Sprintf(
 		"Error making API request.\n\n"+
-			"URL: %s %s\n"+
+			"URL: %s %s\n%s"+
 			"Code: %d. Errors:\n\n",
-		r.Request.Method, r.Request.URL.String(),
+		r.Request.Method, r.Request.URL.String(), r.requestIDLine(),
 		r.StatusCode))
 	for _, err := range resp.Errors {
 		errBody.WriteString(fmt.Sprintf("* %s", err))
@@ -66,6 +82,34 @@ func (r *Response) Error() error {
 	return fmt.Errorf(errBody.String())
 }
 
+// RequestID returns the identifier which the server assigned to the request,
+// included in its audit entries and logs, or an empty string if unknown
+func (r *Response) RequestID() string {
+	return r.Header.Get("X-Vault-Request-ID")
+}
+
+func (r *Response) requestIDLine() string {
+	if id := r.RequestID(); id != "" {
+		return fmt.Sprintf("Request ID: %s\n", id)
+	}
+	return ""
+}
+
+// RateLimitError is the error of a request rejected with a 429 status code,
+// by a rate limit or lease count quota of the server. These requests aren't
+// retried by the client, so that callers can back off as they see fit.
+type RateLimitError struct {
+	// RetryAfter is how long the server asked to wait before retrying the
+	// request, or 0 if it didn't say
+	RetryAfter time.Duration
+
+	err error
+}
+
+func (e *RateLimitError) Error() string {
+	return e.err.Error()
+}
+
 // ErrorResponse is the raw structure of errors when they're returned by the
 // HTTP API.
 type ErrorResponse struct {
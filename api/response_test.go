@@ -0,0 +1,50 @@
+package api
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestResponse_ErrorRequestID(t *testing.T) {
+	newResponse := func(body string, header http.Header) *Response {
+		return &Response{
+			Response: &http.Response{
+				StatusCode: 403,
+				Header:     header,
+				Body:       ioutil.NopCloser(strings.NewReader(body)),
+				Request: &http.Request{
+					Method: "GET",
+					URL:    &url.URL{Scheme: "https", Host: "vault.example.com", Path: "/v1/secret/foo"},
+				},
+			},
+		}
+	}
+
+	header := http.Header{}
+	header.Set("X-Vault-Request-ID", "3f0a8e42")
+	resp := newResponse(`{"errors":["permission denied"]}`, header)
+	if resp.RequestID() != "3f0a8e42" {
+		t.Fatalf("bad: %q", resp.RequestID())
+	}
+	err := resp.Error()
+	if err == nil || !strings.Contains(err.Error(), "Request ID: 3f0a8e42\nCode: 403. Errors:") {
+		t.Fatalf("bad: %v", err)
+	}
+
+	// The body can be read again after the error is returned
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != `{"errors":["permission denied"]}` {
+		t.Fatalf("bad: %s", body)
+	}
+
+	err = newResponse("not json", http.Header{}).Error()
+	if err == nil || strings.Contains(err.Error(), "Request ID") || !strings.Contains(err.Error(), "Code: 403. Raw Message") {
+		t.Fatalf("bad: %v", err)
+	}
+}
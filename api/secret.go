@@ -16,6 +16,10 @@ type Secret struct {
 	LeaseDuration int    `json:"lease_duration"`
 	Renewable     bool   `json:"renewable"`
 
+	// LeaseContract, if non-nil, contains hints from Vault about when the
+	// lease should be renewed or rotated.
+	LeaseContract *SecretLeaseContract `json:"lease_contract,omitempty"`
+
 	// Data is the actual contents of the secret. The format of the data
 	// is arbitrary and up to the secret backend.
 	Data map[string]interface{} `json:"data"`
@@ -46,6 +50,16 @@ type SecretWrapInfo struct {
 	WrappedAccessor string    `json:"wrapped_accessor"`
 }
 
+// SecretLeaseContract contains hints about the validity of a lease. Clients
+// should begin renewing or rotating the secret at EarliestRotation, and can
+// rely on it being valid until GuaranteedValidUntil. RenewEndpoint is empty if
+// the lease is not renewable.
+type SecretLeaseContract struct {
+	EarliestRotation     time.Time `json:"earliest_rotation"`
+	GuaranteedValidUntil time.Time `json:"guaranteed_valid_until"`
+	RenewEndpoint        string    `json:"renew_endpoint"`
+}
+
 // SecretAuth is the structure containing auth information if we have it.
 type SecretAuth struct {
 	ClientToken string            `json:"client_token"`
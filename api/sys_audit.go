@@ -36,6 +36,47 @@ func (c *Sys) AuditHash(path string, input string) (string, error) {
 	return result.Hash, err
 }
 
+// AuditVerifyResult is the result of the verification of the chain of the
+// entries logged by an audit backend
+type AuditVerifyResult struct {
+	Valid            bool   `json:"valid"`
+	Error            string `json:"error"`
+	Line             int    `json:"line"`
+	Entries          int    `json:"entries"`
+	UnchainedEntries int    `json:"unchained_entries"`
+	FirstPrevHash    string `json:"first_prev_hash"`
+	ChainStart       bool   `json:"chain_start"`
+	LastHash         string `json:"last_hash"`
+}
+
+// AuditVerify verifies the chain of the entries logged by the audit backend
+// at the given path, in the given log file or in its current log file if
+// empty
+func (c *Sys) AuditVerify(path string, filePath string) (*AuditVerifyResult, error) {
+	body := map[string]interface{}{
+		"file_path": filePath,
+	}
+
+	r := c.c.NewRequest("PUT", fmt.Sprintf("/v1/sys/audit-verify/%s", path))
+	if err := r.SetJSONBody(body); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.c.RawRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Data AuditVerifyResult `json:"data"`
+	}
+	if err := resp.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+	return &result.Data, nil
+}
+
 func (c *Sys) ListAudit() (map[string]*Audit, error) {
 	r := c.c.NewRequest("GET", "/v1/sys/audit")
 	resp, err := c.c.RawRequest(r)
@@ -120,9 +161,9 @@ type EnableAuditOptions struct {
 }
 
 type Audit struct {
-	Path        string
-	Type        string
-	Description string
-	Options     map[string]string
-	Local       bool
+	Path        string            `json:"path" mapstructure:"path"`
+	Type        string            `json:"type" mapstructure:"type"`
+	Description string            `json:"description" mapstructure:"description"`
+	Options     map[string]string `json:"options" mapstructure:"options"`
+	Local       bool              `json:"local" mapstructure:"local"`
 }
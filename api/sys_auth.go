@@ -86,11 +86,15 @@ type EnableAuthOptions struct {
 	Description string          `json:"description" structs:"description"`
 	Config      AuthConfigInput `json:"config" structs:"config"`
 	Local       bool            `json:"local" structs:"local"`
+	SealWrap    bool            `json:"seal_wrap" structs:"seal_wrap"`
 	PluginName  string          `json:"plugin_name,omitempty" structs:"plugin_name,omitempty"`
 }
 
 type AuthConfigInput struct {
-	PluginName string `json:"plugin_name,omitempty" structs:"plugin_name,omitempty" mapstructure:"plugin_name"`
+	PluginName             string `json:"plugin_name,omitempty" structs:"plugin_name,omitempty" mapstructure:"plugin_name"`
+	PluginVersion          string `json:"plugin_version,omitempty" structs:"plugin_version,omitempty" mapstructure:"plugin_version"`
+	AutoCreateGroupAliases bool   `json:"auto_create_group_aliases,omitempty" structs:"auto_create_group_aliases,omitempty" mapstructure:"auto_create_group_aliases"`
+	TokenType              string `json:"token_type,omitempty" structs:"token_type,omitempty" mapstructure:"token_type"`
 }
 
 type AuthMount struct {
@@ -99,10 +103,15 @@ type AuthMount struct {
 	Accessor    string           `json:"accessor" structs:"accessor" mapstructure:"accessor"`
 	Config      AuthConfigOutput `json:"config" structs:"config" mapstructure:"config"`
 	Local       bool             `json:"local" structs:"local" mapstructure:"local"`
+	SealWrap    bool             `json:"seal_wrap" structs:"seal_wrap" mapstructure:"seal_wrap"`
 }
 
 type AuthConfigOutput struct {
 	DefaultLeaseTTL int    `json:"default_lease_ttl" structs:"default_lease_ttl" mapstructure:"default_lease_ttl"`
 	MaxLeaseTTL     int    `json:"max_lease_ttl" structs:"max_lease_ttl" mapstructure:"max_lease_ttl"`
 	PluginName      string `json:"plugin_name,omitempty" structs:"plugin_name,omitempty" mapstructure:"plugin_name"`
+	PluginVersion   string `json:"plugin_version,omitempty" structs:"plugin_version,omitempty" mapstructure:"plugin_version"`
+
+	AutoCreateGroupAliases bool   `json:"auto_create_group_aliases" structs:"auto_create_group_aliases" mapstructure:"auto_create_group_aliases"`
+	TokenType              string `json:"token_type" structs:"token_type" mapstructure:"token_type"`
 }
@@ -2,11 +2,24 @@ package api
 
 import "fmt"
 
+// CapabilityGrant identifies a path rule of a policy granting a capability
+type CapabilityGrant struct {
+	Policy string `json:"policy"`
+	Path   string `json:"path"`
+}
+
 func (c *Sys) CapabilitiesSelf(path string) ([]string, error) {
 	return c.Capabilities(c.c.Token(), path)
 }
 
 func (c *Sys) Capabilities(token, path string) ([]string, error) {
+	capabilities, _, err := c.CapabilitiesWithGrants(token, path)
+	return capabilities, err
+}
+
+// CapabilitiesWithGrants returns the capabilities of the token on the path,
+// along with the policy path rules granting each of them
+func (c *Sys) CapabilitiesWithGrants(token, path string) ([]string, map[string][]*CapabilityGrant, error) {
 	body := map[string]string{
 		"token": token,
 		"path":  path,
@@ -19,25 +32,23 @@ func (c *Sys) Capabilities(token, path string) ([]string, error) {
 
 	r := c.c.NewRequest("POST", reqPath)
 	if err := r.SetJSONBody(body); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	resp, err := c.c.RawRequest(r)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer resp.Body.Close()
 
-	var result map[string]interface{}
+	var result struct {
+		Capabilities     []string                      `json:"capabilities"`
+		CapabilityGrants map[string][]*CapabilityGrant `json:"capability_grants"`
+	}
 	err = resp.DecodeJSON(&result)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	var capabilities []string
-	capabilitiesRaw := result["capabilities"].([]interface{})
-	for _, capability := range capabilitiesRaw {
-		capabilities = append(capabilities, capability.(string))
-	}
-	return capabilities, nil
+	return result.Capabilities, result.CapabilityGrants, nil
 }
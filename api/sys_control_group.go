@@ -0,0 +1,61 @@
+package api
+
+import "time"
+
+// ControlGroupRequest describes a request parked by a control group
+type ControlGroupRequest struct {
+	ID              string                       `json:"id"`
+	Path            string                       `json:"path"`
+	Operation       string                       `json:"operation"`
+	RequestEntityID string                       `json:"request_entity_id"`
+	CreationTime    time.Time                    `json:"creation_time"`
+	ExpireTime      time.Time                    `json:"expire_time"`
+	Factors         []*ControlGroupFactor        `json:"factors"`
+	Authorizations  []*ControlGroupAuthorization `json:"authorizations"`
+	Approved        bool                         `json:"approved"`
+}
+
+// ControlGroupFactor is satisfied once the given number of members of the
+// given identity groups authorized a request
+type ControlGroupFactor struct {
+	Name       string   `json:"name"`
+	GroupNames []string `json:"group_names"`
+	Approvals  int      `json:"approvals"`
+}
+
+// ControlGroupAuthorization records the authorization of a request by an
+// approver
+type ControlGroupAuthorization struct {
+	EntityID string    `json:"entity_id"`
+	Groups   []string  `json:"groups"`
+	Time     time.Time `json:"time"`
+}
+
+func (c *Sys) ControlGroupRequest(id string) (*ControlGroupRequest, error) {
+	return c.controlGroup("/v1/sys/control-group/request", id)
+}
+
+func (c *Sys) ControlGroupAuthorize(id string) (*ControlGroupRequest, error) {
+	return c.controlGroup("/v1/sys/control-group/authorize", id)
+}
+
+func (c *Sys) controlGroup(path, id string) (*ControlGroupRequest, error) {
+	r := c.c.NewRequest("PUT", path)
+	if err := r.SetJSONBody(map[string]interface{}{"id": id}); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.c.RawRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Data *ControlGroupRequest `json:"data"`
+	}
+	if err := resp.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+	return result.Data, nil
+}
@@ -4,9 +4,12 @@ func (c *Sys) Health() (*HealthResponse, error) {
 	r := c.c.NewRequest("GET", "/v1/sys/health")
 	// If the code is 400 or above it will automatically turn into an error,
 	// but the sys/health API defaults to returning 5xx when not sealed or
-	// inited, so we force this code to be something else so we parse correctly
+	// inited, and 429 on standby nodes, so we force this code to be something
+	// else so we parse correctly
+	r.Params.Add("standbycode", "299")
 	r.Params.Add("sealedcode", "299")
 	r.Params.Add("uninitcode", "299")
+	r.Params.Add("drsecondarycode", "299")
 	resp, err := c.c.RawRequest(r)
 	if err != nil {
 		return nil, err
@@ -26,4 +29,26 @@ type HealthResponse struct {
 	Version       string `json:"version"`
 	ClusterName   string `json:"cluster_name,omitempty"`
 	ClusterID     string `json:"cluster_id,omitempty"`
+
+	LeaseRestore *LeaseRestoreStatus `json:"lease_restore,omitempty"`
+	Subsystems   *SubsystemStatus    `json:"subsystems,omitempty"`
+}
+
+type LeaseRestoreStatus struct {
+	Complete bool  `json:"complete"`
+	Loaded   int64 `json:"loaded"`
+	Total    int64 `json:"total"`
+}
+
+type SubsystemStatus struct {
+	Ready          bool               `json:"ready"`
+	LeasesRestored bool               `json:"leases_restored"`
+	IdentityLoaded bool               `json:"identity_loaded"`
+	Replication    *ReplicationStatus `json:"replication"`
+}
+
+type ReplicationStatus struct {
+	State         string `json:"state"`
+	LastRemoteWAL uint64 `json:"last_remote_wal"`
+	WALLag        uint64 `json:"wal_lag"`
 }
@@ -1,5 +1,7 @@
 package api
 
+import "time"
+
 func (c *Sys) Renew(id string, increment int) (*Secret, error) {
 	r := c.c.NewRequest("PUT", "/v1/sys/leases/renew")
 
@@ -46,3 +48,46 @@ func (c *Sys) RevokeForce(id string) error {
 	}
 	return err
 }
+
+// IrrevocableLease describes a lease which could not be revoked
+type IrrevocableLease struct {
+	LeaseID    string    `json:"lease_id"`
+	Path       string    `json:"path"`
+	IssueTime  time.Time `json:"issue_time"`
+	ExpireTime time.Time `json:"expire_time"`
+	RevokeErr  string    `json:"revoke_error"`
+}
+
+func (c *Sys) IrrevocableLeases() ([]*IrrevocableLease, error) {
+	r := c.c.NewRequest("GET", "/v1/sys/leases/irrevocable")
+	resp, err := c.c.RawRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Data struct {
+			Leases []*IrrevocableLease `json:"leases"`
+		} `json:"data"`
+	}
+	if err := resp.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+	return result.Data.Leases, nil
+}
+
+func (c *Sys) RemoveIrrevocableLease(id string) error {
+	r := c.c.NewRequest("PUT", "/v1/sys/leases/irrevocable/remove")
+	if err := r.SetJSONBody(map[string]interface{}{
+		"lease_id": id,
+	}); err != nil {
+		return err
+	}
+
+	resp, err := c.c.RawRequest(r)
+	if err == nil {
+		defer resp.Body.Close()
+	}
+	return err
+}
@@ -119,12 +119,79 @@ func (c *Sys) MountConfig(path string) (*MountConfigOutput, error) {
 	return &result, err
 }
 
+// ExportMountConfig returns the signed configuration of the mount, for
+// import into a mount of the same type on another cluster
+func (c *Sys) ExportMountConfig(path string) (*MountConfigExport, error) {
+	r := c.c.NewRequest("GET", fmt.Sprintf("/v1/sys/mounts/%s/export-config", path))
+
+	resp, err := c.c.RawRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result MountConfigExport
+	err = resp.DecodeJSON(&result)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, err
+}
+
+// ImportMountConfig compares an exported configuration with the mount,
+// applying it if input.Apply is set
+func (c *Sys) ImportMountConfig(path string, input *MountConfigImportInput) (*MountConfigImportOutput, error) {
+	r := c.c.NewRequest("POST", fmt.Sprintf("/v1/sys/mounts/%s/import-config", path))
+	if err := r.SetJSONBody(input); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.c.RawRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result MountConfigImportOutput
+	err = resp.DecodeJSON(&result)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, err
+}
+
+type MountConfigExport struct {
+	Document  string `json:"document"`
+	Signature string `json:"signature"`
+	PublicKey string `json:"public_key"`
+}
+
+type MountConfigImportInput struct {
+	Document  string `json:"document"`
+	Signature string `json:"signature"`
+	PublicKey string `json:"public_key"`
+	Apply     bool   `json:"apply"`
+	Prune     bool   `json:"prune"`
+}
+
+type MountConfigImportOutput struct {
+	Tune    map[string]interface{} `json:"tune"`
+	Added   []string               `json:"added"`
+	Changed []string               `json:"changed"`
+	Removed []string               `json:"removed"`
+	Applied bool                   `json:"applied"`
+}
+
 type MountInput struct {
-	Type        string           `json:"type" structs:"type"`
-	Description string           `json:"description" structs:"description"`
-	Config      MountConfigInput `json:"config" structs:"config"`
-	Local       bool             `json:"local" structs:"local"`
-	PluginName  string           `json:"plugin_name,omitempty" structs:"plugin_name"`
+	Type        string            `json:"type" structs:"type"`
+	Description string            `json:"description" structs:"description"`
+	Config      MountConfigInput  `json:"config" structs:"config"`
+	Local       bool              `json:"local" structs:"local"`
+	SealWrap    bool              `json:"seal_wrap" structs:"seal_wrap"`
+	PluginName  string            `json:"plugin_name,omitempty" structs:"plugin_name"`
+	Options     map[string]string `json:"options,omitempty" structs:"options"`
 }
 
 type MountConfigInput struct {
@@ -132,6 +199,7 @@ type MountConfigInput struct {
 	MaxLeaseTTL     string `json:"max_lease_ttl" structs:"max_lease_ttl" mapstructure:"max_lease_ttl"`
 	ForceNoCache    bool   `json:"force_no_cache" structs:"force_no_cache" mapstructure:"force_no_cache"`
 	PluginName      string `json:"plugin_name,omitempty" structs:"plugin_name,omitempty" mapstructure:"plugin_name"`
+	PluginVersion   string `json:"plugin_version,omitempty" structs:"plugin_version,omitempty" mapstructure:"plugin_version"`
 }
 
 type MountOutput struct {
@@ -140,6 +208,8 @@ type MountOutput struct {
 	Accessor    string            `json:"accessor" structs:"accessor"`
 	Config      MountConfigOutput `json:"config" structs:"config"`
 	Local       bool              `json:"local" structs:"local"`
+	SealWrap    bool              `json:"seal_wrap" structs:"seal_wrap"`
+	Options     map[string]string `json:"options" structs:"options"`
 }
 
 type MountConfigOutput struct {
@@ -147,4 +217,5 @@ type MountConfigOutput struct {
 	MaxLeaseTTL     int    `json:"max_lease_ttl" structs:"max_lease_ttl" mapstructure:"max_lease_ttl"`
 	ForceNoCache    bool   `json:"force_no_cache" structs:"force_no_cache" mapstructure:"force_no_cache"`
 	PluginName      string `json:"plugin_name,omitempty" structs:"plugin_name,omitempty" mapstructure:"plugin_name"`
+	PluginVersion   string `json:"plugin_version,omitempty" structs:"plugin_version,omitempty" mapstructure:"plugin_version"`
 }
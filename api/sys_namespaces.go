@@ -0,0 +1,86 @@
+package api
+
+// Namespace describes a namespace, whose path is relative to the root
+// namespace
+type Namespace struct {
+	ID   string `json:"id"`
+	Path string `json:"path"`
+}
+
+// ListNamespaces returns the child namespaces of the namespace of the client,
+// keyed by their path relative to it
+func (c *Sys) ListNamespaces() (map[string]*Namespace, error) {
+	r := c.c.NewRequest("LIST", "/v1/sys/namespaces")
+	resp, err := c.c.RawRequest(r)
+	if resp != nil && resp.StatusCode == 404 {
+		resp.Body.Close()
+		return map[string]*Namespace{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Data struct {
+			KeyInfo map[string]*Namespace `json:"key_info"`
+		} `json:"data"`
+	}
+	if err := resp.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+	if result.Data.KeyInfo == nil {
+		return map[string]*Namespace{}, nil
+	}
+	return result.Data.KeyInfo, nil
+}
+
+// GetNamespace returns a child namespace of the namespace of the client, or
+// nil if it doesn't exist
+func (c *Sys) GetNamespace(name string) (*Namespace, error) {
+	r := c.c.NewRequest("GET", "/v1/sys/namespaces/"+name)
+	resp, err := c.c.RawRequest(r)
+	if resp != nil && resp.StatusCode == 404 {
+		resp.Body.Close()
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return decodeNamespace(resp)
+}
+
+// CreateNamespace creates a child namespace of the namespace of the client
+func (c *Sys) CreateNamespace(name string) (*Namespace, error) {
+	r := c.c.NewRequest("PUT", "/v1/sys/namespaces/"+name)
+	resp, err := c.c.RawRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return decodeNamespace(resp)
+}
+
+// DeleteNamespace deletes a child namespace of the namespace of the client,
+// along with everything it contains
+func (c *Sys) DeleteNamespace(name string) error {
+	r := c.c.NewRequest("DELETE", "/v1/sys/namespaces/"+name)
+	resp, err := c.c.RawRequest(r)
+	if err == nil {
+		defer resp.Body.Close()
+	}
+	return err
+}
+
+func decodeNamespace(resp *Response) (*Namespace, error) {
+	var result struct {
+		Data *Namespace `json:"data"`
+	}
+	if err := resp.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+	return result.Data, nil
+}
@@ -0,0 +1,27 @@
+package api
+
+import (
+	"io/ioutil"
+	"strconv"
+)
+
+// Pprof returns the named runtime profile of the server, in the format of the
+// Go pprof tool. The CPU profile, named "profile", and the execution trace,
+// named "trace", are sampled for the given number of seconds, which is
+// ignored for the other profiles.
+func (c *Sys) Pprof(profile string, seconds int) ([]byte, error) {
+	r := c.c.NewRequest("GET", "/v1/sys/pprof/"+profile)
+	if seconds > 0 {
+		r.Params.Set("seconds", strconv.Itoa(seconds))
+	}
+
+	resp, err := c.c.RawRequest(r)
+	if resp != nil {
+		defer resp.Body.Close()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return ioutil.ReadAll(resp.Body)
+}
@@ -0,0 +1,147 @@
+package api
+
+import (
+	"fmt"
+	"io"
+)
+
+// RaftPeer is a server of the raft cluster
+type RaftPeer struct {
+	NodeID  string `json:"node_id"`
+	Address string `json:"address"`
+	Leader  bool   `json:"leader"`
+	Voter   bool   `json:"voter"`
+}
+
+// RaftJoinRequest describes the cluster a node joins. The token of the
+// client must be allowed to add peers on the leader.
+type RaftJoinRequest struct {
+	LeaderAPIAddr string `json:"leader_api_addr"`
+	LeaderCACert  string `json:"leader_ca_cert,omitempty"`
+}
+
+// RaftBootstrap makes the node of the client the single server of a new raft
+// cluster
+func (c *Sys) RaftBootstrap() error {
+	r := c.c.NewRequest("POST", "/v1/sys/storage/raft/bootstrap")
+	resp, err := c.c.RawRequest(r)
+	if err == nil {
+		defer resp.Body.Close()
+	}
+	return err
+}
+
+// RaftJoin adds the node of the client, which must not have any raft state
+// yet, to an existing raft cluster
+func (c *Sys) RaftJoin(opts *RaftJoinRequest) error {
+	r := c.c.NewRequest("POST", "/v1/sys/storage/raft/join")
+	if err := r.SetJSONBody(opts); err != nil {
+		return err
+	}
+
+	resp, err := c.c.RawRequest(r)
+	if err == nil {
+		defer resp.Body.Close()
+	}
+	return err
+}
+
+// RaftConfiguration returns the servers of the raft cluster
+func (c *Sys) RaftConfiguration() ([]*RaftPeer, error) {
+	r := c.c.NewRequest("GET", "/v1/sys/storage/raft/configuration")
+	resp, err := c.c.RawRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Data struct {
+			Servers []*RaftPeer `json:"servers"`
+		} `json:"data"`
+	}
+	if err := resp.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+	return result.Data.Servers, nil
+}
+
+// RaftAddPeerResponse holds the TLS certificate and private key the servers
+// of the raft cluster authenticate each other with, which the added server
+// must be configured with
+type RaftAddPeerResponse struct {
+	TLSCert []byte `json:"tls_cert"`
+	TLSKey  []byte `json:"tls_key"`
+}
+
+// RaftAddPeer adds a voting server to the raft cluster
+func (c *Sys) RaftAddPeer(nodeID, address string) (*RaftAddPeerResponse, error) {
+	r := c.c.NewRequest("POST", "/v1/sys/storage/raft/add-peer")
+	body := map[string]interface{}{
+		"server_id": nodeID,
+		"address":   address,
+	}
+	if err := r.SetJSONBody(body); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.c.RawRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Data *RaftAddPeerResponse `json:"data"`
+	}
+	if err := resp.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+	if result.Data == nil {
+		return nil, fmt.Errorf("no TLS key in the response")
+	}
+	return result.Data, nil
+}
+
+// RaftRemovePeer removes a server from the raft cluster
+func (c *Sys) RaftRemovePeer(nodeID string) error {
+	r := c.c.NewRequest("POST", "/v1/sys/storage/raft/remove-peer")
+	body := map[string]interface{}{
+		"server_id": nodeID,
+	}
+	if err := r.SetJSONBody(body); err != nil {
+		return err
+	}
+
+	resp, err := c.c.RawRequest(r)
+	if err == nil {
+		defer resp.Body.Close()
+	}
+	return err
+}
+
+// RaftSnapshot writes a snapshot of the storage of the raft cluster to w
+func (c *Sys) RaftSnapshot(w io.Writer) error {
+	r := c.c.NewRequest("GET", "/v1/sys/storage/raft/snapshot")
+	resp, err := c.c.RawRequest(r)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// RaftSnapshotRestore replaces the storage of the raft cluster with a
+// snapshot read from snapshot
+func (c *Sys) RaftSnapshotRestore(snapshot io.Reader) error {
+	r := c.c.NewRequest("POST", "/v1/sys/storage/raft/snapshot")
+	r.Body = snapshot
+
+	resp, err := c.c.RawRequest(r)
+	if err == nil {
+		defer resp.Body.Close()
+	}
+	return err
+}
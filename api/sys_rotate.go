@@ -24,7 +24,40 @@ func (c *Sys) KeyStatus() (*KeyStatus, error) {
 	return result, err
 }
 
+func (c *Sys) RotateConfig() (*RotateConfig, error) {
+	r := c.c.NewRequest("GET", "/v1/sys/rotate/config")
+	resp, err := c.c.RawRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	result := new(RotateConfig)
+	err = resp.DecodeJSON(result)
+	return result, err
+}
+
+func (c *Sys) SetRotateConfig(config *RotateConfig) error {
+	r := c.c.NewRequest("PUT", "/v1/sys/rotate/config")
+	if err := r.SetJSONBody(config); err != nil {
+		return err
+	}
+
+	resp, err := c.c.RawRequest(r)
+	if err == nil {
+		defer resp.Body.Close()
+	}
+	return err
+}
+
 type KeyStatus struct {
 	Term        int       `json:"term"`
 	InstallTime time.Time `json:"install_time"`
+	Encryptions int64     `json:"encryptions"`
+}
+
+type RotateConfig struct {
+	Enabled       bool  `json:"enabled"`
+	MaxOperations int64 `json:"max_operations"`
+	Interval      int64 `json:"interval"`
 }
@@ -3,6 +3,7 @@ package audit
 import (
 	"github.com/hashicorp/vault/helper/salt"
 	"github.com/hashicorp/vault/logical"
+	log "github.com/mgutz/logxi/v1"
 )
 
 // Backend interface must be implemented for an audit
@@ -34,6 +35,13 @@ type Backend interface {
 	Invalidate()
 }
 
+// Closer is implemented by the backends holding resources, such as the
+// deliveries of a retry buffer, which must be released once the backend is
+// disabled or the Vault is sealed.
+type Closer interface {
+	Close() error
+}
+
 type BackendConfig struct {
 	// The view to store the salt
 	SaltView logical.Storage
@@ -43,6 +51,10 @@ type BackendConfig struct {
 
 	// Config is the opaque user configuration provided when mounting
 	Config map[string]string
+
+	// Logger is the logger of the backends delivering entries in the
+	// background
+	Logger log.Logger
 }
 
 // Factory is the factory function to create an audit backend.
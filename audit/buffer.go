@@ -0,0 +1,433 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/vault/helper/parseutil"
+	log "github.com/mgutz/logxi/v1"
+)
+
+const (
+	// bufferHeaderSize is the size of the header of a buffer file, holding the
+	// offset of its first entry
+	bufferHeaderSize = 8
+
+	// bufferCompactSize is the amount of delivered entries after which a
+	// buffer file is compacted
+	bufferCompactSize = 4 * 1024 * 1024
+
+	defaultBufferMaxEntries     = 10000
+	defaultBufferMinRetryPeriod = time.Second
+	defaultBufferMaxRetryPeriod = time.Minute
+)
+
+// BufferConfig configures the buffering of the entries of a non-blocking
+// audit backend
+type BufferConfig struct {
+	// Path is the file persisting the buffered entries. Entries are only kept
+	// in memory if empty.
+	Path string
+
+	// MaxEntries is the number of entries buffered before the oldest ones
+	// are dropped
+	MaxEntries int
+
+	// MinRetryInterval and MaxRetryInterval bound the exponential backoff
+	// between the delivery attempts
+	MinRetryInterval time.Duration
+	MaxRetryInterval time.Duration
+}
+
+// ParseBufferConfig parses the buffering options of an audit backend. A nil
+// config is returned unless the backend is non-blocking.
+func ParseBufferConfig(conf map[string]string) (*BufferConfig, error) {
+	if raw, ok := conf["non_blocking"]; !ok {
+		return nil, nil
+	} else if nonBlocking, err := strconv.ParseBool(raw); err != nil {
+		return nil, fmt.Errorf("invalid non_blocking: %v", err)
+	} else if !nonBlocking {
+		return nil, nil
+	}
+
+	bc := &BufferConfig{
+		Path:             conf["buffer_path"],
+		MaxEntries:       defaultBufferMaxEntries,
+		MinRetryInterval: defaultBufferMinRetryPeriod,
+		MaxRetryInterval: defaultBufferMaxRetryPeriod,
+	}
+
+	if raw, ok := conf["buffer_max_entries"]; ok {
+		maxEntries, err := strconv.Atoi(raw)
+		if err != nil || maxEntries <= 0 {
+			return nil, fmt.Errorf("buffer_max_entries must be a positive number")
+		}
+		bc.MaxEntries = maxEntries
+	}
+
+	for option, value := range map[string]*time.Duration{
+		"retry_min_interval": &bc.MinRetryInterval,
+		"retry_max_interval": &bc.MaxRetryInterval,
+	} {
+		raw, ok := conf[option]
+		if !ok {
+			continue
+		}
+		interval, err := parseutil.ParseDurationSecond(raw)
+		if err != nil || interval <= 0 {
+			return nil, fmt.Errorf("%s must be a positive duration", option)
+		}
+		*value = interval
+	}
+	if bc.MinRetryInterval > bc.MaxRetryInterval {
+		return nil, fmt.Errorf("retry_min_interval cannot be greater than retry_max_interval")
+	}
+
+	return bc, nil
+}
+
+// Buffer queues the entries of a non-blocking audit backend and delivers them
+// in the background, retrying with an exponential backoff while the
+// destination is unavailable. Once MaxEntries are queued the oldest entries
+// are dropped. If a path is configured, the queued entries are also written
+// to a file so that they survive restarts.
+type Buffer struct {
+	config BufferConfig
+	send   func([]byte) error
+	logger log.Logger
+
+	l       sync.Mutex
+	entries [][]byte
+
+	// first counts the entries removed from the queue, identifying the
+	// entry at its front
+	first uint64
+
+	// file holds a header with the offset of the first queued entry, followed
+	// by the length prefixed entries
+	file *os.File
+	head int64
+	size int64
+
+	notifyCh chan struct{}
+	stopCh   chan struct{}
+	doneCh   chan struct{}
+}
+
+// NewBuffer creates a buffer delivering its entries with the given function,
+// restoring the entries queued in its file if any
+func NewBuffer(config *BufferConfig, send func([]byte) error, logger log.Logger) (*Buffer, error) {
+	b := &Buffer{
+		config:   *config,
+		send:     send,
+		logger:   logger,
+		head:     bufferHeaderSize,
+		size:     bufferHeaderSize,
+		notifyCh: make(chan struct{}, 1),
+		stopCh:   make(chan struct{}),
+		doneCh:   make(chan struct{}),
+	}
+	if b.logger == nil {
+		b.logger = log.NullLog
+	}
+
+	if config.Path != "" {
+		if err := b.open(); err != nil {
+			return nil, err
+		}
+	}
+
+	go b.run()
+	if len(b.entries) > 0 {
+		b.notify()
+	}
+	return b, nil
+}
+
+// open opens the buffer file and loads the entries it holds
+func (b *Buffer) open() error {
+	f, err := os.OpenFile(b.config.Path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit buffer: %v", err)
+	}
+	b.file = f
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() < bufferHeaderSize {
+		return b.reset()
+	}
+
+	var header [bufferHeaderSize]byte
+	if _, err := f.ReadAt(header[:], 0); err != nil {
+		return err
+	}
+	head := int64(binary.BigEndian.Uint64(header[:]))
+	if head < bufferHeaderSize || head > info.Size() {
+		b.logger.Warn("audit: discarding corrupted buffer", "path", b.config.Path)
+		return b.reset()
+	}
+
+	// Load the entries, ignoring a partially written trailing entry
+	r := bufio.NewReader(io.NewSectionReader(f, head, info.Size()-head))
+	offset := head
+	for {
+		var length [4]byte
+		if _, err := io.ReadFull(r, length[:]); err != nil {
+			break
+		}
+		entry := make([]byte, binary.BigEndian.Uint32(length[:]))
+		if _, err := io.ReadFull(r, entry); err != nil {
+			break
+		}
+		b.entries = append(b.entries, entry)
+		offset += int64(len(length) + len(entry))
+	}
+	b.head = head
+	b.size = offset
+	if offset != info.Size() {
+		if err := f.Truncate(offset); err != nil {
+			return err
+		}
+	}
+
+	// Apply a lower maximum than the one the file was written with
+	for len(b.entries) > b.config.MaxEntries {
+		b.dropLocked()
+	}
+	return nil
+}
+
+// reset empties the buffer file
+func (b *Buffer) reset() error {
+	if err := b.file.Truncate(0); err != nil {
+		return err
+	}
+	b.head = bufferHeaderSize
+	b.size = bufferHeaderSize
+	return b.writeHead()
+}
+
+func (b *Buffer) writeHead() error {
+	var header [bufferHeaderSize]byte
+	binary.BigEndian.PutUint64(header[:], uint64(b.head))
+	_, err := b.file.WriteAt(header[:], 0)
+	return err
+}
+
+// Write queues an entry for delivery
+func (b *Buffer) Write(entry []byte) {
+	b.l.Lock()
+	defer b.l.Unlock()
+
+	if len(b.entries) >= b.config.MaxEntries {
+		b.dropLocked()
+		metrics.IncrCounter([]string{"audit", "buffer", "dropped"}, 1)
+	}
+
+	entry = append([]byte(nil), entry...)
+	b.entries = append(b.entries, entry)
+
+	if b.file != nil {
+		record := make([]byte, 4+len(entry))
+		binary.BigEndian.PutUint32(record, uint32(len(entry)))
+		copy(record[4:], entry)
+		if _, err := b.file.WriteAt(record, b.size); err != nil {
+			b.persistFailed(err)
+		} else {
+			b.size += int64(len(record))
+		}
+	}
+
+	b.notify()
+}
+
+// Len returns the number of queued entries
+func (b *Buffer) Len() int {
+	b.l.Lock()
+	defer b.l.Unlock()
+	return len(b.entries)
+}
+
+// Close stops delivering the entries. The queued entries are kept in the
+// buffer file, if any.
+func (b *Buffer) Close() error {
+	close(b.stopCh)
+	<-b.doneCh
+
+	b.l.Lock()
+	defer b.l.Unlock()
+	if b.file == nil {
+		return nil
+	}
+	err := b.file.Close()
+	b.file = nil
+	return err
+}
+
+func (b *Buffer) notify() {
+	select {
+	case b.notifyCh <- struct{}{}:
+	default:
+	}
+}
+
+func (b *Buffer) run() {
+	defer close(b.doneCh)
+
+	backoff := b.config.MinRetryInterval
+	for {
+		b.l.Lock()
+		queued := len(b.entries) > 0
+		var entry []byte
+		if queued {
+			entry = b.entries[0]
+		}
+		first := b.first
+		b.l.Unlock()
+
+		if !queued {
+			select {
+			case <-b.notifyCh:
+				continue
+			case <-b.stopCh:
+				return
+			}
+		}
+
+		if err := b.send(entry); err != nil {
+			metrics.IncrCounter([]string{"audit", "buffer", "retry"}, 1)
+			b.logger.Warn("audit: failed to deliver buffered entry, retrying", "backoff", backoff, "error", err)
+			select {
+			case <-time.After(backoff):
+			case <-b.stopCh:
+				return
+			}
+			backoff *= 2
+			if backoff > b.config.MaxRetryInterval {
+				backoff = b.config.MaxRetryInterval
+			}
+			continue
+		}
+		backoff = b.config.MinRetryInterval
+
+		b.l.Lock()
+		// The entry may have been dropped while it was being delivered
+		if b.first == first {
+			b.dropLocked()
+		}
+		b.l.Unlock()
+
+		b.compact()
+	}
+}
+
+// dropLocked removes the oldest entry. The lock must be held.
+func (b *Buffer) dropLocked() {
+	entry := b.entries[0]
+	b.entries[0] = nil
+	b.entries = b.entries[1:]
+	b.first++
+
+	if b.file == nil {
+		return
+	}
+
+	var err error
+	if len(b.entries) == 0 {
+		err = b.reset()
+	} else {
+		b.head += int64(4 + len(entry))
+		err = b.writeHead()
+	}
+	if err != nil {
+		b.persistFailed(err)
+	}
+}
+
+// persistFailed stops writing the entries to the buffer file, which no longer
+// matches the queue, and keeps them in memory only. The lock must be held.
+func (b *Buffer) persistFailed(err error) {
+	b.logger.Error("audit: failed to update buffer file, buffering in memory only", "path", b.config.Path, "error", err)
+	b.file.Close()
+	b.file = nil
+}
+
+// compact rewrites the buffer file without the delivered entries once they
+// take most of it. It runs in the delivery goroutine, and copies the queued
+// entries without holding the lock so that Write is not blocked; the entries
+// written meanwhile are appended once the lock is taken back.
+func (b *Buffer) compact() {
+	b.l.Lock()
+	file, head, size := b.file, b.head, b.size
+	b.l.Unlock()
+	if file == nil || head-bufferHeaderSize <= bufferCompactSize || head <= size/2 {
+		return
+	}
+
+	tmpPath := b.config.Path + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err == nil {
+		_, err = tmp.Write(make([]byte, bufferHeaderSize))
+	}
+	if err == nil {
+		_, err = io.Copy(tmp, io.NewSectionReader(file, head, size-head))
+	}
+
+	b.l.Lock()
+	defer b.l.Unlock()
+
+	// The file may have been reset or given up on during the copy
+	if b.file != file || b.head < head || b.size < size {
+		if tmp != nil {
+			tmp.Close()
+			os.Remove(tmpPath)
+		}
+		return
+	}
+	if err == nil {
+		err = b.swapCompacted(tmp, tmpPath, head, size)
+	}
+	if err != nil {
+		if tmp != nil {
+			tmp.Close()
+			os.Remove(tmpPath)
+		}
+		b.persistFailed(err)
+	}
+}
+
+// swapCompacted completes a compacted copy of the file, holding the entries
+// from head to size, and replaces the file with it. The lock must be held.
+func (b *Buffer) swapCompacted(tmp *os.File, tmpPath string, head, size int64) error {
+	// Append the entries written during the copy
+	if _, err := io.Copy(tmp, io.NewSectionReader(b.file, size, b.size-size)); err != nil {
+		return err
+	}
+
+	// Entries may also have been dropped during the copy
+	var header [bufferHeaderSize]byte
+	newHead := bufferHeaderSize + b.head - head
+	binary.BigEndian.PutUint64(header[:], uint64(newHead))
+	if _, err := tmp.WriteAt(header[:], 0); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, b.config.Path); err != nil {
+		return err
+	}
+
+	b.file.Close()
+	b.file = tmp
+	b.size = bufferHeaderSize + b.size - head
+	b.head = newHead
+	return nil
+}
@@ -0,0 +1,228 @@
+package audit
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testSink struct {
+	sync.Mutex
+	failures int
+	entries  []string
+}
+
+func (s *testSink) send(entry []byte) error {
+	s.Lock()
+	defer s.Unlock()
+	if s.failures != 0 {
+		if s.failures > 0 {
+			s.failures--
+		}
+		return fmt.Errorf("unavailable")
+	}
+	s.entries = append(s.entries, string(entry))
+	return nil
+}
+
+func (s *testSink) waitFor(t *testing.T, expected []string) {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		s.Lock()
+		entries := append([]string(nil), s.entries...)
+		s.Unlock()
+		if reflect.DeepEqual(entries, expected) {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("bad: expected %v, got %v", expected, entries)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func testBufferConfig(path string) *BufferConfig {
+	return &BufferConfig{
+		Path:             path,
+		MaxEntries:       3,
+		MinRetryInterval: time.Millisecond,
+		MaxRetryInterval: 5 * time.Millisecond,
+	}
+}
+
+func TestBuffer_Retry(t *testing.T) {
+	sink := &testSink{failures: 3}
+	b, err := NewBuffer(testBufferConfig(""), sink.send, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	b.Write([]byte("a"))
+	b.Write([]byte("b"))
+	sink.waitFor(t, []string{"a", "b"})
+	if b.Len() != 0 {
+		t.Fatalf("bad: %d", b.Len())
+	}
+}
+
+func TestBuffer_Persist(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vault-audit-buffer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "buffer")
+
+	// The oldest entries are dropped once the buffer is full
+	unavailable := &testSink{failures: -1}
+	b, err := NewBuffer(testBufferConfig(path), unavailable.send, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range []string{"a", "", "c", "d"} {
+		b.Write([]byte(entry))
+	}
+	if b.Len() != 3 {
+		t.Fatalf("bad: %d", b.Len())
+	}
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A partially written entry is ignored
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte{0, 0, 0, 8, 'e'}); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	// The queued entries are delivered once restored
+	sink := &testSink{}
+	b, err = NewBuffer(testBufferConfig(path), sink.send, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink.waitFor(t, []string{"", "c", "d"})
+
+	b.Write([]byte("f"))
+	sink.waitFor(t, []string{"", "c", "d", "f"})
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != bufferHeaderSize {
+		t.Fatalf("bad: %d", info.Size())
+	}
+}
+
+func TestBuffer_Compact(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vault-audit-buffer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "buffer")
+
+	// Write a buffer file mostly holding delivered entries
+	head := int64(bufferHeaderSize + bufferCompactSize + 1)
+	data := make([]byte, head)
+	binary.BigEndian.PutUint64(data, uint64(head))
+	for _, entry := range []string{"a", "b", "c"} {
+		record := make([]byte, 4+len(entry))
+		binary.BigEndian.PutUint32(record, uint32(len(entry)))
+		copy(record[4:], entry)
+		data = append(data, record...)
+	}
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// The file is compacted once an entry is delivered
+	sink := &testSink{}
+	b, err := NewBuffer(testBufferConfig(path), func(entry []byte) error {
+		if string(entry) != "a" {
+			return fmt.Errorf("unavailable")
+		}
+		return sink.send(entry)
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink.waitFor(t, []string{"a"})
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Size() == bufferHeaderSize+2*5 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("bad: size %d", info.Size())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	sink = &testSink{}
+	b, err = NewBuffer(testBufferConfig(path), sink.send, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	sink.waitFor(t, []string{"b", "c"})
+}
+
+func TestParseBufferConfig(t *testing.T) {
+	bc, err := ParseBufferConfig(map[string]string{"address": "127.0.0.1:9090"})
+	if err != nil || bc != nil {
+		t.Fatalf("bad: %#v, err: %v", bc, err)
+	}
+
+	bc, err = ParseBufferConfig(map[string]string{
+		"non_blocking":       "true",
+		"buffer_path":        "/tmp/buffer",
+		"buffer_max_entries": "10",
+		"retry_max_interval": "30s",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &BufferConfig{
+		Path:             "/tmp/buffer",
+		MaxEntries:       10,
+		MinRetryInterval: time.Second,
+		MaxRetryInterval: 30 * time.Second,
+	}
+	if !reflect.DeepEqual(bc, expected) {
+		t.Fatalf("bad: %#v", bc)
+	}
+
+	for _, conf := range []map[string]string{
+		{"non_blocking": "maybe"},
+		{"non_blocking": "true", "buffer_max_entries": "0"},
+		{"non_blocking": "true", "retry_min_interval": "-1s"},
+		{"non_blocking": "true", "retry_min_interval": "2m"},
+	} {
+		if _, err := ParseBufferConfig(conf); err == nil {
+			t.Fatalf("expected an error for %#v", conf)
+		}
+	}
+}
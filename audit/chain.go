@@ -0,0 +1,145 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/hashicorp/vault/helper/salt"
+)
+
+// ChainVerifier is implemented by the backends which can chain their entries
+// by including in each of them the hash of the previous one
+type ChainVerifier interface {
+	// VerifyChain verifies the chain of the entries of the given log file, or
+	// of the current log file of the backend if empty
+	VerifyChain(path string) (*ChainVerification, error)
+}
+
+// ChainVerification is the result of the verification of a chained audit log
+type ChainVerification struct {
+	// Valid is whether the chain is unbroken
+	Valid bool
+
+	// Error describes why the chain is broken, and Line is the line of the
+	// first invalid entry if any
+	Error string
+	Line  int
+
+	// Entries is the number of chained entries which were verified, and
+	// UnchainedEntries the number of leading entries written before the
+	// chaining was enabled
+	Entries          int
+	UnchainedEntries int
+
+	// FirstPrevHash is the hash which the first chained entry links to, which
+	// is the last hash of the previous log file if the log was rotated.
+	// ChainStart is set if the chain starts in the log instead. The first
+	// chained entry following unchained entries links to the last of them.
+	FirstPrevHash string
+	ChainStart    bool
+
+	// LastHash is the hash of the last entry, which the first entry of the
+	// next log file links to
+	LastHash string
+}
+
+// ChainHead is the end of the chain of the current log file of a backend. It
+// is persisted in the storage of the backend rather than read from the log
+// file, so that the truncation of the file is detected.
+type ChainHead struct {
+	// Anchor is the hash the first chained entry of the log file links to,
+	// so that the removal of the leading entries is detected
+	Anchor string `json:"anchor"`
+
+	// Seq is the sequence number of the last entry written to the log file,
+	// counted from its first chained entry, and Hash is its hash
+	Seq  uint64 `json:"seq"`
+	Hash string `json:"hash"`
+}
+
+// ChainHash returns the hash of a line of a chained audit log, which is
+// included in the following entry
+func ChainHash(salter *salt.Salt, line []byte) string {
+	return salter.GetIdentifiedHMAC(string(line))
+}
+
+// ChainGenesis returns the hash the first entry of a chain links to
+func ChainGenesis(salter *salt.Salt) string {
+	return salter.GetIdentifiedHMAC("")
+}
+
+// VerifyChain reads the lines of a chained audit log and verifies that each
+// entry links to the previous one, so that modified, inserted and removed
+// entries are detected. The head of the chain of the log, if given, is compared
+// to its first and last entries to detect the removal of the leading entries
+// and the truncation of the log.
+func VerifyChain(r io.Reader, prefix string, salter *salt.Salt, head *ChainHead) (*ChainVerification, error) {
+	result := &ChainVerification{Valid: true}
+	fail := func(line int, msg string) (*ChainVerification, error) {
+		result.Valid = false
+		result.Line = line
+		result.Error = msg
+		return result, nil
+	}
+
+	genesis := ChainGenesis(salter)
+	br := bufio.NewReader(r)
+
+	// prev is the hash of the previous line, and chained is set once the
+	// first chained entry is read
+	var prev string
+	var chained bool
+	for lineNum := 1; ; lineNum++ {
+		line, err := br.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if len(line) == 0 && err == io.EOF {
+			break
+		}
+		line = bytes.TrimSuffix(line, []byte("\n"))
+
+		if !bytes.HasPrefix(line, []byte(prefix)) {
+			return fail(lineNum, "entry does not start with the prefix of the backend")
+		}
+		var entry struct {
+			PrevHash string `json:"prev_hash"`
+		}
+		if err := json.Unmarshal(line[len(prefix):], &entry); err != nil {
+			return fail(lineNum, "entry is not valid JSON")
+		}
+
+		switch {
+		case entry.PrevHash == "":
+			if chained {
+				return fail(lineNum, "entry is not chained")
+			}
+			result.UnchainedEntries++
+
+		case prev == "":
+			// The first line links to the previous log file, if any
+			result.FirstPrevHash = entry.PrevHash
+			result.ChainStart = entry.PrevHash == genesis
+
+		case entry.PrevHash != prev:
+			return fail(lineNum, "entry does not link to the previous entry, which was modified or removed")
+		}
+
+		if entry.PrevHash != "" {
+			if !chained && head != nil && entry.PrevHash != head.Anchor {
+				return fail(lineNum, "the first entry does not match the first entry written, leading entries were removed")
+			}
+			chained = true
+			result.Entries++
+		}
+		prev = ChainHash(salter, line)
+	}
+
+	result.LastHash = prev
+	if head != nil && (uint64(result.Entries) != head.Seq || head.Seq > 0 && prev != head.Hash) {
+		return fail(0, "the last entry does not match the last entry written, the log was truncated or modified")
+	}
+	return result, nil
+}
@@ -0,0 +1,114 @@
+package audit
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/helper/salt"
+)
+
+func testChainedLog(t *testing.T, salter *salt.Salt, prefix string, unchained, chained int) ([]string, string) {
+	var lines []string
+	prev := ""
+	for i := 0; i < unchained; i++ {
+		line := fmt.Sprintf(`%s{"type":"request","n":%d}`, prefix, i)
+		lines = append(lines, line)
+		prev = ChainHash(salter, []byte(line))
+	}
+	if prev == "" {
+		prev = ChainGenesis(salter)
+	}
+	for i := 0; i < chained; i++ {
+		line := fmt.Sprintf(`%s{"type":"request","n":%d,"prev_hash":%q}`, prefix, unchained+i, prev)
+		lines = append(lines, line)
+		prev = ChainHash(salter, []byte(line))
+	}
+	return lines, prev
+}
+
+func TestVerifyChain(t *testing.T) {
+	salter, err := salt.NewSalt(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verify := func(lines []string, prefix string, head *ChainHead) *ChainVerification {
+		result, err := VerifyChain(strings.NewReader(strings.Join(lines, "\n")+"\n"), prefix, salter, head)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	lines, last := testChainedLog(t, salter, "", 0, 3)
+	head := &ChainHead{Anchor: ChainGenesis(salter), Seq: 3, Hash: last}
+	result := verify(lines, "", head)
+	if !result.Valid || result.Entries != 3 || !result.ChainStart || result.LastHash != last {
+		t.Fatalf("bad: %#v", result)
+	}
+
+	// Entries written before the chaining was enabled are reported
+	lines, last = testChainedLog(t, salter, "@cee: ", 2, 2)
+	head = &ChainHead{Anchor: ChainHash(salter, []byte(lines[1])), Seq: 2, Hash: last}
+	result = verify(lines, "@cee: ", head)
+	if !result.Valid || result.Entries != 2 || result.UnchainedEntries != 2 || result.ChainStart {
+		t.Fatalf("bad: %#v", result)
+	}
+
+	lines, last = testChainedLog(t, salter, "", 1, 4)
+	head = &ChainHead{Anchor: ChainHash(salter, []byte(lines[0])), Seq: 4, Hash: last}
+	cases := map[string]struct {
+		lines []string
+		line  int
+	}{
+		"modified": {
+			[]string{lines[0], lines[1], strings.Replace(lines[2], `"n":2`, `"n":9`, 1), lines[3], lines[4]},
+			4,
+		},
+		"removed": {
+			[]string{lines[0], lines[1], lines[3], lines[4]},
+			3,
+		},
+		"unchained insertion": {
+			[]string{lines[0], lines[1], lines[2], `{"type":"request"}`, lines[3], lines[4]},
+			4,
+		},
+		"truncated": {
+			lines[:4],
+			0,
+		},
+		"modified first entry": {
+			[]string{`{"type":"response"}`, lines[1], lines[2], lines[3], lines[4]},
+			2,
+		},
+		"removed leading entries": {
+			lines[2:],
+			1,
+		},
+	}
+	for name, tc := range cases {
+		result := verify(tc.lines, "", head)
+		if result.Valid || result.Line != tc.line || result.Error == "" {
+			t.Fatalf("bad: %s: %#v", name, result)
+		}
+	}
+
+	// Without the head of the chain, only the links are verified
+	if result := verify(lines[2:4], "", nil); !result.Valid {
+		t.Fatalf("bad: %#v", result)
+	}
+
+	// A rotated log links to the previous one
+	rotated, _ := testChainedLog(t, salter, "", 0, 2)
+	result = verify(rotated[1:], "", nil)
+	if !result.Valid || result.ChainStart || result.FirstPrevHash != ChainHash(salter, []byte(rotated[0])) {
+		t.Fatalf("bad: %#v", result)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("not json\n")
+	if result, err := VerifyChain(&buf, "", salter, nil); err != nil || result.Valid || result.Line != 1 {
+		t.Fatalf("bad: %#v, err: %v", result, err)
+	}
+}
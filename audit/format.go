@@ -91,17 +91,19 @@ func (f *AuditFormatter) FormatRequest(
 			}
 		}
 
-		// Cache and restore accessor in the request
+		// Cache and restore accessor and exempted fields in the request
 		var clientTokenAccessor string
 		if !config.HMACAccessor && req != nil && req.ClientTokenAccessor != "" {
 			clientTokenAccessor = req.ClientTokenAccessor
 		}
+		reqExempt := exemptFields(config, req.Data)
 		if err := Hash(salt, req); err != nil {
 			return err
 		}
 		if clientTokenAccessor != "" {
 			req.ClientTokenAccessor = clientTokenAccessor
 		}
+		restoreFields(req.Data, reqExempt)
 	}
 
 	// If auth is nil, make an empty one
@@ -148,6 +150,8 @@ func (f *AuditFormatter) FormatRequest(
 		reqEntry.Time = time.Now().UTC().Format(time.RFC3339)
 	}
 
+	reqEntry.PrevHash = config.PrevHash
+
 	return f.AuditFormatWriter.WriteRequest(w, reqEntry)
 }
 
@@ -227,17 +231,19 @@ func (f *AuditFormatter) FormatResponse(
 			}
 		}
 
-		// Cache and restore accessor in the request
+		// Cache and restore accessor and exempted fields in the request
 		var clientTokenAccessor string
 		if !config.HMACAccessor && req != nil && req.ClientTokenAccessor != "" {
 			clientTokenAccessor = req.ClientTokenAccessor
 		}
+		reqExempt := exemptFields(config, req.Data)
 		if err := Hash(salt, req); err != nil {
 			return err
 		}
 		if clientTokenAccessor != "" {
 			req.ClientTokenAccessor = clientTokenAccessor
 		}
+		restoreFields(req.Data, reqExempt)
 
 		// Cache and restore accessor in the response
 		if resp != nil {
@@ -248,12 +254,14 @@ func (f *AuditFormatter) FormatResponse(
 			if !config.HMACAccessor && resp != nil && resp.WrapInfo != nil && resp.WrapInfo.WrappedAccessor != "" {
 				wrappedAccessor = resp.WrapInfo.WrappedAccessor
 			}
+			respExempt := exemptFields(config, resp.Data)
 			if err := Hash(salt, resp); err != nil {
 				return err
 			}
 			if accessor != "" {
 				resp.Auth.Accessor = accessor
 			}
+			restoreFields(resp.Data, respExempt)
 			if wrappedAccessor != "" {
 				resp.WrapInfo.WrappedAccessor = wrappedAccessor
 			}
@@ -348,6 +356,8 @@ func (f *AuditFormatter) FormatResponse(
 		respEntry.Time = time.Now().UTC().Format(time.RFC3339)
 	}
 
+	respEntry.PrevHash = config.PrevHash
+
 	return f.AuditFormatWriter.WriteResponse(w, respEntry)
 }
 
@@ -358,6 +368,9 @@ type AuditRequestEntry struct {
 	Auth    AuditAuth    `json:"auth"`
 	Request AuditRequest `json:"request"`
 	Error   string       `json:"error"`
+
+	// PrevHash is the hash of the previous entry of a chained log
+	PrevHash string `json:"prev_hash,omitempty"`
 }
 
 // AuditResponseEntry is the structure of a response audit log entry in Audit.
@@ -368,6 +381,9 @@ type AuditResponseEntry struct {
 	Request  AuditRequest  `json:"request"`
 	Response AuditResponse `json:"response"`
 	Error    string        `json:"error"`
+
+	// PrevHash is the hash of the previous entry of a chained log
+	PrevHash string `json:"prev_hash,omitempty"`
 }
 
 type AuditRequest struct {
@@ -422,6 +438,32 @@ func getRemoteAddr(req *logical.Request) string {
 	return ""
 }
 
+// exemptFields returns the values of the data fields which are exempted from
+// hashing
+func exemptFields(config FormatterConfig, data map[string]interface{}) map[string]interface{} {
+	if len(config.HMACExemptFields) == 0 || len(data) == 0 {
+		return nil
+	}
+	fields := make(map[string]interface{})
+	for _, field := range config.HMACExemptFields {
+		if value, ok := data[field]; ok {
+			fields[field] = value
+		}
+	}
+	return fields
+}
+
+// restoreFields restores the values of the exempted fields once the data has
+// been hashed
+func restoreFields(data map[string]interface{}, fields map[string]interface{}) {
+	if data == nil {
+		return
+	}
+	for field, value := range fields {
+		data[field] = value
+	}
+}
+
 // parseVaultTokenFromJWT returns a string iff the token was a JWT and we could
 // extract the original token ID from inside
 func parseVaultTokenFromJWT(token string) *string {
@@ -61,3 +61,71 @@ func TestFormatResponseErrors(t *testing.T) {
 		t.Fatal("expected error due to nil writer")
 	}
 }
+
+type captureFormatWriter struct {
+	noopFormatWriter
+	req  *AuditRequestEntry
+	resp *AuditResponseEntry
+}
+
+func (c *captureFormatWriter) WriteRequest(_ io.Writer, entry *AuditRequestEntry) error {
+	c.req = entry
+	return nil
+}
+
+func (c *captureFormatWriter) WriteResponse(_ io.Writer, entry *AuditResponseEntry) error {
+	c.resp = entry
+	return nil
+}
+
+func TestFormat_HMACExemptFields(t *testing.T) {
+	writer := &captureFormatWriter{}
+	formatter := AuditFormatter{
+		AuditFormatWriter: writer,
+	}
+	config := FormatterConfig{
+		HMACExemptFields: []string{"common_name", "role_name"},
+	}
+	salter, err := writer.Salt()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "pki/issue/web",
+		Data: map[string]interface{}{
+			"common_name": "www.example.com",
+			"ttl":         "1h",
+		},
+	}
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"role_name":   "web",
+			"private_key": "secret",
+		},
+	}
+
+	if err := formatter.FormatResponse(ioutil.Discard, config, nil, req, resp, nil); err != nil {
+		t.Fatal(err)
+	}
+	reqData, respData := writer.resp.Request.Data, writer.resp.Response.Data
+	if reqData["common_name"] != "www.example.com" || reqData["ttl"] != salter.GetIdentifiedHMAC("1h") {
+		t.Fatalf("bad: %#v", reqData)
+	}
+	if respData["role_name"] != "web" || respData["private_key"] != salter.GetIdentifiedHMAC("secret") {
+		t.Fatalf("bad: %#v", respData)
+	}
+
+	if err := formatter.FormatRequest(ioutil.Discard, config, nil, req, nil); err != nil {
+		t.Fatal(err)
+	}
+	if writer.req.Request.Data["common_name"] != "www.example.com" {
+		t.Fatalf("bad: %#v", writer.req.Request.Data)
+	}
+
+	// The original data is left untouched
+	if req.Data["ttl"] != "1h" || resp.Data["private_key"] != "secret" {
+		t.Fatalf("bad: %#v %#v", req.Data, resp.Data)
+	}
+}
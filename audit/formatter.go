@@ -20,6 +20,14 @@ type FormatterConfig struct {
 	Raw          bool
 	HMACAccessor bool
 
+	// HMACExemptFields are the keys of the request and response data whose
+	// values are logged without being hashed
+	HMACExemptFields []string
+
+	// PrevHash is the hash of the previous entry, included in the entry when
+	// the backend chains its entries
+	PrevHash string
+
 	// This should only ever be used in a testing context
 	OmitTime bool
 }
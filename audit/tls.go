@@ -0,0 +1,53 @@
+package audit
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+)
+
+// ParseTLSConfig parses the TLS options of the audit backends shipping
+// entries over the network
+func ParseTLSConfig(conf map[string]string) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: conf["tls_server_name"],
+	}
+
+	if raw, ok := conf["tls_skip_verify"]; ok {
+		skipVerify, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tls_skip_verify: %v", err)
+		}
+		tlsConfig.InsecureSkipVerify = skipVerify
+	}
+
+	if caCert := conf["tls_ca_cert"]; caCert != "" {
+		pem, err := ioutil.ReadFile(caCert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tls_ca_cert: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in tls_ca_cert")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	certFile, keyFile := conf["tls_cert_file"], conf["tls_key_file"]
+	switch {
+	case certFile == "" && keyFile == "":
+	case certFile == "" || keyFile == "":
+		return nil, fmt.Errorf("tls_cert_file and tls_key_file must be set together")
+	default:
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
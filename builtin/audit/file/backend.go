@@ -1,7 +1,9 @@
 package file
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -10,10 +12,16 @@ import (
 	"sync"
 
 	"github.com/hashicorp/vault/audit"
+	"github.com/hashicorp/vault/helper/jsonutil"
 	"github.com/hashicorp/vault/helper/salt"
+	"github.com/hashicorp/vault/helper/strutil"
 	"github.com/hashicorp/vault/logical"
 )
 
+// chainHeadPath is the path in the salt view of the head of the chain of the
+// log file
+const chainHeadPath = "chain_head"
+
 func Factory(conf *audit.BackendConfig) (audit.Backend, error) {
 	if conf.SaltConfig == nil {
 		return nil, fmt.Errorf("nil salt config")
@@ -58,6 +66,9 @@ func Factory(conf *audit.BackendConfig) (audit.Backend, error) {
 		hmacAccessor = value
 	}
 
+	// Data fields which are logged without being hashed
+	hmacExemptFields := strutil.ParseDedupAndSortStrings(conf.Config["hmac_exempt_fields"], ",")
+
 	// Check if raw logging is enabled
 	logRaw := false
 	if raw, ok := conf.Config["log_raw"]; ok {
@@ -68,6 +79,19 @@ func Factory(conf *audit.BackendConfig) (audit.Backend, error) {
 		logRaw = b
 	}
 
+	// Check if the entries must be chained
+	hashChain := false
+	if raw, ok := conf.Config["hash_chain"]; ok {
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, err
+		}
+		if value && format != "json" {
+			return nil, fmt.Errorf("hash_chain is only supported with the json format")
+		}
+		hashChain = value
+	}
+
 	// Check if mode is provided
 	mode := os.FileMode(0600)
 	if modeRaw, ok := conf.Config["mode"]; ok {
@@ -81,11 +105,14 @@ func Factory(conf *audit.BackendConfig) (audit.Backend, error) {
 	b := &Backend{
 		path:       path,
 		mode:       mode,
+		prefix:     conf.Config["prefix"],
+		hashChain:  hashChain,
 		saltConfig: conf.SaltConfig,
 		saltView:   conf.SaltView,
 		formatConfig: audit.FormatterConfig{
-			Raw:          logRaw,
-			HMACAccessor: hmacAccessor,
+			Raw:              logRaw,
+			HMACAccessor:     hmacAccessor,
+			HMACExemptFields: hmacExemptFields,
 		},
 	}
 
@@ -131,6 +158,13 @@ type Backend struct {
 	fileLock sync.RWMutex
 	f        *os.File
 	mode     os.FileMode
+	prefix   string
+
+	// hashChain is set if each entry includes the hash of the previous one.
+	// chain is the head of the chain of the log file, which is persisted in
+	// the salt view.
+	hashChain bool
+	chain     *audit.ChainHead
 
 	saltMutex  sync.RWMutex
 	salt       *salt.Salt
@@ -167,33 +201,9 @@ func (b *Backend) GetHash(data string) (string, error) {
 }
 
 func (b *Backend) LogRequest(auth *logical.Auth, req *logical.Request, outerErr error) error {
-	b.fileLock.Lock()
-	defer b.fileLock.Unlock()
-
-	switch b.path {
-	case "stdout":
-		return b.formatter.FormatRequest(os.Stdout, b.formatConfig, auth, req, outerErr)
-	case "discard":
-		return b.formatter.FormatRequest(ioutil.Discard, b.formatConfig, auth, req, outerErr)
-	}
-
-	if err := b.open(); err != nil {
-		return err
-	}
-
-	if err := b.formatter.FormatRequest(b.f, b.formatConfig, auth, req, outerErr); err == nil {
-		return nil
-	}
-
-	// Opportunistically try to re-open the FD, once per call
-	b.f.Close()
-	b.f = nil
-
-	if err := b.open(); err != nil {
-		return err
-	}
-
-	return b.formatter.FormatRequest(b.f, b.formatConfig, auth, req, outerErr)
+	return b.log(func(w io.Writer, config audit.FormatterConfig) error {
+		return b.formatter.FormatRequest(w, config, auth, req, outerErr)
+	})
 }
 
 func (b *Backend) LogResponse(
@@ -202,33 +212,73 @@ func (b *Backend) LogResponse(
 	resp *logical.Response,
 	err error) error {
 
+	return b.log(func(w io.Writer, config audit.FormatterConfig) error {
+		return b.formatter.FormatResponse(w, config, auth, req, resp, err)
+	})
+}
+
+// log writes an entry formatted by the given function
+func (b *Backend) log(format func(io.Writer, audit.FormatterConfig) error) error {
 	b.fileLock.Lock()
 	defer b.fileLock.Unlock()
 
 	switch b.path {
 	case "stdout":
-		return b.formatter.FormatResponse(os.Stdout, b.formatConfig, auth, req, resp, err)
+		return format(os.Stdout, b.formatConfig)
 	case "discard":
-		return b.formatter.FormatResponse(ioutil.Discard, b.formatConfig, auth, req, resp, err)
+		return format(ioutil.Discard, b.formatConfig)
 	}
 
 	if err := b.open(); err != nil {
 		return err
 	}
 
-	if err := b.formatter.FormatResponse(b.f, b.formatConfig, auth, req, resp, err); err == nil {
-		return nil
+	write := func(w io.Writer) error {
+		return format(w, b.formatConfig)
 	}
 
-	// Opportunistically try to re-open the FD, once per call
-	b.f.Close()
-	b.f = nil
+	// Chained entries are formatted beforehand, as the hash of the line
+	// written is needed for the next entry
+	var line []byte
+	if b.hashChain {
+		config := b.formatConfig
+		config.PrevHash = b.chain.Hash
+		var buf bytes.Buffer
+		if err := format(&buf, config); err != nil {
+			return err
+		}
+		line = buf.Bytes()
+		write = func(w io.Writer) error {
+			_, err := w.Write(line)
+			return err
+		}
+	}
 
-	if err := b.open(); err != nil {
-		return err
+	err := write(b.f)
+	if err != nil {
+		// Opportunistically try to re-open the FD, once per call
+		b.f.Close()
+		b.f = nil
+
+		if err := b.open(); err != nil {
+			return err
+		}
+
+		err = write(b.f)
 	}
 
-	return b.formatter.FormatResponse(b.f, b.formatConfig, auth, req, resp, err)
+	if err == nil && b.hashChain {
+		salt, err := b.Salt()
+		if err != nil {
+			return err
+		}
+		return b.storeChainHead(&audit.ChainHead{
+			Anchor: b.chain.Anchor,
+			Seq:    b.chain.Seq + 1,
+			Hash:   audit.ChainHash(salt, bytes.TrimSuffix(line, []byte("\n"))),
+		})
+	}
+	return err
 }
 
 // The file lock must be held before calling this
@@ -246,6 +296,14 @@ func (b *Backend) open() error {
 		return err
 	}
 
+	if b.hashChain {
+		if err := b.loadChainHead(); err != nil {
+			b.f.Close()
+			b.f = nil
+			return err
+		}
+	}
+
 	// Change the file mode in case the log file already existed. We special
 	// case /dev/null since we can't chmod it
 	switch b.path {
@@ -260,6 +318,162 @@ func (b *Backend) open() error {
 	return nil
 }
 
+// loadChainHead continues the chain from the last entry written, as stored in
+// the salt view, rather than from the last line of the log file, so that the
+// truncation of the file is detected. The chain of a rotated log continues
+// from the last entry written, or starts anew. The file lock must be held
+// before calling this.
+func (b *Backend) loadChainHead() error {
+	salt, err := b.Salt()
+	if err != nil {
+		return err
+	}
+
+	entry, err := b.saltView.Get(chainHeadPath)
+	if err != nil {
+		return fmt.Errorf("failed to read the head of the audit log chain: %v", err)
+	}
+	var stored *audit.ChainHead
+	if entry != nil {
+		stored = new(audit.ChainHead)
+		if err := jsonutil.DecodeJSON(entry.Value, stored); err != nil {
+			return fmt.Errorf("failed to decode the head of the audit log chain: %v", err)
+		}
+	}
+
+	line, err := lastLine(b.path)
+	if err != nil {
+		return err
+	}
+	switch {
+	case line == nil:
+		// The first entry of a new log file links to the last entry written
+		head := audit.ChainGenesis(salt)
+		if stored != nil {
+			head = stored.Hash
+		}
+		return b.storeChainHead(&audit.ChainHead{
+			Anchor: head,
+			Hash:   head,
+		})
+
+	case stored != nil:
+		b.chain = stored
+		return nil
+
+	default:
+		// The log file holds entries written before the chaining was enabled
+		head := audit.ChainHash(salt, line)
+		return b.storeChainHead(&audit.ChainHead{
+			Anchor: head,
+			Hash:   head,
+		})
+	}
+}
+
+// storeChainHead persists the head of the chain of the log file. The file lock
+// must be held before calling this.
+func (b *Backend) storeChainHead(head *audit.ChainHead) error {
+	entry, err := logical.StorageEntryJSON(chainHeadPath, head)
+	if err != nil {
+		return err
+	}
+	if err := b.saltView.Put(entry); err != nil {
+		return fmt.Errorf("failed to persist the head of the audit log chain: %v", err)
+	}
+	b.chain = head
+	return nil
+}
+
+// lastLine returns the last line of a file, without its line feed, or nil if
+// the file is empty
+func lastLine(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	end := info.Size()
+	if end == 0 {
+		return nil, nil
+	}
+
+	// Read the file backwards until the line feed preceding the last line
+	var line []byte
+	chunk := make([]byte, 64*1024)
+	for offset := end; offset > 0; {
+		n := int64(len(chunk))
+		if n > offset {
+			n = offset
+		}
+		offset -= n
+		if _, err := f.ReadAt(chunk[:n], offset); err != nil {
+			return nil, err
+		}
+		line = append(append([]byte(nil), chunk[:n]...), line...)
+
+		trimmed := bytes.TrimSuffix(line, []byte("\n"))
+		if i := bytes.LastIndexByte(trimmed, '\n'); i >= 0 {
+			return trimmed[i+1:], nil
+		}
+	}
+	return bytes.TrimSuffix(line, []byte("\n")), nil
+}
+
+// VerifyChain verifies the chain of the entries of the given log file, which
+// must be in the directory of the log of the backend. The truncation of the
+// current log file is detected too.
+func (b *Backend) VerifyChain(path string) (*audit.ChainVerification, error) {
+	if !b.hashChain {
+		return nil, fmt.Errorf("hash chaining is not enabled on the backend")
+	}
+	switch b.path {
+	case "stdout", "discard":
+		return nil, fmt.Errorf("only the entries written to a file can be verified")
+	}
+	if path == "" {
+		path = b.path
+	} else if filepath.Dir(filepath.Clean(path)) != filepath.Dir(b.path) {
+		return nil, fmt.Errorf("only the files in the directory of the log of the backend can be verified")
+	}
+
+	salt, err := b.Salt()
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	// Snapshot the end of the chain if the current log file is verified, so
+	// that the entries can be logged meanwhile
+	var head *audit.ChainHead
+	var r io.Reader = f
+	b.fileLock.RLock()
+	if b.f != nil {
+		if current, err := b.f.Stat(); err == nil && os.SameFile(info, current) {
+			chain := *b.chain
+			head = &chain
+			r = io.LimitReader(f, current.Size())
+		}
+	}
+	b.fileLock.RUnlock()
+
+	return audit.VerifyChain(r, b.prefix, salt, head)
+}
+
 func (b *Backend) Reload() error {
 	switch b.path {
 	case "stdout", "discard":
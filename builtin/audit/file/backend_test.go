@@ -1,6 +1,7 @@
 package file
 
 import (
+	"bytes"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -89,3 +90,118 @@ func TestAuditFile_fileModeExisting(t *testing.T) {
 		t.Fatalf("File mode does not match.")
 	}
 }
+
+func TestAuditFile_hashChain(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vault-test_audit_file-hash_chain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.log")
+
+	conf := &audit.BackendConfig{
+		SaltConfig: &salt.Config{},
+		SaltView:   &logical.InmemStorage{},
+		Config: map[string]string{
+			"path":       path,
+			"hash_chain": "true",
+			"prefix":     "@cee: ",
+		},
+	}
+	newBackend := func() *Backend {
+		b, err := Factory(conf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b.(*Backend)
+	}
+	log := func(b *Backend, n int) {
+		for i := 0; i < n; i++ {
+			req := &logical.Request{
+				Operation: logical.UpdateOperation,
+				Path:      "secret/foo",
+			}
+			if err := b.LogRequest(nil, req, nil); err != nil {
+				t.Fatal(err)
+			}
+			if err := b.LogResponse(nil, req, &logical.Response{}, nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	b := newBackend()
+	log(b, 2)
+	result, err := b.VerifyChain("")
+	if err != nil || !result.Valid || result.Entries != 4 || !result.ChainStart {
+		t.Fatalf("bad: %#v, err: %v", result, err)
+	}
+
+	// The chain continues after a restart
+	b = newBackend()
+	log(b, 1)
+	result, err = b.VerifyChain("")
+	if err != nil || !result.Valid || result.Entries != 6 {
+		t.Fatalf("bad: %#v, err: %v", result, err)
+	}
+
+	// The rotated logs link to each other
+	rotated := path + ".1"
+	if err := os.Rename(path, rotated); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if result, err := b.VerifyChain(""); err != nil || !result.Valid || result.Entries != 0 {
+		t.Fatalf("bad: %#v, err: %v", result, err)
+	}
+	log(b, 1)
+	previous, err := b.VerifyChain(rotated)
+	if err != nil || !previous.Valid || previous.Entries != 6 {
+		t.Fatalf("bad: %#v, err: %v", previous, err)
+	}
+	result, err = b.VerifyChain("")
+	if err != nil || !result.Valid || result.Entries != 2 || result.FirstPrevHash != previous.LastHash {
+		t.Fatalf("bad: %#v, err: %v", result, err)
+	}
+
+	// Truncating the current log is detected, even after a restart
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	if err := ioutil.WriteFile(path, lines[0], 0600); err != nil {
+		t.Fatal(err)
+	}
+	result, err = b.VerifyChain("")
+	if err != nil || result.Valid {
+		t.Fatalf("bad: %#v, err: %v", result, err)
+	}
+	b = newBackend()
+	log(b, 1)
+	result, err = b.VerifyChain("")
+	if err != nil || result.Valid || result.Line != 2 {
+		t.Fatalf("bad: %#v, err: %v", result, err)
+	}
+
+	// Removing the leading entries of the current log is detected
+	if err := ioutil.WriteFile(path, bytes.Join(lines[1:], nil), 0600); err != nil {
+		t.Fatal(err)
+	}
+	result, err = b.VerifyChain("")
+	if err != nil || result.Valid || result.Line != 1 {
+		t.Fatalf("bad: %#v, err: %v", result, err)
+	}
+
+	// Only the logs of the directory of the backend can be verified
+	if _, err := b.VerifyChain("/etc/passwd"); err == nil {
+		t.Fatalf("expected an error")
+	}
+
+	conf.Config["format"] = "jsonx"
+	if _, err := Factory(conf); err == nil {
+		t.Fatalf("expected an error")
+	}
+}
@@ -2,6 +2,7 @@ package socket
 
 import (
 	"bytes"
+	"crypto/tls"
 	"fmt"
 	"net"
 	"strconv"
@@ -12,6 +13,7 @@ import (
 	"github.com/hashicorp/vault/audit"
 	"github.com/hashicorp/vault/helper/parseutil"
 	"github.com/hashicorp/vault/helper/salt"
+	"github.com/hashicorp/vault/helper/strutil"
 	"github.com/hashicorp/vault/logical"
 )
 
@@ -62,6 +64,9 @@ func Factory(conf *audit.BackendConfig) (audit.Backend, error) {
 		hmacAccessor = value
 	}
 
+	// Data fields which are logged without being hashed
+	hmacExemptFields := strutil.ParseDedupAndSortStrings(conf.Config["hmac_exempt_fields"], ",")
+
 	// Check if raw logging is enabled
 	logRaw := false
 	if raw, ok := conf.Config["log_raw"]; ok {
@@ -72,17 +77,38 @@ func Factory(conf *audit.BackendConfig) (audit.Backend, error) {
 		logRaw = b
 	}
 
+	var tlsConfig *tls.Config
+	if raw, ok := conf.Config["tls"]; ok {
+		useTLS, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, err
+		}
+		if useTLS {
+			tlsConfig, err = audit.ParseTLSConfig(conf.Config)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	bufferConfig, err := audit.ParseBufferConfig(conf.Config)
+	if err != nil {
+		return nil, err
+	}
+
 	b := &Backend{
 		saltConfig: conf.SaltConfig,
 		saltView:   conf.SaltView,
 		formatConfig: audit.FormatterConfig{
-			Raw:          logRaw,
-			HMACAccessor: hmacAccessor,
+			Raw:              logRaw,
+			HMACAccessor:     hmacAccessor,
+			HMACExemptFields: hmacExemptFields,
 		},
 
 		writeDuration: writeDuration,
 		address:       address,
 		socketType:    socketType,
+		tlsConfig:     tlsConfig,
 	}
 
 	switch format {
@@ -98,6 +124,16 @@ func Factory(conf *audit.BackendConfig) (audit.Backend, error) {
 		}
 	}
 
+	// Non-blocking backends queue the entries and deliver them in the
+	// background, so that the requests are not failed while the socket is
+	// unavailable
+	if bufferConfig != nil {
+		b.buffer, err = audit.NewBuffer(bufferConfig, b.deliver, conf.Logger)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return b, nil
 }
 
@@ -111,6 +147,10 @@ type Backend struct {
 	writeDuration time.Duration
 	address       string
 	socketType    string
+	tlsConfig     *tls.Config
+
+	// buffer queues the entries of non-blocking backends
+	buffer *audit.Buffer
 
 	sync.Mutex
 
@@ -134,21 +174,7 @@ func (b *Backend) LogRequest(auth *logical.Auth, req *logical.Request, outerErr
 		return err
 	}
 
-	b.Lock()
-	defer b.Unlock()
-
-	err := b.write(buf.Bytes())
-	if err != nil {
-		rErr := b.reconnect()
-		if rErr != nil {
-			err = multierror.Append(err, rErr)
-		} else {
-			// Try once more after reconnecting
-			err = b.write(buf.Bytes())
-		}
-	}
-
-	return err
+	return b.log(buf.Bytes())
 }
 
 func (b *Backend) LogResponse(auth *logical.Auth, req *logical.Request,
@@ -158,17 +184,31 @@ func (b *Backend) LogResponse(auth *logical.Auth, req *logical.Request,
 		return err
 	}
 
+	return b.log(buf.Bytes())
+}
+
+// log delivers an entry, or queues it if the backend is non-blocking
+func (b *Backend) log(entry []byte) error {
+	if b.buffer != nil {
+		b.buffer.Write(entry)
+		return nil
+	}
+	return b.deliver(entry)
+}
+
+// deliver writes an entry to the socket, reconnecting once on failure
+func (b *Backend) deliver(entry []byte) error {
 	b.Lock()
 	defer b.Unlock()
 
-	err := b.write(buf.Bytes())
+	err := b.write(entry)
 	if err != nil {
 		rErr := b.reconnect()
 		if rErr != nil {
 			err = multierror.Append(err, rErr)
 		} else {
 			// Try once more after reconnecting
-			err = b.write(buf.Bytes())
+			err = b.write(entry)
 		}
 	}
 
@@ -201,7 +241,14 @@ func (b *Backend) reconnect() error {
 		b.connection = nil
 	}
 
-	conn, err := net.Dial(b.socketType, b.address)
+	var conn net.Conn
+	var err error
+	if b.tlsConfig != nil {
+		dialer := &net.Dialer{Timeout: b.writeDuration}
+		conn, err = tls.DialWithDialer(dialer, b.socketType, b.address, b.tlsConfig)
+	} else {
+		conn, err = net.Dial(b.socketType, b.address)
+	}
 	if err != nil {
 		return err
 	}
@@ -220,6 +267,24 @@ func (b *Backend) Reload() error {
 	return err
 }
 
+// Close stops the background deliveries and closes the connection
+func (b *Backend) Close() error {
+	if b.buffer != nil {
+		if err := b.buffer.Close(); err != nil {
+			return err
+		}
+	}
+
+	b.Lock()
+	defer b.Unlock()
+
+	if b.connection != nil {
+		b.connection.Close()
+		b.connection = nil
+	}
+	return nil
+}
+
 func (b *Backend) Salt() (*salt.Salt, error) {
 	b.saltMutex.RLock()
 	if b.salt != nil {
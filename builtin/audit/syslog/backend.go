@@ -9,6 +9,7 @@ import (
 	"github.com/hashicorp/go-syslog"
 	"github.com/hashicorp/vault/audit"
 	"github.com/hashicorp/vault/helper/salt"
+	"github.com/hashicorp/vault/helper/strutil"
 	"github.com/hashicorp/vault/logical"
 )
 
@@ -52,6 +53,9 @@ func Factory(conf *audit.BackendConfig) (audit.Backend, error) {
 		hmacAccessor = value
 	}
 
+	// Data fields which are logged without being hashed
+	hmacExemptFields := strutil.ParseDedupAndSortStrings(conf.Config["hmac_exempt_fields"], ",")
+
 	// Check if raw logging is enabled
 	logRaw := false
 	if raw, ok := conf.Config["log_raw"]; ok {
@@ -73,8 +77,9 @@ func Factory(conf *audit.BackendConfig) (audit.Backend, error) {
 		saltConfig: conf.SaltConfig,
 		saltView:   conf.SaltView,
 		formatConfig: audit.FormatterConfig{
-			Raw:          logRaw,
-			HMACAccessor: hmacAccessor,
+			Raw:              logRaw,
+			HMACAccessor:     hmacAccessor,
+			HMACExemptFields: hmacExemptFields,
 		},
 	}
 
@@ -0,0 +1,117 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/audit"
+	"github.com/hashicorp/vault/helper/salt"
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestAuditWebhook(t *testing.T) {
+	var l sync.Mutex
+	var paths []string
+	available := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		l.Lock()
+		defer l.Unlock()
+		if !available {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("bad: %s", r.Header.Get("Content-Type"))
+		}
+		var entry audit.AuditRequestEntry
+		body, _ := ioutil.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &entry); err != nil {
+			t.Errorf("err: %v", err)
+		}
+		paths = append(paths, entry.Request.Path)
+	}))
+	defer server.Close()
+
+	newBackend := func(config map[string]string) audit.Backend {
+		b, err := Factory(&audit.BackendConfig{
+			SaltConfig: &salt.Config{},
+			SaltView:   &logical.InmemStorage{},
+			Config:     config,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	req := &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "secret/foo",
+	}
+
+	// Blocking backends fail the requests while the webhook is unavailable
+	b := newBackend(map[string]string{"url": server.URL})
+	if err := b.LogRequest(nil, req, nil); err == nil {
+		t.Fatalf("expected an error")
+	}
+
+	// Non-blocking backends retry in the background
+	nb := newBackend(map[string]string{
+		"url":                server.URL,
+		"non_blocking":       "true",
+		"retry_min_interval": "1ms",
+		"retry_max_interval": "10ms",
+	})
+	defer nb.(audit.Closer).Close()
+	if err := nb.LogRequest(nil, req, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	l.Lock()
+	available = true
+	l.Unlock()
+
+	if err := b.LogRequest(nil, req, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		l.Lock()
+		delivered := len(paths)
+		l.Unlock()
+		if delivered == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("bad: %v", paths)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if paths[0] != "secret/foo" || paths[1] != "secret/foo" {
+		t.Fatalf("bad: %v", paths)
+	}
+}
+
+func TestAuditWebhook_Config(t *testing.T) {
+	for _, config := range []map[string]string{
+		{},
+		{"url": "ftp://example.com"},
+		{"url": "http://example.com", "format": "xml"},
+		{"url": "http://example.com", "tls_cert_file": "cert.pem"},
+		{"url": "http://example.com", "non_blocking": "true", "buffer_max_entries": "-1"},
+	} {
+		_, err := Factory(&audit.BackendConfig{
+			SaltConfig: &salt.Config{},
+			SaltView:   &logical.InmemStorage{},
+			Config:     config,
+		})
+		if err == nil {
+			t.Fatalf("expected an error for %#v", config)
+		}
+	}
+}
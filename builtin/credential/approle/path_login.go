@@ -33,7 +33,7 @@ func pathLogin(b *backend) *framework.Path {
 // Returns the Auth object indicating the authentication and authorization information
 // if the credentials provided are validated by the backend.
 func (b *backend) pathLoginUpdate(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	role, roleName, metadata, err := b.validateCredentials(req, data)
+	role, roleName, metadata, tokenBoundCIDRs, err := b.validateCredentials(req, data)
 	if err != nil || role == nil {
 		return logical.ErrorResponse(fmt.Sprintf("failed to validate SecretID: %s", err)), nil
 	}
@@ -47,8 +47,9 @@ func (b *backend) pathLoginUpdate(req *logical.Request, data *framework.FieldDat
 		InternalData: map[string]interface{}{
 			"role_name": roleName,
 		},
-		Metadata: metadata,
-		Policies: role.Policies,
+		Metadata:   metadata,
+		Policies:   role.Policies,
+		BoundCIDRs: tokenBoundCIDRs,
 		LeaseOptions: logical.LeaseOptions{
 			Renewable: true,
 		},
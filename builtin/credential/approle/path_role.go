@@ -11,6 +11,7 @@ import (
 	"github.com/hashicorp/vault/helper/locksutil"
 	"github.com/hashicorp/vault/helper/policyutil"
 	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/helper/wrapping"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
 )
@@ -36,6 +37,10 @@ type roleStorageEntry struct {
 	// SecretID generated against the role will expire
 	SecretIDTTL time.Duration `json:"secret_id_ttl" structs:"secret_id_ttl" mapstructure:"secret_id_ttl"`
 
+	// If set, the SecretIDs generated against the role are always returned
+	// wrapped in a response-wrapping token with this TTL
+	SecretIDWrapTTL time.Duration `json:"secret_id_wrap_ttl" structs:"secret_id_wrap_ttl" mapstructure:"secret_id_wrap_ttl"`
+
 	// TokenNumUses defines the number of allowed uses of the token issued
 	TokenNumUses int `json:"token_num_uses" mapstructure:"token_num_uses" structs:"token_num_uses"`
 
@@ -72,6 +77,7 @@ type roleIDStorageEntry struct {
 // role/<role_name>/policies - For updating the param
 // role/<role_name>/secret-id-num-uses - For updating the param
 // role/<role_name>/secret-id-ttl - For updating the param
+// role/<role_name>/secret-id-wrap-ttl - For updating the param
 // role/<role_name>/token-ttl - For updating the param
 // role/<role_name>/token-max-ttl - For updating the param
 // role/<role_name>/token-num-uses - For updating the param
@@ -126,6 +132,13 @@ will expire. Defaults to 0 meaning that the the secret_id is of unlimited use.`,
 					Type: framework.TypeDurationSecond,
 					Description: `Duration in seconds after which the issued SecretID should expire. Defaults
 to 0, meaning no expiration.`,
+				},
+				"secret_id_wrap_ttl": &framework.FieldSchema{
+					Type: framework.TypeDurationSecond,
+					Description: `If set, the SecretIDs generated against the role are always returned
+wrapped in a response-wrapping token with this TTL. A shorter wrapping TTL
+requested by the client takes precedence. Defaults to 0, meaning that SecretIDs
+are only wrapped on request.`,
 				},
 				"token_num_uses": &framework.FieldSchema{
 					Type:        framework.TypeInt,
@@ -268,6 +281,28 @@ to 0, meaning no expiration.`,
 			HelpSynopsis:    strings.TrimSpace(roleHelp["role-secret-id-ttl"][0]),
 			HelpDescription: strings.TrimSpace(roleHelp["role-secret-id-ttl"][1]),
 		},
+		&framework.Path{
+			Pattern: "role/" + framework.GenericNameRegex("role_name") + "/secret-id-wrap-ttl$",
+			Fields: map[string]*framework.FieldSchema{
+				"role_name": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "Name of the role.",
+				},
+				"secret_id_wrap_ttl": &framework.FieldSchema{
+					Type: framework.TypeDurationSecond,
+					Description: `If set, the SecretIDs generated against the role are always returned
+wrapped in a response-wrapping token with this TTL. Defaults to 0, meaning
+that SecretIDs are only wrapped on request.`,
+				},
+			},
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: b.pathRoleSecretIDWrapTTLUpdate,
+				logical.ReadOperation:   b.pathRoleSecretIDWrapTTLRead,
+				logical.DeleteOperation: b.pathRoleSecretIDWrapTTLDelete,
+			},
+			HelpSynopsis:    strings.TrimSpace(roleHelp["role-secret-id-wrap-ttl"][0]),
+			HelpDescription: strings.TrimSpace(roleHelp["role-secret-id-wrap-ttl"][1]),
+		},
 		&framework.Path{
 			Pattern: "role/" + framework.GenericNameRegex("role_name") + "/period$",
 			Fields: map[string]*framework.FieldSchema{
@@ -392,6 +427,19 @@ specific set of IP addresses. If 'bound_cidr_list' is set on the role, then the
 list of CIDR blocks listed here should be a subset of the CIDR blocks listed on
 the role.`,
 				},
+				"num_uses": &framework.FieldSchema{
+					Type: framework.TypeInt,
+					Description: `Number of times the SecretID can be used to perform the login operation.
+Defaults to the 'secret_id_num_uses' of the role. If the role sets a limit, this
+value can only lower it.`,
+				},
+				"token_bound_cidrs": &framework.FieldSchema{
+					Type: framework.TypeCommaStringSlice,
+					Description: `Comma separated list of CIDR blocks from which the tokens issued by logging
+in with this SecretID can be used. If 'bound_cidr_list' is set on the role, then
+the list of CIDR blocks listed here should be a subset of the CIDR blocks listed
+on the role.`,
+				},
 			},
 			Callbacks: map[logical.Operation]framework.OperationFunc{
 				logical.UpdateOperation: b.pathRoleSecretIDUpdate,
@@ -497,6 +545,19 @@ specific set of IP addresses. If 'bound_cidr_list' is set on the role, then the
 list of CIDR blocks listed here should be a subset of the CIDR blocks listed on
 the role.`,
 				},
+				"num_uses": &framework.FieldSchema{
+					Type: framework.TypeInt,
+					Description: `Number of times the SecretID can be used to perform the login operation.
+Defaults to the 'secret_id_num_uses' of the role. If the role sets a limit, this
+value can only lower it.`,
+				},
+				"token_bound_cidrs": &framework.FieldSchema{
+					Type: framework.TypeCommaStringSlice,
+					Description: `Comma separated list of CIDR blocks from which the tokens issued by logging
+in with this SecretID can be used. If 'bound_cidr_list' is set on the role, then
+the list of CIDR blocks listed here should be a subset of the CIDR blocks listed
+on the role.`,
+				},
 			},
 			Callbacks: map[logical.Operation]framework.OperationFunc{
 				logical.UpdateOperation: b.pathRoleCustomSecretIDUpdate,
@@ -798,6 +859,15 @@ func (b *backend) pathRoleCreateUpdate(req *logical.Request, data *framework.Fie
 		role.SecretIDTTL = time.Second * time.Duration(data.Get("secret_id_ttl").(int))
 	}
 
+	if secretIDWrapTTLRaw, ok := data.GetOk("secret_id_wrap_ttl"); ok {
+		role.SecretIDWrapTTL = time.Second * time.Duration(secretIDWrapTTLRaw.(int))
+	} else if req.Operation == logical.CreateOperation {
+		role.SecretIDWrapTTL = time.Second * time.Duration(data.Get("secret_id_wrap_ttl").(int))
+	}
+	if role.SecretIDWrapTTL < 0 {
+		return logical.ErrorResponse("secret_id_wrap_ttl cannot be negative"), nil
+	}
+
 	if tokenNumUsesRaw, ok := data.GetOk("token_num_uses"); ok {
 		role.TokenNumUses = tokenNumUsesRaw.(int)
 	} else if req.Operation == logical.CreateOperation {
@@ -850,6 +920,7 @@ func (b *backend) pathRoleRead(req *logical.Request, data *framework.FieldData)
 	} else {
 		// Convert the 'time.Duration' values to second.
 		role.SecretIDTTL /= time.Second
+		role.SecretIDWrapTTL /= time.Second
 		role.TokenTTL /= time.Second
 		role.TokenMaxTTL /= time.Second
 		role.Period /= time.Second
@@ -1555,6 +1626,80 @@ func (b *backend) pathRoleSecretIDTTLDelete(req *logical.Request, data *framewor
 	return nil, b.setRoleEntry(req.Storage, roleName, role, "")
 }
 
+func (b *backend) pathRoleSecretIDWrapTTLUpdate(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	roleName := data.Get("role_name").(string)
+	if roleName == "" {
+		return logical.ErrorResponse("missing role_name"), nil
+	}
+
+	role, err := b.roleEntry(req.Storage, strings.ToLower(roleName))
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, nil
+	}
+
+	lock := b.roleLock(roleName)
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	if secretIDWrapTTLRaw, ok := data.GetOk("secret_id_wrap_ttl"); ok {
+		role.SecretIDWrapTTL = time.Second * time.Duration(secretIDWrapTTLRaw.(int))
+		if role.SecretIDWrapTTL < 0 {
+			return logical.ErrorResponse("secret_id_wrap_ttl cannot be negative"), nil
+		}
+		return nil, b.setRoleEntry(req.Storage, roleName, role, "")
+	} else {
+		return logical.ErrorResponse("missing secret_id_wrap_ttl"), nil
+	}
+}
+
+func (b *backend) pathRoleSecretIDWrapTTLRead(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	roleName := data.Get("role_name").(string)
+	if roleName == "" {
+		return logical.ErrorResponse("missing role_name"), nil
+	}
+
+	if role, err := b.roleEntry(req.Storage, strings.ToLower(roleName)); err != nil {
+		return nil, err
+	} else if role == nil {
+		return nil, nil
+	} else {
+		role.SecretIDWrapTTL /= time.Second
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"secret_id_wrap_ttl": role.SecretIDWrapTTL,
+			},
+		}, nil
+	}
+}
+
+func (b *backend) pathRoleSecretIDWrapTTLDelete(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	roleName := data.Get("role_name").(string)
+	if roleName == "" {
+		return logical.ErrorResponse("missing role_name"), nil
+	}
+
+	role, err := b.roleEntry(req.Storage, strings.ToLower(roleName))
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, nil
+	}
+
+	lock := b.roleLock(roleName)
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	role.SecretIDWrapTTL = time.Second * time.Duration(data.GetDefaultOrZero("secret_id_wrap_ttl").(int))
+
+	return nil, b.setRoleEntry(req.Storage, roleName, role, "")
+}
+
 func (b *backend) pathRolePeriodUpdate(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 	roleName := data.Get("role_name").(string)
 	if roleName == "" {
@@ -1902,11 +2047,41 @@ func (b *backend) handleRoleSecretIDCommon(req *logical.Request, data *framework
 		return nil, err
 	}
 
+	// Validate the CIDR blocks the issued tokens are bound to, which should
+	// also be a subset of that of role's
+	tokenBoundCIDRs := strutil.RemoveDuplicates(data.Get("token_bound_cidrs").([]string), true)
+	if len(tokenBoundCIDRs) != 0 {
+		valid, err := cidrutil.ValidateCIDRListSlice(tokenBoundCIDRs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to validate CIDR blocks: %q", err)
+		}
+		if !valid {
+			return logical.ErrorResponse("failed to validate token_bound_cidrs"), nil
+		}
+		if err := verifyCIDRRoleSecretIDSubset(tokenBoundCIDRs, role.BoundCIDRList); err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+	}
+
+	// The usage limit of the SecretID defaults to that of the role, and can
+	// only be lowered
+	secretIDNumUses := role.SecretIDNumUses
+	if numUsesRaw, ok := data.GetOk("num_uses"); ok {
+		secretIDNumUses = numUsesRaw.(int)
+		switch {
+		case secretIDNumUses < 0:
+			return logical.ErrorResponse("num_uses cannot be negative"), nil
+		case role.SecretIDNumUses > 0 && (secretIDNumUses == 0 || secretIDNumUses > role.SecretIDNumUses):
+			return logical.ErrorResponse(fmt.Sprintf("num_uses cannot be unlimited or greater than the secret_id_num_uses of %d set on the role", role.SecretIDNumUses)), nil
+		}
+	}
+
 	secretIDStorage := &secretIDStorageEntry{
-		SecretIDNumUses: role.SecretIDNumUses,
+		SecretIDNumUses: secretIDNumUses,
 		SecretIDTTL:     role.SecretIDTTL,
 		Metadata:        make(map[string]string),
 		CIDRList:        secretIDCIDRs,
+		TokenBoundCIDRs: tokenBoundCIDRs,
 	}
 
 	if err = strutil.ParseArbitraryKeyValues(data.Get("metadata").(string), secretIDStorage.Metadata, ","); err != nil {
@@ -1917,12 +2092,22 @@ func (b *backend) handleRoleSecretIDCommon(req *logical.Request, data *framework
 		return nil, fmt.Errorf("failed to store SecretID: %s", err)
 	}
 
-	return &logical.Response{
+	resp := &logical.Response{
 		Data: map[string]interface{}{
 			"secret_id":          secretID,
 			"secret_id_accessor": secretIDStorage.SecretIDAccessor,
 		},
-	}, nil
+	}
+
+	// Deliver the SecretID in a response-wrapping token if the role requires
+	// it; core uses the shorter of this TTL and any requested by the client
+	if role.SecretIDWrapTTL > 0 {
+		resp.WrapInfo = &wrapping.ResponseWrapInfo{
+			TTL: role.SecretIDWrapTTL,
+		}
+	}
+
+	return resp, nil
 }
 
 func (b *backend) roleIDLock(roleID string) *locksutil.LockEntry {
@@ -2052,6 +2237,14 @@ that are generated against the role using 'role/<role_name>/secret-id' or
 'role/<role_name>/custom-secret-id' endpoints.`,
 		``,
 	},
+	"role-secret-id-wrap-ttl": {
+		`Duration in seconds, representing the TTL of the response-wrapping tokens
+in which the SecretIDs generated against the role are returned.`,
+		`If set, the SecretIDs generated using the 'role/<role_name>/secret-id' or
+'role/<role_name>/custom-secret-id' endpoints are always returned wrapped in a
+response-wrapping token, so that they can only be read once by the intended
+recipient. If the client requests a shorter wrapping TTL, that TTL is used.`,
+	},
 	"role-secret-id-lookup": {
 		"Read the properties of an issued secret_id",
 		`This endpoint is used to read the properties of a secret_id associated to a
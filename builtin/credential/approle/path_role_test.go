@@ -574,6 +574,134 @@ func TestAppRole_RoleSecretID(t *testing.T) {
 	}
 }
 
+func TestAppRole_RoleSecretIDConstraints(t *testing.T) {
+	var resp *logical.Response
+	var err error
+	b, storage := createBackendWithStorage(t)
+
+	roleReq := &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "role/role1",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"policies":           "p,q",
+			"secret_id_num_uses": 5,
+			"secret_id_wrap_ttl": 60,
+			"bound_cidr_list":    "127.0.0.1/24",
+		},
+	}
+	resp, err = b.HandleRequest(roleReq)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+
+	secretIDReq := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "role/role1/secret-id",
+		Storage:   storage,
+	}
+
+	// The use limit of the role can't be raised or lifted
+	for _, numUses := range []int{-1, 0, 6} {
+		secretIDReq.Data = map[string]interface{}{
+			"num_uses": numUses,
+		}
+		resp, err = b.HandleRequest(secretIDReq)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp == nil || !resp.IsError() {
+			t.Fatalf("expected an error for num_uses %d; resp: %#v", numUses, resp)
+		}
+	}
+
+	// Tokens can't be bound outside of the CIDR blocks of the role
+	secretIDReq.Data = map[string]interface{}{
+		"token_bound_cidrs": "10.0.0.0/8",
+	}
+	resp, err = b.HandleRequest(secretIDReq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error; resp: %#v", resp)
+	}
+
+	secretIDReq.Data = map[string]interface{}{
+		"num_uses":          2,
+		"token_bound_cidrs": "127.0.0.1/32",
+	}
+	resp, err = b.HandleRequest(secretIDReq)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+	if resp.WrapInfo == nil || resp.WrapInfo.TTL != 60*time.Second {
+		t.Fatalf("expected the secret_id to be wrapped; resp: %#v", resp)
+	}
+	secretID := resp.Data["secret_id"].(string)
+
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "role/role1/secret-id/lookup",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"secret_id": secretID,
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+	if resp.Data["secret_id_num_uses"].(int) != 2 {
+		t.Fatalf("bad: secret_id_num_uses: %#v", resp.Data["secret_id_num_uses"])
+	}
+	if !reflect.DeepEqual(resp.Data["token_bound_cidrs"], []string{"127.0.0.1/32"}) {
+		t.Fatalf("bad: token_bound_cidrs: %#v", resp.Data["token_bound_cidrs"])
+	}
+
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "role/role1/role-id",
+		Storage:   storage,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+	roleID := resp.Data["role_id"].(string)
+
+	loginReq := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "login",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"role_id":   roleID,
+			"secret_id": secretID,
+		},
+		Connection: &logical.Connection{
+			RemoteAddr: "127.0.0.1",
+		},
+	}
+
+	// The issued tokens are bound to the CIDR blocks of the SecretID, which
+	// can only be used twice
+	for i := 0; i < 2; i++ {
+		resp, err = b.HandleRequest(loginReq)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("err:%v resp:%#v", err, resp)
+		}
+		if !reflect.DeepEqual(resp.Auth.BoundCIDRs, []string{"127.0.0.1/32"}) {
+			t.Fatalf("bad: bound CIDRs: %#v", resp.Auth.BoundCIDRs)
+		}
+	}
+
+	resp, err = b.HandleRequest(loginReq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error; resp: %#v", resp)
+	}
+}
+
 func TestAppRole_RoleCRUD(t *testing.T) {
 	var resp *logical.Response
 	var err error
@@ -865,6 +993,40 @@ func TestAppRole_RoleCRUD(t *testing.T) {
 		t.Fatalf("expected value to be reset")
 	}
 
+	// RUD for secret_id_wrap_ttl field
+	roleReq.Path = "role/role1/secret-id-wrap-ttl"
+	roleReq.Data = map[string]interface{}{"secret_id_wrap_ttl": 120}
+	roleReq.Operation = logical.UpdateOperation
+	resp, err = b.HandleRequest(roleReq)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+
+	roleReq.Operation = logical.ReadOperation
+	resp, err = b.HandleRequest(roleReq)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+
+	if resp.Data["secret_id_wrap_ttl"].(time.Duration) != 120 {
+		t.Fatalf("bad: secret_id_wrap_ttl: expected:120 actual:%d\n", resp.Data["secret_id_wrap_ttl"].(time.Duration))
+	}
+	roleReq.Operation = logical.DeleteOperation
+	resp, err = b.HandleRequest(roleReq)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+
+	roleReq.Operation = logical.ReadOperation
+	resp, err = b.HandleRequest(roleReq)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+
+	if resp.Data["secret_id_wrap_ttl"].(time.Duration) != 0 {
+		t.Fatalf("expected value to be reset")
+	}
+
 	// RUD for secret-id-num-uses field
 	roleReq.Path = "role/role1/token-num-uses"
 	roleReq.Operation = logical.ReadOperation
@@ -53,6 +53,10 @@ type secretIDStorageEntry struct {
 	// restrictions on the usage of SecretID
 	CIDRList []string `json:"cidr_list" structs:"cidr_list" mapstructure:"cidr_list"`
 
+	// TokenBoundCIDRs is a set of CIDR blocks that impose source address
+	// restrictions on the usage of the tokens issued using the SecretID
+	TokenBoundCIDRs []string `json:"token_bound_cidrs" structs:"token_bound_cidrs" mapstructure:"token_bound_cidrs"`
+
 	// This is a deprecated field
 	SecretIDNumUsesDeprecated int `json:"SecretIDNumUses" structs:"SecretIDNumUses" mapstructure:"SecretIDNumUses"`
 }
@@ -90,26 +94,27 @@ func (b *backend) validateRoleID(s logical.Storage, roleID string) (*roleStorage
 }
 
 // Validates the supplied RoleID and SecretID
-func (b *backend) validateCredentials(req *logical.Request, data *framework.FieldData) (*roleStorageEntry, string, map[string]string, error) {
+func (b *backend) validateCredentials(req *logical.Request, data *framework.FieldData) (*roleStorageEntry, string, map[string]string, []string, error) {
 	metadata := make(map[string]string)
+	var tokenBoundCIDRs []string
 	// RoleID must be supplied during every login
 	roleID := strings.TrimSpace(data.Get("role_id").(string))
 	if roleID == "" {
-		return nil, "", metadata, fmt.Errorf("missing role_id")
+		return nil, "", metadata, nil, fmt.Errorf("missing role_id")
 	}
 
 	// Validate the RoleID and get the Role entry
 	role, roleName, err := b.validateRoleID(req.Storage, roleID)
 	if err != nil {
-		return nil, "", metadata, err
+		return nil, "", metadata, nil, err
 	}
 	if role == nil || roleName == "" {
-		return nil, "", metadata, fmt.Errorf("failed to validate role_id")
+		return nil, "", metadata, nil, fmt.Errorf("failed to validate role_id")
 	}
 
 	// Calculate the TTL boundaries since this reflects the properties of the token issued
 	if role.TokenTTL, role.TokenMaxTTL, err = b.SanitizeTTL(role.TokenTTL, role.TokenMaxTTL); err != nil {
-		return nil, "", metadata, err
+		return nil, "", metadata, nil, err
 	}
 
 	if role.BindSecretID {
@@ -117,42 +122,44 @@ func (b *backend) validateCredentials(req *logical.Request, data *framework.Fiel
 		// to be specified and validate it.
 		secretID := strings.TrimSpace(data.Get("secret_id").(string))
 		if secretID == "" {
-			return nil, "", metadata, fmt.Errorf("missing secret_id")
+			return nil, "", metadata, nil, fmt.Errorf("missing secret_id")
 		}
 
 		// Check if the SecretID supplied is valid. If use limit was specified
 		// on the SecretID, it will be decremented in this call.
-		var valid bool
-		valid, metadata, err = b.validateBindSecretID(req, roleName, secretID, role.HMACKey, role.BoundCIDRList)
+		valid, secretIDEntry, err := b.validateBindSecretID(req, roleName, secretID, role.HMACKey, role.BoundCIDRList)
 		if err != nil {
-			return nil, "", metadata, err
+			return nil, "", metadata, nil, err
 		}
 		if !valid {
-			return nil, "", metadata, fmt.Errorf("invalid secret_id %q", secretID)
+			return nil, "", metadata, nil, fmt.Errorf("invalid secret_id %q", secretID)
 		}
+		metadata = secretIDEntry.Metadata
+		tokenBoundCIDRs = secretIDEntry.TokenBoundCIDRs
 	}
 
 	if role.BoundCIDRList != "" {
 		// If 'bound_cidr_list' was set, verify the CIDR restrictions
 		if req.Connection == nil || req.Connection.RemoteAddr == "" {
-			return nil, "", metadata, fmt.Errorf("failed to get connection information")
+			return nil, "", metadata, nil, fmt.Errorf("failed to get connection information")
 		}
 
 		belongs, err := cidrutil.IPBelongsToCIDRBlocksString(req.Connection.RemoteAddr, role.BoundCIDRList, ",")
 		if err != nil {
-			return nil, "", metadata, fmt.Errorf("failed to verify the CIDR restrictions set on the role: %v", err)
+			return nil, "", metadata, nil, fmt.Errorf("failed to verify the CIDR restrictions set on the role: %v", err)
 		}
 		if !belongs {
-			return nil, "", metadata, fmt.Errorf("source address %q unauthorized through CIDR restrictions on the role", req.Connection.RemoteAddr)
+			return nil, "", metadata, nil, fmt.Errorf("source address %q unauthorized through CIDR restrictions on the role", req.Connection.RemoteAddr)
 		}
 	}
 
-	return role, roleName, metadata, nil
+	return role, roleName, metadata, tokenBoundCIDRs, nil
 }
 
-// validateBindSecretID is used to determine if the given SecretID is a valid one.
+// validateBindSecretID is used to determine if the given SecretID is a valid
+// one. If it is, the storage entry of the SecretID is returned.
 func (b *backend) validateBindSecretID(req *logical.Request, roleName, secretID,
-	hmacKey, roleBoundCIDRList string) (bool, map[string]string, error) {
+	hmacKey, roleBoundCIDRList string) (bool, *secretIDStorageEntry, error) {
 	secretIDHMAC, err := createHMAC(hmacKey, secretID)
 	if err != nil {
 		return false, nil, fmt.Errorf("failed to create HMAC of secret_id: %v", err)
@@ -204,7 +211,7 @@ func (b *backend) validateBindSecretID(req *logical.Request, roleName, secretID,
 		}
 
 		lock.RUnlock()
-		return true, result.Metadata, nil
+		return true, result, nil
 	}
 
 	// If the SecretIDNumUses is non-zero, it means that its use-count should be updated
@@ -265,7 +272,7 @@ func (b *backend) validateBindSecretID(req *logical.Request, roleName, secretID,
 		}
 	}
 
-	return true, result.Metadata, nil
+	return true, result, nil
 }
 
 // verifyCIDRRoleSecretIDSubset checks if the CIDR blocks set on the secret ID
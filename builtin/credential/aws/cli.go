@@ -19,6 +19,14 @@ type CLIHandler struct{}
 // Generates the necessary data to send to the Vault server for generating a token
 // This is useful for other API clients to use
 func GenerateLoginData(accessKey, secretKey, sessionToken, headerValue string) (map[string]interface{}, error) {
+	return GenerateLoginDataForRegion(accessKey, secretKey, sessionToken, headerValue, "")
+}
+
+// GenerateLoginDataForRegion is like GenerateLoginData, but signs the request
+// for the STS endpoint of the given region, as required by roles setting
+// sts_region. If region is empty, the request is signed for the global
+// endpoint.
+func GenerateLoginDataForRegion(accessKey, secretKey, sessionToken, headerValue, region string) (map[string]interface{}, error) {
 	loginData := make(map[string]interface{})
 
 	credConfig := &awsutil.CredentialsConfig{
@@ -35,8 +43,13 @@ func GenerateLoginData(accessKey, secretKey, sessionToken, headerValue string) (
 	}
 
 	// Use the credentials we've found to construct an STS session
+	stsConfig := aws.Config{Credentials: creds}
+	if region != "" {
+		stsConfig.Region = aws.String(region)
+		stsConfig.Endpoint = aws.String(fmt.Sprintf("https://sts.%s.amazonaws.com", region))
+	}
 	stsSession, err := session.NewSessionWithOptions(session.Options{
-		Config: aws.Config{Credentials: creds},
+		Config: stsConfig,
 	})
 	if err != nil {
 		return nil, err
@@ -85,7 +98,7 @@ func (h *CLIHandler) Auth(c *api.Client, m map[string]string) (*api.Secret, erro
 		headerValue = ""
 	}
 
-	loginData, err := GenerateLoginData(m["aws_access_key_id"], m["aws_secret_access_key"], m["aws_security_token"], headerValue)
+	loginData, err := GenerateLoginDataForRegion(m["aws_access_key_id"], m["aws_secret_access_key"], m["aws_security_token"], headerValue, m["region"])
 	if err != nil {
 		return nil, err
 	}
@@ -130,6 +143,8 @@ Key/Value Pairs:
   aws_security_token=<token>          Security token for temporary credentials
   header_value                        The Value of the X-Vault-AWS-IAM-Server-ID header.
   role                                The name of the role you're requesting a token for
+  region                              The STS region to sign the request for, if the
+                                      role pins one with sts_region
   `
 
 	return strings.TrimSpace(help)
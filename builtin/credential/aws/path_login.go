@@ -11,7 +11,6 @@ import (
 	"net/http"
 	"net/url"
 	"reflect"
-	"regexp"
 	"strings"
 	"time"
 
@@ -1128,6 +1127,13 @@ func (b *backend) pathLoginUpdateIam(
 		return logical.ErrorResponse("nil response when parsing iam_request_headers"), nil
 	}
 
+	// Ensure that what is submitted to STS on behalf of the client is a
+	// signed GetCallerIdentity request and nothing else
+	signature, err := validateCallerIdentityRequest(parsedUrl, body, headers)
+	if err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("invalid GetCallerIdentity request: %v", err)), nil
+	}
+
 	config, err := b.lockedClientConfigEntry(req.Storage)
 	if err != nil {
 		return logical.ErrorResponse("error getting configuration"), nil
@@ -1147,6 +1153,21 @@ func (b *backend) pathLoginUpdateIam(
 		}
 	}
 
+	// If the role is given, the STS endpoint it pins takes precedence over
+	// that of the client configuration
+	roleName := data.Get("role").(string)
+	var roleEntry *awsRoleEntry
+	if roleName != "" {
+		roleEntry, err = b.lockedAWSRole(req.Storage, roleName)
+		if err != nil {
+			return nil, err
+		}
+		if roleEntry == nil {
+			return logical.ErrorResponse(fmt.Sprintf("entry for role %s not found", roleName)), nil
+		}
+		endpoint = roleEntry.stsEndpoint(endpoint)
+	}
+
 	callerID, err := submitCallerIdentityRequest(method, endpoint, parsedUrl, body, headers)
 	if err != nil {
 		return logical.ErrorResponse(fmt.Sprintf("error making upstream request: %v", err)), nil
@@ -1159,23 +1180,30 @@ func (b *backend) pathLoginUpdateIam(
 		return logical.ErrorResponse(fmt.Sprintf("error parsing arn %q: %v", callerID.Arn, err)), nil
 	}
 
-	roleName := data.Get("role").(string)
-	if roleName == "" {
+	if roleEntry == nil {
 		roleName = entity.FriendlyName
-	}
+		roleEntry, err = b.lockedAWSRole(req.Storage, roleName)
+		if err != nil {
+			return nil, err
+		}
+		if roleEntry == nil {
+			return logical.ErrorResponse(fmt.Sprintf("entry for role %s not found", roleName)), nil
+		}
 
-	roleEntry, err := b.lockedAWSRole(req.Storage, roleName)
-	if err != nil {
-		return nil, err
-	}
-	if roleEntry == nil {
-		return logical.ErrorResponse(fmt.Sprintf("entry for role %s not found", roleName)), nil
+		// The request was not submitted to the endpoint pinned by the role
+		if roleEntry.STSEndpoint != "" {
+			return logical.ErrorResponse(fmt.Sprintf("role %s sets sts_endpoint; the role must be specified on login", roleName)), nil
+		}
 	}
 
 	if roleEntry.AuthType != iamAuthType {
 		return logical.ErrorResponse(fmt.Sprintf("auth method iam not allowed for role %s", roleName)), nil
 	}
 
+	if roleEntry.STSRegion != "" && signature.Region != roleEntry.STSRegion {
+		return logical.ErrorResponse(fmt.Sprintf("GetCallerIdentity request was signed for STS region %q but role %s requires %q", signature.Region, roleName, roleEntry.STSRegion)), nil
+	}
+
 	// The role creation should ensure that either we're inferring this is an EC2 instance
 	// or that we're binding an ARN
 	// The only way BoundIamPrincipalID could get set is if BoundIamPrincipalARN was also set and
@@ -1212,6 +1240,7 @@ func (b *backend) pathLoginUpdateIam(
 
 	inferredEntityType := ""
 	inferredEntityId := ""
+	var instanceMetadata map[string]string
 	if roleEntry.InferredEntityType == ec2EntityType {
 		instance, err := b.validateInstance(req.Storage, entity.SessionInfo, roleEntry.InferredAWSRegion, callerID.Account)
 		if err != nil {
@@ -1238,6 +1267,10 @@ func (b *backend) pathLoginUpdateIam(
 
 		inferredEntityType = ec2EntityType
 		inferredEntityId = entity.SessionInfo
+
+		if roleEntry.InferredInstanceMetadata {
+			instanceMetadata = inferredInstanceMetadata(instance)
+		}
 	}
 
 	resp := &logical.Response{
@@ -1253,6 +1286,7 @@ func (b *backend) pathLoginUpdateIam(
 				"inferred_entity_id":   inferredEntityId,
 				"inferred_aws_region":  roleEntry.InferredAWSRegion,
 				"account_id":           entity.AccountNumber,
+				"sts_region":           signature.Region,
 			},
 			InternalData: map[string]interface{}{
 				"role_name": roleName,
@@ -1264,6 +1298,9 @@ func (b *backend) pathLoginUpdateIam(
 			},
 		},
 	}
+	for k, v := range instanceMetadata {
+		resp.Auth.Metadata[k] = v
+	}
 
 	if roleEntry.Period > time.Duration(0) {
 		resp.Auth.TTL = roleEntry.Period
@@ -1357,40 +1394,145 @@ func parseIamArn(iamArn string) (*iamEntity, error) {
 }
 
 func validateVaultHeaderValue(headers http.Header, requestUrl *url.URL, requiredHeaderValue string) error {
-	providedValue := ""
+	var providedValues []string
 	for k, v := range headers {
 		if strings.ToLower(iamServerIdHeader) == strings.ToLower(k) {
-			providedValue = strings.Join(v, ",")
+			providedValues = v
 			break
 		}
 	}
-	if providedValue == "" {
+	switch len(providedValues) {
+	case 0:
 		return fmt.Errorf("didn't find %s", iamServerIdHeader)
+	case 1:
+	default:
+		return fmt.Errorf("found multiple values for %s", iamServerIdHeader)
 	}
 
 	// NOT doing a constant time compare here since the value is NOT intended to be secret
-	if providedValue != requiredHeaderValue {
-		return fmt.Errorf("expected %s but got %s", requiredHeaderValue, providedValue)
+	if providedValues[0] != requiredHeaderValue {
+		return fmt.Errorf("expected %s but got %s", requiredHeaderValue, providedValues[0])
 	}
 
-	if authzHeaders, ok := headers["Authorization"]; ok {
-		// authzHeader looks like AWS4-HMAC-SHA256 Credential=AKI..., SignedHeaders=host;x-amz-date;x-vault-awsiam-id, Signature=...
-		// We need to extract out the SignedHeaders
-		re := regexp.MustCompile(".*SignedHeaders=([^,]+)")
-		authzHeader := strings.Join(authzHeaders, ",")
-		matches := re.FindSubmatch([]byte(authzHeader))
-		if len(matches) < 1 {
-			return fmt.Errorf("vault header wasn't signed")
+	// TODO: If we support GET requests, then we need to parse the X-Amz-SignedHeaders
+	// argument out of the query string and search in there for the header value
+	authz, err := parseSigV4Authorization(headers)
+	if err != nil {
+		return err
+	}
+	return ensureHeaderIsSigned(strings.Join(authz.SignedHeaders, ";"), iamServerIdHeader)
+}
+
+// sigV4Authorization holds the parts of the AWS Signature Version 4
+// Authorization header of a signed request that are checked on login
+type sigV4Authorization struct {
+	Algorithm     string
+	Region        string
+	Service       string
+	SignedHeaders []string
+}
+
+// parseSigV4Authorization parses the Authorization header, which looks like
+// AWS4-HMAC-SHA256 Credential=AKI.../20150830/us-east-1/sts/aws4_request, SignedHeaders=host;x-amz-date;x-vault-awsiam-id, Signature=...
+// Some clients split it into multiple values at the commas.
+func parseSigV4Authorization(headers http.Header) (*sigV4Authorization, error) {
+	authzHeaders, ok := headers["Authorization"]
+	if !ok {
+		return nil, fmt.Errorf("missing Authorization header")
+	}
+	authzHeader := strings.TrimSpace(strings.Join(authzHeaders, ","))
+
+	algorithmEnd := strings.Index(authzHeader, " ")
+	if algorithmEnd < 0 {
+		return nil, fmt.Errorf("malformed Authorization header")
+	}
+
+	authz := &sigV4Authorization{
+		Algorithm: authzHeader[:algorithmEnd],
+	}
+	var hasCredential, hasSignedHeaders bool
+	for _, component := range strings.Split(authzHeader[algorithmEnd+1:], ",") {
+		kv := strings.SplitN(strings.TrimSpace(component), "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("malformed Authorization header component %q", component)
 		}
-		if len(matches) > 2 {
-			return fmt.Errorf("found multiple SignedHeaders components")
+		switch kv[0] {
+		case "Credential":
+			if hasCredential {
+				return nil, fmt.Errorf("found multiple Credential components")
+			}
+			hasCredential = true
+
+			// The credential scope is <access key>/<date>/<region>/<service>/aws4_request
+			scope := strings.Split(kv[1], "/")
+			if len(scope) != 5 || scope[4] != "aws4_request" {
+				return nil, fmt.Errorf("malformed credential scope %q", kv[1])
+			}
+			authz.Region = scope[2]
+			authz.Service = scope[3]
+		case "SignedHeaders":
+			if hasSignedHeaders {
+				return nil, fmt.Errorf("found multiple SignedHeaders components")
+			}
+			hasSignedHeaders = true
+			authz.SignedHeaders = strings.Split(kv[1], ";")
 		}
-		signedHeaders := string(matches[1])
-		return ensureHeaderIsSigned(signedHeaders, iamServerIdHeader)
 	}
-	// TODO: If we support GET requests, then we need to parse the X-Amz-SignedHeaders
-	// argument out of the query string and search in there for the header value
-	return fmt.Errorf("missing Authorization header")
+
+	if !hasCredential {
+		return nil, fmt.Errorf("missing Credential in Authorization header")
+	}
+	if !hasSignedHeaders {
+		return nil, fmt.Errorf("vault header wasn't signed")
+	}
+	return authz, nil
+}
+
+// validateCallerIdentityRequest ensures that the request to be submitted to
+// STS is a GetCallerIdentity request signed for STS, and returns its
+// signature
+func validateCallerIdentityRequest(requestUrl *url.URL, body string, headers http.Header) (*sigV4Authorization, error) {
+	form, err := url.ParseQuery(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse request body: %v", err)
+	}
+	if actions := form["Action"]; len(actions) != 1 || actions[0] != "GetCallerIdentity" {
+		return nil, fmt.Errorf("request body must contain the single action GetCallerIdentity")
+	}
+	if actions, ok := requestUrl.Query()["Action"]; ok && (len(actions) != 1 || actions[0] != "GetCallerIdentity") {
+		return nil, fmt.Errorf("request URL contains an action other than GetCallerIdentity")
+	}
+
+	authz, err := parseSigV4Authorization(headers)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case authz.Algorithm != "AWS4-HMAC-SHA256":
+		return nil, fmt.Errorf("unsupported signing algorithm %q", authz.Algorithm)
+	case authz.Service != "sts":
+		return nil, fmt.Errorf("request was signed for service %q rather than sts", authz.Service)
+	case authz.Region == "":
+		return nil, fmt.Errorf("missing region in credential scope")
+	}
+	if err := ensureHeaderIsSigned(strings.Join(authz.SignedHeaders, ";"), "Host"); err != nil {
+		return nil, fmt.Errorf("host header wasn't signed")
+	}
+	return authz, nil
+}
+
+// inferredInstanceMetadata returns the token metadata describing an inferred
+// EC2 instance
+func inferredInstanceMetadata(instance *ec2.Instance) map[string]string {
+	metadata := map[string]string{
+		"inferred_ami_id":    aws.StringValue(instance.ImageId),
+		"inferred_vpc_id":    aws.StringValue(instance.VpcId),
+		"inferred_subnet_id": aws.StringValue(instance.SubnetId),
+	}
+	if instance.IamInstanceProfile != nil {
+		metadata["inferred_iam_instance_profile_arn"] = aws.StringValue(instance.IamInstanceProfile.Arn)
+	}
+	return metadata
 }
 
 func buildHttpRequest(method, endpoint string, parsedUrl *url.URL, body string, headers http.Header) *http.Request {
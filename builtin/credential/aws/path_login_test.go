@@ -5,9 +5,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"reflect"
 	"testing"
+
+	"github.com/hashicorp/vault/logical"
 )
 
 func TestBackend_pathLogin_getCallerIdentityResponse(t *testing.T) {
@@ -208,3 +211,137 @@ func TestBackend_pathLogin_parseIamRequestHeaders(t *testing.T) {
 		t.Errorf("error parsing mixed-style headers: %v", err)
 	}
 }
+
+func TestBackend_validateCallerIdentityRequest(t *testing.T) {
+	requestUrl, err := url.Parse("https://sts.us-west-2.amazonaws.com/")
+	if err != nil {
+		t.Fatalf("error parsing test URL: %v", err)
+	}
+	body := "Action=GetCallerIdentity&Version=2011-06-15"
+	headers := func(authz string) http.Header {
+		return http.Header{
+			"Authorization": []string{authz},
+		}
+	}
+	validHeaders := headers("AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-west-2/sts/aws4_request, SignedHeaders=content-type;host;x-amz-date, Signature=5d672d79c15b13162d9279b0855cfba6789a8edb4c82c400e06b5924a6f2b5d7")
+
+	authz, err := validateCallerIdentityRequest(requestUrl, body, validHeaders)
+	if err != nil {
+		t.Fatalf("did NOT validate valid request: %v", err)
+	}
+	if authz.Region != "us-west-2" || authz.Service != "sts" {
+		t.Fatalf("bad: signature: %#v", authz)
+	}
+
+	if _, err := validateCallerIdentityRequest(requestUrl, "Action=GetCallerIdentity&Action=AssumeRole", validHeaders); err == nil {
+		t.Error("validated request with multiple actions")
+	}
+	if _, err := validateCallerIdentityRequest(requestUrl, "Action=GetSessionToken", validHeaders); err == nil {
+		t.Error("validated request with another action")
+	}
+
+	queryUrl, _ := url.Parse("https://sts.us-west-2.amazonaws.com/?Action=AssumeRole")
+	if _, err := validateCallerIdentityRequest(queryUrl, body, validHeaders); err == nil {
+		t.Error("validated request with another action in the URL")
+	}
+
+	for _, authz := range []string{
+		"AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-west-2/iam/aws4_request, SignedHeaders=content-type;host;x-amz-date, Signature=abc",
+		"AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-west-2/sts/aws4_request, SignedHeaders=content-type;x-amz-date, Signature=abc",
+		"AWS4-HMAC-SHA1 Credential=AKIDEXAMPLE/20150830/us-west-2/sts/aws4_request, SignedHeaders=content-type;host;x-amz-date, Signature=abc",
+		"AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/us-west-2/sts/aws4_request, SignedHeaders=content-type;host;x-amz-date, Signature=abc",
+		"AWS4-HMAC-SHA256 SignedHeaders=content-type;host;x-amz-date, Signature=abc",
+	} {
+		if _, err := validateCallerIdentityRequest(requestUrl, body, headers(authz)); err == nil {
+			t.Errorf("validated request with Authorization header %q", authz)
+		}
+	}
+	if _, err := validateCallerIdentityRequest(requestUrl, body, http.Header{}); err == nil {
+		t.Error("validated request without Authorization header")
+	}
+}
+
+func TestBackend_pathLoginIam_stsRegion(t *testing.T) {
+	sts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<GetCallerIdentityResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <GetCallerIdentityResult>
+    <Arn>arn:aws:iam::123456789012:user/MyUserName</Arn>
+    <UserId>ASOMETHINGSOMETHINGSOMETHING</UserId>
+    <Account>123456789012</Account>
+  </GetCallerIdentityResult>
+</GetCallerIdentityResponse>`))
+	}))
+	defer sts.Close()
+
+	storage := &logical.InmemStorage{}
+	config := logical.TestBackendConfig()
+	config.StorageView = storage
+	b, err := Backend(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Setup(config); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "role/MyUserName",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"auth_type":               iamAuthType,
+			"bound_iam_principal_arn": "arn:aws:iam::123456789012:user/MyUserName",
+			"resolve_aws_unique_ids":  false,
+			"sts_region":              "us-west-2",
+			"sts_endpoint":            sts.URL,
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("resp: %#v, err: %v", resp, err)
+	}
+
+	login := func(role, region string) *logical.Response {
+		headers, err := json.Marshal(map[string][]string{
+			"Authorization": {fmt.Sprintf("AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/%s/sts/aws4_request, SignedHeaders=content-type;host;x-amz-date, Signature=abc", region)},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := b.HandleRequest(&logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "login",
+			Storage:   storage,
+			Data: map[string]interface{}{
+				"role":                    role,
+				"iam_http_request_method": "POST",
+				"iam_request_url":         base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("https://sts.%s.amazonaws.com/", region))),
+				"iam_request_body":        base64.StdEncoding.EncodeToString([]byte("Action=GetCallerIdentity&Version=2011-06-15")),
+				"iam_request_headers":     base64.StdEncoding.EncodeToString(headers),
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	resp = login("MyUserName", "us-west-2")
+	if resp == nil || resp.IsError() || resp.Auth == nil {
+		t.Fatalf("bad: %#v", resp)
+	}
+	if resp.Auth.Metadata["sts_region"] != "us-west-2" {
+		t.Fatalf("bad: metadata: %#v", resp.Auth.Metadata)
+	}
+
+	// Requests signed for other regions are rejected
+	resp = login("MyUserName", "us-east-1")
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error; resp: %#v", resp)
+	}
+
+	// The role pins the STS endpoint, so it must be given
+	resp = login("", "us-west-2")
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error; resp: %#v", resp)
+	}
+}
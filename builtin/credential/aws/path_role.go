@@ -2,6 +2,7 @@ package awsauth
 
 import (
 	"fmt"
+	"net/url"
 	"strings"
 	"time"
 
@@ -89,6 +90,25 @@ fail.`,
 				Type: framework.TypeString,
 				Description: `When auth_type is iam and
 inferred_entity_type is set, the region to assume the inferred entity exists in.`,
+			},
+			"inferred_instance_metadata": {
+				Type:    framework.TypeBool,
+				Default: false,
+				Description: `When auth_type is iam and inferred_entity_type is ec2_instance,
+if set, the AMI ID, VPC ID, subnet ID and IAM instance profile ARN of the
+inferred EC2 instance are added to the metadata of the issued tokens.`,
+			},
+			"sts_region": {
+				Type: framework.TypeString,
+				Description: `If set, the GetCallerIdentity requests of logins against this role
+must be signed for this STS region, and are submitted to the regional STS
+endpoint unless sts_endpoint is set. Only applicable when auth_type is iam.`,
+			},
+			"sts_endpoint": {
+				Type: framework.TypeString,
+				Description: `If set, the STS endpoint the GetCallerIdentity requests of logins against
+this role are submitted to, overriding the one of the client configuration. The
+role must then be specified on login. Only applicable when auth_type is iam.`,
 			},
 			"bound_vpc_id": {
 				Type: framework.TypeString,
@@ -521,6 +541,24 @@ func (b *backend) pathRoleCreateUpdate(
 		roleEntry.InferredAWSRegion = inferredAWSRegionRaw.(string)
 	}
 
+	if inferredInstanceMetadataRaw, ok := data.GetOk("inferred_instance_metadata"); ok {
+		roleEntry.InferredInstanceMetadata = inferredInstanceMetadataRaw.(bool)
+	}
+
+	if stsRegionRaw, ok := data.GetOk("sts_region"); ok {
+		roleEntry.STSRegion = strings.TrimSpace(stsRegionRaw.(string))
+	}
+
+	if stsEndpointRaw, ok := data.GetOk("sts_endpoint"); ok {
+		roleEntry.STSEndpoint = strings.TrimSpace(stsEndpointRaw.(string))
+		if roleEntry.STSEndpoint != "" {
+			u, err := url.Parse(roleEntry.STSEndpoint)
+			if err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") {
+				return logical.ErrorResponse(fmt.Sprintf("invalid sts_endpoint %q", roleEntry.STSEndpoint)), nil
+			}
+		}
+	}
+
 	// auth_type is a special case as it's immutable and can't be changed once a role is created
 	if authTypeRaw, ok := data.GetOk("auth_type"); ok {
 		// roleEntry.AuthType should only be "" when it's a new role; existing roles without an
@@ -562,6 +600,17 @@ func (b *backend) pathRoleCreateUpdate(
 		allowEc2Binds = true
 	} else if roleEntry.InferredAWSRegion != "" {
 		return logical.ErrorResponse("specified inferred_aws_region but not inferred_entity_type"), nil
+	} else if roleEntry.InferredInstanceMetadata {
+		return logical.ErrorResponse("specified inferred_instance_metadata but not inferred_entity_type"), nil
+	}
+
+	if roleEntry.AuthType != iamAuthType {
+		switch {
+		case roleEntry.STSRegion != "":
+			return logical.ErrorResponse("specified sts_region but not allowing iam auth_type"), nil
+		case roleEntry.STSEndpoint != "":
+			return logical.ErrorResponse("specified sts_endpoint but not allowing iam auth_type"), nil
+		}
 	}
 
 	numBinds := 0
@@ -746,6 +795,9 @@ type awsRoleEntry struct {
 	BoundVpcID                 string        `json:"bound_vpc_id" structs:"bound_vpc_id" mapstructure:"bound_vpc_id"`
 	InferredEntityType         string        `json:"inferred_entity_type" structs:"inferred_entity_type" mapstructure:"inferred_entity_type"`
 	InferredAWSRegion          string        `json:"inferred_aws_region" structs:"inferred_aws_region" mapstructure:"inferred_aws_region"`
+	InferredInstanceMetadata   bool          `json:"inferred_instance_metadata" structs:"inferred_instance_metadata" mapstructure:"inferred_instance_metadata"`
+	STSRegion                  string        `json:"sts_region" structs:"sts_region" mapstructure:"sts_region"`
+	STSEndpoint                string        `json:"sts_endpoint" structs:"sts_endpoint" mapstructure:"sts_endpoint"`
 	ResolveAWSUniqueIDs        bool          `json:"resolve_aws_unique_ids" structs:"resolve_aws_unique_ids" mapstructure:"resolve_aws_unique_ids"`
 	RoleTag                    string        `json:"role_tag" structs:"role_tag" mapstructure:"role_tag"`
 	AllowInstanceMigration     bool          `json:"allow_instance_migration" structs:"allow_instance_migration" mapstructure:"allow_instance_migration"`
@@ -757,6 +809,19 @@ type awsRoleEntry struct {
 	Period                     time.Duration `json:"period" mapstructure:"period" structs:"period"`
 }
 
+// stsEndpoint returns the STS endpoint that the GetCallerIdentity requests of
+// logins against the role are submitted to, or defaultEndpoint if the role
+// doesn't pin one
+func (r *awsRoleEntry) stsEndpoint(defaultEndpoint string) string {
+	switch {
+	case r.STSEndpoint != "":
+		return r.STSEndpoint
+	case r.STSRegion != "":
+		return fmt.Sprintf("https://sts.%s.amazonaws.com", r.STSRegion)
+	}
+	return defaultEndpoint
+}
+
 const pathRoleSyn = `
 Create a role and associate policies to it.
 `
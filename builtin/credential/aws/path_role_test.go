@@ -478,6 +478,57 @@ func TestBackend_pathRoleMixedTypes(t *testing.T) {
 		t.Fatalf("allowed changing resolve_aws_unique_ids from true to false")
 	}
 
+	// STS settings only apply to iam roles
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "role/ec2WithSTSRegion",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"auth_type":    ec2AuthType,
+			"bound_ami_id": "ami-abc1234",
+			"sts_region":   "us-west-2",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("allowed setting sts_region on an ec2 role")
+	}
+
+	data = map[string]interface{}{
+		"auth_type":               iamAuthType,
+		"bound_iam_principal_arn": "arn:aws:iam::123456789012:role/MyRole",
+		"resolve_aws_unique_ids":  false,
+		"sts_endpoint":            "sts.us-west-2.amazonaws.com",
+	}
+	resp, err = submitRequest("iamWithSTSEndpoint", logical.CreateOperation)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("allowed setting an sts_endpoint without scheme")
+	}
+
+	data["sts_endpoint"] = "https://sts.us-west-2.amazonaws.com"
+	data["sts_region"] = "us-west-2"
+	data["inferred_instance_metadata"] = true
+	resp, err = submitRequest("iamWithSTSEndpoint", logical.CreateOperation)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("allowed setting inferred_instance_metadata without inferred_entity_type")
+	}
+
+	delete(data, "inferred_instance_metadata")
+	resp, err = submitRequest("iamWithSTSEndpoint", logical.CreateOperation)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp != nil && resp.IsError() {
+		t.Fatalf("failed to create valid role; resp: %#v", resp)
+	}
 }
 
 func TestAwsEc2_RoleCrud(t *testing.T) {
@@ -561,6 +612,9 @@ func TestAwsEc2_RoleCrud(t *testing.T) {
 		"bound_vpc_id":                   "testvpcid",
 		"inferred_entity_type":           "",
 		"inferred_aws_region":            "",
+		"inferred_instance_metadata":     false,
+		"sts_region":                     "",
+		"sts_endpoint":                   "",
 		"resolve_aws_unique_ids":         false,
 		"role_tag":                       "testtag",
 		"allow_instance_migration":       true,
@@ -24,6 +24,10 @@ func Backend() *backend {
 			Unauthenticated: []string{
 				"login",
 			},
+
+			ExportableStorage: []string{
+				"cert/",
+			},
 		},
 		Paths: append([]*framework.Path{
 			pathConfig(&b),
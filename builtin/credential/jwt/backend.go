@@ -0,0 +1,295 @@
+package jwtauth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+const (
+	configPath string = "config"
+	rolePrefix string = "role/"
+
+	// jwksRefreshInterval is the minimum time between two fetches of the
+	// keys made because a token was signed with an unknown key, so that
+	// unauthenticated callers can't use Vault to flood the identity provider
+	jwksRefreshInterval = time.Minute
+)
+
+// Factory returns a new backend as logical.Backend.
+func Factory(conf *logical.BackendConfig) (logical.Backend, error) {
+	b := Backend()
+	if err := b.Setup(conf); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// jwtAuthBackend implements logical.Backend
+type jwtAuthBackend struct {
+	*framework.Backend
+
+	l sync.RWMutex
+
+	// keysLock guards the cached keys, which are fetched from the JWKS or
+	// OIDC discovery URL the first time a token is validated
+	keysLock   sync.Mutex
+	cachedKeys *keySet
+
+	// lastRefresh is when the keys were last fetched again for a token
+	// signed with an unknown key
+	lastRefresh time.Time
+}
+
+func Backend() *jwtAuthBackend {
+	b := &jwtAuthBackend{}
+
+	b.Backend = &framework.Backend{
+		AuthRenew:   b.pathLoginRenew,
+		BackendType: logical.TypeCredential,
+		Invalidate:  b.invalidate,
+		Help:        backendHelp,
+		PathsSpecial: &logical.Paths{
+			Unauthenticated: []string{
+				"login",
+			},
+		},
+		Paths: framework.PathAppend(
+			[]*framework.Path{
+				pathConfig(b),
+				pathLogin(b),
+			},
+			pathsRole(b),
+		),
+	}
+
+	return b
+}
+
+func (b *jwtAuthBackend) invalidate(key string) {
+	switch key {
+	case configPath:
+		b.resetKeys()
+	}
+}
+
+// keySet holds the public keys that tokens are validated against
+type keySet struct {
+	keys []jose.JSONWebKey
+
+	// issuer is the issuer given by the OIDC discovery document, if the keys
+	// were discovered
+	issuer string
+
+	// remote is set if the keys were fetched and can be fetched again when
+	// a token is signed with an unknown key
+	remote bool
+}
+
+// hasKeyID returns whether the set contains a key with the given key ID
+func (s *keySet) hasKeyID(kid string) bool {
+	for _, key := range s.keys {
+		if key.KeyID == kid {
+			return true
+		}
+	}
+	return false
+}
+
+// candidates returns the keys a token signed with the given key ID may
+// have been signed with
+func (s *keySet) candidates(kid string) []jose.JSONWebKey {
+	if kid == "" {
+		return s.keys
+	}
+
+	var keys []jose.JSONWebKey
+	for _, key := range s.keys {
+		if key.KeyID == "" || key.KeyID == kid {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func (b *jwtAuthBackend) resetKeys() {
+	b.keysLock.Lock()
+	defer b.keysLock.Unlock()
+
+	b.cachedKeys = nil
+	b.lastRefresh = time.Time{}
+}
+
+// keys returns the keys to validate tokens against. If refresh is set, they
+// are fetched again unless they already were in the last
+// jwksRefreshInterval.
+func (b *jwtAuthBackend) keys(config *jwtConfig, refresh bool) (*keySet, error) {
+	b.keysLock.Lock()
+	defer b.keysLock.Unlock()
+
+	if refresh && time.Since(b.lastRefresh) < jwksRefreshInterval {
+		refresh = false
+	}
+	if b.cachedKeys != nil && !refresh {
+		return b.cachedKeys, nil
+	}
+
+	keys, err := loadKeys(config)
+	if err != nil {
+		return nil, err
+	}
+	b.cachedKeys = keys
+	if refresh {
+		b.lastRefresh = time.Now()
+	}
+	return keys, nil
+}
+
+// loadKeys loads the keys given by the configuration
+func loadKeys(config *jwtConfig) (*keySet, error) {
+	switch {
+	case len(config.JWTValidationPubKeys) > 0:
+		keys := &keySet{}
+		for _, pem := range config.JWTValidationPubKeys {
+			key, err := parsePublicKeyPEM([]byte(pem))
+			if err != nil {
+				return nil, err
+			}
+			keys.keys = append(keys.keys, jose.JSONWebKey{Key: key})
+		}
+		return keys, nil
+
+	case config.JWKSURL != "":
+		keys, err := fetchJWKS(config.JWKSURL, config.JWKSCAPEM)
+		if err != nil {
+			return nil, err
+		}
+		return &keySet{
+			keys:   keys,
+			remote: true,
+		}, nil
+
+	case config.OIDCDiscoveryURL != "":
+		client, err := httpClient(config.OIDCDiscoveryCAPEM)
+		if err != nil {
+			return nil, err
+		}
+
+		discoveryURL := strings.TrimSuffix(config.OIDCDiscoveryURL, "/")
+		var discovery struct {
+			Issuer  string `json:"issuer"`
+			JWKSURI string `json:"jwks_uri"`
+		}
+		if err := getJSON(client, discoveryURL+"/.well-known/openid-configuration", &discovery); err != nil {
+			return nil, fmt.Errorf("error fetching OIDC discovery document: %v", err)
+		}
+
+		// The issuer must be the URL the document was discovered at
+		if strings.TrimSuffix(discovery.Issuer, "/") != discoveryURL {
+			return nil, fmt.Errorf("issuer %q of the OIDC discovery document does not match %q", discovery.Issuer, config.OIDCDiscoveryURL)
+		}
+		if discovery.JWKSURI == "" {
+			return nil, fmt.Errorf("OIDC discovery document does not contain a jwks_uri")
+		}
+
+		keys, err := fetchJWKS(discovery.JWKSURI, config.OIDCDiscoveryCAPEM)
+		if err != nil {
+			return nil, err
+		}
+		return &keySet{
+			keys:   keys,
+			issuer: discovery.Issuer,
+			remote: true,
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("no keys configured")
+	}
+}
+
+// fetchJWKS fetches a JSON Web Key Set, keeping only its public keys
+func fetchJWKS(url, caPEM string) ([]jose.JSONWebKey, error) {
+	client, err := httpClient(caPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	var keySet jose.JSONWebKeySet
+	if err := getJSON(client, url, &keySet); err != nil {
+		return nil, fmt.Errorf("error fetching JWKS: %v", err)
+	}
+
+	var keys []jose.JSONWebKey
+	for _, key := range keySet.Keys {
+		// Symmetric keys must never be used to validate tokens, since
+		// anyone able to fetch them could sign tokens
+		if key.IsPublic() && (key.Use == "" || key.Use == "sig") {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("JWKS at %q contains no public signing keys", url)
+	}
+	return keys, nil
+}
+
+func httpClient(caPEM string) (*http.Client, error) {
+	client := cleanhttp.DefaultClient()
+
+	// If we have a CA cert build the TLSConfig
+	if caPEM != "" {
+		certPool := x509.NewCertPool()
+		if !certPool.AppendCertsFromPEM([]byte(caPEM)) {
+			return nil, fmt.Errorf("could not parse CA certificate")
+		}
+
+		client.Transport.(*http.Transport).TLSClientConfig = &tls.Config{
+			MinVersion: tls.VersionTLS12,
+			RootCAs:    certPool,
+		}
+	}
+
+	return client, nil
+}
+
+func getJSON(client *http.Client, url string, out interface{}) error {
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned status %d: %s", url, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	return json.Unmarshal(body, out)
+}
+
+const backendHelp = `
+The JWT backend authenticates clients presenting JSON Web Tokens, such as
+OIDC ID tokens or tokens issued by CI systems.
+
+Tokens are validated against the keys of a JWKS URL, the keys found through
+OIDC discovery or a set of configured public keys. Each role binds the
+audiences, subject and claims a token must have to log in with it, and maps
+claims of the token to the name and metadata of the entity alias.
+
+After enabling the backend, use the "config" route to configure how tokens
+are validated and the "role" route to create roles.
+`
@@ -0,0 +1,430 @@
+package jwtauth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+	jose "gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+// fakeProvider stands in for an OIDC provider, serving its discovery
+// document and keys
+type fakeProvider struct {
+	sync.Mutex
+
+	server *httptest.Server
+	keys   []jose.JSONWebKey
+
+	// fetches counts the requests for the keys
+	fetches int
+}
+
+func newFakeProvider(t *testing.T) *fakeProvider {
+	p := &fakeProvider{}
+	p.server = httptest.NewServer(p)
+	p.rotate(t, "key-1")
+	return p
+}
+
+func (p *fakeProvider) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.Lock()
+	defer p.Unlock()
+
+	switch r.URL.Path {
+	case "/.well-known/openid-configuration":
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":   p.server.URL,
+			"jwks_uri": p.server.URL + "/keys",
+		})
+	case "/keys":
+		p.fetches++
+		var keys []jose.JSONWebKey
+		for _, key := range p.keys {
+			keys = append(keys, publicKey(key))
+		}
+		json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: keys})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// rotate replaces the signing key of the provider
+func (p *fakeProvider) rotate(t *testing.T, kid string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p.Lock()
+	defer p.Unlock()
+	p.keys = []jose.JSONWebKey{{Key: key, KeyID: kid, Algorithm: string(jose.RS256), Use: "sig"}}
+}
+
+// publicKey returns the public half of an RSA signing key
+func publicKey(key jose.JSONWebKey) jose.JSONWebKey {
+	key.Key = &key.Key.(*rsa.PrivateKey).PublicKey
+	return key
+}
+
+func (p *fakeProvider) token(t *testing.T, claims map[string]interface{}) string {
+	p.Lock()
+	key := p.keys[0]
+	p.Unlock()
+
+	return signToken(t, jose.SigningKey{Algorithm: jose.RS256, Key: key}, claims)
+}
+
+func signToken(t *testing.T, key jose.SigningKey, claims map[string]interface{}) string {
+	signer, err := jose.NewSigner(key, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := jwt.Signed(signer).Claims(claims).CompactSerialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func testBackend(t *testing.T) (*jwtAuthBackend, logical.Storage) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+	config.System = &logical.StaticSystemView{
+		DefaultLeaseTTLVal: time.Hour,
+		MaxLeaseTTLVal:     24 * time.Hour,
+	}
+
+	b := Backend()
+	if err := b.Setup(config); err != nil {
+		t.Fatal(err)
+	}
+	return b, config.StorageView
+}
+
+func testWrite(t *testing.T, b *jwtAuthBackend, storage logical.Storage, path string, data map[string]interface{}) *logical.Response {
+	var operation logical.Operation = logical.UpdateOperation
+	if strings.HasPrefix(path, rolePrefix) {
+		operation = logical.CreateOperation
+	}
+
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: operation,
+		Path:      path,
+		Storage:   storage,
+		Data:      data,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func testLogin(t *testing.T, b *jwtAuthBackend, storage logical.Storage, role, token string) *logical.Response {
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "login",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"role": role,
+			"jwt":  token,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestBackend_oidcDiscovery(t *testing.T) {
+	provider := newFakeProvider(t)
+	defer provider.server.Close()
+
+	b, storage := testBackend(t)
+
+	resp := testWrite(t, b, storage, "config", map[string]interface{}{
+		"oidc_discovery_url": provider.server.URL,
+	})
+	if resp != nil && resp.IsError() {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	resp = testWrite(t, b, storage, "role/dev", map[string]interface{}{
+		"bound_audiences": "vault",
+		"bound_claims": map[string]interface{}{
+			"/org/teams": []interface{}{"dev", "ops"},
+		},
+		"user_claim": "email",
+		"claim_mappings": map[string]interface{}{
+			"name":          "name",
+			"/org/employee": "employee_id",
+		},
+		"policies": "dev",
+		"ttl":      "30m",
+	})
+	if resp != nil && resp.IsError() {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	claims := func() map[string]interface{} {
+		return map[string]interface{}{
+			"iss":   provider.server.URL,
+			"sub":   "user-1",
+			"aud":   []string{"vault", "other"},
+			"exp":   time.Now().Add(time.Minute).Unix(),
+			"email": "jane@example.com",
+			"name":  "Jane",
+			"org": map[string]interface{}{
+				"teams":    []string{"qa", "dev"},
+				"employee": 42,
+			},
+		}
+	}
+
+	resp = testLogin(t, b, storage, "dev", provider.token(t, claims()))
+	if resp == nil || resp.IsError() || resp.Auth == nil {
+		t.Fatalf("bad: %#v", resp)
+	}
+	if resp.Auth.Alias.Name != "jane@example.com" || resp.Auth.DisplayName != "jane@example.com" {
+		t.Fatalf("bad: %#v", resp.Auth)
+	}
+	if resp.Auth.Alias.Metadata["name"] != "Jane" || resp.Auth.Alias.Metadata["employee_id"] != "42" {
+		t.Fatalf("bad: %#v", resp.Auth.Alias.Metadata)
+	}
+	if resp.Auth.Metadata["role"] != "dev" || resp.Auth.Metadata["name"] != "Jane" {
+		t.Fatalf("bad: %#v", resp.Auth.Metadata)
+	}
+	if len(resp.Auth.Policies) != 1 || resp.Auth.Policies[0] != "dev" || resp.Auth.TTL != 30*time.Minute {
+		t.Fatalf("bad: %#v", resp.Auth)
+	}
+
+	// Tokens signed with a rotated key are accepted once the keys are
+	// fetched again
+	provider.rotate(t, "key-2")
+	resp = testLogin(t, b, storage, "dev", provider.token(t, claims()))
+	if resp == nil || resp.IsError() {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// The keys are fetched again at most once per jwksRefreshInterval, however
+	// many tokens signed with unknown keys are presented
+	provider.Lock()
+	fetches := provider.fetches
+	provider.Unlock()
+	provider.rotate(t, "key-3")
+	for i := 0; i < 3; i++ {
+		resp = testLogin(t, b, storage, "dev", provider.token(t, claims()))
+		if resp == nil || !resp.IsError() {
+			t.Fatalf("expected an error, got %#v", resp)
+		}
+	}
+	provider.Lock()
+	if provider.fetches != fetches {
+		t.Fatalf("expected no fetch of the keys, got %d", provider.fetches-fetches)
+	}
+	provider.Unlock()
+
+	b.keysLock.Lock()
+	b.lastRefresh = time.Now().Add(-jwksRefreshInterval)
+	b.keysLock.Unlock()
+	resp = testLogin(t, b, storage, "dev", provider.token(t, claims()))
+	if resp == nil || resp.IsError() {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	for name, modify := range map[string]func(map[string]interface{}){
+		"wrong issuer":      func(c map[string]interface{}) { c["iss"] = "https://example.com" },
+		"wrong audience":    func(c map[string]interface{}) { c["aud"] = "other" },
+		"expired":           func(c map[string]interface{}) { c["exp"] = time.Now().Add(-time.Hour).Unix() },
+		"missing expiry":    func(c map[string]interface{}) { delete(c, "exp") },
+		"unbound claim":     func(c map[string]interface{}) { c["org"].(map[string]interface{})["teams"] = []string{"qa"} },
+		"missing user name": func(c map[string]interface{}) { delete(c, "email") },
+	} {
+		c := claims()
+		modify(c)
+		resp = testLogin(t, b, storage, "dev", provider.token(t, c))
+		if resp == nil || !resp.IsError() {
+			t.Fatalf("%s: expected an error, got %#v", name, resp)
+		}
+	}
+
+	// Tokens signed by another key are rejected
+	other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp = testLogin(t, b, storage, "dev", signToken(t, jose.SigningKey{Algorithm: jose.ES256, Key: other}, claims()))
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error, got %#v", resp)
+	}
+
+	// Tokens signed with a symmetric key are rejected, even if the key is a
+	// published public key
+	provider.Lock()
+	published := publicKey(provider.keys[0])
+	provider.Unlock()
+	publicKeyBytes, err := json.Marshal(published)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp = testLogin(t, b, storage, "dev", signToken(t, jose.SigningKey{Algorithm: jose.HS256, Key: publicKeyBytes}, claims()))
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error, got %#v", resp)
+	}
+
+	// Renewal requires the role to still exist
+	req := &logical.Request{
+		Operation: logical.RenewOperation,
+		Path:      "login",
+		Storage:   storage,
+		Auth: &logical.Auth{
+			InternalData: map[string]interface{}{
+				"role": "dev",
+			},
+			LeaseOptions: logical.LeaseOptions{
+				TTL:       time.Minute,
+				IssueTime: time.Now(),
+			},
+		},
+	}
+	resp, err = b.HandleRequest(req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: resp: %#v err: %v", resp, err)
+	}
+
+	if _, err := b.HandleRequest(&logical.Request{
+		Operation: logical.DeleteOperation,
+		Path:      "role/dev",
+		Storage:   storage,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.HandleRequest(req); err == nil {
+		t.Fatalf("expected an error renewing with a deleted role")
+	}
+}
+
+func TestBackend_validationPubKeys(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKeyPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+
+	b, storage := testBackend(t)
+
+	resp := testWrite(t, b, storage, "config", map[string]interface{}{
+		"jwt_validation_pubkeys": []string{string(publicKeyPEM)},
+		"bound_issuer":           "https://ci.example.com",
+	})
+	if resp != nil && resp.IsError() {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	resp = testWrite(t, b, storage, "role/deploy", map[string]interface{}{
+		"bound_subject": "repo:app:ref:main",
+		"user_claim":    "repository",
+		"policies":      "deploy",
+	})
+	if resp != nil && resp.IsError() {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	claims := map[string]interface{}{
+		"iss":        "https://ci.example.com",
+		"sub":        "repo:app:ref:main",
+		"exp":        time.Now().Add(time.Minute).Unix(),
+		"repository": "app",
+	}
+	signingKey := jose.SigningKey{Algorithm: jose.ES256, Key: key}
+
+	resp = testLogin(t, b, storage, "deploy", signToken(t, signingKey, claims))
+	if resp == nil || resp.IsError() || resp.Auth.Alias.Name != "app" {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// The role has no bound audiences, so tokens with an audience are
+	// rejected
+	claims["aud"] = "vault"
+	resp = testLogin(t, b, storage, "deploy", signToken(t, signingKey, claims))
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error, got %#v", resp)
+	}
+	delete(claims, "aud")
+
+	claims["sub"] = "repo:app:ref:feature"
+	resp = testLogin(t, b, storage, "deploy", signToken(t, signingKey, claims))
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error, got %#v", resp)
+	}
+}
+
+func TestBackend_configAndRoleValidation(t *testing.T) {
+	b, storage := testBackend(t)
+
+	for _, data := range []map[string]interface{}{
+		{},
+		{
+			"jwks_url":           "https://example.com/keys",
+			"oidc_discovery_url": "https://example.com",
+		},
+		{
+			"jwt_validation_pubkeys": []string{"not a key"},
+		},
+		{
+			"jwks_url": "example.com/keys",
+		},
+	} {
+		resp := testWrite(t, b, storage, "config", data)
+		if resp == nil || !resp.IsError() {
+			t.Fatalf("expected an error for %#v", data)
+		}
+	}
+
+	for _, data := range []map[string]interface{}{
+		{
+			"policies": "dev",
+		},
+		{
+			"bound_subject": "user",
+			"user_claim":    "",
+		},
+		{
+			"bound_claims": map[string]interface{}{
+				"groups": 1,
+			},
+		},
+		{
+			"bound_subject": "user",
+			"claim_mappings": map[string]interface{}{
+				"name": "role",
+			},
+		},
+		{
+			"bound_subject": "user",
+			"ttl":           "2h",
+			"max_ttl":       "1h",
+		},
+	} {
+		resp := testWrite(t, b, storage, "role/invalid", data)
+		if resp == nil || !resp.IsError() {
+			t.Fatalf("expected an error for %#v", data)
+		}
+	}
+}
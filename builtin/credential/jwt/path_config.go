@@ -0,0 +1,195 @@
+package jwtauth
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+func pathConfig(b *jwtAuthBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config$",
+		Fields: map[string]*framework.FieldSchema{
+			"oidc_discovery_url": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `OIDC discovery URL, without any .well-known component (base path).
+Cannot be used with "jwks_url" or "jwt_validation_pubkeys".`,
+			},
+			"oidc_discovery_ca_pem": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "The CA certificate or chain of certificates, in PEM format, to use to validate connections to the OIDC discovery URL. If not set, system certificates are used.",
+			},
+			"jwks_url": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `JWKS URL to use to authenticate signatures. Cannot be used with
+"oidc_discovery_url" or "jwt_validation_pubkeys".`,
+			},
+			"jwks_ca_pem": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "The CA certificate or chain of certificates, in PEM format, to use to validate connections to the JWKS URL. If not set, system certificates are used.",
+			},
+			"jwt_validation_pubkeys": &framework.FieldSchema{
+				Type: framework.TypeCommaStringSlice,
+				Description: `A list of PEM-encoded public keys to use to authenticate signatures
+locally. Cannot be used with "jwks_url" or "oidc_discovery_url".`,
+			},
+			"bound_issuer": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `The value against which to match the "iss" claim in a JWT. Defaults
+to the issuer of the OIDC discovery document.`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathConfigRead,
+			logical.UpdateOperation: b.pathConfigWrite,
+		},
+
+		HelpSynopsis:    confHelpSyn,
+		HelpDescription: confHelpDesc,
+	}
+}
+
+// config takes a storage object and returns a jwtConfig object
+func (b *jwtAuthBackend) config(s logical.Storage) (*jwtConfig, error) {
+	entry, err := s.Get(configPath)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var result jwtConfig
+	if err := entry.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (b *jwtAuthBackend) pathConfigRead(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	b.l.RLock()
+	defer b.l.RUnlock()
+
+	config, err := b.config(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"oidc_discovery_url":     config.OIDCDiscoveryURL,
+			"oidc_discovery_ca_pem":  config.OIDCDiscoveryCAPEM,
+			"jwks_url":               config.JWKSURL,
+			"jwks_ca_pem":            config.JWKSCAPEM,
+			"jwt_validation_pubkeys": config.JWTValidationPubKeys,
+			"bound_issuer":           config.BoundIssuer,
+		},
+	}, nil
+}
+
+func (b *jwtAuthBackend) pathConfigWrite(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config := &jwtConfig{
+		OIDCDiscoveryURL:     d.Get("oidc_discovery_url").(string),
+		OIDCDiscoveryCAPEM:   d.Get("oidc_discovery_ca_pem").(string),
+		JWKSURL:              d.Get("jwks_url").(string),
+		JWKSCAPEM:            d.Get("jwks_ca_pem").(string),
+		JWTValidationPubKeys: d.Get("jwt_validation_pubkeys").([]string),
+		BoundIssuer:          d.Get("bound_issuer").(string),
+	}
+
+	var sources int
+	for _, set := range []bool{config.OIDCDiscoveryURL != "", config.JWKSURL != "", len(config.JWTValidationPubKeys) > 0} {
+		if set {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return logical.ErrorResponse("exactly one of oidc_discovery_url, jwks_url or jwt_validation_pubkeys must be set"), nil
+	}
+
+	for _, rawURL := range []string{config.OIDCDiscoveryURL, config.JWKSURL} {
+		if rawURL == "" {
+			continue
+		}
+		if u, err := url.Parse(rawURL); err != nil || u.Scheme == "" || u.Host == "" {
+			return logical.ErrorResponse(fmt.Sprintf("invalid URL %q", rawURL)), nil
+		}
+	}
+
+	// Load the keys now so that a misconfiguration is reported when it is
+	// made rather than on the first login
+	if _, err := loadKeys(config); err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("error loading keys: %v", err)), nil
+	}
+
+	b.l.Lock()
+	defer b.l.Unlock()
+
+	entry, err := logical.StorageEntryJSON(configPath, config)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(entry); err != nil {
+		return nil, err
+	}
+
+	b.resetKeys()
+
+	return nil, nil
+}
+
+type jwtConfig struct {
+	OIDCDiscoveryURL     string   `json:"oidc_discovery_url"`
+	OIDCDiscoveryCAPEM   string   `json:"oidc_discovery_ca_pem"`
+	JWKSURL              string   `json:"jwks_url"`
+	JWKSCAPEM            string   `json:"jwks_ca_pem"`
+	JWTValidationPubKeys []string `json:"jwt_validation_pubkeys"`
+	BoundIssuer          string   `json:"bound_issuer"`
+}
+
+// parsePublicKeyPEM is used to parse RSA and ECDSA public keys from PEMs
+func parsePublicKeyPEM(data []byte) (interface{}, error) {
+	block, _ := pem.Decode(data)
+	if block != nil {
+		var rawKey interface{}
+		var err error
+		if rawKey, err = x509.ParsePKIXPublicKey(block.Bytes); err != nil {
+			if cert, err := x509.ParseCertificate(block.Bytes); err == nil {
+				rawKey = cert.PublicKey
+			} else {
+				return nil, err
+			}
+		}
+
+		if rsaPublicKey, ok := rawKey.(*rsa.PublicKey); ok {
+			return rsaPublicKey, nil
+		}
+		if ecPublicKey, ok := rawKey.(*ecdsa.PublicKey); ok {
+			return ecPublicKey, nil
+		}
+	}
+
+	return nil, errors.New("data does not contain any valid RSA or ECDSA public keys")
+}
+
+const confHelpSyn = `Configures how JWTs are validated.`
+const confHelpDesc = `
+The JWT backend validates the signatures of JWTs against the keys of a JWKS
+URL, the keys found through OIDC discovery, or a list of PEM-encoded public
+keys. Exactly one of these must be configured. The "iss" claim of tokens is
+matched against "bound_issuer" or, if not set, the issuer of the OIDC
+discovery document.
+`
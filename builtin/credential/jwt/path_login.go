@@ -0,0 +1,317 @@
+package jwtauth
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+// pathLogin returns the path configurations for login endpoints
+func pathLogin(b *jwtAuthBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "login$",
+		Fields: map[string]*framework.FieldSchema{
+			"role": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: `Name of the role against which the login is being attempted. This field is required`,
+			},
+			"jwt": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: `The signed JWT to authenticate with. This field is required.`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation:         b.pathLogin,
+			logical.AliasLookaheadOperation: b.pathLogin,
+		},
+
+		HelpSynopsis:    pathLoginHelpSyn,
+		HelpDescription: pathLoginHelpDesc,
+	}
+}
+
+// pathLogin is used to authenticate to this backend
+func (b *jwtAuthBackend) pathLogin(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	roleName := data.Get("role").(string)
+	if len(roleName) == 0 {
+		return logical.ErrorResponse("missing role"), nil
+	}
+
+	token := data.Get("jwt").(string)
+	if len(token) == 0 {
+		return logical.ErrorResponse("missing jwt"), nil
+	}
+
+	b.l.RLock()
+	defer b.l.RUnlock()
+
+	role, err := b.role(req.Storage, roleName)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return logical.ErrorResponse(fmt.Sprintf("invalid role name %q", roleName)), nil
+	}
+
+	config, err := b.config(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return logical.ErrorResponse("could not load backend configuration"), nil
+	}
+
+	allClaims, err := b.verifyToken(config, role, token)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	userName, err := stringClaim(allClaims, role.UserClaim)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	alias := &logical.Alias{
+		Name:     userName,
+		Metadata: make(map[string]string),
+	}
+	for claim, key := range role.ClaimMappings {
+		if getClaim(allClaims, claim) == nil {
+			continue
+		}
+		value, err := stringClaim(allClaims, claim)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+		alias.Metadata[key] = value
+	}
+
+	if req.Operation == logical.AliasLookaheadOperation {
+		return &logical.Response{
+			Auth: &logical.Auth{
+				Alias: alias,
+			},
+		}, nil
+	}
+
+	metadata := map[string]string{
+		"role": roleName,
+	}
+	for key, value := range alias.Metadata {
+		metadata[key] = value
+	}
+
+	resp := &logical.Response{
+		Auth: &logical.Auth{
+			NumUses: role.NumUses,
+			Period:  role.Period,
+			Alias:   alias,
+			InternalData: map[string]interface{}{
+				"role": roleName,
+			},
+			Policies:    role.Policies,
+			Metadata:    metadata,
+			DisplayName: userName,
+			LeaseOptions: logical.LeaseOptions{
+				Renewable: true,
+				TTL:       role.TTL,
+			},
+		},
+	}
+
+	// If 'Period' is set, use the value of 'Period' as the TTL.
+	if role.Period > time.Duration(0) {
+		resp.Auth.TTL = role.Period
+	}
+
+	return resp, nil
+}
+
+// verifyToken validates the signature of the token and its claims against
+// the configuration and role, returning its claims
+func (b *jwtAuthBackend) verifyToken(config *jwtConfig, role *jwtRole, token string) (map[string]interface{}, error) {
+	parsed, err := jwt.ParseSigned(token)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing token: %v", err)
+	}
+	if len(parsed.Headers) != 1 {
+		return nil, errors.New("token must have exactly one signature")
+	}
+	kid := parsed.Headers[0].KeyID
+
+	keys, err := b.keys(config, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// The keys are fetched again when the token was signed with a key they
+	// don't contain, in case they have been rotated. Tokens without a key ID
+	// or failing to verify with a known key never cause a fetch.
+	if keys.remote && kid != "" && !keys.hasKeyID(kid) {
+		keys, err = b.keys(config, true)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var claims jwt.Claims
+	var allClaims map[string]interface{}
+	verified := false
+	for _, key := range keys.candidates(kid) {
+		if err := parsed.Claims(key, &claims, &allClaims); err == nil {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, errors.New("failed to verify token signature")
+	}
+
+	issuer := config.BoundIssuer
+	if issuer == "" {
+		issuer = keys.issuer
+	}
+	expected := jwt.Expected{
+		Issuer:  issuer,
+		Subject: role.BoundSubject,
+		Time:    time.Now(),
+	}
+	if err := claims.Validate(expected); err != nil {
+		return nil, fmt.Errorf("error validating claims: %v", err)
+	}
+
+	switch {
+	case len(role.BoundAudiences) > 0:
+		found := false
+		for _, audience := range role.BoundAudiences {
+			if claims.Audience.Contains(audience) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, errors.New("aud claim does not match any bound audience")
+		}
+	case len(claims.Audience) > 0:
+		return nil, errors.New("audience claim found in JWT but no audiences bound to the role")
+	}
+
+	for claim, values := range role.BoundClaims {
+		if !claimMatches(getClaim(allClaims, claim), values) {
+			return nil, fmt.Errorf("claim %q does not match any associated bound claim values", claim)
+		}
+	}
+
+	return allClaims, nil
+}
+
+// getClaim returns the value of a top-level claim, or of a nested claim if
+// the name is a JSON pointer such as "/groups/0"
+func getClaim(allClaims map[string]interface{}, claim string) interface{} {
+	if !strings.HasPrefix(claim, "/") {
+		return allClaims[claim]
+	}
+
+	var value interface{} = allClaims
+	for _, part := range strings.Split(claim[1:], "/") {
+		part = strings.Replace(strings.Replace(part, "~1", "/", -1), "~0", "~", -1)
+
+		switch v := value.(type) {
+		case map[string]interface{}:
+			value = v[part]
+		case []interface{}:
+			i, err := strconv.Atoi(part)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil
+			}
+			value = v[i]
+		default:
+			return nil
+		}
+	}
+	return value
+}
+
+// stringClaim returns the value of a claim holding a string, number or
+// boolean as a string
+func stringClaim(allClaims map[string]interface{}, claim string) (string, error) {
+	switch value := getClaim(allClaims, claim).(type) {
+	case string:
+		if value == "" {
+			break
+		}
+		return value, nil
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(value), nil
+	}
+	return "", fmt.Errorf("claim %q not found in token or is not a string, number or boolean", claim)
+}
+
+// claimMatches returns whether the value of a claim, or any of its items if
+// it is a list, is one of the given values
+func claimMatches(value interface{}, values []string) bool {
+	switch value := value.(type) {
+	case []interface{}:
+		for _, item := range value {
+			if claimMatches(item, values) {
+				return true
+			}
+		}
+		return false
+	case string:
+		return strutil.StrListContains(values, value)
+	case float64:
+		return strutil.StrListContains(values, strconv.FormatFloat(value, 'f', -1, 64))
+	case bool:
+		return strutil.StrListContains(values, strconv.FormatBool(value))
+	default:
+		return false
+	}
+}
+
+func (b *jwtAuthBackend) pathLoginRenew(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	roleName, _ := req.Auth.InternalData["role"].(string)
+	if roleName == "" {
+		return nil, fmt.Errorf("failed to fetch role_name during renewal")
+	}
+
+	b.l.RLock()
+	defer b.l.RUnlock()
+
+	// Ensure that the Role still exists.
+	role, err := b.role(req.Storage, roleName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to validate role %s during renewal: %v", roleName, err)
+	}
+	if role == nil {
+		return nil, fmt.Errorf("role %s does not exist during renewal", roleName)
+	}
+
+	// If 'Period' is set on the Role, the token should never expire.
+	// Replenish the TTL with 'Period's value.
+	if role.Period > time.Duration(0) {
+		// If 'Period' was updated after the token was issued,
+		// token will bear the updated 'Period' value as its TTL.
+		req.Auth.TTL = role.Period
+		return &logical.Response{Auth: req.Auth}, nil
+	}
+
+	return framework.LeaseExtend(role.TTL, role.MaxTTL, b.System())(req, data)
+}
+
+const pathLoginHelpSyn = `Authenticates to Vault using a JWT.`
+const pathLoginHelpDesc = `
+Authenticates JWTs, such as OIDC ID tokens, against a role. The signature of
+the JWT is validated against the configured keys and its claims against the
+bindings of the role.
+`
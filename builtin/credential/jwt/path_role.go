@@ -0,0 +1,372 @@
+package jwtauth
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/helper/policyutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// pathsRole returns the path configurations for the CRUD operations on roles
+func pathsRole(b *jwtAuthBackend) []*framework.Path {
+	return []*framework.Path{
+		&framework.Path{
+			Pattern: "role/?",
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ListOperation: b.pathRoleList,
+			},
+			HelpSynopsis:    strings.TrimSpace(roleHelp["role-list"][0]),
+			HelpDescription: strings.TrimSpace(roleHelp["role-list"][1]),
+		},
+		&framework.Path{
+			Pattern: "role/" + framework.GenericNameRegex("name"),
+			Fields: map[string]*framework.FieldSchema{
+				"name": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "Name of the role.",
+				},
+				"bound_audiences": &framework.FieldSchema{
+					Type: framework.TypeCommaStringSlice,
+					Description: `Comma-separated list of audiences. One of them must be in the "aud"
+claim of a JWT. Tokens with an "aud" claim are rejected if none are set.`,
+				},
+				"bound_subject": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: `The value a JWT must have in its "sub" claim.`,
+				},
+				"bound_claims": &framework.FieldSchema{
+					Type: framework.TypeMap,
+					Description: `Map of claims to the value, or list of values, that a JWT must have.
+Claims are top-level keys, or JSON pointers such as "/groups" for nested ones.
+A claim holding a list matches if any of its items matches.`,
+				},
+				"user_claim": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Default:     "sub",
+					Description: `The claim to use as the name of the entity alias.`,
+				},
+				"claim_mappings": &framework.FieldSchema{
+					Type: framework.TypeMap,
+					Description: `Map of claims to the metadata keys they are copied to on the token
+and the entity alias.`,
+				},
+				"policies": &framework.FieldSchema{
+					Type:        framework.TypeCommaStringSlice,
+					Description: "List of policies on the role.",
+				},
+				"num_uses": &framework.FieldSchema{
+					Type:        framework.TypeInt,
+					Description: `Number of times issued tokens can be used`,
+				},
+				"ttl": &framework.FieldSchema{
+					Type: framework.TypeDurationSecond,
+					Description: `Duration in seconds after which the issued token should expire. Defaults
+to 0, in which case the value will fall back to the system/mount defaults.`,
+				},
+				"max_ttl": &framework.FieldSchema{
+					Type: framework.TypeDurationSecond,
+					Description: `Duration in seconds after which the issued token should not be allowed to
+be renewed. Defaults to 0, in which case the value will fall back to the system/mount defaults.`,
+				},
+				"period": &framework.FieldSchema{
+					Type:    framework.TypeDurationSecond,
+					Default: 0,
+					Description: `If set, indicates that the token generated using this role
+should never expire. The token should be renewed within the
+duration specified by this value. At each renewal, the token's
+TTL will be set to the value of this parameter.`,
+				},
+			},
+			ExistenceCheck: b.pathRoleExistenceCheck,
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.CreateOperation: b.pathRoleCreateUpdate,
+				logical.UpdateOperation: b.pathRoleCreateUpdate,
+				logical.ReadOperation:   b.pathRoleRead,
+				logical.DeleteOperation: b.pathRoleDelete,
+			},
+			HelpSynopsis:    strings.TrimSpace(roleHelp["role"][0]),
+			HelpDescription: strings.TrimSpace(roleHelp["role"][1]),
+		},
+	}
+}
+
+// role takes a storage backend and the name and returns the role's storage
+// entry
+func (b *jwtAuthBackend) role(s logical.Storage, name string) (*jwtRole, error) {
+	raw, err := s.Get(rolePrefix + strings.ToLower(name))
+	if err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return nil, nil
+	}
+
+	role := &jwtRole{}
+	if err := raw.DecodeJSON(role); err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+// pathRoleExistenceCheck returns whether the role with the given name exists or not.
+func (b *jwtAuthBackend) pathRoleExistenceCheck(req *logical.Request, data *framework.FieldData) (bool, error) {
+	b.l.RLock()
+	defer b.l.RUnlock()
+
+	role, err := b.role(req.Storage, data.Get("name").(string))
+	if err != nil {
+		return false, err
+	}
+	return role != nil, nil
+}
+
+// pathRoleList is used to list all the Roles registered with the backend.
+func (b *jwtAuthBackend) pathRoleList(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	b.l.RLock()
+	defer b.l.RUnlock()
+
+	roles, err := req.Storage.List(rolePrefix)
+	if err != nil {
+		return nil, err
+	}
+	return logical.ListResponse(roles), nil
+}
+
+// pathRoleRead grabs a read lock and reads the options set on the role from the storage
+func (b *jwtAuthBackend) pathRoleRead(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	b.l.RLock()
+	defer b.l.RUnlock()
+
+	role, err := b.role(req.Storage, data.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"bound_audiences": role.BoundAudiences,
+			"bound_subject":   role.BoundSubject,
+			"bound_claims":    role.BoundClaims,
+			"user_claim":      role.UserClaim,
+			"claim_mappings":  role.ClaimMappings,
+			"policies":        role.Policies,
+			"num_uses":        role.NumUses,
+			"ttl":             int64(role.TTL.Seconds()),
+			"max_ttl":         int64(role.MaxTTL.Seconds()),
+			"period":          int64(role.Period.Seconds()),
+		},
+	}, nil
+}
+
+// pathRoleDelete removes the role from storage
+func (b *jwtAuthBackend) pathRoleDelete(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	b.l.Lock()
+	defer b.l.Unlock()
+
+	if err := req.Storage.Delete(rolePrefix + strings.ToLower(data.Get("name").(string))); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// pathRoleCreateUpdate registers a new role with the backend or updates the options
+// of an existing role
+func (b *jwtAuthBackend) pathRoleCreateUpdate(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	roleName := data.Get("name").(string)
+
+	b.l.Lock()
+	defer b.l.Unlock()
+
+	// Check if the role already exists
+	role, err := b.role(req.Storage, roleName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a new entry object if this is a CreateOperation
+	if role == nil && req.Operation == logical.CreateOperation {
+		role = &jwtRole{}
+	} else if role == nil {
+		return nil, fmt.Errorf("role entry not found during update operation")
+	}
+
+	if policiesRaw, ok := data.GetOk("policies"); ok {
+		role.Policies = policyutil.ParsePolicies(policiesRaw)
+	}
+
+	if periodRaw, ok := data.GetOk("period"); ok {
+		role.Period = time.Second * time.Duration(periodRaw.(int))
+	}
+	if role.Period > b.System().MaxLeaseTTL() {
+		return logical.ErrorResponse(fmt.Sprintf("'period' of '%q' is greater than the backend's maximum lease TTL of '%q'", role.Period.String(), b.System().MaxLeaseTTL().String())), nil
+	}
+
+	if tokenNumUsesRaw, ok := data.GetOk("num_uses"); ok {
+		role.NumUses = tokenNumUsesRaw.(int)
+	}
+	if role.NumUses < 0 {
+		return logical.ErrorResponse("num_uses cannot be negative"), nil
+	}
+
+	if tokenTTLRaw, ok := data.GetOk("ttl"); ok {
+		role.TTL = time.Second * time.Duration(tokenTTLRaw.(int))
+	}
+	if tokenMaxTTLRaw, ok := data.GetOk("max_ttl"); ok {
+		role.MaxTTL = time.Second * time.Duration(tokenMaxTTLRaw.(int))
+	}
+
+	// Check that the TTL value provided is less than the MaxTTL.
+	// Sanitizing the TTL and MaxTTL is not required now and can be performed
+	// at credential issue time.
+	if role.MaxTTL > time.Duration(0) && role.TTL > role.MaxTTL {
+		return logical.ErrorResponse("ttl should not be greater than max_ttl"), nil
+	}
+
+	var resp *logical.Response
+	if role.MaxTTL > b.System().MaxLeaseTTL() {
+		resp = &logical.Response{}
+		resp.AddWarning("max_ttl is greater than the system or backend mount's maximum TTL value; issued tokens' max TTL value will be truncated")
+	}
+
+	if boundAudiences, ok := data.GetOk("bound_audiences"); ok {
+		role.BoundAudiences = boundAudiences.([]string)
+	}
+
+	if boundSubject, ok := data.GetOk("bound_subject"); ok {
+		role.BoundSubject = boundSubject.(string)
+	}
+
+	if boundClaimsRaw, ok := data.GetOk("bound_claims"); ok {
+		boundClaims := make(map[string][]string)
+		for claim, valuesRaw := range boundClaimsRaw.(map[string]interface{}) {
+			values, err := stringValues(valuesRaw)
+			if err != nil {
+				return logical.ErrorResponse(fmt.Sprintf("bound claim %q: %v", claim, err)), nil
+			}
+			boundClaims[claim] = values
+		}
+		role.BoundClaims = boundClaims
+	}
+
+	if userClaim, ok := data.GetOk("user_claim"); ok {
+		role.UserClaim = userClaim.(string)
+	} else if req.Operation == logical.CreateOperation {
+		role.UserClaim = data.Get("user_claim").(string)
+	}
+	if role.UserClaim == "" {
+		return logical.ErrorResponse("a user claim must be given"), nil
+	}
+
+	if claimMappingsRaw, ok := data.GetOk("claim_mappings"); ok {
+		claimMappings := make(map[string]string)
+		targets := make(map[string]bool)
+		for claim, targetRaw := range claimMappingsRaw.(map[string]interface{}) {
+			target, ok := targetRaw.(string)
+			if !ok || target == "" {
+				return logical.ErrorResponse(fmt.Sprintf("claim %q must be mapped to a metadata key", claim)), nil
+			}
+			if target == "role" || targets[target] {
+				return logical.ErrorResponse(fmt.Sprintf("metadata key %q is reserved or used by more than one claim", target)), nil
+			}
+			targets[target] = true
+			claimMappings[claim] = target
+		}
+		role.ClaimMappings = claimMappings
+	}
+
+	// A role without bindings would accept any token the issuer signs,
+	// including those issued to other applications
+	if len(role.BoundAudiences) == 0 && role.BoundSubject == "" && len(role.BoundClaims) == 0 {
+		return logical.ErrorResponse("must have at least one bound constraint when creating/updating a role"), nil
+	}
+
+	// Store the entry.
+	entry, err := logical.StorageEntryJSON(rolePrefix+strings.ToLower(roleName), role)
+	if err != nil {
+		return nil, err
+	}
+	if err = req.Storage.Put(entry); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// stringValues returns the string or list of strings of a bound claim
+func stringValues(raw interface{}) ([]string, error) {
+	switch raw := raw.(type) {
+	case string:
+		return []string{raw}, nil
+	case []interface{}:
+		values := make([]string, 0, len(raw))
+		for _, valueRaw := range raw {
+			value, ok := valueRaw.(string)
+			if !ok {
+				return nil, fmt.Errorf("values must be strings")
+			}
+			values = append(values, value)
+		}
+		if len(values) == 0 {
+			return nil, fmt.Errorf("at least one value must be given")
+		}
+		return values, nil
+	default:
+		return nil, fmt.Errorf("value must be a string or a list of strings")
+	}
+}
+
+// jwtRole stores all the options that are set on a role
+type jwtRole struct {
+	// Policies that are to be required by the token to access this role
+	Policies []string `json:"policies"`
+
+	// TokenNumUses defines the number of allowed uses of the token issued
+	NumUses int `json:"num_uses"`
+
+	// Duration before which an issued token must be renewed
+	TTL time.Duration `json:"ttl"`
+
+	// Duration after which an issued token should not be allowed to be renewed
+	MaxTTL time.Duration `json:"max_ttl"`
+
+	// Period, if set, indicates that the token generated using this role
+	// should never expire. The token should be renewed within the duration
+	// specified by this value.
+	Period time.Duration `json:"period"`
+
+	// BoundAudiences are the audiences of which one must be in the "aud"
+	// claim
+	BoundAudiences []string `json:"bound_audiences"`
+
+	// BoundSubject is the value the "sub" claim must have
+	BoundSubject string `json:"bound_subject"`
+
+	// BoundClaims maps claims to the values they may have
+	BoundClaims map[string][]string `json:"bound_claims"`
+
+	// UserClaim is the claim used as the entity alias name
+	UserClaim string `json:"user_claim"`
+
+	// ClaimMappings maps claims to the metadata keys they are copied to
+	ClaimMappings map[string]string `json:"claim_mappings"`
+}
+
+var roleHelp = map[string][2]string{
+	"role-list": {
+		"Lists all the roles registered with the backend.",
+		"The list will contain the names of the roles.",
+	},
+	"role": {
+		"Register a role with the backend.",
+		`A role is required to authenticate with this backend. The role binds
+		the audiences, subject and claims a JWT must have with token policies and
+		settings, and maps claims of the JWT to the name and metadata of the
+		entity alias.`,
+	},
+}
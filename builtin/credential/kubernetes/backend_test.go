@@ -0,0 +1,234 @@
+package kubeauth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SermoDigital/jose/crypto"
+	"github.com/SermoDigital/jose/jws"
+	"github.com/hashicorp/vault/logical"
+)
+
+// testTokenReview returns a fixed result for every token review
+type testTokenReview struct {
+	result *tokenReviewResult
+}
+
+func (m *testTokenReview) Review(string) (*tokenReviewResult, error) {
+	return m.result, nil
+}
+
+func testBackend(t *testing.T, result *tokenReviewResult) (*kubeAuthBackend, logical.Storage, *ecdsa.PrivateKey) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+	config.System = &logical.StaticSystemView{
+		DefaultLeaseTTLVal: time.Hour,
+		MaxLeaseTTLVal:     24 * time.Hour,
+	}
+
+	b := Backend()
+	if err := b.Setup(config); err != nil {
+		t.Fatal(err)
+	}
+	b.reviewFactory = func(*kubeConfig) tokenReviewer {
+		return &testTokenReview{result: result}
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+
+	testWrite(t, b, config.StorageView, "config", map[string]interface{}{
+		"kubernetes_host": "https://kubernetes.example.com",
+		"pem_keys":        string(pemKey),
+		"issuer":          "https://kubernetes.default.svc",
+	})
+	testWrite(t, b, config.StorageView, "role/test", map[string]interface{}{
+		"bound_service_account_names":      "vault-auth",
+		"bound_service_account_namespaces": "default",
+		"policies":                         "dev",
+	})
+
+	return b, config.StorageView, key
+}
+
+func testWrite(t *testing.T, b *kubeAuthBackend, storage logical.Storage, path string, data map[string]interface{}) {
+	var operation logical.Operation = logical.UpdateOperation
+	if strings.HasPrefix(path, rolePrefix) {
+		operation = logical.CreateOperation
+	}
+
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: operation,
+		Path:      path,
+		Storage:   storage,
+		Data:      data,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+	}
+}
+
+func testLogin(b *kubeAuthBackend, storage logical.Storage, token string) (*logical.Response, error) {
+	return b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "login",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"role": "test",
+			"jwt":  token,
+		},
+	})
+}
+
+func signToken(t *testing.T, key *ecdsa.PrivateKey, claims map[string]interface{}) string {
+	token, err := jws.NewJWT(jws.Claims(claims), crypto.SigningMethodES256).Serialize(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(token)
+}
+
+func legacyTokenClaims() map[string]interface{} {
+	return map[string]interface{}{
+		"iss":                                    "kubernetes/serviceaccount",
+		"kubernetes.io/serviceaccount/namespace": "default",
+		"kubernetes.io/serviceaccount/secret.name":          "vault-auth-token-t5pcn",
+		"kubernetes.io/serviceaccount/service-account.name": "vault-auth",
+		"kubernetes.io/serviceaccount/service-account.uid":  "d77f89bc-9055-11e7-a068-0800276d99bf",
+		"sub": "system:serviceaccount:default:vault-auth",
+	}
+}
+
+func projectedTokenClaims(issuer string) map[string]interface{} {
+	return map[string]interface{}{
+		"iss": issuer,
+		"aud": []string{"vault"},
+		"exp": time.Now().Add(time.Hour).Unix(),
+		"kubernetes.io": map[string]interface{}{
+			"namespace": "default",
+			"pod": map[string]interface{}{
+				"name": "app-7d9c5",
+				"uid":  "5a2b6d7e-9055-11e7-a068-0800276d99bf",
+			},
+			"serviceaccount": map[string]interface{}{
+				"name": "vault-auth",
+				"uid":  "d77f89bc-9055-11e7-a068-0800276d99bf",
+			},
+		},
+		"sub": "system:serviceaccount:default:vault-auth",
+	}
+}
+
+func TestLogin_legacyToken(t *testing.T) {
+	b, storage, key := testBackend(t, &tokenReviewResult{
+		Name:      "vault-auth",
+		Namespace: "default",
+		UID:       "d77f89bc-9055-11e7-a068-0800276d99bf",
+	})
+
+	resp, err := testLogin(b, storage, signToken(t, key, legacyTokenClaims()))
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+	}
+
+	expected := map[string]string{
+		"service_account_uid":         "d77f89bc-9055-11e7-a068-0800276d99bf",
+		"service_account_name":        "vault-auth",
+		"service_account_namespace":   "default",
+		"service_account_secret_name": "vault-auth-token-t5pcn",
+	}
+	if !reflect.DeepEqual(resp.Auth.Alias.Metadata, expected) {
+		t.Fatalf("bad: alias metadata: %#v", resp.Auth.Alias.Metadata)
+	}
+	if resp.Auth.Alias.Name != "d77f89bc-9055-11e7-a068-0800276d99bf" {
+		t.Fatalf("bad: alias name: %q", resp.Auth.Alias.Name)
+	}
+
+	// Legacy tokens must be issued by the service account token controller
+	claims := legacyTokenClaims()
+	claims["iss"] = "https://kubernetes.default.svc"
+	if _, err := testLogin(b, storage, signToken(t, key, claims)); err == nil {
+		t.Fatal("expected an error for a legacy token with another issuer")
+	}
+}
+
+func TestLogin_projectedToken(t *testing.T) {
+	result := &tokenReviewResult{
+		Name:      "vault-auth",
+		Namespace: "default",
+		UID:       "d77f89bc-9055-11e7-a068-0800276d99bf",
+		PodName:   "app-7d9c5",
+		PodUID:    "5a2b6d7e-9055-11e7-a068-0800276d99bf",
+	}
+	b, storage, key := testBackend(t, result)
+
+	resp, err := testLogin(b, storage, signToken(t, key, projectedTokenClaims("https://kubernetes.default.svc")))
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+	}
+
+	expected := map[string]string{
+		"service_account_uid":       "d77f89bc-9055-11e7-a068-0800276d99bf",
+		"service_account_name":      "vault-auth",
+		"service_account_namespace": "default",
+		"pod_name":                  "app-7d9c5",
+		"pod_uid":                   "5a2b6d7e-9055-11e7-a068-0800276d99bf",
+	}
+	if !reflect.DeepEqual(resp.Auth.Alias.Metadata, expected) {
+		t.Fatalf("bad: alias metadata: %#v", resp.Auth.Alias.Metadata)
+	}
+	if resp.Auth.Metadata["role"] != "test" || resp.Auth.Metadata["pod_name"] != "app-7d9c5" {
+		t.Fatalf("bad: metadata: %#v", resp.Auth.Metadata)
+	}
+
+	// The issuer of projected tokens must match the configured one
+	if _, err := testLogin(b, storage, signToken(t, key, projectedTokenClaims("https://other.example.com"))); err == nil {
+		t.Fatal("expected an error for a projected token with another issuer")
+	}
+
+	// The pod returned by the token review must match the token
+	result.PodUID = "0c9f4a1e-9055-11e7-a068-0800276d99bf"
+	if _, err := testLogin(b, storage, signToken(t, key, projectedTokenClaims("https://kubernetes.default.svc"))); err == nil {
+		t.Fatal("expected an error for a mismatched pod")
+	}
+
+	// Older API servers don't return the pod a token is bound to
+	result.PodName, result.PodUID = "", ""
+	resp, err = testLogin(b, storage, signToken(t, key, projectedTokenClaims("https://kubernetes.default.svc")))
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+	}
+}
+
+func TestAliasLookahead_projectedToken(t *testing.T) {
+	b, storage, key := testBackend(t, nil)
+
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: logical.AliasLookaheadOperation,
+		Path:      "login",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"jwt": signToken(t, key, projectedTokenClaims("https://kubernetes.default.svc")),
+		},
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+	}
+	if resp.Auth.Alias.Name != "d77f89bc-9055-11e7-a068-0800276d99bf" {
+		t.Fatalf("bad: alias name: %q", resp.Auth.Alias.Name)
+	}
+}
@@ -41,6 +41,12 @@ used to verify the signatures of kubernetes service account
 JWTs. If a certificate is given, its public key will be
 extracted. Not every installation of Kuberentes exposes these keys.`,
 			},
+			"issuer": {
+				Type: framework.TypeString,
+				Description: `Optional issuer that the "iss" claim of projected
+service account tokens must match. Legacy service account tokens are always
+issued by "kubernetes/serviceaccount".`,
+			},
 		},
 		Callbacks: map[logical.Operation]framework.OperationFunc{
 			logical.UpdateOperation: b.pathConfigWrite(),
@@ -68,6 +74,7 @@ func (b *kubeAuthBackend) pathConfigRead() framework.OperationFunc {
 					"kubernetes_ca_cert": config.CACert,
 					"token_reviewer_jwt": config.TokenReviewerJWT,
 					"pem_keys":           config.PEMKeys,
+					"issuer":             config.Issuer,
 				},
 			}
 
@@ -105,6 +112,7 @@ func (b *kubeAuthBackend) pathConfigWrite() framework.OperationFunc {
 			Host:             host,
 			CACert:           caCert,
 			TokenReviewerJWT: tokenReviewer,
+			Issuer:           data.Get("issuer").(string),
 		}
 
 		var err error
@@ -141,6 +149,8 @@ type kubeConfig struct {
 	CACert string `json:"ca_cert"`
 	// TokenReviewJWT is the bearer to use during the TokenReview API call
 	TokenReviewerJWT string `json:"token_reviewer_jwt"`
+	// Issuer is the expected issuer of projected service account tokens
+	Issuer string `json:"issuer"`
 }
 
 // PasrsePublicKeyPEM is used to parse RSA and ECDSA public keys from PEMs
@@ -23,6 +23,10 @@ var (
 
 	uidJWTClaimKey string = "kubernetes.io/serviceaccount/service-account.uid"
 
+	// projectedJWTClaimKey holds the claims of projected service account
+	// tokens, which are bound to a pod and have a configurable issuer.
+	projectedJWTClaimKey string = "kubernetes.io"
+
 	// errMismatchedSigningMethod is used if the certificate doesn't match the
 	// JWT's expected signing method.
 	errMismatchedSigningMethod = errors.New("invalid signing method")
@@ -96,24 +100,28 @@ func (b *kubeAuthBackend) pathLogin() framework.OperationFunc {
 			return nil, err
 		}
 
+		aliasMetadata := serviceAccount.metadata()
+		metadata := map[string]string{
+			"service_account_secret_name": serviceAccount.SecretName,
+			"role":                        roleName,
+		}
+		for k, v := range aliasMetadata {
+			metadata[k] = v
+		}
+
 		resp := &logical.Response{
 			Auth: &logical.Auth{
 				NumUses: role.NumUses,
 				Period:  role.Period,
 				Alias: &logical.Alias{
-					Name: serviceAccount.UID,
+					Name:     serviceAccount.UID,
+					Metadata: aliasMetadata,
 				},
 				InternalData: map[string]interface{}{
 					"role": roleName,
 				},
-				Policies: role.Policies,
-				Metadata: map[string]string{
-					"service_account_uid":         serviceAccount.UID,
-					"service_account_name":        serviceAccount.Name,
-					"service_account_namespace":   serviceAccount.Namespace,
-					"service_account_secret_name": serviceAccount.SecretName,
-					"role": roleName,
-				},
+				Policies:    role.Policies,
+				Metadata:    metadata,
 				DisplayName: serviceAccount.Name,
 				LeaseOptions: logical.LeaseOptions{
 					Renewable: true,
@@ -149,15 +157,18 @@ func (b *kubeAuthBackend) aliasLookahead() framework.OperationFunc {
 			return nil, err
 		}
 
-		saUID, ok := parsedJWT.Claims().Get(uidJWTClaimKey).(string)
-		if !ok || saUID == "" {
+		sa := &serviceAccount{}
+		if err := sa.parseClaims(parsedJWT.Claims()); err != nil {
+			return nil, err
+		}
+		if sa.UID == "" {
 			return nil, errors.New("could not parse UID from claims")
 		}
 
 		return &logical.Response{
 			Auth: &logical.Auth{
 				Alias: &logical.Alias{
-					Name: saUID,
+					Name: sa.UID,
 				},
 			},
 		}, nil
@@ -174,13 +185,20 @@ func (b *kubeAuthBackend) parseAndValidateJWT(jwtStr string, role *roleStorageEn
 
 	sa := &serviceAccount{}
 	validator := &jwt.Validator{
-		Expected: jwt.Claims{
-			"iss": expectedJWTIssuer,
-		},
 		Fn: func(c jwt.Claims) error {
+			// Legacy tokens are always issued by the service account token
+			// controller, while the issuer of projected tokens is configured
+			// on the API server.
+			if c.Get(projectedJWTClaimKey) == nil {
+				if c.Get("iss") != expectedJWTIssuer {
+					return jwt.ErrInvalidISSClaim
+				}
+			} else if config.Issuer != "" && c.Get("iss") != config.Issuer {
+				return jwt.ErrInvalidISSClaim
+			}
+
 			// Decode claims into a service account object
-			err := mapstructure.Decode(c, sa)
-			if err != nil {
+			if err := sa.parseClaims(c); err != nil {
 				return err
 			}
 
@@ -283,6 +301,62 @@ type serviceAccount struct {
 	UID        string `mapstructure:"kubernetes.io/serviceaccount/service-account.uid"`
 	SecretName string `mapstructure:"kubernetes.io/serviceaccount/secret.name"`
 	Namespace  string `mapstructure:"kubernetes.io/serviceaccount/namespace"`
+
+	// PodName and PodUID are only set for projected tokens bound to a pod
+	PodName string
+	PodUID  string
+}
+
+// projectedClaims holds the claims nested under the "kubernetes.io" claim of
+// projected service account tokens.
+type projectedClaims struct {
+	Namespace      string `mapstructure:"namespace"`
+	ServiceAccount struct {
+		Name string `mapstructure:"name"`
+		UID  string `mapstructure:"uid"`
+	} `mapstructure:"serviceaccount"`
+	Pod struct {
+		Name string `mapstructure:"name"`
+		UID  string `mapstructure:"uid"`
+	} `mapstructure:"pod"`
+}
+
+// parseClaims decodes the claims of either a legacy or a projected service
+// account token into the service account.
+func (s *serviceAccount) parseClaims(c jwt.Claims) error {
+	raw := c.Get(projectedJWTClaimKey)
+	if raw == nil {
+		return mapstructure.Decode(c, s)
+	}
+
+	var claims projectedClaims
+	if err := mapstructure.Decode(raw, &claims); err != nil {
+		return err
+	}
+
+	s.Name = claims.ServiceAccount.Name
+	s.UID = claims.ServiceAccount.UID
+	s.Namespace = claims.Namespace
+	s.PodName = claims.Pod.Name
+	s.PodUID = claims.Pod.UID
+	return nil
+}
+
+// metadata returns the metadata of the entity alias of the service account.
+func (s *serviceAccount) metadata() map[string]string {
+	metadata := map[string]string{
+		"service_account_uid":       s.UID,
+		"service_account_name":      s.Name,
+		"service_account_namespace": s.Namespace,
+	}
+	if s.SecretName != "" {
+		metadata["service_account_secret_name"] = s.SecretName
+	}
+	if s.PodName != "" {
+		metadata["pod_name"] = s.PodName
+		metadata["pod_uid"] = s.PodUID
+	}
+	return metadata
 }
 
 // lookup calls the TokenReview API in kubernetes to verify the token and secret
@@ -305,6 +379,11 @@ func (s *serviceAccount) lookup(jwtStr string, tr tokenReviewer) error {
 		return errors.New("JWT namepaces did not match")
 	}
 
+	// Older API servers do not return the pod a token is bound to
+	if r.PodName != "" && (s.PodName != r.PodName || s.PodUID != r.PodUID) {
+		return errors.New("JWT pods did not match")
+	}
+
 	return nil
 }
 
@@ -24,8 +24,17 @@ type tokenReviewResult struct {
 	Name      string
 	Namespace string
 	UID       string
+	PodName   string
+	PodUID    string
 }
 
+const (
+	// podNameExtraKey and podUIDExtraKey hold the pod a projected service
+	// account token is bound to in the extra info of a token review
+	podNameExtraKey string = "authentication.kubernetes.io/pod-name"
+	podUIDExtraKey  string = "authentication.kubernetes.io/pod-uid"
+)
+
 // This exists so we can use a mock TokenReview when running tests
 type tokenReviewer interface {
 	Review(string) (*tokenReviewResult, error)
@@ -123,11 +132,19 @@ func (t *tokenReviewAPI) Review(jwt string) (*tokenReviewResult, error) {
 		return nil, errors.New("lookup failed: username returned is not a service account")
 	}
 
-	return &tokenReviewResult{
+	result := &tokenReviewResult{
 		Name:      parts[3],
 		Namespace: parts[2],
 		UID:       string(r.Status.User.UID),
-	}, nil
+	}
+	if podName := r.Status.User.Extra[podNameExtraKey]; len(podName) > 0 {
+		result.PodName = podName[0]
+	}
+	if podUID := r.Status.User.Extra[podUIDExtraKey]; len(podUID) > 0 {
+		result.PodUID = podUID[0]
+	}
+
+	return result, nil
 }
 
 // parseResponse takes the API response and either returns the appropriate error
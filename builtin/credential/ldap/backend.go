@@ -3,6 +3,7 @@ package ldap
 import (
 	"bytes"
 	"fmt"
+	"sync"
 	"text/template"
 
 	"github.com/go-ldap/ldap"
@@ -31,6 +32,11 @@ func Backend() *backend {
 			Unauthenticated: []string{
 				"login/*",
 			},
+
+			ExportableStorage: []string{
+				"group/",
+				"user/",
+			},
 		},
 
 		Paths: append([]*framework.Path{
@@ -43,8 +49,11 @@ func Backend() *backend {
 			mfa.MFAPaths(b.Backend, pathLogin(&b))...,
 		),
 
-		AuthRenew:   b.pathLoginRenew,
-		BackendType: logical.TypeCredential,
+		AuthRenew:    b.pathLoginRenew,
+		BackendType:  logical.TypeCredential,
+		PeriodicFunc: b.periodicFunc,
+		Invalidate:   b.invalidate,
+		Clean:        b.resetConnPool,
 	}
 
 	return &b
@@ -52,6 +61,54 @@ func Backend() *backend {
 
 type backend struct {
 	*framework.Backend
+
+	// pool holds the connections to the LDAP servers; it is created on the
+	// first login and reset when the configuration changes
+	pool     *connPool
+	poolLock sync.Mutex
+}
+
+// connPool returns the connection pool, creating it from the configuration
+// if needed
+func (b *backend) connPool(cfg *ConfigEntry) *connPool {
+	b.poolLock.Lock()
+	defer b.poolLock.Unlock()
+
+	if b.pool == nil {
+		b.pool = newConnPool(cfg, b.Logger())
+	}
+	return b.pool
+}
+
+// resetConnPool closes the connection pool, so that the next login creates
+// one from the current configuration
+func (b *backend) resetConnPool() {
+	b.poolLock.Lock()
+	defer b.poolLock.Unlock()
+
+	if b.pool != nil {
+		b.pool.close()
+		b.pool = nil
+	}
+}
+
+func (b *backend) invalidate(key string) {
+	switch key {
+	case "config":
+		b.resetConnPool()
+	}
+}
+
+// periodicFunc health checks the servers of the connection pool
+func (b *backend) periodicFunc(req *logical.Request) error {
+	b.poolLock.Lock()
+	pool := b.pool
+	b.poolLock.Unlock()
+
+	if pool != nil {
+		pool.healthCheck()
+	}
+	return nil
 }
 
 func EscapeLDAPValue(input string) string {
@@ -88,30 +145,27 @@ func EscapeLDAPValue(input string) string {
 	return input
 }
 
-func (b *backend) Login(req *logical.Request, username string, password string) ([]string, *logical.Response, error) {
+func (b *backend) Login(req *logical.Request, username string, password string) ([]string, []string, *logical.Response, error) {
 
 	cfg, err := b.Config(req)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 	if cfg == nil {
-		return nil, logical.ErrorResponse("ldap backend not configured"), nil
+		return nil, nil, logical.ErrorResponse("ldap backend not configured"), nil
 	}
 
-	c, err := cfg.DialLDAP()
+	c, err := b.connPool(cfg).get()
 	if err != nil {
-		return nil, logical.ErrorResponse(err.Error()), nil
-	}
-	if c == nil {
-		return nil, logical.ErrorResponse("invalid connection returned from LDAP dial"), nil
+		return nil, nil, logical.ErrorResponse(err.Error()), nil
 	}
 
-	// Clean connection
-	defer c.Close()
+	// Return the connection to the pool
+	defer c.Release()
 
 	userBindDN, err := b.getUserBindDN(cfg, c, username)
 	if err != nil {
-		return nil, logical.ErrorResponse(err.Error()), nil
+		return nil, nil, logical.ErrorResponse(err.Error()), nil
 	}
 
 	if b.Logger().IsDebug() {
@@ -119,7 +173,7 @@ func (b *backend) Login(req *logical.Request, username string, password string)
 	}
 
 	if cfg.DenyNullBind && len(password) == 0 {
-		return nil, logical.ErrorResponse("password cannot be of zero length when passwordless binds are being denied"), nil
+		return nil, nil, logical.ErrorResponse("password cannot be of zero length when passwordless binds are being denied"), nil
 	}
 
 	// Try to bind as the login user. This is where the actual authentication takes place.
@@ -129,14 +183,14 @@ func (b *backend) Login(req *logical.Request, username string, password string)
 		err = c.UnauthenticatedBind(userBindDN)
 	}
 	if err != nil {
-		return nil, logical.ErrorResponse(fmt.Sprintf("LDAP bind failed: %v", err)), nil
+		return nil, nil, logical.ErrorResponse(fmt.Sprintf("LDAP bind failed: %v", err)), nil
 	}
 
 	// We re-bind to the BindDN if it's defined because we assume
 	// the BindDN should be the one to search, not the user logging in.
 	if cfg.BindDN != "" && cfg.BindPassword != "" {
 		if err := c.Bind(cfg.BindDN, cfg.BindPassword); err != nil {
-			return nil, logical.ErrorResponse(fmt.Sprintf("Encountered an error while attempting to re-bind with the BindDN User: %s", err.Error())), nil
+			return nil, nil, logical.ErrorResponse(fmt.Sprintf("Encountered an error while attempting to re-bind with the BindDN User: %s", err.Error())), nil
 		}
 		if b.Logger().IsDebug() {
 			b.Logger().Debug("auth/ldap: Re-Bound to original BindDN")
@@ -145,12 +199,12 @@ func (b *backend) Login(req *logical.Request, username string, password string)
 
 	userDN, err := b.getUserDN(cfg, c, userBindDN)
 	if err != nil {
-		return nil, logical.ErrorResponse(err.Error()), nil
+		return nil, nil, logical.ErrorResponse(err.Error()), nil
 	}
 
 	ldapGroups, err := b.getLdapGroups(cfg, c, userDN, username)
 	if err != nil {
-		return nil, logical.ErrorResponse(err.Error()), nil
+		return nil, nil, logical.ErrorResponse(err.Error()), nil
 	}
 	if b.Logger().IsDebug() {
 		b.Logger().Debug("auth/ldap: Groups fetched from server", "num_server_groups", len(ldapGroups), "server_groups", ldapGroups)
@@ -177,6 +231,8 @@ func (b *backend) Login(req *logical.Request, username string, password string)
 	}
 	// Merge local and LDAP groups
 	allGroups = append(allGroups, ldapGroups...)
+	// Local groups may also be returned by the server
+	allGroups = strutil.RemoveDuplicates(allGroups, false)
 
 	// Retrieve policies
 	var policies []string
@@ -199,10 +255,10 @@ func (b *backend) Login(req *logical.Request, username string, password string)
 		}
 
 		ldapResponse.Data["error"] = errStr
-		return nil, ldapResponse, nil
+		return nil, nil, ldapResponse, nil
 	}
 
-	return policies, ldapResponse, nil
+	return policies, allGroups, ldapResponse, nil
 }
 
 /*
@@ -239,7 +295,7 @@ func (b *backend) getCN(dn string) string {
  * 2. If upndomain is set, the user dn is constructed as 'username@upndomain'. See https://msdn.microsoft.com/en-us/library/cc223499.aspx
  *
  */
-func (b *backend) getUserBindDN(cfg *ConfigEntry, c *ldap.Conn, username string) (string, error) {
+func (b *backend) getUserBindDN(cfg *ConfigEntry, c ldapClient, username string) (string, error) {
 	bindDN := ""
 	if cfg.DiscoverDN || (cfg.BindDN != "" && cfg.BindPassword != "") {
 		var err error
@@ -282,7 +338,7 @@ func (b *backend) getUserBindDN(cfg *ConfigEntry, c *ldap.Conn, username string)
 /*
  * Returns the DN of the object representing the authenticated user.
  */
-func (b *backend) getUserDN(cfg *ConfigEntry, c *ldap.Conn, bindDN string) (string, error) {
+func (b *backend) getUserDN(cfg *ConfigEntry, c ldapClient, bindDN string) (string, error) {
 	userDN := ""
 	if cfg.UPNDomain != "" {
 		// Find the distinguished name for the user if userPrincipalName used for login
@@ -326,7 +382,7 @@ func (b *backend) getUserDN(cfg *ConfigEntry, c *ldap.Conn, bindDN string) (stri
  * NOTE - If cfg.GroupFilter is empty, no query is performed and an empty result slice is returned.
  *
  */
-func (b *backend) getLdapGroups(cfg *ConfigEntry, c *ldap.Conn, userDN string, username string) ([]string, error) {
+func (b *backend) getLdapGroups(cfg *ConfigEntry, c ldapClient, userDN string, username string) ([]string, error) {
 	// retrieve the groups in a string/bool map as a structure to avoid duplicates inside
 	ldapMap := make(map[string]bool)
 
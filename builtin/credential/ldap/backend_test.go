@@ -2,6 +2,7 @@ package ldap
 
 import (
 	"fmt"
+	"net"
 	"reflect"
 	"sort"
 	"testing"
@@ -263,6 +264,10 @@ func TestBackend_configDefaultsAfterUpdate(t *testing.T) {
 						t.Errorf("Default mismatch: deny_null_bind. Expected: '%s', received :'%s'", defaultDenyNullBind, cfg["deny_null_bind"])
 					}
 
+					if cfg["max_idle_connections"] != 5 || cfg["connection_timeout"] != 30 {
+						t.Errorf("Default mismatch: connection pool settings: %#v", cfg)
+					}
+
 					return nil
 				},
 			},
@@ -539,3 +544,125 @@ func testAccStepUserList(t *testing.T, users []string) logicaltest.TestStep {
 		},
 	}
 }
+
+// testListener accepts TCP connections without ever responding, which is
+// enough to open plain LDAP connections that are not used
+func testListener(t *testing.T, addr string) net.Listener {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+	return ln
+}
+
+func TestBackend_connPoolFailover(t *testing.T) {
+	// Find an address nothing listens on
+	down := testListener(t, "127.0.0.1:0")
+	downAddr := down.Addr().String()
+	down.Close()
+
+	up := testListener(t, "127.0.0.1:0")
+	defer up.Close()
+
+	cfg := &ConfigEntry{
+		Url:                 fmt.Sprintf("ldap://%s,ldap://%s", downAddr, up.Addr()),
+		MaxIdleConnections:  1,
+		IdleTimeout:         300,
+		ConnectionTimeout:   1,
+		ServerRetryInterval: 300,
+	}
+	b, _ := createBackendWithStorage(t)
+	pool := newConnPool(cfg, b.Logger())
+	defer pool.close()
+
+	// The first server is down, so the connection is made to the second one
+	pc, err := pool.get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pc.server != pool.servers[1] || !pool.servers[0].down {
+		t.Fatalf("expected failover to the second server")
+	}
+
+	// The connection is reused once released
+	pc.Release()
+	reused, err := pool.get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reused != pc || pool.open != 1 {
+		t.Fatalf("expected the idle connection to be reused")
+	}
+
+	// Broken connections are closed rather than reused
+	reused.broken = true
+	reused.Release()
+	if len(pool.idle) != 0 || pool.open != 0 {
+		t.Fatalf("expected the broken connection to be closed")
+	}
+
+	// The first server is used again once it passes a health check
+	ln, err := net.Listen("tcp", downAddr)
+	if err != nil {
+		t.Skipf("could not listen on %s again: %v", downAddr, err)
+	}
+	ln.Close()
+	recovered := testListener(t, downAddr)
+	defer recovered.Close()
+
+	pool.healthCheck()
+	if pool.servers[0].down {
+		t.Fatalf("expected the first server to be back up")
+	}
+
+	pc, err = pool.get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Release()
+	if pc.server != pool.servers[0] {
+		t.Fatalf("expected a connection to the first server")
+	}
+}
+
+func TestBackend_connPoolMaxConnections(t *testing.T) {
+	ln := testListener(t, "127.0.0.1:0")
+	defer ln.Close()
+
+	cfg := &ConfigEntry{
+		Url:                fmt.Sprintf("ldap://%s", ln.Addr()),
+		MaxConnections:     1,
+		MaxIdleConnections: 1,
+		IdleTimeout:        300,
+		ConnectionTimeout:  1,
+	}
+	b, _ := createBackendWithStorage(t)
+	pool := newConnPool(cfg, b.Logger())
+	defer pool.close()
+
+	pc, err := pool.get()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// No other connection can be opened until the first one is released
+	if _, err := pool.get(); err == nil {
+		t.Fatalf("expected an error")
+	}
+
+	pc.Release()
+	pc, err = pool.get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pc.Release()
+}
@@ -9,6 +9,7 @@ import (
 	"net/url"
 	"strings"
 	"text/template"
+	"time"
 
 	"github.com/fatih/structs"
 	"github.com/go-ldap/ldap"
@@ -114,6 +115,35 @@ Default: cn`,
 				Default:     true,
 				Description: "Denies an unauthenticated LDAP bind request if the user's password is empty; defaults to true",
 			},
+
+			"max_connections": &framework.FieldSchema{
+				Type:        framework.TypeInt,
+				Description: "Maximum number of connections open to the LDAP servers at a time; logins wait for a connection to be released beyond it. Defaults to 0, which is unlimited",
+			},
+
+			"max_idle_connections": &framework.FieldSchema{
+				Type:        framework.TypeInt,
+				Default:     5,
+				Description: "Maximum number of idle connections kept open to be reused by later logins. Defaults to 5",
+			},
+
+			"idle_timeout": &framework.FieldSchema{
+				Type:        framework.TypeDurationSecond,
+				Default:     300,
+				Description: "Duration after which idle connections are closed. Defaults to 300 seconds",
+			},
+
+			"connection_timeout": &framework.FieldSchema{
+				Type:        framework.TypeDurationSecond,
+				Default:     30,
+				Description: "Timeout for connecting to an LDAP server, and for waiting for a connection when max_connections is reached. Defaults to 30 seconds",
+			},
+
+			"server_retry_interval": &framework.FieldSchema{
+				Type:        framework.TypeDurationSecond,
+				Default:     30,
+				Description: "Duration for which an LDAP server that could not be connected to is skipped in favor of the next servers, unless it passes a health check first. Defaults to 30 seconds",
+			},
 		},
 
 		Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -286,6 +316,24 @@ func (b *backend) newConfigEntry(d *framework.FieldData) (*ConfigEntry, error) {
 		cfg.DiscoverDN = discoverDN
 	}
 
+	cfg.MaxConnections = d.Get("max_connections").(int)
+	cfg.MaxIdleConnections = d.Get("max_idle_connections").(int)
+	cfg.IdleTimeout = d.Get("idle_timeout").(int)
+	cfg.ConnectionTimeout = d.Get("connection_timeout").(int)
+	cfg.ServerRetryInterval = d.Get("server_retry_interval").(int)
+	switch {
+	case cfg.MaxConnections < 0:
+		return nil, fmt.Errorf("'max_connections' cannot be negative")
+	case cfg.MaxIdleConnections < 0:
+		return nil, fmt.Errorf("'max_idle_connections' cannot be negative")
+	case cfg.IdleTimeout < 0:
+		return nil, fmt.Errorf("'idle_timeout' cannot be negative")
+	case cfg.ConnectionTimeout <= 0:
+		return nil, fmt.Errorf("'connection_timeout' must be positive")
+	case cfg.ServerRetryInterval < 0:
+		return nil, fmt.Errorf("'server_retry_interval' cannot be negative")
+	}
+
 	return cfg, nil
 }
 
@@ -306,6 +354,8 @@ func (b *backend) pathConfigWrite(
 		return nil, err
 	}
 
+	b.resetConnPool()
+
 	return nil, nil
 }
 
@@ -327,6 +377,25 @@ type ConfigEntry struct {
 	DiscoverDN    bool   `json:"discoverdn" structs:"discoverdn" mapstructure:"discoverdn"`
 	TLSMinVersion string `json:"tls_min_version" structs:"tls_min_version" mapstructure:"tls_min_version"`
 	TLSMaxVersion string `json:"tls_max_version" structs:"tls_max_version" mapstructure:"tls_max_version"`
+
+	// Connection pool settings; durations are in seconds
+	MaxConnections      int `json:"max_connections" structs:"max_connections" mapstructure:"max_connections"`
+	MaxIdleConnections  int `json:"max_idle_connections" structs:"max_idle_connections" mapstructure:"max_idle_connections"`
+	IdleTimeout         int `json:"idle_timeout" structs:"idle_timeout" mapstructure:"idle_timeout"`
+	ConnectionTimeout   int `json:"connection_timeout" structs:"connection_timeout" mapstructure:"connection_timeout"`
+	ServerRetryInterval int `json:"server_retry_interval" structs:"server_retry_interval" mapstructure:"server_retry_interval"`
+}
+
+func (c *ConfigEntry) idleTimeout() time.Duration {
+	return time.Duration(c.IdleTimeout) * time.Second
+}
+
+func (c *ConfigEntry) connectionTimeout() time.Duration {
+	return time.Duration(c.ConnectionTimeout) * time.Second
+}
+
+func (c *ConfigEntry) serverRetryInterval() time.Duration {
+	return time.Duration(c.ServerRetryInterval) * time.Second
 }
 
 func (c *ConfigEntry) GetTLSConfig(host string) (*tls.Config, error) {
@@ -369,50 +438,8 @@ func (c *ConfigEntry) DialLDAP() (*ldap.Conn, error) {
 	var conn *ldap.Conn
 	urls := strings.Split(c.Url, ",")
 	for _, uut := range urls {
-		u, err := url.Parse(uut)
-		if err != nil {
-			retErr = multierror.Append(retErr, fmt.Errorf("error parsing url %q: %s", uut, err.Error()))
-			continue
-		}
-		host, port, err := net.SplitHostPort(u.Host)
-		if err != nil {
-			host = u.Host
-		}
-
-		var tlsConfig *tls.Config
-		switch u.Scheme {
-		case "ldap":
-			if port == "" {
-				port = "389"
-			}
-			conn, err = ldap.Dial("tcp", net.JoinHostPort(host, port))
-			if err != nil {
-				break
-			}
-			if conn == nil {
-				err = fmt.Errorf("empty connection after dialing")
-				break
-			}
-			if c.StartTLS {
-				tlsConfig, err = c.GetTLSConfig(host)
-				if err != nil {
-					break
-				}
-				err = conn.StartTLS(tlsConfig)
-			}
-		case "ldaps":
-			if port == "" {
-				port = "636"
-			}
-			tlsConfig, err = c.GetTLSConfig(host)
-			if err != nil {
-				break
-			}
-			conn, err = ldap.DialTLS("tcp", net.JoinHostPort(host, port), tlsConfig)
-		default:
-			retErr = multierror.Append(retErr, fmt.Errorf("invalid LDAP scheme in url %q", net.JoinHostPort(host, port)))
-			continue
-		}
+		var err error
+		conn, err = c.dialURL(uut, c.connectionTimeout())
 		if err == nil {
 			if retErr != nil {
 				if c.logger.IsDebug() {
@@ -428,6 +455,68 @@ func (c *ConfigEntry) DialLDAP() (*ldap.Conn, error) {
 	return conn, retErr.ErrorOrNil()
 }
 
+// dialURL connects to the LDAP server of the given URL, giving up after the
+// timeout
+func (c *ConfigEntry) dialURL(uut string, timeout time.Duration) (*ldap.Conn, error) {
+	u, err := url.Parse(uut)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing url %q: %s", uut, err.Error())
+	}
+	host, port, err := net.SplitHostPort(u.Host)
+	if err != nil {
+		host = u.Host
+	}
+
+	var tlsConfig *tls.Config
+	switch u.Scheme {
+	case "ldap":
+		if port == "" {
+			port = "389"
+		}
+	case "ldaps":
+		if port == "" {
+			port = "636"
+		}
+	default:
+		return nil, fmt.Errorf("invalid LDAP scheme in url %q", net.JoinHostPort(host, port))
+	}
+	if u.Scheme == "ldaps" || c.StartTLS {
+		tlsConfig, err = c.GetTLSConfig(host)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	netConn, err := net.DialTimeout("tcp", net.JoinHostPort(host, port), timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	var conn *ldap.Conn
+	if u.Scheme == "ldaps" {
+		tlsConn := tls.Client(netConn, tlsConfig)
+		tlsConn.SetDeadline(time.Now().Add(timeout))
+		if err := tlsConn.Handshake(); err != nil {
+			netConn.Close()
+			return nil, err
+		}
+		tlsConn.SetDeadline(time.Time{})
+		conn = ldap.NewConn(tlsConn, true)
+	} else {
+		conn = ldap.NewConn(netConn, false)
+	}
+	conn.Start()
+
+	if u.Scheme == "ldap" && c.StartTLS {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	return conn, nil
+}
+
 /*
  * Returns FieldData describing our ConfigEntry struct schema
  */
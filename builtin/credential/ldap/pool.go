@@ -0,0 +1,300 @@
+package ldap
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	metrics "github.com/armon/go-metrics"
+	"github.com/go-ldap/ldap"
+	multierror "github.com/hashicorp/go-multierror"
+	log "github.com/mgutz/logxi/v1"
+)
+
+// ldapClient is the set of operations performed on an LDAP connection on
+// login
+type ldapClient interface {
+	Bind(username, password string) error
+	UnauthenticatedBind(username string) error
+	Search(searchRequest *ldap.SearchRequest) (*ldap.SearchResult, error)
+}
+
+// ldapServer holds the health of one of the configured LDAP servers
+type ldapServer struct {
+	url string
+
+	// down is set when connecting to the server failed. The server is then
+	// skipped until retryAt, or until it passes a health check.
+	down    bool
+	retryAt time.Time
+}
+
+// connPool keeps connections to the configured LDAP servers open across
+// logins. Connections are made to the first healthy server in the order of
+// the configured URLs, failing over to the next ones.
+type connPool struct {
+	config *ConfigEntry
+	logger log.Logger
+
+	l       sync.Mutex
+	servers []*ldapServer
+	idle    []*pooledConn
+	open    int
+	closed  bool
+
+	// slots limits the number of open connections; it is nil if the number
+	// is unlimited
+	slots chan struct{}
+}
+
+// pooledConn is a connection of the pool. Connections are rebound on each
+// login, so they can be shared by logins of different users.
+type pooledConn struct {
+	*ldap.Conn
+
+	pool     *connPool
+	server   *ldapServer
+	lastUsed time.Time
+
+	// broken is set when an operation failed because of the connection, in
+	// which case the connection is closed rather than returned to the pool
+	broken bool
+}
+
+func newConnPool(config *ConfigEntry, logger log.Logger) *connPool {
+	p := &connPool{
+		config: config,
+		logger: logger,
+	}
+	for _, url := range strings.Split(config.Url, ",") {
+		p.servers = append(p.servers, &ldapServer{
+			url: strings.TrimSpace(url),
+		})
+	}
+	if config.MaxConnections > 0 {
+		p.slots = make(chan struct{}, config.MaxConnections)
+	}
+	return p
+}
+
+// get returns an idle connection of the pool, or a new connection if there
+// are none
+func (p *connPool) get() (*pooledConn, error) {
+	if p.slots != nil {
+		select {
+		case p.slots <- struct{}{}:
+		case <-time.After(p.config.connectionTimeout()):
+			metrics.IncrCounter([]string{"auth", "ldap", "pool_exhausted"}, 1)
+			return nil, errors.New("timed out waiting for a connection to the LDAP server")
+		}
+	}
+
+	if pc := p.popIdle(); pc != nil {
+		return pc, nil
+	}
+
+	pc, err := p.dial()
+	if err != nil {
+		p.releaseSlot()
+		return nil, err
+	}
+
+	p.l.Lock()
+	p.open++
+	metrics.SetGauge([]string{"auth", "ldap", "open_connections"}, float32(p.open))
+	p.l.Unlock()
+
+	return pc, nil
+}
+
+// popIdle returns the most recently used idle connection to a healthy server,
+// closing the expired ones
+func (p *connPool) popIdle() *pooledConn {
+	p.l.Lock()
+	defer p.l.Unlock()
+
+	for len(p.idle) > 0 {
+		pc := p.idle[len(p.idle)-1]
+		p.idle = p.idle[:len(p.idle)-1]
+
+		if !pc.server.down && time.Since(pc.lastUsed) < p.config.idleTimeout() {
+			return pc
+		}
+		p.closeLocked(pc)
+	}
+	return nil
+}
+
+// dial connects to the first healthy server. Servers which are down are only
+// tried once their retry interval has elapsed, or if no server is healthy.
+func (p *connPool) dial() (*pooledConn, error) {
+	now := time.Now()
+
+	p.l.Lock()
+	var candidates, skipped []*ldapServer
+	for _, server := range p.servers {
+		if server.down && now.Before(server.retryAt) {
+			skipped = append(skipped, server)
+			continue
+		}
+		candidates = append(candidates, server)
+	}
+	p.l.Unlock()
+	candidates = append(candidates, skipped...)
+
+	var retErr *multierror.Error
+	for _, server := range candidates {
+		conn, err := p.dialServer(server)
+		if err != nil {
+			retErr = multierror.Append(retErr, err)
+			continue
+		}
+		return &pooledConn{
+			Conn:   conn,
+			pool:   p,
+			server: server,
+		}, nil
+	}
+
+	return nil, retErr.ErrorOrNil()
+}
+
+// dialServer connects to a server, updating its health
+func (p *connPool) dialServer(server *ldapServer) (*ldap.Conn, error) {
+	start := time.Now()
+	conn, err := p.config.dialURL(server.url, p.config.connectionTimeout())
+	metrics.MeasureSince([]string{"auth", "ldap", "dial"}, start)
+
+	p.l.Lock()
+	defer p.l.Unlock()
+
+	if err != nil {
+		if !server.down {
+			metrics.IncrCounter([]string{"auth", "ldap", "failover"}, 1)
+			p.logger.Warn("auth/ldap: marking LDAP server as down", "url", server.url, "error", err)
+		}
+		server.down = true
+		server.retryAt = time.Now().Add(p.config.serverRetryInterval())
+		return nil, fmt.Errorf("error connecting to host %q: %v", server.url, err)
+	}
+
+	if server.down {
+		p.logger.Info("auth/ldap: LDAP server is back up", "url", server.url)
+		server.down = false
+	}
+	return conn, nil
+}
+
+// healthCheck closes the expired idle connections and tries to connect to
+// the servers which are down, so that they are used again once they are back
+// up
+func (p *connPool) healthCheck() {
+	p.l.Lock()
+	if p.closed {
+		p.l.Unlock()
+		return
+	}
+
+	var idle []*pooledConn
+	for _, pc := range p.idle {
+		if time.Since(pc.lastUsed) < p.config.idleTimeout() {
+			idle = append(idle, pc)
+			continue
+		}
+		p.closeLocked(pc)
+	}
+	p.idle = idle
+
+	var down []*ldapServer
+	for _, server := range p.servers {
+		if server.down {
+			down = append(down, server)
+		}
+	}
+	p.l.Unlock()
+
+	for _, server := range down {
+		conn, err := p.dialServer(server)
+		if err != nil {
+			continue
+		}
+		conn.Close()
+	}
+}
+
+// release returns a connection to the pool. Broken connections, and those in
+// excess of the maximum number of idle connections, are closed.
+func (p *connPool) release(pc *pooledConn) {
+	defer p.releaseSlot()
+
+	p.l.Lock()
+	defer p.l.Unlock()
+
+	if pc.broken || pc.server.down || p.closed || len(p.idle) >= p.config.MaxIdleConnections {
+		p.closeLocked(pc)
+		return
+	}
+
+	pc.lastUsed = time.Now()
+	p.idle = append(p.idle, pc)
+}
+
+func (p *connPool) releaseSlot() {
+	if p.slots != nil {
+		<-p.slots
+	}
+}
+
+// closeLocked closes a connection of the pool. The pool lock must be held.
+func (p *connPool) closeLocked(pc *pooledConn) {
+	pc.Conn.Close()
+	p.open--
+	metrics.SetGauge([]string{"auth", "ldap", "open_connections"}, float32(p.open))
+}
+
+// close closes the idle connections of the pool. The connections in use are
+// closed once they are released.
+func (p *connPool) close() {
+	p.l.Lock()
+	defer p.l.Unlock()
+
+	for _, pc := range p.idle {
+		p.closeLocked(pc)
+	}
+	p.idle = nil
+	p.closed = true
+}
+
+// Release returns the connection to its pool
+func (pc *pooledConn) Release() {
+	pc.pool.release(pc)
+}
+
+func (pc *pooledConn) Bind(username, password string) error {
+	defer metrics.MeasureSince([]string{"auth", "ldap", "bind"}, time.Now())
+	return pc.check(pc.Conn.Bind(username, password))
+}
+
+func (pc *pooledConn) UnauthenticatedBind(username string) error {
+	defer metrics.MeasureSince([]string{"auth", "ldap", "bind"}, time.Now())
+	return pc.check(pc.Conn.UnauthenticatedBind(username))
+}
+
+func (pc *pooledConn) Search(searchRequest *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	result, err := pc.Conn.Search(searchRequest)
+	return result, pc.check(err)
+}
+
+// check marks the connection as broken if the error is a network error, or
+// is not an LDAP result, and returns the error
+func (pc *pooledConn) check(err error) error {
+	if err == nil {
+		return nil
+	}
+	if ldapErr, ok := err.(*ldap.Error); !ok || ldapErr.ResultCode == ldap.ErrorNetwork {
+		pc.broken = true
+	}
+	return err
+}
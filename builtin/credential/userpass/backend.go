@@ -32,6 +32,7 @@ func Backend() *backend {
 			pathUsersList(&b),
 			pathUserPolicies(&b),
 			pathUserPassword(&b),
+			pathConfigHashing(&b),
 		},
 			mfa.MFAPaths(b.Backend, pathLogin(&b))...,
 		),
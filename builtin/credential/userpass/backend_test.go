@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/hashicorp/vault/helper/passwordpolicy"
 	"github.com/hashicorp/vault/helper/policyutil"
 	"github.com/hashicorp/vault/logical"
 	logicaltest "github.com/hashicorp/vault/logical/testing"
@@ -186,6 +187,60 @@ func TestBackend_policiesUpdate(t *testing.T) {
 
 }
 
+func TestBackend_passwordPolicy(t *testing.T) {
+	b, err := Factory(&logical.BackendConfig{
+		Logger: nil,
+		System: &logical.StaticSystemView{
+			DefaultLeaseTTLVal: testSysTTL,
+			MaxLeaseTTLVal:     testSysMaxTTL,
+			PasswordPolicies: map[string]*passwordpolicy.Policy{
+				"strict": &passwordpolicy.Policy{
+					Name:            "strict",
+					Length:          10,
+					MinUppercase:    1,
+					MinDigits:       1,
+					BannedPasswords: []string{"Password123"},
+					History:         2,
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unable to create backend: %s", err)
+	}
+
+	logicaltest.Test(t, logicaltest.TestCase{
+		Backend: b,
+		Steps: []logicaltest.TestStep{
+			// The password given along with the policy must satisfy it
+			testUsersWrite(t, "web", map[string]interface{}{
+				"password":        "password",
+				"password_policy": "strict",
+			}, true),
+			testUsersWrite(t, "web", map[string]interface{}{
+				"password":        "Password000",
+				"password_policy": "unknown",
+			}, true),
+			testUsersWrite(t, "web", map[string]interface{}{
+				"password":        "Password000",
+				"password_policy": "strict",
+				"policies":        "foo",
+			}, false),
+			testAccStepReadUserPasswordPolicy(t, "web", "strict"),
+			testUpdatePasswordPolicyCheck(t, "web", "Short1", true),
+			testUpdatePasswordPolicyCheck(t, "web", "nouppercase1", true),
+			testUpdatePasswordPolicyCheck(t, "web", "PASSWORD123", true),
+			// The current password and the previous one can't be reused
+			testUpdatePasswordPolicyCheck(t, "web", "Password000", true),
+			testUpdatePasswordPolicyCheck(t, "web", "Password001", false),
+			testUpdatePasswordPolicyCheck(t, "web", "Password000", true),
+			testUpdatePasswordPolicyCheck(t, "web", "Password002", false),
+			testUpdatePasswordPolicyCheck(t, "web", "Password000", false),
+			testAccStepLogin(t, "web", "Password000", []string{"default", "foo"}),
+		},
+	})
+}
+
 func testUpdatePassword(t *testing.T, user, password string) logicaltest.TestStep {
 	return logicaltest.TestStep{
 		Operation: logical.UpdateOperation,
@@ -196,6 +251,24 @@ func testUpdatePassword(t *testing.T, user, password string) logicaltest.TestSte
 	}
 }
 
+func testUpdatePasswordPolicyCheck(t *testing.T, user, password string, expectError bool) logicaltest.TestStep {
+	return logicaltest.TestStep{
+		Operation: logical.UpdateOperation,
+		Path:      "users/" + user + "/password",
+		Data: map[string]interface{}{
+			"password": password,
+		},
+		ErrorOk: true,
+		Check: func(resp *logical.Response) error {
+			isError := resp != nil && resp.IsError()
+			if isError != expectError {
+				return fmt.Errorf("bad: password %q, expected error: %t, resp: %#v", password, expectError, resp)
+			}
+			return nil
+		},
+	}
+}
+
 func testUpdatePolicies(t *testing.T, user, policies string) logicaltest.TestStep {
 	return logicaltest.TestStep{
 		Operation: logical.UpdateOperation,
@@ -326,3 +399,19 @@ func testAccStepReadUser(t *testing.T, name string, policies string) logicaltest
 		},
 	}
 }
+
+func testAccStepReadUserPasswordPolicy(t *testing.T, name string, passwordPolicy string) logicaltest.TestStep {
+	return logicaltest.TestStep{
+		Operation: logical.ReadOperation,
+		Path:      "users/" + name,
+		Check: func(resp *logical.Response) error {
+			if resp == nil {
+				return fmt.Errorf("user not found")
+			}
+			if resp.Data["password_policy"] != passwordPolicy {
+				return fmt.Errorf("bad: %#v", resp.Data)
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,208 @@
+package userpass
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	hashAlgorithmBcrypt = "bcrypt"
+	hashAlgorithmScrypt = "scrypt"
+	hashAlgorithmArgon2 = "argon2id"
+
+	// scryptPrefix and argon2Prefix identify password hashes created with
+	// scrypt and argon2id. Hashes without either were created with bcrypt.
+	scryptPrefix = "$scrypt$"
+	argon2Prefix = "$argon2id$"
+
+	scryptSaltLength = 16
+	scryptKeyLength  = 32
+
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+)
+
+var errPasswordMismatch = errors.New("password does not match")
+
+// hashPassword hashes the password using the algorithm and cost parameters
+// of the given configuration
+func hashPassword(password []byte, config *hashingConfig) ([]byte, error) {
+	switch config.Algorithm {
+	case hashAlgorithmBcrypt:
+		return bcrypt.GenerateFromPassword(password, config.BcryptCost)
+
+	case hashAlgorithmScrypt:
+		salt := make([]byte, scryptSaltLength)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, err
+		}
+		key, err := scrypt.Key(password, salt, config.ScryptN, config.ScryptR, config.ScryptP, scryptKeyLength)
+		if err != nil {
+			return nil, err
+		}
+		return []byte(fmt.Sprintf("%sn=%d,r=%d,p=%d$%s$%s", scryptPrefix,
+			config.ScryptN, config.ScryptR, config.ScryptP,
+			base64.RawStdEncoding.EncodeToString(salt),
+			base64.RawStdEncoding.EncodeToString(key))), nil
+
+	case hashAlgorithmArgon2:
+		salt := make([]byte, argon2SaltLength)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, err
+		}
+		key := argon2.IDKey(password, salt, uint32(config.Argon2Time), uint32(config.Argon2Memory), uint8(config.Argon2Threads), argon2KeyLength)
+		return []byte(fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s", argon2Prefix, argon2.Version,
+			config.Argon2Memory, config.Argon2Time, config.Argon2Threads,
+			base64.RawStdEncoding.EncodeToString(salt),
+			base64.RawStdEncoding.EncodeToString(key))), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported hashing algorithm %q", config.Algorithm)
+	}
+}
+
+// comparePasswordHash returns nil if the password matches the hash, which may
+// have been created with any supported algorithm
+func comparePasswordHash(hash, password []byte) error {
+	var computed, key []byte
+	switch {
+	case bytes.HasPrefix(hash, []byte(scryptPrefix)):
+		params, salt, hashKey, err := parseScryptHash(hash)
+		if err != nil {
+			return err
+		}
+		computed, err = scrypt.Key(password, salt, params.ScryptN, params.ScryptR, params.ScryptP, len(hashKey))
+		if err != nil {
+			return err
+		}
+		key = hashKey
+
+	case bytes.HasPrefix(hash, []byte(argon2Prefix)):
+		params, salt, hashKey, err := parseArgon2Hash(hash)
+		if err != nil {
+			return err
+		}
+		computed = argon2.IDKey(password, salt, uint32(params.Argon2Time), uint32(params.Argon2Memory), uint8(params.Argon2Threads), uint32(len(hashKey)))
+		key = hashKey
+
+	default:
+		return bcrypt.CompareHashAndPassword(hash, password)
+	}
+
+	if subtle.ConstantTimeCompare(computed, key) != 1 {
+		return errPasswordMismatch
+	}
+	return nil
+}
+
+// passwordNeedsRehash returns whether the hash was created with a different
+// algorithm or cost than the configuration currently asks for
+func passwordNeedsRehash(hash []byte, config *hashingConfig) bool {
+	switch {
+	case bytes.HasPrefix(hash, []byte(scryptPrefix)):
+		if config.Algorithm != hashAlgorithmScrypt {
+			return true
+		}
+		params, _, _, err := parseScryptHash(hash)
+		if err != nil {
+			return true
+		}
+		return params.ScryptN != config.ScryptN ||
+			params.ScryptR != config.ScryptR ||
+			params.ScryptP != config.ScryptP
+
+	case bytes.HasPrefix(hash, []byte(argon2Prefix)):
+		if config.Algorithm != hashAlgorithmArgon2 {
+			return true
+		}
+		params, _, _, err := parseArgon2Hash(hash)
+		if err != nil {
+			return true
+		}
+		return params.Argon2Time != config.Argon2Time ||
+			params.Argon2Memory != config.Argon2Memory ||
+			params.Argon2Threads != config.Argon2Threads
+
+	default:
+		if config.Algorithm != hashAlgorithmBcrypt {
+			return true
+		}
+		cost, err := bcrypt.Cost(hash)
+		return err != nil || cost != config.BcryptCost
+	}
+}
+
+// parseScryptHash splits an scrypt hash of the form
+// $scrypt$n=<N>,r=<r>,p=<p>$<salt>$<key> into its parts
+func parseScryptHash(hash []byte) (*hashingConfig, []byte, []byte, error) {
+	parts := strings.Split(strings.TrimPrefix(string(hash), scryptPrefix), "$")
+	if len(parts) != 3 {
+		return nil, nil, nil, errors.New("malformed scrypt hash")
+	}
+
+	params := &hashingConfig{
+		Algorithm: hashAlgorithmScrypt,
+	}
+	if _, err := fmt.Sscanf(parts[0], "n=%d,r=%d,p=%d", &params.ScryptN, &params.ScryptR, &params.ScryptP); err != nil {
+		return nil, nil, nil, fmt.Errorf("malformed scrypt hash parameters: %v", err)
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("malformed scrypt hash salt: %v", err)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("malformed scrypt hash key: %v", err)
+	}
+
+	return params, salt, key, nil
+}
+
+// parseArgon2Hash splits an argon2id hash of the form
+// $argon2id$v=<version>$m=<memory>,t=<time>,p=<threads>$<salt>$<key> into
+// its parts
+func parseArgon2Hash(hash []byte) (*hashingConfig, []byte, []byte, error) {
+	parts := strings.Split(strings.TrimPrefix(string(hash), argon2Prefix), "$")
+	if len(parts) != 4 {
+		return nil, nil, nil, errors.New("malformed argon2id hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[0], "v=%d", &version); err != nil {
+		return nil, nil, nil, fmt.Errorf("malformed argon2id hash version: %v", err)
+	}
+	if version != argon2.Version {
+		return nil, nil, nil, fmt.Errorf("unsupported argon2id version %d", version)
+	}
+
+	params := &hashingConfig{
+		Algorithm: hashAlgorithmArgon2,
+	}
+	if _, err := fmt.Sscanf(parts[1], "m=%d,t=%d,p=%d", &params.Argon2Memory, &params.Argon2Time, &params.Argon2Threads); err != nil {
+		return nil, nil, nil, fmt.Errorf("malformed argon2id hash parameters: %v", err)
+	}
+	if err := params.validate(); err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid argon2id hash parameters: %v", err)
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("malformed argon2id hash salt: %v", err)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[3])
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("malformed argon2id hash key: %v", err)
+	}
+
+	return params, salt, key, nil
+}
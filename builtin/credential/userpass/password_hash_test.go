@@ -0,0 +1,170 @@
+package userpass
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/logical"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestPasswordHash(t *testing.T) {
+	bcryptConfig := defaultHashingConfig()
+	bcryptConfig.BcryptCost = bcrypt.MinCost
+	scryptConfig := defaultHashingConfig()
+	scryptConfig.Algorithm = hashAlgorithmScrypt
+	scryptConfig.ScryptN = 1024
+	argon2Config := defaultHashingConfig()
+	argon2Config.Algorithm = hashAlgorithmArgon2
+	argon2Config.Argon2Memory = 1024
+	argon2Config.Argon2Threads = 1
+
+	for _, config := range []*hashingConfig{bcryptConfig, scryptConfig, argon2Config} {
+		hash, err := hashPassword([]byte("password"), config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := comparePasswordHash(hash, []byte("password")); err != nil {
+			t.Fatalf("%s: expected password to match: %v", config.Algorithm, err)
+		}
+		if err := comparePasswordHash(hash, []byte("wrong")); err == nil {
+			t.Fatalf("%s: expected wrong password not to match", config.Algorithm)
+		}
+		if passwordNeedsRehash(hash, config) {
+			t.Fatalf("%s: expected hash to match its own config", config.Algorithm)
+		}
+	}
+
+	hash, err := hashPassword([]byte("password"), scryptConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(hash), "$scrypt$n=1024,r=8,p=1$") {
+		t.Fatalf("bad: %s", hash)
+	}
+	if !passwordNeedsRehash(hash, bcryptConfig) {
+		t.Fatal("expected algorithm change to require rehash")
+	}
+	tuned := *scryptConfig
+	tuned.ScryptN = 2048
+	if !passwordNeedsRehash(hash, &tuned) {
+		t.Fatal("expected cost change to require rehash")
+	}
+
+	hash, err = hashPassword([]byte("password"), argon2Config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(hash), "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Fatalf("bad: %s", hash)
+	}
+	if !passwordNeedsRehash(hash, scryptConfig) {
+		t.Fatal("expected algorithm change to require rehash")
+	}
+	tuned = *argon2Config
+	tuned.Argon2Time = 2
+	if !passwordNeedsRehash(hash, &tuned) {
+		t.Fatal("expected cost change to require rehash")
+	}
+	if _, _, _, err := parseArgon2Hash([]byte("$argon2id$v=19$m=1024,t=1,p=0$c2FsdA$a2V5")); err == nil {
+		t.Fatal("expected invalid parameters to be rejected")
+	}
+}
+
+func TestBackend_hashingMigration(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b, err := Factory(&logical.BackendConfig{
+		StorageView: storage,
+		System: &logical.StaticSystemView{
+			DefaultLeaseTTLVal: testSysTTL,
+			MaxLeaseTTLVal:     testSysMaxTTL,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doReq := func(op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		resp, err := b.HandleRequest(&logical.Request{
+			Operation: op,
+			Path:      path,
+			Storage:   storage,
+			Data:      data,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: %s: err: %v resp: %#v", path, err, resp)
+		}
+		return resp
+	}
+	storedHash := func() []byte {
+		user, err := b.(*backend).user(storage, "web")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return user.PasswordHash
+	}
+
+	resp := doReq(logical.ReadOperation, "config/hashing", nil)
+	if resp.Data["algorithm"] != hashAlgorithmBcrypt || resp.Data["bcrypt_cost"] != bcrypt.DefaultCost {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	doReq(logical.UpdateOperation, "config/hashing", map[string]interface{}{
+		"bcrypt_cost": bcrypt.MinCost,
+	})
+	doReq(logical.CreateOperation, "users/web", map[string]interface{}{
+		"password": "password",
+	})
+	if cost, err := bcrypt.Cost(storedHash()); err != nil || cost != bcrypt.MinCost {
+		t.Fatalf("expected bcrypt hash with cost %d, got %d: %v", bcrypt.MinCost, cost, err)
+	}
+
+	// Switching algorithms leaves the stored hash alone until the next login
+	doReq(logical.UpdateOperation, "config/hashing", map[string]interface{}{
+		"algorithm": hashAlgorithmScrypt,
+		"scrypt_n":  1024,
+	})
+	if strings.HasPrefix(string(storedHash()), scryptPrefix) {
+		t.Fatal("expected hash not to change before login")
+	}
+
+	// A failed login does not migrate the hash
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "login/web",
+		Storage:   storage,
+		Data:      map[string]interface{}{"password": "wrong"},
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected login to fail: %v %#v", err, resp)
+	}
+	if strings.HasPrefix(string(storedHash()), scryptPrefix) {
+		t.Fatal("expected hash not to change after failed login")
+	}
+
+	resp = doReq(logical.UpdateOperation, "login/web", map[string]interface{}{
+		"password": "password",
+	})
+	if resp.Auth == nil {
+		t.Fatal("expected login to succeed")
+	}
+	if !strings.HasPrefix(string(storedHash()), "$scrypt$n=1024,r=8,p=1$") {
+		t.Fatalf("expected hash to be migrated to scrypt, got %s", storedHash())
+	}
+
+	// The migrated hash still allows logging in
+	doReq(logical.UpdateOperation, "login/web", map[string]interface{}{
+		"password": "password",
+	})
+
+	// Invalid configurations are rejected
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config/hashing",
+		Storage:   storage,
+		Data:      map[string]interface{}{"scrypt_n": 1000},
+	})
+	if err == nil || !resp.IsError() {
+		t.Fatalf("expected error for invalid scrypt_n: %#v", resp)
+	}
+}
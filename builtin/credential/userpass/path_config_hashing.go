@@ -0,0 +1,233 @@
+package userpass
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const hashingConfigPath = "config/hashing"
+
+func pathConfigHashing(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config/hashing$",
+		Fields: map[string]*framework.FieldSchema{
+			"algorithm": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Default:     hashAlgorithmBcrypt,
+				Description: `Algorithm used to hash passwords. One of "bcrypt", "scrypt" or "argon2id".`,
+			},
+
+			"bcrypt_cost": &framework.FieldSchema{
+				Type:        framework.TypeInt,
+				Default:     bcrypt.DefaultCost,
+				Description: "Cost factor for bcrypt hashes.",
+			},
+
+			"scrypt_n": &framework.FieldSchema{
+				Type:        framework.TypeInt,
+				Default:     32768,
+				Description: "CPU/memory cost parameter for scrypt hashes. Must be a power of two.",
+			},
+
+			"scrypt_r": &framework.FieldSchema{
+				Type:        framework.TypeInt,
+				Default:     8,
+				Description: "Block size parameter for scrypt hashes.",
+			},
+
+			"scrypt_p": &framework.FieldSchema{
+				Type:        framework.TypeInt,
+				Default:     1,
+				Description: "Parallelization parameter for scrypt hashes.",
+			},
+
+			"argon2_time": &framework.FieldSchema{
+				Type:        framework.TypeInt,
+				Default:     1,
+				Description: "Number of passes over the memory for argon2id hashes.",
+			},
+
+			"argon2_memory": &framework.FieldSchema{
+				Type:        framework.TypeInt,
+				Default:     65536,
+				Description: "Memory used by argon2id hashes, in KiB.",
+			},
+
+			"argon2_threads": &framework.FieldSchema{
+				Type:        framework.TypeInt,
+				Default:     4,
+				Description: "Number of threads used by argon2id hashes.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathConfigHashingRead,
+			logical.UpdateOperation: b.pathConfigHashingWrite,
+		},
+
+		HelpSynopsis:    pathConfigHashingHelpSyn,
+		HelpDescription: pathConfigHashingHelpDesc,
+	}
+}
+
+// hashingConfig controls how user passwords are hashed
+type hashingConfig struct {
+	Algorithm  string `json:"algorithm"`
+	BcryptCost int    `json:"bcrypt_cost"`
+	ScryptN    int    `json:"scrypt_n"`
+	ScryptR    int    `json:"scrypt_r"`
+	ScryptP    int    `json:"scrypt_p"`
+
+	Argon2Time    int `json:"argon2_time"`
+	Argon2Memory  int `json:"argon2_memory"`
+	Argon2Threads int `json:"argon2_threads"`
+}
+
+// defaultHashingConfig matches how passwords were hashed before hashing
+// became configurable
+func defaultHashingConfig() *hashingConfig {
+	return &hashingConfig{
+		Algorithm:  hashAlgorithmBcrypt,
+		BcryptCost: bcrypt.DefaultCost,
+		ScryptN:    32768,
+		ScryptR:    8,
+		ScryptP:    1,
+
+		Argon2Time:    1,
+		Argon2Memory:  65536,
+		Argon2Threads: 4,
+	}
+}
+
+func (c *hashingConfig) validate() error {
+	switch c.Algorithm {
+	case hashAlgorithmBcrypt:
+		if c.BcryptCost < bcrypt.MinCost || c.BcryptCost > bcrypt.MaxCost {
+			return fmt.Errorf("bcrypt_cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+		}
+	case hashAlgorithmScrypt:
+		if c.ScryptN <= 1 || c.ScryptN&(c.ScryptN-1) != 0 {
+			return fmt.Errorf("scrypt_n must be a power of two greater than 1")
+		}
+		if c.ScryptR < 1 || c.ScryptP < 1 {
+			return fmt.Errorf("scrypt_r and scrypt_p must be at least 1")
+		}
+		if uint64(c.ScryptR)*uint64(c.ScryptP) >= 1<<30 {
+			return fmt.Errorf("scrypt_r * scrypt_p must be less than 2^30")
+		}
+	case hashAlgorithmArgon2:
+		if c.Argon2Time < 1 || int64(c.Argon2Time) > math.MaxUint32 {
+			return fmt.Errorf("argon2_time must be at least 1")
+		}
+		if c.Argon2Threads < 1 || c.Argon2Threads > math.MaxUint8 {
+			return fmt.Errorf("argon2_threads must be between 1 and %d", math.MaxUint8)
+		}
+		if c.Argon2Memory < 8*c.Argon2Threads || int64(c.Argon2Memory) > math.MaxUint32 {
+			return fmt.Errorf("argon2_memory must be at least 8 KiB per thread")
+		}
+	default:
+		return fmt.Errorf("unsupported algorithm %q", c.Algorithm)
+	}
+	return nil
+}
+
+// hashingConfig returns the stored hashing configuration, or the default if
+// none has been written
+func (b *backend) hashingConfig(s logical.Storage) (*hashingConfig, error) {
+	entry, err := s.Get(hashingConfigPath)
+	if err != nil {
+		return nil, err
+	}
+	config := defaultHashingConfig()
+	if entry == nil {
+		return config, nil
+	}
+	if err := entry.DecodeJSON(config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func (b *backend) pathConfigHashingRead(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config, err := b.hashingConfig(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"algorithm":   config.Algorithm,
+			"bcrypt_cost": config.BcryptCost,
+			"scrypt_n":    config.ScryptN,
+			"scrypt_r":    config.ScryptR,
+			"scrypt_p":    config.ScryptP,
+
+			"argon2_time":    config.Argon2Time,
+			"argon2_memory":  config.Argon2Memory,
+			"argon2_threads": config.Argon2Threads,
+		},
+	}, nil
+}
+
+func (b *backend) pathConfigHashingWrite(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config, err := b.hashingConfig(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	if algorithmRaw, ok := d.GetOk("algorithm"); ok {
+		config.Algorithm = algorithmRaw.(string)
+	}
+	if costRaw, ok := d.GetOk("bcrypt_cost"); ok {
+		config.BcryptCost = costRaw.(int)
+	}
+	if nRaw, ok := d.GetOk("scrypt_n"); ok {
+		config.ScryptN = nRaw.(int)
+	}
+	if rRaw, ok := d.GetOk("scrypt_r"); ok {
+		config.ScryptR = rRaw.(int)
+	}
+	if pRaw, ok := d.GetOk("scrypt_p"); ok {
+		config.ScryptP = pRaw.(int)
+	}
+	if timeRaw, ok := d.GetOk("argon2_time"); ok {
+		config.Argon2Time = timeRaw.(int)
+	}
+	if memoryRaw, ok := d.GetOk("argon2_memory"); ok {
+		config.Argon2Memory = memoryRaw.(int)
+	}
+	if threadsRaw, ok := d.GetOk("argon2_threads"); ok {
+		config.Argon2Threads = threadsRaw.(int)
+	}
+
+	if err := config.validate(); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	entry, err := logical.StorageEntryJSON(hashingConfigPath, config)
+	if err != nil {
+		return nil, err
+	}
+	return nil, req.Storage.Put(entry)
+}
+
+const pathConfigHashingHelpSyn = `
+Configure how user passwords are hashed.
+`
+
+const pathConfigHashingHelpDesc = `
+This endpoint selects the algorithm and cost parameters used to
+hash user passwords. Passwords are hashed with bcrypt at its
+default cost unless configured otherwise.
+
+Changing the configuration does not rehash existing passwords
+immediately. Instead, each user's password is rehashed with the
+new settings the next time they log in successfully, or when the
+password is next set.
+`
@@ -8,7 +8,6 @@ import (
 	"github.com/hashicorp/vault/helper/policyutil"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
-	"golang.org/x/crypto/bcrypt"
 )
 
 func pathLogin(b *backend) *framework.Path {
@@ -57,7 +56,7 @@ func (b *backend) pathLogin(
 	// but handle the older legacy passwords with a constant time comparison.
 	passwordBytes := []byte(password)
 	if user.PasswordHash != nil {
-		if err := bcrypt.CompareHashAndPassword(user.PasswordHash, passwordBytes); err != nil {
+		if err := comparePasswordHash(user.PasswordHash, passwordBytes); err != nil {
 			return logical.ErrorResponse("invalid username or password"), nil
 		}
 	} else {
@@ -66,6 +65,12 @@ func (b *backend) pathLogin(
 		}
 	}
 
+	// Now that the password is known to be correct, migrate it to the
+	// configured hashing settings if it was stored differently
+	if err := b.rehashPassword(req.Storage, username, user, passwordBytes); err != nil {
+		b.Logger().Warn("userpass: failed to rehash password", "username", username, "error", err)
+	}
+
 	return &logical.Response{
 		Auth: &logical.Auth{
 			Policies: user.Policies,
@@ -81,6 +86,26 @@ func (b *backend) pathLogin(
 	}, nil
 }
 
+// rehashPassword rehashes and stores the user's password if it is stored in
+// plaintext or was hashed with other settings than the configured ones
+func (b *backend) rehashPassword(s logical.Storage, username string, user *UserEntry, password []byte) error {
+	config, err := b.hashingConfig(s)
+	if err != nil {
+		return err
+	}
+	if user.PasswordHash != nil && !passwordNeedsRehash(user.PasswordHash, config) {
+		return nil
+	}
+
+	hash, err := hashPassword(password, config)
+	if err != nil {
+		return err
+	}
+	user.PasswordHash = hash
+	user.Password = ""
+	return b.setUser(s, username, user)
+}
+
 func (b *backend) pathLoginRenew(
 	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
 	// Get the user
@@ -3,8 +3,6 @@ package userpass
 import (
 	"fmt"
 
-	"golang.org/x/crypto/bcrypt"
-
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
 )
@@ -48,7 +46,7 @@ func (b *backend) pathUserPasswordUpdate(
 
 	userErr, intErr := b.updateUserPassword(req, d, userEntry)
 	if intErr != nil {
-		return nil, err
+		return nil, intErr
 	}
 	if userErr != nil {
 		return logical.ErrorResponse(userErr.Error()), logical.ErrInvalidRequest
@@ -62,12 +60,54 @@ func (b *backend) updateUserPassword(req *logical.Request, d *framework.FieldDat
 	if password == "" {
 		return fmt.Errorf("missing password"), nil
 	}
+
+	var history int
+	if userEntry.PasswordPolicy != "" {
+		policy, err := b.System().PasswordPolicy(userEntry.PasswordPolicy)
+		if err != nil {
+			return nil, err
+		}
+		if policy == nil {
+			return fmt.Errorf("password policy %q does not exist", userEntry.PasswordPolicy), nil
+		}
+		if err := policy.Check(password); err != nil {
+			return err, nil
+		}
+		history = policy.History
+	}
+
+	// Reject the current password and the previous ones kept in the history.
+	// The hashes are salted, so each of them is compared on its own.
+	if history > 0 {
+		previous := userEntry.PasswordHistory
+		if userEntry.PasswordHash != nil {
+			previous = append([][]byte{userEntry.PasswordHash}, previous...)
+		}
+		if len(previous) > history {
+			previous = previous[:history]
+		}
+		for _, hash := range previous {
+			if comparePasswordHash(hash, []byte(password)) == nil {
+				return fmt.Errorf("password cannot be one of the last %d passwords of the user", history), nil
+			}
+		}
+		userEntry.PasswordHistory = previous
+	} else {
+		userEntry.PasswordHistory = nil
+	}
+
+	config, err := b.hashingConfig(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
 	// Generate a hash of the password
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	hash, err := hashPassword([]byte(password), config)
 	if err != nil {
 		return nil, err
 	}
 	userEntry.PasswordHash = hash
+	userEntry.Password = ""
 	return nil, nil
 }
 
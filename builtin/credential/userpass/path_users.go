@@ -51,6 +51,10 @@ func pathUsers(b *backend) *framework.Path {
 				Default:     "",
 				Description: "Maximum duration after which login should expire",
 			},
+			"password_policy": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of the password policy the passwords of the user must satisfy",
+			},
 		},
 
 		Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -137,9 +141,10 @@ func (b *backend) pathUserRead(
 
 	return &logical.Response{
 		Data: map[string]interface{}{
-			"policies": user.Policies,
-			"ttl":      user.TTL.Seconds(),
-			"max_ttl":  user.MaxTTL.Seconds(),
+			"policies":        user.Policies,
+			"ttl":             user.TTL.Seconds(),
+			"max_ttl":         user.MaxTTL.Seconds(),
+			"password_policy": user.PasswordPolicy,
 		},
 	}, nil
 }
@@ -155,10 +160,16 @@ func (b *backend) userCreateUpdate(req *logical.Request, d *framework.FieldData)
 		userEntry = &UserEntry{}
 	}
 
+	// The password policy is set first, so that a password given along with
+	// it must satisfy it
+	if passwordPolicyRaw, ok := d.GetOk("password_policy"); ok {
+		userEntry.PasswordPolicy = passwordPolicyRaw.(string)
+	}
+
 	if _, ok := d.GetOk("password"); ok {
 		userErr, intErr := b.updateUserPassword(req, d, userEntry)
 		if intErr != nil {
-			return nil, err
+			return nil, intErr
 		}
 		if userErr != nil {
 			return logical.ErrorResponse(userErr.Error()), logical.ErrInvalidRequest
@@ -201,8 +212,9 @@ type UserEntry struct {
 	// PasswordHash, but is retained for backwards compatibility.
 	Password string
 
-	// PasswordHash is a bcrypt hash of the password. This is
-	// used instead of the actual password in Vault 0.2+.
+	// PasswordHash is a hash of the password. This is used
+	// instead of the actual password in Vault 0.2+. It is a
+	// bcrypt hash unless another algorithm is configured.
 	PasswordHash []byte
 
 	Policies []string
@@ -212,6 +224,14 @@ type UserEntry struct {
 
 	// Maximum duration for which user can be valid
 	MaxTTL time.Duration
+
+	// PasswordPolicy is the name of the password policy the passwords of the
+	// user must satisfy
+	PasswordPolicy string
+
+	// PasswordHistory holds the hashes of the previous passwords of the user,
+	// most recent first, as many as the history of the password policy
+	PasswordHistory [][]byte
 }
 
 const pathUserHelpSyn = `
@@ -25,6 +25,11 @@ func Backend() *backend {
 			LocalStorage: []string{
 				framework.WALPrefix,
 			},
+
+			ExportableStorage: []string{
+				"policy/",
+				"sts_options/",
+			},
 		},
 
 		Paths: []*framework.Path{
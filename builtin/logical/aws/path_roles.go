@@ -43,6 +43,29 @@ func pathRoles() *framework.Path {
 				Type:        framework.TypeString,
 				Description: "IAM policy document",
 			},
+
+			"source_role_arns": &framework.FieldSchema{
+				Type: framework.TypeCommaStringSlice,
+				Description: `ARNs of roles to assume in order, each with the credentials
+of the previous one, before assuming the role in arn. Only used with the
+ARN of a role.`,
+			},
+
+			"session_tags": &framework.FieldSchema{
+				Type: framework.TypeMap,
+				Description: `Session tags to pass when assuming the role. Values may
+reference {{display_name}} of the requesting token, and {{identity.entity.id}},
+{{identity.entity.name}}, {{identity.entity.metadata.<key>}},
+{{identity.entity.aliases.<mount accessor>.name}} and
+{{identity.entity.aliases.<mount accessor>.metadata.<key>}} of its entity.
+Only used with the ARN of a role.`,
+			},
+
+			"transitive_tag_keys": &framework.FieldSchema{
+				Type: framework.TypeCommaStringSlice,
+				Description: `Keys of the session tags that are passed on to every role
+assumed after the first one in source_role_arns.`,
+			},
 		},
 
 		Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -72,6 +95,11 @@ func pathRolesDelete(
 		return nil, err
 	}
 
+	err = req.Storage.Delete("sts_options/" + d.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+
 	return nil, nil
 }
 
@@ -87,10 +115,15 @@ func pathRolesRead(
 
 	val := string(entry.Value)
 	if strings.HasPrefix(val, "arn:") {
+		opts, err := getSTSOptions(req.Storage, d.Get("name").(string))
+		if err != nil {
+			return nil, err
+		}
+
+		data := opts.toMap()
+		data["arn"] = val
 		return &logical.Response{
-			Data: map[string]interface{}{
-				"arn": val,
-			},
+			Data: data,
 		}, nil
 	}
 	return &logical.Response{
@@ -122,6 +155,11 @@ func pathRolesWrite(
 		return nil, err
 	}
 
+	opts, err := parseSTSOptions(d, d.Get("arn").(string))
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
 	if uip {
 		if err := json.Compact(&buf, []byte(d.Get("policy").(string))); err != nil {
 			return logical.ErrorResponse(fmt.Sprintf(
@@ -146,6 +184,10 @@ func pathRolesWrite(
 		}
 	}
 
+	if err := putSTSOptions(req.Storage, d.Get("name").(string), opts); err != nil {
+		return nil, err
+	}
+
 	return nil, nil
 }
 
@@ -170,5 +212,10 @@ reference (via the arn argument). Inline user policies written are normal
 IAM policies. Vault will not attempt to parse these except to validate
 that they're basic JSON. No validation is performed on arn references.
 
+When the arn references a role, the "sts" path assumes it. The role can be
+reached through a chain of other roles given in "source_role_arns", and
+"session_tags" can be passed to the assumed roles, with those listed in
+"transitive_tag_keys" carried through the whole chain.
+
 To validate the keys, attempt to read an access key after writing the policy.
 `
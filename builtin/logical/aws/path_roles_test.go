@@ -1,9 +1,16 @@
 package aws
 
 import (
+	"io/ioutil"
+	"net/url"
+	"reflect"
 	"strconv"
 	"testing"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
 	"github.com/hashicorp/vault/logical"
 )
 
@@ -62,3 +69,221 @@ func TestBackend_PathListRoles(t *testing.T) {
 		t.Fatalf("failed to list all 10 roles")
 	}
 }
+
+func TestBackend_roleSTSOptions(t *testing.T) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+
+	b := Backend()
+	if err := b.Setup(config); err != nil {
+		t.Fatal(err)
+	}
+
+	writeRole := func(data map[string]interface{}) *logical.Response {
+		resp, err := b.HandleRequest(&logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "roles/test",
+			Storage:   config.StorageView,
+			Data:      data,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	resp := writeRole(map[string]interface{}{
+		"arn":                 "arn:aws:iam::123456789012:role/target",
+		"source_role_arns":    "arn:aws:iam::123456789012:role/first,arn:aws:iam::123456789012:role/second",
+		"session_tags":        map[string]interface{}{"team": "payments", "vault-entity": "{{entity_id}}"},
+		"transitive_tag_keys": "team",
+	})
+	if resp != nil && resp.IsError() {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "roles/test",
+		Storage:   config.StorageView,
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: resp: %#v err: %v", resp, err)
+	}
+	expected := map[string]interface{}{
+		"arn":                 "arn:aws:iam::123456789012:role/target",
+		"source_role_arns":    []string{"arn:aws:iam::123456789012:role/first", "arn:aws:iam::123456789012:role/second"},
+		"session_tags":        map[string]string{"team": "payments", "vault-entity": "{{entity_id}}"},
+		"transitive_tag_keys": []string{"team"},
+	}
+	if !reflect.DeepEqual(resp.Data, expected) {
+		t.Fatalf("bad: expected %#v, got %#v", expected, resp.Data)
+	}
+
+	opts, err := getSTSOptions(config.StorageView, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entity := &logical.Entity{ID: "entity-id"}
+	transitive, err := opts.sessionTags(true, "token-test", entity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(transitive, []sessionTag{{Key: "team", Value: "payments"}}) {
+		t.Fatalf("bad: transitive tags %v", transitive)
+	}
+	others, err := opts.sessionTags(false, "token-test", entity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(others, []sessionTag{{Key: "vault-entity", Value: "entity-id"}}) {
+		t.Fatalf("bad: other tags %v", others)
+	}
+
+	// Tokens without an entity can't expand the entity templates
+	if _, err := opts.sessionTags(false, "token-test", nil); err == nil {
+		t.Fatalf("expected an error without an entity")
+	}
+
+	// Rewriting the role without options removes them
+	resp = writeRole(map[string]interface{}{
+		"arn": "arn:aws:iam::123456789012:role/target",
+	})
+	if resp != nil && resp.IsError() {
+		t.Fatalf("bad: %#v", resp)
+	}
+	entry, err := config.StorageView.Get("sts_options/test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry != nil {
+		t.Fatalf("expected options to be removed")
+	}
+
+	for _, data := range []map[string]interface{}{
+		{
+			"policy":       `{"Version": "2012-10-17"}`,
+			"session_tags": map[string]interface{}{"team": "payments"},
+		},
+		{
+			"arn":              "arn:aws:iam::123456789012:policy/managed",
+			"source_role_arns": "arn:aws:iam::123456789012:role/first",
+		},
+		{
+			"arn":              "arn:aws:iam::123456789012:role/target",
+			"source_role_arns": "arn:aws:iam::123456789012:policy/managed",
+		},
+		{
+			"arn":                 "arn:aws:iam::123456789012:role/target",
+			"session_tags":        map[string]interface{}{"team": "payments"},
+			"transitive_tag_keys": "project",
+		},
+		{
+			"arn":          "arn:aws:iam::123456789012:role/target",
+			"session_tags": map[string]interface{}{"team": 1},
+		},
+		{
+			"arn":          "arn:aws:iam::123456789012:role/target",
+			"session_tags": map[string]interface{}{"team": "{{identity.entity.policies}}"},
+		},
+		{
+			"arn":          "arn:aws:iam::123456789012:role/target",
+			"session_tags": map[string]interface{}{"team": "{{identity.entity.aliases.auth_userpass_1234}}"},
+		},
+	} {
+		resp = writeRole(data)
+		if resp == nil || !resp.IsError() {
+			t.Fatalf("expected an error for %#v", data)
+		}
+	}
+}
+
+func TestBackend_sessionTagTemplates(t *testing.T) {
+	opts := &stsOptions{
+		SessionTags: map[string]string{
+			"display-name": "{{display_name}}",
+			"entity-id":    "{{identity.entity.id}}",
+			"entity-name":  "{{ identity.entity.name }}",
+			"team":         "team-{{identity.entity.metadata.team}}",
+			"user":         "{{identity.entity.aliases.auth_userpass_1234.name}}",
+			"org":          "{{identity.entity.aliases.auth_userpass_1234.metadata.org.unit}}",
+		},
+	}
+	entity := &logical.Entity{
+		ID:       "entity-id",
+		Name:     "alice-entity",
+		Metadata: map[string]string{"team": "payments"},
+		Aliases: []*logical.Alias{
+			&logical.Alias{
+				MountType:     "userpass",
+				MountAccessor: "auth_userpass_1234",
+				Name:          "alice",
+				Metadata:      map[string]string{"org.unit": "finance"},
+			},
+		},
+	}
+
+	tags, err := opts.sessionTags(false, "userpass-alice", entity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []sessionTag{
+		{Key: "display-name", Value: "userpass-alice"},
+		{Key: "entity-id", Value: "entity-id"},
+		{Key: "entity-name", Value: "alice-entity"},
+		{Key: "org", Value: "finance"},
+		{Key: "team", Value: "team-payments"},
+		{Key: "user", Value: "alice"},
+	}
+	if !reflect.DeepEqual(tags, expected) {
+		t.Fatalf("bad: expected %v, got %v", expected, tags)
+	}
+
+	// Values the entity doesn't have are errors rather than empty tags
+	entity.Metadata = nil
+	if _, err := opts.sessionTags(false, "userpass-alice", entity); err == nil {
+		t.Fatalf("expected an error for missing metadata")
+	}
+}
+
+func TestBackend_addSessionTags(t *testing.T) {
+	client := sts.New(session.New(&aws.Config{
+		Region:      aws.String("us-east-1"),
+		Credentials: credentials.NewStaticCredentials("access", "secret", ""),
+	}))
+	req, _ := client.AssumeRoleRequest(&sts.AssumeRoleInput{
+		RoleArn:         aws.String("arn:aws:iam::123456789012:role/target"),
+		RoleSessionName: aws.String("vault-test"),
+	})
+	req.Handlers.Build.PushBack(addSessionTags(
+		[]sessionTag{{Key: "team", Value: "payments"}, {Key: "env", Value: "prod"}},
+		[]string{"team"},
+	))
+	if err := req.Build(); err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := ioutil.ReadAll(req.GetBody())
+	if err != nil {
+		t.Fatal(err)
+	}
+	query, err := url.ParseQuery(string(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"Action":                     "AssumeRole",
+		"RoleArn":                    "arn:aws:iam::123456789012:role/target",
+		"RoleSessionName":            "vault-test",
+		"Tags.member.1.Key":          "team",
+		"Tags.member.1.Value":        "payments",
+		"Tags.member.2.Key":          "env",
+		"Tags.member.2.Value":        "prod",
+		"TransitiveTagKeys.member.1": "team",
+	}
+	for key, value := range expected {
+		if query.Get(key) != value {
+			t.Fatalf("bad: expected %s=%s in %s", key, value, body)
+		}
+	}
+}
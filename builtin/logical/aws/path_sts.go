@@ -56,9 +56,20 @@ func (b *backend) pathSTSRead(
 	policyValue := string(policy.Value)
 	if strings.HasPrefix(policyValue, "arn:") {
 		if strings.Contains(policyValue, ":role/") {
+			opts, err := getSTSOptions(req.Storage, policyName)
+			if err != nil {
+				return nil, fmt.Errorf("error retrieving role: %s", err)
+			}
+			var entity *logical.Entity
+			if len(opts.SessionTags) > 0 && req.EntityID != "" {
+				entity, err = b.System().EntityInfo(req.EntityID)
+				if err != nil {
+					return nil, fmt.Errorf("error retrieving the entity of the token: %s", err)
+				}
+			}
 			return b.assumeRole(
 				req.Storage,
-				req.DisplayName, policyName, policyValue,
+				req.DisplayName, entity, policyName, policyValue, opts,
 				ttl,
 			)
 		} else {
@@ -85,7 +96,9 @@ accessing AWS. The IAM policy used to back this key pair will be
 the "name" parameter. For example, if this backend is mounted at "aws",
 then "aws/sts/deploy" would generate access keys for the "deploy" role.
 
-Note, these credentials are instantiated using the AWS STS backend.
+Note, these credentials are instantiated using the AWS STS backend. If the
+role assumes an IAM role through a chain of source roles, AWS limits the
+lifetime of the credentials to one hour.
 
 The access keys will have a lease associated with them. The access keys
 can be revoked by using the lease ID.
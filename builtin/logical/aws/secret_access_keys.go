@@ -9,6 +9,9 @@ import (
 	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/iam"
 	"github.com/aws/aws-sdk-go/service/sts"
 	"github.com/hashicorp/vault/logical"
@@ -110,25 +113,58 @@ func (b *backend) secretTokenCreate(s logical.Storage,
 }
 
 func (b *backend) assumeRole(s logical.Storage,
-	displayName, policyName, policy string,
+	displayName string, entity *logical.Entity, policyName, policy string, opts *stsOptions,
 	lifeTimeInSeconds int64) (*logical.Response, error) {
-	STSClient, err := clientSTS(s)
+	awsConfig, err := getRootConfig(s)
 	if err != nil {
 		return logical.ErrorResponse(err.Error()), nil
 	}
 
 	username, usernameWarning := genUsername(displayName, policyName, "iam_user")
 
-	tokenResp, err := STSClient.AssumeRole(
-		&sts.AssumeRoleInput{
+	transitiveTags, err := opts.sessionTags(true, displayName, entity)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+	otherTags, err := opts.sessionTags(false, displayName, entity)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	// Walk the chain of source roles, assuming each with the credentials of
+	// the previous one. Transitive session tags are set on the first role so
+	// that they reach the last one, which also gets the remaining tags.
+	roleARNs := append(append([]string{}, opts.SourceRoleARNs...), policy)
+	var tokenResp *sts.AssumeRoleOutput
+	for i, roleARN := range roleARNs {
+		input := &sts.AssumeRoleInput{
 			RoleSessionName: aws.String(username),
-			RoleArn:         aws.String(policy),
-			DurationSeconds: &lifeTimeInSeconds,
-		})
+			RoleArn:         aws.String(roleARN),
+		}
+		var tags []sessionTag
+		var transitiveTagKeys []string
+		if i == 0 {
+			tags = transitiveTags
+			transitiveTagKeys = opts.TransitiveTagKeys
+		}
+		if i == len(roleARNs)-1 {
+			tags = append(tags, otherTags...)
+			input.DurationSeconds = &lifeTimeInSeconds
+		}
 
-	if err != nil {
-		return logical.ErrorResponse(fmt.Sprintf(
-			"Error assuming role: %s", err)), nil
+		var req *request.Request
+		req, tokenResp = sts.New(session.New(awsConfig)).AssumeRoleRequest(input)
+		req.Handlers.Build.PushBack(addSessionTags(tags, transitiveTagKeys))
+		if err := req.Send(); err != nil {
+			return logical.ErrorResponse(fmt.Sprintf(
+				"Error assuming role %q: %s", roleARN, err)), nil
+		}
+
+		awsConfig = awsConfig.Copy().WithCredentials(credentials.NewStaticCredentials(
+			*tokenResp.Credentials.AccessKeyId,
+			*tokenResp.Credentials.SecretAccessKey,
+			*tokenResp.Credentials.SessionToken,
+		))
 	}
 
 	resp := b.Secret(SecretAccessKeyType).Response(map[string]interface{}{
@@ -0,0 +1,263 @@
+package aws
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// sessionTagTemplate matches the templates in the values of session tags
+var sessionTagTemplate = regexp.MustCompile(`\{\{\s*([^{}\s]+)\s*\}\}`)
+
+// stsOptions holds the settings of a role that assumes an IAM role which
+// don't fit in the plain policy or ARN kept at "policy/<name>"
+type stsOptions struct {
+	// SourceRoleARNs are assumed in order, each with the credentials of the
+	// previous one, before the role itself is assumed
+	SourceRoleARNs []string `json:"source_role_arns"`
+
+	// SessionTags are passed when the roles are assumed. Their values may
+	// reference the display name and the identity entity of the requesting
+	// token, see resolveTagTemplate.
+	SessionTags map[string]string `json:"session_tags"`
+
+	// TransitiveTagKeys are the keys of the session tags that carry over to
+	// every role in the chain
+	TransitiveTagKeys []string `json:"transitive_tag_keys"`
+}
+
+// sessionTag is a tag passed when assuming a role. The vendored STS client
+// doesn't know about session tags, so they are added to the query of the
+// AssumeRole requests by addSessionTags.
+type sessionTag struct {
+	Key   string
+	Value string
+}
+
+func (o *stsOptions) empty() bool {
+	return len(o.SourceRoleARNs) == 0 && len(o.SessionTags) == 0 && len(o.TransitiveTagKeys) == 0
+}
+
+func (o *stsOptions) toMap() map[string]interface{} {
+	return map[string]interface{}{
+		"source_role_arns":    o.SourceRoleARNs,
+		"session_tags":        o.SessionTags,
+		"transitive_tag_keys": o.TransitiveTagKeys,
+	}
+}
+
+// sessionTags returns the session tags to pass when assuming a role, either
+// the transitive ones or the others, with their values expanded for the
+// requesting token. The entity is nil if the token has none.
+func (o *stsOptions) sessionTags(transitive bool, displayName string, entity *logical.Entity) ([]sessionTag, error) {
+	keys := make([]string, 0, len(o.SessionTags))
+	for key := range o.SessionTags {
+		if strutil.StrListContains(o.TransitiveTagKeys, key) == transitive {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var tags []sessionTag
+	for _, key := range keys {
+		var expandErr error
+		value := sessionTagTemplate.ReplaceAllStringFunc(o.SessionTags[key], func(match string) string {
+			name := sessionTagTemplate.FindStringSubmatch(match)[1]
+			value, found, err := resolveTagTemplate(name, displayName, entity)
+			switch {
+			case err != nil:
+				expandErr = err
+			case !found && expandErr == nil:
+				expandErr = fmt.Errorf("session tag %q references %s, which the requesting token has no value for", key, name)
+			}
+			return value
+		})
+		if expandErr != nil {
+			return nil, expandErr
+		}
+		tags = append(tags, sessionTag{
+			Key:   key,
+			Value: value,
+		})
+	}
+	return tags, nil
+}
+
+// resolveTagTemplate returns the value of a template referenced by a session
+// tag, and whether the requesting token has one. The supported templates are
+// display_name, entity_id or identity.entity.id, identity.entity.name,
+// identity.entity.metadata.<key>, identity.entity.aliases.<mount accessor>.name
+// and identity.entity.aliases.<mount accessor>.metadata.<key>; an error is
+// returned for others. The entity is nil if the token has none, and roles are
+// validated by resolving their templates without one.
+func resolveTagTemplate(name, displayName string, entity *logical.Entity) (string, bool, error) {
+	switch name {
+	case "display_name":
+		return displayName, true, nil
+	case "entity_id", "identity.entity.id":
+		if entity == nil {
+			return "", false, nil
+		}
+		return entity.ID, true, nil
+	case "identity.entity.name":
+		if entity == nil {
+			return "", false, nil
+		}
+		return entity.Name, true, nil
+	}
+
+	parts := strings.SplitN(name, ".", 4)
+	if len(parts) != 4 || parts[0] != "identity" || parts[1] != "entity" {
+		return "", false, fmt.Errorf("unknown template %q", name)
+	}
+
+	switch parts[2] {
+	case "metadata":
+		if entity == nil {
+			return "", false, nil
+		}
+		value, ok := entity.Metadata[parts[3]]
+		return value, ok, nil
+
+	case "aliases":
+		aliasParts := strings.SplitN(parts[3], ".", 3)
+		valid := (len(aliasParts) == 2 && aliasParts[1] == "name") ||
+			(len(aliasParts) == 3 && aliasParts[1] == "metadata")
+		if !valid {
+			return "", false, fmt.Errorf("unknown template %q", name)
+		}
+		if entity == nil {
+			return "", false, nil
+		}
+
+		for _, alias := range entity.Aliases {
+			if alias.MountAccessor != aliasParts[0] {
+				continue
+			}
+			if aliasParts[1] == "name" {
+				return alias.Name, true, nil
+			}
+			value, ok := alias.Metadata[aliasParts[2]]
+			return value, ok, nil
+		}
+		return "", false, nil
+	}
+
+	return "", false, fmt.Errorf("unknown template %q", name)
+}
+
+// addSessionTags returns a build handler adding session tags and transitive
+// tag keys to the query of an AssumeRole request, once it has been built
+// from its input
+func addSessionTags(tags []sessionTag, transitiveTagKeys []string) func(*request.Request) {
+	return func(r *request.Request) {
+		if r.Error != nil || (len(tags) == 0 && len(transitiveTagKeys) == 0) {
+			return
+		}
+
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			r.Error = awserr.New("SerializationError", "failed reading the query of the request", err)
+			return
+		}
+		query, err := url.ParseQuery(string(body))
+		if err != nil {
+			r.Error = awserr.New("SerializationError", "failed parsing the query of the request", err)
+			return
+		}
+
+		for i, tag := range tags {
+			query.Set(fmt.Sprintf("Tags.member.%d.Key", i+1), tag.Key)
+			query.Set(fmt.Sprintf("Tags.member.%d.Value", i+1), tag.Value)
+		}
+		for i, key := range transitiveTagKeys {
+			query.Set(fmt.Sprintf("TransitiveTagKeys.member.%d", i+1), key)
+		}
+		r.SetBufferBody([]byte(query.Encode()))
+	}
+}
+
+// parseSTSOptions reads the options from the request and validates them
+// against the policy or ARN being written for the role
+func parseSTSOptions(d *framework.FieldData, policy string) (*stsOptions, error) {
+	opts := &stsOptions{
+		SourceRoleARNs:    d.Get("source_role_arns").([]string),
+		TransitiveTagKeys: d.Get("transitive_tag_keys").([]string),
+	}
+
+	rawTags := d.Get("session_tags").(map[string]interface{})
+	if len(rawTags) > 0 {
+		opts.SessionTags = make(map[string]string, len(rawTags))
+		for key, rawValue := range rawTags {
+			value, ok := rawValue.(string)
+			if !ok {
+				return nil, fmt.Errorf("session tag %q must have a string value", key)
+			}
+			opts.SessionTags[key] = value
+		}
+	}
+
+	if opts.empty() {
+		return opts, nil
+	}
+
+	if !strings.HasPrefix(policy, "arn:") || !strings.Contains(policy, ":role/") {
+		return nil, fmt.Errorf("source_role_arns, session_tags and transitive_tag_keys can only be used with the ARN of a role to assume")
+	}
+	for _, arn := range opts.SourceRoleARNs {
+		if !strings.HasPrefix(arn, "arn:") || !strings.Contains(arn, ":role/") {
+			return nil, fmt.Errorf("source role %q is not the ARN of a role", arn)
+		}
+	}
+	for key, value := range opts.SessionTags {
+		for _, match := range sessionTagTemplate.FindAllStringSubmatch(value, -1) {
+			if _, _, err := resolveTagTemplate(match[1], "", nil); err != nil {
+				return nil, fmt.Errorf("session tag %q: %v", key, err)
+			}
+		}
+	}
+	for _, key := range opts.TransitiveTagKeys {
+		if _, ok := opts.SessionTags[key]; !ok {
+			return nil, fmt.Errorf("transitive tag key %q is not a key of session_tags", key)
+		}
+	}
+
+	return opts, nil
+}
+
+func getSTSOptions(s logical.Storage, name string) (*stsOptions, error) {
+	entry, err := s.Get("sts_options/" + name)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return &stsOptions{}, nil
+	}
+
+	var opts stsOptions
+	if err := entry.DecodeJSON(&opts); err != nil {
+		return nil, err
+	}
+	return &opts, nil
+}
+
+func putSTSOptions(s logical.Storage, name string, opts *stsOptions) error {
+	if opts.empty() {
+		return s.Delete("sts_options/" + name)
+	}
+
+	entry, err := logical.StorageEntryJSON("sts_options/"+name, opts)
+	if err != nil {
+		return err
+	}
+	return s.Put(entry)
+}
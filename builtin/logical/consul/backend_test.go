@@ -2,10 +2,14 @@ package consul
 
 import (
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"log"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"reflect"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -472,3 +476,117 @@ key "" {
 	policy = "write"
 }
 `
+
+func TestBackend_aclPolicies(t *testing.T) {
+	// Stand in for the token endpoints of a Consul 1.4+ server
+	var created consulapi.ACLToken
+	var createNamespace, deletedAccessor, deleteNamespace string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "PUT" && r.URL.Path == "/v1/acl/token":
+			if err := json.NewDecoder(r.Body).Decode(&created); err != nil {
+				t.Fatal(err)
+			}
+			createNamespace = r.URL.Query().Get("ns")
+			created.AccessorID = "accessor-id"
+			created.SecretID = "secret-id"
+			json.NewEncoder(w).Encode(created)
+		case r.Method == "DELETE" && strings.HasPrefix(r.URL.Path, "/v1/acl/token/"):
+			deletedAccessor = strings.TrimPrefix(r.URL.Path, "/v1/acl/token/")
+			deleteNamespace = r.URL.Query().Get("ns")
+			w.Write([]byte("true"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+	b, err := Factory(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := &logical.Request{
+		Storage:   config.StorageView,
+		Operation: logical.UpdateOperation,
+		Path:      "config/access",
+		Data: map[string]interface{}{
+			"address": strings.TrimPrefix(ts.URL, "http://"),
+			"token":   "management",
+		},
+	}
+	if resp, err := b.HandleRequest(req); err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v err: %v", resp, err)
+	}
+
+	// Legacy rules can't be mixed with policies
+	req.Path = "roles/test"
+	req.Data = map[string]interface{}{
+		"policy":   base64.StdEncoding.EncodeToString([]byte(testPolicy)),
+		"policies": "web",
+	}
+	resp, err := b.HandleRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error mixing policy and policies")
+	}
+
+	req.Data = map[string]interface{}{
+		"policies":         "web,db",
+		"consul_roles":     "ops",
+		"consul_namespace": "team-a",
+		"local":            true,
+		"lease":            "6h",
+	}
+	if resp, err := b.HandleRequest(req); err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v err: %v", resp, err)
+	}
+
+	req.Operation = logical.ReadOperation
+	resp, err = b.HandleRequest(req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: resp: %#v err: %v", resp, err)
+	}
+	expected := map[string]interface{}{
+		"lease":            "6h0m0s",
+		"token_type":       "client",
+		"policies":         []string{"web", "db"},
+		"consul_roles":     []string{"ops"},
+		"consul_namespace": "team-a",
+		"local":            true,
+	}
+	if !reflect.DeepEqual(resp.Data, expected) {
+		t.Fatalf("bad: expected %#v, got %#v", expected, resp.Data)
+	}
+
+	req.Path = "creds/test"
+	resp, err = b.HandleRequest(req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: resp: %#v err: %v", resp, err)
+	}
+	if resp.Data["token"] != "secret-id" || resp.Data["accessor"] != "accessor-id" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if createNamespace != "team-a" || !created.Local {
+		t.Fatalf("bad: namespace %q, token %#v", createNamespace, created)
+	}
+	if len(created.Policies) != 2 || created.Policies[0].Name != "web" || created.Policies[1].Name != "db" {
+		t.Fatalf("bad: policies %#v", created.Policies)
+	}
+	if len(created.Roles) != 1 || created.Roles[0].Name != "ops" {
+		t.Fatalf("bad: roles %#v", created.Roles)
+	}
+
+	req.Operation = logical.RevokeOperation
+	req.Secret = resp.Secret
+	if _, err := b.HandleRequest(req); err != nil {
+		t.Fatal(err)
+	}
+	if deletedAccessor != "accessor-id" || deleteNamespace != "team-a" {
+		t.Fatalf("bad: deleted %q in namespace %q", deletedAccessor, deleteNamespace)
+	}
+}
@@ -31,7 +31,35 @@ func pathRoles() *framework.Path {
 			"policy": &framework.FieldSchema{
 				Type: framework.TypeString,
 				Description: `Policy document, base64 encoded. Required
-for 'client' tokens.`,
+for 'client' tokens unless policies or
+consul_roles are given.`,
+			},
+
+			"policies": &framework.FieldSchema{
+				Type: framework.TypeCommaStringSlice,
+				Description: `Names of the Consul ACL policies to attach
+to tokens. Requires Consul 1.4 or later.`,
+			},
+
+			"consul_roles": &framework.FieldSchema{
+				Type: framework.TypeCommaStringSlice,
+				Description: `Names of the Consul ACL roles to attach
+to tokens. Requires Consul 1.5 or later.`,
+			},
+
+			"consul_namespace": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `Consul Enterprise namespace to create
+tokens in. Only used with policies or
+consul_roles.`,
+			},
+
+			"local": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `Whether tokens are local to the datacenter
+of the Consul servers instead of replicated
+globally. Only used with policies or
+consul_roles.`,
 			},
 
 			"token_type": &framework.FieldSchema{
@@ -98,6 +126,12 @@ func pathRolesRead(
 	if result.Policy != "" {
 		resp.Data["policy"] = base64.StdEncoding.EncodeToString([]byte(result.Policy))
 	}
+	if result.usesPolicies() {
+		resp.Data["policies"] = result.Policies
+		resp.Data["consul_roles"] = result.ConsulRoles
+		resp.Data["consul_namespace"] = result.ConsulNamespace
+		resp.Data["local"] = result.Local
+	}
 	return resp, nil
 }
 
@@ -115,9 +149,27 @@ func pathRolesWrite(
 
 	name := d.Get("name").(string)
 	policy := d.Get("policy").(string)
+	policies := d.Get("policies").([]string)
+	consulRoles := d.Get("consul_roles").([]string)
+	consulNamespace := d.Get("consul_namespace").(string)
+	local := d.Get("local").(bool)
+
+	usesPolicies := len(policies) > 0 || len(consulRoles) > 0
+	switch {
+	case usesPolicies && tokenType != "client":
+		return logical.ErrorResponse(
+			"policies and consul_roles can only be used with \"client\" tokens"), nil
+	case usesPolicies && policy != "":
+		return logical.ErrorResponse(
+			"policy cannot be used together with policies or consul_roles"), nil
+	case !usesPolicies && (consulNamespace != "" || local):
+		return logical.ErrorResponse(
+			"consul_namespace and local can only be used with policies or consul_roles"), nil
+	}
+
 	var policyRaw []byte
 	var err error
-	if tokenType != "management" {
+	if tokenType != "management" && !usesPolicies {
 		if policy == "" {
 			return logical.ErrorResponse(
 				"policy cannot be empty when not using management tokens"), nil
@@ -140,9 +192,13 @@ func pathRolesWrite(
 	}
 
 	entry, err := logical.StorageEntryJSON("policy/"+name, roleConfig{
-		Policy:    string(policyRaw),
-		Lease:     lease,
-		TokenType: tokenType,
+		Policy:          string(policyRaw),
+		Policies:        policies,
+		ConsulRoles:     consulRoles,
+		ConsulNamespace: consulNamespace,
+		Local:           local,
+		Lease:           lease,
+		TokenType:       tokenType,
 	})
 	if err != nil {
 		return nil, err
@@ -168,4 +224,17 @@ type roleConfig struct {
 	Policy    string        `json:"policy"`
 	Lease     time.Duration `json:"lease"`
 	TokenType string        `json:"token_type"`
+
+	// Policies, ConsulRoles, ConsulNamespace and Local describe tokens of the
+	// ACL system of Consul 1.4 and later, used instead of the legacy Policy
+	Policies        []string `json:"policies"`
+	ConsulRoles     []string `json:"consul_roles"`
+	ConsulNamespace string   `json:"consul_namespace"`
+	Local           bool     `json:"local"`
+}
+
+// usesPolicies returns whether tokens for the role are bound to ACL policies
+// and roles instead of legacy rules
+func (r *roleConfig) usesPolicies() bool {
+	return len(r.Policies) > 0 || len(r.ConsulRoles) > 0
 }
@@ -58,7 +58,39 @@ func (b *backend) pathTokenRead(
 	// Generate a name for the token
 	tokenName := fmt.Sprintf("Vault %s %s %d", name, req.DisplayName, time.Now().UnixNano())
 
-	// Create it
+	if result.usesPolicies() {
+		token := &api.ACLToken{
+			Description: tokenName,
+			Local:       result.Local,
+		}
+		for _, policy := range result.Policies {
+			token.Policies = append(token.Policies, &api.ACLLink{Name: policy})
+		}
+		for _, role := range result.ConsulRoles {
+			token.Roles = append(token.Roles, &api.ACLLink{Name: role})
+		}
+
+		created, _, err := c.ACL().TokenCreate(token, &api.WriteOptions{
+			Namespace: result.ConsulNamespace,
+		})
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+
+		s := b.Secret(SecretTokenType).Response(map[string]interface{}{
+			"token":    created.SecretID,
+			"accessor": created.AccessorID,
+		}, map[string]interface{}{
+			"token":     created.SecretID,
+			"accessor":  created.AccessorID,
+			"namespace": result.ConsulNamespace,
+		})
+		s.Secret.TTL = result.Lease
+
+		return s, nil
+	}
+
+	// Create a legacy token
 	token, _, err := c.ACL().Create(&api.ACLEntry{
 		Name:  tokenName,
 		Type:  result.TokenType,
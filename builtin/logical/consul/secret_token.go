@@ -1,6 +1,7 @@
 package consul
 
 import (
+	"github.com/hashicorp/consul/api"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
 )
@@ -17,6 +18,11 @@ func secretToken(b *backend) *framework.Secret {
 				Type:        framework.TypeString,
 				Description: "Request token",
 			},
+
+			"accessor": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Accessor of tokens bound to ACL policies",
+			},
 		},
 
 		Renew:  b.secretTokenRenew,
@@ -50,6 +56,23 @@ func secretTokenRevoke(
 		return nil, nil
 	}
 
+	// Tokens bound to ACL policies are deleted by their accessor, from the
+	// namespace they were created in
+	if accessorRaw, ok := req.Secret.InternalData["accessor"]; ok {
+		var namespace string
+		if namespaceRaw, ok := req.Secret.InternalData["namespace"]; ok {
+			namespace, _ = namespaceRaw.(string)
+		}
+
+		_, err := c.ACL().TokenDelete(accessorRaw.(string), &api.WriteOptions{
+			Namespace: namespace,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}
+
 	_, err := c.ACL().Destroy(tokenRaw.(string), nil)
 	if err != nil {
 		return nil, err
@@ -5,10 +5,13 @@ import (
 	"net/rpc"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/hashicorp/go-multierror"
 	log "github.com/mgutz/logxi/v1"
 
 	"github.com/hashicorp/vault/builtin/logical/database/dbplugin"
+	"github.com/hashicorp/vault/helper/pluginutil"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
 )
@@ -28,6 +31,12 @@ func Backend(conf *logical.BackendConfig) *databaseBackend {
 	b.Backend = &framework.Backend{
 		Help: strings.TrimSpace(backendHelp),
 
+		PathsSpecial: &logical.Paths{
+			ExportableStorage: []string{
+				"role/",
+			},
+		},
+
 		Paths: []*framework.Path{
 			pathListPluginConnection(&b),
 			pathConfigurePluginConnection(&b),
@@ -35,18 +44,27 @@ func Backend(conf *logical.BackendConfig) *databaseBackend {
 			pathRoles(&b),
 			pathCredsCreate(&b),
 			pathResetConnection(&b),
+			pathRotateRootCredentials(&b),
+			pathListStaticRoles(&b),
+			pathStaticRoles(&b),
+			pathStaticCreds(&b),
+			pathRotateRole(&b),
 		},
 
 		Secrets: []*framework.Secret{
 			secretCreds(&b),
 		},
-		Clean:       b.closeAllDBs,
-		Invalidate:  b.invalidate,
-		BackendType: logical.TypeLogical,
+		Clean:             b.closeAllDBs,
+		Invalidate:        b.invalidate,
+		PeriodicFunc:      b.periodicFunc,
+		WALRollback:       b.walRollback,
+		WALRollbackMinAge: time.Minute,
+		BackendType:       logical.TypeLogical,
 	}
 
 	b.logger = conf.Logger
 	b.connections = make(map[string]dbplugin.Database)
+	b.monitors = make(map[string]*connectionMonitor)
 	return &b
 }
 
@@ -54,10 +72,33 @@ type databaseBackend struct {
 	connections map[string]dbplugin.Database
 	logger      log.Logger
 
+	// monitors holds the health monitoring of the connections served by
+	// external plugins, keyed by connection name
+	monitors map[string]*connectionMonitor
+
+	// staticRotationLock serializes the password rotations of static roles
+	staticRotationLock sync.Mutex
+
+	// roleLock serializes the writes and patches of roles
+	roleLock sync.Mutex
+
 	*framework.Backend
 	sync.RWMutex
 }
 
+// periodicFunc removes the users whose revocation grace period has passed
+// and rotates the passwords of static roles that are due
+func (b *databaseBackend) periodicFunc(req *logical.Request) error {
+	var result error
+	if err := b.revokePendingUsers(req); err != nil {
+		result = multierror.Append(result, err)
+	}
+	if err := b.rotateStaticRoles(req); err != nil {
+		result = multierror.Append(result, err)
+	}
+	return result
+}
+
 // closeAllDBs closes all connections from all database types
 func (b *databaseBackend) closeAllDBs() {
 	b.Lock()
@@ -66,8 +107,12 @@ func (b *databaseBackend) closeAllDBs() {
 	for _, db := range b.connections {
 		db.Close()
 	}
+	for _, m := range b.monitors {
+		m.stop()
+	}
 
 	b.connections = make(map[string]dbplugin.Database)
+	b.monitors = make(map[string]*connectionMonitor)
 }
 
 // This function is used to retrieve a database object either from the cached
@@ -103,10 +148,76 @@ func (b *databaseBackend) createDBObj(s logical.Storage, name string) (dbplugin.
 	}
 
 	b.connections[name] = db
+	b.monitorConnection(s, name, config.PluginName, db)
 
 	return db, nil
 }
 
+// connectionMonitor is the health monitoring of the plugin process of a
+// connection
+type connectionMonitor struct {
+	stop func()
+}
+
+// monitorConnection registers the plugin process of the connection with the
+// health monitor of the core, which restarts it if it crashes. The caller of
+// this function needs to hold the backend's write lock.
+func (b *databaseBackend) monitorConnection(s logical.Storage, name, pluginName string, db dbplugin.Database) {
+	if _, ok := b.monitors[name]; ok {
+		return
+	}
+	if _, ok := dbplugin.PluginClient(db); !ok {
+		return
+	}
+	monitor, ok := b.System().(pluginutil.HealthMonitor)
+	if !ok {
+		return
+	}
+
+	m := &connectionMonitor{}
+	m.stop = monitor.MonitorPlugin(&pluginutil.MonitoredPlugin{
+		Name:     pluginName,
+		Instance: name,
+		Ping: func() error {
+			b.RLock()
+			db, ok := b.connections[name]
+			b.RUnlock()
+			if !ok {
+				return nil
+			}
+			client, ok := dbplugin.PluginClient(db)
+			if !ok {
+				return nil
+			}
+			return client.Ping()
+		},
+		Restart: func() error {
+			return b.restartConnection(s, name, m)
+		},
+	})
+	b.monitors[name] = m
+}
+
+// restartConnection replaces the plugin process of a connection on behalf of
+// its health monitor
+func (b *databaseBackend) restartConnection(s logical.Storage, name string, m *connectionMonitor) error {
+	b.Lock()
+	defer b.Unlock()
+
+	// The connection was closed while the restart was pending
+	if b.monitors[name] != m {
+		return nil
+	}
+
+	if db, ok := b.connections[name]; ok {
+		db.Close()
+		delete(b.connections, name)
+	}
+
+	_, err := b.createDBObj(s, name)
+	return err
+}
+
 func (b *databaseBackend) DatabaseConfig(s logical.Storage, name string) (*DatabaseConfig, error) {
 	entry, err := s.Get(fmt.Sprintf("config/%s", name))
 	if err != nil {
@@ -160,6 +271,10 @@ func (b *databaseBackend) clearConnection(name string) {
 		db.Close()
 		delete(b.connections, name)
 	}
+	if m, ok := b.monitors[name]; ok {
+		m.stop()
+		delete(b.monitors, name)
+	}
 }
 
 func (b *databaseBackend) closeIfShutdown(name string, err error) {
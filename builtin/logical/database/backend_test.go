@@ -2,17 +2,20 @@ package database
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"reflect"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/vault/builtin/logical/database/dbplugin"
 	"github.com/hashicorp/vault/helper/pluginutil"
 	vaulthttp "github.com/hashicorp/vault/http"
 	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
 	"github.com/hashicorp/vault/plugins/database/postgresql"
 	"github.com/hashicorp/vault/vault"
 	"github.com/lib/pq"
@@ -155,7 +158,8 @@ func TestBackend_config_connection(t *testing.T) {
 		"connection_details": map[string]interface{}{
 			"connection_url": "sample_connection_url",
 		},
-		"allowed_roles": []string{"*"},
+		"allowed_roles":            []string{"*"},
+		"root_rotation_statements": "",
 	}
 	configReq.Operation = logical.ReadOperation
 	resp, err = b.HandleRequest(configReq)
@@ -751,3 +755,463 @@ REVOKE USAGE ON SCHEMA public FROM {{name}};
 
 DROP ROLE IF EXISTS {{name}};
 `
+
+// testGraceDB is a database that records the statements run for each user
+type testGraceDB struct {
+	sync.Mutex
+	users     map[string][]string
+	passwords map[string]string
+
+	// setErr is returned by SetCredentials after the password is set
+	setErr error
+}
+
+func (d *testGraceDB) Type() (string, error) { return "test", nil }
+
+func (d *testGraceDB) CreateUser(statements dbplugin.Statements, usernameConfig dbplugin.UsernameConfig, expiration time.Time) (string, string, error) {
+	d.Lock()
+	defer d.Unlock()
+	username := fmt.Sprintf("v-%s-%d", usernameConfig.RoleName, len(d.users))
+	d.users[username] = []string{statements.CreationStatements}
+	return username, "password", nil
+}
+
+func (d *testGraceDB) RenewUser(statements dbplugin.Statements, username string, expiration time.Time) error {
+	return nil
+}
+
+func (d *testGraceDB) RevokeUser(statements dbplugin.Statements, username string) error {
+	d.Lock()
+	defer d.Unlock()
+	d.users[username] = append(d.users[username], statements.RevocationStatements)
+	return nil
+}
+
+func (d *testGraceDB) SetCredentials(statements dbplugin.Statements, username, password string) error {
+	d.Lock()
+	defer d.Unlock()
+	if username == "missing" {
+		return fmt.Errorf("user %q does not exist", username)
+	}
+	d.users[username] = append(d.users[username], statements.RotationStatements)
+	if d.passwords == nil {
+		d.passwords = make(map[string]string)
+	}
+	d.passwords[username] = password
+	return d.setErr
+}
+
+func (d *testGraceDB) RotateRootCredentials(statements dbplugin.Statements) (map[string]interface{}, error) {
+	d.Lock()
+	defer d.Unlock()
+	d.users["root"] = append(d.users["root"], statements.RotationStatements)
+	return map[string]interface{}{
+		"username": "root",
+		"password": fmt.Sprintf("password-%d", len(d.users["root"])),
+	}, nil
+}
+
+func (d *testGraceDB) password(username string) string {
+	d.Lock()
+	defer d.Unlock()
+	return d.passwords[username]
+}
+
+func (d *testGraceDB) statements(username string) []string {
+	d.Lock()
+	defer d.Unlock()
+	return d.users[username]
+}
+
+func (d *testGraceDB) Initialize(config map[string]interface{}, verifyConnection bool) error {
+	return nil
+}
+
+func (d *testGraceDB) Close() error { return nil }
+
+func TestBackend_revocationGrace(t *testing.T) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+	b := Backend(config)
+	if err := b.Setup(config); err != nil {
+		t.Fatal(err)
+	}
+
+	db := &testGraceDB{users: make(map[string][]string)}
+	b.connections["test"] = db
+	entry, err := logical.StorageEntryJSON("config/test", &DatabaseConfig{
+		PluginName:   "test",
+		AllowedRoles: []string{"*"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := config.StorageView.Put(entry); err != nil {
+		t.Fatal(err)
+	}
+
+	writeRole := func(name string, data map[string]interface{}) *logical.Response {
+		data["db_name"] = "test"
+		data["creation_statements"] = "create"
+		data["revocation_statements"] = "revoke"
+		resp, err := b.HandleRequest(&logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "roles/" + name,
+			Storage:   config.StorageView,
+			Data:      data,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+	issueAndRevoke := func(role string) string {
+		resp, err := b.HandleRequest(&logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "creds/" + role,
+			Storage:   config.StorageView,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("err:%s resp:%#v\n", err, resp)
+		}
+		username := resp.Data["username"].(string)
+
+		resp, err = b.HandleRequest(&logical.Request{
+			Operation: logical.RevokeOperation,
+			Storage:   config.StorageView,
+			Secret:    resp.Secret,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("err:%s resp:%#v\n", err, resp)
+		}
+		return username
+	}
+
+	// A grace period needs statements to disable users with
+	resp := writeRole("grace", map[string]interface{}{
+		"revocation_grace": "1h",
+	})
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected error, got %#v", resp)
+	}
+	if resp := writeRole("grace", map[string]interface{}{
+		"revocation_grace":   "1h",
+		"disable_statements": "disable",
+	}); resp != nil && resp.IsError() {
+		t.Fatalf("bad: %#v", resp)
+	}
+	if resp := writeRole("plain", map[string]interface{}{}); resp != nil && resp.IsError() {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// Without a grace period users are removed right away
+	plain := issueAndRevoke("plain")
+	if actual := db.statements(plain); !reflect.DeepEqual(actual, []string{"create", "revoke"}) {
+		t.Fatalf("bad: %v", actual)
+	}
+
+	// With one they are only disabled until it passes
+	grace := issueAndRevoke("grace")
+	if actual := db.statements(grace); !reflect.DeepEqual(actual, []string{"create", "disable"}) {
+		t.Fatalf("bad: %v", actual)
+	}
+	periodicReq := &logical.Request{Storage: config.StorageView}
+	if err := b.revokePendingUsers(periodicReq); err != nil {
+		t.Fatal(err)
+	}
+	if actual := db.statements(grace); !reflect.DeepEqual(actual, []string{"create", "disable"}) {
+		t.Fatalf("bad: %v", actual)
+	}
+
+	// Move the end of the grace period into the past
+	entry, err = config.StorageView.Get(pendingRevocationPath + grace)
+	if err != nil || entry == nil {
+		t.Fatalf("pending revocation not stored: %v", err)
+	}
+	var pending pendingRevocation
+	if err := entry.DecodeJSON(&pending); err != nil {
+		t.Fatal(err)
+	}
+	pending.RevokeTime = time.Now().Add(-time.Second)
+	if entry, err = logical.StorageEntryJSON(pendingRevocationPath+grace, &pending); err != nil {
+		t.Fatal(err)
+	}
+	if err := config.StorageView.Put(entry); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.revokePendingUsers(periodicReq); err != nil {
+		t.Fatal(err)
+	}
+	if actual := db.statements(grace); !reflect.DeepEqual(actual, []string{"create", "disable", "revoke"}) {
+		t.Fatalf("bad: %v", actual)
+	}
+	if entry, err := config.StorageView.Get(pendingRevocationPath + grace); err != nil || entry != nil {
+		t.Fatalf("pending revocation not removed: %v %#v", err, entry)
+	}
+}
+
+func TestBackend_staticRoles(t *testing.T) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+	b := Backend(config)
+	if err := b.Setup(config); err != nil {
+		t.Fatal(err)
+	}
+
+	db := &testGraceDB{users: make(map[string][]string)}
+	b.connections["test"] = db
+	entry, err := logical.StorageEntryJSON("config/test", &DatabaseConfig{
+		PluginName:   "test",
+		AllowedRoles: []string{"app", "missing"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := config.StorageView.Put(entry); err != nil {
+		t.Fatal(err)
+	}
+
+	request := func(op logical.Operation, path string, data map[string]interface{}) (*logical.Response, error) {
+		return b.HandleRequest(&logical.Request{
+			Operation: op,
+			Path:      path,
+			Storage:   config.StorageView,
+			Data:      data,
+		})
+	}
+	readCreds := func(name string) map[string]interface{} {
+		resp, err := request(logical.ReadOperation, "static-creds/"+name, nil)
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("err:%s resp:%#v\n", err, resp)
+		}
+		return resp.Data
+	}
+
+	// Invalid roles are rejected
+	for name, data := range map[string]map[string]interface{}{
+		"app":   {"db_name": "test", "username": "app-user", "rotation_period": "1s"},
+		"other": {"db_name": "test", "username": "other-user", "rotation_period": "1h"},
+	} {
+		resp, err := request(logical.CreateOperation, "static-roles/"+name, data)
+		if err == nil && (resp == nil || !resp.IsError()) {
+			t.Fatalf("expected %q to be rejected, got %#v", name, resp)
+		}
+	}
+
+	// A user that cannot be rotated fails the creation of the role
+	resp, err := request(logical.CreateOperation, "static-roles/missing", map[string]interface{}{
+		"db_name":         "test",
+		"username":        "missing",
+		"rotation_period": "1h",
+	})
+	if err == nil {
+		t.Fatalf("expected error, got %#v", resp)
+	}
+	if role, err := b.StaticRole(config.StorageView, "missing"); err != nil || role != nil {
+		t.Fatalf("failed role stored: %v %#v", err, role)
+	}
+
+	// Creating the role takes over the password right away
+	resp, err = request(logical.CreateOperation, "static-roles/app", map[string]interface{}{
+		"db_name":             "test",
+		"username":            "app-user",
+		"rotation_period":     "1h",
+		"rotation_statements": "rotate",
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%s resp:%#v\n", err, resp)
+	}
+	if actual := db.statements("app-user"); !reflect.DeepEqual(actual, []string{"rotate"}) {
+		t.Fatalf("bad: %v", actual)
+	}
+	creds := readCreds("app")
+	password := db.password("app-user")
+	if creds["username"] != "app-user" || password == "" || creds["password"] != password {
+		t.Fatalf("bad: %#v", creds)
+	}
+	if ttl := creds["ttl"].(int64); ttl <= 3500 || ttl > 3600 {
+		t.Fatalf("bad ttl: %d", ttl)
+	}
+
+	// The managed user cannot be changed
+	resp, err = request(logical.UpdateOperation, "static-roles/app", map[string]interface{}{
+		"username": "someone-else",
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected error, got %#v", resp)
+	}
+
+	// Updating the role does not rotate the password
+	resp, err = request(logical.UpdateOperation, "static-roles/app", map[string]interface{}{
+		"rotation_period": "2h",
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%s resp:%#v\n", err, resp)
+	}
+	resp, err = request(logical.ReadOperation, "static-roles/app", nil)
+	if err != nil || resp == nil {
+		t.Fatalf("err:%s resp:%#v\n", err, resp)
+	}
+	if resp.Data["username"] != "app-user" || resp.Data["rotation_period"] != float64(7200) || resp.Data["password"] != nil {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if creds := readCreds("app"); creds["password"] != password {
+		t.Fatalf("bad: %#v", creds)
+	}
+
+	// Rotations are only done by the periodic function once they are due
+	periodicReq := &logical.Request{Storage: config.StorageView}
+	if err := b.periodicFunc(periodicReq); err != nil {
+		t.Fatal(err)
+	}
+	if creds := readCreds("app"); creds["password"] != password {
+		t.Fatalf("bad: %#v", creds)
+	}
+
+	role, err := b.StaticRole(config.StorageView, "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	role.LastVaultRotation = time.Now().Add(-3 * time.Hour)
+	if err := b.putStaticRole(config.StorageView, "app", role); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.periodicFunc(periodicReq); err != nil {
+		t.Fatal(err)
+	}
+	creds = readCreds("app")
+	if creds["password"] == password || creds["password"] != db.password("app-user") {
+		t.Fatalf("bad: %#v", creds)
+	}
+	lastRotation, err := time.Parse(time.RFC3339, creds["last_vault_rotation"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(lastRotation) > time.Minute {
+		t.Fatalf("bad last rotation: %s", lastRotation)
+	}
+
+	// And can be triggered manually
+	resp, err = request(logical.UpdateOperation, "rotate-role/app", nil)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%s resp:%#v\n", err, resp)
+	}
+	if creds := readCreds("app"); creds["password"] == password || creds["password"] != db.password("app-user") {
+		t.Fatalf("bad: %#v", creds)
+	}
+
+	// A password the database accepted is kept even if the rotation fails
+	// before the role is stored, by the WAL entry written before it
+	password = db.password("app-user")
+	db.setErr = errors.New("connection lost")
+	resp, err = request(logical.UpdateOperation, "rotate-role/app", nil)
+	if err == nil {
+		t.Fatalf("expected error, got %#v", resp)
+	}
+	db.setErr = nil
+	if creds := readCreds("app"); creds["password"] != password || db.password("app-user") == password {
+		t.Fatalf("bad: %#v", creds)
+	}
+	resp, err = request(logical.RollbackOperation, "", map[string]interface{}{
+		"immediate": true,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%s resp:%#v\n", err, resp)
+	}
+	if creds := readCreds("app"); creds["password"] == password || creds["password"] != db.password("app-user") {
+		t.Fatalf("bad: %#v", creds)
+	}
+	if keys, err := framework.ListWAL(config.StorageView); err != nil || len(keys) != 0 {
+		t.Fatalf("WAL entries left: %v %v", err, keys)
+	}
+
+	resp, err = request(logical.ListOperation, "static-roles/", nil)
+	if err != nil || resp == nil || !reflect.DeepEqual(resp.Data["keys"], []string{"app"}) {
+		t.Fatalf("err:%s resp:%#v\n", err, resp)
+	}
+
+	// Deleting the role leaves the user alone
+	if _, err := request(logical.DeleteOperation, "static-roles/app", nil); err != nil {
+		t.Fatal(err)
+	}
+	resp, err = request(logical.ReadOperation, "static-creds/app", nil)
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected error, got %#v", resp)
+	}
+	if actual := db.statements("app-user"); len(actual) != 5 {
+		t.Fatalf("bad: %v", actual)
+	}
+}
+
+func TestBackend_rotateRootCredentials(t *testing.T) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+	b := Backend(config)
+	if err := b.Setup(config); err != nil {
+		t.Fatal(err)
+	}
+
+	db := &testGraceDB{users: make(map[string][]string)}
+	b.connections["test"] = db
+	entry, err := logical.StorageEntryJSON("config/test", &DatabaseConfig{
+		PluginName: "test",
+		ConnectionDetails: map[string]interface{}{
+			"connection_url": "{{username}}:{{password}}@localhost",
+			"username":       "root",
+			"password":       "bootstrap",
+		},
+		RootRotationStatements: "rotate-root",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := config.StorageView.Put(entry); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "rotate-root/test",
+		Storage:   config.StorageView,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%s resp:%#v\n", err, resp)
+	}
+	if actual := db.statements("root"); !reflect.DeepEqual(actual, []string{"rotate-root"}) {
+		t.Fatalf("bad: %v", actual)
+	}
+
+	// The new password is stored, so that the connection keeps working
+	dbConfig, err := b.DatabaseConfig(config.StorageView, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dbConfig.ConnectionDetails["password"] != "password-1" || dbConfig.RootRotationStatements != "rotate-root" {
+		t.Fatalf("bad: %#v", dbConfig)
+	}
+
+	// But is not returned
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "config/test",
+		Storage:   config.StorageView,
+	})
+	if err != nil || resp == nil {
+		t.Fatalf("err:%s resp:%#v\n", err, resp)
+	}
+	details := resp.Data["connection_details"].(map[string]interface{})
+	if _, ok := details["password"]; ok || details["username"] != "root" {
+		t.Fatalf("bad: %#v", details)
+	}
+
+	// Unknown connections cannot be rotated
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "rotate-root/unknown",
+		Storage:   config.StorageView,
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected error, got %#v", resp)
+	}
+}
@@ -14,7 +14,8 @@ import (
 // DatabasePluginClient embeds a databasePluginRPCClient and wraps it's Close
 // method to also call Kill() on the plugin.Client.
 type DatabasePluginClient struct {
-	client *plugin.Client
+	client   *plugin.Client
+	protocol plugin.ClientProtocol
 	sync.Mutex
 
 	*databasePluginRPCClient
@@ -27,6 +28,11 @@ func (dc *DatabasePluginClient) Close() error {
 	return err
 }
 
+// Ping checks that the plugin process is running and answers
+func (dc *DatabasePluginClient) Ping() error {
+	return pluginutil.PingPlugin(dc.client, dc.protocol)
+}
+
 // newPluginClient returns a databaseRPCClient with a connection to a running
 // plugin. The client is wrapped in a DatabasePluginClient object to ensure the
 // plugin is killed on call of Close().
@@ -60,6 +66,7 @@ func newPluginClient(sys pluginutil.RunnerUtil, pluginRunner *pluginutil.PluginR
 	// Wrap RPC implimentation in DatabasePluginClient
 	return &DatabasePluginClient{
 		client:                  client,
+		protocol:                rpcClient,
 		databasePluginRPCClient: databaseRPC,
 	}, nil
 }
@@ -115,6 +122,29 @@ func (dr *databasePluginRPCClient) RevokeUser(statements Statements, username st
 	return err
 }
 
+func (dr *databasePluginRPCClient) SetCredentials(statements Statements, username, password string) error {
+	req := SetCredentialsRequest{
+		Statements: statements,
+		Username:   username,
+		Password:   password,
+	}
+
+	err := dr.client.Call("Plugin.SetCredentials", req, &struct{}{})
+
+	return err
+}
+
+func (dr *databasePluginRPCClient) RotateRootCredentials(statements Statements) (config map[string]interface{}, err error) {
+	req := RotateRootCredentialsRequest{
+		Statements: statements,
+	}
+
+	var resp RotateRootCredentialsResponse
+	err = dr.client.Call("Plugin.RotateRootCredentials", req, &resp)
+
+	return resp.Config, err
+}
+
 func (dr *databasePluginRPCClient) Initialize(conf map[string]interface{}, verifyConnection bool) error {
 	req := InitializeRequest{
 		Config:           conf,
@@ -16,6 +16,8 @@ type Database interface {
 	CreateUser(statements Statements, usernameConfig UsernameConfig, expiration time.Time) (username string, password string, err error)
 	RenewUser(statements Statements, username string, expiration time.Time) error
 	RevokeUser(statements Statements, username string) error
+	SetCredentials(statements Statements, username, password string) error
+	RotateRootCredentials(statements Statements) (config map[string]interface{}, err error)
 
 	Initialize(config map[string]interface{}, verifyConnection bool) error
 	Close() error
@@ -27,6 +29,7 @@ type Statements struct {
 	RevocationStatements string `json:"revocation_statements" mapstructure:"revocation_statements" structs:"revocation_statements"`
 	RollbackStatements   string `json:"rollback_statements" mapstructure:"rollback_statements" structs:"rollback_statements"`
 	RenewStatements      string `json:"renew_statements" mapstructure:"renew_statements" structs:"renew_statements"`
+	RotationStatements   string `json:"rotation_statements" mapstructure:"rotation_statements" structs:"rotation_statements"`
 }
 
 // UsernameConfig is used to configure prefixes for the username to be
@@ -91,6 +94,24 @@ func PluginFactory(pluginName string, sys pluginutil.LookRunnerUtil, logger log.
 	return db, nil
 }
 
+// PluginClient returns the client of the plugin process running the
+// database, unwrapping the middlewares. It returns false for the databases
+// built into Vault.
+func PluginClient(db Database) (*DatabasePluginClient, bool) {
+	for {
+		switch d := db.(type) {
+		case *databaseMetricsMiddleware:
+			db = d.next
+		case *databaseTracingMiddleware:
+			db = d.next
+		case *DatabasePluginClient:
+			return d, true
+		default:
+			return nil, false
+		}
+	}
+}
+
 // handshakeConfigs are used to just do a basic handshake between
 // a plugin and host. If the handshake fails, a user friendly error is shown.
 // This prevents users from executing bad plugins or executing a plugin
@@ -139,9 +160,23 @@ type RevokeUserRequest struct {
 	Username   string
 }
 
+type SetCredentialsRequest struct {
+	Statements Statements
+	Username   string
+	Password   string
+}
+
+type RotateRootCredentialsRequest struct {
+	Statements Statements
+}
+
 // ---- RPC Response Args Domain ----
 
 type CreateUserResponse struct {
 	Username string
 	Password string
 }
+
+type RotateRootCredentialsResponse struct {
+	Config map[string]interface{}
+}
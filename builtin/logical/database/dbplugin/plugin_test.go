@@ -16,7 +16,8 @@ import (
 )
 
 type mockPlugin struct {
-	users map[string][]string
+	users  map[string][]string
+	config map[string]interface{}
 }
 
 func (m *mockPlugin) Type() (string, error) { return "mock", nil }
@@ -59,12 +60,34 @@ func (m *mockPlugin) RevokeUser(statements dbplugin.Statements, username string)
 	delete(m.users, username)
 	return nil
 }
+func (m *mockPlugin) SetCredentials(statements dbplugin.Statements, username, password string) error {
+	err := errors.New("err")
+	if username == "" {
+		return err
+	}
+
+	if _, ok := m.users[username]; !ok {
+		return err
+	}
+
+	m.users[username] = append(m.users[username], password)
+	return nil
+}
+func (m *mockPlugin) RotateRootCredentials(statements dbplugin.Statements) (config map[string]interface{}, err error) {
+	if m.config == nil {
+		return nil, errors.New("err")
+	}
+
+	m.config["test"] = m.config["test"].(int) + 1
+	return m.config, nil
+}
 func (m *mockPlugin) Initialize(conf map[string]interface{}, _ bool) error {
 	err := errors.New("err")
 	if len(conf) != 1 {
 		return err
 	}
 
+	m.config = conf
 	return nil
 }
 func (m *mockPlugin) Close() error {
@@ -243,3 +266,93 @@ func TestPlugin_RevokeUser(t *testing.T) {
 		t.Fatalf("err: %s", err)
 	}
 }
+
+func TestPlugin_SetCredentials(t *testing.T) {
+	cluster, sys := getCluster(t)
+	defer cluster.Cleanup()
+
+	db, err := dbplugin.PluginFactory("test-plugin", sys, &log.NullLogger{})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer db.Close()
+
+	connectionDetails := map[string]interface{}{
+		"test": 1,
+	}
+	err = db.Initialize(connectionDetails, true)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	usernameConf := dbplugin.UsernameConfig{
+		DisplayName: "test",
+		RoleName:    "test",
+	}
+
+	us, _, err := db.CreateUser(dbplugin.Statements{}, usernameConf, time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if err := db.SetCredentials(dbplugin.Statements{}, us, "new-password"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Unknown users cannot be rotated
+	err = db.SetCredentials(dbplugin.Statements{}, "unknown", "new-password")
+	if err == nil {
+		t.Fatal("expected an error rotating an unknown user")
+	}
+}
+
+func TestPlugin_RotateRootCredentials(t *testing.T) {
+	cluster, sys := getCluster(t)
+	defer cluster.Cleanup()
+
+	db, err := dbplugin.PluginFactory("test-plugin", sys, &log.NullLogger{})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer db.Close()
+
+	connectionDetails := map[string]interface{}{
+		"test": 1,
+	}
+	err = db.Initialize(connectionDetails, true)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	config, err := db.RotateRootCredentials(dbplugin.Statements{})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if config["test"] != 2 {
+		t.Fatalf("expected the updated config, got %#v", config)
+	}
+}
+
+func TestPlugin_Ping(t *testing.T) {
+	cluster, sys := getCluster(t)
+	defer cluster.Cleanup()
+
+	db, err := dbplugin.PluginFactory("test-plugin", sys, &log.NullLogger{})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	client, ok := dbplugin.PluginClient(db)
+	if !ok {
+		t.Fatal("expected the database to run in a plugin process")
+	}
+	if err := client.Ping(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The process is killed once the database is closed
+	db.Close()
+	if err := client.Ping(); err == nil {
+		t.Fatal("expected an error probing a closed plugin")
+	}
+}
@@ -59,6 +59,19 @@ func (ds *databasePluginRPCServer) RevokeUser(args *RevokeUserRequest, _ *struct
 	return err
 }
 
+func (ds *databasePluginRPCServer) SetCredentials(args *SetCredentialsRequest, _ *struct{}) error {
+	err := ds.impl.SetCredentials(args.Statements, args.Username, args.Password)
+
+	return err
+}
+
+func (ds *databasePluginRPCServer) RotateRootCredentials(args *RotateRootCredentialsRequest, resp *RotateRootCredentialsResponse) error {
+	var err error
+	resp.Config, err = ds.impl.RotateRootCredentials(args.Statements)
+
+	return err
+}
+
 func (ds *databasePluginRPCServer) Initialize(args *InitializeRequest, _ *struct{}) error {
 	err := ds.impl.Initialize(args.Config, args.VerifyConnection)
 
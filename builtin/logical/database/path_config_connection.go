@@ -3,6 +3,7 @@ package database
 import (
 	"errors"
 	"fmt"
+	"net/rpc"
 
 	"github.com/fatih/structs"
 	"github.com/hashicorp/vault/builtin/logical/database/dbplugin"
@@ -23,6 +24,9 @@ type DatabaseConfig struct {
 	// by each database type.
 	ConnectionDetails map[string]interface{} `json:"connection_details" structs:"connection_details" mapstructure:"connection_details"`
 	AllowedRoles      []string               `json:"allowed_roles" structs:"allowed_roles" mapstructure:"allowed_roles"`
+	// RootRotationStatements are run to set the password of the user Vault
+	// connects as when the root credentials are rotated.
+	RootRotationStatements string `json:"root_rotation_statements" structs:"root_rotation_statements" mapstructure:"root_rotation_statements"`
 }
 
 // pathResetConnection configures a path to reset a plugin.
@@ -71,6 +75,76 @@ func (b *databaseBackend) pathConnectionReset() framework.OperationFunc {
 	}
 }
 
+// pathRotateRootCredentials configures a path to rotate the root credentials
+// of a connection.
+func pathRotateRootCredentials(b *databaseBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: fmt.Sprintf("rotate-root/%s", framework.GenericNameRegex("name")),
+		Fields: map[string]*framework.FieldSchema{
+			"name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of this database connection",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathRotateRootCredentialsUpdate(),
+		},
+
+		HelpSynopsis:    pathRotateRootCredentialsHelpSyn,
+		HelpDescription: pathRotateRootCredentialsHelpDesc,
+	}
+}
+
+// pathRotateRootCredentialsUpdate sets a new password for the user the
+// connection is made as, and stores it in the connection configuration.
+func (b *databaseBackend) pathRotateRootCredentialsUpdate() framework.OperationFunc {
+	return func(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		name := data.Get("name").(string)
+		if name == "" {
+			return logical.ErrorResponse(respErrEmptyName), nil
+		}
+
+		// Grab the mutex lock
+		b.Lock()
+		defer b.Unlock()
+
+		config, err := b.DatabaseConfig(req.Storage, name)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+
+		db, err := b.createDBObj(req.Storage, name)
+		if err != nil {
+			return nil, fmt.Errorf("cound not retrieve db with name: %s, got error: %s", name, err)
+		}
+
+		connectionDetails, err := db.RotateRootCredentials(dbplugin.Statements{
+			RotationStatements: config.RootRotationStatements,
+		})
+		if err != nil {
+			// Plugin has shutdown, close it so next call can reconnect.
+			if err == rpc.ErrShutdown {
+				b.clearConnection(name)
+			}
+			return nil, fmt.Errorf("failed to rotate root credentials: %s", err)
+		}
+
+		// The old password no longer works, so the new one has to be stored
+		// for the connection to keep working
+		config.ConnectionDetails = connectionDetails
+		entry, err := logical.StorageEntryJSON(fmt.Sprintf("config/%s", name), config)
+		if err != nil {
+			return nil, err
+		}
+		if err := req.Storage.Put(entry); err != nil {
+			return nil, err
+		}
+
+		return nil, nil
+	}
+}
+
 // pathConfigurePluginConnection returns a configured framework.Path setup to
 // operate on plugins.
 func pathConfigurePluginConnection(b *databaseBackend) *framework.Path {
@@ -102,6 +176,14 @@ func pathConfigurePluginConnection(b *databaseBackend) *framework.Path {
 				allowed to get creds from this database connection. If empty no
 				roles are allowed. If "*" all roles are allowed.`,
 			},
+
+			"root_rotation_statements": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `Specifies the database statements to be executed
+				to set the password of the user Vault connects as when the root
+				credentials are rotated. See the plugin's API page for more
+				information on support and formatting for this parameter.`,
+			},
 		},
 
 		Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -159,6 +241,11 @@ func (b *databaseBackend) connectionReadHandler() framework.OperationFunc {
 		if err := entry.DecodeJSON(&config); err != nil {
 			return nil, err
 		}
+
+		// The password may have been rotated, in which case only Vault
+		// should know it
+		delete(config.ConnectionDetails, "password")
+
 		return &logical.Response{
 			Data: structs.New(config).Map(),
 		}, nil
@@ -212,17 +299,21 @@ func (b *databaseBackend) connectionWriteHandler() framework.OperationFunc {
 
 		allowedRoles := data.Get("allowed_roles").([]string)
 
+		rootRotationStmts := data.Get("root_rotation_statements").(string)
+
 		// Remove these entries from the data before we store it keyed under
 		// ConnectionDetails.
 		delete(data.Raw, "name")
 		delete(data.Raw, "plugin_name")
 		delete(data.Raw, "allowed_roles")
 		delete(data.Raw, "verify_connection")
+		delete(data.Raw, "root_rotation_statements")
 
 		config := &DatabaseConfig{
-			ConnectionDetails: data.Raw,
-			PluginName:        pluginName,
-			AllowedRoles:      allowedRoles,
+			ConnectionDetails:      data.Raw,
+			PluginName:             pluginName,
+			AllowedRoles:           allowedRoles,
+			RootRotationStatements: rootRotationStmts,
 		}
 
 		db, err := dbplugin.PluginFactory(config.PluginName, b.System(), b.logger)
@@ -282,6 +373,22 @@ accepts:
 	* "verify_connection" (default: true) - A boolean value denoting if the plugin should verify
 	   it is able to connect to the database using the provided connection
        details.
+
+	* "root_rotation_statements" - The statements used to set the password of
+	   the user Vault connects as when the root credentials are rotated.
+`
+
+const pathRotateRootCredentialsHelpSyn = `
+Rotates the root credentials of a database connection.
+`
+
+const pathRotateRootCredentialsHelpDesc = `
+This path sets a new random password for the user Vault connects to the
+database as, so that the credentials it was configured with no longer work.
+The new password is stored in the connection configuration and is not
+returned. Not every plugin supports this; the SQL based plugins require the
+"username" and "password" connection details, and a "connection_url" that
+uses the {{username}} and {{password}} templates.
 `
 
 const pathResetConnectionHelpSyn = `
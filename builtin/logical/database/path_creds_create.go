@@ -97,6 +97,9 @@ func (b *databaseBackend) pathCredsCreateRead() framework.OperationFunc {
 			"role":     name,
 		})
 		resp.Secret.TTL = role.DefaultTTL
+		resp.Secret.Artifacts = map[string]string{
+			"username": username,
+		}
 		return resp, nil
 	}
 }
@@ -62,6 +62,21 @@ func pathRoles(b *databaseBackend) *framework.Path {
 				parameter.`,
 			},
 
+			"disable_statements": {
+				Type: framework.TypeString,
+				Description: `Specifies the database statements to be executed
+				to disable a user when its lease is revoked, if the role has a
+				revocation grace period. The revocation statements are
+				executed once the grace period has passed.`,
+			},
+
+			"revocation_grace": {
+				Type: framework.TypeDurationSecond,
+				Description: `Time a user is kept disabled after its lease is
+				revoked before it is removed from the database. Requires
+				disable_statements.`,
+			},
+
 			"default_ttl": {
 				Type:        framework.TypeDurationSecond,
 				Description: "Default ttl for role.",
@@ -75,7 +90,8 @@ func pathRoles(b *databaseBackend) *framework.Path {
 
 		Callbacks: map[logical.Operation]framework.OperationFunc{
 			logical.ReadOperation:   b.pathRoleRead(),
-			logical.UpdateOperation: b.pathRoleCreate(),
+			logical.UpdateOperation: b.withRoleLock(b.pathRoleCreate()),
+			logical.PatchOperation:  b.withRoleLock(framework.PatchOperation(b.pathRoleRead(), b.pathRoleCreate())),
 			logical.DeleteOperation: b.pathRoleDelete(),
 		},
 
@@ -84,6 +100,16 @@ func pathRoles(b *databaseBackend) *framework.Path {
 	}
 }
 
+// withRoleLock serializes the writes of the roles, so that a role written
+// while it is patched is not overwritten by the patch
+func (b *databaseBackend) withRoleLock(f framework.OperationFunc) framework.OperationFunc {
+	return func(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		b.roleLock.Lock()
+		defer b.roleLock.Unlock()
+		return f(req, data)
+	}
+}
+
 func (b *databaseBackend) pathRoleDelete() framework.OperationFunc {
 	return func(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 		err := req.Storage.Delete("role/" + data.Get("name").(string))
@@ -112,6 +138,8 @@ func (b *databaseBackend) pathRoleRead() framework.OperationFunc {
 				"revocation_statements": role.Statements.RevocationStatements,
 				"rollback_statements":   role.Statements.RollbackStatements,
 				"renew_statements":      role.Statements.RenewStatements,
+				"disable_statements":    role.DisableStatements,
+				"revocation_grace":      role.RevocationGrace.Seconds(),
 				"default_ttl":           role.DefaultTTL.Seconds(),
 				"max_ttl":               role.MaxTTL.Seconds(),
 			},
@@ -154,6 +182,15 @@ func (b *databaseBackend) pathRoleCreate() framework.OperationFunc {
 		defaultTTL := time.Duration(defaultTTLRaw) * time.Second
 		maxTTL := time.Duration(maxTTLRaw) * time.Second
 
+		disableStmts := data.Get("disable_statements").(string)
+		revocationGrace := time.Duration(data.Get("revocation_grace").(int)) * time.Second
+		if revocationGrace < 0 {
+			return logical.ErrorResponse("revocation_grace cannot be negative"), nil
+		}
+		if revocationGrace > 0 && disableStmts == "" {
+			return logical.ErrorResponse("disable_statements are required when revocation_grace is set"), nil
+		}
+
 		statements := dbplugin.Statements{
 			CreationStatements:   creationStmts,
 			RevocationStatements: revocationStmts,
@@ -163,10 +200,12 @@ func (b *databaseBackend) pathRoleCreate() framework.OperationFunc {
 
 		// Store it
 		entry, err := logical.StorageEntryJSON("role/"+name, &roleEntry{
-			DBName:     dbName,
-			Statements: statements,
-			DefaultTTL: defaultTTL,
-			MaxTTL:     maxTTL,
+			DBName:            dbName,
+			Statements:        statements,
+			DisableStatements: disableStmts,
+			RevocationGrace:   revocationGrace,
+			DefaultTTL:        defaultTTL,
+			MaxTTL:            maxTTL,
 		})
 		if err != nil {
 			return nil, err
@@ -180,10 +219,12 @@ func (b *databaseBackend) pathRoleCreate() framework.OperationFunc {
 }
 
 type roleEntry struct {
-	DBName     string              `json:"db_name" mapstructure:"db_name" structs:"db_name"`
-	Statements dbplugin.Statements `json:"statments" mapstructure:"statements" structs:"statments"`
-	DefaultTTL time.Duration       `json:"default_ttl" mapstructure:"default_ttl" structs:"default_ttl"`
-	MaxTTL     time.Duration       `json:"max_ttl" mapstructure:"max_ttl" structs:"max_ttl"`
+	DBName            string              `json:"db_name" mapstructure:"db_name" structs:"db_name"`
+	Statements        dbplugin.Statements `json:"statments" mapstructure:"statements" structs:"statments"`
+	DisableStatements string              `json:"disable_statements" mapstructure:"disable_statements" structs:"disable_statements"`
+	RevocationGrace   time.Duration       `json:"revocation_grace" mapstructure:"revocation_grace" structs:"revocation_grace"`
+	DefaultTTL        time.Duration       `json:"default_ttl" mapstructure:"default_ttl" structs:"default_ttl"`
+	MaxTTL            time.Duration       `json:"max_ttl" mapstructure:"max_ttl" structs:"max_ttl"`
 }
 
 const pathRoleHelpSyn = `
@@ -227,6 +268,16 @@ database plugin:
 
 The "renew_statements" parameter customizes the statement string used to renew a
 user.
+
+The "revocation_grace" parameter delays the removal of users whose lease has
+been revoked, so that applications which are slow to pick up new credentials
+keep working in the meantime. The users are disabled right away by the
+"disable_statements", and removed by the "revocation_statements" once the grace
+period has passed. Example of a decent disable_statements for a postgresql
+database plugin:
+
+	ALTER ROLE "{{name}}" NOLOGIN;
+
 The "rollback_statements' parameter customizes the statement string used to
 rollback a change if needed.
 `
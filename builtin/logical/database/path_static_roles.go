@@ -0,0 +1,567 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/vault/builtin/logical/database/dbplugin"
+	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+	"github.com/hashicorp/vault/plugins/helper/database/credsutil"
+	"github.com/mitchellh/mapstructure"
+)
+
+const (
+	staticRolePath = "static-role/"
+
+	// minRotationPeriod is the shortest rotation period a static role can
+	// have. Rotations are driven by the periodic function, so in practice
+	// passwords are rotated at most about once a minute.
+	minRotationPeriod = 5 * time.Second
+
+	// staticRotationWALKind is the kind of the WAL entries written before the
+	// password of a static role's user is changed
+	staticRotationWALKind = "static_rotation"
+)
+
+func pathListStaticRoles(b *databaseBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "static-roles/?$",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ListOperation: b.pathStaticRoleList(),
+		},
+
+		HelpSynopsis:    pathStaticRoleHelpSyn,
+		HelpDescription: pathStaticRoleHelpDesc,
+	}
+}
+
+func pathStaticRoles(b *databaseBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "static-roles/" + framework.GenericNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "Name of the static role.",
+			},
+
+			"db_name": {
+				Type:        framework.TypeString,
+				Description: "Name of the database this role acts on.",
+			},
+			"username": {
+				Type: framework.TypeString,
+				Description: `Name of the existing database user whose password
+				is managed by this role.`,
+			},
+			"rotation_period": {
+				Type: framework.TypeDurationSecond,
+				Description: `Time between rotations of the user's password.
+				Must be at least 5 seconds.`,
+			},
+			"rotation_statements": {
+				Type: framework.TypeString,
+				Description: `Specifies the database statements to be executed
+				to set the password of the user. See the plugin's API page for
+				more information on support and formatting for this
+				parameter.`,
+			},
+		},
+
+		ExistenceCheck: b.pathStaticRoleExistenceCheck(),
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathStaticRoleRead(),
+			logical.CreateOperation: b.pathStaticRoleCreateUpdate(),
+			logical.UpdateOperation: b.pathStaticRoleCreateUpdate(),
+			logical.DeleteOperation: b.pathStaticRoleDelete(),
+		},
+
+		HelpSynopsis:    pathStaticRoleHelpSyn,
+		HelpDescription: pathStaticRoleHelpDesc,
+	}
+}
+
+func pathStaticCreds(b *databaseBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "static-creds/" + framework.GenericNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "Name of the static role.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathStaticCredsRead(),
+		},
+
+		HelpSynopsis:    pathStaticCredsHelpSyn,
+		HelpDescription: pathStaticCredsHelpDesc,
+	}
+}
+
+func pathRotateRole(b *databaseBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "rotate-role/" + framework.GenericNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "Name of the static role.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathRotateRoleUpdate(),
+		},
+
+		HelpSynopsis:    pathRotateRoleHelpSyn,
+		HelpDescription: pathRotateRoleHelpDesc,
+	}
+}
+
+func (b *databaseBackend) pathStaticRoleExistenceCheck() func(*logical.Request, *framework.FieldData) (bool, error) {
+	return func(req *logical.Request, data *framework.FieldData) (bool, error) {
+		role, err := b.StaticRole(req.Storage, data.Get("name").(string))
+		if err != nil {
+			return false, err
+		}
+		return role != nil, nil
+	}
+}
+
+func (b *databaseBackend) pathStaticRoleDelete() framework.OperationFunc {
+	return func(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		b.staticRotationLock.Lock()
+		defer b.staticRotationLock.Unlock()
+
+		err := req.Storage.Delete(staticRolePath + data.Get("name").(string))
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, nil
+	}
+}
+
+func (b *databaseBackend) pathStaticRoleRead() framework.OperationFunc {
+	return func(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		role, err := b.StaticRole(req.Storage, data.Get("name").(string))
+		if err != nil {
+			return nil, err
+		}
+		if role == nil {
+			return nil, nil
+		}
+
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"db_name":             role.DBName,
+				"username":            role.Username,
+				"rotation_period":     role.RotationPeriod.Seconds(),
+				"rotation_statements": role.RotationStatements,
+				"last_vault_rotation": role.LastVaultRotation.Format(time.RFC3339),
+			},
+		}, nil
+	}
+}
+
+func (b *databaseBackend) pathStaticRoleList() framework.OperationFunc {
+	return func(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		entries, err := req.Storage.List(staticRolePath)
+		if err != nil {
+			return nil, err
+		}
+
+		return logical.ListResponse(entries), nil
+	}
+}
+
+func (b *databaseBackend) pathStaticRoleCreateUpdate() framework.OperationFunc {
+	return func(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		name := data.Get("name").(string)
+		if name == "" {
+			return logical.ErrorResponse("empty role name attribute given"), nil
+		}
+
+		b.staticRotationLock.Lock()
+		defer b.staticRotationLock.Unlock()
+
+		role, err := b.StaticRole(req.Storage, name)
+		if err != nil {
+			return nil, err
+		}
+		if role == nil {
+			if req.Operation == logical.UpdateOperation {
+				return logical.ErrorResponse(fmt.Sprintf("unknown static role: %s", name)), nil
+			}
+			role = &staticRoleEntry{}
+		}
+
+		// The user a static role manages cannot be changed, since Vault
+		// would lose track of the password of the previous one
+		if dbNameRaw, ok := data.GetOk("db_name"); ok {
+			dbName := dbNameRaw.(string)
+			if role.DBName != "" && dbName != role.DBName {
+				return logical.ErrorResponse("db_name cannot be changed"), nil
+			}
+			role.DBName = dbName
+		}
+		if usernameRaw, ok := data.GetOk("username"); ok {
+			username := usernameRaw.(string)
+			if role.Username != "" && username != role.Username {
+				return logical.ErrorResponse("username cannot be changed"), nil
+			}
+			role.Username = username
+		}
+		if role.DBName == "" {
+			return logical.ErrorResponse("empty database name attribute given"), nil
+		}
+		if role.Username == "" {
+			return logical.ErrorResponse("empty username attribute given"), nil
+		}
+
+		if rotationPeriodRaw, ok := data.GetOk("rotation_period"); ok {
+			role.RotationPeriod = time.Duration(rotationPeriodRaw.(int)) * time.Second
+		}
+		if role.RotationPeriod < minRotationPeriod {
+			return logical.ErrorResponse(fmt.Sprintf("rotation_period must be at least %s", minRotationPeriod)), nil
+		}
+		if rotationStmtsRaw, ok := data.GetOk("rotation_statements"); ok {
+			role.RotationStatements = rotationStmtsRaw.(string)
+		}
+
+		dbConfig, err := b.DatabaseConfig(req.Storage, role.DBName)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+
+		// If role name isn't in the database's allowed roles, send back a
+		// permission denied.
+		if !strutil.StrListContains(dbConfig.AllowedRoles, "*") && !strutil.StrListContains(dbConfig.AllowedRoles, name) {
+			return nil, logical.ErrPermissionDenied
+		}
+
+		// A new role takes over the password of its user right away, so
+		// that Vault knows it from then on
+		if req.Operation == logical.CreateOperation {
+			if err := b.rotateStaticRole(req.Storage, name, role); err != nil {
+				return nil, fmt.Errorf("failed to set the password of user %q: %s", role.Username, err)
+			}
+			return nil, nil
+		}
+
+		if err := b.putStaticRole(req.Storage, name, role); err != nil {
+			return nil, err
+		}
+
+		return nil, nil
+	}
+}
+
+func (b *databaseBackend) pathStaticCredsRead() framework.OperationFunc {
+	return func(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		name := data.Get("name").(string)
+
+		role, err := b.StaticRole(req.Storage, name)
+		if err != nil {
+			return nil, err
+		}
+		if role == nil {
+			return logical.ErrorResponse(fmt.Sprintf("unknown static role: %s", name)), nil
+		}
+
+		dbConfig, err := b.DatabaseConfig(req.Storage, role.DBName)
+		if err != nil {
+			return nil, err
+		}
+
+		// If role name isn't in the database's allowed roles, send back a
+		// permission denied.
+		if !strutil.StrListContains(dbConfig.AllowedRoles, "*") && !strutil.StrListContains(dbConfig.AllowedRoles, name) {
+			return nil, logical.ErrPermissionDenied
+		}
+
+		ttl := role.nextRotation().Sub(time.Now())
+		if ttl < 0 {
+			ttl = 0
+		}
+
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"username":            role.Username,
+				"password":            role.Password,
+				"last_vault_rotation": role.LastVaultRotation.Format(time.RFC3339),
+				"rotation_period":     role.RotationPeriod.Seconds(),
+				"ttl":                 int64(ttl.Seconds()),
+			},
+		}, nil
+	}
+}
+
+func (b *databaseBackend) pathRotateRoleUpdate() framework.OperationFunc {
+	return func(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		name := data.Get("name").(string)
+
+		b.staticRotationLock.Lock()
+		defer b.staticRotationLock.Unlock()
+
+		role, err := b.StaticRole(req.Storage, name)
+		if err != nil {
+			return nil, err
+		}
+		if role == nil {
+			return logical.ErrorResponse(fmt.Sprintf("unknown static role: %s", name)), nil
+		}
+
+		if err := b.rotateStaticRole(req.Storage, name, role); err != nil {
+			return nil, fmt.Errorf("failed to rotate the password of user %q: %s", role.Username, err)
+		}
+
+		return nil, nil
+	}
+}
+
+func (b *databaseBackend) StaticRole(s logical.Storage, roleName string) (*staticRoleEntry, error) {
+	entry, err := s.Get(staticRolePath + roleName)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var result staticRoleEntry
+	if err := entry.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (b *databaseBackend) putStaticRole(s logical.Storage, roleName string, role *staticRoleEntry) error {
+	entry, err := logical.StorageEntryJSON(staticRolePath+roleName, role)
+	if err != nil {
+		return err
+	}
+	return s.Put(entry)
+}
+
+// walStaticRotation is written before the password of a static role's user
+// is changed, so that a password the database may have accepted is not lost
+// if Vault stops before storing it in the role
+type walStaticRotation struct {
+	RoleName string
+	Username string
+	Password string
+
+	// LastVaultRotation is the rotation time of the role, in Unix
+	// nanoseconds, when the rotation started. A role rotated since then
+	// holds a newer password.
+	LastVaultRotation int64
+}
+
+// rotateStaticRole sets a new password for the user of the role and stores
+// the role. The new password is first written to the WAL, which is only
+// removed once the role holds it. The caller of this function needs to hold
+// the backend's static rotation lock.
+func (b *databaseBackend) rotateStaticRole(s logical.Storage, name string, role *staticRoleEntry) error {
+	password, err := credsutil.RandomAlphaNumeric(20, true)
+	if err != nil {
+		return err
+	}
+
+	walID, err := framework.PutWAL(s, staticRotationWALKind, &walStaticRotation{
+		RoleName:          name,
+		Username:          role.Username,
+		Password:          password,
+		LastVaultRotation: role.LastVaultRotation.UnixNano(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write WAL entry: %s", err)
+	}
+
+	// The WAL entry is kept if the password cannot be set, since the
+	// database may have applied it anyway
+	if err := b.setCredentials(s, role.DBName, dbplugin.Statements{
+		RotationStatements: role.RotationStatements,
+	}, role.Username, password); err != nil {
+		return err
+	}
+
+	role.Password = password
+	role.LastVaultRotation = time.Now().UTC()
+	if err := b.putStaticRole(s, name, role); err != nil {
+		return err
+	}
+
+	if err := framework.DeleteWAL(s, walID); err != nil {
+		return fmt.Errorf("failed to commit WAL entry: %s", err)
+	}
+	return nil
+}
+
+// walRollback completes a static role rotation interrupted after its WAL
+// entry was written, by setting the password of the entry again and storing
+// it in the role, unless the role has been rotated or replaced since
+func (b *databaseBackend) walRollback(req *logical.Request, kind string, data interface{}) error {
+	if kind != staticRotationWALKind {
+		return fmt.Errorf("unknown type to rollback")
+	}
+
+	var entry walStaticRotation
+	if err := mapstructure.Decode(data, &entry); err != nil {
+		return err
+	}
+
+	b.staticRotationLock.Lock()
+	defer b.staticRotationLock.Unlock()
+
+	role, err := b.StaticRole(req.Storage, entry.RoleName)
+	if err != nil {
+		return err
+	}
+	if role == nil || role.Username != entry.Username ||
+		role.LastVaultRotation.UnixNano() != entry.LastVaultRotation || role.Password == entry.Password {
+		return nil
+	}
+
+	if err := b.setCredentials(req.Storage, role.DBName, dbplugin.Statements{
+		RotationStatements: role.RotationStatements,
+	}, role.Username, entry.Password); err != nil {
+		return err
+	}
+
+	role.Password = entry.Password
+	role.LastVaultRotation = time.Now().UTC()
+	return b.putStaticRole(req.Storage, entry.RoleName, role)
+}
+
+// setCredentials runs the rotation statements setting the password of the
+// user against the database
+func (b *databaseBackend) setCredentials(s logical.Storage, dbName string, statements dbplugin.Statements, username, password string) error {
+	// Grab the read lock
+	b.RLock()
+	var unlockFunc func() = b.RUnlock
+
+	// Get our connection
+	db, ok := b.getDBObj(dbName)
+	if !ok {
+		// Upgrade lock
+		b.RUnlock()
+		b.Lock()
+		unlockFunc = b.Unlock
+
+		// Create a new DB object
+		var err error
+		db, err = b.createDBObj(s, dbName)
+		if err != nil {
+			unlockFunc()
+			return fmt.Errorf("cound not retrieve db with name: %s, got error: %s", dbName, err)
+		}
+	}
+
+	err := db.SetCredentials(statements, username, password)
+	// Unlock
+	unlockFunc()
+	if err != nil {
+		b.closeIfShutdown(dbName, err)
+		return err
+	}
+
+	return nil
+}
+
+// rotateStaticRoles rotates the passwords of the static roles whose rotation
+// period has passed. Roles that cannot be rotated keep their current password
+// and are retried on the next run.
+func (b *databaseBackend) rotateStaticRoles(req *logical.Request) error {
+	names, err := req.Storage.List(staticRolePath)
+	if err != nil {
+		return err
+	}
+
+	b.staticRotationLock.Lock()
+	defer b.staticRotationLock.Unlock()
+
+	var result error
+	for _, name := range names {
+		role, err := b.StaticRole(req.Storage, name)
+		if err != nil {
+			result = multierror.Append(result, err)
+			continue
+		}
+		if role == nil || time.Now().Before(role.nextRotation()) {
+			continue
+		}
+
+		if err := b.rotateStaticRole(req.Storage, name, role); err != nil {
+			result = multierror.Append(result, fmt.Errorf("failed to rotate the password of static role %q: %s", name, err))
+		}
+	}
+
+	return result
+}
+
+// staticRoleEntry is a role managing the password of an existing database
+// user
+type staticRoleEntry struct {
+	DBName             string        `json:"db_name" mapstructure:"db_name" structs:"db_name"`
+	Username           string        `json:"username" mapstructure:"username" structs:"username"`
+	RotationPeriod     time.Duration `json:"rotation_period" mapstructure:"rotation_period" structs:"rotation_period"`
+	RotationStatements string        `json:"rotation_statements" mapstructure:"rotation_statements" structs:"rotation_statements"`
+	Password           string        `json:"password" mapstructure:"password" structs:"password"`
+	LastVaultRotation  time.Time     `json:"last_vault_rotation" mapstructure:"last_vault_rotation" structs:"last_vault_rotation"`
+}
+
+func (r *staticRoleEntry) nextRotation() time.Time {
+	return r.LastVaultRotation.Add(r.RotationPeriod)
+}
+
+const pathStaticRoleHelpSyn = `
+Manage the static roles that can be used with this backend.
+`
+
+const pathStaticRoleHelpDesc = `
+This path lets you manage static roles, which give Vault control of the
+password of an existing database user instead of creating a new user for each
+request. Vault sets a new password when the role is created, and rotates it
+every "rotation_period" from then on. The current password can be read from
+the "static-creds/<name>" path.
+
+The "db_name" parameter is required and configures the name of the database
+connection to use. The "username" parameter is required and names the user
+whose password is managed. Neither can be changed once the role is created.
+
+The "rotation_statements" parameter customizes the statements used to set the
+password of the user. The "name" and "password" keys are substituted as for
+the creation statements of roles. Example of a decent rotation_statements for
+a postgresql database plugin, which is also its default:
+
+	ALTER ROLE "{{name}}" WITH PASSWORD '{{password}}';
+
+Deleting a static role leaves the database user and its current password in
+place.
+`
+
+const pathStaticCredsHelpSyn = `
+Request the current credentials of a static role.
+`
+
+const pathStaticCredsHelpDesc = `
+This path reads the username and the current password of the user managed by
+a static role. The password is valid until the next rotation, in "ttl"
+seconds. These credentials are not leased and are not revoked by Vault.
+`
+
+const pathRotateRoleHelpSyn = `
+Rotate the password of a static role right away.
+`
+
+const pathRotateRoleHelpDesc = `
+This path sets a new password for the user managed by a static role. The
+next scheduled rotation happens "rotation_period" after this one.
+`
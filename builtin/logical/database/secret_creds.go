@@ -2,12 +2,21 @@ package database
 
 import (
 	"fmt"
+	"time"
 
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/vault/builtin/logical/database/dbplugin"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
 )
 
-const SecretCredsType = "creds"
+const (
+	SecretCredsType = "creds"
+
+	// pendingRevocationPath is the storage prefix of users waiting out
+	// their role's revocation grace period
+	pendingRevocationPath = "revoke-pending/"
+)
 
 func secretCreds(b *databaseBackend) *framework.Secret {
 	return &framework.Secret{
@@ -106,34 +115,121 @@ func (b *databaseBackend) secretCredsRevoke() framework.OperationFunc {
 			return nil, fmt.Errorf("error during revoke: could not find role with name %s", req.Secret.InternalData["role"])
 		}
 
-		// Grab the read lock
-		b.RLock()
-		var unlockFunc func() = b.RUnlock
-
-		// Get our connection
-		db, ok := b.getDBObj(role.DBName)
-		if !ok {
-			// Upgrade lock
-			b.RUnlock()
-			b.Lock()
-			unlockFunc = b.Unlock
+		// With a grace period the user is only disabled for now, and removed
+		// by the periodic function once the grace period has passed
+		if role.RevocationGrace > 0 {
+			err = b.revokeUser(req.Storage, role.DBName, dbplugin.Statements{
+				RevocationStatements: role.DisableStatements,
+			}, username)
+			if err != nil {
+				return nil, err
+			}
 
-			// Create a new DB object
-			db, err = b.createDBObj(req.Storage, role.DBName)
+			entry, err := logical.StorageEntryJSON(pendingRevocationPath+username, &pendingRevocation{
+				Username:   username,
+				DBName:     role.DBName,
+				Statements: role.Statements,
+				RevokeTime: time.Now().Add(role.RevocationGrace),
+			})
 			if err != nil {
-				unlockFunc()
-				return nil, fmt.Errorf("cound not retrieve db with name: %s, got error: %s", role.DBName, err)
+				return nil, err
+			}
+			if err := req.Storage.Put(entry); err != nil {
+				return nil, err
 			}
+
+			return resp, nil
 		}
 
-		err = db.RevokeUser(role.Statements, username)
-		// Unlock
-		unlockFunc()
-		if err != nil {
-			b.closeIfShutdown(role.DBName, err)
+		if err := b.revokeUser(req.Storage, role.DBName, role.Statements, username); err != nil {
 			return nil, err
 		}
 
 		return resp, nil
 	}
 }
+
+// pendingRevocation is a user that has been disabled and is to be removed
+// from the database once its role's revocation grace period has passed
+type pendingRevocation struct {
+	Username   string              `json:"username"`
+	DBName     string              `json:"db_name"`
+	Statements dbplugin.Statements `json:"statements"`
+	RevokeTime time.Time           `json:"revoke_time"`
+}
+
+// revokeUser runs the revocation statements for the user against the
+// database
+func (b *databaseBackend) revokeUser(s logical.Storage, dbName string, statements dbplugin.Statements, username string) error {
+	// Grab the read lock
+	b.RLock()
+	var unlockFunc func() = b.RUnlock
+
+	// Get our connection
+	db, ok := b.getDBObj(dbName)
+	if !ok {
+		// Upgrade lock
+		b.RUnlock()
+		b.Lock()
+		unlockFunc = b.Unlock
+
+		// Create a new DB object
+		var err error
+		db, err = b.createDBObj(s, dbName)
+		if err != nil {
+			unlockFunc()
+			return fmt.Errorf("cound not retrieve db with name: %s, got error: %s", dbName, err)
+		}
+	}
+
+	err := db.RevokeUser(statements, username)
+	// Unlock
+	unlockFunc()
+	if err != nil {
+		b.closeIfShutdown(dbName, err)
+		return err
+	}
+
+	return nil
+}
+
+// revokePendingUsers removes the disabled users whose revocation grace
+// period has passed. Users that cannot be removed are retried on the next
+// run.
+func (b *databaseBackend) revokePendingUsers(req *logical.Request) error {
+	usernames, err := req.Storage.List(pendingRevocationPath)
+	if err != nil {
+		return err
+	}
+
+	var result error
+	for _, username := range usernames {
+		entry, err := req.Storage.Get(pendingRevocationPath + username)
+		if err != nil {
+			result = multierror.Append(result, err)
+			continue
+		}
+		if entry == nil {
+			continue
+		}
+
+		var pending pendingRevocation
+		if err := entry.DecodeJSON(&pending); err != nil {
+			result = multierror.Append(result, err)
+			continue
+		}
+		if time.Now().Before(pending.RevokeTime) {
+			continue
+		}
+
+		if err := b.revokeUser(req.Storage, pending.DBName, pending.Statements, pending.Username); err != nil {
+			result = multierror.Append(result, fmt.Errorf("failed to revoke disabled user %q: %s", pending.Username, err))
+			continue
+		}
+		if err := req.Storage.Delete(pendingRevocationPath + username); err != nil {
+			result = multierror.Append(result, err)
+		}
+	}
+
+	return result
+}
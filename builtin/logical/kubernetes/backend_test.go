@@ -0,0 +1,329 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+// fakeKubeAPI stands in for the parts of the Kubernetes API used by the
+// backend, tracking the objects that exist
+type fakeKubeAPI struct {
+	sync.Mutex
+
+	serviceAccounts map[string]bool
+	roleBindings    map[string]string
+	tokenRequests   []map[string]interface{}
+}
+
+func (f *fakeKubeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer vault-jwt" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var body map[string]interface{}
+	if r.Method == "POST" {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	// /api/v1/namespaces/<ns>/serviceaccounts/<name>/token
+	case r.Method == "POST" && len(parts) == 7 && parts[6] == "token":
+		key := parts[3] + "/" + parts[5]
+		if parts[5] != "existing" && !f.serviceAccounts[key] {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]interface{}{"message": "serviceaccounts not found"})
+			return
+		}
+		f.tokenRequests = append(f.tokenRequests, body)
+		spec := body["spec"].(map[string]interface{})
+		expiration := time.Now().Add(time.Duration(spec["expirationSeconds"].(float64)) * time.Second)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status": map[string]interface{}{
+				"token":               "token-for-" + key,
+				"expirationTimestamp": expiration.UTC().Format(time.RFC3339),
+			},
+		})
+
+	// /api/v1/namespaces/<ns>/serviceaccounts[/<name>]
+	case len(parts) >= 5 && parts[4] == "serviceaccounts":
+		if r.Method == "POST" {
+			name := body["metadata"].(map[string]interface{})["name"].(string)
+			f.serviceAccounts[parts[3]+"/"+name] = true
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+		key := parts[3] + "/" + parts[5]
+		if !f.serviceAccounts[key] {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		delete(f.serviceAccounts, key)
+
+	// /apis/rbac.authorization.k8s.io/v1/namespaces/<ns>/rolebindings[/<name>]
+	case len(parts) >= 6 && parts[5] == "rolebindings":
+		if r.Method == "POST" {
+			name := body["metadata"].(map[string]interface{})["name"].(string)
+			roleRef := body["roleRef"].(map[string]interface{})
+			f.roleBindings[parts[4]+"/"+name] = fmt.Sprintf("%s/%s", roleRef["kind"], roleRef["name"])
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+		delete(f.roleBindings, parts[4]+"/"+parts[6])
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func testBackend(t *testing.T) (*backend, logical.Storage, *fakeKubeAPI) {
+	api := &fakeKubeAPI{
+		serviceAccounts: make(map[string]bool),
+		roleBindings:    make(map[string]string),
+	}
+	ts := httptest.NewServer(api)
+
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+	config.System = &logical.StaticSystemView{
+		DefaultLeaseTTLVal: time.Hour,
+		MaxLeaseTTLVal:     24 * time.Hour,
+	}
+
+	b := Backend()
+	if err := b.Setup(config); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   config.StorageView,
+		Data: map[string]interface{}{
+			"kubernetes_host":     ts.URL,
+			"service_account_jwt": "vault-jwt",
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v err: %v", resp, err)
+	}
+
+	return b, config.StorageView, api
+}
+
+func TestBackend_existingServiceAccount(t *testing.T) {
+	b, storage, api := testBackend(t)
+
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "roles/ci",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"service_account_name":          "existing",
+			"allowed_kubernetes_namespaces": "build,test",
+			"token_max_ttl":                 "2h",
+			"token_default_audiences":       "vault",
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v err: %v", resp, err)
+	}
+
+	credsReq := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "creds/ci",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"kubernetes_namespace": "prod",
+		},
+	}
+	resp, err = b.HandleRequest(credsReq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error for a namespace that is not allowed")
+	}
+
+	credsReq.Data = map[string]interface{}{
+		"kubernetes_namespace": "build",
+		"ttl":                  "5h",
+	}
+	resp, err = b.HandleRequest(credsReq)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: resp: %#v err: %v", resp, err)
+	}
+	if resp.Data["service_account_token"] != "token-for-build/existing" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if len(resp.Warnings) != 1 {
+		t.Fatalf("expected a warning about the capped ttl, got %v", resp.Warnings)
+	}
+	if resp.Secret.TTL > 2*time.Hour || resp.Secret.TTL < 2*time.Hour-time.Minute {
+		t.Fatalf("bad: ttl %s", resp.Secret.TTL)
+	}
+	if resp.Secret.Renewable {
+		t.Fatalf("expected the lease to not be renewable")
+	}
+
+	spec := api.tokenRequests[0]["spec"].(map[string]interface{})
+	if spec["expirationSeconds"].(float64) != 7200 {
+		t.Fatalf("bad: %#v", spec)
+	}
+	if audiences := spec["audiences"].([]interface{}); len(audiences) != 1 || audiences[0] != "vault" {
+		t.Fatalf("bad: %#v", spec)
+	}
+
+	// Revoking does not touch the existing service account
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.RevokeOperation,
+		Storage:   storage,
+		Secret:    resp.Secret,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v err: %v", resp, err)
+	}
+
+	credsReq.Data["ttl"] = "5m"
+	resp, err = b.HandleRequest(credsReq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error for a ttl below the minimum")
+	}
+}
+
+func TestBackend_ephemeralServiceAccount(t *testing.T) {
+	b, storage, api := testBackend(t)
+
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "roles/deployer",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"kubernetes_role_name":          "edit",
+			"kubernetes_role_type":          "ClusterRole",
+			"allowed_kubernetes_namespaces": "*",
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v err: %v", resp, err)
+	}
+
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "roles/deployer",
+		Storage:   storage,
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: resp: %#v err: %v", resp, err)
+	}
+	if resp.Data["kubernetes_role_type"] != "ClusterRole" || resp.Data["kubernetes_role_name"] != "edit" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "creds/deployer",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"kubernetes_namespace": "apps",
+		},
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: resp: %#v err: %v", resp, err)
+	}
+
+	name := resp.Data["service_account_name"].(string)
+	if !strings.HasPrefix(name, "vault-deployer-") {
+		t.Fatalf("bad: service account name %q", name)
+	}
+	if resp.Data["service_account_token"] != "token-for-apps/"+name {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if !api.serviceAccounts["apps/"+name] || api.roleBindings["apps/"+name] != "ClusterRole/edit" {
+		t.Fatalf("expected service account and role binding, got %v and %v", api.serviceAccounts, api.roleBindings)
+	}
+	if resp.Secret.TTL > time.Hour || resp.Secret.TTL < time.Hour-time.Minute {
+		t.Fatalf("bad: ttl %s", resp.Secret.TTL)
+	}
+
+	// No WAL entries are left behind
+	wals, err := storage.List("wal/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(wals) != 0 {
+		t.Fatalf("expected no WAL entries, got %v", wals)
+	}
+
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.RevokeOperation,
+		Storage:   storage,
+		Secret:    resp.Secret,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v err: %v", resp, err)
+	}
+	if len(api.serviceAccounts) != 0 || len(api.roleBindings) != 0 {
+		t.Fatalf("expected service account and role binding to be deleted, got %v and %v", api.serviceAccounts, api.roleBindings)
+	}
+}
+
+func TestBackend_roleValidation(t *testing.T) {
+	b, storage, _ := testBackend(t)
+
+	for _, data := range []map[string]interface{}{
+		{
+			"service_account_name": "existing",
+		},
+		{
+			"allowed_kubernetes_namespaces": "build",
+		},
+		{
+			"allowed_kubernetes_namespaces": "build",
+			"service_account_name":          "existing",
+			"kubernetes_role_name":          "edit",
+		},
+		{
+			"allowed_kubernetes_namespaces": "build",
+			"kubernetes_role_name":          "edit",
+			"kubernetes_role_type":          "Group",
+		},
+		{
+			"allowed_kubernetes_namespaces": "build",
+			"service_account_name":          "existing",
+			"token_default_ttl":             "2h",
+			"token_max_ttl":                 "1h",
+		},
+	} {
+		resp, err := b.HandleRequest(&logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "roles/invalid",
+			Storage:   storage,
+			Data:      data,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp == nil || !resp.IsError() {
+			t.Fatalf("expected an error for %#v", data)
+		}
+	}
+}
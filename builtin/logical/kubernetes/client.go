@@ -0,0 +1,200 @@
+package kubernetes
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	cleanhttp "github.com/hashicorp/go-cleanhttp"
+)
+
+// managedByLabel marks the Kubernetes objects created by Vault
+const managedByLabel = "app.kubernetes.io/managed-by"
+
+// kubeClient makes the few Kubernetes API calls the backend needs
+type kubeClient struct {
+	config *kubeConfig
+	client *http.Client
+}
+
+func newKubeClient(config *kubeConfig) *kubeClient {
+	client := cleanhttp.DefaultClient()
+
+	// If we have a CA cert build the TLSConfig
+	if len(config.CACert) > 0 {
+		certPool := x509.NewCertPool()
+		certPool.AppendCertsFromPEM([]byte(config.CACert))
+
+		client.Transport.(*http.Transport).TLSClientConfig = &tls.Config{
+			MinVersion: tls.VersionTLS12,
+			RootCAs:    certPool,
+		}
+	}
+
+	return &kubeClient{
+		config: config,
+		client: client,
+	}
+}
+
+// kubeAPIError is returned when the Kubernetes API responds with an error
+type kubeAPIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *kubeAPIError) Error() string {
+	return fmt.Sprintf("kubernetes API returned status %d: %s", e.StatusCode, e.Message)
+}
+
+func isNotFound(err error) bool {
+	apiErr, ok := err.(*kubeAPIError)
+	return ok && apiErr.StatusCode == http.StatusNotFound
+}
+
+func (c *kubeClient) do(method, path string, in, out interface{}) error {
+	var body bytes.Buffer
+	if in != nil {
+		if err := json.NewEncoder(&body).Encode(in); err != nil {
+			return err
+		}
+	}
+
+	host := strings.TrimSuffix(c.config.Host, "/")
+	if !strings.Contains(host, "://") {
+		host = "https://" + host
+	}
+
+	req, err := http.NewRequest(method, host+path, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if c.config.ServiceAccountJWT != "" {
+		req.Header.Set("Authorization", "Bearer "+c.config.ServiceAccountJWT)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode > http.StatusPartialContent {
+		// Errors are returned as Status objects; fall back to the raw body
+		// if it isn't one
+		var status struct {
+			Message string `json:"message"`
+		}
+		message := strings.TrimSpace(string(respBody))
+		if err := json.Unmarshal(respBody, &status); err == nil && status.Message != "" {
+			message = status.Message
+		}
+		return &kubeAPIError{
+			StatusCode: resp.StatusCode,
+			Message:    message,
+		}
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(respBody, out)
+}
+
+type objectMeta struct {
+	Name      string            `json:"name"`
+	Namespace string            `json:"namespace,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+}
+
+func (c *kubeClient) createServiceAccount(namespace, name string) error {
+	return c.do("POST", fmt.Sprintf("/api/v1/namespaces/%s/serviceaccounts", namespace), map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ServiceAccount",
+		"metadata": objectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    map[string]string{managedByLabel: "vault"},
+		},
+	}, nil)
+}
+
+func (c *kubeClient) deleteServiceAccount(namespace, name string) error {
+	err := c.do("DELETE", fmt.Sprintf("/api/v1/namespaces/%s/serviceaccounts/%s", namespace, name), nil, nil)
+	if isNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// createRoleBinding binds the service account to the Role or ClusterRole
+// within its namespace
+func (c *kubeClient) createRoleBinding(namespace, name, roleType, roleName, serviceAccountName string) error {
+	return c.do("POST", fmt.Sprintf("/apis/rbac.authorization.k8s.io/v1/namespaces/%s/rolebindings", namespace), map[string]interface{}{
+		"apiVersion": "rbac.authorization.k8s.io/v1",
+		"kind":       "RoleBinding",
+		"metadata": objectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    map[string]string{managedByLabel: "vault"},
+		},
+		"roleRef": map[string]string{
+			"apiGroup": "rbac.authorization.k8s.io",
+			"kind":     roleType,
+			"name":     roleName,
+		},
+		"subjects": []map[string]string{
+			{
+				"kind":      "ServiceAccount",
+				"name":      serviceAccountName,
+				"namespace": namespace,
+			},
+		},
+	}, nil)
+}
+
+func (c *kubeClient) deleteRoleBinding(namespace, name string) error {
+	err := c.do("DELETE", fmt.Sprintf("/apis/rbac.authorization.k8s.io/v1/namespaces/%s/rolebindings/%s", namespace, name), nil, nil)
+	if isNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// createToken uses the TokenRequest API to issue a token for the service
+// account, returning the token and the time it expires
+func (c *kubeClient) createToken(namespace, serviceAccountName string, ttl time.Duration, audiences []string) (string, time.Time, error) {
+	var tokenRequest struct {
+		Status struct {
+			Token               string    `json:"token"`
+			ExpirationTimestamp time.Time `json:"expirationTimestamp"`
+		} `json:"status"`
+	}
+
+	err := c.do("POST", fmt.Sprintf("/api/v1/namespaces/%s/serviceaccounts/%s/token", namespace, serviceAccountName), map[string]interface{}{
+		"apiVersion": "authentication.k8s.io/v1",
+		"kind":       "TokenRequest",
+		"spec": map[string]interface{}{
+			"audiences":         audiences,
+			"expirationSeconds": int64(ttl.Seconds()),
+		},
+	}, &tokenRequest)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return tokenRequest.Status.Token, tokenRequest.Status.ExpirationTimestamp, nil
+}
@@ -0,0 +1,128 @@
+package kubernetes
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+func pathConfig(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config",
+		Fields: map[string]*framework.FieldSchema{
+			"kubernetes_host": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Host must be a host string, a host:port pair, or a URL to the base of the Kubernetes API server.",
+			},
+
+			"kubernetes_ca_cert": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "PEM encoded CA cert for use by the TLS client used to talk with the Kubernetes API.",
+			},
+
+			"service_account_jwt": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `A service account JWT used to access the Kubernetes API. It
+must be allowed to create tokens for service accounts, and to
+create and delete service accounts and role bindings if roles
+bind new service accounts to Kubernetes roles.`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathConfigRead,
+			logical.UpdateOperation: b.pathConfigWrite,
+		},
+
+		HelpSynopsis:    pathConfigHelpSyn,
+		HelpDescription: pathConfigHelpDesc,
+	}
+}
+
+func (b *backend) pathConfigRead(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config, err := b.config(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return nil, nil
+	}
+
+	// The service account JWT is not returned
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"kubernetes_host":    config.Host,
+			"kubernetes_ca_cert": config.CACert,
+		},
+	}, nil
+}
+
+func (b *backend) pathConfigWrite(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	host := d.Get("kubernetes_host").(string)
+	if host == "" {
+		return logical.ErrorResponse("no host provided"), nil
+	}
+
+	caCert := d.Get("kubernetes_ca_cert").(string)
+	if caCert != "" {
+		block, _ := pem.Decode([]byte(caCert))
+		if block == nil {
+			return logical.ErrorResponse("kubernetes_ca_cert is not PEM encoded"), nil
+		}
+		if _, err := x509.ParseCertificate(block.Bytes); err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("error parsing kubernetes_ca_cert: %s", err)), nil
+		}
+	}
+
+	entry, err := logical.StorageEntryJSON("config", &kubeConfig{
+		Host:              host,
+		CACert:            caCert,
+		ServiceAccountJWT: d.Get("service_account_jwt").(string),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(entry); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func (b *backend) config(s logical.Storage) (*kubeConfig, error) {
+	entry, err := s.Get("config")
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var config kubeConfig
+	if err := entry.DecodeJSON(&config); err != nil {
+		return nil, fmt.Errorf("error reading kubernetes configuration: %s", err)
+	}
+	return &config, nil
+}
+
+// kubeConfig contains the information needed to talk to the Kubernetes API
+type kubeConfig struct {
+	Host              string `json:"kubernetes_host"`
+	CACert            string `json:"kubernetes_ca_cert"`
+	ServiceAccountJWT string `json:"service_account_jwt"`
+}
+
+const pathConfigHelpSyn = `
+Configure the Kubernetes API that service account tokens are issued by.
+`
+
+const pathConfigHelpDesc = `
+This path configures the address and CA certificate of the Kubernetes API,
+and the JWT of the service account Vault uses to create tokens, service
+accounts and role bindings.
+`
@@ -0,0 +1,200 @@
+package kubernetes
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	uuid "github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// minTokenTTL is the shortest lifetime the TokenRequest API accepts
+const minTokenTTL = 10 * time.Minute
+
+func pathCreds(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "creds/" + framework.GenericNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of the role",
+			},
+
+			"kubernetes_namespace": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Kubernetes namespace to issue the service account token in",
+			},
+
+			"ttl": &framework.FieldSchema{
+				Type: framework.TypeDurationSecond,
+				Description: `Lifetime of the token. Defaults to the token_default_ttl
+of the role, and must be at least 10 minutes.`,
+			},
+
+			"audiences": &framework.FieldSchema{
+				Type: framework.TypeCommaStringSlice,
+				Description: `Audiences of the token. Defaults to the
+token_default_audiences of the role.`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathCredsCreate,
+		},
+
+		HelpSynopsis:    pathCredsHelpSyn,
+		HelpDescription: pathCredsHelpDesc,
+	}
+}
+
+func (b *backend) pathCredsCreate(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	role, err := b.Role(req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return logical.ErrorResponse(fmt.Sprintf("unknown role: %s", name)), nil
+	}
+
+	namespace := d.Get("kubernetes_namespace").(string)
+	if namespace == "" {
+		return logical.ErrorResponse("kubernetes_namespace must be given"), nil
+	}
+	if !strutil.StrListContains(role.AllowedNamespaces, "*") && !strutil.StrListContains(role.AllowedNamespaces, namespace) {
+		return logical.ErrorResponse(fmt.Sprintf("namespace %q is not allowed by the role", namespace)), nil
+	}
+
+	config, err := b.config(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return logical.ErrorResponse("the Kubernetes API has not been configured"), nil
+	}
+
+	var warnings []string
+	ttl := time.Duration(d.Get("ttl").(int)) * time.Second
+	if ttl == 0 {
+		ttl = role.TokenDefaultTTL
+	}
+	if ttl == 0 {
+		ttl = b.System().DefaultLeaseTTL()
+	}
+	maxTTL := b.System().MaxLeaseTTL()
+	if role.TokenMaxTTL > 0 && role.TokenMaxTTL < maxTTL {
+		maxTTL = role.TokenMaxTTL
+	}
+	if ttl > maxTTL {
+		warnings = append(warnings, fmt.Sprintf("ttl of %s is greater than the maximum of %s and was capped", ttl, maxTTL))
+		ttl = maxTTL
+	}
+	if ttl < minTokenTTL {
+		return logical.ErrorResponse(fmt.Sprintf("ttl must be at least %s", minTokenTTL)), nil
+	}
+
+	audiences := d.Get("audiences").([]string)
+	if len(audiences) == 0 {
+		audiences = role.TokenDefaultAudiences
+	}
+
+	client := newKubeClient(config)
+
+	serviceAccountName := role.ServiceAccountName
+	ephemeral := serviceAccountName == ""
+	var walID string
+	if ephemeral {
+		serviceAccountName, err = genServiceAccountName(name)
+		if err != nil {
+			return nil, err
+		}
+
+		// Write to the WAL that the service account and its role binding
+		// will be created, so they are removed if issuing the token fails
+		walID, err = framework.PutWAL(req.Storage, "service_account", &walServiceAccount{
+			Namespace: namespace,
+			Name:      serviceAccountName,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error writing WAL entry: %s", err)
+		}
+
+		if err := client.createServiceAccount(namespace, serviceAccountName); err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("error creating service account: %s", err)), nil
+		}
+		err := client.createRoleBinding(namespace, serviceAccountName, role.KubernetesRoleType, role.KubernetesRoleName, serviceAccountName)
+		if err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("error creating role binding: %s", err)), nil
+		}
+	}
+
+	token, expiration, err := client.createToken(namespace, serviceAccountName, ttl, audiences)
+	if err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("error creating service account token: %s", err)), nil
+	}
+
+	if walID != "" {
+		if err := framework.DeleteWAL(req.Storage, walID); err != nil {
+			return nil, fmt.Errorf("failed to commit WAL entry: %s", err)
+		}
+	}
+
+	resp := b.Secret(SecretServiceAccountTokenType).Response(map[string]interface{}{
+		"service_account_token":     token,
+		"service_account_name":      serviceAccountName,
+		"service_account_namespace": namespace,
+	}, map[string]interface{}{
+		"service_account_name":      serviceAccountName,
+		"service_account_namespace": namespace,
+		"ephemeral":                 ephemeral,
+	})
+
+	// The token can't be renewed, so the lease ends when it expires
+	resp.Secret.TTL = ttl
+	if !expiration.IsZero() {
+		resp.Secret.TTL = expiration.Sub(time.Now())
+	}
+	resp.Secret.Renewable = false
+
+	for _, warning := range warnings {
+		resp.AddWarning(warning)
+	}
+
+	return resp, nil
+}
+
+var invalidNameChars = regexp.MustCompile("[^a-z0-9-]")
+
+// genServiceAccountName returns a unique, valid Kubernetes object name for a
+// service account created for the role
+func genServiceAccountName(roleName string) (string, error) {
+	id, err := uuid.GenerateUUID()
+	if err != nil {
+		return "", err
+	}
+
+	normalized := invalidNameChars.ReplaceAllString(strings.ToLower(roleName), "-")
+	if len(normalized) > 32 {
+		normalized = normalized[:32]
+	}
+	return fmt.Sprintf("vault-%s-%s", normalized, id[:8]), nil
+}
+
+const pathCredsHelpSyn = `
+Issue a Kubernetes service account token for a role.
+`
+
+const pathCredsHelpDesc = `
+This path issues a token for the service account of the role in the given
+Kubernetes namespace. If the role binds new service accounts to a Kubernetes
+Role or ClusterRole, a service account and role binding are created for the
+token and deleted when the lease is revoked.
+
+The lease of the token matches its lifetime and cannot be renewed.
+`
@@ -0,0 +1,218 @@
+package kubernetes
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+func pathListRoles(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "roles/?$",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ListOperation: b.pathRoleList,
+		},
+
+		HelpSynopsis:    pathListRolesHelpSyn,
+		HelpDescription: pathListRolesHelpDesc,
+	}
+}
+
+func pathRoles(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "roles/" + framework.GenericNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of the role",
+			},
+
+			"allowed_kubernetes_namespaces": &framework.FieldSchema{
+				Type: framework.TypeCommaStringSlice,
+				Description: `Kubernetes namespaces that credentials can be requested
+for. If set to "*", all namespaces are allowed.`,
+			},
+
+			"service_account_name": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `Existing Kubernetes service account to issue tokens for.
+Mutually exclusive with kubernetes_role_name.`,
+			},
+
+			"kubernetes_role_name": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `Kubernetes Role or ClusterRole that a new service account
+is bound to for each set of credentials. Mutually exclusive with
+service_account_name.`,
+			},
+
+			"kubernetes_role_type": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Default:     "Role",
+				Description: `Type of kubernetes_role_name: "Role" or "ClusterRole".`,
+			},
+
+			"token_default_ttl": &framework.FieldSchema{
+				Type: framework.TypeDurationSecond,
+				Description: `Default lifetime of issued tokens. Defaults to the default
+lease TTL of the mount.`,
+			},
+
+			"token_max_ttl": &framework.FieldSchema{
+				Type: framework.TypeDurationSecond,
+				Description: `Maximum lifetime of issued tokens. Defaults to the maximum
+lease TTL of the mount.`,
+			},
+
+			"token_default_audiences": &framework.FieldSchema{
+				Type: framework.TypeCommaStringSlice,
+				Description: `Audiences of issued tokens if none are requested. Defaults
+to the audience of the Kubernetes API server.`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.DeleteOperation: b.pathRoleDelete,
+			logical.ReadOperation:   b.pathRoleRead,
+			logical.UpdateOperation: b.pathRoleWrite,
+		},
+
+		HelpSynopsis:    pathRolesHelpSyn,
+		HelpDescription: pathRolesHelpDesc,
+	}
+}
+
+func (b *backend) Role(s logical.Storage, name string) (*roleEntry, error) {
+	entry, err := s.Get("role/" + name)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var result roleEntry
+	if err := entry.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (b *backend) pathRoleList(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	entries, err := req.Storage.List("role/")
+	if err != nil {
+		return nil, err
+	}
+	return logical.ListResponse(entries), nil
+}
+
+func (b *backend) pathRoleDelete(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	err := req.Storage.Delete("role/" + d.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+func (b *backend) pathRoleRead(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	role, err := b.Role(req.Storage, d.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"allowed_kubernetes_namespaces": role.AllowedNamespaces,
+			"service_account_name":          role.ServiceAccountName,
+			"kubernetes_role_name":          role.KubernetesRoleName,
+			"kubernetes_role_type":          role.KubernetesRoleType,
+			"token_default_ttl":             int64(role.TokenDefaultTTL.Seconds()),
+			"token_max_ttl":                 int64(role.TokenMaxTTL.Seconds()),
+			"token_default_audiences":       role.TokenDefaultAudiences,
+		},
+	}, nil
+}
+
+func (b *backend) pathRoleWrite(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	role := &roleEntry{
+		AllowedNamespaces:     d.Get("allowed_kubernetes_namespaces").([]string),
+		ServiceAccountName:    d.Get("service_account_name").(string),
+		KubernetesRoleName:    d.Get("kubernetes_role_name").(string),
+		KubernetesRoleType:    d.Get("kubernetes_role_type").(string),
+		TokenDefaultTTL:       time.Duration(d.Get("token_default_ttl").(int)) * time.Second,
+		TokenMaxTTL:           time.Duration(d.Get("token_max_ttl").(int)) * time.Second,
+		TokenDefaultAudiences: d.Get("token_default_audiences").([]string),
+	}
+
+	if len(role.AllowedNamespaces) == 0 {
+		return logical.ErrorResponse("allowed_kubernetes_namespaces must be given"), nil
+	}
+
+	switch {
+	case role.ServiceAccountName == "" && role.KubernetesRoleName == "":
+		return logical.ErrorResponse("one of service_account_name or kubernetes_role_name must be given"), nil
+	case role.ServiceAccountName != "" && role.KubernetesRoleName != "":
+		return logical.ErrorResponse("only one of service_account_name or kubernetes_role_name can be given"), nil
+	}
+
+	switch role.KubernetesRoleType {
+	case "Role", "ClusterRole":
+	default:
+		return logical.ErrorResponse(fmt.Sprintf(
+			"kubernetes_role_type must be \"Role\" or \"ClusterRole\", got %q", role.KubernetesRoleType)), nil
+	}
+
+	if role.TokenMaxTTL > 0 && role.TokenDefaultTTL > role.TokenMaxTTL {
+		return logical.ErrorResponse("token_default_ttl cannot be greater than token_max_ttl"), nil
+	}
+
+	entry, err := logical.StorageEntryJSON("role/"+d.Get("name").(string), role)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(entry); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+type roleEntry struct {
+	AllowedNamespaces     []string      `json:"allowed_kubernetes_namespaces"`
+	ServiceAccountName    string        `json:"service_account_name"`
+	KubernetesRoleName    string        `json:"kubernetes_role_name"`
+	KubernetesRoleType    string        `json:"kubernetes_role_type"`
+	TokenDefaultTTL       time.Duration `json:"token_default_ttl"`
+	TokenMaxTTL           time.Duration `json:"token_max_ttl"`
+	TokenDefaultAudiences []string      `json:"token_default_audiences"`
+}
+
+const pathListRolesHelpSyn = `List the existing roles in this backend`
+
+const pathListRolesHelpDesc = `Roles will be listed by the role name.`
+
+const pathRolesHelpSyn = `
+Manage the roles that Kubernetes service account tokens can be issued for.
+`
+
+const pathRolesHelpDesc = `
+This path lets you manage the roles that can be used to issue Kubernetes
+service account tokens. A role either issues tokens for an existing service
+account given in "service_account_name", or creates a new service account for
+each set of credentials and binds it to the Role or ClusterRole given in
+"kubernetes_role_name". Service accounts created by Vault are deleted when
+their lease is revoked.
+
+Credentials can only be requested in the namespaces listed in
+"allowed_kubernetes_namespaces".
+`
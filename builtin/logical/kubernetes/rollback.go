@@ -0,0 +1,28 @@
+package kubernetes
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/mitchellh/mapstructure"
+)
+
+// walServiceAccount is written before a service account is created for a
+// lease, so that it is removed if the credentials are never returned
+type walServiceAccount struct {
+	Namespace string
+	Name      string
+}
+
+func (b *backend) walRollback(req *logical.Request, kind string, data interface{}) error {
+	if kind != "service_account" {
+		return fmt.Errorf("unknown type to rollback")
+	}
+
+	var entry walServiceAccount
+	if err := mapstructure.Decode(data, &entry); err != nil {
+		return err
+	}
+
+	return b.deleteServiceAccount(req.Storage, entry.Namespace, entry.Name)
+}
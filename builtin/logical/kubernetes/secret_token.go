@@ -0,0 +1,86 @@
+package kubernetes
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+const SecretServiceAccountTokenType = "service_account_token"
+
+func secretServiceAccountToken(b *backend) *framework.Secret {
+	return &framework.Secret{
+		Type: SecretServiceAccountTokenType,
+		Fields: map[string]*framework.FieldSchema{
+			"service_account_token": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Service account token",
+			},
+
+			"service_account_name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of the service account",
+			},
+
+			"service_account_namespace": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Namespace of the service account",
+			},
+		},
+
+		Revoke: b.secretServiceAccountTokenRevoke,
+	}
+}
+
+func (b *backend) secretServiceAccountTokenRevoke(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	// Tokens for existing service accounts can't be revoked before they
+	// expire; only the service accounts created for a lease are removed
+	ephemeralRaw, ok := req.Secret.InternalData["ephemeral"]
+	if !ok {
+		return nil, fmt.Errorf("secret is missing ephemeral internal data")
+	}
+	ephemeral, ok := ephemeralRaw.(bool)
+	if !ok {
+		return nil, fmt.Errorf("secret has ephemeral but value could not be understood")
+	}
+	if !ephemeral {
+		return nil, nil
+	}
+
+	name, ok := req.Secret.InternalData["service_account_name"].(string)
+	if !ok {
+		return nil, fmt.Errorf("secret is missing service_account_name internal data")
+	}
+	namespace, ok := req.Secret.InternalData["service_account_namespace"].(string)
+	if !ok {
+		return nil, fmt.Errorf("secret is missing service_account_namespace internal data")
+	}
+
+	if err := b.deleteServiceAccount(req.Storage, namespace, name); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// deleteServiceAccount removes a service account created by Vault along with
+// its role binding, which share the same name
+func (b *backend) deleteServiceAccount(s logical.Storage, namespace, name string) error {
+	config, err := b.config(s)
+	if err != nil {
+		return err
+	}
+	if config == nil {
+		return fmt.Errorf("the Kubernetes API has not been configured")
+	}
+
+	client := newKubeClient(config)
+	if err := client.deleteRoleBinding(namespace, name); err != nil {
+		return fmt.Errorf("error deleting role binding: %s", err)
+	}
+	if err := client.deleteServiceAccount(namespace, name); err != nil {
+		return fmt.Errorf("error deleting service account: %s", err)
+	}
+	return nil
+}
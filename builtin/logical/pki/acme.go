@@ -0,0 +1,471 @@
+package pki
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+const (
+	acmeNonceTTL          = 10 * time.Minute
+	acmeOrderTTL          = 24 * time.Hour
+	acmeValidationTimeout = 10 * time.Second
+
+	acmeStatusPending     = "pending"
+	acmeStatusProcessing  = "processing"
+	acmeStatusReady       = "ready"
+	acmeStatusValid       = "valid"
+	acmeStatusInvalid     = "invalid"
+	acmeStatusDeactivated = "deactivated"
+
+	acmeChallengeHTTP01 = "http-01"
+	acmeChallengeDNS01  = "dns-01"
+
+	acmeErrorPrefix = "urn:ietf:params:acme:error:"
+)
+
+// acmeSignatureAlgorithms are the JWS algorithms accepted on ACME requests
+var acmeSignatureAlgorithms = []string{
+	string(jose.RS256),
+	string(jose.ES256),
+	string(jose.ES384),
+	string(jose.ES512),
+	string(jose.EdDSA),
+}
+
+// acmeProblem is an ACME error, returned as an RFC 7807 problem document
+type acmeProblem struct {
+	Type   string `json:"type"`
+	Detail string `json:"detail"`
+	Status int    `json:"status"`
+}
+
+func newACMEProblem(status int, errType, format string, args ...interface{}) *acmeProblem {
+	return &acmeProblem{
+		Type:   acmeErrorPrefix + errType,
+		Detail: fmt.Sprintf(format, args...),
+		Status: status,
+	}
+}
+
+type acmeAccount struct {
+	ID          string          `json:"id"`
+	Key         json.RawMessage `json:"key"`
+	Thumbprint  string          `json:"thumbprint"`
+	Status      string          `json:"status"`
+	Contact     []string        `json:"contact"`
+	CreatedTime time.Time       `json:"created_time"`
+}
+
+type acmeIdentifier struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type acmeOrder struct {
+	ID             string           `json:"id"`
+	AccountID      string           `json:"account_id"`
+	Role           string           `json:"role"`
+	Status         string           `json:"status"`
+	Expires        time.Time        `json:"expires"`
+	Identifiers    []acmeIdentifier `json:"identifiers"`
+	Authorizations []string         `json:"authorizations"`
+	CertSerial     string           `json:"cert_serial"`
+	CertChain      string           `json:"cert_chain"`
+	Error          *acmeProblem     `json:"error,omitempty"`
+}
+
+type acmeChallenge struct {
+	Type      string       `json:"type"`
+	Token     string       `json:"token"`
+	Status    string       `json:"status"`
+	Validated time.Time    `json:"validated"`
+	Error     *acmeProblem `json:"error,omitempty"`
+}
+
+type acmeAuthorization struct {
+	ID         string           `json:"id"`
+	AccountID  string           `json:"account_id"`
+	Role       string           `json:"role"`
+	Status     string           `json:"status"`
+	Expires    time.Time        `json:"expires"`
+	Identifier acmeIdentifier   `json:"identifier"`
+	Wildcard   bool             `json:"wildcard"`
+	Challenges []*acmeChallenge `json:"challenges"`
+}
+
+// acmeRequest is a verified ACME request
+type acmeRequest struct {
+	config  *acmeConfig
+	role    string
+	payload []byte
+	jwk     *jose.JSONWebKey
+	account *acmeAccount
+}
+
+// acmeURL returns the absolute URL of the ACME endpoint of the role
+func (c *acmeConfig) acmeURL(role, path string) string {
+	return c.BaseURL + "/acme/" + role + "/" + path
+}
+
+// acmeRandomID returns a random identifier suitable for URLs and challenge
+// tokens
+func acmeRandomID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// newACMENonce issues a nonce that can be used once on a later request
+func (b *backend) newACMENonce() (string, error) {
+	nonce, err := acmeRandomID()
+	if err != nil {
+		return "", err
+	}
+
+	b.acmeNoncesLock.Lock()
+	defer b.acmeNoncesLock.Unlock()
+
+	now := time.Now()
+	for n, expires := range b.acmeNonces {
+		if now.After(expires) {
+			delete(b.acmeNonces, n)
+		}
+	}
+	b.acmeNonces[nonce] = now.Add(acmeNonceTTL)
+	return nonce, nil
+}
+
+// consumeACMENonce returns whether the nonce was issued and not yet used
+func (b *backend) consumeACMENonce(nonce string) bool {
+	b.acmeNoncesLock.Lock()
+	defer b.acmeNoncesLock.Unlock()
+
+	expires, ok := b.acmeNonces[nonce]
+	if !ok {
+		return false
+	}
+	delete(b.acmeNonces, nonce)
+	return time.Now().Before(expires)
+}
+
+// acmeResponse returns a raw response carrying the body as JSON along with a
+// fresh nonce and the headers
+func (b *backend) acmeResponse(config *acmeConfig, role string, status int, body interface{}, headers map[string][]string) (*logical.Response, error) {
+	contentType := "application/json"
+	var raw []byte
+	switch body := body.(type) {
+	case nil:
+	case *acmeProblem:
+		contentType = "application/problem+json"
+		status = body.Status
+		var err error
+		if raw, err = json.Marshal(body); err != nil {
+			return nil, err
+		}
+	case []byte:
+		contentType = "application/pem-certificate-chain"
+		raw = body
+	default:
+		var err error
+		if raw, err = json.Marshal(body); err != nil {
+			return nil, err
+		}
+	}
+
+	nonce, err := b.newACMENonce()
+	if err != nil {
+		return nil, err
+	}
+	if headers == nil {
+		headers = make(map[string][]string)
+	}
+	headers["Replay-Nonce"] = []string{nonce}
+	headers["Cache-Control"] = []string{"no-store"}
+	if config != nil {
+		headers["Link"] = append(headers["Link"], fmt.Sprintf(`<%s>;rel="index"`, config.acmeURL(role, "directory")))
+	}
+
+	data := map[string]interface{}{
+		logical.HTTPStatusCode: status,
+		logical.HTTPRawHeaders: headers,
+	}
+	if raw != nil {
+		data[logical.HTTPContentType] = contentType
+		data[logical.HTTPRawBody] = raw
+	}
+	return &logical.Response{
+		Data: data,
+	}, nil
+}
+
+// acmeProblemResponse returns the problem as a raw response
+func (b *backend) acmeProblemResponse(config *acmeConfig, role string, problem *acmeProblem) (*logical.Response, error) {
+	return b.acmeResponse(config, role, problem.Status, problem, nil)
+}
+
+// acmeEnabled returns the ACME configuration if ACME orders can be placed
+// against the role
+func (b *backend) acmeEnabled(req *logical.Request, roleName string) (*acmeConfig, *roleEntry, *acmeProblem, error) {
+	config, err := b.acmeConfig(req.Storage)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if config == nil || !config.Enabled {
+		return nil, nil, newACMEProblem(http.StatusNotFound, "malformed", "ACME is not enabled on this backend"), nil
+	}
+	if !config.roleAllowed(roleName) {
+		return config, nil, newACMEProblem(http.StatusForbidden, "unauthorized", "ACME is not allowed for role %q", roleName), nil
+	}
+
+	role, err := b.getRole(req.Storage, roleName)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if role == nil {
+		return config, nil, newACMEProblem(http.StatusNotFound, "malformed", "unknown role %q", roleName), nil
+	}
+	return config, role, nil, nil
+}
+
+// verifyACMERequest verifies the JWS in the request body was signed for the
+// URL with a fresh nonce. Requests creating an account must carry the
+// account key; all others must name an existing account.
+func (b *backend) verifyACMERequest(req *logical.Request, data *framework.FieldData, config *acmeConfig, role, path string, newAccount bool) (*acmeRequest, *acmeProblem, error) {
+	body, err := json.Marshal(map[string]string{
+		"protected": data.Get("protected").(string),
+		"payload":   data.Get("payload").(string),
+		"signature": data.Get("signature").(string),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	jws, err := jose.ParseSigned(string(body))
+	if err != nil {
+		return nil, newACMEProblem(http.StatusBadRequest, "malformed", "failed to parse JWS: %v", err), nil
+	}
+	if len(jws.Signatures) != 1 {
+		return nil, newACMEProblem(http.StatusBadRequest, "malformed", "JWS must have exactly one signature"), nil
+	}
+	header := jws.Signatures[0].Header
+
+	supported := false
+	for _, alg := range acmeSignatureAlgorithms {
+		if header.Algorithm == alg {
+			supported = true
+		}
+	}
+	if !supported {
+		return nil, newACMEProblem(http.StatusBadRequest, "badSignatureAlgorithm", "unsupported signature algorithm %q", header.Algorithm), nil
+	}
+
+	if header.Nonce == "" || !b.consumeACMENonce(header.Nonce) {
+		return nil, newACMEProblem(http.StatusBadRequest, "badNonce", "invalid or reused nonce"), nil
+	}
+
+	url, _ := header.ExtraHeaders[jose.HeaderKey("url")].(string)
+	if url != config.acmeURL(role, path) {
+		return nil, newACMEProblem(http.StatusUnauthorized, "unauthorized", "JWS url %q does not match the request URL", url), nil
+	}
+
+	acmeReq := &acmeRequest{
+		config: config,
+		role:   role,
+	}
+	switch {
+	case newAccount:
+		if header.JSONWebKey == nil || header.KeyID != "" {
+			return nil, newACMEProblem(http.StatusBadRequest, "malformed", "JWS must carry the account key and no key ID"), nil
+		}
+		if !header.JSONWebKey.Valid() || !header.JSONWebKey.IsPublic() {
+			return nil, newACMEProblem(http.StatusBadRequest, "badPublicKey", "invalid account key"), nil
+		}
+		acmeReq.jwk = header.JSONWebKey
+
+	default:
+		if header.JSONWebKey != nil || header.KeyID == "" {
+			return nil, newACMEProblem(http.StatusBadRequest, "malformed", "JWS must carry a key ID and no key"), nil
+		}
+		accountPrefix := config.acmeURL(role, "account/")
+		if !strings.HasPrefix(header.KeyID, accountPrefix) {
+			return nil, newACMEProblem(http.StatusBadRequest, "accountDoesNotExist", "unknown account %q", header.KeyID), nil
+		}
+		account, err := b.acmeAccount(req.Storage, strings.TrimPrefix(header.KeyID, accountPrefix))
+		if err != nil {
+			return nil, nil, err
+		}
+		if account == nil {
+			return nil, newACMEProblem(http.StatusBadRequest, "accountDoesNotExist", "unknown account %q", header.KeyID), nil
+		}
+		if account.Status != acmeStatusValid {
+			return nil, newACMEProblem(http.StatusUnauthorized, "unauthorized", "account is %s", account.Status), nil
+		}
+		var jwk jose.JSONWebKey
+		if err := json.Unmarshal(account.Key, &jwk); err != nil {
+			return nil, nil, err
+		}
+		acmeReq.jwk = &jwk
+		acmeReq.account = account
+	}
+
+	payload, err := jws.Verify(acmeReq.jwk)
+	if err != nil {
+		return nil, newACMEProblem(http.StatusBadRequest, "malformed", "JWS verification failed"), nil
+	}
+	acmeReq.payload = payload
+	return acmeReq, nil, nil
+}
+
+// decodePayload decodes the JSON payload of the request into out. An empty
+// payload, as sent by POST-as-GET requests, leaves out unchanged.
+func (r *acmeRequest) decodePayload(out interface{}) *acmeProblem {
+	if len(r.payload) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(r.payload, out); err != nil {
+		return newACMEProblem(http.StatusBadRequest, "malformed", "failed to decode payload: %v", err)
+	}
+	return nil
+}
+
+// keyAuthorization returns the key authorization of the challenge token for
+// the account key
+func keyAuthorization(token string, jwk *jose.JSONWebKey) (string, error) {
+	thumbprint, err := jwk.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return "", err
+	}
+	return token + "." + base64.RawURLEncoding.EncodeToString(thumbprint), nil
+}
+
+// validateACMEChallenge checks that the client has provisioned the key
+// authorization for the challenge, returning a problem if not
+func (b *backend) validateACMEChallenge(authz *acmeAuthorization, challenge *acmeChallenge, jwk *jose.JSONWebKey) (*acmeProblem, error) {
+	keyAuth, err := keyAuthorization(challenge.Token, jwk)
+	if err != nil {
+		return nil, err
+	}
+	domain := authz.Identifier.Value
+
+	switch challenge.Type {
+	case acmeChallengeHTTP01:
+		url := fmt.Sprintf("http://%s/.well-known/acme-challenge/%s", domain, challenge.Token)
+		resp, err := b.acmeHTTPClient.Get(url)
+		if err != nil {
+			return newACMEProblem(http.StatusBadRequest, "connection", "failed to fetch %s: %v", url, err), nil
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return newACMEProblem(http.StatusForbidden, "unauthorized", "fetching %s returned status %d", url, resp.StatusCode), nil
+		}
+		body, err := ioutil.ReadAll(io.LimitReader(resp.Body, 4096))
+		if err != nil {
+			return newACMEProblem(http.StatusBadRequest, "connection", "failed to read %s: %v", url, err), nil
+		}
+		if strings.TrimSpace(string(body)) != keyAuth {
+			return newACMEProblem(http.StatusForbidden, "incorrectResponse", "key authorization at %s does not match", url), nil
+		}
+
+	case acmeChallengeDNS01:
+		name := "_acme-challenge." + domain
+		records, err := b.acmeLookupTXT(name)
+		if err != nil {
+			return newACMEProblem(http.StatusBadRequest, "dns", "failed to look up TXT records of %s: %v", name, err), nil
+		}
+		sum := sha256.Sum256([]byte(keyAuth))
+		expected := base64.RawURLEncoding.EncodeToString(sum[:])
+		found := false
+		for _, record := range records {
+			if record == expected {
+				found = true
+			}
+		}
+		if !found {
+			return newACMEProblem(http.StatusForbidden, "incorrectResponse", "no TXT record of %s matches the key authorization", name), nil
+		}
+
+	default:
+		return newACMEProblem(http.StatusBadRequest, "malformed", "unsupported challenge type %q", challenge.Type), nil
+	}
+
+	return nil, nil
+}
+
+func (b *backend) acmeAccount(s logical.Storage, id string) (*acmeAccount, error) {
+	var account acmeAccount
+	ok, err := getACMEEntry(s, "acme/account/"+id, &account)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return &account, nil
+}
+
+func (b *backend) acmeOrder(s logical.Storage, id string) (*acmeOrder, error) {
+	var order acmeOrder
+	ok, err := getACMEEntry(s, "acme/order/"+id, &order)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return &order, nil
+}
+
+func (b *backend) acmeAuthorization(s logical.Storage, id string) (*acmeAuthorization, error) {
+	var authz acmeAuthorization
+	ok, err := getACMEEntry(s, "acme/authz/"+id, &authz)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return &authz, nil
+}
+
+func getACMEEntry(s logical.Storage, key string, out interface{}) (bool, error) {
+	entry, err := s.Get(key)
+	if err != nil {
+		return false, err
+	}
+	if entry == nil {
+		return false, nil
+	}
+	if err := entry.DecodeJSON(out); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func putACMEEntry(s logical.Storage, key string, value interface{}) error {
+	entry, err := logical.StorageEntryJSON(key, value)
+	if err != nil {
+		return err
+	}
+	return s.Put(entry)
+}
+
+// defaultACMEHTTPClient is used to fetch http-01 challenge responses
+func defaultACMEHTTPClient() *http.Client {
+	return &http.Client{
+		Timeout: acmeValidationTimeout,
+		Transport: &http.Transport{
+			Proxy: nil,
+			DialContext: (&net.Dialer{
+				Timeout: acmeValidationTimeout,
+			}).DialContext,
+			DisableKeepAlives: true,
+		},
+	}
+}
@@ -1,6 +1,8 @@
 package pki
 
 import (
+	"net"
+	"net/http"
 	"strings"
 	"sync"
 	"time"
@@ -32,12 +34,26 @@ func Backend() *backend {
 				"ca",
 				"crl/pem",
 				"crl",
+				"crl/delta/pem",
+				"crl/delta",
+				"crl/info",
+				"crl/issuer/*",
+				"acme/*",
+			},
+
+			ExportableStorage: []string{
+				"role/",
 			},
 
 			LocalStorage: []string{
 				"revoked/",
 				"crl",
 				"certs/",
+				"cert-index/",
+				"cert-index-cn/",
+				"cert-index-san/",
+				"cert-index-expiry/",
+				"cert-index-status",
 			},
 
 			Root: []string{
@@ -58,6 +74,8 @@ func Backend() *backend {
 			pathConfigCA(&b),
 			pathConfigCRL(&b),
 			pathConfigURLs(&b),
+			pathConfigACME(&b),
+			pathConfigIssuers(&b),
 			pathSignVerbatim(&b),
 			pathSign(&b),
 			pathIssue(&b),
@@ -65,21 +83,46 @@ func Backend() *backend {
 			pathFetchCA(&b),
 			pathFetchCAChain(&b),
 			pathFetchCRL(&b),
+			pathFetchDeltaCRL(&b),
+			pathFetchCRLInfo(&b),
+			pathFetchIssuerCRL(&b),
 			pathFetchCRLViaCertPath(&b),
 			pathFetchValid(&b),
 			pathFetchListCerts(&b),
 			pathRevoke(&b),
 			pathTidy(&b),
+			pathTidyStatus(&b),
+			pathTidyCancel(&b),
+			pathListIssuers(&b),
+			pathIssuer(&b),
+			pathImportIssuer(&b),
+			pathGenerateIssuerRoot(&b),
+			pathACMEDirectory(&b),
+			pathACMENewNonce(&b),
+			pathACMENewAccount(&b),
+			pathACMEAccount(&b),
+			pathACMENewOrder(&b),
+			pathACMEOrder(&b),
+			pathACMEOrderFinalize(&b),
+			pathACMEAuthorization(&b),
+			pathACMEChallenge(&b),
+			pathACMECert(&b),
 		},
 
 		Secrets: []*framework.Secret{
 			secretCerts(&b),
 		},
 
+		PeriodicFunc: b.rebuildCRLs,
+		Clean:        b.cancelTidy,
+
 		BackendType: logical.TypeLogical,
 	}
 
 	b.crlLifetime = time.Hour * 72
+	b.acmeNonces = make(map[string]time.Time)
+	b.acmeHTTPClient = defaultACMEHTTPClient()
+	b.acmeLookupTXT = net.LookupTXT
 
 	return &b
 }
@@ -89,6 +132,28 @@ type backend struct {
 
 	crlLifetime       time.Duration
 	revokeStorageLock sync.RWMutex
+
+	// crlBuildLock serializes CRL builds, which share the CRL number
+	// sequence
+	crlBuildLock sync.Mutex
+
+	// certIndexLock serializes the indexing of the certificates stored
+	// before the index existed
+	certIndexLock sync.Mutex
+
+	// acmeLock serializes changes to ACME accounts, orders and
+	// authorizations. Nonces are only kept in memory, as ACME clients retry
+	// requests rejected for a bad nonce.
+	acmeLock       sync.Mutex
+	acmeNoncesLock sync.Mutex
+	acmeNonces     map[string]time.Time
+
+	// acmeHTTPClient and acmeLookupTXT are used to validate challenges
+	acmeHTTPClient *http.Client
+	acmeLookupTXT  func(string) ([]string, error)
+
+	tidyStatusLock sync.RWMutex
+	tidyStatus     *tidyStatus
 }
 
 const backendHelp = `
@@ -65,7 +65,7 @@ func TestBackend_RSAKey(t *testing.T) {
 
 	intdata := map[string]interface{}{}
 	reqdata := map[string]interface{}{}
-	testCase.Steps = append(testCase.Steps, generateCATestingSteps(t, rsaCACert, rsaCAKey, ecCACert, intdata, reqdata)...)
+	testCase.Steps = append(testCase.Steps, generateCATestingSteps(t, b, rsaCACert, rsaCAKey, ecCACert, intdata, reqdata)...)
 
 	logicaltest.Test(t, testCase)
 }
@@ -95,7 +95,7 @@ func TestBackend_ECKey(t *testing.T) {
 
 	intdata := map[string]interface{}{}
 	reqdata := map[string]interface{}{}
-	testCase.Steps = append(testCase.Steps, generateCATestingSteps(t, ecCACert, ecCAKey, rsaCACert, intdata, reqdata)...)
+	testCase.Steps = append(testCase.Steps, generateCATestingSteps(t, b, ecCACert, ecCAKey, rsaCACert, intdata, reqdata)...)
 
 	logicaltest.Test(t, testCase)
 }
@@ -721,7 +721,7 @@ func generateCSRSteps(t *testing.T, caCert, caKey string, intdata, reqdata map[s
 
 // Generates steps to test out CA configuration -- certificates + CRL expiry,
 // and ensure that the certificates are readable after storing them
-func generateCATestingSteps(t *testing.T, caCert, caKey, otherCaCert string, intdata, reqdata map[string]interface{}) []logicaltest.TestStep {
+func generateCATestingSteps(t *testing.T, b logical.Backend, caCert, caKey, otherCaCert string, intdata, reqdata map[string]interface{}) []logicaltest.TestStep {
 	setSerialUnderTest := func(req *logical.Request) error {
 		req.Path = serialUnderTest
 		return nil
@@ -1231,6 +1231,9 @@ func generateCATestingSteps(t *testing.T, caCert, caKey, otherCaCert string, int
 				"tidy_cert_store":      true,
 				"tidy_revocation_list": true,
 			},
+			Check: func(resp *logical.Response) error {
+				return waitForTidy(b)
+			},
 		},
 
 		// We still expect to find these
@@ -1303,6 +1306,9 @@ func generateCATestingSteps(t *testing.T, caCert, caKey, otherCaCert string, int
 			Data: map[string]interface{}{
 				"safety_buffer": "1s",
 			},
+			Check: func(resp *logical.Response) error {
+				return waitForTidy(b)
+			},
 		},
 
 		// We still expect to find these
@@ -1343,6 +1349,9 @@ func generateCATestingSteps(t *testing.T, caCert, caKey, otherCaCert string, int
 				"tidy_cert_store":      true,
 				"tidy_revocation_list": true,
 			},
+			Check: func(resp *logical.Response) error {
+				return waitForTidy(b)
+			},
 		},
 
 		// We do *not* expect to find these
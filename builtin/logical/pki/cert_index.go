@@ -0,0 +1,304 @@
+package pki
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/helper/certutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/ryanuber/go-glob"
+)
+
+const (
+	// certIndexPrefix holds the metadata of each certificate by serial
+	certIndexPrefix = "cert-index/"
+
+	// The certificates are also indexed by common name, by Subject
+	// Alternative Name and by expiry day, under
+	// "<prefix><base64url name or day>/<serial>", so that the filtered list
+	// only reads the metadata of the certificates that can match
+	certIndexCNPrefix      = "cert-index-cn/"
+	certIndexAltNamePrefix = "cert-index-san/"
+	certIndexExpiryPrefix  = "cert-index-expiry/"
+	certIndexExpiryLayout  = "2006-01-02"
+
+	// certIndexStatusPath records that the certificates stored before the
+	// index existed have been indexed
+	certIndexStatusPath = "cert-index-status"
+)
+
+// certMetadata is the searchable summary of a stored certificate, kept at
+// "cert-index/<serial>" so that the certificate list can be filtered without
+// parsing every certificate.
+type certMetadata struct {
+	SerialNumber   string    `json:"serial_number"`
+	CommonName     string    `json:"common_name"`
+	AltNames       []string  `json:"alt_names"`
+	IsCA           bool      `json:"is_ca"`
+	NotBefore      time.Time `json:"not_before"`
+	NotAfter       time.Time `json:"not_after"`
+	Revoked        bool      `json:"revoked"`
+	RevocationTime time.Time `json:"revocation_time"`
+}
+
+func newCertMetadata(cert *x509.Certificate) *certMetadata {
+	meta := &certMetadata{
+		SerialNumber: certutil.GetHexFormatted(cert.SerialNumber.Bytes(), ":"),
+		CommonName:   cert.Subject.CommonName,
+		IsCA:         cert.IsCA,
+		NotBefore:    cert.NotBefore,
+		NotAfter:     cert.NotAfter,
+	}
+	meta.AltNames = append(meta.AltNames, cert.DNSNames...)
+	meta.AltNames = append(meta.AltNames, cert.EmailAddresses...)
+	for _, ip := range cert.IPAddresses {
+		meta.AltNames = append(meta.AltNames, ip.String())
+	}
+	return meta
+}
+
+// storeCert stores the certificate by serial number, so it can be fetched
+// and revoked, along with its index entry
+func storeCert(s logical.Storage, serial string, cert *x509.Certificate) error {
+	err := s.Put(&logical.StorageEntry{
+		Key:   "certs/" + normalizeSerial(serial),
+		Value: cert.Raw,
+	})
+	if err != nil {
+		return err
+	}
+	return storeCertMetadata(s, newCertMetadata(cert))
+}
+
+// storeCertMetadata stores the metadata of a certificate and its index keys
+func storeCertMetadata(s logical.Storage, meta *certMetadata) error {
+	serial := normalizeSerial(meta.SerialNumber)
+	entry, err := logical.StorageEntryJSON(certIndexPrefix+serial, meta)
+	if err != nil {
+		return err
+	}
+	if err := s.Put(entry); err != nil {
+		return err
+	}
+	for _, key := range meta.indexKeys(serial) {
+		if err := s.Put(&logical.StorageEntry{Key: key}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteCertMetadata removes the metadata of a certificate and its index
+// keys
+func deleteCertMetadata(s logical.Storage, serial string) error {
+	entry, err := s.Get(certIndexPrefix + serial)
+	if err != nil || entry == nil {
+		return err
+	}
+	var meta certMetadata
+	if err := entry.DecodeJSON(&meta); err != nil {
+		return err
+	}
+	for _, key := range meta.indexKeys(serial) {
+		if err := s.Delete(key); err != nil {
+			return err
+		}
+	}
+	return s.Delete(certIndexPrefix + serial)
+}
+
+// indexKeys returns the keys the certificate is indexed under
+func (m *certMetadata) indexKeys(serial string) []string {
+	var keys []string
+	if m.CommonName != "" {
+		keys = append(keys, certIndexCNPrefix+encodeIndexName(m.CommonName)+"/"+serial)
+	}
+	for _, name := range m.AltNames {
+		keys = append(keys, certIndexAltNamePrefix+encodeIndexName(name)+"/"+serial)
+	}
+	return append(keys, certIndexExpiryPrefix+m.NotAfter.UTC().Format(certIndexExpiryLayout)+"/"+serial)
+}
+
+func encodeIndexName(name string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(name))
+}
+
+// indexCerts indexes the certificates stored before the index existed, once
+func (b *backend) indexCerts(s logical.Storage) error {
+	b.certIndexLock.Lock()
+	defer b.certIndexLock.Unlock()
+
+	status, err := s.Get(certIndexStatusPath)
+	if err != nil || status != nil {
+		return err
+	}
+
+	serials, err := s.List("certs/")
+	if err != nil {
+		return err
+	}
+	for _, serial := range serials {
+		meta, err := fetchCertMetadata(s, serial)
+		if err != nil {
+			return err
+		}
+		if meta == nil {
+			continue
+		}
+		if err := storeCertMetadata(s, meta); err != nil {
+			return err
+		}
+	}
+
+	return s.Put(&logical.StorageEntry{
+		Key:   certIndexStatusPath,
+		Value: []byte("indexed"),
+	})
+}
+
+// serialsByName returns the serials indexed under the prefix with a name
+// matching the glob
+func serialsByName(s logical.Storage, prefix, pattern string) (map[string]bool, error) {
+	names, err := s.List(prefix)
+	if err != nil {
+		return nil, err
+	}
+	serials := make(map[string]bool)
+	for _, encoded := range names {
+		name, err := base64.RawURLEncoding.DecodeString(strings.TrimSuffix(encoded, "/"))
+		if err != nil || !glob.Glob(pattern, string(name)) {
+			continue
+		}
+		if err := addSerials(s, prefix+encoded, serials); err != nil {
+			return nil, err
+		}
+	}
+	return serials, nil
+}
+
+// serialsByExpiry returns the serials of the certificates expiring on the
+// days overlapping the given window; zero times leave it open
+func serialsByExpiry(s logical.Storage, after, before time.Time) (map[string]bool, error) {
+	days, err := s.List(certIndexExpiryPrefix)
+	if err != nil {
+		return nil, err
+	}
+	serials := make(map[string]bool)
+	for _, day := range days {
+		start, err := time.Parse(certIndexExpiryLayout, strings.TrimSuffix(day, "/"))
+		if err != nil {
+			continue
+		}
+		if (!after.IsZero() && !start.Add(24*time.Hour).After(after)) || (!before.IsZero() && !start.Before(before)) {
+			continue
+		}
+		if err := addSerials(s, certIndexExpiryPrefix+day, serials); err != nil {
+			return nil, err
+		}
+	}
+	return serials, nil
+}
+
+func addSerials(s logical.Storage, prefix string, serials map[string]bool) error {
+	keys, err := s.List(prefix)
+	if err != nil {
+		return err
+	}
+	for _, serial := range keys {
+		serials[serial] = true
+	}
+	return nil
+}
+
+// fetchCertMetadata returns the index entry of the stored certificate with
+// the given normalized serial, indexing the certificate if needed. It
+// returns nil if the certificate is not stored.
+func fetchCertMetadata(s logical.Storage, serial string) (*certMetadata, error) {
+	entry, err := s.Get(certIndexPrefix + serial)
+	if err != nil {
+		return nil, err
+	}
+	if entry != nil {
+		var meta certMetadata
+		if err := entry.DecodeJSON(&meta); err != nil {
+			return nil, err
+		}
+		return &meta, nil
+	}
+
+	certEntry, err := s.Get("certs/" + serial)
+	if err != nil {
+		return nil, err
+	}
+	if certEntry == nil {
+		return nil, nil
+	}
+	cert, err := x509.ParseCertificate(certEntry.Value)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse stored certificate with serial %s: %s", serial, err)
+	}
+	meta := newCertMetadata(cert)
+
+	revokedEntry, err := s.Get("revoked/" + serial)
+	if err != nil {
+		return nil, err
+	}
+	if revokedEntry != nil {
+		var revInfo revocationInfo
+		if err := revokedEntry.DecodeJSON(&revInfo); err != nil {
+			return nil, fmt.Errorf("error decoding revocation entry for serial %s: %s", serial, err)
+		}
+		meta.Revoked = true
+		meta.RevocationTime = time.Unix(revInfo.RevocationTime, 0).UTC()
+	}
+
+	if err := storeCertMetadata(s, meta); err != nil {
+		return nil, err
+	}
+	return meta, nil
+}
+
+// markCertRevoked records the revocation in the index entry of the
+// certificate
+func markCertRevoked(s logical.Storage, serial string, revocationTime time.Time) error {
+	meta, err := fetchCertMetadata(s, normalizeSerial(serial))
+	if err != nil || meta == nil {
+		return err
+	}
+	if meta.Revoked {
+		return nil
+	}
+	meta.Revoked = true
+	meta.RevocationTime = revocationTime.UTC()
+	return storeCertMetadata(s, meta)
+}
+
+// parseSerial parses a serial number in colon- or hyphen-separated hex
+func parseSerial(serial string) (*big.Int, error) {
+	hex := strings.NewReplacer(":", "", "-", "").Replace(serial)
+	n, ok := new(big.Int).SetString(hex, 16)
+	if !ok {
+		return nil, fmt.Errorf("invalid serial number %q", serial)
+	}
+	return n, nil
+}
+
+func (m *certMetadata) toMap() map[string]interface{} {
+	ret := map[string]interface{}{
+		"serial_number": m.SerialNumber,
+		"common_name":   m.CommonName,
+		"alt_names":     m.AltNames,
+		"is_ca":         m.IsCA,
+		"not_before":    m.NotBefore.UTC().Format(time.RFC3339),
+		"not_after":     m.NotAfter.UTC().Format(time.RFC3339),
+		"revoked":       m.Revoked,
+	}
+	if m.Revoked {
+		ret["revocation_time"] = m.RevocationTime.Format(time.RFC3339)
+	}
+	return ret
+}
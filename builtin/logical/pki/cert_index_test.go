@@ -0,0 +1,144 @@
+package pki
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestPki_CertListFilters(t *testing.T) {
+	b, storage := createBackendWithStorage(t)
+
+	handle := func(op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		resp, err := b.HandleRequest(&logical.Request{
+			Operation: op,
+			Path:      path,
+			Storage:   storage,
+			Data:      data,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: path: %s err: %v resp: %#v", path, err, resp)
+		}
+		return resp
+	}
+
+	resp := handle(logical.UpdateOperation, "root/generate/internal", map[string]interface{}{
+		"common_name": "myvault.com",
+		"ttl":         "20h",
+	})
+	rootSerial := normalizeSerial(resp.Data["serial_number"].(string))
+	handle(logical.UpdateOperation, "roles/testrole", map[string]interface{}{
+		"allowed_domains":  "myvault.com",
+		"allow_subdomains": true,
+		"max_ttl":          "20h",
+	})
+
+	issue := func(cn, ttl string, altNames string) string {
+		resp := handle(logical.UpdateOperation, "issue/testrole", map[string]interface{}{
+			"common_name": cn,
+			"alt_names":   altNames,
+			"ttl":         ttl,
+		})
+		return normalizeSerial(resp.Data["serial_number"].(string))
+	}
+	short := issue("short.myvault.com", "2h", "")
+	long := issue("long.myvault.com", "10h", "www.myvault.com")
+	revoked := issue("revoked.myvault.com", "2h", "")
+	handle(logical.UpdateOperation, "revoke", map[string]interface{}{
+		"serial_number": revoked,
+	})
+
+	// Certificates stored before the index existed are indexed by the first
+	// filtered list, including those with metadata but no index keys
+	for _, serial := range []string{short, revoked} {
+		if err := deleteCertMetadata(storage, serial); err != nil {
+			t.Fatal(err)
+		}
+	}
+	meta, err := fetchCertMetadata(storage, long)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range meta.indexKeys(long) {
+		if err := storage.Delete(key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if keys, _ := storage.List(certIndexCNPrefix); len(keys) != 1 {
+		t.Fatalf("bad: %#v", keys)
+	}
+
+	list := func(data map[string]interface{}) []string {
+		resp := handle(logical.ListOperation, "certs/", data)
+		keys, _ := resp.Data["keys"].([]string)
+		if keys == nil {
+			keys = []string{}
+		}
+		sort.Strings(keys)
+		return keys
+	}
+	sorted := func(keys ...string) []string {
+		sort.Strings(keys)
+		return keys
+	}
+
+	if keys := list(nil); !reflect.DeepEqual(keys, sorted(rootSerial, short, long, revoked)) {
+		t.Fatalf("bad: %#v", keys)
+	}
+
+	cases := []struct {
+		data     map[string]interface{}
+		expected []string
+	}{
+		{map[string]interface{}{"expires_before": "5h"}, sorted(short, revoked)},
+		{map[string]interface{}{"expires_before": "5h", "revoked": "false"}, sorted(short)},
+		{map[string]interface{}{"revoked": true}, sorted(revoked)},
+		{map[string]interface{}{"expires_after": "5h", "expires_before": "15h"}, sorted(long)},
+		{map[string]interface{}{"common_name": "*.myvault.com"}, sorted(short, long, revoked)},
+		{map[string]interface{}{"common_name": "myvault.com"}, sorted(rootSerial)},
+		{map[string]interface{}{"alt_name": "www.*"}, sorted(long)},
+		{map[string]interface{}{"serial_min": long, "serial_max": long}, sorted(long)},
+		{map[string]interface{}{"expires_before": "-1h"}, []string{}},
+	}
+	for i, c := range cases {
+		if keys := list(c.data); !reflect.DeepEqual(keys, c.expected) {
+			t.Fatalf("case %d: expected %#v, got %#v", i, c.expected, keys)
+		}
+	}
+
+	resp = handle(logical.ListOperation, "certs/", map[string]interface{}{
+		"revoked": true,
+	})
+	info := resp.Data["key_info"].(map[string]interface{})[revoked].(map[string]interface{})
+	if info["common_name"] != "revoked.myvault.com" || info["revoked"] != true || info["revocation_time"] == nil {
+		t.Fatalf("bad: %#v", info)
+	}
+
+	// Removing a certificate from the index, as tidy does, deletes its keys
+	deleted, err := fetchCertMetadata(storage, short)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := deleteCertMetadata(storage, short); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range append(deleted.indexKeys(short), certIndexPrefix+short) {
+		if entry, err := storage.Get(key); err != nil || entry != nil {
+			t.Fatalf("%s not deleted: %v", key, err)
+		}
+	}
+
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.ListOperation,
+		Path:      "certs/",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"serial_min": "zz",
+		},
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error: err: %v resp: %#v", err, resp)
+	}
+}
@@ -157,6 +157,28 @@ func fetchCAInfo(req *logical.Request) (*caInfoBundle, error) {
 		return nil, errutil.InternalError{Err: fmt.Sprintf("unable to decode local CA certificate/key: %v", err)}
 	}
 
+	return caInfoFromBundle(req, &bundle)
+}
+
+// Fetches the CA info of the issuer with the given ID or name, or of the
+// default issuer if the reference is empty
+func fetchCAInfoByIssuer(req *logical.Request, issuerRef string) (*caInfoBundle, error) {
+	if issuerRef == "" {
+		return fetchCAInfo(req)
+	}
+
+	issuer, err := resolveIssuer(req.Storage, issuerRef)
+	if err != nil {
+		return nil, errutil.InternalError{Err: fmt.Sprintf("unable to fetch issuer %q: %v", issuerRef, err)}
+	}
+	if issuer == nil {
+		return nil, errutil.UserError{Err: fmt.Sprintf("unknown issuer %q", issuerRef)}
+	}
+
+	return caInfoFromBundle(req, issuer.Bundle)
+}
+
+func caInfoFromBundle(req *logical.Request, bundle *certutil.CertBundle) (*caInfoBundle, error) {
 	parsedBundle, err := bundle.ToParsedCertBundle()
 	if err != nil {
 		return nil, errutil.InternalError{Err: err.Error()}
@@ -204,6 +226,10 @@ func fetchCertBySerial(req *logical.Request, prefix, serial string) (*logical.St
 		path = "ca"
 	case serial == "crl":
 		path = "crl"
+	case serial == "crl-delta":
+		path = "crl-delta"
+	case strings.HasPrefix(serial, "crl-issuer/"):
+		path = serial
 	default:
 		legacyPath = "certs/" + colonSerial
 		path = "certs/" + hyphenSerial
@@ -1,10 +1,19 @@
 package pki
 
 import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/rand"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"encoding/asn1"
 	"fmt"
+	"math/big"
 	"time"
 
 	"github.com/hashicorp/vault/helper/errutil"
@@ -96,9 +105,24 @@ func revokeCert(b *backend, req *logical.Request, serial string, fromLease bool)
 			return nil, fmt.Errorf("Error saving revoked certificate to new location")
 		}
 
+		if err := markCertRevoked(req.Storage, serial, currTime); err != nil {
+			return nil, fmt.Errorf("Error updating certificate index: %s", err)
+		}
+
 	}
 
-	crlErr := buildCRL(b, req)
+	// With delta CRLs enabled, revocations only go into the delta CRL and
+	// are folded into the full CRL on its next scheduled rebuild
+	var crlErr error
+	crlInfo, err := b.CRL(req.Storage)
+	if err != nil {
+		return nil, fmt.Errorf("Error fetching CRL config information: %s", err)
+	}
+	if crlInfo != nil && crlInfo.EnableDelta {
+		crlErr = buildDeltaCRL(b, req)
+	} else {
+		crlErr = buildCRL(b, req)
+	}
 	switch crlErr.(type) {
 	case errutil.UserError:
 		return logical.ErrorResponse(fmt.Sprintf("Error during CRL building: %s", crlErr)), nil
@@ -117,39 +141,362 @@ func revokeCert(b *backend, req *logical.Request, serial string, fromLease bool)
 	return resp, nil
 }
 
+// crlState records the numbers and validity of the CRLs last built. Full and
+// delta CRLs share a single number sequence, as RFC 5280 requires.
+type crlState struct {
+	LastNumber      int64     `json:"last_number"`
+	Number          int64     `json:"number"`
+	ThisUpdate      time.Time `json:"this_update"`
+	NextUpdate      time.Time `json:"next_update"`
+	DeltaNumber     int64     `json:"delta_number"`
+	DeltaThisUpdate time.Time `json:"delta_this_update"`
+	DeltaNextUpdate time.Time `json:"delta_next_update"`
+}
+
+var (
+	// oidExtensionDeltaCRLIndicator marks a CRL as a delta of the base CRL
+	// with the number in the extension's value
+	oidExtensionDeltaCRLIndicator = asn1.ObjectIdentifier{2, 5, 29, 27}
+
+	oidExtensionAuthorityKeyID = asn1.ObjectIdentifier{2, 5, 29, 35}
+	oidExtensionCRLNumber      = asn1.ObjectIdentifier{2, 5, 29, 20}
+
+	oidSignatureSHA256WithRSA   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 11}
+	oidSignatureECDSAWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+	oidSignatureECDSAWithSHA384 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 3}
+	oidSignatureECDSAWithSHA512 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 4}
+)
+
+// authorityKeyID is the value of the authority key identifier extension of
+// the CRLs
+type authorityKeyID struct {
+	ID []byte `asn1:"optional,tag:0"`
+}
+
+func fetchCRLState(s logical.Storage) (*crlState, error) {
+	entry, err := s.Get("crl-state")
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var state crlState
+	if err := entry.DecodeJSON(&state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
 // Builds a CRL by going through the list of revoked certificates and building
-// a new CRL with the stored revocation times and serial numbers.
+// a new CRL with the stored revocation times and serial numbers. If delta CRLs
+// are enabled, an empty delta CRL against the new CRL is built as well.
 func buildCRL(b *backend, req *logical.Request) error {
+	b.crlBuildLock.Lock()
+	defer b.crlBuildLock.Unlock()
+
+	return buildFullCRL(b, req)
+}
+
+func buildFullCRL(b *backend, req *logical.Request) error {
+	signingBundle, caErr := fetchCAInfo(req)
+	switch caErr.(type) {
+	case errutil.UserError:
+		return errutil.UserError{Err: fmt.Sprintf("Could not fetch the CA certificate: %s", caErr)}
+	case errutil.InternalError:
+		return errutil.InternalError{Err: fmt.Sprintf("Error fetching CA certificate: %s", caErr)}
+	}
+
+	revokedCerts, certs, err := fetchRevokedCerts(req, time.Time{})
+	if err != nil {
+		return err
+	}
+	issuers, revokedByIssuer, err := partitionRevokedCerts(req, signingBundle.Certificate, revokedCerts, certs)
+	if err != nil {
+		return err
+	}
+
+	crlLifetime := b.crlLifetime
+	crlInfo, err := b.CRL(req.Storage)
+	if err != nil {
+		return errutil.InternalError{Err: fmt.Sprintf("Error fetching CRL config information: %s", err)}
+	}
+	if crlInfo != nil {
+		crlDur, err := time.ParseDuration(crlInfo.Expiry)
+		if err != nil {
+			return errutil.InternalError{Err: fmt.Sprintf("Error parsing CRL duration of %s", crlInfo.Expiry)}
+		}
+		crlLifetime = crlDur
+	}
+
+	state, err := fetchCRLState(req.Storage)
+	if err != nil {
+		return errutil.InternalError{Err: fmt.Sprintf("Error fetching CRL state: %s", err)}
+	}
+	if state == nil {
+		state = &crlState{}
+	}
+
+	now := time.Now().UTC()
+	state.LastNumber++
+	state.Number = state.LastNumber
+	state.ThisUpdate = now
+	state.NextUpdate = now.Add(crlLifetime)
+
+	crlBytes, err := createCRL(signingBundle, revokedCerts, state.Number, now, state.NextUpdate, nil)
+	if err != nil {
+		return errutil.InternalError{Err: fmt.Sprintf("Error creating new CRL: %s", err)}
+	}
+
+	err = req.Storage.Put(&logical.StorageEntry{
+		Key:   "crl",
+		Value: crlBytes,
+	})
+	if err != nil {
+		return errutil.InternalError{Err: fmt.Sprintf("Error storing CRL: %s", err)}
+	}
+
+	// Each of the other issuers signs a CRL of the certificates it issued
+	for _, issuer := range issuers {
+		issuerBundle, err := caInfoFromBundle(req, issuer.Bundle)
+		if err != nil {
+			return errutil.InternalError{Err: fmt.Sprintf("Error fetching certificate of issuer %s: %s", issuer.ID, err)}
+		}
+		issuerCRLBytes, err := createCRL(issuerBundle, revokedByIssuer[issuer.ID], state.Number, now, state.NextUpdate, nil)
+		if err != nil {
+			return errutil.InternalError{Err: fmt.Sprintf("Error creating new CRL of issuer %s: %s", issuer.ID, err)}
+		}
+		err = req.Storage.Put(&logical.StorageEntry{
+			Key:   "crl-issuer/" + issuer.ID,
+			Value: issuerCRLBytes,
+		})
+		if err != nil {
+			return errutil.InternalError{Err: fmt.Sprintf("Error storing CRL of issuer %s: %s", issuer.ID, err)}
+		}
+	}
+
+	if crlInfo != nil && crlInfo.EnableDelta {
+		if err := writeDeltaCRL(req, signingBundle, crlInfo, state, nil); err != nil {
+			return err
+		}
+	}
+
+	return storeCRLState(req, state)
+}
+
+// Builds a delta CRL listing the certificates revoked since the last full CRL
+// was built, or a full CRL if none has been built with a number yet
+func buildDeltaCRL(b *backend, req *logical.Request) error {
+	b.crlBuildLock.Lock()
+	defer b.crlBuildLock.Unlock()
+
+	crlInfo, err := b.CRL(req.Storage)
+	if err != nil {
+		return errutil.InternalError{Err: fmt.Sprintf("Error fetching CRL config information: %s", err)}
+	}
+	if crlInfo == nil || !crlInfo.EnableDelta {
+		return errutil.UserError{Err: "delta CRLs are not enabled"}
+	}
+
+	state, err := fetchCRLState(req.Storage)
+	if err != nil {
+		return errutil.InternalError{Err: fmt.Sprintf("Error fetching CRL state: %s", err)}
+	}
+	if state == nil || state.Number == 0 {
+		return buildFullCRL(b, req)
+	}
+
+	signingBundle, caErr := fetchCAInfo(req)
+	switch caErr.(type) {
+	case errutil.UserError:
+		return errutil.UserError{Err: fmt.Sprintf("Could not fetch the CA certificate: %s", caErr)}
+	case errutil.InternalError:
+		return errutil.InternalError{Err: fmt.Sprintf("Error fetching CA certificate: %s", caErr)}
+	}
+
+	// Delta CRLs are only built for the default issuer
+	revokedCerts, _, err := fetchRevokedCerts(req, state.ThisUpdate)
+	if err != nil {
+		return err
+	}
+
+	if err := writeDeltaCRL(req, signingBundle, crlInfo, state, revokedCerts); err != nil {
+		return err
+	}
+	return storeCRLState(req, state)
+}
+
+func writeDeltaCRL(req *logical.Request, signingBundle *caInfoBundle, crlInfo *crlConfig, state *crlState, revokedCerts []pkix.RevokedCertificate) error {
+	deltaLifetime, err := time.ParseDuration(crlInfo.deltaRebuildInterval())
+	if err != nil {
+		return errutil.InternalError{Err: fmt.Sprintf("Error parsing delta CRL rebuild interval of %s", crlInfo.DeltaRebuildInterval)}
+	}
+
+	baseNumber, err := asn1.Marshal(big.NewInt(state.Number))
+	if err != nil {
+		return errutil.InternalError{Err: fmt.Sprintf("Error encoding base CRL number: %s", err)}
+	}
+	indicator := pkix.Extension{
+		Id:       oidExtensionDeltaCRLIndicator,
+		Critical: true,
+		Value:    baseNumber,
+	}
+
+	now := time.Now().UTC()
+	state.LastNumber++
+	state.DeltaNumber = state.LastNumber
+	state.DeltaThisUpdate = now
+	state.DeltaNextUpdate = now.Add(deltaLifetime)
+
+	crlBytes, err := createCRL(signingBundle, revokedCerts, state.DeltaNumber, now, state.DeltaNextUpdate, []pkix.Extension{indicator})
+	if err != nil {
+		return errutil.InternalError{Err: fmt.Sprintf("Error creating new delta CRL: %s", err)}
+	}
+
+	err = req.Storage.Put(&logical.StorageEntry{
+		Key:   "crl-delta",
+		Value: crlBytes,
+	})
+	if err != nil {
+		return errutil.InternalError{Err: fmt.Sprintf("Error storing delta CRL: %s", err)}
+	}
+	return nil
+}
+
+func storeCRLState(req *logical.Request, state *crlState) error {
+	entry, err := logical.StorageEntryJSON("crl-state", state)
+	if err != nil {
+		return errutil.InternalError{Err: fmt.Sprintf("Error encoding CRL state: %s", err)}
+	}
+	if err := req.Storage.Put(entry); err != nil {
+		return errutil.InternalError{Err: fmt.Sprintf("Error storing CRL state: %s", err)}
+	}
+	return nil
+}
+
+// createCRL signs a CRL with the CA. CAs lacking the cRLSign key usage or a
+// subject key identifier, such as some imported ones, cannot carry a CRL
+// number, so they get an unnumbered CRL and no delta CRLs.
+func createCRL(signingBundle *caInfoBundle, revokedCerts []pkix.RevokedCertificate, number int64, thisUpdate, nextUpdate time.Time, extensions []pkix.Extension) ([]byte, error) {
+	caCert := signingBundle.Certificate
+	if caCert.KeyUsage&x509.KeyUsageCRLSign == 0 || len(caCert.SubjectKeyId) == 0 {
+		if len(extensions) != 0 {
+			return nil, fmt.Errorf("the CA certificate must have the cRLSign key usage and a subject key identifier to sign delta CRLs")
+		}
+		return caCert.CreateCRL(rand.Reader, signingBundle.PrivateKey, revokedCerts, thisUpdate, nextUpdate)
+	}
+
+	sigAlgo, hash, err := crlSignatureAlgorithm(caCert.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	numberBytes, err := asn1.Marshal(big.NewInt(number))
+	if err != nil {
+		return nil, err
+	}
+	akiBytes, err := asn1.Marshal(authorityKeyID{ID: caCert.SubjectKeyId})
+	if err != nil {
+		return nil, err
+	}
+
+	// Revocation times are in UTC per RFC 5280
+	revokedCertsUTC := make([]pkix.RevokedCertificate, len(revokedCerts))
+	for i, rc := range revokedCerts {
+		rc.RevocationTime = rc.RevocationTime.UTC()
+		revokedCertsUTC[i] = rc
+	}
+
+	tbsCertList := pkix.TBSCertificateList{
+		Version:             1,
+		Signature:           sigAlgo,
+		Issuer:              caCert.Subject.ToRDNSequence(),
+		ThisUpdate:          thisUpdate.UTC(),
+		NextUpdate:          nextUpdate.UTC(),
+		RevokedCertificates: revokedCertsUTC,
+		Extensions: append([]pkix.Extension{
+			{Id: oidExtensionAuthorityKeyID, Value: akiBytes},
+			{Id: oidExtensionCRLNumber, Value: numberBytes},
+		}, extensions...),
+	}
+	tbsBytes, err := asn1.Marshal(tbsCertList)
+	if err != nil {
+		return nil, err
+	}
+	tbsCertList.Raw = tbsBytes
+
+	h := hash.New()
+	h.Write(tbsBytes)
+	signature, err := signingBundle.PrivateKey.Sign(rand.Reader, h.Sum(nil), hash)
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(pkix.CertificateList{
+		TBSCertList:        tbsCertList,
+		SignatureAlgorithm: sigAlgo,
+		SignatureValue:     asn1.BitString{Bytes: signature, BitLength: len(signature) * 8},
+	})
+}
+
+// crlSignatureAlgorithm returns the algorithm numbered CRLs are signed with
+// by a CA with the given public key, matching the ones of the unnumbered CRLs
+func crlSignatureAlgorithm(pub crypto.PublicKey) (pkix.AlgorithmIdentifier, crypto.Hash, error) {
+	switch pub := pub.(type) {
+	case *rsa.PublicKey:
+		return pkix.AlgorithmIdentifier{
+			Algorithm:  oidSignatureSHA256WithRSA,
+			Parameters: asn1.RawValue{Tag: asn1.TagNull},
+		}, crypto.SHA256, nil
+	case *ecdsa.PublicKey:
+		switch pub.Curve {
+		case elliptic.P224(), elliptic.P256():
+			return pkix.AlgorithmIdentifier{Algorithm: oidSignatureECDSAWithSHA256}, crypto.SHA256, nil
+		case elliptic.P384():
+			return pkix.AlgorithmIdentifier{Algorithm: oidSignatureECDSAWithSHA384}, crypto.SHA384, nil
+		case elliptic.P521():
+			return pkix.AlgorithmIdentifier{Algorithm: oidSignatureECDSAWithSHA512}, crypto.SHA512, nil
+		}
+	}
+	return pkix.AlgorithmIdentifier{}, 0, fmt.Errorf("unsupported CA key type %T for signing CRLs", pub)
+}
+
+// fetchRevokedCerts returns the certificates revoked at or after the given
+// time, or all revoked certificates if it is zero, along with the parsed
+// certificates
+func fetchRevokedCerts(req *logical.Request, since time.Time) ([]pkix.RevokedCertificate, []*x509.Certificate, error) {
 	revokedSerials, err := req.Storage.List("revoked/")
 	if err != nil {
-		return errutil.InternalError{Err: fmt.Sprintf("Error fetching list of revoked certs: %s", err)}
+		return nil, nil, errutil.InternalError{Err: fmt.Sprintf("Error fetching list of revoked certs: %s", err)}
 	}
 
 	revokedCerts := []pkix.RevokedCertificate{}
-	var revInfo revocationInfo
+	certs := []*x509.Certificate{}
 	for _, serial := range revokedSerials {
+		var revInfo revocationInfo
 		revokedEntry, err := req.Storage.Get("revoked/" + serial)
 		if err != nil {
-			return errutil.InternalError{Err: fmt.Sprintf("Unable to fetch revoked cert with serial %s: %s", serial, err)}
+			return nil, nil, errutil.InternalError{Err: fmt.Sprintf("Unable to fetch revoked cert with serial %s: %s", serial, err)}
 		}
 		if revokedEntry == nil {
-			return errutil.InternalError{Err: fmt.Sprintf("Revoked certificate entry for serial %s is nil", serial)}
+			return nil, nil, errutil.InternalError{Err: fmt.Sprintf("Revoked certificate entry for serial %s is nil", serial)}
 		}
 		if revokedEntry.Value == nil || len(revokedEntry.Value) == 0 {
 			// TODO: In this case, remove it and continue? How likely is this to
 			// happen? Alternately, could skip it entirely, or could implement a
 			// delete function so that there is a way to remove these
-			return errutil.InternalError{Err: fmt.Sprintf("Found revoked serial but actual certificate is empty")}
+			return nil, nil, errutil.InternalError{Err: fmt.Sprintf("Found revoked serial but actual certificate is empty")}
 		}
 
 		err = revokedEntry.DecodeJSON(&revInfo)
 		if err != nil {
-			return errutil.InternalError{Err: fmt.Sprintf("Error decoding revocation entry for serial %s: %s", serial, err)}
+			return nil, nil, errutil.InternalError{Err: fmt.Sprintf("Error decoding revocation entry for serial %s: %s", serial, err)}
 		}
 
 		revokedCert, err := x509.ParseCertificate(revInfo.CertificateBytes)
 		if err != nil {
-			return errutil.InternalError{Err: fmt.Sprintf("Unable to parse stored revoked certificate with serial %s: %s", serial, err)}
+			return nil, nil, errutil.InternalError{Err: fmt.Sprintf("Unable to parse stored revoked certificate with serial %s: %s", serial, err)}
 		}
 
 		// NOTE: We have to change this to UTC time because the CRL standard
@@ -162,42 +509,98 @@ func buildCRL(b *backend, req *logical.Request) error {
 		} else {
 			newRevCert.RevocationTime = time.Unix(revInfo.RevocationTime, 0).UTC()
 		}
+		if newRevCert.RevocationTime.Before(since) {
+			continue
+		}
 		revokedCerts = append(revokedCerts, newRevCert)
+		certs = append(certs, revokedCert)
 	}
 
-	signingBundle, caErr := fetchCAInfo(req)
-	switch caErr.(type) {
-	case errutil.UserError:
-		return errutil.UserError{Err: fmt.Sprintf("Could not fetch the CA certificate: %s", caErr)}
-	case errutil.InternalError:
-		return errutil.InternalError{Err: fmt.Sprintf("Error fetching CA certificate: %s", caErr)}
-	}
+	return revokedCerts, certs, nil
+}
 
-	crlLifetime := b.crlLifetime
-	crlInfo, err := b.CRL(req.Storage)
+// partitionRevokedCerts returns the issuers other than the default one, and
+// the revoked certificates issued by each of them. The default issuer's CRL
+// lists all revoked certificates, as it did before there were other issuers.
+func partitionRevokedCerts(req *logical.Request, defaultCA *x509.Certificate, revokedCerts []pkix.RevokedCertificate, certs []*x509.Certificate) ([]*issuerEntry, map[string][]pkix.RevokedCertificate, error) {
+	allIssuers, err := fetchIssuers(req.Storage)
 	if err != nil {
-		return errutil.InternalError{Err: fmt.Sprintf("Error fetching CRL config information: %s", err)}
+		return nil, nil, errutil.InternalError{Err: fmt.Sprintf("Error fetching issuers: %s", err)}
 	}
-	if crlInfo != nil {
-		crlDur, err := time.ParseDuration(crlInfo.Expiry)
+
+	var issuers []*issuerEntry
+	revokedByIssuer := map[string][]pkix.RevokedCertificate{}
+	for _, issuer := range allIssuers {
+		issuerCert, err := issuer.certificate()
 		if err != nil {
-			return errutil.InternalError{Err: fmt.Sprintf("Error parsing CRL duration of %s", crlInfo.Expiry)}
+			return nil, nil, errutil.InternalError{Err: fmt.Sprintf("Error parsing certificate of issuer %s: %s", issuer.ID, err)}
+		}
+		if bytes.Equal(issuerCert.Raw, defaultCA.Raw) {
+			continue
+		}
+		issuers = append(issuers, issuer)
+
+		for idx, cert := range certs {
+			if issuedBy(cert, issuerCert) {
+				revokedByIssuer[issuer.ID] = append(revokedByIssuer[issuer.ID], revokedCerts[idx])
+			}
 		}
-		crlLifetime = crlDur
 	}
+	return issuers, revokedByIssuer, nil
+}
 
-	crlBytes, err := signingBundle.Certificate.CreateCRL(rand.Reader, signingBundle.PrivateKey, revokedCerts, time.Now(), time.Now().Add(crlLifetime))
+// crlRebuildMargin is how long before its next update a delta CRL is rebuilt,
+// covering the time between runs of the periodic function
+const crlRebuildMargin = time.Minute
+
+// rebuildCRLs is the periodic function of the backend, rebuilding the CRL and
+// delta CRL when they are due according to the CRL config
+func (b *backend) rebuildCRLs(req *logical.Request) error {
+	crlInfo, err := b.CRL(req.Storage)
 	if err != nil {
-		return errutil.InternalError{Err: fmt.Sprintf("Error creating new CRL: %s", err)}
+		return err
+	}
+	if crlInfo == nil || crlInfo.RebuildInterval == "" {
+		return nil
+	}
+	rebuildInterval, err := time.ParseDuration(crlInfo.RebuildInterval)
+	if err != nil {
+		return err
 	}
 
-	err = req.Storage.Put(&logical.StorageEntry{
-		Key:   "crl",
-		Value: crlBytes,
-	})
+	// Nothing to sign with until a CA is configured
+	caEntry, err := req.Storage.Get("config/ca_bundle")
 	if err != nil {
-		return errutil.InternalError{Err: fmt.Sprintf("Error storing CRL: %s", err)}
+		return err
+	}
+	if caEntry == nil {
+		return nil
+	}
+
+	b.revokeStorageLock.RLock()
+	defer b.revokeStorageLock.RUnlock()
+
+	state, err := fetchCRLState(req.Storage)
+	if err != nil {
+		return err
 	}
 
+	now := time.Now()
+	switch {
+	case state == nil || !now.Before(state.ThisUpdate.Add(rebuildInterval)):
+		return buildCRL(b, req)
+	case crlInfo.EnableDelta:
+		deltaInterval, err := time.ParseDuration(crlInfo.deltaRebuildInterval())
+		if err != nil {
+			return err
+		}
+		due := state.DeltaThisUpdate.Add(deltaInterval)
+		if margin := state.DeltaNextUpdate.Add(-crlRebuildMargin); margin.Before(due) {
+			due = margin
+		}
+		if state.DeltaNumber < state.Number || !now.Before(due) {
+			return buildDeltaCRL(b, req)
+		}
+	}
 	return nil
 }
@@ -67,6 +67,13 @@ default TTL is used, in that order. Cannot
 be later than the role max TTL.`,
 	}
 
+	fields["issuer_ref"] = &framework.FieldSchema{
+		Type: framework.TypeString,
+		Description: `ID or name of the issuer signing the
+certificate; defaults to the issuer of the role,
+or the default issuer`,
+	}
+
 	return fields
 }
 
@@ -0,0 +1,925 @@
+package pki
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/helper/errutil"
+	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+var acmePathPrefix = "acme/" + framework.GenericNameRegex("role") + "/"
+
+// acmeFields returns the fields of an ACME request, which carries a JWS in
+// flattened JSON serialization
+func acmeFields(fields map[string]*framework.FieldSchema) map[string]*framework.FieldSchema {
+	fields["role"] = &framework.FieldSchema{
+		Type:        framework.TypeString,
+		Description: `The role to order certificates against`,
+	}
+	fields["protected"] = &framework.FieldSchema{
+		Type:        framework.TypeString,
+		Description: `The protected header of the JWS`,
+	}
+	fields["payload"] = &framework.FieldSchema{
+		Type:        framework.TypeString,
+		Description: `The payload of the JWS`,
+	}
+	fields["signature"] = &framework.FieldSchema{
+		Type:        framework.TypeString,
+		Description: `The signature of the JWS`,
+	}
+	return fields
+}
+
+func acmeIDField() map[string]*framework.FieldSchema {
+	return map[string]*framework.FieldSchema{
+		"id": &framework.FieldSchema{
+			Type:        framework.TypeString,
+			Description: `The ID of the object`,
+		},
+	}
+}
+
+func pathACMEDirectory(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: acmePathPrefix + "directory",
+		Fields:  acmeFields(map[string]*framework.FieldSchema{}),
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathACMEDirectory,
+		},
+
+		HelpSynopsis:    pathACMEHelpSyn,
+		HelpDescription: pathACMEHelpDesc,
+	}
+}
+
+func pathACMENewNonce(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: acmePathPrefix + "new-nonce",
+		Fields:  acmeFields(map[string]*framework.FieldSchema{}),
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathACMENewNonce,
+			logical.HeaderOperation: b.pathACMENewNonce,
+			logical.UpdateOperation: b.pathACMENewNonce,
+		},
+
+		HelpSynopsis:    pathACMEHelpSyn,
+		HelpDescription: pathACMEHelpDesc,
+	}
+}
+
+func pathACMENewAccount(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: acmePathPrefix + "new-account",
+		Fields:  acmeFields(map[string]*framework.FieldSchema{}),
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathACMENewAccount,
+		},
+
+		HelpSynopsis:    pathACMEHelpSyn,
+		HelpDescription: pathACMEHelpDesc,
+	}
+}
+
+func pathACMEAccount(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: acmePathPrefix + "account/" + framework.GenericNameRegex("id"),
+		Fields:  acmeFields(acmeIDField()),
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathACMEAccount,
+		},
+
+		HelpSynopsis:    pathACMEHelpSyn,
+		HelpDescription: pathACMEHelpDesc,
+	}
+}
+
+func pathACMENewOrder(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: acmePathPrefix + "new-order",
+		Fields:  acmeFields(map[string]*framework.FieldSchema{}),
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathACMENewOrder,
+		},
+
+		HelpSynopsis:    pathACMEHelpSyn,
+		HelpDescription: pathACMEHelpDesc,
+	}
+}
+
+func pathACMEOrder(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: acmePathPrefix + "order/" + framework.GenericNameRegex("id"),
+		Fields:  acmeFields(acmeIDField()),
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathACMEOrder,
+		},
+
+		HelpSynopsis:    pathACMEHelpSyn,
+		HelpDescription: pathACMEHelpDesc,
+	}
+}
+
+func pathACMEOrderFinalize(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: acmePathPrefix + "order/" + framework.GenericNameRegex("id") + "/finalize",
+		Fields:  acmeFields(acmeIDField()),
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathACMEOrderFinalize,
+		},
+
+		HelpSynopsis:    pathACMEHelpSyn,
+		HelpDescription: pathACMEHelpDesc,
+	}
+}
+
+func pathACMEAuthorization(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: acmePathPrefix + "authz/" + framework.GenericNameRegex("id"),
+		Fields:  acmeFields(acmeIDField()),
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathACMEAuthorization,
+		},
+
+		HelpSynopsis:    pathACMEHelpSyn,
+		HelpDescription: pathACMEHelpDesc,
+	}
+}
+
+func pathACMEChallenge(b *backend) *framework.Path {
+	fields := acmeIDField()
+	fields["type"] = &framework.FieldSchema{
+		Type:        framework.TypeString,
+		Description: `The type of the challenge`,
+	}
+
+	return &framework.Path{
+		Pattern: acmePathPrefix + "challenge/" + framework.GenericNameRegex("id") + "/(?P<type>http-01|dns-01)",
+		Fields:  acmeFields(fields),
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathACMEChallenge,
+		},
+
+		HelpSynopsis:    pathACMEHelpSyn,
+		HelpDescription: pathACMEHelpDesc,
+	}
+}
+
+func pathACMECert(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: acmePathPrefix + "cert/" + framework.GenericNameRegex("id"),
+		Fields:  acmeFields(acmeIDField()),
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathACMECert,
+		},
+
+		HelpSynopsis:    pathACMEHelpSyn,
+		HelpDescription: pathACMEHelpDesc,
+	}
+}
+
+func (b *backend) pathACMEDirectory(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	roleName := data.Get("role").(string)
+	config, _, problem, err := b.acmeEnabled(req, roleName)
+	if err != nil {
+		return nil, err
+	}
+	if problem != nil {
+		return b.acmeProblemResponse(config, roleName, problem)
+	}
+
+	return b.acmeResponse(nil, roleName, http.StatusOK, map[string]interface{}{
+		"newNonce":   config.acmeURL(roleName, "new-nonce"),
+		"newAccount": config.acmeURL(roleName, "new-account"),
+		"newOrder":   config.acmeURL(roleName, "new-order"),
+		"meta": map[string]interface{}{
+			"externalAccountRequired": false,
+		},
+	}, nil)
+}
+
+func (b *backend) pathACMENewNonce(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	roleName := data.Get("role").(string)
+	config, _, problem, err := b.acmeEnabled(req, roleName)
+	if err != nil {
+		return nil, err
+	}
+	if problem != nil {
+		return b.acmeProblemResponse(config, roleName, problem)
+	}
+
+	// RFC 8555 answers HEAD requests, which clients use to get their first
+	// nonce, with 200 and other requests with 204
+	status := http.StatusNoContent
+	if req.Operation == logical.HeaderOperation {
+		status = http.StatusOK
+	}
+	return b.acmeResponse(config, roleName, status, nil, nil)
+}
+
+// handleACMERequest checks that ACME is enabled for the role in the path and
+// verifies the request's JWS. If the request cannot be served, the response
+// to return is given instead.
+func (b *backend) handleACMERequest(req *logical.Request, data *framework.FieldData, path string, newAccount bool) (*acmeRequest, *roleEntry, *logical.Response, error) {
+	roleName := data.Get("role").(string)
+	config, role, problem, err := b.acmeEnabled(req, roleName)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if problem != nil {
+		resp, err := b.acmeProblemResponse(config, roleName, problem)
+		return nil, nil, resp, err
+	}
+
+	acmeReq, problem, err := b.verifyACMERequest(req, data, config, roleName, path, newAccount)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if problem != nil {
+		resp, err := b.acmeProblemResponse(config, roleName, problem)
+		return nil, nil, resp, err
+	}
+	return acmeReq, role, nil, nil
+}
+
+func (b *backend) pathACMENewAccount(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	acmeReq, _, resp, err := b.handleACMERequest(req, data, "new-account", true)
+	if acmeReq == nil {
+		return resp, err
+	}
+
+	var payload struct {
+		Contact              []string `json:"contact"`
+		TermsOfServiceAgreed bool     `json:"termsOfServiceAgreed"`
+		OnlyReturnExisting   bool     `json:"onlyReturnExisting"`
+	}
+	if problem := acmeReq.decodePayload(&payload); problem != nil {
+		return b.acmeProblemResponse(acmeReq.config, acmeReq.role, problem)
+	}
+
+	thumbprintBytes, err := acmeReq.jwk.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return nil, err
+	}
+	thumbprint := base64.RawURLEncoding.EncodeToString(thumbprintBytes)
+
+	b.acmeLock.Lock()
+	defer b.acmeLock.Unlock()
+
+	var id string
+	if _, err := getACMEEntry(req.Storage, "acme/account-key/"+thumbprint, &id); err != nil {
+		return nil, err
+	}
+	if id != "" {
+		account, err := b.acmeAccount(req.Storage, id)
+		if err != nil {
+			return nil, err
+		}
+		if account != nil {
+			return b.acmeAccountResponse(acmeReq, account, http.StatusOK)
+		}
+	}
+	if payload.OnlyReturnExisting {
+		return b.acmeProblemResponse(acmeReq.config, acmeReq.role, newACMEProblem(http.StatusBadRequest, "accountDoesNotExist", "no account exists for the key"))
+	}
+	for _, contact := range payload.Contact {
+		if !strings.HasPrefix(contact, "mailto:") {
+			return b.acmeProblemResponse(acmeReq.config, acmeReq.role, newACMEProblem(http.StatusBadRequest, "unsupportedContact", "unsupported contact %q", contact))
+		}
+	}
+
+	key, err := json.Marshal(acmeReq.jwk)
+	if err != nil {
+		return nil, err
+	}
+	id, err = acmeRandomID()
+	if err != nil {
+		return nil, err
+	}
+	account := &acmeAccount{
+		ID:          id,
+		Key:         key,
+		Thumbprint:  thumbprint,
+		Status:      acmeStatusValid,
+		Contact:     payload.Contact,
+		CreatedTime: time.Now().UTC(),
+	}
+	if err := putACMEEntry(req.Storage, "acme/account/"+id, account); err != nil {
+		return nil, err
+	}
+	if err := putACMEEntry(req.Storage, "acme/account-key/"+thumbprint, id); err != nil {
+		return nil, err
+	}
+
+	return b.acmeAccountResponse(acmeReq, account, http.StatusCreated)
+}
+
+func (b *backend) pathACMEAccount(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	id := data.Get("id").(string)
+	acmeReq, _, resp, err := b.handleACMERequest(req, data, "account/"+id, false)
+	if acmeReq == nil {
+		return resp, err
+	}
+	if acmeReq.account.ID != id {
+		return b.acmeProblemResponse(acmeReq.config, acmeReq.role, newACMEProblem(http.StatusForbidden, "unauthorized", "account does not match the key ID"))
+	}
+
+	var payload struct {
+		Contact []string `json:"contact"`
+		Status  string   `json:"status"`
+	}
+	if problem := acmeReq.decodePayload(&payload); problem != nil {
+		return b.acmeProblemResponse(acmeReq.config, acmeReq.role, problem)
+	}
+
+	b.acmeLock.Lock()
+	defer b.acmeLock.Unlock()
+
+	account := acmeReq.account
+	if payload.Contact != nil || payload.Status != "" {
+		switch payload.Status {
+		case "":
+		case acmeStatusDeactivated:
+			account.Status = acmeStatusDeactivated
+		default:
+			return b.acmeProblemResponse(acmeReq.config, acmeReq.role, newACMEProblem(http.StatusBadRequest, "malformed", "accounts can only be deactivated"))
+		}
+		if payload.Contact != nil {
+			account.Contact = payload.Contact
+		}
+		if err := putACMEEntry(req.Storage, "acme/account/"+account.ID, account); err != nil {
+			return nil, err
+		}
+	}
+
+	return b.acmeAccountResponse(acmeReq, account, http.StatusOK)
+}
+
+func (b *backend) acmeAccountResponse(acmeReq *acmeRequest, account *acmeAccount, status int) (*logical.Response, error) {
+	contact := account.Contact
+	if contact == nil {
+		contact = []string{}
+	}
+	return b.acmeResponse(acmeReq.config, acmeReq.role, status, map[string]interface{}{
+		"status":  account.Status,
+		"contact": contact,
+	}, map[string][]string{
+		"Location": []string{acmeReq.config.acmeURL(acmeReq.role, "account/"+account.ID)},
+	})
+}
+
+func (b *backend) pathACMENewOrder(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	acmeReq, role, resp, err := b.handleACMERequest(req, data, "new-order", false)
+	if acmeReq == nil {
+		return resp, err
+	}
+
+	var payload struct {
+		Identifiers []acmeIdentifier `json:"identifiers"`
+		NotBefore   string           `json:"notBefore"`
+		NotAfter    string           `json:"notAfter"`
+	}
+	if problem := acmeReq.decodePayload(&payload); problem != nil {
+		return b.acmeProblemResponse(acmeReq.config, acmeReq.role, problem)
+	}
+	if payload.NotBefore != "" || payload.NotAfter != "" {
+		return b.acmeProblemResponse(acmeReq.config, acmeReq.role, newACMEProblem(http.StatusBadRequest, "malformed", "notBefore and notAfter are not supported; validity is set by the role"))
+	}
+	if len(payload.Identifiers) == 0 {
+		return b.acmeProblemResponse(acmeReq.config, acmeReq.role, newACMEProblem(http.StatusBadRequest, "malformed", "no identifiers given"))
+	}
+
+	// Check the identifiers against the role up front so that clients do
+	// not go through validation for names that cannot be issued
+	seen := make(map[string]bool)
+	var identifiers []acmeIdentifier
+	for _, identifier := range payload.Identifiers {
+		if identifier.Type != "dns" {
+			return b.acmeProblemResponse(acmeReq.config, acmeReq.role, newACMEProblem(http.StatusBadRequest, "unsupportedIdentifier", "unsupported identifier type %q", identifier.Type))
+		}
+		value := strings.ToLower(identifier.Value)
+		if value == "" || strings.Contains(value, "@") {
+			return b.acmeProblemResponse(acmeReq.config, acmeReq.role, newACMEProblem(http.StatusBadRequest, "rejectedIdentifier", "invalid identifier %q", identifier.Value))
+		}
+		if badName := validateNames(req, []string{value}, role); badName != "" {
+			return b.acmeProblemResponse(acmeReq.config, acmeReq.role, newACMEProblem(http.StatusForbidden, "rejectedIdentifier", "%s is not allowed by role %q", badName, acmeReq.role))
+		}
+		if seen[value] {
+			continue
+		}
+		seen[value] = true
+		identifiers = append(identifiers, acmeIdentifier{Type: "dns", Value: value})
+	}
+
+	b.acmeLock.Lock()
+	defer b.acmeLock.Unlock()
+
+	orderID, err := acmeRandomID()
+	if err != nil {
+		return nil, err
+	}
+	order := &acmeOrder{
+		ID:          orderID,
+		AccountID:   acmeReq.account.ID,
+		Role:        acmeReq.role,
+		Status:      acmeStatusPending,
+		Expires:     time.Now().Add(acmeOrderTTL).UTC(),
+		Identifiers: identifiers,
+	}
+
+	for _, identifier := range identifiers {
+		authzID, err := acmeRandomID()
+		if err != nil {
+			return nil, err
+		}
+		authz := &acmeAuthorization{
+			ID:         authzID,
+			AccountID:  order.AccountID,
+			Role:       order.Role,
+			Status:     acmeStatusPending,
+			Expires:    order.Expires,
+			Identifier: identifier,
+		}
+
+		// Wildcard names can only be validated through DNS
+		challengeTypes := []string{acmeChallengeHTTP01, acmeChallengeDNS01}
+		if strings.HasPrefix(identifier.Value, "*.") {
+			authz.Identifier.Value = identifier.Value[2:]
+			authz.Wildcard = true
+			challengeTypes = []string{acmeChallengeDNS01}
+		}
+		for _, challengeType := range challengeTypes {
+			token, err := acmeRandomID()
+			if err != nil {
+				return nil, err
+			}
+			authz.Challenges = append(authz.Challenges, &acmeChallenge{
+				Type:   challengeType,
+				Token:  token,
+				Status: acmeStatusPending,
+			})
+		}
+
+		if err := putACMEEntry(req.Storage, "acme/authz/"+authzID, authz); err != nil {
+			return nil, err
+		}
+		order.Authorizations = append(order.Authorizations, authzID)
+	}
+
+	if err := putACMEEntry(req.Storage, "acme/order/"+orderID, order); err != nil {
+		return nil, err
+	}
+
+	return b.acmeOrderResponse(acmeReq, order, http.StatusCreated)
+}
+
+// acmeRequestOrder returns the order of the request's account with the ID,
+// updating its status from its authorizations
+func (b *backend) acmeRequestOrder(req *logical.Request, acmeReq *acmeRequest, id string) (*acmeOrder, *acmeProblem, error) {
+	order, err := b.acmeOrder(req.Storage, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if order == nil || order.Role != acmeReq.role {
+		return nil, newACMEProblem(http.StatusNotFound, "malformed", "unknown order %q", id), nil
+	}
+	if order.AccountID != acmeReq.account.ID {
+		return nil, newACMEProblem(http.StatusForbidden, "unauthorized", "order belongs to another account"), nil
+	}
+
+	status := order.Status
+	if status == acmeStatusPending {
+		ready := true
+		for _, authzID := range order.Authorizations {
+			authz, err := b.acmeAuthorization(req.Storage, authzID)
+			if err != nil {
+				return nil, nil, err
+			}
+			switch {
+			case authz == nil || authz.Status == acmeStatusInvalid:
+				status = acmeStatusInvalid
+			case authz.Status != acmeStatusValid:
+				ready = false
+			}
+		}
+		if status == acmeStatusPending && ready {
+			status = acmeStatusReady
+		}
+	}
+	if status != acmeStatusValid && status != acmeStatusInvalid && time.Now().After(order.Expires) {
+		status = acmeStatusInvalid
+	}
+
+	if status != order.Status {
+		order.Status = status
+		if err := putACMEEntry(req.Storage, "acme/order/"+order.ID, order); err != nil {
+			return nil, nil, err
+		}
+	}
+	return order, nil, nil
+}
+
+func (b *backend) pathACMEOrder(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	id := data.Get("id").(string)
+	acmeReq, _, resp, err := b.handleACMERequest(req, data, "order/"+id, false)
+	if acmeReq == nil {
+		return resp, err
+	}
+
+	b.acmeLock.Lock()
+	defer b.acmeLock.Unlock()
+
+	order, problem, err := b.acmeRequestOrder(req, acmeReq, id)
+	if err != nil {
+		return nil, err
+	}
+	if problem != nil {
+		return b.acmeProblemResponse(acmeReq.config, acmeReq.role, problem)
+	}
+
+	return b.acmeOrderResponse(acmeReq, order, http.StatusOK)
+}
+
+func (b *backend) pathACMEOrderFinalize(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	id := data.Get("id").(string)
+	acmeReq, role, resp, err := b.handleACMERequest(req, data, "order/"+id+"/finalize", false)
+	if acmeReq == nil {
+		return resp, err
+	}
+
+	var payload struct {
+		CSR string `json:"csr"`
+	}
+	if problem := acmeReq.decodePayload(&payload); problem != nil {
+		return b.acmeProblemResponse(acmeReq.config, acmeReq.role, problem)
+	}
+
+	b.acmeLock.Lock()
+	defer b.acmeLock.Unlock()
+
+	order, problem, err := b.acmeRequestOrder(req, acmeReq, id)
+	if err != nil {
+		return nil, err
+	}
+	if problem != nil {
+		return b.acmeProblemResponse(acmeReq.config, acmeReq.role, problem)
+	}
+	if order.Status != acmeStatusReady {
+		return b.acmeProblemResponse(acmeReq.config, acmeReq.role, newACMEProblem(http.StatusForbidden, "orderNotReady", "order is %s", order.Status))
+	}
+
+	der, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(payload.CSR, "="))
+	if err != nil {
+		return b.acmeProblemResponse(acmeReq.config, acmeReq.role, newACMEProblem(http.StatusBadRequest, "badCSR", "failed to decode CSR: %v", err))
+	}
+	csr, err := x509.ParseCertificateRequest(der)
+	if err == nil {
+		err = csr.CheckSignature()
+	}
+	if err != nil {
+		return b.acmeProblemResponse(acmeReq.config, acmeReq.role, newACMEProblem(http.StatusBadRequest, "badCSR", "invalid CSR: %v", err))
+	}
+
+	// The CSR must request exactly the names of the order
+	if len(csr.IPAddresses) != 0 || len(csr.EmailAddresses) != 0 {
+		return b.acmeProblemResponse(acmeReq.config, acmeReq.role, newACMEProblem(http.StatusBadRequest, "badCSR", "CSR requests names that are not in the order"))
+	}
+	names := csr.DNSNames
+	if csr.Subject.CommonName != "" {
+		names = append(names, csr.Subject.CommonName)
+	}
+	names = strutil.RemoveDuplicates(names, true)
+	var orderNames []string
+	for _, identifier := range order.Identifiers {
+		orderNames = append(orderNames, identifier.Value)
+	}
+	sort.Strings(orderNames)
+	if !strutil.EquivalentSlices(names, orderNames) {
+		return b.acmeProblemResponse(acmeReq.config, acmeReq.role, newACMEProblem(http.StatusBadRequest, "badCSR", "CSR names %v do not match the order's %v", names, orderNames))
+	}
+
+	commonName := csr.Subject.CommonName
+	if commonName == "" {
+		commonName = orderNames[0]
+	}
+	signData := &framework.FieldData{
+		Raw: map[string]interface{}{
+			"csr": string(pem.EncodeToMemory(&pem.Block{
+				Type:  "CERTIFICATE REQUEST",
+				Bytes: der,
+			})),
+			"common_name": commonName,
+			"alt_names":   strings.Join(orderNames, ","),
+
+			// The common name is one of the order's names, so it is
+			// among the alternate names already
+			"exclude_cn_from_sans": true,
+		},
+		Schema: pathSign(b).Fields,
+	}
+
+	signingBundle, err := fetchCAInfoByIssuer(req, role.IssuerRef)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching CA certificate: %s", err)
+	}
+	// The names of the CSR have been checked against the order, so they are
+	// passed as parameters whatever the role says about taking them from
+	// the CSR
+	signRole := *role
+	signRole.UseCSRCommonName = false
+	signRole.UseCSRSANs = false
+	parsedBundle, err := signCert(b, &signRole, signingBundle, false, false, req, signData)
+	if err != nil {
+		switch err.(type) {
+		case errutil.UserError:
+			return b.acmeProblemResponse(acmeReq.config, acmeReq.role, newACMEProblem(http.StatusBadRequest, "badCSR", "%v", err))
+		default:
+			return nil, err
+		}
+	}
+	cb, err := parsedBundle.ToCertBundle()
+	if err != nil {
+		return nil, fmt.Errorf("error converting raw cert bundle to cert bundle: %s", err)
+	}
+
+	if !role.NoStore {
+		err = storeCert(req.Storage, cb.SerialNumber, parsedBundle.Certificate)
+		if err != nil {
+			return nil, fmt.Errorf("unable to store certificate locally: %v", err)
+		}
+	}
+
+	chain := []string{cb.Certificate}
+	chain = append(chain, cb.CAChain...)
+	order.Status = acmeStatusValid
+	order.CertSerial = cb.SerialNumber
+	order.CertChain = strings.Join(chain, "\n") + "\n"
+	if err := putACMEEntry(req.Storage, "acme/order/"+order.ID, order); err != nil {
+		return nil, err
+	}
+
+	return b.acmeOrderResponse(acmeReq, order, http.StatusOK)
+}
+
+func (b *backend) acmeOrderResponse(acmeReq *acmeRequest, order *acmeOrder, status int) (*logical.Response, error) {
+	config := acmeReq.config
+	authorizations := make([]string, 0, len(order.Authorizations))
+	for _, authzID := range order.Authorizations {
+		authorizations = append(authorizations, config.acmeURL(order.Role, "authz/"+authzID))
+	}
+
+	body := map[string]interface{}{
+		"status":         order.Status,
+		"expires":        order.Expires.Format(time.RFC3339),
+		"identifiers":    order.Identifiers,
+		"authorizations": authorizations,
+		"finalize":       config.acmeURL(order.Role, "order/"+order.ID+"/finalize"),
+	}
+	if order.Status == acmeStatusValid {
+		body["certificate"] = config.acmeURL(order.Role, "cert/"+order.ID)
+	}
+	if order.Error != nil {
+		body["error"] = order.Error
+	}
+
+	return b.acmeResponse(config, acmeReq.role, status, body, map[string][]string{
+		"Location": []string{config.acmeURL(order.Role, "order/"+order.ID)},
+	})
+}
+
+// acmeRequestAuthorization returns the authorization of the request's
+// account with the ID
+func (b *backend) acmeRequestAuthorization(req *logical.Request, acmeReq *acmeRequest, id string) (*acmeAuthorization, *acmeProblem, error) {
+	authz, err := b.acmeAuthorization(req.Storage, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if authz == nil || authz.Role != acmeReq.role {
+		return nil, newACMEProblem(http.StatusNotFound, "malformed", "unknown authorization %q", id), nil
+	}
+	if authz.AccountID != acmeReq.account.ID {
+		return nil, newACMEProblem(http.StatusForbidden, "unauthorized", "authorization belongs to another account"), nil
+	}
+	if authz.Status == acmeStatusPending && time.Now().After(authz.Expires) {
+		authz.Status = acmeStatusInvalid
+		if err := putACMEEntry(req.Storage, "acme/authz/"+authz.ID, authz); err != nil {
+			return nil, nil, err
+		}
+	}
+	return authz, nil, nil
+}
+
+func (b *backend) pathACMEAuthorization(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	id := data.Get("id").(string)
+	acmeReq, _, resp, err := b.handleACMERequest(req, data, "authz/"+id, false)
+	if acmeReq == nil {
+		return resp, err
+	}
+
+	b.acmeLock.Lock()
+	defer b.acmeLock.Unlock()
+
+	authz, problem, err := b.acmeRequestAuthorization(req, acmeReq, id)
+	if err != nil {
+		return nil, err
+	}
+	if problem != nil {
+		return b.acmeProblemResponse(acmeReq.config, acmeReq.role, problem)
+	}
+
+	challenges := make([]interface{}, 0, len(authz.Challenges))
+	for _, challenge := range authz.Challenges {
+		challenges = append(challenges, acmeChallengeData(acmeReq.config, authz, challenge))
+	}
+	body := map[string]interface{}{
+		"status":     authz.Status,
+		"expires":    authz.Expires.Format(time.RFC3339),
+		"identifier": authz.Identifier,
+		"challenges": challenges,
+	}
+	if authz.Wildcard {
+		body["wildcard"] = true
+	}
+	return b.acmeResponse(acmeReq.config, acmeReq.role, http.StatusOK, body, nil)
+}
+
+func (b *backend) pathACMEChallenge(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	id := data.Get("id").(string)
+	challengeType := data.Get("type").(string)
+	acmeReq, _, resp, err := b.handleACMERequest(req, data, "challenge/"+id+"/"+challengeType, false)
+	if acmeReq == nil {
+		return resp, err
+	}
+
+	// Challenges are validated once, when the client first responds to them.
+	// The challenge is marked as processing while it is validated, so that
+	// requests repeated meanwhile don't validate it again.
+	b.acmeLock.Lock()
+	authz, challenge, problem, err := b.acmeRequestChallenge(req, acmeReq, id, challengeType)
+	validate := err == nil && problem == nil &&
+		authz.Status == acmeStatusPending && challenge.Status == acmeStatusPending && len(acmeReq.payload) != 0
+	if validate {
+		challenge.Status = acmeStatusProcessing
+		err = putACMEEntry(req.Storage, "acme/authz/"+authz.ID, authz)
+	}
+	b.acmeLock.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if problem != nil {
+		return b.acmeProblemResponse(acmeReq.config, acmeReq.role, problem)
+	}
+	if !validate {
+		return b.acmeChallengeResponse(acmeReq, authz, challenge)
+	}
+
+	// The validation waits on the client's servers, so it runs without the
+	// lock, which would otherwise hold up the ACME requests of every client
+	validationProblem, validationErr := b.validateACMEChallenge(authz, challenge, acmeReq.jwk)
+
+	b.acmeLock.Lock()
+	defer b.acmeLock.Unlock()
+
+	// The authorization is loaded again, since it may have been changed,
+	// such as deactivated, during the validation
+	authz, challenge, problem, err = b.acmeRequestChallenge(req, acmeReq, id, challengeType)
+	if err != nil {
+		return nil, err
+	}
+	if problem != nil {
+		return b.acmeProblemResponse(acmeReq.config, acmeReq.role, problem)
+	}
+	if challenge.Status == acmeStatusProcessing {
+		switch {
+		case validationErr != nil:
+			// The client may try again
+			challenge.Status = acmeStatusPending
+		case authz.Status != acmeStatusPending:
+			challenge.Status = acmeStatusInvalid
+		case validationProblem != nil:
+			challenge.Status = acmeStatusInvalid
+			challenge.Error = validationProblem
+			authz.Status = acmeStatusInvalid
+		default:
+			challenge.Status = acmeStatusValid
+			challenge.Validated = time.Now().UTC()
+			authz.Status = acmeStatusValid
+		}
+		if err := putACMEEntry(req.Storage, "acme/authz/"+authz.ID, authz); err != nil {
+			return nil, err
+		}
+	}
+	if validationErr != nil {
+		return nil, validationErr
+	}
+
+	return b.acmeChallengeResponse(acmeReq, authz, challenge)
+}
+
+// acmeRequestChallenge returns the authorization named by the request and its
+// challenge of the given type
+func (b *backend) acmeRequestChallenge(req *logical.Request, acmeReq *acmeRequest, id, challengeType string) (*acmeAuthorization, *acmeChallenge, *acmeProblem, error) {
+	authz, problem, err := b.acmeRequestAuthorization(req, acmeReq, id)
+	if err != nil || problem != nil {
+		return nil, nil, problem, err
+	}
+	for _, c := range authz.Challenges {
+		if c.Type == challengeType {
+			return authz, c, nil, nil
+		}
+	}
+	return nil, nil, newACMEProblem(http.StatusNotFound, "malformed", "authorization has no %s challenge", challengeType), nil
+}
+
+func (b *backend) acmeChallengeResponse(acmeReq *acmeRequest, authz *acmeAuthorization, challenge *acmeChallenge) (*logical.Response, error) {
+	return b.acmeResponse(acmeReq.config, acmeReq.role, http.StatusOK, acmeChallengeData(acmeReq.config, authz, challenge), map[string][]string{
+		"Link": []string{fmt.Sprintf(`<%s>;rel="up"`, acmeReq.config.acmeURL(authz.Role, "authz/"+authz.ID))},
+	})
+}
+
+func acmeChallengeData(config *acmeConfig, authz *acmeAuthorization, challenge *acmeChallenge) map[string]interface{} {
+	data := map[string]interface{}{
+		"type":   challenge.Type,
+		"url":    config.acmeURL(authz.Role, "challenge/"+authz.ID+"/"+challenge.Type),
+		"token":  challenge.Token,
+		"status": challenge.Status,
+	}
+	if !challenge.Validated.IsZero() {
+		data["validated"] = challenge.Validated.Format(time.RFC3339)
+	}
+	if challenge.Error != nil {
+		data["error"] = challenge.Error
+	}
+	return data
+}
+
+func (b *backend) pathACMECert(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	id := data.Get("id").(string)
+	acmeReq, _, resp, err := b.handleACMERequest(req, data, "cert/"+id, false)
+	if acmeReq == nil {
+		return resp, err
+	}
+
+	b.acmeLock.Lock()
+	defer b.acmeLock.Unlock()
+
+	order, problem, err := b.acmeRequestOrder(req, acmeReq, id)
+	if err != nil {
+		return nil, err
+	}
+	if problem != nil {
+		return b.acmeProblemResponse(acmeReq.config, acmeReq.role, problem)
+	}
+	if order.Status != acmeStatusValid {
+		return b.acmeProblemResponse(acmeReq.config, acmeReq.role, newACMEProblem(http.StatusNotFound, "malformed", "order %q has no certificate", id))
+	}
+
+	return b.acmeResponse(acmeReq.config, acmeReq.role, http.StatusOK, []byte(order.CertChain), nil)
+}
+
+const pathACMEHelpSyn = `
+ACME (RFC 8555) endpoints for ordering certificates against a role.
+`
+
+const pathACMEHelpDesc = `
+These endpoints implement the ACME protocol so that ACME clients can obtain
+certificates from this backend. Point clients at the directory endpoint,
+"acme/<role>/directory". Orders are subject to the restrictions of the role,
+and each name must be validated with an http-01 or dns-01 challenge before
+the certificate is issued.
+
+These endpoints are unauthenticated, as ACME requests are authenticated by
+the signature of the client's account key. They must be enabled with the
+"config/acme" endpoint.
+`
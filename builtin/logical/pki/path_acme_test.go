@@ -0,0 +1,465 @@
+package pki
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/helper/certutil"
+	"github.com/hashicorp/vault/logical"
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+const acmeTestBaseURL = "https://vault.example.com/v1/pki"
+
+// acmeTestClient drives the ACME endpoints of a backend the way an ACME
+// client would
+type acmeTestClient struct {
+	t       *testing.T
+	b       *backend
+	storage logical.Storage
+	key     *ecdsa.PrivateKey
+	kid     string
+}
+
+type acmeTestResponse struct {
+	status  int
+	headers map[string][]string
+	body    []byte
+}
+
+func (r *acmeTestResponse) decode(t *testing.T) map[string]interface{} {
+	var out map[string]interface{}
+	if err := json.Unmarshal(r.body, &out); err != nil {
+		t.Fatalf("bad: %s: %v", r.body, err)
+	}
+	return out
+}
+
+func (r *acmeTestResponse) problemType() string {
+	var problem acmeProblem
+	json.Unmarshal(r.body, &problem)
+	return strings.TrimPrefix(problem.Type, acmeErrorPrefix)
+}
+
+func rawACMEResponse(t *testing.T, resp *logical.Response, err error) *acmeTestResponse {
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: err: %v resp: %#v", err, resp)
+	}
+	out := &acmeTestResponse{
+		status:  resp.Data[logical.HTTPStatusCode].(int),
+		headers: resp.Data[logical.HTTPRawHeaders].(map[string][]string),
+	}
+	if body, ok := resp.Data[logical.HTTPRawBody]; ok {
+		out.body = body.([]byte)
+	}
+	return out
+}
+
+type staticNonce string
+
+func (n staticNonce) Nonce() (string, error) {
+	return string(n), nil
+}
+
+func (c *acmeTestClient) Nonce() (string, error) {
+	resp, err := c.b.HandleRequest(&logical.Request{
+		Operation: logical.HeaderOperation,
+		Path:      "acme/web/new-nonce",
+		Storage:   c.storage,
+	})
+	raw := rawACMEResponse(c.t, resp, err)
+	if raw.status != http.StatusOK {
+		c.t.Fatalf("bad: %d", raw.status)
+	}
+	return raw.headers["Replay-Nonce"][0], nil
+}
+
+// post sends the payload signed by the account key to the path. A nil
+// payload sends a POST-as-GET request.
+func (c *acmeTestClient) post(path string, payload interface{}) *acmeTestResponse {
+	var signingKey interface{} = c.key
+	if c.kid != "" {
+		signingKey = jose.JSONWebKey{Key: c.key, KeyID: c.kid}
+	}
+	opts := (&jose.SignerOptions{
+		NonceSource: c,
+		EmbedJWK:    c.kid == "",
+	}).WithHeader("url", acmeTestBaseURL+"/acme/web/"+path)
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.ES256, Key: signingKey}, opts)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+
+	var body []byte
+	if payload != nil {
+		if body, err = json.Marshal(payload); err != nil {
+			c.t.Fatal(err)
+		}
+	}
+	jws, err := signer.Sign(body)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return c.postJWS(path, jws.FullSerialize())
+}
+
+func (c *acmeTestClient) postJWS(path, serialized string) *acmeTestResponse {
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(serialized), &data); err != nil {
+		c.t.Fatal(err)
+	}
+	resp, err := c.b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "acme/web/" + path,
+		Storage:   c.storage,
+		Data:      data,
+	})
+	return rawACMEResponse(c.t, resp, err)
+}
+
+// relative returns the path of an ACME URL relative to the role's endpoints
+func (c *acmeTestClient) relative(url string) string {
+	prefix := acmeTestBaseURL + "/acme/web/"
+	if !strings.HasPrefix(url, prefix) {
+		c.t.Fatalf("bad url: %s", url)
+	}
+	return strings.TrimPrefix(url, prefix)
+}
+
+func setupACMETest(t *testing.T) *acmeTestClient {
+	b, storage := createBackendWithStorage(t)
+
+	for _, req := range []*logical.Request{
+		{
+			Operation: logical.UpdateOperation,
+			Path:      "root/generate/internal",
+			Data: map[string]interface{}{
+				"common_name": "example.com",
+				"ttl":         "48h",
+			},
+		},
+		{
+			Operation: logical.UpdateOperation,
+			Path:      "roles/web",
+			Data: map[string]interface{}{
+				"allowed_domains":  "example.com",
+				"allow_subdomains": true,
+				"ttl":              "1h",
+				"key_type":         "ec",
+				"key_bits":         256,
+			},
+		},
+		{
+			Operation: logical.UpdateOperation,
+			Path:      "config/acme",
+			Data: map[string]interface{}{
+				"enabled":  true,
+				"base_url": acmeTestBaseURL,
+			},
+		},
+	} {
+		req.Storage = storage
+		resp, err := b.HandleRequest(req)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: err: %v resp: %#v", err, resp)
+		}
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &acmeTestClient{
+		t:       t,
+		b:       b,
+		storage: storage,
+		key:     key,
+	}
+}
+
+// newAccount registers the client's key and switches it to signing with the
+// account URL
+func (c *acmeTestClient) newAccount() {
+	resp := c.post("new-account", map[string]interface{}{
+		"contact":              []string{"mailto:admin@example.com"},
+		"termsOfServiceAgreed": true,
+	})
+	if resp.status != http.StatusCreated {
+		c.t.Fatalf("bad: %d %s", resp.status, resp.body)
+	}
+	c.kid = resp.headers["Location"][0]
+}
+
+func TestPki_ACME(t *testing.T) {
+	c := setupACMETest(t)
+
+	// Serve the http-01 response for every name from a local server
+	keyAuths := make(map[string]string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.URL.Path, "/.well-known/acme-challenge/")
+		keyAuth, ok := keyAuths[token]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(keyAuth))
+	}))
+	defer server.Close()
+	c.b.acmeHTTPClient = &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				return net.Dial(network, server.Listener.Addr().String())
+			},
+		},
+	}
+	// The ACME endpoints stay available while a challenge is validated, and
+	// show it as processing
+	txtRecords := make(map[string][]string)
+	var validating string
+	c.b.acmeLookupTXT = func(name string) ([]string, error) {
+		if validating != "" {
+			authz := c.post(validating, nil).decode(t)
+			challenge := authz["challenges"].([]interface{})[0].(map[string]interface{})
+			if challenge["status"] != acmeStatusProcessing {
+				t.Fatalf("bad: %#v", authz)
+			}
+		}
+		return txtRecords[name], nil
+	}
+
+	resp, err := c.b.HandleRequest(&logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "acme/web/directory",
+		Storage:   c.storage,
+	})
+	directory := rawACMEResponse(t, resp, err).decode(t)
+	if directory["newOrder"] != acmeTestBaseURL+"/acme/web/new-order" {
+		t.Fatalf("bad: %#v", directory)
+	}
+
+	c.newAccount()
+
+	// Registering the same key again returns the existing account
+	kid := c.kid
+	c.kid = ""
+	existing := c.post("new-account", map[string]interface{}{"onlyReturnExisting": true})
+	if existing.status != http.StatusOK || existing.headers["Location"][0] != kid {
+		t.Fatalf("bad: %d %#v", existing.status, existing.headers)
+	}
+	c.kid = kid
+
+	orderResp := c.post("new-order", map[string]interface{}{
+		"identifiers": []map[string]string{
+			{"type": "dns", "value": "www.example.com"},
+			{"type": "dns", "value": "*.example.com"},
+		},
+	})
+	if orderResp.status != http.StatusCreated {
+		t.Fatalf("bad: %d %s", orderResp.status, orderResp.body)
+	}
+	orderURL := orderResp.headers["Location"][0]
+	order := orderResp.decode(t)
+	if order["status"] != acmeStatusPending {
+		t.Fatalf("bad: %#v", order)
+	}
+
+	// Finalizing before the names are validated fails
+	finalize := c.relative(order["finalize"].(string))
+	if resp := c.post(finalize, map[string]interface{}{"csr": ""}); resp.problemType() != "orderNotReady" {
+		t.Fatalf("bad: %d %s", resp.status, resp.body)
+	}
+
+	thumbprint, err := (&jose.JSONWebKey{Key: c.key.Public()}).Thumbprint(crypto.SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, authzURL := range order["authorizations"].([]interface{}) {
+		authz := c.post(c.relative(authzURL.(string)), nil).decode(t)
+		identifier := authz["identifier"].(map[string]interface{})["value"].(string)
+		wildcard, _ := authz["wildcard"].(bool)
+
+		wantType := acmeChallengeHTTP01
+		if wildcard {
+			wantType = acmeChallengeDNS01
+		}
+		var challenge map[string]interface{}
+		for _, raw := range authz["challenges"].([]interface{}) {
+			if raw.(map[string]interface{})["type"] == wantType {
+				challenge = raw.(map[string]interface{})
+			}
+		}
+		if challenge == nil {
+			t.Fatalf("no %s challenge: %#v", wantType, authz)
+		}
+
+		token := challenge["token"].(string)
+		keyAuth := token + "." + base64.RawURLEncoding.EncodeToString(thumbprint)
+		if wildcard {
+			sum := sha256.Sum256([]byte(keyAuth))
+			txtRecords["_acme-challenge."+identifier] = []string{base64.RawURLEncoding.EncodeToString(sum[:])}
+			validating = c.relative(authzURL.(string))
+		} else {
+			keyAuths[token] = keyAuth
+		}
+
+		result := c.post(c.relative(challenge["url"].(string)), map[string]interface{}{}).decode(t)
+		if result["status"] != acmeStatusValid {
+			t.Fatalf("bad: %#v", result)
+		}
+	}
+
+	order = c.post(c.relative(orderURL), nil).decode(t)
+	if order["status"] != acmeStatusReady {
+		t.Fatalf("bad: %#v", order)
+	}
+
+	// The CSR must request exactly the names of the order
+	certKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	csr := func(names ...string) string {
+		der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+			Subject:  pkix.Name{CommonName: names[0]},
+			DNSNames: names,
+		}, certKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(der)
+	}
+	if resp := c.post(finalize, map[string]interface{}{"csr": csr("www.example.com")}); resp.problemType() != "badCSR" {
+		t.Fatalf("bad: %d %s", resp.status, resp.body)
+	}
+
+	order = c.post(finalize, map[string]interface{}{"csr": csr("www.example.com", "*.example.com")}).decode(t)
+	if order["status"] != acmeStatusValid {
+		t.Fatalf("bad: %#v", order)
+	}
+
+	certResp := c.post(c.relative(order["certificate"].(string)), nil)
+	if certResp.status != http.StatusOK {
+		t.Fatalf("bad: %d %s", certResp.status, certResp.body)
+	}
+	block, _ := pem.Decode(certResp.body)
+	if block == nil {
+		t.Fatalf("bad: %s", certResp.body)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dnsNames := cert.DNSNames
+	sort.Strings(dnsNames)
+	if !reflect.DeepEqual(dnsNames, []string{"*.example.com", "www.example.com"}) {
+		t.Fatalf("bad: %v", cert.DNSNames)
+	}
+
+	// The certificate is stored like those issued by the sign endpoint
+	serial := certutil.GetHexFormatted(cert.SerialNumber.Bytes(), ":")
+	entry, err := c.storage.Get("certs/" + normalizeSerial(serial))
+	if err != nil || entry == nil {
+		t.Fatalf("certificate not stored: %v", err)
+	}
+}
+
+func TestPki_ACMERejected(t *testing.T) {
+	c := setupACMETest(t)
+	c.b.acmeLookupTXT = func(name string) ([]string, error) {
+		return nil, nil
+	}
+
+	// Requests must be signed by a registered account
+	c.kid = acmeTestBaseURL + "/acme/web/account/unknown"
+	if resp := c.post("new-order", map[string]interface{}{}); resp.problemType() != "accountDoesNotExist" {
+		t.Fatalf("bad: %d %s", resp.status, resp.body)
+	}
+	c.kid = ""
+	c.newAccount()
+
+	// Names outside of the role are rejected
+	resp := c.post("new-order", map[string]interface{}{
+		"identifiers": []map[string]string{{"type": "dns", "value": "www.example.org"}},
+	})
+	if resp.status != http.StatusForbidden || resp.problemType() != "rejectedIdentifier" {
+		t.Fatalf("bad: %d %s", resp.status, resp.body)
+	}
+
+	// Nonces cannot be replayed, and requests must be signed for the URL
+	// they are sent to
+	nonce, _ := c.Nonce()
+	signer, err := jose.NewSigner(jose.SigningKey{
+		Algorithm: jose.ES256,
+		Key:       jose.JSONWebKey{Key: c.key, KeyID: c.kid},
+	}, (&jose.SignerOptions{
+		NonceSource: staticNonce(nonce),
+	}).WithHeader("url", acmeTestBaseURL+"/acme/web/new-order"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	jws, err := signer.Sign([]byte(`{"identifiers":[{"type":"dns","value":"www.example.com"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp := c.postJWS("new-account", jws.FullSerialize()); resp.problemType() != "unauthorized" {
+		t.Fatalf("bad: %d %s", resp.status, resp.body)
+	}
+	if resp := c.postJWS("new-order", jws.FullSerialize()); resp.problemType() != "badNonce" {
+		t.Fatalf("bad: %d %s", resp.status, resp.body)
+	}
+
+	// A failed challenge invalidates the authorization and the order
+	orderResp := c.post("new-order", map[string]interface{}{
+		"identifiers": []map[string]string{{"type": "dns", "value": "www.example.com"}},
+	})
+	orderURL := orderResp.headers["Location"][0]
+	order := orderResp.decode(t)
+	authzURL := order["authorizations"].([]interface{})[0].(string)
+	authz := c.post(c.relative(authzURL), nil).decode(t)
+	for _, raw := range authz["challenges"].([]interface{}) {
+		challenge := raw.(map[string]interface{})
+		if challenge["type"] != acmeChallengeDNS01 {
+			continue
+		}
+		result := c.post(c.relative(challenge["url"].(string)), map[string]interface{}{}).decode(t)
+		if result["status"] != acmeStatusInvalid || result["error"] == nil {
+			t.Fatalf("bad: %#v", result)
+		}
+	}
+	order = c.post(c.relative(orderURL), nil).decode(t)
+	if order["status"] != acmeStatusInvalid {
+		t.Fatalf("bad: %#v", order)
+	}
+
+	// Other accounts cannot see the order
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := &acmeTestClient{
+		t:       t,
+		b:       c.b,
+		storage: c.storage,
+		key:     otherKey,
+	}
+	other.newAccount()
+	if resp := other.post(c.relative(authzURL), nil); resp.status != http.StatusForbidden {
+		t.Fatalf("bad: %d %s", resp.status, resp.body)
+	}
+}
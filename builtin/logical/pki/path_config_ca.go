@@ -18,6 +18,11 @@ func pathConfigCA(b *backend) *framework.Path {
 				Description: `PEM-format, concatenated unencrypted
 secret key and certificate.`,
 			},
+
+			"issuer_name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: `Name of the issuer`,
+			},
 		},
 
 		Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -61,27 +66,16 @@ func (b *backend) pathCAWrite(
 		return nil, fmt.Errorf("error converting raw values into cert bundle: %s", err)
 	}
 
-	entry, err := logical.StorageEntryJSON("config/ca_bundle", cb)
-	if err != nil {
-		return nil, err
-	}
-	err = req.Storage.Put(entry)
-	if err != nil {
-		return nil, err
-	}
-
-	// For ease of later use, also store just the certificate at a known
-	// location, plus a fresh CRL
-	entry.Key = "ca"
-	entry.Value = parsedBundle.CertificateBytes
-	err = req.Storage.Put(entry)
-	if err != nil {
+	// Register it as the default issuer, which also stores it as the CA
+	// bundle and the certificate at "ca", plus a fresh CRL. The previous CA
+	// is kept as an issuer.
+	_, err = b.importIssuer(req, cb, data.Get("issuer_name").(string), true)
+	switch err.(type) {
+	case errutil.UserError:
+		return logical.ErrorResponse(err.Error()), nil
+	default:
 		return nil, err
 	}
-
-	err = buildCRL(b, req)
-
-	return nil, err
 }
 
 const pathConfigCAHelpSyn = `
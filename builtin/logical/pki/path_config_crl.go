@@ -10,7 +10,17 @@ import (
 
 // CRLConfig holds basic CRL configuration information
 type crlConfig struct {
-	Expiry string `json:"expiry" mapstructure:"expiry" structs:"expiry"`
+	Expiry               string `json:"expiry" mapstructure:"expiry" structs:"expiry"`
+	RebuildInterval      string `json:"rebuild_interval" mapstructure:"rebuild_interval" structs:"rebuild_interval"`
+	EnableDelta          bool   `json:"enable_delta" mapstructure:"enable_delta" structs:"enable_delta"`
+	DeltaRebuildInterval string `json:"delta_rebuild_interval" mapstructure:"delta_rebuild_interval" structs:"delta_rebuild_interval"`
+}
+
+func (c *crlConfig) deltaRebuildInterval() string {
+	if c.DeltaRebuildInterval == "" {
+		return "15m"
+	}
+	return c.DeltaRebuildInterval
 }
 
 func pathConfigCRL(b *backend) *framework.Path {
@@ -23,6 +33,27 @@ func pathConfigCRL(b *backend) *framework.Path {
 valid; defaults to 72 hours`,
 				Default: "72h",
 			},
+
+			"rebuild_interval": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `How often the CRL is rebuilt in the background,
+such as "12h"; must be shorter than the expiry. If empty, the CRL is only
+rebuilt on revocation or rotation.`,
+			},
+
+			"enable_delta": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `Whether to build delta CRLs. Revocations then
+only rebuild the delta CRL, and the full CRL is rebuilt on its schedule.
+Requires rebuild_interval.`,
+			},
+
+			"delta_rebuild_interval": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `How often the delta CRL is rebuilt in the
+background; defaults to 15 minutes`,
+				Default: "15m",
+			},
 		},
 
 		Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -64,22 +95,63 @@ func (b *backend) pathCRLRead(
 
 	return &logical.Response{
 		Data: map[string]interface{}{
-			"expiry": config.Expiry,
+			"expiry":                 config.Expiry,
+			"rebuild_interval":       config.RebuildInterval,
+			"enable_delta":           config.EnableDelta,
+			"delta_rebuild_interval": config.deltaRebuildInterval(),
 		},
 	}, nil
 }
 
 func (b *backend) pathCRLWrite(
 	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
-	expiry := d.Get("expiry").(string)
+	config, err := b.CRL(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		config = &crlConfig{
+			Expiry: d.Get("expiry").(string),
+		}
+	}
+
+	if expiryRaw, ok := d.GetOk("expiry"); ok {
+		config.Expiry = expiryRaw.(string)
+	}
+	if rebuildIntervalRaw, ok := d.GetOk("rebuild_interval"); ok {
+		config.RebuildInterval = rebuildIntervalRaw.(string)
+	}
+	if enableDeltaRaw, ok := d.GetOk("enable_delta"); ok {
+		config.EnableDelta = enableDeltaRaw.(bool)
+	}
+	if deltaRebuildIntervalRaw, ok := d.GetOk("delta_rebuild_interval"); ok {
+		config.DeltaRebuildInterval = deltaRebuildIntervalRaw.(string)
+	}
 
-	_, err := time.ParseDuration(expiry)
+	expiry, err := time.ParseDuration(config.Expiry)
 	if err != nil {
 		return logical.ErrorResponse(fmt.Sprintf("Given expiry could not be decoded: %s", err)), nil
 	}
-
-	config := &crlConfig{
-		Expiry: expiry,
+	if config.RebuildInterval != "" {
+		rebuildInterval, err := time.ParseDuration(config.RebuildInterval)
+		if err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("Given rebuild_interval could not be decoded: %s", err)), nil
+		}
+		if rebuildInterval <= 0 || rebuildInterval >= expiry {
+			return logical.ErrorResponse("rebuild_interval must be positive and shorter than the expiry"), nil
+		}
+	}
+	if config.EnableDelta {
+		if config.RebuildInterval == "" {
+			return logical.ErrorResponse("rebuild_interval is required to enable delta CRLs"), nil
+		}
+		deltaRebuildInterval, err := time.ParseDuration(config.deltaRebuildInterval())
+		if err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("Given delta_rebuild_interval could not be decoded: %s", err)), nil
+		}
+		if deltaRebuildInterval <= 0 {
+			return logical.ErrorResponse("delta_rebuild_interval must be positive"), nil
+		}
 	}
 
 	entry, err := logical.StorageEntryJSON("config/crl", config)
@@ -95,9 +167,11 @@ func (b *backend) pathCRLWrite(
 }
 
 const pathConfigCRLHelpSyn = `
-Configure the CRL expiration.
+Configure the CRL expiration and rebuild schedule.
 `
 
 const pathConfigCRLHelpDesc = `
-This endpoint allows configuration of the CRL lifetime.
+This endpoint allows configuration of the CRL lifetime, and of how often the
+CRL and delta CRL are rebuilt in the background so that relying parties never
+see an expired CRL.
 `
@@ -0,0 +1,185 @@
+package pki
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestPki_CRLRebuild(t *testing.T) {
+	b, storage := createBackendWithStorage(t)
+
+	handle := func(op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		resp, err := b.HandleRequest(&logical.Request{
+			Operation: op,
+			Path:      path,
+			Storage:   storage,
+			Data:      data,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: path: %s err: %v resp: %#v", path, err, resp)
+		}
+		return resp
+	}
+	// The CRLs are checked against the CA certificate
+	var caCert *x509.Certificate
+	fetchCRL := func(path string) *pkix.CertificateList {
+		resp := handle(logical.ReadOperation, path, nil)
+		der := resp.Data[logical.HTTPRawBody].([]byte)
+		if strings.HasSuffix(path, "/pem") {
+			block, _ := pem.Decode(der)
+			if block == nil {
+				t.Fatalf("bad: %s", der)
+			}
+			der = block.Bytes
+		}
+		crl, err := x509.ParseDERCRL(der)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := caCert.CheckCRLSignature(crl); err != nil {
+			t.Fatal(err)
+		}
+		return crl
+	}
+	// crlNumber returns the number held by the given extension of a CRL
+	crlNumber := func(crl *pkix.CertificateList, oid asn1.ObjectIdentifier) int64 {
+		for _, ext := range crl.TBSCertList.Extensions {
+			if ext.Id.Equal(oid) {
+				var number *big.Int
+				if _, err := asn1.Unmarshal(ext.Value, &number); err != nil {
+					t.Fatal(err)
+				}
+				return number.Int64()
+			}
+		}
+		t.Fatalf("no extension %s in CRL", oid)
+		return 0
+	}
+
+	resp := handle(logical.UpdateOperation, "root/generate/internal", map[string]interface{}{
+		"common_name": "myvault.com",
+		"ttl":         "5h",
+	})
+	block, _ := pem.Decode([]byte(resp.Data["certificate"].(string)))
+	if block == nil {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	caCert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handle(logical.UpdateOperation, "roles/testrole", map[string]interface{}{
+		"allowed_domains":  "myvault.com",
+		"allow_subdomains": true,
+		"ttl":              "2h",
+	})
+
+	// Delta CRLs need a rebuild schedule shorter than the expiry
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config/crl",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"enable_delta": true,
+		},
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error: err: %v resp: %#v", err, resp)
+	}
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config/crl",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"expiry":           "1h",
+			"rebuild_interval": "2h",
+		},
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error: err: %v resp: %#v", err, resp)
+	}
+
+	handle(logical.UpdateOperation, "config/crl", map[string]interface{}{
+		"rebuild_interval": "1h",
+		"enable_delta":     true,
+	})
+	resp = handle(logical.ReadOperation, "config/crl", nil)
+	if resp.Data["expiry"] != "72h" || resp.Data["delta_rebuild_interval"] != "15m" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// The periodic function builds the missing delta CRL
+	if err := b.rebuildCRLs(&logical.Request{Storage: storage}); err != nil {
+		t.Fatal(err)
+	}
+	resp = handle(logical.ReadOperation, "crl/info", nil)
+	if resp.Data["crl_number"] != int64(1) || resp.Data["delta_crl_number"] != int64(2) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	resp = handle(logical.UpdateOperation, "issue/testrole", map[string]interface{}{
+		"common_name": "cert.myvault.com",
+	})
+	serial := resp.Data["serial_number"].(string)
+	handle(logical.UpdateOperation, "revoke", map[string]interface{}{
+		"serial_number": serial,
+	})
+
+	// The revocation only shows up in the delta CRL until the next rebuild
+	crl := fetchCRL("crl")
+	if crlNumber(crl, oidExtensionCRLNumber) != 1 || len(crl.TBSCertList.RevokedCertificates) != 0 {
+		t.Fatalf("bad: number %d entries %d", crlNumber(crl, oidExtensionCRLNumber), len(crl.TBSCertList.RevokedCertificates))
+	}
+	delta := fetchCRL("crl/delta")
+	if crlNumber(delta, oidExtensionCRLNumber) != 3 || crlNumber(delta, oidExtensionDeltaCRLIndicator) != 1 || len(delta.TBSCertList.RevokedCertificates) != 1 {
+		t.Fatalf("bad: number %d entries %d", crlNumber(delta, oidExtensionCRLNumber), len(delta.TBSCertList.RevokedCertificates))
+	}
+
+	// Nothing is due yet
+	if err := b.rebuildCRLs(&logical.Request{Storage: storage}); err != nil {
+		t.Fatal(err)
+	}
+	if crlNumber(fetchCRL("crl/delta"), oidExtensionCRLNumber) != 3 {
+		t.Fatal("expected the delta CRL not to be rebuilt")
+	}
+
+	// Once the rebuild interval passes, the full CRL picks up the revocation
+	// and the delta CRL starts over
+	state, err := fetchCRLState(storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state.ThisUpdate = state.ThisUpdate.Add(-time.Hour)
+	if err := storeCRLState(&logical.Request{Storage: storage}, state); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.rebuildCRLs(&logical.Request{Storage: storage}); err != nil {
+		t.Fatal(err)
+	}
+
+	crl = fetchCRL("crl")
+	if crlNumber(crl, oidExtensionCRLNumber) != 4 || len(crl.TBSCertList.RevokedCertificates) != 1 {
+		t.Fatalf("bad: number %d entries %d", crlNumber(crl, oidExtensionCRLNumber), len(crl.TBSCertList.RevokedCertificates))
+	}
+	delta = fetchCRL("crl/delta/pem")
+	if crlNumber(delta, oidExtensionCRLNumber) != 5 || crlNumber(delta, oidExtensionDeltaCRLIndicator) != 4 || len(delta.TBSCertList.RevokedCertificates) != 0 {
+		t.Fatalf("bad: number %d entries %d", crlNumber(delta, oidExtensionCRLNumber), len(delta.TBSCertList.RevokedCertificates))
+	}
+
+	resp = handle(logical.ReadOperation, "crl/info", nil)
+	nextUpdate, err := time.Parse(time.RFC3339, resp.Data["next_update"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nextUpdate.Sub(time.Now()) < 71*time.Hour {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+}
@@ -0,0 +1,77 @@
+package pki
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+func pathConfigIssuers(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config/issuers",
+		Fields: map[string]*framework.FieldSchema{
+			"default": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: `ID or name of the issuer to make the default`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathIssuersConfigRead,
+			logical.UpdateOperation: b.pathIssuersConfigWrite,
+		},
+
+		HelpSynopsis:    pathConfigIssuersHelpSyn,
+		HelpDescription: pathConfigIssuersHelpDesc,
+	}
+}
+
+func (b *backend) pathIssuersConfigRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	issuer, err := resolveIssuer(req.Storage, defaultIssuerRef)
+	if err != nil {
+		return nil, err
+	}
+	if issuer == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"default":      issuer.ID,
+			"default_name": issuer.Name,
+		},
+	}, nil
+}
+
+func (b *backend) pathIssuersConfigWrite(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	ref := data.Get("default").(string)
+	if ref == "" {
+		return logical.ErrorResponse("missing default issuer"), nil
+	}
+
+	issuer, err := resolveIssuer(req.Storage, ref)
+	if err != nil {
+		return nil, err
+	}
+	if issuer == nil {
+		return logical.ErrorResponse(fmt.Sprintf("unknown issuer %q", ref)), nil
+	}
+
+	b.revokeStorageLock.RLock()
+	defer b.revokeStorageLock.RUnlock()
+
+	return nil, b.setDefaultIssuer(req, issuer)
+}
+
+const pathConfigIssuersHelpSyn = `
+Read or rotate the default issuer.
+`
+
+const pathConfigIssuersHelpDesc = `
+The default issuer signs certificates for requests and roles that do not
+select an issuer, is returned by the "ca" and "ca_chain" endpoints, and signs
+the CRL at "crl". Changing the default issuer rebuilds the CRLs.
+`
@@ -3,10 +3,13 @@ package pki
 import (
 	"encoding/pem"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/hashicorp/vault/helper/errutil"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
+	"github.com/ryanuber/go-glob"
 )
 
 // Returns the CA in raw format
@@ -51,6 +54,54 @@ func pathFetchCRL(b *backend) *framework.Path {
 	}
 }
 
+// Returns the delta CRL in raw format
+func pathFetchDeltaCRL(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: `crl/delta(/pem)?`,
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathFetchRead,
+		},
+
+		HelpSynopsis:    pathFetchHelpSyn,
+		HelpDescription: pathFetchHelpDesc,
+	}
+}
+
+// Returns the CRL of an issuer in raw format
+func pathFetchIssuerCRL(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: `crl/issuer/` + framework.GenericNameRegex("issuer_ref") + `(/pem)?`,
+		Fields: map[string]*framework.FieldSchema{
+			"issuer_ref": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: `ID or name of the issuer, or "default"`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathFetchRead,
+		},
+
+		HelpSynopsis:    pathFetchHelpSyn,
+		HelpDescription: pathFetchHelpDesc,
+	}
+}
+
+// Returns the numbers and validity of the current CRLs
+func pathFetchCRLInfo(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: `crl/info`,
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathFetchCRLInfoRead,
+		},
+
+		HelpSynopsis:    pathFetchCRLInfoHelpSyn,
+		HelpDescription: pathFetchCRLInfoHelpDesc,
+	}
+}
+
 // Returns any valid (non-revoked) cert. Since "ca" fits the pattern, this path
 // also handles returning the CA cert in a non-raw format.
 func pathFetchValid(b *backend) *framework.Path {
@@ -87,17 +138,61 @@ func pathFetchCRLViaCertPath(b *backend) *framework.Path {
 	}
 }
 
-// This returns the list of serial numbers for certs
+// This returns the list of serial numbers for certs, optionally filtered
 func pathFetchListCerts(b *backend) *framework.Path {
 	return &framework.Path{
 		Pattern: "certs/?$",
+		Fields: map[string]*framework.FieldSchema{
+			"common_name": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `Only list certificates whose common name
+matches this value, which may contain globs. Certificates without a common
+name never match`,
+			},
+
+			"alt_name": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `Only list certificates with a DNS, email or
+IP Subject Alternative Name matching this value, which may contain globs`,
+			},
+
+			"serial_min": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `Only list certificates whose serial number is
+at least this value, in colon- or hyphen-separated hex`,
+			},
+
+			"serial_max": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `Only list certificates whose serial number is
+at most this value, in colon- or hyphen-separated hex`,
+			},
+
+			"expires_after": &framework.FieldSchema{
+				Type: framework.TypeDurationSecond,
+				Description: `Only list certificates expiring after this
+duration from now; negative values refer to the past`,
+			},
+
+			"expires_before": &framework.FieldSchema{
+				Type: framework.TypeDurationSecond,
+				Description: `Only list certificates expiring before this
+duration from now; negative values refer to the past`,
+			},
+
+			"revoked": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `If set, only list revoked certificates when
+true, or only certificates not revoked when false`,
+			},
+		},
 
 		Callbacks: map[logical.Operation]framework.OperationFunc{
 			logical.ListOperation: b.pathFetchCertList,
 		},
 
-		HelpSynopsis:    pathFetchHelpSyn,
-		HelpDescription: pathFetchHelpDesc,
+		HelpSynopsis:    pathFetchListCertsHelpSyn,
+		HelpDescription: pathFetchListCertsHelpDesc,
 	}
 }
 
@@ -107,7 +202,162 @@ func (b *backend) pathFetchCertList(req *logical.Request, data *framework.FieldD
 		return nil, err
 	}
 
-	return logical.ListResponse(entries), nil
+	// The filters are checked against the metadata of the certificates.
+	// Those by serial are checked against the listed serials first, and the
+	// others look up the serials that can match in the index, so that only
+	// the metadata of the candidates is read.
+	var filters []func(*certMetadata) bool
+	var serialFilters []func(string) bool
+	var lookups []func() (map[string]bool, error)
+	if cn := data.Get("common_name").(string); cn != "" {
+		filters = append(filters, func(m *certMetadata) bool {
+			return glob.Glob(cn, m.CommonName)
+		})
+		lookups = append(lookups, func() (map[string]bool, error) {
+			return serialsByName(req.Storage, certIndexCNPrefix, cn)
+		})
+	}
+	if altName := data.Get("alt_name").(string); altName != "" {
+		filters = append(filters, func(m *certMetadata) bool {
+			for _, name := range m.AltNames {
+				if glob.Glob(altName, name) {
+					return true
+				}
+			}
+			return false
+		})
+		lookups = append(lookups, func() (map[string]bool, error) {
+			return serialsByName(req.Storage, certIndexAltNamePrefix, altName)
+		})
+	}
+	for _, field := range []string{"serial_min", "serial_max"} {
+		value := data.Get(field).(string)
+		if value == "" {
+			continue
+		}
+		bound, err := parseSerial(value)
+		if err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("invalid %s: %s", field, err)), nil
+		}
+		isMin := field == "serial_min"
+		serialFilters = append(serialFilters, func(s string) bool {
+			serial, err := parseSerial(s)
+			if err != nil {
+				return false
+			}
+			if isMin {
+				return serial.Cmp(bound) >= 0
+			}
+			return serial.Cmp(bound) <= 0
+		})
+	}
+	now := time.Now()
+	var expiresAfter, expiresBefore time.Time
+	if expiresAfterRaw, ok := data.GetOk("expires_after"); ok {
+		expiresAfter = now.Add(time.Duration(expiresAfterRaw.(int)) * time.Second)
+		filters = append(filters, func(m *certMetadata) bool {
+			return m.NotAfter.After(expiresAfter)
+		})
+	}
+	if expiresBeforeRaw, ok := data.GetOk("expires_before"); ok {
+		expiresBefore = now.Add(time.Duration(expiresBeforeRaw.(int)) * time.Second)
+		filters = append(filters, func(m *certMetadata) bool {
+			return m.NotAfter.Before(expiresBefore)
+		})
+	}
+	if !expiresAfter.IsZero() || !expiresBefore.IsZero() {
+		lookups = append(lookups, func() (map[string]bool, error) {
+			return serialsByExpiry(req.Storage, expiresAfter, expiresBefore)
+		})
+	}
+	var excluded map[string]bool
+	if revokedRaw, ok := data.GetOk("revoked"); ok {
+		revoked := revokedRaw.(bool)
+		filters = append(filters, func(m *certMetadata) bool {
+			return m.Revoked == revoked
+		})
+		revokedSerials := make(map[string]bool)
+		if err := addSerials(req.Storage, "revoked/", revokedSerials); err != nil {
+			return nil, err
+		}
+		if revoked {
+			lookups = append(lookups, func() (map[string]bool, error) {
+				return revokedSerials, nil
+			})
+		} else {
+			excluded = revokedSerials
+		}
+	}
+
+	// Without filters, this stays a plain listing of the stored serials
+	if len(filters) == 0 && len(serialFilters) == 0 {
+		return logical.ListResponse(entries), nil
+	}
+
+	if err := b.indexCerts(req.Storage); err != nil {
+		return nil, err
+	}
+	candidates := make(map[string]bool, len(entries))
+	for _, serial := range entries {
+		if excluded[serial] {
+			continue
+		}
+		matches := true
+		for _, filter := range serialFilters {
+			if !filter(serial) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			candidates[serial] = true
+		}
+	}
+	for _, lookup := range lookups {
+		if len(candidates) == 0 {
+			break
+		}
+		serials, err := lookup()
+		if err != nil {
+			return nil, err
+		}
+		for serial := range candidates {
+			if !serials[serial] {
+				delete(candidates, serial)
+			}
+		}
+	}
+
+	keys := []string{}
+	keyInfo := map[string]interface{}{}
+	for _, serial := range entries {
+		if !candidates[serial] {
+			continue
+		}
+		meta, err := fetchCertMetadata(req.Storage, serial)
+		if err != nil {
+			return nil, err
+		}
+		if meta == nil {
+			continue
+		}
+
+		matches := true
+		for _, filter := range filters {
+			if !filter(meta) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			keys = append(keys, serial)
+			keyInfo[serial] = meta.toMap()
+		}
+	}
+
+	resp := logical.ListResponse(keys)
+	resp.Data["key_info"] = keyInfo
+	return resp, nil
 }
 
 func (b *backend) pathFetchRead(req *logical.Request, data *framework.FieldData) (response *logical.Response, retErr error) {
@@ -143,6 +393,36 @@ func (b *backend) pathFetchRead(req *logical.Request, data *framework.FieldData)
 		if req.Path == "crl/pem" {
 			pemType = "X509 CRL"
 		}
+	case req.Path == "crl/delta" || req.Path == "crl/delta/pem":
+		serial = "crl-delta"
+		contentType = "application/pkix-crl"
+		if req.Path == "crl/delta/pem" {
+			pemType = "X509 CRL"
+		}
+	case strings.HasPrefix(req.Path, "crl/issuer/"):
+		serial = "crl"
+		contentType = "application/pkix-crl"
+		if strings.HasSuffix(req.Path, "/pem") {
+			pemType = "X509 CRL"
+		}
+		// The default issuer signs the main CRL
+		issuer, err := resolveIssuer(req.Storage, data.Get("issuer_ref").(string))
+		if err != nil {
+			retErr = err
+			goto reply
+		}
+		if issuer == nil {
+			response = nil
+			goto reply
+		}
+		config, err := fetchIssuerConfig(req.Storage)
+		if err != nil {
+			retErr = err
+			goto reply
+		}
+		if config == nil || config.Default != issuer.ID {
+			serial = "crl-issuer/" + issuer.ID
+		}
 	case req.Path == "cert/crl":
 		serial = "crl"
 		pemType = "X509 CRL"
@@ -254,9 +534,56 @@ reply:
 		response.Data["revocation_time"] = revocationTime
 	}
 
+	// The CA certificates and CRL are public and fetched often, so let
+	// clients and proxies revalidate them instead of downloading them again
+	switch {
+	case serial == "ca", serial == "ca_chain", serial == "crl", serial == "crl-delta",
+		strings.HasPrefix(serial, "crl-issuer/"):
+		response.CacheControl = &logical.CacheControl{
+			Public: true,
+		}
+	}
+
 	return
 }
 
+func (b *backend) pathFetchCRLInfoRead(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	state, err := fetchCRLState(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if state == nil {
+		return nil, nil
+	}
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"crl_number":  state.Number,
+			"this_update": state.ThisUpdate.Format(time.RFC3339),
+			"next_update": state.NextUpdate.Format(time.RFC3339),
+		},
+	}
+	if state.DeltaNumber > state.Number {
+		resp.Data["delta_crl_number"] = state.DeltaNumber
+		resp.Data["delta_this_update"] = state.DeltaThisUpdate.Format(time.RFC3339)
+		resp.Data["delta_next_update"] = state.DeltaNextUpdate.Format(time.RFC3339)
+	}
+	return resp, nil
+}
+
+const pathFetchListCertsHelpSyn = `
+List the serial numbers of the stored certificates, optionally filtered.
+`
+
+const pathFetchListCertsHelpDesc = `
+Without parameters, this lists the serial numbers of all stored certificates.
+Certificates can be filtered by common name, Subject Alternative Name, serial
+number range, expiry window and revocation status, in which case the
+matching certificates are listed along with their metadata in "key_info". For
+instance, "expires_before=168h" lists the certificates expiring in the next 7
+days.
+`
+
 const pathFetchHelpSyn = `
 Fetch a CA, CRL, CA Chain, or non-revoked certificate.
 `
@@ -267,4 +594,17 @@ This allows certificates to be fetched. If using the fetch/ prefix any non-revok
 Using "ca" or "crl" as the value fetches the appropriate information in DER encoding. Add "/pem" to either to get PEM encoding.
 
 Using "ca_chain" as the value fetches the certificate authority trust chain in PEM encoding.
+
+Using "crl/issuer/<issuer>" fetches the CRL of the given issuer in DER encoding. Add "/pem" to get PEM encoding.
+
+Using "crl/delta" fetches the delta CRL, if enabled, in DER encoding. Add "/pem" to get PEM encoding.
+`
+
+const pathFetchCRLInfoHelpSyn = `
+Fetch the numbers and validity of the current CRL and delta CRL.
+`
+
+const pathFetchCRLInfoHelpDesc = `
+This returns the CRL number and the this_update and next_update times of the
+current CRL, along with those of the delta CRL if delta CRLs are enabled.
 `
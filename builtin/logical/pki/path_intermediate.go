@@ -40,6 +40,11 @@ certificate with a public key matching the
 previously-generated key from the generation
 endpoint.`,
 			},
+
+			"issuer_name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: `Name of the issuer`,
+			},
 		},
 
 		Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -106,6 +111,12 @@ func (b *backend) pathGenerateIntermediate(
 		}
 	}
 
+	// Keep the current CA as an issuer before its bundle is replaced by the
+	// pending key
+	if err := migrateLegacyIssuer(req.Storage); err != nil {
+		return nil, err
+	}
+
 	cb := &certutil.CertBundle{}
 	cb.PrivateKey = csrb.PrivateKey
 	cb.PrivateKeyType = csrb.PrivateKeyType
@@ -187,35 +198,20 @@ func (b *backend) pathSetSignedIntermediate(
 		return nil, fmt.Errorf("error converting raw values into cert bundle: %s", err)
 	}
 
-	entry, err = logical.StorageEntryJSON("config/ca_bundle", cb)
-	if err != nil {
-		return nil, err
-	}
-	err = req.Storage.Put(entry)
-	if err != nil {
-		return nil, err
-	}
-
-	entry.Key = "certs/" + normalizeSerial(cb.SerialNumber)
-	entry.Value = inputBundle.CertificateBytes
-	err = req.Storage.Put(entry)
+	err = storeCert(req.Storage, cb.SerialNumber, inputBundle.Certificate)
 	if err != nil {
 		return nil, err
 	}
 
-	// For ease of later use, also store just the certificate at a known
-	// location
-	entry.Key = "ca"
-	entry.Value = inputBundle.CertificateBytes
-	err = req.Storage.Put(entry)
-	if err != nil {
+	// Register it as the default issuer, which also stores it as the CA
+	// bundle and the certificate at "ca", plus a fresh CRL
+	_, err = b.importIssuer(req, cb, data.Get("issuer_name").(string), true)
+	switch err.(type) {
+	case errutil.UserError:
+		return logical.ErrorResponse(err.Error()), nil
+	default:
 		return nil, err
 	}
-
-	// Build a fresh CRL
-	err = buildCRL(b, req)
-
-	return nil, err
 }
 
 const pathGenerateIntermediateHelpSyn = `
@@ -166,8 +166,13 @@ func (b *backend) pathIssueSignCert(
 			`The "format" path parameter must be "pem", "der", or "pem_bundle"`), nil
 	}
 
+	issuerRef := role.IssuerRef
+	if issuerRefRaw, ok := data.GetOk("issuer_ref"); ok {
+		issuerRef = issuerRefRaw.(string)
+	}
+
 	var caErr error
-	signingBundle, caErr := fetchCAInfo(req)
+	signingBundle, caErr := fetchCAInfoByIssuer(req, issuerRef)
 	switch caErr.(type) {
 	case errutil.UserError:
 		return nil, errutil.UserError{Err: fmt.Sprintf(
@@ -265,13 +270,13 @@ func (b *backend) pathIssueSignCert(
 				"serial_number": cb.SerialNumber,
 			})
 		resp.Secret.TTL = parsedBundle.Certificate.NotAfter.Sub(time.Now())
+		resp.Secret.Artifacts = map[string]string{
+			"serial_number": cb.SerialNumber,
+		}
 	}
 
 	if !role.NoStore {
-		err = req.Storage.Put(&logical.StorageEntry{
-			Key:   "certs/" + normalizeSerial(cb.SerialNumber),
-			Value: parsedBundle.CertificateBytes,
-		})
+		err = storeCert(req.Storage, cb.SerialNumber, parsedBundle.Certificate)
 		if err != nil {
 			return nil, fmt.Errorf("unable to store certificate locally: %v", err)
 		}
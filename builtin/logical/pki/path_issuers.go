@@ -0,0 +1,579 @@
+package pki
+
+import (
+	"bytes"
+	"crypto/x509"
+	"fmt"
+	"regexp"
+
+	uuid "github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/helper/certutil"
+	"github.com/hashicorp/vault/helper/errutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// defaultIssuerRef refers to the default issuer wherever an issuer can be
+// selected
+const defaultIssuerRef = "default"
+
+var issuerNameRegex = regexp.MustCompile(`^\w(([\w-.]+)?\w)?$`)
+
+// issuerEntry is a CA able to issue certificates from this backend. The
+// default issuer is also stored as "config/ca_bundle" and "ca", which is what
+// requests not selecting an issuer use.
+type issuerEntry struct {
+	ID     string               `json:"id"`
+	Name   string               `json:"name"`
+	Bundle *certutil.CertBundle `json:"bundle"`
+}
+
+func (i *issuerEntry) certificate() (*x509.Certificate, error) {
+	parsedBundle, err := i.Bundle.ToParsedCertBundle()
+	if err != nil {
+		return nil, err
+	}
+	if parsedBundle.Certificate == nil {
+		return nil, fmt.Errorf("issuer %s has no certificate", i.ID)
+	}
+	return parsedBundle.Certificate, nil
+}
+
+// issuerConfig records which issuer is the default
+type issuerConfig struct {
+	Default string `json:"default"`
+}
+
+func pathListIssuers(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "issuers/?$",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ListOperation: b.pathIssuerList,
+		},
+
+		HelpSynopsis:    pathListIssuersHelpSyn,
+		HelpDescription: pathListIssuersHelpDesc,
+	}
+}
+
+func pathIssuer(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "issuer/" + framework.GenericNameRegex("issuer_ref"),
+		Fields: map[string]*framework.FieldSchema{
+			"issuer_ref": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: `ID or name of the issuer, or "default"`,
+			},
+
+			"issuer_name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: `New name of the issuer`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathIssuerRead,
+			logical.UpdateOperation: b.pathIssuerWrite,
+			logical.DeleteOperation: b.pathIssuerDelete,
+		},
+
+		HelpSynopsis:    pathIssuerHelpSyn,
+		HelpDescription: pathIssuerHelpDesc,
+	}
+}
+
+func pathImportIssuer(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "issuers/import",
+		Fields: map[string]*framework.FieldSchema{
+			"pem_bundle": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `PEM-format, concatenated unencrypted
+secret key and certificate.`,
+			},
+
+			"issuer_name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: `Name of the issuer`,
+			},
+
+			"set_default": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `If true, the issuer becomes the default
+issuer. The first issuer always does.`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathIssuerImport,
+		},
+
+		HelpSynopsis:    pathImportIssuerHelpSyn,
+		HelpDescription: pathImportIssuerHelpDesc,
+	}
+}
+
+func pathGenerateIssuerRoot(b *backend) *framework.Path {
+	ret := &framework.Path{
+		Pattern: "issuers/generate/root/" + framework.GenericNameRegex("exported"),
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathIssuerGenerateRoot,
+		},
+
+		HelpSynopsis:    pathGenerateIssuerRootHelpSyn,
+		HelpDescription: pathGenerateIssuerRootHelpDesc,
+	}
+
+	ret.Fields = addCACommonFields(map[string]*framework.FieldSchema{})
+	ret.Fields = addCAKeyGenerationFields(ret.Fields)
+	ret.Fields = addCAIssueFields(ret.Fields)
+
+	ret.Fields["issuer_name"] = &framework.FieldSchema{
+		Type:        framework.TypeString,
+		Description: `Name of the issuer`,
+	}
+
+	ret.Fields["set_default"] = &framework.FieldSchema{
+		Type: framework.TypeBool,
+		Description: `If true, the issuer becomes the default
+issuer. The first issuer always does.`,
+	}
+
+	return ret
+}
+
+func fetchIssuerConfig(s logical.Storage) (*issuerConfig, error) {
+	entry, err := s.Get("config/issuers")
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var config issuerConfig
+	if err := entry.DecodeJSON(&config); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+func fetchIssuer(s logical.Storage, id string) (*issuerEntry, error) {
+	entry, err := s.Get("issuer/" + id)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var issuer issuerEntry
+	if err := entry.DecodeJSON(&issuer); err != nil {
+		return nil, err
+	}
+	return &issuer, nil
+}
+
+func fetchIssuers(s logical.Storage) ([]*issuerEntry, error) {
+	if err := migrateLegacyIssuer(s); err != nil {
+		return nil, err
+	}
+
+	ids, err := s.List("issuer/")
+	if err != nil {
+		return nil, err
+	}
+
+	var issuers []*issuerEntry
+	for _, id := range ids {
+		issuer, err := fetchIssuer(s, id)
+		if err != nil {
+			return nil, err
+		}
+		if issuer != nil {
+			issuers = append(issuers, issuer)
+		}
+	}
+	return issuers, nil
+}
+
+// resolveIssuer returns the issuer with the given ID or name, or the default
+// issuer for "default"
+func resolveIssuer(s logical.Storage, ref string) (*issuerEntry, error) {
+	if err := migrateLegacyIssuer(s); err != nil {
+		return nil, err
+	}
+
+	if ref == defaultIssuerRef {
+		config, err := fetchIssuerConfig(s)
+		if err != nil {
+			return nil, err
+		}
+		if config == nil || config.Default == "" {
+			return nil, nil
+		}
+		ref = config.Default
+	}
+
+	issuer, err := fetchIssuer(s, ref)
+	if err != nil || issuer != nil {
+		return issuer, err
+	}
+
+	issuers, err := fetchIssuers(s)
+	if err != nil {
+		return nil, err
+	}
+	for _, issuer := range issuers {
+		if issuer.Name == ref {
+			return issuer, nil
+		}
+	}
+	return nil, nil
+}
+
+// migrateLegacyIssuer registers the CA of backends predating multiple
+// issuers as their default issuer
+func migrateLegacyIssuer(s logical.Storage) error {
+	config, err := fetchIssuerConfig(s)
+	if err != nil {
+		return err
+	}
+	if config != nil {
+		return nil
+	}
+
+	entry, err := s.Get("config/ca_bundle")
+	if err != nil {
+		return err
+	}
+	if entry == nil {
+		return nil
+	}
+	var bundle certutil.CertBundle
+	if err := entry.DecodeJSON(&bundle); err != nil {
+		return err
+	}
+	// A pending intermediate CA only has its key, and is registered once
+	// its certificate is set
+	if bundle.Certificate == "" {
+		return nil
+	}
+
+	issuer := &issuerEntry{
+		Bundle: &bundle,
+	}
+	if issuer.ID, err = uuid.GenerateUUID(); err != nil {
+		return err
+	}
+	if err := storeIssuer(s, issuer); err != nil {
+		return err
+	}
+	return storeIssuerConfig(s, &issuerConfig{Default: issuer.ID})
+}
+
+func storeIssuer(s logical.Storage, issuer *issuerEntry) error {
+	entry, err := logical.StorageEntryJSON("issuer/"+issuer.ID, issuer)
+	if err != nil {
+		return err
+	}
+	return s.Put(entry)
+}
+
+func storeIssuerConfig(s logical.Storage, config *issuerConfig) error {
+	entry, err := logical.StorageEntryJSON("config/issuers", config)
+	if err != nil {
+		return err
+	}
+	return s.Put(entry)
+}
+
+// validateIssuerName checks that the name can be used to refer to the issuer
+func validateIssuerName(s logical.Storage, name, id string) error {
+	if name == "" {
+		return nil
+	}
+	if name == defaultIssuerRef {
+		return fmt.Errorf("%q is reserved and cannot be used as an issuer name", name)
+	}
+	if !issuerNameRegex.MatchString(name) {
+		return fmt.Errorf("invalid issuer name %q", name)
+	}
+
+	issuers, err := fetchIssuers(s)
+	if err != nil {
+		return err
+	}
+	for _, issuer := range issuers {
+		if issuer.ID != id && (issuer.Name == name || issuer.ID == name) {
+			return fmt.Errorf("issuer name %q is already in use", name)
+		}
+	}
+	return nil
+}
+
+// importIssuer registers the CA bundle as a new issuer. It becomes the
+// default issuer if requested or if there is no default issuer yet.
+func (b *backend) importIssuer(req *logical.Request, cb *certutil.CertBundle, name string, makeDefault bool) (*issuerEntry, error) {
+	if err := migrateLegacyIssuer(req.Storage); err != nil {
+		return nil, err
+	}
+
+	// Importing the certificate of an existing issuer again, as setting
+	// the same CA twice does, reuses that issuer
+	issuers, err := fetchIssuers(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	var issuer *issuerEntry
+	for _, existing := range issuers {
+		if existing.Bundle.Certificate == cb.Certificate && existing.Bundle.PrivateKey == cb.PrivateKey {
+			issuer = existing
+			break
+		}
+	}
+
+	if issuer == nil {
+		if err := validateIssuerName(req.Storage, name, ""); err != nil {
+			return nil, errutil.UserError{Err: err.Error()}
+		}
+		issuer = &issuerEntry{
+			Name:   name,
+			Bundle: cb,
+		}
+		if issuer.ID, err = uuid.GenerateUUID(); err != nil {
+			return nil, err
+		}
+		if err := storeIssuer(req.Storage, issuer); err != nil {
+			return nil, err
+		}
+	}
+
+	config, err := fetchIssuerConfig(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if makeDefault || config == nil || config.Default == "" {
+		if err := b.setDefaultIssuer(req, issuer); err != nil {
+			return nil, err
+		}
+	}
+	return issuer, nil
+}
+
+// setDefaultIssuer makes the issuer the default one, used by requests not
+// selecting an issuer, and rebuilds the CRL with it
+func (b *backend) setDefaultIssuer(req *logical.Request, issuer *issuerEntry) error {
+	cert, err := issuer.certificate()
+	if err != nil {
+		return err
+	}
+
+	entry, err := logical.StorageEntryJSON("config/ca_bundle", issuer.Bundle)
+	if err != nil {
+		return err
+	}
+	if err := req.Storage.Put(entry); err != nil {
+		return err
+	}
+
+	// For ease of later use, also store just the certificate at a known
+	// location
+	err = req.Storage.Put(&logical.StorageEntry{
+		Key:   "ca",
+		Value: cert.Raw,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := storeIssuerConfig(req.Storage, &issuerConfig{Default: issuer.ID}); err != nil {
+		return err
+	}
+
+	return buildCRL(b, req)
+}
+
+// issuedBy returns whether the certificate was issued by the CA
+func issuedBy(cert, ca *x509.Certificate) bool {
+	if !bytes.Equal(cert.RawIssuer, ca.RawSubject) {
+		return false
+	}
+	if len(cert.AuthorityKeyId) > 0 && len(ca.SubjectKeyId) > 0 {
+		return bytes.Equal(cert.AuthorityKeyId, ca.SubjectKeyId)
+	}
+	return cert.CheckSignatureFrom(ca) == nil
+}
+
+func (b *backend) pathIssuerList(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	if err := migrateLegacyIssuer(req.Storage); err != nil {
+		return nil, err
+	}
+
+	ids, err := req.Storage.List("issuer/")
+	if err != nil {
+		return nil, err
+	}
+	return logical.ListResponse(ids), nil
+}
+
+func (b *backend) pathIssuerRead(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	issuer, err := resolveIssuer(req.Storage, data.Get("issuer_ref").(string))
+	if err != nil {
+		return nil, err
+	}
+	if issuer == nil {
+		return nil, nil
+	}
+
+	cert, err := issuer.certificate()
+	if err != nil {
+		return nil, err
+	}
+	config, err := fetchIssuerConfig(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"issuer_id":     issuer.ID,
+			"issuer_name":   issuer.Name,
+			"certificate":   issuer.Bundle.Certificate,
+			"ca_chain":      issuer.Bundle.CAChain,
+			"serial_number": issuer.Bundle.SerialNumber,
+			"expiration":    cert.NotAfter.Unix(),
+			"is_default":    config != nil && config.Default == issuer.ID,
+		},
+	}, nil
+}
+
+func (b *backend) pathIssuerWrite(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	issuer, err := resolveIssuer(req.Storage, data.Get("issuer_ref").(string))
+	if err != nil {
+		return nil, err
+	}
+	if issuer == nil {
+		return logical.ErrorResponse("unknown issuer"), nil
+	}
+
+	if nameRaw, ok := data.GetOk("issuer_name"); ok {
+		name := nameRaw.(string)
+		if err := validateIssuerName(req.Storage, name, issuer.ID); err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+		issuer.Name = name
+	}
+
+	return nil, storeIssuer(req.Storage, issuer)
+}
+
+func (b *backend) pathIssuerDelete(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	issuer, err := resolveIssuer(req.Storage, data.Get("issuer_ref").(string))
+	if err != nil {
+		return nil, err
+	}
+	if issuer == nil {
+		return nil, nil
+	}
+
+	config, err := fetchIssuerConfig(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config != nil && config.Default == issuer.ID {
+		return logical.ErrorResponse("the default issuer cannot be deleted; make another issuer the default first"), nil
+	}
+
+	if err := req.Storage.Delete("issuer/" + issuer.ID); err != nil {
+		return nil, err
+	}
+	return nil, req.Storage.Delete("crl-issuer/" + issuer.ID)
+}
+
+func (b *backend) pathIssuerImport(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	parsedBundle, err := certutil.ParsePEMBundle(data.Get("pem_bundle").(string))
+	if err != nil {
+		switch err.(type) {
+		case errutil.InternalError:
+			return nil, err
+		default:
+			return logical.ErrorResponse(err.Error()), nil
+		}
+	}
+
+	if parsedBundle.PrivateKey == nil ||
+		parsedBundle.PrivateKeyType == certutil.UnknownPrivateKey {
+		return logical.ErrorResponse("private key not found in the PEM bundle"), nil
+	}
+	if parsedBundle.Certificate == nil {
+		return logical.ErrorResponse("no certificate found in the PEM bundle"), nil
+	}
+	if !parsedBundle.Certificate.IsCA {
+		return logical.ErrorResponse("the given certificate is not marked for CA use and cannot be used with this backend"), nil
+	}
+
+	cb, err := parsedBundle.ToCertBundle()
+	if err != nil {
+		return nil, fmt.Errorf("error converting raw values into cert bundle: %s", err)
+	}
+
+	issuer, err := b.importIssuer(req, cb, data.Get("issuer_name").(string), data.Get("set_default").(bool))
+	if err != nil {
+		switch err.(type) {
+		case errutil.UserError:
+			return logical.ErrorResponse(err.Error()), nil
+		default:
+			return nil, err
+		}
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"issuer_id": issuer.ID,
+		},
+	}, nil
+}
+
+func (b *backend) pathIssuerGenerateRoot(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	return b.generateRoot(req, data, data.Get("set_default").(bool))
+}
+
+const pathListIssuersHelpSyn = `
+List the IDs of the issuers of the backend.
+`
+
+const pathListIssuersHelpDesc = `
+This endpoint lists the IDs of the CA issuers of the backend.
+`
+
+const pathIssuerHelpSyn = `
+Read, rename or delete an issuer.
+`
+
+const pathIssuerHelpDesc = `
+An issuer can be referred to by its ID or its name, and the default issuer by
+"default". The default issuer cannot be deleted.
+`
+
+const pathImportIssuerHelpSyn = `
+Import a CA certificate and private key as a new issuer.
+`
+
+const pathImportIssuerHelpDesc = `
+This adds an issuer from a PEM-format, concatenated unencrypted secret key and
+certificate, without replacing the existing issuers. The issuer only becomes
+the default issuer if set_default is given or if it is the first issuer.
+`
+
+const pathGenerateIssuerRootHelpSyn = `
+Generate a new root CA as a new issuer.
+`
+
+const pathGenerateIssuerRootHelpDesc = `
+This generates a self-signed CA certificate and private key, without replacing
+the existing issuers. The issuer only becomes the default issuer if set_default
+is given or if it is the first issuer.
+`
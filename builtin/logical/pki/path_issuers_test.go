@@ -0,0 +1,151 @@
+package pki
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"testing"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestPki_MultipleIssuers(t *testing.T) {
+	b, storage := createBackendWithStorage(t)
+
+	handle := func(op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		resp, err := b.HandleRequest(&logical.Request{
+			Operation: op,
+			Path:      path,
+			Storage:   storage,
+			Data:      data,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: path: %s err: %v resp: %#v", path, err, resp)
+		}
+		return resp
+	}
+	parseCert := func(certPEM string) *x509.Certificate {
+		block, _ := pem.Decode([]byte(certPEM))
+		if block == nil {
+			t.Fatalf("bad: %s", certPEM)
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cert
+	}
+
+	resp := handle(logical.UpdateOperation, "root/generate/internal", map[string]interface{}{
+		"common_name": "root-a.myvault.com",
+		"issuer_name": "root-a",
+		"ttl":         "5h",
+	})
+	rootA := resp.Data["issuer_id"].(string)
+
+	// A second root does not replace the first one
+	resp = handle(logical.UpdateOperation, "issuers/generate/root/internal", map[string]interface{}{
+		"common_name": "root-b.myvault.com",
+		"issuer_name": "root-b",
+		"ttl":         "5h",
+	})
+	rootB := resp.Data["issuer_id"].(string)
+
+	resp = handle(logical.ListOperation, "issuers/", nil)
+	if keys := resp.Data["keys"].([]string); len(keys) != 2 {
+		t.Fatalf("bad: %#v", keys)
+	}
+	resp = handle(logical.ReadOperation, "config/issuers", nil)
+	if resp.Data["default"] != rootA || resp.Data["default_name"] != "root-a" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	handle(logical.UpdateOperation, "roles/testrole", map[string]interface{}{
+		"allowed_domains":  "myvault.com",
+		"allow_subdomains": true,
+		"ttl":              "2h",
+	})
+	handle(logical.UpdateOperation, "roles/rolebyb", map[string]interface{}{
+		"allowed_domains":  "myvault.com",
+		"allow_subdomains": true,
+		"ttl":              "2h",
+		"issuer_ref":       "root-b",
+	})
+
+	// Roles must refer to an existing issuer
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "roles/badrole",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"allowed_domains": "myvault.com",
+			"issuer_ref":      "root-c",
+		},
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error: err: %v resp: %#v", err, resp)
+	}
+
+	issuerCN := func(role string, data map[string]interface{}) (string, string) {
+		data["common_name"] = "cert.myvault.com"
+		resp := handle(logical.UpdateOperation, "issue/"+role, data)
+		return parseCert(resp.Data["certificate"].(string)).Issuer.CommonName, resp.Data["serial_number"].(string)
+	}
+	if cn, _ := issuerCN("testrole", map[string]interface{}{}); cn != "root-a.myvault.com" {
+		t.Fatalf("bad: %s", cn)
+	}
+	if cn, _ := issuerCN("testrole", map[string]interface{}{"issuer_ref": rootB}); cn != "root-b.myvault.com" {
+		t.Fatalf("bad: %s", cn)
+	}
+	cn, serialB := issuerCN("rolebyb", map[string]interface{}{})
+	if cn != "root-b.myvault.com" {
+		t.Fatalf("bad: %s", cn)
+	}
+
+	// The revocation shows up in the CRL of the issuer
+	handle(logical.UpdateOperation, "revoke", map[string]interface{}{
+		"serial_number": serialB,
+	})
+	resp = handle(logical.ReadOperation, "crl/issuer/root-b", nil)
+	crl, err := x509.ParseDERCRL(resp.Data[logical.HTTPRawBody].([]byte))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var issuer pkix.Name
+	issuer.FillFromRDNSequence(&crl.TBSCertList.Issuer)
+	if issuer.CommonName != "root-b.myvault.com" || len(crl.TBSCertList.RevokedCertificates) != 1 {
+		t.Fatalf("bad: issuer %s entries %d", issuer.CommonName, len(crl.TBSCertList.RevokedCertificates))
+	}
+
+	// The default issuer cannot be deleted
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.DeleteOperation,
+		Path:      "issuer/default",
+		Storage:   storage,
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error: err: %v resp: %#v", err, resp)
+	}
+
+	// Rotating the default issuer changes the CA used by default
+	handle(logical.UpdateOperation, "config/issuers", map[string]interface{}{
+		"default": "root-b",
+	})
+	resp = handle(logical.ReadOperation, "cert/ca", nil)
+	if parseCert(resp.Data["certificate"].(string)).Subject.CommonName != "root-b.myvault.com" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if cn, _ := issuerCN("testrole", map[string]interface{}{}); cn != "root-b.myvault.com" {
+		t.Fatalf("bad: %s", cn)
+	}
+	resp = handle(logical.ReadOperation, "issuer/"+rootA, nil)
+	if resp.Data["is_default"] != false {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	handle(logical.DeleteOperation, "issuer/root-a", nil)
+	resp = handle(logical.ListOperation, "issuers/", nil)
+	if keys := resp.Data["keys"].([]string); len(keys) != 1 || keys[0] != rootB {
+		t.Fatalf("bad: %#v", keys)
+	}
+}
@@ -223,6 +223,11 @@ or revoked, so this option is recommended only for certificates that are
 non-sensitive, or extremely short-lived. This option implies a value of "false"
 for "generate_lease".`,
 			},
+			"issuer_ref": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `ID or name of the issuer signing certificates
+for this role. Defaults to the default issuer.`,
+			},
 		},
 
 		Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -405,6 +410,7 @@ func (b *backend) pathRoleCreate(
 		Organization:        data.Get("organization").(string),
 		GenerateLease:       new(bool),
 		NoStore:             data.Get("no_store").(bool),
+		IssuerRef:           data.Get("issuer_ref").(string),
 	}
 
 	// no_store implies generate_lease := false
@@ -461,6 +467,16 @@ func (b *backend) pathRoleCreate(
 		return errResp, nil
 	}
 
+	if entry.IssuerRef != "" && entry.IssuerRef != defaultIssuerRef {
+		issuer, err := resolveIssuer(req.Storage, entry.IssuerRef)
+		if err != nil {
+			return nil, err
+		}
+		if issuer == nil {
+			return logical.ErrorResponse(fmt.Sprintf("unknown issuer %q", entry.IssuerRef)), nil
+		}
+	}
+
 	// Store it
 	jsonEntry, err := logical.StorageEntryJSON("role/"+name, entry)
 	if err != nil {
@@ -532,6 +548,7 @@ type roleEntry struct {
 	Organization          string `json:"organization" structs:"organization" mapstructure:"organization"`
 	GenerateLease         *bool  `json:"generate_lease,omitempty" structs:"generate_lease,omitempty"`
 	NoStore               bool   `json:"no_store" structs:"no_store" mapstructure:"no_store"`
+	IssuerRef             string `json:"issuer_ref" structs:"issuer_ref" mapstructure:"issuer_ref"`
 
 	// Used internally for signing intermediates
 	AllowExpirationPastCA bool
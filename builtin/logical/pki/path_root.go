@@ -31,6 +31,11 @@ func pathGenerateRoot(b *backend) *framework.Path {
 	ret.Fields = addCAKeyGenerationFields(ret.Fields)
 	ret.Fields = addCAIssueFields(ret.Fields)
 
+	ret.Fields["issuer_name"] = &framework.FieldSchema{
+		Type:        framework.TypeString,
+		Description: `Name of the issuer`,
+	}
+
 	return ret
 }
 
@@ -70,6 +75,12 @@ func pathSignIntermediate(b *backend) *framework.Path {
 		Description: `PEM-format CSR to be signed.`,
 	}
 
+	ret.Fields["issuer_ref"] = &framework.FieldSchema{
+		Type: framework.TypeString,
+		Description: `ID or name of the issuer signing the
+certificate; defaults to the default issuer`,
+	}
+
 	ret.Fields["use_csr_values"] = &framework.FieldSchema{
 		Type:    framework.TypeBool,
 		Default: false,
@@ -100,6 +111,12 @@ func pathSignSelfIssued(b *backend) *framework.Path {
 				Type:        framework.TypeString,
 				Description: `PEM-format self-issued certificate to be signed.`,
 			},
+
+			"issuer_ref": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `ID or name of the issuer signing the
+certificate; defaults to the default issuer`,
+			},
 		},
 
 		HelpSynopsis:    pathSignSelfIssuedHelpSyn,
@@ -111,6 +128,21 @@ func pathSignSelfIssued(b *backend) *framework.Path {
 
 func (b *backend) pathCADeleteRoot(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	// The default issuer is the CA being deleted; other issuers are kept
+	config, err := fetchIssuerConfig(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config != nil && config.Default != "" {
+		if err := req.Storage.Delete("issuer/" + config.Default); err != nil {
+			return nil, err
+		}
+		config.Default = ""
+		if err := storeIssuerConfig(req.Storage, config); err != nil {
+			return nil, err
+		}
+	}
+
 	return nil, req.Storage.Delete("config/ca_bundle")
 }
 
@@ -126,6 +158,12 @@ func (b *backend) pathCAGenerateRoot(
 		return nil, nil
 	}
 
+	return b.generateRoot(req, data, true)
+}
+
+// generateRoot generates a self-signed CA and registers it as an issuer
+func (b *backend) generateRoot(
+	req *logical.Request, data *framework.FieldData, makeDefault bool) (*logical.Response, error) {
 	exported, format, role, errorResp := b.getGenerationParams(data)
 	if errorResp != nil {
 		return errorResp, nil
@@ -188,41 +226,26 @@ func (b *backend) pathCAGenerateRoot(
 		}
 	}
 
-	// Store it as the CA bundle
-	entry, err = logical.StorageEntryJSON("config/ca_bundle", cb)
+	// Register it as an issuer, which stores it as the CA bundle if it is
+	// the default issuer
+	issuer, err := b.importIssuer(req, cb, data.Get("issuer_name").(string), makeDefault)
 	if err != nil {
-		return nil, err
-	}
-	err = req.Storage.Put(entry)
-	if err != nil {
-		return nil, err
+		switch err.(type) {
+		case errutil.UserError:
+			return logical.ErrorResponse(err.Error()), nil
+		default:
+			return nil, err
+		}
 	}
+	resp.Data["issuer_id"] = issuer.ID
 
 	// Also store it as just the certificate identified by serial number, so it
 	// can be revoked
-	err = req.Storage.Put(&logical.StorageEntry{
-		Key:   "certs/" + normalizeSerial(cb.SerialNumber),
-		Value: parsedBundle.CertificateBytes,
-	})
+	err = storeCert(req.Storage, cb.SerialNumber, parsedBundle.Certificate)
 	if err != nil {
 		return nil, fmt.Errorf("Unable to store certificate locally: %v", err)
 	}
 
-	// For ease of later use, also store just the certificate at a known
-	// location
-	entry.Key = "ca"
-	entry.Value = parsedBundle.CertificateBytes
-	err = req.Storage.Put(entry)
-	if err != nil {
-		return nil, err
-	}
-
-	// Build a fresh CRL
-	err = buildCRL(b, req)
-	if err != nil {
-		return nil, err
-	}
-
 	if parsedBundle.Certificate.MaxPathLen == 0 {
 		resp.AddWarning("Max path length of the generated certificate is zero. This certificate cannot be used to issue intermediate CA certificates.")
 	}
@@ -256,7 +279,7 @@ func (b *backend) pathCASignIntermediate(
 	}
 
 	var caErr error
-	signingBundle, caErr := fetchCAInfo(req)
+	signingBundle, caErr := fetchCAInfoByIssuer(req, data.Get("issuer_ref").(string))
 	switch caErr.(type) {
 	case errutil.UserError:
 		return nil, errutil.UserError{Err: fmt.Sprintf(
@@ -337,10 +360,7 @@ func (b *backend) pathCASignIntermediate(
 		}
 	}
 
-	err = req.Storage.Put(&logical.StorageEntry{
-		Key:   "certs/" + normalizeSerial(cb.SerialNumber),
-		Value: parsedBundle.CertificateBytes,
-	})
+	err = storeCert(req.Storage, cb.SerialNumber, parsedBundle.Certificate)
 	if err != nil {
 		return nil, fmt.Errorf("Unable to store certificate locally: %v", err)
 	}
@@ -378,7 +398,7 @@ func (b *backend) pathCASignSelfIssued(
 	}
 
 	var caErr error
-	signingBundle, caErr := fetchCAInfo(req)
+	signingBundle, caErr := fetchCAInfoByIssuer(req, data.Get("issuer_ref").(string))
 	switch caErr.(type) {
 	case errutil.UserError:
 		return nil, errutil.UserError{Err: fmt.Sprintf(
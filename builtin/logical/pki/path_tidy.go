@@ -3,12 +3,44 @@ package pki
 import (
 	"crypto/x509"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
 )
 
+const (
+	tidyStatusInactive  = "inactive"
+	tidyStatusRunning   = "running"
+	tidyStatusComplete  = "complete"
+	tidyStatusCancelled = "cancelled"
+	tidyStatusFailed    = "failed"
+)
+
+// tidyStatus tracks the tidy operation running in the background, or the
+// last one to have run. Only one tidy operation runs at a time.
+type tidyStatus struct {
+	sync.RWMutex
+
+	status              string
+	err                 string
+	startTime           time.Time
+	endTime             time.Time
+	safetyBuffer        time.Duration
+	revokedSafetyBuffer time.Duration
+	tidyCertStore       bool
+	tidyRevocationList  bool
+
+	certsScanned        int
+	certsDeleted        int
+	revokedCertsScanned int
+	revokedCertsDeleted int
+
+	cancelOnce sync.Once
+	cancelCh   chan struct{}
+}
+
 func pathTidy(b *backend) *framework.Path {
 	return &framework.Path{
 		Pattern: "tidy",
@@ -35,6 +67,14 @@ from the backend storage and/or revocation list.
 Defaults to 72 hours.`,
 				Default: 259200, //72h, but TypeDurationSecond currently requires defaults to be int
 			},
+
+			"revoked_safety_buffer": &framework.FieldSchema{
+				Type: framework.TypeDurationSecond,
+				Description: `The amount of extra time that must have passed
+beyond the expiration of a revoked certificate before
+it is removed from the revocation list. Defaults to
+the value of safety_buffer.`,
+			},
 		},
 
 		Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -46,100 +86,308 @@ Defaults to 72 hours.`,
 	}
 }
 
+func pathTidyStatus(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "tidy-status$",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathTidyStatusRead,
+		},
+
+		HelpSynopsis:    pathTidyStatusHelpSyn,
+		HelpDescription: pathTidyStatusHelpDesc,
+	}
+}
+
+func pathTidyCancel(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "tidy-cancel$",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathTidyCancelWrite,
+		},
+
+		HelpSynopsis:    pathTidyCancelHelpSyn,
+		HelpDescription: pathTidyCancelHelpDesc,
+	}
+}
+
 func (b *backend) pathTidyWrite(
 	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
 	safetyBuffer := d.Get("safety_buffer").(int)
 	tidyCertStore := d.Get("tidy_cert_store").(bool)
 	tidyRevocationList := d.Get("tidy_revocation_list").(bool)
 
-	bufferDuration := time.Duration(safetyBuffer) * time.Second
+	revokedSafetyBuffer := safetyBuffer
+	if revokedSafetyBufferRaw, ok := d.GetOk("revoked_safety_buffer"); ok {
+		revokedSafetyBuffer = revokedSafetyBufferRaw.(int)
+	}
+	if safetyBuffer < 0 || revokedSafetyBuffer < 0 {
+		return logical.ErrorResponse("safety buffers cannot be negative"), nil
+	}
+
+	status := &tidyStatus{
+		status:              tidyStatusRunning,
+		startTime:           time.Now(),
+		safetyBuffer:        time.Duration(safetyBuffer) * time.Second,
+		revokedSafetyBuffer: time.Duration(revokedSafetyBuffer) * time.Second,
+		tidyCertStore:       tidyCertStore,
+		tidyRevocationList:  tidyRevocationList,
+		cancelCh:            make(chan struct{}),
+	}
+
+	b.tidyStatusLock.Lock()
+	if b.tidyStatus != nil {
+		b.tidyStatus.RLock()
+		running := b.tidyStatus.status == tidyStatusRunning
+		b.tidyStatus.RUnlock()
+		if running {
+			b.tidyStatusLock.Unlock()
+			return logical.ErrorResponse("a tidy operation is already in progress"), nil
+		}
+	}
+	b.tidyStatus = status
+	b.tidyStatusLock.Unlock()
+
+	go b.runTidy(req.Storage, status)
+
+	resp := &logical.Response{}
+	resp.AddWarning("Tidy operation successfully started; its progress can be read at tidy-status.")
+	return resp, nil
+}
+
+func (b *backend) pathTidyStatusRead(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	b.tidyStatusLock.RLock()
+	status := b.tidyStatus
+	b.tidyStatusLock.RUnlock()
+
+	if status == nil {
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"status": tidyStatusInactive,
+			},
+		}, nil
+	}
+
+	status.RLock()
+	defer status.RUnlock()
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"status":                status.status,
+			"start_time":            status.startTime.Format(time.RFC3339Nano),
+			"end_time":              "",
+			"safety_buffer":         int64(status.safetyBuffer / time.Second),
+			"revoked_safety_buffer": int64(status.revokedSafetyBuffer / time.Second),
+			"tidy_cert_store":       status.tidyCertStore,
+			"tidy_revocation_list":  status.tidyRevocationList,
+			"certs_scanned":         status.certsScanned,
+			"certs_deleted":         status.certsDeleted,
+			"revoked_certs_scanned": status.revokedCertsScanned,
+			"revoked_certs_deleted": status.revokedCertsDeleted,
+		},
+	}
+	if status.err != "" {
+		resp.Data["error"] = status.err
+	}
+	if status.status != tidyStatusRunning {
+		resp.Data["end_time"] = status.endTime.Format(time.RFC3339Nano)
+	}
+	return resp, nil
+}
+
+func (b *backend) pathTidyCancelWrite(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	b.tidyStatusLock.RLock()
+	status := b.tidyStatus
+	b.tidyStatusLock.RUnlock()
+
+	if status == nil {
+		return logical.ErrorResponse("no tidy operation is in progress"), nil
+	}
+	status.RLock()
+	running := status.status == tidyStatusRunning
+	status.RUnlock()
+	if !running {
+		return logical.ErrorResponse("no tidy operation is in progress"), nil
+	}
+
+	status.cancel()
+	return nil, nil
+}
+
+// runTidy removes expired certificates and revocation entries as configured
+// in the status, recording its progress there
+func (b *backend) runTidy(s logical.Storage, status *tidyStatus) {
+	err := b.tidy(s, status)
+	switch {
+	case err == errTidyCancelled:
+		status.finish(tidyStatusCancelled, "")
+	case err != nil:
+		b.Logger().Error("pki: tidy operation failed", "error", err)
+		status.finish(tidyStatusFailed, err.Error())
+	default:
+		status.finish(tidyStatusComplete, "")
+	}
+}
+
+var errTidyCancelled = fmt.Errorf("tidy operation cancelled")
 
-	if tidyCertStore {
-		serials, err := req.Storage.List("certs/")
+func (b *backend) tidy(s logical.Storage, status *tidyStatus) error {
+	if status.tidyCertStore {
+		serials, err := s.List("certs/")
 		if err != nil {
-			return nil, fmt.Errorf("error fetching list of certs: %s", err)
+			return fmt.Errorf("error fetching list of certs: %s", err)
 		}
 
 		for _, serial := range serials {
-			certEntry, err := req.Storage.Get("certs/" + serial)
+			if status.cancelled() {
+				return errTidyCancelled
+			}
+
+			certEntry, err := s.Get("certs/" + serial)
 			if err != nil {
-				return nil, fmt.Errorf("error fetching certificate %s: %s", serial, err)
+				return fmt.Errorf("error fetching certificate %s: %s", serial, err)
 			}
 
 			if certEntry == nil {
-				return nil, fmt.Errorf("certificate entry for serial %s is nil", serial)
+				return fmt.Errorf("certificate entry for serial %s is nil", serial)
 			}
 
 			if certEntry.Value == nil || len(certEntry.Value) == 0 {
-				return nil, fmt.Errorf("found entry for serial %s but actual certificate is empty", serial)
+				return fmt.Errorf("found entry for serial %s but actual certificate is empty", serial)
 			}
 
 			cert, err := x509.ParseCertificate(certEntry.Value)
 			if err != nil {
-				return nil, fmt.Errorf("unable to parse stored certificate with serial %s: %s", serial, err)
+				return fmt.Errorf("unable to parse stored certificate with serial %s: %s", serial, err)
 			}
 
-			if time.Now().After(cert.NotAfter.Add(bufferDuration)) {
-				if err := req.Storage.Delete("certs/" + serial); err != nil {
-					return nil, fmt.Errorf("error deleting serial %s from storage: %s", serial, err)
+			deleted := false
+			if time.Now().After(cert.NotAfter.Add(status.safetyBuffer)) {
+				if err := s.Delete("certs/" + serial); err != nil {
+					return fmt.Errorf("error deleting serial %s from storage: %s", serial, err)
+				}
+				if err := deleteCertMetadata(s, serial); err != nil {
+					return fmt.Errorf("error deleting serial %s from the certificate index: %s", serial, err)
 				}
+				deleted = true
+			}
+
+			status.Lock()
+			status.certsScanned++
+			if deleted {
+				status.certsDeleted++
 			}
+			status.Unlock()
 		}
 	}
 
-	if tidyRevocationList {
+	if status.tidyRevocationList {
 		b.revokeStorageLock.Lock()
 		defer b.revokeStorageLock.Unlock()
 
 		tidiedRevoked := false
 
-		revokedSerials, err := req.Storage.List("revoked/")
+		// The CRL is rebuilt even if the operation is cancelled, so that it
+		// stops listing the entries removed so far
+		defer func() {
+			if tidiedRevoked {
+				if err := buildCRL(b, &logical.Request{Storage: s}); err != nil {
+					b.Logger().Error("pki: error rebuilding the CRL after tidying", "error", err)
+				}
+			}
+		}()
+
+		revokedSerials, err := s.List("revoked/")
 		if err != nil {
-			return nil, fmt.Errorf("error fetching list of revoked certs: %s", err)
+			return fmt.Errorf("error fetching list of revoked certs: %s", err)
 		}
 
 		var revInfo revocationInfo
 		for _, serial := range revokedSerials {
-			revokedEntry, err := req.Storage.Get("revoked/" + serial)
+			if status.cancelled() {
+				return errTidyCancelled
+			}
+
+			revokedEntry, err := s.Get("revoked/" + serial)
 			if err != nil {
-				return nil, fmt.Errorf("unable to fetch revoked cert with serial %s: %s", serial, err)
+				return fmt.Errorf("unable to fetch revoked cert with serial %s: %s", serial, err)
 			}
 			if revokedEntry == nil {
-				return nil, fmt.Errorf("revoked certificate entry for serial %s is nil", serial)
+				return fmt.Errorf("revoked certificate entry for serial %s is nil", serial)
 			}
 			if revokedEntry.Value == nil || len(revokedEntry.Value) == 0 {
 				// TODO: In this case, remove it and continue? How likely is this to
 				// happen? Alternately, could skip it entirely, or could implement a
 				// delete function so that there is a way to remove these
-				return nil, fmt.Errorf("found revoked serial but actual certificate is empty")
+				return fmt.Errorf("found revoked serial but actual certificate is empty")
 			}
 
 			err = revokedEntry.DecodeJSON(&revInfo)
 			if err != nil {
-				return nil, fmt.Errorf("error decoding revocation entry for serial %s: %s", serial, err)
+				return fmt.Errorf("error decoding revocation entry for serial %s: %s", serial, err)
 			}
 
 			revokedCert, err := x509.ParseCertificate(revInfo.CertificateBytes)
 			if err != nil {
-				return nil, fmt.Errorf("unable to parse stored revoked certificate with serial %s: %s", serial, err)
+				return fmt.Errorf("unable to parse stored revoked certificate with serial %s: %s", serial, err)
 			}
 
-			if time.Now().After(revokedCert.NotAfter.Add(bufferDuration)) {
-				if err := req.Storage.Delete("revoked/" + serial); err != nil {
-					return nil, fmt.Errorf("error deleting serial %s from revoked list: %s", serial, err)
+			deleted := false
+			if time.Now().After(revokedCert.NotAfter.Add(status.revokedSafetyBuffer)) {
+				if err := s.Delete("revoked/" + serial); err != nil {
+					return fmt.Errorf("error deleting serial %s from revoked list: %s", serial, err)
 				}
 				tidiedRevoked = true
+				deleted = true
 			}
-		}
 
-		if tidiedRevoked {
-			if err := buildCRL(b, req); err != nil {
-				return nil, err
+			status.Lock()
+			status.revokedCertsScanned++
+			if deleted {
+				status.revokedCertsDeleted++
 			}
+			status.Unlock()
 		}
 	}
 
-	return nil, nil
+	return nil
+}
+
+func (status *tidyStatus) finish(state, err string) {
+	status.Lock()
+	defer status.Unlock()
+
+	status.status = state
+	status.err = err
+	status.endTime = time.Now()
+}
+
+func (status *tidyStatus) cancel() {
+	status.cancelOnce.Do(func() {
+		close(status.cancelCh)
+	})
+}
+
+func (status *tidyStatus) cancelled() bool {
+	select {
+	case <-status.cancelCh:
+		return true
+	default:
+		return false
+	}
+}
+
+// cancelTidy stops the running tidy operation when the backend is unmounted
+func (b *backend) cancelTidy() {
+	b.tidyStatusLock.RLock()
+	defer b.tidyStatusLock.RUnlock()
+
+	if b.tidyStatus != nil {
+		b.tidyStatus.cancel()
+	}
 }
 
 const pathTidyHelpSyn = `
@@ -159,7 +407,8 @@ The 'safety_buffer' parameter is useful to ensure that clock skew amongst your
 hosts cannot lead to a certificate being removed from the CRL while it is still
 considered valid by other hosts (for instance, if their clocks are a few
 minutes behind). The 'safety_buffer' parameter can be an integer number of
-seconds or a string duration like "72h".
+seconds or a string duration like "72h". The 'revoked_safety_buffer' parameter
+sets a different buffer for the revocation information.
 
 All certificates and/or revocation information currently stored in the backend
 will be checked when this endpoint is hit. The expiration of the
@@ -167,4 +416,29 @@ certificate/revocation information of each certificate being held in
 certificate storage or in revocation infomation will then be checked. If the
 current time, minus the value of 'safety_buffer', is greater than the
 expiration, it will be removed.
+
+The operation runs in the background; its progress can be read at
+'tidy-status' and it can be stopped with 'tidy-cancel'. Only one tidy
+operation runs at a time.
+`
+
+const pathTidyStatusHelpSyn = `
+Read the progress of the current or last tidy operation.
+`
+
+const pathTidyStatusHelpDesc = `
+This returns the status of the tidy operation running in the background, or of
+the last one to have run since the backend was loaded, along with its
+parameters and the number of certificates and revocation entries scanned and
+removed so far.
+`
+
+const pathTidyCancelHelpSyn = `
+Cancel the running tidy operation.
+`
+
+const pathTidyCancelHelpDesc = `
+This stops the tidy operation running in the background after the entry being
+processed. Entries already removed stay removed, and the CRL is rebuilt if any
+revocation information was removed.
 `
@@ -0,0 +1,170 @@
+package pki
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+// waitForTidy waits for the tidy operation started in the background to
+// complete
+func waitForTidy(b logical.Backend) error {
+	for i := 0; i < 100; i++ {
+		resp, err := b.HandleRequest(&logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "tidy-status",
+		})
+		if err != nil {
+			return err
+		}
+		switch resp.Data["status"] {
+		case tidyStatusRunning:
+			time.Sleep(100 * time.Millisecond)
+		case tidyStatusComplete:
+			return nil
+		default:
+			return fmt.Errorf("tidy operation did not complete: %#v", resp.Data)
+		}
+	}
+	return fmt.Errorf("timed out waiting for the tidy operation")
+}
+
+// blockingStorage signals reads of certificates and blocks them until
+// released
+type blockingStorage struct {
+	logical.Storage
+	reached  chan struct{}
+	released chan struct{}
+}
+
+func (s *blockingStorage) Get(key string) (*logical.StorageEntry, error) {
+	if strings.HasPrefix(key, "certs/") {
+		select {
+		case s.reached <- struct{}{}:
+		default:
+		}
+		<-s.released
+	}
+	return s.Storage.Get(key)
+}
+
+func TestPki_TidyStatus(t *testing.T) {
+	b, storage := createBackendWithStorage(t)
+
+	handle := func(op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		resp, err := b.HandleRequest(&logical.Request{
+			Operation: op,
+			Path:      path,
+			Storage:   storage,
+			Data:      data,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: path: %s err: %v resp: %#v", path, err, resp)
+		}
+		return resp
+	}
+
+	resp := handle(logical.ReadOperation, "tidy-status", nil)
+	if resp.Data["status"] != tidyStatusInactive {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	handle(logical.UpdateOperation, "root/generate/internal", map[string]interface{}{
+		"common_name": "myvault.com",
+		"ttl":         "5h",
+	})
+	handle(logical.UpdateOperation, "roles/testrole", map[string]interface{}{
+		"allowed_domains":  "myvault.com",
+		"allow_subdomains": true,
+	})
+	resp = handle(logical.UpdateOperation, "issue/testrole", map[string]interface{}{
+		"common_name": "cert.myvault.com",
+		"ttl":         "1s",
+	})
+	serial := resp.Data["serial_number"].(string)
+	handle(logical.UpdateOperation, "revoke", map[string]interface{}{
+		"serial_number": serial,
+	})
+	time.Sleep(2 * time.Second)
+
+	// The revoked certificate is past the safety buffer of the revocation
+	// list only
+	handle(logical.UpdateOperation, "tidy", map[string]interface{}{
+		"tidy_cert_store":       true,
+		"tidy_revocation_list":  true,
+		"safety_buffer":         "1h",
+		"revoked_safety_buffer": "1s",
+	})
+	if err := waitForTidy(b); err != nil {
+		t.Fatal(err)
+	}
+	resp = handle(logical.ReadOperation, "tidy-status", nil)
+	if resp.Data["certs_scanned"] != 2 || resp.Data["certs_deleted"] != 0 ||
+		resp.Data["revoked_certs_scanned"] != 1 || resp.Data["revoked_certs_deleted"] != 1 ||
+		resp.Data["revoked_safety_buffer"] != int64(1) || resp.Data["end_time"] == "" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if resp := handle(logical.ReadOperation, "cert/"+serial, nil); resp == nil {
+		t.Fatal("expected the certificate to be kept")
+	}
+
+	// A running operation can be cancelled
+	blocking := &blockingStorage{
+		Storage:  storage,
+		reached:  make(chan struct{}, 1),
+		released: make(chan struct{}),
+	}
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "tidy",
+		Storage:   blocking,
+		Data: map[string]interface{}{
+			"tidy_cert_store": true,
+			"safety_buffer":   "1s",
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: err: %v resp: %#v", err, resp)
+	}
+
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "tidy",
+		Storage:   storage,
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error: err: %v resp: %#v", err, resp)
+	}
+
+	<-blocking.reached
+	handle(logical.UpdateOperation, "tidy-cancel", nil)
+	close(blocking.released)
+
+	for i := 0; i < 100; i++ {
+		resp = handle(logical.ReadOperation, "tidy-status", nil)
+		if resp.Data["status"] != tidyStatusRunning {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if resp.Data["status"] != tidyStatusCancelled || resp.Data["certs_scanned"] != 1 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	deleted := resp.Data["certs_deleted"].(int)
+	resp = handle(logical.ListOperation, "certs/", nil)
+	if len(resp.Data["keys"].([]string)) != 2-deleted {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "tidy-cancel",
+		Storage:   storage,
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error: err: %v resp: %#v", err, resp)
+	}
+}
@@ -42,6 +42,10 @@ func Backend(conf *logical.BackendConfig) (*backend, error) {
 			LocalStorage: []string{
 				"otp/",
 			},
+
+			ExportableStorage: []string{
+				"roles/",
+			},
 		},
 
 		Paths: []*framework.Path{
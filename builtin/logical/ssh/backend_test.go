@@ -1,11 +1,13 @@
 package ssh
 
 import (
+	"crypto/rand"
 	"fmt"
 	"reflect"
 	"testing"
 	"time"
 
+	"golang.org/x/crypto/ed25519"
 	"golang.org/x/crypto/ssh"
 
 	"encoding/base64"
@@ -740,6 +742,101 @@ func TestBackend_DisallowUserProvidedKeyIDs(t *testing.T) {
 	logicaltest.Test(t, testCase)
 }
 
+func TestBackend_AllowedUserKeyLengths(t *testing.T) {
+	config := logical.TestBackendConfig()
+
+	b, err := Factory(config)
+	if err != nil {
+		t.Fatalf("Cannot create backend: %s", err)
+	}
+
+	edPub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edSSHPub, err := ssh.NewPublicKey(edPub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edPublicKey := string(ssh.MarshalAuthorizedKey(edSSHPub))
+
+	signStep := func(role, key string, errorOk bool, check func(resp *logical.Response) error) logicaltest.TestStep {
+		return logicaltest.TestStep{
+			Operation: logical.UpdateOperation,
+			Path:      "sign/" + role,
+			Data: map[string]interface{}{
+				"public_key": key,
+			},
+			ErrorOk: errorOk,
+			Check:   check,
+		}
+	}
+	expectError := func(resp *logical.Response) error {
+		if resp == nil || !resp.IsError() {
+			return fmt.Errorf("expected an error, got %#v", resp)
+		}
+		return nil
+	}
+
+	testCase := logicaltest.TestCase{
+		Backend: b,
+		Steps: []logicaltest.TestStep{
+			configCaStep(),
+
+			createRoleStep("strongrsa", map[string]interface{}{
+				"key_type":                "ca",
+				"allowed_users":           "tuber",
+				"default_user":            "tuber",
+				"allow_user_certificates": true,
+				"allowed_user_key_lengths": map[string]interface{}{
+					"rsa": 4096,
+				},
+			}),
+			createRoleStep("rsaor25519", map[string]interface{}{
+				"key_type":                "ca",
+				"allowed_users":           "tuber",
+				"default_user":            "tuber",
+				"allow_user_certificates": true,
+				"allowed_user_key_lengths": map[string]interface{}{
+					"rsa":     "2048",
+					"ed25519": 0,
+				},
+			}),
+			logicaltest.TestStep{
+				Operation: logical.UpdateOperation,
+				Path:      "roles/badrole",
+				Data: map[string]interface{}{
+					"key_type":                "ca",
+					"allow_user_certificates": true,
+					"allowed_user_key_lengths": map[string]interface{}{
+						"rsa1": 2048,
+					},
+				},
+				ErrorOk: true,
+				Check:   expectError,
+			},
+
+			// publicKey2 is a 2048-bit RSA key
+			signStep("strongrsa", publicKey2, true, expectError),
+			signStep("strongrsa", edPublicKey, true, expectError),
+			signStep("rsaor25519", publicKey2, false, func(resp *logical.Response) error {
+				if resp.Data["public_key_type"] != "rsa" || resp.Data["public_key_bits"] != 2048 {
+					return fmt.Errorf("bad: %#v", resp.Data)
+				}
+				return nil
+			}),
+			signStep("rsaor25519", edPublicKey, false, func(resp *logical.Response) error {
+				if resp.Data["public_key_type"] != "ed25519" || resp.Data["signed_key"] == "" {
+					return fmt.Errorf("bad: %#v", resp.Data)
+				}
+				return nil
+			}),
+		},
+	}
+
+	logicaltest.Test(t, testCase)
+}
+
 func configCaStep() logicaltest.TestStep {
 	return logicaltest.TestStep{
 		Operation: logical.UpdateOperation,
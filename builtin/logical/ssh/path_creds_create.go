@@ -159,6 +159,16 @@ func (b *backend) pathCredsCreateWrite(
 			"port":               role.Port,
 			"install_script":     role.InstallScript,
 		})
+
+		// Index the lease by the fingerprint of the installed key so that it
+		// can be found from the authorized_keys of the target host
+		fingerprint, err := keyFingerprint(dynamicPublicKey)
+		if err != nil {
+			return nil, err
+		}
+		result.Secret.Artifacts = map[string]string{
+			"ssh_key_fingerprint": fingerprint,
+		}
 	} else {
 		return nil, fmt.Errorf("key type unknown")
 	}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/hashicorp/vault/helper/cidrutil"
 	"github.com/hashicorp/vault/helper/parseutil"
+	"github.com/hashicorp/vault/helper/strutil"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
 )
@@ -46,6 +47,7 @@ type sshRole struct {
 	AllowSubdomains        bool              `mapstructure:"allow_subdomains" json:"allow_subdomains"`
 	AllowUserKeyIDs        bool              `mapstructure:"allow_user_key_ids" json:"allow_user_key_ids"`
 	KeyIDFormat            string            `mapstructure:"key_id_format" json:"key_id_format"`
+	AllowedUserKeyLengths  map[string]int    `mapstructure:"allowed_user_key_lengths" json:"allowed_user_key_lengths"`
 }
 
 func pathListRoles(b *backend) *framework.Path {
@@ -277,6 +279,15 @@ func pathRoles(b *backend) *framework.Path {
 				'{{public_key_hash}}' - A SHA256 checksum of the public key that is being signed.
 				`,
 			},
+			"allowed_user_key_lengths": &framework.FieldSchema{
+				Type: framework.TypeMap,
+				Description: `
+				[Not applicable for Dynamic type] [Not applicable for OTP type] [Optional for CA type]
+				If set, only public keys of the listed types are signed, and only if their size in bits
+				is at least the given minimum, e.g. {"rsa": 4096, "ed25519": 0}. Key types are "rsa",
+				"ec", "ed25519" and "dsa". Defaults to allowing any key.
+				`,
+			},
 		},
 
 		Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -454,6 +465,20 @@ func (b *backend) createCARole(allowedUsers, defaultUser string, data *framework
 		return nil, logical.ErrorResponse("Either 'allow_user_certificates' or 'allow_host_certificates' must be set to 'true'")
 	}
 
+	allowedUserKeyLengths, err := convertMapToIntValue(data.Get("allowed_user_key_lengths").(map[string]interface{}))
+	if err != nil {
+		return nil, logical.ErrorResponse(fmt.Sprintf("invalid allowed_user_key_lengths: %s", err))
+	}
+	for keyType, minBits := range allowedUserKeyLengths {
+		if !strutil.StrListContains(userKeyTypes, keyType) {
+			return nil, logical.ErrorResponse(fmt.Sprintf("invalid key type %q in allowed_user_key_lengths; must be one of %s", keyType, strings.Join(userKeyTypes, ", ")))
+		}
+		if minBits < 0 {
+			return nil, logical.ErrorResponse(fmt.Sprintf("invalid minimum size %d for key type %q in allowed_user_key_lengths", minBits, keyType))
+		}
+	}
+	role.AllowedUserKeyLengths = allowedUserKeyLengths
+
 	defaultCriticalOptions := convertMapToStringValue(data.Get("default_critical_options").(map[string]interface{}))
 	defaultExtensions := convertMapToStringValue(data.Get("default_extensions").(map[string]interface{}))
 
@@ -569,6 +594,7 @@ func (b *backend) pathRoleRead(req *logical.Request, d *framework.FieldData) (*l
 				"key_type":                 role.KeyType,
 				"default_critical_options": role.DefaultCriticalOptions,
 				"default_extensions":       role.DefaultExtensions,
+				"allowed_user_key_lengths": role.AllowedUserKeyLengths,
 			},
 		}, nil
 	} else {
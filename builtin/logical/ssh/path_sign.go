@@ -1,7 +1,10 @@
 package ssh
 
 import (
+	"crypto/dsa"
+	"crypto/ecdsa"
 	"crypto/rand"
+	"crypto/rsa"
 	"crypto/sha256"
 	"errors"
 	"fmt"
@@ -14,6 +17,7 @@ import (
 	"github.com/hashicorp/vault/helper/strutil"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
+	"golang.org/x/crypto/ed25519"
 	"golang.org/x/crypto/ssh"
 )
 
@@ -108,6 +112,11 @@ func (b *backend) pathSignCertificate(req *logical.Request, data *framework.Fiel
 		return logical.ErrorResponse(fmt.Sprintf("failed to parse public_key as SSH key: %s", err)), nil
 	}
 
+	keyType, keyBits, err := validateUserKey(userPublicKey, role)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
 	// Note that these various functions always return "user errors" so we pass
 	// them as 4xx values
 	keyId, err := b.calculateKeyId(data, req, role, userPublicKey)
@@ -190,9 +199,64 @@ func (b *backend) pathSignCertificate(req *logical.Request, data *framework.Fiel
 		},
 	}
 
+	// Record the checked key parameters, so that they show up in the audit
+	// log along with the requirements they were checked against
+	if len(role.AllowedUserKeyLengths) != 0 {
+		response.Data["public_key_type"] = keyType
+		response.Data["public_key_bits"] = keyBits
+		response.Data["allowed_user_key_lengths"] = role.AllowedUserKeyLengths
+	}
+
 	return response, nil
 }
 
+// userKeyTypes are the key types that roles can restrict in
+// allowed_user_key_lengths
+var userKeyTypes = []string{"rsa", "ec", "ed25519", "dsa"}
+
+// userKeyInfo returns the type and size in bits of the public key
+func userKeyInfo(publicKey ssh.PublicKey) (string, int, error) {
+	cryptoKey, ok := publicKey.(ssh.CryptoPublicKey)
+	if !ok {
+		return "", 0, fmt.Errorf("unsupported public key type %q", publicKey.Type())
+	}
+
+	switch key := cryptoKey.CryptoPublicKey().(type) {
+	case *rsa.PublicKey:
+		return "rsa", key.N.BitLen(), nil
+	case *ecdsa.PublicKey:
+		return "ec", key.Curve.Params().BitSize, nil
+	case ed25519.PublicKey:
+		return "ed25519", 256, nil
+	case *dsa.PublicKey:
+		return "dsa", key.P.BitLen(), nil
+	default:
+		return "", 0, fmt.Errorf("unsupported public key type %q", publicKey.Type())
+	}
+}
+
+// validateUserKey checks the public key against the key types and minimum
+// sizes allowed by the role, and returns its type and size
+func validateUserKey(publicKey ssh.PublicKey, role *sshRole) (string, int, error) {
+	keyType, keyBits, err := userKeyInfo(publicKey)
+	if len(role.AllowedUserKeyLengths) == 0 {
+		// Any key is allowed, even of a type whose size is unknown
+		return keyType, keyBits, nil
+	}
+	if err != nil {
+		return "", 0, err
+	}
+
+	minBits, ok := role.AllowedUserKeyLengths[keyType]
+	if !ok {
+		return "", 0, fmt.Errorf("public keys of type %q are not allowed by the role", keyType)
+	}
+	if keyBits < minBits {
+		return "", 0, fmt.Errorf("public key of type %q is %d bits, but the role requires at least %d bits", keyType, keyBits, minBits)
+	}
+	return keyType, keyBits, nil
+}
+
 func (b *backend) calculateValidPrincipals(data *framework.FieldData, defaultPrincipal, principalsAllowedByRole string, validatePrincipal func([]string, string) bool) ([]string, error) {
 	validPrincipals := ""
 	validPrincipalsRaw, ok := data.GetOk("valid_principals")
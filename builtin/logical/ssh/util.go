@@ -9,6 +9,7 @@ import (
 	"encoding/pem"
 	"fmt"
 	"net"
+	"strconv"
 	"strings"
 	"time"
 
@@ -205,6 +206,16 @@ func parsePublicSSHKey(key string) (ssh.PublicKey, error) {
 	return ssh.ParsePublicKey([]byte(decodedKey))
 }
 
+// keyFingerprint returns the SHA256 fingerprint of a public key in the
+// format printed by ssh-keygen
+func keyFingerprint(key string) (string, error) {
+	publicKey, err := parsePublicSSHKey(strings.TrimSpace(key))
+	if err != nil {
+		return "", fmt.Errorf("error parsing public key: %v", err)
+	}
+	return ssh.FingerprintSHA256(publicKey), nil
+}
+
 func convertMapToStringValue(initial map[string]interface{}) map[string]string {
 	result := map[string]string{}
 	for key, value := range initial {
@@ -213,6 +224,18 @@ func convertMapToStringValue(initial map[string]interface{}) map[string]string {
 	return result
 }
 
+func convertMapToIntValue(initial map[string]interface{}) (map[string]int, error) {
+	result := map[string]int{}
+	for key, value := range initial {
+		v, err := strconv.Atoi(fmt.Sprintf("%v", value))
+		if err != nil {
+			return nil, fmt.Errorf("value of %q is not an integer", key)
+		}
+		result[key] = v
+	}
+	return result, nil
+}
+
 // Serve a template processor for custom format inputs
 func substQuery(tpl string, data map[string]string) string {
 	for k, v := range data {
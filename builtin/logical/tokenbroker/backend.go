@@ -0,0 +1,50 @@
+package tokenbroker
+
+import (
+	"strings"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+func Factory(conf *logical.BackendConfig) (logical.Backend, error) {
+	b := Backend()
+	if err := b.Setup(conf); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func Backend() *backend {
+	var b backend
+	b.Backend = &framework.Backend{
+		Help: strings.TrimSpace(backendHelp),
+
+		Paths: []*framework.Path{
+			pathListRoles(&b),
+			pathRoles(&b),
+			pathCreds(&b),
+		},
+
+		Secrets: []*framework.Secret{
+			secretToken(&b),
+		},
+
+		BackendType: logical.TypeLogical,
+	}
+
+	return &b
+}
+
+type backend struct {
+	*framework.Backend
+}
+
+const backendHelp = `
+The token broker backend issues API tokens of HTTP services.
+
+Each role describes, with templated HTTP requests, how a service creates a
+token and how it revokes one. Tokens are created when credentials are read
+from "creds/<role>" and revoked when their lease ends, so a service can have
+its tokens brokered by Vault without a dedicated plugin.
+`
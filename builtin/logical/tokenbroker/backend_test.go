@@ -0,0 +1,253 @@
+package tokenbroker
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+// fakeService stands in for a service issuing API tokens in the style of
+// Terraform Cloud
+type fakeService struct {
+	sync.Mutex
+
+	nextID int
+	tokens map[string]string
+	bodies []map[string]interface{}
+}
+
+func (f *fakeService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer admin" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	switch {
+	case r.Method == "POST" && r.URL.Path == "/api/v2/teams/team-1/authentication-tokens":
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.bodies = append(f.bodies, body)
+
+		f.nextID++
+		id := fmt.Sprintf("at-%d", f.nextID)
+		f.tokens[id] = "secret-" + id
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{
+				"id": id,
+				"attributes": map[string]interface{}{
+					"token": f.tokens[id],
+				},
+			},
+		})
+
+	case r.Method == "DELETE" && strings.HasPrefix(r.URL.Path, "/api/v2/authentication-tokens/"):
+		id := strings.TrimPrefix(r.URL.Path, "/api/v2/authentication-tokens/")
+		if _, ok := f.tokens[id]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		delete(f.tokens, id)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func testBackend(t *testing.T) (*backend, logical.Storage) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+	config.System = &logical.StaticSystemView{
+		DefaultLeaseTTLVal: time.Hour,
+		MaxLeaseTTLVal:     24 * time.Hour,
+	}
+
+	b := Backend()
+	if err := b.Setup(config); err != nil {
+		t.Fatal(err)
+	}
+	return b, config.StorageView
+}
+
+func TestBackend_createAndRevoke(t *testing.T) {
+	service := &fakeService{tokens: make(map[string]string)}
+	ts := httptest.NewServer(service)
+	defer ts.Close()
+
+	b, storage := testBackend(t)
+
+	roleData := map[string]interface{}{
+		"base_url":    ts.URL + "/api/v2",
+		"headers":     map[string]interface{}{"Authorization": "Bearer admin"},
+		"create_path": "/teams/team-1/authentication-tokens",
+		"create_body": `{"data": {"type": "authentication-tokens", "attributes": {"description": {{json .Name}}, "ttl": {{.TTL}}}}}`,
+		"token_field": "data.attributes.token",
+		"id_field":    "data.id",
+		"revoke_path": "/authentication-tokens/{{urlquery .ID}}",
+		"ttl":         "30m",
+		"max_ttl":     "2h",
+	}
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "roles/terraform",
+		Storage:   storage,
+		Data:      roleData,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v err: %v", resp, err)
+	}
+
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "roles/terraform",
+		Storage:   storage,
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: resp: %#v err: %v", resp, err)
+	}
+	if !reflect.DeepEqual(resp.Data["header_names"], []string{"Authorization"}) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if _, ok := resp.Data["headers"]; ok {
+		t.Fatalf("header values should not be returned")
+	}
+	if resp.Data["create_method"] != "POST" || resp.Data["revoke_method"] != "DELETE" || resp.Data["ttl"] != int64(1800) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation:   logical.ReadOperation,
+		Path:        "creds/terraform",
+		Storage:     storage,
+		DisplayName: "token-ci",
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: resp: %#v err: %v", resp, err)
+	}
+	if resp.Data["token"] != "secret-at-1" || resp.Data["token_id"] != "at-1" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if resp.Secret.TTL != 30*time.Minute {
+		t.Fatalf("bad: ttl %s", resp.Secret.TTL)
+	}
+
+	attributes := service.bodies[0]["data"].(map[string]interface{})["attributes"].(map[string]interface{})
+	if attributes["description"] != resp.Data["token_name"] || attributes["ttl"] != float64(1800) {
+		t.Fatalf("bad: %#v", attributes)
+	}
+	if !strings.HasPrefix(resp.Data["token_name"].(string), "vault-terraform-token-ci-") {
+		t.Fatalf("bad: token name %q", resp.Data["token_name"])
+	}
+
+	secret := resp.Secret
+	secret.IssueTime = time.Now()
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.RenewOperation,
+		Storage:   storage,
+		Secret:    secret,
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: resp: %#v err: %v", resp, err)
+	}
+
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.RevokeOperation,
+		Storage:   storage,
+		Secret:    secret,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v err: %v", resp, err)
+	}
+	if len(service.tokens) != 0 {
+		t.Fatalf("expected the token to be revoked, got %v", service.tokens)
+	}
+
+	// Revoking a token the service no longer knows fails so that it is
+	// retried
+	_, err = b.HandleRequest(&logical.Request{
+		Operation: logical.RevokeOperation,
+		Storage:   storage,
+		Secret:    secret,
+	})
+	if err == nil {
+		t.Fatalf("expected an error revoking an unknown token")
+	}
+}
+
+func TestBackend_roleValidation(t *testing.T) {
+	b, storage := testBackend(t)
+
+	valid := map[string]interface{}{
+		"base_url":    "https://example.com/api",
+		"create_path": "/tokens",
+		"token_field": "token",
+		"revoke_path": "/tokens/{{.ID}}",
+	}
+
+	for field, value := range map[string]interface{}{
+		"base_url":    "example.com/api",
+		"create_path": "",
+		"revoke_path": "/tokens/{{.Missing}}",
+		"create_body": `{"name": {{json .Name}`,
+		"token_field": "",
+		"headers":     map[string]interface{}{"X-Count": 1},
+		"ttl":         "3h",
+	} {
+		data := make(map[string]interface{})
+		for k, v := range valid {
+			data[k] = v
+		}
+		data[field] = value
+		data["max_ttl"] = "2h"
+
+		resp, err := b.HandleRequest(&logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "roles/invalid",
+			Storage:   storage,
+			Data:      data,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp == nil || !resp.IsError() {
+			t.Fatalf("expected an error for %s %#v", field, value)
+		}
+	}
+}
+
+func TestLookupField(t *testing.T) {
+	body := []byte(`{"data": {"id": 42, "tokens": [{"value": "a"}, {"value": "b"}]}}`)
+
+	for path, expected := range map[string]string{
+		"data.id":             "42",
+		"data.tokens.1.value": "b",
+	} {
+		actual, err := lookupString(body, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual != expected {
+			t.Fatalf("bad: %s: expected %q, got %q", path, expected, actual)
+		}
+	}
+
+	for _, path := range []string{"data.missing", "data.tokens.2.value", "data.tokens", "data.id.value"} {
+		if _, err := lookupString(body, path); err == nil {
+			t.Fatalf("expected an error for %s", path)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package tokenbroker
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+func pathCreds(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "creds/" + framework.GenericNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of the role",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathCredsRead,
+		},
+
+		HelpSynopsis:    pathCredsHelpSyn,
+		HelpDescription: pathCredsHelpDesc,
+	}
+}
+
+func (b *backend) pathCredsRead(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	role, err := b.Role(req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return logical.ErrorResponse(fmt.Sprintf("unknown role: %s", name)), nil
+	}
+
+	ttl := role.TTL
+	if ttl == 0 {
+		ttl = b.System().DefaultLeaseTTL()
+	}
+
+	data := &templateData{
+		RoleName:    name,
+		DisplayName: req.DisplayName,
+		Name:        genTokenName(name, req.DisplayName),
+		TTL:         int64(ttl.Seconds()),
+	}
+
+	body, err := role.Create.send(role.BaseURL, role.Headers, data)
+	if err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("error creating token: %s", err)), nil
+	}
+
+	// The token has been created, so failing to understand the response
+	// leaves it behind; say so in the error
+	token, err := lookupString(body, role.TokenField)
+	if err != nil {
+		return nil, fmt.Errorf("token %q was created but could not be read from the response and must be revoked manually: %s", data.Name, err)
+	}
+	var id string
+	if role.IDField != "" {
+		id, err = lookupString(body, role.IDField)
+		if err != nil {
+			return nil, fmt.Errorf("token %q was created but its identifier could not be read from the response and it must be revoked manually: %s", data.Name, err)
+		}
+	}
+
+	resp := b.Secret(SecretTokenType).Response(map[string]interface{}{
+		"token":      token,
+		"token_id":   id,
+		"token_name": data.Name,
+	}, map[string]interface{}{
+		"role":       name,
+		"token":      token,
+		"token_id":   id,
+		"token_name": data.Name,
+	})
+	resp.Secret.TTL = ttl
+
+	return resp, nil
+}
+
+var invalidNameChars = regexp.MustCompile("[^a-zA-Z0-9_.-]")
+
+// genTokenName returns a unique name for a token that services can show to
+// identify where it came from
+func genTokenName(roleName, displayName string) string {
+	name := fmt.Sprintf("vault-%s-%s", roleName, displayName)
+	name = invalidNameChars.ReplaceAllString(name, "_")
+	if len(name) > 48 {
+		name = name[:48]
+	}
+	return fmt.Sprintf("%s-%d", name, time.Now().UnixNano())
+}
+
+const pathCredsHelpSyn = `
+Request a token of the service of a role.
+`
+
+const pathCredsHelpDesc = `
+This path creates a token by sending the create request of the role to its
+service. The token is revoked with the revoke request of the role when its
+lease ends.
+`
@@ -0,0 +1,284 @@
+package tokenbroker
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+func pathListRoles(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "roles/?$",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ListOperation: b.pathRoleList,
+		},
+
+		HelpSynopsis:    pathListRolesHelpSyn,
+		HelpDescription: pathListRolesHelpDesc,
+	}
+}
+
+func pathRoles(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "roles/" + framework.GenericNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of the role",
+			},
+
+			"base_url": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Base URL of the service API, such as https://app.terraform.io/api/v2",
+			},
+
+			"headers": &framework.FieldSchema{
+				Type: framework.TypeMap,
+				Description: `Headers sent with every request to the service, such as the
+Authorization header with the credentials Vault uses. Their values
+are not returned when the role is read.`,
+			},
+
+			"create_method": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Default:     "POST",
+				Description: "HTTP method of the request creating a token",
+			},
+
+			"create_path": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Path template, relative to base_url, of the request creating a token",
+			},
+
+			"create_body": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Body template of the request creating a token",
+			},
+
+			"token_field": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `Dot separated path to the token in the response to the create
+request, such as "data.attributes.token"`,
+			},
+
+			"id_field": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `Dot separated path to the identifier of the token in the
+response to the create request, available to the revoke request
+templates as {{.ID}}`,
+			},
+
+			"revoke_method": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Default:     "DELETE",
+				Description: "HTTP method of the request revoking a token",
+			},
+
+			"revoke_path": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Path template, relative to base_url, of the request revoking a token",
+			},
+
+			"revoke_body": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Body template of the request revoking a token",
+			},
+
+			"ttl": &framework.FieldSchema{
+				Type:        framework.TypeDurationSecond,
+				Description: "Default lease duration of tokens",
+			},
+
+			"max_ttl": &framework.FieldSchema{
+				Type:        framework.TypeDurationSecond,
+				Description: "Maximum lease duration of tokens",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.DeleteOperation: b.pathRoleDelete,
+			logical.ReadOperation:   b.pathRoleRead,
+			logical.UpdateOperation: b.pathRoleWrite,
+		},
+
+		HelpSynopsis:    pathRolesHelpSyn,
+		HelpDescription: pathRolesHelpDesc,
+	}
+}
+
+func (b *backend) Role(s logical.Storage, name string) (*roleEntry, error) {
+	entry, err := s.Get("role/" + name)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var result roleEntry
+	if err := entry.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (b *backend) pathRoleList(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	entries, err := req.Storage.List("role/")
+	if err != nil {
+		return nil, err
+	}
+	return logical.ListResponse(entries), nil
+}
+
+func (b *backend) pathRoleDelete(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	err := req.Storage.Delete("role/" + d.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+func (b *backend) pathRoleRead(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	role, err := b.Role(req.Storage, d.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, nil
+	}
+
+	// Header values usually hold credentials, so only their names are
+	// returned
+	headerNames := make([]string, 0, len(role.Headers))
+	for name := range role.Headers {
+		headerNames = append(headerNames, name)
+	}
+	sort.Strings(headerNames)
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"base_url":      role.BaseURL,
+			"header_names":  headerNames,
+			"create_method": role.Create.Method,
+			"create_path":   role.Create.Path,
+			"create_body":   role.Create.Body,
+			"token_field":   role.TokenField,
+			"id_field":      role.IDField,
+			"revoke_method": role.Revoke.Method,
+			"revoke_path":   role.Revoke.Path,
+			"revoke_body":   role.Revoke.Body,
+			"ttl":           int64(role.TTL.Seconds()),
+			"max_ttl":       int64(role.MaxTTL.Seconds()),
+		},
+	}, nil
+}
+
+func (b *backend) pathRoleWrite(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	role := &roleEntry{
+		BaseURL: d.Get("base_url").(string),
+		Create: requestSpec{
+			Method: strings.ToUpper(d.Get("create_method").(string)),
+			Path:   d.Get("create_path").(string),
+			Body:   d.Get("create_body").(string),
+		},
+		TokenField: d.Get("token_field").(string),
+		IDField:    d.Get("id_field").(string),
+		Revoke: requestSpec{
+			Method: strings.ToUpper(d.Get("revoke_method").(string)),
+			Path:   d.Get("revoke_path").(string),
+			Body:   d.Get("revoke_body").(string),
+		},
+		TTL:    time.Duration(d.Get("ttl").(int)) * time.Second,
+		MaxTTL: time.Duration(d.Get("max_ttl").(int)) * time.Second,
+	}
+
+	u, err := url.Parse(role.BaseURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return logical.ErrorResponse("base_url must be an http or https URL"), nil
+	}
+
+	rawHeaders := d.Get("headers").(map[string]interface{})
+	if len(rawHeaders) > 0 {
+		role.Headers = make(map[string]string, len(rawHeaders))
+		for name, rawValue := range rawHeaders {
+			value, ok := rawValue.(string)
+			if !ok {
+				return logical.ErrorResponse(fmt.Sprintf("header %q must have a string value", name)), nil
+			}
+			role.Headers[name] = value
+		}
+	}
+
+	if err := role.Create.validate("create"); err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+	if err := role.Revoke.validate("revoke"); err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+	if role.TokenField == "" {
+		return logical.ErrorResponse("token_field must be given"), nil
+	}
+	if role.MaxTTL > 0 && role.TTL > role.MaxTTL {
+		return logical.ErrorResponse("ttl cannot be greater than max_ttl"), nil
+	}
+
+	entry, err := logical.StorageEntryJSON("role/"+d.Get("name").(string), role)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(entry); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// roleEntry declares how tokens are created and revoked at a service
+type roleEntry struct {
+	BaseURL    string            `json:"base_url"`
+	Headers    map[string]string `json:"headers"`
+	Create     requestSpec       `json:"create"`
+	TokenField string            `json:"token_field"`
+	IDField    string            `json:"id_field"`
+	Revoke     requestSpec       `json:"revoke"`
+	TTL        time.Duration     `json:"ttl"`
+	MaxTTL     time.Duration     `json:"max_ttl"`
+}
+
+const pathListRolesHelpSyn = `List the existing roles in this backend`
+
+const pathListRolesHelpDesc = `Roles will be listed by the role name.`
+
+const pathRolesHelpSyn = `
+Manage the roles that describe how tokens of a service are created and revoked.
+`
+
+const pathRolesHelpDesc = `
+This path lets you manage the roles of the backend. A role describes the
+request that creates a token at a service, where the token and its identifier
+are found in the response, and the request that revokes the token.
+
+Paths are relative to "base_url", and paths and bodies are Go templates that
+can reference:
+
+  {{.RoleName}}     the name of the role
+  {{.DisplayName}}  the display name of the requesting token
+  {{.Name}}         a unique name generated for the token
+  {{.TTL}}          the lease duration of the token in seconds
+  {{.ID}}           the identifier of the token, only when revoking
+  {{.Token}}        the token, only when revoking
+
+The "json" function quotes a value for a JSON body, as in
+{"description": {{json .Name}}}, and the "urlquery" function escapes a
+value for a path.
+`
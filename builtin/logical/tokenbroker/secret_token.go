@@ -0,0 +1,83 @@
+package tokenbroker
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+const SecretTokenType = "token"
+
+func secretToken(b *backend) *framework.Secret {
+	return &framework.Secret{
+		Type: SecretTokenType,
+		Fields: map[string]*framework.FieldSchema{
+			"token": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Token of the service",
+			},
+
+			"token_id": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Identifier of the token at the service",
+			},
+
+			"token_name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name generated for the token",
+			},
+		},
+
+		Renew:  b.secretTokenRenew,
+		Revoke: b.secretTokenRevoke,
+	}
+}
+
+func (b *backend) secretRole(req *logical.Request) (string, *roleEntry, error) {
+	roleName, ok := req.Secret.InternalData["role"].(string)
+	if !ok {
+		return "", nil, fmt.Errorf("secret is missing role internal data")
+	}
+
+	role, err := b.Role(req.Storage, roleName)
+	if err != nil {
+		return "", nil, err
+	}
+	if role == nil {
+		return "", nil, fmt.Errorf("could not find role with name: %s", roleName)
+	}
+	return roleName, role, nil
+}
+
+func (b *backend) secretTokenRenew(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	_, role, err := b.secretRole(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return framework.LeaseExtend(role.TTL, role.MaxTTL, b.System())(req, d)
+}
+
+func (b *backend) secretTokenRevoke(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	roleName, role, err := b.secretRole(req)
+	if err != nil {
+		return nil, err
+	}
+
+	data := &templateData{
+		RoleName: roleName,
+		TTL:      int64(req.Secret.TTL / time.Second),
+	}
+	data.ID, _ = req.Secret.InternalData["token_id"].(string)
+	data.Token, _ = req.Secret.InternalData["token"].(string)
+	data.Name, _ = req.Secret.InternalData["token_name"].(string)
+
+	if _, err := role.Revoke.send(role.BaseURL, role.Headers, data); err != nil {
+		return nil, fmt.Errorf("error revoking token: %s", err)
+	}
+	return nil, nil
+}
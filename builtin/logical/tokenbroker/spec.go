@@ -0,0 +1,178 @@
+package tokenbroker
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"text/template"
+
+	cleanhttp "github.com/hashicorp/go-cleanhttp"
+)
+
+// requestSpec describes an HTTP request made to the service of a role. The
+// path and body are templates rendered with a templateData.
+type requestSpec struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Body   string `json:"body"`
+}
+
+// templateData is available to the templates of a requestSpec
+type templateData struct {
+	// RoleName is the name of the role the token is for
+	RoleName string
+
+	// DisplayName is the display name of the requesting token
+	DisplayName string
+
+	// Name is a unique name generated for the token
+	Name string
+
+	// TTL is the lease duration of the token in seconds
+	TTL int64
+
+	// ID and Token are the identifier and value of the created token, only
+	// available when it is revoked
+	ID    string
+	Token string
+}
+
+var templateFuncs = template.FuncMap{
+	// json encodes a value for inclusion in a JSON body, quoting strings
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+func parseTemplate(name, text string) (*template.Template, error) {
+	return template.New(name).Funcs(templateFuncs).Parse(text)
+}
+
+// validate checks that the templates of the spec render, which catches
+// syntax errors and references to unknown fields
+func (s *requestSpec) validate(name string) error {
+	if s.Path == "" {
+		return fmt.Errorf("%s_path must be given", name)
+	}
+	if _, err := render(name+"_path", s.Path, &templateData{}); err != nil {
+		return fmt.Errorf("error in %s_path: %s", name, err)
+	}
+	if _, err := render(name+"_body", s.Body, &templateData{}); err != nil {
+		return fmt.Errorf("error in %s_body: %s", name, err)
+	}
+	return nil
+}
+
+func render(name, text string, data *templateData) (string, error) {
+	tmpl, err := parseTemplate(name, text)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// send renders the spec and sends it to the service with the headers of the
+// role, returning the body of a successful response
+func (s *requestSpec) send(baseURL string, headers map[string]string, data *templateData) ([]byte, error) {
+	path, err := render("path", s.Path, data)
+	if err != nil {
+		return nil, fmt.Errorf("error rendering path: %s", err)
+	}
+	body, err := render("body", s.Body, data)
+	if err != nil {
+		return nil, fmt.Errorf("error rendering body: %s", err)
+	}
+
+	u, err := url.Parse(strings.TrimSuffix(baseURL, "/") + "/" + strings.TrimPrefix(path, "/"))
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(s.Method, u.String(), strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := cleanhttp.DefaultClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("%s %s returned status %d: %s", s.Method, u.Path, resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	return respBody, nil
+}
+
+// lookupField returns the value at a dot separated path in a JSON document,
+// such as "data.attributes.token". Numeric path elements index arrays.
+func lookupField(body []byte, path string) (interface{}, error) {
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf("error decoding response: %s", err)
+	}
+
+	for _, key := range strings.Split(path, ".") {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			next, ok := v[key]
+			if !ok {
+				return nil, fmt.Errorf("response has no field %q", path)
+			}
+			value = next
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, fmt.Errorf("response has no field %q", path)
+			}
+			value = v[i]
+		default:
+			return nil, fmt.Errorf("response has no field %q", path)
+		}
+	}
+
+	return value, nil
+}
+
+// lookupString returns the value at the path as a string, accepting numbers
+// for identifiers
+func lookupString(body []byte, path string) (string, error) {
+	value, err := lookupField(body, path)
+	if err != nil {
+		return "", err
+	}
+
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	default:
+		return "", fmt.Errorf("response field %q is not a string", path)
+	}
+}
@@ -26,6 +26,7 @@ func Backend() *backend {
 			pathListKeys(&b),
 			pathKeys(&b),
 			pathCode(&b),
+			pathBatchCode(&b),
 		},
 
 		Secrets:     []*framework.Secret{},
@@ -33,6 +34,7 @@ func Backend() *backend {
 	}
 
 	b.usedCodes = cache.New(0, 30*time.Second)
+	b.failedValidations = cache.New(0, 30*time.Second)
 
 	return &b
 }
@@ -41,6 +43,9 @@ type backend struct {
 	*framework.Backend
 
 	usedCodes *cache.Cache
+
+	// failedValidations counts the invalid codes given for each key
+	failedValidations *cache.Cache
 }
 
 const backendHelp = `
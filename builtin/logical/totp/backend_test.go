@@ -5,6 +5,7 @@ import (
 	"log"
 	"net/url"
 	"path"
+	"reflect"
 	"testing"
 	"time"
 
@@ -721,7 +722,7 @@ func TestBackend_generatedKeyInvalidSkew(t *testing.T) {
 	keyData := map[string]interface{}{
 		"issuer":       "Vault",
 		"account_name": "Test",
-		"skew":         "2",
+		"skew":         "11",
 		"generate":     true,
 	}
 
@@ -924,6 +925,187 @@ func TestBackend_generatedKeyExportedFalse(t *testing.T) {
 	})
 }
 
+func TestBackend_keySkew(t *testing.T) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+	b, err := Factory(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, _ := createKey()
+
+	keyData := map[string]interface{}{
+		"issuer":       "Vault",
+		"account_name": "Test",
+		"key":          key,
+		"skew":         2,
+		"generate":     false,
+	}
+
+	expected := map[string]interface{}{
+		"issuer":       "Vault",
+		"account_name": "Test",
+		"digits":       otplib.DigitsSix,
+		"period":       30,
+		"algorithm":    otplib.AlgorithmSHA1,
+		"skew":         uint(2),
+	}
+
+	codeAt := func(offset time.Duration) string {
+		code, err := totplib.GenerateCodeCustom(key, time.Now().Add(offset), totplib.ValidateOpts{
+			Period:    30,
+			Digits:    otplib.DigitsSix,
+			Algorithm: otplib.AlgorithmSHA1,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return code
+	}
+
+	logicaltest.Test(t, logicaltest.TestCase{
+		Backend: b,
+		Steps: []logicaltest.TestStep{
+			testAccStepCreateKey(t, "test", keyData, false),
+			testAccStepReadKey(t, "test", expected),
+			// Codes are accepted up to two periods away
+			testAccStepValidateCode(t, "test", codeAt(-60*time.Second), true, false),
+			testAccStepValidateCode(t, "test", codeAt(60*time.Second), true, false),
+			testAccStepValidateCode(t, "test", codeAt(-90*time.Second), false, false),
+			testAccStepValidateCode(t, "test", codeAt(90*time.Second), false, false),
+		},
+	})
+}
+
+func TestBackend_maxValidationAttempts(t *testing.T) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+	b, err := Factory(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, _ := createKey()
+	code, _ := generateCode(key, 30, otplib.DigitsSix, otplib.AlgorithmSHA1)
+
+	keyData := map[string]interface{}{
+		"issuer":                  "Vault",
+		"account_name":            "Test",
+		"key":                     key,
+		"max_validation_attempts": 2,
+		"generate":                false,
+	}
+	unlimitedKeyData := map[string]interface{}{
+		"issuer":                  "Vault",
+		"account_name":            "Test",
+		"key":                     key,
+		"max_validation_attempts": 0,
+		"generate":                false,
+	}
+
+	logicaltest.Test(t, logicaltest.TestCase{
+		Backend: b,
+		Steps: []logicaltest.TestStep{
+			testAccStepCreateKey(t, "test", map[string]interface{}{
+				"issuer":                  "Vault",
+				"account_name":            "Test",
+				"key":                     key,
+				"max_validation_attempts": -1,
+				"generate":                false,
+			}, true),
+			testAccStepCreateKey(t, "test", keyData, false),
+			testAccStepCreateKey(t, "unlimited", unlimitedKeyData, false),
+			testAccStepValidateCode(t, "test", "1234567", false, false),
+			testAccStepValidateCode(t, "test", "12345678", false, false),
+			// Once the attempts are used up even valid codes are refused
+			testAccStepValidateCode(t, "test", code, false, true),
+			testAccStepValidateCode(t, "unlimited", "1234567", false, false),
+			testAccStepValidateCode(t, "unlimited", "12345678", false, false),
+			testAccStepValidateCode(t, "unlimited", "123456789", false, false),
+			testAccStepValidateCode(t, "unlimited", code, true, false),
+		},
+	})
+}
+
+func TestBackend_batchValidateCodes(t *testing.T) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+	b, err := Factory(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, _ := createKey()
+	code, _ := generateCode(key, 30, otplib.DigitsSix, otplib.AlgorithmSHA1)
+
+	keyData := map[string]interface{}{
+		"issuer":       "Vault",
+		"account_name": "Test",
+		"key":          key,
+		"generate":     false,
+	}
+
+	logicaltest.Test(t, logicaltest.TestCase{
+		Backend: b,
+		Steps: []logicaltest.TestStep{
+			testAccStepCreateKey(t, "first", keyData, false),
+			testAccStepCreateKey(t, "second", keyData, false),
+			testAccStepValidateBatch(t, []interface{}{
+				map[string]interface{}{"name": "first", "code": code},
+				map[string]interface{}{"name": "second", "code": "12345678"},
+				map[string]interface{}{"name": "missing", "code": code},
+				map[string]interface{}{"name": "second"},
+			}, []BatchResponseItem{
+				{Valid: true},
+				{Valid: false},
+				{Error: "unknown key: missing"},
+				{Error: "the code value is required"},
+			}),
+			// Codes validated in a batch cannot be used again
+			testAccStepValidateBatch(t, []interface{}{
+				map[string]interface{}{"name": "first", "code": code},
+				map[string]interface{}{"name": "second", "code": code},
+			}, []BatchResponseItem{
+				{Error: "code already used; wait until the next time period"},
+				{Valid: true},
+			}),
+			logicaltest.TestStep{
+				Operation: logical.UpdateOperation,
+				Path:      "code",
+				ErrorOk:   true,
+				Check: func(resp *logical.Response) error {
+					if resp == nil || !resp.IsError() {
+						return fmt.Errorf("expected error, got %#v", resp)
+					}
+					return nil
+				},
+			},
+		},
+	})
+}
+
+func testAccStepValidateBatch(t *testing.T, batchInput []interface{}, expected []BatchResponseItem) logicaltest.TestStep {
+	return logicaltest.TestStep{
+		Operation: logical.UpdateOperation,
+		Path:      "code",
+		Data: map[string]interface{}{
+			"batch_input": batchInput,
+		},
+		Check: func(resp *logical.Response) error {
+			if resp == nil {
+				return fmt.Errorf("bad: %#v", resp)
+			}
+
+			results := resp.Data["batch_results"].([]BatchResponseItem)
+			if !reflect.DeepEqual(results, expected) {
+				return fmt.Errorf("expected %#v, got %#v", expected, results)
+			}
+			return nil
+		},
+	}
+}
+
 func testAccStepCreateKey(t *testing.T, name string, keyData map[string]interface{}, expectFail bool) logicaltest.TestStep {
 	return logicaltest.TestStep{
 		Operation: logical.UpdateOperation,
@@ -1058,6 +1240,7 @@ func testAccStepReadKey(t *testing.T, name string, expected map[string]interface
 				Period      uint          `mapstructure:"period"`
 				Algorithm   string        `mapstructure:"algorithm"`
 				Digits      otplib.Digits `mapstructure:"digits"`
+				Skew        uint          `mapstructure:"skew"`
 			}
 
 			if err := mapstructure.Decode(resp.Data, &d); err != nil {
@@ -1087,6 +1270,8 @@ func testAccStepReadKey(t *testing.T, name string, expected map[string]interface
 				return fmt.Errorf("algorithm should equal: %s", expected["algorithm"])
 			case d.Digits != expected["digits"]:
 				return fmt.Errorf("digits should equal: %d", expected["digits"])
+			case expected["skew"] != nil && d.Skew != expected["skew"]:
+				return fmt.Errorf("skew should equal: %d", expected["skew"])
 			}
 			return nil
 		},
@@ -5,12 +5,35 @@ import (
 	"time"
 
 	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/helper/errutil"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
+	"github.com/mitchellh/mapstructure"
 	otplib "github.com/pquerna/otp"
 	totplib "github.com/pquerna/otp/totp"
 )
 
+func pathBatchCode(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "code/?$",
+		Fields: map[string]*framework.FieldSchema{
+			"batch_input": &framework.FieldSchema{
+				Type: framework.TypeSlice,
+				Description: `List of items to be validated in a single batch.
+Each item is a map with the "name" of a key and the "code" to validate with
+it.`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathValidateBatch,
+		},
+
+		HelpSynopsis:    pathBatchCodeHelpSyn,
+		HelpDescription: pathBatchCodeHelpDesc,
+	}
+}
+
 func pathCode(b *backend) *framework.Path {
 	return &framework.Path{
 		Pattern: "code/" + framework.GenericNameRegex("name"),
@@ -76,20 +99,114 @@ func (b *backend) pathValidateCode(
 		return logical.ErrorResponse("the code value is required"), nil
 	}
 
+	valid, err := b.validateCode(req.Storage, name, code)
+	if err != nil {
+		switch err.(type) {
+		case errutil.UserError:
+			return logical.ErrorResponse(err.Error()), nil
+		default:
+			return nil, err
+		}
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"valid": valid,
+		},
+	}, nil
+}
+
+// BatchRequestItem is a code to validate in a batch
+type BatchRequestItem struct {
+	// Name of the key to validate the code with
+	Name string `json:"name" structs:"name" mapstructure:"name"`
+
+	// Code to validate
+	Code string `json:"code" structs:"code" mapstructure:"code"`
+}
+
+// BatchResponseItem is the result of validating a code in a batch
+type BatchResponseItem struct {
+	// Valid is whether the code in the corresponding batch request item is
+	// valid
+	Valid bool `json:"valid" structs:"valid" mapstructure:"valid"`
+
+	// Error, if set represents a failure encountered while validating the
+	// code in the corresponding batch request item
+	Error string `json:"error,omitempty" structs:"error" mapstructure:"error"`
+}
+
+func (b *backend) pathValidateBatch(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	batchInputRaw := data.Raw["batch_input"]
+	if batchInputRaw == nil {
+		return logical.ErrorResponse("missing batch input to process"), logical.ErrInvalidRequest
+	}
+
+	var batchInputItems []BatchRequestItem
+	if err := mapstructure.Decode(batchInputRaw, &batchInputItems); err != nil {
+		return nil, fmt.Errorf("failed to parse batch input: %v", err)
+	}
+	if len(batchInputItems) == 0 {
+		return logical.ErrorResponse("missing batch input to process"), logical.ErrInvalidRequest
+	}
+
+	batchResponseItems := make([]BatchResponseItem, len(batchInputItems))
+	for i, item := range batchInputItems {
+		if item.Name == "" {
+			batchResponseItems[i].Error = "the name value is required"
+			continue
+		}
+		if item.Code == "" {
+			batchResponseItems[i].Error = "the code value is required"
+			continue
+		}
+
+		valid, err := b.validateCode(req.Storage, item.Name, item.Code)
+		if err != nil {
+			switch err.(type) {
+			case errutil.UserError:
+				batchResponseItems[i].Error = err.Error()
+				continue
+			default:
+				return nil, err
+			}
+		}
+		batchResponseItems[i].Valid = valid
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"batch_results": batchResponseItems,
+		},
+	}, nil
+}
+
+// validateCode validates the code with the named key. Codes can only be used
+// once, and keys that limit validation attempts stop validating codes once
+// too many invalid ones were given within the time a code is valid for.
+// Problems with the request are returned as errutil.UserError.
+func (b *backend) validateCode(s logical.Storage, name, code string) (bool, error) {
 	// Get the key's stored values
-	key, err := b.Key(req.Storage, name)
+	key, err := b.Key(s, name)
 	if err != nil {
-		return nil, err
+		return false, err
 	}
 	if key == nil {
-		return logical.ErrorResponse(fmt.Sprintf("unknown key: %s", name)), nil
+		return false, errutil.UserError{Err: fmt.Sprintf("unknown key: %s", name)}
 	}
 
 	usedName := fmt.Sprintf("%s_%s", name, code)
 
 	_, ok := b.usedCodes.Get(usedName)
 	if ok {
-		return logical.ErrorResponse("code already used; wait until the next time period"), nil
+		return false, errutil.UserError{Err: "code already used; wait until the next time period"}
+	}
+
+	if key.MaxValidationAttempts > 0 {
+		if failures, ok := b.failedValidations.Get(name); ok && failures.(int) >= key.MaxValidationAttempts {
+			return false, errutil.UserError{Err: "maximum validation attempts exceeded; wait until the next time period"}
+		}
 	}
 
 	valid, err := totplib.ValidateCustom(code, key.Key, time.Now(), totplib.ValidateOpts{
@@ -99,24 +216,30 @@ func (b *backend) pathValidateCode(
 		Algorithm: key.Algorithm,
 	})
 	if err != nil && err != otplib.ErrValidateInputInvalidLength {
-		return logical.ErrorResponse("an error occured while validating the code"), err
+		return false, errwrap.Wrapf("an error occured while validating the code: {{err}}", err)
 	}
 
-	// Take the key skew, add two for behind and in front, and multiple that by
-	// the period to cover the full possibility of the validity of the key
-	err = b.usedCodes.Add(usedName, nil, time.Duration(
-		int64(time.Second)*
-			int64(key.Period)*
-			int64((2+key.Skew))))
+	// Take the key skew on both sides, add two, and multiply that by the
+	// period to cover the full possibility of the validity of the key
+	validity := time.Duration(
+		int64(time.Second) *
+			int64(key.Period) *
+			int64((2 + 2*key.Skew)))
+
+	err = b.usedCodes.Add(usedName, nil, validity)
 	if err != nil {
-		return nil, errwrap.Wrapf("error adding code to used cache: {{err}}", err)
+		return false, errwrap.Wrapf("error adding code to used cache: {{err}}", err)
 	}
 
-	return &logical.Response{
-		Data: map[string]interface{}{
-			"valid": valid,
-		},
-	}, nil
+	if key.MaxValidationAttempts > 0 {
+		if valid {
+			b.failedValidations.Delete(name)
+		} else if err := b.failedValidations.Add(name, 1, validity); err != nil {
+			b.failedValidations.IncrementInt(name, 1)
+		}
+	}
+
+	return valid, nil
 }
 
 const pathCodeHelpSyn = `
@@ -126,3 +249,13 @@ const pathCodeHelpDesc = `
 This path generates and validates time-based one-time use passwords for a certain key. 
 
 `
+
+const pathBatchCodeHelpSyn = `
+Validate a batch of time-based one-time use passwords.
+`
+const pathBatchCodeHelpDesc = `
+This path validates the codes in "batch_input", each with the named key, and
+returns whether each of them is valid in "batch_results". Codes are validated
+as they would be by the "code/<name>" path; the errors that path would return
+are reported per item instead.
+`
@@ -16,6 +16,10 @@ import (
 	totplib "github.com/pquerna/otp/totp"
 )
 
+// maxSkew is the largest number of periods on either side of the current one
+// that a key can accept tokens from
+const maxSkew = 10
+
 func pathListKeys(b *backend) *framework.Path {
 	return &framework.Path{
 		Pattern: "keys/?$",
@@ -53,6 +57,7 @@ func pathKeys(b *backend) *framework.Path {
 			"key_size": {
 				Type:        framework.TypeInt,
 				Default:     20,
+				Min:         framework.Bound(1),
 				Description: "Determines the size in bytes of the generated key. Only used if generate is true.",
 			},
 
@@ -78,26 +83,38 @@ func pathKeys(b *backend) *framework.Path {
 			},
 
 			"algorithm": {
-				Type:        framework.TypeString,
-				Default:     "SHA1",
-				Description: `The hashing algorithm used to generate the TOTP token. Options include SHA1, SHA256 and SHA512.`,
+				Type:          framework.TypeString,
+				Default:       "SHA1",
+				AllowedValues: []interface{}{"SHA1", "SHA256", "SHA512"},
+				Description:   `The hashing algorithm used to generate the TOTP token. Options include SHA1, SHA256 and SHA512.`,
 			},
 
 			"digits": {
-				Type:        framework.TypeInt,
-				Default:     6,
-				Description: `The number of digits in the generated TOTP token. This value can either be 6 or 8.`,
+				Type:          framework.TypeInt,
+				Default:       6,
+				AllowedValues: []interface{}{6, 8},
+				Description:   `The number of digits in the generated TOTP token. This value can either be 6 or 8.`,
 			},
 
 			"skew": {
 				Type:        framework.TypeInt,
 				Default:     1,
-				Description: `The number of delay periods that are allowed when validating a TOTP token. This value can either be 0 or 1. Only used if generate is true.`,
+				Min:         framework.Bound(0),
+				Max:         framework.Bound(maxSkew),
+				Description: `The number of periods before and after the current one whose TOTP tokens are also accepted when validating a token. This value can be between 0 and 10.`,
+			},
+
+			"max_validation_attempts": {
+				Type:        framework.TypeInt,
+				Default:     5,
+				Min:         framework.Bound(0),
+				Description: `The number of invalid TOTP tokens that can be given for the key before validation is refused until the tokens expire. If this value is 0, validation attempts are not limited.`,
 			},
 
 			"qr_size": {
 				Type:        framework.TypeInt,
 				Default:     200,
+				Min:         framework.Bound(0),
 				Description: `The pixel size of the generated square QR code. Only used if generate is true and exported is true. If this value is 0, a QR code will not be returned.`,
 			},
 
@@ -166,6 +183,9 @@ func (b *backend) pathKeyRead(
 			"period":       key.Period,
 			"algorithm":    algorithm,
 			"digits":       key.Digits,
+			"skew":         key.Skew,
+
+			"max_validation_attempts": key.MaxValidationAttempts,
 		},
 	}, nil
 }
@@ -192,6 +212,7 @@ func (b *backend) pathKeyCreate(
 	algorithm := data.Get("algorithm").(string)
 	digits := data.Get("digits").(int)
 	skew := data.Get("skew").(int)
+	maxValidationAttempts := data.Get("max_validation_attempts").(int)
 	qrSize := data.Get("qr_size").(int)
 	keySize := data.Get("key_size").(int)
 	inputURL := data.Get("url").(string)
@@ -293,22 +314,6 @@ func (b *backend) pathKeyCreate(
 		return logical.ErrorResponse("the period value must be greater than zero"), nil
 	}
 
-	switch skew {
-	case 0:
-	case 1:
-	default:
-		return logical.ErrorResponse("the skew value must be 0 or 1"), nil
-	}
-
-	// QR size can be zero but it shouldn't be negative
-	if qrSize < 0 {
-		return logical.ErrorResponse("the qr_size value must be greater than or equal to zero"), nil
-	}
-
-	if keySize <= 0 {
-		return logical.ErrorResponse("the key_size value must be greater than zero"), nil
-	}
-
 	// Period, Skew and Key Size need to be unsigned ints
 	uintPeriod := uint(period)
 	uintSkew := uint(skew)
@@ -393,6 +398,8 @@ func (b *backend) pathKeyCreate(
 		Algorithm:   keyAlgorithm,
 		Digits:      keyDigits,
 		Skew:        uintSkew,
+
+		MaxValidationAttempts: maxValidationAttempts,
 	})
 	if err != nil {
 		return nil, err
@@ -412,6 +419,8 @@ type keyEntry struct {
 	Algorithm   otplib.Algorithm `json:"algorithm" mapstructure:"algorithm" structs:"algorithm"`
 	Digits      otplib.Digits    `json:"digits" mapstructure:"digits" structs:"digits"`
 	Skew        uint             `json:"skew" mapstructure:"skew" structs:"skew"`
+
+	MaxValidationAttempts int `json:"max_validation_attempts" mapstructure:"max_validation_attempts" structs:"max_validation_attempts"`
 }
 
 const pathKeyHelpSyn = `
@@ -2,7 +2,10 @@ package transit
 
 import (
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/vault/helper/keysutil"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
@@ -25,12 +28,16 @@ func Backend(conf *logical.BackendConfig) *backend {
 			b.pathConfig(),
 			b.pathRotate(),
 			b.pathRewrap(),
+			b.pathRewrapAll(),
+			b.pathRewrapAllStatus(),
 			b.pathKeys(),
+			b.pathKeysJWKS(),
 			b.pathListKeys(),
 			b.pathExportKeys(),
 			b.pathEncrypt(),
 			b.pathDecrypt(),
 			b.pathDatakey(),
+			b.pathDerive(),
 			b.pathRandom(),
 			b.pathHash(),
 			b.pathHMAC(),
@@ -38,12 +45,16 @@ func Backend(conf *logical.BackendConfig) *backend {
 			b.pathVerify(),
 		},
 
-		Secrets:     []*framework.Secret{},
-		Invalidate:  b.invalidate,
-		BackendType: logical.TypeLogical,
+		Secrets:      []*framework.Secret{},
+		Invalidate:   b.invalidate,
+		PeriodicFunc: b.periodicFunc,
+		Clean:        b.stopRewrapJobs,
+		BackendType:  logical.TypeLogical,
 	}
 
 	b.lm = keysutil.NewLockManager(conf.System.CachingDisabled())
+	b.rewrapJobs = make(map[string]*rewrapJob)
+	b.rewrapStopCh = make(chan struct{})
 
 	return &b
 }
@@ -51,6 +62,12 @@ func Backend(conf *logical.BackendConfig) *backend {
 type backend struct {
 	*framework.Backend
 	lm *keysutil.LockManager
+
+	// rewrapJobs are the rewrap jobs running on this node
+	rewrapJobs     map[string]*rewrapJob
+	rewrapJobsLock sync.RWMutex
+	rewrapStopCh   chan struct{}
+	rewrapStopOnce sync.Once
 }
 
 func (b *backend) invalidate(key string) {
@@ -63,3 +80,66 @@ func (b *backend) invalidate(key string) {
 		b.lm.InvalidatePolicy(name)
 	}
 }
+
+// periodicFunc is invoked once a minute by the RollbackManager. It rotates
+// any keys whose rotation period has elapsed, discards old rewrap jobs and
+// resumes interrupted ones.
+func (b *backend) periodicFunc(req *logical.Request) error {
+	var retErr error
+	if err := b.tidyRewrapJobs(req.Storage, time.Now()); err != nil {
+		b.Logger().Error("transit: failed to tidy rewrap jobs", "error", err)
+		retErr = multierror.Append(retErr, err)
+	}
+
+	names, err := req.Storage.List("policy/")
+	if err != nil {
+		return multierror.Append(retErr, err)
+	}
+
+	for _, name := range names {
+		if err := b.rotateIfNeeded(req.Storage, name); err != nil {
+			b.Logger().Error("transit: failed to automatically rotate key", "name", name, "error", err)
+			retErr = multierror.Append(retErr, err)
+		}
+	}
+	return retErr
+}
+
+func (b *backend) rotateIfNeeded(storage logical.Storage, name string) error {
+	// Check under a shared lock first so that keys without a rotation
+	// period don't contend with regular operations
+	p, lock, err := b.lm.GetPolicyShared(storage, name)
+	if err != nil {
+		if lock != nil {
+			lock.RUnlock()
+		}
+		return err
+	}
+	needsRotation := p != nil && p.NeedsRotation(time.Now())
+	if lock != nil {
+		lock.RUnlock()
+	}
+	if !needsRotation {
+		return nil
+	}
+
+	p, lock, err = b.lm.GetPolicyExclusive(storage, name)
+	if lock != nil {
+		defer lock.Unlock()
+	}
+	if err != nil {
+		return err
+	}
+	// Recheck now that we hold the lock in case another caller rotated
+	if p == nil || !p.NeedsRotation(time.Now()) {
+		return nil
+	}
+
+	if err := p.Rotate(storage); err != nil {
+		return err
+	}
+	if p.TrimOnRotation {
+		return p.Trim(storage)
+	}
+	return nil
+}
@@ -96,6 +96,50 @@ func TestBackend_datakey(t *testing.T) {
 	})
 }
 
+func TestBackend_datakeyHMAC(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend(&logical.BackendConfig{
+		StorageView: storage,
+		System:      logical.TestSystemView(),
+	})
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/test",
+	}
+	if _, err := b.HandleRequest(req); err != nil {
+		t.Fatal(err)
+	}
+
+	req.Path = "datakey/plaintext/test"
+	req.Data = map[string]interface{}{
+		"hmac": true,
+	}
+	resp, err := b.HandleRequest(req)
+	if err != nil || resp.IsError() {
+		t.Fatalf("bad: err: %v resp: %#v", err, resp)
+	}
+	hmac, ok := resp.Data["hmac"].(string)
+	if !ok || !strings.HasPrefix(hmac, "vault:v1:") {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// The HMAC verifies against the plaintext data key
+	req.Path = "verify/test"
+	req.Data = map[string]interface{}{
+		"input": resp.Data["plaintext"],
+		"hmac":  hmac,
+	}
+	resp, err = b.HandleRequest(req)
+	if err != nil || resp.IsError() {
+		t.Fatalf("bad: err: %v resp: %#v", err, resp)
+	}
+	if !resp.Data["valid"].(bool) {
+		t.Fatal("expected data key HMAC to verify")
+	}
+}
+
 func TestBackend_rotation(t *testing.T) {
 	decryptData := make(map[string]interface{})
 	encryptHistory := make(map[int]map[string]interface{})
@@ -699,6 +743,57 @@ func TestDerivedKeyUpgrade(t *testing.T) {
 func TestConvergentEncryption(t *testing.T) {
 	testConvergentEncryptionCommon(t, 0)
 	testConvergentEncryptionCommon(t, 2)
+	testConvergentEncryptionCommon(t, 3)
+}
+
+func TestConvergentEncryption_nonceNotHMAC(t *testing.T) {
+	b, storage := createBackendWithStorage(t)
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/testkey",
+		Data: map[string]interface{}{
+			"derived":               true,
+			"convergent_encryption": true,
+		},
+	}
+	if resp, err := b.HandleRequest(req); err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+	}
+
+	context := []byte("context")
+	plaintext := []byte("zip zap")
+	req.Path = "encrypt/testkey"
+	req.Data = map[string]interface{}{
+		"plaintext": base64.StdEncoding.EncodeToString(plaintext),
+		"context":   base64.StdEncoding.EncodeToString(context),
+	}
+	resp, err := b.HandleRequest(req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(resp.Data["ciphertext"].(string), "vault:v1:"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The nonce can't be computed by callers of the HMAC endpoint
+	req.Path = "hmac/testkey"
+	req.Data = map[string]interface{}{
+		"input": base64.StdEncoding.EncodeToString(append(context, plaintext...)),
+	}
+	resp, err = b.HandleRequest(req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+	}
+	sum, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(resp.Data["hmac"].(string), "vault:v1:"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reflect.DeepEqual(ciphertext[:12], sum[:12]) {
+		t.Fatal("the nonce matches the HMAC of the context and plaintext")
+	}
 }
 
 func testConvergentEncryptionCommon(t *testing.T, ver int) {
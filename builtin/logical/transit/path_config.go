@@ -2,11 +2,17 @@ package transit
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
 )
 
+// minRotationPeriod is the shortest automatic rotation period allowed. Keys
+// are checked for rotation about once a minute, and short periods would
+// quickly accumulate large numbers of key versions.
+const minRotationPeriod = time.Hour
+
 func (b *backend) pathConfig() *framework.Path {
 	return &framework.Path{
 		Pattern: "keys/" + framework.GenericNameRegex("name") + "/config",
@@ -35,6 +41,20 @@ the latest version of the key is allowed.`,
 				Type:        framework.TypeBool,
 				Description: "Whether to allow deletion of the key",
 			},
+
+			"rotation_period": &framework.FieldSchema{
+				Type: framework.TypeDurationSecond,
+				Description: `If set, the key is automatically rotated once
+its latest version is older than this period. Set
+to zero to disable automatic rotation.`,
+			},
+
+			"trim_on_rotation": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `If set, automatic rotation also permanently
+deletes key versions below the minimum decryption
+version.`,
+			},
 		},
 
 		Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -86,6 +106,10 @@ func (b *backend) pathConfigWrite(
 				return logical.ErrorResponse(
 					fmt.Sprintf("cannot set min decryption version of %d, latest key version is %d", minDecryptionVersion, p.LatestVersion)), nil
 			}
+			if minDecryptionVersion < p.MinAvailableVersion {
+				return logical.ErrorResponse(
+					fmt.Sprintf("cannot set min decryption version of %d, versions below %d have been trimmed", minDecryptionVersion, p.MinAvailableVersion)), nil
+			}
 			p.MinDecryptionVersion = minDecryptionVersion
 			persistNeeded = true
 		}
@@ -126,6 +150,31 @@ func (b *backend) pathConfigWrite(
 		}
 	}
 
+	rotationPeriodRaw, ok := d.GetOk("rotation_period")
+	if ok {
+		rotationPeriod := time.Duration(rotationPeriodRaw.(int)) * time.Second
+		if rotationPeriod < 0 {
+			return logical.ErrorResponse("rotation period cannot be negative"), nil
+		}
+		if rotationPeriod > 0 && rotationPeriod < minRotationPeriod {
+			return logical.ErrorResponse(
+				fmt.Sprintf("rotation period must be at least %s", minRotationPeriod)), nil
+		}
+		if rotationPeriod != p.RotationPeriod {
+			p.RotationPeriod = rotationPeriod
+			persistNeeded = true
+		}
+	}
+
+	trimOnRotationRaw, ok := d.GetOk("trim_on_rotation")
+	if ok {
+		trimOnRotation := trimOnRotationRaw.(bool)
+		if trimOnRotation != p.TrimOnRotation {
+			p.TrimOnRotation = trimOnRotation
+			persistNeeded = true
+		}
+	}
+
 	// Add this as a guard here before persisting since we now require the min
 	// decryption version to start at 1; even if it's not explicitly set here,
 	// force the upgrade
@@ -150,5 +199,7 @@ const pathConfigHelpSyn = `Configure a named encryption key`
 const pathConfigHelpDesc = `
 This path is used to configure the named key. Currently, this
 supports adjusting the minimum version of the key allowed to
-be used for decryption via the min_decryption_version paramter.
+be used for decryption via the min_decryption_version paramter,
+and scheduling automatic rotation of the key via the
+rotation_period parameter.
 `
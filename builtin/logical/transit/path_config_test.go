@@ -4,6 +4,7 @@ import (
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/vault/logical"
 )
@@ -221,3 +222,132 @@ func TestTransit_ConfigSettings(t *testing.T) {
 	testHMAC(3, true)
 	testHMAC(2, false)
 }
+
+func TestTransit_AutoRotate(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	b := Backend(&logical.BackendConfig{
+		StorageView: storage,
+		System:      logical.TestSystemView(),
+	})
+
+	doReq := func(op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		resp, err := b.HandleRequest(&logical.Request{
+			Storage:   storage,
+			Operation: op,
+			Path:      path,
+			Data:      data,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: path %s err %v resp %#v", path, err, resp)
+		}
+		return resp
+	}
+
+	// ageLatestKey pretends the latest version was created long ago
+	ageLatestKey := func() {
+		p, lock, err := b.lm.GetPolicyExclusive(storage, "foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		entry := p.Keys[p.LatestVersion]
+		entry.CreationTime = entry.CreationTime.Add(-2 * time.Hour)
+		p.Keys[p.LatestVersion] = entry
+		err = p.Persist(storage)
+		lock.Unlock()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	latestVersion := func() int {
+		return doReq(logical.ReadOperation, "keys/foo", nil).Data["latest_version"].(int)
+	}
+
+	doReq(logical.UpdateOperation, "keys/foo", nil)
+
+	resp, _ := b.HandleRequest(&logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/foo/config",
+		Data:      map[string]interface{}{"rotation_period": "10m"},
+	})
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected error for too short rotation period, got %#v", resp)
+	}
+
+	// Without a rotation period nothing happens
+	ageLatestKey()
+	if err := b.periodicFunc(&logical.Request{Storage: storage}); err != nil {
+		t.Fatal(err)
+	}
+	if v := latestVersion(); v != 1 {
+		t.Fatalf("expected no rotation, latest version is %d", v)
+	}
+
+	doReq(logical.UpdateOperation, "keys/foo/config", map[string]interface{}{
+		"rotation_period": "1h",
+	})
+	resp = doReq(logical.ReadOperation, "keys/foo", nil)
+	if resp.Data["rotation_period"].(int64) != 3600 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	if err := b.periodicFunc(&logical.Request{Storage: storage}); err != nil {
+		t.Fatal(err)
+	}
+	if v := latestVersion(); v != 2 {
+		t.Fatalf("expected rotation, latest version is %d", v)
+	}
+
+	// The new version is fresh, so it is left alone
+	if err := b.periodicFunc(&logical.Request{Storage: storage}); err != nil {
+		t.Fatal(err)
+	}
+	if v := latestVersion(); v != 2 {
+		t.Fatalf("expected no rotation, latest version is %d", v)
+	}
+
+	// With trimming enabled, versions below the minimum decryption version
+	// are removed from the archive as part of the rotation
+	doReq(logical.UpdateOperation, "keys/foo/rotate", nil)
+	doReq(logical.UpdateOperation, "keys/foo/config", map[string]interface{}{
+		"min_decryption_version": 3,
+		"trim_on_rotation":       true,
+	})
+	ageLatestKey()
+	if err := b.periodicFunc(&logical.Request{Storage: storage}); err != nil {
+		t.Fatal(err)
+	}
+	resp = doReq(logical.ReadOperation, "keys/foo", nil)
+	if resp.Data["latest_version"].(int) != 4 || resp.Data["min_available_version"].(int) != 3 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	p, lock, err := b.lm.GetPolicyShared(storage, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive, err := p.LoadArchive(storage)
+	lock.RUnlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i < 3; i++ {
+		if archive.Keys[i].Key != nil || archive.Keys[i].HMACKey != nil {
+			t.Fatalf("expected version %d to be trimmed", i)
+		}
+	}
+	if archive.Keys[3].Key == nil {
+		t.Fatal("expected version 3 to be retained")
+	}
+
+	resp, _ = b.HandleRequest(&logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/foo/config",
+		Data:      map[string]interface{}{"min_decryption_version": 1},
+	})
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected error lowering min decryption version below trimmed versions, got %#v", resp)
+	}
+}
@@ -1,7 +1,9 @@
 package transit
 
 import (
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
 	"fmt"
 
@@ -49,6 +51,13 @@ encryption of the data key. Must be 0 (for latest)
 or a value greater than or equal to the
 min_encryption_version configured on the key.`,
 			},
+
+			"hmac": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `If set, an HMAC of the data key computed with
+the named key is also returned, so that a decrypted
+data key can later be checked with the verify endpoint.`,
+			},
 		},
 
 		Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -109,17 +118,11 @@ func (b *backend) pathDatakeyWrite(
 		return logical.ErrorResponse("encryption key not found"), logical.ErrInvalidRequest
 	}
 
-	newKey := make([]byte, 32)
-	bits := d.Get("bits").(int)
-	switch bits {
-	case 512:
-		newKey = make([]byte, 64)
-	case 256:
-	case 128:
-		newKey = make([]byte, 16)
-	default:
+	length, ok := dataKeyLength(d.Get("bits").(int))
+	if !ok {
 		return logical.ErrorResponse("invalid bit length"), logical.ErrInvalidRequest
 	}
+	newKey := make([]byte, length)
 	_, err = rand.Read(newKey)
 	if err != nil {
 		return nil, err
@@ -152,9 +155,33 @@ func (b *backend) pathDatakeyWrite(
 		resp.Data["plaintext"] = base64.StdEncoding.EncodeToString(newKey)
 	}
 
+	if d.Get("hmac").(bool) {
+		if ver == 0 {
+			ver = p.LatestVersion
+		}
+		hmacKey, err := p.HMACKey(ver)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		hf := hmac.New(sha256.New, hmacKey)
+		hf.Write(newKey)
+		resp.Data["hmac"] = fmt.Sprintf("vault:v%d:%s", ver, base64.StdEncoding.EncodeToString(hf.Sum(nil)))
+	}
+
 	return resp, nil
 }
 
+// dataKeyLength returns the length in bytes of a data key with the given
+// number of bits, and whether that size is supported
+func dataKeyLength(bits int) (int, bool) {
+	switch bits {
+	case 128, 256, 512:
+		return bits / 8, true
+	default:
+		return 0, false
+	}
+}
+
 const pathDatakeyHelpSyn = `Generate a data key`
 
 const pathDatakeyHelpDesc = `
@@ -165,4 +192,8 @@ or 512 bits can be specified; if not specified, the default
 is 256 bits. Call with the the "wrapped" path to prevent the
 (base64-encoded) plaintext key from being returned along with
 the encrypted key, the "plaintext" path returns both.
+
+If "hmac" is set, a sha2-256 HMAC of the plaintext key made
+with the named key is returned as well. It can be checked
+against the data key with the "verify" endpoint.
 `
@@ -0,0 +1,116 @@
+package transit
+
+import (
+	"encoding/base64"
+
+	"github.com/hashicorp/vault/helper/errutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+func (b *backend) pathDerive() *framework.Path {
+	return &framework.Path{
+		Pattern: "derive/" + framework.GenericNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "The backend key to derive the data key from",
+			},
+
+			"context": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Base64-encoded context the data key is derived for. Required.",
+			},
+
+			"bits": &framework.FieldSchema{
+				Type: framework.TypeInt,
+				Description: `Number of bits for the key; currently 128, 256,
+and 512 bits are supported. Defaults to 256.`,
+				Default: 256,
+			},
+
+			"key_version": &framework.FieldSchema{
+				Type: framework.TypeInt,
+				Description: `The version of the Vault key to derive from.
+Must be 0 (for latest) or a value greater than or
+equal to the min_decryption_version and
+min_encryption_version configured on the key.`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathDeriveWrite,
+		},
+
+		HelpSynopsis:    pathDeriveHelpSyn,
+		HelpDescription: pathDeriveHelpDesc,
+	}
+}
+
+func (b *backend) pathDeriveWrite(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+	ver := d.Get("key_version").(int)
+
+	context, err := base64.StdEncoding.DecodeString(d.Get("context").(string))
+	if err != nil {
+		return logical.ErrorResponse("failed to base64-decode context"), logical.ErrInvalidRequest
+	}
+
+	length, ok := dataKeyLength(d.Get("bits").(int))
+	if !ok {
+		return logical.ErrorResponse("invalid bit length"), logical.ErrInvalidRequest
+	}
+
+	// Get the policy
+	p, lock, err := b.lm.GetPolicyShared(req.Storage, name)
+	if lock != nil {
+		defer lock.RUnlock()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return logical.ErrorResponse("encryption key not found"), logical.ErrInvalidRequest
+	}
+
+	switch {
+	case ver == 0:
+		ver = p.LatestVersion
+	case ver > p.LatestVersion:
+		return logical.ErrorResponse("cannot derive key: version does not exist"), logical.ErrInvalidRequest
+	case p.MinDecryptionVersion > 0 && ver < p.MinDecryptionVersion:
+		return logical.ErrorResponse("cannot derive key: version is too old (disallowed by policy)"), logical.ErrInvalidRequest
+	case p.MinEncryptionVersion > 0 && ver < p.MinEncryptionVersion:
+		return logical.ErrorResponse("cannot derive key: version is too old (disallowed by policy)"), logical.ErrInvalidRequest
+	}
+
+	derived, err := p.DeriveDataKey(ver, context, length)
+	if err != nil {
+		switch err.(type) {
+		case errutil.UserError:
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		default:
+			return nil, err
+		}
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"plaintext":   base64.StdEncoding.EncodeToString(derived),
+			"key_version": ver,
+		},
+	}, nil
+}
+
+const pathDeriveHelpSyn = `Derive a data key from a named key and context`
+
+const pathDeriveHelpDesc = `
+This path deterministically derives a data key from the named
+key and the given context: the same key version and context
+always produce the same data key, so it does not need to be
+stored. 128, 256, or 512 bits can be specified; if not
+specified, the default is 256 bits. Rotating the named key
+changes the derived keys for new requests; older versions can
+be selected with "key_version".
+`
@@ -0,0 +1,130 @@
+package transit
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestTransit_Derive(t *testing.T) {
+	var b *backend
+	sysView := logical.TestSystemView()
+	storage := &logical.InmemStorage{}
+
+	b = Backend(&logical.BackendConfig{
+		StorageView: storage,
+		System:      sysView,
+	})
+
+	// First create a key
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/foo",
+	}
+	_, err := b.HandleRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	derive := func(context string, data map[string]interface{}) (*logical.Response, error) {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "derive/foo",
+			Data: map[string]interface{}{
+				"context": base64.StdEncoding.EncodeToString([]byte(context)),
+			},
+		}
+		for k, v := range data {
+			req.Data[k] = v
+		}
+		return b.HandleRequest(req)
+	}
+
+	resp, err := derive("alpha", nil)
+	if err != nil || resp.IsError() {
+		t.Fatalf("bad: err: %v resp: %#v", err, resp)
+	}
+	alpha := resp.Data["plaintext"].(string)
+	key, err := base64.StdEncoding.DecodeString(alpha)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(key) != 32 {
+		t.Fatalf("expected 256 bit key, got %d bytes", len(key))
+	}
+	if resp.Data["key_version"].(int) != 1 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// The same context derives the same key
+	resp, err = derive("alpha", nil)
+	if err != nil || resp.IsError() {
+		t.Fatalf("bad: err: %v resp: %#v", err, resp)
+	}
+	if resp.Data["plaintext"].(string) != alpha {
+		t.Fatal("expected derivation to be deterministic")
+	}
+
+	// A different context derives a different key
+	resp, err = derive("beta", nil)
+	if err != nil || resp.IsError() {
+		t.Fatalf("bad: err: %v resp: %#v", err, resp)
+	}
+	if resp.Data["plaintext"].(string) == alpha {
+		t.Fatal("expected different contexts to derive different keys")
+	}
+
+	resp, err = derive("alpha", map[string]interface{}{"bits": 512})
+	if err != nil || resp.IsError() {
+		t.Fatalf("bad: err: %v resp: %#v", err, resp)
+	}
+	key, _ = base64.StdEncoding.DecodeString(resp.Data["plaintext"].(string))
+	if len(key) != 64 {
+		t.Fatalf("expected 512 bit key, got %d bytes", len(key))
+	}
+
+	// Rotating changes the key derived for the latest version but the old
+	// one can still be derived
+	req.Path = "keys/foo/rotate"
+	if _, err := b.HandleRequest(req); err != nil {
+		t.Fatal(err)
+	}
+	resp, err = derive("alpha", nil)
+	if err != nil || resp.IsError() {
+		t.Fatalf("bad: err: %v resp: %#v", err, resp)
+	}
+	if resp.Data["plaintext"].(string) == alpha || resp.Data["key_version"].(int) != 2 {
+		t.Fatalf("expected key from new version, got %#v", resp.Data)
+	}
+	resp, err = derive("alpha", map[string]interface{}{"key_version": 1})
+	if err != nil || resp.IsError() {
+		t.Fatalf("bad: err: %v resp: %#v", err, resp)
+	}
+	if resp.Data["plaintext"].(string) != alpha {
+		t.Fatal("expected key from version 1 to be unchanged")
+	}
+
+	// Failure cases
+	if resp, err = derive("", nil); err == nil || !resp.IsError() {
+		t.Fatal("expected error when context is missing")
+	}
+	if resp, err = derive("alpha", map[string]interface{}{"bits": 100}); err == nil || !resp.IsError() {
+		t.Fatal("expected error with invalid bit length")
+	}
+	if resp, err = derive("alpha", map[string]interface{}{"key_version": 3}); err == nil || !resp.IsError() {
+		t.Fatal("expected error with nonexistent version")
+	}
+
+	// Versions below the minimum decryption version can't be derived
+	req.Path = "keys/foo/config"
+	req.Data = map[string]interface{}{"min_decryption_version": 2}
+	if resp, err := b.HandleRequest(req); err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: err: %v resp: %#v", err, resp)
+	}
+	if resp, err = derive("alpha", map[string]interface{}{"key_version": 1}); err == nil || !resp.IsError() {
+		t.Fatal("expected error with version below the minimum decryption version")
+	}
+}
@@ -1,10 +1,15 @@
 package transit
 
 import (
+	"crypto/aes"
 	"crypto/ecdsa"
 	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
 	"crypto/x509"
 	"encoding/base64"
+	"encoding/binary"
 	"encoding/pem"
 	"errors"
 	"fmt"
@@ -38,10 +43,16 @@ func (b *backend) pathExportKeys() *framework.Path {
 				Type:        framework.TypeString,
 				Description: "Version of the key",
 			},
+			"public_key": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `PEM-encoded RSA public key to wrap the exported
+key material with. Required when exporting with an update operation.`,
+			},
 		},
 
 		Callbacks: map[logical.Operation]framework.OperationFunc{
-			logical.ReadOperation: b.pathPolicyExportRead,
+			logical.ReadOperation:   b.pathPolicyExportRead,
+			logical.UpdateOperation: b.pathPolicyExportRead,
 		},
 
 		HelpSynopsis:    pathExportHelpSyn,
@@ -63,6 +74,22 @@ func (b *backend) pathPolicyExportRead(
 		return logical.ErrorResponse(fmt.Sprintf("invalid export type: %s", exportType)), logical.ErrInvalidRequest
 	}
 
+	// Update operations return the key material wrapped under the given
+	// public key, so that access to plaintext and wrapped exports can be
+	// granted separately through ACL capabilities
+	var wrappingKey *rsa.PublicKey
+	if req.Operation == logical.UpdateOperation {
+		publicKey := d.Get("public_key").(string)
+		if publicKey == "" {
+			return logical.ErrorResponse("missing public_key to wrap the exported key with"), logical.ErrInvalidRequest
+		}
+		var err error
+		wrappingKey, err = parseWrappingKey(publicKey)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+	}
+
 	p, lock, err := b.lm.GetPolicyShared(req.Storage, name)
 	if lock != nil {
 		defer lock.RUnlock()
@@ -89,15 +116,30 @@ func (b *backend) pathPolicyExportRead(
 		}
 	}
 
+	exportKey := func(key *keysutil.KeyEntry) (string, error) {
+		if wrappingKey == nil {
+			return getExportKey(p, key, exportType)
+		}
+		material, err := getExportKeyMaterial(p, key, exportType)
+		if err != nil {
+			return "", err
+		}
+		wrapped, err := wrapKeyMaterial(wrappingKey, material)
+		if err != nil {
+			return "", err
+		}
+		return base64.StdEncoding.EncodeToString(wrapped), nil
+	}
+
 	retKeys := map[string]string{}
 	switch version {
 	case "":
 		for k, v := range p.Keys {
-			exportKey, err := getExportKey(p, &v, exportType)
+			key, err := exportKey(&v)
 			if err != nil {
 				return nil, err
 			}
-			retKeys[strconv.Itoa(k)] = exportKey
+			retKeys[strconv.Itoa(k)] = key
 		}
 
 	default:
@@ -120,12 +162,12 @@ func (b *backend) pathPolicyExportRead(
 			return logical.ErrorResponse("version does not exist or cannot be found"), logical.ErrInvalidRequest
 		}
 
-		exportKey, err := getExportKey(p, &key, exportType)
+		exported, err := exportKey(&key)
 		if err != nil {
 			return nil, err
 		}
 
-		retKeys[strconv.Itoa(versionValue)] = exportKey
+		retKeys[strconv.Itoa(versionValue)] = exported
 	}
 
 	resp := &logical.Response{
@@ -135,45 +177,68 @@ func (b *backend) pathPolicyExportRead(
 			"keys": retKeys,
 		},
 	}
+	if wrappingKey != nil {
+		resp.Data["wrapping_algorithm"] = wrappingAlgorithm
+	}
 
 	return resp, nil
 }
 
 func getExportKey(policy *keysutil.Policy, key *keysutil.KeyEntry, exportType string) (string, error) {
+	material, err := getExportKeyMaterial(policy, key, exportType)
+	if err != nil {
+		return "", err
+	}
+
+	if exportType == exportTypeSigningKey {
+		switch policy.Type {
+		case keysutil.KeyType_ECDSA_P256:
+			return pemEncode("EC PRIVATE KEY", material), nil
+		case keysutil.KeyType_RSA2048, keysutil.KeyType_RSA4096:
+			return pemEncode("RSA PRIVATE KEY", material), nil
+		}
+	}
+
+	return strings.TrimSpace(base64.StdEncoding.EncodeToString(material)), nil
+}
+
+// getExportKeyMaterial returns the raw bytes of the requested key: the key
+// itself for symmetric and ed25519 keys, or the DER encoding of the private
+// key for ECDSA (SEC 1) and RSA (PKCS #1) keys
+func getExportKeyMaterial(policy *keysutil.Policy, key *keysutil.KeyEntry, exportType string) ([]byte, error) {
 	if policy == nil {
-		return "", errors.New("nil policy provided")
+		return nil, errors.New("nil policy provided")
 	}
 
 	switch exportType {
 	case exportTypeHMACKey:
-		return strings.TrimSpace(base64.StdEncoding.EncodeToString(key.HMACKey)), nil
+		return key.HMACKey, nil
 
 	case exportTypeEncryptionKey:
 		switch policy.Type {
 		case keysutil.KeyType_AES256_GCM96:
-			return strings.TrimSpace(base64.StdEncoding.EncodeToString(key.Key)), nil
+			return key.Key, nil
 		}
 
 	case exportTypeSigningKey:
 		switch policy.Type {
 		case keysutil.KeyType_ECDSA_P256:
-			ecKey, err := keyEntryToECPrivateKey(key, elliptic.P256())
-			if err != nil {
-				return "", err
-			}
-			return ecKey, nil
+			return keyEntryToECPrivateKey(key, elliptic.P256())
 
 		case keysutil.KeyType_ED25519:
-			return strings.TrimSpace(base64.StdEncoding.EncodeToString(key.Key)), nil
+			return key.Key, nil
+
+		case keysutil.KeyType_RSA2048, keysutil.KeyType_RSA4096:
+			return keyEntryToRSAPrivateKey(key)
 		}
 	}
 
-	return "", fmt.Errorf("unknown key type %v", policy.Type)
+	return nil, fmt.Errorf("unknown key type %v", policy.Type)
 }
 
-func keyEntryToECPrivateKey(k *keysutil.KeyEntry, curve elliptic.Curve) (string, error) {
+func keyEntryToECPrivateKey(k *keysutil.KeyEntry, curve elliptic.Curve) ([]byte, error) {
 	if k == nil {
-		return "", errors.New("nil KeyEntry provided")
+		return nil, errors.New("nil KeyEntry provided")
 	}
 
 	privKey := &ecdsa.PrivateKey{
@@ -186,22 +251,131 @@ func keyEntryToECPrivateKey(k *keysutil.KeyEntry, curve elliptic.Curve) (string,
 	}
 	ecder, err := x509.MarshalECPrivateKey(privKey)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	if ecder == nil {
-		return "", errors.New("No data returned when marshalling to private key")
+		return nil, errors.New("No data returned when marshalling to private key")
 	}
+	return ecder, nil
+}
 
+func keyEntryToRSAPrivateKey(k *keysutil.KeyEntry) ([]byte, error) {
+	if k == nil {
+		return nil, errors.New("nil KeyEntry provided")
+	}
+	if k.RSAKey == nil {
+		return nil, errors.New("KeyEntry does not contain an RSA key")
+	}
+	return x509.MarshalPKCS1PrivateKey(k.RSAKey), nil
+}
+
+func pemEncode(blockType string, der []byte) string {
 	block := pem.Block{
-		Type:  "EC PRIVATE KEY",
-		Bytes: ecder,
+		Type:  blockType,
+		Bytes: der,
+	}
+	return strings.TrimSpace(string(pem.EncodeToMemory(&block)))
+}
+
+// parseWrappingKey parses a PEM-encoded PKIX RSA public key
+func parseWrappingKey(publicKey string) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(publicKey))
+	if block == nil {
+		return nil, errors.New("could not decode PEM public_key")
+	}
+
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing public_key: %v", err)
+	}
+	pub, ok := parsed.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("public_key must be an RSA key")
+	}
+
+	if pub.N.BitLen() < 2048 {
+		return nil, errors.New("public_key must be at least 2048 bits")
+	}
+	return pub, nil
+}
+
+// wrappingAlgorithm describes the scheme used by wrapKeyMaterial. It matches
+// the PKCS #11 CKM_RSA_AES_KEY_WRAP mechanism so that the result can be
+// imported directly into most HSMs.
+const wrappingAlgorithm = "rsa-oaep-sha256-aes-kwp"
+
+// wrapKeyMaterial wraps material under a fresh AES-256 key using the AES
+// key wrap with padding algorithm from RFC 5649, then encrypts that AES key
+// to the public key with RSA-OAEP and SHA-256. The result is the RSA
+// ciphertext followed by the wrapped key material.
+func wrapKeyMaterial(pub *rsa.PublicKey, material []byte) ([]byte, error) {
+	if len(material) == 0 {
+		return nil, errors.New("no key material to wrap")
+	}
+
+	kek := make([]byte, 32)
+	if _, err := rand.Read(kek); err != nil {
+		return nil, err
+	}
+
+	encKEK, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, pub, kek, nil)
+	if err != nil {
+		return nil, err
 	}
-	return strings.TrimSpace(string(pem.EncodeToMemory(&block))), nil
+
+	wrapped, err := aesKeyWrapPad(kek, material)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(encKEK, wrapped...), nil
+}
+
+// aesKeyWrapPad implements the wrapping half of RFC 5649
+func aesKeyWrapPad(kek, material []byte) ([]byte, error) {
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+
+	// The alternative initial value carries the unpadded length
+	aiv := make([]byte, 8)
+	copy(aiv, []byte{0xA6, 0x59, 0x59, 0xA6})
+	binary.BigEndian.PutUint32(aiv[4:], uint32(len(material)))
+
+	padded := make([]byte, (len(material)+7)/8*8)
+	copy(padded, material)
+
+	if len(padded) == 8 {
+		out := make([]byte, 16)
+		block.Encrypt(out, append(aiv, padded...))
+		return out, nil
+	}
+
+	// Otherwise this is the RFC 3394 wrapping process using the AIV
+	n := len(padded) / 8
+	a := aiv
+	r := padded
+	buf := make([]byte, 16)
+	for j := 0; j < 6; j++ {
+		for i := 0; i < n; i++ {
+			copy(buf, a)
+			copy(buf[8:], r[i*8:(i+1)*8])
+			block.Encrypt(buf, buf)
+			t := uint64(n*j + i + 1)
+			binary.BigEndian.PutUint64(a, binary.BigEndian.Uint64(buf[:8])^t)
+			copy(r[i*8:(i+1)*8], buf[8:])
+		}
+	}
+
+	return append(a, r...), nil
 }
 
 const pathExportHelpSyn = `Export named encryption or signing key`
 
 const pathExportHelpDesc = `
 This path is used to export the named keys that are configured as
-exportable.
+exportable. Reading returns the keys in plaintext; writing with a
+"public_key" returns each key wrapped under that RSA public key
+for import into an HSM or other escrow system.
 `
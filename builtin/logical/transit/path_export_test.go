@@ -1,6 +1,15 @@
 package transit
 
 import (
+	"bytes"
+	"crypto/aes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/pem"
 	"fmt"
 	"reflect"
 	"strconv"
@@ -412,3 +421,143 @@ func TestTransit_Export_EncryptionKey_DoesNotExportHMACKey(t *testing.T) {
 		t.Fatal("Encryption key data matched hmac key data")
 	}
 }
+
+func TestTransit_Export_Wrapped(t *testing.T) {
+	testExportWrapped(t, "encryption-key", "aes256-gcm96")
+	testExportWrapped(t, "signing-key", "ecdsa-p256")
+	testExportWrapped(t, "signing-key", "ed25519")
+	testExportWrapped(t, "hmac-key", "aes256-gcm96")
+}
+
+func testExportWrapped(t *testing.T, exportType, keyType string) {
+	storage := &logical.InmemStorage{}
+	b := Backend(&logical.BackendConfig{
+		StorageView: storage,
+		System:      logical.TestSystemView(),
+	})
+
+	wrappingKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(&wrappingKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubPEM := string(pem.EncodeToMemory(&pem.Block{
+		Type:  "PUBLIC KEY",
+		Bytes: pubDER,
+	}))
+
+	for _, exportable := range []bool{false, true} {
+		name := fmt.Sprintf("foo-%t", exportable)
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "keys/" + name,
+			Data: map[string]interface{}{
+				"exportable": exportable,
+				"type":       keyType,
+			},
+		}
+		if _, err := b.HandleRequest(req); err != nil {
+			t.Fatal(err)
+		}
+
+		req = &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      fmt.Sprintf("export/%s/%s/1", exportType, name),
+			Data: map[string]interface{}{
+				"public_key": pubPEM,
+			},
+		}
+		resp, err := b.HandleRequest(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !exportable {
+			if resp == nil || !resp.IsError() {
+				t.Fatalf("expected error exporting non-exportable key, got %#v", resp)
+			}
+			continue
+		}
+		if resp.Data["wrapping_algorithm"] != wrappingAlgorithm {
+			t.Fatalf("bad: %#v", resp.Data)
+		}
+		wrapped, err := base64.StdEncoding.DecodeString(resp.Data["keys"].(map[string]string)["1"])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rsaSize := (wrappingKey.N.BitLen() + 7) / 8
+		kek, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, wrappingKey, wrapped[:rsaSize], nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		material, err := aesKeyUnwrapPad(kek, wrapped[rsaSize:])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		p, lock, err := b.lm.GetPolicyShared(storage, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entry := p.Keys[1]
+		expected, err := getExportKeyMaterial(p, &entry, exportType)
+		lock.RUnlock()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(material, expected) {
+			t.Fatalf("unwrapped key material does not match for %s/%s", exportType, keyType)
+		}
+
+		// A public key is required to request a wrapped export
+		req.Data = nil
+		resp, err = b.HandleRequest(req)
+		if err != logical.ErrInvalidRequest {
+			t.Fatalf("expected invalid request, got %v %#v", err, resp)
+		}
+	}
+}
+
+// aesKeyUnwrapPad reverses aesKeyWrapPad following RFC 5649
+func aesKeyUnwrapPad(kek, wrapped []byte) ([]byte, error) {
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+
+	var a, r []byte
+	if len(wrapped) == 16 {
+		out := make([]byte, 16)
+		block.Decrypt(out, wrapped)
+		a, r = out[:8], out[8:]
+	} else {
+		n := len(wrapped)/8 - 1
+		a = append([]byte(nil), wrapped[:8]...)
+		r = append([]byte(nil), wrapped[8:]...)
+		buf := make([]byte, 16)
+		for j := 5; j >= 0; j-- {
+			for i := n - 1; i >= 0; i-- {
+				t := uint64(n*j + i + 1)
+				binary.BigEndian.PutUint64(buf, binary.BigEndian.Uint64(a)^t)
+				copy(buf[8:], r[i*8:(i+1)*8])
+				block.Decrypt(buf, buf)
+				copy(a, buf[:8])
+				copy(r[i*8:(i+1)*8], buf[8:])
+			}
+		}
+	}
+
+	if !bytes.Equal(a[:4], []byte{0xA6, 0x59, 0x59, 0xA6}) {
+		return nil, fmt.Errorf("integrity check failed")
+	}
+	length := int(binary.BigEndian.Uint32(a[4:]))
+	if length > len(r) || length <= len(r)-8 {
+		return nil, fmt.Errorf("invalid length")
+	}
+	return r[:length], nil
+}
@@ -0,0 +1,156 @@
+package transit
+
+import (
+	"crypto/elliptic"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"strconv"
+
+	"golang.org/x/crypto/ed25519"
+
+	"github.com/fatih/structs"
+	"github.com/hashicorp/vault/helper/keysutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// jsonWebKey is the JWK representation of a public key, as defined in RFC
+// 7517 (and RFC 8037 for Ed25519 keys). The algorithm is only set where the
+// key type implies it, since RSA and ECDSA signatures may use any of the
+// supported hash algorithms.
+type jsonWebKey struct {
+	KeyType   string `json:"kty" structs:"kty" mapstructure:"kty"`
+	KeyID     string `json:"kid" structs:"kid" mapstructure:"kid"`
+	Use       string `json:"use" structs:"use" mapstructure:"use"`
+	Algorithm string `json:"alg,omitempty" structs:"alg,omitempty" mapstructure:"alg"`
+	Curve     string `json:"crv,omitempty" structs:"crv,omitempty" mapstructure:"crv"`
+	X         string `json:"x,omitempty" structs:"x,omitempty" mapstructure:"x"`
+	Y         string `json:"y,omitempty" structs:"y,omitempty" mapstructure:"y"`
+	N         string `json:"n,omitempty" structs:"n,omitempty" mapstructure:"n"`
+	E         string `json:"e,omitempty" structs:"e,omitempty" mapstructure:"e"`
+}
+
+func (b *backend) pathKeysJWKS() *framework.Path {
+	return &framework.Path{
+		Pattern: "keys/" + framework.GenericNameRegex("name") + "/jwks",
+		Fields: map[string]*framework.FieldSchema{
+			"name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of the key",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathKeysJWKSRead,
+		},
+
+		HelpSynopsis:    pathKeysJWKSHelpSyn,
+		HelpDescription: pathKeysJWKSHelpDesc,
+	}
+}
+
+func (b *backend) pathKeysJWKSRead(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	p, lock, err := b.lm.GetPolicyShared(req.Storage, name)
+	if lock != nil {
+		defer lock.RUnlock()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, nil
+	}
+
+	if !p.Type.SigningSupported() {
+		return logical.ErrorResponse(fmt.Sprintf("key type %v does not have a public key", p.Type)), logical.ErrInvalidRequest
+	}
+	if p.Derived {
+		return logical.ErrorResponse("public keys of derived keys cannot be published"), logical.ErrInvalidRequest
+	}
+
+	// Only publish keys that can still be used to verify signatures
+	keys := make([]map[string]interface{}, 0, p.LatestVersion-p.MinDecryptionVersion+1)
+	for ver := p.MinDecryptionVersion; ver <= p.LatestVersion; ver++ {
+		entry, ok := p.Keys[ver]
+		if !ok {
+			continue
+		}
+
+		jwk, err := keyEntryToJWK(p.Type, &entry)
+		if err != nil {
+			return nil, err
+		}
+		jwk.KeyID = strconv.Itoa(ver)
+		keys = append(keys, structs.New(jwk).Map())
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"keys": keys,
+		},
+		// Verifiers poll this endpoint, so let them revalidate cheaply
+		CacheControl: &logical.CacheControl{
+			Public: true,
+		},
+	}, nil
+}
+
+func keyEntryToJWK(keyType keysutil.KeyType, k *keysutil.KeyEntry) (*jsonWebKey, error) {
+	switch keyType {
+	case keysutil.KeyType_ECDSA_P256:
+		size := (elliptic.P256().Params().BitSize + 7) / 8
+		return &jsonWebKey{
+			KeyType: "EC",
+			Use:     "sig",
+			Curve:   "P-256",
+			X:       base64.RawURLEncoding.EncodeToString(padBytes(k.EC_X.Bytes(), size)),
+			Y:       base64.RawURLEncoding.EncodeToString(padBytes(k.EC_Y.Bytes(), size)),
+		}, nil
+
+	case keysutil.KeyType_ED25519:
+		pubKey := ed25519.PrivateKey(k.Key).Public().(ed25519.PublicKey)
+		return &jsonWebKey{
+			KeyType:   "OKP",
+			Use:       "sig",
+			Algorithm: "EdDSA",
+			Curve:     "Ed25519",
+			X:         base64.RawURLEncoding.EncodeToString(pubKey),
+		}, nil
+
+	case keysutil.KeyType_RSA2048, keysutil.KeyType_RSA4096:
+		if k.RSAKey == nil {
+			return nil, fmt.Errorf("key entry does not contain an RSA key")
+		}
+		return &jsonWebKey{
+			KeyType: "RSA",
+			Use:     "sig",
+			N:       base64.RawURLEncoding.EncodeToString(k.RSAKey.N.Bytes()),
+			E:       base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.RSAKey.E)).Bytes()),
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported key type %v", keyType)
+}
+
+// padBytes left-pads the given big-endian value with zeroes to size bytes,
+// as required for JWK elliptic curve coordinates
+func padBytes(b []byte, size int) []byte {
+	if len(b) >= size {
+		return b
+	}
+	padded := make([]byte, size)
+	copy(padded[size-len(b):], b)
+	return padded
+}
+
+const pathKeysJWKSHelpSyn = `Publish the public keys of a named signing key in JWKS format`
+
+const pathKeysJWKSHelpDesc = `
+This path returns the public keys of all versions of the named key that can
+still be used for verification, formatted as a JSON Web Key Set (RFC 7517).
+Services can use this to verify Vault-issued signatures without calling Vault.
+`
@@ -0,0 +1,145 @@
+package transit
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/base64"
+	"math/big"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ed25519"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestTransit_JWKS(t *testing.T) {
+	var b *backend
+	sysView := logical.TestSystemView()
+	storage := &logical.InmemStorage{}
+
+	b = Backend(&logical.BackendConfig{
+		StorageView: storage,
+		System:      sysView,
+	})
+
+	input := []byte("the quick brown fox")
+	digest := sha256.Sum256(input)
+
+	for _, keyType := range []string{"ecdsa-p256", "ed25519", "rsa-2048"} {
+		req := &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "keys/" + keyType,
+			Data: map[string]interface{}{
+				"type": keyType,
+			},
+		}
+		if _, err := b.HandleRequest(req); err != nil {
+			t.Fatal(err)
+		}
+
+		// Rotate so that more than one version is published
+		req.Path = "keys/" + keyType + "/rotate"
+		req.Data = nil
+		if _, err := b.HandleRequest(req); err != nil {
+			t.Fatal(err)
+		}
+
+		req.Path = "sign/" + keyType
+		req.Data = map[string]interface{}{
+			"input": base64.StdEncoding.EncodeToString(input),
+		}
+		resp, err := b.HandleRequest(req)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: err: %v\nresp: %#v", err, resp)
+		}
+		sigBytes, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(resp.Data["signature"].(string), "vault:v2:"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Operation = logical.ReadOperation
+		req.Path = "keys/" + keyType + "/jwks"
+		req.Data = nil
+		resp, err = b.HandleRequest(req)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: err: %v\nresp: %#v", err, resp)
+		}
+		if resp.CacheControl == nil || !resp.CacheControl.Public {
+			t.Fatalf("expected public cache control: %#v", resp.CacheControl)
+		}
+
+		keys := resp.Data["keys"].([]map[string]interface{})
+		if len(keys) != 2 {
+			t.Fatalf("expected 2 keys, got %d", len(keys))
+		}
+		jwk := keys[1]
+		if jwk["kid"].(string) != "2" || jwk["use"].(string) != "sig" {
+			t.Fatalf("bad jwk: %#v", jwk)
+		}
+
+		decode := func(field string) []byte {
+			val, err := base64.RawURLEncoding.DecodeString(jwk[field].(string))
+			if err != nil {
+				t.Fatalf("failed decoding %s: %v", field, err)
+			}
+			return val
+		}
+
+		// Verify the signature using only the published key material
+		switch keyType {
+		case "ecdsa-p256":
+			var sig ecdsaSignature
+			if _, err := asn1.Unmarshal(sigBytes, &sig); err != nil {
+				t.Fatal(err)
+			}
+			pub := &ecdsa.PublicKey{
+				Curve: elliptic.P256(),
+				X:     new(big.Int).SetBytes(decode("x")),
+				Y:     new(big.Int).SetBytes(decode("y")),
+			}
+			if !ecdsa.Verify(pub, digest[:], sig.R, sig.S) {
+				t.Fatal("ecdsa signature did not verify against jwk")
+			}
+
+		case "ed25519":
+			if !ed25519.Verify(ed25519.PublicKey(decode("x")), input, sigBytes) {
+				t.Fatal("ed25519 signature did not verify against jwk")
+			}
+
+		case "rsa-2048":
+			pub := &rsa.PublicKey{
+				N: new(big.Int).SetBytes(decode("n")),
+				E: int(new(big.Int).SetBytes(decode("e")).Int64()),
+			}
+			if err := rsa.VerifyPSS(pub, crypto.SHA256, digest[:], sigBytes, nil); err != nil {
+				t.Fatalf("rsa signature did not verify against jwk: %v", err)
+			}
+		}
+	}
+
+	// Symmetric keys have nothing to publish
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/aes",
+	}
+	if _, err := b.HandleRequest(req); err != nil {
+		t.Fatal(err)
+	}
+	req.Operation = logical.ReadOperation
+	req.Path = "keys/aes/jwks"
+	resp, err := b.HandleRequest(req)
+	if err == nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected error, got: %#v", resp)
+	}
+}
+
+type ecdsaSignature struct {
+	R, S *big.Int
+}
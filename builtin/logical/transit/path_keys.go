@@ -41,8 +41,10 @@ func (b *backend) pathKeys() *framework.Path {
 				Type:    framework.TypeString,
 				Default: "aes256-gcm96",
 				Description: `The type of key to create. Currently,
-"aes256-gcm96" (symmetric) and "ecdsa-p256" (asymmetric), and
-'ed25519' (asymmetric) are supported. Defaults to "aes256-gcm96".`,
+"aes256-gcm96" (symmetric), "ecdsa-p256" (asymmetric),
+"ed25519" (asymmetric), "rsa-2048" (asymmetric) and
+"rsa-4096" (asymmetric) are supported. Defaults to
+"aes256-gcm96".`,
 			},
 
 			"derived": &framework.FieldSchema{
@@ -57,15 +59,13 @@ keys for encryption operations.`,
 				Description: `Whether to support convergent encryption.
 This is only supported when using a key with
 key derivation enabled and will require all
-requests to carry both a context and 96-bit
-(12-byte) nonce. The given nonce will be used
-in place of a randomly generated nonce. As a
-result, when the same context and nonce are
-supplied, the same ciphertext is generated. It
-is *very important* when using this mode that
-you ensure that all nonces are unique for a
-given context. Failing to do so will severely
-impact the ciphertext's security.`,
+requests to carry a context. The nonce is
+derived from the context and plaintext using
+a key derived from the encryption key in place
+of a randomly generated nonce. As a result, when the same
+context and plaintext are supplied, the same
+ciphertext is generated, allowing equality
+lookups on encrypted values.`,
 			},
 
 			"exportable": &framework.FieldSchema{
@@ -131,6 +131,10 @@ func (b *backend) pathPolicyWrite(
 		polReq.KeyType = keysutil.KeyType_ECDSA_P256
 	case "ed25519":
 		polReq.KeyType = keysutil.KeyType_ED25519
+	case "rsa-2048":
+		polReq.KeyType = keysutil.KeyType_RSA2048
+	case "rsa-4096":
+		polReq.KeyType = keysutil.KeyType_RSA4096
 	default:
 		return logical.ErrorResponse(fmt.Sprintf("unknown key type %v", keyType)), logical.ErrInvalidRequest
 	}
@@ -191,8 +195,13 @@ func (b *backend) pathPolicyRead(
 			"supports_decryption":    p.Type.DecryptionSupported(),
 			"supports_signing":       p.Type.SigningSupported(),
 			"supports_derivation":    p.Type.DerivationSupported(),
+			"rotation_period":        int64(p.RotationPeriod.Seconds()),
+			"trim_on_rotation":       p.TrimOnRotation,
 		},
 	}
+	if p.MinAvailableVersion > 0 {
+		resp.Data["min_available_version"] = p.MinAvailableVersion
+	}
 
 	if p.Derived {
 		switch p.KDF {
@@ -225,7 +234,7 @@ func (b *backend) pathPolicyRead(
 		}
 		resp.Data["keys"] = retKeys
 
-	case keysutil.KeyType_ECDSA_P256, keysutil.KeyType_ED25519:
+	case keysutil.KeyType_ECDSA_P256, keysutil.KeyType_ED25519, keysutil.KeyType_RSA2048, keysutil.KeyType_RSA4096:
 		retKeys := map[string]map[string]interface{}{}
 		for k, v := range p.Keys {
 			key := asymKey{
@@ -253,6 +262,8 @@ func (b *backend) pathPolicyRead(
 					}
 				}
 				key.Name = "ed25519"
+			case keysutil.KeyType_RSA2048, keysutil.KeyType_RSA4096:
+				key.Name = "rsa"
 			}
 
 			retKeys[strconv.Itoa(k)] = structs.New(key).Map()
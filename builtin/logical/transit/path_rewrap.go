@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/hashicorp/vault/helper/errutil"
+	"github.com/hashicorp/vault/helper/keysutil"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
 	"github.com/mitchellh/mapstructure"
@@ -93,22 +94,9 @@ func (b *backend) pathRewrapWrite(
 			continue
 		}
 
-		// Decode the context
-		if len(item.Context) != 0 {
-			batchInputItems[i].DecodedContext, err = base64.StdEncoding.DecodeString(item.Context)
-			if err != nil {
-				batchResponseItems[i].Error = err.Error()
-				continue
-			}
-		}
-
-		// Decode the nonce
-		if len(item.Nonce) != 0 {
-			batchInputItems[i].DecodedNonce, err = base64.StdEncoding.DecodeString(item.Nonce)
-			if err != nil {
-				batchResponseItems[i].Error = err.Error()
-				continue
-			}
+		if err := decodeBatchRequestItem(&batchInputItems[i]); err != nil {
+			batchResponseItems[i].Error = err.Error()
+			continue
 		}
 	}
 
@@ -129,34 +117,17 @@ func (b *backend) pathRewrapWrite(
 			continue
 		}
 
-		plaintext, err := p.Decrypt(item.DecodedContext, item.DecodedNonce, item.Ciphertext)
+		ciphertext, err := rewrapBatchRequestItem(p, item)
 		if err != nil {
 			switch err.(type) {
 			case errutil.UserError:
 				batchResponseItems[i].Error = err.Error()
 				continue
 			default:
-				return nil, err
+				return nil, fmt.Errorf("failed to rewrap input item %d: %v", i, err)
 			}
 		}
 
-		ciphertext, err := p.Encrypt(item.KeyVersion, item.DecodedContext, item.DecodedNonce, plaintext)
-		if err != nil {
-			switch err.(type) {
-			case errutil.UserError:
-				batchResponseItems[i].Error = err.Error()
-				continue
-			case errutil.InternalError:
-				return nil, err
-			default:
-				return nil, err
-			}
-		}
-
-		if ciphertext == "" {
-			return nil, fmt.Errorf("empty ciphertext returned for input item %d", i)
-		}
-
 		batchResponseItems[i].Ciphertext = ciphertext
 	}
 
@@ -177,6 +148,44 @@ func (b *backend) pathRewrapWrite(
 	return resp, nil
 }
 
+// decodeBatchRequestItem decodes the base64-encoded context and nonce of
+// the item, if set
+func decodeBatchRequestItem(item *BatchRequestItem) error {
+	var err error
+	if len(item.Context) != 0 {
+		item.DecodedContext, err = base64.StdEncoding.DecodeString(item.Context)
+		if err != nil {
+			return err
+		}
+	}
+	if len(item.Nonce) != 0 {
+		item.DecodedNonce, err = base64.StdEncoding.DecodeString(item.Nonce)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rewrapBatchRequestItem decrypts the item's ciphertext and encrypts it again
+// with the requested key version. Errors caused by the item itself are
+// returned as errutil.UserError.
+func rewrapBatchRequestItem(p *keysutil.Policy, item BatchRequestItem) (string, error) {
+	plaintext, err := p.Decrypt(item.DecodedContext, item.DecodedNonce, item.Ciphertext)
+	if err != nil {
+		return "", err
+	}
+
+	ciphertext, err := p.Encrypt(item.KeyVersion, item.DecodedContext, item.DecodedNonce, plaintext)
+	if err != nil {
+		return "", err
+	}
+	if ciphertext == "" {
+		return "", fmt.Errorf("empty ciphertext returned")
+	}
+	return ciphertext, nil
+}
+
 const pathRewrapHelpSyn = `Rewrap ciphertext`
 
 const pathRewrapHelpDesc = `
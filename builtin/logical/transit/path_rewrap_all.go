@@ -0,0 +1,483 @@
+package transit
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/helper/errutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+	"github.com/mitchellh/mapstructure"
+)
+
+const (
+	rewrapJobStatusRunning   = "running"
+	rewrapJobStatusComplete  = "complete"
+	rewrapJobStatusCancelled = "cancelled"
+	rewrapJobStatusFailed    = "failed"
+
+	// rewrapJobPrefix is the storage prefix of rewrap jobs
+	rewrapJobPrefix = "rewrap-job/"
+
+	// rewrapJobChunkSize is how many items are rewrapped while holding the
+	// policy lock, so that long jobs don't block rotation. Jobs are persisted
+	// after every chunk.
+	rewrapJobChunkSize = 100
+
+	// rewrapJobRetention is how long finished jobs are kept so that their
+	// results can be fetched
+	rewrapJobRetention = time.Hour
+)
+
+// rewrapJob tracks an asynchronous rewrap of a batch of ciphertexts. Jobs are
+// persisted so that they survive restarts and leadership changes; a job that
+// was running when its node stopped is resumed by the periodic function of
+// the next active node.
+type rewrapJob struct {
+	sync.RWMutex
+	rewrapJobEntry
+
+	cancelOnce sync.Once
+	cancelCh   chan struct{}
+}
+
+// rewrapJobEntry is the persisted state of a rewrap job. Processed items are
+// a prefix of Items, so a resumed job continues from the last chunk stored.
+type rewrapJobEntry struct {
+	ID        string              `json:"id"`
+	Name      string              `json:"name"`
+	Status    string              `json:"status"`
+	Error     string              `json:"error"`
+	StartTime time.Time           `json:"start_time"`
+	EndTime   time.Time           `json:"end_time"`
+	Processed int                 `json:"processed"`
+	Failed    int                 `json:"failed"`
+	Items     []BatchRequestItem  `json:"items"`
+	Results   []BatchResponseItem `json:"results"`
+}
+
+func (b *backend) pathRewrapAll() *framework.Path {
+	return &framework.Path{
+		Pattern: "keys/" + framework.GenericNameRegex("name") + "/rewrap-all$",
+		Fields: map[string]*framework.FieldSchema{
+			"name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of the key",
+			},
+
+			"batch_input": &framework.FieldSchema{
+				Type: framework.TypeSlice,
+				Description: `List of items to rewrap, each with a "ciphertext" and,
+if required by the key, a "context" and "nonce".`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathRewrapAllWrite,
+		},
+
+		HelpSynopsis:    pathRewrapAllHelpSyn,
+		HelpDescription: pathRewrapAllHelpDesc,
+	}
+}
+
+func (b *backend) pathRewrapAllStatus() *framework.Path {
+	return &framework.Path{
+		Pattern: "keys/" + framework.GenericNameRegex("name") + "/rewrap-all/" + framework.GenericNameRegex("job_id"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of the key",
+			},
+
+			"job_id": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "ID of the rewrap job",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathRewrapAllStatusRead,
+			logical.DeleteOperation: b.pathRewrapAllStatusDelete,
+		},
+
+		HelpSynopsis:    pathRewrapAllStatusHelpSyn,
+		HelpDescription: pathRewrapAllStatusHelpDesc,
+	}
+}
+
+func (b *backend) pathRewrapAllWrite(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	var items []BatchRequestItem
+	if err := mapstructure.Decode(d.Raw["batch_input"], &items); err != nil {
+		return nil, fmt.Errorf("failed to parse batch input: %v", err)
+	}
+	if len(items) == 0 {
+		return logical.ErrorResponse("missing batch input to process"), logical.ErrInvalidRequest
+	}
+
+	results := make([]BatchResponseItem, len(items))
+	contextSet := len(items[0].Context) != 0
+	for i, item := range items {
+		if (len(item.Context) == 0 && contextSet) || (len(item.Context) != 0 && !contextSet) {
+			return logical.ErrorResponse("context should be set either in all the request blocks or in none"), logical.ErrInvalidRequest
+		}
+		if item.Ciphertext == "" {
+			results[i].Error = "missing ciphertext to decrypt"
+			continue
+		}
+		if err := decodeBatchRequestItem(&items[i]); err != nil {
+			results[i].Error = err.Error()
+		}
+	}
+
+	// Make sure the key exists before accepting the job
+	p, lock, err := b.lm.GetPolicyShared(req.Storage, name)
+	if lock != nil {
+		lock.RUnlock()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return logical.ErrorResponse("encryption key not found"), logical.ErrInvalidRequest
+	}
+
+	id, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, err
+	}
+
+	job := newRewrapJob(rewrapJobEntry{
+		ID:        id,
+		Name:      name,
+		Status:    rewrapJobStatusRunning,
+		StartTime: time.Now(),
+		Items:     items,
+		Results:   results,
+	})
+
+	// The job is tracked before it is stored so that the periodic function
+	// never mistakes it for an interrupted job
+	b.rewrapJobsLock.Lock()
+	b.rewrapJobs[id] = job
+	b.rewrapJobsLock.Unlock()
+
+	if err := b.storeRewrapJob(req.Storage, job); err != nil {
+		b.rewrapJobsLock.Lock()
+		delete(b.rewrapJobs, id)
+		b.rewrapJobsLock.Unlock()
+		return nil, err
+	}
+
+	go b.runRewrapJob(req.Storage, job)
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"job_id": id,
+			"total":  len(items),
+		},
+	}, nil
+}
+
+func (b *backend) pathRewrapAllStatusRead(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	job, err := b.rewrapJob(req.Storage, d.Get("name").(string), d.Get("job_id").(string))
+	if err != nil {
+		return nil, err
+	}
+	if job == nil {
+		return nil, nil
+	}
+
+	job.RLock()
+	defer job.RUnlock()
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"job_id":     job.ID,
+			"status":     job.Status,
+			"total":      len(job.Results),
+			"processed":  job.Processed,
+			"failed":     job.Failed,
+			"start_time": job.StartTime.Format(time.RFC3339Nano),
+			"end_time":   "",
+		},
+	}
+	if job.Error != "" {
+		resp.Data["error"] = job.Error
+	}
+	if job.Status != rewrapJobStatusRunning {
+		resp.Data["end_time"] = job.EndTime.Format(time.RFC3339Nano)
+		resp.Data["batch_results"] = job.Results
+	}
+	return resp, nil
+}
+
+func (b *backend) pathRewrapAllStatusDelete(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name, id := d.Get("name").(string), d.Get("job_id").(string)
+
+	// Jobs running on this node are cancelled and kept so their partial
+	// results can be fetched
+	b.rewrapJobsLock.RLock()
+	job, ok := b.rewrapJobs[id]
+	b.rewrapJobsLock.RUnlock()
+	if ok && job.Name == name {
+		job.cancel()
+		return nil, nil
+	}
+
+	job, err := b.loadRewrapJob(req.Storage, id)
+	if err != nil {
+		return nil, err
+	}
+	if job == nil || job.Name != name {
+		return nil, nil
+	}
+
+	// An interrupted job that hasn't been resumed yet is cancelled in
+	// storage; finished jobs are discarded
+	if job.Status == rewrapJobStatusRunning {
+		job.finish(rewrapJobStatusCancelled, "")
+		return nil, b.storeRewrapJob(req.Storage, job)
+	}
+	return nil, req.Storage.Delete(rewrapJobPrefix + id)
+}
+
+func newRewrapJob(entry rewrapJobEntry) *rewrapJob {
+	return &rewrapJob{
+		rewrapJobEntry: entry,
+		cancelCh:       make(chan struct{}),
+	}
+}
+
+// rewrapJob returns the job with the given ID if it belongs to the named key,
+// preferring the live state of a job running on this node
+func (b *backend) rewrapJob(storage logical.Storage, name, id string) (*rewrapJob, error) {
+	b.rewrapJobsLock.RLock()
+	job, ok := b.rewrapJobs[id]
+	b.rewrapJobsLock.RUnlock()
+
+	if !ok {
+		var err error
+		job, err = b.loadRewrapJob(storage, id)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if job == nil || job.Name != name {
+		return nil, nil
+	}
+	return job, nil
+}
+
+func (b *backend) loadRewrapJob(storage logical.Storage, id string) (*rewrapJob, error) {
+	raw, err := storage.Get(rewrapJobPrefix + id)
+	if err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return nil, nil
+	}
+
+	var entry rewrapJobEntry
+	if err := raw.DecodeJSON(&entry); err != nil {
+		return nil, fmt.Errorf("failed to decode rewrap job %q: %v", id, err)
+	}
+	return newRewrapJob(entry), nil
+}
+
+func (b *backend) storeRewrapJob(storage logical.Storage, job *rewrapJob) error {
+	job.RLock()
+	entry, err := logical.StorageEntryJSON(rewrapJobPrefix+job.ID, job.rewrapJobEntry)
+	job.RUnlock()
+	if err != nil {
+		return err
+	}
+	return storage.Put(entry)
+}
+
+// runRewrapJob rewraps the job's remaining items in chunks until done,
+// cancelled, or the backend is shut down. A job stopped by a shutdown stays
+// running in storage and is resumed by the next active node.
+func (b *backend) runRewrapJob(storage logical.Storage, job *rewrapJob) {
+	defer func() {
+		b.rewrapJobsLock.Lock()
+		delete(b.rewrapJobs, job.ID)
+		b.rewrapJobsLock.Unlock()
+	}()
+
+	job.RLock()
+	start, total := job.Processed, len(job.Items)
+	job.RUnlock()
+
+	for ; start < total; start += rewrapJobChunkSize {
+		select {
+		case <-job.cancelCh:
+			b.finishRewrapJob(storage, job, rewrapJobStatusCancelled, "")
+			return
+		case <-b.rewrapStopCh:
+			return
+		default:
+		}
+
+		end := start + rewrapJobChunkSize
+		if end > total {
+			end = total
+		}
+		if err := b.rewrapJobChunk(storage, job, start, end); err != nil {
+			b.Logger().Error("transit: rewrap job failed", "name", job.Name, "job_id", job.ID, "error", err)
+			b.finishRewrapJob(storage, job, rewrapJobStatusFailed, err.Error())
+			return
+		}
+		if end < total {
+			if err := b.storeRewrapJob(storage, job); err != nil {
+				b.Logger().Error("transit: failed to persist rewrap job progress", "name", job.Name, "job_id", job.ID, "error", err)
+				return
+			}
+		}
+	}
+	b.finishRewrapJob(storage, job, rewrapJobStatusComplete, "")
+}
+
+func (b *backend) rewrapJobChunk(storage logical.Storage, job *rewrapJob, start, end int) error {
+	p, lock, err := b.lm.GetPolicyShared(storage, job.Name)
+	if lock != nil {
+		defer lock.RUnlock()
+	}
+	if err != nil {
+		return err
+	}
+	if p == nil {
+		return fmt.Errorf("encryption key not found")
+	}
+
+	for i := start; i < end; i++ {
+		// Items that failed validation are only counted
+		job.RLock()
+		item := job.Items[i]
+		itemErr := job.Results[i].Error
+		job.RUnlock()
+
+		var ciphertext string
+		if itemErr == "" {
+			ciphertext, err = rewrapBatchRequestItem(p, item)
+			if err != nil {
+				if _, ok := err.(errutil.UserError); !ok {
+					return fmt.Errorf("failed to rewrap input item %d: %v", i, err)
+				}
+				itemErr = err.Error()
+			}
+		}
+
+		job.Lock()
+		job.Processed++
+		if itemErr != "" {
+			job.Results[i].Error = itemErr
+			job.Failed++
+		} else {
+			job.Results[i].Ciphertext = ciphertext
+		}
+		job.Unlock()
+	}
+	return nil
+}
+
+// finishRewrapJob records the outcome of a job and persists it
+func (b *backend) finishRewrapJob(storage logical.Storage, job *rewrapJob, status, err string) {
+	job.finish(status, err)
+	if err := b.storeRewrapJob(storage, job); err != nil {
+		b.Logger().Error("transit: failed to persist rewrap job", "name", job.Name, "job_id", job.ID, "error", err)
+	}
+}
+
+func (job *rewrapJob) finish(status, err string) {
+	job.Lock()
+	defer job.Unlock()
+
+	job.Status = status
+	job.Error = err
+	job.EndTime = time.Now()
+	job.Items = nil
+}
+
+func (job *rewrapJob) cancel() {
+	job.cancelOnce.Do(func() {
+		close(job.cancelCh)
+	})
+}
+
+// tidyRewrapJobs removes finished jobs that are past their retention period
+// and resumes running jobs that were interrupted by a restart or a leadership
+// change
+func (b *backend) tidyRewrapJobs(storage logical.Storage, now time.Time) error {
+	ids, err := storage.List(rewrapJobPrefix)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		job, err := b.loadRewrapJob(storage, id)
+		if err != nil {
+			return err
+		}
+		if job == nil {
+			continue
+		}
+
+		if job.Status != rewrapJobStatusRunning {
+			if now.Sub(job.EndTime) > rewrapJobRetention {
+				if err := storage.Delete(rewrapJobPrefix + id); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		b.rewrapJobsLock.Lock()
+		_, tracked := b.rewrapJobs[id]
+		if !tracked {
+			b.rewrapJobs[id] = job
+		}
+		b.rewrapJobsLock.Unlock()
+		if !tracked {
+			b.Logger().Info("transit: resuming rewrap job", "name", job.Name, "job_id", id)
+			go b.runRewrapJob(storage, job)
+		}
+	}
+	return nil
+}
+
+// stopRewrapJobs stops the jobs running on this node when the backend is
+// unloaded, leaving them to be resumed by the next active node
+func (b *backend) stopRewrapJobs() {
+	b.rewrapStopOnce.Do(func() {
+		close(b.rewrapStopCh)
+	})
+}
+
+const pathRewrapAllHelpSyn = `Rewrap a batch of ciphertexts in the background`
+
+const pathRewrapAllHelpDesc = `
+This path starts a background job that rewraps every ciphertext in
+"batch_input" with the latest version of the named key, and returns
+the ID of the job. Its progress and, once finished, its results can
+be read from "keys/<name>/rewrap-all/<job_id>".
+`
+
+const pathRewrapAllStatusHelpSyn = `Read or cancel a background rewrap job`
+
+const pathRewrapAllStatusHelpDesc = `
+Reading this path returns the progress of a rewrap job and, once it
+has finished, the rewrapped ciphertexts in the order they were
+submitted. Jobs are persisted, and resumed by the active node if
+interrupted. Results are kept for an hour after the job finishes.
+
+Deleting this path cancels a running job, keeping the results
+completed so far, or discards the results of a finished job.
+`
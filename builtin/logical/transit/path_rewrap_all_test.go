@@ -0,0 +1,224 @@
+package transit
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestTransit_RewrapAll(t *testing.T) {
+	b, s := createBackendWithStorage(t)
+
+	doReq := func(op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		resp, err := b.HandleRequest(&logical.Request{
+			Operation: op,
+			Path:      path,
+			Storage:   s,
+			Data:      data,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: %s: err: %v resp: %#v", path, err, resp)
+		}
+		return resp
+	}
+
+	doReq(logical.UpdateOperation, "keys/test", nil)
+
+	// Encrypt more items than fit in one chunk
+	count := rewrapJobChunkSize + 10
+	var encInput []interface{}
+	for i := 0; i < count; i++ {
+		encInput = append(encInput, map[string]interface{}{
+			"plaintext": "dGhlIHF1aWNrIGJyb3duIGZveA==",
+		})
+	}
+	resp := doReq(logical.UpdateOperation, "encrypt/test", map[string]interface{}{
+		"batch_input": encInput,
+	})
+	var rewrapInput []interface{}
+	for _, item := range resp.Data["batch_results"].([]BatchResponseItem) {
+		rewrapInput = append(rewrapInput, map[string]interface{}{
+			"ciphertext": item.Ciphertext,
+		})
+	}
+	// One bad item is reported without failing the job
+	rewrapInput = append(rewrapInput, map[string]interface{}{
+		"ciphertext": "vault:v1:invalid",
+	})
+
+	doReq(logical.UpdateOperation, "keys/test/rotate", nil)
+
+	resp = doReq(logical.UpdateOperation, "keys/test/rewrap-all", map[string]interface{}{
+		"batch_input": rewrapInput,
+	})
+	jobID := resp.Data["job_id"].(string)
+	if resp.Data["total"].(int) != count+1 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Wait for the job to finish
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		resp = doReq(logical.ReadOperation, "keys/test/rewrap-all/"+jobID, nil)
+		if resp.Data["status"] != rewrapJobStatusRunning {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for rewrap job")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if resp.Data["status"] != rewrapJobStatusComplete {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if resp.Data["processed"].(int) != count+1 || resp.Data["failed"].(int) != 1 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	results := resp.Data["batch_results"].([]BatchResponseItem)
+	for i, item := range results[:count] {
+		if !strings.HasPrefix(item.Ciphertext, "vault:v2:") || item.Error != "" {
+			t.Fatalf("bad: item %d: %#v", i, item)
+		}
+	}
+	if results[count].Error == "" {
+		t.Fatalf("expected error for invalid ciphertext: %#v", results[count])
+	}
+
+	// Rewrapped ciphertexts still decrypt
+	resp = doReq(logical.UpdateOperation, "decrypt/test", map[string]interface{}{
+		"ciphertext": results[0].Ciphertext,
+	})
+	if resp.Data["plaintext"] != "dGhlIHF1aWNrIGJyb3duIGZveA==" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Jobs are only visible under their own key
+	resp = doReq(logical.ReadOperation, "keys/other/rewrap-all/"+jobID, nil)
+	if resp != nil {
+		t.Fatalf("expected no job under another key: %#v", resp)
+	}
+
+	// Deleting a finished job discards it
+	doReq(logical.DeleteOperation, "keys/test/rewrap-all/"+jobID, nil)
+	resp = doReq(logical.ReadOperation, "keys/test/rewrap-all/"+jobID, nil)
+	if resp != nil {
+		t.Fatalf("expected job to be deleted: %#v", resp)
+	}
+}
+
+func TestTransit_RewrapAll_Tidy(t *testing.T) {
+	b, s := createBackendWithStorage(t)
+
+	now := time.Now()
+	for _, entry := range []rewrapJobEntry{
+		{ID: "recent", Name: "test", Status: rewrapJobStatusComplete, EndTime: now},
+		{ID: "old", Name: "test", Status: rewrapJobStatusCancelled, EndTime: now.Add(-2 * rewrapJobRetention)},
+	} {
+		if err := b.storeRewrapJob(s, newRewrapJob(entry)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := b.tidyRewrapJobs(s, now); err != nil {
+		t.Fatal(err)
+	}
+
+	ids, err := s.List(rewrapJobPrefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 1 || ids[0] != "recent" {
+		t.Fatalf("expected only the recent job to be kept: %v", ids)
+	}
+}
+
+func TestTransit_RewrapAll_Resume(t *testing.T) {
+	b, s := createBackendWithStorage(t)
+
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "keys/test",
+		Storage:   s,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: err: %v resp: %#v", err, resp)
+	}
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "encrypt/test",
+		Storage:   s,
+		Data: map[string]interface{}{
+			"plaintext": "dGhlIHF1aWNrIGJyb3duIGZveA==",
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: err: %v resp: %#v", err, resp)
+	}
+	ciphertext := resp.Data["ciphertext"].(string)
+
+	// A job interrupted after its first chunk, as left in storage by a node
+	// that was sealed
+	count := rewrapJobChunkSize + 10
+	entry := rewrapJobEntry{
+		ID:        "interrupted",
+		Name:      "test",
+		Status:    rewrapJobStatusRunning,
+		StartTime: time.Now(),
+		Processed: rewrapJobChunkSize,
+		Items:     make([]BatchRequestItem, count),
+		Results:   make([]BatchResponseItem, count),
+	}
+	for i := range entry.Items {
+		entry.Items[i].Ciphertext = ciphertext
+		if i < rewrapJobChunkSize {
+			entry.Results[i].Ciphertext = "vault:v1:done"
+		}
+	}
+	if err := b.storeRewrapJob(s, newRewrapJob(entry)); err != nil {
+		t.Fatal(err)
+	}
+
+	// A new backend, as mounted on the next active node, resumes it
+	config := logical.TestBackendConfig()
+	config.StorageView = s
+	b2 := Backend(config)
+	if err := b2.Backend.Setup(config); err != nil {
+		t.Fatal(err)
+	}
+	if err := b2.tidyRewrapJobs(s, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		resp, err = b2.HandleRequest(&logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "keys/test/rewrap-all/interrupted",
+			Storage:   s,
+		})
+		if err != nil || resp == nil {
+			t.Fatalf("bad: err: %v resp: %#v", err, resp)
+		}
+		if resp.Data["status"] != rewrapJobStatusRunning {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for rewrap job")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if resp.Data["status"] != rewrapJobStatusComplete || resp.Data["processed"].(int) != count {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	results := resp.Data["batch_results"].([]BatchResponseItem)
+	if results[0].Ciphertext != "vault:v1:done" {
+		t.Fatalf("expected processed items to be kept: %#v", results[0])
+	}
+	if !strings.HasPrefix(results[count-1].Ciphertext, "vault:v1:") || results[count-1].Error != "" {
+		t.Fatalf("bad: %#v", results[count-1])
+	}
+}
@@ -1,13 +1,12 @@
 package transit
 
 import (
-	"crypto/sha256"
-	"crypto/sha512"
+	"crypto"
 	"encoding/base64"
 	"fmt"
-	"hash"
 
 	"github.com/hashicorp/vault/helper/errutil"
+	"github.com/hashicorp/vault/helper/keysutil"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
 )
@@ -44,7 +43,7 @@ derivation is enabled; currently only available with ed25519 keys.`,
 * sha2-512
 
 Defaults to "sha2-256". Not valid for all key types,
-including ed25519.`,
+including ed25519. RSA keys do not support "none".`,
 			},
 
 			"urlalgorithm": &framework.FieldSchema{
@@ -168,27 +167,28 @@ func (b *backend) pathSignWrite(
 		}
 	}
 
-	if p.Type.HashSignatureInput() && algorithm != "none" {
-		var hf hash.Hash
-		switch algorithm {
-		case "sha2-224":
-			hf = sha256.New224()
-		case "sha2-256":
-			hf = sha256.New()
-		case "sha2-384":
-			hf = sha512.New384()
-		case "sha2-512":
-			hf = sha512.New()
-		default:
+	var hashAlgorithm crypto.Hash
+	if p.Type.HashSignatureInput() {
+		var ok bool
+		hashAlgorithm, ok = keysutil.HashAlgorithm(algorithm)
+		if !ok {
 			return logical.ErrorResponse(fmt.Sprintf("unsupported algorithm %s", algorithm)), nil
 		}
-		hf.Write(input)
-		input = hf.Sum(nil)
+		if hashAlgorithm != crypto.Hash(0) {
+			hf := hashAlgorithm.New()
+			hf.Write(input)
+			input = hf.Sum(nil)
+		}
 	}
 
-	sig, err := p.Sign(ver, context, input)
+	sig, err := p.Sign(ver, context, input, hashAlgorithm)
 	if err != nil {
-		return nil, err
+		switch err.(type) {
+		case errutil.UserError:
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		default:
+			return nil, err
+		}
 	}
 	if sig == nil {
 		return nil, fmt.Errorf("signature could not be computed")
@@ -261,25 +261,21 @@ func (b *backend) pathVerifyWrite(
 		}
 	}
 
-	if p.Type.HashSignatureInput() && algorithm != "none" {
-		var hf hash.Hash
-		switch algorithm {
-		case "sha2-224":
-			hf = sha256.New224()
-		case "sha2-256":
-			hf = sha256.New()
-		case "sha2-384":
-			hf = sha512.New384()
-		case "sha2-512":
-			hf = sha512.New()
-		default:
+	var hashAlgorithm crypto.Hash
+	if p.Type.HashSignatureInput() {
+		var ok bool
+		hashAlgorithm, ok = keysutil.HashAlgorithm(algorithm)
+		if !ok {
 			return logical.ErrorResponse(fmt.Sprintf("unsupported algorithm %s", algorithm)), nil
 		}
-		hf.Write(input)
-		input = hf.Sum(nil)
+		if hashAlgorithm != crypto.Hash(0) {
+			hf := hashAlgorithm.New()
+			hf.Write(input)
+			input = hf.Sum(nil)
+		}
 	}
 
-	valid, err := p.VerifySignature(context, input, sig)
+	valid, err := p.VerifySignature(context, input, sig, hashAlgorithm)
 	if err != nil {
 		switch err.(type) {
 		case errutil.UserError:
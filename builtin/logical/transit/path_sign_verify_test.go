@@ -415,3 +415,74 @@ func TestTransit_SignVerify_ED25519(t *testing.T) {
 	verifyRequest(req, false, "bar", sig)
 	verifyRequest(req, true, "bar", v1sig)
 }
+
+func TestTransit_SignVerify_RSA(t *testing.T) {
+	var b *backend
+	sysView := logical.TestSystemView()
+	storage := &logical.InmemStorage{}
+
+	b = Backend(&logical.BackendConfig{
+		StorageView: storage,
+		System:      sysView,
+	})
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/foo",
+		Data: map[string]interface{}{
+			"type": "rsa-2048",
+		},
+	}
+	_, err := b.HandleRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := base64.StdEncoding.EncodeToString([]byte("the quick brown fox"))
+
+	for _, algorithm := range []string{"sha2-224", "sha2-256", "sha2-384", "sha2-512"} {
+		req.Path = "sign/foo/" + algorithm
+		req.Data = map[string]interface{}{
+			"input": input,
+		}
+		resp, err := b.HandleRequest(req)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: err: %v\nresp: %#v", err, resp)
+		}
+		sig := resp.Data["signature"].(string)
+		if !strings.HasPrefix(sig, "vault:v1:") {
+			t.Fatalf("bad signature: %s", sig)
+		}
+
+		req.Path = "verify/foo/" + algorithm
+		req.Data["signature"] = sig
+		resp, err = b.HandleRequest(req)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: err: %v\nresp: %#v", err, resp)
+		}
+		if !resp.Data["valid"].(bool) {
+			t.Fatalf("signature did not verify with %s", algorithm)
+		}
+
+		// Tampered input must fail
+		req.Data["input"] = base64.StdEncoding.EncodeToString([]byte("the quick brown fix"))
+		resp, err = b.HandleRequest(req)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: err: %v\nresp: %#v", err, resp)
+		}
+		if resp.Data["valid"].(bool) {
+			t.Fatalf("tampered input verified with %s", algorithm)
+		}
+	}
+
+	// RSA keys require a hash algorithm
+	req.Path = "sign/foo/none"
+	req.Data = map[string]interface{}{
+		"input": input,
+	}
+	resp, err := b.HandleRequest(req)
+	if err == nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected error signing with no hash algorithm, got: %#v", resp)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"sync"
 
 	uuid "github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/helper/pluginutil"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
 	bplugin "github.com/hashicorp/vault/logical/plugin"
@@ -40,10 +41,11 @@ func Backend(conf *logical.BackendConfig) (logical.Backend, error) {
 	var b backend
 
 	name := conf.Config["plugin_name"]
+	version := conf.Config["plugin_version"]
 	sys := conf.System
 
-	// NewBackend with isMetadataMode set to true
-	raw, err := bplugin.NewBackend(name, sys, conf.Logger, true)
+	// NewBackendVersion with isMetadataMode set to true
+	raw, err := bplugin.NewBackendVersion(name, version, sys, conf.Logger, true)
 	if err != nil {
 		return nil, err
 	}
@@ -82,6 +84,10 @@ type backend struct {
 
 	// Used to detect if plugin is set
 	loaded bool
+
+	// Stops the monitoring of the plugin process, set once the process has
+	// been started
+	stopMonitor func()
 }
 
 func (b *backend) reloadBackend() error {
@@ -92,11 +98,12 @@ func (b *backend) reloadBackend() error {
 // startBackend starts a plugin backend
 func (b *backend) startBackend() error {
 	pluginName := b.config.Config["plugin_name"]
+	pluginVersion := b.config.Config["plugin_version"]
 
 	// Ensure proper cleanup of the backend (i.e. call client.Kill())
 	b.Backend.Cleanup()
 
-	nb, err := bplugin.NewBackend(pluginName, b.config.System, b.config.Logger, false)
+	nb, err := bplugin.NewBackendVersion(pluginName, pluginVersion, b.config.System, b.config.Logger, false)
 	if err != nil {
 		return err
 	}
@@ -123,6 +130,8 @@ func (b *backend) startBackend() error {
 	b.Backend = nb
 	b.loaded = true
 
+	b.monitorBackend()
+
 	// Call initialize
 	if err := b.Backend.Initialize(); err != nil {
 		return err
@@ -131,6 +140,71 @@ func (b *backend) startBackend() error {
 	return nil
 }
 
+// monitorBackend registers the plugin process with the health monitor of the
+// core, which restarts it if it crashes. Builtin plugins run in Vault's
+// process and are not monitored.
+func (b *backend) monitorBackend() {
+	if b.stopMonitor != nil {
+		return
+	}
+	if _, ok := b.Backend.(*bplugin.BackendPluginClient); !ok {
+		return
+	}
+	monitor, ok := b.config.System.(pluginutil.HealthMonitor)
+	if !ok {
+		return
+	}
+
+	b.stopMonitor = monitor.MonitorPlugin(&pluginutil.MonitoredPlugin{
+		Name:    b.config.Config["plugin_name"],
+		Ping:    b.pingBackend,
+		Restart: b.restartBackend,
+	})
+}
+
+// pingBackend probes the plugin process. The lock is not held during the
+// call so that a process which hangs doesn't block its restart.
+func (b *backend) pingBackend() error {
+	b.RLock()
+	client, ok := b.Backend.(*bplugin.BackendPluginClient)
+	b.RUnlock()
+	if !ok {
+		return nil
+	}
+	return client.Ping()
+}
+
+// restartBackend replaces the plugin process on behalf of the health monitor
+func (b *backend) restartBackend() error {
+	b.Lock()
+	defer b.Unlock()
+
+	// The backend was cleaned up while the restart was pending
+	if b.stopMonitor == nil {
+		return nil
+	}
+
+	if err := b.reloadBackend(); err != nil {
+		return err
+	}
+
+	var err error
+	b.canary, err = uuid.GenerateUUID()
+	return err
+}
+
+// Cleanup stops monitoring the plugin process and cleans the backend up
+func (b *backend) Cleanup() {
+	b.Lock()
+	defer b.Unlock()
+
+	if b.stopMonitor != nil {
+		b.stopMonitor()
+		b.stopMonitor = nil
+	}
+	b.Backend.Cleanup()
+}
+
 // HandleRequest is a thin wrapper implementation of HandleRequest that includes automatic plugin reload.
 func (b *backend) HandleRequest(req *logical.Request) (*logical.Response, error) {
 	b.RLock()
@@ -6,16 +6,18 @@ import (
 	auditFile "github.com/hashicorp/vault/builtin/audit/file"
 	auditSocket "github.com/hashicorp/vault/builtin/audit/socket"
 	auditSyslog "github.com/hashicorp/vault/builtin/audit/syslog"
+	auditWebhook "github.com/hashicorp/vault/builtin/audit/webhook"
 	"github.com/hashicorp/vault/physical"
 	"github.com/hashicorp/vault/version"
 
 	credGcp "github.com/hashicorp/vault-plugin-auth-gcp/plugin"
-	credKube "github.com/hashicorp/vault-plugin-auth-kubernetes"
 	credAppId "github.com/hashicorp/vault/builtin/credential/app-id"
 	credAppRole "github.com/hashicorp/vault/builtin/credential/approle"
 	credAws "github.com/hashicorp/vault/builtin/credential/aws"
 	credCert "github.com/hashicorp/vault/builtin/credential/cert"
 	credGitHub "github.com/hashicorp/vault/builtin/credential/github"
+	credJWT "github.com/hashicorp/vault/builtin/credential/jwt"
+	credKube "github.com/hashicorp/vault/builtin/credential/kubernetes"
 	credLdap "github.com/hashicorp/vault/builtin/credential/ldap"
 	credOkta "github.com/hashicorp/vault/builtin/credential/okta"
 	credRadius "github.com/hashicorp/vault/builtin/credential/radius"
@@ -34,6 +36,7 @@ import (
 	physMSSQL "github.com/hashicorp/vault/physical/mssql"
 	physMySQL "github.com/hashicorp/vault/physical/mysql"
 	physPostgreSQL "github.com/hashicorp/vault/physical/postgresql"
+	physRaft "github.com/hashicorp/vault/physical/raft"
 	physS3 "github.com/hashicorp/vault/physical/s3"
 	physSwift "github.com/hashicorp/vault/physical/swift"
 	physZooKeeper "github.com/hashicorp/vault/physical/zookeeper"
@@ -42,6 +45,7 @@ import (
 	"github.com/hashicorp/vault/builtin/logical/cassandra"
 	"github.com/hashicorp/vault/builtin/logical/consul"
 	"github.com/hashicorp/vault/builtin/logical/database"
+	"github.com/hashicorp/vault/builtin/logical/kubernetes"
 	"github.com/hashicorp/vault/builtin/logical/mongodb"
 	"github.com/hashicorp/vault/builtin/logical/mssql"
 	"github.com/hashicorp/vault/builtin/logical/mysql"
@@ -49,6 +53,7 @@ import (
 	"github.com/hashicorp/vault/builtin/logical/postgresql"
 	"github.com/hashicorp/vault/builtin/logical/rabbitmq"
 	"github.com/hashicorp/vault/builtin/logical/ssh"
+	"github.com/hashicorp/vault/builtin/logical/tokenbroker"
 	"github.com/hashicorp/vault/builtin/logical/totp"
 	"github.com/hashicorp/vault/builtin/logical/transit"
 	"github.com/hashicorp/vault/builtin/plugin"
@@ -60,6 +65,33 @@ import (
 	"github.com/mitchellh/cli"
 )
 
+// physicalBackends are the storage backends available to the server and the
+// storage migration
+var physicalBackends = map[string]physical.Factory{
+	"azure":                  physAzure.NewAzureBackend,
+	"cassandra":              physCassandra.NewCassandraBackend,
+	"cockroachdb":            physCockroachDB.NewCockroachDBBackend,
+	"consul":                 physConsul.NewConsulBackend,
+	"couchdb":                physCouchDB.NewCouchDBBackend,
+	"couchdb_transactional":  physCouchDB.NewTransactionalCouchDBBackend,
+	"dynamodb":               physDynamoDB.NewDynamoDBBackend,
+	"etcd":                   physEtcd.NewEtcdBackend,
+	"file":                   physFile.NewFileBackend,
+	"file_transactional":     physFile.NewTransactionalFileBackend,
+	"gcs":                    physGCS.NewGCSBackend,
+	"inmem":                  physInmem.NewInmem,
+	"inmem_ha":               physInmem.NewInmemHA,
+	"inmem_transactional":    physInmem.NewTransactionalInmem,
+	"inmem_transactional_ha": physInmem.NewTransactionalInmemHA,
+	"mssql":                  physMSSQL.NewMSSQLBackend,
+	"mysql":                  physMySQL.NewMySQLBackend,
+	"postgresql":             physPostgreSQL.NewPostgreSQLBackend,
+	"raft":                   physRaft.NewRaftBackend,
+	"s3":                     physS3.NewS3Backend,
+	"swift":                  physSwift.NewSwiftBackend,
+	"zookeeper":              physZooKeeper.NewZooKeeperBackend,
+}
+
 // Commands returns the mapping of CLI commands for Vault. The meta
 // parameter lets you set meta options for all commands.
 func Commands(metaPtr *meta.Meta) map[string]cli.CommandFactory {
@@ -86,9 +118,10 @@ func Commands(metaPtr *meta.Meta) map[string]cli.CommandFactory {
 			c := &command.ServerCommand{
 				Meta: *metaPtr,
 				AuditBackends: map[string]audit.Factory{
-					"file":   auditFile.Factory,
-					"syslog": auditSyslog.Factory,
-					"socket": auditSocket.Factory,
+					"file":    auditFile.Factory,
+					"syslog":  auditSyslog.Factory,
+					"socket":  auditSocket.Factory,
+					"webhook": auditWebhook.Factory,
 				},
 				CredentialBackends: map[string]logical.Factory{
 					"approle":    credAppRole.Factory,
@@ -97,6 +130,7 @@ func Commands(metaPtr *meta.Meta) map[string]cli.CommandFactory {
 					"app-id":     credAppId.Factory,
 					"gcp":        credGcp.Factory,
 					"github":     credGitHub.Factory,
+					"jwt":        credJWT.Factory,
 					"userpass":   credUserpass.Factory,
 					"ldap":       credLdap.Factory,
 					"okta":       credOkta.Factory,
@@ -105,53 +139,40 @@ func Commands(metaPtr *meta.Meta) map[string]cli.CommandFactory {
 					"plugin":     plugin.Factory,
 				},
 				LogicalBackends: map[string]logical.Factory{
-					"aws":        aws.Factory,
-					"consul":     consul.Factory,
-					"postgresql": postgresql.Factory,
-					"cassandra":  cassandra.Factory,
-					"pki":        pki.Factory,
-					"transit":    transit.Factory,
-					"mongodb":    mongodb.Factory,
-					"mssql":      mssql.Factory,
-					"mysql":      mysql.Factory,
-					"ssh":        ssh.Factory,
-					"rabbitmq":   rabbitmq.Factory,
-					"database":   database.Factory,
-					"totp":       totp.Factory,
-					"plugin":     plugin.Factory,
+					"aws":         aws.Factory,
+					"consul":      consul.Factory,
+					"postgresql":  postgresql.Factory,
+					"cassandra":   cassandra.Factory,
+					"pki":         pki.Factory,
+					"transit":     transit.Factory,
+					"mongodb":     mongodb.Factory,
+					"mssql":       mssql.Factory,
+					"mysql":       mysql.Factory,
+					"ssh":         ssh.Factory,
+					"rabbitmq":    rabbitmq.Factory,
+					"database":    database.Factory,
+					"totp":        totp.Factory,
+					"kubernetes":  kubernetes.Factory,
+					"tokenbroker": tokenbroker.Factory,
+					"plugin":      plugin.Factory,
 				},
 
 				ShutdownCh: command.MakeShutdownCh(),
 				SighupCh:   command.MakeSighupCh(),
 			}
 
-			c.PhysicalBackends = map[string]physical.Factory{
-				"azure":                  physAzure.NewAzureBackend,
-				"cassandra":              physCassandra.NewCassandraBackend,
-				"cockroachdb":            physCockroachDB.NewCockroachDBBackend,
-				"consul":                 physConsul.NewConsulBackend,
-				"couchdb":                physCouchDB.NewCouchDBBackend,
-				"couchdb_transactional":  physCouchDB.NewTransactionalCouchDBBackend,
-				"dynamodb":               physDynamoDB.NewDynamoDBBackend,
-				"etcd":                   physEtcd.NewEtcdBackend,
-				"file":                   physFile.NewFileBackend,
-				"file_transactional":     physFile.NewTransactionalFileBackend,
-				"gcs":                    physGCS.NewGCSBackend,
-				"inmem":                  physInmem.NewInmem,
-				"inmem_ha":               physInmem.NewInmemHA,
-				"inmem_transactional":    physInmem.NewTransactionalInmem,
-				"inmem_transactional_ha": physInmem.NewTransactionalInmemHA,
-				"mssql":                  physMSSQL.NewMSSQLBackend,
-				"mysql":                  physMySQL.NewMySQLBackend,
-				"postgresql":             physPostgreSQL.NewPostgreSQLBackend,
-				"s3":                     physS3.NewS3Backend,
-				"swift":                  physSwift.NewSwiftBackend,
-				"zookeeper":              physZooKeeper.NewZooKeeperBackend,
-			}
+			c.PhysicalBackends = physicalBackends
 
 			return c, nil
 		},
 
+		"agent": func() (cli.Command, error) {
+			return &command.AgentCommand{
+				Meta:       *metaPtr,
+				ShutdownCh: command.MakeShutdownCh(),
+			}, nil
+		},
+
 		"ssh": func() (cli.Command, error) {
 			return &command.SSHCommand{
 				Meta: *metaPtr,
@@ -169,6 +190,7 @@ func Commands(metaPtr *meta.Meta) map[string]cli.CommandFactory {
 				Meta: *metaPtr,
 				Handlers: map[string]command.AuthHandler{
 					"github":   &credGitHub.CLIHandler{},
+					"jwt":      &credJWT.CLIHandler{},
 					"userpass": &credUserpass.CLIHandler{DefaultMount: "userpass"},
 					"ldap":     &credLdap.CLIHandler{},
 					"okta":     &credOkta.CLIHandler{},
@@ -209,6 +231,12 @@ func Commands(metaPtr *meta.Meta) map[string]cli.CommandFactory {
 			}, nil
 		},
 
+		"audit-verify": func() (cli.Command, error) {
+			return &command.AuditVerifyCommand{
+				Meta: *metaPtr,
+			}, nil
+		},
+
 		"key-status": func() (cli.Command, error) {
 			return &command.KeyStatusCommand{
 				Meta: *metaPtr,
@@ -263,6 +291,54 @@ func Commands(metaPtr *meta.Meta) map[string]cli.CommandFactory {
 			}, nil
 		},
 
+		"kv get": func() (cli.Command, error) {
+			return &command.KVGetCommand{
+				Meta: *metaPtr,
+			}, nil
+		},
+
+		"kv put": func() (cli.Command, error) {
+			return &command.KVPutCommand{
+				Meta: *metaPtr,
+			}, nil
+		},
+
+		"kv patch": func() (cli.Command, error) {
+			return &command.KVPatchCommand{
+				Meta: *metaPtr,
+			}, nil
+		},
+
+		"kv delete": func() (cli.Command, error) {
+			return &command.KVDeleteCommand{
+				Meta: *metaPtr,
+			}, nil
+		},
+
+		"kv metadata get": func() (cli.Command, error) {
+			return &command.KVMetadataGetCommand{
+				Meta: *metaPtr,
+			}, nil
+		},
+
+		"kv metadata put": func() (cli.Command, error) {
+			return &command.KVMetadataPutCommand{
+				Meta: *metaPtr,
+			}, nil
+		},
+
+		"kv metadata delete": func() (cli.Command, error) {
+			return &command.KVMetadataDeleteCommand{
+				Meta: *metaPtr,
+			}, nil
+		},
+
+		"kv rollback": func() (cli.Command, error) {
+			return &command.KVRollbackCommand{
+				Meta: *metaPtr,
+			}, nil
+		},
+
 		"rekey": func() (cli.Command, error) {
 			return &command.RekeyCommand{
 				Meta: *metaPtr,
@@ -311,6 +387,21 @@ func Commands(metaPtr *meta.Meta) map[string]cli.CommandFactory {
 			}, nil
 		},
 
+		"debug": func() (cli.Command, error) {
+			return &command.DebugCommand{
+				Meta:       *metaPtr,
+				ShutdownCh: command.MakeShutdownCh(),
+			}, nil
+		},
+
+		"operator migrate": func() (cli.Command, error) {
+			return &command.OperatorMigrateCommand{
+				Meta:             *metaPtr,
+				PhysicalBackends: physicalBackends,
+				ShutdownCh:       command.MakeShutdownCh(),
+			}, nil
+		},
+
 		"mount": func() (cli.Command, error) {
 			return &command.MountCommand{
 				Meta: *metaPtr,
@@ -381,8 +472,8 @@ func Commands(metaPtr *meta.Meta) map[string]cli.CommandFactory {
 			versionInfo := version.GetVersion()
 
 			return &command.VersionCommand{
+				Meta:        *metaPtr,
 				VersionInfo: versionInfo,
-				Ui:          metaPtr.Ui,
 			}, nil
 		},
 	}
@@ -0,0 +1,307 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/command/agent"
+	"github.com/hashicorp/vault/command/server"
+	"github.com/hashicorp/vault/helper/logformat"
+	"github.com/hashicorp/vault/meta"
+)
+
+// AgentCommand is a Command that authenticates to Vault on behalf of an
+// application and keeps a token available to it.
+type AgentCommand struct {
+	meta.Meta
+
+	ShutdownCh chan struct{}
+}
+
+func (c *AgentCommand) Run(args []string) int {
+	var configPath, logLevel string
+	flags := c.Meta.FlagSet("agent", meta.FlagSetDefault)
+	flags.StringVar(&configPath, "config", "", "")
+	flags.StringVar(&logLevel, "log-level", "info", "")
+	flags.Usage = func() { c.Ui.Error(c.Help()) }
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	if configPath == "" {
+		c.Ui.Error("The -config flag is required")
+		flags.Usage()
+		return 1
+	}
+
+	level, err := parseLogLevel(strings.ToLower(strings.TrimSpace(logLevel)))
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+	logger := logformat.NewVaultLoggerWithWriter(os.Stderr, level)
+
+	config, err := agent.LoadConfig(configPath)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error loading configuration from %s: %s", configPath, err))
+		return 1
+	}
+
+	method, err := agent.NewAuthMethod(config.AutoAuth.Method)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error configuring the %s auth method: %s",
+			config.AutoAuth.Method.Type, err))
+		return 1
+	}
+	var sinks []*agent.SinkTarget
+	for _, sc := range config.AutoAuth.Sinks {
+		sink, err := agent.NewSink(sc)
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Error configuring the %s sink: %s", sc.Type, err))
+			return 1
+		}
+		sinks = append(sinks, sink)
+	}
+
+	// The auth handler and the servers consuming its tokens each have a
+	// client, since they set its token concurrently. The agent authenticates
+	// by itself, and wraps only the tokens written to the sinks configured
+	// with a wrap_ttl.
+	newClient := func() (*api.Client, error) {
+		client, err := c.Client()
+		if err != nil {
+			return nil, err
+		}
+		client.ClearToken()
+		client.SetWrappingLookupFunc(func(string, string) string { return "" })
+		return client, nil
+	}
+	authClient, err := newClient()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	// Every server runs with a channel receiving the tokens of the handler
+	var servers []func(context.Context, <-chan string)
+
+	sinkClient, err := newClient()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+	sinkServer := &agent.SinkServer{
+		Client:        sinkClient,
+		Sinks:         sinks,
+		Logger:        logger,
+		ExitAfterAuth: config.ExitAfterAuth,
+	}
+	servers = append(servers, sinkServer.Run)
+
+	if len(config.Templates) > 0 {
+		templateClient, err := newClient()
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+			return 1
+		}
+		templateServer, err := agent.NewTemplateServer(&agent.TemplateServerConfig{
+			Client:                     templateClient,
+			Templates:                  config.Templates,
+			StaticSecretRenderInterval: config.StaticSecretRenderInterval,
+			ExitAfterAuth:              config.ExitAfterAuth,
+			Logger:                     logger,
+		})
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Error configuring the templates: %s", err))
+			return 1
+		}
+		servers = append(servers, templateServer.Run)
+	}
+
+	// The listeners serve a proxy to Vault, caching leases and tokens
+	var lns []net.Listener
+	defer func() {
+		for _, ln := range lns {
+			ln.Close()
+		}
+	}()
+	if config.Cache != nil {
+		proxyClient, err := newClient()
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+			return 1
+		}
+		proxy := agent.NewProxy(&agent.ProxyConfig{
+			Client:           proxyClient,
+			UseAutoAuthToken: config.Cache.UseAutoAuthToken,
+			Logger:           logger,
+		})
+		servers = append(servers, proxy.Run)
+
+		for _, lnConfig := range config.Listeners {
+			ln, props, _, err := server.NewListener(lnConfig.Type, lnConfig.Config, os.Stderr)
+			if err != nil {
+				c.Ui.Error(fmt.Sprintf("Error initializing listener of type %s: %s",
+					lnConfig.Type, err))
+				return 1
+			}
+			lns = append(lns, ln)
+			c.Ui.Output(fmt.Sprintf("==> Vault agent listening on %s (%s)", props["addr"], lnConfig.Type))
+
+			srv := &http.Server{
+				Handler:      proxy,
+				ReadTimeout:  agent.ProxyReadTimeout,
+				WriteTimeout: agent.ProxyWriteTimeout,
+			}
+			go srv.Serve(ln)
+		}
+	}
+
+	if err := storePidFile(config.PidFile); err != nil {
+		c.Ui.Error(fmt.Sprintf("Error storing PID: %v", err))
+		return 1
+	}
+	defer func() {
+		if err := removePidFile(config.PidFile); err != nil {
+			c.Ui.Error(fmt.Sprintf("Error deleting the PID file: %v", err))
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	tokenCh := make(chan string)
+	handler := &agent.AuthHandler{
+		Client:    authClient,
+		Method:    method,
+		MountPath: config.AutoAuth.Method.MountPath,
+		Logger:    logger,
+		OutputCh:  tokenCh,
+	}
+	go handler.Run(ctx)
+
+	var wg sync.WaitGroup
+	var serverChs []chan string
+	for _, run := range servers {
+		ch := make(chan string, 1)
+		serverChs = append(serverChs, ch)
+		wg.Add(1)
+		go func(run func(context.Context, <-chan string)) {
+			defer wg.Done()
+			run(ctx, ch)
+		}(run)
+	}
+
+	// The tokens are sent to every server. A server still busy with the
+	// previous token receives the latest one once it is done.
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case token := <-tokenCh:
+				for _, ch := range serverChs {
+					select {
+					case <-ch:
+					default:
+					}
+					ch <- token
+				}
+			}
+		}
+	}()
+
+	doneCh := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(doneCh)
+	}()
+
+	c.Ui.Output(fmt.Sprintf("==> Vault agent started! Authenticating with the %s auth method at %s",
+		config.AutoAuth.Method.Type, authClient.Address()))
+
+	select {
+	case <-c.ShutdownCh:
+		c.Ui.Output("==> Vault agent shutdown triggered")
+		cancel()
+		<-doneCh
+	case <-doneCh:
+	}
+
+	return 0
+}
+
+func (c *AgentCommand) Synopsis() string {
+	return "Authenticate to Vault and keep a token available to applications"
+}
+
+func (c *AgentCommand) Help() string {
+	helpText := `
+Usage: vault agent [options]
+
+  Start a Vault agent, which authenticates to Vault with an auth method on
+  behalf of the applications of the machine, renews the token, and writes it
+  to sinks such as files, from which the applications read it. The agent
+  authenticates again when the token can no longer be renewed, so that the
+  applications don't need to embed the login logic.
+
+  The agent is configured in a file holding an auto_auth block, with the auth
+  method and the sinks:
+
+      auto_auth {
+        method "approle" {
+          role_id_file_path   = "/etc/vault/role-id"
+          secret_id_file_path = "/etc/vault/secret-id"
+        }
+
+        sink "file" {
+          path = "/var/run/vault/token"
+        }
+      }
+
+  The approle, aws and kubernetes auth methods are supported. The sinks with a
+  wrap_ttl receive the response-wrapping information of the token instead of
+  the token itself.
+
+  The agent can also render template blocks into files with the secrets read
+  using its token, render them again before the secrets expire, and run a
+  command when a file changes:
+
+      template {
+        source      = "/etc/vault/app.ctmpl"
+        destination = "/etc/app/app.conf"
+        command     = "systemctl reload app"
+      }
+
+  With a cache block and listener blocks, the agent serves a proxy to Vault,
+  which attaches the token of the agent to the requests without a token if
+  use_auto_auth_token is set, and caches the responses holding leases or
+  tokens, renewing them until they expire:
+
+      cache {
+        use_auto_auth_token = true
+      }
+
+      listener "tcp" {
+        address     = "127.0.0.1:8100"
+        tls_disable = true
+      }
+
+General Options:
+` + meta.GeneralOptionsUsage() + `
+Agent Options:
+
+  -config=<path>          Path of the agent configuration file. Required.
+
+  -log-level=info         Log verbosity. Defaults to "info", can be set to
+                          one of: "trace", "debug", "info", "notice", "warn",
+                          or "err".
+`
+	return strings.TrimSpace(helpText)
+}
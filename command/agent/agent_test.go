@@ -0,0 +1,233 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	credAppRole "github.com/hashicorp/vault/builtin/credential/approle"
+	"github.com/hashicorp/vault/helper/logformat"
+	vaulthttp "github.com/hashicorp/vault/http"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/vault"
+	log "github.com/mgutz/logxi/v1"
+)
+
+func TestAgent_appRole(t *testing.T) {
+	cluster := vault.NewTestCluster(t, &vault.CoreConfig{
+		CredentialBackends: map[string]logical.Factory{
+			"approle": credAppRole.Factory,
+		},
+	}, &vault.TestClusterOptions{
+		HandlerFunc: vaulthttp.Handler,
+	})
+	cluster.Start()
+	defer cluster.Cleanup()
+
+	client := cluster.Cores[0].Client
+	if err := client.Sys().EnableAuthWithOptions("approle", &api.EnableAuthOptions{
+		Type: "approle",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Logical().Write("auth/approle/role/test", map[string]interface{}{
+		"policies":  "default",
+		"token_ttl": "1h",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	secret, err := client.Logical().Read("auth/approle/role/test/role-id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	roleID := secret.Data["role_id"].(string)
+	secret, err = client.Logical().Write("auth/approle/role/test/secret-id", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secretID := secret.Data["secret_id"].(string)
+
+	dir, err := ioutil.TempDir("", "vault-agent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	roleIDPath := filepath.Join(dir, "role-id")
+	secretIDPath := filepath.Join(dir, "secret-id")
+	if err := ioutil.WriteFile(roleIDPath, []byte(roleID+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(secretIDPath, []byte(secretID), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	method, err := NewAuthMethod(&MethodConfig{
+		Type: "approle",
+		Config: map[string]string{
+			"role_id_file_path":   roleIDPath,
+			"secret_id_file_path": secretIDPath,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tokenPath := filepath.Join(dir, "token")
+	wrappedPath := filepath.Join(dir, "wrapped")
+	var sinks []*SinkTarget
+	for _, sc := range []*SinkConfig{
+		&SinkConfig{Type: "file", Config: map[string]string{"path": tokenPath}},
+		&SinkConfig{Type: "file", WrapTTL: 5 * time.Minute, Config: map[string]string{"path": wrappedPath}},
+	} {
+		sink, err := NewSink(sc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sinks = append(sinks, sink)
+	}
+
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+	tokenCh := make(chan string)
+	handler := &AuthHandler{
+		Client:    testAgentClient(t, cluster),
+		Method:    method,
+		MountPath: "auth/approle",
+		Logger:    logger,
+		OutputCh:  tokenCh,
+	}
+	sinkServer := &SinkServer{
+		Client:        testAgentClient(t, cluster),
+		Sinks:         sinks,
+		Logger:        logger,
+		ExitAfterAuth: true,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go handler.Run(ctx)
+
+	doneCh := make(chan struct{})
+	go func() {
+		sinkServer.Run(ctx, tokenCh)
+		close(doneCh)
+	}()
+	select {
+	case <-doneCh:
+	case <-time.After(30 * time.Second):
+		t.Fatal("timeout waiting for the token to be written")
+	}
+
+	// The token of the file sink belongs to the role
+	token, err := ioutil.ReadFile(tokenPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(tokenPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != defaultFileSinkMode {
+		t.Fatalf("bad: %v", info.Mode())
+	}
+	client.SetToken(cluster.RootToken)
+	lookup, err := client.Auth().Token().Lookup(string(token))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lookup.Data["meta"].(map[string]interface{})["role_name"] != "test" {
+		t.Fatalf("bad: %#v", lookup.Data)
+	}
+
+	// The wrapped sink holds the wrapping information of the same token
+	d, err := ioutil.ReadFile(wrappedPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wrapInfo api.SecretWrapInfo
+	if err := json.Unmarshal(d, &wrapInfo); err != nil {
+		t.Fatal(err)
+	}
+	if wrapInfo.TTL != 300 {
+		t.Fatalf("bad: %#v", wrapInfo)
+	}
+	unwrapped, err := client.Logical().Unwrap(wrapInfo.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if unwrapped.Data["token"] != string(token) {
+		t.Fatalf("bad: %#v", unwrapped.Data)
+	}
+}
+
+// testAgentClient returns a client of the cluster without a token
+func testAgentClient(t *testing.T, cluster *vault.TestCluster) *api.Client {
+	config := api.DefaultConfig()
+	config.Address = cluster.Cores[0].Client.Address()
+	if err := config.ConfigureTLS(&api.TLSConfig{
+		CACert: cluster.CACertPEMFile,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	client, err := api.NewClient(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.ClearToken()
+	return client
+}
+
+func TestFileSink(t *testing.T) {
+	if _, err := NewFileSink(map[string]string{}); err == nil {
+		t.Fatal("expected an error without a path")
+	}
+	if _, err := NewFileSink(map[string]string{"path": "/tmp/foo", "mode": "0999"}); err == nil {
+		t.Fatal("expected an error with an invalid mode")
+	}
+
+	dir, err := ioutil.TempDir("", "vault-agent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "token")
+	sink, err := NewFileSink(map[string]string{"path": path, "mode": "0600"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, token := range []string{"foo", "bar"} {
+		if err := sink.WriteToken(token); err != nil {
+			t.Fatal(err)
+		}
+		d, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(d) != token {
+			t.Fatalf("bad: %s", d)
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Fatalf("bad: %v", info.Mode())
+	}
+
+	// The temporary files are removed
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("bad: %d files", len(files))
+	}
+}
@@ -0,0 +1,147 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	log "github.com/mgutz/logxi/v1"
+)
+
+const (
+	// initialAuthBackoff and maxAuthBackoff bound the delay between failed
+	// authentication attempts, which doubles after each failure
+	initialAuthBackoff = 1 * time.Second
+	maxAuthBackoff     = 5 * time.Minute
+)
+
+// AuthMethod builds the login requests of an auth method
+type AuthMethod interface {
+	// Authenticate returns the data of a login request. It is called before
+	// every authentication, so that the credentials are read again.
+	Authenticate() (map[string]interface{}, error)
+}
+
+// NewAuthMethod constructs the auth method of the given configuration
+func NewAuthMethod(m *MethodConfig) (AuthMethod, error) {
+	switch m.Type {
+	case "approle":
+		return newAppRoleMethod(m.Config)
+	case "aws":
+		return newAWSMethod(m.Config)
+	case "kubernetes":
+		return newKubernetesMethod(m.Config)
+	default:
+		return nil, fmt.Errorf("unknown auth method %q", m.Type)
+	}
+}
+
+// AuthHandler authenticates to Vault with an auth method, renews the token
+// for as long as possible, then authenticates again. The tokens are sent to
+// OutputCh as they are obtained.
+type AuthHandler struct {
+	Client    *api.Client
+	Method    AuthMethod
+	MountPath string
+	Logger    log.Logger
+	OutputCh  chan string
+}
+
+// Run authenticates and renews the token until the context is canceled
+func (h *AuthHandler) Run(ctx context.Context) {
+	backoff := initialAuthBackoff
+	for {
+		secret, err := h.authenticate()
+		if err != nil {
+			h.Logger.Error("agent: authentication failed", "error", err, "backoff", backoff)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+			if backoff > maxAuthBackoff {
+				backoff = maxAuthBackoff
+			}
+			continue
+		}
+		backoff = initialAuthBackoff
+
+		h.Logger.Info("agent: authentication successful", "accessor", secret.Auth.Accessor)
+		select {
+		case <-ctx.Done():
+			return
+		case h.OutputCh <- secret.Auth.ClientToken:
+		}
+
+		if !h.renew(ctx, secret) {
+			return
+		}
+		h.Logger.Info("agent: token can no longer be renewed, authenticating again")
+	}
+}
+
+// authenticate logs in and sets the token of the client
+func (h *AuthHandler) authenticate() (*api.Secret, error) {
+	data, err := h.Method.Authenticate()
+	if err != nil {
+		return nil, err
+	}
+
+	h.Client.ClearToken()
+	secret, err := h.Client.Logical().Write(h.MountPath+"/login", data)
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil || secret.Auth == nil || secret.Auth.ClientToken == "" {
+		return nil, fmt.Errorf("login response did not return a token")
+	}
+
+	h.Client.SetToken(secret.Auth.ClientToken)
+	return secret, nil
+}
+
+// renew renews the token of the secret until it expires, returning false if
+// the context was canceled first
+func (h *AuthHandler) renew(ctx context.Context, secret *api.Secret) bool {
+	if !secret.Auth.Renewable {
+		// The token cannot be extended, so authenticate again shortly before
+		// it expires, or never if it doesn't expire
+		var expiry <-chan time.Time
+		if secret.Auth.LeaseDuration > 0 {
+			ttl := time.Duration(secret.Auth.LeaseDuration) * time.Second
+			expiry = time.After(ttl * 2 / 3)
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case <-expiry:
+			return true
+		}
+	}
+
+	renewer, err := h.Client.NewRenewer(&api.RenewerInput{
+		Secret: secret,
+	})
+	if err != nil {
+		h.Logger.Error("agent: error creating the token renewer", "error", err)
+		return true
+	}
+	go renewer.Renew()
+	defer renewer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case err := <-renewer.DoneCh():
+			if err != nil {
+				h.Logger.Error("agent: token renewal failed", "error", err)
+			}
+			return true
+		case <-renewer.RenewCh():
+			h.Logger.Debug("agent: token renewed")
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package agent
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// appRoleMethod logs in with a role ID and a secret ID read from files
+type appRoleMethod struct {
+	roleIDFilePath   string
+	secretIDFilePath string
+}
+
+func newAppRoleMethod(conf map[string]string) (AuthMethod, error) {
+	m := &appRoleMethod{
+		roleIDFilePath:   conf["role_id_file_path"],
+		secretIDFilePath: conf["secret_id_file_path"],
+	}
+	if m.roleIDFilePath == "" {
+		return nil, fmt.Errorf("'role_id_file_path' is required")
+	}
+	return m, nil
+}
+
+func (m *appRoleMethod) Authenticate() (map[string]interface{}, error) {
+	roleID, err := readCredentialFile(m.roleIDFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the role ID: %s", err)
+	}
+	data := map[string]interface{}{
+		"role_id": roleID,
+	}
+
+	// The secret ID is optional for roles which don't bind it
+	if m.secretIDFilePath != "" {
+		secretID, err := readCredentialFile(m.secretIDFilePath)
+		if err != nil {
+			return nil, fmt.Errorf("error reading the secret ID: %s", err)
+		}
+		data["secret_id"] = secretID
+	}
+
+	return data, nil
+}
+
+// readCredentialFile reads a credential from a file, which cannot be empty
+func readCredentialFile(path string) (string, error) {
+	d, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	v := strings.TrimSpace(string(d))
+	if v == "" {
+		return "", fmt.Errorf("%s is empty", path)
+	}
+	return v, nil
+}
@@ -0,0 +1,83 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/ec2metadata"
+	"github.com/aws/aws-sdk-go/aws/session"
+	uuid "github.com/hashicorp/go-uuid"
+	awsauth "github.com/hashicorp/vault/builtin/credential/aws"
+)
+
+// awsMethod logs in with the IAM credentials of the environment, or with the
+// identity document of the EC2 instance
+type awsMethod struct {
+	authType     string
+	role         string
+	headerValue  string
+	region       string
+	accessKey    string
+	secretKey    string
+	sessionToken string
+
+	// nonce is sent with every ec2 login, since the role may require the
+	// same nonce for the later logins of the instance
+	nonce string
+}
+
+func newAWSMethod(conf map[string]string) (AuthMethod, error) {
+	m := &awsMethod{
+		authType:     strings.ToLower(conf["type"]),
+		role:         conf["role"],
+		headerValue:  conf["header_value"],
+		region:       conf["region"],
+		accessKey:    conf["access_key"],
+		secretKey:    conf["secret_key"],
+		sessionToken: conf["session_token"],
+		nonce:        conf["nonce"],
+	}
+
+	switch m.authType {
+	case "":
+		m.authType = "iam"
+	case "iam":
+	case "ec2":
+		if m.nonce == "" {
+			nonce, err := uuid.GenerateUUID()
+			if err != nil {
+				return nil, fmt.Errorf("error generating the nonce: %s", err)
+			}
+			m.nonce = nonce
+		}
+	default:
+		return nil, fmt.Errorf("unknown aws auth type %q", m.authType)
+	}
+
+	return m, nil
+}
+
+func (m *awsMethod) Authenticate() (map[string]interface{}, error) {
+	if m.authType == "ec2" {
+		sess, err := session.NewSession()
+		if err != nil {
+			return nil, err
+		}
+		pkcs7, err := ec2metadata.New(sess).GetDynamicData("instance-identity/pkcs7")
+		if err != nil {
+			return nil, fmt.Errorf("error fetching the instance identity document: %s", err)
+		}
+		return map[string]interface{}{
+			"role":  m.role,
+			"pkcs7": strings.Replace(strings.TrimSpace(pkcs7), "\n", "", -1),
+			"nonce": m.nonce,
+		}, nil
+	}
+
+	data, err := awsauth.GenerateLoginDataForRegion(m.accessKey, m.secretKey, m.sessionToken, m.headerValue, m.region)
+	if err != nil {
+		return nil, err
+	}
+	data["role"] = m.role
+	return data, nil
+}
@@ -0,0 +1,41 @@
+package agent
+
+import (
+	"fmt"
+)
+
+// defaultKubernetesTokenPath is where Kubernetes mounts the service account
+// token of a pod
+const defaultKubernetesTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// kubernetesMethod logs in with the service account token of the pod
+type kubernetesMethod struct {
+	role      string
+	tokenPath string
+}
+
+func newKubernetesMethod(conf map[string]string) (AuthMethod, error) {
+	m := &kubernetesMethod{
+		role:      conf["role"],
+		tokenPath: conf["token_path"],
+	}
+	if m.role == "" {
+		return nil, fmt.Errorf("'role' is required")
+	}
+	if m.tokenPath == "" {
+		m.tokenPath = defaultKubernetesTokenPath
+	}
+	return m, nil
+}
+
+func (m *kubernetesMethod) Authenticate() (map[string]interface{}, error) {
+	// The token is read on every authentication since it may be rotated
+	jwt, err := readCredentialFile(m.tokenPath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the service account token: %s", err)
+	}
+	return map[string]interface{}{
+		"role": m.role,
+		"jwt":  jwt,
+	}, nil
+}
@@ -0,0 +1,349 @@
+package agent
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/hcl"
+	"github.com/hashicorp/hcl/hcl/ast"
+	"github.com/hashicorp/vault/command/server"
+	"github.com/hashicorp/vault/helper/parseutil"
+)
+
+// Config is the configuration of the Vault agent
+type Config struct {
+	PidFile          string      `hcl:"pid_file"`
+	ExitAfterAuthRaw interface{} `hcl:"exit_after_auth"`
+	ExitAfterAuth    bool        `hcl:"-"`
+
+	StaticSecretRenderIntervalRaw interface{}   `hcl:"static_secret_render_interval"`
+	StaticSecretRenderInterval    time.Duration `hcl:"-"`
+
+	AutoAuth  *AutoAuth          `hcl:"-"`
+	Templates []*TemplateConfig  `hcl:"-"`
+	Cache     *CacheConfig       `hcl:"-"`
+	Listeners []*server.Listener `hcl:"-"`
+}
+
+// CacheConfig configures the proxy served on the listeners of the agent,
+// which caches the responses holding leases and tokens
+type CacheConfig struct {
+	UseAutoAuthTokenRaw interface{} `hcl:"use_auto_auth_token"`
+	UseAutoAuthToken    bool        `hcl:"-"`
+}
+
+// AutoAuth is the auto_auth block, configuring how the agent authenticates
+// to Vault and where it writes the token
+type AutoAuth struct {
+	Method *MethodConfig
+	Sinks  []*SinkConfig
+}
+
+// MethodConfig configures the auth method the agent authenticates with
+type MethodConfig struct {
+	Type      string
+	MountPath string
+	Config    map[string]string
+}
+
+// SinkConfig configures a destination of the token of the agent
+type SinkConfig struct {
+	Type    string
+	WrapTTL time.Duration
+	Config  map[string]string
+}
+
+// TemplateConfig configures a template rendered to a file
+type TemplateConfig struct {
+	Source         string
+	Contents       string
+	Destination    string
+	Perms          os.FileMode
+	Command        string
+	CommandTimeout time.Duration
+	LeftDelim      string
+	RightDelim     string
+}
+
+// LoadConfig loads the agent configuration from the given file
+func LoadConfig(path string) (*Config, error) {
+	d, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseConfig(string(d))
+}
+
+// ParseConfig parses an agent configuration
+func ParseConfig(d string) (*Config, error) {
+	obj, err := hcl.Parse(d)
+	if err != nil {
+		return nil, err
+	}
+
+	list, ok := obj.Node.(*ast.ObjectList)
+	if !ok {
+		return nil, fmt.Errorf("error parsing: file doesn't contain a root object")
+	}
+
+	valid := []string{
+		"pid_file",
+		"exit_after_auth",
+		"static_secret_render_interval",
+		"auto_auth",
+		"template",
+		"cache",
+		"listener",
+	}
+	if err := checkHCLKeys(list, valid); err != nil {
+		return nil, err
+	}
+
+	var result Config
+	if err := hcl.DecodeObject(&result, obj); err != nil {
+		return nil, err
+	}
+	if result.ExitAfterAuthRaw != nil {
+		if result.ExitAfterAuth, err = parseutil.ParseBool(result.ExitAfterAuthRaw); err != nil {
+			return nil, err
+		}
+		result.ExitAfterAuthRaw = nil
+	}
+	if result.StaticSecretRenderIntervalRaw != nil {
+		if result.StaticSecretRenderInterval, err = parseutil.ParseDurationSecond(result.StaticSecretRenderIntervalRaw); err != nil {
+			return nil, err
+		}
+		result.StaticSecretRenderIntervalRaw = nil
+	}
+
+	o := list.Filter("auto_auth")
+	if len(o.Items) == 0 {
+		return nil, fmt.Errorf("missing 'auto_auth'")
+	}
+	if err := parseAutoAuth(&result, o); err != nil {
+		return nil, multierror.Prefix(err, "auto_auth:")
+	}
+
+	if o := list.Filter("template"); len(o.Items) > 0 {
+		if err := parseTemplates(&result, o); err != nil {
+			return nil, multierror.Prefix(err, "template:")
+		}
+	}
+
+	if o := list.Filter("cache"); len(o.Items) > 0 {
+		if err := parseCache(&result, o); err != nil {
+			return nil, multierror.Prefix(err, "cache:")
+		}
+	}
+
+	if o := list.Filter("listener"); len(o.Items) > 0 {
+		if result.Listeners, err = server.ParseListeners(o); err != nil {
+			return nil, err
+		}
+	}
+	if (result.Cache == nil) != (len(result.Listeners) == 0) {
+		return nil, fmt.Errorf("the 'cache' block and the 'listener' blocks must be configured together")
+	}
+
+	if len(result.AutoAuth.Sinks) == 0 && len(result.Templates) == 0 && len(result.Listeners) == 0 {
+		return nil, fmt.Errorf("at least one 'sink', 'template' or 'listener' block is required")
+	}
+
+	return &result, nil
+}
+
+func parseAutoAuth(result *Config, list *ast.ObjectList) error {
+	if len(list.Items) > 1 {
+		return fmt.Errorf("only one 'auto_auth' block is permitted")
+	}
+
+	item := list.Items[0]
+	objType, ok := item.Val.(*ast.ObjectType)
+	if !ok {
+		return fmt.Errorf("value should be an object")
+	}
+	if err := checkHCLKeys(objType.List, []string{"method", "sink"}); err != nil {
+		return err
+	}
+
+	result.AutoAuth = &AutoAuth{}
+
+	methods := objType.List.Filter("method")
+	switch len(methods.Items) {
+	case 0:
+		return fmt.Errorf("missing 'method'")
+	case 1:
+	default:
+		return fmt.Errorf("only one 'method' block is permitted")
+	}
+	typ, m, err := parseBlock(methods.Items[0], "method")
+	if err != nil {
+		return err
+	}
+	method := &MethodConfig{
+		Type:   typ,
+		Config: m,
+	}
+	if v, ok := m["mount_path"]; ok {
+		method.MountPath = strings.Trim(v, "/")
+		delete(m, "mount_path")
+	}
+	if method.MountPath == "" {
+		method.MountPath = "auth/" + method.Type
+	}
+	result.AutoAuth.Method = method
+
+	sinks := objType.List.Filter("sink")
+	for _, item := range sinks.Items {
+		typ, m, err := parseBlock(item, "sink")
+		if err != nil {
+			return err
+		}
+		sink := &SinkConfig{
+			Type:   typ,
+			Config: m,
+		}
+		if v, ok := m["wrap_ttl"]; ok {
+			if sink.WrapTTL, err = parseutil.ParseDurationSecond(v); err != nil {
+				return multierror.Prefix(err, fmt.Sprintf("sink.%s:", typ))
+			}
+			delete(m, "wrap_ttl")
+		}
+		result.AutoAuth.Sinks = append(result.AutoAuth.Sinks, sink)
+	}
+
+	return nil
+}
+
+func parseTemplates(result *Config, list *ast.ObjectList) error {
+	valid := []string{
+		"source",
+		"contents",
+		"destination",
+		"perms",
+		"command",
+		"command_timeout",
+		"left_delimiter",
+		"right_delimiter",
+	}
+
+	for i, item := range list.Items {
+		if err := checkHCLKeys(item.Val, valid); err != nil {
+			return multierror.Prefix(err, fmt.Sprintf("template %d:", i))
+		}
+
+		var m map[string]string
+		if err := hcl.DecodeObject(&m, item.Val); err != nil {
+			return multierror.Prefix(err, fmt.Sprintf("template %d:", i))
+		}
+
+		t := &TemplateConfig{
+			Source:      m["source"],
+			Contents:    m["contents"],
+			Destination: m["destination"],
+			Perms:       defaultTemplatePerms,
+			Command:     m["command"],
+			LeftDelim:   m["left_delimiter"],
+			RightDelim:  m["right_delimiter"],
+		}
+		if (t.Source == "") == (t.Contents == "") {
+			return fmt.Errorf("template %d: exactly one of 'source' and 'contents' is required", i)
+		}
+		if t.Destination == "" {
+			return fmt.Errorf("template %d: 'destination' is required", i)
+		}
+		if v, ok := m["perms"]; ok {
+			perms, err := strconv.ParseUint(v, 8, 32)
+			if err != nil {
+				return fmt.Errorf("template %d: invalid 'perms' %q: %s", i, v, err)
+			}
+			t.Perms = os.FileMode(perms)
+		}
+		if v, ok := m["command_timeout"]; ok {
+			var err error
+			if t.CommandTimeout, err = parseutil.ParseDurationSecond(v); err != nil {
+				return multierror.Prefix(err, fmt.Sprintf("template %d:", i))
+			}
+		}
+
+		result.Templates = append(result.Templates, t)
+	}
+
+	return nil
+}
+
+func parseCache(result *Config, list *ast.ObjectList) error {
+	if len(list.Items) > 1 {
+		return fmt.Errorf("only one 'cache' block is permitted")
+	}
+
+	item := list.Items[0]
+	if err := checkHCLKeys(item.Val, []string{"use_auto_auth_token"}); err != nil {
+		return err
+	}
+
+	var c CacheConfig
+	if err := hcl.DecodeObject(&c, item.Val); err != nil {
+		return err
+	}
+	if c.UseAutoAuthTokenRaw != nil {
+		var err error
+		if c.UseAutoAuthToken, err = parseutil.ParseBool(c.UseAutoAuthTokenRaw); err != nil {
+			return err
+		}
+		c.UseAutoAuthTokenRaw = nil
+	}
+
+	result.Cache = &c
+	return nil
+}
+
+// parseBlock decodes a block labeled with its type, such as method "approle"
+func parseBlock(item *ast.ObjectItem, name string) (string, map[string]string, error) {
+	if len(item.Keys) == 0 {
+		return "", nil, fmt.Errorf("'%s' block requires a type", name)
+	}
+	typ := strings.ToLower(item.Keys[0].Token.Value().(string))
+
+	var m map[string]string
+	if err := hcl.DecodeObject(&m, item.Val); err != nil {
+		return "", nil, multierror.Prefix(err, fmt.Sprintf("%s.%s:", name, typ))
+	}
+	if m == nil {
+		m = make(map[string]string)
+	}
+	return typ, m, nil
+}
+
+func checkHCLKeys(node ast.Node, valid []string) error {
+	var list *ast.ObjectList
+	switch n := node.(type) {
+	case *ast.ObjectList:
+		list = n
+	case *ast.ObjectType:
+		list = n.List
+	default:
+		return fmt.Errorf("cannot check HCL keys of type %T", n)
+	}
+
+	validMap := make(map[string]struct{}, len(valid))
+	for _, v := range valid {
+		validMap[v] = struct{}{}
+	}
+
+	var result error
+	for _, item := range list.Items {
+		key := item.Keys[0].Token.Value().(string)
+		if _, ok := validMap[key]; !ok {
+			result = multierror.Append(result, fmt.Errorf(
+				"invalid key '%s' on line %d", key, item.Assign.Line))
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,241 @@
+package agent
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/command/server"
+)
+
+func TestLoadConfig(t *testing.T) {
+	config, err := LoadConfig("./test-fixtures/config.hcl")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := &Config{
+		PidFile:       "./pidfile",
+		ExitAfterAuth: true,
+		AutoAuth: &AutoAuth{
+			Method: &MethodConfig{
+				Type:      "aws",
+				MountPath: "auth/aws-iam",
+				Config: map[string]string{
+					"type": "iam",
+					"role": "foobar",
+				},
+			},
+			Sinks: []*SinkConfig{
+				&SinkConfig{
+					Type: "file",
+					Config: map[string]string{
+						"path": "/tmp/file-foo",
+					},
+				},
+				&SinkConfig{
+					Type:    "file",
+					WrapTTL: 5 * time.Minute,
+					Config: map[string]string{
+						"path": "/tmp/file-bar",
+						"mode": "0600",
+					},
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(config, expected) {
+		t.Fatalf("expected \n\n%#v\n\n to be \n\n%#v\n\n", config, expected)
+	}
+}
+
+func TestParseConfig_defaultMountPath(t *testing.T) {
+	config, err := ParseConfig(`
+auto_auth {
+  method "approle" {
+    role_id_file_path = "/tmp/role-id"
+  }
+  sink "file" {
+    path = "/tmp/token"
+  }
+}
+`)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if config.AutoAuth.Method.MountPath != "auth/approle" {
+		t.Fatalf("bad: %s", config.AutoAuth.Method.MountPath)
+	}
+}
+
+func TestParseConfig_templates(t *testing.T) {
+	config, err := ParseConfig(`
+static_secret_render_interval = "10m"
+
+auto_auth {
+  method "approle" {
+    role_id_file_path = "/tmp/role-id"
+  }
+}
+
+template {
+  source          = "/etc/vault/app.ctmpl"
+  destination     = "/etc/app/app.conf"
+  perms           = "0600"
+  command         = "systemctl reload app"
+  command_timeout = "1m"
+}
+
+template {
+  contents        = "[[ (secret \"secret/foo\").Data.password ]]"
+  destination     = "/etc/app/password"
+  left_delimiter  = "[["
+  right_delimiter = "]]"
+}
+`)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if config.StaticSecretRenderInterval != 10*time.Minute {
+		t.Fatalf("bad: %s", config.StaticSecretRenderInterval)
+	}
+	expected := []*TemplateConfig{
+		&TemplateConfig{
+			Source:         "/etc/vault/app.ctmpl",
+			Destination:    "/etc/app/app.conf",
+			Perms:          0600,
+			Command:        "systemctl reload app",
+			CommandTimeout: time.Minute,
+		},
+		&TemplateConfig{
+			Contents:    `[[ (secret "secret/foo").Data.password ]]`,
+			Destination: "/etc/app/password",
+			Perms:       defaultTemplatePerms,
+			LeftDelim:   "[[",
+			RightDelim:  "]]",
+		},
+	}
+	if !reflect.DeepEqual(config.Templates, expected) {
+		t.Fatalf("expected \n\n%#v\n\n to be \n\n%#v\n\n", config.Templates, expected)
+	}
+}
+
+func TestParseConfig_cache(t *testing.T) {
+	config, err := ParseConfig(`
+auto_auth {
+  method "approle" {
+    role_id_file_path = "/tmp/role-id"
+  }
+}
+
+cache {
+  use_auto_auth_token = true
+}
+
+listener "tcp" {
+  address     = "127.0.0.1:8100"
+  tls_disable = true
+}
+
+listener "unix" {
+  address     = "/var/run/vault-agent.sock"
+  socket_mode = "0600"
+}
+`)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if !reflect.DeepEqual(config.Cache, &CacheConfig{UseAutoAuthToken: true}) {
+		t.Fatalf("bad: %#v", config.Cache)
+	}
+	expected := []*server.Listener{
+		&server.Listener{
+			Type: "tcp",
+			Config: map[string]interface{}{
+				"address":     "127.0.0.1:8100",
+				"tls_disable": true,
+			},
+		},
+		&server.Listener{
+			Type: "unix",
+			Config: map[string]interface{}{
+				"address":     "/var/run/vault-agent.sock",
+				"socket_mode": "0600",
+			},
+		},
+	}
+	if !reflect.DeepEqual(config.Listeners, expected) {
+		t.Fatalf("expected \n\n%#v\n\n to be \n\n%#v\n\n", config.Listeners, expected)
+	}
+}
+
+func TestParseConfig_invalid(t *testing.T) {
+	cases := map[string]string{
+		"invalid key": `
+foo = "bar"
+auto_auth {
+  method "approle" {}
+  sink "file" {}
+}`,
+		"missing auto_auth": `
+pid_file = "./pidfile"
+`,
+		"missing method": `
+auto_auth {
+  sink "file" {}
+}`,
+		"two methods": `
+auto_auth {
+  method "approle" {}
+  method "aws" {}
+  sink "file" {}
+}`,
+		"missing sink": `
+auto_auth {
+  method "approle" {}
+}`,
+		"template without destination": `
+auto_auth {
+  method "approle" {}
+}
+template {
+  contents = "foo"
+}`,
+		"template with source and contents": `
+auto_auth {
+  method "approle" {}
+}
+template {
+  source      = "/tmp/foo"
+  contents    = "foo"
+  destination = "/tmp/bar"
+}`,
+		"listener without cache": `
+auto_auth {
+  method "approle" {}
+}
+listener "tcp" {
+  address = "127.0.0.1:8100"
+}`,
+		"cache without listener": `
+auto_auth {
+  method "approle" {}
+}
+cache {}`,
+		"invalid wrap_ttl": `
+auto_auth {
+  method "approle" {}
+  sink "file" {
+    wrap_ttl = "foo"
+  }
+}`,
+	}
+
+	for name, d := range cases {
+		if _, err := ParseConfig(d); err == nil {
+			t.Fatalf("%s: expected an error", name)
+		}
+	}
+}
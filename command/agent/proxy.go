@@ -0,0 +1,423 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	log "github.com/mgutz/logxi/v1"
+)
+
+const (
+	// maxProxyRequestSize bounds the size of the request bodies forwarded by
+	// the proxy, like the limit of the Vault server
+	maxProxyRequestSize = 32 * 1024 * 1024
+
+	// proxyCacheGrace is the remaining lease duration under which a cached
+	// response is evicted rather than served
+	proxyCacheGrace = 15 * time.Second
+
+	// ProxyReadTimeout bounds the time the proxy waits for a request
+	ProxyReadTimeout = 30 * time.Second
+
+	// ProxyWriteTimeout bounds the time the proxy takes to forward a request
+	// and write its response. It exceeds the default timeout of the client
+	// forwarding the requests.
+	ProxyWriteTimeout = 90 * time.Second
+)
+
+// hopHeaders are the headers which are not forwarded by the proxy
+var hopHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// Proxy is an HTTP handler forwarding the requests of applications to Vault.
+// It attaches the token of the agent to the requests without a token, and
+// caches the responses holding a lease or a token, which it renews until they
+// expire, so that identical requests are served the same secret.
+type Proxy struct {
+	client           *api.Client
+	useAutoAuthToken bool
+	logger           log.Logger
+
+	tokenLock sync.RWMutex
+	token     string
+
+	// ctx is the parent of the contexts of the renewals of the cache entries
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	cacheLock sync.Mutex
+	cache     map[string]*proxyCacheEntry
+}
+
+// ProxyConfig is the configuration of a proxy. Its client cannot be shared
+// with the auth handler or the other servers of the agent.
+type ProxyConfig struct {
+	Client           *api.Client
+	UseAutoAuthToken bool
+	Logger           log.Logger
+}
+
+// proxyCacheEntry is a cached response, holding either a lease or a token
+type proxyCacheEntry struct {
+	// token is the token of the request
+	token string
+
+	// leaseID is the lease of the secret of the response, and authToken the
+	// token it created
+	leaseID   string
+	authToken string
+
+	status int
+	header http.Header
+	body   []byte
+
+	cancel context.CancelFunc
+}
+
+// NewProxy constructs a proxy
+func NewProxy(conf *ProxyConfig) *Proxy {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Proxy{
+		client:           conf.Client,
+		useAutoAuthToken: conf.UseAutoAuthToken,
+		logger:           conf.Logger,
+		ctx:              ctx,
+		cancel:           cancel,
+		cache:            make(map[string]*proxyCacheEntry),
+	}
+}
+
+// Run attaches the tokens of the channel to the requests without a token
+// until the context is canceled, then stops the renewals of the cache
+func (p *Proxy) Run(ctx context.Context, tokenCh <-chan string) {
+	defer p.cancel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case token := <-tokenCh:
+			p.tokenLock.Lock()
+			p.token = token
+			p.tokenLock.Unlock()
+		}
+	}
+}
+
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxProxyRequestSize+1))
+	if err != nil {
+		proxyRespondError(w, http.StatusBadRequest, fmt.Errorf("error reading the request: %s", err))
+		return
+	}
+	if len(body) > maxProxyRequestSize {
+		proxyRespondError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("request body exceeds %d bytes", maxProxyRequestSize))
+		return
+	}
+
+	token := r.Header.Get("X-Vault-Token")
+	if token == "" && p.useAutoAuthToken {
+		p.tokenLock.RLock()
+		token = p.token
+		p.tokenLock.RUnlock()
+	}
+
+	key := proxyCacheKey(token, r, body)
+	if entry := p.cached(key); entry != nil {
+		p.logger.Debug("agent: serving cached response", "method", r.Method, "path", r.URL.Path)
+		proxyWriteResponse(w, entry.status, entry.header, entry.body)
+		return
+	}
+
+	req := p.client.NewRequest(r.Method, r.URL.Path)
+	req.ClientToken = token
+	req.WrapTTL = ""
+	req.Params = r.URL.Query()
+	req.Headers = make(http.Header, len(r.Header))
+	for k, v := range r.Header {
+		req.Headers[k] = v
+	}
+	req.Headers.Del("X-Vault-Token")
+	req.Headers.Del("Content-Length")
+	for _, h := range hopHeaders {
+		req.Headers.Del(h)
+	}
+	if len(body) > 0 {
+		// A JSON body is sent again if the request is redirected to the
+		// active node
+		if json.Valid(body) {
+			if err := req.SetJSONBody(json.RawMessage(body)); err != nil {
+				proxyRespondError(w, http.StatusBadRequest, err)
+				return
+			}
+		} else {
+			req.Body = bytes.NewReader(body)
+			req.BodySize = int64(len(body))
+		}
+	}
+
+	// The request is aborted if the application gives up on it
+	resp, err := p.client.RawRequestWithContext(r.Context(), req)
+	if resp == nil {
+		proxyRespondError(w, http.StatusBadGateway, fmt.Errorf("error forwarding the request to Vault: %s", err))
+		return
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		proxyRespondError(w, http.StatusBadGateway, fmt.Errorf("error reading the response of Vault: %s", err))
+		return
+	}
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		p.evictRevoked(token, r.URL.Path, body)
+		if resp.StatusCode == http.StatusOK {
+			p.cacheResponse(key, token, resp, respBody)
+		}
+	}
+
+	proxyWriteResponse(w, resp.StatusCode, resp.Header, respBody)
+}
+
+// cached returns the cache entry of the key, if any
+func (p *Proxy) cached(key string) *proxyCacheEntry {
+	p.cacheLock.Lock()
+	defer p.cacheLock.Unlock()
+	return p.cache[key]
+}
+
+// cacheResponse caches a response holding a lease or a token, and renews it
+// until it expires
+func (p *Proxy) cacheResponse(key, token string, resp *api.Response, body []byte) {
+	secret, err := api.ParseSecret(bytes.NewReader(body))
+	if err != nil || secret == nil || secret.WrapInfo != nil {
+		return
+	}
+
+	entry := &proxyCacheEntry{
+		token:  token,
+		status: resp.StatusCode,
+		header: resp.Header,
+		body:   body,
+	}
+	switch {
+	case secret.LeaseID != "":
+		entry.leaseID = secret.LeaseID
+	case secret.Auth != nil && secret.Auth.ClientToken != "":
+		entry.authToken = secret.Auth.ClientToken
+	default:
+		return
+	}
+
+	ctx, cancel := context.WithCancel(p.ctx)
+	entry.cancel = cancel
+
+	p.cacheLock.Lock()
+	if existing, ok := p.cache[key]; ok {
+		existing.cancel()
+	}
+	p.cache[key] = entry
+	p.cacheLock.Unlock()
+
+	p.logger.Debug("agent: cached response", "lease_id", entry.leaseID)
+	go p.renew(ctx, key, entry, secret)
+}
+
+// renew renews the lease or the token of a cache entry, evicting it once it
+// can no longer be renewed
+func (p *Proxy) renew(ctx context.Context, key string, entry *proxyCacheEntry, secret *api.Secret) {
+	defer p.evict(key, entry)
+
+	for {
+		ttl, renewable := proxyLease(entry, secret)
+		if ttl <= 0 {
+			// The lease doesn't expire, so the entry lives until it is
+			// revoked through the proxy or the agent stops
+			<-ctx.Done()
+			return
+		}
+		if !renewable || ttl <= proxyCacheGrace {
+			select {
+			case <-ctx.Done():
+			case <-time.After(ttl - proxyCacheGrace):
+			}
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(ttl * 2 / 3):
+		}
+
+		var err error
+		if secret, err = p.renewEntry(entry); err != nil {
+			p.logger.Warn("agent: evicting cached response after a failed renewal",
+				"lease_id", entry.leaseID, "error", err)
+			return
+		}
+	}
+}
+
+// renewEntry renews the lease or the token of a cache entry
+func (p *Proxy) renewEntry(entry *proxyCacheEntry) (*api.Secret, error) {
+	var req *api.Request
+	if entry.leaseID != "" {
+		req = p.client.NewRequest("PUT", "/v1/sys/leases/renew")
+		req.ClientToken = entry.token
+		if err := req.SetJSONBody(map[string]interface{}{
+			"lease_id": entry.leaseID,
+		}); err != nil {
+			return nil, err
+		}
+	} else {
+		req = p.client.NewRequest("PUT", "/v1/auth/token/renew-self")
+		req.ClientToken = entry.authToken
+	}
+	req.WrapTTL = ""
+
+	resp, err := p.client.RawRequest(req)
+	if resp != nil {
+		defer resp.Body.Close()
+	}
+	if err != nil {
+		return nil, err
+	}
+	secret, err := api.ParseSecret(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil {
+		return nil, fmt.Errorf("empty renewal response")
+	}
+	return secret, nil
+}
+
+// proxyLease returns the lease duration of the secret of a cache entry, and
+// whether it is renewable
+func proxyLease(entry *proxyCacheEntry, secret *api.Secret) (time.Duration, bool) {
+	if entry.leaseID != "" {
+		return time.Duration(secret.LeaseDuration) * time.Second, secret.Renewable
+	}
+	if secret.Auth == nil {
+		return 0, false
+	}
+	return time.Duration(secret.Auth.LeaseDuration) * time.Second, secret.Auth.Renewable
+}
+
+// evict removes a cache entry, unless it was replaced already
+func (p *Proxy) evict(key string, entry *proxyCacheEntry) {
+	p.cacheLock.Lock()
+	defer p.cacheLock.Unlock()
+	if p.cache[key] == entry {
+		delete(p.cache, key)
+	}
+	entry.cancel()
+}
+
+// evictRevoked evicts the cache entries whose leases or tokens were revoked
+// by a request forwarded by the proxy
+func (p *Proxy) evictRevoked(token, path string, body []byte) {
+	path = strings.TrimPrefix(path, "/v1/")
+
+	var data struct {
+		LeaseID string `json:"lease_id"`
+		Token   string `json:"token"`
+	}
+	if len(body) > 0 {
+		json.Unmarshal(body, &data)
+	}
+
+	var match func(*proxyCacheEntry) bool
+	if path == "sys/revoke" || path == "sys/leases/revoke" {
+		match = func(e *proxyCacheEntry) bool { return e.leaseID == data.LeaseID }
+	} else if leaseID, ok := trimAnyPrefix(path, "sys/revoke/", "sys/leases/revoke/"); ok {
+		match = func(e *proxyCacheEntry) bool { return e.leaseID == leaseID }
+	} else if prefix, ok := trimAnyPrefix(path, "sys/revoke-prefix/", "sys/leases/revoke-prefix/",
+		"sys/revoke-force/", "sys/leases/revoke-force/"); ok {
+		match = func(e *proxyCacheEntry) bool { return e.leaseID != "" && strings.HasPrefix(e.leaseID, prefix) }
+	} else if path == "auth/token/revoke-self" {
+		match = func(e *proxyCacheEntry) bool { return e.token == token || e.authToken == token }
+	} else if path == "auth/token/revoke" || path == "auth/token/revoke-orphan" {
+		match = func(e *proxyCacheEntry) bool { return e.token == data.Token || e.authToken == data.Token }
+	} else {
+		return
+	}
+
+	p.cacheLock.Lock()
+	defer p.cacheLock.Unlock()
+	for key, entry := range p.cache {
+		if match(entry) {
+			delete(p.cache, key)
+			entry.cancel()
+		}
+	}
+}
+
+// trimAnyPrefix trims the first of the prefixes the string starts with
+func trimAnyPrefix(s string, prefixes ...string) (string, bool) {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return strings.TrimPrefix(s, prefix), true
+		}
+	}
+	return "", false
+}
+
+// proxyCacheKey identifies a request by its token, namespace, wrapping TTL,
+// method, path, query and body
+func proxyCacheKey(token string, r *http.Request, body []byte) string {
+	h := sha256.New()
+	for _, v := range []string{
+		token,
+		r.Header.Get(api.NamespaceHeaderName),
+		r.Header.Get("X-Vault-Wrap-TTL"),
+		r.Method,
+		r.URL.Path,
+		r.URL.RawQuery,
+	} {
+		h.Write([]byte(v))
+		h.Write([]byte{0})
+	}
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func proxyWriteResponse(w http.ResponseWriter, status int, header http.Header, body []byte) {
+	for k, v := range header {
+		w.Header()[k] = v
+	}
+	for _, h := range hopHeaders {
+		w.Header().Del(h)
+	}
+	w.Header().Del("Content-Length")
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+func proxyRespondError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"errors": []string{err.Error()},
+	})
+}
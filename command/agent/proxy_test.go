@@ -0,0 +1,204 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/helper/logformat"
+	vaulthttp "github.com/hashicorp/vault/http"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/vault"
+	log "github.com/mgutz/logxi/v1"
+)
+
+// testProxy starts a proxy attaching the root token of the cluster, and
+// returns a client of the proxy without a token
+func testProxy(t *testing.T, cluster *vault.TestCluster) (*Proxy, *api.Client, func()) {
+	proxy := NewProxy(&ProxyConfig{
+		Client:           testAgentClient(t, cluster),
+		UseAutoAuthToken: true,
+		Logger:           logformat.NewVaultLogger(log.LevelTrace),
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	tokenCh := make(chan string)
+	go proxy.Run(ctx, tokenCh)
+	tokenCh <- cluster.RootToken
+
+	ts := httptest.NewServer(proxy)
+
+	config := api.DefaultConfig()
+	config.Address = ts.URL
+	client, err := api.NewClient(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.ClearToken()
+
+	return proxy, client, func() {
+		ts.Close()
+		cancel()
+	}
+}
+
+func TestProxy_leases(t *testing.T) {
+	cluster := vault.NewTestCluster(t, &vault.CoreConfig{
+		LogicalBackends: map[string]logical.Factory{
+			"leased-kv": vault.LeasedPassthroughBackendFactory,
+		},
+	}, &vault.TestClusterOptions{
+		HandlerFunc: vaulthttp.Handler,
+	})
+	cluster.Start()
+	defer cluster.Cleanup()
+
+	vaultClient := cluster.Cores[0].Client
+	if err := vaultClient.Sys().Mount("leased", &api.MountInput{
+		Type: "leased-kv",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := vaultClient.Logical().Write("leased/foo", map[string]interface{}{
+		"value": "bar",
+		"ttl":   "1h",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := vaultClient.Logical().Write("secret/foo", map[string]interface{}{
+		"value": "bar",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	proxy, client, cleanup := testProxy(t, cluster)
+	defer cleanup()
+
+	// The leased secret is served from the cache
+	first, err := client.Logical().Read("leased/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.LeaseID == "" || first.Data["value"] != "bar" {
+		t.Fatalf("bad: %#v", first)
+	}
+	if _, err := vaultClient.Logical().Write("leased/foo", map[string]interface{}{
+		"value": "baz",
+		"ttl":   "1h",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	second, err := client.Logical().Read("leased/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.LeaseID != first.LeaseID || second.Data["value"] != "bar" {
+		t.Fatalf("bad: %#v", second)
+	}
+
+	// The secrets without a lease are not cached
+	if _, err := vaultClient.Logical().Write("secret/foo", map[string]interface{}{
+		"value": "baz",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		secret, err := client.Logical().Read("secret/foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if secret.Data["value"] != "baz" {
+			t.Fatalf("bad: %#v", secret)
+		}
+	}
+
+	// Revoking the lease through the proxy evicts it
+	if err := client.Sys().Revoke(first.LeaseID); err != nil {
+		t.Fatal(err)
+	}
+	third, err := client.Logical().Read("leased/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if third.LeaseID == first.LeaseID || third.Data["value"] != "baz" {
+		t.Fatalf("bad: %#v", third)
+	}
+
+	// The errors of Vault are returned as is
+	_, err = client.Logical().Write("sys/mounts/leased", map[string]interface{}{})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	proxy.cacheLock.Lock()
+	n := len(proxy.cache)
+	proxy.cacheLock.Unlock()
+	if n != 1 {
+		t.Fatalf("expected 1 cache entry, got %d", n)
+	}
+}
+
+func TestProxy_tokens(t *testing.T) {
+	cluster := vault.NewTestCluster(t, nil, &vault.TestClusterOptions{
+		HandlerFunc: vaulthttp.Handler,
+	})
+	cluster.Start()
+	defer cluster.Cleanup()
+
+	_, client, cleanup := testProxy(t, cluster)
+	defer cleanup()
+
+	create := func(policy string) string {
+		secret, err := client.Auth().Token().Create(&api.TokenCreateRequest{
+			Policies: []string{policy},
+			TTL:      "1h",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return secret.Auth.ClientToken
+	}
+
+	// Identical requests are served the same token
+	token := create("default")
+	if create("default") != token {
+		t.Fatal("expected the cached token")
+	}
+	if create("foo") == token {
+		t.Fatal("expected a new token")
+	}
+
+	// The token of a request is used instead of the token of the agent
+	client.SetToken(token)
+	secret, err := client.Auth().Token().LookupSelf()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret.Data["id"] != token {
+		t.Fatalf("bad: %#v", secret.Data)
+	}
+
+	// Revoking the token through the proxy evicts it
+	if err := client.Auth().Token().RevokeSelf(""); err != nil {
+		t.Fatal(err)
+	}
+	client.ClearToken()
+	if create("default") == token {
+		t.Fatal("expected a new token")
+	}
+}
+
+func TestProxy_requestTooLarge(t *testing.T) {
+	proxy := NewProxy(&ProxyConfig{
+		Logger: logformat.NewVaultLogger(log.LevelTrace),
+	})
+
+	req := httptest.NewRequest("PUT", "/v1/secret/foo", bytes.NewReader(make([]byte, maxProxyRequestSize+1)))
+	w := httptest.NewRecorder()
+	proxy.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("bad: status code: %d", w.Code)
+	}
+}
@@ -0,0 +1,199 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	log "github.com/mgutz/logxi/v1"
+)
+
+const (
+	// defaultFileSinkMode is the mode of the files written by file sinks
+	defaultFileSinkMode = 0640
+
+	// sinkRetryInterval is the delay before retrying the sinks which failed
+	// to write the token
+	sinkRetryInterval = 5 * time.Second
+)
+
+// Sink is a destination of the token obtained by the agent
+type Sink interface {
+	// WriteToken writes the token, or its response-wrapping information if
+	// the sink wraps the token
+	WriteToken(token string) error
+}
+
+// SinkTarget is a sink and the wrapping TTL of the tokens written to it
+type SinkTarget struct {
+	Sink    Sink
+	WrapTTL time.Duration
+}
+
+// NewSink constructs the sink of the given configuration
+func NewSink(s *SinkConfig) (*SinkTarget, error) {
+	var sink Sink
+	var err error
+	switch s.Type {
+	case "file":
+		sink, err = NewFileSink(s.Config)
+	default:
+		return nil, fmt.Errorf("unknown sink type %q", s.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &SinkTarget{
+		Sink:    sink,
+		WrapTTL: s.WrapTTL,
+	}, nil
+}
+
+// FileSink writes the token to a file, which is replaced atomically
+type FileSink struct {
+	path string
+	mode os.FileMode
+}
+
+// NewFileSink constructs a file sink from the path and the optional octal
+// mode of the configuration
+func NewFileSink(conf map[string]string) (*FileSink, error) {
+	s := &FileSink{
+		path: conf["path"],
+		mode: defaultFileSinkMode,
+	}
+	if s.path == "" {
+		return nil, fmt.Errorf("'path' is required")
+	}
+	if v, ok := conf["mode"]; ok {
+		mode, err := strconv.ParseUint(v, 8, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid 'mode' %q: %s", v, err)
+		}
+		s.mode = os.FileMode(mode)
+	}
+	return s, nil
+}
+
+func (s *FileSink) WriteToken(token string) error {
+	return writeFileAtomic(s.path, []byte(token), s.mode)
+}
+
+// writeFileAtomic writes a file through a temporary file renamed over it, so
+// that readers never see a partially written file
+func writeFileAtomic(path string, data []byte, mode os.FileMode) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	tmpPath := f.Name()
+	defer os.Remove(tmpPath)
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpPath, mode); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// SinkServer writes the tokens received from an auth handler to the sinks.
+// Its client wraps the tokens, so it cannot be shared with the auth handler.
+type SinkServer struct {
+	Client *api.Client
+	Sinks  []*SinkTarget
+	Logger log.Logger
+
+	// ExitAfterAuth makes Run return once the first token is written to
+	// all the sinks
+	ExitAfterAuth bool
+}
+
+// Run writes the tokens of the channel until the context is canceled. The
+// sinks which fail to write a token are retried until they succeed or a new
+// token is received.
+func (s *SinkServer) Run(ctx context.Context, tokenCh <-chan string) {
+	var token string
+	var pending []*SinkTarget
+	var retry <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case token = <-tokenCh:
+			pending = s.Sinks
+		case <-retry:
+		}
+
+		pending = s.write(token, pending)
+		if len(pending) > 0 {
+			retry = time.After(sinkRetryInterval)
+			continue
+		}
+		retry = nil
+		if s.ExitAfterAuth {
+			return
+		}
+	}
+}
+
+// write writes the token to the sinks, returning those which failed
+func (s *SinkServer) write(token string, sinks []*SinkTarget) []*SinkTarget {
+	var failed []*SinkTarget
+	for _, sc := range sinks {
+		v := token
+		if sc.WrapTTL > 0 {
+			var err error
+			if v, err = s.wrapToken(token, sc.WrapTTL); err != nil {
+				s.Logger.Error("agent: error wrapping the token", "error", err)
+				failed = append(failed, sc)
+				continue
+			}
+		}
+		if err := sc.Sink.WriteToken(v); err != nil {
+			s.Logger.Error("agent: error writing the token to a sink", "error", err)
+			failed = append(failed, sc)
+			continue
+		}
+	}
+	if len(failed) == 0 {
+		s.Logger.Info("agent: token written to the sinks")
+	}
+	return failed
+}
+
+// wrapToken response-wraps the token, returning the JSON-encoded wrapping
+// information. The token is found in the token key of the unwrapped data.
+func (s *SinkServer) wrapToken(token string, ttl time.Duration) (string, error) {
+	s.Client.SetToken(token)
+	s.Client.SetWrappingLookupFunc(func(string, string) string {
+		return ttl.String()
+	})
+
+	secret, err := s.Client.Logical().Write("sys/wrapping/wrap", map[string]interface{}{
+		"token": token,
+	})
+	if err != nil {
+		return "", err
+	}
+	if secret == nil || secret.WrapInfo == nil {
+		return "", fmt.Errorf("wrapping response did not return wrapping information")
+	}
+
+	d, err := json.Marshal(secret.WrapInfo)
+	if err != nil {
+		return "", err
+	}
+	return string(d), nil
+}
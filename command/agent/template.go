@@ -0,0 +1,314 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/vault/api"
+	log "github.com/mgutz/logxi/v1"
+)
+
+const (
+	// defaultTemplatePerms is the mode of the rendered files
+	defaultTemplatePerms = 0640
+
+	// defaultStaticSecretRenderInterval is the interval at which the secrets
+	// without a lease, such as key/value secrets, are read again
+	defaultStaticSecretRenderInterval = 5 * time.Minute
+
+	// defaultTemplateCommandTimeout bounds the run time of the commands
+	// executed when a rendered file changes
+	defaultTemplateCommandTimeout = 30 * time.Second
+
+	// templateRetryInterval is the delay before rendering the templates
+	// again after a failure
+	templateRetryInterval = 5 * time.Second
+)
+
+// TemplateServer renders templates into files with the secrets read using
+// the token of the agent, renders them again before the secrets expire, and
+// runs the commands of the templates whose files change.
+type TemplateServer struct {
+	client                     *api.Client
+	templates                  []*agentTemplate
+	staticSecretRenderInterval time.Duration
+	exitAfterAuth              bool
+	logger                     log.Logger
+
+	// cache holds the secrets read by the templates until they are due to be
+	// read again, so that dynamic secrets are not issued on every render
+	cache map[string]*cachedSecret
+
+	// used holds the keys of the secrets read during the current render
+	used map[string]struct{}
+}
+
+// TemplateServerConfig is the configuration of a template server. Its client
+// cannot be shared with the auth handler or the sink server.
+type TemplateServerConfig struct {
+	Client                     *api.Client
+	Templates                  []*TemplateConfig
+	StaticSecretRenderInterval time.Duration
+	ExitAfterAuth              bool
+	Logger                     log.Logger
+}
+
+type agentTemplate struct {
+	config *TemplateConfig
+	tmpl   *template.Template
+}
+
+type cachedSecret struct {
+	secret    *api.Secret
+	refreshAt time.Time
+}
+
+// NewTemplateServer parses the templates of the configuration
+func NewTemplateServer(conf *TemplateServerConfig) (*TemplateServer, error) {
+	s := &TemplateServer{
+		client:                     conf.Client,
+		staticSecretRenderInterval: conf.StaticSecretRenderInterval,
+		exitAfterAuth:              conf.ExitAfterAuth,
+		logger:                     conf.Logger,
+		cache:                      make(map[string]*cachedSecret),
+	}
+	if s.staticSecretRenderInterval <= 0 {
+		s.staticSecretRenderInterval = defaultStaticSecretRenderInterval
+	}
+
+	funcs := template.FuncMap{
+		"secret": s.secret,
+		"toJSON": toJSON,
+	}
+	for _, tc := range conf.Templates {
+		contents := tc.Contents
+		if tc.Source != "" {
+			d, err := ioutil.ReadFile(tc.Source)
+			if err != nil {
+				return nil, fmt.Errorf("error reading template %s: %s", tc.Source, err)
+			}
+			contents = string(d)
+		}
+
+		tmpl, err := template.New(tc.Destination).
+			Delims(tc.LeftDelim, tc.RightDelim).
+			Funcs(funcs).
+			Option("missingkey=error").
+			Parse(contents)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing the template of %s: %s", tc.Destination, err)
+		}
+		s.templates = append(s.templates, &agentTemplate{
+			config: tc,
+			tmpl:   tmpl,
+		})
+	}
+
+	return s, nil
+}
+
+// Run renders the templates with the tokens of the channel until the context
+// is canceled. The secrets are read again after a new token is received.
+func (s *TemplateServer) Run(ctx context.Context, tokenCh <-chan string) {
+	var render <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case token := <-tokenCh:
+			s.client.SetToken(token)
+			s.cache = make(map[string]*cachedSecret)
+		case <-render:
+		}
+
+		next, err := s.renderAll(ctx)
+		if err != nil {
+			s.logger.Error("agent: error rendering the templates", "error", err)
+			render = time.After(templateRetryInterval)
+			continue
+		}
+		if s.exitAfterAuth {
+			return
+		}
+		render = time.After(next.Sub(time.Now()))
+	}
+}
+
+// renderAll renders the templates, runs the commands of those whose files
+// changed, and returns when the templates should be rendered again. The
+// commands run even if other templates fail to render.
+func (s *TemplateServer) renderAll(ctx context.Context) (time.Time, error) {
+	s.used = make(map[string]struct{})
+
+	var result error
+	var commands []*TemplateConfig
+	for _, t := range s.templates {
+		changed, err := s.render(t)
+		if err != nil {
+			result = multierror.Append(result, fmt.Errorf("%s: %s", t.config.Destination, err))
+			continue
+		}
+		if changed {
+			s.logger.Info("agent: rendered template", "destination", t.config.Destination)
+			if t.config.Command != "" {
+				commands = append(commands, t.config)
+			}
+		}
+	}
+
+	// The secrets no longer read by the templates are forgotten, and the
+	// templates are rendered again when the first secret is due
+	next := time.Now().Add(s.staticSecretRenderInterval)
+	for key, cached := range s.cache {
+		if _, ok := s.used[key]; !ok {
+			delete(s.cache, key)
+			continue
+		}
+		if cached.refreshAt.Before(next) {
+			next = cached.refreshAt
+		}
+	}
+
+	// A command shared by several templates runs once
+	ran := make(map[string]struct{})
+	for _, tc := range commands {
+		if _, ok := ran[tc.Command]; ok {
+			continue
+		}
+		ran[tc.Command] = struct{}{}
+		if err := runTemplateCommand(ctx, tc); err != nil {
+			s.logger.Error("agent: error running the command of a template",
+				"destination", tc.Destination, "command", tc.Command, "error", err)
+		}
+	}
+
+	return next, result
+}
+
+// render renders a template, writing its file only if the contents changed
+func (s *TemplateServer) render(t *agentTemplate) (bool, error) {
+	var buf bytes.Buffer
+	if err := t.tmpl.Execute(&buf, nil); err != nil {
+		return false, err
+	}
+
+	existing, err := ioutil.ReadFile(t.config.Destination)
+	if err == nil && bytes.Equal(existing, buf.Bytes()) {
+		return false, nil
+	}
+	if err := writeFileAtomic(t.config.Destination, buf.Bytes(), t.config.Perms); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// secret is the secret template function. It reads the secret at the path,
+// or writes the key=value parameters to it if any are given, such as to
+// issue a certificate.
+func (s *TemplateServer) secret(path string, params ...string) (*api.Secret, error) {
+	path = strings.Trim(path, "/")
+	key := path
+	if len(params) > 0 {
+		key += "?" + strings.Join(params, "&")
+	}
+	s.used[key] = struct{}{}
+
+	if cached, ok := s.cache[key]; ok && time.Now().Before(cached.refreshAt) {
+		return cached.secret, nil
+	}
+
+	var secret *api.Secret
+	var err error
+	if len(params) == 0 {
+		secret, err = s.client.Logical().Read(path)
+	} else {
+		data := make(map[string]interface{}, len(params))
+		for _, p := range params {
+			kv := strings.SplitN(p, "=", 2)
+			if len(kv) != 2 {
+				return nil, fmt.Errorf("invalid parameter %q of secret %s, expected key=value", p, path)
+			}
+			data[kv[0]] = kv[1]
+		}
+		secret, err = s.client.Logical().Write(path, data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil {
+		return nil, fmt.Errorf("no secret at %s", path)
+	}
+
+	s.cache[key] = &cachedSecret{
+		secret:    secret,
+		refreshAt: s.refreshTime(secret),
+	}
+	return secret, nil
+}
+
+// refreshTime returns when a secret should be read again: after two thirds
+// of its lease, or of the validity of a certificate, and otherwise after the
+// static secret render interval
+func (s *TemplateServer) refreshTime(secret *api.Secret) time.Time {
+	now := time.Now()
+	if secret.LeaseID != "" && secret.LeaseDuration > 0 {
+		return now.Add(time.Duration(secret.LeaseDuration) * time.Second * 2 / 3)
+	}
+	if expiration, ok := secretExpiration(secret); ok {
+		return now.Add(expiration.Sub(now) * 2 / 3)
+	}
+	return now.Add(s.staticSecretRenderInterval)
+}
+
+// secretExpiration returns the expiration of secrets holding certificates,
+// which are issued without a lease
+func secretExpiration(secret *api.Secret) (time.Time, bool) {
+	var seconds int64
+	switch v := secret.Data["expiration"].(type) {
+	case json.Number:
+		n, err := v.Int64()
+		if err != nil {
+			return time.Time{}, false
+		}
+		seconds = n
+	case float64:
+		seconds = int64(v)
+	case int64:
+		seconds = v
+	default:
+		return time.Time{}, false
+	}
+	return time.Unix(seconds, 0), true
+}
+
+// runTemplateCommand runs the command of a template with the shell
+func runTemplateCommand(ctx context.Context, tc *TemplateConfig) error {
+	timeout := tc.CommandTimeout
+	if timeout <= 0 {
+		timeout = defaultTemplateCommandTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, "/bin/sh", "-c", tc.Command).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func toJSON(v interface{}) (string, error) {
+	d, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(d), nil
+}
@@ -0,0 +1,189 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/helper/logformat"
+	vaulthttp "github.com/hashicorp/vault/http"
+	"github.com/hashicorp/vault/vault"
+	log "github.com/mgutz/logxi/v1"
+)
+
+func TestTemplateServer_render(t *testing.T) {
+	cluster := vault.NewTestCluster(t, nil, &vault.TestClusterOptions{
+		HandlerFunc: vaulthttp.Handler,
+	})
+	cluster.Start()
+	defer cluster.Cleanup()
+
+	client := cluster.Cores[0].Client
+	if _, err := client.Logical().Write("secret/foo", map[string]interface{}{
+		"password": "bar",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "vault-agent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	destination := filepath.Join(dir, "app.conf")
+	counter := filepath.Join(dir, "counter")
+	server, err := NewTemplateServer(&TemplateServerConfig{
+		Client: testAgentClient(t, cluster),
+		Templates: []*TemplateConfig{
+			&TemplateConfig{
+				Contents:    `{{ with secret "secret/foo" }}password={{ .Data.password }}{{ end }}`,
+				Destination: destination,
+				Perms:       0600,
+				Command:     "echo x >> " + counter,
+			},
+		},
+		Logger: logformat.NewVaultLogger(log.LevelTrace),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.client.SetToken(cluster.RootToken)
+
+	assertRender := func(expected string, runs int) {
+		t.Helper()
+		if _, err := server.renderAll(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		d, err := ioutil.ReadFile(destination)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(d) != expected {
+			t.Fatalf("bad: %s", d)
+		}
+		d, err = ioutil.ReadFile(counter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := strings.Count(string(d), "x"); n != runs {
+			t.Fatalf("expected the command to run %d times, ran %d times", runs, n)
+		}
+	}
+
+	assertRender("password=bar", 1)
+
+	info, err := os.Stat(destination)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Fatalf("bad: %v", info.Mode())
+	}
+
+	// The cached secret is used until it is due, and the command doesn't run
+	// again when the file is unchanged
+	if _, err := client.Logical().Write("secret/foo", map[string]interface{}{
+		"password": "baz",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	assertRender("password=bar", 1)
+
+	for _, cached := range server.cache {
+		cached.refreshAt = time.Now()
+	}
+	assertRender("password=baz", 2)
+}
+
+func TestTemplateServer_missingSecret(t *testing.T) {
+	cluster := vault.NewTestCluster(t, nil, &vault.TestClusterOptions{
+		HandlerFunc: vaulthttp.Handler,
+	})
+	cluster.Start()
+	defer cluster.Cleanup()
+
+	dir, err := ioutil.TempDir("", "vault-agent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	destination := filepath.Join(dir, "app.conf")
+	server, err := NewTemplateServer(&TemplateServerConfig{
+		Client: testAgentClient(t, cluster),
+		Templates: []*TemplateConfig{
+			&TemplateConfig{
+				Contents:    `{{ (secret "secret/missing").Data.password }}`,
+				Destination: destination,
+				Perms:       0600,
+			},
+		},
+		Logger: logformat.NewVaultLogger(log.LevelTrace),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.client.SetToken(cluster.RootToken)
+
+	if _, err := server.renderAll(context.Background()); err == nil {
+		t.Fatal("expected an error")
+	}
+	if _, err := os.Stat(destination); !os.IsNotExist(err) {
+		t.Fatalf("bad: %v", err)
+	}
+}
+
+func TestNewTemplateServer_invalid(t *testing.T) {
+	_, err := NewTemplateServer(&TemplateServerConfig{
+		Templates: []*TemplateConfig{
+			&TemplateConfig{
+				Contents:    `{{ with secret "secret/foo" }}`,
+				Destination: "/tmp/foo",
+			},
+		},
+	})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestTemplateServer_refreshTime(t *testing.T) {
+	s := &TemplateServer{
+		staticSecretRenderInterval: time.Hour,
+	}
+	now := time.Now()
+
+	cases := map[string]struct {
+		secret   *api.Secret
+		expected time.Duration
+	}{
+		"static": {
+			&api.Secret{LeaseDuration: 3600},
+			time.Hour,
+		},
+		"lease": {
+			&api.Secret{LeaseID: "database/creds/foo/1", LeaseDuration: 300},
+			200 * time.Second,
+		},
+		"certificate": {
+			&api.Secret{Data: map[string]interface{}{
+				"expiration": json.Number(strconv.FormatInt(now.Add(30*time.Minute).Unix(), 10)),
+			}},
+			20 * time.Minute,
+		},
+	}
+	for name, tc := range cases {
+		refresh := s.refreshTime(tc.secret).Sub(now)
+		if refresh < tc.expected-5*time.Second || refresh > tc.expected+5*time.Second {
+			t.Fatalf("%s: expected %s, got %s", name, tc.expected, refresh)
+		}
+	}
+}
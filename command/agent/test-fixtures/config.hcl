@@ -0,0 +1,20 @@
+pid_file = "./pidfile"
+exit_after_auth = true
+
+auto_auth {
+  method "aws" {
+    mount_path = "auth/aws-iam"
+    type       = "iam"
+    role       = "foobar"
+  }
+
+  sink "file" {
+    path = "/tmp/file-foo"
+  }
+
+  sink "file" {
+    wrap_ttl = "5m"
+    path     = "/tmp/file-bar"
+    mode     = "0600"
+  }
+}
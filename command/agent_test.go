@@ -0,0 +1,120 @@
+package command
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	credAppRole "github.com/hashicorp/vault/builtin/credential/approle"
+	"github.com/hashicorp/vault/http"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/meta"
+	"github.com/hashicorp/vault/vault"
+	"github.com/mitchellh/cli"
+)
+
+func TestAgent_noConfig(t *testing.T) {
+	ui := new(cli.MockUi)
+	c := &AgentCommand{
+		Meta: meta.Meta{
+			Ui: ui,
+		},
+	}
+
+	if code := c.Run(nil); code != 1 {
+		t.Fatalf("bad: %d", code)
+	}
+}
+
+func TestAgent_exitAfterAuth(t *testing.T) {
+	cluster := vault.NewTestCluster(t, &vault.CoreConfig{
+		CredentialBackends: map[string]logical.Factory{
+			"approle": credAppRole.Factory,
+		},
+	}, &vault.TestClusterOptions{
+		HandlerFunc: http.Handler,
+	})
+	cluster.Start()
+	defer cluster.Cleanup()
+
+	client := cluster.Cores[0].Client
+	if err := client.Sys().EnableAuthWithOptions("approle", &api.EnableAuthOptions{
+		Type: "approle",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Logical().Write("auth/approle/role/test", map[string]interface{}{
+		"bind_secret_id":  false,
+		"bound_cidr_list": "127.0.0.1/32",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	secret, err := client.Logical().Read("auth/approle/role/test/role-id")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "vault-agent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	roleIDPath := filepath.Join(dir, "role-id")
+	if err := ioutil.WriteFile(roleIDPath, []byte(secret.Data["role_id"].(string)), 0600); err != nil {
+		t.Fatal(err)
+	}
+	tokenPath := filepath.Join(dir, "token")
+	pidPath := filepath.Join(dir, "pid")
+	configPath := filepath.Join(dir, "agent.hcl")
+	config := fmt.Sprintf(`
+pid_file        = %q
+exit_after_auth = true
+
+auto_auth {
+  method "approle" {
+    role_id_file_path = %q
+  }
+
+  sink "file" {
+    path = %q
+  }
+}
+`, pidPath, roleIDPath, tokenPath)
+	if err := ioutil.WriteFile(configPath, []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	ui := new(cli.MockUi)
+	c := &AgentCommand{
+		Meta: meta.Meta{
+			Ui: ui,
+		},
+		ShutdownCh: make(chan struct{}),
+	}
+
+	args := []string{
+		"-address", client.Address(),
+		"-ca-cert", cluster.CACertPEMFile,
+		"-config", configPath,
+	}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	token, err := ioutil.ReadFile(tokenPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Auth().Token().Lookup(string(token)); err != nil {
+		t.Fatal(err)
+	}
+
+	// The PID file is removed on exit
+	if _, err := os.Stat(pidPath); !os.IsNotExist(err) {
+		t.Fatalf("bad: %v", err)
+	}
+}
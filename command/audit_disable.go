@@ -42,8 +42,10 @@ func (c *AuditDisableCommand) Run(args []string) int {
 		return 2
 	}
 
-	c.Ui.Output(fmt.Sprintf(
-		"Successfully disabled audit backend '%s' if it was enabled", id))
+	if !c.StructuredOutput() {
+		c.Ui.Output(fmt.Sprintf(
+			"Successfully disabled audit backend '%s' if it was enabled", id))
+	}
 
 	return 0
 }
@@ -84,8 +84,10 @@ func (c *AuditEnableCommand) Run(args []string) int {
 		return 1
 	}
 
-	c.Ui.Output(fmt.Sprintf(
-		"Successfully enabled audit backend '%s' with path '%s'!", auditType, path))
+	if !c.StructuredOutput() {
+		c.Ui.Output(fmt.Sprintf(
+			"Successfully enabled audit backend '%s' with path '%s'!", auditType, path))
+	}
 	return 0
 }
 
@@ -134,6 +136,7 @@ func (c *AuditEnableCommand) AutocompleteArgs() complete.Predictor {
 		"file",
 		"syslog",
 		"socket",
+		"webhook",
 	)
 }
 
@@ -35,6 +35,10 @@ func (c *AuditListCommand) Run(args []string) int {
 		return 2
 	}
 
+	if c.StructuredOutput() {
+		return OutputData(c.Ui, c.Format(), c.Field(), audits)
+	}
+
 	if len(audits) == 0 {
 		c.Ui.Error(fmt.Sprintf(
 			"No audit backends are enabled. Use `vault audit-enable` to\n" +
@@ -0,0 +1,118 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/vault/meta"
+	"github.com/posener/complete"
+	"github.com/ryanuber/columnize"
+)
+
+// AuditVerifyCommand is a Command that verifies the chain of the entries
+// logged by an audit backend.
+type AuditVerifyCommand struct {
+	meta.Meta
+}
+
+func (c *AuditVerifyCommand) Run(args []string) int {
+	var filePath string
+	flags := c.Meta.FlagSet("audit-verify", meta.FlagSetDefault)
+	flags.StringVar(&filePath, "file-path", "", "")
+	flags.Usage = func() { c.Ui.Error(c.Help()) }
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	args = flags.Args()
+	if len(args) != 1 {
+		flags.Usage()
+		c.Ui.Error(fmt.Sprintf(
+			"\naudit-verify expects one argument: the path of the audit backend"))
+		return 1
+	}
+
+	client, err := c.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf(
+			"Error initializing client: %s", err))
+		return 2
+	}
+
+	result, err := client.Sys().AuditVerify(args[0], filePath)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf(
+			"Error verifying audit log: %s", err))
+		return 2
+	}
+
+	if c.StructuredOutput() {
+		ret := OutputData(c.Ui, c.Format(), c.Field(), result)
+		if ret == 0 && !result.Valid {
+			return 2
+		}
+		return ret
+	}
+
+	columns := []string{
+		"Key | Value",
+		fmt.Sprintf("Entries | %d", result.Entries),
+		fmt.Sprintf("Unchained Entries | %d", result.UnchainedEntries),
+		fmt.Sprintf("Chain Start | %t", result.ChainStart),
+		fmt.Sprintf("First Previous Hash | %s", result.FirstPrevHash),
+		fmt.Sprintf("Last Hash | %s", result.LastHash),
+	}
+	c.Ui.Output(columnize.SimpleFormat(columns))
+
+	if !result.Valid {
+		if result.Line > 0 {
+			c.Ui.Error(fmt.Sprintf(
+				"\nThe audit log is invalid at line %d: %s", result.Line, result.Error))
+		} else {
+			c.Ui.Error(fmt.Sprintf(
+				"\nThe audit log is invalid: %s", result.Error))
+		}
+		return 2
+	}
+
+	c.Ui.Output("\nThe chain of the audit log is valid")
+	return 0
+}
+
+func (c *AuditVerifyCommand) Synopsis() string {
+	return "Verify the integrity of the log of an audit backend"
+}
+
+func (c *AuditVerifyCommand) Help() string {
+	helpText := `
+Usage: vault audit-verify [options] id
+
+  Verify the integrity of the log of an audit backend.
+
+  The audit backend must chain its entries, each of them including the
+  hash of the previous one, which is enabled by the "hash_chain" option of
+  the file audit backend. Modified, inserted and removed entries are
+  detected, as well as the truncation of the current log file.
+
+  The "id" parameter should map to the "path" used in "audit-enable".
+
+General Options:
+` + meta.GeneralOptionsUsage() + `
+Audit Verify Options:
+
+  -file-path=path         Verify the given log file, such as a rotated log,
+                          instead of the current one. The file must be in
+                          the directory of the log of the backend.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *AuditVerifyCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictNothing
+}
+
+func (c *AuditVerifyCommand) AutocompleteFlags() complete.Flags {
+	return complete.Flags{
+		"-file-path": complete.PredictFiles("*"),
+	}
+}
@@ -0,0 +1,85 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/audit"
+	auditFile "github.com/hashicorp/vault/builtin/audit/file"
+	"github.com/hashicorp/vault/http"
+	"github.com/hashicorp/vault/meta"
+	"github.com/hashicorp/vault/vault"
+	"github.com/mitchellh/cli"
+)
+
+func TestAuditVerify(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vault-test_audit_verify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.log")
+
+	cluster := vault.NewTestCluster(t, &vault.CoreConfig{
+		AuditBackends: map[string]audit.Factory{
+			"file": auditFile.Factory,
+		},
+	}, &vault.TestClusterOptions{
+		HandlerFunc: http.Handler,
+		NumCores:    1,
+	})
+	cluster.Start()
+	defer cluster.Cleanup()
+
+	client := cluster.Cores[0].Client
+	if err := client.Sys().EnableAuditWithOptions("file", &api.EnableAuditOptions{
+		Type: "file",
+		Options: map[string]string{
+			"file_path":  path,
+			"hash_chain": "true",
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Sys().ListAudit(); err != nil {
+		t.Fatal(err)
+	}
+
+	ui := new(cli.MockUi)
+	c := &AuditVerifyCommand{
+		Meta: meta.Meta{
+			ClientToken: client.Token(),
+			Ui:          ui,
+		},
+	}
+	args := []string{"-address", client.Address(), "-ca-cert", cluster.CACertPEMFile, "file"}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	if !strings.Contains(ui.OutputWriter.String(), "The chain of the audit log is valid") {
+		t.Fatalf("bad: %s", ui.OutputWriter.String())
+	}
+
+	// Tampering with the log is detected
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = []byte(strings.Replace(string(data), `"path":"sys/audit"`, `"path":"sys/mounts"`, 1))
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	ui = new(cli.MockUi)
+	c.Ui = ui
+	if code := c.Run(args); code != 2 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.OutputWriter.String())
+	}
+	if !strings.Contains(ui.ErrorWriter.String(), "The audit log is invalid at line") {
+		t.Fatalf("bad: %s", ui.ErrorWriter.String())
+	}
+}
@@ -130,8 +130,9 @@ func (c *AuthCommand) Run(args []string) int {
 	}
 
 	// Warn if the VAULT_TOKEN environment variable is set, as that will take
-	// precedence. Don't output on token-only since we're likely piping output.
-	if os.Getenv("VAULT_TOKEN") != "" && !tokenOnly {
+	// precedence. Don't output on token-only or structured output since we're
+	// likely piping output.
+	if os.Getenv("VAULT_TOKEN") != "" && !tokenOnly && !c.StructuredOutput() {
 		c.Ui.Output("==> WARNING: VAULT_TOKEN environment variable set!\n")
 		c.Ui.Output("  The environment variable takes precedence over the value")
 		c.Ui.Output("  set by the auth command. Either update the value of the")
@@ -183,6 +184,10 @@ func (c *AuthCommand) Run(args []string) int {
 		return ""
 	})
 
+	// authSecret is the response of the login, which is the structured
+	// output of the command
+	var authSecret *api.Secret
+
 CHECK_TOKEN:
 	var token string
 	switch {
@@ -192,6 +197,7 @@ CHECK_TOKEN:
 
 	case secret.Auth != nil:
 		token = secret.Auth.ClientToken
+		authSecret = secret
 
 	case secret.WrapInfo != nil:
 		if secret.WrapInfo.WrappedAccessor == "" {
@@ -203,7 +209,7 @@ CHECK_TOKEN:
 			return 0
 		}
 		if noStore {
-			return OutputSecret(c.Ui, "table", secret)
+			return c.outputSecret(secret)
 		}
 		client.SetToken(secret.WrapInfo.Token)
 		secret, err = client.Logical().Unwrap("")
@@ -239,10 +245,6 @@ CHECK_TOKEN:
 	}
 
 	if noVerify {
-		c.Ui.Output(fmt.Sprintf(
-			"Authenticated - no token verification has been performed.",
-		))
-
 		if noStore {
 			if err := tokenHelper.Erase(); err != nil {
 				c.Ui.Error(fmt.Sprintf(
@@ -253,6 +255,13 @@ CHECK_TOKEN:
 				return 1
 			}
 		}
+
+		if c.StructuredOutput() {
+			return c.outputSecret(authSecret)
+		}
+		c.Ui.Output(fmt.Sprintf(
+			"Authenticated - no token verification has been performed.",
+		))
 		return 0
 	}
 
@@ -316,6 +325,10 @@ CHECK_TOKEN:
 		}
 	}
 
+	if c.StructuredOutput() {
+		return c.outputSecret(authSecret)
+	}
+
 	// Get the policies we have
 	policiesRaw, ok := secret.Data["policies"]
 	if !ok || policiesRaw == nil {
@@ -346,6 +359,15 @@ CHECK_TOKEN:
 
 }
 
+// outputSecret outputs a response of the login in the format given with
+// -format, or the raw value of the field given with -field
+func (c *AuthCommand) outputSecret(secret *api.Secret) int {
+	if c.Field() != "" {
+		return PrintRawField(c.Ui, secret, c.Field())
+	}
+	return OutputSecret(c.Ui, c.Format(), secret)
+}
+
 func (c *AuthCommand) getMethods() (map[string]*api.AuthMount, error) {
 	client, err := c.Client()
 	if err != nil {
@@ -371,6 +393,10 @@ func (c *AuthCommand) listMethods() int {
 		return 1
 	}
 
+	if c.StructuredOutput() {
+		return OutputData(c.Ui, c.Format(), c.Field(), auth)
+	}
+
 	paths := make([]string, 0, len(auth))
 	for path := range auth {
 		paths = append(paths, path)
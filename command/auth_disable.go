@@ -42,8 +42,10 @@ func (c *AuthDisableCommand) Run(args []string) int {
 		return 2
 	}
 
-	c.Ui.Output(fmt.Sprintf(
-		"Disabled auth provider at path '%s' if it was enabled", path))
+	if !c.StructuredOutput() {
+		c.Ui.Output(fmt.Sprintf(
+			"Disabled auth provider at path '%s' if it was enabled", path))
+	}
 
 	return 0
 }
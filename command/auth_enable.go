@@ -16,12 +16,13 @@ type AuthEnableCommand struct {
 
 func (c *AuthEnableCommand) Run(args []string) int {
 	var description, path, pluginName string
-	var local bool
+	var local, sealWrap bool
 	flags := c.Meta.FlagSet("auth-enable", meta.FlagSetDefault)
 	flags.StringVar(&description, "description", "", "")
 	flags.StringVar(&path, "path", "", "")
 	flags.StringVar(&pluginName, "plugin-name", "", "")
 	flags.BoolVar(&local, "local", false, "")
+	flags.BoolVar(&sealWrap, "seal-wrap", false, "")
 	flags.Usage = func() { c.Ui.Error(c.Help()) }
 	if err := flags.Parse(args); err != nil {
 		return 1
@@ -60,7 +61,8 @@ func (c *AuthEnableCommand) Run(args []string) int {
 		Config: api.AuthConfigInput{
 			PluginName: pluginName,
 		},
-		Local: local,
+		Local:    local,
+		SealWrap: sealWrap,
 	}); err != nil {
 		c.Ui.Error(fmt.Sprintf(
 			"Error: %s", err))
@@ -72,9 +74,11 @@ func (c *AuthEnableCommand) Run(args []string) int {
 		authTypeOutput = fmt.Sprintf("plugin '%s'", pluginName)
 	}
 
-	c.Ui.Output(fmt.Sprintf(
-		"Successfully enabled %s at '%s'!",
-		authTypeOutput, path))
+	if !c.StructuredOutput() {
+		c.Ui.Output(fmt.Sprintf(
+			"Successfully enabled %s at '%s'!",
+			authTypeOutput, path))
+	}
 
 	return 0
 }
@@ -110,6 +114,10 @@ Auth Enable Options:
   -local                  Mark the mount as a local mount. Local mounts
                           are not replicated nor (if a secondary)
                           removed by replication.
+
+  -seal-wrap              Seal-wrap the storage entries of the auth
+                          provider, encrypting them with the seal in
+                          addition to the barrier.
 `
 	return strings.TrimSpace(helpText)
 }
@@ -122,6 +130,7 @@ func (c *AuthEnableCommand) AutocompleteArgs() complete.Predictor {
 		"app-id",
 		"gcp",
 		"github",
+		"jwt",
 		"userpass",
 		"ldap",
 		"okta",
@@ -137,5 +146,6 @@ func (c *AuthEnableCommand) AutocompleteFlags() complete.Flags {
 		"-path":        complete.PredictNothing,
 		"-plugin-name": complete.PredictNothing,
 		"-local":       complete.PredictNothing,
+		"-seal-wrap":   complete.PredictNothing,
 	}
 }
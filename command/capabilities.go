@@ -13,7 +13,9 @@ type CapabilitiesCommand struct {
 }
 
 func (c *CapabilitiesCommand) Run(args []string) int {
+	var grants bool
 	flags := c.Meta.FlagSet("capabilities", meta.FlagSetDefault)
+	flags.BoolVar(&grants, "grants", false, "")
 	flags.Usage = func() { c.Ui.Error(c.Help()) }
 	if err := flags.Parse(args); err != nil {
 		return 1
@@ -48,19 +50,34 @@ func (c *CapabilitiesCommand) Run(args []string) int {
 		return 2
 	}
 
-	var capabilities []string
 	if token == "" {
-		capabilities, err = client.Sys().CapabilitiesSelf(path)
-	} else {
-		capabilities, err = client.Sys().Capabilities(token, path)
+		token = client.Token()
 	}
+	capabilities, capabilityGrants, err := client.Sys().CapabilitiesWithGrants(token, path)
 	if err != nil {
 		c.Ui.Error(fmt.Sprintf(
 			"Error retrieving capabilities: %s", err))
 		return 1
 	}
 
+	if c.StructuredOutput() {
+		out := map[string]interface{}{
+			"capabilities": capabilities,
+		}
+		if grants {
+			out["grants"] = capabilityGrants
+		}
+		return OutputData(c.Ui, c.Format(), c.Field(), out)
+	}
+
 	c.Ui.Output(fmt.Sprintf("Capabilities: %s", capabilities))
+	if grants {
+		for _, capability := range capabilities {
+			for _, grant := range capabilityGrants[capability] {
+				c.Ui.Output(fmt.Sprintf("  %s: policy %q, path %q", capability, grant.Policy, grant.Path))
+			}
+		}
+	}
 	return 0
 }
 
@@ -82,6 +99,10 @@ Usage: vault capabilities [options] [token] path
   is invalid, this command will respond with a ["deny"].
 
 General Options:
-` + meta.GeneralOptionsUsage()
+` + meta.GeneralOptionsUsage() + `
+Capabilities Options:
+  -grants                 Also output the policies and the path rules granting
+                          each capability.
+`
 	return strings.TrimSpace(helpText)
 }
@@ -1,6 +1,7 @@
 package command
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/hashicorp/vault/http"
@@ -38,6 +39,14 @@ func TestCapabilities_Basic(t *testing.T) {
 		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
 	}
 
+	args = []string{"-address", addr, "-grants", "test"}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	if !strings.Contains(ui.OutputWriter.String(), `root: policy "root", path "*"`) {
+		t.Fatalf("bad: %s", ui.OutputWriter.String())
+	}
+
 	args = []string{"-address", addr, "invalidtoken", "test"}
 	if code := c.Run(args); code == 0 {
 		t.Fatalf("expected failure due to invalid token")
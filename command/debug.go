@@ -0,0 +1,468 @@
+package command
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/meta"
+	"github.com/hashicorp/vault/version"
+	"github.com/posener/complete"
+)
+
+// debugTargets are the kinds of information collected by vault debug
+var debugTargets = []string{"config", "host", "metrics", "pprof", "replication-status"}
+
+// debugProfiles are the runtime profiles collected at each interval, along
+// with the CPU profile and the execution trace sampled over the interval. The
+// heap profile also holds the allocations, which the "allocs" profile of Go
+// 1.11 and later only returns by default.
+var debugProfiles = []string{"goroutine", "heap", "block", "mutex", "threadcreate"}
+
+// debugTimeFormat is the format of the times in the names of the files of a
+// debug bundle
+const debugTimeFormat = "2006-01-02T15-04-05Z"
+
+// DebugCommand is a Command that collects information about a Vault server
+// over a period of time into a bundle for troubleshooting.
+type DebugCommand struct {
+	meta.Meta
+
+	ShutdownCh chan struct{}
+}
+
+// debugIndex describes a debug bundle, and is written to its index.json
+type debugIndex struct {
+	VaultAddress  string        `json:"vault_address"`
+	ClientVersion string        `json:"client_version"`
+	Timestamp     time.Time     `json:"timestamp"`
+	Duration      string        `json:"duration"`
+	Interval      string        `json:"interval"`
+	Targets       []string      `json:"targets"`
+	Errors        []*debugError `json:"errors"`
+}
+
+// debugError is an error which occurred while collecting a target
+type debugError struct {
+	Target    string    `json:"target"`
+	Timestamp time.Time `json:"timestamp"`
+	Error     string    `json:"error"`
+}
+
+// debugCollector collects the targets of vault debug into a directory
+type debugCollector struct {
+	sync.Mutex
+
+	command *DebugCommand
+	client  *api.Client
+	dir     string
+	index   *debugIndex
+
+	// polls holds the responses of the targets polled at each interval
+	polls map[string][]map[string]interface{}
+}
+
+func (c *DebugCommand) Run(args []string) int {
+	var output, targetsRaw string
+	var duration, interval time.Duration
+	flags := c.Meta.FlagSet("debug", meta.FlagSetDefault)
+	flags.StringVar(&output, "output", "", "")
+	flags.DurationVar(&duration, "duration", 2*time.Minute, "")
+	flags.DurationVar(&interval, "interval", 30*time.Second, "")
+	flags.StringVar(&targetsRaw, "targets", strings.Join(debugTargets, ","), "")
+	flags.Usage = func() { c.Ui.Error(c.Help()) }
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	if len(flags.Args()) != 0 {
+		c.Ui.Error("debug expects no arguments")
+		flags.Usage()
+		return 1
+	}
+	if interval < time.Second || interval%time.Second != 0 {
+		c.Ui.Error("The -interval flag must be a whole number of seconds")
+		return 1
+	}
+	if duration < interval {
+		c.Ui.Error("The -duration flag cannot be shorter than the -interval flag")
+		return 1
+	}
+
+	targets, err := parseDebugTargets(targetsRaw)
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
+	if output == "" {
+		output = fmt.Sprintf("vault-debug-%s.tar.gz", time.Now().UTC().Format(debugTimeFormat))
+	}
+	if _, err := os.Stat(output); err == nil {
+		c.Ui.Error(fmt.Sprintf("Output file %s already exists", output))
+		return 1
+	}
+
+	client, err := c.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf(
+			"Error initializing client: %s", err))
+		return 2
+	}
+
+	// The CPU profile and the execution trace hold their request for an
+	// interval
+	client.SetClientTimeout(interval + time.Minute)
+
+	dir, err := ioutil.TempDir("", "vault-debug")
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error creating a temporary directory: %s", err))
+		return 1
+	}
+	defer os.RemoveAll(dir)
+
+	collector := &debugCollector{
+		command: c,
+		client:  client,
+		dir:     dir,
+		index: &debugIndex{
+			VaultAddress:  client.Address(),
+			ClientVersion: version.GetVersion().VersionNumber(),
+			Timestamp:     time.Now().UTC(),
+			Duration:      duration.String(),
+			Interval:      interval.String(),
+			Targets:       targets,
+			Errors:        []*debugError{},
+		},
+		polls: make(map[string][]map[string]interface{}),
+	}
+
+	c.Ui.Output(fmt.Sprintf(
+		"==> Collecting %s from %s every %s for %s",
+		strings.Join(targets, ", "), client.Address(), interval, duration))
+	collector.run(targets, duration, interval, c.ShutdownCh)
+
+	if err := collector.write(); err != nil {
+		c.Ui.Error(fmt.Sprintf("Error writing the collected data: %s", err))
+		return 1
+	}
+	if err := writeDebugArchive(dir, output); err != nil {
+		c.Ui.Error(fmt.Sprintf("Error writing the bundle to %s: %s", output, err))
+		return 1
+	}
+
+	if len(collector.index.Errors) > 0 {
+		c.Ui.Output(fmt.Sprintf(
+			"%d errors occurred while collecting, see index.json in the bundle",
+			len(collector.index.Errors)))
+	}
+	c.Ui.Output(fmt.Sprintf("Success! Bundle written to: %s", output))
+	return 0
+}
+
+// parseDebugTargets parses the comma-separated targets of the -targets flag
+func parseDebugTargets(raw string) ([]string, error) {
+	var targets []string
+	for _, target := range strings.Split(raw, ",") {
+		target = strings.TrimSpace(target)
+		if target == "" {
+			continue
+		}
+		if !strutil.StrListContains(debugTargets, target) {
+			return nil, fmt.Errorf("Unknown target %q, the targets are %s",
+				target, strings.Join(debugTargets, ", "))
+		}
+		targets = append(targets, target)
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("The -targets flag requires at least one target")
+	}
+	return targets, nil
+}
+
+// run collects the targets until the duration elapses or the shutdown
+// channel is closed. The configuration is collected once, and the other
+// targets at each interval.
+func (d *debugCollector) run(targets []string, duration, interval time.Duration, shutdownCh chan struct{}) {
+	if strutil.StrListContains(targets, "config") {
+		d.poll("config", "sys/config/state")
+	}
+
+	captures := int(duration / interval)
+
+	// The profiles are sampled over each interval back to back, since only
+	// one CPU profile can be sampled at once, concurrently with the polling
+	// of the other targets
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	stopCh := make(chan struct{})
+	if strutil.StrListContains(targets, "pprof") {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < captures; i++ {
+				select {
+				case <-stopCh:
+					return
+				default:
+				}
+				d.profile(time.Now().UTC(), interval)
+			}
+		}()
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for i := 0; i < captures; i++ {
+		if i > 0 {
+			select {
+			case <-ticker.C:
+			case <-shutdownCh:
+				d.command.Ui.Output("==> Interrupted, writing the data collected so far")
+				close(stopCh)
+				return
+			}
+		}
+
+		for _, target := range targets {
+			switch target {
+			case "host":
+				d.poll(target, "sys/host-info")
+			case "metrics":
+				d.poll(target, "sys/metrics")
+			case "replication-status":
+				d.poll(target, "sys/replication/status")
+			}
+		}
+	}
+}
+
+// poll reads the path and appends its data to the polls of the target
+func (d *debugCollector) poll(target, path string) {
+	secret, err := d.client.Logical().Read(path)
+	if err == nil && secret == nil {
+		err = fmt.Errorf("no data at %s", path)
+	}
+	if err != nil {
+		d.addError(target, err)
+		return
+	}
+
+	data := secret.Data
+	if data == nil {
+		data = make(map[string]interface{})
+	}
+	if _, ok := data["timestamp"]; !ok {
+		data["timestamp"] = time.Now().UTC().Format(time.RFC3339Nano)
+	}
+
+	d.Lock()
+	d.polls[target] = append(d.polls[target], data)
+	d.Unlock()
+}
+
+// profile writes the runtime profiles of the server, and its CPU profile and
+// execution trace sampled over the interval, to a directory named after the
+// start of the interval
+func (d *debugCollector) profile(start time.Time, interval time.Duration) {
+	dir := filepath.Join(d.dir, "pprof", start.Format(debugTimeFormat))
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		d.addError("pprof", err)
+		return
+	}
+
+	seconds := int(interval / time.Second)
+	files := map[string]string{
+		"profile": "profile.prof",
+		"trace":   "trace.out",
+	}
+	for _, name := range debugProfiles {
+		files[name] = name + ".prof"
+	}
+
+	var wg sync.WaitGroup
+	for name, file := range files {
+		wg.Add(1)
+		go func(name, file string) {
+			defer wg.Done()
+			data, err := d.client.Sys().Pprof(name, seconds)
+			if err != nil {
+				d.addError("pprof", fmt.Errorf("error collecting the %s profile: %s", name, err))
+				return
+			}
+			if err := ioutil.WriteFile(filepath.Join(dir, file), data, 0600); err != nil {
+				d.addError("pprof", err)
+			}
+		}(name, file)
+	}
+	wg.Wait()
+}
+
+// addError records an error in the index and reports it
+func (d *debugCollector) addError(target string, err error) {
+	d.Lock()
+	defer d.Unlock()
+
+	d.index.Errors = append(d.index.Errors, &debugError{
+		Target:    target,
+		Timestamp: time.Now().UTC(),
+		Error:     err.Error(),
+	})
+	d.command.Ui.Warn(fmt.Sprintf("Error collecting %s: %s", target, err))
+}
+
+// write writes the polled targets and the index to the directory
+func (d *debugCollector) write() error {
+	d.Lock()
+	defer d.Unlock()
+
+	files := map[string]interface{}{
+		"index.json": d.index,
+	}
+	for target, polls := range d.polls {
+		name := strings.Replace(target, "-", "_", -1) + ".json"
+		if target == "config" {
+			// The configuration is only read once
+			files[name] = polls[0]
+			continue
+		}
+		files[name] = polls
+	}
+
+	for name, v := range files {
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(filepath.Join(d.dir, name), data, 0600); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeDebugArchive writes the files of the directory to a gzipped tarball,
+// under a directory named after the tarball
+func writeDebugArchive(dir, output string) (err error) {
+	f, err := os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(output)
+		}
+	}()
+
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	root := strings.TrimSuffix(filepath.Base(output), ".tar.gz")
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(filepath.Join(root, rel))
+		if info.IsDir() {
+			header.Name += "/"
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		src, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		_, err = io.Copy(tw, src)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func (c *DebugCommand) Synopsis() string {
+	return "Collect information about a server for troubleshooting"
+}
+
+func (c *DebugCommand) Help() string {
+	helpText := `
+Usage: vault debug [options]
+
+  Collect information about a Vault server over a period of time into a
+  gzipped tarball, which can be attached to support requests:
+
+      $ vault debug -duration=5m -interval=30s
+
+  The configuration of the server, without its secrets, is collected once.
+  Its host information, metrics and replication status are collected at each
+  interval, along with its runtime profiles, and its CPU profile and execution
+  trace sampled over the interval. The errors, such as those of the targets
+  the token is not allowed to read, are listed in the index.json file of the
+  bundle rather than stopping the collection. Interrupting the command writes
+  the bundle with the data collected so far.
+
+  All the targets but the replication status require a root token.
+
+General Options:
+` + meta.GeneralOptionsUsage() + `
+Debug Options:
+
+  -output=<path>          Path of the bundle. Defaults to
+                          "vault-debug-<time>.tar.gz" in the current directory.
+
+  -duration=<duration>    Duration of the collection. Defaults to "2m".
+
+  -interval=<duration>    Interval between the collections of the targets, and
+                          duration of the CPU profiles and execution traces.
+                          Defaults to "30s".
+
+  -targets=<list>         Comma-separated targets to collect, among config,
+                          host, metrics, pprof and replication-status. Defaults
+                          to all of them.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *DebugCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictNothing
+}
+
+func (c *DebugCommand) AutocompleteFlags() complete.Flags {
+	return complete.Flags{
+		"-output":   complete.PredictFiles("*.tar.gz"),
+		"-duration": complete.PredictAnything,
+		"-interval": complete.PredictAnything,
+		"-targets":  complete.PredictSet(debugTargets...),
+	}
+}
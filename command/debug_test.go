@@ -0,0 +1,124 @@
+package command
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/http"
+	"github.com/hashicorp/vault/meta"
+	"github.com/hashicorp/vault/vault"
+	"github.com/mitchellh/cli"
+)
+
+func TestDebug(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := http.TestServer(t, core)
+	defer ln.Close()
+
+	dir, err := ioutil.TempDir("", "vault-debug")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	output := filepath.Join(dir, "bundle.tar.gz")
+
+	ui := new(cli.MockUi)
+	c := &DebugCommand{
+		Meta: meta.Meta{
+			ClientToken: token,
+			Ui:          ui,
+		},
+	}
+	args := []string{
+		"-address", addr,
+		"-output", output,
+		"-duration=2s",
+		"-interval=1s",
+	}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	f, err := os.Open(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string][]byte)
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err != nil {
+			break
+		}
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[header.Name] = data
+	}
+
+	var hostInfo []map[string]interface{}
+	if err := json.Unmarshal(files["bundle/host.json"], &hostInfo); err != nil {
+		t.Fatal(err)
+	}
+	if len(hostInfo) != 2 || hostInfo[0]["hostname"] == nil {
+		t.Fatalf("bad: %#v", hostInfo)
+	}
+	if !strings.Contains(string(files["bundle/replication_status.json"]), "mode") {
+		t.Fatalf("bad: %s", files["bundle/replication_status.json"])
+	}
+
+	var profiles int
+	for name, data := range files {
+		if strings.HasPrefix(name, "bundle/pprof/") && strings.HasSuffix(name, "/profile.prof") && len(data) > 0 {
+			profiles++
+		}
+	}
+	if profiles != 2 {
+		t.Fatalf("expected 2 CPU profiles, got %d", profiles)
+	}
+
+	// The test server has no configuration or metrics, whose errors are
+	// recorded in the index
+	var index debugIndex
+	if err := json.Unmarshal(files["bundle/index.json"], &index); err != nil {
+		t.Fatal(err)
+	}
+	targets := make(map[string]bool)
+	for _, e := range index.Errors {
+		targets[e.Target] = true
+	}
+	if len(targets) != 2 || !targets["config"] || !targets["metrics"] {
+		t.Fatalf("bad: %s", files["bundle/index.json"])
+	}
+}
+
+func TestDebug_invalid(t *testing.T) {
+	for _, args := range [][]string{
+		{"-interval=500ms"},
+		{"-duration=1s", "-interval=2s"},
+		{"-targets=foo"},
+		{"-targets="},
+	} {
+		ui := new(cli.MockUi)
+		c := &DebugCommand{
+			Meta: meta.Meta{
+				Ui: ui,
+			},
+		}
+		if code := c.Run(args); code != 1 {
+			t.Fatalf("%v: bad: %d", args, code)
+		}
+	}
+}
@@ -41,7 +41,9 @@ func (c *DeleteCommand) Run(args []string) int {
 		return 1
 	}
 
-	c.Ui.Output(fmt.Sprintf("Success! Deleted '%s' if it existed.", path))
+	if !c.StructuredOutput() {
+		c.Ui.Output(fmt.Sprintf("Success! Deleted '%s' if it existed.", path))
+	}
 	return 0
 }
 
@@ -18,7 +18,7 @@ import (
 	"github.com/ryanuber/columnize"
 )
 
-var predictFormat complete.Predictor = complete.PredictSet("json", "yaml")
+var predictFormat complete.Predictor = complete.PredictSet("table", "json", "yaml")
 
 func OutputSecret(ui cli.Ui, format string, secret *api.Secret) int {
 	return outputWithFormat(ui, format, secret, secret)
@@ -41,6 +41,69 @@ func outputWithFormat(ui cli.Ui, format string, secret *api.Secret, data interfa
 	return 0
 }
 
+// OutputData outputs the structured result of a command, such as the
+// response of a sys endpoint, in the json or yaml format, or the raw value of
+// one of its top-level fields if field is set. The fields are named by the
+// JSON tags of the result in every format, so that their names are stable.
+func OutputData(ui cli.Ui, format, field string, data interface{}) int {
+	if field != "" {
+		return printRawDataField(ui, data, field)
+	}
+
+	formatter, ok := Formatters[strings.ToLower(format)]
+	if !ok {
+		ui.Error(fmt.Sprintf("Invalid output format: %s", format))
+		return 1
+	}
+	if _, ok := formatter.(TableFormatter); ok {
+		ui.Error("The table format is not supported by this output")
+		return 1
+	}
+	if err := formatter.Output(ui, nil, data); err != nil {
+		ui.Error(fmt.Sprintf("Could not output data: %s", err.Error()))
+		return 1
+	}
+	return 0
+}
+
+// printRawDataField prints the raw value of a top-level field of the
+// structured result of a command
+func printRawDataField(ui cli.Ui, data interface{}, field string) int {
+	// The result is converted to a map through JSON, so that the fields are
+	// named by their JSON tags
+	b, err := json.Marshal(data)
+	if err != nil {
+		ui.Error(fmt.Sprintf("Could not output data: %s", err.Error()))
+		return 1
+	}
+	var m map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&m); err != nil {
+		ui.Error(fmt.Sprintf("Field %s not present in output", field))
+		return 1
+	}
+
+	val, ok := m[field]
+	if !ok || val == nil {
+		ui.Error(fmt.Sprintf("Field %s not present in output", field))
+		return 1
+	}
+
+	// Objects and lists are output as JSON, scalars as is
+	switch val.(type) {
+	case map[string]interface{}, []interface{}:
+		b, err := json.Marshal(val)
+		if err != nil {
+			ui.Error(fmt.Sprintf("Could not output data: %s", err.Error()))
+			return 1
+		}
+		val = string(b)
+	}
+	printRaw(ui, val)
+	return 0
+}
+
 type Formatter interface {
 	Output(ui cli.Ui, secret *api.Secret, data interface{}) error
 }
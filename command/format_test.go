@@ -7,6 +7,7 @@ import (
 	"github.com/ghodss/yaml"
 	"github.com/hashicorp/vault/api"
 	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/mitchellh/cli"
 )
 
 var output string
@@ -80,3 +81,53 @@ func TestTableFormatter(t *testing.T) {
 		t.Fatal("did not find 'something'")
 	}
 }
+
+func TestOutputData(t *testing.T) {
+	data := &api.SealStatusResponse{
+		Sealed:   true,
+		T:        3,
+		N:        5,
+		Progress: 1,
+		Nonce:    "foo",
+	}
+
+	ui := new(cli.MockUi)
+	if code := OutputData(ui, "json", "", data); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	var result map[string]interface{}
+	if err := jsonutil.DecodeJSON(ui.OutputWriter.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	if result["sealed"] != true || result["nonce"] != "foo" {
+		t.Fatalf("bad: %#v", result)
+	}
+
+	ui = new(cli.MockUi)
+	if code := OutputData(ui, "yaml", "", data); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	if !strings.Contains(ui.OutputWriter.String(), "progress: 1") {
+		t.Fatalf("bad: %s", ui.OutputWriter.String())
+	}
+
+	cases := map[string]string{
+		"sealed": "true",
+		"t":      "3",
+		"nonce":  "foo",
+	}
+	for field, expected := range cases {
+		ui = new(cli.MockUi)
+		if code := OutputData(ui, "table", field, data); code != 0 {
+			t.Fatalf("%s: bad: %d\n\n%s", field, code, ui.ErrorWriter.String())
+		}
+		if actual := strings.TrimSpace(ui.OutputWriter.String()); actual != expected {
+			t.Fatalf("%s: expected %q, got %q", field, expected, actual)
+		}
+	}
+
+	ui = new(cli.MockUi)
+	if code := OutputData(ui, "json", "missing", data); code != 1 {
+		t.Fatalf("bad: %d", code)
+	}
+}
@@ -57,7 +57,13 @@ func (c *GenerateRootCommand) Run(args []string) int {
 			c.Ui.Error(fmt.Sprintf("Read %d bytes when we should have read 16", readLen))
 			return 1
 		}
-		c.Ui.Output(fmt.Sprintf("OTP: %s", base64.StdEncoding.EncodeToString(buf)))
+		otp := base64.StdEncoding.EncodeToString(buf)
+		if c.StructuredOutput() {
+			return OutputData(c.Ui, c.Format(), c.Field(), map[string]interface{}{
+				"otp": otp,
+			})
+		}
+		c.Ui.Output(fmt.Sprintf("OTP: %s", otp))
 		return 0
 	}
 
@@ -185,9 +191,7 @@ func (c *GenerateRootCommand) Run(args []string) int {
 		return 1
 	}
 
-	c.dumpStatus(statusResp)
-
-	return 0
+	return c.dumpStatus(statusResp)
 }
 
 func (c *GenerateRootCommand) verifyOTP(otp string) error {
@@ -218,6 +222,12 @@ func (c *GenerateRootCommand) decode(encodedVal, otp string) int {
 		return 1
 	}
 
+	if c.StructuredOutput() {
+		return OutputData(c.Ui, c.Format(), c.Field(), map[string]interface{}{
+			"root_token": token,
+		})
+	}
+
 	c.Ui.Output(fmt.Sprintf("Root token: %s", token))
 
 	return 0
@@ -232,9 +242,7 @@ func (c *GenerateRootCommand) initGenerateRoot(client *api.Client, otp string, p
 		return 1
 	}
 
-	c.dumpStatus(status)
-
-	return 0
+	return c.dumpStatus(status)
 }
 
 // cancelGenerateRoot is used to abort the generation process
@@ -244,7 +252,9 @@ func (c *GenerateRootCommand) cancelGenerateRoot(client *api.Client) int {
 		c.Ui.Error(fmt.Sprintf("Failed to cancel root generation: %s", err))
 		return 1
 	}
-	c.Ui.Output("Root generation canceled.")
+	if !c.StructuredOutput() {
+		c.Ui.Output("Root generation canceled.")
+	}
 	return 0
 }
 
@@ -257,13 +267,15 @@ func (c *GenerateRootCommand) rootGenerationStatus(client *api.Client) int {
 		return 1
 	}
 
-	c.dumpStatus(status)
-
-	return 0
+	return c.dumpStatus(status)
 }
 
 // dumpStatus dumps the status to output
-func (c *GenerateRootCommand) dumpStatus(status *api.GenerateRootStatusResponse) {
+func (c *GenerateRootCommand) dumpStatus(status *api.GenerateRootStatusResponse) int {
+	if c.StructuredOutput() {
+		return OutputData(c.Ui, c.Format(), c.Field(), status)
+	}
+
 	// Dump the status
 	statString := fmt.Sprintf(
 		"Nonce: %s\n"+
@@ -284,6 +296,7 @@ func (c *GenerateRootCommand) dumpStatus(status *api.GenerateRootStatusResponse)
 		statString = fmt.Sprintf("%s\n\nEncoded root token: %s", statString, status.EncodedRootToken)
 	}
 	c.Ui.Output(statString)
+	return 0
 }
 
 func (c *GenerateRootCommand) Synopsis() string {
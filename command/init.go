@@ -225,6 +225,10 @@ func (c *InitCommand) runInit(check bool, initRequest *api.InitRequest) int {
 		return 1
 	}
 
+	if c.StructuredOutput() {
+		return OutputData(c.Ui, c.Format(), c.Field(), resp)
+	}
+
 	for i, key := range resp.Keys {
 		if resp.KeysB64 != nil && len(resp.KeysB64) == len(resp.Keys) {
 			c.Ui.Output(fmt.Sprintf("Unseal Key %d: %s", i+1, resp.KeysB64[i]))
@@ -242,7 +246,7 @@ func (c *InitCommand) runInit(check bool, initRequest *api.InitRequest) int {
 
 	c.Ui.Output(fmt.Sprintf("Initial Root Token: %s", resp.RootToken))
 
-	if initRequest.StoredShares < 1 {
+	if len(resp.Keys) > 0 {
 		c.Ui.Output(fmt.Sprintf(
 			"\n"+
 				"Vault initialized with %d keys and a key threshold of %d. Please\n"+
@@ -282,6 +286,14 @@ func (c *InitCommand) checkStatus(client *api.Client) int {
 		c.Ui.Error(fmt.Sprintf(
 			"Error checking initialization status: %s", err))
 		return 1
+	case c.StructuredOutput():
+		ret := OutputData(c.Ui, c.Format(), c.Field(), map[string]interface{}{
+			"initialized": inited,
+		})
+		if ret == 0 && !inited {
+			return 2
+		}
+		return ret
 	case inited:
 		c.Ui.Output("Vault has been initialized")
 		return 0
@@ -323,9 +335,9 @@ Init Options:
   -key-threshold=3          The number of key shares required to reconstruct
                             the master key.
 
-  -stored-shares=0          The number of unseal keys to store. Only used with 
-                            Vault HSM. Must currently be equivalent to the
-                            number of shares.
+  -stored-shares=0          The number of unseal keys to store. Only used with
+                            auto-unseal seals, which store the single unseal
+                            key when this is not set.
 
   -pgp-keys                 If provided, must be a comma-separated list of
                             files on disk containing binary- or base64-format
@@ -349,13 +361,14 @@ Init Options:
                             to base64-decode and decrypt the result.
 
   -recovery-shares=5        The number of key shares to split the recovery key
-                            into. Only used with Vault HSM.
+                            into. Only used with auto-unseal seals.
 
   -recovery-threshold=3     The number of key shares required to reconstruct
-                            the recovery key. Only used with Vault HSM.
+                            the recovery key. Only used with auto-unseal seals.
 
   -recovery-pgp-keys        If provided, behaves like "pgp-keys" but for the
-                            recovery key shares. Only used with Vault HSM.
+                            recovery key shares. Only used with auto-unseal
+                            seals.
 
   -auto                     If set, performs service discovery using Consul. 
                             When all the nodes of a Vault cluster are
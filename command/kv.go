@@ -0,0 +1,119 @@
+package command
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/helper/kv-builder"
+	"github.com/mitchellh/cli"
+)
+
+// kvMount is the KV mount holding the path given to a kv command
+type kvMount struct {
+	// Path is the path of the mount, with a trailing slash
+	Path string
+
+	// Versioned is whether the mount is a versioned KV mount, whose keys
+	// are read and written under its data/ prefix
+	Versioned bool
+}
+
+// kvPreflight finds the KV mount holding the path in the mount table, and
+// returns it along with the key relative to the mount
+func kvPreflight(client *api.Client, path string) (*kvMount, string, error) {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil, "", fmt.Errorf("missing path")
+	}
+
+	mounts, err := client.Sys().ListMounts()
+	if err != nil {
+		return nil, "", fmt.Errorf("error listing the mounts, which requires the read capability on sys/mounts: %s", err)
+	}
+
+	// The longest mount path prefixing the path holds it
+	var mountPath string
+	for p := range mounts {
+		if strings.HasPrefix(path+"/", p) && len(p) > len(mountPath) {
+			mountPath = p
+		}
+	}
+	if mountPath == "" {
+		return nil, "", fmt.Errorf("no mount holds %s", path)
+	}
+
+	mount := mounts[mountPath]
+	switch mount.Type {
+	case "kv", "generic":
+	default:
+		return nil, "", fmt.Errorf("%s is not a KV mount but a %s mount", mountPath, mount.Type)
+	}
+
+	return &kvMount{
+		Path:      mountPath,
+		Versioned: mount.Options["version"] == "2",
+	}, strings.TrimSuffix(strings.TrimPrefix(path+"/", mountPath), "/"), nil
+}
+
+// kvVersionedPreflight is kvPreflight for the commands only supported by
+// versioned KV mounts
+func kvVersionedPreflight(client *api.Client, path string) (*kvMount, string, error) {
+	mount, key, err := kvPreflight(client, path)
+	if err != nil {
+		return nil, "", err
+	}
+	if !mount.Versioned {
+		return nil, "", fmt.Errorf("%s is not a versioned KV mount", mount.Path)
+	}
+	return mount, key, nil
+}
+
+// versionedPath returns the path of a key under one of the prefixes of a
+// versioned KV mount, such as data or metadata
+func (m *kvMount) versionedPath(prefix, key string) string {
+	return m.Path + prefix + "/" + key
+}
+
+// keyPath returns the path of a key of an unversioned KV mount
+func (m *kvMount) keyPath(key string) string {
+	return m.Path + key
+}
+
+// parseKVData parses the key=value arguments of the commands writing data,
+// reading the values given as "-" from stdin and those prefixed with "@"
+// from files
+func parseKVData(stdin io.Reader, args []string) (map[string]interface{}, error) {
+	if stdin == nil {
+		stdin = os.Stdin
+	}
+
+	builder := &kvbuilder.Builder{Stdin: stdin}
+	if err := builder.Add(args...); err != nil {
+		return nil, err
+	}
+
+	return builder.Map(), nil
+}
+
+// outputKVVersioned outputs a key read from a versioned KV mount: its data
+// and the metadata of its version as a table, the whole secret in the
+// structured formats, or the raw value of a field of the data
+func outputKVVersioned(ui cli.Ui, format, field string, secret *api.Secret) int {
+	data, _ := secret.Data["data"].(map[string]interface{})
+	metadata, _ := secret.Data["metadata"].(map[string]interface{})
+
+	if field != "" {
+		return PrintRawField(ui, &api.Secret{Data: data}, field)
+	}
+	if strings.ToLower(format) != "table" {
+		return OutputSecret(ui, format, secret)
+	}
+
+	ui.Output("====== Metadata ======")
+	OutputSecret(ui, format, &api.Secret{Data: metadata})
+	ui.Output("\n======== Data ========")
+	return OutputSecret(ui, format, &api.Secret{Data: data})
+}
@@ -0,0 +1,139 @@
+package command
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/vault/meta"
+	"github.com/posener/complete"
+)
+
+// KVDeleteCommand is a Command that deletes a key, or some of its versions,
+// from a KV mount.
+type KVDeleteCommand struct {
+	meta.Meta
+}
+
+func (c *KVDeleteCommand) Run(args []string) int {
+	var versionsRaw string
+	flags := c.Meta.FlagSet("kv delete", meta.FlagSetDefault)
+	flags.StringVar(&versionsRaw, "versions", "", "")
+	flags.Usage = func() { c.Ui.Error(c.Help()) }
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	args = flags.Args()
+	if len(args) != 1 {
+		c.Ui.Error("kv delete expects one argument")
+		flags.Usage()
+		return 1
+	}
+
+	versions, err := parseKVVersions(versionsRaw)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Invalid -versions: %s", err))
+		return 1
+	}
+
+	client, err := c.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf(
+			"Error initializing client: %s", err))
+		return 2
+	}
+
+	mount, key, err := kvPreflight(client, args[0])
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error deleting %s: %s", args[0], err))
+		return 1
+	}
+
+	var path string
+	switch {
+	case !mount.Versioned && len(versions) > 0:
+		c.Ui.Error(fmt.Sprintf("%s is not a versioned KV mount, so -versions is not supported", mount.Path))
+		return 1
+	case !mount.Versioned:
+		path = mount.keyPath(key)
+		_, err = client.Logical().Delete(path)
+	case len(versions) > 0:
+		// The versions are deleted through the delete/ path, since the
+		// data/ path only deletes the current version
+		path = mount.versionedPath("delete", key)
+		_, err = client.Logical().Write(path, map[string]interface{}{
+			"versions": versions,
+		})
+	default:
+		path = mount.versionedPath("data", key)
+		_, err = client.Logical().Delete(path)
+	}
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf(
+			"Error deleting %s: %s", path, err))
+		return 1
+	}
+
+	if !c.StructuredOutput() {
+		c.Ui.Output(fmt.Sprintf("Success! Deleted %s if it existed.", path))
+	}
+	return 0
+}
+
+// parseKVVersions parses a comma-separated list of versions of a key
+func parseKVVersions(raw string) ([]int, error) {
+	var versions []int
+	for _, v := range strings.Split(raw, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		version, err := strconv.Atoi(v)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("%q is not a version", v)
+		}
+		versions = append(versions, version)
+	}
+	return versions, nil
+}
+
+func (c *KVDeleteCommand) Synopsis() string {
+	return "Delete a key, or some of its versions, from a KV mount"
+}
+
+func (c *KVDeleteCommand) Help() string {
+	helpText := `
+Usage: vault kv delete [options] path
+
+  Delete a key from a KV mount.
+
+  On a versioned KV mount, the current version of the key is deleted, or the
+  versions given with -versions. Deleted versions can be restored with the
+  undelete/ path of the mount until they are destroyed, and their metadata is
+  kept until "vault kv metadata delete" removes the key entirely:
+
+      $ vault kv delete -versions=1,2 secret/foo
+
+  This command requires the read capability on sys/mounts to find the mount
+  holding the key.
+
+General Options:
+` + meta.GeneralOptionsUsage() + `
+KV Delete Options:
+
+  -versions=<list>        Comma-separated versions of the key to delete on a
+                          versioned KV mount. Defaults to the current version.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *KVDeleteCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictNothing
+}
+
+func (c *KVDeleteCommand) AutocompleteFlags() complete.Flags {
+	return complete.Flags{
+		"-versions": complete.PredictAnything,
+	}
+}
@@ -0,0 +1,49 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+func TestKVDelete(t *testing.T) {
+	ln, addr, client := testKV(t)
+	defer ln.Close()
+
+	for i := 0; i < 3; i++ {
+		if _, err := client.Logical().Write("kv/data/foo", map[string]interface{}{
+			"data": map[string]interface{}{"value": "bar"},
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ui := new(cli.MockUi)
+	c := &KVDeleteCommand{Meta: testKVMeta(client, ui)}
+	for _, args := range [][]string{
+		{"-address", addr, "-versions=1,2", "kv/foo"},
+		{"-address", addr, "kv/foo"},
+	} {
+		if code := c.Run(args); code != 0 {
+			t.Fatalf("%v: bad: %d\n\n%s", args, code, ui.ErrorWriter.String())
+		}
+	}
+
+	secret, err := client.Logical().Read("kv/metadata/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	versions := secret.Data["versions"].(map[string]interface{})
+	for _, v := range []string{"1", "2", "3"} {
+		if versions[v].(map[string]interface{})["deletion_time"] == "" {
+			t.Fatalf("expected version %s to be deleted: %#v", v, versions)
+		}
+	}
+
+	if code := c.Run([]string{"-address", addr, "-versions=1", "secret/foo"}); code != 1 {
+		t.Fatalf("expected -versions to fail on an unversioned mount, got %d", code)
+	}
+	if code := c.Run([]string{"-address", addr, "-versions=foo", "kv/foo"}); code != 1 {
+		t.Fatalf("expected an invalid version to fail, got %d", code)
+	}
+}
@@ -0,0 +1,132 @@
+package command
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/vault/meta"
+	"github.com/posener/complete"
+)
+
+// KVGetCommand is a Command that reads a key from a KV mount.
+type KVGetCommand struct {
+	meta.Meta
+}
+
+func (c *KVGetCommand) Run(args []string) int {
+	var version int
+	flags := c.Meta.FlagSet("kv get", meta.FlagSetDefault)
+	flags.IntVar(&version, "version", 0, "")
+	flags.Usage = func() { c.Ui.Error(c.Help()) }
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	args = flags.Args()
+	if len(args) != 1 {
+		c.Ui.Error("kv get expects one argument")
+		flags.Usage()
+		return 1
+	}
+
+	client, err := c.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf(
+			"Error initializing client: %s", err))
+		return 2
+	}
+
+	mount, key, err := kvPreflight(client, args[0])
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error reading %s: %s", args[0], err))
+		return 1
+	}
+
+	if !mount.Versioned {
+		if version != 0 {
+			c.Ui.Error(fmt.Sprintf("%s is not a versioned KV mount, so -version is not supported", mount.Path))
+			return 1
+		}
+
+		path := mount.keyPath(key)
+		secret, err := client.Logical().Read(path)
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Error reading %s: %s", path, err))
+			return 1
+		}
+		if secret == nil {
+			c.Ui.Error(fmt.Sprintf("No value found at %s", path))
+			return 1
+		}
+
+		if c.Field() != "" {
+			return PrintRawField(c.Ui, secret, c.Field())
+		}
+		return OutputSecret(c.Ui, c.Format(), secret)
+	}
+
+	path := mount.versionedPath("data", key)
+	var params map[string][]string
+	if version > 0 {
+		params = map[string][]string{
+			"version": {strconv.Itoa(version)},
+		}
+	}
+	secret, err := client.Logical().ReadWithData(path, params)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error reading %s: %s", path, err))
+		return 1
+	}
+	if secret == nil {
+		c.Ui.Error(fmt.Sprintf("No value found at %s", path))
+		return 1
+	}
+
+	return outputKVVersioned(c.Ui, c.Format(), c.Field(), secret)
+}
+
+func (c *KVGetCommand) Synopsis() string {
+	return "Read a key from a KV mount"
+}
+
+func (c *KVGetCommand) Help() string {
+	helpText := `
+Usage: vault kv get [options] path
+
+  Read a key from a KV mount.
+
+  The path is the path of the key including the path of the mount, such as
+  "secret/foo". On a versioned KV mount, the current version of the key is
+  read from its data/ path, and is output along with the metadata of the
+  version:
+
+      $ vault kv get secret/foo
+
+  This command requires the read capability on sys/mounts to find the mount
+  holding the key.
+
+General Options:
+` + meta.GeneralOptionsUsage() + `
+KV Get Options:
+
+  -version=<int>          Version of the key to read on a versioned KV mount.
+                          Defaults to the current version.
+
+  -field=<name>           Print only the value of the given field of the data
+                          of the key.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *KVGetCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictNothing
+}
+
+func (c *KVGetCommand) AutocompleteFlags() complete.Flags {
+	return complete.Flags{
+		"-version": complete.PredictAnything,
+		"-format":  predictFormat,
+		"-field":   complete.PredictNothing,
+	}
+}
@@ -0,0 +1,82 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+func TestKVGet(t *testing.T) {
+	ln, addr, client := testKV(t)
+	defer ln.Close()
+
+	for _, value := range []string{"bar", "baz"} {
+		if _, err := client.Logical().Write("kv/data/foo", map[string]interface{}{
+			"data": map[string]interface{}{"value": value},
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"-field=value", "kv/foo"}, "baz\n"},
+		{[]string{"-field=value", "-version=1", "kv/foo"}, "bar\n"},
+	}
+	for _, tc := range cases {
+		ui := new(cli.MockUi)
+		c := &KVGetCommand{Meta: testKVMeta(client, ui)}
+		if code := c.Run(append([]string{"-address", addr}, tc.args...)); code != 0 {
+			t.Fatalf("%v: bad: %d\n\n%s", tc.args, code, ui.ErrorWriter.String())
+		}
+		if output := ui.OutputWriter.String(); output != tc.expected {
+			t.Fatalf("%v: bad: %q", tc.args, output)
+		}
+	}
+
+	// The table holds the metadata of the version and the data
+	ui := new(cli.MockUi)
+	c := &KVGetCommand{Meta: testKVMeta(client, ui)}
+	if code := c.Run([]string{"-address", addr, "kv/foo"}); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	output := ui.OutputWriter.String()
+	for _, s := range []string{"Metadata", "version", "Data", "baz"} {
+		if !strings.Contains(output, s) {
+			t.Fatalf("expected %q in the output: %s", s, output)
+		}
+	}
+
+	ui = new(cli.MockUi)
+	c = &KVGetCommand{Meta: testKVMeta(client, ui)}
+	if code := c.Run([]string{"-address", addr, "kv/missing"}); code != 1 {
+		t.Fatalf("bad: %d", code)
+	}
+}
+
+func TestKVGet_unversioned(t *testing.T) {
+	ln, addr, client := testKV(t)
+	defer ln.Close()
+
+	if _, err := client.Logical().Write("secret/foo", map[string]interface{}{
+		"value": "bar",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	ui := new(cli.MockUi)
+	c := &KVGetCommand{Meta: testKVMeta(client, ui)}
+	if code := c.Run([]string{"-address", addr, "-field=value", "secret/foo"}); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	if output := ui.OutputWriter.String(); output != "bar\n" {
+		t.Fatalf("bad: %q", output)
+	}
+
+	if code := c.Run([]string{"-address", addr, "-version=1", "secret/foo"}); code != 1 {
+		t.Fatalf("expected -version to fail on an unversioned mount, got %d", code)
+	}
+}
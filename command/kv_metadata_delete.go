@@ -0,0 +1,84 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/vault/meta"
+	"github.com/posener/complete"
+)
+
+// KVMetadataDeleteCommand is a Command that removes a key of a versioned KV
+// mount along with all of its versions.
+type KVMetadataDeleteCommand struct {
+	meta.Meta
+}
+
+func (c *KVMetadataDeleteCommand) Run(args []string) int {
+	flags := c.Meta.FlagSet("kv metadata delete", meta.FlagSetDefault)
+	flags.Usage = func() { c.Ui.Error(c.Help()) }
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	args = flags.Args()
+	if len(args) != 1 {
+		c.Ui.Error("kv metadata delete expects one argument")
+		flags.Usage()
+		return 1
+	}
+
+	client, err := c.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf(
+			"Error initializing client: %s", err))
+		return 2
+	}
+
+	mount, key, err := kvVersionedPreflight(client, args[0])
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error deleting %s: %s", args[0], err))
+		return 1
+	}
+
+	path := mount.versionedPath("metadata", key)
+	if _, err := client.Logical().Delete(path); err != nil {
+		c.Ui.Error(fmt.Sprintf(
+			"Error deleting %s: %s", path, err))
+		return 1
+	}
+
+	if !c.StructuredOutput() {
+		c.Ui.Output(fmt.Sprintf("Success! Deleted %s and all of its versions if it existed.", path))
+	}
+	return 0
+}
+
+func (c *KVMetadataDeleteCommand) Synopsis() string {
+	return "Remove a key of a versioned KV mount with all of its versions"
+}
+
+func (c *KVMetadataDeleteCommand) Help() string {
+	helpText := `
+Usage: vault kv metadata delete [options] path
+
+  Remove a key of a versioned KV mount through its metadata/ path, destroying
+  all of its versions and its metadata. This cannot be undone.
+
+      $ vault kv metadata delete secret/foo
+
+  This command requires the read capability on sys/mounts to find the mount
+  holding the key.
+
+General Options:
+` + meta.GeneralOptionsUsage()
+	return strings.TrimSpace(helpText)
+}
+
+func (c *KVMetadataDeleteCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictNothing
+}
+
+func (c *KVMetadataDeleteCommand) AutocompleteFlags() complete.Flags {
+	return complete.Flags{}
+}
@@ -0,0 +1,121 @@
+package command
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/meta"
+	"github.com/posener/complete"
+)
+
+// KVMetadataGetCommand is a Command that reads the metadata of a key of a
+// versioned KV mount.
+type KVMetadataGetCommand struct {
+	meta.Meta
+}
+
+func (c *KVMetadataGetCommand) Run(args []string) int {
+	flags := c.Meta.FlagSet("kv metadata get", meta.FlagSetDefault)
+	flags.Usage = func() { c.Ui.Error(c.Help()) }
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	args = flags.Args()
+	if len(args) != 1 {
+		c.Ui.Error("kv metadata get expects one argument")
+		flags.Usage()
+		return 1
+	}
+
+	client, err := c.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf(
+			"Error initializing client: %s", err))
+		return 2
+	}
+
+	mount, key, err := kvVersionedPreflight(client, args[0])
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error reading the metadata of %s: %s", args[0], err))
+		return 1
+	}
+
+	path := mount.versionedPath("metadata", key)
+	secret, err := client.Logical().Read(path)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error reading %s: %s", path, err))
+		return 1
+	}
+	if secret == nil {
+		c.Ui.Error(fmt.Sprintf("No value found at %s", path))
+		return 1
+	}
+
+	if c.Field() != "" {
+		return PrintRawField(c.Ui, secret, c.Field())
+	}
+	if c.StructuredOutput() {
+		return OutputSecret(c.Ui, c.Format(), secret)
+	}
+
+	metadata := make(map[string]interface{}, len(secret.Data))
+	for k, v := range secret.Data {
+		if k != "versions" {
+			metadata[k] = v
+		}
+	}
+	c.Ui.Output("======= Metadata =======")
+	OutputSecret(c.Ui, c.Format(), &api.Secret{Data: metadata})
+
+	// The versions are listed from the most recent
+	versions, _ := secret.Data["versions"].(map[string]interface{})
+	numbers := make([]int, 0, len(versions))
+	for v := range versions {
+		if n, err := strconv.Atoi(v); err == nil {
+			numbers = append(numbers, n)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(numbers)))
+	for _, n := range numbers {
+		vm, _ := versions[strconv.Itoa(n)].(map[string]interface{})
+		c.Ui.Output(fmt.Sprintf("\n====== Version %d ======", n))
+		OutputSecret(c.Ui, c.Format(), &api.Secret{Data: vm})
+	}
+	return 0
+}
+
+func (c *KVMetadataGetCommand) Synopsis() string {
+	return "Read the metadata of a key of a versioned KV mount"
+}
+
+func (c *KVMetadataGetCommand) Help() string {
+	helpText := `
+Usage: vault kv metadata get [options] path
+
+  Read the metadata of a key of a versioned KV mount from its metadata/ path:
+  its settings and the creation and deletion times of each of its versions.
+
+      $ vault kv metadata get secret/foo
+
+  This command requires the read capability on sys/mounts to find the mount
+  holding the key.
+
+General Options:
+` + meta.GeneralOptionsUsage()
+	return strings.TrimSpace(helpText)
+}
+
+func (c *KVMetadataGetCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictNothing
+}
+
+func (c *KVMetadataGetCommand) AutocompleteFlags() complete.Flags {
+	return complete.Flags{
+		"-format": predictFormat,
+		"-field":  complete.PredictNothing,
+	}
+}
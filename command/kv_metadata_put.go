@@ -0,0 +1,122 @@
+package command
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/vault/meta"
+	"github.com/posener/complete"
+)
+
+// KVMetadataPutCommand is a Command that updates the settings of a key of a
+// versioned KV mount.
+type KVMetadataPutCommand struct {
+	meta.Meta
+}
+
+func (c *KVMetadataPutCommand) Run(args []string) int {
+	var maxVersions int
+	var casRequired bool
+	var deleteAfter string
+	flags := c.Meta.FlagSet("kv metadata put", meta.FlagSetDefault)
+	flags.IntVar(&maxVersions, "max-versions", 0, "")
+	flags.BoolVar(&casRequired, "cas-required", false, "")
+	flags.StringVar(&deleteAfter, "delete-after", "", "")
+	flags.Usage = func() { c.Ui.Error(c.Help()) }
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	args = flags.Args()
+	if len(args) != 1 {
+		c.Ui.Error("kv metadata put expects one argument")
+		flags.Usage()
+		return 1
+	}
+
+	// Only the settings given as flags are updated
+	data := make(map[string]interface{})
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "max-versions":
+			data["max_versions"] = maxVersions
+		case "cas-required":
+			data["cas_required"] = casRequired
+		case "delete-after":
+			data["delete_after"] = deleteAfter
+		}
+	})
+
+	client, err := c.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf(
+			"Error initializing client: %s", err))
+		return 2
+	}
+
+	mount, key, err := kvVersionedPreflight(client, args[0])
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error writing the metadata of %s: %s", args[0], err))
+		return 1
+	}
+
+	path := mount.versionedPath("metadata", key)
+	if _, err := client.Logical().Write(path, data); err != nil {
+		c.Ui.Error(fmt.Sprintf(
+			"Error writing data to %s: %s", path, err))
+		return 1
+	}
+
+	if !c.StructuredOutput() {
+		c.Ui.Output(fmt.Sprintf("Success! Data written to: %s", path))
+	}
+	return 0
+}
+
+func (c *KVMetadataPutCommand) Synopsis() string {
+	return "Update the settings of a key of a versioned KV mount"
+}
+
+func (c *KVMetadataPutCommand) Help() string {
+	helpText := `
+Usage: vault kv metadata put [options] path
+
+  Update the settings of a key of a versioned KV mount through its metadata/
+  path. Only the settings given as flags are changed, and the key doesn't need
+  to exist yet:
+
+      $ vault kv metadata put -max-versions=5 -cas-required secret/foo
+
+  This command requires the read capability on sys/mounts to find the mount
+  holding the key.
+
+General Options:
+` + meta.GeneralOptionsUsage() + `
+KV Metadata Put Options:
+
+  -max-versions=<int>     Number of versions of the key to keep. A value of 0
+                          uses the setting of the mount.
+
+  -cas-required           Require the check-and-set option on the writes to the
+                          key, such as with "vault kv put -cas".
+
+  -delete-after=<duration>
+                          Duration after the latest write at which the key and
+                          all of its versions are removed, such as "72h". A
+                          value of 0 keeps the key forever.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *KVMetadataPutCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictNothing
+}
+
+func (c *KVMetadataPutCommand) AutocompleteFlags() complete.Flags {
+	return complete.Flags{
+		"-max-versions": complete.PredictAnything,
+		"-cas-required": complete.PredictNothing,
+		"-delete-after": complete.PredictAnything,
+	}
+}
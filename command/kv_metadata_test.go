@@ -0,0 +1,61 @@
+package command
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+func TestKVMetadata(t *testing.T) {
+	ln, addr, client := testKV(t)
+	defer ln.Close()
+
+	ui := new(cli.MockUi)
+	put := &KVMetadataPutCommand{Meta: testKVMeta(client, ui)}
+	args := []string{"-address", addr, "-max-versions=2", "-cas-required", "-delete-after=1h", "kv/foo"}
+	if code := put.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	// Only the given settings are changed
+	args = []string{"-address", addr, "-max-versions=3", "kv/foo"}
+	if code := put.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	ui = new(cli.MockUi)
+	get := &KVMetadataGetCommand{Meta: testKVMeta(client, ui)}
+	if code := get.Run([]string{"-address", addr, "-format=json", "kv/foo"}); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	var secret struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(ui.OutputWriter.Bytes(), &secret); err != nil {
+		t.Fatal(err)
+	}
+	if secret.Data["max_versions"] != float64(3) || secret.Data["cas_required"] != true || secret.Data["delete_after"] != float64(3600) {
+		t.Fatalf("bad: %#v", secret.Data)
+	}
+
+	ui = new(cli.MockUi)
+	del := &KVMetadataDeleteCommand{Meta: testKVMeta(client, ui)}
+	if code := del.Run([]string{"-address", addr, "kv/foo"}); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	if code := get.Run([]string{"-address", addr, "kv/foo"}); code != 1 {
+		t.Fatalf("expected the metadata to be deleted, got %d", code)
+	}
+
+	// The metadata commands are only supported by versioned KV mounts
+	ui = new(cli.MockUi)
+	get = &KVMetadataGetCommand{Meta: testKVMeta(client, ui)}
+	if code := get.Run([]string{"-address", addr, "secret/foo"}); code != 1 {
+		t.Fatalf("bad: %d", code)
+	}
+	if !strings.Contains(ui.ErrorWriter.String(), "not a versioned KV mount") {
+		t.Fatalf("bad: %s", ui.ErrorWriter.String())
+	}
+}
@@ -0,0 +1,137 @@
+package command
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/hashicorp/vault/meta"
+	"github.com/posener/complete"
+)
+
+// KVPatchCommand is a Command that updates some of the fields of a key of a
+// KV mount.
+type KVPatchCommand struct {
+	meta.Meta
+
+	// The fields below can be overwritten for tests
+	testStdin io.Reader
+}
+
+func (c *KVPatchCommand) Run(args []string) int {
+	var cas int
+	flags := c.Meta.FlagSet("kv patch", meta.FlagSetDefault)
+	flags.IntVar(&cas, "cas", -1, "")
+	flags.Usage = func() { c.Ui.Error(c.Help()) }
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	args = flags.Args()
+	if len(args) < 2 {
+		c.Ui.Error("kv patch expects a path and at least one key=value pair")
+		flags.Usage()
+		return 1
+	}
+
+	data, err := parseKVData(c.testStdin, args[1:])
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf(
+			"Error loading data: %s", err))
+		return 1
+	}
+
+	client, err := c.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf(
+			"Error initializing client: %s", err))
+		return 2
+	}
+
+	mount, key, err := kvPreflight(client, args[0])
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error patching %s: %s", args[0], err))
+		return 1
+	}
+
+	path := mount.keyPath(key)
+	if mount.Versioned {
+		path = mount.versionedPath("data", key)
+		body := map[string]interface{}{
+			"data": data,
+		}
+		if cas >= 0 {
+			body["options"] = map[string]interface{}{
+				"cas": cas,
+			}
+		}
+		data = body
+	} else if cas >= 0 {
+		c.Ui.Error(fmt.Sprintf("%s is not a versioned KV mount, so -cas is not supported", mount.Path))
+		return 1
+	}
+
+	secret, err := client.Logical().JSONMergePatch(path, data)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf(
+			"Error patching %s: %s", path, err))
+		return 1
+	}
+
+	if secret == nil {
+		if !c.StructuredOutput() {
+			c.Ui.Output(fmt.Sprintf("Success! Data patched at: %s", path))
+		}
+		return 0
+	}
+
+	if c.Field() != "" {
+		return PrintRawField(c.Ui, secret, c.Field())
+	}
+
+	return OutputSecret(c.Ui, c.Format(), secret)
+}
+
+func (c *KVPatchCommand) Synopsis() string {
+	return "Update some of the fields of a key of a KV mount"
+}
+
+func (c *KVPatchCommand) Help() string {
+	helpText := `
+Usage: vault kv patch [options] path key=value [key=value...]
+
+  Update some of the fields of a key of a KV mount, keeping the others.
+
+  The data is applied to the key as a JSON merge patch: the given fields are
+  set, the fields set to null in JSON data are removed, and the other fields
+  are left unchanged. On a versioned KV mount, the patched data is written as
+  a new version of the key:
+
+      $ vault kv patch secret/foo bar=qux
+
+  The key must exist. Data is given as with "vault kv put".
+
+  This command requires the read capability on sys/mounts to find the mount
+  holding the key.
+
+General Options:
+` + meta.GeneralOptionsUsage() + `
+KV Patch Options:
+
+  -cas=<int>              Patch only if the current version of the key on a
+                          versioned KV mount is the given version.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *KVPatchCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictNothing
+}
+
+func (c *KVPatchCommand) AutocompleteFlags() complete.Flags {
+	return complete.Flags{
+		"-cas":    complete.PredictAnything,
+		"-format": predictFormat,
+		"-field":  complete.PredictNothing,
+	}
+}
@@ -0,0 +1,56 @@
+package command
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+func TestKVPatch(t *testing.T) {
+	ln, addr, client := testKV(t)
+	defer ln.Close()
+
+	if _, err := client.Logical().Write("kv/data/foo", map[string]interface{}{
+		"data": map[string]interface{}{"a": "1", "b": "2"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Logical().Write("secret/foo", map[string]interface{}{
+		"a": "1", "b": "2",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	ui := new(cli.MockUi)
+	c := &KVPatchCommand{Meta: testKVMeta(client, ui)}
+	for _, args := range [][]string{
+		{"-address", addr, "-cas=1", "kv/foo", "b=3"},
+		{"-address", addr, "secret/foo", "b=3"},
+	} {
+		if code := c.Run(args); code != 0 {
+			t.Fatalf("%v: bad: %d\n\n%s", args, code, ui.ErrorWriter.String())
+		}
+	}
+
+	expected := map[string]interface{}{"a": "1", "b": "3"}
+	secret, err := client.Logical().Read("kv/data/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(secret.Data["data"], expected) {
+		t.Fatalf("bad: %#v", secret.Data)
+	}
+	secret, err = client.Logical().Read("secret/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(secret.Data, expected) {
+		t.Fatalf("bad: %#v", secret.Data)
+	}
+
+	// The check-and-set version is now stale
+	if code := c.Run([]string{"-address", addr, "-cas=1", "kv/foo", "b=4"}); code != 1 {
+		t.Fatalf("bad: %d", code)
+	}
+}
@@ -0,0 +1,137 @@
+package command
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/hashicorp/vault/meta"
+	"github.com/posener/complete"
+)
+
+// KVPutCommand is a Command that writes a key to a KV mount.
+type KVPutCommand struct {
+	meta.Meta
+
+	// The fields below can be overwritten for tests
+	testStdin io.Reader
+}
+
+func (c *KVPutCommand) Run(args []string) int {
+	var cas int
+	flags := c.Meta.FlagSet("kv put", meta.FlagSetDefault)
+	flags.IntVar(&cas, "cas", -1, "")
+	flags.Usage = func() { c.Ui.Error(c.Help()) }
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	args = flags.Args()
+	if len(args) < 2 {
+		c.Ui.Error("kv put expects a path and at least one key=value pair")
+		flags.Usage()
+		return 1
+	}
+
+	data, err := parseKVData(c.testStdin, args[1:])
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf(
+			"Error loading data: %s", err))
+		return 1
+	}
+
+	client, err := c.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf(
+			"Error initializing client: %s", err))
+		return 2
+	}
+
+	mount, key, err := kvPreflight(client, args[0])
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error writing data to %s: %s", args[0], err))
+		return 1
+	}
+
+	path := mount.keyPath(key)
+	if mount.Versioned {
+		path = mount.versionedPath("data", key)
+		body := map[string]interface{}{
+			"data": data,
+		}
+		if cas >= 0 {
+			body["options"] = map[string]interface{}{
+				"cas": cas,
+			}
+		}
+		data = body
+	} else if cas >= 0 {
+		c.Ui.Error(fmt.Sprintf("%s is not a versioned KV mount, so -cas is not supported", mount.Path))
+		return 1
+	}
+
+	secret, err := client.Logical().Write(path, data)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf(
+			"Error writing data to %s: %s", path, err))
+		return 1
+	}
+
+	if secret == nil {
+		if !c.StructuredOutput() {
+			c.Ui.Output(fmt.Sprintf("Success! Data written to: %s", path))
+		}
+		return 0
+	}
+
+	if c.Field() != "" {
+		return PrintRawField(c.Ui, secret, c.Field())
+	}
+
+	return OutputSecret(c.Ui, c.Format(), secret)
+}
+
+func (c *KVPutCommand) Synopsis() string {
+	return "Write a key to a KV mount"
+}
+
+func (c *KVPutCommand) Help() string {
+	helpText := `
+Usage: vault kv put [options] path key=value [key=value...]
+
+  Write a key to a KV mount, replacing its data.
+
+  The path is the path of the key including the path of the mount, such as
+  "secret/foo". On a versioned KV mount, the data is written as a new version
+  of the key to its data/ path, and the metadata of the new version is output:
+
+      $ vault kv put secret/foo bar=baz
+
+  Data is sent as "key=value" pairs. If a value begins with an "@", it is
+  loaded from a file, and a "-" reads the data from stdin as JSON.
+
+  This command requires the read capability on sys/mounts to find the mount
+  holding the key.
+
+General Options:
+` + meta.GeneralOptionsUsage() + `
+KV Put Options:
+
+  -cas=<int>              Write only if the current version of the key on a
+                          versioned KV mount is the given version. A value of
+                          0 writes only if the key doesn't exist.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *KVPutCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictNothing
+}
+
+func (c *KVPutCommand) AutocompleteFlags() complete.Flags {
+	return complete.Flags{
+		"-cas":    complete.PredictAnything,
+		"-format": predictFormat,
+		"-field":  complete.PredictNothing,
+	}
+}
@@ -0,0 +1,59 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+func TestKVPut(t *testing.T) {
+	ln, addr, client := testKV(t)
+	defer ln.Close()
+
+	ui := new(cli.MockUi)
+	c := &KVPutCommand{Meta: testKVMeta(client, ui)}
+	for _, path := range []string{"secret/foo", "kv/foo"} {
+		args := []string{"-address", addr, path, "value=bar"}
+		if code := c.Run(args); code != 0 {
+			t.Fatalf("%s: bad: %d\n\n%s", path, code, ui.ErrorWriter.String())
+		}
+	}
+
+	secret, err := client.Logical().Read("secret/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret == nil || secret.Data["value"] != "bar" {
+		t.Fatalf("bad: %#v", secret)
+	}
+
+	secret, err = client.Logical().Read("kv/data/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret == nil || secret.Data["data"].(map[string]interface{})["value"] != "bar" {
+		t.Fatalf("bad: %#v", secret)
+	}
+}
+
+func TestKVPut_cas(t *testing.T) {
+	ln, addr, client := testKV(t)
+	defer ln.Close()
+
+	ui := new(cli.MockUi)
+	c := &KVPutCommand{Meta: testKVMeta(client, ui)}
+
+	// The version of the first write is 1, so a second write expecting no
+	// version fails
+	for i, expected := range []int{0, 1} {
+		args := []string{"-address", addr, "-cas=0", "kv/foo", "value=bar"}
+		if code := c.Run(args); code != expected {
+			t.Fatalf("write %d: expected %d, got %d\n\n%s", i, expected, code, ui.ErrorWriter.String())
+		}
+	}
+
+	args := []string{"-address", addr, "-cas=0", "secret/foo", "value=bar"}
+	if code := c.Run(args); code != 1 {
+		t.Fatalf("expected -cas to fail on an unversioned mount, got %d", code)
+	}
+}
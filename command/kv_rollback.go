@@ -0,0 +1,149 @@
+package command
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/vault/meta"
+	"github.com/posener/complete"
+)
+
+// KVRollbackCommand is a Command that restores the data of a previous
+// version of a key of a versioned KV mount.
+type KVRollbackCommand struct {
+	meta.Meta
+}
+
+func (c *KVRollbackCommand) Run(args []string) int {
+	var version int
+	flags := c.Meta.FlagSet("kv rollback", meta.FlagSetDefault)
+	flags.IntVar(&version, "version", 0, "")
+	flags.Usage = func() { c.Ui.Error(c.Help()) }
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	args = flags.Args()
+	if len(args) != 1 {
+		c.Ui.Error("kv rollback expects one argument")
+		flags.Usage()
+		return 1
+	}
+	if version <= 0 {
+		c.Ui.Error("The -version flag is required")
+		flags.Usage()
+		return 1
+	}
+
+	client, err := c.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf(
+			"Error initializing client: %s", err))
+		return 2
+	}
+
+	mount, key, err := kvVersionedPreflight(client, args[0])
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error rolling back %s: %s", args[0], err))
+		return 1
+	}
+
+	metadataPath := mount.versionedPath("metadata", key)
+	metadata, err := client.Logical().Read(metadataPath)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error reading %s: %s", metadataPath, err))
+		return 1
+	}
+	if metadata == nil {
+		c.Ui.Error(fmt.Sprintf("No value found at %s", metadataPath))
+		return 1
+	}
+	currentVersion, err := strconv.ParseUint(fmt.Sprintf("%v", metadata.Data["current_version"]), 10, 64)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Invalid current version of %s: %v", metadataPath, metadata.Data["current_version"]))
+		return 1
+	}
+
+	path := mount.versionedPath("data", key)
+	secret, err := client.Logical().ReadWithData(path, map[string][]string{
+		"version": {strconv.Itoa(version)},
+	})
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error reading %s: %s", path, err))
+		return 1
+	}
+	if secret == nil {
+		c.Ui.Error(fmt.Sprintf("Version %d of %s is deleted, destroyed or doesn't exist", version, path))
+		return 1
+	}
+
+	// The data is written back with the current version read from the
+	// metadata as the check-and-set version, so that the rollback fails
+	// rather than overwriting a concurrent write
+	secret, err = client.Logical().Write(path, map[string]interface{}{
+		"data": secret.Data["data"],
+		"options": map[string]interface{}{
+			"cas": currentVersion,
+		},
+	})
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf(
+			"Error writing data to %s: %s", path, err))
+		return 1
+	}
+
+	if secret == nil {
+		if !c.StructuredOutput() {
+			c.Ui.Output(fmt.Sprintf("Success! Version %d written back to: %s", version, path))
+		}
+		return 0
+	}
+
+	if c.Field() != "" {
+		return PrintRawField(c.Ui, secret, c.Field())
+	}
+
+	return OutputSecret(c.Ui, c.Format(), secret)
+}
+
+func (c *KVRollbackCommand) Synopsis() string {
+	return "Restore a previous version of a key of a versioned KV mount"
+}
+
+func (c *KVRollbackCommand) Help() string {
+	helpText := `
+Usage: vault kv rollback [options] path
+
+  Restore a previous version of a key of a versioned KV mount, by writing its
+  data as a new version of the key. The previous versions are kept, so a
+  rollback can itself be rolled back:
+
+      $ vault kv rollback -version=2 secret/foo
+
+  The rollback fails if the key is written between the read of the version
+  and the write of its data.
+
+  This command requires the read capability on sys/mounts to find the mount
+  holding the key.
+
+General Options:
+` + meta.GeneralOptionsUsage() + `
+KV Rollback Options:
+
+  -version=<int>          Version of the key to restore. Required.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *KVRollbackCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictNothing
+}
+
+func (c *KVRollbackCommand) AutocompleteFlags() complete.Flags {
+	return complete.Flags{
+		"-version": complete.PredictAnything,
+		"-format":  predictFormat,
+		"-field":   complete.PredictNothing,
+	}
+}
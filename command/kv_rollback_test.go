@@ -0,0 +1,46 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+func TestKVRollback(t *testing.T) {
+	ln, addr, client := testKV(t)
+	defer ln.Close()
+
+	for _, value := range []string{"bar", "baz"} {
+		if _, err := client.Logical().Write("kv/data/foo", map[string]interface{}{
+			"data": map[string]interface{}{"value": value},
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ui := new(cli.MockUi)
+	c := &KVRollbackCommand{Meta: testKVMeta(client, ui)}
+	if code := c.Run([]string{"-address", addr, "-version=1", "kv/foo"}); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	secret, err := client.Logical().Read("kv/data/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := secret.Data["data"].(map[string]interface{})
+	metadata := secret.Data["metadata"].(map[string]interface{})
+	if data["value"] != "bar" || metadata["version"].(interface{ String() string }).String() != "3" {
+		t.Fatalf("bad: %#v", secret.Data)
+	}
+
+	for _, args := range [][]string{
+		{"-address", addr, "kv/foo"},
+		{"-address", addr, "-version=5", "kv/foo"},
+		{"-address", addr, "-version=1", "secret/foo"},
+	} {
+		if code := c.Run(args); code != 1 {
+			t.Fatalf("%v: expected an error, got %d", args, code)
+		}
+	}
+}
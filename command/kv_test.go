@@ -0,0 +1,74 @@
+package command
+
+import (
+	"net"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/http"
+	"github.com/hashicorp/vault/meta"
+	"github.com/hashicorp/vault/vault"
+	"github.com/mitchellh/cli"
+)
+
+// testKV starts a server with a versioned KV mount at kv/, next to the
+// unversioned one at secret/
+func testKV(t *testing.T) (net.Listener, string, *api.Client) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := http.TestServer(t, core)
+
+	client := testClient(t, addr, token)
+	if err := client.Sys().Mount("kv", &api.MountInput{
+		Type:    "kv",
+		Options: map[string]string{"version": "2"},
+	}); err != nil {
+		ln.Close()
+		t.Fatal(err)
+	}
+
+	return ln, addr, client
+}
+
+func testKVMeta(client *api.Client, ui cli.Ui) meta.Meta {
+	return meta.Meta{
+		ClientToken: client.Token(),
+		Ui:          ui,
+	}
+}
+
+func TestKVPreflight(t *testing.T) {
+	ln, _, client := testKV(t)
+	defer ln.Close()
+
+	if err := client.Sys().Mount("kv-old", &api.MountInput{
+		Type: "kv",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		path      string
+		mountPath string
+		key       string
+		versioned bool
+	}{
+		{"secret/foo", "secret/", "foo", false},
+		{"/kv/foo/bar", "kv/", "foo/bar", true},
+		{"kv-old/foo/", "kv-old/", "foo", false},
+	}
+	for _, tc := range cases {
+		mount, key, err := kvPreflight(client, tc.path)
+		if err != nil {
+			t.Fatalf("%s: %s", tc.path, err)
+		}
+		if mount.Path != tc.mountPath || key != tc.key || mount.Versioned != tc.versioned {
+			t.Fatalf("%s: bad: %#v %q", tc.path, mount, key)
+		}
+	}
+
+	for _, path := range []string{"", "sys/foo", "missing/foo"} {
+		if _, _, err := kvPreflight(client, path); err == nil {
+			t.Fatalf("%s: expected an error", path)
+		}
+	}
+}
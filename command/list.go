@@ -15,12 +15,10 @@ type ListCommand struct {
 }
 
 func (c *ListCommand) Run(args []string) int {
-	var format string
 	var err error
 	var secret *api.Secret
 	var flags *flag.FlagSet
 	flags = c.Meta.FlagSet("list", meta.FlagSetDefault)
-	flags.StringVar(&format, "format", "table", "")
 	flags.Usage = func() { c.Ui.Error(c.Help()) }
 	if err := flags.Parse(args); err != nil {
 		return 1
@@ -61,7 +59,7 @@ func (c *ListCommand) Run(args []string) int {
 		return 1
 	}
 	if secret.WrapInfo != nil && secret.WrapInfo.TTL != 0 {
-		return OutputSecret(c.Ui, format, secret)
+		return OutputSecret(c.Ui, c.Format(), secret)
 	}
 
 	if secret.Data["keys"] == nil {
@@ -69,7 +67,7 @@ func (c *ListCommand) Run(args []string) int {
 		return 0
 	}
 
-	return OutputList(c.Ui, format, secret)
+	return OutputList(c.Ui, c.Format(), secret)
 }
 
 func (c *ListCommand) Synopsis() string {
@@ -87,11 +85,6 @@ Usage: vault list [options] path
   and endpoint-specific.
 
 General Options:
-` + meta.GeneralOptionsUsage() + `
-Read Options:
-
-  -format=table           The format for output. By default it is a whitespace-
-                          delimited table. This can also be json or yaml.
-`
+` + meta.GeneralOptionsUsage()
 	return strings.TrimSpace(helpText)
 }
@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/helper/flag-kv"
 	"github.com/hashicorp/vault/meta"
 	"github.com/posener/complete"
 )
@@ -16,7 +17,8 @@ type MountCommand struct {
 
 func (c *MountCommand) Run(args []string) int {
 	var description, path, defaultLeaseTTL, maxLeaseTTL, pluginName string
-	var local, forceNoCache bool
+	var local, sealWrap, forceNoCache bool
+	var options map[string]string
 	flags := c.Meta.FlagSet("mount", meta.FlagSetDefault)
 	flags.StringVar(&description, "description", "", "")
 	flags.StringVar(&path, "path", "", "")
@@ -25,6 +27,8 @@ func (c *MountCommand) Run(args []string) int {
 	flags.StringVar(&pluginName, "plugin-name", "", "")
 	flags.BoolVar(&forceNoCache, "force-no-cache", false, "")
 	flags.BoolVar(&local, "local", false, "")
+	flags.BoolVar(&sealWrap, "seal-wrap", false, "")
+	flags.Var((*kvFlag.Flag)(&options), "options", "")
 	flags.Usage = func() { c.Ui.Error(c.Help()) }
 	if err := flags.Parse(args); err != nil {
 		return 1
@@ -66,7 +70,9 @@ func (c *MountCommand) Run(args []string) int {
 			ForceNoCache:    forceNoCache,
 			PluginName:      pluginName,
 		},
-		Local: local,
+		Local:    local,
+		SealWrap: sealWrap,
+		Options:  options,
 	}
 
 	if err := client.Sys().Mount(path, mountInfo); err != nil {
@@ -80,9 +86,11 @@ func (c *MountCommand) Run(args []string) int {
 		mountTypeOutput = fmt.Sprintf("plugin '%s'", pluginName)
 	}
 
-	c.Ui.Output(fmt.Sprintf(
-		"Successfully mounted %s at '%s'!",
-		mountTypeOutput, path))
+	if !c.StructuredOutput() {
+		c.Ui.Output(fmt.Sprintf(
+			"Successfully mounted %s at '%s'!",
+			mountTypeOutput, path))
+	}
 
 	return 0
 }
@@ -131,6 +139,14 @@ Mount Options:
   -local                         Mark the mount as a local mount. Local mounts
                                  are not replicated nor (if a secondary)
                                  removed by replication.
+
+  -seal-wrap                     Seal-wrap the storage entries of the backend,
+                                 encrypting them with the seal in addition to
+                                 the barrier.
+
+  -options=<key=value>           Backend-specific option for the mount, such
+                                 as "version=2" for the kv backend. This can
+                                 be specified multiple times.
 `
 	return strings.TrimSpace(helpText)
 }
@@ -146,6 +162,8 @@ func (c *MountCommand) AutocompleteArgs() complete.Predictor {
 		"rabbitmq",
 		"database",
 		"totp",
+		"kubernetes",
+		"tokenbroker",
 		"plugin",
 	)
 
@@ -160,5 +178,7 @@ func (c *MountCommand) AutocompleteFlags() complete.Flags {
 		"-force-no-cache":    complete.PredictNothing,
 		"-plugin-name":       complete.PredictNothing,
 		"-local":             complete.PredictNothing,
+		"-seal-wrap":         complete.PredictNothing,
+		"-options":           complete.PredictNothing,
 	}
 }
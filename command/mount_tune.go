@@ -52,8 +52,10 @@ func (c *MountTuneCommand) Run(args []string) int {
 		return 2
 	}
 
-	c.Ui.Output(fmt.Sprintf(
-		"Successfully tuned mount '%s'!", path))
+	if !c.StructuredOutput() {
+		c.Ui.Output(fmt.Sprintf(
+			"Successfully tuned mount '%s'!", path))
+	}
 
 	return 0
 }
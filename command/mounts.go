@@ -36,6 +36,10 @@ func (c *MountsCommand) Run(args []string) int {
 		return 2
 	}
 
+	if c.StructuredOutput() {
+		return OutputData(c.Ui, c.Format(), c.Field(), mounts)
+	}
+
 	paths := make([]string, 0, len(mounts))
 	for path := range mounts {
 		paths = append(paths, path)
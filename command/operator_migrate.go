@@ -0,0 +1,211 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/command/server"
+	"github.com/hashicorp/vault/helper/logformat"
+	"github.com/hashicorp/vault/meta"
+	"github.com/hashicorp/vault/physical"
+	physRaft "github.com/hashicorp/vault/physical/raft"
+	log "github.com/mgutz/logxi/v1"
+)
+
+// maxMismatchedKeysOutput is the number of differing keys listed by a
+// failed verification
+const maxMismatchedKeysOutput = 20
+
+// OperatorMigrateCommand is a Command that copies the storage of Vault from
+// a physical backend to another.
+type OperatorMigrateCommand struct {
+	meta.Meta
+
+	PhysicalBackends map[string]physical.Factory
+
+	ShutdownCh chan struct{}
+}
+
+func (c *OperatorMigrateCommand) Run(args []string) int {
+	var configPath, logLevel string
+	var rate int
+	var reset, verifyOnly bool
+	flags := c.Meta.FlagSet("operator migrate", meta.FlagSetNone)
+	flags.StringVar(&configPath, "config", "", "")
+	flags.StringVar(&logLevel, "log-level", "info", "")
+	flags.IntVar(&rate, "rate", 0, "")
+	flags.BoolVar(&reset, "reset", false, "")
+	flags.BoolVar(&verifyOnly, "verify-only", false, "")
+	flags.Usage = func() { c.Ui.Error(c.Help()) }
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	if configPath == "" {
+		c.Ui.Error("The -config flag is required")
+		flags.Usage()
+		return 1
+	}
+	if rate < 0 {
+		c.Ui.Error("The -rate flag cannot be negative")
+		return 1
+	}
+
+	var level int
+	switch strings.ToLower(strings.TrimSpace(logLevel)) {
+	case "trace":
+		level = log.LevelTrace
+	case "debug":
+		level = log.LevelDebug
+	case "info":
+		level = log.LevelInfo
+	case "warn":
+		level = log.LevelWarn
+	case "err":
+		level = log.LevelError
+	default:
+		c.Ui.Error(fmt.Sprintf("Unknown log level %s", logLevel))
+		return 1
+	}
+	logger := logformat.NewVaultLoggerWithWriter(os.Stderr, level)
+
+	config, err := server.LoadMigrationConfig(configPath)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error loading configuration from %s: %s", configPath, err))
+		return 1
+	}
+
+	source, err := c.newBackend(config.Source, logger)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing source storage: %s", err))
+		return 1
+	}
+	destination, err := c.newBackend(config.Destination, logger)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing destination storage: %s", err))
+		return 1
+	}
+
+	// A new raft node is made the single server of its cluster, which the
+	// other nodes join once Vault runs on it
+	if raftBackend, ok := destination.(*physRaft.RaftBackend); ok {
+		defer raftBackend.Close()
+		if err := raftBackend.Bootstrap(); err != nil && err != physRaft.ErrAlreadyBootstrapped {
+			c.Ui.Error(fmt.Sprintf("Error bootstrapping the raft destination: %s", err))
+			return 1
+		}
+		if err := raftBackend.WaitForLeader(30 * time.Second); err != nil {
+			c.Ui.Error(fmt.Sprintf("Error waiting for the raft destination: %s", err))
+			return 1
+		}
+	}
+
+	migrator := physical.NewMigrator(source, destination, &physical.MigrationConfig{
+		Rate:   rate,
+		Reset:  reset,
+		Logger: logger,
+	})
+
+	if !verifyOnly {
+		c.Ui.Output(fmt.Sprintf("Migrating entries from %s storage to %s storage...",
+			config.Source.Type, config.Destination.Type))
+		copied, err := migrator.Migrate(c.ShutdownCh)
+		if err == physical.ErrMigrationStopped {
+			c.Ui.Error(fmt.Sprintf("Migration stopped after copying %d entries. Run the "+
+				"command again to resume it.", copied))
+			return 1
+		}
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Error migrating storage after copying %d entries: %s\n\n"+
+				"Run the command again to resume the migration.", copied, err))
+			return 1
+		}
+		c.Ui.Output(fmt.Sprintf("Copied %d entries", copied))
+	}
+
+	c.Ui.Output("Verifying the entries of the destination...")
+	mismatched, err := migrator.Verify(c.ShutdownCh)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error verifying storage: %s", err))
+		return 1
+	}
+	if len(mismatched) > 0 {
+		c.Ui.Error(fmt.Sprintf("%d entries of the destination are missing or differ "+
+			"from the source:", len(mismatched)))
+		for i, key := range mismatched {
+			if i == maxMismatchedKeysOutput {
+				c.Ui.Error(fmt.Sprintf("  ... and %d more", len(mismatched)-i))
+				break
+			}
+			c.Ui.Error("  " + key)
+		}
+		c.Ui.Error("\nRun the command again with -reset to copy all the entries again, " +
+			"after stopping the Vault servers writing to the source.")
+		return 2
+	}
+
+	c.Ui.Output("Success! The destination storage holds all the entries of the source.")
+	return 0
+}
+
+// newBackend constructs the physical backend of a storage configuration
+func (c *OperatorMigrateCommand) newBackend(storage *server.Storage, logger log.Logger) (physical.Backend, error) {
+	factory, ok := c.PhysicalBackends[storage.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown storage type %s", storage.Type)
+	}
+	return factory(storage.Config, logger)
+}
+
+func (c *OperatorMigrateCommand) Synopsis() string {
+	return "Migrate the Vault storage to another storage backend"
+}
+
+func (c *OperatorMigrateCommand) Help() string {
+	helpText := `
+Usage: vault operator migrate [options]
+
+  Copy all the entries of the Vault storage from a storage backend to another,
+  then verify that the destination holds the same entries as the source.
+
+  The storage backends are configured in a file holding a storage_source and
+  a storage_destination block, in the format of the storage block of the
+  server configuration:
+
+      storage_source "consul" {
+        address = "127.0.0.1:8500"
+        path    = "vault"
+      }
+
+      storage_destination "raft" {
+        path = "/var/lib/vault"
+      }
+
+  The entries are copied in lexicographic order of their keys, and the
+  progress is checkpointed in the destination, so that an interrupted
+  migration resumes where it left off when the command is run again.
+
+  The migration can run while Vault serves requests from the source, but the
+  entries written during the migration may not be copied. Vault should be
+  stopped before the final migration, whose verification then ensures the
+  destination is complete.
+
+Migrate Options:
+
+  -config=<path>          Path of the migration configuration file. Required.
+
+  -rate=<n>               Maximum number of entries copied, then verified,
+                          per second. Defaults to unlimited.
+
+  -reset                  Ignore the checkpoint of a previous migration and
+                          copy all the entries again.
+
+  -verify-only            Only verify the destination against the source,
+                          without copying any entry.
+
+  -log-level=<level>      Log verbosity. Defaults to "info".
+`
+	return strings.TrimSpace(helpText)
+}
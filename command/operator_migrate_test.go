@@ -0,0 +1,148 @@
+package command
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/helper/logformat"
+	"github.com/hashicorp/vault/meta"
+	"github.com/hashicorp/vault/physical"
+	physFile "github.com/hashicorp/vault/physical/file"
+	log "github.com/mgutz/logxi/v1"
+	"github.com/mitchellh/cli"
+)
+
+func testOperatorMigrate(t *testing.T) (physical.Backend, physical.Backend, string, func()) {
+	dir, err := ioutil.TempDir("", "vault-migrate")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sourcePath := filepath.Join(dir, "source")
+	destinationPath := filepath.Join(dir, "destination")
+	configPath := filepath.Join(dir, "migrate.hcl")
+	config := fmt.Sprintf(`
+storage_source "file" {
+  path = %q
+}
+
+storage_destination "file" {
+  path = %q
+}
+`, sourcePath, destinationPath)
+	if err := ioutil.WriteFile(configPath, []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+	source, err := physFile.NewFileBackend(map[string]string{"path": sourcePath}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	destination, err := physFile.NewFileBackend(map[string]string{"path": destinationPath}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{"a", "a0", "a/b", "a/c/d", "b", "core/keyring"} {
+		if err := source.Put(&physical.Entry{Key: key, Value: []byte("value of " + key)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return source, destination, configPath, func() { os.RemoveAll(dir) }
+}
+
+func testOperatorMigrateCommand(ui cli.Ui) *OperatorMigrateCommand {
+	return &OperatorMigrateCommand{
+		Meta: meta.Meta{
+			Ui: ui,
+		},
+		PhysicalBackends: map[string]physical.Factory{
+			"file": physFile.NewFileBackend,
+		},
+	}
+}
+
+func TestOperatorMigrate(t *testing.T) {
+	source, destination, configPath, cleanup := testOperatorMigrate(t)
+	defer cleanup()
+
+	ui := new(cli.MockUi)
+	c := testOperatorMigrateCommand(ui)
+	if code := c.Run([]string{"-config", configPath}); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	keys := []string{"a", "a0", "a/b", "a/c/d", "b", "core/keyring"}
+	for _, key := range keys {
+		entry, err := destination.Get(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if entry == nil || string(entry.Value) != "value of "+key {
+			t.Fatalf("bad entry for %q: %#v", key, entry)
+		}
+	}
+	entry, err := destination.Get(physical.MigrationCheckpointKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry != nil {
+		t.Fatal("checkpoint should have been deleted")
+	}
+
+	// A change of the source fails the verification
+	if err := source.Put(&physical.Entry{Key: "a/b", Value: []byte("changed")}); err != nil {
+		t.Fatal(err)
+	}
+	ui = new(cli.MockUi)
+	c = testOperatorMigrateCommand(ui)
+	if code := c.Run([]string{"-config", configPath, "-verify-only"}); code != 2 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+}
+
+func TestOperatorMigrate_resume(t *testing.T) {
+	_, destination, configPath, cleanup := testOperatorMigrate(t)
+	defer cleanup()
+
+	// Resume a migration which copied the keys up to a/c/d
+	value, err := jsonutil.EncodeJSON(map[string]interface{}{
+		"last_key": "a/c/d",
+		"copied":   3,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := destination.Put(&physical.Entry{Key: physical.MigrationCheckpointKey, Value: value}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The keys before the checkpoint are missing from the destination
+	ui := new(cli.MockUi)
+	c := testOperatorMigrateCommand(ui)
+	if code := c.Run([]string{"-config", configPath, "-rate", "1000"}); code != 2 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	for key, copied := range map[string]bool{"a": false, "a/b": false, "a/c/d": false, "a0": true, "b": true, "core/keyring": true} {
+		entry, err := destination.Get(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (entry != nil) != copied {
+			t.Fatalf("bad entry for %q: %#v", key, entry)
+		}
+	}
+
+	// Resetting copies all of them
+	ui = new(cli.MockUi)
+	c = testOperatorMigrateCommand(ui)
+	if code := c.Run([]string{"-config", configPath, "-reset"}); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+}
@@ -49,6 +49,10 @@ mount points of secret backends are known.`)
 		return 1
 	}
 
+	if c.StructuredOutput() {
+		return OutputData(c.Ui, c.Format(), c.Field(), help)
+	}
+
 	c.Ui.Output(help.Help)
 	return 0
 }
@@ -41,7 +41,9 @@ func (c *PolicyDeleteCommand) Run(args []string) int {
 		return 1
 	}
 
-	c.Ui.Output(fmt.Sprintf("Policy '%s' deleted.", name))
+	if !c.StructuredOutput() {
+		c.Ui.Output(fmt.Sprintf("Policy '%s' deleted.", name))
+	}
 	return 0
 }
 
@@ -47,6 +47,12 @@ func (c *PolicyListCommand) list() int {
 		return 1
 	}
 
+	if c.StructuredOutput() {
+		return OutputData(c.Ui, c.Format(), c.Field(), map[string]interface{}{
+			"policies": policies,
+		})
+	}
+
 	for _, p := range policies {
 		c.Ui.Output(p)
 	}
@@ -69,6 +75,13 @@ func (c *PolicyListCommand) read(n string) int {
 		return 1
 	}
 
+	if c.StructuredOutput() {
+		return OutputData(c.Ui, c.Format(), c.Field(), map[string]interface{}{
+			"name":  n,
+			"rules": rules,
+		})
+	}
+
 	c.Ui.Output(rules)
 	return 0
 }
@@ -67,7 +67,9 @@ func (c *PolicyWriteCommand) Run(args []string) int {
 		return 1
 	}
 
-	c.Ui.Output(fmt.Sprintf("Policy '%s' written.", name))
+	if !c.StructuredOutput() {
+		c.Ui.Output(fmt.Sprintf("Policy '%s' written.", name))
+	}
 	return 0
 }
 
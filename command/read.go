@@ -16,14 +16,10 @@ type ReadCommand struct {
 }
 
 func (c *ReadCommand) Run(args []string) int {
-	var format string
-	var field string
 	var err error
 	var secret *api.Secret
 	var flags *flag.FlagSet
 	flags = c.Meta.FlagSet("read", meta.FlagSetDefault)
-	flags.StringVar(&format, "format", "table", "")
-	flags.StringVar(&field, "field", "", "")
 	flags.Usage = func() { c.Ui.Error(c.Help()) }
 	if err := flags.Parse(args); err != nil {
 		return 1
@@ -61,11 +57,11 @@ func (c *ReadCommand) Run(args []string) int {
 	}
 
 	// Handle single field output
-	if field != "" {
-		return PrintRawField(c.Ui, secret, field)
+	if c.Field() != "" {
+		return PrintRawField(c.Ui, secret, c.Field())
 	}
 
-	return OutputSecret(c.Ui, format, secret)
+	return OutputSecret(c.Ui, c.Format(), secret)
 }
 
 func (c *ReadCommand) Synopsis() string {
@@ -84,16 +80,7 @@ Usage: vault read [options] path
   backends in use to determine key structure.
 
 General Options:
-` + meta.GeneralOptionsUsage() + `
-Read Options:
-
-  -format=table           The format for output. By default it is a whitespace-
-                          delimited table. This can also be json or yaml.
-
-  -field=field            If included, the raw value of the specified field
-                          will be output raw to stdout.
-
-`
+` + meta.GeneralOptionsUsage()
 	return strings.TrimSpace(helpText)
 }
 
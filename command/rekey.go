@@ -29,7 +29,7 @@ type RekeyCommand struct {
 }
 
 func (c *RekeyCommand) Run(args []string) int {
-	var init, cancel, status, delete, retrieve, backup, recoveryKey bool
+	var init, cancel, status, delete, retrieve, backup, recoveryKey, verify bool
 	var shares, threshold int
 	var nonce string
 	var pgpKeys pgpkeys.PubKeyFilesFlag
@@ -41,6 +41,7 @@ func (c *RekeyCommand) Run(args []string) int {
 	flags.BoolVar(&retrieve, "retrieve", false, "")
 	flags.BoolVar(&backup, "backup", false, "")
 	flags.BoolVar(&recoveryKey, "recovery-key", c.RecoveryKey, "")
+	flags.BoolVar(&verify, "verify", false, "")
 	flags.IntVar(&shares, "key-shares", 5, "")
 	flags.IntVar(&threshold, "key-threshold", 3, "")
 	flags.StringVar(&nonce, "nonce", "", "")
@@ -64,15 +65,21 @@ func (c *RekeyCommand) Run(args []string) int {
 	// Check if we are running doing any restricted variants
 	switch {
 	case init:
-		return c.initRekey(client, shares, threshold, pgpKeys, backup, recoveryKey)
+		return c.initRekey(client, shares, threshold, pgpKeys, backup, recoveryKey, verify)
+	case cancel && verify:
+		return c.restartRekeyVerification(client, recoveryKey)
 	case cancel:
 		return c.cancelRekey(client, recoveryKey)
+	case status && verify:
+		return c.rekeyVerificationStatus(client, recoveryKey)
 	case status:
 		return c.rekeyStatus(client, recoveryKey)
 	case retrieve:
 		return c.rekeyRetrieveStored(client, recoveryKey)
 	case delete:
 		return c.rekeyDeleteStored(client, recoveryKey)
+	case verify:
+		return c.verifyRekey(client, recoveryKey, flags.Args())
 	}
 
 	// Check if the rekey is started
@@ -124,19 +131,7 @@ func (c *RekeyCommand) Run(args []string) int {
 	if key == "" {
 		c.Nonce = serverNonce
 		fmt.Printf("Rekey operation nonce: %s\n", serverNonce)
-		fmt.Printf("Key (will be hidden): ")
-		key, err = password.Read(os.Stdin)
-		fmt.Printf("\n")
-		if err != nil {
-			c.Ui.Error(fmt.Sprintf(
-				"Error attempting to ask for password. The raw error message\n"+
-					"is shown below, but the most common reason for this error is\n"+
-					"that you attempted to pipe a value into unseal or you're\n"+
-					"executing `vault rekey` from outside of a terminal.\n\n"+
-					"You should use `vault rekey` from a terminal for maximum\n"+
-					"security. If this isn't an option, the unseal key can be passed\n"+
-					"in using the first parameter.\n\n"+
-					"Raw error: %s", err))
+		if key, err = c.readKey(); err != nil {
 			return 1
 		}
 	}
@@ -158,6 +153,10 @@ func (c *RekeyCommand) Run(args []string) int {
 		return c.rekeyStatus(client, recoveryKey)
 	}
 
+	if c.StructuredOutput() {
+		return OutputData(c.Ui, c.Format(), c.Field(), result)
+	}
+
 	// Space between the key prompt, if any, and the output
 	c.Ui.Output("\n")
 	// Provide the keys
@@ -183,6 +182,18 @@ func (c *RekeyCommand) Run(args []string) int {
 
 	c.Ui.Output(fmt.Sprintf("\nOperation nonce: %s", result.Nonce))
 
+	if result.VerificationRequired {
+		c.Ui.Output(fmt.Sprintf(
+			"\n"+
+				"The new keys must be verified before they are installed: until %d of\n"+
+				"them are provided with 'vault rekey -verify', the current keys remain\n"+
+				"in use. Verification nonce: %s",
+			threshold,
+			result.VerificationNonce,
+		))
+		return 0
+	}
+
 	if len(result.PGPFingerprints) > 0 && result.Backup {
 		c.Ui.Output(fmt.Sprintf(
 			"\n" +
@@ -213,13 +224,14 @@ func (c *RekeyCommand) Run(args []string) int {
 func (c *RekeyCommand) initRekey(client *api.Client,
 	shares, threshold int,
 	pgpKeys pgpkeys.PubKeyFilesFlag,
-	backup, recoveryKey bool) int {
+	backup, recoveryKey, verify bool) int {
 	// Start the rekey
 	request := &api.RekeyInitRequest{
-		SecretShares:    shares,
-		SecretThreshold: threshold,
-		PGPKeys:         pgpKeys,
-		Backup:          backup,
+		SecretShares:        shares,
+		SecretThreshold:     threshold,
+		PGPKeys:             pgpKeys,
+		Backup:              backup,
+		RequireVerification: verify,
 	}
 	var status *api.RekeyStatusResponse
 	var err error
@@ -233,6 +245,10 @@ func (c *RekeyCommand) initRekey(client *api.Client,
 		return 1
 	}
 
+	if c.StructuredOutput() {
+		return c.dumpRekeyStatus(status)
+	}
+
 	if pgpKeys == nil || len(pgpKeys) == 0 {
 		c.Ui.Output(`
 WARNING: If you lose the keys after they are returned to you, there is no
@@ -269,7 +285,9 @@ func (c *RekeyCommand) cancelRekey(client *api.Client, recovery bool) int {
 		c.Ui.Error(fmt.Sprintf("Failed to cancel rekey: %s", err))
 		return 1
 	}
-	c.Ui.Output("Rekey canceled.")
+	if !c.StructuredOutput() {
+		c.Ui.Output("Rekey canceled.")
+	}
 	return 0
 }
 
@@ -292,6 +310,10 @@ func (c *RekeyCommand) rekeyStatus(client *api.Client, recovery bool) int {
 }
 
 func (c *RekeyCommand) dumpRekeyStatus(status *api.RekeyStatusResponse) int {
+	if c.StructuredOutput() {
+		return OutputData(c.Ui, c.Format(), c.Field(), status)
+	}
+
 	// Dump the status
 	statString := fmt.Sprintf(
 		"Nonce: %s\n"+
@@ -311,10 +333,141 @@ func (c *RekeyCommand) dumpRekeyStatus(status *api.RekeyStatusResponse) int {
 		statString = fmt.Sprintf("%s\nPGP Key Fingerprints: %s", statString, status.PGPFingerprints)
 		statString = fmt.Sprintf("%s\nBackup Storage: %t", statString, status.Backup)
 	}
+	if status.VerificationRequired {
+		statString = fmt.Sprintf("%s\nVerification Required: %t", statString, status.VerificationRequired)
+	}
+	if status.VerificationNonce != "" {
+		statString = fmt.Sprintf("%s\nVerification Nonce: %s", statString, status.VerificationNonce)
+	}
 	c.Ui.Output(statString)
 	return 0
 }
 
+// verifyRekey is used to provide a new key to verify the rekey
+func (c *RekeyCommand) verifyRekey(client *api.Client, recovery bool, args []string) int {
+	var status *api.RekeyVerificationStatusResponse
+	var err error
+	if recovery {
+		status, err = client.Sys().RekeyRecoveryKeyVerificationStatus()
+	} else {
+		status, err = client.Sys().RekeyVerificationStatus()
+	}
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error reading rekey verification status: %s", err))
+		return 1
+	}
+	if !status.Started {
+		c.Ui.Error("No rekey verification is in progress")
+		return 1
+	}
+
+	key := c.Key
+	if len(args) > 0 {
+		key = args[0]
+	}
+	if key == "" {
+		c.Nonce = status.Nonce
+		fmt.Printf("Rekey verification nonce: %s\n", status.Nonce)
+		if key, err = c.readKey(); err != nil {
+			return 1
+		}
+	}
+
+	var result *api.RekeyVerificationUpdateResponse
+	if recovery {
+		result, err = client.Sys().RekeyRecoveryKeyVerificationUpdate(strings.TrimSpace(key), c.Nonce)
+	} else {
+		result, err = client.Sys().RekeyVerificationUpdate(strings.TrimSpace(key), c.Nonce)
+	}
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error attempting rekey verification: %s", err))
+		return 1
+	}
+
+	// If we are not complete, then dump the status
+	if !result.Complete {
+		return c.rekeyVerificationStatus(client, recovery)
+	}
+
+	if c.StructuredOutput() {
+		return OutputData(c.Ui, c.Format(), c.Field(), result)
+	}
+
+	c.Ui.Output(fmt.Sprintf(
+		"Rekey verification successful. The new keys are now active.\n\n"+
+			"Operation nonce: %s", result.Nonce))
+	return 0
+}
+
+// restartRekeyVerification is used to discard the keys provided to verify
+// the rekey, starting the verification over
+func (c *RekeyCommand) restartRekeyVerification(client *api.Client, recovery bool) int {
+	var err error
+	if recovery {
+		err = client.Sys().RekeyRecoveryKeyVerificationCancel()
+	} else {
+		err = client.Sys().RekeyVerificationCancel()
+	}
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Failed to restart rekey verification: %s", err))
+		return 1
+	}
+	return c.rekeyVerificationStatus(client, recovery)
+}
+
+// rekeyVerificationStatus is used to fetch and dump the verification status
+func (c *RekeyCommand) rekeyVerificationStatus(client *api.Client, recovery bool) int {
+	var status *api.RekeyVerificationStatusResponse
+	var err error
+	if recovery {
+		status, err = client.Sys().RekeyRecoveryKeyVerificationStatus()
+	} else {
+		status, err = client.Sys().RekeyVerificationStatus()
+	}
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error reading rekey verification status: %s", err))
+		return 1
+	}
+
+	if c.StructuredOutput() {
+		return OutputData(c.Ui, c.Format(), c.Field(), status)
+	}
+
+	c.Ui.Output(fmt.Sprintf(
+		"Verification Nonce: %s\n"+
+			"Started: %t\n"+
+			"New Key Shares: %d\n"+
+			"New Key Threshold: %d\n"+
+			"Verification Progress: %d",
+		status.Nonce,
+		status.Started,
+		status.N,
+		status.T,
+		status.Progress,
+	))
+	return 0
+}
+
+// readKey asks for a key without echoing it
+func (c *RekeyCommand) readKey() (string, error) {
+	fmt.Printf("Key (will be hidden): ")
+	key, err := password.Read(os.Stdin)
+	fmt.Printf("\n")
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf(
+			"Error attempting to ask for password. The raw error message\n"+
+				"is shown below, but the most common reason for this error is\n"+
+				"that you attempted to pipe a value into unseal or you're\n"+
+				"executing `vault rekey` from outside of a terminal.\n\n"+
+				"You should use `vault rekey` from a terminal for maximum\n"+
+				"security. If this isn't an option, the unseal key can be passed\n"+
+				"in using the first parameter.\n\n"+
+				"Raw error: %s", err))
+		return "", err
+	}
+	return key, nil
+}
+
 func (c *RekeyCommand) rekeyRetrieveStored(client *api.Client, recovery bool) int {
 	var storedKeys *api.RekeyRetrieveResponse
 	var err error
@@ -328,6 +481,10 @@ func (c *RekeyCommand) rekeyRetrieveStored(client *api.Client, recovery bool) in
 		return 1
 	}
 
+	if c.StructuredOutput() {
+		return OutputData(c.Ui, c.Format(), c.Field(), storedKeys)
+	}
+
 	secret := &api.Secret{
 		Data: structs.New(storedKeys).Map(),
 	}
@@ -346,7 +503,9 @@ func (c *RekeyCommand) rekeyDeleteStored(client *api.Client, recovery bool) int
 		c.Ui.Error(fmt.Sprintf("Failed to delete stored keys: %s", err))
 		return 1
 	}
-	c.Ui.Output("Stored keys deleted.")
+	if !c.StructuredOutput() {
+		c.Ui.Output("Stored keys deleted.")
+	}
 	return 0
 }
 
@@ -415,7 +574,13 @@ Rekey Options:
                           'sys/rekey/backup' endpoint.
 
   -recovery-key=false     Whether to rekey the recovery key instead of the
-                          barrier key. Only used with Vault HSM.
+                          barrier key. Only used with auto-unseal seals.
+
+  -verify=false           With -init, require the new keys to be verified
+                          before they are installed: a threshold of them must
+                          be provided back, guarding against lost keys. Without
+                          -init, provide a new key to verify, and combined with
+                          -status or -cancel, print or restart the verification.
 `
 	return strings.TrimSpace(helpText)
 }
@@ -437,5 +602,6 @@ func (c *RekeyCommand) AutocompleteFlags() complete.Flags {
 		"-pgp-keys":      complete.PredictNothing,
 		"-backup":        complete.PredictNothing,
 		"-recovery-key":  complete.PredictNothing,
+		"-verify":        complete.PredictNothing,
 	}
 }
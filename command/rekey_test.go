@@ -3,6 +3,7 @@ package command
 import (
 	"encoding/hex"
 	"os"
+	"regexp"
 	"sort"
 	"strings"
 	"testing"
@@ -187,6 +188,77 @@ func TestRekey_status(t *testing.T) {
 	}
 }
 
+func TestRekey_verify(t *testing.T) {
+	core, keys, _ := vault.TestCoreUnsealed(t)
+	ln, addr := http.TestServer(t, core)
+	defer ln.Close()
+
+	ui := new(cli.MockUi)
+	c := &RekeyCommand{
+		Meta: meta.Meta{
+			Ui: ui,
+		},
+	}
+
+	args := []string{
+		"-address", addr,
+		"-init",
+		"-verify",
+		"-key-threshold", "2",
+		"-key-shares", "3",
+	}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	config, err := core.RekeyConfig(false)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	c.Nonce = config.Nonce
+
+	for _, key := range keys {
+		c.Key = hex.EncodeToString(key)
+		if code := c.Run([]string{"-address", addr}); code != 0 {
+			t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+		}
+	}
+	output := ui.OutputWriter.String()
+	if !strings.Contains(output, "must be verified") {
+		t.Fatalf("bad: %s", output)
+	}
+	var newKeys []string
+	for _, match := range regexp.MustCompile(`(?m)^Key \d+: (\S+)$`).FindAllStringSubmatch(output, -1) {
+		newKeys = append(newKeys, match[1])
+	}
+	if len(newKeys) != 3 {
+		t.Fatalf("bad: %s", output)
+	}
+
+	// The new keys are only installed once verified
+	config, err = core.RekeyConfig(false)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	c.Nonce = config.VerificationNonce
+	for _, key := range newKeys[:2] {
+		c.Key = key
+		if code := c.Run([]string{"-address", addr, "-verify"}); code != 0 {
+			t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+		}
+	}
+	if !strings.Contains(ui.OutputWriter.String(), "Rekey verification successful") {
+		t.Fatalf("bad: %s", ui.OutputWriter.String())
+	}
+
+	sealConfig, err := core.SealAccess().BarrierConfig()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if sealConfig.SecretShares != 3 || sealConfig.SecretThreshold != 2 {
+		t.Fatalf("bad: %#v", sealConfig)
+	}
+}
+
 func TestRekey_init_pgp(t *testing.T) {
 	core, keys, token := vault.TestCoreUnsealed(t)
 	ln, addr := http.TestServer(t, core)
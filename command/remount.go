@@ -44,8 +44,10 @@ func (c *RemountCommand) Run(args []string) int {
 		return 2
 	}
 
-	c.Ui.Output(fmt.Sprintf(
-		"Successfully remounted from '%s' to '%s'!", from, to))
+	if !c.StructuredOutput() {
+		c.Ui.Output(fmt.Sprintf(
+			"Successfully remounted from '%s' to '%s'!", from, to))
+	}
 
 	return 0
 }
@@ -14,9 +14,7 @@ type RenewCommand struct {
 }
 
 func (c *RenewCommand) Run(args []string) int {
-	var format string
 	flags := c.Meta.FlagSet("renew", meta.FlagSetDefault)
-	flags.StringVar(&format, "format", "table", "")
 	flags.Usage = func() { c.Ui.Error(c.Help()) }
 	if err := flags.Parse(args); err != nil {
 		return 1
@@ -57,7 +55,12 @@ func (c *RenewCommand) Run(args []string) int {
 		return 1
 	}
 
-	return OutputSecret(c.Ui, format, secret)
+	// Handle single field output
+	if c.Field() != "" {
+		return PrintRawField(c.Ui, secret, c.Field())
+	}
+
+	return OutputSecret(c.Ui, c.Format(), secret)
 }
 
 func (c *RenewCommand) Synopsis() string {
@@ -80,11 +83,6 @@ Usage: vault renew [options] id [increment]
   is not required to honor this request.
 
 General Options:
-` + meta.GeneralOptionsUsage() + `
-Renew Options:
-
-  -format=table           The format for output. By default it is a whitespace-
-                          delimited table. This can also be json or yaml.
-`
+` + meta.GeneralOptionsUsage()
 	return strings.TrimSpace(helpText)
 }
@@ -42,6 +42,10 @@ func (c *RotateCommand) Run(args []string) int {
 		return 2
 	}
 
+	if c.StructuredOutput() {
+		return OutputData(c.Ui, c.Format(), c.Field(), status)
+	}
+
 	c.Ui.Output(fmt.Sprintf("Key Term: %d", status.Term))
 	c.Ui.Output(fmt.Sprintf("Installation Time: %v", status.InstallTime))
 	return 0
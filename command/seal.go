@@ -31,7 +31,9 @@ func (c *SealCommand) Run(args []string) int {
 		return 1
 	}
 
-	c.Ui.Output("Vault is now sealed.")
+	if !c.StructuredOutput() {
+		c.Ui.Output("Vault is now sealed.")
+	}
 	return 0
 }
 
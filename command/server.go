@@ -2,6 +2,7 @@ package command
 
 import (
 	"encoding/base64"
+	"flag"
 	"fmt"
 	"io/ioutil"
 	"net"
@@ -10,6 +11,7 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"reflect"
 	"runtime"
 	"sort"
 	"strconv"
@@ -69,6 +71,19 @@ type ServerCommand struct {
 
 	reloadFuncsLock *sync.RWMutex
 	reloadFuncs     *map[string][]reload.ReloadFunc
+
+	// logLevelFlag is set when the log level is given with -log-level, in
+	// which case the log level of the configuration is ignored
+	logLevelFlag bool
+
+	// metricsSink sends the metrics to the sinks of the telemetry
+	// configuration, which are replaced on reload
+	metricsSink *reloadableMetricsSink
+
+	// The configuration in effect and the log level, which change on reload
+	configLock sync.RWMutex
+	config     *server.Config
+	logLevel   string
 }
 
 func (c *ServerCommand) Run(args []string) int {
@@ -96,26 +111,21 @@ func (c *ServerCommand) Run(args []string) int {
 		return 1
 	}
 
+	// The log level given with -log-level takes precedence over the one of
+	// the configuration
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == "log-level" {
+			c.logLevelFlag = true
+		}
+	})
+
 	// Create a logger. We wrap it in a gated writer so that it doesn't
 	// start logging too early.
 	c.logGate = &gatedwriter.Writer{Writer: colorable.NewColorable(os.Stderr)}
-	var level int
 	logLevel = strings.ToLower(strings.TrimSpace(logLevel))
-	switch logLevel {
-	case "trace":
-		level = log.LevelTrace
-	case "debug":
-		level = log.LevelDebug
-	case "info":
-		level = log.LevelInfo
-	case "notice":
-		level = log.LevelNotice
-	case "warn":
-		level = log.LevelWarn
-	case "err":
-		level = log.LevelError
-	default:
-		c.Ui.Output(fmt.Sprintf("Unknown log level %s", logLevel))
+	level, err := parseLogLevel(logLevel)
+	if err != nil {
+		c.Ui.Output(err.Error())
 		return 1
 	}
 
@@ -190,6 +200,21 @@ func (c *ServerCommand) Run(args []string) int {
 		return 1
 	}
 
+	// Apply the log level of the configuration
+	if config.LogLevel != "" && !c.logLevelFlag {
+		logLevel = strings.ToLower(strings.TrimSpace(config.LogLevel))
+		level, err := parseLogLevel(logLevel)
+		if err != nil {
+			c.Ui.Output(err.Error())
+			return 1
+		}
+		c.logger.SetLevel(level)
+	}
+	c.configLock.Lock()
+	c.config = config
+	c.logLevel = logLevel
+	c.configLock.Unlock()
+
 	// Ensure that a backend is provided
 	if config.Storage == nil {
 		c.Ui.Output("A storage backend must be specified")
@@ -230,12 +255,32 @@ func (c *ServerCommand) Run(args []string) int {
 	info := make(map[string]string)
 
 	var seal vault.Seal = &vault.DefaultSeal{}
+	var unwrapSeal vault.Seal
+	if config.Seal != nil {
+		configured, err := c.newSeal(config.Seal, info, &infoKeys)
+		if err != nil {
+			c.Ui.Output(fmt.Sprintf(
+				"Error initializing seal of type %s: %s",
+				config.Seal.Type, err))
+			return 1
+		}
+
+		// A disabled seal is only used to migrate the master key away from
+		// it, to Shamir
+		if config.Seal.Disabled {
+			unwrapSeal = configured
+		} else {
+			seal = configured
+		}
+	}
 
 	// Ensure that the seal finalizer is called, even if using verify-only
 	defer func() {
-		if seal != nil {
-			err = seal.Finalize()
-			if err != nil {
+		for _, s := range []vault.Seal{seal, unwrapSeal} {
+			if s == nil {
+				continue
+			}
+			if err := s.Finalize(); err != nil {
 				c.Ui.Error(fmt.Sprintf("Error finalizing seals: %v", err))
 			}
 		}
@@ -247,22 +292,65 @@ func (c *ServerCommand) Run(args []string) int {
 	}
 
 	coreConfig := &vault.CoreConfig{
-		Physical:           backend,
-		RedirectAddr:       config.Storage.RedirectAddr,
-		HAPhysical:         nil,
-		Seal:               seal,
-		AuditBackends:      c.AuditBackends,
-		CredentialBackends: c.CredentialBackends,
-		LogicalBackends:    c.LogicalBackends,
-		Logger:             c.logger,
-		DisableCache:       config.DisableCache,
-		DisableMlock:       config.DisableMlock,
-		MaxLeaseTTL:        config.MaxLeaseTTL,
-		DefaultLeaseTTL:    config.DefaultLeaseTTL,
-		ClusterName:        config.ClusterName,
-		CacheSize:          config.CacheSize,
-		PluginDirectory:    config.PluginDirectory,
-		EnableRaw:          config.EnableRawEndpoint,
+		Physical:            backend,
+		RedirectAddr:        config.Storage.RedirectAddr,
+		HAPhysical:          nil,
+		Seal:                seal,
+		UnwrapSeal:          unwrapSeal,
+		AuditBackends:       c.AuditBackends,
+		CredentialBackends:  c.CredentialBackends,
+		LogicalBackends:     c.LogicalBackends,
+		Logger:              c.logger,
+		DisableCache:        config.DisableCache,
+		DisableMlock:        config.DisableMlock,
+		MaxLeaseTTL:         config.MaxLeaseTTL,
+		DefaultLeaseTTL:     config.DefaultLeaseTTL,
+		ClusterName:         config.ClusterName,
+		CacheSize:           config.CacheSize,
+		PluginDirectory:     config.PluginDirectory,
+		TokenTidyInterval:   config.TokenTidyInterval,
+		LeaseRestoreWorkers: config.LeaseRestoreWorkers,
+		EnableRaw:           config.EnableRawEndpoint,
+		EnableChaos:         config.EnableChaosEndpoints,
+		MaxEntrySize:        config.Storage.MaxEntrySize,
+	}
+
+	// Reload the same way as on SIGHUP when requested through the API
+	coreConfig.ReloadHook = func() error {
+		return c.Reload(c.reloadFuncsLock, c.reloadFuncs, configPath)
+	}
+	coreConfig.ConfigStateHook = c.configState
+	coreConfig.MetricsSink = c.metricsSink.inm
+	if config.Storage.CacheSize != 0 || config.Storage.CacheType != "" ||
+		config.Storage.CacheTTL != 0 || len(config.Storage.CacheBypassPrefixes) > 0 {
+		coreConfig.PhysicalCache = &physical.CacheConfig{
+			Size:           config.Storage.CacheSize,
+			Type:           config.Storage.CacheType,
+			TTL:            config.Storage.CacheTTL,
+			BypassPrefixes: config.Storage.CacheBypassPrefixes,
+		}
+	}
+	if config.LoadShedding != nil {
+		coreConfig.LoadShedding = &vault.LoadSheddingConfig{
+			MaxInFlightRequests: config.LoadShedding.MaxInFlightRequests,
+			MaxStorageLatency:   config.LoadShedding.MaxStorageLatency,
+			RetryAfter:          config.LoadShedding.RetryAfter,
+		}
+	}
+	if config.RequestForwarding != nil {
+		coreConfig.RequestForwarding = &vault.RequestForwardingConfig{
+			PoolSize:     config.RequestForwarding.PoolSize,
+			MaxInFlight:  config.RequestForwarding.MaxInFlight,
+			QueueTimeout: config.RequestForwarding.QueueTimeout,
+		}
+	}
+	if config.PluginRestart != nil {
+		coreConfig.PluginRestart = &vault.PluginRestartConfig{
+			CheckInterval:  config.PluginRestart.CheckInterval,
+			InitialBackoff: config.PluginRestart.InitialBackoff,
+			MaxBackoff:     config.PluginRestart.MaxBackoff,
+			MaxAttempts:    config.PluginRestart.MaxAttempts,
+		}
 	}
 	if dev {
 		coreConfig.DevToken = devRootTokenID
@@ -475,9 +563,9 @@ CLUSTER_SYNTHESIS_COMPLETE:
 		lns = append(lns, ln)
 
 		if reloadFunc != nil {
-			relSlice := (*c.reloadFuncs)["listener|"+lnConfig.Type]
+			relSlice := (*c.reloadFuncs)[lnConfig.ReloadKey()]
 			relSlice = append(relSlice, reloadFunc)
-			(*c.reloadFuncs)["listener|"+lnConfig.Type] = relSlice
+			(*c.reloadFuncs)[lnConfig.ReloadKey()] = relSlice
 		}
 
 		if !disableClustering && lnConfig.Type == "tcp" {
@@ -680,13 +768,13 @@ CLUSTER_SYNTHESIS_COMPLETE:
 	c.logGate.Flush()
 
 	// Write out the PID to the file now that server has successfully started
-	if err := c.storePidFile(config.PidFile); err != nil {
+	if err := storePidFile(config.PidFile); err != nil {
 		c.Ui.Output(fmt.Sprintf("Error storing PID: %v", err))
 		return 1
 	}
 
 	defer func() {
-		if err := c.removePidFile(config.PidFile); err != nil {
+		if err := removePidFile(config.PidFile); err != nil {
 			c.Ui.Output(fmt.Sprintf("Error deleting the PID file: %v", err))
 		}
 	}()
@@ -1105,23 +1193,44 @@ func (c *ServerCommand) setupTelemetry(config *server.Config) error {
 	metricsConf := metrics.DefaultConfig("vault")
 	metricsConf.EnableHostname = !telConfig.DisableHostname
 
+	// The sinks are swapped on reload, while the global metrics and the
+	// in-memory sink stay
+	c.metricsSink = &reloadableMetricsSink{
+		inm:      inm,
+		hostName: metricsConf.HostName,
+	}
+	if err := c.metricsSink.reload(telConfig); err != nil {
+		return err
+	}
+
+	// Initialize the global sink
+	if len(c.metricsSink.sinks) == 0 {
+		metricsConf.EnableHostname = false
+	}
+	metrics.NewGlobal(metricsConf, c.metricsSink)
+	return nil
+}
+
+// telemetrySinks creates the sinks the metrics are sent to
+func telemetrySinks(telConfig *server.Telemetry, hostName string) ([]metrics.MetricSink, error) {
+	var sinks []metrics.MetricSink
+
 	// Configure the statsite sink
-	var fanout metrics.FanoutSink
 	if telConfig.StatsiteAddr != "" {
 		sink, err := metrics.NewStatsiteSink(telConfig.StatsiteAddr)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		fanout = append(fanout, sink)
+		sinks = append(sinks, sink)
 	}
 
 	// Configure the statsd sink
 	if telConfig.StatsdAddr != "" {
 		sink, err := metrics.NewStatsdSink(telConfig.StatsdAddr)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		fanout = append(fanout, sink)
+		sinks = append(sinks, sink)
 	}
 
 	// Configure the Circonus sink
@@ -1155,10 +1264,10 @@ func (c *ServerCommand) setupTelemetry(config *server.Config) error {
 
 		sink, err := circonus.NewCirconusSink(cfg)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		sink.Start()
-		fanout = append(fanout, sink)
+		sinks = append(sinks, sink)
 	}
 
 	if telConfig.DogStatsDAddr != "" {
@@ -1168,37 +1277,135 @@ func (c *ServerCommand) setupTelemetry(config *server.Config) error {
 			tags = telConfig.DogStatsDTags
 		}
 
-		sink, err := datadog.NewDogStatsdSink(telConfig.DogStatsDAddr, metricsConf.HostName)
+		sink, err := datadog.NewDogStatsdSink(telConfig.DogStatsDAddr, hostName)
 		if err != nil {
-			return fmt.Errorf("failed to start DogStatsD sink. Got: %s", err)
+			return nil, fmt.Errorf("failed to start DogStatsD sink. Got: %s", err)
 		}
 		sink.SetTags(tags)
-		fanout = append(fanout, sink)
+		sinks = append(sinks, sink)
 	}
 
-	// Initialize the global sink
-	if len(fanout) > 0 {
-		fanout = append(fanout, inm)
-		metrics.NewGlobal(metricsConf, fanout)
-	} else {
-		metricsConf.EnableHostname = false
-		metrics.NewGlobal(metricsConf, inm)
+	return sinks, nil
+}
+
+// reloadableMetricsSink sends the metrics to the in-memory sink and to the
+// sinks of the telemetry configuration, which are replaced when it changes
+type reloadableMetricsSink struct {
+	sync.RWMutex
+
+	inm      *metrics.InmemSink
+	hostName string
+
+	config *server.Telemetry
+	sinks  []metrics.MetricSink
+	fanout metrics.FanoutSink
+}
+
+// reload creates the sinks of the telemetry configuration if it changed.
+// The current sinks are kept if the new ones can't be created.
+func (s *reloadableMetricsSink) reload(telConfig *server.Telemetry) error {
+	if telConfig == nil {
+		telConfig = &server.Telemetry{}
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	if s.config != nil && reflect.DeepEqual(s.config, telConfig) {
+		return nil
 	}
+
+	sinks, err := telemetrySinks(telConfig, s.hostName)
+	if err != nil {
+		return err
+	}
+
+	// Stop sending to the previous sinks
+	for _, sink := range s.sinks {
+		switch sink := sink.(type) {
+		case interface {
+			Shutdown()
+		}:
+			sink.Shutdown()
+		case interface {
+			Flush()
+		}:
+			sink.Flush()
+		}
+	}
+
+	s.config = telConfig
+	s.sinks = sinks
+	s.fanout = append(metrics.FanoutSink{s.inm}, sinks...)
 	return nil
 }
 
+func (s *reloadableMetricsSink) current() metrics.FanoutSink {
+	s.RLock()
+	defer s.RUnlock()
+	return s.fanout
+}
+
+func (s *reloadableMetricsSink) SetGauge(key []string, val float32) {
+	s.current().SetGauge(key, val)
+}
+
+func (s *reloadableMetricsSink) SetGaugeWithLabels(key []string, val float32, labels []metrics.Label) {
+	s.current().SetGaugeWithLabels(key, val, labels)
+}
+
+func (s *reloadableMetricsSink) EmitKey(key []string, val float32) {
+	s.current().EmitKey(key, val)
+}
+
+func (s *reloadableMetricsSink) IncrCounter(key []string, val float32) {
+	s.current().IncrCounter(key, val)
+}
+
+func (s *reloadableMetricsSink) IncrCounterWithLabels(key []string, val float32, labels []metrics.Label) {
+	s.current().IncrCounterWithLabels(key, val, labels)
+}
+
+func (s *reloadableMetricsSink) AddSample(key []string, val float32) {
+	s.current().AddSample(key, val)
+}
+
+func (s *reloadableMetricsSink) AddSampleWithLabels(key []string, val float32, labels []metrics.Label) {
+	s.current().AddSampleWithLabels(key, val, labels)
+}
+
 func (c *ServerCommand) Reload(lock *sync.RWMutex, reloadFuncs *map[string][]reload.ReloadFunc, configPath []string) error {
 	lock.RLock()
 	defer lock.RUnlock()
 
 	var reloadErrors *multierror.Error
 
+	// Read the configuration again so that the listeners pick up the
+	// changes of their TLS settings. The listeners which aren't found in it,
+	// such as the dev listener, read the files of their settings again.
+	listenerConfigs := make(map[string]map[string]interface{})
+	for _, path := range configPath {
+		current, err := server.LoadConfig(path, c.logger)
+		if err != nil {
+			return fmt.Errorf("Error loading configuration from %s: %s", path, err)
+		}
+		for _, lnConfig := range current.Listeners {
+			listenerConfigs[lnConfig.ReloadKey()] = lnConfig.Config
+		}
+	}
+
+	if len(configPath) > 0 {
+		if err := c.reloadConfig(configPath); err != nil {
+			reloadErrors = multierror.Append(reloadErrors, err)
+		}
+	}
+
 	for k, relFuncs := range *reloadFuncs {
 		switch {
 		case strings.HasPrefix(k, "listener|"):
 			for _, relFunc := range relFuncs {
 				if relFunc != nil {
-					if err := relFunc(nil); err != nil {
+					if err := relFunc(listenerConfigs[k]); err != nil {
 						reloadErrors = multierror.Append(reloadErrors, fmt.Errorf("Error encountered reloading listener: %v", err))
 					}
 				}
@@ -1218,6 +1425,82 @@ func (c *ServerCommand) Reload(lock *sync.RWMutex, reloadFuncs *map[string][]rel
 	return reloadErrors.ErrorOrNil()
 }
 
+// reloadConfig applies the log level and the telemetry settings of the
+// configuration, which is read again
+func (c *ServerCommand) reloadConfig(configPath []string) error {
+	var config *server.Config
+	for _, path := range configPath {
+		current, err := server.LoadConfig(path, c.logger)
+		if err != nil {
+			return fmt.Errorf("Error loading configuration from %s: %s", path, err)
+		}
+		if config == nil {
+			config = current
+		} else {
+			config = config.Merge(current)
+		}
+	}
+
+	var reloadErrors *multierror.Error
+
+	c.configLock.Lock()
+	defer c.configLock.Unlock()
+
+	if config.LogLevel != "" && !c.logLevelFlag {
+		logLevel := strings.ToLower(strings.TrimSpace(config.LogLevel))
+		level, err := parseLogLevel(logLevel)
+		if err != nil {
+			reloadErrors = multierror.Append(reloadErrors, err)
+		} else if logLevel != c.logLevel {
+			c.logger.SetLevel(level)
+			c.logLevel = logLevel
+			c.logger.Info("core: log level changed", "level", logLevel)
+		}
+	}
+
+	if c.metricsSink != nil {
+		if err := c.metricsSink.reload(config.Telemetry); err != nil {
+			reloadErrors = multierror.Append(reloadErrors, fmt.Errorf("Error encountered reloading telemetry: %v", err))
+
+			// The previous sinks are kept, and so is their configuration
+			config.Telemetry = c.config.Telemetry
+		}
+	}
+
+	c.config = config
+	return reloadErrors.ErrorOrNil()
+}
+
+// configState returns the configuration in effect, without its secrets, for
+// sys/config/state
+func (c *ServerCommand) configState() map[string]interface{} {
+	c.configLock.RLock()
+	defer c.configLock.RUnlock()
+
+	state := c.config.Sanitized()
+	state["log_level"] = c.logLevel
+	return state
+}
+
+func parseLogLevel(logLevel string) (int, error) {
+	switch logLevel {
+	case "trace":
+		return log.LevelTrace, nil
+	case "debug":
+		return log.LevelDebug, nil
+	case "info":
+		return log.LevelInfo, nil
+	case "notice":
+		return log.LevelNotice, nil
+	case "warn":
+		return log.LevelWarn, nil
+	case "err":
+		return log.LevelError, nil
+	default:
+		return 0, fmt.Errorf("Unknown log level %s", logLevel)
+	}
+}
+
 func (c *ServerCommand) Synopsis() string {
 	return "Start a Vault server"
 }
@@ -1281,7 +1564,7 @@ func (c *ServerCommand) AutocompleteFlags() complete.Flags {
 }
 
 // storePidFile is used to write out our PID to a file if necessary
-func (c *ServerCommand) storePidFile(pidPath string) error {
+func storePidFile(pidPath string) error {
 	// Quit fast if no pidfile
 	if pidPath == "" {
 		return nil
@@ -1304,7 +1587,7 @@ func (c *ServerCommand) storePidFile(pidPath string) error {
 }
 
 // removePidFile is used to cleanup the PID file if necessary
-func (c *ServerCommand) removePidFile(pidPath string) error {
+func removePidFile(pidPath string) error {
 	if pidPath == "" {
 		return nil
 	}
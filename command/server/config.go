@@ -26,6 +26,8 @@ type Config struct {
 
 	HSM *HSM `hcl:"-"`
 
+	Seal *Seal `hcl:"-"`
+
 	CacheSize       int         `hcl:"cache_size"`
 	DisableCache    bool        `hcl:"-"`
 	DisableCacheRaw interface{} `hcl:"disable_cache"`
@@ -37,19 +39,35 @@ type Config struct {
 
 	Telemetry *Telemetry `hcl:"telemetry"`
 
+	LoadShedding *LoadShedding `hcl:"-"`
+
+	RequestForwarding *RequestForwarding `hcl:"-"`
+
+	PluginRestart *PluginRestart `hcl:"-"`
+
 	MaxLeaseTTL        time.Duration `hcl:"-"`
 	MaxLeaseTTLRaw     interface{}   `hcl:"max_lease_ttl"`
 	DefaultLeaseTTL    time.Duration `hcl:"-"`
 	DefaultLeaseTTLRaw interface{}   `hcl:"default_lease_ttl"`
 
+	TokenTidyInterval    time.Duration `hcl:"-"`
+	TokenTidyIntervalRaw interface{}   `hcl:"token_tidy_interval"`
+
+	LeaseRestoreWorkers int `hcl:"lease_restore_workers"`
+
 	ClusterName         string `hcl:"cluster_name"`
 	ClusterCipherSuites string `hcl:"cluster_cipher_suites"`
 
 	PluginDirectory string `hcl:"plugin_directory"`
 
+	LogLevel string `hcl:"log_level"`
+
 	PidFile              string      `hcl:"pid_file"`
 	EnableRawEndpoint    bool        `hcl:"-"`
 	EnableRawEndpointRaw interface{} `hcl:"raw_storage_endpoint"`
+
+	EnableChaosEndpoints    bool        `hcl:"-"`
+	EnableChaosEndpointsRaw interface{} `hcl:"enable_chaos_endpoints"`
 }
 
 // DevConfig is a Config that is used for dev mode of Vault.
@@ -102,6 +120,13 @@ func (l *Listener) GoString() string {
 	return fmt.Sprintf("*%#v", *l)
 }
 
+// ReloadKey identifies the listener among the reload functions of the
+// server, so that it can be given its new configuration on reload
+func (l *Listener) ReloadKey() string {
+	addr, _ := l.Config["address"].(string)
+	return "listener|" + l.Type + "|" + addr
+}
+
 // Storage is the underlying storage configuration for the server.
 type Storage struct {
 	Type              string
@@ -109,6 +134,15 @@ type Storage struct {
 	ClusterAddr       string
 	DisableClustering bool
 	Config            map[string]string
+
+	// The cache of the storage, overriding the size set by cache_size
+	CacheSize           int
+	CacheType           string
+	CacheTTL            time.Duration
+	CacheBypassPrefixes []string
+
+	// The maximum size in bytes of the entries written to the storage
+	MaxEntrySize int
 }
 
 func (b *Storage) GoString() string {
@@ -125,6 +159,62 @@ func (h *HSM) GoString() string {
 	return fmt.Sprintf("*%#v", *h)
 }
 
+// Seal contains the configuration of the auto-unseal seal of the server
+type Seal struct {
+	Type   string
+	Config map[string]string
+
+	// Disabled is set to migrate the master key away from the seal, to
+	// Shamir
+	Disabled bool
+}
+
+func (s *Seal) GoString() string {
+	return fmt.Sprintf("*%#v", *s)
+}
+
+// LoadShedding is the load shedding configuration for the server
+type LoadShedding struct {
+	MaxInFlightRequests  int64         `hcl:"max_in_flight_requests"`
+	MaxStorageLatency    time.Duration `hcl:"-"`
+	MaxStorageLatencyRaw interface{}   `hcl:"max_storage_latency"`
+	RetryAfter           time.Duration `hcl:"-"`
+	RetryAfterRaw        interface{}   `hcl:"retry_after"`
+}
+
+func (l *LoadShedding) GoString() string {
+	return fmt.Sprintf("*%#v", *l)
+}
+
+// RequestForwarding is the configuration of the forwarding of requests from
+// standby nodes to the active node
+type RequestForwarding struct {
+	PoolSize        int           `hcl:"pool_size"`
+	MaxInFlight     int           `hcl:"max_in_flight"`
+	QueueTimeout    time.Duration `hcl:"-"`
+	QueueTimeoutRaw interface{}   `hcl:"queue_timeout"`
+}
+
+func (r *RequestForwarding) GoString() string {
+	return fmt.Sprintf("*%#v", *r)
+}
+
+// PluginRestart is the configuration of the probing of the external plugin
+// processes and of the restart of the ones which crashed
+type PluginRestart struct {
+	CheckInterval     time.Duration `hcl:"-"`
+	CheckIntervalRaw  interface{}   `hcl:"check_interval"`
+	InitialBackoff    time.Duration `hcl:"-"`
+	InitialBackoffRaw interface{}   `hcl:"initial_backoff"`
+	MaxBackoff        time.Duration `hcl:"-"`
+	MaxBackoffRaw     interface{}   `hcl:"max_backoff"`
+	MaxAttempts       int           `hcl:"max_attempts"`
+}
+
+func (p *PluginRestart) GoString() string {
+	return fmt.Sprintf("*%#v", *p)
+}
+
 // Telemetry is the telemetry configuration for the server
 type Telemetry struct {
 	StatsiteAddr string `hcl:"statsite_address"`
@@ -246,11 +336,31 @@ func (c *Config) Merge(c2 *Config) *Config {
 		result.HSM = c2.HSM
 	}
 
+	result.Seal = c.Seal
+	if c2.Seal != nil {
+		result.Seal = c2.Seal
+	}
+
 	result.Telemetry = c.Telemetry
 	if c2.Telemetry != nil {
 		result.Telemetry = c2.Telemetry
 	}
 
+	result.LoadShedding = c.LoadShedding
+	if c2.LoadShedding != nil {
+		result.LoadShedding = c2.LoadShedding
+	}
+
+	result.RequestForwarding = c.RequestForwarding
+	if c2.RequestForwarding != nil {
+		result.RequestForwarding = c2.RequestForwarding
+	}
+
+	result.PluginRestart = c.PluginRestart
+	if c2.PluginRestart != nil {
+		result.PluginRestart = c2.PluginRestart
+	}
+
 	result.CacheSize = c.CacheSize
 	if c2.CacheSize != 0 {
 		result.CacheSize = c2.CacheSize
@@ -278,6 +388,16 @@ func (c *Config) Merge(c2 *Config) *Config {
 		result.DefaultLeaseTTL = c2.DefaultLeaseTTL
 	}
 
+	result.TokenTidyInterval = c.TokenTidyInterval
+	if c2.TokenTidyInterval != 0 {
+		result.TokenTidyInterval = c2.TokenTidyInterval
+	}
+
+	result.LeaseRestoreWorkers = c.LeaseRestoreWorkers
+	if c2.LeaseRestoreWorkers != 0 {
+		result.LeaseRestoreWorkers = c2.LeaseRestoreWorkers
+	}
+
 	result.ClusterName = c.ClusterName
 	if c2.ClusterName != "" {
 		result.ClusterName = c2.ClusterName
@@ -298,6 +418,11 @@ func (c *Config) Merge(c2 *Config) *Config {
 		result.EnableRawEndpoint = c2.EnableRawEndpoint
 	}
 
+	result.EnableChaosEndpoints = c.EnableChaosEndpoints
+	if c2.EnableChaosEndpoints {
+		result.EnableChaosEndpoints = c2.EnableChaosEndpoints
+	}
+
 	result.PluginDirectory = c.PluginDirectory
 	if c2.PluginDirectory != "" {
 		result.PluginDirectory = c2.PluginDirectory
@@ -308,6 +433,111 @@ func (c *Config) Merge(c2 *Config) *Config {
 		result.PidFile = c2.PidFile
 	}
 
+	result.LogLevel = c.LogLevel
+	if c2.LogLevel != "" {
+		result.LogLevel = c2.LogLevel
+	}
+
+	return result
+}
+
+// sanitizedListenerKeys are the listener options reported by Sanitized
+var sanitizedListenerKeys = []string{
+	"address",
+	"cluster_address",
+	"endpoint",
+	"infrastructure",
+	"node_id",
+	"proxy_protocol_behavior",
+	"proxy_protocol_authorized_addrs",
+	"tls_disable",
+	"tls_cert_file",
+	"tls_key_file",
+	"tls_min_version",
+	"tls_cipher_suites",
+	"tls_prefer_server_cipher_suites",
+	"tls_require_and_verify_client_cert",
+	"tls_disable_client_certs",
+	"tls_client_ca_file",
+	"socket_mode",
+	"socket_user",
+	"socket_group",
+	"peer_credentials",
+}
+
+// Sanitized returns the configuration without the settings which may hold
+// secrets, such as the configuration of the storage and seal backends and
+// the credentials of the telemetry sinks
+func (c *Config) Sanitized() map[string]interface{} {
+	result := map[string]interface{}{
+		"cache_size":             c.CacheSize,
+		"disable_cache":          c.DisableCache,
+		"disable_mlock":          c.DisableMlock,
+		"ui":                     c.EnableUI,
+		"max_lease_ttl":          int64(c.MaxLeaseTTL.Seconds()),
+		"default_lease_ttl":      int64(c.DefaultLeaseTTL.Seconds()),
+		"token_tidy_interval":    int64(c.TokenTidyInterval.Seconds()),
+		"lease_restore_workers":  c.LeaseRestoreWorkers,
+		"cluster_name":           c.ClusterName,
+		"cluster_cipher_suites":  c.ClusterCipherSuites,
+		"plugin_directory":       c.PluginDirectory,
+		"pid_file":               c.PidFile,
+		"log_level":              c.LogLevel,
+		"raw_storage_endpoint":   c.EnableRawEndpoint,
+		"enable_chaos_endpoints": c.EnableChaosEndpoints,
+	}
+
+	// Only the listener options known not to be secrets are reported
+	var listeners []interface{}
+	for _, l := range c.Listeners {
+		config := make(map[string]interface{})
+		for _, key := range sanitizedListenerKeys {
+			if v, ok := l.Config[key]; ok {
+				config[key] = v
+			}
+		}
+		listeners = append(listeners, map[string]interface{}{
+			"type":   l.Type,
+			"config": config,
+		})
+	}
+	result["listeners"] = listeners
+
+	if c.Storage != nil {
+		result["storage"] = map[string]interface{}{
+			"type":          c.Storage.Type,
+			"redirect_addr": c.Storage.RedirectAddr,
+			"cluster_addr":  c.Storage.ClusterAddr,
+		}
+	}
+	if c.HAStorage != nil {
+		result["ha_storage"] = map[string]interface{}{
+			"type":          c.HAStorage.Type,
+			"redirect_addr": c.HAStorage.RedirectAddr,
+			"cluster_addr":  c.HAStorage.ClusterAddr,
+		}
+	}
+	if c.Seal != nil {
+		result["seal"] = map[string]interface{}{
+			"type": c.Seal.Type,
+		}
+	}
+
+	if c.Telemetry != nil {
+		result["telemetry"] = map[string]interface{}{
+			"statsite_address":             c.Telemetry.StatsiteAddr,
+			"statsd_address":               c.Telemetry.StatsdAddr,
+			"disable_hostname":             c.Telemetry.DisableHostname,
+			"circonus_api_app":             c.Telemetry.CirconusAPIApp,
+			"circonus_api_url":             c.Telemetry.CirconusAPIURL,
+			"circonus_submission_interval": c.Telemetry.CirconusSubmissionInterval,
+			"circonus_submission_url":      c.Telemetry.CirconusCheckSubmissionURL,
+			"circonus_check_id":            c.Telemetry.CirconusCheckID,
+			"dogstatsd_addr":               c.Telemetry.DogStatsDAddr,
+			"dogstatsd_tags":               c.Telemetry.DogStatsDTags,
+		}
+	}
+
 	return result
 }
 
@@ -358,6 +588,14 @@ func ParseConfig(d string, logger log.Logger) (*Config, error) {
 			return nil, err
 		}
 	}
+	if result.TokenTidyIntervalRaw != nil {
+		if result.TokenTidyInterval, err = parseutil.ParseDurationSecond(result.TokenTidyIntervalRaw); err != nil {
+			return nil, err
+		}
+	}
+	if result.LeaseRestoreWorkers < 0 {
+		return nil, fmt.Errorf("lease_restore_workers cannot be negative")
+	}
 
 	if result.EnableUIRaw != nil {
 		if result.EnableUI, err = parseutil.ParseBool(result.EnableUIRaw); err != nil {
@@ -383,6 +621,12 @@ func ParseConfig(d string, logger log.Logger) (*Config, error) {
 		}
 	}
 
+	if result.EnableChaosEndpointsRaw != nil {
+		if result.EnableChaosEndpoints, err = parseutil.ParseBool(result.EnableChaosEndpointsRaw); err != nil {
+			return nil, err
+		}
+	}
+
 	list, ok := obj.Node.(*ast.ObjectList)
 	if !ok {
 		return nil, fmt.Errorf("error parsing: file doesn't contain a root object")
@@ -394,19 +638,27 @@ func ParseConfig(d string, logger log.Logger) (*Config, error) {
 		"backend",
 		"ha_backend",
 		"hsm",
+		"seal",
 		"listener",
 		"cache_size",
 		"disable_cache",
 		"disable_mlock",
 		"ui",
 		"telemetry",
+		"load_shedding",
+		"request_forwarding",
 		"default_lease_ttl",
 		"max_lease_ttl",
+		"token_tidy_interval",
+		"lease_restore_workers",
 		"cluster_name",
 		"cluster_cipher_suites",
 		"plugin_directory",
+		"plugin_restart",
 		"pid_file",
+		"log_level",
 		"raw_storage_endpoint",
+		"enable_chaos_endpoints",
 	}
 	if err := checkHCLKeys(list, valid); err != nil {
 		return nil, err
@@ -443,6 +695,12 @@ func ParseConfig(d string, logger log.Logger) (*Config, error) {
 		}
 	}
 
+	if o := list.Filter("seal"); len(o.Items) > 0 {
+		if err := parseSeal(&result, o); err != nil {
+			return nil, fmt.Errorf("error parsing 'seal': %s", err)
+		}
+	}
+
 	if o := list.Filter("listener"); len(o.Items) > 0 {
 		if err := parseListeners(&result, o); err != nil {
 			return nil, fmt.Errorf("error parsing 'listener': %s", err)
@@ -455,6 +713,24 @@ func ParseConfig(d string, logger log.Logger) (*Config, error) {
 		}
 	}
 
+	if o := list.Filter("load_shedding"); len(o.Items) > 0 {
+		if err := parseLoadShedding(&result, o); err != nil {
+			return nil, fmt.Errorf("error parsing 'load_shedding': %s", err)
+		}
+	}
+
+	if o := list.Filter("request_forwarding"); len(o.Items) > 0 {
+		if err := parseRequestForwarding(&result, o); err != nil {
+			return nil, fmt.Errorf("error parsing 'request_forwarding': %s", err)
+		}
+	}
+
+	if o := list.Filter("plugin_restart"); len(o.Items) > 0 {
+		if err := parsePluginRestart(&result, o); err != nil {
+			return nil, fmt.Errorf("error parsing 'plugin_restart': %s", err)
+		}
+	}
+
 	return &result, nil
 }
 
@@ -580,12 +856,65 @@ func parseStorage(result *Config, list *ast.ObjectList, name string) error {
 		delete(m, "disable_clustering")
 	}
 
+	// Pull out the cache parameters, which configure the cache layer
+	// wrapping the backend rather than the backend itself
+	var cacheSize int
+	if v, ok := m["cache_size"]; ok {
+		cacheSize, err = strconv.Atoi(v)
+		if err != nil {
+			return multierror.Prefix(err, fmt.Sprintf("%s.%s:", name, key))
+		}
+		if cacheSize < 0 {
+			return fmt.Errorf("%s.%s: cache_size cannot be negative", name, key)
+		}
+		delete(m, "cache_size")
+	}
+
+	cacheType := strings.ToLower(m["cache_type"])
+	delete(m, "cache_type")
+
+	var cacheTTL time.Duration
+	if v, ok := m["cache_ttl"]; ok {
+		cacheTTL, err = parseutil.ParseDurationSecond(v)
+		if err != nil {
+			return multierror.Prefix(err, fmt.Sprintf("%s.%s:", name, key))
+		}
+		delete(m, "cache_ttl")
+	}
+
+	var cacheBypassPrefixes []string
+	if v, ok := m["cache_bypass_prefixes"]; ok {
+		for _, prefix := range strings.Split(v, ",") {
+			if prefix = strings.TrimSpace(prefix); prefix != "" {
+				cacheBypassPrefixes = append(cacheBypassPrefixes, prefix)
+			}
+		}
+		delete(m, "cache_bypass_prefixes")
+	}
+
+	var maxEntrySize int
+	if v, ok := m["max_entry_size"]; ok {
+		maxEntrySize, err = strconv.Atoi(v)
+		if err != nil {
+			return multierror.Prefix(err, fmt.Sprintf("%s.%s:", name, key))
+		}
+		if maxEntrySize < 0 {
+			return fmt.Errorf("%s.%s: max_entry_size cannot be negative", name, key)
+		}
+		delete(m, "max_entry_size")
+	}
+
 	result.Storage = &Storage{
-		RedirectAddr:      redirectAddr,
-		ClusterAddr:       clusterAddr,
-		DisableClustering: disableClustering,
-		Type:              strings.ToLower(key),
-		Config:            m,
+		RedirectAddr:        redirectAddr,
+		ClusterAddr:         clusterAddr,
+		DisableClustering:   disableClustering,
+		Type:                strings.ToLower(key),
+		Config:              m,
+		CacheSize:           cacheSize,
+		CacheType:           cacheType,
+		CacheTTL:            cacheTTL,
+		CacheBypassPrefixes: cacheBypassPrefixes,
+		MaxEntrySize:        maxEntrySize,
 	}
 	return nil
 }
@@ -684,7 +1013,91 @@ func parseHSMs(result *Config, list *ast.ObjectList) error {
 	return nil
 }
 
+func parseSeal(result *Config, list *ast.ObjectList) error {
+	if len(list.Items) > 1 {
+		return fmt.Errorf("only one 'seal' block is permitted")
+	}
+
+	// Get our item
+	item := list.Items[0]
+
+	if len(item.Keys) == 0 {
+		return fmt.Errorf("seal type must be specified")
+	}
+	key := item.Keys[0].Token.Value().(string)
+
+	var valid []string
+	switch strings.ToLower(key) {
+	case "awskms":
+		valid = []string{
+			"region",
+			"access_key",
+			"secret_key",
+			"session_token",
+			"kms_key_id",
+			"endpoint",
+		}
+	case "gcpckms":
+		valid = []string{
+			"credentials",
+			"project",
+			"region",
+			"key_ring",
+			"crypto_key",
+			"endpoint",
+		}
+	case "azurekeyvault":
+		valid = []string{
+			"tenant_id",
+			"client_id",
+			"client_secret",
+			"environment",
+			"vault_name",
+			"key_name",
+		}
+	default:
+		return fmt.Errorf("invalid seal type %q", key)
+	}
+	valid = append(valid, "disabled")
+	if err := checkHCLKeys(item.Val, valid); err != nil {
+		return multierror.Prefix(err, fmt.Sprintf("seal.%s:", key))
+	}
+
+	var m map[string]string
+	if err := hcl.DecodeObject(&m, item.Val); err != nil {
+		return multierror.Prefix(err, fmt.Sprintf("seal.%s:", key))
+	}
+
+	var disabled bool
+	if raw, ok := m["disabled"]; ok {
+		var err error
+		if disabled, err = strconv.ParseBool(raw); err != nil {
+			return multierror.Prefix(fmt.Errorf("invalid value for 'disabled': %v", err), fmt.Sprintf("seal.%s:", key))
+		}
+		delete(m, "disabled")
+	}
+
+	result.Seal = &Seal{
+		Type:     strings.ToLower(key),
+		Config:   m,
+		Disabled: disabled,
+	}
+
+	return nil
+}
+
 func parseListeners(result *Config, list *ast.ObjectList) error {
+	listeners, err := ParseListeners(list)
+	if err != nil {
+		return err
+	}
+	result.Listeners = listeners
+	return nil
+}
+
+// ParseListeners parses listener blocks, which are also used outside of the
+// server configuration
+func ParseListeners(list *ast.ObjectList) ([]*Listener, error) {
 	listeners := make([]*Listener, 0, len(list.Items))
 	for _, item := range list.Items {
 		key := "listener"
@@ -710,14 +1123,18 @@ func parseListeners(result *Config, list *ast.ObjectList) error {
 			"tls_disable_client_certs",
 			"tls_client_ca_file",
 			"token",
+			"socket_mode",
+			"socket_user",
+			"socket_group",
+			"peer_credentials",
 		}
 		if err := checkHCLKeys(item.Val, valid); err != nil {
-			return multierror.Prefix(err, fmt.Sprintf("listeners.%s:", key))
+			return nil, multierror.Prefix(err, fmt.Sprintf("listeners.%s:", key))
 		}
 
 		var m map[string]interface{}
 		if err := hcl.DecodeObject(&m, item.Val); err != nil {
-			return multierror.Prefix(err, fmt.Sprintf("listeners.%s:", key))
+			return nil, multierror.Prefix(err, fmt.Sprintf("listeners.%s:", key))
 		}
 
 		lnType := strings.ToLower(key)
@@ -728,8 +1145,7 @@ func parseListeners(result *Config, list *ast.ObjectList) error {
 		})
 	}
 
-	result.Listeners = listeners
-	return nil
+	return listeners, nil
 }
 
 func parseTelemetry(result *Config, list *ast.ObjectList) error {
@@ -780,6 +1196,151 @@ func parseTelemetry(result *Config, list *ast.ObjectList) error {
 	return nil
 }
 
+func parseLoadShedding(result *Config, list *ast.ObjectList) error {
+	if len(list.Items) > 1 {
+		return fmt.Errorf("only one 'load_shedding' block is permitted")
+	}
+
+	// Get our one item
+	item := list.Items[0]
+
+	valid := []string{
+		"max_in_flight_requests",
+		"max_storage_latency",
+		"retry_after",
+	}
+	if err := checkHCLKeys(item.Val, valid); err != nil {
+		return multierror.Prefix(err, "load_shedding:")
+	}
+
+	var l LoadShedding
+	if err := hcl.DecodeObject(&l, item.Val); err != nil {
+		return multierror.Prefix(err, "load_shedding:")
+	}
+
+	var err error
+	if l.MaxStorageLatencyRaw != nil {
+		if l.MaxStorageLatency, err = parseutil.ParseDurationSecond(l.MaxStorageLatencyRaw); err != nil {
+			return multierror.Prefix(err, "load_shedding:")
+		}
+		l.MaxStorageLatencyRaw = nil
+	}
+	if l.RetryAfterRaw != nil {
+		if l.RetryAfter, err = parseutil.ParseDurationSecond(l.RetryAfterRaw); err != nil {
+			return multierror.Prefix(err, "load_shedding:")
+		}
+		l.RetryAfterRaw = nil
+	}
+
+	if l.MaxInFlightRequests < 0 {
+		return fmt.Errorf("load_shedding: max_in_flight_requests cannot be negative")
+	}
+
+	result.LoadShedding = &l
+	return nil
+}
+
+func parseRequestForwarding(result *Config, list *ast.ObjectList) error {
+	if len(list.Items) > 1 {
+		return fmt.Errorf("only one 'request_forwarding' block is permitted")
+	}
+
+	// Get our one item
+	item := list.Items[0]
+
+	valid := []string{
+		"pool_size",
+		"max_in_flight",
+		"queue_timeout",
+	}
+	if err := checkHCLKeys(item.Val, valid); err != nil {
+		return multierror.Prefix(err, "request_forwarding:")
+	}
+
+	var r RequestForwarding
+	if err := hcl.DecodeObject(&r, item.Val); err != nil {
+		return multierror.Prefix(err, "request_forwarding:")
+	}
+
+	if r.QueueTimeoutRaw != nil {
+		var err error
+		if r.QueueTimeout, err = parseutil.ParseDurationSecond(r.QueueTimeoutRaw); err != nil {
+			return multierror.Prefix(err, "request_forwarding:")
+		}
+		r.QueueTimeoutRaw = nil
+	}
+
+	switch {
+	case r.PoolSize < 0:
+		return fmt.Errorf("request_forwarding: pool_size cannot be negative")
+	case r.MaxInFlight < 0:
+		return fmt.Errorf("request_forwarding: max_in_flight cannot be negative")
+	case r.QueueTimeout < 0:
+		return fmt.Errorf("request_forwarding: queue_timeout cannot be negative")
+	}
+
+	result.RequestForwarding = &r
+	return nil
+}
+
+func parsePluginRestart(result *Config, list *ast.ObjectList) error {
+	if len(list.Items) > 1 {
+		return fmt.Errorf("only one 'plugin_restart' block is permitted")
+	}
+
+	// Get our one item
+	item := list.Items[0]
+
+	valid := []string{
+		"check_interval",
+		"initial_backoff",
+		"max_backoff",
+		"max_attempts",
+	}
+	if err := checkHCLKeys(item.Val, valid); err != nil {
+		return multierror.Prefix(err, "plugin_restart:")
+	}
+
+	var p PluginRestart
+	if err := hcl.DecodeObject(&p, item.Val); err != nil {
+		return multierror.Prefix(err, "plugin_restart:")
+	}
+
+	var err error
+	if p.CheckIntervalRaw != nil {
+		if p.CheckInterval, err = parseutil.ParseDurationSecond(p.CheckIntervalRaw); err != nil {
+			return multierror.Prefix(err, "plugin_restart:")
+		}
+		p.CheckIntervalRaw = nil
+	}
+	if p.InitialBackoffRaw != nil {
+		if p.InitialBackoff, err = parseutil.ParseDurationSecond(p.InitialBackoffRaw); err != nil {
+			return multierror.Prefix(err, "plugin_restart:")
+		}
+		p.InitialBackoffRaw = nil
+	}
+	if p.MaxBackoffRaw != nil {
+		if p.MaxBackoff, err = parseutil.ParseDurationSecond(p.MaxBackoffRaw); err != nil {
+			return multierror.Prefix(err, "plugin_restart:")
+		}
+		p.MaxBackoffRaw = nil
+	}
+
+	switch {
+	case p.CheckInterval < 0:
+		return fmt.Errorf("plugin_restart: check_interval cannot be negative")
+	case p.InitialBackoff < 0:
+		return fmt.Errorf("plugin_restart: initial_backoff cannot be negative")
+	case p.MaxBackoff < 0:
+		return fmt.Errorf("plugin_restart: max_backoff cannot be negative")
+	case p.MaxAttempts < 0:
+		return fmt.Errorf("plugin_restart: max_attempts cannot be negative")
+	}
+
+	result.PluginRestart = &p
+	return nil
+}
+
 func checkHCLKeys(node ast.Node, valid []string) error {
 	var list *ast.ObjectList
 	switch n := node.(type) {
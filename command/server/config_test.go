@@ -71,6 +71,10 @@ func TestLoadConfigFile(t *testing.T) {
 		DefaultLeaseTTLRaw: "10h",
 		ClusterName:        "testcluster",
 
+		TokenTidyInterval:    24 * time.Hour,
+		TokenTidyIntervalRaw: "24h",
+		LeaseRestoreWorkers:  16,
+
 		PidFile: "./pidfile",
 	}
 	if !reflect.DeepEqual(config, expected) {
@@ -380,3 +384,310 @@ telemetry {
 		t.Errorf("bad error: %q", err)
 	}
 }
+
+func TestParseConfig_loadShedding(t *testing.T) {
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+
+	config, err := ParseConfig(strings.TrimSpace(`
+load_shedding {
+	max_in_flight_requests = 256
+	max_storage_latency = "250ms"
+	retry_after = 10
+}
+`), logger)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := &LoadShedding{
+		MaxInFlightRequests: 256,
+		MaxStorageLatency:   250 * time.Millisecond,
+		RetryAfter:          10 * time.Second,
+	}
+	if !reflect.DeepEqual(config.LoadShedding, expected) {
+		t.Fatalf("expected \n\n%#v\n\n to be \n\n%#v\n\n", config.LoadShedding, expected)
+	}
+
+	_, err = ParseConfig(strings.TrimSpace(`
+load_shedding {
+	max_in_flight = 256
+}
+`), logger)
+	if err == nil || !strings.Contains(err.Error(), "invalid key 'max_in_flight'") {
+		t.Fatalf("bad error: %v", err)
+	}
+}
+
+func TestParseConfig_storageParameters(t *testing.T) {
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+
+	config, err := ParseConfig(strings.TrimSpace(`
+storage "consul" {
+	foo = "bar"
+	cache_size = 1024
+	cache_type = "LRU"
+	cache_ttl = "5m"
+	cache_bypass_prefixes = "sys/expire/, sys/token/"
+	max_entry_size = 524288
+}
+`), logger)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := &Storage{
+		Type: "consul",
+		Config: map[string]string{
+			"foo": "bar",
+		},
+		CacheSize:           1024,
+		CacheType:           "lru",
+		CacheTTL:            5 * time.Minute,
+		CacheBypassPrefixes: []string{"sys/expire/", "sys/token/"},
+		MaxEntrySize:        524288,
+	}
+	if !reflect.DeepEqual(config.Storage, expected) {
+		t.Fatalf("expected \n\n%#v\n\n to be \n\n%#v\n\n", config.Storage, expected)
+	}
+
+	_, err = ParseConfig(strings.TrimSpace(`
+storage "consul" {
+	cache_size = -1
+}
+`), logger)
+	if err == nil || !strings.Contains(err.Error(), "cache_size cannot be negative") {
+		t.Fatalf("bad error: %v", err)
+	}
+}
+
+func TestParseConfig_requestForwarding(t *testing.T) {
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+
+	config, err := ParseConfig(strings.TrimSpace(`
+request_forwarding {
+	pool_size = 8
+	max_in_flight = 512
+	queue_timeout = "2s"
+}
+`), logger)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := &RequestForwarding{
+		PoolSize:     8,
+		MaxInFlight:  512,
+		QueueTimeout: 2 * time.Second,
+	}
+	if !reflect.DeepEqual(config.RequestForwarding, expected) {
+		t.Fatalf("expected \n\n%#v\n\n to be \n\n%#v\n\n", config.RequestForwarding, expected)
+	}
+
+	_, err = ParseConfig(strings.TrimSpace(`
+request_forwarding {
+	pool_size = -1
+}
+`), logger)
+	if err == nil || !strings.Contains(err.Error(), "pool_size cannot be negative") {
+		t.Fatalf("bad error: %v", err)
+	}
+}
+
+func TestParseConfig_pluginRestart(t *testing.T) {
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+
+	config, err := ParseConfig(strings.TrimSpace(`
+plugin_restart {
+	check_interval = "5s"
+	initial_backoff = "500ms"
+	max_backoff = "30s"
+	max_attempts = 3
+}
+`), logger)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := &PluginRestart{
+		CheckInterval:  5 * time.Second,
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     30 * time.Second,
+		MaxAttempts:    3,
+	}
+	if !reflect.DeepEqual(config.PluginRestart, expected) {
+		t.Fatalf("expected \n\n%#v\n\n to be \n\n%#v\n\n", config.PluginRestart, expected)
+	}
+
+	_, err = ParseConfig(strings.TrimSpace(`
+plugin_restart {
+	max_attempts = -1
+}
+`), logger)
+	if err == nil || !strings.Contains(err.Error(), "max_attempts cannot be negative") {
+		t.Fatalf("bad error: %v", err)
+	}
+}
+
+func TestParseConfig_chaosEndpoints(t *testing.T) {
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+
+	config, err := ParseConfig(`enable_chaos_endpoints = "true"`, logger)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !config.EnableChaosEndpoints {
+		t.Fatal("expected chaos endpoints to be enabled")
+	}
+
+	config, err = ParseConfig(`disable_mlock = true`, logger)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if config.EnableChaosEndpoints {
+		t.Fatal("expected chaos endpoints to be disabled by default")
+	}
+}
+
+func TestParseConfig_seal(t *testing.T) {
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+
+	config, err := ParseConfig(strings.TrimSpace(`
+seal "awskms" {
+	region = "us-east-1"
+	kms_key_id = "alias/vault"
+}
+`), logger)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := &Seal{
+		Type: "awskms",
+		Config: map[string]string{
+			"region":     "us-east-1",
+			"kms_key_id": "alias/vault",
+		},
+	}
+	if !reflect.DeepEqual(config.Seal, expected) {
+		t.Fatalf("expected \n\n%#v\n\n to be \n\n%#v\n\n", config.Seal, expected)
+	}
+
+	config, err = ParseConfig(`seal "gcpckms" { disabled = "true" }`, logger)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expected = &Seal{
+		Type:     "gcpckms",
+		Config:   map[string]string{},
+		Disabled: true,
+	}
+	if !reflect.DeepEqual(config.Seal, expected) {
+		t.Fatalf("expected \n\n%#v\n\n to be \n\n%#v\n\n", config.Seal, expected)
+	}
+
+	for input, expectedErr := range map[string]string{
+		`seal "awskms" { key_ring = "vault" }`:   "invalid key 'key_ring'",
+		`seal "awskms" { disabled = "maybe" }`:   "invalid value for 'disabled'",
+		`seal "pkcs11" { lib = "/usr/lib/p11" }`: `invalid seal type "pkcs11"`,
+		`seal { kms_key_id = "alias/vault" }`:    "seal type must be specified",
+	} {
+		_, err := ParseConfig(input, logger)
+		if err == nil || !strings.Contains(err.Error(), expectedErr) {
+			t.Fatalf("bad error for %s: %v", input, err)
+		}
+	}
+}
+
+func TestConfig_Sanitized(t *testing.T) {
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+
+	config, err := ParseConfig(strings.TrimSpace(`
+log_level = "debug"
+
+storage "consul" {
+	address = "127.0.0.1:8500"
+	token = "secret"
+	redirect_addr = "https://vault.example.com"
+}
+
+listener "tcp" {
+	address = "127.0.0.1:8200"
+	token = "secret"
+}
+
+telemetry {
+	statsd_address = "127.0.0.1:8125"
+	circonus_api_token = "secret"
+}
+`), logger)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if config.LogLevel != "debug" {
+		t.Fatalf("bad: %#v", config)
+	}
+
+	state := config.Sanitized()
+	if state["log_level"] != "debug" {
+		t.Fatalf("bad: %#v", state)
+	}
+	expectedStorage := map[string]interface{}{
+		"type":          "consul",
+		"redirect_addr": "https://vault.example.com",
+		"cluster_addr":  "",
+	}
+	if !reflect.DeepEqual(state["storage"], expectedStorage) {
+		t.Fatalf("bad: %#v", state["storage"])
+	}
+	telemetry := state["telemetry"].(map[string]interface{})
+	if telemetry["statsd_address"] != "127.0.0.1:8125" {
+		t.Fatalf("bad: %#v", telemetry)
+	}
+	if _, ok := telemetry["circonus_api_token"]; ok {
+		t.Fatalf("bad: %#v", telemetry)
+	}
+	expectedListeners := []interface{}{
+		map[string]interface{}{
+			"type": "tcp",
+			"config": map[string]interface{}{
+				"address": "127.0.0.1:8200",
+			},
+		},
+	}
+	if !reflect.DeepEqual(state["listeners"], expectedListeners) {
+		t.Fatalf("bad: %#v", state["listeners"])
+	}
+}
+
+func TestParseConfig_unixListener(t *testing.T) {
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+
+	config, err := ParseConfig(strings.TrimSpace(`
+listener "unix" {
+	address = "/run/vault.sock"
+	socket_mode = "0660"
+	socket_user = "vault"
+	socket_group = "vault"
+	peer_credentials = true
+}
+`), logger)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := []*Listener{
+		{
+			Type: "unix",
+			Config: map[string]interface{}{
+				"address":          "/run/vault.sock",
+				"socket_mode":      "0660",
+				"socket_user":      "vault",
+				"socket_group":     "vault",
+				"peer_credentials": true,
+			},
+		},
+	}
+	if !reflect.DeepEqual(config.Listeners, expected) {
+		t.Fatalf("expected \n\n%#v\n\n to be \n\n%#v\n\n", config.Listeners, expected)
+	}
+}
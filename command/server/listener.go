@@ -10,6 +10,7 @@ import (
 	"io"
 	"io/ioutil"
 	"net"
+	"sync"
 
 	"github.com/hashicorp/vault/helper/parseutil"
 	"github.com/hashicorp/vault/helper/proxyutil"
@@ -22,7 +23,8 @@ type ListenerFactory func(map[string]interface{}, io.Writer) (net.Listener, map[
 
 // BuiltinListeners is the list of built-in listener types.
 var BuiltinListeners = map[string]ListenerFactory{
-	"tcp": tcpListenerFactory,
+	"tcp":  tcpListenerFactory,
+	"unix": unixListenerFactory,
 }
 
 // NewListener creates a new listener of the given type with the given
@@ -73,30 +75,105 @@ func listenerWrapTLS(
 	config map[string]interface{}) (net.Listener, map[string]string, reload.ReloadFunc, error) {
 	props["tls"] = "disabled"
 
-	if v, ok := config["tls_disable"]; ok {
-		disabled, err := parseutil.ParseBool(v)
-		if err != nil {
-			return nil, nil, nil, fmt.Errorf("invalid value for 'tls_disable': %v", err)
-		}
-		if disabled {
-			return ln, props, nil, nil
-		}
+	disabled, err := listenerTLSDisabled(config)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if disabled {
+		return ln, props, nil, nil
+	}
+
+	reloader := &listenerTLSReloader{}
+	if err := reloader.Reload(config); err != nil {
+		return nil, nil, nil, err
+	}
+
+	ln = tls.NewListener(ln, &tls.Config{
+		GetConfigForClient: reloader.GetConfigForClient,
+	})
+	props["tls"] = "enabled"
+	return ln, props, reloader.Reload, nil
+}
+
+// listenerTLSReloader holds the TLS configuration of a listener. It is
+// rebuilt from the listener configuration on reload, so that the new
+// connections use the new certificate, client CAs and cipher suites while the
+// established ones are left alone.
+type listenerTLSReloader struct {
+	sync.RWMutex
+
+	config  map[string]interface{}
+	tlsConf *tls.Config
+}
+
+// Reload rebuilds the TLS configuration from the given listener
+// configuration, or from the current one if it is nil so that the files it
+// refers to are read again. The current TLS configuration is kept if the new
+// one is invalid.
+func (r *listenerTLSReloader) Reload(config map[string]interface{}) error {
+	r.Lock()
+	defer r.Unlock()
+
+	if config == nil {
+		config = r.config
+	}
+
+	disabled, err := listenerTLSDisabled(config)
+	if err != nil {
+		return err
 	}
+	if disabled {
+		return fmt.Errorf("'tls_disable' can't be changed without restarting")
+	}
+
+	tlsConf, err := listenerTLSConfig(config)
+	if err != nil {
+		return err
+	}
+
+	r.config = config
+	r.tlsConf = tlsConf
+	return nil
+}
+
+// GetConfigForClient satisfies the tls.Config GetConfigForClient function
+// signature
+func (r *listenerTLSReloader) GetConfigForClient(*tls.ClientHelloInfo) (*tls.Config, error) {
+	r.RLock()
+	defer r.RUnlock()
 
+	return r.tlsConf, nil
+}
+
+func listenerTLSDisabled(config map[string]interface{}) (bool, error) {
+	v, ok := config["tls_disable"]
+	if !ok {
+		return false, nil
+	}
+
+	disabled, err := parseutil.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("invalid value for 'tls_disable': %v", err)
+	}
+	return disabled, nil
+}
+
+// listenerTLSConfig builds the TLS configuration of a listener, loading its
+// certificate and client CAs
+func listenerTLSConfig(config map[string]interface{}) (*tls.Config, error) {
 	_, ok := config["tls_cert_file"]
 	if !ok {
-		return nil, nil, nil, fmt.Errorf("'tls_cert_file' must be set")
+		return nil, fmt.Errorf("'tls_cert_file' must be set")
 	}
 
 	_, ok = config["tls_key_file"]
 	if !ok {
-		return nil, nil, nil, fmt.Errorf("'tls_key_file' must be set")
+		return nil, fmt.Errorf("'tls_key_file' must be set")
 	}
 
-	cg := reload.NewCertificateGetter(config["tls_cert_file"].(string), config["tls_key_file"].(string))
-
-	if err := cg.Reload(config); err != nil {
-		return nil, nil, nil, fmt.Errorf("error loading TLS cert: %s", err)
+	cert, err := tls.LoadX509KeyPair(config["tls_cert_file"].(string), config["tls_key_file"].(string))
+	if err != nil {
+		return nil, fmt.Errorf("error loading TLS cert: %s", err)
 	}
 
 	var tlsvers string
@@ -108,34 +185,33 @@ func listenerWrapTLS(
 	}
 
 	tlsConf := &tls.Config{}
-	tlsConf.GetCertificate = cg.GetCertificate
+	tlsConf.Certificates = []tls.Certificate{cert}
 	tlsConf.NextProtos = []string{"h2", "http/1.1"}
 	tlsConf.MinVersion, ok = tlsutil.TLSLookup[tlsvers]
 	if !ok {
-		return nil, nil, nil, fmt.Errorf("'tls_min_version' value %s not supported, please specify one of [tls10,tls11,tls12]", tlsvers)
+		return nil, fmt.Errorf("'tls_min_version' value %s not supported, please specify one of [tls10,tls11,tls12]", tlsvers)
 	}
 	tlsConf.ClientAuth = tls.RequestClientCert
 
 	if v, ok := config["tls_cipher_suites"]; ok {
 		ciphers, err := tlsutil.ParseCiphers(v.(string))
 		if err != nil {
-			return nil, nil, nil, fmt.Errorf("invalid value for 'tls_cipher_suites': %v", err)
+			return nil, fmt.Errorf("invalid value for 'tls_cipher_suites': %v", err)
 		}
 		tlsConf.CipherSuites = ciphers
 	}
 	if v, ok := config["tls_prefer_server_cipher_suites"]; ok {
 		preferServer, err := parseutil.ParseBool(v)
 		if err != nil {
-			return nil, nil, nil, fmt.Errorf("invalid value for 'tls_prefer_server_cipher_suites': %v", err)
+			return nil, fmt.Errorf("invalid value for 'tls_prefer_server_cipher_suites': %v", err)
 		}
 		tlsConf.PreferServerCipherSuites = preferServer
 	}
 	var requireVerifyCerts bool
-	var err error
 	if v, ok := config["tls_require_and_verify_client_cert"]; ok {
 		requireVerifyCerts, err = parseutil.ParseBool(v)
 		if err != nil {
-			return nil, nil, nil, fmt.Errorf("invalid value for 'tls_require_and_verify_client_cert': %v", err)
+			return nil, fmt.Errorf("invalid value for 'tls_require_and_verify_client_cert': %v", err)
 		}
 		if requireVerifyCerts {
 			tlsConf.ClientAuth = tls.RequireAndVerifyClientCert
@@ -144,11 +220,11 @@ func listenerWrapTLS(
 			caPool := x509.NewCertPool()
 			data, err := ioutil.ReadFile(tlsClientCaFile.(string))
 			if err != nil {
-				return nil, nil, nil, fmt.Errorf("failed to read tls_client_ca_file: %v", err)
+				return nil, fmt.Errorf("failed to read tls_client_ca_file: %v", err)
 			}
 
 			if !caPool.AppendCertsFromPEM(data) {
-				return nil, nil, nil, fmt.Errorf("failed to parse CA certificate in tls_client_ca_file")
+				return nil, fmt.Errorf("failed to parse CA certificate in tls_client_ca_file")
 			}
 			tlsConf.ClientCAs = caPool
 		}
@@ -156,15 +232,13 @@ func listenerWrapTLS(
 	if v, ok := config["tls_disable_client_certs"]; ok {
 		disableClientCerts, err := parseutil.ParseBool(v)
 		if err != nil {
-			return nil, nil, nil, fmt.Errorf("invalid value for 'tls_disable_client_certs': %v", err)
+			return nil, fmt.Errorf("invalid value for 'tls_disable_client_certs': %v", err)
 		}
 		if disableClientCerts && requireVerifyCerts {
-			return nil, nil, nil, fmt.Errorf("'tls_disable_client_certs' and 'tls_require_and_verify_client_cert' are mutually exclusive")
+			return nil, fmt.Errorf("'tls_disable_client_certs' and 'tls_require_and_verify_client_cert' are mutually exclusive")
 		}
 		tlsConf.ClientAuth = tls.NoClientCert
 	}
 
-	ln = tls.NewListener(ln, tlsConf)
-	props["tls"] = "enabled"
-	return ln, props, cg.Reload, nil
+	return tlsConf, nil
 }
@@ -111,3 +111,83 @@ func TestTCPListener_tls(t *testing.T) {
 
 	testListenerImpl(t, ln, connFn(false), "foo.example.com")
 }
+
+func TestTCPListener_tlsReload(t *testing.T) {
+	wd, _ := os.Getwd()
+	wd += "/test-fixtures/reload/"
+
+	inBytes, _ := ioutil.ReadFile(wd + "reload_ca.pem")
+	certPool := x509.NewCertPool()
+	if !certPool.AppendCertsFromPEM(inBytes) {
+		t.Fatal("not ok when appending CA cert")
+	}
+
+	ln, _, reloadFunc, err := tcpListenerFactory(map[string]interface{}{
+		"address":       "127.0.0.1:0",
+		"tls_cert_file": wd + "reload_foo.pem",
+		"tls_key_file":  wd + "reload_foo.key",
+	}, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer ln.Close()
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				conn.(*tls.Conn).Handshake()
+				conn.Close()
+			}()
+		}
+	}()
+
+	testCertificateName := func(cn string) {
+		conn, err := tls.Dial("tcp", ln.Addr().String(), &tls.Config{
+			RootCAs: certPool,
+		})
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		defer conn.Close()
+		if name := conn.ConnectionState().PeerCertificates[0].Subject.CommonName; name != cn {
+			t.Fatalf("expected %s, got %s", cn, name)
+		}
+	}
+
+	testCertificateName("foo.example.com")
+
+	// The new connections use the certificate of the new configuration
+	err = reloadFunc(map[string]interface{}{
+		"address":       "127.0.0.1:0",
+		"tls_cert_file": wd + "reload_bar.pem",
+		"tls_key_file":  wd + "reload_bar.key",
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	testCertificateName("bar.example.com")
+
+	// An invalid configuration is rejected and the current one is kept
+	err = reloadFunc(map[string]interface{}{
+		"address":       "127.0.0.1:0",
+		"tls_cert_file": wd + "missing.pem",
+		"tls_key_file":  wd + "reload_bar.key",
+	})
+	if err == nil {
+		t.Fatal("expected error loading missing certificate")
+	}
+	if err := reloadFunc(map[string]interface{}{"tls_disable": "true"}); err == nil {
+		t.Fatal("expected error disabling TLS on reload")
+	}
+	testCertificateName("bar.example.com")
+
+	// Reloading without a configuration reads the files again
+	if err := reloadFunc(nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	testCertificateName("bar.example.com")
+}
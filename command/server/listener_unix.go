@@ -0,0 +1,220 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+
+	"github.com/hashicorp/vault/helper/parseutil"
+	"github.com/hashicorp/vault/helper/reload"
+	"github.com/hashicorp/vault/logical"
+)
+
+func unixListenerFactory(config map[string]interface{}, _ io.Writer) (net.Listener, map[string]string, reload.ReloadFunc, error) {
+	addrRaw, ok := config["address"]
+	if !ok {
+		return nil, nil, nil, fmt.Errorf("'address' must be set to the path of the socket")
+	}
+	addr := addrRaw.(string)
+
+	var capturePeerCreds bool
+	if v, ok := config["peer_credentials"]; ok {
+		var err error
+		capturePeerCreds, err = parseutil.ParseBool(v)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("invalid value for 'peer_credentials': %v", err)
+		}
+		if capturePeerCreds && !peerCredentialsSupported {
+			return nil, nil, nil, fmt.Errorf("'peer_credentials' is not supported on this platform")
+		}
+	}
+
+	// Remove the socket left behind by a previous run, but nothing else
+	if info, err := os.Lstat(addr); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, nil, nil, fmt.Errorf("%q exists and is not a socket", addr)
+		}
+		if err := os.Remove(addr); err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to remove existing socket: %v", err)
+		}
+	}
+
+	// The socket is created in a directory only the server can access, and
+	// moved into place once its permissions are set, so that nobody can
+	// connect to it in between
+	dir, err := ioutil.TempDir(filepath.Dir(addr), ".vault-socket")
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to create the socket directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	tmpAddr := filepath.Join(dir, filepath.Base(addr))
+
+	ln, err := net.Listen("unix", tmpAddr)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	ln.(*net.UnixListener).SetUnlinkOnClose(false)
+
+	if err := setSocketPermissions(tmpAddr, config); err != nil {
+		ln.Close()
+		return nil, nil, nil, err
+	}
+	if err := os.Rename(tmpAddr, addr); err != nil {
+		ln.Close()
+		return nil, nil, nil, fmt.Errorf("failed to move the socket into place: %v", err)
+	}
+	ln = &unixListener{
+		Listener: ln,
+		addr:     &net.UnixAddr{Name: addr, Net: "unix"},
+	}
+
+	props := map[string]string{"addr": addr}
+	if capturePeerCreds {
+		ln = &peerCredentialsListener{Listener: ln}
+		props["peer_credentials"] = "enabled"
+	}
+
+	return listenerWrapTLS(ln, props, config)
+}
+
+// setSocketPermissions sets the mode, the owner and the group of the socket
+// file, which control who can connect to it
+func setSocketPermissions(addr string, config map[string]interface{}) error {
+	if v, ok := config["socket_mode"]; ok {
+		mode, err := strconv.ParseUint(v.(string), 8, 32)
+		if err != nil {
+			return fmt.Errorf("invalid value for 'socket_mode': %v", err)
+		}
+		if err := os.Chmod(addr, os.FileMode(mode)); err != nil {
+			return fmt.Errorf("failed to set the mode of the socket: %v", err)
+		}
+	}
+
+	uid, gid := -1, -1
+	if v, ok := config["socket_user"]; ok {
+		id, err := lookupSocketID(v.(string), func(name string) (string, error) {
+			u, err := user.Lookup(name)
+			if err != nil {
+				return "", err
+			}
+			return u.Uid, nil
+		})
+		if err != nil {
+			return fmt.Errorf("invalid value for 'socket_user': %v", err)
+		}
+		uid = id
+	}
+	if v, ok := config["socket_group"]; ok {
+		id, err := lookupSocketID(v.(string), func(name string) (string, error) {
+			g, err := user.LookupGroup(name)
+			if err != nil {
+				return "", err
+			}
+			return g.Gid, nil
+		})
+		if err != nil {
+			return fmt.Errorf("invalid value for 'socket_group': %v", err)
+		}
+		gid = id
+	}
+	if uid != -1 || gid != -1 {
+		if err := os.Chown(addr, uid, gid); err != nil {
+			return fmt.Errorf("failed to set the owner of the socket: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// lookupSocketID returns the numeric ID given, or the ID of the user or group
+// of the given name
+func lookupSocketID(v string, lookup func(string) (string, error)) (int, error) {
+	if id, err := strconv.Atoi(v); err == nil {
+		return id, nil
+	}
+
+	idStr, err := lookup(v)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(idStr)
+}
+
+// unixListener is a listener on a socket which was moved into place after
+// being created. It removes the socket once closed.
+type unixListener struct {
+	net.Listener
+
+	addr *net.UnixAddr
+}
+
+func (ln *unixListener) Close() error {
+	err := ln.Listener.Close()
+	os.Remove(ln.addr.Name)
+	return err
+}
+
+func (ln *unixListener) Addr() net.Addr {
+	return ln.addr
+}
+
+// peerCredentialsListener captures the credentials of the processes which
+// connect to a unix socket. Its connections carry them to the HTTP handler.
+type peerCredentialsListener struct {
+	net.Listener
+}
+
+func (ln *peerCredentialsListener) Accept() (net.Conn, error) {
+	conn, err := ln.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	// The connection is served without credentials if they can't be read,
+	// so that the backends relying on them deny it
+	creds, _ := peerCredentials(conn)
+	return &peerCredentialsConn{
+		Conn:  conn,
+		creds: creds,
+	}, nil
+}
+
+type peerCredentialsConn struct {
+	net.Conn
+
+	creds *logical.PeerCredentials
+}
+
+// PeerCredentials returns the credentials of the process at the other end
+// of the connection
+func (c *peerCredentialsConn) PeerCredentials() *logical.PeerCredentials {
+	return c.creds
+}
+
+// LocalAddr returns the local address of the connection, which carries the
+// credentials of the peer to the HTTP handler
+func (c *peerCredentialsConn) LocalAddr() net.Addr {
+	return &peerCredentialsAddr{
+		Addr:  c.Conn.LocalAddr(),
+		creds: c.creds,
+	}
+}
+
+// peerCredentialsAddr is the local address of a connection of a listener
+// capturing peer credentials
+type peerCredentialsAddr struct {
+	net.Addr
+
+	creds *logical.PeerCredentials
+}
+
+// PeerCredentials returns the credentials of the process at the other end
+// of the connection
+func (a *peerCredentialsAddr) PeerCredentials() *logical.PeerCredentials {
+	return a.creds
+}
@@ -0,0 +1,43 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+const peerCredentialsSupported = true
+
+// peerCredentials reads the credentials of the peer of a unix socket with
+// SO_PEERCRED
+func peerCredentials(conn net.Conn) (*logical.PeerCredentials, error) {
+	unixConn, ok := conn.(*net.UnixConn)
+	if !ok {
+		return nil, fmt.Errorf("not a unix socket connection")
+	}
+
+	raw, err := unixConn.SyscallConn()
+	if err != nil {
+		return nil, err
+	}
+
+	var ucred *syscall.Ucred
+	var credErr error
+	err = raw.Control(func(fd uintptr) {
+		ucred, credErr = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if credErr != nil {
+		return nil, credErr
+	}
+
+	return &logical.PeerCredentials{
+		PID: ucred.Pid,
+		UID: ucred.Uid,
+		GID: ucred.Gid,
+	}, nil
+}
@@ -0,0 +1,16 @@
+// +build !linux
+
+package server
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+const peerCredentialsSupported = false
+
+func peerCredentials(conn net.Conn) (*logical.PeerCredentials, error) {
+	return nil, fmt.Errorf("peer credentials are not supported on this platform")
+}
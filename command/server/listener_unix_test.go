@@ -0,0 +1,128 @@
+package server
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestUnixListener(t *testing.T) {
+	td, err := ioutil.TempDir("", "vault-test-unix")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(td)
+	addr := filepath.Join(td, "vault.sock")
+
+	ln, props, _, err := unixListenerFactory(map[string]interface{}{
+		"address":     addr,
+		"socket_mode": "0600",
+		"tls_disable": "1",
+	}, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if props["addr"] != addr {
+		t.Fatalf("bad: %#v", props)
+	}
+
+	info, err := os.Stat(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Fatalf("bad: %v", info.Mode())
+	}
+
+	connFn := func(lnReal net.Listener) (net.Conn, error) {
+		return net.Dial("unix", addr)
+	}
+
+	testListenerImpl(t, ln, connFn, "")
+
+	// The socket is created in a temporary directory, which is removed once
+	// the socket is moved into place, and the socket is removed once closed
+	if ln.Addr().String() != addr {
+		t.Fatalf("bad: %s", ln.Addr())
+	}
+	if files, err := ioutil.ReadDir(td); err != nil || len(files) != 1 {
+		t.Fatalf("bad: %v, err: %v", files, err)
+	}
+	ln.Close()
+	if _, err := os.Lstat(addr); !os.IsNotExist(err) {
+		t.Fatalf("expected the socket to be removed; err: %v", err)
+	}
+
+	// The socket left behind is replaced, but not other files
+	ln, _, _, err = unixListenerFactory(map[string]interface{}{
+		"address":     addr,
+		"tls_disable": "1",
+	}, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	ln.Close()
+
+	file := filepath.Join(td, "file")
+	if err := ioutil.WriteFile(file, []byte("foo"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	_, _, _, err = unixListenerFactory(map[string]interface{}{
+		"address":     file,
+		"tls_disable": "1",
+	}, nil)
+	if err == nil {
+		t.Fatal("expected error replacing a file which is not a socket")
+	}
+}
+
+func TestUnixListener_peerCredentials(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("peer credentials are only supported on linux")
+	}
+
+	td, err := ioutil.TempDir("", "vault-test-unix")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(td)
+	addr := filepath.Join(td, "vault.sock")
+
+	ln, props, _, err := unixListenerFactory(map[string]interface{}{
+		"address":          addr,
+		"peer_credentials": "true",
+		"tls_disable":      "1",
+	}, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer ln.Close()
+	if props["peer_credentials"] != "enabled" {
+		t.Fatalf("bad: %#v", props)
+	}
+
+	client, err := net.Dial("unix", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// The credentials are handed to the HTTP handler with the local address
+	// of the connection
+	creds := conn.LocalAddr().(*peerCredentialsAddr).PeerCredentials()
+	if creds == nil {
+		t.Fatal("expected peer credentials")
+	}
+	if int(creds.PID) != os.Getpid() || int(creds.UID) != os.Getuid() || int(creds.GID) != os.Getgid() {
+		t.Fatalf("bad: %#v", creds)
+	}
+}
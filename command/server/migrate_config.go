@@ -0,0 +1,67 @@
+package server
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/hashicorp/hcl"
+	"github.com/hashicorp/hcl/hcl/ast"
+)
+
+// MigrationConfig is the configuration of a storage migration
+type MigrationConfig struct {
+	Source      *Storage
+	Destination *Storage
+}
+
+// LoadMigrationConfig loads the migration configuration from the given file
+func LoadMigrationConfig(path string) (*MigrationConfig, error) {
+	d, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseMigrationConfig(string(d))
+}
+
+// ParseMigrationConfig parses a migration configuration, which holds a
+// storage_source and a storage_destination block in the format of the
+// storage block of the server configuration
+func ParseMigrationConfig(d string) (*MigrationConfig, error) {
+	obj, err := hcl.Parse(d)
+	if err != nil {
+		return nil, err
+	}
+
+	list, ok := obj.Node.(*ast.ObjectList)
+	if !ok {
+		return nil, fmt.Errorf("error parsing: file doesn't contain a root object")
+	}
+
+	valid := []string{
+		"storage_source",
+		"storage_destination",
+	}
+	if err := checkHCLKeys(list, valid); err != nil {
+		return nil, err
+	}
+
+	var result MigrationConfig
+	for _, name := range valid {
+		o := list.Filter(name)
+		if len(o.Items) == 0 {
+			return nil, fmt.Errorf("missing '%s'", name)
+		}
+
+		var storage Config
+		if err := parseStorage(&storage, o, name); err != nil {
+			return nil, fmt.Errorf("error parsing '%s': %s", name, err)
+		}
+		if name == "storage_source" {
+			result.Source = storage.Storage
+		} else {
+			result.Destination = storage.Storage
+		}
+	}
+
+	return &result, nil
+}
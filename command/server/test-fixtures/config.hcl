@@ -27,6 +27,8 @@ telemetry {
 
 max_lease_ttl = "10h"
 default_lease_ttl = "10h"
+token_tidy_interval = "24h"
+lease_restore_workers = 16
 cluster_name = "testcluster"
 pid_file = "./pidfile"
 raw_storage_endpoint = true
@@ -0,0 +1,53 @@
+package command
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/vault/command/server"
+	"github.com/hashicorp/vault/vault"
+	"github.com/hashicorp/vault/vault/seal"
+	"github.com/hashicorp/vault/vault/seal/awskms"
+	"github.com/hashicorp/vault/vault/seal/azurekeyvault"
+	"github.com/hashicorp/vault/vault/seal/gcpckms"
+)
+
+// configurableSealAccess is a key management service configured from the
+// seal stanza of the server configuration
+type configurableSealAccess interface {
+	seal.Access
+	SetConfig(map[string]string) (map[string]string, error)
+}
+
+// newSeal creates the auto-unseal seal configured for the server, adding the
+// information about its configuration to the displayed info. A disabled seal
+// is still created, to migrate the master key away from it.
+func (c *ServerCommand) newSeal(config *server.Seal, info map[string]string, infoKeys *[]string) (vault.Seal, error) {
+	var access configurableSealAccess
+	switch config.Type {
+	case seal.AWSKMS:
+		access = awskms.NewSeal(c.logger)
+	case seal.GCPCKMS:
+		access = gcpckms.NewSeal(c.logger)
+	case seal.AzureKeyVault:
+		access = azurekeyvault.NewSeal(c.logger)
+	default:
+		return nil, fmt.Errorf("unknown seal type %q", config.Type)
+	}
+
+	sealInfo, err := access.SetConfig(config.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	info["seal type"] = config.Type
+	if config.Disabled {
+		info["seal type"] = fmt.Sprintf("%s, migrating from %s", seal.Shamir, config.Type)
+	}
+	*infoKeys = append(*infoKeys, "seal type")
+	for k, v := range sealInfo {
+		info[k] = v
+		*infoKeys = append(*infoKeys, k)
+	}
+
+	return vault.NewAutoSeal(access), nil
+}
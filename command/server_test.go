@@ -14,8 +14,11 @@ import (
 	"testing"
 	"time"
 
+	"github.com/hashicorp/vault/command/server"
+	"github.com/hashicorp/vault/helper/logformat"
 	"github.com/hashicorp/vault/meta"
 	"github.com/hashicorp/vault/physical"
+	log "github.com/mgutz/logxi/v1"
 	"github.com/mitchellh/cli"
 
 	physFile "github.com/hashicorp/vault/physical/file"
@@ -177,3 +180,114 @@ func TestServer_ReloadListener(t *testing.T) {
 
 	wg.Wait()
 }
+
+func TestServer_reloadConfig(t *testing.T) {
+	td, err := ioutil.TempDir("", "vault-test-reload-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(td)
+
+	configFile := td + "/config.hcl"
+	writeConfig := func(hcl string) {
+		if err := ioutil.WriteFile(configFile, []byte(hcl), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeConfig(`
+storage "inmem" {}
+`)
+	logger := logformat.NewVaultLogger(log.LevelInfo)
+	config, err := server.LoadConfig(configFile, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := &ServerCommand{
+		logger:   logger,
+		config:   config,
+		logLevel: "info",
+	}
+	if err := c.setupTelemetry(config); err != nil {
+		t.Fatal(err)
+	}
+
+	// The log level and the telemetry sinks of the new configuration are
+	// applied
+	writeConfig(`
+storage "inmem" {}
+log_level = "debug"
+telemetry {
+	statsd_address = "127.0.0.1:8125"
+}
+`)
+	if err := c.reloadConfig([]string{configFile}); err != nil {
+		t.Fatal(err)
+	}
+	if !c.logger.IsDebug() || c.logLevel != "debug" {
+		t.Fatalf("bad: %s", c.logLevel)
+	}
+	if len(c.metricsSink.sinks) != 1 || len(c.metricsSink.fanout) != 2 {
+		t.Fatalf("bad: %#v", c.metricsSink.sinks)
+	}
+	state := c.configState()
+	if state["log_level"] != "debug" || state["telemetry"].(map[string]interface{})["statsd_address"] != "127.0.0.1:8125" {
+		t.Fatalf("bad: %#v", state)
+	}
+
+	// The sinks are kept if the telemetry configuration didn't change
+	sink := c.metricsSink.sinks[0]
+	if err := c.reloadConfig([]string{configFile}); err != nil {
+		t.Fatal(err)
+	}
+	if c.metricsSink.sinks[0] != sink {
+		t.Fatal("expected the sinks to be kept")
+	}
+
+	// The previous sinks and their configuration are kept if the new sinks
+	// can't be set up
+	writeConfig(`
+storage "inmem" {}
+log_level = "debug"
+telemetry {
+	dogstatsd_addr = "nope"
+}
+`)
+	if err := c.reloadConfig([]string{configFile}); err == nil {
+		t.Fatal("expected error")
+	}
+	if len(c.metricsSink.sinks) != 1 || c.metricsSink.sinks[0] != sink {
+		t.Fatalf("bad: %#v", c.metricsSink.sinks)
+	}
+	state = c.configState()
+	if telemetry := state["telemetry"].(map[string]interface{}); telemetry["statsd_address"] != "127.0.0.1:8125" || telemetry["dogstatsd_addr"] != "" {
+		t.Fatalf("bad: %#v", telemetry)
+	}
+
+	// The log level given with -log-level isn't overridden
+	c.logLevelFlag = true
+	writeConfig(`
+storage "inmem" {}
+log_level = "err"
+`)
+	if err := c.reloadConfig([]string{configFile}); err != nil {
+		t.Fatal(err)
+	}
+	if !c.logger.IsDebug() || c.logLevel != "debug" {
+		t.Fatalf("bad: %s", c.logLevel)
+	}
+	if len(c.metricsSink.sinks) != 0 {
+		t.Fatalf("bad: %#v", c.metricsSink.sinks)
+	}
+
+	// An invalid log level is an error
+	c.logLevelFlag = false
+	writeConfig(`
+storage "inmem" {}
+log_level = "loud"
+`)
+	if err := c.reloadConfig([]string{configFile}); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -30,7 +30,6 @@ type SSHCommand struct {
 	// Common options
 	mode       string
 	noExec     bool
-	format     string
 	mountPoint string
 	role       string
 	username   string
@@ -79,7 +78,6 @@ func (c *SSHCommand) Run(args []string) int {
 	// Common options
 	flags.StringVar(&c.mode, "mode", "", "")
 	flags.BoolVar(&c.noExec, "no-exec", false, "")
-	flags.StringVar(&c.format, "format", "table", "")
 	flags.StringVar(&c.mountPoint, "mount-point", "ssh", "")
 	flags.StringVar(&c.role, "role", "", "")
 
@@ -250,7 +248,7 @@ func (c *SSHCommand) handleTypeCA() error {
 	// Handle no-exec
 	if c.noExec {
 		// This is hacky, but OutputSecret returns an int, not an error :(
-		if i := OutputSecret(c.Ui, c.format, secret); i != 0 {
+		if i := c.outputCredentials(secret); i != 0 {
 			return fmt.Errorf("an error occurred outputting the secret")
 		}
 		return nil
@@ -337,7 +335,7 @@ func (c *SSHCommand) handleTypeOTP() error {
 	// Handle no-exec
 	if c.noExec {
 		// This is hacky, but OutputSecret returns an int, not an error :(
-		if i := OutputSecret(c.Ui, c.format, secret); i != 0 {
+		if i := c.outputCredentials(secret); i != 0 {
 			return fmt.Errorf("an error occurred outputting the secret")
 		}
 		return nil
@@ -407,7 +405,7 @@ func (c *SSHCommand) handleTypeDynamic() error {
 	// Handle no-exec
 	if c.noExec {
 		// This is hacky, but OutputSecret returns an int, not an error :(
-		if i := OutputSecret(c.Ui, c.format, secret); i != 0 {
+		if i := c.outputCredentials(secret); i != 0 {
 			return fmt.Errorf("an error occurred outputting the secret")
 		}
 		return nil
@@ -546,6 +544,14 @@ func (c *SSHCommand) defaultRole(mountPoint, ip string) (string, error) {
 	}
 }
 
+// outputCredentials outputs the credentials of the -no-exec mode
+func (c *SSHCommand) outputCredentials(secret *api.Secret) int {
+	if c.Field() != "" {
+		return PrintRawField(c.Ui, secret, c.Field())
+	}
+	return OutputSecret(c.Ui, c.Format(), secret)
+}
+
 // userAndIP takes an argument in the format foo@1.2.3.4 and separates the IP
 // and user parts, returning any errors.
 func (c *SSHCommand) userAndIP(s string) (string, string, error) {
@@ -627,14 +633,13 @@ SSH Options:
                    "roles/" endpoint.
 
   -no-exec         Shows the credentials but does not establish connection.
+                   The credentials are output in the format given with
+                   -format, or as the raw value of the field given with
+                   -field.
 
   -mount-point     Mount point of SSH backend. If the backend is mounted at
                    "ssh" (default), this parameter can be skipped.
 
-  -format          If the "no-exec" option is enabled, the credentials will be
-                   printed out and SSH connection will not be established. The
-                   format of the output can be "json" or "table" (default).
-
   -strict-host-key-checking   This option corresponds to "StrictHostKeyChecking"
                    of SSH configuration. If "sshpass" is employed to enable
                    automated login, then if host key is not "known" to the
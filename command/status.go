@@ -8,6 +8,13 @@ import (
 	"github.com/hashicorp/vault/meta"
 )
 
+// statusOutput is the structured output of the status command, merging the
+// seal status and the leader status
+type statusOutput struct {
+	*api.SealStatusResponse
+	*api.LeaderResponse
+}
+
 // StatusCommand is a Command that outputs the status of whether
 // Vault is sealed or not as well as HA information.
 type StatusCommand struct {
@@ -35,6 +42,30 @@ func (c *StatusCommand) Run(args []string) int {
 		return 1
 	}
 
+	// Mask the 'Vault is sealed' error, since this means HA is enabled,
+	// but that we cannot query for the leader since we are sealed.
+	leaderStatus, err := client.Sys().Leader()
+	if err != nil && strings.Contains(err.Error(), "Vault is sealed") {
+		leaderStatus = &api.LeaderResponse{HAEnabled: true}
+		err = nil
+	}
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf(
+			"Error checking leader status: %s", err))
+		return 1
+	}
+
+	if c.StructuredOutput() {
+		ret := OutputData(c.Ui, c.Format(), c.Field(), &statusOutput{
+			SealStatusResponse: sealStatus,
+			LeaderResponse:     leaderStatus,
+		})
+		if ret == 0 && sealStatus.Sealed {
+			return 2
+		}
+		return ret
+	}
+
 	outStr := fmt.Sprintf(
 		"Sealed: %v\n"+
 			"Key Shares: %d\n"+
@@ -55,19 +86,6 @@ func (c *StatusCommand) Run(args []string) int {
 
 	c.Ui.Output(outStr)
 
-	// Mask the 'Vault is sealed' error, since this means HA is enabled,
-	// but that we cannot query for the leader since we are sealed.
-	leaderStatus, err := client.Sys().Leader()
-	if err != nil && strings.Contains(err.Error(), "Vault is sealed") {
-		leaderStatus = &api.LeaderResponse{HAEnabled: true}
-		err = nil
-	}
-	if err != nil {
-		c.Ui.Error(fmt.Sprintf(
-			"Error checking leader status: %s", err))
-		return 1
-	}
-
 	// Output if HA is enabled
 	c.Ui.Output("")
 	c.Ui.Output(fmt.Sprintf("High-Availability Enabled: %v", leaderStatus.HAEnabled))
@@ -1,8 +1,10 @@
 package command
 
 import (
+	"strings"
 	"testing"
 
+	"github.com/hashicorp/vault/helper/jsonutil"
 	"github.com/hashicorp/vault/http"
 	"github.com/hashicorp/vault/meta"
 	"github.com/hashicorp/vault/vault"
@@ -37,3 +39,40 @@ func TestStatus(t *testing.T) {
 		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
 	}
 }
+
+func TestStatus_format(t *testing.T) {
+	core := vault.TestCore(t)
+	vault.TestCoreInit(t, core)
+	ln, addr := http.TestServer(t, core)
+	defer ln.Close()
+
+	ui := new(cli.MockUi)
+	c := &StatusCommand{
+		Meta: meta.Meta{
+			Ui: ui,
+		},
+	}
+	args := []string{"-address", addr, "-format", "json"}
+	if code := c.Run(args); code != 2 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	var result map[string]interface{}
+	if err := jsonutil.DecodeJSON(ui.OutputWriter.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	if result["sealed"] != true || result["type"] != "shamir" {
+		t.Fatalf("bad: %#v", result)
+	}
+
+	ui = new(cli.MockUi)
+	c.Meta = meta.Meta{
+		Ui: ui,
+	}
+	args = []string{"-address", addr, "-field", "t"}
+	if code := c.Run(args); code != 2 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	if actual := strings.TrimSpace(ui.OutputWriter.String()); actual != "3" {
+		t.Fatalf("bad: %q", actual)
+	}
+}
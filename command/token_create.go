@@ -16,14 +16,12 @@ type TokenCreateCommand struct {
 }
 
 func (c *TokenCreateCommand) Run(args []string) int {
-	var format string
-	var id, displayName, lease, ttl, explicitMaxTTL, period, role string
+	var id, displayName, lease, ttl, explicitMaxTTL, period, role, tokenType, entityAlias string
 	var orphan, noDefaultPolicy, renewable bool
 	var metadata map[string]string
 	var numUses int
-	var policies []string
+	var policies, boundCIDRs []string
 	flags := c.Meta.FlagSet("mount", meta.FlagSetDefault)
-	flags.StringVar(&format, "format", "table", "")
 	flags.StringVar(&displayName, "display-name", "", "")
 	flags.StringVar(&id, "id", "", "")
 	flags.StringVar(&lease, "lease", "", "")
@@ -31,12 +29,15 @@ func (c *TokenCreateCommand) Run(args []string) int {
 	flags.StringVar(&explicitMaxTTL, "explicit-max-ttl", "", "")
 	flags.StringVar(&period, "period", "", "")
 	flags.StringVar(&role, "role", "", "")
+	flags.StringVar(&tokenType, "type", "", "")
+	flags.StringVar(&entityAlias, "entity-alias", "", "")
 	flags.BoolVar(&orphan, "orphan", false, "")
 	flags.BoolVar(&renewable, "renewable", true, "")
 	flags.BoolVar(&noDefaultPolicy, "no-default-policy", false, "")
 	flags.IntVar(&numUses, "use-limit", 0, "")
 	flags.Var((*kvFlag.Flag)(&metadata), "metadata", "")
 	flags.Var((*sliceflag.StringFlag)(&policies), "policy", "")
+	flags.Var((*sliceflag.StringFlag)(&boundCIDRs), "bound-cidr", "")
 	flags.Usage = func() { c.Ui.Error(c.Help()) }
 	if err := flags.Parse(args); err != nil {
 		return 1
@@ -73,6 +74,9 @@ func (c *TokenCreateCommand) Run(args []string) int {
 		Renewable:       new(bool),
 		ExplicitMaxTTL:  explicitMaxTTL,
 		Period:          period,
+		Type:            tokenType,
+		BoundCIDRs:      boundCIDRs,
+		EntityAlias:     entityAlias,
 	}
 	*tcr.Renewable = renewable
 
@@ -89,7 +93,12 @@ func (c *TokenCreateCommand) Run(args []string) int {
 		return 2
 	}
 
-	return OutputSecret(c.Ui, format, secret)
+	// Handle single field output
+	if c.Field() != "" {
+		return PrintRawField(c.Ui, secret, c.Field())
+	}
+
+	return OutputSecret(c.Ui, c.Format(), secret)
 }
 
 func (c *TokenCreateCommand) Synopsis() string {
@@ -164,8 +173,20 @@ Token Options:
   -use-limit=5            The number of times this token can be used until
                           it is automatically revoked.
 
-  -format=table           The format for output. By default it is a whitespace-
-                          delimited table. This can also be json or yaml.
+  -bound-cidr="10.0.0.0/8"
+                          CIDR block from which the token can be used. This
+                          can be specified multiple times. The blocks must be
+                          within those of your token, if it is bound.
+
+  -type=batch             The type of the token, "service" or "batch". Batch
+                          tokens are not persisted, and cannot be renewed,
+                          revoked, or create child tokens. Defaults to
+                          "service".
+
+  -entity-alias="name"    Alias of the token store mount whose entity the
+                          token is bound to. The role given with -role must
+                          allow the alias. The entity is created if the alias
+                          doesn't exist.
 
   -role=name              If set, the token will be created against the named
                           role. The role may override other parameters. This
@@ -15,11 +15,9 @@ type TokenLookupCommand struct {
 }
 
 func (c *TokenLookupCommand) Run(args []string) int {
-	var format string
 	var accessor bool
 	flags := c.Meta.FlagSet("token-lookup", meta.FlagSetDefault)
 	flags.BoolVar(&accessor, "accessor", false, "")
-	flags.StringVar(&format, "format", "table", "")
 	flags.Usage = func() { c.Ui.Error(c.Help()) }
 	if err := flags.Parse(args); err != nil {
 		return 1
@@ -59,7 +57,12 @@ func (c *TokenLookupCommand) Run(args []string) int {
 			"error looking up token: %s", err))
 		return 1
 	}
-	return OutputSecret(c.Ui, format, secret)
+	// Handle single field output
+	if c.Field() != "" {
+		return PrintRawField(c.Ui, secret, c.Field())
+	}
+
+	return OutputSecret(c.Ui, c.Format(), secret)
 }
 
 func doTokenLookup(args []string, client *api.Client) (*api.Secret, error) {
@@ -91,10 +94,6 @@ Token Lookup Options:
                           Note that the response of the command when this is set, will not contain
                           the token ID. Accessor is only meant for looking up the token properties
                           (and for revocation via '/auth/token/revoke-accessor/<accessor>' endpoint).
-
-  -format=table           The format for output. By default it is a whitespace-
-                          delimited table. This can also be json or yaml.
-
 `
 	return strings.TrimSpace(helpText)
 }
@@ -16,9 +16,8 @@ type TokenRenewCommand struct {
 }
 
 func (c *TokenRenewCommand) Run(args []string) int {
-	var format, increment string
+	var increment string
 	flags := c.Meta.FlagSet("token-renew", meta.FlagSetDefault)
-	flags.StringVar(&format, "format", "table", "")
 	flags.StringVar(&increment, "increment", "", "")
 	flags.Usage = func() { c.Ui.Error(c.Help()) }
 	if err := flags.Parse(args); err != nil {
@@ -74,7 +73,12 @@ func (c *TokenRenewCommand) Run(args []string) int {
 		return 1
 	}
 
-	return OutputSecret(c.Ui, format, secret)
+	// Handle single field output
+	if c.Field() != "" {
+		return PrintRawField(c.Ui, secret, c.Field())
+	}
+
+	return OutputSecret(c.Ui, c.Format(), secret)
 }
 
 func (c *TokenRenewCommand) Synopsis() string {
@@ -106,10 +110,6 @@ Token Renew Options:
                           use the default TTL. If supplied, it may still be
                           ignored. This can be submitted as an integer number
                           of seconds or a string duration (e.g. "72h").
-
-  -format=table           The format for output. By default it is a whitespace-
-                          delimited table. This can also be json or yaml.
-
 `
 	return strings.TrimSpace(helpText)
 }
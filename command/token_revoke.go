@@ -82,7 +82,9 @@ func (c *TokenRevokeCommand) Run(args []string) int {
 		return 2
 	}
 
-	c.Ui.Output("Success! Token revoked if it existed.")
+	if !c.StructuredOutput() {
+		c.Ui.Output("Success! Token revoked if it existed.")
+	}
 	return 0
 }
 
@@ -42,8 +42,10 @@ func (c *UnmountCommand) Run(args []string) int {
 		return 2
 	}
 
-	c.Ui.Output(fmt.Sprintf(
-		"Successfully unmounted '%s' if it was mounted", path))
+	if !c.StructuredOutput() {
+		c.Ui.Output(fmt.Sprintf(
+			"Successfully unmounted '%s' if it was mounted", path))
+	}
 
 	return 0
 }
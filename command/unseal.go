@@ -19,9 +19,10 @@ type UnsealCommand struct {
 }
 
 func (c *UnsealCommand) Run(args []string) int {
-	var reset bool
+	var reset, migrate bool
 	flags := c.Meta.FlagSet("unseal", meta.FlagSetDefault)
 	flags.BoolVar(&reset, "reset", false, "")
+	flags.BoolVar(&migrate, "migrate", false, "")
 	flags.Usage = func() { c.Ui.Error(c.Help()) }
 	if err := flags.Parse(args); err != nil {
 		return 1
@@ -42,9 +43,17 @@ func (c *UnsealCommand) Run(args []string) int {
 	}
 
 	if !sealStatus.Sealed {
+		if c.StructuredOutput() {
+			return OutputData(c.Ui, c.Format(), c.Field(), sealStatus)
+		}
 		c.Ui.Output("Vault is already unsealed.")
 		return 0
 	}
+	if sealStatus.Migration && !migrate && !reset {
+		c.Ui.Error("A seal migration is pending. Run this command with -migrate to\n" +
+			"submit the keys and complete the migration.")
+		return 1
+	}
 
 	args = flags.Args()
 	if reset {
@@ -71,7 +80,11 @@ func (c *UnsealCommand) Run(args []string) int {
 				return 1
 			}
 		}
-		sealStatus, err = client.Sys().Unseal(strings.TrimSpace(value))
+		if migrate {
+			sealStatus, err = client.Sys().UnsealMigrate(strings.TrimSpace(value))
+		} else {
+			sealStatus, err = client.Sys().Unseal(strings.TrimSpace(value))
+		}
 	}
 
 	if err != nil {
@@ -80,6 +93,13 @@ func (c *UnsealCommand) Run(args []string) int {
 		return 1
 	}
 
+	if c.StructuredOutput() {
+		return OutputData(c.Ui, c.Format(), c.Field(), sealStatus)
+	}
+
+	if migrate && !sealStatus.Sealed {
+		c.Ui.Output("Seal migration complete.")
+	}
 	c.Ui.Output(fmt.Sprintf(
 		"Sealed: %v\n"+
 			"Key Shares: %d\n"+
@@ -123,6 +143,13 @@ Unseal Options:
   -reset                  Reset the unsealing process by throwing away
                           prior keys in process to unseal the vault.
 
+  -migrate                Complete a pending seal migration. The keys to
+                          enter are the unseal keys when migrating from
+                          Shamir to an auto-unseal seal, or the recovery keys
+                          when migrating from an auto-unseal seal to Shamir.
+                          Once the threshold is reached, the master key is
+                          migrated and the vault is unsealed.
+
 `
 	return strings.TrimSpace(helpText)
 }
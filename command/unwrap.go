@@ -16,14 +16,12 @@ type UnwrapCommand struct {
 }
 
 func (c *UnwrapCommand) Run(args []string) int {
-	var format string
-	var field string
+	var entry string
 	var err error
 	var secret *api.Secret
 	var flags *flag.FlagSet
 	flags = c.Meta.FlagSet("unwrap", meta.FlagSetDefault)
-	flags.StringVar(&format, "format", "table", "")
-	flags.StringVar(&field, "field", "", "")
+	flags.StringVar(&entry, "entry", "", "")
 	flags.Usage = func() { c.Ui.Error(c.Help()) }
 	if err := flags.Parse(args); err != nil {
 		return 1
@@ -49,7 +47,11 @@ func (c *UnwrapCommand) Run(args []string) int {
 		return 2
 	}
 
-	secret, err = client.Logical().Unwrap(tokenID)
+	if entry != "" {
+		secret, err = client.Logical().UnwrapEntry(tokenID, entry)
+	} else {
+		secret, err = client.Logical().Unwrap(tokenID)
+	}
 	if err != nil {
 		c.Ui.Error(err.Error())
 		return 1
@@ -60,8 +62,8 @@ func (c *UnwrapCommand) Run(args []string) int {
 	}
 
 	// Handle single field output
-	if field != "" {
-		return PrintRawField(c.Ui, secret, field)
+	if c.Field() != "" {
+		return PrintRawField(c.Ui, secret, c.Field())
 	}
 
 	// Check if the original was a list response and format as a list if so
@@ -70,10 +72,10 @@ func (c *UnwrapCommand) Run(args []string) int {
 		secret.Data["keys"] != nil {
 		_, ok := secret.Data["keys"].([]interface{})
 		if ok {
-			return OutputList(c.Ui, format, secret)
+			return OutputList(c.Ui, c.Format(), secret)
 		}
 	}
-	return OutputSecret(c.Ui, format, secret)
+	return OutputSecret(c.Ui, c.Format(), secret)
 }
 
 func (c *UnwrapCommand) Synopsis() string {
@@ -91,13 +93,11 @@ Usage: vault unwrap [options] <wrapping token ID>
 
 General Options:
 ` + meta.GeneralOptionsUsage() + `
-Read Options:
-
-  -format=table           The format for output. By default it is a whitespace-
-                          delimited table. This can also be json or yaml.
+Unwrap Options:
 
-  -field=field            If included, the raw value of the specified field
-                          will be output raw to stdout.
+  -entry=name             The name of the entry to unwrap from a token wrapping
+                          multiple entries. Each entry can only be unwrapped
+                          once.
 
 `
 	return strings.TrimSpace(helpText)
@@ -75,16 +75,7 @@ func PrintRawField(ui cli.Ui, secret *api.Secret, field string) int {
 	}
 
 	if val != nil {
-		// c.Ui.Output() prints a CR character which in this case is
-		// not desired. Since Vault CLI currently only uses BasicUi,
-		// which writes to standard output, os.Stdout is used here to
-		// directly print the message. If mitchellh/cli exposes method
-		// to print without CR, this check needs to be removed.
-		if reflect.TypeOf(ui).String() == "*cli.BasicUi" {
-			fmt.Fprintf(os.Stdout, "%v", val)
-		} else {
-			ui.Output(fmt.Sprintf("%v", val))
-		}
+		printRaw(ui, val)
 		return 0
 	} else {
 		ui.Error(fmt.Sprintf(
@@ -92,3 +83,17 @@ func PrintRawField(ui cli.Ui, secret *api.Secret, field string) int {
 		return 1
 	}
 }
+
+// printRaw prints a raw value, without the trailing newline of the Ui
+func printRaw(ui cli.Ui, val interface{}) {
+	// c.Ui.Output() prints a CR character which in this case is
+	// not desired. Since Vault CLI currently only uses BasicUi,
+	// which writes to standard output, os.Stdout is used here to
+	// directly print the message. If mitchellh/cli exposes method
+	// to print without CR, this check needs to be removed.
+	if reflect.TypeOf(ui).String() == "*cli.BasicUi" {
+		fmt.Fprintf(os.Stdout, "%v", val)
+	} else {
+		ui.Output(fmt.Sprintf("%v", val))
+	}
+}
@@ -1,21 +1,36 @@
 package command
 
 import (
+	"strings"
+
+	"github.com/hashicorp/vault/meta"
 	"github.com/hashicorp/vault/version"
-	"github.com/mitchellh/cli"
 )
 
 // VersionCommand is a Command implementation prints the version.
 type VersionCommand struct {
+	meta.Meta
 	VersionInfo *version.VersionInfo
-	Ui          cli.Ui
 }
 
 func (c *VersionCommand) Help() string {
 	return ""
 }
 
-func (c *VersionCommand) Run(_ []string) int {
+func (c *VersionCommand) Run(args []string) int {
+	flags := c.Meta.FlagSet("version", meta.FlagSetOutput)
+	if err := flags.Parse(outputFlags(args)); err != nil {
+		return 1
+	}
+
+	if c.StructuredOutput() {
+		return OutputData(c.Ui, c.Format(), c.Field(), map[string]interface{}{
+			"version":  c.VersionInfo.VersionNumber(),
+			"revision": c.VersionInfo.Revision,
+			"cgo":      version.CgoEnabled,
+		})
+	}
+
 	out := c.VersionInfo.FullVersionNumber(true)
 	if version.CgoEnabled {
 		out += " (cgo)"
@@ -27,3 +42,27 @@ func (c *VersionCommand) Run(_ []string) int {
 func (c *VersionCommand) Synopsis() string {
 	return "Prints the Vault version"
 }
+
+// outputFlags returns the -format and -field flags of the arguments, which
+// may hold the arguments of any other command since the -v and -version
+// flags of any command print the version
+func outputFlags(args []string) []string {
+	var result []string
+	for i := 0; i < len(args); i++ {
+		name := strings.TrimLeft(args[i], "-")
+		if name == args[i] {
+			continue
+		}
+		switch {
+		case name == "format" || name == "field":
+			result = append(result, args[i])
+			if i+1 < len(args) {
+				result = append(result, args[i+1])
+				i++
+			}
+		case strings.HasPrefix(name, "format=") || strings.HasPrefix(name, "field="):
+			result = append(result, args[i])
+		}
+	}
+	return result
+}
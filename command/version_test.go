@@ -1,6 +1,7 @@
 package command
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/mitchellh/cli"
@@ -9,3 +10,11 @@ import (
 func TestVersionCommand_implements(t *testing.T) {
 	var _ cli.Command = &VersionCommand{}
 }
+
+func TestOutputFlags(t *testing.T) {
+	args := []string{"read", "-format", "json", "-v", "-field=version", "secret/foo", "--address=foo"}
+	expected := []string{"-format", "json", "-field=version"}
+	if actual := outputFlags(args); !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("expected %v, got %v", expected, actual)
+	}
+}
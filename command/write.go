@@ -3,10 +3,8 @@ package command
 import (
 	"fmt"
 	"io"
-	"os"
 	"strings"
 
-	"github.com/hashicorp/vault/helper/kv-builder"
 	"github.com/hashicorp/vault/meta"
 	"github.com/posener/complete"
 )
@@ -20,11 +18,8 @@ type WriteCommand struct {
 }
 
 func (c *WriteCommand) Run(args []string) int {
-	var field, format string
 	var force bool
 	flags := c.Meta.FlagSet("write", meta.FlagSetDefault)
-	flags.StringVar(&format, "format", "table", "")
-	flags.StringVar(&field, "field", "", "")
 	flags.BoolVar(&force, "force", false, "")
 	flags.BoolVar(&force, "f", false, "")
 	flags.Usage = func() { c.Ui.Error(c.Help()) }
@@ -73,32 +68,22 @@ func (c *WriteCommand) Run(args []string) int {
 
 	if secret == nil {
 		// Don't output anything if people aren't using the "human" output
-		if format == "table" {
+		if !c.StructuredOutput() {
 			c.Ui.Output(fmt.Sprintf("Success! Data written to: %s", path))
 		}
 		return 0
 	}
 
 	// Handle single field output
-	if field != "" {
-		return PrintRawField(c.Ui, secret, field)
+	if c.Field() != "" {
+		return PrintRawField(c.Ui, secret, c.Field())
 	}
 
-	return OutputSecret(c.Ui, format, secret)
+	return OutputSecret(c.Ui, c.Format(), secret)
 }
 
 func (c *WriteCommand) parseData(args []string) (map[string]interface{}, error) {
-	var stdin io.Reader = os.Stdin
-	if c.testStdin != nil {
-		stdin = c.testStdin
-	}
-
-	builder := &kvbuilder.Builder{Stdin: stdin}
-	if err := builder.Add(args...); err != nil {
-		return nil, err
-	}
-
-	return builder.Map(), nil
+	return parseKVData(c.testStdin, args)
 }
 
 func (c *WriteCommand) Synopsis() string {
@@ -131,12 +116,6 @@ Write Options:
                           specified. This allows writing to keys that do not
                           need or expect any fields to be specified.
 
-  -format=table           The format for output. By default it is a whitespace-
-                          delimited table. This can also be json or yaml.
-
-  -field=field            If included, the raw value of the specified field
-                          will be output raw to stdout.
-
 `
 	return strings.TrimSpace(helpText)
 }
@@ -0,0 +1,1202 @@
+// Package cel evaluates rules written in a subset of the Common Expression
+// Language (CEL). It supports literals, lists and maps, field and index
+// access, the arithmetic, comparison, logical and ternary operators, the `in`
+// operator, the `has` macro, and the size, int, double, string, startsWith,
+// endsWith, contains, matches and cidr/containsIP functions.
+//
+// Since expressions have no loops, the cost of evaluating them is bounded by
+// their size, which is limited along with their nesting depth. Integer
+// arithmetic fails on overflow.
+package cel
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// maxExpressionLength is the maximum length of an expression, in bytes
+	maxExpressionLength = 8192
+
+	// maxNestingDepth is the maximum depth of nested expressions, such as
+	// parenthesized expressions, lists, arguments and unary operators
+	maxNestingDepth = 64
+)
+
+// Program is a compiled expression
+type Program struct {
+	expr string
+	root node
+}
+
+// Compile parses the given expression
+func Compile(expr string) (*Program, error) {
+	if len(expr) > maxExpressionLength {
+		return nil, fmt.Errorf("expression is longer than %d bytes", maxExpressionLength)
+	}
+
+	tokens, err := lex(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	root, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos)
+	}
+
+	return &Program{
+		expr: expr,
+		root: root,
+	}, nil
+}
+
+// String returns the source of the expression
+func (p *Program) String() string {
+	return p.expr
+}
+
+// Eval evaluates the expression with the given variables. Integers, floats,
+// string slices and string maps are converted to their CEL equivalents.
+func (p *Program) Eval(vars map[string]interface{}) (interface{}, error) {
+	env := make(map[string]interface{}, len(vars))
+	for name, value := range vars {
+		env[name] = normalize(value)
+	}
+	return p.root.eval(env)
+}
+
+// EvalBool evaluates an expression which must return a bool
+func (p *Program) EvalBool(vars map[string]interface{}) (bool, error) {
+	value, err := p.Eval(vars)
+	if err != nil {
+		return false, err
+	}
+	b, ok := value.(bool)
+	if !ok {
+		return false, fmt.Errorf("expression returned %s, not a bool", typeName(value))
+	}
+	return b, nil
+}
+
+// normalize converts Go values to the types handled by the evaluator: int64,
+// float64, string, bool, nil, []interface{} and map[string]interface{}
+func normalize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case int:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case uint32:
+		return int64(v)
+	case float32:
+		return float64(v)
+	case []string:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			list[i] = item
+		}
+		return list
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			list[i] = normalize(item)
+		}
+		return list
+	case map[string]string:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			m[key] = item
+		}
+		return m
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			m[key] = normalize(item)
+		}
+		return m
+	}
+	return value
+}
+
+func typeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "bool"
+	case int64:
+		return "int"
+	case float64:
+		return "double"
+	case string:
+		return "string"
+	case []interface{}:
+		return "list"
+	case map[string]interface{}:
+		return "map"
+	case *net.IPNet:
+		return "cidr"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+//
+// Lexer
+//
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokInt
+	tokFloat
+	tokString
+	tokOp
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	value interface{}
+	pos   int
+}
+
+var twoCharOps = []string{"==", "!=", "<=", ">=", "&&", "||"}
+
+func lex(expr string) ([]token, error) {
+	var tokens []token
+	for pos := 0; pos < len(expr); {
+		r, size := utf8.DecodeRuneInString(expr[pos:])
+		switch {
+		case unicode.IsSpace(r):
+			pos += size
+
+		case r == '_' || unicode.IsLetter(r):
+			start := pos
+			for pos < len(expr) {
+				r, size := utf8.DecodeRuneInString(expr[pos:])
+				if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+					break
+				}
+				pos += size
+			}
+			tokens = append(tokens, token{kind: tokIdent, text: expr[start:pos], pos: start})
+
+		case r >= '0' && r <= '9':
+			start := pos
+			isFloat := false
+			for pos < len(expr) && (expr[pos] >= '0' && expr[pos] <= '9' ||
+				expr[pos] == '.' && !isFloat && pos+1 < len(expr) && expr[pos+1] >= '0' && expr[pos+1] <= '9') {
+				if expr[pos] == '.' {
+					isFloat = true
+				}
+				pos++
+			}
+			text := expr[start:pos]
+			if isFloat {
+				f, err := strconv.ParseFloat(text, 64)
+				if err != nil {
+					return nil, fmt.Errorf("invalid number %q at position %d", text, start)
+				}
+				tokens = append(tokens, token{kind: tokFloat, text: text, value: f, pos: start})
+			} else {
+				i, err := strconv.ParseInt(text, 10, 64)
+				if err != nil {
+					return nil, fmt.Errorf("invalid number %q at position %d", text, start)
+				}
+				tokens = append(tokens, token{kind: tokInt, text: text, value: i, pos: start})
+			}
+
+		case r == '"' || r == '\'':
+			start := pos
+			s, end, err := lexString(expr, pos)
+			if err != nil {
+				return nil, err
+			}
+			pos = end
+			tokens = append(tokens, token{kind: tokString, text: expr[start:pos], value: s, pos: start})
+
+		default:
+			op := ""
+			for _, candidate := range twoCharOps {
+				if strings.HasPrefix(expr[pos:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				if !strings.ContainsRune("()[]{},.?:!<>+-*/%", r) {
+					return nil, fmt.Errorf("unexpected character %q at position %d", r, pos)
+				}
+				op = string(r)
+			}
+			tokens = append(tokens, token{kind: tokOp, text: op, pos: pos})
+			pos += len(op)
+		}
+	}
+
+	return append(tokens, token{kind: tokEOF, text: "end of expression", pos: len(expr)}), nil
+}
+
+// lexString reads the quoted string starting at the given position and
+// returns its value and the position following it
+func lexString(expr string, start int) (string, int, error) {
+	quote := expr[start]
+	var b bytes.Buffer
+	for pos := start + 1; pos < len(expr); pos++ {
+		c := expr[pos]
+		switch {
+		case c == quote:
+			return b.String(), pos + 1, nil
+		case c == '\\':
+			pos++
+			if pos >= len(expr) {
+				break
+			}
+			switch expr[pos] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			case '\\', '"', '\'':
+				b.WriteByte(expr[pos])
+			default:
+				return "", 0, fmt.Errorf("invalid escape sequence at position %d", pos-1)
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string at position %d", start)
+}
+
+//
+// Parser
+//
+
+type parser struct {
+	tokens []token
+	pos    int
+	depth  int
+}
+
+// enter increases the nesting depth as the parser descends into a nested
+// expression, and must be followed by leave
+func (p *parser) enter() error {
+	p.depth++
+	if p.depth > maxNestingDepth {
+		return fmt.Errorf("expression is nested more than %d levels deep at position %d", maxNestingDepth, p.peek().pos)
+	}
+	return nil
+}
+
+func (p *parser) leave() {
+	p.depth--
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// accept consumes the next token if it is the given operator or keyword
+func (p *parser) accept(op string) bool {
+	tok := p.peek()
+	if (tok.kind == tokOp || tok.kind == tokIdent) && tok.text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(op string) error {
+	if !p.accept(op) {
+		tok := p.peek()
+		return fmt.Errorf("expected %q but found %q at position %d", op, tok.text, tok.pos)
+	}
+	return nil
+}
+
+func (p *parser) parseExpr() (node, error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+
+	cond, err := p.parseBinary(0)
+	if err != nil {
+		return nil, err
+	}
+	if !p.accept("?") {
+		return cond, nil
+	}
+
+	ifTrue, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	ifFalse, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	return &ternaryNode{cond: cond, ifTrue: ifTrue, ifFalse: ifFalse}, nil
+}
+
+// binaryPrecedence lists the binary operators from the lowest to the
+// highest precedence
+var binaryPrecedence = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!=", "<", "<=", ">", ">=", "in"},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+func (p *parser) parseBinary(level int) (node, error) {
+	if level == len(binaryPrecedence) {
+		return p.parseUnary()
+	}
+
+	left, err := p.parseBinary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := ""
+		for _, candidate := range binaryPrecedence[level] {
+			if p.accept(candidate) {
+				op = candidate
+				break
+			}
+		}
+		if op == "" {
+			return left, nil
+		}
+
+		right, err := p.parseBinary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		if op == "&&" || op == "||" {
+			left = &logicalNode{and: op == "&&", left: left, right: right}
+		} else {
+			left = &binaryNode{op: op, left: left, right: right}
+		}
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	switch {
+	case p.accept("!"):
+		operand, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{operand: operand}, nil
+	case p.accept("-"):
+		operand, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		return &negNode{operand: operand}, nil
+	}
+	return p.parseMember()
+}
+
+// parseOperand parses the operand of a unary operator
+func (p *parser) parseOperand() (node, error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+
+	return p.parseUnary()
+}
+
+func (p *parser) parseMember() (node, error) {
+	n, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		switch {
+		case p.accept("."):
+			tok := p.next()
+			if tok.kind != tokIdent {
+				return nil, fmt.Errorf("expected a field name but found %q at position %d", tok.text, tok.pos)
+			}
+			if p.accept("(") {
+				args, err := p.parseList(")")
+				if err != nil {
+					return nil, err
+				}
+				n = &callNode{name: tok.text, target: n, args: args}
+			} else {
+				n = &selectNode{target: n, field: tok.text}
+			}
+
+		case p.accept("["):
+			index, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			n = &indexNode{target: n, index: index}
+
+		default:
+			return n, nil
+		}
+	}
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokInt, tokFloat, tokString:
+		return &literalNode{value: tok.value}, nil
+
+	case tokIdent:
+		switch tok.text {
+		case "true":
+			return &literalNode{value: true}, nil
+		case "false":
+			return &literalNode{value: false}, nil
+		case "null":
+			return &literalNode{value: nil}, nil
+		}
+
+		if !p.accept("(") {
+			return &identNode{name: tok.text}, nil
+		}
+		args, err := p.parseList(")")
+		if err != nil {
+			return nil, err
+		}
+
+		// has() is a macro testing the presence of a field
+		if tok.text == "has" {
+			if len(args) != 1 {
+				return nil, fmt.Errorf("has() takes a single field selection at position %d", tok.pos)
+			}
+			sel, ok := args[0].(*selectNode)
+			if !ok {
+				return nil, fmt.Errorf("has() takes a field selection at position %d", tok.pos)
+			}
+			return &hasNode{sel: sel}, nil
+		}
+		return &callNode{name: tok.text, args: args}, nil
+
+	case tokOp:
+		switch tok.text {
+		case "(":
+			n, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return n, nil
+
+		case "[":
+			items, err := p.parseList("]")
+			if err != nil {
+				return nil, err
+			}
+			return &listNode{items: items}, nil
+
+		case "{":
+			return p.parseMap()
+		}
+	}
+
+	return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos)
+}
+
+// parseList parses comma-separated expressions up to the given closing
+// token, which is consumed
+func (p *parser) parseList(closing string) ([]node, error) {
+	var items []node
+	if p.accept(closing) {
+		return items, nil
+	}
+	for {
+		item, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+
+		if p.accept(closing) {
+			return items, nil
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *parser) parseMap() (node, error) {
+	n := &mapNode{}
+	if p.accept("}") {
+		return n, nil
+	}
+	for {
+		key, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		value, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		n.keys = append(n.keys, key)
+		n.values = append(n.values, value)
+
+		if p.accept("}") {
+			return n, nil
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+//
+// Evaluation
+//
+
+type node interface {
+	eval(env map[string]interface{}) (interface{}, error)
+}
+
+type literalNode struct {
+	value interface{}
+}
+
+func (n *literalNode) eval(env map[string]interface{}) (interface{}, error) {
+	return n.value, nil
+}
+
+type identNode struct {
+	name string
+}
+
+func (n *identNode) eval(env map[string]interface{}) (interface{}, error) {
+	value, ok := env[n.name]
+	if !ok {
+		return nil, fmt.Errorf("undeclared reference to %q", n.name)
+	}
+	return value, nil
+}
+
+type listNode struct {
+	items []node
+}
+
+func (n *listNode) eval(env map[string]interface{}) (interface{}, error) {
+	list := make([]interface{}, 0, len(n.items))
+	for _, item := range n.items {
+		value, err := item.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, value)
+	}
+	return list, nil
+}
+
+type mapNode struct {
+	keys   []node
+	values []node
+}
+
+func (n *mapNode) eval(env map[string]interface{}) (interface{}, error) {
+	m := make(map[string]interface{}, len(n.keys))
+	for i := range n.keys {
+		key, err := n.keys[i].eval(env)
+		if err != nil {
+			return nil, err
+		}
+		keyStr, ok := key.(string)
+		if !ok {
+			return nil, fmt.Errorf("map keys must be strings, not %s", typeName(key))
+		}
+		value, err := n.values[i].eval(env)
+		if err != nil {
+			return nil, err
+		}
+		m[keyStr] = value
+	}
+	return m, nil
+}
+
+type selectNode struct {
+	target node
+	field  string
+}
+
+func (n *selectNode) eval(env map[string]interface{}) (interface{}, error) {
+	target, err := n.target.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := target.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("cannot select field %q of %s", n.field, typeName(target))
+	}
+	value, ok := m[n.field]
+	if !ok {
+		return nil, fmt.Errorf("no such key: %s", n.field)
+	}
+	return value, nil
+}
+
+type hasNode struct {
+	sel *selectNode
+}
+
+func (n *hasNode) eval(env map[string]interface{}) (interface{}, error) {
+	target, err := n.sel.target.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := target.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("cannot test field %q of %s", n.sel.field, typeName(target))
+	}
+	_, ok = m[n.sel.field]
+	return ok, nil
+}
+
+type indexNode struct {
+	target node
+	index  node
+}
+
+func (n *indexNode) eval(env map[string]interface{}) (interface{}, error) {
+	target, err := n.target.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	index, err := n.index.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := target.(type) {
+	case map[string]interface{}:
+		key, ok := index.(string)
+		if !ok {
+			return nil, fmt.Errorf("cannot index a map with %s", typeName(index))
+		}
+		value, ok := t[key]
+		if !ok {
+			return nil, fmt.Errorf("no such key: %s", key)
+		}
+		return value, nil
+
+	case []interface{}:
+		i, ok := index.(int64)
+		if !ok {
+			return nil, fmt.Errorf("cannot index a list with %s", typeName(index))
+		}
+		if i < 0 || i >= int64(len(t)) {
+			return nil, fmt.Errorf("index %d out of range", i)
+		}
+		return t[i], nil
+	}
+	return nil, fmt.Errorf("cannot index %s", typeName(target))
+}
+
+type ternaryNode struct {
+	cond    node
+	ifTrue  node
+	ifFalse node
+}
+
+func (n *ternaryNode) eval(env map[string]interface{}) (interface{}, error) {
+	cond, err := n.cond.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	b, ok := cond.(bool)
+	if !ok {
+		return nil, fmt.Errorf("condition must be a bool, not %s", typeName(cond))
+	}
+	if b {
+		return n.ifTrue.eval(env)
+	}
+	return n.ifFalse.eval(env)
+}
+
+// logicalNode implements && and ||. As in CEL, an error on one side is
+// ignored if the other side determines the result.
+type logicalNode struct {
+	and   bool
+	left  node
+	right node
+}
+
+func (n *logicalNode) eval(env map[string]interface{}) (interface{}, error) {
+	// The value which determines the result regardless of the other side
+	decisive := !n.and
+
+	operand := func(side node) (bool, error) {
+		value, err := side.eval(env)
+		if err != nil {
+			return false, err
+		}
+		b, ok := value.(bool)
+		if !ok {
+			return false, fmt.Errorf("logical operators take bools, not %s", typeName(value))
+		}
+		return b, nil
+	}
+
+	left, leftErr := operand(n.left)
+	if leftErr == nil && left == decisive {
+		return decisive, nil
+	}
+	right, rightErr := operand(n.right)
+	if rightErr == nil && right == decisive {
+		return decisive, nil
+	}
+	if leftErr != nil {
+		return nil, leftErr
+	}
+	if rightErr != nil {
+		return nil, rightErr
+	}
+	return !decisive, nil
+}
+
+type notNode struct {
+	operand node
+}
+
+func (n *notNode) eval(env map[string]interface{}) (interface{}, error) {
+	value, err := n.operand.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	b, ok := value.(bool)
+	if !ok {
+		return nil, fmt.Errorf("cannot negate %s", typeName(value))
+	}
+	return !b, nil
+}
+
+type negNode struct {
+	operand node
+}
+
+func (n *negNode) eval(env map[string]interface{}) (interface{}, error) {
+	value, err := n.operand.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	switch v := value.(type) {
+	case int64:
+		if v == math.MinInt64 {
+			return nil, errIntOverflow
+		}
+		return -v, nil
+	case float64:
+		return -v, nil
+	}
+	return nil, fmt.Errorf("cannot negate %s", typeName(value))
+}
+
+type binaryNode struct {
+	op    string
+	left  node
+	right node
+}
+
+func (n *binaryNode) eval(env map[string]interface{}) (interface{}, error) {
+	left, err := n.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	right, err := n.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return equal(left, right), nil
+	case "!=":
+		return !equal(left, right), nil
+	case "in":
+		return contains(right, left)
+	case "<", "<=", ">", ">=":
+		return compare(n.op, left, right)
+	}
+	return arithmetic(n.op, left, right)
+}
+
+func equal(left, right interface{}) bool {
+	if l, r, ok := numbers(left, right); ok {
+		return l == r
+	}
+	return reflect.DeepEqual(left, right)
+}
+
+// numbers returns the given values as floats if they are both numbers
+func numbers(left, right interface{}) (float64, float64, bool) {
+	toFloat := func(value interface{}) (float64, bool) {
+		switch v := value.(type) {
+		case int64:
+			return float64(v), true
+		case float64:
+			return v, true
+		}
+		return 0, false
+	}
+	l, lok := toFloat(left)
+	r, rok := toFloat(right)
+	return l, r, lok && rok
+}
+
+func contains(container, element interface{}) (interface{}, error) {
+	switch c := container.(type) {
+	case []interface{}:
+		for _, item := range c {
+			if equal(item, element) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case map[string]interface{}:
+		key, ok := element.(string)
+		if !ok {
+			return false, nil
+		}
+		_, ok = c[key]
+		return ok, nil
+	}
+	return nil, fmt.Errorf("'in' requires a list or a map, not %s", typeName(container))
+}
+
+func compare(op string, left, right interface{}) (interface{}, error) {
+	var cmp int
+	if l, r, ok := numbers(left, right); ok {
+		switch {
+		case l < r:
+			cmp = -1
+		case l > r:
+			cmp = 1
+		}
+	} else {
+		l, lok := left.(string)
+		r, rok := right.(string)
+		if !lok || !rok {
+			return nil, fmt.Errorf("cannot compare %s and %s", typeName(left), typeName(right))
+		}
+		cmp = strings.Compare(l, r)
+	}
+
+	switch op {
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	}
+	return cmp >= 0, nil
+}
+
+var errIntOverflow = errors.New("integer overflow")
+
+func arithmetic(op string, left, right interface{}) (interface{}, error) {
+	if op == "+" {
+		switch l := left.(type) {
+		case string:
+			if r, ok := right.(string); ok {
+				return l + r, nil
+			}
+		case []interface{}:
+			if r, ok := right.([]interface{}); ok {
+				return append(append([]interface{}{}, l...), r...), nil
+			}
+		}
+	}
+
+	li, lok := left.(int64)
+	ri, rok := right.(int64)
+	if lok && rok {
+		switch op {
+		case "+":
+			if ri > 0 && li > math.MaxInt64-ri || ri < 0 && li < math.MinInt64-ri {
+				return nil, errIntOverflow
+			}
+			return li + ri, nil
+		case "-":
+			if ri < 0 && li > math.MaxInt64+ri || ri > 0 && li < math.MinInt64+ri {
+				return nil, errIntOverflow
+			}
+			return li - ri, nil
+		case "*":
+			product := li * ri
+			if li != 0 && (product/li != ri || li == -1 && ri == math.MinInt64 || ri == -1 && li == math.MinInt64) {
+				return nil, errIntOverflow
+			}
+			return product, nil
+		case "/", "%":
+			if ri == 0 {
+				return nil, fmt.Errorf("division by zero")
+			}
+			if ri == -1 && li == math.MinInt64 {
+				return nil, errIntOverflow
+			}
+			if op == "/" {
+				return li / ri, nil
+			}
+			return li % ri, nil
+		}
+	}
+
+	if lf, rf, ok := numbers(left, right); ok {
+		switch op {
+		case "+":
+			return lf + rf, nil
+		case "-":
+			return lf - rf, nil
+		case "*":
+			return lf * rf, nil
+		case "/":
+			return lf / rf, nil
+		case "%":
+			return math.Mod(lf, rf), nil
+		}
+	}
+
+	return nil, fmt.Errorf("no such operator: %s %s %s", typeName(left), op, typeName(right))
+}
+
+type callNode struct {
+	name string
+
+	// target is the receiver of method calls, such as the string of
+	// "foo".startsWith("f"), and nil for global functions
+	target node
+	args   []node
+}
+
+func (n *callNode) eval(env map[string]interface{}) (interface{}, error) {
+	var args []interface{}
+	if n.target != nil {
+		target, err := n.target.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, target)
+	}
+	for _, arg := range n.args {
+		value, err := arg.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, value)
+	}
+
+	fn, ok := functions[n.name]
+	if !ok {
+		return nil, fmt.Errorf("undeclared function %q", n.name)
+	}
+	return fn(args)
+}
+
+// functions are called with the receiver, for method calls, followed by
+// the arguments
+var functions = map[string]func(args []interface{}) (interface{}, error){
+	"size": func(args []interface{}) (interface{}, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("size() takes a single argument")
+		}
+		switch v := args[0].(type) {
+		case string:
+			return int64(utf8.RuneCountInString(v)), nil
+		case []interface{}:
+			return int64(len(v)), nil
+		case map[string]interface{}:
+			return int64(len(v)), nil
+		}
+		return nil, fmt.Errorf("size() is not defined for %s", typeName(args[0]))
+	},
+
+	"int": func(args []interface{}) (interface{}, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("int() takes a single argument")
+		}
+		switch v := args[0].(type) {
+		case int64:
+			return v, nil
+		case float64:
+			return int64(v), nil
+		case string:
+			i, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("cannot convert %q to int", v)
+			}
+			return i, nil
+		}
+		return nil, fmt.Errorf("cannot convert %s to int", typeName(args[0]))
+	},
+
+	"double": func(args []interface{}) (interface{}, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("double() takes a single argument")
+		}
+		switch v := args[0].(type) {
+		case int64:
+			return float64(v), nil
+		case float64:
+			return v, nil
+		case string:
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return nil, fmt.Errorf("cannot convert %q to double", v)
+			}
+			return f, nil
+		}
+		return nil, fmt.Errorf("cannot convert %s to double", typeName(args[0]))
+	},
+
+	"string": func(args []interface{}) (interface{}, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("string() takes a single argument")
+		}
+		switch v := args[0].(type) {
+		case string:
+			return v, nil
+		case int64:
+			return strconv.FormatInt(v, 10), nil
+		case float64:
+			return strconv.FormatFloat(v, 'g', -1, 64), nil
+		case bool:
+			return strconv.FormatBool(v), nil
+		case *net.IPNet:
+			return v.String(), nil
+		}
+		return nil, fmt.Errorf("cannot convert %s to string", typeName(args[0]))
+	},
+
+	"startsWith": stringFunction("startsWith", strings.HasPrefix),
+	"endsWith":   stringFunction("endsWith", strings.HasSuffix),
+	"contains":   stringFunction("contains", strings.Contains),
+
+	"matches": func(args []interface{}) (interface{}, error) {
+		s, pattern, err := stringArgs("matches", args)
+		if err != nil {
+			return nil, err
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression %q: %v", pattern, err)
+		}
+		return re.MatchString(s), nil
+	},
+
+	"cidr": func(args []interface{}) (interface{}, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("cidr() takes a single argument")
+		}
+		s, ok := args[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("cidr() takes a string, not %s", typeName(args[0]))
+		}
+		_, ipNet, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q", s)
+		}
+		return ipNet, nil
+	},
+
+	"containsIP": func(args []interface{}) (interface{}, error) {
+		if len(args) != 2 {
+			return nil, fmt.Errorf("containsIP() takes a single argument")
+		}
+		ipNet, ok := args[0].(*net.IPNet)
+		if !ok {
+			return nil, fmt.Errorf("containsIP() is not defined for %s", typeName(args[0]))
+		}
+		s, ok := args[1].(string)
+		if !ok {
+			return nil, fmt.Errorf("containsIP() takes a string, not %s", typeName(args[1]))
+		}
+		ip := net.ParseIP(s)
+		if ip == nil {
+			// Addresses may come with a port
+			if host, _, err := net.SplitHostPort(s); err == nil {
+				ip = net.ParseIP(host)
+			}
+		}
+		if ip == nil {
+			return false, nil
+		}
+		return ipNet.Contains(ip), nil
+	},
+}
+
+func stringFunction(name string, fn func(s, arg string) bool) func(args []interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		s, arg, err := stringArgs(name, args)
+		if err != nil {
+			return nil, err
+		}
+		return fn(s, arg), nil
+	}
+}
+
+// stringArgs checks the arguments of the methods of strings taking a string
+func stringArgs(name string, args []interface{}) (string, string, error) {
+	if len(args) != 2 {
+		return "", "", fmt.Errorf("%s() takes a single argument", name)
+	}
+	s, ok := args[0].(string)
+	if !ok {
+		return "", "", fmt.Errorf("%s() is not defined for %s", name, typeName(args[0]))
+	}
+	arg, ok := args[1].(string)
+	if !ok {
+		return "", "", fmt.Errorf("%s() takes a string, not %s", name, typeName(args[1]))
+	}
+	return s, arg, nil
+}
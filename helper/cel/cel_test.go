@@ -0,0 +1,535 @@
+package cel
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestProgram_Eval(t *testing.T) {
+	vars := map[string]interface{}{
+		"request": map[string]interface{}{
+			"path":        "secret/prod/db",
+			"operation":   "read",
+			"remote_addr": "10.1.2.3",
+		},
+		"token": map[string]interface{}{
+			"policies": []string{"default", "ops"},
+			"meta":     map[string]string{"team": "ops"},
+		},
+		"time": map[string]interface{}{
+			"hour":    14,
+			"weekday": 3,
+		},
+	}
+
+	cases := []struct {
+		expr     string
+		expected interface{}
+	}{
+		{`1 + 2 * 3`, int64(7)},
+		{`(1 + 2) * 3`, int64(9)},
+		{`7 / 2`, int64(3)},
+		{`7 % 2`, int64(1)},
+		{`7.0 / 2`, 3.5},
+		{`-3 + 1`, int64(-2)},
+		{`"foo" + 'bar'`, "foobar"},
+		{`"a\"b"`, `a"b`},
+		{`[1, 2] + [3]`, []interface{}{int64(1), int64(2), int64(3)}},
+		{`{"a": 1}["a"]`, int64(1)},
+		{`1 == 1.0`, true},
+		{`1 != 2 && !false`, true},
+		{`"a" < "b"`, true},
+		{`2 >= 3`, false},
+		{`true ? "yes" : "no"`, "yes"},
+		{`false ? 1 : 2 > 1 ? 3 : 4`, int64(3)},
+		{`null == null`, true},
+		{`request.path.startsWith("secret/prod/")`, true},
+		{`request.path.endsWith("/db")`, true},
+		{`request.path.contains("prod")`, true},
+		{`request.path.matches("^secret/[a-z]+/db$")`, true},
+		{`request.operation in ["create", "update"]`, false},
+		{`"ops" in token.policies`, true},
+		{`"team" in token.meta`, true},
+		{`token.meta.team == "ops"`, true},
+		{`token.meta["team"] == "ops"`, true},
+		{`has(token.meta.team)`, true},
+		{`has(token.meta.owner)`, false},
+		{`size(token.policies) == 2`, true},
+		{`token.policies.size()`, int64(2)},
+		{`size("héllo")`, int64(5)},
+		{`int("42") + 1`, int64(43)},
+		{`double(1) / 4`, 0.25},
+		{`string(42) + string(true)`, "42true"},
+		{`cidr("10.0.0.0/8").containsIP(request.remote_addr)`, true},
+		{`cidr("192.168.0.0/16").containsIP("192.168.1.1:8200")`, true},
+		{`cidr("192.168.0.0/16").containsIP(request.remote_addr)`, false},
+		{`time.hour >= 9 && time.hour < 17 && time.weekday in [1, 2, 3, 4, 5]`, true},
+
+		// Errors are ignored when the other side decides the result
+		{`token.meta.missing == "x" || true`, true},
+		{`false && token.meta.missing == "x"`, false},
+	}
+
+	for _, tc := range cases {
+		program, err := Compile(tc.expr)
+		if err != nil {
+			t.Fatalf("failed to compile %s: %v", tc.expr, err)
+		}
+		actual, err := program.Eval(vars)
+		if err != nil {
+			t.Fatalf("failed to evaluate %s: %v", tc.expr, err)
+		}
+		if !reflect.DeepEqual(actual, tc.expected) {
+			t.Fatalf("bad: %s: expected %#v, got %#v", tc.expr, tc.expected, actual)
+		}
+	}
+}
+
+func TestProgram_EvalErrors(t *testing.T) {
+	vars := map[string]interface{}{
+		"token": map[string]interface{}{
+			"meta": map[string]interface{}{},
+		},
+	}
+
+	cases := map[string]string{
+		`unknown`:                       `undeclared reference to "unknown"`,
+		`token.meta.team == "ops"`:      "no such key: team",
+		`token.meta.team == "" || 1`:    "no such key: team",
+		`1 + "a"`:                       "no such operator",
+		`1 / 0`:                         "division by zero",
+		`[1][2]`:                        "out of range",
+		`1 < "a"`:                       "cannot compare",
+		`!1`:                            "cannot negate",
+		`"a".startsWith(1)`:             "takes a string",
+		`unknown(1)`:                    `undeclared function "unknown"`,
+		`"a".matches("(")`:              "invalid regular expression",
+		`cidr("nope")`:                  "invalid CIDR",
+		`1 ? 2 : 3`:                     "condition must be a bool",
+		`1 in 2`:                        "'in' requires a list or a map",
+		`size(1)`:                       "size() is not defined for int",
+		`int("x")`:                      "cannot convert",
+		`{1: 2}`:                        "map keys must be strings",
+		`token.meta.team.size() > 0`:    "no such key: team",
+		`cidr("10.0.0.0/8").size() > 0`: "size() is not defined for cidr",
+	}
+	for expr, expected := range cases {
+		program, err := Compile(expr)
+		if err != nil {
+			t.Fatalf("failed to compile %s: %v", expr, err)
+		}
+		_, err = program.Eval(vars)
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Fatalf("bad: %s: expected error %q, got %v", expr, expected, err)
+		}
+	}
+
+	program, err := Compile(`1 + 1`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := program.EvalBool(nil); err == nil || !strings.Contains(err.Error(), "not a bool") {
+		t.Fatalf("bad: %v", err)
+	}
+}
+
+func TestCompile_Errors(t *testing.T) {
+	for _, expr := range []string{
+		``,
+		`1 +`,
+		`(1`,
+		`[1, 2`,
+		`a.`,
+		`a.1`,
+		`"unterminated`,
+		`"bad \q escape"`,
+		`1 2`,
+		`a ? b`,
+		`has(a)`,
+		`#`,
+		`{"a" 1}`,
+	} {
+		if _, err := Compile(expr); err == nil {
+			t.Fatalf("expected an error compiling %q", expr)
+		}
+	}
+}
+
+func TestProgram_Precedence(t *testing.T) {
+	cases := []struct {
+		expr     string
+		expected interface{}
+	}{
+		// Multiplicative operators bind tighter than additive ones, and
+		// both are left-associative
+		{`2 + 3 * 4 - 1`, int64(13)},
+		{`10 - 4 - 3`, int64(3)},
+		{`100 / 10 / 5`, int64(2)},
+		{`7 % 4 * 2`, int64(6)},
+		{`2 * 3 % 4`, int64(2)},
+
+		// Unary operators bind tighter than binary ones, and member access
+		// tighter than unary ones
+		{`-2 * 3`, int64(-6)},
+		{`- -2`, int64(2)},
+		{`-size([1, 2])`, int64(-2)},
+		{`-[1, 2][1]`, int64(-2)},
+		{`!true == false`, true},
+		{`!!true`, true},
+		{`!has({"a": 1}.a)`, false},
+
+		// Arithmetic binds tighter than relations
+		{`1 + 2 < 4`, true},
+		{`2 * 2 == 4`, true},
+		{`1 + 1 in [2]`, true},
+
+		// Relations are left-associative and share a level
+		{`1 < 2 == true`, true},
+		{`"a" in ["a"] == true`, true},
+		{`1 == 1 != false`, true},
+
+		// && binds tighter than ||, and both bind looser than relations
+		{`true || false && false`, true},
+		{`(true || false) && false`, false},
+		{`false && false || true`, true},
+		{`1 < 2 && 2 < 3`, true},
+		{`1 == 2 || 2 == 2`, true},
+
+		// The conditional operator binds loosest and is right-associative
+		{`true ? 1 : false ? 2 : 3`, int64(1)},
+		{`false ? 1 : true ? 2 : 3`, int64(2)},
+		{`false ? 1 : false ? 2 : 3`, int64(3)},
+		{`true ? false ? 1 : 2 : 3`, int64(2)},
+		{`1 < 2 || false ? "a" : "b"`, "a"},
+		{`true ? 1 + 1 : 0`, int64(2)},
+		{`(true ? 1 : 2) + 1`, int64(2)},
+
+		// Indexes and selections chain from left to right
+		{`{"a": {"b": [10, 20]}}.a.b[1]`, int64(20)},
+		{`{"a": {"b": [10, 20]}}["a"]["b"][0]`, int64(10)},
+		{`[[1, 2], [3]][0][1]`, int64(2)},
+		{`"abc".startsWith("a") == true`, true},
+	}
+
+	for _, tc := range cases {
+		program, err := Compile(tc.expr)
+		if err != nil {
+			t.Fatalf("failed to compile %s: %v", tc.expr, err)
+		}
+		actual, err := program.Eval(nil)
+		if err != nil {
+			t.Fatalf("failed to evaluate %s: %v", tc.expr, err)
+		}
+		if !reflect.DeepEqual(actual, tc.expected) {
+			t.Fatalf("bad: %s: expected %#v, got %#v", tc.expr, tc.expected, actual)
+		}
+	}
+}
+
+func TestProgram_TypeErrors(t *testing.T) {
+	vars := map[string]interface{}{
+		"s": "str",
+		"i": 1,
+		"l": []string{"a"},
+		"m": map[string]string{"a": "b"},
+	}
+
+	cases := map[string]string{
+		// Arithmetic is only defined on numbers, and + on strings and lists
+		`"a" + 1`:     "no such operator: string + int",
+		`1 + "a"`:     "no such operator: int + string",
+		`[1] + "a"`:   "no such operator: list + string",
+		`true + 1`:    "no such operator: bool + int",
+		`"a" - "b"`:   "no such operator: string - string",
+		`"a" * 2`:     "no such operator: string * int",
+		`m + m`:       "no such operator: map + map",
+		`null + 1`:    "no such operator: null + int",
+		`-"a"`:        "cannot negate string",
+		`-true`:       "cannot negate bool",
+		`!"a"`:        "cannot negate string",
+		`!null`:       "cannot negate null",
+		`[1] - [1]`:   "no such operator: list - list",
+		`1 % 0`:       "division by zero",
+		`i / (i - 1)`: "division by zero",
+
+		// Comparisons are only defined on numbers and strings
+		`"a" < 1`:      "cannot compare string and int",
+		`null < 1`:     "cannot compare null and int",
+		`true > false`: "cannot compare bool and bool",
+		`[1] <= [2]`:   "cannot compare list and list",
+
+		// Logical operators and conditions take bools
+		`true && 1`:    "logical operators take bools, not int",
+		`"a" || false`: "logical operators take bools, not string",
+		`s ? 1 : 2`:    "condition must be a bool, not string",
+		`null ? 1 : 2`: "condition must be a bool, not null",
+
+		// Selections, indexes and membership
+		`s.a`:      `cannot select field "a" of string`,
+		`i.a`:      `cannot select field "a" of int`,
+		`has(s.a)`: `cannot test field "a" of string`,
+		`l["a"]`:   "cannot index a list with string",
+		`m[0]`:     "cannot index a map with int",
+		`s[0]`:     "cannot index string",
+		`l[-1]`:    "index -1 out of range",
+		`l[1.0]`:   "cannot index a list with double",
+		`"a" in s`: "'in' requires a list or a map, not string",
+		`m.a.b`:    `cannot select field "b" of string`,
+
+		// Functions check their arguments
+		`size()`:                           "size() takes a single argument",
+		`size(null)`:                       "size() is not defined for null",
+		`int(true)`:                        "cannot convert bool to int",
+		`int("1.5")`:                       `cannot convert "1.5" to int`,
+		`double([1])`:                      "cannot convert list to double",
+		`double("x")`:                      `cannot convert "x" to double`,
+		`string([1])`:                      "cannot convert list to string",
+		`string(1, 2)`:                     "string() takes a single argument",
+		`s.startsWith()`:                   "startsWith() takes a single argument",
+		`i.endsWith("a")`:                  "endsWith() is not defined for int",
+		`s.contains(null)`:                 "contains() takes a string, not null",
+		`s.matches(1)`:                     "matches() takes a string, not int",
+		`cidr(1)`:                          "cidr() takes a string, not int",
+		`s.containsIP("1.2.3.4")`:          "containsIP() is not defined for string",
+		`cidr("10.0.0.0/8").containsIP(1)`: "containsIP() takes a string, not int",
+		`startsWith("a")`:                  "startsWith() takes a single argument",
+	}
+	for expr, expected := range cases {
+		program, err := Compile(expr)
+		if err != nil {
+			t.Fatalf("failed to compile %s: %v", expr, err)
+		}
+		_, err = program.Eval(vars)
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Fatalf("bad: %s: expected error %q, got %v", expr, expected, err)
+		}
+	}
+}
+
+func TestProgram_ShortCircuit(t *testing.T) {
+	// count records the calls of the right-hand sides
+	var calls int
+	functions["count"] = func(args []interface{}) (interface{}, error) {
+		calls++
+		return true, nil
+	}
+	defer delete(functions, "count")
+
+	cases := []struct {
+		expr     string
+		expected interface{}
+		calls    int
+	}{
+		{`false && count()`, false, 0},
+		{`true || count()`, true, 0},
+		{`true && count()`, true, 1},
+		{`false || count()`, true, 1},
+		{`true ? 1 : count()`, int64(1), 0},
+		{`false ? count() : 2`, int64(2), 0},
+		{`false && count() && count()`, false, 0},
+		{`true || count() && count()`, true, 0},
+
+		// The right-hand side is not evaluated when the left-hand side
+		// decides, even if it would fail
+		{`false && 1 / 0 == 1`, false, 0},
+		{`true || unknown`, true, 0},
+		{`true ? 1 : 1 / 0`, int64(1), 0},
+
+		// As in CEL, an error on the left-hand side is ignored if the
+		// right-hand side decides the result
+		{`1 / 0 == 1 || true`, true, 0},
+		{`unknown && false`, false, 0},
+		{`"a" && false`, false, 0},
+	}
+
+	for _, tc := range cases {
+		calls = 0
+		program, err := Compile(tc.expr)
+		if err != nil {
+			t.Fatalf("failed to compile %s: %v", tc.expr, err)
+		}
+		actual, err := program.Eval(nil)
+		if err != nil {
+			t.Fatalf("failed to evaluate %s: %v", tc.expr, err)
+		}
+		if !reflect.DeepEqual(actual, tc.expected) || calls != tc.calls {
+			t.Fatalf("bad: %s: expected %#v with %d calls, got %#v with %d calls", tc.expr, tc.expected, tc.calls, actual, calls)
+		}
+	}
+
+	// Errors on both sides are reported
+	for expr, expected := range map[string]string{
+		`unknown || 1 / 0 == 1`: "undeclared reference",
+		`true && 1 / 0 == 1`:    "division by zero",
+		`false || "a"`:          "logical operators take bools",
+	} {
+		program, err := Compile(expr)
+		if err != nil {
+			t.Fatalf("failed to compile %s: %v", expr, err)
+		}
+		if _, err := program.Eval(nil); err == nil || !strings.Contains(err.Error(), expected) {
+			t.Fatalf("bad: %s: expected error %q, got %v", expr, expected, err)
+		}
+	}
+}
+
+func TestProgram_IntOverflow(t *testing.T) {
+	vars := map[string]interface{}{
+		"max": int64(math.MaxInt64),
+		"min": int64(math.MinInt64),
+	}
+
+	for _, expr := range []string{
+		`max + 1`,
+		`1 + max`,
+		`min - 1`,
+		`-1 + min`,
+		`0 - min`,
+		`max * 2`,
+		`min * -1`,
+		`-1 * min`,
+		`min / -1`,
+		`min % -1`,
+		`-min`,
+		`9223372036854775808`,
+	} {
+		program, err := Compile(expr)
+		if err != nil {
+			if strings.Contains(err.Error(), "invalid number") {
+				continue
+			}
+			t.Fatalf("failed to compile %s: %v", expr, err)
+		}
+		if _, err := program.Eval(vars); err == nil {
+			t.Fatalf("expected an error evaluating %s", expr)
+		}
+	}
+
+	// Results at the bounds are valid
+	for expr, expected := range map[string]int64{
+		`max - 1 + 1`:              math.MaxInt64,
+		`min + 1 - 1`:              math.MinInt64,
+		`-max - 1`:                 math.MinInt64,
+		`max * -1`:                 -math.MaxInt64,
+		`min / 1`:                  math.MinInt64,
+		`4611686018427387904 * -2`: math.MinInt64,
+	} {
+		program, err := Compile(expr)
+		if err != nil {
+			t.Fatalf("failed to compile %s: %v", expr, err)
+		}
+		actual, err := program.Eval(vars)
+		if err != nil || actual != expected {
+			t.Fatalf("bad: %s: expected %d, got %v, err: %v", expr, expected, actual, err)
+		}
+	}
+}
+
+func TestCompile_Limits(t *testing.T) {
+	// Expressions at the limits compile
+	for _, expr := range []string{
+		`"` + strings.Repeat("a", maxExpressionLength-2) + `"`,
+		strings.Repeat("(", maxNestingDepth-1) + "1" + strings.Repeat(")", maxNestingDepth-1),
+	} {
+		if _, err := Compile(expr); err != nil {
+			t.Fatalf("failed to compile an expression of %d bytes: %v", len(expr), err)
+		}
+	}
+
+	cases := map[string]string{
+		`"` + strings.Repeat("a", maxExpressionLength-1) + `"`:                                 "longer than",
+		strings.Repeat("(", maxNestingDepth) + "1" + strings.Repeat(")", maxNestingDepth):      "nested more than",
+		strings.Repeat("[", maxNestingDepth) + "1" + strings.Repeat("]", maxNestingDepth):      "nested more than",
+		strings.Repeat("!", 2*maxNestingDepth) + "true":                                        "nested more than",
+		strings.Repeat("-", 2*maxNestingDepth) + "1":                                           "nested more than",
+		strings.Repeat("size(", maxNestingDepth) + `""` + strings.Repeat(")", maxNestingDepth): "nested more than",
+		strings.Repeat(`{"a": `, maxNestingDepth) + "1" + strings.Repeat("}", maxNestingDepth): "nested more than",
+		strings.Repeat("true ? 1 : ", maxNestingDepth) + "1":                                   "nested more than",
+		"a" + strings.Repeat("[a", maxNestingDepth) + strings.Repeat("]", maxNestingDepth):     "nested more than",
+	}
+	for expr, expected := range cases {
+		if _, err := Compile(expr); err == nil || !strings.Contains(err.Error(), expected) {
+			t.Fatalf("bad: expected error %q compiling an expression of %d bytes, got %v", expected, len(expr), err)
+		}
+	}
+
+	// Long chains of binary operators don't nest, and evaluate within the
+	// length limit
+	expr := "1" + strings.Repeat(" + 1", (maxExpressionLength-1)/4)
+	program, err := Compile(expr)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if actual, err := program.Eval(nil); err != nil || actual != int64((maxExpressionLength-1)/4+1) {
+		t.Fatalf("bad: %v, err: %v", actual, err)
+	}
+}
+
+func TestLex(t *testing.T) {
+	cases := []struct {
+		expr     string
+		expected interface{}
+	}{
+		{`"a\nb\tc\rd\\e\'f"`, "a\nb\tc\rd\\e'f"},
+		{`'single "quoted"'`, `single "quoted"`},
+		{`""`, ""},
+		{`"héllo wörld"`, "héllo wörld"},
+		{`0`, int64(0)},
+		{`007`, int64(7)},
+		{`1.5`, 1.5},
+		{`0.25`, 0.25},
+		{`[1.0][0]`, 1.0},
+		{`{"a": 1}.a`, int64(1)},
+		{`size(_x1)`, int64(2)},
+		{`size(ünïcode)`, int64(1)},
+		{"\t1 +\n 2\r\n", int64(3)},
+		{`1<=2`, true},
+		{`1>=2`, false},
+		{`1!=2`, true},
+	}
+
+	vars := map[string]interface{}{
+		"_x1":     []string{"a", "b"},
+		"ünïcode": "x",
+	}
+	for _, tc := range cases {
+		program, err := Compile(tc.expr)
+		if err != nil {
+			t.Fatalf("failed to compile %q: %v", tc.expr, err)
+		}
+		actual, err := program.Eval(vars)
+		if err != nil {
+			t.Fatalf("failed to evaluate %q: %v", tc.expr, err)
+		}
+		if !reflect.DeepEqual(actual, tc.expected) {
+			t.Fatalf("bad: %q: expected %#v, got %#v", tc.expr, tc.expected, actual)
+		}
+	}
+
+	for expr, expected := range map[string]string{
+		`1.`:        `expected a field name`,
+		`1 = 2`:     `unexpected character '='`,
+		`1 & 2`:     `unexpected character '&'`,
+		`a | b`:     `unexpected character '|'`,
+		`"a\`:       `unterminated string`,
+		`'a"`:       `unterminated string`,
+		`"\x41"`:    `invalid escape sequence`,
+		`$a`:        `unexpected character '$'`,
+		`[1,]`:      `unexpected "]"`,
+		`{"a": 1,}`: `unexpected "}"`,
+		`f(1,)`:     `unexpected ")"`,
+		`()`:        `unexpected ")"`,
+		`a.b.`:      `expected a field name`,
+		`a[1`:       `expected "]"`,
+		`a ? 1 2`:   `expected ":"`,
+		`has()`:     `has() takes a single field selection`,
+		`has(a, b)`: `has() takes a single field selection`,
+		`has(a[0])`: `has() takes a field selection`,
+	} {
+		if _, err := Compile(expr); err == nil || !strings.Contains(err.Error(), expected) {
+			t.Fatalf("bad: %q: expected error %q, got %v", expr, expected, err)
+		}
+	}
+}
@@ -59,6 +59,17 @@ type Group struct {
 	// the groups belonging to a particular bucket during invalidation of the
 	// storage key.
 	BucketKeyHash string `protobuf:"bytes,10,opt,name=bucket_key_hash,json=bucketKeyHash" json:"bucket_key_hash,omitempty"`
+	// Type indicates if this is an internal group or an external group.
+	// Memberships of internal groups are managed explicitly, while those of
+	// external groups are managed by the auth backends that the group alias
+	// belongs to.
+	Type string `protobuf:"bytes,11,opt,name=type" json:"type,omitempty"`
+	// Alias is used to mark this group as an external group and to tie it to
+	// a group in the authentication source.
+	Alias *Alias `protobuf:"bytes,12,opt,name=alias" json:"alias,omitempty"`
+	// NamespaceID is the identifier of the namespace of the group. It is
+	// empty for the root namespace.
+	NamespaceID string `protobuf:"bytes,13,opt,name=namespace_id,json=namespaceId" json:"namespace_id,omitempty"`
 }
 
 func (m *Group) Reset()                    { *m = Group{} }
@@ -136,6 +147,27 @@ func (m *Group) GetBucketKeyHash() string {
 	return ""
 }
 
+func (m *Group) GetType() string {
+	if m != nil {
+		return m.Type
+	}
+	return ""
+}
+
+func (m *Group) GetAlias() *Alias {
+	if m != nil {
+		return m.Alias
+	}
+	return nil
+}
+
+func (m *Group) GetNamespaceID() string {
+	if m != nil {
+		return m.NamespaceID
+	}
+	return ""
+}
+
 // Entity represents an entity that gets persisted and indexed.
 // Entity is fundamentally composed of zero or many aliases.
 type Entity struct {
@@ -177,6 +209,9 @@ type Entity struct {
 	// the entities belonging to a particular bucket during invalidation of the
 	// storage key.
 	BucketKeyHash string `protobuf:"bytes,9,opt,name=bucket_key_hash,json=bucketKeyHash" json:"bucket_key_hash,omitempty"`
+	// NamespaceID is the identifier of the namespace of the entity. It is
+	// empty for the root namespace.
+	NamespaceID string `protobuf:"bytes,11,opt,name=namespace_id,json=namespaceId" json:"namespace_id,omitempty"`
 }
 
 func (m *Entity) Reset()                    { *m = Entity{} }
@@ -247,6 +282,13 @@ func (m *Entity) GetBucketKeyHash() string {
 	return ""
 }
 
+func (m *Entity) GetNamespaceID() string {
+	if m != nil {
+		return m.NamespaceID
+	}
+	return ""
+}
+
 // Alias represents the alias that gets stored inside of the
 // entity object in storage and also represents in an in-memory index of an
 // alias object.
@@ -285,6 +327,9 @@ type Alias struct {
 	// which this alias is transfered over to the entity to which it
 	// currently belongs to.
 	MergedFromEntityIDs []string `protobuf:"bytes,10,rep,name=merged_from_entity_ids,json=mergedFromEntityIDs" json:"merged_from_entity_ids,omitempty"`
+	// CanonicalID is the identifier of the group to which a group alias
+	// belongs. It is not set for the aliases of entities.
+	CanonicalID string `protobuf:"bytes,11,opt,name=canonical_id,json=canonicalId" json:"canonical_id,omitempty"`
 }
 
 func (m *Alias) Reset()                    { *m = Alias{} }
@@ -362,6 +407,13 @@ func (m *Alias) GetMergedFromEntityIDs() []string {
 	return nil
 }
 
+func (m *Alias) GetCanonicalID() string {
+	if m != nil {
+		return m.CanonicalID
+	}
+	return ""
+}
+
 func init() {
 	proto.RegisterType((*Group)(nil), "identity.Group")
 	proto.RegisterType((*Entity)(nil), "identity.Entity")
@@ -371,41 +423,44 @@ func init() {
 func init() { proto.RegisterFile("types.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 570 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x94, 0xcd, 0x6e, 0xd3, 0x40,
-	0x10, 0xc7, 0xe5, 0x38, 0x1f, 0xf6, 0xa4, 0x4d, 0xcb, 0x82, 0x90, 0x15, 0x54, 0x08, 0x95, 0x40,
-	0x86, 0x83, 0x2b, 0xb5, 0x17, 0x28, 0x07, 0x54, 0x89, 0x02, 0x15, 0x42, 0x42, 0x56, 0x39, 0x5b,
-	0x9b, 0x78, 0x9a, 0xac, 0x1a, 0x7b, 0x2d, 0xef, 0x1a, 0xe1, 0x27, 0xe4, 0x39, 0x38, 0xf1, 0x1a,
-	0xc8, 0xb3, 0x76, 0x62, 0x08, 0x5f, 0x15, 0xb9, 0xd9, 0xff, 0x99, 0x1d, 0xcf, 0xce, 0xff, 0x37,
-	0x86, 0xa1, 0x2e, 0x33, 0x54, 0x41, 0x96, 0x4b, 0x2d, 0x99, 0x23, 0x62, 0x4c, 0xb5, 0xd0, 0xe5,
-	0xf8, 0xc1, 0x5c, 0xca, 0xf9, 0x12, 0x8f, 0x48, 0x9f, 0x16, 0x57, 0x47, 0x5a, 0x24, 0xa8, 0x34,
-	0x4f, 0x32, 0x93, 0x7a, 0xf8, 0xcd, 0x86, 0xde, 0x9b, 0x5c, 0x16, 0x19, 0x1b, 0x41, 0x47, 0xc4,
-	0x9e, 0x35, 0xb1, 0x7c, 0x37, 0xec, 0x88, 0x98, 0x31, 0xe8, 0xa6, 0x3c, 0x41, 0xaf, 0x43, 0x0a,
-	0x3d, 0xb3, 0x31, 0x38, 0x99, 0x5c, 0x8a, 0x99, 0x40, 0xe5, 0xd9, 0x13, 0xdb, 0x77, 0xc3, 0xd5,
-	0x3b, 0xf3, 0x61, 0x3f, 0xe3, 0x39, 0xa6, 0x3a, 0x9a, 0x57, 0xf5, 0x22, 0x11, 0x2b, 0xaf, 0x4b,
-	0x39, 0x23, 0xa3, 0xd3, 0x67, 0x2e, 0x62, 0xc5, 0x9e, 0xc2, 0xad, 0x04, 0x93, 0x29, 0xe6, 0x91,
-	0xe9, 0x92, 0x52, 0x7b, 0x94, 0xba, 0x67, 0x02, 0xe7, 0xa4, 0x57, 0xb9, 0xcf, 0xc1, 0x49, 0x50,
-	0xf3, 0x98, 0x6b, 0xee, 0xf5, 0x27, 0xb6, 0x3f, 0x3c, 0x3e, 0x08, 0x9a, 0xdb, 0x05, 0x54, 0x31,
-	0x78, 0x5f, 0xc7, 0xcf, 0x53, 0x9d, 0x97, 0xe1, 0x2a, 0x9d, 0xbd, 0x84, 0xdd, 0x59, 0x8e, 0x5c,
-	0x0b, 0x99, 0x46, 0xd5, 0xb5, 0xbd, 0xc1, 0xc4, 0xf2, 0x87, 0xc7, 0xe3, 0xc0, 0xcc, 0x24, 0x68,
-	0x66, 0x12, 0x5c, 0x36, 0x33, 0x09, 0x77, 0x9a, 0x03, 0x95, 0xc4, 0x5e, 0xc1, 0xfe, 0x92, 0x2b,
-	0x1d, 0x15, 0x59, 0xcc, 0x35, 0x9a, 0x1a, 0xce, 0x5f, 0x6b, 0x8c, 0xaa, 0x33, 0x1f, 0xe9, 0x08,
-	0x55, 0x79, 0x08, 0x3b, 0x89, 0x8c, 0xc5, 0x55, 0x19, 0x89, 0x34, 0xc6, 0xcf, 0x9e, 0x3b, 0xb1,
-	0xfc, 0x6e, 0x38, 0x34, 0xda, 0x45, 0x25, 0xb1, 0xc7, 0xb0, 0x37, 0x2d, 0x66, 0xd7, 0xa8, 0xa3,
-	0x6b, 0x2c, 0xa3, 0x05, 0x57, 0x0b, 0x0f, 0x68, 0xea, 0xbb, 0x46, 0x7e, 0x87, 0xe5, 0x5b, 0xae,
-	0x16, 0xe3, 0x17, 0xb0, 0xfb, 0xc3, 0x65, 0xd9, 0x3e, 0xd8, 0xd7, 0x58, 0xd6, 0xa6, 0x55, 0x8f,
-	0xec, 0x0e, 0xf4, 0x3e, 0xf1, 0x65, 0xd1, 0xd8, 0x66, 0x5e, 0x4e, 0x3b, 0xcf, 0xac, 0xc3, 0x2f,
-	0x36, 0xf4, 0xcd, 0x5c, 0xd9, 0x13, 0x18, 0xf0, 0xa5, 0xe0, 0x0a, 0x95, 0x67, 0xd1, 0x4c, 0xf7,
-	0xd6, 0x33, 0x3d, 0xab, 0x02, 0x61, 0x13, 0xaf, 0xa9, 0xe8, 0x6c, 0x50, 0x61, 0xb7, 0xa8, 0x38,
-	0x6d, 0x79, 0xd4, 0xa5, 0x7a, 0xf7, 0xd7, 0xf5, 0xcc, 0x27, 0xff, 0xdd, 0xa4, 0xde, 0x16, 0x4c,
-	0xea, 0xdf, 0xd8, 0x24, 0x42, 0x32, 0x9f, 0x63, 0xdc, 0x46, 0x72, 0xd0, 0x20, 0x59, 0x05, 0xd6,
-	0x48, 0xb6, 0x97, 0xc0, 0xf9, 0x69, 0x09, 0x7e, 0xe1, 0xa4, 0xbb, 0x75, 0x27, 0xbf, 0xda, 0xd0,
-	0x23, 0x9b, 0x36, 0x76, 0xf6, 0x1e, 0xb8, 0xab, 0xfe, 0xeb, 0x73, 0x0e, 0xd6, 0x8d, 0xb3, 0x03,
-	0x80, 0x44, 0x16, 0xa9, 0x8e, 0xaa, 0x5f, 0x45, 0x6d, 0xa0, 0x4b, 0xca, 0x65, 0x99, 0x21, 0x7b,
-	0x04, 0x23, 0x13, 0xe6, 0xb3, 0x19, 0x2a, 0x25, 0x73, 0xaf, 0x6b, 0x3a, 0x27, 0xf5, 0xac, 0x16,
-	0xd7, 0x55, 0x32, 0xae, 0x17, 0xe4, 0x56, 0x53, 0xe5, 0x03, 0xd7, 0x8b, 0x3f, 0xef, 0x2b, 0x35,
-	0xfd, 0x5b, 0x14, 0x1a, 0xb4, 0x06, 0x2d, 0xb4, 0x36, 0xf0, 0x70, 0xb6, 0x80, 0x87, 0x7b, 0x63,
-	0x3c, 0x4e, 0xe0, 0x6e, 0x8d, 0xc7, 0x55, 0x2e, 0x93, 0x36, 0x23, 0x40, 0x00, 0xdc, 0x36, 0xd1,
-	0xd7, 0xb9, 0x4c, 0x56, 0x9c, 0xfc, 0x97, 0xc7, 0xd3, 0x3e, 0x75, 0x75, 0xf2, 0x3d, 0x00, 0x00,
-	0xff, 0xff, 0x17, 0x1c, 0xfc, 0x89, 0xd8, 0x05, 0x00, 0x00,
+	// 622 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x94, 0x5d, 0x6f, 0xd3, 0x3c,
+	0x14, 0xc7, 0xd5, 0x26, 0x69, 0x93, 0x93, 0xb6, 0xdb, 0xe3, 0x07, 0x21, 0xab, 0x68, 0xd0, 0x4d,
+	0x02, 0x15, 0x2e, 0x32, 0x69, 0xbb, 0x81, 0x71, 0x81, 0x26, 0x31, 0x60, 0x42, 0x48, 0xa8, 0x1a,
+	0xd7, 0x91, 0x1b, 0x7b, 0xad, 0xb5, 0x26, 0x8e, 0x62, 0x07, 0x91, 0x7b, 0xbe, 0x1a, 0x5f, 0x86,
+	0x4f, 0x81, 0x6c, 0x27, 0x6d, 0xd8, 0xc6, 0xcb, 0x44, 0xef, 0x92, 0xff, 0x39, 0xfe, 0xfb, 0xbc,
+	0xfc, 0x12, 0x08, 0x55, 0x95, 0x33, 0x19, 0xe5, 0x85, 0x50, 0x02, 0xf9, 0x9c, 0xb2, 0x4c, 0x71,
+	0x55, 0x8d, 0x1f, 0x2d, 0x84, 0x58, 0xac, 0xd8, 0xa1, 0xd1, 0xe7, 0xe5, 0xe5, 0xa1, 0xe2, 0x29,
+	0x93, 0x8a, 0xa4, 0xb9, 0x4d, 0x3d, 0xf8, 0xe6, 0x82, 0xf7, 0xb6, 0x10, 0x65, 0x8e, 0x46, 0xd0,
+	0xe5, 0x14, 0x77, 0x26, 0x9d, 0x69, 0x30, 0xeb, 0x72, 0x8a, 0x10, 0xb8, 0x19, 0x49, 0x19, 0xee,
+	0x1a, 0xc5, 0x3c, 0xa3, 0x31, 0xf8, 0xb9, 0x58, 0xf1, 0x84, 0x33, 0x89, 0x9d, 0x89, 0x33, 0x0d,
+	0x66, 0xeb, 0x77, 0x34, 0x85, 0xdd, 0x9c, 0x14, 0x2c, 0x53, 0xf1, 0x42, 0xfb, 0xc5, 0x9c, 0x4a,
+	0xec, 0x9a, 0x9c, 0x91, 0xd5, 0xcd, 0x35, 0xe7, 0x54, 0xa2, 0x67, 0xf0, 0x5f, 0xca, 0xd2, 0x39,
+	0x2b, 0x62, 0x5b, 0xa5, 0x49, 0xf5, 0x4c, 0xea, 0x8e, 0x0d, 0x9c, 0x19, 0x5d, 0xe7, 0xbe, 0x00,
+	0x3f, 0x65, 0x8a, 0x50, 0xa2, 0x08, 0xee, 0x4d, 0x9c, 0x69, 0x78, 0xb4, 0x17, 0x35, 0xdd, 0x45,
+	0xc6, 0x31, 0xfa, 0x50, 0xc7, 0xcf, 0x32, 0x55, 0x54, 0xb3, 0x75, 0x3a, 0x7a, 0x05, 0xc3, 0xa4,
+	0x60, 0x44, 0x71, 0x91, 0xc5, 0xba, 0x6d, 0xdc, 0x9f, 0x74, 0xa6, 0xe1, 0xd1, 0x38, 0xb2, 0x33,
+	0x89, 0x9a, 0x99, 0x44, 0x17, 0xcd, 0x4c, 0x66, 0x83, 0xe6, 0x80, 0x96, 0xd0, 0x6b, 0xd8, 0x5d,
+	0x11, 0xa9, 0xe2, 0x32, 0xa7, 0x44, 0x31, 0xeb, 0xe1, 0xff, 0xd1, 0x63, 0xa4, 0xcf, 0x7c, 0x32,
+	0x47, 0x8c, 0xcb, 0x3e, 0x0c, 0x52, 0x41, 0xf9, 0x65, 0x15, 0xf3, 0x8c, 0xb2, 0x2f, 0x38, 0x98,
+	0x74, 0xa6, 0xee, 0x2c, 0xb4, 0xda, 0xb9, 0x96, 0xd0, 0x13, 0xd8, 0x99, 0x97, 0xc9, 0x15, 0x53,
+	0xf1, 0x15, 0xab, 0xe2, 0x25, 0x91, 0x4b, 0x0c, 0x66, 0xea, 0x43, 0x2b, 0xbf, 0x67, 0xd5, 0x3b,
+	0x22, 0x97, 0x7a, 0x25, 0x7a, 0xcd, 0x38, 0xb4, 0x2b, 0xd1, 0xcf, 0xe8, 0x31, 0x78, 0x64, 0xc5,
+	0x89, 0xc4, 0x03, 0x53, 0xd9, 0xce, 0x66, 0x3a, 0xa7, 0x5a, 0x9e, 0xd9, 0xa8, 0xae, 0x42, 0x6f,
+	0x50, 0xe6, 0x24, 0x61, 0x31, 0xa7, 0x78, 0x68, 0x2c, 0xc2, 0xb5, 0x76, 0x4e, 0xc7, 0x2f, 0x61,
+	0xf8, 0xd3, 0x28, 0xd1, 0x2e, 0x38, 0x57, 0xac, 0xaa, 0x91, 0xd0, 0x8f, 0xe8, 0x1e, 0x78, 0x9f,
+	0xc9, 0xaa, 0x6c, 0xa0, 0xb0, 0x2f, 0x27, 0xdd, 0xe7, 0x9d, 0x83, 0xef, 0x0e, 0xf4, 0xec, 0xd6,
+	0xd0, 0x53, 0xe8, 0x9b, 0x3b, 0x99, 0xc4, 0x9d, 0x89, 0x73, 0x5b, 0x4d, 0x4d, 0xbc, 0x66, 0xae,
+	0x7b, 0x83, 0x39, 0xa7, 0xc5, 0xdc, 0x49, 0x8b, 0x00, 0xd7, 0xf8, 0x3d, 0xdc, 0xf8, 0xd9, 0x2b,
+	0xff, 0x1e, 0x01, 0x6f, 0x0b, 0x08, 0xf4, 0xee, 0x8c, 0x80, 0x01, 0xbe, 0x58, 0x30, 0xda, 0x06,
+	0xbe, 0xdf, 0x00, 0xaf, 0x03, 0x1b, 0xe0, 0xdb, 0x9f, 0x98, 0x7f, 0xed, 0x13, 0xbb, 0x85, 0x93,
+	0xe0, 0x36, 0x4e, 0xae, 0x2f, 0x3b, 0xdc, 0xf2, 0xb2, 0xbf, 0xba, 0xe0, 0x99, 0x4d, 0xde, 0xf8,
+	0x69, 0x3c, 0x80, 0x60, 0xdd, 0x62, 0x7d, 0xce, 0x67, 0x75, 0x6f, 0x68, 0x0f, 0x20, 0x15, 0x65,
+	0xa6, 0x62, 0x03, 0xb1, 0xdd, 0x71, 0x60, 0x94, 0x0b, 0x4b, 0xf2, 0xc8, 0x86, 0x49, 0x92, 0x30,
+	0x29, 0x45, 0x81, 0x5d, 0xdb, 0x9c, 0x51, 0x4f, 0x6b, 0x71, 0xe3, 0x92, 0x13, 0xb5, 0xc4, 0x5e,
+	0xcb, 0xe5, 0x23, 0x51, 0xcb, 0xdf, 0xff, 0x30, 0x4c, 0xd1, 0xbf, 0xa4, 0xa5, 0xa1, 0xaf, 0xdf,
+	0xa2, 0xef, 0x06, 0x41, 0xfe, 0x16, 0x08, 0x0a, 0xee, 0x4c, 0xd0, 0x31, 0xdc, 0xaf, 0x09, 0xba,
+	0x2c, 0x44, 0xda, 0xc6, 0x08, 0x0c, 0x23, 0xff, 0xdb, 0xe8, 0x9b, 0x42, 0xa4, 0x1b, 0x94, 0xf6,
+	0x61, 0x90, 0x90, 0x4c, 0x64, 0x3c, 0x21, 0xab, 0x16, 0x06, 0x6b, 0xed, 0x1f, 0x31, 0x98, 0xf7,
+	0x4c, 0xe1, 0xc7, 0x3f, 0x06, 0x00, 0xeb, 0x3d, 0xdd, 0xa3, 0x7c, 0x06, 0x00, 0x00,
 }
@@ -247,7 +247,7 @@ func (lm *LockManager) getPolicyCommon(req PolicyRequest, lockType bool) (*Polic
 				return nil, nil, false, fmt.Errorf("convergent encryption requires derivation to be enabled")
 			}
 
-		case KeyType_ECDSA_P256:
+		case KeyType_ECDSA_P256, KeyType_RSA2048, KeyType_RSA4096:
 			if req.Derived || req.Convergent {
 				lm.UnlockPolicy(lock, lockType)
 				return nil, nil, false, fmt.Errorf("key derivation and convergent encryption not supported for keys of type %v", req.KeyType)
@@ -273,7 +273,7 @@ func (lm *LockManager) getPolicyCommon(req PolicyRequest, lockType bool) (*Polic
 		if req.Derived {
 			p.KDF = Kdf_hkdf_sha256
 			p.ConvergentEncryption = req.Convergent
-			p.ConvergentVersion = 2
+			p.ConvergentVersion = LatestConvergentVersion
 		}
 
 		err = p.Rotate(req.Storage)
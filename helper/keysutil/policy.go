@@ -9,10 +9,13 @@ import (
 	"crypto/elliptic"
 	"crypto/hmac"
 	"crypto/rand"
+	"crypto/rsa"
 	"crypto/sha256"
+	_ "crypto/sha512"
 	"crypto/x509"
 	"encoding/asn1"
 	"encoding/base64"
+	"encoding/binary"
 	"encoding/json"
 	"encoding/pem"
 	"fmt"
@@ -44,8 +47,14 @@ const (
 	KeyType_AES256_GCM96 = iota
 	KeyType_ECDSA_P256
 	KeyType_ED25519
+	KeyType_RSA2048
+	KeyType_RSA4096
 )
 
+// LatestConvergentVersion is the convergent encryption version used for newly
+// created keys
+const LatestConvergentVersion = 3
+
 const ErrTooOld = "ciphertext or signature version is disallowed by policy (too old)"
 
 type SigningResult struct {
@@ -77,7 +86,7 @@ func (kt KeyType) DecryptionSupported() bool {
 
 func (kt KeyType) SigningSupported() bool {
 	switch kt {
-	case KeyType_ECDSA_P256, KeyType_ED25519:
+	case KeyType_ECDSA_P256, KeyType_ED25519, KeyType_RSA2048, KeyType_RSA4096:
 		return true
 	}
 	return false
@@ -85,7 +94,7 @@ func (kt KeyType) SigningSupported() bool {
 
 func (kt KeyType) HashSignatureInput() bool {
 	switch kt {
-	case KeyType_ECDSA_P256:
+	case KeyType_ECDSA_P256, KeyType_RSA2048, KeyType_RSA4096:
 		return true
 	}
 	return false
@@ -107,11 +116,34 @@ func (kt KeyType) String() string {
 		return "ecdsa-p256"
 	case KeyType_ED25519:
 		return "ed25519"
+	case KeyType_RSA2048:
+		return "rsa-2048"
+	case KeyType_RSA4096:
+		return "rsa-4096"
 	}
 
 	return "[unknown]"
 }
 
+// HashAlgorithm maps a signature hash algorithm name, as accepted by the
+// sign and verify endpoints, to its crypto.Hash identifier. The "none"
+// algorithm maps to crypto.Hash(0).
+func HashAlgorithm(algorithm string) (crypto.Hash, bool) {
+	switch algorithm {
+	case "none":
+		return crypto.Hash(0), true
+	case "sha2-224":
+		return crypto.SHA224, true
+	case "sha2-256":
+		return crypto.SHA256, true
+	case "sha2-384":
+		return crypto.SHA384, true
+	case "sha2-512":
+		return crypto.SHA512, true
+	}
+	return crypto.Hash(0), false
+}
+
 // KeyEntry stores the key and metadata
 type KeyEntry struct {
 	// AES or some other kind that is a pure byte slice like ED25519
@@ -127,6 +159,8 @@ type KeyEntry struct {
 	EC_Y *big.Int `json:"ec_y"`
 	EC_D *big.Int `json:"ec_d"`
 
+	RSAKey *rsa.PrivateKey `json:"rsa_key"`
+
 	// The public key in an appropriate format for the type of key
 	FormattedPublicKey string `json:"public_key"`
 
@@ -196,11 +230,26 @@ type Policy struct {
 	// Whether the key is allowed to be deleted
 	DeletionAllowed bool `json:"deletion_allowed"`
 
-	// The version of the convergent nonce to use
+	// The version of the convergent nonce to use. Version 1 requires the
+	// caller to supply the nonce, version 2 derives it from the context and
+	// plaintext, and version 3 additionally keys the derivation with the
+	// key's HMAC key.
 	ConvergentVersion int `json:"convergent_version"`
 
 	// The type of key
 	Type KeyType `json:"type"`
+
+	// RotationPeriod, if non-zero, is how long the latest key version may be
+	// used before it is automatically rotated
+	RotationPeriod time.Duration `json:"rotation_period"`
+
+	// Whether automatic rotation also permanently deletes archived key
+	// versions below the minimum decryption version
+	TrimOnRotation bool `json:"trim_on_rotation"`
+
+	// The lowest key version whose material is still stored. Versions below
+	// this have been trimmed and can never be made available again.
+	MinAvailableVersion int `json:"min_available_version"`
 }
 
 // ArchivedKeys stores old keys. This is used to keep the key loading time sane
@@ -277,6 +326,9 @@ func (p *Policy) handleArchiving(storage logical.Storage) error {
 	case p.MinDecryptionVersion > p.LatestVersion:
 		return fmt.Errorf("minimum decryption version of %d is greater than the latest version %d",
 			p.MinDecryptionVersion, p.LatestVersion)
+	case p.MinDecryptionVersion < p.MinAvailableVersion:
+		return fmt.Errorf("minimum decryption version of %d is less than the minimum available version %d; older versions have been trimmed",
+			p.MinDecryptionVersion, p.MinAvailableVersion)
 	}
 
 	archive, err := p.LoadArchive(storage)
@@ -574,11 +626,16 @@ func (p *Policy) Encrypt(ver int, context, nonce []byte, value string) (string,
 			if len(nonce) != gcm.NonceSize() {
 				return "", errutil.UserError{Err: fmt.Sprintf("base64-decoded nonce must be %d bytes long when using convergent encryption with this key", gcm.NonceSize())}
 			}
-		default:
+		case 2:
 			nonceHmac := hmac.New(sha256.New, context)
 			nonceHmac.Write(plaintext)
 			nonceSum := nonceHmac.Sum(nil)
 			nonce = nonceSum[:gcm.NonceSize()]
+		default:
+			nonce, err = convergentNonce(key, context, plaintext, gcm.NonceSize())
+			if err != nil {
+				return "", err
+			}
 		}
 	} else {
 		// Compute random nonce
@@ -693,6 +750,27 @@ func (p *Policy) Decrypt(context, nonce []byte, value string) (string, error) {
 	return base64.StdEncoding.EncodeToString(plain), nil
 }
 
+// convergentNonce derives the nonce of version 3 convergent encryption from
+// the encryption key, the context and the plaintext. The HMAC key is derived
+// with HKDF under its own label, so that the nonce cannot be obtained from
+// the HMAC endpoint, and the context is length-prefixed so that different
+// contexts and plaintexts cannot produce the same input.
+func convergentNonce(key, context, plaintext []byte, size int) ([]byte, error) {
+	nonceKey := make([]byte, 32)
+	reader := hkdf.New(sha256.New, key, nil, []byte("transit-convergent-nonce"))
+	if _, err := io.ReadFull(reader, nonceKey); err != nil {
+		return nil, errutil.InternalError{Err: fmt.Sprintf("error reading derived bytes: %v", err)}
+	}
+
+	var length [8]byte
+	binary.BigEndian.PutUint64(length[:], uint64(len(context)))
+	nonceHmac := hmac.New(sha256.New, nonceKey)
+	nonceHmac.Write(length[:])
+	nonceHmac.Write(context)
+	nonceHmac.Write(plaintext)
+	return nonceHmac.Sum(nil)[:size], nil
+}
+
 func (p *Policy) HMACKey(version int) ([]byte, error) {
 	switch {
 	case version < 0:
@@ -708,7 +786,32 @@ func (p *Policy) HMACKey(version int) ([]byte, error) {
 	return p.Keys[version].HMACKey, nil
 }
 
-func (p *Policy) Sign(ver int, context, input []byte) (*SigningResult, error) {
+// DeriveDataKey deterministically derives a data key of the given length in
+// bytes from the HMAC key of the given version and the context. HKDF is used
+// rather than a plain HMAC so that the derived key cannot be obtained from
+// the HMAC endpoint.
+func (p *Policy) DeriveDataKey(ver int, context []byte, length int) ([]byte, error) {
+	if len(context) == 0 {
+		return nil, errutil.UserError{Err: "missing 'context' for data key derivation"}
+	}
+
+	key, err := p.HMACKey(ver)
+	if err != nil {
+		return nil, errutil.UserError{Err: err.Error()}
+	}
+
+	reader := hkdf.New(sha256.New, key, []byte("transit-datakey"), context)
+	derived := make([]byte, length)
+	if _, err := io.ReadFull(reader, derived); err != nil {
+		return nil, errutil.InternalError{Err: fmt.Sprintf("error reading derived bytes: %v", err)}
+	}
+	return derived, nil
+}
+
+// Sign signs the given input with the requested key version. For key types
+// that hash their input, hashAlgorithm must identify the hash that was used
+// to compute input.
+func (p *Policy) Sign(ver int, context, input []byte, hashAlgorithm crypto.Hash) (*SigningResult, error) {
 	if !p.Type.SigningSupported() {
 		return nil, fmt.Errorf("message signing not supported for key type %v", p.Type)
 	}
@@ -773,6 +876,21 @@ func (p *Policy) Sign(ver int, context, input []byte) (*SigningResult, error) {
 			return nil, err
 		}
 
+	case KeyType_RSA2048, KeyType_RSA4096:
+		if hashAlgorithm == crypto.Hash(0) {
+			return nil, errutil.UserError{Err: fmt.Sprintf("a hash algorithm must be specified for key type %v", p.Type)}
+		}
+
+		key := p.Keys[ver].RSAKey
+		if key == nil {
+			return nil, errutil.InternalError{Err: "no RSA key found for the requested version"}
+		}
+
+		sig, err = rsa.SignPSS(rand.Reader, key, hashAlgorithm, input, nil)
+		if err != nil {
+			return nil, err
+		}
+
 	default:
 		return nil, fmt.Errorf("unsupported key type %v", p.Type)
 	}
@@ -788,7 +906,10 @@ func (p *Policy) Sign(ver int, context, input []byte) (*SigningResult, error) {
 	return res, nil
 }
 
-func (p *Policy) VerifySignature(context, input []byte, sig string) (bool, error) {
+// VerifySignature verifies the given signature against the input. For key
+// types that hash their input, hashAlgorithm must identify the hash that was
+// used to compute input.
+func (p *Policy) VerifySignature(context, input []byte, sig string, hashAlgorithm crypto.Hash) (bool, error) {
 	if !p.Type.SigningSupported() {
 		return false, errutil.UserError{Err: fmt.Sprintf("message verification not supported for key type %v", p.Type)}
 	}
@@ -857,6 +978,19 @@ func (p *Policy) VerifySignature(context, input []byte, sig string) (bool, error
 
 		return ed25519.Verify(key.Public().(ed25519.PublicKey), input, sigBytes), nil
 
+	case KeyType_RSA2048, KeyType_RSA4096:
+		if hashAlgorithm == crypto.Hash(0) {
+			return false, errutil.UserError{Err: fmt.Sprintf("a hash algorithm must be specified for key type %v", p.Type)}
+		}
+
+		key := p.Keys[ver].RSAKey
+		if key == nil {
+			return false, errutil.InternalError{Err: "no RSA key found for the requested version"}
+		}
+
+		err = rsa.VerifyPSS(&key.PublicKey, hashAlgorithm, input, sigBytes, nil)
+		return err == nil, nil
+
 	default:
 		return false, errutil.InternalError{Err: fmt.Sprintf("unsupported key type %v", p.Type)}
 	}
@@ -923,6 +1057,30 @@ func (p *Policy) Rotate(storage logical.Storage) error {
 		}
 		entry.Key = pri
 		entry.FormattedPublicKey = base64.StdEncoding.EncodeToString(pub)
+
+	case KeyType_RSA2048, KeyType_RSA4096:
+		bitSize := 2048
+		if p.Type == KeyType_RSA4096 {
+			bitSize = 4096
+		}
+
+		privKey, err := rsa.GenerateKey(rand.Reader, bitSize)
+		if err != nil {
+			return err
+		}
+		entry.RSAKey = privKey
+		derBytes, err := x509.MarshalPKIXPublicKey(privKey.Public())
+		if err != nil {
+			return fmt.Errorf("error marshaling public key: %s", err)
+		}
+		pemBytes := pem.EncodeToMemory(&pem.Block{
+			Type:  "PUBLIC KEY",
+			Bytes: derBytes,
+		})
+		if pemBytes == nil || len(pemBytes) == 0 {
+			return fmt.Errorf("error PEM-encoding public key")
+		}
+		entry.FormattedPublicKey = string(pemBytes)
 	}
 
 	p.Keys[p.LatestVersion] = entry
@@ -937,6 +1095,46 @@ func (p *Policy) Rotate(storage logical.Storage) error {
 	return p.Persist(storage)
 }
 
+// NeedsRotation returns whether the policy has a rotation period and its
+// latest key version is older than that period
+func (p *Policy) NeedsRotation(now time.Time) bool {
+	if p.RotationPeriod <= 0 {
+		return false
+	}
+	latest, ok := p.Keys[p.LatestVersion]
+	if !ok {
+		return false
+	}
+	created := latest.CreationTime
+	if created.IsZero() {
+		created = time.Unix(latest.DeprecatedCreationTime, 0)
+	}
+	return now.Sub(created) >= p.RotationPeriod
+}
+
+// Trim permanently deletes the material of all key versions below the
+// minimum decryption version from the archive. Afterwards the minimum
+// decryption version can no longer be lowered past this point.
+func (p *Policy) Trim(storage logical.Storage) error {
+	if p.MinDecryptionVersion <= p.MinAvailableVersion {
+		return nil
+	}
+
+	archive, err := p.LoadArchive(storage)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < p.MinDecryptionVersion && i < len(archive.Keys); i++ {
+		archive.Keys[i] = KeyEntry{}
+	}
+	if err := p.storeArchive(archive, storage); err != nil {
+		return err
+	}
+
+	p.MinAvailableVersion = p.MinDecryptionVersion
+	return p.Persist(storage)
+}
+
 func (p *Policy) MigrateKeyToKeysMap() {
 	now := time.Now()
 	p.Keys = keyEntryMap{
@@ -2,6 +2,7 @@ package locksutil
 
 import (
 	"crypto/md5"
+	"sort"
 	"sync"
 )
 
@@ -30,3 +31,25 @@ func LockIndexForKey(key string) uint8 {
 func LockForKey(locks []*LockEntry, key string) *LockEntry {
 	return locks[LockIndexForKey(key)]
 }
+
+// LocksForKeys returns the distinct locks of the given keys, sorted by their
+// index so that taking them in order can't deadlock with another caller
+func LocksForKeys(locks []*LockEntry, keys []string) []*LockEntry {
+	seen := make(map[uint8]bool, len(keys))
+	indexes := make([]int, 0, len(keys))
+	for _, key := range keys {
+		index := LockIndexForKey(key)
+		if seen[index] {
+			continue
+		}
+		seen[index] = true
+		indexes = append(indexes, int(index))
+	}
+	sort.Ints(indexes)
+
+	ret := make([]*LockEntry, 0, len(indexes))
+	for _, index := range indexes {
+		ret = append(ret, locks[index])
+	}
+	return ret
+}
@@ -0,0 +1,172 @@
+// Package passwordpolicy validates and generates passwords according to the
+// password policies configured in Vault.
+package passwordpolicy
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+const (
+	lowercaseChars = "abcdefghijklmnopqrstuvwxyz"
+	uppercaseChars = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	digitChars     = "0123456789"
+
+	// DefaultSymbols is the set of symbols used when a policy doesn't set one
+	DefaultSymbols = "!#$%&*+-.=?@^_~"
+)
+
+// Policy describes the passwords accepted, and generated, by Vault
+type Policy struct {
+	Name string `json:"name" structs:"name" mapstructure:"name"`
+
+	// Length is the length of the generated passwords, and the minimum length
+	// of the validated ones
+	Length int `json:"length" structs:"length" mapstructure:"length"`
+
+	// Minimum number of characters of each class
+	MinLowercase int `json:"min_lowercase" structs:"min_lowercase" mapstructure:"min_lowercase"`
+	MinUppercase int `json:"min_uppercase" structs:"min_uppercase" mapstructure:"min_uppercase"`
+	MinDigits    int `json:"min_digits" structs:"min_digits" mapstructure:"min_digits"`
+	MinSymbols   int `json:"min_symbols" structs:"min_symbols" mapstructure:"min_symbols"`
+
+	// Symbols is the set of characters of the symbol class
+	Symbols string `json:"symbols" structs:"symbols" mapstructure:"symbols"`
+
+	// BannedPasswords are rejected regardless of case
+	BannedPasswords []string `json:"banned_passwords" structs:"banned_passwords" mapstructure:"banned_passwords"`
+
+	// History is the number of previous passwords of a user that can't be
+	// reused. It is enforced by the backends storing the passwords.
+	History int `json:"history" structs:"history" mapstructure:"history"`
+}
+
+// Validate checks the consistency of the policy
+func (p *Policy) Validate() error {
+	switch {
+	case p.Name == "":
+		return fmt.Errorf("missing policy name")
+	case p.Length <= 0:
+		return fmt.Errorf("length must be positive")
+	case p.MinLowercase < 0 || p.MinUppercase < 0 || p.MinDigits < 0 || p.MinSymbols < 0:
+		return fmt.Errorf("minimum numbers of characters cannot be negative")
+	case p.MinLowercase+p.MinUppercase+p.MinDigits+p.MinSymbols > p.Length:
+		return fmt.Errorf("minimum numbers of characters cannot exceed the length")
+	case p.History < 0:
+		return fmt.Errorf("history cannot be negative")
+	}
+
+	for _, c := range p.Symbols {
+		if c <= ' ' || c > '~' || strings.ContainsRune(lowercaseChars+uppercaseChars+digitChars, c) {
+			return fmt.Errorf("symbols must be printable ASCII characters other than letters and digits")
+		}
+	}
+	return nil
+}
+
+func (p *Policy) symbols() string {
+	if p.Symbols == "" {
+		return DefaultSymbols
+	}
+	return p.Symbols
+}
+
+// Check returns an error describing why the password doesn't satisfy the
+// policy, if it doesn't
+func (p *Policy) Check(password string) error {
+	if len(password) < p.Length {
+		return fmt.Errorf("password must be at least %d characters long", p.Length)
+	}
+
+	var lower, upper, digits, symbols int
+	for _, c := range password {
+		switch {
+		case strings.ContainsRune(lowercaseChars, c):
+			lower++
+		case strings.ContainsRune(uppercaseChars, c):
+			upper++
+		case strings.ContainsRune(digitChars, c):
+			digits++
+		case strings.ContainsRune(p.symbols(), c):
+			symbols++
+		}
+	}
+
+	switch {
+	case lower < p.MinLowercase:
+		return fmt.Errorf("password must contain at least %d lowercase letters", p.MinLowercase)
+	case upper < p.MinUppercase:
+		return fmt.Errorf("password must contain at least %d uppercase letters", p.MinUppercase)
+	case digits < p.MinDigits:
+		return fmt.Errorf("password must contain at least %d digits", p.MinDigits)
+	case symbols < p.MinSymbols:
+		return fmt.Errorf("password must contain at least %d of the symbols %q", p.MinSymbols, p.symbols())
+	}
+
+	for _, banned := range p.BannedPasswords {
+		if strings.EqualFold(password, banned) {
+			return fmt.Errorf("password is banned")
+		}
+	}
+	return nil
+}
+
+// Generate returns a random password satisfying the policy
+func (p *Policy) Generate() (string, error) {
+	all := lowercaseChars + uppercaseChars + digitChars + p.symbols()
+
+	// Banned passwords are unlikely to be generated, but are retried
+	for attempt := 0; attempt < 10; attempt++ {
+		var chars []byte
+		for _, class := range []struct {
+			charset string
+			min     int
+		}{
+			{lowercaseChars, p.MinLowercase},
+			{uppercaseChars, p.MinUppercase},
+			{digitChars, p.MinDigits},
+			{p.symbols(), p.MinSymbols},
+		} {
+			for i := 0; i < class.min; i++ {
+				c, err := randomChar(class.charset)
+				if err != nil {
+					return "", err
+				}
+				chars = append(chars, c)
+			}
+		}
+		for len(chars) < p.Length {
+			c, err := randomChar(all)
+			if err != nil {
+				return "", err
+			}
+			chars = append(chars, c)
+		}
+
+		// Shuffle so that the required characters are not all at the start
+		for i := len(chars) - 1; i > 0; i-- {
+			j, err := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+			if err != nil {
+				return "", err
+			}
+			chars[i], chars[j.Int64()] = chars[j.Int64()], chars[i]
+		}
+
+		password := string(chars)
+		if p.Check(password) == nil {
+			return password, nil
+		}
+	}
+
+	return "", fmt.Errorf("failed to generate a password satisfying policy %q", p.Name)
+}
+
+func randomChar(charset string) (byte, error) {
+	i, err := rand.Int(rand.Reader, big.NewInt(int64(len(charset))))
+	if err != nil {
+		return 0, err
+	}
+	return charset[i.Int64()], nil
+}
@@ -0,0 +1,97 @@
+package passwordpolicy
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPolicy_Validate(t *testing.T) {
+	valid := Policy{
+		Name:         "test",
+		Length:       8,
+		MinLowercase: 2,
+		MinUppercase: 2,
+		MinDigits:    2,
+		MinSymbols:   2,
+	}
+	if err := valid.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, modify := range []func(p *Policy){
+		func(p *Policy) { p.Name = "" },
+		func(p *Policy) { p.Length = 0 },
+		func(p *Policy) { p.MinDigits = -1 },
+		func(p *Policy) { p.MinDigits = 3 },
+		func(p *Policy) { p.Symbols = "!a" },
+		func(p *Policy) { p.Symbols = "! " },
+		func(p *Policy) { p.History = -1 },
+	} {
+		p := valid
+		modify(&p)
+		if err := p.Validate(); err == nil {
+			t.Fatalf("expected an error for %#v", p)
+		}
+	}
+}
+
+func TestPolicy_Check(t *testing.T) {
+	p := &Policy{
+		Name:            "test",
+		Length:          8,
+		MinLowercase:    1,
+		MinUppercase:    1,
+		MinDigits:       1,
+		MinSymbols:      1,
+		Symbols:         "!?",
+		BannedPasswords: []string{"Passw0rd!"},
+	}
+
+	for password, expected := range map[string]string{
+		"aB3!":      "at least 8 characters",
+		"ABCDEF3!":  "lowercase",
+		"abcdef3!":  "uppercase",
+		"abcdEF!!":  "digits",
+		"abcdEF33":  "symbols",
+		"abcdEF3#":  "symbols",
+		"pASSW0RD!": "banned",
+	} {
+		err := p.Check(password)
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Fatalf("expected an error containing %q for %q; got: %v", expected, password, err)
+		}
+	}
+
+	if err := p.Check("abcdEF3?"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPolicy_Generate(t *testing.T) {
+	p := &Policy{
+		Name:         "test",
+		Length:       16,
+		MinLowercase: 3,
+		MinUppercase: 3,
+		MinDigits:    3,
+		MinSymbols:   3,
+	}
+
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		password, err := p.Generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(password) != p.Length {
+			t.Fatalf("bad: length of %q", password)
+		}
+		if err := p.Check(password); err != nil {
+			t.Fatalf("generated password %q doesn't satisfy the policy: %v", password, err)
+		}
+		if seen[password] {
+			t.Fatalf("password %q generated twice", password)
+		}
+		seen[password] = true
+	}
+}
@@ -0,0 +1,44 @@
+package pluginutil
+
+import (
+	"errors"
+
+	plugin "github.com/hashicorp/go-plugin"
+)
+
+// ErrPluginExited is returned when probing a plugin whose process exited
+var ErrPluginExited = errors.New("plugin process exited")
+
+// HealthMonitor is implemented by the system views which probe the liveness
+// of the plugin processes run by their backends and restart the processes
+// which stop answering
+type HealthMonitor interface {
+	// MonitorPlugin starts probing the process. The returned function stops
+	// monitoring it.
+	MonitorPlugin(p *MonitoredPlugin) (stop func())
+}
+
+// MonitoredPlugin is a plugin process watched by a HealthMonitor
+type MonitoredPlugin struct {
+	// Name is the name of the plugin in the plugin catalog
+	Name string
+
+	// Instance tells apart the processes of a plugin run by the same backend,
+	// such as the connections of the database backend
+	Instance string
+
+	// Ping returns an error if the process is not running or doesn't answer
+	Ping func() error
+
+	// Restart replaces the process with a new one
+	Restart func() error
+}
+
+// PingPlugin checks that the process of a plugin is still running and
+// answers over its connection
+func PingPlugin(client *plugin.Client, protocol plugin.ClientProtocol) error {
+	if client.Exited() {
+		return ErrPluginExited
+	}
+	return protocol.Ping()
+}
@@ -19,6 +19,15 @@ type hclogFaker struct {
 	implied []interface{}
 }
 
+// NewHCLogger returns an hclog.Logger writing to the given logxi logger under
+// the given name, for the libraries logging through hclog
+func NewHCLogger(logger log.Logger, name string) hclog.Logger {
+	return &hclogFaker{
+		logger: logger,
+		name:   name,
+	}
+}
+
 func (f *hclogFaker) buildLog(msg string, args ...interface{}) (string, []interface{}) {
 	if f.name != "" {
 		msg = fmt.Sprintf("%s: %s", f.name, msg)
@@ -29,6 +29,12 @@ type RunnerUtil interface {
 	MlockEnabled() bool
 }
 
+// VersionLooker is implemented by the system views which can look up the
+// versions of the plugins registered in the plugin catalog
+type VersionLooker interface {
+	LookupPluginVersion(name, version string) (*PluginRunner, error)
+}
+
 // LookWrapper defines the functions for both Looker and Wrapper
 type LookRunnerUtil interface {
 	Looker
@@ -44,24 +50,32 @@ type PluginRunner struct {
 	Sha256         []byte                      `json:"sha256" structs:"sha256"`
 	Builtin        bool                        `json:"builtin" structs:"builtin"`
 	BuiltinFactory func() (interface{}, error) `json:"-" structs:"-"`
+	Version        string                      `json:"version,omitempty" structs:"version,omitempty"`
 }
 
 // Run takes a wrapper RunnerUtil instance along with the go-plugin paramaters and
 // returns a configured plugin.Client with TLS Configured and a wrapping token set
 // on PluginUnwrapTokenEnv for plugin process consumption.
 func (r *PluginRunner) Run(wrapper RunnerUtil, pluginMap map[string]plugin.Plugin, hs plugin.HandshakeConfig, env []string, logger log.Logger) (*plugin.Client, error) {
-	return r.runCommon(wrapper, pluginMap, hs, env, logger, false)
+	return r.runCommon(wrapper, pluginMap, hs, env, logger, false, nil)
 }
 
 // RunMetadataMode returns a configured plugin.Client that will dispense a plugin
 // in metadata mode. The PluginMetadaModeEnv is passed in as part of the Cmd to
 // plugin.Client, and consumed by the plugin process on pluginutil.VaultPluginTLSProvider.
 func (r *PluginRunner) RunMetadataMode(wrapper RunnerUtil, pluginMap map[string]plugin.Plugin, hs plugin.HandshakeConfig, env []string, logger log.Logger) (*plugin.Client, error) {
-	return r.runCommon(wrapper, pluginMap, hs, env, logger, true)
+	return r.runCommon(wrapper, pluginMap, hs, env, logger, true, nil)
+
+}
 
+// RunProtocols is like Run, or RunMetadataMode if isMetadataMode is true, but
+// the returned plugin.Client only accepts plugins served over one of the given
+// protocols rather than net/rpc.
+func (r *PluginRunner) RunProtocols(wrapper RunnerUtil, pluginMap map[string]plugin.Plugin, hs plugin.HandshakeConfig, env []string, logger log.Logger, isMetadataMode bool, protocols []plugin.Protocol) (*plugin.Client, error) {
+	return r.runCommon(wrapper, pluginMap, hs, env, logger, isMetadataMode, protocols)
 }
 
-func (r *PluginRunner) runCommon(wrapper RunnerUtil, pluginMap map[string]plugin.Plugin, hs plugin.HandshakeConfig, env []string, logger log.Logger, isMetadataMode bool) (*plugin.Client, error) {
+func (r *PluginRunner) runCommon(wrapper RunnerUtil, pluginMap map[string]plugin.Plugin, hs plugin.HandshakeConfig, env []string, logger log.Logger, isMetadataMode bool, protocols []plugin.Protocol) (*plugin.Client, error) {
 	cmd := exec.Command(r.Command, r.Args...)
 	cmd.Env = append(cmd.Env, env...)
 
@@ -113,12 +127,13 @@ func (r *PluginRunner) runCommon(wrapper RunnerUtil, pluginMap map[string]plugin
 	}
 
 	clientConfig := &plugin.ClientConfig{
-		HandshakeConfig: hs,
-		Plugins:         pluginMap,
-		Cmd:             cmd,
-		SecureConfig:    secureConfig,
-		TLSConfig:       clientTLSConfig,
-		Logger:          namedLogger,
+		HandshakeConfig:  hs,
+		Plugins:          pluginMap,
+		Cmd:              cmd,
+		SecureConfig:     secureConfig,
+		TLSConfig:        clientTLSConfig,
+		Logger:           namedLogger,
+		AllowedProtocols: protocols,
 	}
 
 	client := plugin.NewClient(clientConfig)
@@ -66,6 +66,11 @@ func (s *StoragePacker) GetBucket(key string) (*Bucket, error) {
 	lock.RLock()
 	defer lock.RUnlock()
 
+	return s.getBucket(key)
+}
+
+// getBucket reads a bucket without locking it
+func (s *StoragePacker) getBucket(key string) (*Bucket, error) {
 	// Read from the underlying view
 	storageEntry, err := s.view.Get(key)
 	if err != nil {
@@ -214,16 +219,9 @@ func (s *StoragePacker) PutBucket(bucket *Bucket) error {
 		return fmt.Errorf("incorrect prefix; bucket entry key should have %q prefix", s.viewPrefix)
 	}
 
-	marshaledBucket, err := proto.Marshal(bucket)
+	compressedBucket, err := encodeBucket(bucket)
 	if err != nil {
-		return errwrap.Wrapf("failed to marshal bucket: {{err}}", err)
-	}
-
-	compressedBucket, err := compressutil.Compress(marshaledBucket, &compressutil.CompressionConfig{
-		Type: compressutil.CompressionTypeSnappy,
-	})
-	if err != nil {
-		return errwrap.Wrapf("failed to compress packed bucket: {{err}}", err)
+		return err
 	}
 
 	// Store the compressed value
@@ -238,6 +236,23 @@ func (s *StoragePacker) PutBucket(bucket *Bucket) error {
 	return nil
 }
 
+// encodeBucket marshals and compresses a bucket
+func encodeBucket(bucket *Bucket) ([]byte, error) {
+	marshaledBucket, err := proto.Marshal(bucket)
+	if err != nil {
+		return nil, errwrap.Wrapf("failed to marshal bucket: {{err}}", err)
+	}
+
+	compressedBucket, err := compressutil.Compress(marshaledBucket, &compressutil.CompressionConfig{
+		Type: compressutil.CompressionTypeSnappy,
+	})
+	if err != nil {
+		return nil, errwrap.Wrapf("failed to compress packed bucket: {{err}}", err)
+	}
+
+	return compressedBucket, nil
+}
+
 // GetItem fetches the storage entry for a given key from its corresponding
 // bucket.
 func (s *StoragePacker) GetItem(itemID string) (*Item, error) {
@@ -266,63 +281,77 @@ func (s *StoragePacker) GetItem(itemID string) (*Item, error) {
 
 // PutItem stores a storage entry in its corresponding bucket
 func (s *StoragePacker) PutItem(item *Item) error {
-	if item == nil {
-		return fmt.Errorf("nil item")
-	}
+	return s.PutItems([]*Item{item})
+}
 
-	if item.ID == "" {
-		return fmt.Errorf("missing ID in item")
-	}
+// PutItems stores storage entries in their corresponding buckets. The
+// buckets are written in a single transaction if the view supports it, so
+// that either all the entries are stored or none of them.
+func (s *StoragePacker) PutItems(items []*Item) error {
+	var bucketPaths []string
+	bucketItems := make(map[string][]*Item)
+	for _, item := range items {
+		if item == nil {
+			return fmt.Errorf("nil item")
+		}
 
-	var err error
-	bucketKey := s.BucketKey(item.ID)
-	bucketPath := s.BucketPath(bucketKey)
+		if item.ID == "" {
+			return fmt.Errorf("missing ID in item")
+		}
 
-	bucket := &Bucket{
-		Key: bucketPath,
+		bucketPath := s.BucketPath(s.BucketKey(item.ID))
+		if _, ok := bucketItems[bucketPath]; !ok {
+			bucketPaths = append(bucketPaths, bucketPath)
+		}
+		bucketItems[bucketPath] = append(bucketItems[bucketPath], item)
 	}
 
-	// In this case, we persist the storage entry regardless of the read
-	// storageEntry below is nil or not. Hence, directly acquire write lock
-	// even to read the entry.
-	lock := locksutil.LockForKey(s.storageLocks, bucketPath)
-	lock.Lock()
-	defer lock.Unlock()
-
-	// Check if there is an existing bucket for a given key
-	storageEntry, err := s.view.Get(bucketPath)
-	if err != nil {
-		return errwrap.Wrapf("failed to read packed storage bucket entry: {{err}}", err)
+	// In this case, we persist the buckets regardless of whether they exist.
+	// Hence, directly acquire write locks even to read them.
+	for _, lock := range locksutil.LocksForKeys(s.storageLocks, bucketPaths) {
+		lock.Lock()
+		defer lock.Unlock()
 	}
 
-	if storageEntry == nil {
-		// If the bucket entry does not exist, this will be the only item the
-		// bucket that is going to be persisted.
-		bucket.Items = []*Item{
-			item,
-		}
-	} else {
-		uncompressedData, notCompressed, err := compressutil.Decompress(storageEntry.Value)
+	txns := make([]*logical.TxnEntry, 0, len(bucketPaths))
+	for _, bucketPath := range bucketPaths {
+		// Check if there is an existing bucket for a given key
+		bucket, err := s.getBucket(bucketPath)
 		if err != nil {
-			return errwrap.Wrapf("failed to decompress packed storage entry: {{err}}", err)
+			return err
 		}
-		if notCompressed {
-			uncompressedData = storageEntry.Value
+		if bucket == nil {
+			bucket = &Bucket{
+				Key: bucketPath,
+			}
 		}
 
-		err = proto.Unmarshal(uncompressedData, bucket)
-		if err != nil {
-			return errwrap.Wrapf("failed to decode packed storage entry: {{err}}", err)
+		for _, item := range bucketItems[bucketPath] {
+			err = bucket.upsert(item)
+			if err != nil {
+				return errwrap.Wrapf("failed to update entry in packed storage entry: {{err}}", err)
+			}
 		}
 
-		err = bucket.upsert(item)
+		compressedBucket, err := encodeBucket(bucket)
 		if err != nil {
-			return errwrap.Wrapf("failed to update entry in packed storage entry: {{err}}", err)
+			return err
 		}
+		txns = append(txns, &logical.TxnEntry{
+			Operation: logical.UpdateOperation,
+			Entry: &logical.StorageEntry{
+				Key:   bucketPath,
+				Value: compressedBucket,
+			},
+		})
 	}
 
 	// Persist the result
-	return s.PutBucket(bucket)
+	if err := logical.StorageTransaction(s.view, txns); err != nil {
+		return errwrap.Wrapf("failed to persist packed storage entry: {{err}}", err)
+	}
+
+	return nil
 }
 
 // NewStoragePacker creates a new storage packer for a given view
@@ -170,3 +170,68 @@ func TestStoragePacker_SerializeDeserializeComplexItem(t *testing.T) {
 		t.Fatalf("bad: expected: %#v\nactual: %#v\n", entity, itemDecoded)
 	}
 }
+
+// txnStorage counts the transactions of an in-memory storage
+type txnStorage struct {
+	*logical.InmemStorage
+	transactions int
+}
+
+func (s *txnStorage) Transaction(txns []*logical.TxnEntry) error {
+	s.transactions++
+	return logical.StorageTransaction(s.InmemStorage, txns)
+}
+
+func TestStoragePacker_PutItems(t *testing.T) {
+	storage := &txnStorage{InmemStorage: &logical.InmemStorage{}}
+	storagePacker, err := NewStoragePacker(storage, log.New("storagepackertest"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var items []*Item
+	buckets := make(map[string]bool)
+	for i := 0; i < 10; i++ {
+		itemID, err := uuid.GenerateUUID()
+		if err != nil {
+			t.Fatal(err)
+		}
+		items = append(items, &Item{ID: itemID})
+		buckets[storagePacker.BucketKey(itemID)] = true
+	}
+
+	// The buckets of all the items are written in a single transaction
+	err = storagePacker.PutItems(items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if storage.transactions != 1 {
+		t.Fatalf("bad: transactions: %d", storage.transactions)
+	}
+	keys, err := storage.List(StoragePackerBucketsPrefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != len(buckets) {
+		t.Fatalf("bad: keys: %v", keys)
+	}
+
+	for _, item := range items {
+		fetchedItem, err := storagePacker.GetItem(item.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fetchedItem == nil || fetchedItem.ID != item.ID {
+			t.Fatalf("bad: item: %#v", fetchedItem)
+		}
+	}
+
+	// Invalid items fail before anything is written
+	err = storagePacker.PutItems([]*Item{{ID: "item1"}, {}})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if storage.transactions != 1 {
+		t.Fatalf("bad: transactions: %d", storage.transactions)
+	}
+}
@@ -4,9 +4,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/errwrap"
 	"github.com/hashicorp/vault/helper/consts"
@@ -32,6 +35,28 @@ const (
 	// not to use request forwarding
 	NoRequestForwardingHeaderName = "X-Vault-No-Request-Forwarding"
 
+	// ControlGroupHeaderName is the name of the header containing the ID of
+	// an authorized control group request being sent again
+	ControlGroupHeaderName = "X-Vault-Control-Group"
+
+	// NamespaceHeaderName is the name of the header containing the path of
+	// the namespace a request targets, to which the request path is relative
+	NamespaceHeaderName = "X-Vault-Namespace"
+
+	// IndexHeaderName is the name of the header carrying the state index of
+	// the node that served a request. Clients that send it back are
+	// guaranteed to observe at least the writes it describes.
+	IndexHeaderName = "X-Vault-Index"
+
+	// RequestIDHeaderName is the name of the header returning the identifier
+	// of a request, which is included in its audit entries and in the server
+	// logs
+	RequestIDHeaderName = "X-Vault-Request-ID"
+
+	// stateIndexWaitTimeout is how long a request carrying a state index
+	// waits for the node to catch up before failing
+	stateIndexWaitTimeout = 2 * time.Second
+
 	// MaxRequestSize is the maximum accepted request size. This is to prevent
 	// a denial of service attack where no Content-Length is provided and the server
 	// is fed ever more data until it exhausts memory.
@@ -54,8 +79,14 @@ func Handler(core *vault.Core) http.Handler {
 	mux.Handle("/v1/sys/generate-root/update", handleRequestForwarding(core, handleSysGenerateRootUpdate(core)))
 	mux.Handle("/v1/sys/rekey/init", handleRequestForwarding(core, handleSysRekeyInit(core, false)))
 	mux.Handle("/v1/sys/rekey/update", handleRequestForwarding(core, handleSysRekeyUpdate(core, false)))
+	mux.Handle("/v1/sys/rekey/verify", handleRequestForwarding(core, handleSysRekeyVerify(core, false)))
 	mux.Handle("/v1/sys/rekey-recovery-key/init", handleRequestForwarding(core, handleSysRekeyInit(core, true)))
 	mux.Handle("/v1/sys/rekey-recovery-key/update", handleRequestForwarding(core, handleSysRekeyUpdate(core, true)))
+	mux.Handle("/v1/sys/rekey-recovery-key/verify", handleRequestForwarding(core, handleSysRekeyVerify(core, true)))
+	mux.Handle("/v1/sys/storage/raft/bootstrap", handleSysRaftBootstrap(core))
+	mux.Handle("/v1/sys/storage/raft/join", handleSysRaftJoin(core))
+	mux.Handle("/v1/sys/storage/raft/snapshot", handleRequestForwarding(core, handleSysRaftSnapshot(core)))
+	mux.Handle("/v1/sys/pprof/", handleRequestForwarding(core, handleSysPprof(core)))
 	mux.Handle("/v1/sys/wrapping/lookup", handleRequestForwarding(core, handleLogical(core, false, wrappingVerificationFunc)))
 	mux.Handle("/v1/sys/wrapping/rewrap", handleRequestForwarding(core, handleLogical(core, false, wrappingVerificationFunc)))
 	mux.Handle("/v1/sys/wrapping/unwrap", handleRequestForwarding(core, handleLogical(core, false, wrappingVerificationFunc)))
@@ -178,6 +209,13 @@ func handleRequestForwarding(core *vault.Core, handler http.Handler) http.Handle
 		// been disabled on the active node -- this will return with an
 		// ErrCannotForward and we simply fall back
 		statusCode, header, retBytes, err := core.ForwardRequest(r)
+		if err == vault.ErrForwardingBackpressure {
+			// The active node is already handling as many forwarded
+			// requests as allowed; redirecting would only move the load
+			w.Header().Set("Retry-After", "1")
+			respondError(w, http.StatusServiceUnavailable, err)
+			return
+		}
 		if err != nil {
 			if err == vault.ErrCannotForward {
 				core.Logger().Trace("http/handleRequestForwarding: cannot forward (possibly disabled on active node), falling back")
@@ -315,11 +353,20 @@ func requestWrapInfo(r *http.Request, req *logical.Request) (*logical.Request, e
 func respondError(w http.ResponseWriter, status int, err error) {
 	logical.AdjustErrorStatusCode(&status, err)
 
+	if rlErr, ok := err.(*logical.RateLimitQuotaError); ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(rlErr.RetryAfter.Seconds()))))
+	}
+
 	w.Header().Add("Content-Type", "application/json")
 	w.WriteHeader(status)
 
 	resp := &ErrorResponse{Errors: make([]string, 0, 1)}
-	if err != nil {
+	switch {
+	case errwrap.ContainsType(err, new(logical.FieldValidationError)):
+		// Report each invalid field as an error of its own
+		fieldErr := errwrap.GetType(err, new(logical.FieldValidationError)).(*logical.FieldValidationError)
+		resp.Errors = append(resp.Errors, fieldErr.Errors...)
+	case err != nil:
 		resp.Errors = append(resp.Errors, err.Error())
 	}
 
@@ -164,7 +164,8 @@ func TestSysMounts_headerAuth(t *testing.T) {
 					"max_lease_ttl":     json.Number("0"),
 					"force_no_cache":    false,
 				},
-				"local": false,
+				"local":     false,
+				"seal_wrap": false,
 			},
 			"sys/": map[string]interface{}{
 				"description": "system endpoints used for control, policy and debugging",
@@ -174,7 +175,8 @@ func TestSysMounts_headerAuth(t *testing.T) {
 					"max_lease_ttl":     json.Number("0"),
 					"force_no_cache":    false,
 				},
-				"local": false,
+				"local":     false,
+				"seal_wrap": false,
 			},
 			"cubbyhole/": map[string]interface{}{
 				"description": "per-token private secret storage",
@@ -184,7 +186,8 @@ func TestSysMounts_headerAuth(t *testing.T) {
 					"max_lease_ttl":     json.Number("0"),
 					"force_no_cache":    false,
 				},
-				"local": true,
+				"local":     true,
+				"seal_wrap": false,
 			},
 			"identity/": map[string]interface{}{
 				"description": "identity store",
@@ -194,7 +197,8 @@ func TestSysMounts_headerAuth(t *testing.T) {
 					"max_lease_ttl":     json.Number("0"),
 					"force_no_cache":    false,
 				},
-				"local": false,
+				"local":     false,
+				"seal_wrap": false,
 			},
 		},
 		"secret/": map[string]interface{}{
@@ -205,7 +209,8 @@ func TestSysMounts_headerAuth(t *testing.T) {
 				"max_lease_ttl":     json.Number("0"),
 				"force_no_cache":    false,
 			},
-			"local": false,
+			"local":     false,
+			"seal_wrap": false,
 		},
 		"sys/": map[string]interface{}{
 			"description": "system endpoints used for control, policy and debugging",
@@ -215,7 +220,8 @@ func TestSysMounts_headerAuth(t *testing.T) {
 				"max_lease_ttl":     json.Number("0"),
 				"force_no_cache":    false,
 			},
-			"local": false,
+			"local":     false,
+			"seal_wrap": false,
 		},
 		"cubbyhole/": map[string]interface{}{
 			"description": "per-token private secret storage",
@@ -225,7 +231,8 @@ func TestSysMounts_headerAuth(t *testing.T) {
 				"max_lease_ttl":     json.Number("0"),
 				"force_no_cache":    false,
 			},
-			"local": true,
+			"local":     true,
+			"seal_wrap": false,
 		},
 		"identity/": map[string]interface{}{
 			"description": "identity store",
@@ -235,7 +242,8 @@ func TestSysMounts_headerAuth(t *testing.T) {
 				"max_lease_ttl":     json.Number("0"),
 				"force_no_cache":    false,
 			},
-			"local": false,
+			"local":     false,
+			"seal_wrap": false,
 		},
 	}
 	testResponseStatus(t, resp, 200)
@@ -357,4 +365,22 @@ func TestHandler_error(t *testing.T) {
 		t.Fatalf("expected 503, got %d", w3.Code)
 	}
 
+	// Every invalid field is listed as an error of its own
+	w4 := httptest.NewRecorder()
+	fieldErr := &logical.FieldValidationError{
+		Errors: []string{"bar: 0 is less than the minimum of 1", "foo: \"x\" is not one of a, b"},
+	}
+
+	respondError(w4, 500, fieldErr)
+
+	if w4.Code != 400 {
+		t.Fatalf("expected 400, got %d", w4.Code)
+	}
+	var resp ErrorResponse
+	if err := json.NewDecoder(w4.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(resp.Errors, fieldErr.Errors) {
+		t.Fatalf("bad: %#v", resp.Errors)
+	}
 }
@@ -34,6 +34,7 @@ func handleHelp(core *vault.Core, w http.ResponseWriter, req *http.Request) {
 	lreq := requestAuth(core, req, &logical.Request{
 		Operation:  logical.HelpOperation,
 		Path:       path,
+		Namespace:  req.Header.Get(NamespaceHeaderName),
 		Connection: getConnection(req),
 	})
 
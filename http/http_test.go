@@ -38,6 +38,10 @@ func testHttpPut(t *testing.T, token string, addr string, body interface{}) *htt
 }
 
 // Go 1.8+ clients redirect automatically which breaks our 307 standby testing
+func testHttpPatch(t *testing.T, token string, addr string, body interface{}) *http.Response {
+	return testHttpData(t, "PATCH", token, addr, body, false)
+}
+
 func testHttpPutDisableRedirect(t *testing.T, token string, addr string, body interface{}) *http.Response {
 	return testHttpData(t, "PUT", token, addr, body, true)
 }
@@ -61,7 +65,11 @@ func testHttpData(t *testing.T, method string, token string, addr string, body i
 	hostURLRegexp, _ := regexp.Compile("http[s]?://.+:[0-9]+")
 	req.Header.Set("Origin", hostURLRegexp.FindString(addr))
 
-	req.Header.Set("Content-Type", "application/json")
+	if method == "PATCH" {
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+	} else {
+		req.Header.Set("Content-Type", "application/json")
+	}
 
 	if len(token) != 0 {
 		req.Header.Set("X-Vault-Token", token)
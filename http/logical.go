@@ -1,6 +1,10 @@
 package http
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
 	"io"
 	"net"
 	"net/http"
@@ -16,6 +20,10 @@ import (
 
 type PrepareRequestFunc func(*vault.Core, *logical.Request) error
 
+// mergePatchContentType is the content type of the bodies of the PATCH
+// requests, which are JSON merge patches (RFC 7396)
+const mergePatchContentType = "application/merge-patch+json"
+
 func buildLogicalRequest(core *vault.Core, w http.ResponseWriter, r *http.Request) (*logical.Request, int, error) {
 	// Determine the path...
 	if !strings.HasPrefix(r.URL.Path, "/v1/") {
@@ -47,6 +55,15 @@ func buildLogicalRequest(core *vault.Core, w http.ResponseWriter, r *http.Reques
 		}
 	case "POST", "PUT":
 		op = logical.UpdateOperation
+	case "PATCH":
+		op = logical.PatchOperation
+		if ct := r.Header.Get("Content-Type"); !strings.HasPrefix(ct, mergePatchContentType) {
+			return nil, http.StatusUnsupportedMediaType, fmt.Errorf("PATCH requires the %q content type", mergePatchContentType)
+		}
+	case "HEAD":
+		// Only paths with a header callback answer HEAD requests, since a
+		// read may not be safe to repeat just for its headers
+		op = logical.HeaderOperation
 	case "LIST":
 		op = logical.ListOperation
 	case "OPTIONS":
@@ -62,7 +79,7 @@ func buildLogicalRequest(core *vault.Core, w http.ResponseWriter, r *http.Reques
 
 	// Parse the request if we can
 	var data map[string]interface{}
-	if op == logical.UpdateOperation {
+	if op == logical.UpdateOperation || op == logical.PatchOperation {
 		err := parseRequest(r, w, &data)
 		if err == io.EOF {
 			data = nil
@@ -73,6 +90,22 @@ func buildLogicalRequest(core *vault.Core, w http.ResponseWriter, r *http.Reques
 		}
 	}
 
+	// Query parameters are passed to reads and HEAD requests, such as the
+	// version of a key to read from a versioned kv mount, and to lists, such
+	// as the filters of the PKI certificate list. Backends only see the ones
+	// declared in the schema of the path.
+	if op == logical.ReadOperation || op == logical.HeaderOperation || op == logical.ListOperation {
+		for k, v := range r.URL.Query() {
+			if len(v) == 0 || (op == logical.ListOperation && k == "list") {
+				continue
+			}
+			if data == nil {
+				data = make(map[string]interface{})
+			}
+			data[k] = v[0]
+		}
+	}
+
 	var err error
 	request_id, err := uuid.GenerateUUID()
 	if err != nil {
@@ -87,6 +120,9 @@ func buildLogicalRequest(core *vault.Core, w http.ResponseWriter, r *http.Reques
 		Connection: getConnection(r),
 		Headers:    r.Header,
 	})
+	req.ControlGroupID = r.Header.Get(ControlGroupHeaderName)
+	req.Namespace = r.Header.Get(NamespaceHeaderName)
+	req.SetContext(r.Context())
 
 	req, err = requestWrapInfo(r, req)
 	if err != nil {
@@ -103,6 +139,7 @@ func handleLogical(core *vault.Core, injectDataIntoTopLevel bool, prepareRequest
 			respondError(w, statusCode, err)
 			return
 		}
+		w.Header().Set(RequestIDHeaderName, req.ID)
 
 		// Certain endpoints may require changes to the request object. They
 		// will have a callback registered to do the needed operations, so
@@ -114,6 +151,31 @@ func handleLogical(core *vault.Core, injectDataIntoTopLevel bool, prepareRequest
 			}
 		}
 
+		// Reject low-priority requests early if the node is overloaded so
+		// that logins and renewals can continue to be served
+		if shed, retryAfter := core.ShouldShedLoad(req); shed {
+			w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())))
+			respondError(w, http.StatusServiceUnavailable, vault.ErrLoadShed)
+			return
+		}
+
+		// Honor read-your-writes requests by waiting until this node has
+		// applied the writes described by the client's state index
+		if index := r.Header.Get(IndexHeaderName); index != "" {
+			ok, err := core.WaitForStateIndex(index, stateIndexWaitTimeout)
+			switch {
+			case err == vault.ErrStateIndexInactive:
+				// The request is redirected to the active node, which
+				// waits for the index instead
+			case err != nil:
+				respondError(w, http.StatusBadRequest, err)
+				return
+			case !ok:
+				respondError(w, http.StatusPreconditionFailed, fmt.Errorf("timed out waiting for state index %q", index))
+				return
+			}
+		}
+
 		// Make the internal request. We attach the connection info
 		// as well in case this is an authentication request that requires
 		// it. Vault core handles stripping this if we need to. This also
@@ -124,6 +186,10 @@ func handleLogical(core *vault.Core, injectDataIntoTopLevel bool, prepareRequest
 			return
 		}
 
+		if index := core.StateIndex(); index != "" {
+			w.Header().Set(IndexHeaderName, index)
+		}
+
 		// Build the proper response
 		respondLogical(w, r, req, injectDataIntoTopLevel, resp)
 	})
@@ -143,10 +209,17 @@ func respondLogical(w http.ResponseWriter, r *http.Request, req *logical.Request
 
 		// Check if this is a raw response
 		if _, ok := resp.Data[logical.HTTPStatusCode]; ok {
-			respondRaw(w, r, resp)
+			respondRaw(w, r, req, resp)
 			return
 		}
 
+		if resp.Cacheable() && cacheableOperation(req) {
+			etag, err := responseETag(resp)
+			if err == nil && setCacheHeaders(w, r, resp.CacheControl, etag) {
+				return
+			}
+		}
+
 		if resp.WrapInfo != nil && resp.WrapInfo.Token != "" {
 			httpResp = &logical.HTTPResponse{
 				WrapInfo: &logical.HTTPWrapInfo{
@@ -180,7 +253,7 @@ func respondLogical(w http.ResponseWriter, r *http.Request, req *logical.Request
 // respondRaw is used when the response is using HTTPContentType and HTTPRawBody
 // to change the default response handling. This is only used for specific things like
 // returning the CRL information on the PKI backends.
-func respondRaw(w http.ResponseWriter, r *http.Request, resp *logical.Response) {
+func respondRaw(w http.ResponseWriter, r *http.Request, req *logical.Request, resp *logical.Response) {
 	retErr := func(w http.ResponseWriter, err string) {
 		w.Header().Set("X-Vault-Raw-Error", err)
 		w.WriteHeader(http.StatusInternalServerError)
@@ -238,6 +311,26 @@ func respondRaw(w http.ResponseWriter, r *http.Request, resp *logical.Response)
 		}
 	}
 
+	if status == http.StatusOK && resp.Cacheable() && cacheableOperation(req) {
+		if setCacheHeaders(w, r, resp.CacheControl, bodyETag(body)) {
+			return
+		}
+	}
+
+	// Set any additional headers
+	if headersRaw, ok := resp.Data[logical.HTTPRawHeaders]; ok {
+		headers, ok := headersRaw.(map[string][]string)
+		if !ok {
+			retErr(w, "cannot decode headers")
+			return
+		}
+		for k, values := range headers {
+			for _, v := range values {
+				w.Header().Add(k, v)
+			}
+		}
+	}
+
 	// Write the response
 	if contentType != "" {
 		w.Header().Set("Content-Type", contentType)
@@ -247,6 +340,76 @@ func respondRaw(w http.ResponseWriter, r *http.Request, resp *logical.Response)
 	w.Write(body)
 }
 
+// cacheableOperation returns whether responses to the request may be cached
+func cacheableOperation(req *logical.Request) bool {
+	return req.Operation == logical.ReadOperation || req.Operation == logical.HeaderOperation ||
+		req.Operation == logical.ListOperation
+}
+
+// responseETag returns the ETag provided by the backend, or one computed over
+// the response's data
+func responseETag(resp *logical.Response) (string, error) {
+	if resp.CacheControl.ETag != "" {
+		return `"` + resp.CacheControl.ETag + `"`, nil
+	}
+	return dataETag(resp.Data)
+}
+
+// dataETag computes an ETag over the data of a response. Only the data is
+// used since other parts of the response, such as the request ID, differ
+// on every request.
+func dataETag(data map[string]interface{}) (string, error) {
+	body, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	return bodyETag(body), nil
+}
+
+func bodyETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+// setCacheHeaders replaces the default no-store Cache-Control header with
+// the backend's caching hints and sets the ETag. If the backend found the
+// response unchanged or the request carries a matching If-None-Match header,
+// a 304 is written and true is returned.
+func setCacheHeaders(w http.ResponseWriter, r *http.Request, cc *logical.CacheControl, etag string) bool {
+	directive := "private"
+	if cc.Public {
+		directive = "public"
+	}
+	if cc.MaxAge > 0 {
+		directive += fmt.Sprintf(", max-age=%d", int64(cc.MaxAge.Seconds()))
+	} else {
+		directive += ", no-cache"
+	}
+	w.Header().Set("Cache-Control", directive)
+	w.Header().Set("ETag", etag)
+
+	if cc.NotModified || etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return true
+	}
+	return false
+}
+
+// etagMatches reports whether the If-None-Match header value matches the
+// ETag, allowing for lists of ETags and weak validators
+func etagMatches(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
 // getConnection is used to format the connection information for
 // attaching to a logical request
 func getConnection(r *http.Request) (connection *logical.Connection) {
@@ -261,5 +424,17 @@ func getConnection(r *http.Request) (connection *logical.Connection) {
 		RemoteAddr: remoteAddr,
 		ConnState:  r.TLS,
 	}
+	if addr, ok := r.Context().Value(http.LocalAddrContextKey).(PeerCredentialsAddr); ok {
+		connection.PeerCredentials = addr.PeerCredentials()
+	}
 	return
 }
+
+// PeerCredentialsAddr is implemented by the local addresses of the
+// connections of the listeners which capture the credentials of the process
+// at the other end of a unix socket. The credentials are given to the
+// backends along with the requests sent over the connection.
+type PeerCredentialsAddr interface {
+	net.Addr
+	PeerCredentials() *logical.PeerCredentials
+}
@@ -2,18 +2,23 @@ package http
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"io"
+	"net"
 	"net/http"
+	"net/http/httptest"
 	"reflect"
 	"strconv"
 	"strings"
 	"testing"
 	"time"
 
+	cleanhttp "github.com/hashicorp/go-cleanhttp"
 	log "github.com/mgutz/logxi/v1"
 
 	"github.com/hashicorp/vault/helper/logformat"
+	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/physical"
 	"github.com/hashicorp/vault/physical/inmem"
 	"github.com/hashicorp/vault/vault"
@@ -52,6 +57,10 @@ func TestLogical(t *testing.T) {
 	testResponseStatus(t, resp, 200)
 	testResponseBody(t, resp, &actual)
 	delete(actual, "lease_id")
+	if _, ok := actual["lease_contract"]; !ok {
+		t.Fatalf("expected lease contract in response: %#v", actual)
+	}
+	delete(actual, "lease_contract")
 	expected["request_id"] = actual["request_id"]
 	if !reflect.DeepEqual(actual, expected) {
 		t.Fatalf("bad:\nactual:\n%#v\nexpected:\n%#v", actual, expected)
@@ -65,6 +74,55 @@ func TestLogical(t *testing.T) {
 	testResponseStatus(t, resp, 404)
 }
 
+func TestLogical_Patch(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+	TestServerAuth(t, addr, token)
+
+	// A secret which doesn't exist can't be patched
+	resp := testHttpPatch(t, token, addr+"/v1/secret/foo", map[string]interface{}{
+		"data": "bar",
+	})
+	testResponseStatus(t, resp, 404)
+
+	resp = testHttpPut(t, token, addr+"/v1/secret/foo", map[string]interface{}{
+		"data":    "bar",
+		"removed": "baz",
+	})
+	testResponseStatus(t, resp, 204)
+
+	// The body of a patch must be a JSON merge patch
+	req, err := http.NewRequest("PATCH", addr+"/v1/secret/foo", strings.NewReader(`{"data": "qux"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Vault-Token", token)
+	resp, err = cleanhttp.DefaultClient().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testResponseStatus(t, resp, 415)
+
+	resp = testHttpPatch(t, token, addr+"/v1/secret/foo", map[string]interface{}{
+		"data":    "qux",
+		"removed": nil,
+	})
+	testResponseStatus(t, resp, 204)
+
+	resp = testHttpGet(t, token, addr+"/v1/secret/foo")
+	var actual map[string]interface{}
+	testResponseStatus(t, resp, 200)
+	testResponseBody(t, resp, &actual)
+	expected := map[string]interface{}{
+		"data": "qux",
+	}
+	if !reflect.DeepEqual(actual["data"], expected) {
+		t.Fatalf("bad: %#v", actual["data"])
+	}
+}
+
 func TestLogical_noExist(t *testing.T) {
 	core, _, token := vault.TestCoreUnsealed(t)
 	ln, addr := TestServer(t, core)
@@ -158,6 +216,7 @@ func TestLogical_StandbyRedirect(t *testing.T) {
 			"explicit_max_ttl": json.Number("0"),
 			"expire_time":      nil,
 			"entity_id":        "",
+			"type":             "service",
 		},
 		"warnings":  nilWarnings,
 		"wrap_info": nil,
@@ -240,6 +299,9 @@ func TestLogical_RawHTTP(t *testing.T) {
 	if resp.Header.Get("Content-Type") != "plain/text" {
 		t.Fatalf("Bad: %#v", resp.Header)
 	}
+	if !reflect.DeepEqual(resp.Header["Link"], []string{"<hello>", "<world>"}) {
+		t.Fatalf("Bad: %#v", resp.Header)
+	}
 
 	// Get the body
 	body := new(bytes.Buffer)
@@ -247,6 +309,29 @@ func TestLogical_RawHTTP(t *testing.T) {
 	if string(body.Bytes()) != "hello world" {
 		t.Fatalf("Bad: %s", body.Bytes())
 	}
+
+	// HEAD requests are served, without the body, by the paths that handle
+	// them
+	resp, err := http.Head(addr + "/v1/foo/raw")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	testResponseStatus(t, resp, 200)
+	if resp.Header.Get("Link") == "" {
+		t.Fatalf("Bad: %#v", resp.Header)
+	}
+
+	// Other paths don't serve them as reads
+	req, err := http.NewRequest("HEAD", addr+"/v1/secret/foo", nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	req.Header.Set(AuthHeaderName, token)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	testResponseStatus(t, resp, 405)
 }
 
 func TestLogical_RequestSizeLimit(t *testing.T) {
@@ -262,6 +347,43 @@ func TestLogical_RequestSizeLimit(t *testing.T) {
 	testResponseStatus(t, resp, 413)
 }
 
+func TestLogical_MaxEntrySize(t *testing.T) {
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+	inm, err := inmem.NewInmem(nil, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	core, err := vault.NewCore(&vault.CoreConfig{
+		Physical:     inm,
+		DisableMlock: true,
+		Logger:       logger,
+		MaxEntrySize: 4096,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, token := vault.TestCoreInit(t, core)
+	for _, key := range keys {
+		if _, err := core.Unseal(vault.TestKeyCopy(key)); err != nil {
+			t.Fatalf("unseal err: %s", err)
+		}
+	}
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+	TestServerAuth(t, addr, token)
+
+	resp := testHttpPut(t, token, addr+"/v1/secret/foo", map[string]interface{}{
+		"data": strings.Repeat("a", 8192),
+	})
+	testResponseStatus(t, resp, 413)
+	var actual map[string]interface{}
+	testResponseBody(t, resp, &actual)
+	errs := actual["errors"].([]interface{})
+	if len(errs) != 1 || !strings.Contains(errs[0].(string), `for path "foo" of mount "secret/"`) {
+		t.Fatalf("bad: %#v", errs)
+	}
+}
+
 func TestLogical_ListSuffix(t *testing.T) {
 	core, _, _ := vault.TestCoreUnsealed(t)
 	req, _ := http.NewRequest("GET", "http://127.0.0.1:8200/v1/secret/foo", nil)
@@ -300,3 +422,300 @@ func TestLogical_ListSuffix(t *testing.T) {
 		t.Fatal("trailing slash not found on path")
 	}
 }
+
+func TestLogical_StateIndex(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+	TestServerAuth(t, addr, token)
+
+	resp := testHttpPut(t, token, addr+"/v1/secret/foo", map[string]interface{}{
+		"data": "bar",
+	})
+	testResponseStatus(t, resp, 204)
+	index := resp.Header.Get(IndexHeaderName)
+	if index == "" {
+		t.Fatal("expected state index header on write")
+	}
+
+	get := func(index string) *http.Response {
+		req, err := http.NewRequest("GET", addr+"/v1/secret/foo", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set(AuthHeaderName, token)
+		req.Header.Set(IndexHeaderName, index)
+		resp, err := cleanhttp.DefaultClient().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	// The index of our own write is satisfied immediately
+	resp = get(index)
+	testResponseStatus(t, resp, 200)
+	if resp.Header.Get(IndexHeaderName) != index {
+		t.Fatalf("expected index to be unchanged by a read, got %q want %q", resp.Header.Get(IndexHeaderName), index)
+	}
+
+	// Tokens from the future cannot be satisfied
+	n, err := strconv.ParseUint(index, 10, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp = get(strconv.FormatUint(n+1000000, 10))
+	testResponseStatus(t, resp, http.StatusPreconditionFailed)
+
+	resp = get("bogus")
+	testResponseStatus(t, resp, http.StatusBadRequest)
+}
+
+func TestLogical_RequestID(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+	TestServerAuth(t, addr, token)
+
+	resp := testHttpPut(t, token, addr+"/v1/secret/foo", map[string]interface{}{
+		"data": "bar",
+	})
+	testResponseStatus(t, resp, 204)
+	if resp.Header.Get(RequestIDHeaderName) == "" {
+		t.Fatal("expected request ID header on write")
+	}
+
+	// The header matches the identifier in the response body
+	resp = testHttpGet(t, token, addr+"/v1/secret/foo")
+	var body map[string]interface{}
+	testResponseStatus(t, resp, 200)
+	testResponseBody(t, resp, &body)
+	if id := resp.Header.Get(RequestIDHeaderName); id == "" || body["request_id"] != id {
+		t.Fatalf("bad: header %q, body %#v", id, body)
+	}
+
+	// Failing requests can be correlated too
+	resp = testHttpGet(t, "", addr+"/v1/secret/foo")
+	testResponseStatus(t, resp, 400)
+	if resp.Header.Get(RequestIDHeaderName) == "" {
+		t.Fatal("expected request ID header on error")
+	}
+}
+
+func TestLogical_CacheControl(t *testing.T) {
+	respond := func(op logical.Operation, resp *logical.Response, ifNoneMatch string) *httptest.ResponseRecorder {
+		r, err := http.NewRequest("GET", "/v1/foo", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ifNoneMatch != "" {
+			r.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		w.Header().Set("Cache-Control", "no-store")
+		respondLogical(w, r, &logical.Request{Operation: op}, false, resp)
+		return w
+	}
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"foo": "bar",
+		},
+		CacheControl: &logical.CacheControl{
+			MaxAge: time.Minute,
+			Public: true,
+		},
+	}
+
+	w := respond(logical.ReadOperation, resp, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("bad: %d", w.Code)
+	}
+	if cc := w.Header().Get("Cache-Control"); cc != "public, max-age=60" {
+		t.Fatalf("bad: %q", cc)
+	}
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("expected ETag")
+	}
+
+	// A matching conditional request gets no body
+	w = respond(logical.ReadOperation, resp, etag)
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Fatalf("bad: %d %q", w.Code, w.Body.String())
+	}
+	w = respond(logical.ReadOperation, resp, `"other", W/`+etag)
+	if w.Code != http.StatusNotModified {
+		t.Fatalf("bad: %d", w.Code)
+	}
+
+	// Changed data produces a different ETag
+	resp.Data["foo"] = "baz"
+	w = respond(logical.ReadOperation, resp, etag)
+	if w.Code != http.StatusOK || w.Header().Get("ETag") == etag {
+		t.Fatalf("bad: %d %q", w.Code, w.Header().Get("ETag"))
+	}
+
+	// Private responses must be revalidated when there is no max age
+	resp.CacheControl = &logical.CacheControl{}
+	w = respond(logical.ReadOperation, resp, "")
+	if cc := w.Header().Get("Cache-Control"); cc != "private, no-cache" {
+		t.Fatalf("bad: %q", cc)
+	}
+
+	// Leased responses and writes are never cached
+	resp.Secret = &logical.Secret{LeaseID: "foo"}
+	w = respond(logical.ReadOperation, resp, "")
+	if w.Header().Get("Cache-Control") != "no-store" || w.Header().Get("ETag") != "" {
+		t.Fatalf("bad: %#v", w.Header())
+	}
+	resp.Secret = nil
+	w = respond(logical.UpdateOperation, resp, "")
+	if w.Header().Get("Cache-Control") != "no-store" || w.Header().Get("ETag") != "" {
+		t.Fatalf("bad: %#v", w.Header())
+	}
+}
+
+func TestLogical_ReadIfChanged(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+	TestServerAuth(t, addr, token)
+
+	resp := testHttpPost(t, token, addr+"/v1/sys/mounts/versioned", map[string]interface{}{
+		"type": "kv",
+		"options": map[string]interface{}{
+			"version": "2",
+		},
+	})
+	testResponseStatus(t, resp, 204)
+
+	resp = testHttpPut(t, token, addr+"/v1/versioned/data/foo", map[string]interface{}{
+		"data": map[string]interface{}{
+			"value": "bar",
+		},
+	})
+	testResponseStatus(t, resp, 200)
+
+	resp = testHttpGet(t, token, addr+"/v1/versioned/data/foo")
+	testResponseStatus(t, resp, 200)
+	etag := resp.Header.Get("ETag")
+	if etag == "" {
+		t.Fatal("expected ETag")
+	}
+
+	// The last seen version and the ETag both avoid refetching the data
+	resp = testHttpGet(t, token, addr+"/v1/versioned/data/foo?known_version=1")
+	testResponseStatus(t, resp, 304)
+	if resp.Header.Get("ETag") != etag {
+		t.Fatalf("bad: %q", resp.Header.Get("ETag"))
+	}
+
+	req, err := http.NewRequest("GET", addr+"/v1/versioned/data/foo", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set(AuthHeaderName, token)
+	req.Header.Set("If-None-Match", etag)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testResponseStatus(t, resp, 304)
+
+	// A new version is returned in full
+	resp = testHttpPut(t, token, addr+"/v1/versioned/data/foo", map[string]interface{}{
+		"data": map[string]interface{}{
+			"value": "baz",
+		},
+	})
+	testResponseStatus(t, resp, 200)
+
+	resp = testHttpGet(t, token, addr+"/v1/versioned/data/foo?known_version=1")
+	testResponseStatus(t, resp, 200)
+	var actual map[string]interface{}
+	testResponseBody(t, resp, &actual)
+	if value := actual["data"].(map[string]interface{})["data"].(map[string]interface{})["value"]; value != "baz" {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestLogical_Namespace(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+	TestServerAuth(t, addr, token)
+
+	resp := testHttpPut(t, token, addr+"/v1/sys/namespaces/ns1", nil)
+	testResponseStatus(t, resp, 200)
+
+	do := func(method, path string, body io.Reader) *http.Response {
+		req, err := http.NewRequest(method, addr+path, body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set(AuthHeaderName, token)
+		req.Header.Set(NamespaceHeaderName, "ns1")
+		resp, err := cleanhttp.DefaultClient().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	// The paths of the requests are relative to the namespace of the header
+	resp = do("POST", "/v1/sys/mounts/secret", strings.NewReader(`{"type": "kv"}`))
+	testResponseStatus(t, resp, 204)
+	resp = do("PUT", "/v1/secret/foo", strings.NewReader(`{"data": "bar"}`))
+	testResponseStatus(t, resp, 204)
+
+	var body map[string]interface{}
+	resp = testHttpGet(t, token, addr+"/v1/ns1/secret/foo")
+	testResponseStatus(t, resp, 200)
+	testResponseBody(t, resp, &body)
+	if body["data"].(map[string]interface{})["data"] != "bar" {
+		t.Fatalf("bad: %#v", body)
+	}
+	resp = testHttpGet(t, token, addr+"/v1/secret/foo")
+	testResponseStatus(t, resp, 404)
+
+	// Unknown namespaces are rejected
+	req, err := http.NewRequest("GET", addr+"/v1/secret/foo", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set(AuthHeaderName, token)
+	req.Header.Set(NamespaceHeaderName, "nope")
+	resp, err = cleanhttp.DefaultClient().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testResponseStatus(t, resp, 400)
+}
+
+type testPeerCredentialsAddr struct {
+	net.Addr
+
+	creds *logical.PeerCredentials
+}
+
+func (a *testPeerCredentialsAddr) PeerCredentials() *logical.PeerCredentials {
+	return a.creds
+}
+
+func TestLogical_getConnectionPeerCredentials(t *testing.T) {
+	creds := &logical.PeerCredentials{PID: 1, UID: 2, GID: 3}
+
+	req := httptest.NewRequest("GET", "/v1/sys/health", nil)
+	ctx := context.WithValue(req.Context(), http.LocalAddrContextKey, &testPeerCredentialsAddr{creds: creds})
+	conn := getConnection(req.WithContext(ctx))
+	if !reflect.DeepEqual(conn.PeerCredentials, creds) {
+		t.Fatalf("bad: %#v", conn)
+	}
+
+	// Connections of other listeners don't carry credentials
+	ctx = context.WithValue(req.Context(), http.LocalAddrContextKey, &net.UnixAddr{Name: "vault.sock", Net: "unix"})
+	if conn := getConnection(req.WithContext(ctx)); conn.PeerCredentials != nil {
+		t.Fatalf("bad: %#v", conn)
+	}
+}
@@ -29,20 +29,26 @@ func TestSysAuth(t *testing.T) {
 				"description": "token based credentials",
 				"type":        "token",
 				"config": map[string]interface{}{
-					"default_lease_ttl": json.Number("0"),
-					"max_lease_ttl":     json.Number("0"),
+					"default_lease_ttl":         json.Number("0"),
+					"max_lease_ttl":             json.Number("0"),
+					"auto_create_group_aliases": false,
+					"token_type":                "service",
 				},
-				"local": false,
+				"local":     false,
+				"seal_wrap": false,
 			},
 		},
 		"token/": map[string]interface{}{
 			"description": "token based credentials",
 			"type":        "token",
 			"config": map[string]interface{}{
-				"default_lease_ttl": json.Number("0"),
-				"max_lease_ttl":     json.Number("0"),
+				"default_lease_ttl":         json.Number("0"),
+				"max_lease_ttl":             json.Number("0"),
+				"auto_create_group_aliases": false,
+				"token_type":                "service",
 			},
-			"local": false,
+			"local":     false,
+			"seal_wrap": false,
 		},
 	}
 	testResponseStatus(t, resp, 200)
@@ -89,38 +95,50 @@ func TestSysEnableAuth(t *testing.T) {
 				"description": "foo",
 				"type":        "noop",
 				"config": map[string]interface{}{
-					"default_lease_ttl": json.Number("0"),
-					"max_lease_ttl":     json.Number("0"),
+					"default_lease_ttl":         json.Number("0"),
+					"max_lease_ttl":             json.Number("0"),
+					"auto_create_group_aliases": false,
+					"token_type":                "service",
 				},
-				"local": false,
+				"local":     false,
+				"seal_wrap": false,
 			},
 			"token/": map[string]interface{}{
 				"description": "token based credentials",
 				"type":        "token",
 				"config": map[string]interface{}{
-					"default_lease_ttl": json.Number("0"),
-					"max_lease_ttl":     json.Number("0"),
+					"default_lease_ttl":         json.Number("0"),
+					"max_lease_ttl":             json.Number("0"),
+					"auto_create_group_aliases": false,
+					"token_type":                "service",
 				},
-				"local": false,
+				"local":     false,
+				"seal_wrap": false,
 			},
 		},
 		"foo/": map[string]interface{}{
 			"description": "foo",
 			"type":        "noop",
 			"config": map[string]interface{}{
-				"default_lease_ttl": json.Number("0"),
-				"max_lease_ttl":     json.Number("0"),
+				"default_lease_ttl":         json.Number("0"),
+				"max_lease_ttl":             json.Number("0"),
+				"auto_create_group_aliases": false,
+				"token_type":                "service",
 			},
-			"local": false,
+			"local":     false,
+			"seal_wrap": false,
 		},
 		"token/": map[string]interface{}{
 			"description": "token based credentials",
 			"type":        "token",
 			"config": map[string]interface{}{
-				"default_lease_ttl": json.Number("0"),
-				"max_lease_ttl":     json.Number("0"),
+				"default_lease_ttl":         json.Number("0"),
+				"max_lease_ttl":             json.Number("0"),
+				"auto_create_group_aliases": false,
+				"token_type":                "service",
 			},
-			"local": false,
+			"local":     false,
+			"seal_wrap": false,
 		},
 	}
 	testResponseStatus(t, resp, 200)
@@ -168,22 +186,28 @@ func TestSysDisableAuth(t *testing.T) {
 		"data": map[string]interface{}{
 			"token/": map[string]interface{}{
 				"config": map[string]interface{}{
-					"default_lease_ttl": json.Number("0"),
-					"max_lease_ttl":     json.Number("0"),
+					"default_lease_ttl":         json.Number("0"),
+					"max_lease_ttl":             json.Number("0"),
+					"auto_create_group_aliases": false,
+					"token_type":                "service",
 				},
 				"description": "token based credentials",
 				"type":        "token",
 				"local":       false,
+				"seal_wrap":   false,
 			},
 		},
 		"token/": map[string]interface{}{
 			"config": map[string]interface{}{
-				"default_lease_ttl": json.Number("0"),
-				"max_lease_ttl":     json.Number("0"),
+				"default_lease_ttl":         json.Number("0"),
+				"max_lease_ttl":             json.Number("0"),
+				"auto_create_group_aliases": false,
+				"token_type":                "service",
 			},
 			"description": "token based credentials",
 			"type":        "token",
 			"local":       false,
+			"seal_wrap":   false,
 		},
 	}
 	testResponseStatus(t, resp, 200)
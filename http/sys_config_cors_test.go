@@ -76,3 +76,37 @@ func TestSysConfigCors(t *testing.T) {
 	}
 
 }
+
+func TestSysConfigCors_update(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+	TestServerAuth(t, addr, token)
+
+	corsConf := core.CORSConfig()
+
+	// Updating the configuration replaces the headers instead of adding them
+	// to the previous ones
+	for i := 0; i < 2; i++ {
+		resp := testHttpPut(t, token, addr+"/v1/sys/config/cors", map[string]interface{}{
+			"allowed_origins": addr,
+			"allowed_headers": "x-custom-header",
+		})
+		testResponseStatus(t, resp, 204)
+	}
+
+	expected := append(append([]string{}, vault.StdAllowedHeaders...), "X-Custom-Header")
+	if !reflect.DeepEqual(corsConf.AllowedHeaders, expected) {
+		t.Fatalf("bad: expected: %#v\nactual: %#v", expected, corsConf.AllowedHeaders)
+	}
+
+	// Setting enable to false disables CORS
+	resp := testHttpPut(t, token, addr+"/v1/sys/config/cors", map[string]interface{}{
+		"enable": false,
+	})
+	testResponseStatus(t, resp, 204)
+
+	if corsConf.IsEnabled() || corsConf.AllowedOrigins != nil {
+		t.Fatalf("bad: %#v", corsConf)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/hashicorp/vault/helper/consts"
 	"github.com/hashicorp/vault/vault"
 	"github.com/hashicorp/vault/version"
 )
@@ -24,12 +25,20 @@ func handleSysHealth(core *vault.Core) http.Handler {
 	})
 }
 
+// drSecondaryCode is the default status code of a DR replication secondary,
+// which can't serve requests
+const drSecondaryCode = 472
+
 func fetchStatusCode(r *http.Request, field string) (int, bool, bool) {
 	var err error
 	statusCode := http.StatusOK
 	if statusCodeStr, statusCodeOk := r.URL.Query()[field]; statusCodeOk {
+		if len(statusCodeStr) < 1 {
+			return http.StatusBadRequest, false, false
+		}
 		statusCode, err = strconv.Atoi(statusCodeStr[0])
-		if err != nil || len(statusCodeStr) < 1 {
+		// Writing a status code outside of this range panics
+		if err != nil || statusCode < 100 || statusCode > 999 {
 			return http.StatusBadRequest, false, false
 		}
 		return statusCode, true, true
@@ -101,6 +110,31 @@ func getSysHealth(core *vault.Core, r *http.Request) (int, *HealthResponse, erro
 		activeCode = code
 	}
 
+	// An active node whose subsystems are still loading returns the active
+	// code unless told otherwise
+	notReadyCode := activeCode
+	if code, found, ok := fetchStatusCode(r, "notreadycode"); !ok {
+		return http.StatusBadRequest, nil, nil
+	} else if found {
+		notReadyCode = code
+	}
+
+	drSecondaryCode := drSecondaryCode
+	if code, found, ok := fetchStatusCode(r, "drsecondarycode"); !ok {
+		return http.StatusBadRequest, nil, nil
+	} else if found {
+		drSecondaryCode = code
+	}
+
+	var maxWALLag uint64
+	if maxWALLagStr := r.URL.Query().Get("maxwallag"); maxWALLagStr != "" {
+		var err error
+		maxWALLag, err = strconv.ParseUint(maxWALLagStr, 10, 64)
+		if err != nil {
+			return http.StatusBadRequest, nil, nil
+		}
+	}
+
 	// Check system status
 	sealed, _ := core.Sealed()
 	standby, _ := core.Standby()
@@ -109,6 +143,14 @@ func getSysHealth(core *vault.Core, r *http.Request) (int, *HealthResponse, erro
 		return http.StatusInternalServerError, nil, err
 	}
 
+	replicationState := core.ReplicationState()
+
+	// The subsystems are only loaded on the active node
+	var subsystems *vault.SubsystemStatus
+	if init && !sealed && !standby {
+		subsystems = core.SubsystemStatus(maxWALLag)
+	}
+
 	// Determine the status code
 	code := activeCode
 	switch {
@@ -116,8 +158,12 @@ func getSysHealth(core *vault.Core, r *http.Request) (int, *HealthResponse, erro
 		code = uninitCode
 	case sealed:
 		code = sealedCode
+	case replicationState.HasState(consts.ReplicationDRSecondary):
+		code = drSecondaryCode
 	case !standbyOK && standby:
 		code = standbyCode
+	case subsystems != nil && !subsystems.Ready:
+		code = notReadyCode
 	}
 
 	// Fetch the local cluster name and identifier
@@ -144,6 +190,12 @@ func getSysHealth(core *vault.Core, r *http.Request) (int, *HealthResponse, erro
 		ClusterName:   clusterName,
 		ClusterID:     clusterID,
 	}
+
+	// Report the readiness of the subsystems of the active node
+	if subsystems != nil {
+		body.LeaseRestore = core.LeaseRestoreStatus()
+		body.Subsystems = subsystems
+	}
 	return code, body, nil
 }
 
@@ -155,4 +207,7 @@ type HealthResponse struct {
 	Version       string `json:"version"`
 	ClusterName   string `json:"cluster_name,omitempty"`
 	ClusterID     string `json:"cluster_id,omitempty"`
+
+	LeaseRestore *vault.LeaseRestoreStatus `json:"lease_restore,omitempty"`
+	Subsystems   *vault.SubsystemStatus    `json:"subsystems,omitempty"`
 }
@@ -1,12 +1,14 @@
 package http
 
 import (
+	"encoding/json"
 	"io/ioutil"
 
 	"net/http"
 	"net/url"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/vault/vault"
 )
@@ -95,6 +97,29 @@ func TestSysHealth_get(t *testing.T) {
 	testResponseBody(t, resp, &actual)
 	expected["server_time_utc"] = actual["server_time_utc"]
 	expected["version"] = actual["version"]
+
+	// The restore of the leases may still be in progress
+	leaseRestore, ok := actual["lease_restore"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected the lease restore status: %#v", actual)
+	}
+	for _, key := range []string{"complete", "loaded", "total"} {
+		if _, ok := leaseRestore[key]; !ok {
+			t.Fatalf("missing %q in the lease restore status: %#v", key, leaseRestore)
+		}
+	}
+	expected["lease_restore"] = leaseRestore
+
+	subsystems, ok := actual["subsystems"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected the subsystem status: %#v", actual)
+	}
+	for _, key := range []string{"ready", "leases_restored", "identity_loaded", "replication"} {
+		if _, ok := subsystems[key]; !ok {
+			t.Fatalf("missing %q in the subsystem status: %#v", key, subsystems)
+		}
+	}
+	expected["subsystems"] = subsystems
 	if actual["cluster_name"] == nil {
 		delete(expected, "cluster_name")
 	} else {
@@ -201,6 +226,8 @@ func TestSysHealth_customcodes(t *testing.T) {
 	testResponseBody(t, resp, &actual)
 	expected["server_time_utc"] = actual["server_time_utc"]
 	expected["version"] = actual["version"]
+	expected["lease_restore"] = actual["lease_restore"]
+	expected["subsystems"] = actual["subsystems"]
 	if actual["cluster_name"] == nil {
 		delete(expected, "cluster_name")
 	} else {
@@ -228,6 +255,9 @@ func TestSysHealth_head(t *testing.T) {
 		{"", 200},
 		{"?activecode=503", 503},
 		{"?activecode=notacode", 400},
+		{"?activecode=42", 400},
+		{"?notreadycode=notacode", 400},
+		{"?maxwallag=-1", 400},
 	}
 
 	for _, tt := range testData {
@@ -253,3 +283,41 @@ func TestSysHealth_head(t *testing.T) {
 		}
 	}
 }
+
+func TestSysHealth_subsystems(t *testing.T) {
+	core, _, _ := vault.TestCoreUnsealed(t)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+
+	// Wait for the restore of the leases to complete
+	var actual map[string]interface{}
+	for i := 0; ; i++ {
+		resp, err := http.Get(addr + "/v1/sys/health?notreadycode=299")
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		actual = map[string]interface{}{}
+		testResponseBody(t, resp, &actual)
+		if resp.StatusCode == 200 {
+			break
+		}
+		if resp.StatusCode != 299 || i == 50 {
+			t.Fatalf("bad: %d %#v", resp.StatusCode, actual)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	expected := map[string]interface{}{
+		"ready":           true,
+		"leases_restored": true,
+		"identity_loaded": true,
+		"replication": map[string]interface{}{
+			"state":           "disabled",
+			"last_remote_wal": json.Number("0"),
+			"wal_lag":         json.Number("0"),
+		},
+	}
+	if !reflect.DeepEqual(actual["subsystems"], expected) {
+		t.Fatalf("bad: expected:%#v\nactual:%#v", expected, actual["subsystems"])
+	}
+}
@@ -58,6 +58,13 @@ func handleSysInitPut(core *vault.Core, w http.ResponseWriter, r *http.Request)
 	}
 
 	if core.SealAccess().StoredKeysSupported() {
+		// The barrier key is stored by the seal, so the shares only need to
+		// be given when stored shares are explicitly requested
+		if barrierConfig.StoredShares == 0 {
+			barrierConfig.SecretShares = 1
+			barrierConfig.SecretThreshold = 1
+			barrierConfig.StoredShares = 1
+		}
 		if barrierConfig.SecretShares != 1 {
 			respondError(w, http.StatusBadRequest, fmt.Errorf("secret shares must be 1"))
 			return
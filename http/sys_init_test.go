@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/hashicorp/vault/vault"
+	"github.com/hashicorp/vault/vault/seal"
 )
 
 func TestSysInit_get(t *testing.T) {
@@ -127,3 +128,35 @@ func TestSysInit_put(t *testing.T) {
 		t.Fatal("should not be sealed")
 	}
 }
+
+func TestSysInit_autoSeal(t *testing.T) {
+	core := vault.TestCoreWithSeal(t, vault.NewAutoSeal(seal.NewTestSeal("")), false)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+
+	// The key shares are ignored, the barrier key is stored by the seal
+	resp := testHttpPut(t, "", addr+"/v1/sys/init", map[string]interface{}{
+		"secret_shares":      5,
+		"secret_threshold":   3,
+		"recovery_shares":    1,
+		"recovery_threshold": 1,
+	})
+
+	var actual map[string]interface{}
+	testResponseStatus(t, resp, 200)
+	testResponseBody(t, resp, &actual)
+	if keys := actual["keys"].([]interface{}); len(keys) != 0 {
+		t.Fatalf("expected no unseal keys: %#v", keys)
+	}
+	if keys := actual["recovery_keys"].([]interface{}); len(keys) != 1 {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	resp = testHttpGet(t, "", addr+"/v1/sys/seal-status")
+	testResponseStatus(t, resp, 200)
+	actual = map[string]interface{}{}
+	testResponseBody(t, resp, &actual)
+	if actual["type"] != seal.Test || actual["sealed"] != false {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
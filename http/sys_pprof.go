@@ -0,0 +1,83 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/errwrap"
+	uuid "github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/vault"
+)
+
+// handleSysPprof returns the runtime profiles of the server. It is served
+// outside of the system backend since the CPU profile and the execution trace
+// hold the request for as long as they are sampled.
+func handleSysPprof(core *vault.Core) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			respondError(w, http.StatusMethodNotAllowed, nil)
+			return
+		}
+
+		name := strings.TrimPrefix(r.URL.Path, "/v1/sys/pprof/")
+		if name == "" {
+			respondError(w, http.StatusNotFound, nil)
+			return
+		}
+
+		query := r.URL.Query()
+		seconds, err := pprofIntParam(query.Get("seconds"), 30)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, err)
+			return
+		}
+		debug, err := pprofIntParam(query.Get("debug"), 0)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		requestID, err := uuid.GenerateUUID()
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, errwrap.Wrapf("failed to generate identifier for the request: {{err}}", err))
+			return
+		}
+		req := requestAuth(core, r, &logical.Request{
+			ID:         requestID,
+			Operation:  logical.ReadOperation,
+			Path:       "sys/pprof/" + name,
+			Connection: getConnection(r),
+			Headers:    r.Header,
+		})
+
+		data, err := core.Pprof(req, name, seconds, debug)
+		if err != nil {
+			respondErrorCommon(w, req, nil, err)
+			return
+		}
+
+		contentType := "application/octet-stream"
+		if debug > 0 && name != "profile" && name != "trace" {
+			contentType = "text/plain; charset=utf-8"
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(http.StatusOK)
+		w.Write(data)
+	})
+}
+
+// pprofIntParam parses an integer query parameter of sys/pprof, which
+// defaults to def when it is not set
+func pprofIntParam(value string, def int) (int, error) {
+	if value == "" {
+		return def, nil
+	}
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid integer %q", value)
+	}
+	return i, nil
+}
@@ -0,0 +1,74 @@
+package http
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/vault"
+)
+
+func TestSysPprof(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+	TestServerAuth(t, addr, token)
+
+	resp := testHttpGet(t, token, addr+"/v1/sys/pprof/goroutine?debug=1")
+	testResponseStatus(t, resp, 200)
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(body), "goroutine profile") {
+		t.Fatalf("bad: %s", body)
+	}
+
+	resp = testHttpGet(t, token, addr+"/v1/sys/pprof/profile?seconds=1")
+	testResponseStatus(t, resp, 200)
+	body, err = ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(body) == 0 {
+		t.Fatal("expected a CPU profile")
+	}
+
+	// Unknown profiles and invalid durations are rejected
+	resp = testHttpGet(t, token, addr+"/v1/sys/pprof/foo")
+	testResponseStatus(t, resp, 400)
+	resp = testHttpGet(t, token, addr+"/v1/sys/pprof/profile?seconds=0")
+	testResponseStatus(t, resp, 400)
+	resp = testHttpGet(t, token, addr+"/v1/sys/pprof/profile?seconds=foo")
+	testResponseStatus(t, resp, 400)
+
+	// Root privileges are required
+	resp = testHttpGet(t, "foo", addr+"/v1/sys/pprof/goroutine")
+	testResponseStatus(t, resp, 403)
+}
+
+func TestSysPprof_seal(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+	TestServerAuth(t, addr, token)
+
+	doneCh := make(chan struct{})
+	go func() {
+		defer close(doneCh)
+		resp := testHttpGet(t, token, addr+"/v1/sys/pprof/profile?seconds=5")
+		resp.Body.Close()
+	}()
+	time.Sleep(time.Second)
+
+	// The core can be sealed while a profile is sampled
+	start := time.Now()
+	if err := core.Seal(token); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if time.Since(start) > 3*time.Second {
+		t.Fatal("sealing waited for the profile")
+	}
+	<-doneCh
+}
@@ -0,0 +1,43 @@
+package http
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/hashicorp/vault/vault"
+)
+
+func TestSysRateLimitQuota_retryAfter(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+	TestServerAuth(t, addr, token)
+
+	resp := testHttpPut(t, token, addr+"/v1/sys/quotas/rate-limit/secret", map[string]interface{}{
+		"path":          "secret/",
+		"rate":          1,
+		"interval":      "1h",
+		"per_client_ip": true,
+	})
+	testResponseStatus(t, resp, 204)
+
+	resp = testHttpGet(t, token, addr+"/v1/secret/foo")
+	testResponseStatus(t, resp, 404)
+
+	// The next request waits for the bucket to be refilled
+	resp = testHttpGet(t, token, addr+"/v1/secret/foo")
+	testResponseStatus(t, resp, 429)
+	retryAfter, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if retryAfter < 3590 || retryAfter > 3600 {
+		t.Fatalf("bad: Retry-After: %d", retryAfter)
+	}
+
+	// The quota can still be removed
+	resp = testHttpDelete(t, token, addr+"/v1/sys/quotas/rate-limit/secret")
+	testResponseStatus(t, resp, 204)
+	resp = testHttpGet(t, token, addr+"/v1/secret/foo")
+	testResponseStatus(t, resp, 404)
+}
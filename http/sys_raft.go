@@ -0,0 +1,138 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/hashicorp/errwrap"
+	uuid "github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/vault"
+)
+
+func handleSysRaftBootstrap(core *vault.Core) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "PUT", "POST":
+		default:
+			respondError(w, http.StatusMethodNotAllowed, nil)
+			return
+		}
+
+		if err := core.RaftBootstrap(); err != nil {
+			respondError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		respondOk(w, nil)
+	})
+}
+
+func handleSysRaftJoin(core *vault.Core) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "PUT", "POST":
+		default:
+			respondError(w, http.StatusMethodNotAllowed, nil)
+			return
+		}
+
+		// Parse the request
+		var req RaftJoinRequest
+		if err := parseRequest(r, w, &req); err != nil {
+			respondError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		// The token is not checked here, as this node has no storage yet,
+		// but by the leader adding the node
+		err := core.JoinRaftCluster(&vault.RaftJoinRequest{
+			LeaderAPIAddr: req.LeaderAPIAddr,
+			LeaderCACert:  req.LeaderCACert,
+			Token:         r.Header.Get(AuthHeaderName),
+		})
+		if err != nil {
+			respondError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		respondOk(w, nil)
+	})
+}
+
+// handleSysRaftSnapshot saves and restores snapshots of the raft storage,
+// whose bodies are gzipped tar archives rather than JSON
+func handleSysRaftSnapshot(core *vault.Core) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var op logical.Operation
+		switch r.Method {
+		case "GET":
+			op = logical.ReadOperation
+		case "PUT", "POST":
+			op = logical.UpdateOperation
+		default:
+			respondError(w, http.StatusMethodNotAllowed, nil)
+			return
+		}
+
+		requestID, err := uuid.GenerateUUID()
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, errwrap.Wrapf("failed to generate identifier for the request: {{err}}", err))
+			return
+		}
+		req := requestAuth(core, r, &logical.Request{
+			ID:         requestID,
+			Operation:  op,
+			Path:       "sys/storage/raft/snapshot",
+			Connection: getConnection(r),
+			Headers:    r.Header,
+		})
+
+		if op == logical.UpdateOperation {
+			// The size of the snapshot is not limited, but the request is
+			// authorized before it is read
+			if err := core.RestoreRaftSnapshot(req, r.Body); err != nil {
+				respondError(w, http.StatusInternalServerError, err)
+				return
+			}
+			respondOk(w, nil)
+			return
+		}
+
+		sw := &snapshotWriter{w: w}
+		if err := core.RaftSnapshot(req, sw); err != nil {
+			if !sw.started {
+				respondError(w, http.StatusInternalServerError, err)
+			}
+			// Otherwise the archive is truncated, which its reader detects
+			return
+		}
+		if !sw.started {
+			sw.start()
+		}
+	})
+}
+
+// snapshotWriter writes the headers of a snapshot response along with its
+// first bytes, so that errors occurring before then can still be returned
+type snapshotWriter struct {
+	w       http.ResponseWriter
+	started bool
+}
+
+func (s *snapshotWriter) start() {
+	s.w.Header().Set("Content-Type", "application/gzip")
+	s.w.WriteHeader(http.StatusOK)
+	s.started = true
+}
+
+func (s *snapshotWriter) Write(p []byte) (int, error) {
+	if !s.started {
+		s.start()
+	}
+	return s.w.Write(p)
+}
+
+type RaftJoinRequest struct {
+	LeaderAPIAddr string `json:"leader_api_addr"`
+	LeaderCACert  string `json:"leader_ca_cert"`
+}
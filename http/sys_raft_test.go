@@ -0,0 +1,177 @@
+package http
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/helper/logformat"
+	physRaft "github.com/hashicorp/vault/physical/raft"
+	"github.com/hashicorp/vault/vault"
+	log "github.com/mgutz/logxi/v1"
+)
+
+// testRaftCore returns an uninitialized core storing its data in a new raft
+// node, served at the returned address
+func testRaftCore(t *testing.T) (*vault.Core, *physRaft.RaftBackend, string, func()) {
+	dir, err := ioutil.TempDir("", "vault-raft")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+	b, err := physRaft.NewRaftBackend(map[string]string{
+		"path":    dir,
+		"address": "127.0.0.1:0",
+	}, logger)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	backend := b.(*physRaft.RaftBackend)
+
+	ln, addr := TestListener(t)
+	core, err := vault.NewCore(&vault.CoreConfig{
+		Physical:     backend,
+		HAPhysical:   backend,
+		RedirectAddr: addr,
+		DisableMlock: true,
+		Logger:       logger,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	TestServerWithListener(t, ln, addr, core)
+
+	return core, backend, addr, func() {
+		core.Shutdown()
+		ln.Close()
+		backend.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestSysRaft_notInUse(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+	TestServerAuth(t, addr, token)
+
+	resp := testHttpPost(t, token, addr+"/v1/sys/storage/raft/bootstrap", nil)
+	testResponseStatus(t, resp, 400)
+
+	resp = testHttpGet(t, token, addr+"/v1/sys/storage/raft/snapshot")
+	testResponseStatus(t, resp, 500)
+}
+
+func TestSysRaft(t *testing.T) {
+	core1, backend1, addr1, cleanup1 := testRaftCore(t)
+	defer cleanup1()
+	_, backend2, addr2, cleanup2 := testRaftCore(t)
+	defer cleanup2()
+
+	keys, token := vault.TestCoreInit(t, core1)
+	for _, key := range keys {
+		if _, err := core1.Unseal(vault.TestKeyCopy(key)); err != nil {
+			t.Fatalf("unseal err: %s", err)
+		}
+	}
+	TestServerAuth(t, addr1, token)
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		standby, err := core1.Standby()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !standby {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("core did not become active")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	// The initialized node is already bootstrapped
+	resp := testHttpPost(t, token, addr1+"/v1/sys/storage/raft/bootstrap", nil)
+	testResponseStatus(t, resp, 400)
+
+	// The second node joins with a token of the cluster
+	resp = testHttpPost(t, "", addr2+"/v1/sys/storage/raft/join", map[string]interface{}{
+		"leader_api_addr": addr1,
+	})
+	testResponseStatus(t, resp, 400)
+	resp = testHttpPost(t, token, addr2+"/v1/sys/storage/raft/join", map[string]interface{}{
+		"leader_api_addr": addr1,
+	})
+	testResponseStatus(t, resp, 204)
+
+	resp = testHttpGet(t, token, addr1+"/v1/sys/storage/raft/configuration")
+	var actual map[string]interface{}
+	testResponseStatus(t, resp, 200)
+	testResponseBody(t, resp, &actual)
+	servers := actual["data"].(map[string]interface{})["servers"].([]interface{})
+	if len(servers) != 2 {
+		t.Fatalf("bad: %#v", servers)
+	}
+	joined := servers[1].(map[string]interface{})
+	if joined["node_id"] != backend2.NodeID() || joined["address"] != backend2.Address() || joined["leader"] != false {
+		t.Fatalf("bad: %#v", joined)
+	}
+
+	// The joined node receives the storage of the cluster
+	resp = testHttpGet(t, "", addr2+"/v1/sys/init")
+	testResponseStatus(t, resp, 200)
+	deadline = time.Now().Add(10 * time.Second)
+	for {
+		entry, err := backend2.Get("core/seal-config")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if entry != nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("storage was not replicated")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	resp = testHttpPost(t, token, addr1+"/v1/sys/storage/raft/remove-peer", map[string]interface{}{
+		"server_id": backend2.NodeID(),
+	})
+	testResponseStatus(t, resp, 204)
+	peers, err := backend1.Peers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(peers) != 1 {
+		t.Fatalf("bad: %#v", peers)
+	}
+
+	// Save and restore a snapshot
+	resp = testHttpGet(t, token, addr1+"/v1/sys/storage/raft/snapshot")
+	testResponseStatus(t, resp, 200)
+	if contentType := resp.Header.Get("Content-Type"); contentType != "application/gzip" {
+		t.Fatalf("bad content type: %q", contentType)
+	}
+	snapshot, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest("POST", addr1+"/v1/sys/storage/raft/snapshot", bytes.NewReader(snapshot))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set(AuthHeaderName, token)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testResponseStatus(t, resp, 204)
+}
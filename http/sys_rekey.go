@@ -87,6 +87,8 @@ func handleSysRekeyInitGet(core *vault.Core, recovery bool, w http.ResponseWrite
 		status.Started = true
 		status.T = rekeyConf.SecretThreshold
 		status.N = rekeyConf.SecretShares
+		status.VerificationRequired = rekeyConf.VerificationRequired
+		status.VerificationNonce = rekeyConf.VerificationNonce
 		if rekeyConf.PGPKeys != nil && len(rekeyConf.PGPKeys) != 0 {
 			pgpFingerprints, err := pgpkeys.GetFingerprints(rekeyConf.PGPKeys, nil)
 			if err != nil {
@@ -121,6 +123,11 @@ func handleSysRekeyInitPut(core *vault.Core, recovery bool, w http.ResponseWrite
 		return
 	}
 
+	if req.RequireVerification && req.StoredShares > 0 {
+		respondError(w, http.StatusBadRequest, fmt.Errorf("requiring verification not supported when using stored keys"))
+		return
+	}
+
 	if len(req.PGPKeys) > 0 && len(req.PGPKeys) != req.SecretShares-req.StoredShares {
 		respondError(w, http.StatusBadRequest, fmt.Errorf("incorrect number of PGP keys for rekey"))
 		return
@@ -133,6 +140,8 @@ func handleSysRekeyInitPut(core *vault.Core, recovery bool, w http.ResponseWrite
 		StoredShares:    req.StoredShares,
 		PGPKeys:         req.PGPKeys,
 		Backup:          req.Backup,
+
+		VerificationRequired: req.RequireVerification,
 	}, recovery)
 	if err != nil {
 		respondError(w, http.StatusBadRequest, err)
@@ -172,20 +181,10 @@ func handleSysRekeyUpdate(core *vault.Core, recovery bool) http.Handler {
 			return
 		}
 
-		// Decode the key, which is base64 or hex encoded
-		min, max := core.BarrierKeyLength()
-		key, err := hex.DecodeString(req.Key)
-		// We check min and max here to ensure that a string that is base64
-		// encoded but also valid hex will not be valid and we instead base64
-		// decode it
-		if err != nil || len(key) < min || len(key) > max {
-			key, err = base64.StdEncoding.DecodeString(req.Key)
-			if err != nil {
-				respondError(
-					w, http.StatusBadRequest,
-					errors.New("'key' must be a valid hex or base64 string"))
-				return
-			}
+		key, err := decodeRekeyKey(core, req.Key)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, err)
+			return
 		}
 
 		// Use the key to make progress on rekey
@@ -202,6 +201,8 @@ func handleSysRekeyUpdate(core *vault.Core, recovery bool) http.Handler {
 			resp.Nonce = req.Nonce
 			resp.Backup = result.Backup
 			resp.PGPFingerprints = result.PGPFingerprints
+			resp.VerificationRequired = result.VerificationRequired
+			resp.VerificationNonce = result.VerificationNonce
 
 			// Encode the keys
 			keys := make([]string, 0, len(result.SecretShares))
@@ -219,12 +220,135 @@ func handleSysRekeyUpdate(core *vault.Core, recovery bool) http.Handler {
 	})
 }
 
+func handleSysRekeyVerify(core *vault.Core, recovery bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		standby, _ := core.Standby()
+		if standby {
+			respondStandby(core, w, r.URL)
+			return
+		}
+
+		repState := core.ReplicationState()
+		if repState.HasState(consts.ReplicationPerformanceSecondary) {
+			respondError(w, http.StatusBadRequest,
+				fmt.Errorf("rekeying can only be performed on the primary cluster when replication is activated"))
+			return
+		}
+
+		switch {
+		case recovery && !core.SealAccess().RecoveryKeySupported():
+			respondError(w, http.StatusBadRequest, fmt.Errorf("recovery rekeying not supported"))
+		case r.Method == "GET":
+			handleSysRekeyVerifyGet(core, recovery, w, r)
+		case r.Method == "POST" || r.Method == "PUT":
+			handleSysRekeyVerifyPut(core, recovery, w, r)
+		case r.Method == "DELETE":
+			handleSysRekeyVerifyDelete(core, recovery, w, r)
+		default:
+			respondError(w, http.StatusMethodNotAllowed, nil)
+		}
+	})
+}
+
+func handleSysRekeyVerifyGet(core *vault.Core, recovery bool, w http.ResponseWriter, r *http.Request) {
+	// Get the rekey configuration
+	rekeyConf, err := core.RekeyConfig(recovery)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if rekeyConf == nil {
+		respondError(w, http.StatusBadRequest, errors.New("no rekey configuration found"))
+		return
+	}
+
+	// Get the progress
+	progress, err := core.RekeyVerificationProgress(recovery)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// Format the status
+	respondOk(w, &RekeyVerificationStatusResponse{
+		Nonce:    rekeyConf.VerificationNonce,
+		Started:  rekeyConf.VerificationNonce != "",
+		T:        rekeyConf.SecretThreshold,
+		N:        rekeyConf.SecretShares,
+		Progress: progress,
+	})
+}
+
+func handleSysRekeyVerifyPut(core *vault.Core, recovery bool, w http.ResponseWriter, r *http.Request) {
+	// Parse the request
+	var req RekeyVerificationUpdateRequest
+	if err := parseRequest(r, w, &req); err != nil {
+		respondError(w, http.StatusBadRequest, err)
+		return
+	}
+	if req.Key == "" {
+		respondError(
+			w, http.StatusBadRequest,
+			errors.New("'key' must be specified in request body as JSON"))
+		return
+	}
+
+	key, err := decodeRekeyKey(core, req.Key)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// Use the key to make progress on the verification
+	result, err := core.RekeyVerify(key, req.Nonce, recovery)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// Format the response
+	if result == nil {
+		handleSysRekeyVerifyGet(core, recovery, w, r)
+		return
+	}
+	respondOk(w, &RekeyVerificationUpdateResponse{
+		Nonce:    result.Nonce,
+		Complete: true,
+	})
+}
+
+func handleSysRekeyVerifyDelete(core *vault.Core, recovery bool, w http.ResponseWriter, r *http.Request) {
+	if err := core.RekeyVerifyRestart(recovery); err != nil {
+		respondError(w, http.StatusBadRequest, err)
+		return
+	}
+	handleSysRekeyVerifyGet(core, recovery, w, r)
+}
+
+// decodeRekeyKey decodes a key share, which is base64 or hex encoded
+func decodeRekeyKey(core *vault.Core, encoded string) ([]byte, error) {
+	min, max := core.BarrierKeyLength()
+	key, err := hex.DecodeString(encoded)
+	// We check min and max here to ensure that a string that is base64
+	// encoded but also valid hex will not be valid and we instead base64
+	// decode it
+	if err != nil || len(key) < min || len(key) > max {
+		key, err = base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, errors.New("'key' must be a valid hex or base64 string")
+		}
+	}
+	return key, nil
+}
+
 type RekeyRequest struct {
 	SecretShares    int      `json:"secret_shares"`
 	SecretThreshold int      `json:"secret_threshold"`
 	StoredShares    int      `json:"stored_shares"`
 	PGPKeys         []string `json:"pgp_keys"`
 	Backup          bool     `json:"backup"`
+
+	RequireVerification bool `json:"require_verification"`
 }
 
 type RekeyStatusResponse struct {
@@ -236,6 +360,9 @@ type RekeyStatusResponse struct {
 	Required        int      `json:"required"`
 	PGPFingerprints []string `json:"pgp_fingerprints"`
 	Backup          bool     `json:"backup"`
+
+	VerificationRequired bool   `json:"verification_required"`
+	VerificationNonce    string `json:"verification_nonce,omitempty"`
 }
 
 type RekeyUpdateRequest struct {
@@ -250,4 +377,25 @@ type RekeyUpdateResponse struct {
 	KeysB64         []string `json:"keys_base64"`
 	PGPFingerprints []string `json:"pgp_fingerprints"`
 	Backup          bool     `json:"backup"`
+
+	VerificationRequired bool   `json:"verification_required"`
+	VerificationNonce    string `json:"verification_nonce,omitempty"`
+}
+
+type RekeyVerificationUpdateRequest struct {
+	Nonce string
+	Key   string
+}
+
+type RekeyVerificationStatusResponse struct {
+	Nonce    string `json:"nonce"`
+	Started  bool   `json:"started"`
+	T        int    `json:"t"`
+	N        int    `json:"n"`
+	Progress int    `json:"progress"`
+}
+
+type RekeyVerificationUpdateResponse struct {
+	Nonce    string `json:"nonce"`
+	Complete bool   `json:"complete"`
 }
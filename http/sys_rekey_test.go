@@ -40,14 +40,15 @@ func TestSysRekeyInit_Status(t *testing.T) {
 
 	var actual map[string]interface{}
 	expected := map[string]interface{}{
-		"started":          false,
-		"t":                json.Number("0"),
-		"n":                json.Number("0"),
-		"progress":         json.Number("0"),
-		"required":         json.Number("3"),
-		"pgp_fingerprints": interface{}(nil),
-		"backup":           false,
-		"nonce":            "",
+		"started":               false,
+		"t":                     json.Number("0"),
+		"n":                     json.Number("0"),
+		"progress":              json.Number("0"),
+		"required":              json.Number("3"),
+		"pgp_fingerprints":      interface{}(nil),
+		"backup":                false,
+		"verification_required": false,
+		"nonce":                 "",
 	}
 	testResponseStatus(t, resp, 200)
 	testResponseBody(t, resp, &actual)
@@ -70,13 +71,14 @@ func TestSysRekeyInit_Setup(t *testing.T) {
 
 	var actual map[string]interface{}
 	expected := map[string]interface{}{
-		"started":          true,
-		"t":                json.Number("3"),
-		"n":                json.Number("5"),
-		"progress":         json.Number("0"),
-		"required":         json.Number("3"),
-		"pgp_fingerprints": interface{}(nil),
-		"backup":           false,
+		"started":               true,
+		"t":                     json.Number("3"),
+		"n":                     json.Number("5"),
+		"progress":              json.Number("0"),
+		"required":              json.Number("3"),
+		"pgp_fingerprints":      interface{}(nil),
+		"backup":                false,
+		"verification_required": false,
 	}
 	testResponseStatus(t, resp, 200)
 	testResponseBody(t, resp, &actual)
@@ -92,13 +94,14 @@ func TestSysRekeyInit_Setup(t *testing.T) {
 
 	actual = map[string]interface{}{}
 	expected = map[string]interface{}{
-		"started":          true,
-		"t":                json.Number("3"),
-		"n":                json.Number("5"),
-		"progress":         json.Number("0"),
-		"required":         json.Number("3"),
-		"pgp_fingerprints": interface{}(nil),
-		"backup":           false,
+		"started":               true,
+		"t":                     json.Number("3"),
+		"n":                     json.Number("5"),
+		"progress":              json.Number("0"),
+		"required":              json.Number("3"),
+		"pgp_fingerprints":      interface{}(nil),
+		"backup":                false,
+		"verification_required": false,
 	}
 	testResponseStatus(t, resp, 200)
 	testResponseBody(t, resp, &actual)
@@ -136,14 +139,15 @@ func TestSysRekeyInit_Cancel(t *testing.T) {
 
 	var actual map[string]interface{}
 	expected := map[string]interface{}{
-		"started":          false,
-		"t":                json.Number("0"),
-		"n":                json.Number("0"),
-		"progress":         json.Number("0"),
-		"required":         json.Number("3"),
-		"pgp_fingerprints": interface{}(nil),
-		"backup":           false,
-		"nonce":            "",
+		"started":               false,
+		"t":                     json.Number("0"),
+		"n":                     json.Number("0"),
+		"progress":              json.Number("0"),
+		"required":              json.Number("3"),
+		"pgp_fingerprints":      interface{}(nil),
+		"backup":                false,
+		"verification_required": false,
+		"nonce":                 "",
 	}
 	testResponseStatus(t, resp, 200)
 	testResponseBody(t, resp, &actual)
@@ -189,14 +193,15 @@ func TestSysRekey_Update(t *testing.T) {
 
 		actual = map[string]interface{}{}
 		expected = map[string]interface{}{
-			"started":          true,
-			"nonce":            rekeyStatus["nonce"].(string),
-			"backup":           false,
-			"pgp_fingerprints": interface{}(nil),
-			"required":         json.Number("3"),
-			"t":                json.Number("3"),
-			"n":                json.Number("5"),
-			"progress":         json.Number(fmt.Sprintf("%d", i+1)),
+			"started":               true,
+			"nonce":                 rekeyStatus["nonce"].(string),
+			"backup":                false,
+			"verification_required": false,
+			"pgp_fingerprints":      interface{}(nil),
+			"required":              json.Number("3"),
+			"t":                     json.Number("3"),
+			"n":                     json.Number("5"),
+			"progress":              json.Number(fmt.Sprintf("%d", i+1)),
 		}
 		testResponseStatus(t, resp, 200)
 		testResponseBody(t, resp, &actual)
@@ -258,3 +263,91 @@ func TestSysRekey_ReInitUpdate(t *testing.T) {
 
 	testResponseStatus(t, resp, 400)
 }
+
+func TestSysRekey_Verify(t *testing.T) {
+	core, keys, token := vault.TestCoreUnsealed(t)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+	TestServerAuth(t, addr, token)
+
+	resp := testHttpPut(t, token, addr+"/v1/sys/rekey/init", map[string]interface{}{
+		"secret_shares":        3,
+		"secret_threshold":     2,
+		"require_verification": true,
+	})
+	var rekeyStatus map[string]interface{}
+	testResponseStatus(t, resp, 200)
+	testResponseBody(t, resp, &rekeyStatus)
+	if rekeyStatus["verification_required"] != true {
+		t.Fatalf("bad: %#v", rekeyStatus)
+	}
+
+	var result map[string]interface{}
+	for _, key := range keys {
+		resp = testHttpPut(t, token, addr+"/v1/sys/rekey/update", map[string]interface{}{
+			"nonce": rekeyStatus["nonce"].(string),
+			"key":   hex.EncodeToString(key),
+		})
+		result = map[string]interface{}{}
+		testResponseStatus(t, resp, 200)
+		testResponseBody(t, resp, &result)
+	}
+	if result["complete"] != true || result["verification_required"] != true {
+		t.Fatalf("bad: %#v", result)
+	}
+	verificationNonce := result["verification_nonce"].(string)
+	newKeys := result["keys"].([]interface{})
+	if verificationNonce == "" || len(newKeys) != 3 {
+		t.Fatalf("bad: %#v", result)
+	}
+
+	resp = testHttpGet(t, token, addr+"/v1/sys/rekey/verify")
+	var actual map[string]interface{}
+	expected := map[string]interface{}{
+		"nonce":    verificationNonce,
+		"started":  true,
+		"t":        json.Number("2"),
+		"n":        json.Number("3"),
+		"progress": json.Number("0"),
+	}
+	testResponseStatus(t, resp, 200)
+	testResponseBody(t, resp, &actual)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("\nexpected: %#v\nactual: %#v", expected, actual)
+	}
+
+	resp = testHttpPut(t, token, addr+"/v1/sys/rekey/verify", map[string]interface{}{
+		"nonce": verificationNonce,
+		"key":   newKeys[0].(string),
+	})
+	actual = map[string]interface{}{}
+	expected["progress"] = json.Number("1")
+	testResponseStatus(t, resp, 200)
+	testResponseBody(t, resp, &actual)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("\nexpected: %#v\nactual: %#v", expected, actual)
+	}
+
+	resp = testHttpPut(t, token, addr+"/v1/sys/rekey/verify", map[string]interface{}{
+		"nonce": verificationNonce,
+		"key":   newKeys[1].(string),
+	})
+	actual = map[string]interface{}{}
+	expected = map[string]interface{}{
+		"nonce":    verificationNonce,
+		"complete": true,
+	}
+	testResponseStatus(t, resp, 200)
+	testResponseBody(t, resp, &actual)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("\nexpected: %#v\nactual: %#v", expected, actual)
+	}
+
+	// The rekey is complete
+	resp = testHttpGet(t, token, addr+"/v1/sys/rekey/verify")
+	testResponseStatus(t, resp, 400)
+	conf, err := core.SealAccess().BarrierConfig()
+	if err != nil || conf.SecretShares != 3 || conf.SecretThreshold != 2 {
+		t.Fatalf("bad: conf: %#v, err: %v", conf, err)
+	}
+}
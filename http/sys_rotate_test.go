@@ -43,6 +43,10 @@ func TestSysRotate(t *testing.T) {
 	expected["data"].(map[string]interface{})["install_time"] = actualInstallTime
 	expected["install_time"] = actualInstallTime
 
+	// The rotation writes the keyring canary with the new key
+	expected["data"].(map[string]interface{})["encryptions"] = json.Number("1")
+	expected["encryptions"] = json.Number("1")
+
 	expected["request_id"] = actual["request_id"]
 
 	if !reflect.DeepEqual(actual, expected) {
@@ -120,10 +120,16 @@ func handleSysUnseal(core *vault.Core) http.Handler {
 				}
 			}
 
-			// Attempt the unseal
-			if _, err := core.Unseal(key); err != nil {
+			// Attempt the unseal, migrating the seal if requested
+			unseal := core.Unseal
+			if req.Migrate {
+				unseal = core.UnsealMigrate
+			}
+			if _, err := unseal(key); err != nil {
 				switch {
 				case errwrap.ContainsType(err, new(vault.ErrInvalidKey)):
+				case errwrap.Contains(err, vault.ErrSealMigrationPending.Error()):
+				case errwrap.Contains(err, vault.ErrNoSealMigration.Error()):
 				case errwrap.Contains(err, vault.ErrBarrierInvalidKey.Error()):
 				case errwrap.Contains(err, vault.ErrBarrierNotInit.Error()):
 				case errwrap.Contains(err, vault.ErrBarrierSealed.Error()):
@@ -160,10 +166,16 @@ func handleSysSealStatusRaw(core *vault.Core, w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	sealConfig, err := core.SealAccess().BarrierConfig()
-	if err != nil {
-		respondError(w, http.StatusInternalServerError, err)
-		return
+	// During a seal migration, the keys to submit are described by the
+	// configuration of the migration
+	sealConfig := core.SealMigrationConfig()
+	migration := sealConfig != nil
+	if !migration {
+		sealConfig, err = core.SealAccess().BarrierConfig()
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, err)
+			return
+		}
 	}
 	if sealConfig == nil {
 		respondError(w, http.StatusBadRequest, fmt.Errorf(
@@ -190,11 +202,13 @@ func handleSysSealStatusRaw(core *vault.Core, w http.ResponseWriter, r *http.Req
 	progress, nonce := core.SecretProgress()
 
 	respondOk(w, &SealStatusResponse{
+		Type:        sealConfig.Type,
 		Sealed:      sealed,
 		T:           sealConfig.SecretThreshold,
 		N:           sealConfig.SecretShares,
 		Progress:    progress,
 		Nonce:       nonce,
+		Migration:   migration,
 		Version:     version.GetVersion().VersionNumber(),
 		ClusterName: clusterName,
 		ClusterID:   clusterID,
@@ -202,17 +216,20 @@ func handleSysSealStatusRaw(core *vault.Core, w http.ResponseWriter, r *http.Req
 }
 
 type SealStatusResponse struct {
+	Type        string `json:"type"`
 	Sealed      bool   `json:"sealed"`
 	T           int    `json:"t"`
 	N           int    `json:"n"`
 	Progress    int    `json:"progress"`
 	Nonce       string `json:"nonce"`
+	Migration   bool   `json:"migration"`
 	Version     string `json:"version"`
 	ClusterName string `json:"cluster_name,omitempty"`
 	ClusterID   string `json:"cluster_id,omitempty"`
 }
 
 type UnsealRequest struct {
-	Key   string
-	Reset bool
+	Key     string
+	Reset   bool
+	Migrate bool
 }
@@ -26,11 +26,13 @@ func TestSysSealStatus(t *testing.T) {
 
 	var actual map[string]interface{}
 	expected := map[string]interface{}{
-		"sealed":   true,
-		"t":        json.Number("3"),
-		"n":        json.Number("3"),
-		"progress": json.Number("0"),
-		"nonce":    "",
+		"type":      "shamir",
+		"migration": false,
+		"sealed":    true,
+		"t":         json.Number("3"),
+		"n":         json.Number("3"),
+		"progress":  json.Number("0"),
+		"nonce":     "",
 	}
 	testResponseStatus(t, resp, 200)
 	testResponseBody(t, resp, &actual)
@@ -114,11 +116,13 @@ func TestSysUnseal(t *testing.T) {
 
 		var actual map[string]interface{}
 		expected := map[string]interface{}{
-			"sealed":   true,
-			"t":        json.Number("3"),
-			"n":        json.Number("3"),
-			"progress": json.Number(fmt.Sprintf("%d", i+1)),
-			"nonce":    "",
+			"type":      "shamir",
+			"migration": false,
+			"sealed":    true,
+			"t":         json.Number("3"),
+			"n":         json.Number("3"),
+			"progress":  json.Number(fmt.Sprintf("%d", i+1)),
+			"nonce":     "",
 		}
 		if i == len(keys)-1 {
 			expected["sealed"] = false
@@ -163,6 +167,27 @@ func TestSysUnseal_badKey(t *testing.T) {
 	testResponseStatus(t, resp, 400)
 }
 
+func TestSysUnseal_migrateNotPending(t *testing.T) {
+	core := vault.TestCore(t)
+	keys, _ := vault.TestCoreInit(t, core)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+
+	resp := testHttpPut(t, "", addr+"/v1/sys/unseal", map[string]interface{}{
+		"key":     hex.EncodeToString(keys[0]),
+		"migrate": true,
+	})
+	testResponseStatus(t, resp, 400)
+
+	resp = testHttpGet(t, "", addr+"/v1/sys/seal-status")
+	var actual map[string]interface{}
+	testResponseStatus(t, resp, 200)
+	testResponseBody(t, resp, &actual)
+	if actual["migration"] != false || actual["progress"] != json.Number("0") {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
 func TestSysUnseal_Reset(t *testing.T) {
 	core := vault.TestCore(t)
 	ln, addr := TestServer(t, core)
@@ -192,10 +217,12 @@ func TestSysUnseal_Reset(t *testing.T) {
 
 		var actual map[string]interface{}
 		expected := map[string]interface{}{
-			"sealed":   true,
-			"t":        json.Number("3"),
-			"n":        json.Number("5"),
-			"progress": json.Number(strconv.Itoa(i + 1)),
+			"type":      "shamir",
+			"migration": false,
+			"sealed":    true,
+			"t":         json.Number("3"),
+			"n":         json.Number("5"),
+			"progress":  json.Number(strconv.Itoa(i + 1)),
 		}
 		testResponseStatus(t, resp, 200)
 		testResponseBody(t, resp, &actual)
@@ -228,10 +255,12 @@ func TestSysUnseal_Reset(t *testing.T) {
 
 	actual = map[string]interface{}{}
 	expected := map[string]interface{}{
-		"sealed":   true,
-		"t":        json.Number("3"),
-		"n":        json.Number("5"),
-		"progress": json.Number("0"),
+		"type":      "shamir",
+		"migration": false,
+		"sealed":    true,
+		"t":         json.Number("3"),
+		"n":         json.Number("5"),
+		"progress":  json.Number("0"),
 	}
 	testResponseStatus(t, resp, 200)
 	testResponseBody(t, resp, &actual)
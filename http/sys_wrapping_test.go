@@ -352,3 +352,205 @@ func TestHTTP_Wrapping(t *testing.T) {
 		t.Fatalf("secret data did not match expected: %#v", secret.Data)
 	}
 }
+
+func TestHTTP_WrappingEntries(t *testing.T) {
+	cluster := vault.NewTestCluster(t, &vault.CoreConfig{}, &vault.TestClusterOptions{
+		HandlerFunc: Handler,
+	})
+	cluster.Start()
+	defer cluster.Cleanup()
+
+	cores := cluster.Cores
+	vault.TestWaitActive(t, cores[0].Core)
+
+	client := cores[0].Client
+	client.SetToken(cluster.RootToken)
+	client.SetWrappingLookupFunc(func(operation, path string) string {
+		if path == "sys/wrapping/wrap-entries" {
+			return "5m"
+		}
+		return api.DefaultWrappingLookupFunc(operation, path)
+	})
+
+	// Entries must be objects
+	_, err := client.Logical().Write("sys/wrapping/wrap-entries", map[string]interface{}{
+		"tls": "value",
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	secret, err := client.Logical().Write("sys/wrapping/wrap-entries", map[string]interface{}{
+		"tls": map[string]interface{}{
+			"certificate": "cert",
+		},
+		"database": map[string]interface{}{
+			"username": "admin",
+			"password": "hunter2",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret == nil || secret.WrapInfo == nil {
+		t.Fatal("secret or wrap info is nil")
+	}
+	wrapToken := secret.WrapInfo.Token
+
+	// An entry must be given
+	_, err = client.Logical().Unwrap(wrapToken)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	// Tokens wrapping multiple entries cannot be rewrapped
+	_, err = client.Logical().Write("sys/wrapping/rewrap", map[string]interface{}{
+		"token": wrapToken,
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	secret, err = client.Logical().UnwrapEntry(wrapToken, "database")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret == nil || secret.Data["username"] != "admin" || secret.Data["password"] != "hunter2" {
+		t.Fatalf("bad: %#v", secret)
+	}
+
+	// Entries can only be unwrapped once
+	_, err = client.Logical().UnwrapEntry(wrapToken, "database")
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	secret, err = client.Logical().Write("sys/wrapping/lookup", map[string]interface{}{
+		"token": wrapToken,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret == nil || !reflect.DeepEqual(secret.Data["entries"], []interface{}{"tls"}) {
+		t.Fatalf("bad: %#v", secret)
+	}
+	if secret.Data["creation_path"] != "sys/wrapping/wrap-entries" {
+		t.Fatalf("bad: %#v", secret.Data)
+	}
+
+	// The token wrapping the entries can unwrap them itself
+	client.SetToken("")
+	secret, err = client.Logical().UnwrapEntry(wrapToken, "tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret == nil || secret.Data["certificate"] != "cert" {
+		t.Fatalf("bad: %#v", secret)
+	}
+
+	// The token is revoked along with the last entry
+	client.SetToken(cluster.RootToken)
+	_, err = client.Logical().Write("sys/wrapping/lookup", map[string]interface{}{
+		"token": wrapToken,
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestHTTP_WrappingCreationPath(t *testing.T) {
+	cluster := vault.NewTestCluster(t, &vault.CoreConfig{}, &vault.TestClusterOptions{
+		HandlerFunc: Handler,
+	})
+	cluster.Start()
+	defer cluster.Cleanup()
+
+	cores := cluster.Cores
+	vault.TestWaitActive(t, cores[0].Core)
+
+	client := cores[0].Client
+	client.SetToken(cluster.RootToken)
+
+	_, err := client.Logical().Write("secret/foo", map[string]interface{}{
+		"zip": "zap",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetWrappingLookupFunc(func(operation, path string) string {
+		if operation == "GET" && path == "secret/foo" {
+			return "5m"
+		}
+		return api.DefaultWrappingLookupFunc(operation, path)
+	})
+	wrap := func() string {
+		secret, err := client.Logical().Read("secret/foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if secret == nil || secret.WrapInfo == nil {
+			t.Fatal("secret or wrap info is nil")
+		}
+		return secret.WrapInfo.Token
+	}
+
+	// A mismatching creation path fails without consuming the token
+	wrapToken := wrap()
+	_, err = client.Logical().Write("sys/wrapping/unwrap", map[string]interface{}{
+		"token":         wrapToken,
+		"creation_path": "secret/bar",
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	secret, err := client.Logical().Write("sys/wrapping/unwrap", map[string]interface{}{
+		"token":         wrapToken,
+		"creation_path": "secret/foo",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret == nil || secret.Data["zip"] != "zap" {
+		t.Fatalf("bad: %#v", secret)
+	}
+
+	// Policies can require the creation path to be asserted
+	err = client.Sys().PutPolicy("unwrap-foo", `
+path "sys/wrapping/unwrap" {
+  capabilities        = ["update"]
+  required_parameters = ["creation_path"]
+  allowed_parameters  = {
+    "token"         = []
+    "creation_path" = ["secret/foo"]
+  }
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret, err = client.Auth().Token().Create(&api.TokenCreateRequest{
+		Policies: []string{"unwrap-foo"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	unwrapperToken := secret.Auth.ClientToken
+
+	wrapToken = wrap()
+	client.SetToken(unwrapperToken)
+	_, err = client.Logical().Write("sys/wrapping/unwrap", map[string]interface{}{
+		"token": wrapToken,
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	secret, err = client.Logical().Write("sys/wrapping/unwrap", map[string]interface{}{
+		"token":         wrapToken,
+		"creation_path": "secret/foo",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret == nil || secret.Data["zip"] != "zap" {
+		t.Fatalf("bad: %#v", secret)
+	}
+}
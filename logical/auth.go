@@ -59,6 +59,16 @@ type Auth struct {
 	// Alias is the information about the authenticated client returned by
 	// the auth backend
 	Alias *Alias `json:"alias" structs:"alias" mapstructure:"alias"`
+
+	// GroupAliases are the groups of the authenticated client in the auth
+	// source. The client is made a member of the external groups tied to
+	// these group aliases, and removed from the other external groups of the
+	// mount.
+	GroupAliases []*Alias `json:"group_aliases" structs:"group_aliases" mapstructure:"group_aliases"`
+
+	// BoundCIDRs, if set, restricts the usage of the issued token to
+	// requests originating from these CIDR blocks
+	BoundCIDRs []string `json:"bound_cidrs" structs:"bound_cidrs" mapstructure:"bound_cidrs"`
 }
 
 func (a *Auth) GoString() string {
@@ -12,4 +12,16 @@ type Connection struct {
 
 	// ConnState is the TLS connection state if applicable.
 	ConnState *tls.ConnectionState
+
+	// PeerCredentials are the credentials of the process which sent the
+	// request over a unix socket, if the listener captures them.
+	PeerCredentials *PeerCredentials `json:"peer_credentials,omitempty"`
+}
+
+// PeerCredentials are the credentials of the process at the other end of a
+// unix socket, as reported by the kernel.
+type PeerCredentials struct {
+	PID int32  `json:"pid"`
+	UID uint32 `json:"uid"`
+	GID uint32 `json:"gid"`
 }
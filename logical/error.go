@@ -1,5 +1,12 @@
 package logical
 
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
 type HTTPCodedError interface {
 	Error() string
 	Code() int
@@ -22,6 +29,36 @@ func (e *codedError) Code() int {
 	return e.code
 }
 
+// RateLimitQuotaError is returned for the requests rejected by a rate limit
+// quota. RetryAfter is the time until the quota allows a request again.
+type RateLimitQuotaError struct {
+	Quota      string
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitQuotaError) Error() string {
+	return fmt.Sprintf("%s: quota %q", ErrRateLimitQuotaExceeded, e.Quota)
+}
+
+func (e *RateLimitQuotaError) Code() int {
+	return http.StatusTooManyRequests
+}
+
+// FieldValidationError is returned for the requests whose fields don't
+// satisfy the constraints of their schema. Errors holds one message per
+// violated constraint, prefixed with the name of the field.
+type FieldValidationError struct {
+	Errors []string
+}
+
+func (e *FieldValidationError) Error() string {
+	return strings.Join(e.Errors, "; ")
+}
+
+func (e *FieldValidationError) Code() int {
+	return http.StatusBadRequest
+}
+
 // Struct to identify user input errors.  This is helpful in responding the
 // appropriate status codes to clients from the HTTP endpoints.
 type StatusBadRequest struct {
@@ -82,6 +82,14 @@ type Backend struct {
 	// See the built-in AuthRenew helpers in lease.go for common callbacks.
 	AuthRenew OperationFunc
 
+	// LoginHooks are called in order after a login to the backend succeeds.
+	// Each may return metadata computed from the request or the resulting
+	// authentication, such as a location derived from the client's address,
+	// which is added to both the token and the entity alias in the same
+	// login. Metadata set by the login itself is never overwritten. They
+	// aren't called when the authentication is renewed.
+	LoginHooks []LoginHookFunc
+
 	// LicenseRegistration is called to register the license for a backend.
 	LicenseRegistration LicenseRegistrationFunc
 
@@ -101,6 +109,9 @@ type periodicFunc func(*logical.Request) error
 // OperationFunc is the callback called for an operation on a path.
 type OperationFunc func(*logical.Request, *FieldData) (*logical.Response, error)
 
+// LoginHookFunc is the callback called after a successful login.
+type LoginHookFunc func(*logical.Request, *logical.Auth) (map[string]string, error)
+
 // WALRollbackFunc is the callback for rollbacks.
 type WALRollbackFunc func(*logical.Request, string, interface{}) error
 
@@ -140,6 +151,19 @@ func (b *Backend) HandleExistenceCheck(req *logical.Request) (checkFound bool, e
 
 	checkFound = true
 
+	// Reads, HEAD requests and lists carry the query parameters of the HTTP
+	// request as data, which are only passed on for the fields the path
+	// declares. The data is restored afterwards for the caller, such as for
+	// auditing.
+	if req.Operation == logical.ReadOperation || req.Operation == logical.HeaderOperation ||
+		req.Operation == logical.ListOperation {
+		data := req.Data
+		req.Data = declaredFields(data, path.Fields)
+		defer func() {
+			req.Data = data
+		}()
+	}
+
 	// Build up the data for the route, with the URL taking priority
 	// for the fields over the PUT data.
 	raw := make(map[string]interface{}, len(path.Fields))
@@ -190,6 +214,19 @@ func (b *Backend) HandleRequest(req *logical.Request) (*logical.Response, error)
 		return nil, logical.ErrUnsupportedPath
 	}
 
+	// Reads, HEAD requests and lists carry the query parameters of the HTTP
+	// request as data, which are only passed on for the fields the path
+	// declares. The data is restored afterwards for the caller, such as for
+	// auditing.
+	if req.Operation == logical.ReadOperation || req.Operation == logical.HeaderOperation ||
+		req.Operation == logical.ListOperation {
+		data := req.Data
+		req.Data = declaredFields(data, path.Fields)
+		defer func() {
+			req.Data = data
+		}()
+	}
+
 	// Build up the data for the route, with the URL taking priority
 	// for the fields over the PUT data.
 	raw := make(map[string]interface{}, len(path.Fields))
@@ -208,7 +245,7 @@ func (b *Backend) HandleRequest(req *logical.Request) (*logical.Response, error)
 	}
 	if !ok {
 		if req.Operation == logical.HelpOperation {
-			callback = path.helpCallback
+			callback = path.helpCallback(b)
 			ok = true
 		}
 	}
@@ -220,15 +257,91 @@ func (b *Backend) HandleRequest(req *logical.Request) (*logical.Response, error)
 		Raw:    raw,
 		Schema: path.Fields}
 
-	if req.Operation != logical.HelpOperation {
+	switch req.Operation {
+	case logical.HelpOperation:
+	case logical.PatchOperation:
+		// The fields set to null in a patch are removed from the resource,
+		// so only the values of the other fields are validated
+		patch := FieldData{
+			Raw:    make(map[string]interface{}, len(raw)),
+			Schema: path.Fields,
+		}
+		for k, v := range raw {
+			if v != nil {
+				patch.Raw[k] = v
+			}
+		}
+		if err := patch.Validate(); err != nil {
+			return nil, err
+		}
+	default:
 		err := fd.Validate()
 		if err != nil {
 			return nil, err
 		}
 	}
 
+	// Don't start the work of a request which was canceled while waiting
+	if req.Context().Err() != nil {
+		return nil, logical.ErrRequestCanceled
+	}
+
 	// Call the callback with the request and the data
-	return callback(req, &fd)
+	resp, err := callback(req, &fd)
+	if err != nil || resp == nil || resp.Auth == nil || len(b.LoginHooks) == 0 ||
+		req.Operation == logical.RenewOperation {
+		return resp, err
+	}
+
+	if err := b.runLoginHooks(req, resp.Auth); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// declaredFields returns the entries of data that are declared in the schema
+func declaredFields(data map[string]interface{}, schema map[string]*FieldSchema) map[string]interface{} {
+	var declared map[string]interface{}
+	for k, v := range data {
+		if _, ok := schema[k]; !ok {
+			continue
+		}
+		if declared == nil {
+			declared = make(map[string]interface{}, len(data))
+		}
+		declared[k] = v
+	}
+	return declared
+}
+
+// runLoginHooks adds the metadata returned by the login hooks to the token
+// and entity alias of the authentication
+func (b *Backend) runLoginHooks(req *logical.Request, auth *logical.Auth) error {
+	for _, hook := range b.LoginHooks {
+		metadata, err := hook(req, auth)
+		if err != nil {
+			return err
+		}
+
+		for k, v := range metadata {
+			if _, ok := auth.Metadata[k]; !ok {
+				if auth.Metadata == nil {
+					auth.Metadata = make(map[string]string, len(metadata))
+				}
+				auth.Metadata[k] = v
+			}
+			if auth.Alias == nil {
+				continue
+			}
+			if _, ok := auth.Alias.Metadata[k]; !ok {
+				if auth.Alias.Metadata == nil {
+					auth.Alias.Metadata = make(map[string]string, len(metadata))
+				}
+				auth.Alias.Metadata[k] = v
+			}
+		}
+	}
+	return nil
 }
 
 // SpecialPaths is the logical.Backend implementation.
@@ -369,6 +482,15 @@ func (b *Backend) init() {
 			p.Pattern = p.Pattern + "$"
 		}
 		b.pathsRe[i] = regexp.MustCompile(p.Pattern)
+
+		for name, schema := range p.Fields {
+			if schema.Pattern == "" {
+				continue
+			}
+			if _, err := regexp.Compile(schema.Pattern); err != nil {
+				panic(fmt.Sprintf("invalid pattern of field %q of path %q: %s", name, p.Pattern, err))
+			}
+		}
 	}
 }
 
@@ -429,7 +551,17 @@ func (b *Backend) handleRootHelp() (*logical.Response, error) {
 		return nil, err
 	}
 
-	return logical.HelpResponse(help, nil), nil
+	// Describe the paths as an OpenAPI document too
+	doc := NewOASDocument()
+	b.documentPaths(doc)
+	openapi, err := doc.Map()
+	if err != nil {
+		return nil, err
+	}
+
+	resp := logical.HelpResponse(help, nil)
+	resp.Data["openapi"] = openapi
+	return resp, nil
 }
 
 func (b *Backend) handleRevokeRenew(
@@ -552,10 +684,36 @@ func (b *Backend) handleWALRollback(
 }
 
 // FieldSchema is a basic schema to describe the format of a path field.
+//
+// The constraints of the schema are enforced on the values given in the
+// request before the callback of the path is called. A request with values
+// which don't satisfy them is rejected with a logical.FieldValidationError
+// listing every invalid field. Defaults are not checked.
 type FieldSchema struct {
 	Type        FieldType
 	Default     interface{}
 	Description string
+
+	// AllowedValues restricts the value of TypeString, TypeNameString and
+	// TypeInt fields, and each element of TypeStringSlice and
+	// TypeCommaStringSlice fields, to the given values.
+	AllowedValues []interface{}
+
+	// Min and Max bound the value of TypeInt and TypeDurationSecond fields,
+	// in seconds for the latter. A nil bound is not enforced; use Bound to
+	// set one.
+	Min *int
+	Max *int
+
+	// Pattern is a regular expression which the value of TypeString and
+	// TypeNameString fields, and each element of TypeStringSlice and
+	// TypeCommaStringSlice fields, must match. It is not anchored.
+	Pattern string
+}
+
+// Bound returns a bound to set as the Min or Max of a FieldSchema
+func Bound(v int) *int {
+	return &v
 }
 
 // DefaultOrZero returns the default value if it is set, or otherwise
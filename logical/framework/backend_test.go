@@ -1,6 +1,7 @@
 package framework
 
 import (
+	"fmt"
 	"reflect"
 	"sync/atomic"
 	"testing"
@@ -77,6 +78,229 @@ func TestBackendHandleRequest(t *testing.T) {
 	}
 }
 
+func TestBackendHandleRequest_undeclaredReadData(t *testing.T) {
+	var seen map[string]interface{}
+	callback := func(req *logical.Request, data *FieldData) (*logical.Response, error) {
+		seen = req.Data
+		return nil, nil
+	}
+
+	b := &Backend{
+		Paths: []*Path{
+			&Path{
+				Pattern: "foo/bar",
+				Fields: map[string]*FieldSchema{
+					"value": &FieldSchema{Type: TypeInt},
+				},
+				Callbacks: map[logical.Operation]OperationFunc{
+					logical.ReadOperation:   callback,
+					logical.UpdateOperation: callback,
+				},
+			},
+		},
+	}
+
+	data := map[string]interface{}{"value": "42", "other": "true"}
+	if _, err := b.HandleRequest(&logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "foo/bar",
+		Data:      data,
+	}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(seen, map[string]interface{}{"value": "42"}) {
+		t.Fatalf("expected only declared fields on read: %#v", seen)
+	}
+
+	// The caller's request is left untouched
+	req := &logical.Request{
+		Operation: logical.ListOperation,
+		Path:      "foo/bar",
+		Data:      data,
+	}
+	b.Paths[0].Callbacks[logical.ListOperation] = callback
+	if _, err := b.HandleRequest(req); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(seen) != 1 || len(req.Data) != 2 {
+		t.Fatalf("bad: seen: %#v data: %#v", seen, req.Data)
+	}
+
+	// Writes still see all of their data
+	if _, err := b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "foo/bar",
+		Data:      data,
+	}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(seen, data) {
+		t.Fatalf("expected all data on write: %#v", seen)
+	}
+}
+
+func TestBackendHandleRequest_validation(t *testing.T) {
+	called := false
+	callback := func(req *logical.Request, data *FieldData) (*logical.Response, error) {
+		called = true
+		return nil, nil
+	}
+
+	b := &Backend{
+		Paths: []*Path{
+			&Path{
+				Pattern: "foo/bar",
+				Fields: map[string]*FieldSchema{
+					"value": &FieldSchema{
+						Type: TypeInt,
+						Max:  Bound(10),
+					},
+				},
+				Callbacks: map[logical.Operation]OperationFunc{
+					logical.UpdateOperation: callback,
+				},
+			},
+		},
+	}
+
+	_, err := b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "foo/bar",
+		Data:      map[string]interface{}{"value": 42},
+	})
+	fieldErr, ok := err.(*logical.FieldValidationError)
+	if !ok {
+		t.Fatalf("bad: %#v", err)
+	}
+	if fieldErr.Code() != 400 {
+		t.Fatalf("bad: %d", fieldErr.Code())
+	}
+	if called {
+		t.Fatal("callback should not be called with invalid fields")
+	}
+}
+
+func TestBackend_invalidFieldPattern(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a panic with an invalid field pattern")
+		}
+	}()
+
+	b := &Backend{
+		Paths: []*Path{
+			&Path{
+				Pattern: "foo",
+				Fields: map[string]*FieldSchema{
+					"value": &FieldSchema{
+						Type:    TypeString,
+						Pattern: "[",
+					},
+				},
+			},
+		},
+	}
+	b.Route("foo")
+}
+
+func TestBackendHandleRequest_loginHooks(t *testing.T) {
+	callback := func(req *logical.Request, data *FieldData) (*logical.Response, error) {
+		return &logical.Response{
+			Auth: &logical.Auth{
+				Metadata: map[string]string{
+					"user": "armon",
+				},
+				Alias: &logical.Alias{
+					Name: "armon",
+				},
+			},
+		}, nil
+	}
+
+	b := &Backend{
+		Paths: []*Path{
+			&Path{
+				Pattern: "login",
+				Callbacks: map[logical.Operation]OperationFunc{
+					logical.UpdateOperation: callback,
+				},
+			},
+		},
+		LoginHooks: []LoginHookFunc{
+			func(req *logical.Request, auth *logical.Auth) (map[string]string, error) {
+				return map[string]string{
+					"user":      "overwritten",
+					"remote_ip": req.Connection.RemoteAddr,
+				}, nil
+			},
+			func(req *logical.Request, auth *logical.Auth) (map[string]string, error) {
+				// Later hooks see the metadata added by earlier ones
+				return map[string]string{
+					"seen": auth.Metadata["remote_ip"],
+				}, nil
+			},
+		},
+	}
+
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation:  logical.UpdateOperation,
+		Path:       "login",
+		Connection: &logical.Connection{RemoteAddr: "127.0.0.1"},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := map[string]string{
+		"user":      "armon",
+		"remote_ip": "127.0.0.1",
+		"seen":      "127.0.0.1",
+	}
+	if !reflect.DeepEqual(resp.Auth.Metadata, expected) {
+		t.Fatalf("bad: %#v", resp.Auth.Metadata)
+	}
+	expected["user"] = "overwritten"
+	if !reflect.DeepEqual(resp.Auth.Alias.Metadata, expected) {
+		t.Fatalf("bad: %#v", resp.Auth.Alias.Metadata)
+	}
+
+	// Renewals keep the metadata of the login
+	b.AuthRenew = func(req *logical.Request, data *FieldData) (*logical.Response, error) {
+		return &logical.Response{
+			Auth: req.Auth,
+		}, nil
+	}
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation:  logical.RenewOperation,
+		Path:       "login",
+		Connection: &logical.Connection{RemoteAddr: "127.0.0.2"},
+		Auth: &logical.Auth{
+			Metadata: map[string]string{
+				"user": "armon",
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(resp.Auth.Metadata, map[string]string{"user": "armon"}) {
+		t.Fatalf("bad: %#v", resp.Auth.Metadata)
+	}
+
+	// A failing hook fails the login
+	b.LoginHooks = append(b.LoginHooks, func(*logical.Request, *logical.Auth) (map[string]string, error) {
+		return nil, fmt.Errorf("lookup failed")
+	})
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation:  logical.UpdateOperation,
+		Path:       "login",
+		Connection: &logical.Connection{RemoteAddr: "127.0.0.1"},
+	})
+	if err == nil || resp != nil {
+		t.Fatalf("expected an error, got %#v", resp)
+	}
+}
+
 func TestBackendHandleRequest_badwrite(t *testing.T) {
 	callback := func(req *logical.Request, data *FieldData) (*logical.Response, error) {
 		return &logical.Response{
@@ -5,9 +5,12 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"sort"
+	"strings"
 
 	"github.com/hashicorp/vault/helper/parseutil"
 	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/logical"
 	"github.com/mitchellh/mapstructure"
 )
 
@@ -22,9 +25,12 @@ type FieldData struct {
 
 // Validate cycles through raw data and validate conversions in
 // the schema, so we don't get an error/panic later when
-// trying to get data out.  Data not in the schema is not
-// an error at this point, so we don't worry about it.
+// trying to get data out, and checks the converted values against the
+// constraints of the schema. Data not in the schema is not
+// an error at this point, so we don't worry about it. The invalid fields
+// are reported together in a logical.FieldValidationError.
 func (d *FieldData) Validate() error {
+	var errs []string
 	for field, value := range d.Raw {
 
 		schema, ok := d.Schema[field]
@@ -35,17 +41,86 @@ func (d *FieldData) Validate() error {
 		switch schema.Type {
 		case TypeBool, TypeInt, TypeMap, TypeDurationSecond, TypeString,
 			TypeNameString, TypeSlice, TypeStringSlice, TypeCommaStringSlice:
-			_, _, err := d.getPrimitive(field, schema)
+			result, _, err := d.getPrimitive(field, schema)
 			if err != nil {
-				return fmt.Errorf("Error converting input %v for field %s: %s", value, field, err)
+				errs = append(errs, fmt.Sprintf("Error converting input %v for field %s: %s", value, field, err))
+				continue
 			}
+			errs = append(errs, schema.validate(field, result)...)
 		default:
 			return fmt.Errorf("unknown field type %s for field %s",
 				schema.Type, field)
 		}
 	}
 
-	return nil
+	if len(errs) == 0 {
+		return nil
+	}
+	sort.Strings(errs)
+	return &logical.FieldValidationError{Errors: errs}
+}
+
+// validate returns the constraints of the schema which the value of the
+// field, converted to its type, doesn't satisfy
+func (s *FieldSchema) validate(field string, value interface{}) []string {
+	var errs []string
+	switch v := value.(type) {
+	case string:
+		errs = append(errs, s.validateString(field, v)...)
+	case []string:
+		for _, elem := range v {
+			errs = append(errs, s.validateString(field, elem)...)
+		}
+	case int:
+		if !s.allowed(v) {
+			errs = append(errs, fmt.Sprintf("%s: %d is not one of %s", field, v, s.allowedValues()))
+		}
+		if s.Min != nil && v < *s.Min {
+			errs = append(errs, fmt.Sprintf("%s: %d is less than the minimum of %d", field, v, *s.Min))
+		}
+		if s.Max != nil && v > *s.Max {
+			errs = append(errs, fmt.Sprintf("%s: %d is greater than the maximum of %d", field, v, *s.Max))
+		}
+	}
+	return errs
+}
+
+func (s *FieldSchema) validateString(field, value string) []string {
+	var errs []string
+	if !s.allowed(value) {
+		errs = append(errs, fmt.Sprintf("%s: %q is not one of %s", field, value, s.allowedValues()))
+	}
+	if s.Pattern != "" {
+		matched, err := regexp.MatchString(s.Pattern, value)
+		switch {
+		case err != nil:
+			errs = append(errs, fmt.Sprintf("%s: invalid pattern: %s", field, err))
+		case !matched:
+			errs = append(errs, fmt.Sprintf("%s: %q does not match %s", field, value, s.Pattern))
+		}
+	}
+	return errs
+}
+
+// allowed checks the value against the allowed values of the schema, if any
+func (s *FieldSchema) allowed(value interface{}) bool {
+	if len(s.AllowedValues) == 0 {
+		return true
+	}
+	for _, allowed := range s.AllowedValues {
+		if fmt.Sprint(allowed) == fmt.Sprint(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *FieldSchema) allowedValues() string {
+	values := make([]string, len(s.AllowedValues))
+	for i, allowed := range s.AllowedValues {
+		values[i] = fmt.Sprint(allowed)
+	}
+	return strings.Join(values, ", ")
 }
 
 // Get gets the value for the given field. If the key is an invalid field,
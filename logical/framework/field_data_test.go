@@ -3,6 +3,8 @@ package framework
 import (
 	"reflect"
 	"testing"
+
+	"github.com/hashicorp/vault/logical"
 )
 
 func TestFieldDataGet(t *testing.T) {
@@ -355,3 +357,138 @@ func TestFieldDataGet_Error(t *testing.T) {
 		}
 	}
 }
+
+func TestFieldDataValidate_constraints(t *testing.T) {
+	schema := map[string]*FieldSchema{
+		"algorithm": &FieldSchema{
+			Type:          TypeString,
+			AllowedValues: []interface{}{"SHA1", "SHA256"},
+		},
+		"digits": &FieldSchema{
+			Type:          TypeInt,
+			AllowedValues: []interface{}{6, 8},
+		},
+		"size": &FieldSchema{
+			Type: TypeInt,
+			Min:  Bound(1),
+			Max:  Bound(10),
+		},
+		"ttl": &FieldSchema{
+			Type: TypeDurationSecond,
+			Max:  Bound(3600),
+		},
+		"name": &FieldSchema{
+			Type:    TypeString,
+			Pattern: "^[a-z]+$",
+		},
+		"tags": &FieldSchema{
+			Type:          TypeCommaStringSlice,
+			AllowedValues: []interface{}{"a", "b"},
+		},
+	}
+
+	cases := map[string]struct {
+		Raw    map[string]interface{}
+		Errors []string
+	}{
+		"valid": {
+			map[string]interface{}{
+				"algorithm": "SHA256",
+				"digits":    "8",
+				"size":      10,
+				"ttl":       "1h",
+				"name":      "foo",
+				"tags":      "a,b",
+			},
+			nil,
+		},
+		"allowed values": {
+			map[string]interface{}{
+				"algorithm": "MD5",
+				"digits":    7,
+			},
+			[]string{
+				`algorithm: "MD5" is not one of SHA1, SHA256`,
+				`digits: 7 is not one of 6, 8`,
+			},
+		},
+		"bounds": {
+			map[string]interface{}{
+				"size": 0,
+				"ttl":  "2h",
+			},
+			[]string{
+				"size: 0 is less than the minimum of 1",
+				"ttl: 7200 is greater than the maximum of 3600",
+			},
+		},
+		"pattern": {
+			map[string]interface{}{
+				"name": "Foo",
+			},
+			[]string{
+				`name: "Foo" does not match ^[a-z]+$`,
+			},
+		},
+		"slice elements": {
+			map[string]interface{}{
+				"tags": "a,c",
+			},
+			[]string{
+				`tags: "c" is not one of a, b`,
+			},
+		},
+		"conversion error": {
+			map[string]interface{}{
+				"size":   "big",
+				"digits": 7,
+			},
+			[]string{
+				`Error converting input big for field size: cannot parse '' as int: strconv.ParseInt: parsing "big": invalid syntax`,
+				`digits: 7 is not one of 6, 8`,
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		data := &FieldData{
+			Raw:    tc.Raw,
+			Schema: schema,
+		}
+
+		err := data.Validate()
+		if tc.Errors == nil {
+			if err != nil {
+				t.Fatalf("bad: %s: %s", name, err)
+			}
+			continue
+		}
+
+		fieldErr, ok := err.(*logical.FieldValidationError)
+		if !ok {
+			t.Fatalf("bad: %s: expected a field validation error, got %#v", name, err)
+		}
+		if !reflect.DeepEqual(fieldErr.Errors, tc.Errors) {
+			t.Fatalf(
+				"bad: %s\n\nExpected: %#v\nGot: %#v",
+				name, tc.Errors, fieldErr.Errors)
+		}
+	}
+}
+
+func TestFieldDataValidate_defaultNotChecked(t *testing.T) {
+	data := &FieldData{
+		Raw: map[string]interface{}{},
+		Schema: map[string]*FieldSchema{
+			"size": &FieldSchema{
+				Type:    TypeInt,
+				Default: 0,
+				Min:     Bound(1),
+			},
+		},
+	}
+
+	if err := data.Validate(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
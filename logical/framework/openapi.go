@@ -0,0 +1,481 @@
+package framework
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/version"
+)
+
+// OASVersion is the version of the OpenAPI specification which the
+// documents follow
+const OASVersion = "3.0.2"
+
+// OASDocument is an OpenAPI document describing the paths of backends
+type OASDocument struct {
+	Version string                  `json:"openapi" mapstructure:"openapi"`
+	Info    OASInfo                 `json:"info"`
+	Paths   map[string]*OASPathItem `json:"paths"`
+}
+
+type OASInfo struct {
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Version     string     `json:"version"`
+	License     OASLicense `json:"license"`
+}
+
+type OASLicense struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// OASPathItem describes the operations of a path. The paths which require
+// the sudo capability or no authentication are marked with the
+// x-vault-sudo and x-vault-unauthenticated extensions.
+type OASPathItem struct {
+	Description     string         `json:"description,omitempty"`
+	Parameters      []OASParameter `json:"parameters,omitempty"`
+	Sudo            bool           `json:"x-vault-sudo,omitempty" mapstructure:"x-vault-sudo"`
+	Unauthenticated bool           `json:"x-vault-unauthenticated,omitempty" mapstructure:"x-vault-unauthenticated"`
+
+	Get    *OASOperation `json:"get,omitempty"`
+	Head   *OASOperation `json:"head,omitempty"`
+	Post   *OASOperation `json:"post,omitempty"`
+	Patch  *OASOperation `json:"patch,omitempty"`
+	Delete *OASOperation `json:"delete,omitempty"`
+}
+
+type OASOperation struct {
+	Summary     string                  `json:"summary,omitempty"`
+	Description string                  `json:"description,omitempty"`
+	Tags        []string                `json:"tags,omitempty"`
+	Parameters  []OASParameter          `json:"parameters,omitempty"`
+	RequestBody *OASRequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*OASResponse `json:"responses"`
+}
+
+type OASParameter struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description,omitempty"`
+	In          string     `json:"in"`
+	Schema      *OASSchema `json:"schema,omitempty"`
+	Required    bool       `json:"required,omitempty"`
+}
+
+type OASRequestBody struct {
+	Content map[string]*OASMediaType `json:"content"`
+}
+
+type OASMediaType struct {
+	Schema *OASSchema `json:"schema"`
+}
+
+type OASSchema struct {
+	Type        string                `json:"type,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Format      string                `json:"format,omitempty"`
+	Properties  map[string]*OASSchema `json:"properties,omitempty"`
+	Items       *OASSchema            `json:"items,omitempty"`
+	Default     interface{}           `json:"default,omitempty"`
+	Enum        []interface{}         `json:"enum,omitempty"`
+	Minimum     *int                  `json:"minimum,omitempty"`
+	Maximum     *int                  `json:"maximum,omitempty"`
+	Pattern     string                `json:"pattern,omitempty"`
+}
+
+type OASResponse struct {
+	Description string `json:"description"`
+}
+
+// NewOASDocument returns an OpenAPI document without paths
+func NewOASDocument() *OASDocument {
+	return &OASDocument{
+		Version: OASVersion,
+		Info: OASInfo{
+			Title:       "HashiCorp Vault API",
+			Description: "HTTP API that gives you full access to Vault. All API routes are prefixed with `/v1/`.",
+			Version:     version.GetVersion().Version,
+			License: OASLicense{
+				Name: "Mozilla Public License 2.0",
+				URL:  "https://www.mozilla.org/en-US/MPL/2.0",
+			},
+		},
+		Paths: make(map[string]*OASPathItem),
+	}
+}
+
+// Map returns the document in its JSON form, which is how it is sent in
+// help responses so that it can be encoded by any plugin protocol
+func (d *OASDocument) Map() (map[string]interface{}, error) {
+	encoded, err := json.Marshal(d)
+	if err != nil {
+		return nil, err
+	}
+	var result map[string]interface{}
+	if err := json.Unmarshal(encoded, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// DecodeOASDocument returns the OpenAPI document found in the help response
+// of a backend. The document of backends served by plugins is decoded from
+// its JSON form.
+func DecodeOASDocument(raw interface{}) (*OASDocument, error) {
+	if doc, ok := raw.(*OASDocument); ok {
+		return doc, nil
+	}
+
+	encoded, err := jsonutil.EncodeJSON(raw)
+	if err != nil {
+		return nil, err
+	}
+	var doc OASDocument
+	if err := jsonutil.DecodeJSON(encoded, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Version == "" {
+		return nil, fmt.Errorf("no OpenAPI document found")
+	}
+	return &doc, nil
+}
+
+// AddPaths adds the paths of the document of a backend mounted at the given
+// path, tagging their operations with the given tag
+func (d *OASDocument) AddPaths(mountPath string, tag string, other *OASDocument) {
+	mountPath = strings.Trim(mountPath, "/")
+	for path, item := range other.Paths {
+		for _, op := range item.operations() {
+			op.Tags = []string{tag}
+		}
+		d.Paths["/"+mountPath+path] = item
+	}
+}
+
+func (p *OASPathItem) operations() []*OASOperation {
+	var ops []*OASOperation
+	for _, op := range []*OASOperation{p.Get, p.Post, p.Patch, p.Delete} {
+		if op != nil {
+			ops = append(ops, op)
+		}
+	}
+	return ops
+}
+
+// documentPaths adds the paths of the backend to the document
+func (b *Backend) documentPaths(doc *OASDocument) {
+	for _, p := range b.Paths {
+		documentPath(p, b.PathsSpecial, doc)
+	}
+}
+
+// documentPath adds the path to the document, once for each of the URLs
+// matched by its pattern
+func documentPath(p *Path, specialPaths *logical.Paths, doc *OASDocument) {
+	var sudoPaths, unauthPaths []string
+	if specialPaths != nil {
+		sudoPaths = specialPaths.Root
+		unauthPaths = specialPaths.Unauthenticated
+	}
+
+	for _, path := range expandPattern(p.Pattern) {
+		pathParams := make(map[string]bool)
+		item := &OASPathItem{
+			Description:     strings.TrimSpace(p.HelpSynopsis),
+			Sudo:            specialPathMatch(path, sudoPaths),
+			Unauthenticated: specialPathMatch(path, unauthPaths),
+		}
+
+		for _, name := range pathParamRe.FindAllStringSubmatch(path, -1) {
+			pathParams[name[1]] = true
+			param := p.oasParameter(name[1], "path")
+			param.Required = true
+			item.Parameters = append(item.Parameters, param)
+		}
+
+		ops := make([]logical.Operation, 0, len(p.Callbacks))
+		for op := range p.Callbacks {
+			ops = append(ops, op)
+		}
+		sort.Slice(ops, func(i, j int) bool { return ops[i] < ops[j] })
+
+		for _, op := range ops {
+			oasOp := &OASOperation{
+				Summary:     strings.TrimSpace(p.HelpSynopsis),
+				Description: strings.TrimSpace(p.HelpDescription),
+				Responses: map[string]*OASResponse{
+					"200": &OASResponse{Description: "OK"},
+				},
+			}
+
+			switch op {
+			case logical.ReadOperation:
+				oasOp.Parameters = p.oasQueryParameters(pathParams)
+				item.Get = oasOp
+
+			case logical.HeaderOperation:
+				oasOp.Parameters = p.oasQueryParameters(pathParams)
+				item.Head = oasOp
+
+			case logical.ListOperation:
+				// Lists are reads of the path with a trailing slash and the
+				// list query parameter
+				oasOp.Parameters = append(p.oasQueryParameters(pathParams), OASParameter{
+					Name:        "list",
+					Description: "Return a list if `true`",
+					In:          "query",
+					Schema:      &OASSchema{Type: "string", Enum: []interface{}{"true"}},
+					Required:    true,
+				})
+				listPath := path
+				if !strings.HasSuffix(listPath, "/") {
+					listPath += "/"
+				}
+				if listPath == path {
+					item.Get = oasOp
+					continue
+				}
+				listItem, ok := doc.Paths[listPath]
+				if !ok {
+					listItem = &OASPathItem{
+						Description:     item.Description,
+						Parameters:      item.Parameters,
+						Sudo:            specialPathMatch(listPath, sudoPaths),
+						Unauthenticated: specialPathMatch(listPath, unauthPaths),
+					}
+					doc.Paths[listPath] = listItem
+				}
+				listItem.Get = oasOp
+
+			case logical.CreateOperation, logical.UpdateOperation:
+				if item.Post != nil {
+					continue
+				}
+				oasOp.RequestBody = p.oasRequestBody("application/json", pathParams)
+				item.Post = oasOp
+
+			case logical.PatchOperation:
+				oasOp.RequestBody = p.oasRequestBody("application/merge-patch+json", pathParams)
+				item.Patch = oasOp
+
+			case logical.DeleteOperation:
+				item.Delete = oasOp
+			}
+		}
+
+		if item.Get != nil || item.Head != nil || item.Post != nil || item.Patch != nil || item.Delete != nil {
+			doc.Paths[path] = item
+		}
+	}
+}
+
+// oasParameter describes a field given in the URL
+func (p *Path) oasParameter(name, in string) OASParameter {
+	param := OASParameter{
+		Name:   name,
+		In:     in,
+		Schema: &OASSchema{Type: "string"},
+	}
+	if schema, ok := p.Fields[name]; ok {
+		param.Description = strings.TrimSpace(schema.Description)
+		param.Schema = schema.oasSchema()
+	}
+	return param
+}
+
+// oasQueryParameters describes the fields of the path which are not given in
+// the path of the URL as query parameters
+func (p *Path) oasQueryParameters(pathParams map[string]bool) []OASParameter {
+	var params []OASParameter
+	for _, name := range p.sortedFields() {
+		if !pathParams[name] {
+			params = append(params, p.oasParameter(name, "query"))
+		}
+	}
+	return params
+}
+
+// oasRequestBody describes the fields of the path which are not given in the
+// path of the URL as a JSON object
+func (p *Path) oasRequestBody(contentType string, pathParams map[string]bool) *OASRequestBody {
+	properties := make(map[string]*OASSchema)
+	for name, schema := range p.Fields {
+		if !pathParams[name] {
+			properties[name] = schema.oasSchema()
+		}
+	}
+
+	return &OASRequestBody{
+		Content: map[string]*OASMediaType{
+			contentType: &OASMediaType{
+				Schema: &OASSchema{
+					Type:       "object",
+					Properties: properties,
+				},
+			},
+		},
+	}
+}
+
+func (p *Path) sortedFields() []string {
+	names := make([]string, 0, len(p.Fields))
+	for name := range p.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// oasSchema describes the type and constraints of the field
+func (s *FieldSchema) oasSchema() *OASSchema {
+	schema := &OASSchema{
+		Description: strings.TrimSpace(s.Description),
+		Default:     s.Default,
+		Enum:        s.AllowedValues,
+		Minimum:     s.Min,
+		Maximum:     s.Max,
+		Pattern:     s.Pattern,
+	}
+
+	switch s.Type {
+	case TypeString:
+		schema.Type = "string"
+	case TypeNameString:
+		schema.Type = "string"
+		if schema.Pattern == "" {
+			schema.Pattern = `^\w(([\w-.]+)?\w)?$`
+		}
+	case TypeInt:
+		schema.Type = "integer"
+	case TypeBool:
+		schema.Type = "boolean"
+	case TypeMap:
+		schema.Type = "object"
+	case TypeDurationSecond:
+		// Durations are given as a number of seconds or a Go duration
+		// string such as "1h"
+		schema.Type = "integer"
+		schema.Format = "seconds"
+	case TypeSlice:
+		schema.Type = "array"
+		schema.Items = &OASSchema{Type: "object"}
+	case TypeStringSlice, TypeCommaStringSlice:
+		schema.Type = "array"
+		schema.Items = &OASSchema{Type: "string"}
+		schema.Enum = nil
+		if len(s.AllowedValues) > 0 || s.Pattern != "" {
+			schema.Items.Enum = s.AllowedValues
+			schema.Items.Pattern = s.Pattern
+			schema.Pattern = ""
+		}
+	}
+
+	return schema
+}
+
+// pathParamRe matches the parameters of the expanded paths
+var pathParamRe = regexp.MustCompile(`\{(\w+)\}`)
+
+// specialPathMatch checks whether the path matches one of the special paths
+// of a backend, which end with a * to match a prefix
+func specialPathMatch(path string, specialPaths []string) bool {
+	path = strings.TrimPrefix(path, "/")
+	for _, sp := range specialPaths {
+		if strings.HasSuffix(sp, "*") {
+			if strings.HasPrefix(path, strings.TrimSuffix(sp, "*")) {
+				return true
+			}
+			continue
+		}
+		if path == sp {
+			return true
+		}
+	}
+	return false
+}
+
+// expandPattern returns the URLs matched by the pattern of a path, with its
+// named captures replaced by parameters such as {name}. The optional parts
+// and alternations of the pattern are expanded into several URLs, and an
+// unnamed wildcard is documented as a {path} parameter. The parts of the
+// pattern which can't be described as a URL are left out.
+func expandPattern(pattern string) []string {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	var paths []string
+	for _, path := range expandRegexp(re) {
+		path = "/" + strings.TrimPrefix(path, "/")
+		if !seen[path] {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func expandRegexp(re *syntax.Regexp) []string {
+	switch re.Op {
+	case syntax.OpLiteral:
+		return []string{string(re.Rune)}
+
+	case syntax.OpEmptyMatch, syntax.OpBeginLine, syntax.OpEndLine,
+		syntax.OpBeginText, syntax.OpEndText:
+		return []string{""}
+
+	case syntax.OpCapture:
+		if re.Name != "" {
+			return []string{"{" + re.Name + "}"}
+		}
+		return expandRegexp(re.Sub[0])
+
+	case syntax.OpConcat:
+		result := []string{""}
+		for _, sub := range re.Sub {
+			parts := expandRegexp(sub)
+			next := make([]string, 0, len(result)*len(parts))
+			for _, prefix := range result {
+				for _, part := range parts {
+					next = append(next, prefix+part)
+				}
+			}
+			result = next
+		}
+		return result
+
+	case syntax.OpAlternate:
+		var result []string
+		for _, sub := range re.Sub {
+			result = append(result, expandRegexp(sub)...)
+		}
+		return result
+
+	case syntax.OpQuest:
+		// An optional slash allows the part of the path after it to be
+		// empty, and is documented as required
+		sub := re.Sub[0]
+		if sub.Op == syntax.OpLiteral && string(sub.Rune) == "/" {
+			return []string{"/"}
+		}
+		return append([]string{""}, expandRegexp(sub)...)
+
+	case syntax.OpStar, syntax.OpPlus:
+		switch re.Sub[0].Op {
+		case syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+			return []string{"{path}"}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,195 @@
+package framework
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestExpandPattern(t *testing.T) {
+	cases := map[string][]string{
+		"login":                                 []string{"/login"},
+		"roles/?$":                              []string{"/roles/"},
+		"roles/" + GenericNameRegex("name"):     []string{"/roles/{name}"},
+		"metadata/?(?P<path>.*)":                []string{"/metadata/{path}"},
+		"(raw/?$|raw/(?P<path>.+))":             []string{"/raw/", "/raw/{path}"},
+		".*":                                    []string{"/{path}"},
+		"creds/(?P<name>\\w+)(/(?P<ttl>\\d+))?": []string{"/creds/{name}", "/creds/{name}/{ttl}"},
+	}
+
+	for pattern, expected := range cases {
+		actual := expandPattern(pattern)
+		if !reflect.DeepEqual(actual, expected) {
+			t.Fatalf("bad: %s\n\nExpected: %#v\nGot: %#v", pattern, expected, actual)
+		}
+	}
+}
+
+func TestDocumentPath(t *testing.T) {
+	callback := func(*logical.Request, *FieldData) (*logical.Response, error) {
+		return nil, nil
+	}
+
+	p := &Path{
+		Pattern:      "roles/" + GenericNameRegex("name"),
+		HelpSynopsis: "Manage the roles.",
+		Fields: map[string]*FieldSchema{
+			"name": &FieldSchema{
+				Type:        TypeString,
+				Description: "Name of the role.",
+			},
+			"ttl": &FieldSchema{
+				Type:    TypeDurationSecond,
+				Default: 3600,
+			},
+			"mode": &FieldSchema{
+				Type:          TypeString,
+				AllowedValues: []interface{}{"a", "b"},
+			},
+			"count": &FieldSchema{
+				Type: TypeInt,
+				Min:  Bound(1),
+			},
+		},
+		Callbacks: map[logical.Operation]OperationFunc{
+			logical.ReadOperation:   callback,
+			logical.CreateOperation: callback,
+			logical.UpdateOperation: callback,
+			logical.DeleteOperation: callback,
+			logical.ListOperation:   callback,
+		},
+	}
+
+	doc := NewOASDocument()
+	documentPath(p, &logical.Paths{Root: []string{"roles/*"}}, doc)
+
+	if len(doc.Paths) != 2 {
+		t.Fatalf("bad: %#v", doc.Paths)
+	}
+
+	item := doc.Paths["/roles/{name}"]
+	if item == nil {
+		t.Fatalf("bad: %#v", doc.Paths)
+	}
+	if !item.Sudo || item.Unauthenticated || item.Description != "Manage the roles." {
+		t.Fatalf("bad: %#v", item)
+	}
+	expectedParams := []OASParameter{
+		OASParameter{
+			Name:        "name",
+			Description: "Name of the role.",
+			In:          "path",
+			Schema:      &OASSchema{Type: "string", Description: "Name of the role."},
+			Required:    true,
+		},
+	}
+	if !reflect.DeepEqual(item.Parameters, expectedParams) {
+		t.Fatalf("bad: %#v", item.Parameters)
+	}
+	if item.Get == nil || item.Post == nil || item.Delete == nil || item.Patch != nil {
+		t.Fatalf("bad: %#v", item)
+	}
+	if len(item.Get.Parameters) != 3 || item.Get.Parameters[0].Name != "count" || item.Get.Parameters[0].In != "query" {
+		t.Fatalf("bad: %#v", item.Get.Parameters)
+	}
+
+	body := item.Post.RequestBody.Content["application/json"].Schema
+	if body.Type != "object" || len(body.Properties) != 3 {
+		t.Fatalf("bad: %#v", body)
+	}
+	if ttl := body.Properties["ttl"]; ttl.Type != "integer" || ttl.Format != "seconds" || ttl.Default != 3600 {
+		t.Fatalf("bad: %#v", ttl)
+	}
+	if mode := body.Properties["mode"]; !reflect.DeepEqual(mode.Enum, []interface{}{"a", "b"}) {
+		t.Fatalf("bad: %#v", mode)
+	}
+	if count := body.Properties["count"]; count.Minimum == nil || *count.Minimum != 1 || count.Maximum != nil {
+		t.Fatalf("bad: %#v", count)
+	}
+
+	// Lists are reads of the path with a trailing slash
+	list := doc.Paths["/roles/{name}/"]
+	if list == nil || list.Get == nil || list.Post != nil {
+		t.Fatalf("bad: %#v", doc.Paths)
+	}
+	last := list.Get.Parameters[len(list.Get.Parameters)-1]
+	if last.Name != "list" || last.In != "query" || !last.Required {
+		t.Fatalf("bad: %#v", last)
+	}
+}
+
+func TestBackendHandleRequest_helpOpenAPI(t *testing.T) {
+	callback := func(*logical.Request, *FieldData) (*logical.Response, error) {
+		return nil, nil
+	}
+
+	b := &Backend{
+		Paths: []*Path{
+			&Path{
+				Pattern: "login",
+				Callbacks: map[logical.Operation]OperationFunc{
+					logical.UpdateOperation: callback,
+				},
+			},
+			&Path{
+				Pattern: "config",
+				Callbacks: map[logical.Operation]OperationFunc{
+					logical.ReadOperation:  callback,
+					logical.PatchOperation: callback,
+				},
+			},
+		},
+		PathsSpecial: &logical.Paths{
+			Unauthenticated: []string{"login"},
+		},
+	}
+
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: logical.HelpOperation,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := DecodeOASDocument(resp.Data["openapi"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Version != OASVersion || len(doc.Paths) != 2 {
+		t.Fatalf("bad: %#v", doc)
+	}
+	if login := doc.Paths["/login"]; login == nil || !login.Unauthenticated || login.Post == nil {
+		t.Fatalf("bad: %#v", doc.Paths)
+	}
+	config := doc.Paths["/config"]
+	if config == nil || config.Get == nil || config.Patch == nil {
+		t.Fatalf("bad: %#v", doc.Paths)
+	}
+	if _, ok := config.Patch.RequestBody.Content["application/merge-patch+json"]; !ok {
+		t.Fatalf("bad: %#v", config.Patch.RequestBody)
+	}
+
+	// The documents of the paths are merged under the path of the mount
+	merged := NewOASDocument()
+	merged.AddPaths("foo/", "secrets", doc)
+	if op := merged.Paths["/foo/config"]; op == nil || !reflect.DeepEqual(op.Get.Tags, []string{"secrets"}) {
+		t.Fatalf("bad: %#v", merged.Paths)
+	}
+
+	// The help of a single path describes it
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.HelpOperation,
+		Path:      "config",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err = DecodeOASDocument(resp.Data["openapi"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Paths) != 1 || doc.Paths["/config"] == nil {
+		t.Fatalf("bad: %#v", doc.Paths)
+	}
+}
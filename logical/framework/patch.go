@@ -0,0 +1,79 @@
+package framework
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/logical"
+)
+
+// MergePatch applies a JSON merge patch (RFC 7396) to a JSON object and
+// returns the patched object. The members of the patch set to nil are
+// removed from the object, the objects of the patch are merged recursively
+// into the objects of the same name, and every other value replaces the
+// member of the same name. The object given is not modified.
+func MergePatch(object, patch map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(object)+len(patch))
+	for k, v := range object {
+		result[k] = v
+	}
+
+	for k, v := range patch {
+		switch v := v.(type) {
+		case nil:
+			delete(result, k)
+		case map[string]interface{}:
+			nested, _ := result[k].(map[string]interface{})
+			result[k] = MergePatch(nested, v)
+		default:
+			result[k] = v
+		}
+	}
+
+	return result
+}
+
+// PatchOperation returns the callback of the PatchOperation of a path whose
+// update callback replaces the whole resource, such as the roles of most
+// backends. The resource returned by the read callback is patched with the
+// request data as a JSON merge patch and given to the update callback, so
+// that the fields which are not in the patch keep their values and the
+// fields set to null in the patch are reset to their defaults.
+//
+// The patch of a resource which doesn't exist is rejected with a 404.
+func PatchOperation(read, update OperationFunc) OperationFunc {
+	return func(req *logical.Request, data *FieldData) (*logical.Response, error) {
+		resp, err := read(req, data)
+		if err != nil {
+			return nil, err
+		}
+		if resp == nil {
+			return nil, logical.CodedError(http.StatusNotFound, fmt.Sprintf("no entry found at %q", req.Path))
+		}
+		if resp.IsError() {
+			return resp, nil
+		}
+
+		// Round-trip the resource through JSON, so that its values are given
+		// to the update callback in the form they are read from a request
+		encoded, err := jsonutil.EncodeJSON(resp.Data)
+		if err != nil {
+			return nil, err
+		}
+		var resource map[string]interface{}
+		if err := jsonutil.DecodeJSON(encoded, &resource); err != nil {
+			return nil, err
+		}
+
+		patched := &FieldData{
+			Raw:    MergePatch(resource, data.Raw),
+			Schema: data.Schema,
+		}
+		if err := patched.Validate(); err != nil {
+			return nil, err
+		}
+
+		return update(req, patched)
+	}
+}
@@ -0,0 +1,161 @@
+package framework
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestMergePatch(t *testing.T) {
+	cases := map[string]struct {
+		Object   map[string]interface{}
+		Patch    map[string]interface{}
+		Expected map[string]interface{}
+	}{
+		"set": {
+			map[string]interface{}{"a": "b"},
+			map[string]interface{}{"a": "c", "d": "e"},
+			map[string]interface{}{"a": "c", "d": "e"},
+		},
+		"remove": {
+			map[string]interface{}{"a": "b", "c": "d"},
+			map[string]interface{}{"a": nil, "missing": nil},
+			map[string]interface{}{"c": "d"},
+		},
+		"nested": {
+			map[string]interface{}{
+				"a": map[string]interface{}{"b": "c", "d": "e"},
+			},
+			map[string]interface{}{
+				"a": map[string]interface{}{"b": nil, "f": "g"},
+			},
+			map[string]interface{}{
+				"a": map[string]interface{}{"d": "e", "f": "g"},
+			},
+		},
+		"replace non-object": {
+			map[string]interface{}{"a": "b"},
+			map[string]interface{}{"a": map[string]interface{}{"c": "d"}},
+			map[string]interface{}{"a": map[string]interface{}{"c": "d"}},
+		},
+		"replace array": {
+			map[string]interface{}{"a": []interface{}{"b", "c"}},
+			map[string]interface{}{"a": []interface{}{"d"}},
+			map[string]interface{}{"a": []interface{}{"d"}},
+		},
+		"nil object": {
+			nil,
+			map[string]interface{}{"a": "b"},
+			map[string]interface{}{"a": "b"},
+		},
+	}
+
+	for name, tc := range cases {
+		actual := MergePatch(tc.Object, tc.Patch)
+		if !reflect.DeepEqual(actual, tc.Expected) {
+			t.Fatalf(
+				"bad: %s\n\nExpected: %#v\nGot: %#v",
+				name, tc.Expected, actual)
+		}
+	}
+
+	// The patched object is left untouched
+	object := map[string]interface{}{
+		"a": map[string]interface{}{"b": "c"},
+	}
+	MergePatch(object, map[string]interface{}{
+		"a": map[string]interface{}{"b": nil},
+	})
+	if !reflect.DeepEqual(object, map[string]interface{}{"a": map[string]interface{}{"b": "c"}}) {
+		t.Fatalf("bad: %#v", object)
+	}
+}
+
+func TestPatchOperation(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	var stored map[string]interface{}
+
+	read := func(req *logical.Request, data *FieldData) (*logical.Response, error) {
+		if stored == nil {
+			return nil, nil
+		}
+		return &logical.Response{Data: stored}, nil
+	}
+	update := func(req *logical.Request, data *FieldData) (*logical.Response, error) {
+		stored = map[string]interface{}{
+			"name":  data.Get("name"),
+			"ttl":   data.Get("ttl"),
+			"count": data.Get("count"),
+		}
+		return nil, nil
+	}
+
+	b := &Backend{
+		Paths: []*Path{
+			&Path{
+				Pattern: "roles/" + GenericNameRegex("name"),
+				Fields: map[string]*FieldSchema{
+					"name":  &FieldSchema{Type: TypeString},
+					"ttl":   &FieldSchema{Type: TypeDurationSecond},
+					"count": &FieldSchema{Type: TypeInt, Default: 3, Max: Bound(10)},
+				},
+				Callbacks: map[logical.Operation]OperationFunc{
+					logical.ReadOperation:   read,
+					logical.UpdateOperation: update,
+					logical.PatchOperation:  PatchOperation(read, update),
+				},
+			},
+		},
+	}
+
+	patch := func(data map[string]interface{}) error {
+		_, err := b.HandleRequest(&logical.Request{
+			Operation: logical.PatchOperation,
+			Path:      "roles/foo",
+			Data:      data,
+			Storage:   storage,
+		})
+		return err
+	}
+
+	// A role which doesn't exist can't be patched
+	err := patch(map[string]interface{}{"ttl": "1h"})
+	if coded, ok := err.(logical.HTTPCodedError); !ok || coded.Code() != 404 {
+		t.Fatalf("bad: %#v", err)
+	}
+
+	_, err = b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "roles/foo",
+		Data:      map[string]interface{}{"ttl": "1h", "count": 5},
+		Storage:   storage,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The fields which are not patched keep their values
+	if err := patch(map[string]interface{}{"ttl": "2h"}); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{"name": "foo", "ttl": 7200, "count": 5}
+	if !reflect.DeepEqual(stored, expected) {
+		t.Fatalf("bad: %#v", stored)
+	}
+
+	// The fields set to null are reset to their defaults
+	if err := patch(map[string]interface{}{"count": nil}); err != nil {
+		t.Fatal(err)
+	}
+	expected = map[string]interface{}{"name": "foo", "ttl": 7200, "count": 3}
+	if !reflect.DeepEqual(stored, expected) {
+		t.Fatalf("bad: %#v", stored)
+	}
+
+	// The patched values are validated
+	err = patch(map[string]interface{}{"count": 11})
+	if _, ok := err.(*logical.FieldValidationError); !ok {
+		t.Fatalf("bad: %#v", err)
+	}
+}
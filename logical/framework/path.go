@@ -82,8 +82,13 @@ type Path struct {
 	HelpDescription string
 }
 
-func (p *Path) helpCallback(
-	req *logical.Request, data *FieldData) (*logical.Response, error) {
+func (p *Path) helpCallback(b *Backend) OperationFunc {
+	return func(req *logical.Request, data *FieldData) (*logical.Response, error) {
+		return p.help(b, req)
+	}
+}
+
+func (p *Path) help(b *Backend, req *logical.Request) (*logical.Response, error) {
 	var tplData pathTemplateData
 	tplData.Request = req.Path
 	tplData.RoutePattern = p.Pattern
@@ -124,7 +129,17 @@ func (p *Path) helpCallback(
 		return nil, fmt.Errorf("error executing template: %s", err)
 	}
 
-	return logical.HelpResponse(help, nil), nil
+	// Describe the path as an OpenAPI document too
+	doc := NewOASDocument()
+	documentPath(p, b.PathsSpecial, doc)
+	openapi, err := doc.Map()
+	if err != nil {
+		return nil, err
+	}
+
+	resp := logical.HelpResponse(help, nil)
+	resp.Data["openapi"] = openapi
+	return resp, nil
 }
 
 type pathTemplateData struct {
@@ -13,4 +13,25 @@ type Alias struct {
 
 	// Name is the identifier of this identity in its authentication source
 	Name string `json:"name" structs:"name" mapstructure:"name"`
+
+	// Metadata is stored on the entity alias. Metadata already stored on
+	// the alias under other keys is kept.
+	Metadata map[string]string `json:"metadata" structs:"metadata" mapstructure:"metadata"`
+}
+
+// Entity is the identity entity of a client, as exposed to backends through
+// the system view
+type Entity struct {
+	// ID is the unique identifier of the entity
+	ID string `json:"id" structs:"id" mapstructure:"id"`
+
+	// Name is the human-friendly name of the entity
+	Name string `json:"name" structs:"name" mapstructure:"name"`
+
+	// Aliases are the identities of the entity in the authentication
+	// backends
+	Aliases []*Alias `json:"aliases" structs:"aliases" mapstructure:"aliases"`
+
+	// Metadata is the metadata set on the entity
+	Metadata map[string]string `json:"metadata" structs:"metadata" mapstructure:"metadata"`
 }
@@ -46,3 +46,56 @@ func (l *LeaseOptions) ExpirationTime() time.Time {
 	}
 	return expireTime
 }
+
+// LeaseContract contains machine-readable hints about the validity of a
+// lease, so that consumers can schedule renewals and rotations without
+// guessing from the lease duration alone. It is populated by Vault core
+// once a lease has been registered; backends should not set it.
+type LeaseContract struct {
+	// EarliestRotation is the time at which consumers should begin renewing
+	// or rotating the secret.
+	EarliestRotation time.Time
+
+	// GuaranteedValidUntil is the time until which the secret is guaranteed
+	// to be valid, barring explicit revocation.
+	GuaranteedValidUntil time.Time
+
+	// RenewEndpoint is the path used to renew the lease. It is empty if the
+	// lease is not renewable.
+	RenewEndpoint string
+}
+
+// NewLeaseContract computes the lease contract for a lease issued at the
+// given time. Rotation is suggested once rotationFraction of the lease
+// duration has elapsed.
+func NewLeaseContract(issued time.Time, l *LeaseOptions, renewEndpoint string, rotationFraction float64) *LeaseContract {
+	if !l.LeaseEnabled() {
+		return nil
+	}
+
+	contract := &LeaseContract{
+		EarliestRotation:     issued.Add(time.Duration(float64(l.LeaseTotal()) * rotationFraction)),
+		GuaranteedValidUntil: issued.Add(l.LeaseTotal()),
+	}
+	if l.Renewable {
+		contract.RenewEndpoint = renewEndpoint
+	}
+
+	return contract
+}
+
+// Cap limits the guaranteed validity of the contract to the given time, such
+// as the expiry of the token the lease is revoked with, and schedules the
+// rotation within the remaining validity. A zero time doesn't limit it.
+func (c *LeaseContract) Cap(issued, notAfter time.Time, rotationFraction float64) {
+	if notAfter.IsZero() || !notAfter.Before(c.GuaranteedValidUntil) {
+		return
+	}
+
+	validity := notAfter.Sub(issued)
+	if validity < 0 {
+		validity = 0
+	}
+	c.GuaranteedValidUntil = notAfter
+	c.EarliestRotation = issued.Add(time.Duration(float64(validity) * rotationFraction))
+}
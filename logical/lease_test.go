@@ -54,3 +54,49 @@ func TestLeaseOptionsExpirationTime_noLease(t *testing.T) {
 		t.Fatal("should be zero")
 	}
 }
+
+func TestNewLeaseContract(t *testing.T) {
+	issued := time.Date(2017, 10, 1, 0, 0, 0, 0, time.UTC)
+	l := &LeaseOptions{
+		TTL:       3 * time.Hour,
+		Renewable: true,
+	}
+
+	contract := NewLeaseContract(issued, l, "sys/leases/renew", 2.0/3.0)
+	if contract == nil {
+		t.Fatal("expected contract")
+	}
+	if !contract.EarliestRotation.Equal(issued.Add(2 * time.Hour)) {
+		t.Fatalf("bad: %s", contract.EarliestRotation)
+	}
+	if !contract.GuaranteedValidUntil.Equal(issued.Add(3 * time.Hour)) {
+		t.Fatalf("bad: %s", contract.GuaranteedValidUntil)
+	}
+	if contract.RenewEndpoint != "sys/leases/renew" {
+		t.Fatalf("bad: %s", contract.RenewEndpoint)
+	}
+
+	l.Renewable = false
+	contract = NewLeaseContract(issued, l, "sys/leases/renew", 2.0/3.0)
+	if contract.RenewEndpoint != "" {
+		t.Fatalf("bad: %s", contract.RenewEndpoint)
+	}
+
+	// The validity is capped, such as by the expiry of the token
+	contract.Cap(issued, issued.Add(4*time.Hour), 2.0/3.0)
+	if !contract.GuaranteedValidUntil.Equal(issued.Add(3 * time.Hour)) {
+		t.Fatalf("bad: %s", contract.GuaranteedValidUntil)
+	}
+	contract.Cap(issued, issued.Add(30*time.Minute), 2.0/3.0)
+	if !contract.GuaranteedValidUntil.Equal(issued.Add(30 * time.Minute)) {
+		t.Fatalf("bad: %s", contract.GuaranteedValidUntil)
+	}
+	if !contract.EarliestRotation.Equal(issued.Add(20 * time.Minute)) {
+		t.Fatalf("bad: %s", contract.EarliestRotation)
+	}
+
+	l.TTL = 0
+	if contract = NewLeaseContract(issued, l, "sys/leases/renew", 2.0/3.0); contract != nil {
+		t.Fatalf("bad: %#v", contract)
+	}
+}
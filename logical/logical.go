@@ -117,4 +117,14 @@ type Paths struct {
 	// LocalStorage are paths (prefixes) that are local to this instance; this
 	// indicates that these paths should not be replicated
 	LocalStorage []string
+
+	// ExportableStorage are paths (prefixes) of storage holding non-secret
+	// configuration, such as roles, that can be exported from one cluster and
+	// imported into another
+	ExportableStorage []string
+
+	// SealWrapStorage are paths (prefixes) of storage holding critical
+	// values, such as keys, that are seal-wrapped: encrypted by the seal in
+	// addition to the barrier
+	SealWrapStorage []string
 }
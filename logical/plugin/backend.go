@@ -5,12 +5,18 @@ import (
 
 	"github.com/hashicorp/go-plugin"
 	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/plugin/pb"
+	"google.golang.org/grpc"
 )
 
 // BackendPlugin is the plugin.Plugin implementation
 type BackendPlugin struct {
 	Factory      func(*logical.BackendConfig) (logical.Backend, error)
 	metadataMode bool
+
+	// multiplexing allows the plugin to serve the backends of several mounts
+	// when it is served over gRPC
+	multiplexing bool
 }
 
 // Server gets called when on plugin.Serve()
@@ -22,3 +28,20 @@ func (b *BackendPlugin) Server(broker *plugin.MuxBroker) (interface{}, error) {
 func (b BackendPlugin) Client(broker *plugin.MuxBroker, c *rpc.Client) (interface{}, error) {
 	return &backendPluginClient{client: c, broker: broker, metadataMode: b.metadataMode}, nil
 }
+
+// GRPCServer gets called on plugin.Serve() when the plugin is served over gRPC
+func (b *BackendPlugin) GRPCServer(s *grpc.Server) error {
+	server := &backendGRPCPluginServer{
+		factory:      b.Factory,
+		multiplexing: b.multiplexing,
+	}
+	pb.RegisterBackendServer(s, server)
+	pb.RegisterPluginMultiplexingServer(s, server)
+	return nil
+}
+
+// GRPCClient gets called on plugin.NewClient() when the plugin is served over
+// gRPC
+func (b BackendPlugin) GRPCClient(c *grpc.ClientConn) (interface{}, error) {
+	return newGRPCBackendClient(c, b.metadataMode), nil
+}
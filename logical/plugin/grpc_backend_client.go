@@ -0,0 +1,262 @@
+package plugin
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/rpc"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/plugin/pb"
+	log "github.com/mgutz/logxi/v1"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// backendGRPCPluginClient implements logical.Backend and is the
+// go-plugin client of the plugins served over gRPC.
+type backendGRPCPluginClient struct {
+	conn         *grpc.ClientConn
+	client       pb.BackendClient
+	metadataMode bool
+
+	// multiplexID identifies the backend within a multiplexed plugin
+	multiplexID string
+
+	// callbacks serves the storage, system view and logger of the
+	// backend to the plugin
+	callbacks *callbackServer
+
+	system logical.SystemView
+	logger log.Logger
+}
+
+func newGRPCBackendClient(conn *grpc.ClientConn, metadataMode bool) *backendGRPCPluginClient {
+	return &backendGRPCPluginClient{
+		conn:         conn,
+		client:       pb.NewBackendClient(conn),
+		metadataMode: metadataMode,
+	}
+}
+
+// multiplexed returns a client of another backend of the plugin, which must
+// support multiplexing
+func (b *backendGRPCPluginClient) multiplexed(id string) *backendGRPCPluginClient {
+	return &backendGRPCPluginClient{
+		conn:         b.conn,
+		client:       b.client,
+		metadataMode: b.metadataMode,
+		multiplexID:  id,
+	}
+}
+
+// multiplexingSupport asks the plugin whether it can serve the backends of
+// several mounts. The plugins which do not implement the PluginMultiplexing
+// service do not.
+func (b *backendGRPCPluginClient) multiplexingSupport() bool {
+	reply, err := pb.NewPluginMultiplexingClient(b.conn).MultiplexingSupport(context.Background(), &pb.Empty{})
+	if err != nil {
+		return false
+	}
+	return reply.Supported
+}
+
+// ctx adds the multiplexing ID of the backend to the context of a call
+func (b *backendGRPCPluginClient) ctx(ctx context.Context) context.Context {
+	if b.multiplexID == "" {
+		return ctx
+	}
+	return withMultiplexID(ctx, b.multiplexID)
+}
+
+// grpcErr converts the errors of the calls to the plugin, reporting the
+// calls aborted along with their request as canceled requests, and the calls
+// to a plugin whose process is gone as rpc.ErrShutdown like over net/rpc
+func grpcErr(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return logical.ErrRequestCanceled
+	}
+	if s, ok := status.FromError(err); ok {
+		switch s.Code() {
+		case codes.Canceled, codes.DeadlineExceeded:
+			return logical.ErrRequestCanceled
+		case codes.Unavailable:
+			return rpc.ErrShutdown
+		}
+	}
+	return err
+}
+
+func (b *backendGRPCPluginClient) HandleRequest(req *logical.Request) (*logical.Response, error) {
+	if b.metadataMode {
+		return nil, ErrClientInMetadataMode
+	}
+
+	protoReq, err := pb.LogicalRequestToProtoRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// The context of the request is passed along, so that canceling the
+	// request aborts the call and lets the plugin stop its work
+	ctx := req.Context()
+	stream, err := b.client.HandleRequest(b.ctx(ctx), &pb.HandleRequestArgs{
+		Request: protoReq,
+	})
+	if err != nil {
+		return nil, grpcErr(ctx, err)
+	}
+
+	var buf bytes.Buffer
+	for {
+		reply, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, grpcErr(ctx, err)
+		}
+		buf.Write(reply.Chunk)
+	}
+
+	var result pb.HandleRequestResult
+	if err := proto.Unmarshal(buf.Bytes(), &result); err != nil {
+		return nil, err
+	}
+
+	resp, err := pb.ProtoResponseToLogicalResponse(result.Response)
+	if err != nil {
+		return nil, err
+	}
+	return resp, pb.ProtoErrToErr(result.Err)
+}
+
+func (b *backendGRPCPluginClient) SpecialPaths() *logical.Paths {
+	reply, err := b.client.SpecialPaths(b.ctx(context.Background()), &pb.Empty{})
+	if err != nil {
+		return nil
+	}
+
+	return pb.ProtoPathsToLogicalPaths(reply.Paths)
+}
+
+// System returns vault's system view. The backend client stores the view during
+// Setup, so there is no need to shim the system just to get it back.
+func (b *backendGRPCPluginClient) System() logical.SystemView {
+	return b.system
+}
+
+// Logger returns vault's logger. The backend client stores the logger during
+// Setup, so there is no need to shim the logger just to get it back.
+func (b *backendGRPCPluginClient) Logger() log.Logger {
+	return b.logger
+}
+
+func (b *backendGRPCPluginClient) HandleExistenceCheck(req *logical.Request) (bool, bool, error) {
+	if b.metadataMode {
+		return false, false, ErrClientInMetadataMode
+	}
+
+	protoReq, err := pb.LogicalRequestToProtoRequest(req)
+	if err != nil {
+		return false, false, err
+	}
+
+	ctx := req.Context()
+	reply, err := b.client.HandleExistenceCheck(b.ctx(ctx), &pb.HandleExistenceCheckArgs{
+		Request: protoReq,
+	})
+	if err != nil {
+		return false, false, grpcErr(ctx, err)
+	}
+	if reply.Err != nil {
+		return false, false, pb.ProtoErrToErr(reply.Err)
+	}
+
+	return reply.CheckFound, reply.Exists, nil
+}
+
+func (b *backendGRPCPluginClient) Cleanup() {
+	b.client.Cleanup(b.ctx(context.Background()), &pb.Empty{})
+	if b.callbacks != nil {
+		b.callbacks.Stop()
+	}
+}
+
+func (b *backendGRPCPluginClient) Initialize() error {
+	if b.metadataMode {
+		return ErrClientInMetadataMode
+	}
+
+	reply, err := b.client.Initialize(b.ctx(context.Background()), &pb.Empty{})
+	if err != nil {
+		return err
+	}
+	return pb.ProtoErrToErr(reply.Err)
+}
+
+func (b *backendGRPCPluginClient) InvalidateKey(key string) {
+	if b.metadataMode {
+		return
+	}
+	b.client.InvalidateKey(b.ctx(context.Background()), &pb.InvalidateKeyArgs{
+		Key: key,
+	})
+}
+
+func (b *backendGRPCPluginClient) Setup(config *logical.BackendConfig) error {
+	storageImpl := config.StorageView
+	loggerImpl := config.Logger
+	sysViewImpl := config.System
+	if b.metadataMode {
+		storageImpl = &NOOPStorage{}
+		loggerImpl = log.NullLog
+		sysViewImpl = &logical.StaticSystemView{}
+	}
+
+	callbacks, err := newCallbackServer(storageImpl, sysViewImpl, loggerImpl)
+	if err != nil {
+		return err
+	}
+
+	reply, err := b.client.Setup(b.ctx(context.Background()), &pb.SetupArgs{
+		Config:        config.Config,
+		CallbackAddr:  callbacks.Addr(),
+		CallbackToken: callbacks.token,
+	})
+	if err == nil && reply.Err != "" {
+		err = errors.New(reply.Err)
+	}
+	if err != nil {
+		callbacks.Stop()
+		return err
+	}
+	b.callbacks = callbacks
+
+	// Set system and logger for getter methods
+	b.system = config.System
+	b.logger = config.Logger
+
+	return nil
+}
+
+func (b *backendGRPCPluginClient) Type() logical.BackendType {
+	reply, err := b.client.Type(b.ctx(context.Background()), &pb.Empty{})
+	if err != nil {
+		return logical.TypeUnknown
+	}
+
+	return logical.BackendType(reply.Type)
+}
+
+// RegisterLicense is a no-op, as licenses are not forwarded to the plugins
+// served over gRPC.
+func (b *backendGRPCPluginClient) RegisterLicense(license interface{}) error {
+	if b.metadataMode {
+		return ErrClientInMetadataMode
+	}
+	return nil
+}
@@ -0,0 +1,271 @@
+package plugin
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/plugin/pb"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// handleRequestChunkSize is the size of the chunks of the responses streamed
+// back by HandleRequest. It is kept well below the 4MB limit of the size of
+// the gRPC messages.
+const handleRequestChunkSize = 1024 * 1024
+
+// backendGRPCPluginServer is the gRPC server that backendGRPCPluginClient
+// talks to. A multiplexed server holds a backend per mount, keyed by the
+// multiplexing ID sent along with the calls; otherwise it holds a single
+// backend under the empty ID.
+type backendGRPCPluginServer struct {
+	factory      func(*logical.BackendConfig) (logical.Backend, error)
+	multiplexing bool
+
+	instancesLock sync.RWMutex
+	instances     map[string]*backendInstance
+}
+
+// backendInstance is a backend set up by the server
+type backendInstance struct {
+	backend logical.Backend
+
+	// callbacks is the connection to the callback services of the host
+	callbacks *grpc.ClientConn
+	storage   logical.Storage
+}
+
+// instance returns the backend which the call is addressed to
+func (b *backendGRPCPluginServer) instance(ctx context.Context) (*backendInstance, error) {
+	id := multiplexID(ctx)
+
+	b.instancesLock.RLock()
+	defer b.instancesLock.RUnlock()
+
+	instance, ok := b.instances[id]
+	if !ok {
+		return nil, status.Errorf(codes.FailedPrecondition, "backend %q is not set up", id)
+	}
+	return instance, nil
+}
+
+func (b *backendGRPCPluginServer) HandleRequest(args *pb.HandleRequestArgs, stream pb.Backend_HandleRequestServer) error {
+	if inMetadataMode() {
+		return ErrServerInMetadataMode
+	}
+
+	instance, err := b.instance(stream.Context())
+	if err != nil {
+		return err
+	}
+
+	req, err := pb.ProtoRequestToLogicalRequest(args.Request)
+	if err != nil {
+		return err
+	}
+	req.Storage = instance.storage
+
+	// The context of the stream is canceled along with the request
+	req.SetContext(stream.Context())
+
+	resp, respErr := instance.backend.HandleRequest(req)
+	protoResp, err := pb.LogicalResponseToProtoResponse(resp)
+	if err != nil {
+		return err
+	}
+
+	result, err := proto.Marshal(&pb.HandleRequestResult{
+		Response: protoResp,
+		Err:      pb.ErrToProtoErr(respErr),
+	})
+	if err != nil {
+		return err
+	}
+
+	for len(result) > 0 {
+		n := handleRequestChunkSize
+		if n > len(result) {
+			n = len(result)
+		}
+		if err := stream.Send(&pb.HandleRequestReply{Chunk: result[:n]}); err != nil {
+			return err
+		}
+		result = result[n:]
+	}
+
+	return nil
+}
+
+func (b *backendGRPCPluginServer) SpecialPaths(ctx context.Context, _ *pb.Empty) (*pb.SpecialPathsReply, error) {
+	instance, err := b.instance(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.SpecialPathsReply{
+		Paths: pb.LogicalPathsToProtoPaths(instance.backend.SpecialPaths()),
+	}, nil
+}
+
+func (b *backendGRPCPluginServer) HandleExistenceCheck(ctx context.Context, args *pb.HandleExistenceCheckArgs) (*pb.HandleExistenceCheckReply, error) {
+	if inMetadataMode() {
+		return nil, ErrServerInMetadataMode
+	}
+
+	instance, err := b.instance(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := pb.ProtoRequestToLogicalRequest(args.Request)
+	if err != nil {
+		return nil, err
+	}
+	req.Storage = instance.storage
+	req.SetContext(ctx)
+
+	checkFound, exists, err := instance.backend.HandleExistenceCheck(req)
+	return &pb.HandleExistenceCheckReply{
+		CheckFound: checkFound,
+		Exists:     exists,
+		Err:        pb.ErrToProtoErr(err),
+	}, nil
+}
+
+func (b *backendGRPCPluginServer) Initialize(ctx context.Context, _ *pb.Empty) (*pb.InitializeReply, error) {
+	if inMetadataMode() {
+		return nil, ErrServerInMetadataMode
+	}
+
+	instance, err := b.instance(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = instance.backend.Initialize()
+	return &pb.InitializeReply{
+		Err: pb.ErrToProtoErr(err),
+	}, nil
+}
+
+// Cleanup cleans the backend up and removes it from the server
+func (b *backendGRPCPluginServer) Cleanup(ctx context.Context, _ *pb.Empty) (*pb.Empty, error) {
+	id := multiplexID(ctx)
+
+	b.instancesLock.Lock()
+	instance, ok := b.instances[id]
+	delete(b.instances, id)
+	b.instancesLock.Unlock()
+
+	if !ok {
+		return &pb.Empty{}, nil
+	}
+
+	instance.backend.Cleanup()
+
+	// Close the connection to the callback services
+	instance.callbacks.Close()
+	return &pb.Empty{}, nil
+}
+
+func (b *backendGRPCPluginServer) InvalidateKey(ctx context.Context, args *pb.InvalidateKeyArgs) (*pb.Empty, error) {
+	if inMetadataMode() {
+		return nil, ErrServerInMetadataMode
+	}
+
+	instance, err := b.instance(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	instance.backend.InvalidateKey(args.Key)
+	return &pb.Empty{}, nil
+}
+
+// Setup connects to the callback services of the host to get a shimmed
+// storage, logger, and system view of the backend. This method also
+// instantiates the underlying backend through its factory func for the server
+// side of the plugin.
+func (b *backendGRPCPluginServer) Setup(ctx context.Context, args *pb.SetupArgs) (*pb.SetupReply, error) {
+	if args.CallbackAddr == "" {
+		return &pb.SetupReply{
+			Err: "missing callback address",
+		}, nil
+	}
+
+	conn, err := dialCallbacks(args.CallbackAddr, args.CallbackToken)
+	if err != nil {
+		return &pb.SetupReply{
+			Err: pb.ErrToString(err),
+		}, nil
+	}
+
+	storage := newGRPCStorageClient(conn)
+	config := &logical.BackendConfig{
+		StorageView: storage,
+		Logger:      newGRPCLoggerClient(conn),
+		System:      newGRPCSystemViewClient(conn),
+		Config:      args.Config,
+	}
+
+	// Call the underlying backend factory after shims have been created
+	// to create the backend
+	backend, err := b.factory(config)
+	if err != nil {
+		conn.Close()
+		return &pb.SetupReply{
+			Err: pb.ErrToString(err),
+		}, nil
+	}
+	if backend == nil {
+		conn.Close()
+		return &pb.SetupReply{
+			Err: pb.ErrToString(errors.New("backend factory returned no backend")),
+		}, nil
+	}
+
+	id := multiplexID(ctx)
+
+	b.instancesLock.Lock()
+	old := b.instances[id]
+	if b.instances == nil {
+		b.instances = make(map[string]*backendInstance)
+	}
+	b.instances[id] = &backendInstance{
+		backend:   backend,
+		callbacks: conn,
+		storage:   storage,
+	}
+	b.instancesLock.Unlock()
+
+	// A backend set up again replaces the previous one
+	if old != nil {
+		old.backend.Cleanup()
+		old.callbacks.Close()
+	}
+
+	return &pb.SetupReply{}, nil
+}
+
+func (b *backendGRPCPluginServer) Type(ctx context.Context, _ *pb.Empty) (*pb.TypeReply, error) {
+	instance, err := b.instance(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.TypeReply{
+		Type: uint32(instance.backend.Type()),
+	}, nil
+}
+
+// MultiplexingSupport reports whether the server may hold the backends of
+// several mounts
+func (b *backendGRPCPluginServer) MultiplexingSupport(ctx context.Context, _ *pb.Empty) (*pb.MultiplexingSupportReply, error) {
+	return &pb.MultiplexingSupportReply{
+		Supported: b.multiplexing,
+	}, nil
+}
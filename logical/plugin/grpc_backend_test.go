@@ -0,0 +1,331 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	gplugin "github.com/hashicorp/go-plugin"
+	"github.com/hashicorp/vault/helper/logformat"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/plugin/mock"
+	"github.com/hashicorp/vault/logical/plugin/pb"
+	log "github.com/mgutz/logxi/v1"
+)
+
+func TestGRPCBackendPlugin_impl(t *testing.T) {
+	var _ gplugin.GRPCPlugin = new(BackendPlugin)
+	var _ logical.Backend = new(backendGRPCPluginClient)
+	var _ pb.BackendServer = new(backendGRPCPluginServer)
+	var _ logical.Storage = new(GRPCStorageClient)
+	var _ logical.SystemView = new(GRPCSystemViewClient)
+	var _ log.Logger = new(GRPCLoggerClient)
+}
+
+func TestGRPCBackendPlugin_HandleRequest(t *testing.T) {
+	b, cleanup := testGRPCBackend(t)
+	defer cleanup()
+
+	_, err := b.HandleRequest(&logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "kv/foo",
+		Data: map[string]interface{}{
+			"value": "bar",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Read the value back through the storage of the host
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "kv/foo",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Data["value"] != "bar" {
+		t.Fatalf("bad: %#v", resp)
+	}
+}
+
+func TestGRPCBackendPlugin_HandleRequest_errors(t *testing.T) {
+	b, cleanup := testGRPCBackend(t)
+	defer cleanup()
+
+	_, err := b.HandleRequest(&logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "nonexistent",
+	})
+	if err != logical.ErrUnsupportedPath {
+		t.Fatalf("bad: %v", err)
+	}
+}
+
+func TestGRPCBackendPlugin_HandleRequest_canceled(t *testing.T) {
+	b, cleanup := testGRPCBackend(t)
+	defer cleanup()
+
+	req := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "sleep",
+		Data: map[string]interface{}{
+			"duration": 30,
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	req.SetContext(ctx)
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := b.HandleRequest(req)
+	if err != logical.ErrRequestCanceled {
+		t.Fatalf("bad: %v", err)
+	}
+	if time.Since(start) > 10*time.Second {
+		t.Fatalf("request was not canceled")
+	}
+}
+
+func TestGRPCBackendPlugin_HandleRequest_large(t *testing.T) {
+	b, cleanup := testGRPCBackend(t)
+	defer cleanup()
+
+	// The response is larger than the maximum size of the gRPC messages
+	size := 10 * 1024 * 1024
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "large",
+		Data: map[string]interface{}{
+			"size": size,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value := resp.Data["value"].(string); len(value) != size {
+		t.Fatalf("bad: value of %d bytes, expected %d", len(value), size)
+	}
+}
+
+func TestGRPCBackendPlugin_SpecialPaths(t *testing.T) {
+	b, cleanup := testGRPCBackend(t)
+	defer cleanup()
+
+	paths := b.SpecialPaths()
+	if paths == nil {
+		t.Fatal("SpecialPaths() returned nil")
+	}
+	if len(paths.Unauthenticated) != 1 || paths.Unauthenticated[0] != "special" {
+		t.Fatalf("bad: %#v", paths)
+	}
+}
+
+func TestGRPCBackendPlugin_HandleExistenceCheck(t *testing.T) {
+	b, cleanup := testGRPCBackend(t)
+	defer cleanup()
+
+	checkFound, exists, err := b.HandleExistenceCheck(&logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "kv/foo",
+		Data:      map[string]interface{}{"value": "bar"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !checkFound {
+		t.Fatal("existence check not found for path 'kv/foo")
+	}
+	if exists {
+		t.Fatal("existence check should have returned 'false' for 'kv/foo'")
+	}
+}
+
+func TestGRPCBackendPlugin_Initialize(t *testing.T) {
+	b, cleanup := testGRPCBackend(t)
+	defer cleanup()
+
+	err := b.Initialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGRPCBackendPlugin_InvalidateKey(t *testing.T) {
+	b, cleanup := testGRPCBackend(t)
+	defer cleanup()
+
+	b.InvalidateKey("internal")
+
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "internal",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Data["value"] != "" {
+		t.Fatalf("bad: expected empty response data, got %#v", resp)
+	}
+}
+
+func TestGRPCBackendPlugin_Type(t *testing.T) {
+	b, cleanup := testGRPCBackend(t)
+	defer cleanup()
+
+	if b.Type() != logical.TypeLogical {
+		t.Fatalf("bad: %v", b.Type())
+	}
+}
+
+func TestGRPCBackendPlugin_Cleanup(t *testing.T) {
+	b, cleanup := testGRPCBackend(t)
+	defer cleanup()
+
+	b.Cleanup()
+}
+
+func TestGRPCBackendPlugin_multiplexing(t *testing.T) {
+	pluginMap := map[string]gplugin.Plugin{
+		"backend": &BackendPlugin{
+			Factory:      mock.Factory,
+			multiplexing: true,
+		},
+	}
+	client, _ := gplugin.TestPluginGRPCConn(t, pluginMap)
+	defer client.Close()
+
+	raw, err := client.Dispense(BackendPluginName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := raw.(*backendGRPCPluginClient)
+	if !base.multiplexingSupport() {
+		t.Fatal("expected the plugin to support multiplexing")
+	}
+
+	// Set up two backends in the same plugin, each with its own storage
+	backends := make([]*backendGRPCPluginClient, 2)
+	for i := range backends {
+		backends[i] = base.multiplexed(fmt.Sprintf("backend-%d", i))
+		err := backends[i].Setup(&logical.BackendConfig{
+			Logger:      logformat.NewVaultLogger(log.LevelTrace),
+			System:      &logical.StaticSystemView{},
+			StorageView: &logical.InmemStorage{},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, err = backends[0].HandleRequest(&logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "kv/foo",
+		Data: map[string]interface{}{
+			"value": "bar",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := backends[1].HandleRequest(&logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "kv/foo",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp != nil {
+		t.Fatalf("bad: the backends share their data: %#v", resp)
+	}
+
+	// Cleaning a backend up leaves the other one in place
+	backends[0].Cleanup()
+	if _, err := backends[0].HandleRequest(&logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "kv/foo",
+	}); err == nil {
+		t.Fatal("expected an error from a backend cleaned up")
+	}
+	if backends[1].Type() != logical.TypeLogical {
+		t.Fatalf("bad: %v", backends[1].Type())
+	}
+	backends[1].Cleanup()
+}
+
+func TestGRPCBackendPlugin_multiplexingUnsupported(t *testing.T) {
+	b, cleanup := testGRPCBackend(t)
+	defer cleanup()
+
+	if b.(*backendGRPCPluginClient).multiplexingSupport() {
+		t.Fatal("expected the plugin not to support multiplexing")
+	}
+}
+
+func TestGRPCCallbackServer_token(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	storage.Put(&logical.StorageEntry{Key: "foo", Value: []byte("bar")})
+
+	s, err := newCallbackServer(storage, &logical.StaticSystemView{}, log.NullLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+
+	testGet := func(token string) error {
+		conn, err := dialCallbacks(s.Addr(), token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+
+		_, err = newGRPCStorageClient(conn).Get("foo")
+		return err
+	}
+
+	if err := testGet("invalid"); err == nil {
+		t.Fatal("expected an error with an invalid token")
+	}
+	if err := testGet(s.token); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testGRPCBackend(t *testing.T) (logical.Backend, func()) {
+	// Create a mock provider
+	pluginMap := map[string]gplugin.Plugin{
+		"backend": &BackendPlugin{
+			Factory: mock.Factory,
+		},
+	}
+	client, _ := gplugin.TestPluginGRPCConn(t, pluginMap)
+
+	// Request the backend
+	raw, err := client.Dispense(BackendPluginName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := raw.(logical.Backend)
+
+	err = b.Setup(&logical.BackendConfig{
+		Logger: logformat.NewVaultLogger(log.LevelTrace),
+		System: &logical.StaticSystemView{
+			DefaultLeaseTTLVal: 300 * time.Second,
+			MaxLeaseTTLVal:     1800 * time.Second,
+		},
+		StorageView: &logical.InmemStorage{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cleanup := func() {
+		b.(*backendGRPCPluginClient).callbacks.Stop()
+		client.Close()
+	}
+
+	return b, cleanup
+}
@@ -0,0 +1,113 @@
+package plugin
+
+import (
+	"crypto/subtle"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	uuid "github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/plugin/pb"
+	log "github.com/mgutz/logxi/v1"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// callbackTokenKey is the gRPC metadata key of the token authenticating the
+// calls of a plugin to the callback services
+const callbackTokenKey = "vault-plugin-callback-token"
+
+// callbackServer serves the storage, system view and logger of a backend to
+// its plugin. go-plugin cannot broker connections back to the host for the
+// plugins served over gRPC, so the host listens on a unix socket of its own,
+// in a directory only readable by Vault, and the plugin authenticates with
+// a random token sent during Setup.
+type callbackServer struct {
+	dir      string
+	listener net.Listener
+	server   *grpc.Server
+	token    string
+}
+
+func newCallbackServer(storage logical.Storage, sysView logical.SystemView, logger log.Logger) (*callbackServer, error) {
+	token, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := ioutil.TempDir("", "vault-plugin")
+	if err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("unix", filepath.Join(dir, "callbacks.sock"))
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	s := &callbackServer{
+		dir:      dir,
+		listener: listener,
+		token:    token,
+	}
+	s.server = grpc.NewServer(grpc.UnaryInterceptor(s.authenticate))
+	pb.RegisterStorageServer(s.server, &GRPCStorageServer{impl: storage})
+	pb.RegisterSystemViewServer(s.server, &GRPCSystemViewServer{impl: sysView})
+	pb.RegisterLoggerServer(s.server, &GRPCLoggerServer{logger: logger})
+
+	go s.server.Serve(listener)
+
+	return s, nil
+}
+
+// authenticate rejects the calls which do not carry the callback token
+func (s *callbackServer) authenticate(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	tokens := md[callbackTokenKey]
+	if len(tokens) != 1 || subtle.ConstantTimeCompare([]byte(tokens[0]), []byte(s.token)) != 1 {
+		return nil, status.Errorf(codes.Unauthenticated, "invalid callback token")
+	}
+	return handler(ctx, req)
+}
+
+// Addr returns the path of the socket of the server
+func (s *callbackServer) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Stop stops the server and removes its socket
+func (s *callbackServer) Stop() {
+	s.server.Stop()
+	os.RemoveAll(s.dir)
+}
+
+// callbackToken sends the callback token along with the calls of the plugin
+type callbackToken string
+
+func (t callbackToken) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{callbackTokenKey: string(t)}, nil
+}
+
+// RequireTransportSecurity returns false, as the unix socket of the callback
+// server is only reachable by Vault and its plugins
+func (t callbackToken) RequireTransportSecurity() bool {
+	return false
+}
+
+// dialCallbacks connects a plugin to the callback server of the host
+func dialCallbacks(addr, token string) (*grpc.ClientConn, error) {
+	return grpc.Dial(addr,
+		grpc.WithInsecure(),
+		grpc.WithDialer(func(addr string, timeout time.Duration) (net.Conn, error) {
+			return net.DialTimeout("unix", addr, timeout)
+		}),
+		grpc.WithPerRPCCredentials(callbackToken(token)),
+	)
+}
@@ -0,0 +1,155 @@
+package plugin
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/vault/logical/plugin/pb"
+	log "github.com/mgutz/logxi/v1"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+func newGRPCLoggerClient(conn *grpc.ClientConn) *GRPCLoggerClient {
+	return &GRPCLoggerClient{
+		client: pb.NewLoggerClient(conn),
+	}
+}
+
+// GRPCLoggerClient is an implementation of log.Logger that communicates over
+// gRPC. The arguments of the log entries are sent as strings.
+type GRPCLoggerClient struct {
+	client pb.LoggerClient
+}
+
+func (l *GRPCLoggerClient) log(level int, msg string, args []interface{}) error {
+	logArgs := make([]string, len(args))
+	for i, arg := range args {
+		logArgs[i] = fmt.Sprint(arg)
+	}
+
+	reply, err := l.client.Log(context.Background(), &pb.LogArgs{
+		Level: int32(level),
+		Msg:   msg,
+		Args:  logArgs,
+	})
+	if err != nil {
+		return err
+	}
+	return pb.StringToErr(reply.Err)
+}
+
+func (l *GRPCLoggerClient) isLevel(level int) bool {
+	reply, err := l.client.IsLevel(context.Background(), &pb.LevelArgs{
+		Level: int32(level),
+	})
+	if err != nil {
+		return false
+	}
+	return reply.Enabled
+}
+
+func (l *GRPCLoggerClient) Trace(msg string, args ...interface{}) {
+	l.log(log.LevelTrace, msg, args)
+}
+
+func (l *GRPCLoggerClient) Debug(msg string, args ...interface{}) {
+	l.log(log.LevelDebug, msg, args)
+}
+
+func (l *GRPCLoggerClient) Info(msg string, args ...interface{}) {
+	l.log(log.LevelInfo, msg, args)
+}
+
+func (l *GRPCLoggerClient) Warn(msg string, args ...interface{}) error {
+	return l.log(log.LevelWarn, msg, args)
+}
+
+func (l *GRPCLoggerClient) Error(msg string, args ...interface{}) error {
+	return l.log(log.LevelError, msg, args)
+}
+
+func (l *GRPCLoggerClient) Fatal(msg string, args ...interface{}) {
+	// NOOP since it's not actually used within vault
+	return
+}
+
+func (l *GRPCLoggerClient) Log(level int, msg string, args []interface{}) {
+	l.log(level, msg, args)
+}
+
+func (l *GRPCLoggerClient) SetLevel(level int) {
+	l.client.SetLevel(context.Background(), &pb.LevelArgs{
+		Level: int32(level),
+	})
+}
+
+func (l *GRPCLoggerClient) IsTrace() bool {
+	return l.isLevel(log.LevelTrace)
+}
+
+func (l *GRPCLoggerClient) IsDebug() bool {
+	return l.isLevel(log.LevelDebug)
+}
+
+func (l *GRPCLoggerClient) IsInfo() bool {
+	return l.isLevel(log.LevelInfo)
+}
+
+func (l *GRPCLoggerClient) IsWarn() bool {
+	return l.isLevel(log.LevelWarn)
+}
+
+// GRPCLoggerServer is a gRPC server serving a log.Logger
+type GRPCLoggerServer struct {
+	logger log.Logger
+}
+
+func (l *GRPCLoggerServer) Log(ctx context.Context, args *pb.LogArgs) (*pb.LogReply, error) {
+	logArgs := make([]interface{}, len(args.Args))
+	for i, arg := range args.Args {
+		logArgs[i] = arg
+	}
+
+	var err error
+	switch int(args.Level) {
+	case log.LevelTrace:
+		l.logger.Trace(args.Msg, logArgs...)
+	case log.LevelDebug:
+		l.logger.Debug(args.Msg, logArgs...)
+	case log.LevelInfo:
+		l.logger.Info(args.Msg, logArgs...)
+	case log.LevelWarn:
+		err = l.logger.Warn(args.Msg, logArgs...)
+	case log.LevelError:
+		err = l.logger.Error(args.Msg, logArgs...)
+	default:
+		l.logger.Log(int(args.Level), args.Msg, logArgs)
+	}
+
+	return &pb.LogReply{
+		Err: pb.ErrToString(err),
+	}, nil
+}
+
+func (l *GRPCLoggerServer) SetLevel(ctx context.Context, args *pb.LevelArgs) (*pb.Empty, error) {
+	l.logger.SetLevel(int(args.Level))
+	return &pb.Empty{}, nil
+}
+
+func (l *GRPCLoggerServer) IsLevel(ctx context.Context, args *pb.LevelArgs) (*pb.IsLevelReply, error) {
+	var enabled bool
+	switch int(args.Level) {
+	case log.LevelTrace:
+		enabled = l.logger.IsTrace()
+	case log.LevelDebug:
+		enabled = l.logger.IsDebug()
+	case log.LevelInfo:
+		enabled = l.logger.IsInfo()
+	case log.LevelWarn:
+		enabled = l.logger.IsWarn()
+	}
+
+	return &pb.IsLevelReply{
+		Enabled: enabled,
+	}, nil
+}
@@ -0,0 +1,136 @@
+package plugin
+
+import (
+	"encoding/hex"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/go-plugin"
+	uuid "github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/helper/pluginutil"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/metadata"
+)
+
+// multiplexIDKey is the gRPC metadata key of the ID of the backend which a
+// call to a multiplexed plugin is addressed to
+const multiplexIDKey = "vault-plugin-multiplex-id"
+
+// multiplexID returns the multiplexing ID sent along with a call, or the
+// empty ID of the backend of plugins which are not multiplexed
+func multiplexID(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	ids := md[multiplexIDKey]
+	if len(ids) != 1 {
+		return ""
+	}
+	return ids[0]
+}
+
+// withMultiplexID sends the multiplexing ID along with the calls made with
+// the returned context
+func withMultiplexID(ctx context.Context, id string) context.Context {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	return metadata.NewOutgoingContext(ctx, metadata.Join(md, metadata.Pairs(multiplexIDKey, id)))
+}
+
+// multiplexedPlugin is a plugin process serving the backends of several
+// mounts. The process is killed once the last of its backends is cleaned up.
+type multiplexedPlugin struct {
+	key      string
+	client   *plugin.Client
+	protocol plugin.ClientProtocol
+	base     *backendGRPCPluginClient
+
+	// refs is the number of backends served by the process, guarded by the
+	// lock of multiplexedPlugins
+	refs int
+}
+
+// multiplexedPlugins holds the running multiplexed plugins, keyed by the
+// name, command, arguments and checksum of their catalog entry so that
+// updating the entry of a plugin starts a new process.
+var multiplexedPlugins = struct {
+	sync.Mutex
+	m map[string]*multiplexedPlugin
+}{
+	m: make(map[string]*multiplexedPlugin),
+}
+
+func multiplexingKey(pluginRunner *pluginutil.PluginRunner) string {
+	parts := []string{pluginRunner.Name, pluginRunner.Command}
+	parts = append(parts, pluginRunner.Args...)
+	parts = append(parts, hex.EncodeToString(pluginRunner.Sha256))
+	return strings.Join(parts, "\x00")
+}
+
+// dispenseMultiplexed returns a client of a new backend of the running
+// multiplexed plugin of the given catalog entry, if any.
+func dispenseMultiplexed(pluginRunner *pluginutil.PluginRunner) (*BackendPluginClient, error) {
+	multiplexedPlugins.Lock()
+	defer multiplexedPlugins.Unlock()
+
+	p, ok := multiplexedPlugins.m[multiplexingKey(pluginRunner)]
+	if !ok || p.client.Exited() {
+		return nil, nil
+	}
+
+	return p.dispense()
+}
+
+// registerMultiplexed records a plugin process supporting multiplexing, so
+// that the next backends of its catalog entry are served by the same process,
+// and returns the client of its first backend. If another process of the
+// entry was registered in the meantime, the process is not shared.
+func registerMultiplexed(pluginRunner *pluginutil.PluginRunner, client *plugin.Client, protocol plugin.ClientProtocol, base *backendGRPCPluginClient) (*BackendPluginClient, error) {
+	key := multiplexingKey(pluginRunner)
+	p := &multiplexedPlugin{
+		key:      key,
+		client:   client,
+		protocol: protocol,
+		base:     base,
+	}
+
+	multiplexedPlugins.Lock()
+	defer multiplexedPlugins.Unlock()
+
+	if existing, ok := multiplexedPlugins.m[key]; !ok || existing.client.Exited() {
+		multiplexedPlugins.m[key] = p
+	}
+
+	return p.dispense()
+}
+
+// dispense returns a client of a new backend of the plugin. The lock of
+// multiplexedPlugins must be held.
+func (p *multiplexedPlugin) dispense() (*BackendPluginClient, error) {
+	id, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, err
+	}
+
+	p.refs++
+
+	return &BackendPluginClient{
+		client:      p.client,
+		protocol:    p.protocol,
+		multiplexed: p,
+		Backend:     p.base.multiplexed(id),
+	}, nil
+}
+
+// release kills the process once none of its backends are left
+func (p *multiplexedPlugin) release() {
+	multiplexedPlugins.Lock()
+	defer multiplexedPlugins.Unlock()
+
+	p.refs--
+	if p.refs > 0 {
+		return
+	}
+
+	if multiplexedPlugins.m[p.key] == p {
+		delete(multiplexedPlugins.m, p.key)
+	}
+	p.client.Kill()
+}
@@ -0,0 +1,98 @@
+package plugin
+
+import (
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/plugin/pb"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+func newGRPCStorageClient(conn *grpc.ClientConn) *GRPCStorageClient {
+	return &GRPCStorageClient{
+		client: pb.NewStorageClient(conn),
+	}
+}
+
+// GRPCStorageClient is an implementation of logical.Storage that communicates
+// over gRPC.
+type GRPCStorageClient struct {
+	client pb.StorageClient
+}
+
+func (s *GRPCStorageClient) List(prefix string) ([]string, error) {
+	reply, err := s.client.List(context.Background(), &pb.StorageListArgs{
+		Prefix: prefix,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reply.Keys, pb.StringToErr(reply.Err)
+}
+
+func (s *GRPCStorageClient) Get(key string) (*logical.StorageEntry, error) {
+	reply, err := s.client.Get(context.Background(), &pb.StorageGetArgs{
+		Key: key,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if reply.Err != "" {
+		return nil, pb.StringToErr(reply.Err)
+	}
+	return pb.ProtoStorageEntryToLogicalStorageEntry(reply.Entry), nil
+}
+
+func (s *GRPCStorageClient) Put(entry *logical.StorageEntry) error {
+	reply, err := s.client.Put(context.Background(), &pb.StoragePutArgs{
+		Entry: pb.LogicalStorageEntryToProtoStorageEntry(entry),
+	})
+	if err != nil {
+		return err
+	}
+	return pb.StringToErr(reply.Err)
+}
+
+func (s *GRPCStorageClient) Delete(key string) error {
+	reply, err := s.client.Delete(context.Background(), &pb.StorageDeleteArgs{
+		Key: key,
+	})
+	if err != nil {
+		return err
+	}
+	return pb.StringToErr(reply.Err)
+}
+
+// GRPCStorageServer is a gRPC server serving a logical.Storage
+type GRPCStorageServer struct {
+	impl logical.Storage
+}
+
+func (s *GRPCStorageServer) List(ctx context.Context, args *pb.StorageListArgs) (*pb.StorageListReply, error) {
+	keys, err := s.impl.List(args.Prefix)
+	return &pb.StorageListReply{
+		Keys: keys,
+		Err:  pb.ErrToString(err),
+	}, nil
+}
+
+func (s *GRPCStorageServer) Get(ctx context.Context, args *pb.StorageGetArgs) (*pb.StorageGetReply, error) {
+	entry, err := s.impl.Get(args.Key)
+	return &pb.StorageGetReply{
+		Entry: pb.LogicalStorageEntryToProtoStorageEntry(entry),
+		Err:   pb.ErrToString(err),
+	}, nil
+}
+
+func (s *GRPCStorageServer) Put(ctx context.Context, args *pb.StoragePutArgs) (*pb.StoragePutReply, error) {
+	err := s.impl.Put(pb.ProtoStorageEntryToLogicalStorageEntry(args.Entry))
+	return &pb.StoragePutReply{
+		Err: pb.ErrToString(err),
+	}, nil
+}
+
+func (s *GRPCStorageServer) Delete(ctx context.Context, args *pb.StorageDeleteArgs) (*pb.StorageDeleteReply, error) {
+	err := s.impl.Delete(args.Key)
+	return &pb.StorageDeleteReply{
+		Err: pb.ErrToString(err),
+	}, nil
+}
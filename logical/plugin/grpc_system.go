@@ -0,0 +1,283 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault/helper/consts"
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/helper/passwordpolicy"
+	"github.com/hashicorp/vault/helper/pluginutil"
+	"github.com/hashicorp/vault/helper/wrapping"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/plugin/pb"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+func newGRPCSystemViewClient(conn *grpc.ClientConn) *GRPCSystemViewClient {
+	return &GRPCSystemViewClient{
+		client: pb.NewSystemViewClient(conn),
+	}
+}
+
+// GRPCSystemViewClient is an implementation of logical.SystemView that
+// communicates over gRPC.
+type GRPCSystemViewClient struct {
+	client pb.SystemViewClient
+}
+
+func (s *GRPCSystemViewClient) DefaultLeaseTTL() time.Duration {
+	reply, err := s.client.DefaultLeaseTTL(context.Background(), &pb.Empty{})
+	if err != nil {
+		return 0
+	}
+
+	return time.Duration(reply.TTL)
+}
+
+func (s *GRPCSystemViewClient) MaxLeaseTTL() time.Duration {
+	reply, err := s.client.MaxLeaseTTL(context.Background(), &pb.Empty{})
+	if err != nil {
+		return 0
+	}
+
+	return time.Duration(reply.TTL)
+}
+
+func (s *GRPCSystemViewClient) SudoPrivilege(path string, token string) bool {
+	reply, err := s.client.SudoPrivilege(context.Background(), &pb.SudoPrivilegeArgs{
+		Path:  path,
+		Token: token,
+	})
+	if err != nil {
+		return false
+	}
+
+	return reply.Sudo
+}
+
+func (s *GRPCSystemViewClient) Tainted() bool {
+	reply, err := s.client.Tainted(context.Background(), &pb.Empty{})
+	if err != nil {
+		return false
+	}
+
+	return reply.Tainted
+}
+
+func (s *GRPCSystemViewClient) CachingDisabled() bool {
+	reply, err := s.client.CachingDisabled(context.Background(), &pb.Empty{})
+	if err != nil {
+		return false
+	}
+
+	return reply.Disabled
+}
+
+func (s *GRPCSystemViewClient) ReplicationState() consts.ReplicationState {
+	reply, err := s.client.ReplicationState(context.Background(), &pb.Empty{})
+	if err != nil {
+		return consts.ReplicationDisabled
+	}
+
+	return consts.ReplicationState(reply.State)
+}
+
+func (s *GRPCSystemViewClient) ResponseWrapData(data map[string]interface{}, ttl time.Duration, jwt bool) (*wrapping.ResponseWrapInfo, error) {
+	buf, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Do not allow JWTs to be returned
+	reply, err := s.client.ResponseWrapData(context.Background(), &pb.ResponseWrapDataArgs{
+		Data: string(buf),
+		TTL:  int64(ttl),
+		JWT:  false,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if reply.Err != "" {
+		return nil, pb.StringToErr(reply.Err)
+	}
+
+	return pb.ProtoResponseWrapInfoToLogicalResponseWrapInfo(reply.WrapInfo)
+}
+
+func (s *GRPCSystemViewClient) LookupPlugin(name string) (*pluginutil.PluginRunner, error) {
+	return nil, fmt.Errorf("cannot call LookupPlugin from a plugin backend")
+}
+
+func (s *GRPCSystemViewClient) MlockEnabled() bool {
+	reply, err := s.client.MlockEnabled(context.Background(), &pb.Empty{})
+	if err != nil {
+		return false
+	}
+
+	return reply.Enabled
+}
+
+func (s *GRPCSystemViewClient) PasswordPolicy(name string) (*passwordpolicy.Policy, error) {
+	reply, err := s.client.PasswordPolicy(context.Background(), &pb.PasswordPolicyArgs{
+		Name: name,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if reply.Err != "" {
+		return nil, pb.StringToErr(reply.Err)
+	}
+	if reply.Policy == "" {
+		return nil, nil
+	}
+
+	var policy passwordpolicy.Policy
+	if err := jsonutil.DecodeJSON([]byte(reply.Policy), &policy); err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+func (s *GRPCSystemViewClient) EntityInfo(entityID string) (*logical.Entity, error) {
+	reply, err := s.client.EntityInfo(context.Background(), &pb.EntityInfoArgs{
+		EntityID: entityID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if reply.Err != "" {
+		return nil, pb.StringToErr(reply.Err)
+	}
+	if reply.Entity == "" {
+		return nil, nil
+	}
+
+	var entity logical.Entity
+	if err := jsonutil.DecodeJSON([]byte(reply.Entity), &entity); err != nil {
+		return nil, err
+	}
+	return &entity, nil
+}
+
+// GRPCSystemViewServer is a gRPC server serving a logical.SystemView
+type GRPCSystemViewServer struct {
+	impl logical.SystemView
+}
+
+func (s *GRPCSystemViewServer) DefaultLeaseTTL(ctx context.Context, _ *pb.Empty) (*pb.TTLReply, error) {
+	return &pb.TTLReply{
+		TTL: int64(s.impl.DefaultLeaseTTL()),
+	}, nil
+}
+
+func (s *GRPCSystemViewServer) MaxLeaseTTL(ctx context.Context, _ *pb.Empty) (*pb.TTLReply, error) {
+	return &pb.TTLReply{
+		TTL: int64(s.impl.MaxLeaseTTL()),
+	}, nil
+}
+
+func (s *GRPCSystemViewServer) SudoPrivilege(ctx context.Context, args *pb.SudoPrivilegeArgs) (*pb.SudoPrivilegeReply, error) {
+	return &pb.SudoPrivilegeReply{
+		Sudo: s.impl.SudoPrivilege(args.Path, args.Token),
+	}, nil
+}
+
+func (s *GRPCSystemViewServer) Tainted(ctx context.Context, _ *pb.Empty) (*pb.TaintedReply, error) {
+	return &pb.TaintedReply{
+		Tainted: s.impl.Tainted(),
+	}, nil
+}
+
+func (s *GRPCSystemViewServer) CachingDisabled(ctx context.Context, _ *pb.Empty) (*pb.CachingDisabledReply, error) {
+	return &pb.CachingDisabledReply{
+		Disabled: s.impl.CachingDisabled(),
+	}, nil
+}
+
+func (s *GRPCSystemViewServer) ReplicationState(ctx context.Context, _ *pb.Empty) (*pb.ReplicationStateReply, error) {
+	return &pb.ReplicationStateReply{
+		State: uint32(s.impl.ReplicationState()),
+	}, nil
+}
+
+func (s *GRPCSystemViewServer) ResponseWrapData(ctx context.Context, args *pb.ResponseWrapDataArgs) (*pb.ResponseWrapDataReply, error) {
+	var data map[string]interface{}
+	if args.Data != "" {
+		if err := jsonutil.DecodeJSON([]byte(args.Data), &data); err != nil {
+			return &pb.ResponseWrapDataReply{
+				Err: pb.ErrToString(err),
+			}, nil
+		}
+	}
+
+	// Do not allow JWTs to be returned
+	info, err := s.impl.ResponseWrapData(data, time.Duration(args.TTL), false)
+	if err != nil {
+		return &pb.ResponseWrapDataReply{
+			Err: pb.ErrToString(err),
+		}, nil
+	}
+
+	wrapInfo, err := pb.LogicalResponseWrapInfoToProtoResponseWrapInfo(info)
+	if err != nil {
+		return &pb.ResponseWrapDataReply{
+			Err: pb.ErrToString(err),
+		}, nil
+	}
+	return &pb.ResponseWrapDataReply{
+		WrapInfo: wrapInfo,
+	}, nil
+}
+
+func (s *GRPCSystemViewServer) MlockEnabled(ctx context.Context, _ *pb.Empty) (*pb.MlockEnabledReply, error) {
+	return &pb.MlockEnabledReply{
+		Enabled: s.impl.MlockEnabled(),
+	}, nil
+}
+
+func (s *GRPCSystemViewServer) PasswordPolicy(ctx context.Context, args *pb.PasswordPolicyArgs) (*pb.PasswordPolicyReply, error) {
+	policy, err := s.impl.PasswordPolicy(args.Name)
+	if err != nil {
+		return &pb.PasswordPolicyReply{
+			Err: pb.ErrToString(err),
+		}, nil
+	}
+	if policy == nil {
+		return &pb.PasswordPolicyReply{}, nil
+	}
+
+	buf, err := json.Marshal(policy)
+	if err != nil {
+		return &pb.PasswordPolicyReply{
+			Err: pb.ErrToString(err),
+		}, nil
+	}
+	return &pb.PasswordPolicyReply{
+		Policy: string(buf),
+	}, nil
+}
+
+func (s *GRPCSystemViewServer) EntityInfo(ctx context.Context, args *pb.EntityInfoArgs) (*pb.EntityInfoReply, error) {
+	entity, err := s.impl.EntityInfo(args.EntityID)
+	if err != nil {
+		return &pb.EntityInfoReply{
+			Err: pb.ErrToString(err),
+		}, nil
+	}
+	if entity == nil {
+		return &pb.EntityInfoReply{}, nil
+	}
+
+	buf, err := json.Marshal(entity)
+	if err != nil {
+		return &pb.EntityInfoReply{
+			Err: pb.ErrToString(err),
+		}, nil
+	}
+	return &pb.EntityInfoReply{
+		Entity: string(buf),
+	}, nil
+}
@@ -41,9 +41,11 @@ func Backend() *backend {
 		Paths: framework.PathAppend(
 			errorPaths(&b),
 			kvPaths(&b),
+			contextPaths(&b),
 			[]*framework.Path{
 				pathInternal(&b),
 				pathSpecial(&b),
+				pathPID(&b),
 			},
 		),
 		PathsSpecial: &logical.Paths{
@@ -0,0 +1,63 @@
+package mock
+
+import (
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// contextPaths are used to test the propagation of the request contexts and
+// the streaming of large responses to the plugins served over gRPC.
+func contextPaths(b *backend) []*framework.Path {
+	return []*framework.Path{
+		&framework.Path{
+			Pattern: "sleep",
+			Fields: map[string]*framework.FieldSchema{
+				"duration": &framework.FieldSchema{Type: framework.TypeDurationSecond},
+			},
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: b.pathSleepUpdate,
+			},
+		},
+		&framework.Path{
+			Pattern: "large",
+			Fields: map[string]*framework.FieldSchema{
+				"size": &framework.FieldSchema{Type: framework.TypeInt},
+			},
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: b.pathLargeUpdate,
+			},
+		},
+	}
+}
+
+// pathSleepUpdate waits for the given duration, unless the request is
+// canceled first
+func (b *backend) pathSleepUpdate(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	duration := time.Duration(data.Get("duration").(int)) * time.Second
+
+	select {
+	case <-req.Context().Done():
+		return nil, logical.ErrRequestCanceled
+	case <-time.After(duration):
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"slept": true,
+		},
+	}, nil
+}
+
+// pathLargeUpdate returns a value of the given size
+func (b *backend) pathLargeUpdate(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"value": strings.Repeat("a", data.Get("size").(int)),
+		},
+	}, nil
+}
@@ -0,0 +1,28 @@
+package mock
+
+import (
+	"os"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// pathPID is used to test which process serves the backend. In this case,
+// it is used to test plugin multiplexing.
+func pathPID(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "pid",
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathPIDRead,
+		},
+	}
+}
+
+func (b *backend) pathPIDRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"pid": os.Getpid(),
+		},
+	}, nil
+}
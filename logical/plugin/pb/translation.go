@@ -0,0 +1,695 @@
+package pb
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/helper/wrapping"
+	"github.com/hashicorp/vault/logical"
+)
+
+// Kinds of the errors sent in a ProtoError, so that the errors which are
+// checked for by the core can be rebuilt on the other side of the connection
+const (
+	ErrTypeUnknown uint32 = iota
+	ErrTypeCodedError
+	ErrTypeStatusBadRequest
+	ErrTypeUnsupportedOperation
+	ErrTypeUnsupportedPath
+	ErrTypeInvalidRequest
+	ErrTypePermissionDenied
+	ErrTypeRequestCanceled
+)
+
+// ErrToProtoErr converts an error into a ProtoError, or nil if err is nil
+func ErrToProtoErr(err error) *ProtoError {
+	if err == nil {
+		return nil
+	}
+
+	pbErr := &ProtoError{
+		ErrMsg:  err.Error(),
+		ErrType: ErrTypeUnknown,
+	}
+
+	switch e := err.(type) {
+	case logical.HTTPCodedError:
+		pbErr.ErrType = ErrTypeCodedError
+		pbErr.ErrCode = int64(e.Code())
+	case *logical.StatusBadRequest:
+		pbErr.ErrType = ErrTypeStatusBadRequest
+	}
+
+	switch err {
+	case logical.ErrUnsupportedOperation:
+		pbErr.ErrType = ErrTypeUnsupportedOperation
+	case logical.ErrUnsupportedPath:
+		pbErr.ErrType = ErrTypeUnsupportedPath
+	case logical.ErrInvalidRequest:
+		pbErr.ErrType = ErrTypeInvalidRequest
+	case logical.ErrPermissionDenied:
+		pbErr.ErrType = ErrTypePermissionDenied
+	case logical.ErrRequestCanceled:
+		pbErr.ErrType = ErrTypeRequestCanceled
+	}
+
+	return pbErr
+}
+
+// ProtoErrToErr converts a ProtoError back into an error, or nil if e is nil
+func ProtoErrToErr(e *ProtoError) error {
+	if e == nil {
+		return nil
+	}
+
+	switch e.ErrType {
+	case ErrTypeCodedError:
+		return logical.CodedError(int(e.ErrCode), e.ErrMsg)
+	case ErrTypeStatusBadRequest:
+		return &logical.StatusBadRequest{Err: e.ErrMsg}
+	case ErrTypeUnsupportedOperation:
+		return logical.ErrUnsupportedOperation
+	case ErrTypeUnsupportedPath:
+		return logical.ErrUnsupportedPath
+	case ErrTypeInvalidRequest:
+		return logical.ErrInvalidRequest
+	case ErrTypePermissionDenied:
+		return logical.ErrPermissionDenied
+	case ErrTypeRequestCanceled:
+		return logical.ErrRequestCanceled
+	}
+
+	return errors.New(e.ErrMsg)
+}
+
+// ErrToString returns the message of err, or an empty string if err is nil
+func ErrToString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// StringToErr returns an error with the given message, or nil if the message
+// is empty
+func StringToErr(s string) error {
+	if s == "" {
+		return nil
+	}
+	return errors.New(s)
+}
+
+func encodeData(data map[string]interface{}) (string, error) {
+	if data == nil {
+		return "", nil
+	}
+	buf, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+// decodeData decodes JSON data the way the HTTP layer decodes request
+// bodies, so that backends see the same types either way
+func decodeData(s string) (map[string]interface{}, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var data map[string]interface{}
+	if err := jsonutil.DecodeJSON([]byte(s), &data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func timeToProto(t time.Time) (*timestamp.Timestamp, error) {
+	if t.IsZero() {
+		return nil, nil
+	}
+	return ptypes.TimestampProto(t)
+}
+
+func protoToTime(t *timestamp.Timestamp) (time.Time, error) {
+	if t == nil {
+		return time.Time{}, nil
+	}
+	return ptypes.Timestamp(t)
+}
+
+// LogicalRequestToProtoRequest converts a logical.Request into a Request.
+// The storage and the TLS state of the connection are not sent.
+func LogicalRequestToProtoRequest(r *logical.Request) (*Request, error) {
+	if r == nil {
+		return nil, nil
+	}
+
+	data, err := encodeData(r.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request data: %v", err)
+	}
+	secret, err := LogicalSecretToProtoSecret(r.Secret)
+	if err != nil {
+		return nil, err
+	}
+	auth, err := LogicalAuthToProtoAuth(r.Auth)
+	if err != nil {
+		return nil, err
+	}
+
+	var headers map[string]*Header
+	if r.Headers != nil {
+		headers = make(map[string]*Header, len(r.Headers))
+		for k, v := range r.Headers {
+			headers[k] = &Header{Header: v}
+		}
+	}
+
+	var wrapInfo *RequestWrapInfo
+	if r.WrapInfo != nil {
+		wrapInfo = &RequestWrapInfo{
+			TTL:    int64(r.WrapInfo.TTL),
+			Format: r.WrapInfo.Format,
+		}
+	}
+
+	var connection *Connection
+	if r.Connection != nil {
+		connection = &Connection{
+			RemoteAddr: r.Connection.RemoteAddr,
+		}
+		if creds := r.Connection.PeerCredentials; creds != nil {
+			connection.PeerCredentials = &PeerCredentials{
+				Pid: creds.PID,
+				Uid: creds.UID,
+				Gid: creds.GID,
+			}
+		}
+	}
+
+	return &Request{
+		ID:                       r.ID,
+		ReplicationCluster:       r.ReplicationCluster,
+		Operation:                string(r.Operation),
+		Path:                     r.Path,
+		Namespace:                r.Namespace,
+		Data:                     data,
+		Secret:                   secret,
+		Auth:                     auth,
+		Headers:                  headers,
+		ClientToken:              r.ClientToken,
+		ClientTokenAccessor:      r.ClientTokenAccessor,
+		DisplayName:              r.DisplayName,
+		MountPoint:               r.MountPoint,
+		MountType:                r.MountType,
+		MountAccessor:            r.MountAccessor,
+		WrapInfo:                 wrapInfo,
+		ClientTokenRemainingUses: int64(r.ClientTokenRemainingUses),
+		EntityID:                 r.EntityID,
+		ControlGroupID:           r.ControlGroupID,
+		Connection:               connection,
+	}, nil
+}
+
+// ProtoRequestToLogicalRequest converts a Request into a logical.Request
+func ProtoRequestToLogicalRequest(r *Request) (*logical.Request, error) {
+	if r == nil {
+		return nil, nil
+	}
+
+	data, err := decodeData(r.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode request data: %v", err)
+	}
+	secret, err := ProtoSecretToLogicalSecret(r.Secret)
+	if err != nil {
+		return nil, err
+	}
+	auth, err := ProtoAuthToLogicalAuth(r.Auth)
+	if err != nil {
+		return nil, err
+	}
+
+	var headers map[string][]string
+	if r.Headers != nil {
+		headers = make(map[string][]string, len(r.Headers))
+		for k, v := range r.Headers {
+			headers[k] = v.GetHeader()
+		}
+	}
+
+	var wrapInfo *logical.RequestWrapInfo
+	if r.WrapInfo != nil {
+		wrapInfo = &logical.RequestWrapInfo{
+			TTL:    time.Duration(r.WrapInfo.TTL),
+			Format: r.WrapInfo.Format,
+		}
+	}
+
+	var connection *logical.Connection
+	if r.Connection != nil {
+		connection = &logical.Connection{
+			RemoteAddr: r.Connection.RemoteAddr,
+		}
+		if creds := r.Connection.PeerCredentials; creds != nil {
+			connection.PeerCredentials = &logical.PeerCredentials{
+				PID: creds.Pid,
+				UID: creds.Uid,
+				GID: creds.Gid,
+			}
+		}
+	}
+
+	return &logical.Request{
+		ID:                       r.ID,
+		ReplicationCluster:       r.ReplicationCluster,
+		Operation:                logical.Operation(r.Operation),
+		Path:                     r.Path,
+		Namespace:                r.Namespace,
+		Data:                     data,
+		Secret:                   secret,
+		Auth:                     auth,
+		Headers:                  headers,
+		ClientToken:              r.ClientToken,
+		ClientTokenAccessor:      r.ClientTokenAccessor,
+		DisplayName:              r.DisplayName,
+		MountPoint:               r.MountPoint,
+		MountType:                r.MountType,
+		MountAccessor:            r.MountAccessor,
+		WrapInfo:                 wrapInfo,
+		ClientTokenRemainingUses: int(r.ClientTokenRemainingUses),
+		EntityID:                 r.EntityID,
+		ControlGroupID:           r.ControlGroupID,
+		Connection:               connection,
+	}, nil
+}
+
+// LogicalResponseToProtoResponse converts a logical.Response into a Response
+func LogicalResponseToProtoResponse(r *logical.Response) (*Response, error) {
+	if r == nil {
+		return nil, nil
+	}
+
+	data, err := encodeData(r.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode response data: %v", err)
+	}
+	secret, err := LogicalSecretToProtoSecret(r.Secret)
+	if err != nil {
+		return nil, err
+	}
+	auth, err := LogicalAuthToProtoAuth(r.Auth)
+	if err != nil {
+		return nil, err
+	}
+	wrapInfo, err := LogicalResponseWrapInfoToProtoResponseWrapInfo(r.WrapInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	var cacheControl *CacheControl
+	if r.CacheControl != nil {
+		cacheControl = &CacheControl{
+			MaxAge:      int64(r.CacheControl.MaxAge),
+			Public:      r.CacheControl.Public,
+			ETag:        r.CacheControl.ETag,
+			NotModified: r.CacheControl.NotModified,
+		}
+	}
+
+	return &Response{
+		Secret:       secret,
+		Auth:         auth,
+		Data:         data,
+		Redirect:     r.Redirect,
+		Warnings:     r.Warnings,
+		WrapInfo:     wrapInfo,
+		CacheControl: cacheControl,
+	}, nil
+}
+
+// ProtoResponseToLogicalResponse converts a Response into a logical.Response
+func ProtoResponseToLogicalResponse(r *Response) (*logical.Response, error) {
+	if r == nil {
+		return nil, nil
+	}
+
+	data, err := decodeData(r.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode response data: %v", err)
+	}
+	if err := restoreRawResponseData(data); err != nil {
+		return nil, err
+	}
+	secret, err := ProtoSecretToLogicalSecret(r.Secret)
+	if err != nil {
+		return nil, err
+	}
+	auth, err := ProtoAuthToLogicalAuth(r.Auth)
+	if err != nil {
+		return nil, err
+	}
+	wrapInfo, err := ProtoResponseWrapInfoToLogicalResponseWrapInfo(r.WrapInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	var cacheControl *logical.CacheControl
+	if r.CacheControl != nil {
+		cacheControl = &logical.CacheControl{
+			MaxAge:      time.Duration(r.CacheControl.MaxAge),
+			Public:      r.CacheControl.Public,
+			ETag:        r.CacheControl.ETag,
+			NotModified: r.CacheControl.NotModified,
+		}
+	}
+
+	return &logical.Response{
+		Secret:       secret,
+		Auth:         auth,
+		Data:         data,
+		Redirect:     r.Redirect,
+		Warnings:     r.Warnings,
+		WrapInfo:     wrapInfo,
+		CacheControl: cacheControl,
+	}, nil
+}
+
+// restoreRawResponseData restores the types of the values of raw HTTP
+// responses, which the HTTP layer expects but are lost in JSON
+func restoreRawResponseData(data map[string]interface{}) error {
+	if status, ok := data[logical.HTTPStatusCode].(json.Number); ok {
+		code, err := status.Int64()
+		if err != nil {
+			return fmt.Errorf("failed to decode status code: %v", err)
+		}
+		data[logical.HTTPStatusCode] = int(code)
+	}
+
+	if body, ok := data[logical.HTTPRawBody].(string); ok {
+		raw, err := base64.StdEncoding.DecodeString(body)
+		if err != nil {
+			return fmt.Errorf("failed to decode raw body: %v", err)
+		}
+		data[logical.HTTPRawBody] = raw
+	}
+
+	if headersRaw, ok := data[logical.HTTPRawHeaders].(map[string]interface{}); ok {
+		headers := make(map[string][]string, len(headersRaw))
+		for k, v := range headersRaw {
+			values, _ := v.([]interface{})
+			for _, value := range values {
+				headers[k] = append(headers[k], fmt.Sprint(value))
+			}
+		}
+		data[logical.HTTPRawHeaders] = headers
+	}
+
+	return nil
+}
+
+// LogicalLeaseOptionsToProtoLeaseOptions converts logical.LeaseOptions into
+// LeaseOptions
+func LogicalLeaseOptionsToProtoLeaseOptions(l logical.LeaseOptions) (*LeaseOptions, error) {
+	issueTime, err := timeToProto(l.IssueTime)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LeaseOptions{
+		TTL:       int64(l.TTL),
+		Renewable: l.Renewable,
+		Increment: int64(l.Increment),
+		IssueTime: issueTime,
+	}, nil
+}
+
+// ProtoLeaseOptionsToLogicalLeaseOptions converts LeaseOptions into
+// logical.LeaseOptions
+func ProtoLeaseOptionsToLogicalLeaseOptions(l *LeaseOptions) (logical.LeaseOptions, error) {
+	if l == nil {
+		return logical.LeaseOptions{}, nil
+	}
+
+	issueTime, err := protoToTime(l.IssueTime)
+	if err != nil {
+		return logical.LeaseOptions{}, err
+	}
+
+	return logical.LeaseOptions{
+		TTL:       time.Duration(l.TTL),
+		Renewable: l.Renewable,
+		Increment: time.Duration(l.Increment),
+		IssueTime: issueTime,
+	}, nil
+}
+
+// LogicalSecretToProtoSecret converts a logical.Secret into a Secret
+func LogicalSecretToProtoSecret(s *logical.Secret) (*Secret, error) {
+	if s == nil {
+		return nil, nil
+	}
+
+	internalData, err := encodeData(s.InternalData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode secret internal data: %v", err)
+	}
+	leaseOptions, err := LogicalLeaseOptionsToProtoLeaseOptions(s.LeaseOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Secret{
+		LeaseOptions: leaseOptions,
+		InternalData: internalData,
+		LeaseID:      s.LeaseID,
+		Artifacts:    s.Artifacts,
+	}, nil
+}
+
+// ProtoSecretToLogicalSecret converts a Secret into a logical.Secret
+func ProtoSecretToLogicalSecret(s *Secret) (*logical.Secret, error) {
+	if s == nil {
+		return nil, nil
+	}
+
+	internalData, err := decodeData(s.InternalData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode secret internal data: %v", err)
+	}
+	leaseOptions, err := ProtoLeaseOptionsToLogicalLeaseOptions(s.LeaseOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Secret{
+		LeaseOptions: leaseOptions,
+		InternalData: internalData,
+		LeaseID:      s.LeaseID,
+		Artifacts:    s.Artifacts,
+	}, nil
+}
+
+func logicalAliasToProtoAlias(a *logical.Alias) *Alias {
+	if a == nil {
+		return nil
+	}
+	return &Alias{
+		MountType:     a.MountType,
+		MountAccessor: a.MountAccessor,
+		Name:          a.Name,
+		Metadata:      a.Metadata,
+	}
+}
+
+func protoAliasToLogicalAlias(a *Alias) *logical.Alias {
+	if a == nil {
+		return nil
+	}
+	return &logical.Alias{
+		MountType:     a.MountType,
+		MountAccessor: a.MountAccessor,
+		Name:          a.Name,
+		Metadata:      a.Metadata,
+	}
+}
+
+// LogicalAuthToProtoAuth converts a logical.Auth into an Auth
+func LogicalAuthToProtoAuth(a *logical.Auth) (*Auth, error) {
+	if a == nil {
+		return nil, nil
+	}
+
+	internalData, err := encodeData(a.InternalData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode auth internal data: %v", err)
+	}
+	leaseOptions, err := LogicalLeaseOptionsToProtoLeaseOptions(a.LeaseOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	var groupAliases []*Alias
+	for _, alias := range a.GroupAliases {
+		groupAliases = append(groupAliases, logicalAliasToProtoAlias(alias))
+	}
+
+	return &Auth{
+		LeaseOptions: leaseOptions,
+		InternalData: internalData,
+		DisplayName:  a.DisplayName,
+		Policies:     a.Policies,
+		Metadata:     a.Metadata,
+		ClientToken:  a.ClientToken,
+		Accessor:     a.Accessor,
+		Period:       int64(a.Period),
+		NumUses:      int64(a.NumUses),
+		EntityID:     a.EntityID,
+		Alias:        logicalAliasToProtoAlias(a.Alias),
+		GroupAliases: groupAliases,
+		BoundCidrs:   a.BoundCIDRs,
+	}, nil
+}
+
+// ProtoAuthToLogicalAuth converts an Auth into a logical.Auth
+func ProtoAuthToLogicalAuth(a *Auth) (*logical.Auth, error) {
+	if a == nil {
+		return nil, nil
+	}
+
+	internalData, err := decodeData(a.InternalData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode auth internal data: %v", err)
+	}
+	leaseOptions, err := ProtoLeaseOptionsToLogicalLeaseOptions(a.LeaseOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	var groupAliases []*logical.Alias
+	for _, alias := range a.GroupAliases {
+		groupAliases = append(groupAliases, protoAliasToLogicalAlias(alias))
+	}
+
+	return &logical.Auth{
+		LeaseOptions: leaseOptions,
+		InternalData: internalData,
+		DisplayName:  a.DisplayName,
+		Policies:     a.Policies,
+		Metadata:     a.Metadata,
+		ClientToken:  a.ClientToken,
+		Accessor:     a.Accessor,
+		Period:       time.Duration(a.Period),
+		NumUses:      int(a.NumUses),
+		EntityID:     a.EntityID,
+		Alias:        protoAliasToLogicalAlias(a.Alias),
+		GroupAliases: groupAliases,
+		BoundCIDRs:   a.BoundCidrs,
+	}, nil
+}
+
+// LogicalResponseWrapInfoToProtoResponseWrapInfo converts a
+// wrapping.ResponseWrapInfo into a ResponseWrapInfo
+func LogicalResponseWrapInfoToProtoResponseWrapInfo(w *wrapping.ResponseWrapInfo) (*ResponseWrapInfo, error) {
+	if w == nil {
+		return nil, nil
+	}
+
+	creationTime, err := timeToProto(w.CreationTime)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ResponseWrapInfo{
+		TTL:             int64(w.TTL),
+		Token:           w.Token,
+		CreationTime:    creationTime,
+		WrappedAccessor: w.WrappedAccessor,
+		WrappedEntityID: w.WrappedEntityID,
+		Format:          w.Format,
+		CreationPath:    w.CreationPath,
+	}, nil
+}
+
+// ProtoResponseWrapInfoToLogicalResponseWrapInfo converts a ResponseWrapInfo
+// into a wrapping.ResponseWrapInfo
+func ProtoResponseWrapInfoToLogicalResponseWrapInfo(w *ResponseWrapInfo) (*wrapping.ResponseWrapInfo, error) {
+	if w == nil {
+		return nil, nil
+	}
+
+	creationTime, err := protoToTime(w.CreationTime)
+	if err != nil {
+		return nil, err
+	}
+
+	return &wrapping.ResponseWrapInfo{
+		TTL:             time.Duration(w.TTL),
+		Token:           w.Token,
+		CreationTime:    creationTime,
+		WrappedAccessor: w.WrappedAccessor,
+		WrappedEntityID: w.WrappedEntityID,
+		Format:          w.Format,
+		CreationPath:    w.CreationPath,
+	}, nil
+}
+
+// LogicalStorageEntryToProtoStorageEntry converts a logical.StorageEntry into
+// a StorageEntry
+func LogicalStorageEntryToProtoStorageEntry(e *logical.StorageEntry) *StorageEntry {
+	if e == nil {
+		return nil
+	}
+	return &StorageEntry{
+		Key:      e.Key,
+		Value:    e.Value,
+		SealWrap: e.SealWrap,
+	}
+}
+
+// ProtoStorageEntryToLogicalStorageEntry converts a StorageEntry into a
+// logical.StorageEntry
+func ProtoStorageEntryToLogicalStorageEntry(e *StorageEntry) *logical.StorageEntry {
+	if e == nil {
+		return nil
+	}
+	return &logical.StorageEntry{
+		Key:      e.Key,
+		Value:    e.Value,
+		SealWrap: e.SealWrap,
+	}
+}
+
+// LogicalPathsToProtoPaths converts logical.Paths into Paths
+func LogicalPathsToProtoPaths(p *logical.Paths) *Paths {
+	if p == nil {
+		return nil
+	}
+	return &Paths{
+		Root:              p.Root,
+		Unauthenticated:   p.Unauthenticated,
+		LocalStorage:      p.LocalStorage,
+		ExportableStorage: p.ExportableStorage,
+		SealWrapStorage:   p.SealWrapStorage,
+	}
+}
+
+// ProtoPathsToLogicalPaths converts Paths into logical.Paths
+func ProtoPathsToLogicalPaths(p *Paths) *logical.Paths {
+	if p == nil {
+		return nil
+	}
+	return &logical.Paths{
+		Root:              p.Root,
+		Unauthenticated:   p.Unauthenticated,
+		LocalStorage:      p.LocalStorage,
+		ExportableStorage: p.ExportableStorage,
+		SealWrapStorage:   p.SealWrapStorage,
+	}
+}
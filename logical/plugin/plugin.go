@@ -5,6 +5,7 @@ import (
 	"crypto/rsa"
 	"encoding/gob"
 	"fmt"
+	"strings"
 	"time"
 
 	"sync"
@@ -30,30 +31,62 @@ func init() {
 	gob.Register(time.Duration(0))
 }
 
-// BackendPluginClient is a wrapper around backendPluginClient
-// that also contains its plugin.Client instance. It's primarily
+// BackendPluginClient is a wrapper around the net/rpc or gRPC client of
+// the backend that also contains its plugin.Client instance. It's primarily
 // used to cleanly kill the client on Cleanup()
 type BackendPluginClient struct {
-	client *plugin.Client
+	client   *plugin.Client
+	protocol plugin.ClientProtocol
 	sync.Mutex
 
-	*backendPluginClient
+	// multiplexed is set when the plugin process is shared with the
+	// backends of other mounts
+	multiplexed *multiplexedPlugin
+
+	logical.Backend
 }
 
 // Cleanup calls the RPC client's Cleanup() func and also calls
-// the go-plugin's client Kill() func
+// the go-plugin's client Kill() func, unless the plugin process still
+// serves the backends of other mounts
 func (b *BackendPluginClient) Cleanup() {
-	b.backendPluginClient.Cleanup()
+	b.Backend.Cleanup()
+	if b.multiplexed != nil {
+		b.multiplexed.release()
+		return
+	}
 	b.client.Kill()
 }
 
+// Ping checks that the plugin process is running and answers
+func (b *BackendPluginClient) Ping() error {
+	return pluginutil.PingPlugin(b.client, b.protocol)
+}
+
 // NewBackend will return an instance of an RPC-based client implementation of the backend for
 // external plugins, or a concrete implementation of the backend if it is a builtin backend.
 // The backend is returned as a logical.Backend interface. The isMetadataMode param determines whether
 // the plugin should run in metadata mode.
 func NewBackend(pluginName string, sys pluginutil.LookRunnerUtil, logger log.Logger, isMetadataMode bool) (logical.Backend, error) {
+	return NewBackendVersion(pluginName, "", sys, logger, isMetadataMode)
+}
+
+// NewBackendVersion is like NewBackend, but runs the given version of the
+// plugin, as registered in the plugin catalog. An empty version runs the
+// plugin looked up by NewBackend.
+func NewBackendVersion(pluginName, pluginVersion string, sys pluginutil.LookRunnerUtil, logger log.Logger, isMetadataMode bool) (logical.Backend, error) {
 	// Look for plugin in the plugin catalog
-	pluginRunner, err := sys.LookupPlugin(pluginName)
+	var pluginRunner *pluginutil.PluginRunner
+	var err error
+	if pluginVersion == "" {
+		pluginRunner, err = sys.LookupPlugin(pluginName)
+	} else {
+		versionLooker, ok := sys.(pluginutil.VersionLooker)
+		if !ok {
+			return nil, fmt.Errorf("plugin versions are not supported by the system view")
+		}
+		pluginRunner, err = versionLooker.LookupPluginVersion(pluginName, pluginVersion)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -85,6 +118,19 @@ func NewBackend(pluginName string, sys pluginutil.LookRunnerUtil, logger log.Log
 }
 
 func newPluginClient(sys pluginutil.RunnerUtil, pluginRunner *pluginutil.PluginRunner, logger log.Logger, isMetadataMode bool) (logical.Backend, error) {
+	// Serve the backend from the running process of the plugin if it is
+	// multiplexed. Plugins in metadata mode always run in a process of
+	// their own.
+	if !isMetadataMode {
+		backend, err := dispenseMultiplexed(pluginRunner)
+		if err != nil {
+			return nil, err
+		}
+		if backend != nil {
+			return backend, nil
+		}
+	}
+
 	// pluginMap is the map of plugins we can dispense.
 	pluginMap := map[string]plugin.Plugin{
 		"backend": &BackendPlugin{
@@ -92,19 +138,22 @@ func newPluginClient(sys pluginutil.RunnerUtil, pluginRunner *pluginutil.PluginR
 		},
 	}
 
-	var client *plugin.Client
-	var err error
-	if isMetadataMode {
-		client, err = pluginRunner.RunMetadataMode(sys, pluginMap, handshakeConfig, []string{}, logger)
-	} else {
-		client, err = pluginRunner.Run(sys, pluginMap, handshakeConfig, []string{}, logger)
-	}
-	if err != nil {
-		return nil, err
+	// Advertise the supported protocol versions, so that the plugins built
+	// with gRPC support serve the backend over gRPC
+	env := []string{
+		fmt.Sprintf("%s=%d,%d", PluginProtocolVersionsEnv, netRPCProtocolVersion, grpcProtocolVersion),
 	}
 
-	// Connect via RPC
-	rpcClient, err := client.Client()
+	hs := handshakeConfig
+	hs.ProtocolVersion = grpcProtocolVersion
+	client, rpcClient, err := startPluginClient(sys, pluginRunner, pluginMap, hs, env, logger, isMetadataMode, plugin.ProtocolGRPC)
+	if err != nil && strings.Contains(err.Error(), "Incompatible API version") {
+		// The plugin predates the gRPC protocol, run it again over net/rpc
+		logger.Warn("plugin: plugin does not support the gRPC protocol, falling back to net/rpc; rebuild it to receive the contexts of the requests", "plugin", pluginRunner.Name)
+
+		hs.ProtocolVersion = netRPCProtocolVersion
+		client, rpcClient, err = startPluginClient(sys, pluginRunner, pluginMap, hs, env, logger, isMetadataMode, plugin.ProtocolNetRPC)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -112,15 +161,50 @@ func newPluginClient(sys pluginutil.RunnerUtil, pluginRunner *pluginutil.PluginR
 	// Request the plugin
 	raw, err := rpcClient.Dispense("backend")
 	if err != nil {
+		client.Kill()
 		return nil, err
 	}
 
 	// We should have a logical backend type now. This feels like a normal interface
 	// implementation but is in fact over an RPC connection.
-	backendRPC := raw.(*backendPluginClient)
+	backend, ok := raw.(logical.Backend)
+	if !ok {
+		client.Kill()
+		return nil, fmt.Errorf("unsupported plugin client type: %T", raw)
+	}
+
+	// Share the process with the next mounts of the plugin if it supports
+	// multiplexing
+	if grpcBackend, ok := backend.(*backendGRPCPluginClient); ok && !isMetadataMode && grpcBackend.multiplexingSupport() {
+		backend, err := registerMultiplexed(pluginRunner, client, rpcClient, grpcBackend)
+		if err != nil {
+			client.Kill()
+			return nil, err
+		}
+		return backend, nil
+	}
 
 	return &BackendPluginClient{
-		client:              client,
-		backendPluginClient: backendRPC,
+		client:   client,
+		protocol: rpcClient,
+		Backend:  backend,
 	}, nil
 }
+
+// startPluginClient runs the plugin and connects to it with the given
+// protocol
+func startPluginClient(sys pluginutil.RunnerUtil, pluginRunner *pluginutil.PluginRunner, pluginMap map[string]plugin.Plugin, hs plugin.HandshakeConfig, env []string, logger log.Logger, isMetadataMode bool, protocol plugin.Protocol) (*plugin.Client, plugin.ClientProtocol, error) {
+	client, err := pluginRunner.RunProtocols(sys, pluginMap, hs, env, logger, isMetadataMode, []plugin.Protocol{protocol})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Connect via RPC
+	rpcClient, err := client.Client()
+	if err != nil {
+		client.Kill()
+		return nil, nil, err
+	}
+
+	return client, rpcClient, nil
+}
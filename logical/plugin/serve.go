@@ -2,6 +2,9 @@ package plugin
 
 import (
 	"crypto/tls"
+	"os"
+	"strconv"
+	"strings"
 
 	"github.com/hashicorp/go-plugin"
 	"github.com/hashicorp/vault/helper/pluginutil"
@@ -23,10 +26,23 @@ type ServeOpts struct {
 // Serve is a helper function used to serve a backend plugin. This
 // should be ran on the plugin's main process.
 func Serve(opts *ServeOpts) error {
+	return serve(opts, false)
+}
+
+// ServeMultiplex is like Serve, but lets Vault serve all the mounts of the
+// plugin from a single process, each with its own instance of the backend.
+// The backend factory must therefore not rely on state shared between the
+// instances, such as package level variables.
+func ServeMultiplex(opts *ServeOpts) error {
+	return serve(opts, true)
+}
+
+func serve(opts *ServeOpts, multiplexing bool) error {
 	// pluginMap is the map of plugins we can dispense.
 	var pluginMap = map[string]plugin.Plugin{
 		"backend": &BackendPlugin{
-			Factory: opts.BackendFactoryFunc,
+			Factory:      opts.BackendFactoryFunc,
+			multiplexing: multiplexing,
 		},
 	}
 
@@ -35,22 +51,56 @@ func Serve(opts *ServeOpts) error {
 		return err
 	}
 
-	// If FetchMetadata is true, run without TLSProvider
-	plugin.Serve(&plugin.ServeConfig{
+	serveConfig := &plugin.ServeConfig{
 		HandshakeConfig: handshakeConfig,
 		Plugins:         pluginMap,
 		TLSProvider:     opts.TLSProviderFunc,
-	})
+	}
+
+	// Serve the plugin over gRPC if Vault supports it, and over net/rpc for
+	// the versions of Vault which predate the gRPC protocol
+	if hostSupportsProtocol(grpcProtocolVersion) {
+		serveConfig.HandshakeConfig.ProtocolVersion = grpcProtocolVersion
+		serveConfig.GRPCServer = plugin.DefaultGRPCServer
+	}
+
+	// If FetchMetadata is true, run without TLSProvider
+	plugin.Serve(serveConfig)
 
 	return nil
 }
 
+// hostSupportsProtocol returns whether Vault advertised the given protocol
+// version when running the plugin
+func hostSupportsProtocol(version uint) bool {
+	for _, v := range strings.Split(os.Getenv(PluginProtocolVersionsEnv), ",") {
+		if n, err := strconv.ParseUint(strings.TrimSpace(v), 10, 0); err == nil && uint(n) == version {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	// netRPCProtocolVersion is the version of the protocol of the plugins
+	// served over net/rpc
+	netRPCProtocolVersion = 3
+
+	// grpcProtocolVersion is the version of the protocol of the plugins
+	// served over gRPC, which receive the contexts of the requests
+	grpcProtocolVersion = 4
+
+	// PluginProtocolVersionsEnv lists the protocol versions supported by
+	// Vault, so that the plugins can negotiate the newest one they support
+	PluginProtocolVersionsEnv = "VAULT_BACKEND_PLUGIN_PROTOCOL_VERSIONS"
+)
+
 // handshakeConfigs are used to just do a basic handshake between
 // a plugin and host. If the handshake fails, a user friendly error is shown.
 // This prevents users from executing bad plugins or executing a plugin
 // directory. It is a UX feature, not a security feature.
 var handshakeConfig = plugin.HandshakeConfig{
-	ProtocolVersion:  3,
+	ProtocolVersion:  netRPCProtocolVersion,
 	MagicCookieKey:   "VAULT_BACKEND_PLUGIN",
 	MagicCookieValue: "6669da05-b1c8-4f49-97d9-c8e5bed98e20",
 }
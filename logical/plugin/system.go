@@ -8,6 +8,7 @@ import (
 
 	plugin "github.com/hashicorp/go-plugin"
 	"github.com/hashicorp/vault/helper/consts"
+	"github.com/hashicorp/vault/helper/passwordpolicy"
 	"github.com/hashicorp/vault/helper/pluginutil"
 	"github.com/hashicorp/vault/helper/wrapping"
 	"github.com/hashicorp/vault/logical"
@@ -119,6 +120,40 @@ func (s *SystemViewClient) MlockEnabled() bool {
 	return reply.MlockEnabled
 }
 
+func (s *SystemViewClient) PasswordPolicy(name string) (*passwordpolicy.Policy, error) {
+	var reply PasswordPolicyReply
+	args := &PasswordPolicyArgs{
+		Name: name,
+	}
+
+	err := s.client.Call("Plugin.PasswordPolicy", args, &reply)
+	if err != nil {
+		return nil, err
+	}
+	if reply.Error != nil {
+		return nil, reply.Error
+	}
+
+	return reply.Policy, nil
+}
+
+func (s *SystemViewClient) EntityInfo(entityID string) (*logical.Entity, error) {
+	var reply EntityInfoReply
+	args := &EntityInfoArgs{
+		EntityID: entityID,
+	}
+
+	err := s.client.Call("Plugin.EntityInfo", args, &reply)
+	if err != nil {
+		return nil, err
+	}
+	if reply.Error != nil {
+		return nil, reply.Error
+	}
+
+	return reply.Entity, nil
+}
+
 type SystemViewServer struct {
 	impl logical.SystemView
 }
@@ -202,6 +237,36 @@ func (s *SystemViewServer) MlockEnabled(_ interface{}, reply *MlockEnabledReply)
 	return nil
 }
 
+func (s *SystemViewServer) PasswordPolicy(args *PasswordPolicyArgs, reply *PasswordPolicyReply) error {
+	policy, err := s.impl.PasswordPolicy(args.Name)
+	if err != nil {
+		*reply = PasswordPolicyReply{
+			Error: plugin.NewBasicError(err),
+		}
+		return nil
+	}
+	*reply = PasswordPolicyReply{
+		Policy: policy,
+	}
+
+	return nil
+}
+
+func (s *SystemViewServer) EntityInfo(args *EntityInfoArgs, reply *EntityInfoReply) error {
+	entity, err := s.impl.EntityInfo(args.EntityID)
+	if err != nil {
+		*reply = EntityInfoReply{
+			Error: plugin.NewBasicError(err),
+		}
+		return nil
+	}
+	*reply = EntityInfoReply{
+		Entity: entity,
+	}
+
+	return nil
+}
+
 type DefaultLeaseTTLReply struct {
 	DefaultLeaseTTL time.Duration
 }
@@ -245,3 +310,21 @@ type ResponseWrapDataReply struct {
 type MlockEnabledReply struct {
 	MlockEnabled bool
 }
+
+type PasswordPolicyArgs struct {
+	Name string
+}
+
+type PasswordPolicyReply struct {
+	Policy *passwordpolicy.Policy
+	Error  *plugin.BasicError
+}
+
+type EntityInfoArgs struct {
+	EntityID string
+}
+
+type EntityInfoReply struct {
+	Entity *logical.Entity
+	Error  *plugin.BasicError
+}
@@ -7,6 +7,7 @@ import (
 
 	plugin "github.com/hashicorp/go-plugin"
 	"github.com/hashicorp/vault/helper/consts"
+	"github.com/hashicorp/vault/helper/passwordpolicy"
 	"github.com/hashicorp/vault/logical"
 )
 
@@ -172,3 +173,74 @@ func TestSystem_mlockEnabled(t *testing.T) {
 		t.Fatalf("expected: %v, got: %v", expected, actual)
 	}
 }
+
+func TestSystem_passwordPolicy(t *testing.T) {
+	client, server := plugin.TestRPCConn(t)
+	defer client.Close()
+
+	sys := logical.TestSystemView()
+	sys.PasswordPolicies = map[string]*passwordpolicy.Policy{
+		"foo": &passwordpolicy.Policy{
+			Name:            "foo",
+			Length:          12,
+			MinDigits:       2,
+			BannedPasswords: []string{"password1234"},
+			History:         3,
+		},
+	}
+
+	server.RegisterName("Plugin", &SystemViewServer{
+		impl: sys,
+	})
+
+	testSystemView := &SystemViewClient{client: client}
+
+	for _, name := range []string{"foo", "bar"} {
+		expected, _ := sys.PasswordPolicy(name)
+		actual, err := testSystemView.PasswordPolicy(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(expected, actual) {
+			t.Fatalf("expected: %v, got: %v", expected, actual)
+		}
+	}
+}
+
+func TestSystem_entityInfo(t *testing.T) {
+	client, server := plugin.TestRPCConn(t)
+	defer client.Close()
+
+	sys := logical.TestSystemView()
+	sys.Entities = map[string]*logical.Entity{
+		"entity-id": &logical.Entity{
+			ID:   "entity-id",
+			Name: "alice",
+			Aliases: []*logical.Alias{
+				&logical.Alias{
+					MountType:     "userpass",
+					MountAccessor: "auth_userpass_1234",
+					Name:          "alice",
+				},
+			},
+			Metadata: map[string]string{"team": "payments"},
+		},
+	}
+
+	server.RegisterName("Plugin", &SystemViewServer{
+		impl: sys,
+	})
+
+	testSystemView := &SystemViewClient{client: client}
+
+	for _, id := range []string{"entity-id", "missing"} {
+		expected, _ := sys.EntityInfo(id)
+		actual, err := testSystemView.EntityInfo(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(expected, actual) {
+			t.Fatalf("expected: %v, got: %v", expected, actual)
+		}
+	}
+}
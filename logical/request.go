@@ -1,6 +1,7 @@
 package logical
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"time"
@@ -38,6 +39,12 @@ type Request struct {
 	// final path is "foo" since the mount prefix is trimmed.
 	Path string `json:"path" structs:"path" mapstructure:"path"`
 
+	// Namespace is the path of the namespace the request targets. It is set
+	// from the namespace header, and once the request is routed it is the
+	// path of the deepest namespace prefixing the request path, which is
+	// then relative to it. It is empty for the root namespace.
+	Namespace string `json:"namespace" structs:"namespace" mapstructure:"namespace"`
+
 	// Request data is an opaque map that must have string keys.
 	Data map[string]interface{} `json:"map" structs:"data" mapstructure:"data"`
 
@@ -102,9 +109,17 @@ type Request struct {
 	// to make this request
 	EntityID string `json:"entity_id" structs:"entity_id" mapstructure:"entity_id"`
 
+	// ControlGroupID is the ID of a request parked by a control group, given
+	// when sending the request again once it has been authorized
+	ControlGroupID string `json:"control_group_id" structs:"control_group_id" mapstructure:"control_group_id"`
+
 	// For replication, contains the last WAL on the remote side after handling
 	// the request, used for best-effort avoidance of stale read-after-write
 	lastRemoteWAL uint64
+
+	// ctx is canceled when the client goes away or an operator cancels the
+	// request
+	ctx context.Context
 }
 
 // Get returns a data field and guards for nil Data
@@ -126,6 +141,20 @@ func (r *Request) GoString() string {
 	return fmt.Sprintf("*%#v", *r)
 }
 
+// Context returns the context of the request. Backends making long or
+// blocking calls should abort them once it is done.
+func (r *Request) Context() context.Context {
+	if r.ctx == nil {
+		return context.Background()
+	}
+	return r.ctx
+}
+
+// SetContext sets the context of the request
+func (r *Request) SetContext(ctx context.Context) {
+	r.ctx = ctx
+}
+
 func (r *Request) LastRemoteWAL() uint64 {
 	return r.lastRemoteWAL
 }
@@ -184,7 +213,9 @@ const (
 	// The operations below are called per path
 	CreateOperation         Operation = "create"
 	ReadOperation                     = "read"
+	HeaderOperation                   = "header"
 	UpdateOperation                   = "update"
+	PatchOperation                    = "patch"
 	DeleteOperation                   = "delete"
 	ListOperation                     = "list"
 	HelpOperation                     = "help"
@@ -210,4 +241,16 @@ var (
 
 	// ErrPermissionDenied is returned if the client is not authorized
 	ErrPermissionDenied = errors.New("permission denied")
+
+	// ErrLeaseCountQuotaExceeded is returned if issuing a lease would exceed
+	// a lease count quota
+	ErrLeaseCountQuotaExceeded = errors.New("lease count quota exceeded")
+
+	// ErrRateLimitQuotaExceeded is returned if a request exceeds a rate
+	// limit quota
+	ErrRateLimitQuotaExceeded = errors.New("rate limit quota exceeded")
+
+	// ErrRequestCanceled is returned if a request was canceled before it
+	// completed
+	ErrRequestCanceled = errors.New("request canceled")
 )
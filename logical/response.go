@@ -2,6 +2,7 @@ package logical
 
 import (
 	"errors"
+	"time"
 
 	"github.com/hashicorp/vault/helper/wrapping"
 )
@@ -23,6 +24,12 @@ const (
 	// This can only be specified for non-secrets, and should should be similarly
 	// avoided like the HTTPContentType. The value must be an integer.
 	HTTPStatusCode = "http_status_code"
+
+	// HTTPRawHeaders are additional headers of the response that goes with the
+	// HTTPContentType, such as those required by a specification. This should
+	// be similarly avoided like the HTTPContentType. The value must be a
+	// map[string][]string.
+	HTTPRawHeaders = "http_raw_headers"
 )
 
 // Response is a struct that stores the response of a request.
@@ -53,6 +60,42 @@ type Response struct {
 
 	// Information for wrapping the response in a cubbyhole
 	WrapInfo *wrapping.ResponseWrapInfo `json:"wrap_info" structs:"wrap_info" mapstructure:"wrap_info"`
+
+	// CacheControl, if not nil, allows clients to cache the response and
+	// revalidate it with a conditional request. It is ignored for responses
+	// that carry a lease, auth information, or a wrapping token.
+	CacheControl *CacheControl `json:"cache_control" structs:"cache_control" mapstructure:"cache_control"`
+}
+
+// CacheControl describes how clients may cache a response
+type CacheControl struct {
+	// MaxAge is how long clients may reuse the response without checking
+	// with Vault. If zero, clients must revalidate the response before each
+	// use.
+	MaxAge time.Duration `json:"max_age" structs:"max_age" mapstructure:"max_age"`
+
+	// Public allows shared caches such as proxies to store the response.
+	// Otherwise only the requesting client may cache it.
+	Public bool `json:"public" structs:"public" mapstructure:"public"`
+
+	// ETag, if set, identifies the response instead of a hash of its data,
+	// such as the version of a key
+	ETag string `json:"etag" structs:"etag" mapstructure:"etag"`
+
+	// NotModified indicates that the client already holds the current
+	// response. It is answered with a 304 and no body.
+	NotModified bool `json:"not_modified" structs:"not_modified" mapstructure:"not_modified"`
+}
+
+// Cacheable returns whether the response may be cached by clients
+func (r *Response) Cacheable() bool {
+	if r == nil || r.CacheControl == nil || r.IsError() {
+		return false
+	}
+	if r.Auth != nil || r.WrapInfo != nil || r.Redirect != "" {
+		return false
+	}
+	return r.Secret == nil || r.Secret.LeaseID == ""
 }
 
 // AddWarning adds a warning into the response's warning list
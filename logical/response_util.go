@@ -8,6 +8,7 @@ import (
 	"github.com/hashicorp/errwrap"
 	multierror "github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/vault/helper/consts"
+	"github.com/hashicorp/vault/physical"
 )
 
 // RespondErrorCommon pulls most of the functionality from http's
@@ -72,6 +73,8 @@ func RespondErrorCommon(req *Request, resp *Response, err error) (int, error) {
 		switch {
 		case errwrap.ContainsType(err, new(StatusBadRequest)):
 			statusCode = http.StatusBadRequest
+		case errwrap.ContainsType(err, new(FieldValidationError)):
+			statusCode = http.StatusBadRequest
 		case errwrap.Contains(err, ErrPermissionDenied.Error()):
 			statusCode = http.StatusForbidden
 		case errwrap.Contains(err, ErrUnsupportedOperation.Error()):
@@ -80,7 +83,19 @@ func RespondErrorCommon(req *Request, resp *Response, err error) (int, error) {
 			statusCode = http.StatusNotFound
 		case errwrap.Contains(err, ErrInvalidRequest.Error()):
 			statusCode = http.StatusBadRequest
+		case errwrap.Contains(err, ErrLeaseCountQuotaExceeded.Error()):
+			statusCode = http.StatusTooManyRequests
+		case errwrap.ContainsType(err, new(RateLimitQuotaError)):
+			statusCode = http.StatusTooManyRequests
 		}
+
+		// Errors carrying their own status code keep it when wrapped, such
+		// as the coded errors returned by the backends
+		errwrap.Walk(err, func(inErr error) {
+			if coded, ok := inErr.(HTTPCodedError); ok {
+				statusCode = coded.Code()
+			}
+		})
 	}
 
 	if resp != nil && resp.IsError() {
@@ -104,6 +119,12 @@ func AdjustErrorStatusCode(status *int, err error) {
 		*status = http.StatusRequestEntityTooLarge
 	}
 
+	// Adjust status code on entries rejected by the maximum entry size of the
+	// storage
+	if errwrap.ContainsType(err, new(physical.EntryTooLargeError)) {
+		*status = http.StatusRequestEntityTooLarge
+	}
+
 	// Allow HTTPCoded error passthrough to specify a code
 	if t, ok := err.(HTTPCodedError); ok {
 		*status = t.Code()
@@ -1,6 +1,9 @@
 package logical
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 // Secret represents the secret part of a response.
 type Secret struct {
@@ -11,10 +14,20 @@ type Secret struct {
 	// used for those operations.
 	InternalData map[string]interface{} `json:"internal_data"`
 
+	// Artifacts identify what was issued with the secret, such as the serial
+	// number of a certificate or a database username, keyed by the type of
+	// artifact. Vault indexes them so that the lease of a known artifact can
+	// be found with sys/leases/lookup-artifact.
+	Artifacts map[string]string `json:"artifacts"`
+
 	// LeaseID is the ID returned to the user to manage this secret.
 	// This is generated by Vault core. Any set value will be ignored.
 	// For requests, this will always be blank.
 	LeaseID string
+
+	// Contract contains rotation hints for consumers of the secret. This is
+	// generated by Vault core. Any set value will be ignored.
+	Contract *LeaseContract `json:"-"`
 }
 
 func (s *Secret) Validate() error {
@@ -22,6 +35,15 @@ func (s *Secret) Validate() error {
 		return fmt.Errorf("ttl duration must not be less than zero")
 	}
 
+	for artifactType, artifactID := range s.Artifacts {
+		if artifactType == "" || strings.Contains(artifactType, "/") {
+			return fmt.Errorf("invalid artifact type %q", artifactType)
+		}
+		if artifactID == "" {
+			return fmt.Errorf("artifact %q must not be empty", artifactType)
+		}
+	}
+
 	return nil
 }
 
@@ -25,6 +25,49 @@ type Storage interface {
 type StorageEntry struct {
 	Key   string
 	Value []byte
+
+	// SealWrap requests that the entry be seal-wrapped, encrypted by the
+	// seal in addition to the barrier
+	SealWrap bool
+}
+
+// TransactionalStorage is the optional interface of the storage able to put
+// and delete several entries atomically.
+type TransactionalStorage interface {
+	Storage
+	Transaction([]*TxnEntry) error
+}
+
+// TxnEntry is an operation of a storage transaction. The operation is either
+// UpdateOperation, putting the entry, or DeleteOperation, deleting its key.
+type TxnEntry struct {
+	Operation Operation
+	Entry     *StorageEntry
+}
+
+// StorageTransaction applies the operations atomically if the storage is
+// transactional. Otherwise they are applied in order, stopping at the first
+// error.
+func StorageTransaction(s Storage, txns []*TxnEntry) error {
+	if transactional, ok := s.(TransactionalStorage); ok {
+		return transactional.Transaction(txns)
+	}
+
+	for _, txn := range txns {
+		var err error
+		switch txn.Operation {
+		case UpdateOperation:
+			err = s.Put(txn.Entry)
+		case DeleteOperation:
+			err = s.Delete(txn.Entry.Key)
+		default:
+			err = fmt.Errorf("unsupported transaction operation %q", txn.Operation)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // DecodeJSON decodes the 'Value' present in StorageEntry.
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/hashicorp/vault/helper/consts"
+	"github.com/hashicorp/vault/helper/passwordpolicy"
 	"github.com/hashicorp/vault/helper/pluginutil"
 	"github.com/hashicorp/vault/helper/wrapping"
 )
@@ -52,6 +53,16 @@ type SystemView interface {
 	// MlockEnabled returns the configuration setting for enabling mlock on
 	// plugins.
 	MlockEnabled() bool
+
+	// PasswordPolicy returns the password policy of the given name, or nil if
+	// it doesn't exist. Backends validate the passwords given by users, and
+	// generate passwords, with it.
+	PasswordPolicy(name string) (*passwordpolicy.Policy, error)
+
+	// EntityInfo returns the identity entity of the given ID, or nil if it
+	// doesn't exist. Backends look up the entity of the requesting token,
+	// given in the EntityID of requests, with it.
+	EntityInfo(entityID string) (*Entity, error)
 }
 
 type StaticSystemView struct {
@@ -63,6 +74,8 @@ type StaticSystemView struct {
 	Primary             bool
 	EnableMlock         bool
 	ReplicationStateVal consts.ReplicationState
+	PasswordPolicies    map[string]*passwordpolicy.Policy
+	Entities            map[string]*Entity
 }
 
 func (d StaticSystemView) DefaultLeaseTTL() time.Duration {
@@ -100,3 +113,11 @@ func (d StaticSystemView) LookupPlugin(name string) (*pluginutil.PluginRunner, e
 func (d StaticSystemView) MlockEnabled() bool {
 	return d.EnableMlock
 }
+
+func (d StaticSystemView) PasswordPolicy(name string) (*passwordpolicy.Policy, error) {
+	return d.PasswordPolicies[name], nil
+}
+
+func (d StaticSystemView) EntityInfo(entityID string) (*Entity, error) {
+	return d.Entities[entityID], nil
+}
@@ -21,6 +21,13 @@ func LogicalResponseToHTTPResponse(input *Response) *HTTPResponse {
 		httpResp.LeaseID = input.Secret.LeaseID
 		httpResp.Renewable = input.Secret.Renewable
 		httpResp.LeaseDuration = int(input.Secret.TTL.Seconds())
+		if input.Secret.Contract != nil {
+			httpResp.LeaseContract = &HTTPLeaseContract{
+				EarliestRotation:     input.Secret.Contract.EarliestRotation.Format(time.RFC3339),
+				GuaranteedValidUntil: input.Secret.Contract.GuaranteedValidUntil.Format(time.RFC3339),
+				RenewEndpoint:        input.Secret.Contract.RenewEndpoint,
+			}
+		}
 	}
 
 	// If we have authentication information, then
@@ -52,6 +59,15 @@ func HTTPResponseToLogicalResponse(input *HTTPResponse) *Response {
 		}
 		logicalResp.Secret.Renewable = input.Renewable
 		logicalResp.Secret.TTL = time.Second * time.Duration(input.LeaseDuration)
+		if input.LeaseContract != nil {
+			earliestRotation, _ := time.Parse(time.RFC3339, input.LeaseContract.EarliestRotation)
+			guaranteedValidUntil, _ := time.Parse(time.RFC3339, input.LeaseContract.GuaranteedValidUntil)
+			logicalResp.Secret.Contract = &LeaseContract{
+				EarliestRotation:     earliestRotation,
+				GuaranteedValidUntil: guaranteedValidUntil,
+				RenewEndpoint:        input.LeaseContract.RenewEndpoint,
+			}
+		}
 	}
 
 	if input.Auth != nil {
@@ -78,6 +94,7 @@ type HTTPResponse struct {
 	WrapInfo      *HTTPWrapInfo          `json:"wrap_info"`
 	Warnings      []string               `json:"warnings"`
 	Auth          *HTTPAuth              `json:"auth"`
+	LeaseContract *HTTPLeaseContract     `json:"lease_contract,omitempty"`
 }
 
 type HTTPAuth struct {
@@ -90,6 +107,12 @@ type HTTPAuth struct {
 	EntityID      string            `json:"entity_id"`
 }
 
+type HTTPLeaseContract struct {
+	EarliestRotation     string `json:"earliest_rotation"`
+	GuaranteedValidUntil string `json:"guaranteed_valid_until"`
+	RenewEndpoint        string `json:"renew_endpoint,omitempty"`
+}
+
 type HTTPWrapInfo struct {
 	Token           string `json:"token"`
 	TTL             int    `json:"ttl"`
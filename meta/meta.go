@@ -3,7 +3,10 @@ package meta
 import (
 	"bufio"
 	"flag"
+	"fmt"
 	"io"
+	"os"
+	"strings"
 
 	"github.com/hashicorp/errwrap"
 	"github.com/hashicorp/vault/api"
@@ -18,11 +21,19 @@ type FlagSetFlags uint
 type TokenHelperFunc func() (token.TokenHelper, error)
 
 const (
-	FlagSetNone    FlagSetFlags = 0
-	FlagSetServer  FlagSetFlags = 1 << iota
-	FlagSetDefault              = FlagSetServer
+	FlagSetNone   FlagSetFlags = 0
+	FlagSetServer FlagSetFlags = 1 << iota
+	FlagSetOutput
+	FlagSetDefault = FlagSetServer | FlagSetOutput
 )
 
+// EnvVaultFormat is the environment variable holding the default output
+// format of the commands
+const EnvVaultFormat = "VAULT_FORMAT"
+
+// OutputFormats are the values accepted by the -format flag
+var OutputFormats = []string{"table", "json", "yaml", "yml"}
+
 var (
 	additionalOptionsUsage = func() string {
 		return `
@@ -55,6 +66,8 @@ type Meta struct {
 	flagClientKey  string
 	flagWrapTTL    string
 	flagInsecure   bool
+	flagFormat     string
+	flagField      string
 
 	// Queried if no token can be found
 	TokenHelper TokenHelperFunc
@@ -136,6 +149,32 @@ func (m *Meta) Client() (*api.Client, error) {
 	return client, nil
 }
 
+// Format returns the output format given with the -format flag or the
+// VAULT_FORMAT environment variable, defaulting to table
+func (m *Meta) Format() string {
+	format := m.flagFormat
+	if format == "" {
+		format = os.Getenv(EnvVaultFormat)
+	}
+	if format == "" {
+		return "table"
+	}
+	return strings.ToLower(format)
+}
+
+// Field returns the field given with the -field flag, whose raw value is
+// output instead of the whole output of the command
+func (m *Meta) Field() string {
+	return m.flagField
+}
+
+// StructuredOutput returns whether a command should output its result as
+// structured data, in the format given with -format or as the value of the
+// field given with -field, rather than as its human-readable table
+func (m *Meta) StructuredOutput() bool {
+	return m.Field() != "" || m.Format() != "table"
+}
+
 // FlagSet returns a FlagSet with the common flags that every
 // command implements. The exact behavior of FlagSet can be configured
 // using the flags as the second parameter, for example to disable
@@ -156,6 +195,13 @@ func (m *Meta) FlagSet(n string, fs FlagSetFlags) *flag.FlagSet {
 		f.BoolVar(&m.flagInsecure, "tls-skip-verify", false, "")
 	}
 
+	// FlagSetOutput enables the settings selecting the output format, for
+	// the commands outputting data
+	if fs&FlagSetOutput != 0 {
+		f.Var((*formatValue)(&m.flagFormat), "format", "")
+		f.StringVar(&m.flagField, "field", "", "")
+	}
+
 	// Create an io.Writer that writes to our Ui properly for errors.
 	// This is kind of a hack, but it does the job. Basically: create
 	// a pipe, use a scanner to break it into lines, and output each line
@@ -172,6 +218,25 @@ func (m *Meta) FlagSet(n string, fs FlagSetFlags) *flag.FlagSet {
 	return f
 }
 
+// formatValue is the value of the -format flag, which is validated when the
+// flags are parsed so that commands fail before doing anything
+type formatValue string
+
+func (f *formatValue) String() string {
+	return string(*f)
+}
+
+func (f *formatValue) Set(v string) error {
+	v = strings.ToLower(v)
+	for _, format := range OutputFormats {
+		if v == format {
+			*f = formatValue(v)
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid output format %q, must be table, json or yaml", v)
+}
+
 // GeneralOptionsUsage returns the usage documentation for commonly
 // available options
 func GeneralOptionsUsage() string {
@@ -201,6 +266,16 @@ func GeneralOptionsUsage() string {
   -tls-skip-verify        Do not verify TLS certificate. This is highly
                           not recommended. Verification will also be skipped
                           if VAULT_SKIP_VERIFY is set.
+
+  -format=table           The format of the output: table, json or yaml. The
+                          table format is meant for humans, while the json
+                          and yaml formats name the fields of the output
+                          consistently across releases. Overrides the
+                          VAULT_FORMAT environment variable if set.
+
+  -field=field            Output only the raw value of the given top-level
+                          field of the output, such as "token" when creating
+                          a token, without a trailing newline.
 `
 
 	general += additionalOptionsUsage()
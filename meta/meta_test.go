@@ -2,9 +2,12 @@ package meta
 
 import (
 	"flag"
+	"os"
 	"reflect"
 	"sort"
 	"testing"
+
+	"github.com/mitchellh/cli"
 )
 
 func TestFlagSet(t *testing.T) {
@@ -20,6 +23,10 @@ func TestFlagSet(t *testing.T) {
 			FlagSetServer,
 			[]string{"address", "ca-cert", "ca-path", "client-cert", "client-key", "insecure", "tls-skip-verify", "wrap-ttl"},
 		},
+		{
+			FlagSetOutput,
+			[]string{"field", "format"},
+		},
 	}
 
 	for i, tc := range cases {
@@ -39,3 +46,33 @@ func TestFlagSet(t *testing.T) {
 		}
 	}
 }
+
+func TestFlagSet_format(t *testing.T) {
+	os.Setenv(EnvVaultFormat, "YAML")
+	defer os.Unsetenv(EnvVaultFormat)
+
+	var m Meta
+	m.Ui = new(cli.MockUi)
+	fs := m.FlagSet("foo", FlagSetDefault)
+	if err := fs.Parse(nil); err != nil {
+		t.Fatal(err)
+	}
+	if m.Format() != "yaml" || !m.StructuredOutput() {
+		t.Fatalf("bad: %s", m.Format())
+	}
+
+	m = Meta{Ui: new(cli.MockUi)}
+	fs = m.FlagSet("foo", FlagSetDefault)
+	if err := fs.Parse([]string{"-format", "JSON", "-field", "token"}); err != nil {
+		t.Fatal(err)
+	}
+	if m.Format() != "json" || m.Field() != "token" {
+		t.Fatalf("bad: %s %s", m.Format(), m.Field())
+	}
+
+	m = Meta{Ui: new(cli.MockUi)}
+	fs = m.FlagSet("foo", FlagSetDefault)
+	if err := fs.Parse([]string{"-format", "xml"}); err == nil {
+		t.Fatal("expected an error")
+	}
+}
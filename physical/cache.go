@@ -1,8 +1,11 @@
 package physical
 
 import (
+	"fmt"
 	"strings"
+	"time"
 
+	"github.com/armon/go-metrics"
 	"github.com/hashicorp/golang-lru"
 	"github.com/hashicorp/vault/helper/locksutil"
 	log "github.com/mgutz/logxi/v1"
@@ -11,17 +14,81 @@ import (
 const (
 	// DefaultCacheSize is used if no cache size is specified for NewCache
 	DefaultCacheSize = 32 * 1024
+
+	// CacheType2Q is the default type of cache, a 2Q cache tracking both
+	// the frequently and the recently used entries
+	CacheType2Q = "2q"
+
+	// CacheTypeLRU is a cache evicting the least recently used entries
+	CacheTypeLRU = "lru"
 )
 
+// CacheConfig configures the cache layer of a physical backend
+type CacheConfig struct {
+	// Size is the maximum number of cached entries. If zero, the default
+	// size is used.
+	Size int
+
+	// Type is the eviction policy of the cache, CacheType2Q if empty
+	Type string
+
+	// TTL is how long an entry stays cached, or forever if zero, as long as
+	// it isn't evicted
+	TTL time.Duration
+
+	// BypassPrefixes are the prefixes of the keys that are never cached, in
+	// addition to core/, such as those of the short-lived lease entries
+	BypassPrefixes []string
+}
+
+// cacheLRU is the cache holding the entries of a Cache
+type cacheLRU interface {
+	Add(key, value interface{})
+	Get(key interface{}) (interface{}, bool)
+	Remove(key interface{})
+	Purge()
+}
+
+// twoQueueLRU is a 2Q cache counting the entries evicted to make room for
+// the added ones
+type twoQueueLRU struct {
+	*lru.TwoQueueCache
+	size int
+}
+
+func (c *twoQueueLRU) Add(key, value interface{}) {
+	if !c.Contains(key) && c.Len() >= c.size {
+		metrics.IncrCounter([]string{"cache", "eviction"}, 1)
+	}
+	c.TwoQueueCache.Add(key, value)
+}
+
+// lruCache is an LRU cache, whose evictions are counted by its callback
+type lruCache struct {
+	*lru.Cache
+}
+
+func (c *lruCache) Add(key, value interface{}) {
+	c.Cache.Add(key, value)
+}
+
+// cacheEntry is a cached entry, which expires at the given time if set
+type cacheEntry struct {
+	entry   *Entry
+	expires time.Time
+}
+
 // Cache is used to wrap an underlying physical backend
 // and provide an LRU cache layer on top. Most of the reads done by
 // Vault are for policy objects so there is a large read reduction
 // by using a simple write-through cache.
 type Cache struct {
-	backend Backend
-	lru     *lru.TwoQueueCache
-	locks   []*locksutil.LockEntry
-	logger  log.Logger
+	backend        Backend
+	lru            cacheLRU
+	ttl            time.Duration
+	bypassPrefixes []string
+	locks          []*locksutil.LockEntry
+	logger         log.Logger
 }
 
 // TransactionalCache is a Cache that wraps the physical that is transactional
@@ -33,29 +100,76 @@ type TransactionalCache struct {
 // NewCache returns a physical cache of the given size.
 // If no size is provided, the default size is used.
 func NewCache(b Backend, size int, logger log.Logger) *Cache {
+	c, _ := NewCacheWithConfig(b, &CacheConfig{Size: size}, logger)
+	return c
+}
+
+// NewCacheWithConfig returns a physical cache configured by conf
+func NewCacheWithConfig(b Backend, conf *CacheConfig, logger log.Logger) (*Cache, error) {
+	if conf == nil {
+		conf = &CacheConfig{}
+	}
+	size := conf.Size
 	if size <= 0 {
 		size = DefaultCacheSize
 	}
+	if conf.TTL < 0 {
+		return nil, fmt.Errorf("cache TTL cannot be negative")
+	}
+
+	var cache cacheLRU
+	switch conf.Type {
+	case "", CacheType2Q:
+		twoQueue, err := lru.New2Q(size)
+		if err != nil {
+			return nil, err
+		}
+		cache = &twoQueueLRU{TwoQueueCache: twoQueue, size: size}
+	case CacheTypeLRU:
+		evictLRU, err := lru.NewWithEvict(size, func(interface{}, interface{}) {
+			metrics.IncrCounter([]string{"cache", "eviction"}, 1)
+		})
+		if err != nil {
+			return nil, err
+		}
+		cache = &lruCache{Cache: evictLRU}
+	default:
+		return nil, fmt.Errorf("unknown cache type %q", conf.Type)
+	}
+
 	if logger.IsTrace() {
-		logger.Trace("physical/cache: creating LRU cache", "size", size)
+		logger.Trace("physical/cache: creating cache", "type", conf.Type, "size", size, "ttl", conf.TTL)
 	}
-	cache, _ := lru.New2Q(size)
 	c := &Cache{
-		backend: b,
-		lru:     cache,
-		locks:   locksutil.CreateLocks(),
-		logger:  logger,
+		backend:        b,
+		lru:            cache,
+		ttl:            conf.TTL,
+		bypassPrefixes: append([]string{"core/"}, conf.BypassPrefixes...),
+		locks:          locksutil.CreateLocks(),
+		logger:         logger,
 	}
 
-	return c
+	return c, nil
 }
 
 func NewTransactionalCache(b Backend, size int, logger log.Logger) *TransactionalCache {
+	c, _ := NewTransactionalCacheWithConfig(b, &CacheConfig{Size: size}, logger)
+	return c
+}
+
+// NewTransactionalCacheWithConfig returns a transactional physical cache
+// configured by conf
+func NewTransactionalCacheWithConfig(b Backend, conf *CacheConfig, logger log.Logger) (*TransactionalCache, error) {
+	cache, err := NewCacheWithConfig(b, conf, logger)
+	if err != nil {
+		return nil, err
+	}
+
 	c := &TransactionalCache{
-		Cache:         NewCache(b, size, logger),
+		Cache:         cache,
 		Transactional: b.(Transactional),
 	}
-	return c
+	return c, nil
 }
 
 // Purge is used to clear the cache
@@ -69,14 +183,36 @@ func (c *Cache) Purge() {
 	c.lru.Purge()
 }
 
+// bypassed returns whether the key is never cached
+func (c *Cache) bypassed(key string) bool {
+	for _, prefix := range c.bypassPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// add caches the entry
+func (c *Cache) add(entry *Entry) {
+	cached := &cacheEntry{
+		entry: entry,
+	}
+	if c.ttl > 0 {
+		cached.expires = time.Now().Add(c.ttl)
+	}
+	c.lru.Add(entry.Key, cached)
+}
+
 func (c *Cache) Put(entry *Entry) error {
 	lock := locksutil.LockForKey(c.locks, entry.Key)
 	lock.Lock()
 	defer lock.Unlock()
 
 	err := c.backend.Put(entry)
-	if err == nil && !strings.HasPrefix(entry.Key, "core/") {
-		c.lru.Add(entry.Key, entry)
+	if err == nil && !c.bypassed(entry.Key) {
+		metrics.IncrCounter([]string{"cache", "write"}, 1)
+		c.add(entry)
 	}
 	return err
 }
@@ -90,18 +226,23 @@ func (c *Cache) Get(key string) (*Entry, error) {
 	// otherwise we risk certain race conditions upstream. The primary issue is
 	// with the HA mode, we could potentially negatively cache the leader entry
 	// and cause leader discovery to fail.
-	if strings.HasPrefix(key, "core/") {
+	if c.bypassed(key) {
 		return c.backend.Get(key)
 	}
 
 	// Check the LRU first
 	if raw, ok := c.lru.Get(key); ok {
-		if raw == nil {
-			return nil, nil
-		} else {
-			return raw.(*Entry), nil
+		cached := raw.(*cacheEntry)
+		if cached.expires.IsZero() || time.Now().Before(cached.expires) {
+			metrics.IncrCounter([]string{"cache", "hit"}, 1)
+			return cached.entry, nil
 		}
+
+		// Expired entries are removed, then replaced by the entry read below
+		metrics.IncrCounter([]string{"cache", "expiration"}, 1)
+		c.lru.Remove(key)
 	}
+	metrics.IncrCounter([]string{"cache", "miss"}, 1)
 
 	// Read from the underlying backend
 	ent, err := c.backend.Get(key)
@@ -111,7 +252,7 @@ func (c *Cache) Get(key string) (*Entry, error) {
 
 	// Cache the result
 	if ent != nil {
-		c.lru.Add(key, ent)
+		c.add(ent)
 	}
 
 	return ent, nil
@@ -123,7 +264,8 @@ func (c *Cache) Delete(key string) error {
 	defer lock.Unlock()
 
 	err := c.backend.Delete(key)
-	if err == nil && !strings.HasPrefix(key, "core/") {
+	if err == nil && !c.bypassed(key) {
+		metrics.IncrCounter([]string{"cache", "delete"}, 1)
 		c.lru.Remove(key)
 	}
 	return err
@@ -150,8 +292,13 @@ func (c *TransactionalCache) Transaction(txns []TxnEntry) error {
 	for _, txn := range txns {
 		switch txn.Operation {
 		case PutOperation:
-			c.lru.Add(txn.Entry.Key, txn.Entry)
+			if c.bypassed(txn.Entry.Key) {
+				continue
+			}
+			metrics.IncrCounter([]string{"cache", "write"}, 1)
+			c.add(txn.Entry)
 		case DeleteOperation:
+			metrics.IncrCounter([]string{"cache", "delete"}, 1)
 			c.lru.Remove(txn.Entry.Key)
 		}
 	}
@@ -85,6 +85,18 @@ type ConsulBackend struct {
 
 	notifyActiveCh chan notifyEvent
 	notifySealedCh chan notifyEvent
+
+	// fence holds the lock key and session of the active node's lock once
+	// it is registered, until the lock is released. Writes are then made in
+	// transactions that check the session still holds the lock.
+	fenceLock sync.RWMutex
+	fence     *consulFence
+}
+
+type consulFence struct {
+	lock    *ConsulLock
+	key     string
+	session string
 }
 
 // NewConsulBackend constructs a Consul backend using the given API client
@@ -330,6 +342,22 @@ func (c *ConsulBackend) Transaction(txns []physical.TxnEntry) error {
 	c.permitPool.Acquire()
 	defer c.permitPool.Release()
 
+	return c.txn(ops, c.currentFence())
+}
+
+// txn runs the operations in a transaction. If a fence is given, the
+// transaction first checks that its session still holds the lock, failing
+// with ErrFenced otherwise.
+func (c *ConsulBackend) txn(ops []*api.KVTxnOp, fence *consulFence) error {
+	if fence != nil {
+		check := &api.KVTxnOp{
+			Verb:    api.KVCheckSession,
+			Key:     fence.key,
+			Session: fence.session,
+		}
+		ops = append([]*api.KVTxnOp{check}, ops...)
+	}
+
 	ok, resp, _, err := c.kv.Txn(ops, nil)
 	if err != nil {
 		return err
@@ -340,12 +368,56 @@ func (c *ConsulBackend) Transaction(txns []physical.TxnEntry) error {
 
 	var retErr *multierror.Error
 	for _, res := range resp.Errors {
+		if fence != nil && res.OpIndex == 0 {
+			return physical.ErrFenced
+		}
 		retErr = multierror.Append(retErr, errors.New(res.What))
 	}
 
 	return retErr
 }
 
+func (c *ConsulBackend) currentFence() *consulFence {
+	c.fenceLock.RLock()
+	defer c.fenceLock.RUnlock()
+	return c.fence
+}
+
+// RegisterActiveNodeLock fences further writes with the session holding the
+// given lock, so that they fail once the session loses the lock
+func (c *ConsulBackend) RegisterActiveNodeLock(l physical.Lock) error {
+	cl, ok := l.(*ConsulLock)
+	if !ok || cl.client != c.client {
+		return fmt.Errorf("lock was not created by this backend")
+	}
+
+	pair, err := cl.pair()
+	if err != nil {
+		return err
+	}
+	if pair == nil || pair.Session == "" || string(pair.Value) != cl.value {
+		return fmt.Errorf("lock not held")
+	}
+
+	c.fenceLock.Lock()
+	c.fence = &consulFence{
+		lock:    cl,
+		key:     cl.key,
+		session: pair.Session,
+	}
+	c.fenceLock.Unlock()
+	return nil
+}
+
+// clearFence stops fencing writes if they are fenced with the given lock
+func (c *ConsulBackend) clearFence(cl *ConsulLock) {
+	c.fenceLock.Lock()
+	if c.fence != nil && c.fence.lock == cl {
+		c.fence = nil
+	}
+	c.fenceLock.Unlock()
+}
+
 // Put is used to insert or update an entry
 func (c *ConsulBackend) Put(entry *physical.Entry) error {
 	defer metrics.MeasureSince([]string{"consul", "put"}, time.Now())
@@ -353,6 +425,14 @@ func (c *ConsulBackend) Put(entry *physical.Entry) error {
 	c.permitPool.Acquire()
 	defer c.permitPool.Release()
 
+	if fence := c.currentFence(); fence != nil {
+		return c.txn([]*api.KVTxnOp{&api.KVTxnOp{
+			Verb:  api.KVSet,
+			Key:   c.path + entry.Key,
+			Value: entry.Value,
+		}}, fence)
+	}
+
 	pair := &api.KVPair{
 		Key:   c.path + entry.Key,
 		Value: entry.Value,
@@ -397,6 +477,13 @@ func (c *ConsulBackend) Delete(key string) error {
 	c.permitPool.Acquire()
 	defer c.permitPool.Release()
 
+	if fence := c.currentFence(); fence != nil {
+		return c.txn([]*api.KVTxnOp{&api.KVTxnOp{
+			Verb: api.KVDelete,
+			Key:  c.path + key,
+		}}, fence)
+	}
+
 	_, err := c.kv.Delete(c.path+key, nil)
 	return err
 }
@@ -439,8 +526,10 @@ func (c *ConsulBackend) LockWith(key, value string) (physical.Lock, error) {
 		return nil, fmt.Errorf("failed to create lock: %v", err)
 	}
 	cl := &ConsulLock{
+		backend:         c,
 		client:          c.client,
 		key:             c.path + key,
+		value:           value,
 		lock:            lock,
 		consistencyMode: c.consistencyMode,
 	}
@@ -469,8 +558,10 @@ func (c *ConsulBackend) DetectHostAddr() (string, error) {
 
 // ConsulLock is used to provide the Lock interface backed by Consul
 type ConsulLock struct {
+	backend         *ConsulBackend
 	client          *api.Client
 	key             string
+	value           string
 	lock            *api.Lock
 	consistencyMode string
 }
@@ -480,20 +571,13 @@ func (c *ConsulLock) Lock(stopCh <-chan struct{}) (<-chan struct{}, error) {
 }
 
 func (c *ConsulLock) Unlock() error {
+	// Writes are no longer fenced once the active node steps down
+	c.backend.clearFence(c)
 	return c.lock.Unlock()
 }
 
 func (c *ConsulLock) Value() (bool, string, error) {
-	kv := c.client.KV()
-
-	var queryOptions *api.QueryOptions
-	if c.consistencyMode == consistencyModeStrong {
-		queryOptions = &api.QueryOptions{
-			RequireConsistent: true,
-		}
-	}
-
-	pair, _, err := kv.Get(c.key, queryOptions)
+	pair, err := c.pair()
 	if err != nil {
 		return false, "", err
 	}
@@ -505,6 +589,31 @@ func (c *ConsulLock) Value() (bool, string, error) {
 	return held, value, nil
 }
 
+// FencingToken returns the lock index of the key, which Consul increments
+// each time the lock is acquired
+func (c *ConsulLock) FencingToken() (uint64, error) {
+	pair, err := c.pair()
+	if err != nil {
+		return 0, err
+	}
+	if pair == nil || pair.Session == "" || string(pair.Value) != c.value {
+		return 0, fmt.Errorf("lock not held")
+	}
+	return pair.LockIndex, nil
+}
+
+func (c *ConsulLock) pair() (*api.KVPair, error) {
+	var queryOptions *api.QueryOptions
+	if c.consistencyMode == consistencyModeStrong {
+		queryOptions = &api.QueryOptions{
+			RequireConsistent: true,
+		}
+	}
+
+	pair, _, err := c.client.KV().Get(c.key, queryOptions)
+	return pair, err
+}
+
 func (c *ConsulBackend) NotifyActiveStateChange() error {
 	select {
 	case c.notifyActiveCh <- notifyEvent{}:
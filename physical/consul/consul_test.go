@@ -469,6 +469,26 @@ func TestConsulHABackend(t *testing.T) {
 	}
 	physical.ExerciseHABackend(t, ha, ha)
 
+	b2, err := NewConsulBackend(map[string]string{
+		"address":      conf.Address,
+		"path":         randPath,
+		"max_parallel": "-1",
+		"token":        conf.Token,
+	}, logger)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	physical.ExerciseFencingHABackend(t, b.(physical.FencingHABackend), b2.(physical.FencingHABackend), func(l physical.Lock) {
+		// Destroying the session of the lock releases it
+		pair, err := l.(*ConsulLock).pair()
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if _, err := client.Session().Destroy(pair.Session, nil); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	})
+
 	detect, ok := b.(physical.RedirectDetect)
 	if !ok {
 		t.Fatalf("consul does not implement RedirectDetect")
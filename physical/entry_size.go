@@ -0,0 +1,111 @@
+package physical
+
+import (
+	"fmt"
+)
+
+// EntryTooLargeError is returned for the entries rejected by an
+// EntrySizeLimiter
+type EntryTooLargeError struct {
+	// Key is the key of the entry
+	Key string
+
+	// Description identifies the entry, such as the mount and path it was
+	// written for. It is the key if no description is available.
+	Description string
+
+	Size    int
+	MaxSize int
+}
+
+func (e *EntryTooLargeError) Error() string {
+	return fmt.Sprintf("entry of %d bytes for %s exceeds the maximum entry size of %d bytes",
+		e.Size, e.Description, e.MaxSize)
+}
+
+// EntrySizeLimiter is used to reject the entries larger than a maximum size
+// before they are written to the underlying physical backend, which may
+// otherwise fail with an opaque error or store entries it can't serve.
+type EntrySizeLimiter struct {
+	backend     Backend
+	maxSize     int
+	describeKey func(key string) string
+}
+
+// TransactionalEntrySizeLimiter is the transactional version of the entry
+// size limiter
+type TransactionalEntrySizeLimiter struct {
+	*EntrySizeLimiter
+	Transactional
+}
+
+// NewEntrySizeLimiter returns a wrapped physical backend rejecting the
+// entries larger than maxSize bytes. The errors identify the entries with
+// describeKey, if not nil.
+func NewEntrySizeLimiter(b Backend, maxSize int, describeKey func(key string) string) *EntrySizeLimiter {
+	return &EntrySizeLimiter{
+		backend:     b,
+		maxSize:     maxSize,
+		describeKey: describeKey,
+	}
+}
+
+// NewTransactionalEntrySizeLimiter creates a new transactional
+// EntrySizeLimiter
+func NewTransactionalEntrySizeLimiter(b Backend, maxSize int, describeKey func(key string) string) *TransactionalEntrySizeLimiter {
+	return &TransactionalEntrySizeLimiter{
+		EntrySizeLimiter: NewEntrySizeLimiter(b, maxSize, describeKey),
+		Transactional:    b.(Transactional),
+	}
+}
+
+// check returns an EntryTooLargeError if the entry is too large
+func (l *EntrySizeLimiter) check(entry *Entry) error {
+	if len(entry.Value) <= l.maxSize {
+		return nil
+	}
+
+	description := fmt.Sprintf("key %q", entry.Key)
+	if l.describeKey != nil {
+		description = l.describeKey(entry.Key)
+	}
+	return &EntryTooLargeError{
+		Key:         entry.Key,
+		Description: description,
+		Size:        len(entry.Value),
+		MaxSize:     l.maxSize,
+	}
+}
+
+func (l *EntrySizeLimiter) Put(entry *Entry) error {
+	if err := l.check(entry); err != nil {
+		return err
+	}
+	return l.backend.Put(entry)
+}
+
+func (l *EntrySizeLimiter) Get(key string) (*Entry, error) {
+	return l.backend.Get(key)
+}
+
+func (l *EntrySizeLimiter) Delete(key string) error {
+	return l.backend.Delete(key)
+}
+
+func (l *EntrySizeLimiter) List(prefix string) ([]string, error) {
+	return l.backend.List(prefix)
+}
+
+// Transaction rejects the whole transaction if any entry it puts is too
+// large
+func (l *TransactionalEntrySizeLimiter) Transaction(txns []TxnEntry) error {
+	for _, txn := range txns {
+		if txn.Operation != PutOperation {
+			continue
+		}
+		if err := l.check(txn.Entry); err != nil {
+			return err
+		}
+	}
+	return l.Transactional.Transaction(txns)
+}
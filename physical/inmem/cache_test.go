@@ -2,6 +2,7 @@ package inmem
 
 import (
 	"testing"
+	"time"
 
 	"github.com/hashicorp/vault/helper/logformat"
 	"github.com/hashicorp/vault/physical"
@@ -151,3 +152,99 @@ func TestCache_IgnoreCore(t *testing.T) {
 		t.Fatal("expected non-cached value")
 	}
 }
+
+func TestCache_BypassPrefixes(t *testing.T) {
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+
+	inm, err := NewInmem(nil, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache, err := physical.NewCacheWithConfig(inm, &physical.CacheConfig{
+		BypassPrefixes: []string{"sys/expire/"},
+	}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{"foo", "sys/expire/id/foo"} {
+		if err := cache.Put(&physical.Entry{Key: key, Value: []byte("bar")}); err != nil {
+			t.Fatal(err)
+		}
+		if err := inm.Put(&physical.Entry{Key: key, Value: []byte("baz")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ent, err := cache.Get("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(ent.Value) != "bar" {
+		t.Fatal("expected cached value")
+	}
+	ent, err = cache.Get("sys/expire/id/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(ent.Value) != "baz" {
+		t.Fatal("expected non-cached value")
+	}
+}
+
+func TestCache_TTL(t *testing.T) {
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+
+	inm, err := NewInmem(nil, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache, err := physical.NewCacheWithConfig(inm, &physical.CacheConfig{
+		Type: physical.CacheTypeLRU,
+		TTL:  100 * time.Millisecond,
+	}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	physical.ExerciseBackend(t, cache)
+
+	if err := cache.Put(&physical.Entry{Key: "foo", Value: []byte("bar")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := inm.Put(&physical.Entry{Key: "foo", Value: []byte("baz")}); err != nil {
+		t.Fatal(err)
+	}
+
+	ent, err := cache.Get("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(ent.Value) != "bar" {
+		t.Fatal("expected cached value")
+	}
+
+	// The expired entry is read again from the backend
+	time.Sleep(150 * time.Millisecond)
+	ent, err = cache.Get("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(ent.Value) != "baz" {
+		t.Fatal("expected non-cached value")
+	}
+}
+
+func TestCache_badConfig(t *testing.T) {
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+
+	inm, err := NewInmem(nil, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := physical.NewCacheWithConfig(inm, &physical.CacheConfig{Type: "arc"}, logger); err == nil {
+		t.Fatal("expected error")
+	}
+	if _, err := physical.NewCacheWithConfig(inm, &physical.CacheConfig{TTL: -time.Second}, logger); err == nil {
+		t.Fatal("expected error")
+	}
+}
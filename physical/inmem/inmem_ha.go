@@ -14,6 +14,13 @@ type InmemHABackend struct {
 	l      sync.Mutex
 	cond   *sync.Cond
 	logger log.Logger
+
+	// fencingTokens holds the last token issued for each lock key, and
+	// fenceKey and fenceToken those of the lock registered by the active
+	// node, if any, until it is released
+	fencingTokens map[string]uint64
+	fenceKey      string
+	fenceToken    uint64
 }
 
 type TransactionalInmemHABackend struct {
@@ -29,9 +36,10 @@ func NewInmemHA(_ map[string]string, logger log.Logger) (physical.Backend, error
 	}
 
 	in := &InmemHABackend{
-		Backend: be,
-		locks:   make(map[string]string),
-		logger:  logger,
+		Backend:       be,
+		locks:         make(map[string]string),
+		logger:        logger,
+		fencingTokens: make(map[string]uint64),
 	}
 	in.cond = sync.NewCond(&in.l)
 	return in, nil
@@ -43,9 +51,10 @@ func NewTransactionalInmemHA(_ map[string]string, logger log.Logger) (physical.B
 		return nil, err
 	}
 	inmemHA := InmemHABackend{
-		Backend: transInmem,
-		locks:   make(map[string]string),
-		logger:  logger,
+		Backend:       transInmem,
+		locks:         make(map[string]string),
+		logger:        logger,
+		fencingTokens: make(map[string]uint64),
 	}
 
 	in := &TransactionalInmemHABackend{
@@ -66,6 +75,61 @@ func (i *InmemHABackend) LockWith(key, value string) (physical.Lock, error) {
 	return l, nil
 }
 
+// RegisterActiveNodeLock fences further writes with the given lock. Writes
+// are rejected once a newer fencing token has been issued for its key.
+func (i *InmemHABackend) RegisterActiveNodeLock(l physical.Lock) error {
+	lock, ok := l.(*InmemLock)
+	if !ok || lock.in != i {
+		return fmt.Errorf("lock was not created by this backend")
+	}
+	token, err := lock.FencingToken()
+	if err != nil {
+		return err
+	}
+
+	i.l.Lock()
+	i.fenceKey = lock.key
+	i.fenceToken = token
+	i.l.Unlock()
+	return nil
+}
+
+// checkFence returns ErrFenced if the lock of the active node has since been
+// acquired by another holder
+func (i *InmemHABackend) checkFence() error {
+	i.l.Lock()
+	defer i.l.Unlock()
+
+	if i.fenceToken != 0 && i.fenceToken < i.fencingTokens[i.fenceKey] {
+		return physical.ErrFenced
+	}
+	return nil
+}
+
+// Put is used to insert or update an entry, subject to the fence
+func (i *InmemHABackend) Put(entry *physical.Entry) error {
+	if err := i.checkFence(); err != nil {
+		return err
+	}
+	return i.Backend.Put(entry)
+}
+
+// Delete is used to permanently delete an entry, subject to the fence
+func (i *InmemHABackend) Delete(key string) error {
+	if err := i.checkFence(); err != nil {
+		return err
+	}
+	return i.Backend.Delete(key)
+}
+
+// Transaction runs the transaction, subject to the fence
+func (i *TransactionalInmemHABackend) Transaction(txns []physical.TxnEntry) error {
+	if err := i.checkFence(); err != nil {
+		return err
+	}
+	return i.Transactional.Transaction(txns)
+}
+
 // LockMapSize is used in some tests to determine whether this backend has ever
 // been used for HA purposes rather than simply for storage
 func (i *InmemHABackend) LockMapSize() int {
@@ -85,6 +149,7 @@ type InmemLock struct {
 	value string
 
 	held     bool
+	token    uint64
 	leaderCh chan struct{}
 	l        sync.Mutex
 }
@@ -97,6 +162,7 @@ func (i *InmemLock) Lock(stopCh <-chan struct{}) (<-chan struct{}, error) {
 	}
 
 	// Attempt an async acquisition
+	var token uint64
 	didLock := make(chan struct{})
 	releaseCh := make(chan bool, 1)
 	go func() {
@@ -108,6 +174,8 @@ func (i *InmemLock) Lock(stopCh <-chan struct{}) (<-chan struct{}, error) {
 			_, ok = i.in.locks[i.key]
 		}
 		i.in.locks[i.key] = i.value
+		i.in.fencingTokens[i.key]++
+		token = i.in.fencingTokens[i.key]
 		i.in.l.Unlock()
 
 		// Signal that lock is held
@@ -134,6 +202,7 @@ func (i *InmemLock) Lock(stopCh <-chan struct{}) (<-chan struct{}, error) {
 
 	// Create the leader channel
 	i.held = true
+	i.token = token
 	i.leaderCh = make(chan struct{})
 	return i.leaderCh, nil
 }
@@ -152,6 +221,11 @@ func (i *InmemLock) Unlock() error {
 
 	i.in.l.Lock()
 	delete(i.in.locks, i.key)
+	// Writes are no longer fenced once the active node steps down
+	if i.in.fenceKey == i.key && i.in.fenceToken == i.token {
+		i.in.fenceKey = ""
+		i.in.fenceToken = 0
+	}
 	i.in.l.Unlock()
 	i.in.cond.Broadcast()
 	return nil
@@ -163,3 +237,14 @@ func (i *InmemLock) Value() (bool, string, error) {
 	i.in.l.Unlock()
 	return ok, val, nil
 }
+
+// FencingToken returns the token issued when the lock was acquired
+func (i *InmemLock) FencingToken() (uint64, error) {
+	i.l.Lock()
+	defer i.l.Unlock()
+
+	if !i.held {
+		return 0, fmt.Errorf("lock not held")
+	}
+	return i.token, nil
+}
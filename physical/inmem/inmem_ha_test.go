@@ -17,3 +17,47 @@ func TestInmemHA(t *testing.T) {
 	}
 	physical.ExerciseHABackend(t, inm.(physical.HABackend), inm.(physical.HABackend))
 }
+
+func TestInmemHA_Fencing(t *testing.T) {
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+
+	inm, err := NewTransactionalInmemHA(nil, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	physical.ExerciseFencingHABackend(t, inm.(physical.FencingHABackend), inm.(physical.FencingHABackend), expireInmemLock)
+
+	// Transactions are fenced as well
+	fencing := inm.(physical.FencingHABackend)
+	lock, _ := fencing.LockWith("fence", "qux")
+	if _, err := lock.Lock(nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := fencing.RegisterActiveNodeLock(lock); err != nil {
+		t.Fatal(err)
+	}
+	expireInmemLock(lock)
+	lock2, _ := fencing.LockWith("fence", "quux")
+	if _, err := lock2.Lock(nil); err != nil {
+		t.Fatal(err)
+	}
+	txns := []physical.TxnEntry{
+		physical.TxnEntry{
+			Operation: physical.PutOperation,
+			Entry:     &physical.Entry{Key: "foo", Value: []byte("bar")},
+		},
+	}
+	if err := inm.(physical.Transactional).Transaction(txns); err != physical.ErrFenced {
+		t.Fatalf("expected fenced transaction, got: %v", err)
+	}
+}
+
+// expireInmemLock frees the key of a held lock without its holder noticing,
+// so that another holder can take it over
+func expireInmemLock(l physical.Lock) {
+	lock := l.(*InmemLock)
+	lock.in.l.Lock()
+	delete(lock.in.locks, lock.key)
+	lock.in.l.Unlock()
+	lock.in.cond.Broadcast()
+}
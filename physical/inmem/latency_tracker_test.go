@@ -0,0 +1,27 @@
+package inmem
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/helper/logformat"
+	"github.com/hashicorp/vault/physical"
+	log "github.com/mgutz/logxi/v1"
+)
+
+func TestLatencyTracker(t *testing.T) {
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+
+	inm, err := NewInmem(nil, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	injector := physical.NewLatencyInjector(inm, time.Millisecond, 20, logger)
+	tracker := physical.NewLatencyTracker(injector)
+	physical.ExerciseBackend(t, tracker)
+	physical.ExerciseBackend_ListPrefix(t, tracker)
+
+	if tracker.Latency() <= 0 {
+		t.Fatalf("expected latency to be tracked, got %v", tracker.Latency())
+	}
+}
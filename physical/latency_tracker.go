@@ -0,0 +1,96 @@
+package physical
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultLatencyDecay is the weight given to each new sample when
+	// computing the moving average latency of a LatencyTracker
+	DefaultLatencyDecay = 0.1
+)
+
+// LatencyTracker is used to observe the latency of the underlying physical
+// requests. It keeps an exponentially weighted moving average that can be
+// consulted by callers that need a cheap signal of storage health.
+type LatencyTracker struct {
+	backend Backend
+	decay   float64
+
+	l       sync.RWMutex
+	average float64
+}
+
+// TransactionalLatencyTracker is the transactional version of the latency
+// tracker
+type TransactionalLatencyTracker struct {
+	*LatencyTracker
+	Transactional
+}
+
+// NewLatencyTracker returns a wrapped physical backend that tracks the
+// latency of requests made against it
+func NewLatencyTracker(b Backend) *LatencyTracker {
+	return &LatencyTracker{
+		backend: b,
+		decay:   DefaultLatencyDecay,
+	}
+}
+
+// NewTransactionalLatencyTracker creates a new transactional LatencyTracker
+func NewTransactionalLatencyTracker(b Backend) *TransactionalLatencyTracker {
+	return &TransactionalLatencyTracker{
+		LatencyTracker: NewLatencyTracker(b),
+		Transactional:  b.(Transactional),
+	}
+}
+
+// Latency returns the current moving average latency of the backend
+func (l *LatencyTracker) Latency() time.Duration {
+	l.l.RLock()
+	defer l.l.RUnlock()
+	return time.Duration(l.average)
+}
+
+func (l *LatencyTracker) observe(start time.Time) {
+	sample := float64(time.Since(start))
+
+	l.l.Lock()
+	if l.average == 0 {
+		l.average = sample
+	} else {
+		l.average = l.decay*sample + (1-l.decay)*l.average
+	}
+	l.l.Unlock()
+}
+
+// Put is a tracked put request
+func (l *LatencyTracker) Put(entry *Entry) error {
+	defer l.observe(time.Now())
+	return l.backend.Put(entry)
+}
+
+// Get is a tracked get request
+func (l *LatencyTracker) Get(key string) (*Entry, error) {
+	defer l.observe(time.Now())
+	return l.backend.Get(key)
+}
+
+// Delete is a tracked delete request
+func (l *LatencyTracker) Delete(key string) error {
+	defer l.observe(time.Now())
+	return l.backend.Delete(key)
+}
+
+// List is a tracked list request
+func (l *LatencyTracker) List(prefix string) ([]string, error) {
+	defer l.observe(time.Now())
+	return l.backend.List(prefix)
+}
+
+// Transaction is a tracked transaction request
+func (l *TransactionalLatencyTracker) Transaction(txns []TxnEntry) error {
+	defer l.observe(time.Now())
+	return l.Transactional.Transaction(txns)
+}
@@ -0,0 +1,242 @@
+package physical
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/helper/jsonutil"
+	log "github.com/mgutz/logxi/v1"
+)
+
+const (
+	// MigrationCheckpointKey is the key of the destination backend under
+	// which a migration records its progress, so that it can be resumed.
+	// It is deleted once the migration completes.
+	MigrationCheckpointKey = "core/migration-checkpoint"
+
+	// migrationCheckpointInterval is the number of entries copied between
+	// two checkpoints
+	migrationCheckpointInterval = 100
+)
+
+// ErrMigrationStopped is returned by a migration stopped before completing.
+// The entries copied so far are checkpointed.
+var ErrMigrationStopped = errors.New("migration stopped")
+
+// MigrationConfig configures a Migrator
+type MigrationConfig struct {
+	// Rate is the maximum number of entries read or copied per second, or
+	// unlimited if zero
+	Rate int
+
+	// Reset ignores the checkpoint of a previous migration, copying all the
+	// entries again
+	Reset bool
+
+	Logger log.Logger
+}
+
+// Migrator copies all the entries of a physical backend to another one. The
+// copy walks the keys in lexicographic order, periodically recording the
+// last key copied in the destination, so that a stopped or failed migration
+// resumes where it left off.
+type Migrator struct {
+	source      Backend
+	destination Backend
+	rate        int
+	reset       bool
+	logger      log.Logger
+}
+
+// migrationCheckpoint is the progress of a migration
+type migrationCheckpoint struct {
+	LastKey string `json:"last_key"`
+	Copied  int    `json:"copied"`
+}
+
+// NewMigrator returns a Migrator copying the entries of source to
+// destination
+func NewMigrator(source, destination Backend, conf *MigrationConfig) *Migrator {
+	if conf == nil {
+		conf = &MigrationConfig{}
+	}
+	logger := conf.Logger
+	if logger == nil {
+		logger = log.NullLog
+	}
+
+	return &Migrator{
+		source:      source,
+		destination: destination,
+		rate:        conf.Rate,
+		reset:       conf.Reset,
+		logger:      logger,
+	}
+}
+
+// Migrate copies the entries of the source to the destination, resuming
+// after the checkpoint of a previous migration unless reset. It returns the
+// total number of entries copied, including by the previous migrations.
+// Closing stopCh checkpoints the copy and returns ErrMigrationStopped.
+func (m *Migrator) Migrate(stopCh <-chan struct{}) (int, error) {
+	checkpoint := &migrationCheckpoint{}
+	if !m.reset {
+		entry, err := m.destination.Get(MigrationCheckpointKey)
+		if err != nil {
+			return 0, fmt.Errorf("failed to read the migration checkpoint: %v", err)
+		}
+		if entry != nil {
+			if err := jsonutil.DecodeJSON(entry.Value, checkpoint); err != nil {
+				return 0, fmt.Errorf("failed to decode the migration checkpoint: %v", err)
+			}
+			m.logger.Info("physical/migrate: resuming migration", "last_key", checkpoint.LastKey, "copied", checkpoint.Copied)
+		}
+	}
+
+	throttle, stopThrottle := m.throttle()
+	defer stopThrottle()
+
+	sinceCheckpoint := 0
+	err := m.walk("", checkpoint.LastKey, func(key string) error {
+		select {
+		case <-throttle:
+		case <-stopCh:
+			return ErrMigrationStopped
+		}
+
+		entry, err := m.source.Get(key)
+		if err != nil {
+			return fmt.Errorf("failed to read %q from the source: %v", key, err)
+		}
+		// The entry was deleted since it was listed
+		if entry == nil {
+			return nil
+		}
+		if err := m.destination.Put(entry); err != nil {
+			return fmt.Errorf("failed to write %q to the destination: %v", key, err)
+		}
+
+		checkpoint.LastKey = key
+		checkpoint.Copied++
+		sinceCheckpoint++
+		if sinceCheckpoint == migrationCheckpointInterval {
+			sinceCheckpoint = 0
+			if m.logger.IsDebug() {
+				m.logger.Debug("physical/migrate: copied entries", "copied", checkpoint.Copied)
+			}
+			return m.saveCheckpoint(checkpoint)
+		}
+		return nil
+	})
+	if err != nil {
+		if sinceCheckpoint > 0 {
+			if cpErr := m.saveCheckpoint(checkpoint); cpErr != nil {
+				m.logger.Error("physical/migrate: failed to save the migration checkpoint", "error", cpErr)
+			}
+		}
+		return checkpoint.Copied, err
+	}
+
+	if err := m.destination.Delete(MigrationCheckpointKey); err != nil {
+		return checkpoint.Copied, fmt.Errorf("failed to delete the migration checkpoint: %v", err)
+	}
+	return checkpoint.Copied, nil
+}
+
+// Verify compares the entries of the source with those of the destination,
+// and returns the keys of the entries missing from the destination or with
+// a different value. Entries written to the source during a migration may
+// differ and need to be migrated again.
+func (m *Migrator) Verify(stopCh <-chan struct{}) ([]string, error) {
+	throttle, stopThrottle := m.throttle()
+	defer stopThrottle()
+
+	var mismatched []string
+	err := m.walk("", "", func(key string) error {
+		select {
+		case <-throttle:
+		case <-stopCh:
+			return ErrMigrationStopped
+		}
+
+		sourceEntry, err := m.source.Get(key)
+		if err != nil {
+			return fmt.Errorf("failed to read %q from the source: %v", key, err)
+		}
+		if sourceEntry == nil {
+			return nil
+		}
+		destinationEntry, err := m.destination.Get(key)
+		if err != nil {
+			return fmt.Errorf("failed to read %q from the destination: %v", key, err)
+		}
+		if destinationEntry == nil || !bytes.Equal(sourceEntry.Value, destinationEntry.Value) {
+			mismatched = append(mismatched, key)
+		}
+		return nil
+	})
+	return mismatched, err
+}
+
+// saveCheckpoint records the progress of the migration in the destination
+func (m *Migrator) saveCheckpoint(checkpoint *migrationCheckpoint) error {
+	value, err := jsonutil.EncodeJSON(checkpoint)
+	if err != nil {
+		return err
+	}
+	if err := m.destination.Put(&Entry{Key: MigrationCheckpointKey, Value: value}); err != nil {
+		return fmt.Errorf("failed to save the migration checkpoint: %v", err)
+	}
+	return nil
+}
+
+// throttle returns a channel receiving a value for each entry that may be
+// processed, according to the rate, and a function releasing it
+func (m *Migrator) throttle() (<-chan time.Time, func()) {
+	if m.rate <= 0 {
+		ch := make(chan time.Time)
+		close(ch)
+		return ch, func() {}
+	}
+
+	ticker := time.NewTicker(time.Second / time.Duration(m.rate))
+	return ticker.C, ticker.Stop
+}
+
+// walk calls fn for each key of the source under the prefix that sorts
+// after the given key, in lexicographic order. Visiting the children of a
+// folder right after its name yields that order, as all the keys of the
+// folder share its name as prefix.
+func (m *Migrator) walk(prefix, after string, fn func(key string) error) error {
+	keys, err := m.source.List(prefix)
+	if err != nil {
+		return fmt.Errorf("failed to list %q in the source: %v", prefix, err)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		key = prefix + key
+		if strings.HasSuffix(key, "/") {
+			// Skip the folders entirely copied already
+			if key < after && !strings.HasPrefix(after, key) {
+				continue
+			}
+			if err := m.walk(key, after, fn); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if key <= after || key == MigrationCheckpointKey {
+			continue
+		}
+		if err := fn(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package physical
 
 import (
+	"errors"
 	"strings"
 	"sync"
 
@@ -55,6 +56,24 @@ type HABackend interface {
 	HAEnabled() bool
 }
 
+// FencingHABackend is an optional interface for HA backends that can make
+// storage writes conditional on the lock of the active node. Once the active
+// node registers its lock, writes made through the backend fail with
+// ErrFenced if another node has since acquired the lock, so that a node that
+// lost leadership without noticing cannot overwrite the new active node's
+// data.
+type FencingHABackend interface {
+	HABackend
+
+	// RegisterActiveNodeLock fences all further writes with the given lock,
+	// which must be held and have been created by this backend
+	RegisterActiveNodeLock(l Lock) error
+}
+
+// ErrFenced is returned for writes rejected because the lock registered with
+// a FencingHABackend is no longer held
+var ErrFenced = errors.New("write rejected: the HA lock is held by another node")
+
 // Purgable is an optional interface for backends that support
 // purging of their caches.
 type Purgable interface {
@@ -106,10 +125,25 @@ type Lock interface {
 	Value() (bool, string, error)
 }
 
+// FencedLock is an optional interface for locks that issue a fencing token
+// each time they are acquired. Tokens increase with every acquisition of the
+// same lock key, so a holder with a lower token has lost the lock.
+type FencedLock interface {
+	Lock
+
+	// FencingToken returns the token issued when the lock was acquired, or
+	// an error if it is not held
+	FencingToken() (uint64, error)
+}
+
 // Entry is used to represent data stored by the physical backend
 type Entry struct {
 	Key   string
 	Value []byte
+
+	// SealWrap requests an additional layer of encryption by the seal. It is
+	// not persisted by the backends.
+	SealWrap bool
 }
 
 // Factory is the factory function to create a physical backend.
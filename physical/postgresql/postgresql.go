@@ -1,13 +1,16 @@
 package postgresql
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/hashicorp/errwrap"
+	uuid "github.com/hashicorp/go-uuid"
 	"github.com/hashicorp/vault/physical"
 	log "github.com/mgutz/logxi/v1"
 
@@ -15,8 +18,32 @@ import (
 	"github.com/lib/pq"
 )
 
+const (
+	// PostgreSQLDefaultHATable is the default table of the HA locks
+	PostgreSQLDefaultHATable = "vault_ha_locks"
+
+	// PostgreSQLLockTTLSeconds is the number of seconds a lock is valid
+	// without being renewed. It matches the default of the Consul API.
+	PostgreSQLLockTTLSeconds = 15
+
+	// PostgreSQLLockRenewInterval is the amount of time to wait between
+	// the lock renewals
+	PostgreSQLLockRenewInterval = 5 * time.Second
+
+	// PostgreSQLLockRetryInterval is the amount of time to wait if a lock
+	// fails before trying again
+	PostgreSQLLockRetryInterval = time.Second
+
+	// PostgreSQLLockRenewMargin divides the lock TTL into the safety margin
+	// kept before the lease expires: a lock query is abandoned after the
+	// margin, and the lock is considered lost once the TTL minus the margin
+	// has passed since the last successful renewal
+	PostgreSQLLockRenewMargin = 3
+)
+
 // PostgreSQL Backend is a physical backend that stores data
-// within a PostgreSQL database.
+// within a PostgreSQL database. It can be run in high-availability mode,
+// using a table of locks leased by the active node.
 type PostgreSQLBackend struct {
 	table        string
 	client       *sql.DB
@@ -24,8 +51,34 @@ type PostgreSQLBackend struct {
 	get_query    string
 	delete_query string
 	list_query   string
-	logger       log.Logger
-	permitPool   *physical.PermitPool
+
+	haEnabled       bool
+	ha_lock_query   string
+	ha_get_query    string
+	ha_delete_query string
+
+	logger     log.Logger
+	permitPool *physical.PermitPool
+}
+
+// PostgreSQLLock implements a lock leased in the HA table. Its row expires
+// unless renewed by the lock holder, so that a standby takes over from a
+// failed active node.
+type PostgreSQLLock struct {
+	backend  *PostgreSQLBackend
+	key      string
+	value    string
+	identity string
+	held     bool
+	lock     sync.Mutex
+
+	// stopRenewCh is closed to stop renewing the lease of a held lock
+	stopRenewCh chan struct{}
+
+	// Allow modifying the Lock durations for ease of unit testing.
+	renewInterval time.Duration
+	retryInterval time.Duration
+	ttlSeconds    int
 }
 
 // NewPostgreSQLBackend constructs a PostgreSQL backend using the given
@@ -83,6 +136,17 @@ func NewPostgreSQLBackend(conf map[string]string, logger log.Logger) (physical.B
 			" UPDATE SET (parent_path, path, key, value) = ($1, $2, $3, $4)"
 	}
 
+	haEnabled, _ := strconv.ParseBool(conf["ha_enabled"])
+	if haEnabled && upsert_required {
+		return nil, fmt.Errorf("high availability requires PostgreSQL 9.5 or later")
+	}
+
+	unquoted_ha_table, ok := conf["ha_table"]
+	if !ok {
+		unquoted_ha_table = PostgreSQLDefaultHATable
+	}
+	quoted_ha_table := pq.QuoteIdentifier(unquoted_ha_table)
+
 	// Setup the backend.
 	m := &PostgreSQLBackend{
 		table:        quoted_table,
@@ -93,6 +157,19 @@ func NewPostgreSQLBackend(conf map[string]string, logger log.Logger) (physical.B
 		list_query: "SELECT key FROM " + quoted_table + " WHERE path = $1" +
 			"UNION SELECT DISTINCT substring(substr(path, length($1)+1) from '^.*?/') FROM " +
 			quoted_table + " WHERE parent_path LIKE $1 || '%'",
+
+		haEnabled: haEnabled,
+		// The lock is taken, or renewed, if it expired or is already held
+		// by the same identity. The expiration is computed by the database
+		// so that the clocks of the Vault nodes don't need to agree.
+		ha_lock_query: "INSERT INTO " + quoted_ha_table + " AS t (ha_identity, ha_key, ha_value, valid_until)" +
+			" VALUES ($1, $2, $3, NOW() + $4 * INTERVAL '1 second')" +
+			" ON CONFLICT (ha_key) DO " +
+			" UPDATE SET (ha_identity, ha_value, valid_until) = ($1, $3, NOW() + $4 * INTERVAL '1 second')" +
+			" WHERE t.valid_until < NOW() OR t.ha_identity = $1",
+		ha_get_query:    "SELECT ha_value FROM " + quoted_ha_table + " WHERE ha_key = $1 AND valid_until >= NOW()",
+		ha_delete_query: "DELETE FROM " + quoted_ha_table + " WHERE ha_identity = $1 AND ha_key = $2",
+
 		logger:     logger,
 		permitPool: physical.NewPermitPool(maxParInt),
 	}
@@ -208,3 +285,156 @@ func (m *PostgreSQLBackend) List(prefix string) ([]string, error) {
 
 	return keys, nil
 }
+
+// HAEnabled indicates whether the HA functionality should be exposed
+func (m *PostgreSQLBackend) HAEnabled() bool {
+	return m.haEnabled
+}
+
+// LockWith is used for mutual exclusion based on the given key.
+func (m *PostgreSQLBackend) LockWith(key, value string) (physical.Lock, error) {
+	identity, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, err
+	}
+	return &PostgreSQLLock{
+		backend:       m,
+		key:           key,
+		value:         value,
+		identity:      identity,
+		renewInterval: PostgreSQLLockRenewInterval,
+		retryInterval: PostgreSQLLockRetryInterval,
+		ttlSeconds:    PostgreSQLLockTTLSeconds,
+	}, nil
+}
+
+// Lock tries to acquire the lock by repeatedly trying to lease its row in
+// the HA table. It blocks until either the stop channel is closed or the
+// lock is acquired. The returned channel is closed once the lease can't be
+// renewed.
+func (l *PostgreSQLLock) Lock(stopCh <-chan struct{}) (<-chan struct{}, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.held {
+		return nil, fmt.Errorf("lock already held")
+	}
+
+	ticker := time.NewTicker(l.retryInterval)
+	defer ticker.Stop()
+	var acquiredAt time.Time
+	for {
+		acquiredAt = time.Now()
+		acquired, err := l.writeLock()
+		if err != nil {
+			l.backend.logger.Warn("postgres: failed to acquire the HA lock", "error", err)
+		}
+		if acquired {
+			break
+		}
+
+		select {
+		case <-ticker.C:
+		case <-stopCh:
+			return nil, nil
+		}
+	}
+
+	l.held = true
+	l.stopRenewCh = make(chan struct{})
+	leader := make(chan struct{})
+	go l.periodicallyRenewLock(leader, l.stopRenewCh, acquiredAt)
+	return leader, nil
+}
+
+// Unlock releases the lock by deleting its row from the HA table
+func (l *PostgreSQLLock) Unlock() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if !l.held {
+		return nil
+	}
+
+	l.held = false
+	close(l.stopRenewCh)
+	l.backend.permitPool.Acquire()
+	defer l.backend.permitPool.Release()
+	_, err := l.backend.client.Exec(l.backend.ha_delete_query, l.identity, l.key)
+	return err
+}
+
+// Value checks whether or not the lock is held by any instance of
+// PostgreSQLLock, including this one, and returns the current value.
+func (l *PostgreSQLLock) Value() (bool, string, error) {
+	l.backend.permitPool.Acquire()
+	defer l.backend.permitPool.Release()
+
+	var value string
+	err := l.backend.client.QueryRow(l.backend.ha_get_query, l.key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return false, "", nil
+	}
+	if err != nil {
+		return false, "", err
+	}
+	return true, value, nil
+}
+
+// renewMargin returns the safety margin kept from the TTL of the lock
+func (l *PostgreSQLLock) renewMargin() time.Duration {
+	return time.Duration(l.ttlSeconds) * time.Second / PostgreSQLLockRenewMargin
+}
+
+// writeLock leases the row of the lock, returning whether it is now held by
+// this lock. The query is abandoned well before the lease would expire.
+func (l *PostgreSQLLock) writeLock() (bool, error) {
+	l.backend.permitPool.Acquire()
+	defer l.backend.permitPool.Release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), l.renewMargin())
+	defer cancel()
+
+	result, err := l.backend.client.ExecContext(ctx, l.backend.ha_lock_query, l.identity, l.key, l.value, l.ttlSeconds)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+// periodicallyRenewLock renews the lease of the lock until it is released,
+// closing the leader channel once the lease is lost, or once it may expire
+// before being renewed
+func (l *PostgreSQLLock) periodicallyRenewLock(leader, stopCh chan struct{}, lastRenewal time.Time) {
+	defer close(leader)
+
+	ticker := time.NewTicker(l.renewInterval)
+	defer ticker.Stop()
+
+	deadline := time.Duration(l.ttlSeconds)*time.Second - l.renewMargin()
+	for {
+		select {
+		case <-ticker.C:
+		case <-stopCh:
+			return
+		}
+
+		start := time.Now()
+		acquired, err := l.writeLock()
+		switch {
+		case err != nil:
+			l.backend.logger.Warn("postgres: failed to renew the HA lock", "error", err)
+			if time.Since(lastRenewal) >= deadline {
+				l.backend.logger.Error("postgres: giving up renewing the HA lock")
+				return
+			}
+		case !acquired:
+			l.backend.logger.Error("postgres: HA lock taken by another node")
+			return
+		default:
+			lastRenewal = start
+		}
+	}
+}
@@ -3,12 +3,13 @@ package postgresql
 import (
 	"os"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/vault/helper/logformat"
 	"github.com/hashicorp/vault/physical"
 	log "github.com/mgutz/logxi/v1"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 func TestPostgreSQLBackend(t *testing.T) {
@@ -44,3 +45,98 @@ func TestPostgreSQLBackend(t *testing.T) {
 	physical.ExerciseBackend(t, b)
 	physical.ExerciseBackend_ListPrefix(t, b)
 }
+
+func TestPostgreSQLBackend_HA(t *testing.T) {
+	connURL := os.Getenv("PGURL")
+	if connURL == "" {
+		t.SkipNow()
+	}
+
+	haTable := os.Getenv("PGHATABLE")
+	if haTable == "" {
+		haTable = PostgreSQLDefaultHATable
+	}
+
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+	conf := map[string]string{
+		"connection_url": connURL,
+		"ha_enabled":     "true",
+		"ha_table":       haTable,
+	}
+
+	b, err := NewPostgreSQLBackend(conf, logger)
+	if err != nil {
+		t.Fatalf("Failed to create new backend: %v", err)
+	}
+	b2, err := NewPostgreSQLBackend(conf, logger)
+	if err != nil {
+		t.Fatalf("Failed to create new backend: %v", err)
+	}
+
+	defer func() {
+		pg := b.(*PostgreSQLBackend)
+		_, err := pg.client.Exec("TRUNCATE TABLE " + pq.QuoteIdentifier(haTable))
+		if err != nil {
+			t.Fatalf("Failed to truncate table: %v", err)
+		}
+	}()
+
+	ha, ok := b.(physical.HABackend)
+	if !ok || !ha.HAEnabled() {
+		t.Fatalf("PostgreSQL does not implement HABackend")
+	}
+	physical.ExerciseHABackend(t, ha, b2.(physical.HABackend))
+}
+
+func TestPostgreSQLBackend_LockExpiration(t *testing.T) {
+	connURL := os.Getenv("PGURL")
+	if connURL == "" {
+		t.SkipNow()
+	}
+
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+	b, err := NewPostgreSQLBackend(map[string]string{
+		"connection_url": connURL,
+		"ha_enabled":     "true",
+	}, logger)
+	if err != nil {
+		t.Fatalf("Failed to create new backend: %v", err)
+	}
+	pg := b.(*PostgreSQLBackend)
+	defer pg.client.Exec("TRUNCATE TABLE " + PostgreSQLDefaultHATable)
+
+	// A lock that isn't renewed expires
+	lock, err := pg.LockWith("expiring", "bar")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	lock.(*PostgreSQLLock).ttlSeconds = 1
+	lock.(*PostgreSQLLock).renewInterval = time.Hour
+	if leaderCh, err := lock.Lock(nil); err != nil || leaderCh == nil {
+		t.Fatalf("failed to lock: %v", err)
+	}
+
+	lock2, err := pg.LockWith("expiring", "baz")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	lock2.(*PostgreSQLLock).retryInterval = 100 * time.Millisecond
+	stopCh := make(chan struct{})
+	time.AfterFunc(5*time.Second, func() { close(stopCh) })
+	leaderCh2, err := lock2.Lock(stopCh)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if leaderCh2 == nil {
+		t.Fatal("should take over the expired lock")
+	}
+	defer lock2.Unlock()
+
+	held, value, err := lock.Value()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !held || value != "baz" {
+		t.Fatalf("bad: %v %q", held, value)
+	}
+}
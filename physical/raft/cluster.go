@@ -0,0 +1,284 @@
+package raft
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/hashicorp/raft"
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/physical"
+)
+
+const (
+	// snapshotMetaFileName and snapshotStateFileName are the names of the
+	// files of a snapshot archive
+	snapshotMetaFileName  = "meta.json"
+	snapshotStateFileName = "state.bin"
+
+	// configurationChangeTimeout is how long a change of the cluster
+	// configuration waits to be enqueued
+	configurationChangeTimeout = 30 * time.Second
+)
+
+// Peer is a server of the raft cluster
+type Peer struct {
+	NodeID  string `json:"node_id" structs:"node_id" mapstructure:"node_id"`
+	Address string `json:"address" structs:"address" mapstructure:"address"`
+	Leader  bool   `json:"leader" structs:"leader" mapstructure:"leader"`
+	Voter   bool   `json:"voter" structs:"voter" mapstructure:"voter"`
+}
+
+// HasState returns whether the node has any raft state, that is whether it
+// has been bootstrapped or has joined a cluster
+func (b *RaftBackend) HasState() (bool, error) {
+	return raft.HasExistingState(b.logStore, b.logStore, b.snapStore)
+}
+
+// Bootstrap makes the node the single server of a new raft cluster, which
+// other nodes can then join
+func (b *RaftBackend) Bootstrap() error {
+	hasState, err := b.HasState()
+	if err != nil {
+		return err
+	}
+	if hasState {
+		return ErrAlreadyBootstrapped
+	}
+
+	err = b.raft.BootstrapCluster(raft.Configuration{
+		Servers: []raft.Server{
+			{
+				Suffrage: raft.Voter,
+				ID:       raft.ServerID(b.nodeID),
+				Address:  raft.ServerAddress(b.address),
+			},
+		},
+	}).Error()
+	if err == raft.ErrCantBootstrap {
+		return ErrAlreadyBootstrapped
+	}
+	return err
+}
+
+// WaitForLeader blocks until the node becomes the raft leader, or returns
+// ErrNotLeader after the timeout
+func (b *RaftBackend) WaitForLeader(timeout time.Duration) error {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		isLeader, changeCh := b.leadership()
+		if isLeader {
+			return nil
+		}
+		select {
+		case <-changeCh:
+		case <-timer.C:
+			return ErrNotLeader
+		case <-b.shutdownCh:
+			return raft.ErrRaftShutdown
+		}
+	}
+}
+
+// Peers returns the servers of the raft cluster
+func (b *RaftBackend) Peers() ([]*Peer, error) {
+	future := b.raft.GetConfiguration()
+	if err := future.Error(); err != nil {
+		return nil, err
+	}
+
+	leader := b.raft.Leader()
+	peers := make([]*Peer, 0, len(future.Configuration().Servers))
+	for _, server := range future.Configuration().Servers {
+		peers = append(peers, &Peer{
+			NodeID:  string(server.ID),
+			Address: string(server.Address),
+			Leader:  server.Address == leader,
+			Voter:   server.Suffrage == raft.Voter,
+		})
+	}
+	return peers, nil
+}
+
+// AddPeer adds a voting server to the raft cluster. The node must be the
+// leader.
+func (b *RaftBackend) AddPeer(nodeID, address string) error {
+	err := b.raft.AddVoter(raft.ServerID(nodeID), raft.ServerAddress(address), 0, configurationChangeTimeout).Error()
+	if err == raft.ErrNotLeader {
+		return ErrNotLeader
+	}
+	return err
+}
+
+// RemovePeer removes a server from the raft cluster. The node must be the
+// leader.
+func (b *RaftBackend) RemovePeer(nodeID string) error {
+	err := b.raft.RemoveServer(raft.ServerID(nodeID), 0, configurationChangeTimeout).Error()
+	if err == raft.ErrNotLeader {
+		return ErrNotLeader
+	}
+	return err
+}
+
+// Snapshot takes a snapshot of the storage and writes it to w as a gzipped
+// tar archive. The node must be the leader.
+func (b *RaftBackend) Snapshot(w io.Writer) error {
+	// Raft can't take a snapshot until the FSM has applied a log past the
+	// last configuration change, such as a peer being added, so apply an
+	// empty one
+	if err := b.applyLog(nil); err != nil {
+		return err
+	}
+
+	future := b.raft.Snapshot()
+	if err := future.Error(); err != nil {
+		return err
+	}
+	meta, state, err := future.Open()
+	if err != nil {
+		return err
+	}
+	defer state.Close()
+
+	metaJSON, err := jsonutil.EncodeJSON(meta)
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(w)
+	archive := tar.NewWriter(gz)
+	now := time.Now()
+	if err := archive.WriteHeader(&tar.Header{
+		Name:    snapshotMetaFileName,
+		Mode:    0600,
+		Size:    int64(len(metaJSON)),
+		ModTime: now,
+	}); err != nil {
+		return err
+	}
+	if _, err := archive.Write(metaJSON); err != nil {
+		return err
+	}
+	if err := archive.WriteHeader(&tar.Header{
+		Name:    snapshotStateFileName,
+		Mode:    0600,
+		Size:    meta.Size,
+		ModTime: now,
+	}); err != nil {
+		return err
+	}
+	if _, err := io.Copy(archive, state); err != nil {
+		return err
+	}
+	if err := archive.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// SnapshotVerifier checks a snapshot before it is restored, reading its
+// storage entries with get
+type SnapshotVerifier func(get func(key string) (*physical.Entry, error)) error
+
+// Restore replaces the storage of the whole cluster with a snapshot written
+// by Snapshot, once verify accepts its entries. The node must be the leader.
+func (b *RaftBackend) Restore(r io.Reader, verify SnapshotVerifier) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("failed to read the snapshot: %v", err)
+	}
+	defer gz.Close()
+
+	archive := tar.NewReader(gz)
+	var meta *raft.SnapshotMeta
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return fmt.Errorf("snapshot is missing %s", snapshotStateFileName)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read the snapshot: %v", err)
+		}
+
+		switch header.Name {
+		case snapshotMetaFileName:
+			meta = new(raft.SnapshotMeta)
+			if err := jsonutil.DecodeJSONFromReader(archive, meta); err != nil {
+				return fmt.Errorf("failed to decode the snapshot metadata: %v", err)
+			}
+		case snapshotStateFileName:
+			if meta == nil {
+				return fmt.Errorf("snapshot is missing %s", snapshotMetaFileName)
+			}
+			return b.restoreState(meta, archive, verify)
+		}
+	}
+}
+
+// restoreState verifies the database of a snapshot and restores it. The
+// database is written to a file first, so that its entries can be read.
+func (b *RaftBackend) restoreState(meta *raft.SnapshotMeta, state io.Reader, verify SnapshotVerifier) error {
+	statePath := filepath.Join(b.fsm.path, fsmDatabaseFileName+".verify")
+	defer os.Remove(statePath)
+	if err := writeFile(statePath, state); err != nil {
+		return fmt.Errorf("failed to write the snapshot: %v", err)
+	}
+
+	if err := verifySnapshotState(statePath, verify); err != nil {
+		return err
+	}
+
+	file, err := os.Open(statePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	err = b.raft.Restore(meta, file, 0)
+	if err == raft.ErrNotLeader {
+		return ErrNotLeader
+	}
+	return err
+}
+
+// verifySnapshotState opens the database of a snapshot and has verify check
+// its entries
+func verifySnapshotState(path string, verify SnapshotVerifier) error {
+	db, err := bolt.Open(path, 0600, &bolt.Options{
+		ReadOnly: true,
+		Timeout:  time.Second,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to open the snapshot database: %v", err)
+	}
+	defer db.Close()
+
+	return verify(func(key string) (*physical.Entry, error) {
+		var value []byte
+		err := db.View(func(tx *bolt.Tx) error {
+			bucket := tx.Bucket(dataBucketName)
+			if bucket == nil {
+				return fmt.Errorf("snapshot database has no data")
+			}
+			if v := bucket.Get([]byte(key)); v != nil {
+				value = make([]byte, len(v))
+				copy(value, v)
+			}
+			return nil
+		})
+		if err != nil || value == nil {
+			return nil, err
+		}
+		return &physical.Entry{
+			Key:   key,
+			Value: value,
+		}, nil
+	})
+}
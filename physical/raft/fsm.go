@@ -0,0 +1,261 @@
+package raft
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/boltdb/bolt"
+	"github.com/hashicorp/raft"
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/physical"
+	log "github.com/mgutz/logxi/v1"
+)
+
+const (
+	putOp    = "put"
+	deleteOp = "delete"
+
+	// fsmDatabaseFileName is the name of the bolt database holding the
+	// storage entries
+	fsmDatabaseFileName = "vault.db"
+)
+
+// dataBucketName is the bolt bucket holding the storage entries
+var dataBucketName = []byte("data")
+
+// logOperation is an operation on a storage entry carried by a raft log
+type logOperation struct {
+	OpType string `json:"op"`
+	Key    string `json:"key"`
+	Value  []byte `json:"value,omitempty"`
+}
+
+// logData is the content of a raft log, whose operations are applied
+// atomically
+type logData struct {
+	Operations []*logOperation `json:"operations"`
+}
+
+// FSM is the finite state machine of the raft cluster. It holds the storage
+// entries in a bolt database, applying the operations of the committed logs.
+type FSM struct {
+	// l protects db, which is swapped when a snapshot is restored
+	l  sync.RWMutex
+	db *bolt.DB
+
+	path   string
+	logger log.Logger
+}
+
+// NewFSM opens the database of an FSM in the given directory
+func NewFSM(path string, logger log.Logger) (*FSM, error) {
+	f := &FSM{
+		path:   path,
+		logger: logger,
+	}
+	if err := f.openDB(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// openDB opens the database and creates its bucket
+func (f *FSM) openDB() error {
+	db, err := bolt.Open(filepath.Join(f.path, fsmDatabaseFileName), 0600, nil)
+	if err != nil {
+		return fmt.Errorf("failed to open the raft storage database: %v", err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(dataBucketName)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return fmt.Errorf("failed to create the raft storage bucket: %v", err)
+	}
+
+	f.db = db
+	return nil
+}
+
+// Close closes the database of the FSM
+func (f *FSM) Close() error {
+	f.l.Lock()
+	defer f.l.Unlock()
+
+	return f.db.Close()
+}
+
+// Get returns the entry of a key, or nil if there is none
+func (f *FSM) Get(key string) (*physical.Entry, error) {
+	f.l.RLock()
+	defer f.l.RUnlock()
+
+	var value []byte
+	err := f.db.View(func(tx *bolt.Tx) error {
+		if v := tx.Bucket(dataBucketName).Get([]byte(key)); v != nil {
+			// The value is only valid for the life of the transaction
+			value = make([]byte, len(v))
+			copy(value, v)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return nil, nil
+	}
+
+	return &physical.Entry{
+		Key:   key,
+		Value: value,
+	}, nil
+}
+
+// List returns the keys under a prefix, up to the next prefix
+func (f *FSM) List(prefix string) ([]string, error) {
+	f.l.RLock()
+	defer f.l.RUnlock()
+
+	var keys []string
+	err := f.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(dataBucketName).Cursor()
+		for k, _ := c.Seek([]byte(prefix)); k != nil && strings.HasPrefix(string(k), prefix); k, _ = c.Next() {
+			key := strings.TrimPrefix(string(k), prefix)
+			if i := strings.Index(key, "/"); i != -1 {
+				// Keys are sorted, so the entries of a folder are adjacent
+				key = key[:i+1]
+				if len(keys) > 0 && keys[len(keys)-1] == key {
+					continue
+				}
+			}
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	return keys, err
+}
+
+// apply applies operations to the database atomically
+func (f *FSM) apply(ops []*logOperation) error {
+	f.l.RLock()
+	defer f.l.RUnlock()
+
+	return f.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(dataBucketName)
+		for _, op := range ops {
+			var err error
+			switch op.OpType {
+			case putOp:
+				err = b.Put([]byte(op.Key), op.Value)
+			case deleteOp:
+				err = b.Delete([]byte(op.Key))
+			default:
+				err = fmt.Errorf("unknown operation %q", op.OpType)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Apply applies the operations of a committed log. It returns the error of
+// the operations, if any, which is the response of the log.
+func (f *FSM) Apply(l *raft.Log) interface{} {
+	var data logData
+	if err := jsonutil.DecodeJSON(l.Data, &data); err != nil {
+		f.logger.Error("physical/raft: failed to decode log", "index", l.Index, "error", err)
+		return err
+	}
+	if err := f.apply(data.Operations); err != nil {
+		f.logger.Error("physical/raft: failed to apply log", "index", l.Index, "error", err)
+		return err
+	}
+	return nil
+}
+
+// Snapshot returns a snapshot of the database as of the last applied log
+func (f *FSM) Snapshot() (raft.FSMSnapshot, error) {
+	f.l.RLock()
+	defer f.l.RUnlock()
+
+	// The read transaction keeps a consistent view of the database while it
+	// is being persisted, as logs keep being applied
+	tx, err := f.db.Begin(false)
+	if err != nil {
+		return nil, err
+	}
+	return &fsmSnapshot{tx: tx}, nil
+}
+
+// Restore replaces the database with the one of a snapshot
+func (f *FSM) Restore(rc io.ReadCloser) error {
+	defer rc.Close()
+
+	dbPath := filepath.Join(f.path, fsmDatabaseFileName)
+	restorePath := dbPath + ".restore"
+	if err := writeFile(restorePath, rc); err != nil {
+		os.Remove(restorePath)
+		return fmt.Errorf("failed to write the snapshot: %v", err)
+	}
+
+	f.l.Lock()
+	defer f.l.Unlock()
+
+	if err := f.db.Close(); err != nil {
+		os.Remove(restorePath)
+		return err
+	}
+	if err := os.Rename(restorePath, dbPath); err != nil {
+		os.Remove(restorePath)
+		// Reopen the current database rather than leaving the FSM unusable
+		if openErr := f.openDB(); openErr != nil {
+			return openErr
+		}
+		return err
+	}
+	return f.openDB()
+}
+
+// writeFile writes the content of a reader to a file and syncs it
+func writeFile(path string, r io.Reader) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(file, r); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// fsmSnapshot is a snapshot of the FSM database
+type fsmSnapshot struct {
+	tx *bolt.Tx
+}
+
+// Persist writes the database to the sink
+func (s *fsmSnapshot) Persist(sink raft.SnapshotSink) error {
+	if _, err := s.tx.WriteTo(sink); err != nil {
+		sink.Cancel()
+		return err
+	}
+	return sink.Close()
+}
+
+// Release ends the read transaction of the snapshot
+func (s *fsmSnapshot) Release() {
+	s.tx.Rollback()
+}
@@ -0,0 +1,250 @@
+package raft
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/physical"
+)
+
+// lockRetryInterval is how long a leader waits before recording the value
+// of a lock again after failing to
+const lockRetryInterval = time.Second
+
+// monitorLeadership tracks the raft leadership of the node until the
+// backend is closed
+func (b *RaftBackend) monitorLeadership(notifyCh <-chan bool) {
+	var transferTimer *time.Timer
+	for {
+		select {
+		case isLeader := <-notifyCh:
+			b.leaderL.Lock()
+			b.isLeader = isLeader
+			close(b.leaderChangeCh)
+			b.leaderChangeCh = make(chan struct{})
+			b.leaderL.Unlock()
+
+			if transferTimer != nil {
+				transferTimer.Stop()
+				transferTimer = nil
+			}
+			if isLeader {
+				transferTimer = time.AfterFunc(idleLeaderTransferDelay, b.transferIdleLeadership)
+			}
+
+		case <-b.shutdownCh:
+			if transferTimer != nil {
+				transferTimer.Stop()
+			}
+			return
+		}
+	}
+}
+
+// leadership returns whether the node is the raft leader, and a channel
+// closed once that changes
+func (b *RaftBackend) leadership() (bool, <-chan struct{}) {
+	b.leaderL.Lock()
+	defer b.leaderL.Unlock()
+
+	return b.isLeader, b.leaderChangeCh
+}
+
+// transferIdleLeadership hands the leadership over to another server if
+// the Vault of this node is neither waiting for nor holding the HA lock, as
+// the active node can only be the raft leader
+func (b *RaftBackend) transferIdleLeadership() {
+	b.lockL.Lock()
+	idle := b.lockUsers == 0
+	b.lockL.Unlock()
+
+	if isLeader, _ := b.leadership(); !isLeader || !idle {
+		return
+	}
+	b.transferLeadership()
+}
+
+// transferLeadership hands the leadership over to another server, if there
+// is any
+func (b *RaftBackend) transferLeadership() {
+	if err := b.raft.LeadershipTransfer().Error(); err != nil {
+		if b.logger.IsDebug() {
+			b.logger.Debug("physical/raft: leadership not transferred", "error", err)
+		}
+	}
+}
+
+// addLockUser and removeLockUser track the HA locks waited for or held
+func (b *RaftBackend) addLockUser() {
+	b.lockL.Lock()
+	b.lockUsers++
+	b.lockL.Unlock()
+}
+
+func (b *RaftBackend) removeLockUser() {
+	b.lockL.Lock()
+	b.lockUsers--
+	b.lockL.Unlock()
+}
+
+// claimLockKey marks the key of a lock as held by this node, unless it
+// already is. It otherwise returns a channel closed once a lock is
+// released.
+func (b *RaftBackend) claimLockKey(key string) (bool, <-chan struct{}) {
+	b.lockL.Lock()
+	defer b.lockL.Unlock()
+
+	if b.heldLocks[key] {
+		return false, b.lockReleasedCh
+	}
+	b.heldLocks[key] = true
+	return true, nil
+}
+
+// releaseLockKey marks the key of a lock as no longer held by this node
+func (b *RaftBackend) releaseLockKey(key string) {
+	b.lockL.Lock()
+	defer b.lockL.Unlock()
+
+	delete(b.heldLocks, key)
+	close(b.lockReleasedCh)
+	b.lockReleasedCh = make(chan struct{})
+}
+
+// LockWith is used for mutual exclusion based on the given key. The lock is
+// held by the raft leader.
+func (b *RaftBackend) LockWith(key, value string) (physical.Lock, error) {
+	return &RaftLock{
+		b:     b,
+		key:   key,
+		value: value,
+	}, nil
+}
+
+// HAEnabled indicates whether the HA functionality should be exposed.
+// Currently always returns true.
+func (b *RaftBackend) HAEnabled() bool {
+	return true
+}
+
+// RaftLock is a Lock held by the node that is the raft leader. Its value
+// is stored under its key so that the other nodes can find the holder.
+type RaftLock struct {
+	b     *RaftBackend
+	key   string
+	value string
+
+	held   bool
+	stopCh chan struct{}
+	l      sync.Mutex
+}
+
+// Lock waits for the node to become the raft leader, and returns a channel
+// closed once it loses the leadership
+func (l *RaftLock) Lock(stopCh <-chan struct{}) (<-chan struct{}, error) {
+	l.l.Lock()
+	defer l.l.Unlock()
+	if l.held {
+		return nil, fmt.Errorf("lock already held")
+	}
+
+	l.b.addLockUser()
+	for {
+		isLeader, changeCh := l.b.leadership()
+		var releasedCh <-chan struct{}
+		var retryCh <-chan time.Time
+		if isLeader {
+			// Other locks of the key on this node hold it as well
+			var claimed bool
+			claimed, releasedCh = l.b.claimLockKey(l.key)
+			if claimed {
+				err := l.b.applyLog([]*logOperation{{
+					OpType: putOp,
+					Key:    l.key,
+					Value:  []byte(l.value),
+				}})
+				if err == nil {
+					break
+				}
+				l.b.releaseLockKey(l.key)
+				l.b.logger.Error("physical/raft: failed to record the lock value", "error", err)
+				retryCh = time.After(lockRetryInterval)
+			}
+		}
+
+		select {
+		case <-changeCh:
+		case <-releasedCh:
+		case <-retryCh:
+		case <-stopCh:
+			l.b.removeLockUser()
+			return nil, nil
+		case <-l.b.shutdownCh:
+			l.b.removeLockUser()
+			return nil, fmt.Errorf("raft backend is closed")
+		}
+	}
+
+	l.held = true
+	l.stopCh = make(chan struct{})
+	leaderLostCh := make(chan struct{})
+	go l.monitorLeadership(l.stopCh, leaderLostCh)
+	return leaderLostCh, nil
+}
+
+// monitorLeadership closes leaderLostCh once the node loses the leadership
+// or the lock is released
+func (l *RaftLock) monitorLeadership(stopCh <-chan struct{}, leaderLostCh chan struct{}) {
+	defer close(leaderLostCh)
+	for {
+		isLeader, changeCh := l.b.leadership()
+		if !isLeader {
+			return
+		}
+		select {
+		case <-changeCh:
+		case <-stopCh:
+			return
+		case <-l.b.shutdownCh:
+			return
+		}
+	}
+}
+
+// Unlock releases the lock, handing the raft leadership over to another
+// server so that its Vault can become active
+func (l *RaftLock) Unlock() error {
+	l.l.Lock()
+	defer l.l.Unlock()
+
+	if !l.held {
+		return nil
+	}
+	close(l.stopCh)
+	l.held = false
+	l.b.releaseLockKey(l.key)
+	l.b.removeLockUser()
+
+	if isLeader, _ := l.b.leadership(); isLeader {
+		l.b.transferLeadership()
+	}
+	return nil
+}
+
+// Value returns the value of the lock, which is held as long as the
+// cluster has a leader
+func (l *RaftLock) Value() (bool, string, error) {
+	if l.b.raft.Leader() == "" {
+		return false, "", nil
+	}
+
+	entry, err := l.b.fsm.Get(l.key)
+	if err != nil {
+		return false, "", err
+	}
+	if entry == nil {
+		return false, "", nil
+	}
+	return true, string(entry.Value), nil
+}
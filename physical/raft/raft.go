@@ -0,0 +1,340 @@
+package raft
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/armon/go-metrics"
+	hclog "github.com/hashicorp/go-hclog"
+	uuid "github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/raft"
+	raftboltdb "github.com/hashicorp/raft-boltdb"
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/helper/pluginutil"
+	"github.com/hashicorp/vault/physical"
+	log "github.com/mgutz/logxi/v1"
+)
+
+const (
+	// defaultAddress is the address the raft transport listens on if none
+	// is configured
+	defaultAddress = "127.0.0.1:8202"
+
+	// nodeIDFileName is the name of the file persisting the generated
+	// identifier of the node
+	nodeIDFileName = "node-id"
+
+	// raftDirName is the name of the directory holding the raft logs and
+	// snapshots
+	raftDirName = "raft"
+
+	// applyTimeout is how long a write waits to be enqueued
+	applyTimeout = 10 * time.Second
+
+	// transportTimeout is the I/O deadline of the raft transport
+	transportTimeout = 10 * time.Second
+
+	// retainedSnapshots is the number of raft snapshots kept on disk
+	retainedSnapshots = 2
+
+	// idleLeaderTransferDelay is how long a node stays raft leader without
+	// its Vault waiting for or holding the HA lock, such as while it is
+	// sealed, before handing the leadership over
+	idleLeaderTransferDelay = 10 * time.Second
+)
+
+var (
+	// ErrAlreadyBootstrapped is returned when bootstrapping a node which is
+	// already part of a raft cluster
+	ErrAlreadyBootstrapped = errors.New("node is already part of a raft cluster")
+
+	// ErrNotLeader is returned for operations only the raft leader can
+	// perform
+	ErrNotLeader = errors.New("node is not the raft leader")
+)
+
+// RaftBackend is a physical backend replicating the storage entries over a
+// raft cluster of Vault nodes, each of which holds a full copy of the data.
+// It supports HA without an external storage service; the active node is
+// the raft leader.
+type RaftBackend struct {
+	logger log.Logger
+
+	raft      *raft.Raft
+	fsm       *FSM
+	logStore  *raftboltdb.BoltStore
+	snapStore raft.SnapshotStore
+
+	// streamLayer carries the raft connections over TLS once the TLS key of
+	// the cluster is set
+	streamLayer *tlsStreamLayer
+	transport   *raft.NetworkTransport
+
+	nodeID  string
+	address string
+
+	// leaderL protects the leadership state, and leaderChangeCh is closed
+	// and replaced whenever it changes
+	leaderL        sync.Mutex
+	isLeader       bool
+	leaderChangeCh chan struct{}
+
+	// lockUsers is the number of HA locks being waited for or held on this
+	// node. A leader without any hands the leadership over. heldLocks holds
+	// the keys of the locks held, and lockReleasedCh is closed and replaced
+	// whenever one is released.
+	lockUsers      int
+	heldLocks      map[string]bool
+	lockReleasedCh chan struct{}
+	lockL          sync.Mutex
+
+	shutdownCh chan struct{}
+	closeOnce  sync.Once
+}
+
+// NewRaftBackend constructs a raft backend storing its data under the
+// configured path and starts the raft node. A node without any raft state
+// waits to be bootstrapped or added to a cluster, and a node only connects to
+// the other nodes once the TLS key of the cluster is set.
+func NewRaftBackend(conf map[string]string, logger log.Logger) (physical.Backend, error) {
+	path, ok := conf["path"]
+	if !ok {
+		return nil, fmt.Errorf("'path' must be set")
+	}
+	raftPath := filepath.Join(path, raftDirName)
+	if err := os.MkdirAll(raftPath, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create the raft storage directory: %v", err)
+	}
+
+	nodeID, err := nodeID(path, conf["node_id"])
+	if err != nil {
+		return nil, err
+	}
+
+	address := conf["address"]
+	if address == "" {
+		address = defaultAddress
+	}
+
+	raftConfig := raft.DefaultConfig()
+	raftConfig.LocalID = raft.ServerID(nodeID)
+	raftConfig.Logger = pluginutil.NewHCLogger(logger, "physical/raft")
+	for _, key := range []string{"snapshot_threshold", "trailing_logs"} {
+		raw, ok := conf[key]
+		if !ok {
+			continue
+		}
+		value, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed parsing %s parameter: %v", key, err)
+		}
+		if key == "snapshot_threshold" {
+			raftConfig.SnapshotThreshold = value
+		} else {
+			raftConfig.TrailingLogs = value
+		}
+	}
+	stdLogger := raftConfig.Logger.StandardLogger(&hclog.StandardLoggerOptions{InferLevels: true})
+
+	b := &RaftBackend{
+		logger:         logger,
+		nodeID:         nodeID,
+		leaderChangeCh: make(chan struct{}),
+		heldLocks:      make(map[string]bool),
+		lockReleasedCh: make(chan struct{}),
+		shutdownCh:     make(chan struct{}),
+	}
+
+	b.fsm, err = NewFSM(path, logger)
+	if err != nil {
+		return nil, err
+	}
+	b.logStore, err = raftboltdb.NewBoltStore(filepath.Join(raftPath, "raft.db"))
+	if err != nil {
+		b.fsm.Close()
+		return nil, fmt.Errorf("failed to open the raft log store: %v", err)
+	}
+	b.snapStore, err = raft.NewFileSnapshotStoreWithLogger(raftPath, retainedSnapshots, stdLogger)
+	if err != nil {
+		b.closeStores()
+		return nil, fmt.Errorf("failed to open the raft snapshot store: %v", err)
+	}
+	b.streamLayer, err = newTLSStreamLayer(address)
+	if err != nil {
+		b.closeStores()
+		return nil, fmt.Errorf("failed to set up the raft transport: %v", err)
+	}
+	b.transport = raft.NewNetworkTransportWithLogger(b.streamLayer, 3, transportTimeout, stdLogger)
+	b.address = string(b.transport.LocalAddr())
+
+	// The notification channel is buffered so that raft doesn't block on it
+	notifyCh := make(chan bool, 10)
+	raftConfig.NotifyCh = notifyCh
+
+	logCache, err := raft.NewLogCache(512, b.logStore)
+	if err != nil {
+		b.transport.Close()
+		b.closeStores()
+		return nil, err
+	}
+	b.raft, err = raft.NewRaft(raftConfig, b.fsm, logCache, b.logStore, b.snapStore, b.transport)
+	if err != nil {
+		b.transport.Close()
+		b.closeStores()
+		return nil, fmt.Errorf("failed to start raft: %v", err)
+	}
+
+	if logger.IsDebug() {
+		logger.Debug("physical/raft: node started", "node_id", nodeID, "address", b.address)
+	}
+
+	go b.monitorLeadership(notifyCh)
+	return b, nil
+}
+
+// nodeID returns the configured identifier of the node, or the one
+// generated and persisted under the path on its first start
+func nodeID(path, configured string) (string, error) {
+	if configured != "" {
+		return configured, nil
+	}
+
+	idPath := filepath.Join(path, nodeIDFileName)
+	raw, err := ioutil.ReadFile(idPath)
+	switch {
+	case err == nil:
+		return strings.TrimSpace(string(raw)), nil
+	case !os.IsNotExist(err):
+		return "", fmt.Errorf("failed to read the node ID: %v", err)
+	}
+
+	id, err := uuid.GenerateUUID()
+	if err != nil {
+		return "", err
+	}
+	if err := ioutil.WriteFile(idPath, []byte(id), 0600); err != nil {
+		return "", fmt.Errorf("failed to persist the node ID: %v", err)
+	}
+	return id, nil
+}
+
+// closeStores closes the databases of the backend
+func (b *RaftBackend) closeStores() {
+	b.logStore.Close()
+	b.fsm.Close()
+}
+
+// Close shuts the raft node down and closes its stores
+func (b *RaftBackend) Close() error {
+	var err error
+	b.closeOnce.Do(func() {
+		close(b.shutdownCh)
+		err = b.raft.Shutdown().Error()
+		b.transport.Close()
+		b.closeStores()
+	})
+	return err
+}
+
+// SetTLSKey sets the TLS key of the raft cluster, which the node needs to
+// connect to the other nodes and to accept their connections
+func (b *RaftBackend) SetTLSKey(key *TLSKey) error {
+	return b.streamLayer.setTLSKey(key)
+}
+
+// NodeID returns the identifier of the node in the raft cluster
+func (b *RaftBackend) NodeID() string {
+	return b.nodeID
+}
+
+// Address returns the address of the raft transport of the node
+func (b *RaftBackend) Address() string {
+	return b.address
+}
+
+// Put is used to insert or update an entry
+func (b *RaftBackend) Put(entry *physical.Entry) error {
+	defer metrics.MeasureSince([]string{"raft", "put"}, time.Now())
+
+	return b.applyLog([]*logOperation{{
+		OpType: putOp,
+		Key:    entry.Key,
+		Value:  entry.Value,
+	}})
+}
+
+// Get is used to fetch an entry
+func (b *RaftBackend) Get(key string) (*physical.Entry, error) {
+	defer metrics.MeasureSince([]string{"raft", "get"}, time.Now())
+
+	return b.fsm.Get(key)
+}
+
+// Delete is used to permanently delete an entry
+func (b *RaftBackend) Delete(key string) error {
+	defer metrics.MeasureSince([]string{"raft", "delete"}, time.Now())
+
+	return b.applyLog([]*logOperation{{
+		OpType: deleteOp,
+		Key:    key,
+	}})
+}
+
+// List is used to list all the keys under a given prefix, up to the next
+// prefix
+func (b *RaftBackend) List(prefix string) ([]string, error) {
+	defer metrics.MeasureSince([]string{"raft", "list"}, time.Now())
+
+	return b.fsm.List(prefix)
+}
+
+// Transaction applies the operations atomically, as a single raft log
+func (b *RaftBackend) Transaction(txns []physical.TxnEntry) error {
+	defer metrics.MeasureSince([]string{"raft", "transaction"}, time.Now())
+
+	ops := make([]*logOperation, 0, len(txns))
+	for _, txn := range txns {
+		op := &logOperation{
+			Key: txn.Entry.Key,
+		}
+		switch txn.Operation {
+		case physical.PutOperation:
+			op.OpType = putOp
+			op.Value = txn.Entry.Value
+		case physical.DeleteOperation:
+			op.OpType = deleteOp
+		default:
+			return fmt.Errorf("%q is not a supported transaction operation", txn.Operation)
+		}
+		ops = append(ops, op)
+	}
+	return b.applyLog(ops)
+}
+
+// applyLog commits the operations to the raft log and waits for them to be
+// applied to the FSM of this node, which must be the leader
+func (b *RaftBackend) applyLog(ops []*logOperation) error {
+	data, err := jsonutil.EncodeJSON(&logData{Operations: ops})
+	if err != nil {
+		return err
+	}
+
+	future := b.raft.Apply(data, applyTimeout)
+	if err := future.Error(); err != nil {
+		if err == raft.ErrNotLeader {
+			return ErrNotLeader
+		}
+		return err
+	}
+	if err, ok := future.Response().(error); ok {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,369 @@
+package raft
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/raft"
+	"github.com/hashicorp/vault/helper/logformat"
+	"github.com/hashicorp/vault/physical"
+	log "github.com/mgutz/logxi/v1"
+)
+
+func testRaftBackend(t *testing.T, nodeID string) (*RaftBackend, func()) {
+	dir, err := ioutil.TempDir("", "vault-raft")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+	b, err := NewRaftBackend(map[string]string{
+		"path":    dir,
+		"node_id": nodeID,
+		"address": "127.0.0.1:0",
+	}, logger)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+
+	backend := b.(*RaftBackend)
+	return backend, func() {
+		backend.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func testBootstrappedRaftBackend(t *testing.T, nodeID string) (*RaftBackend, func()) {
+	b, cleanup := testRaftBackend(t, nodeID)
+	if err := b.Bootstrap(); err != nil {
+		cleanup()
+		t.Fatal(err)
+	}
+	if err := b.WaitForLeader(10 * time.Second); err != nil {
+		cleanup()
+		t.Fatal(err)
+	}
+	return b, cleanup
+}
+
+// setTestTLSKey sets a new TLS key on the backends, allowing them to connect
+// to each other
+func setTestTLSKey(t *testing.T, backends ...*RaftBackend) {
+	key, err := GenerateTLSKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range backends {
+		if err := b.SetTLSKey(key); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// waitForEntry polls a backend until it holds the given value for a key
+func waitForEntry(t *testing.T, b *RaftBackend, key, value string) {
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		entry, err := b.Get(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if entry != nil && string(entry.Value) == value {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%s: %q was not replicated: %#v", b.NodeID(), key, entry)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestRaft_Backend(t *testing.T) {
+	b, cleanup := testBootstrappedRaftBackend(t, "node1")
+	defer cleanup()
+
+	physical.ExerciseBackend(t, b)
+	physical.ExerciseBackend_ListPrefix(t, b)
+	physical.ExerciseTransactionalBackend(t, b)
+}
+
+func TestRaft_HABackend(t *testing.T) {
+	b, cleanup := testBootstrappedRaftBackend(t, "node1")
+	defer cleanup()
+
+	physical.ExerciseHABackend(t, b, b)
+}
+
+func TestRaft_Bootstrap(t *testing.T) {
+	b, cleanup := testRaftBackend(t, "node1")
+	defer cleanup()
+
+	hasState, err := b.HasState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hasState {
+		t.Fatal("new node should not have raft state")
+	}
+
+	// Nothing can be written before the cluster is bootstrapped
+	if err := b.Put(&physical.Entry{Key: "foo", Value: []byte("bar")}); err != ErrNotLeader {
+		t.Fatalf("expected not leader error, got: %v", err)
+	}
+
+	if err := b.Bootstrap(); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Bootstrap(); err != ErrAlreadyBootstrapped {
+		t.Fatalf("expected already bootstrapped error, got: %v", err)
+	}
+	if err := b.WaitForLeader(10 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Put(&physical.Entry{Key: "foo", Value: []byte("bar")}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRaft_Peers(t *testing.T) {
+	b1, cleanup1 := testBootstrappedRaftBackend(t, "node1")
+	defer cleanup1()
+	b2, cleanup2 := testRaftBackend(t, "node2")
+	defer cleanup2()
+	b3, cleanup3 := testRaftBackend(t, "node3")
+	defer cleanup3()
+	setTestTLSKey(t, b1, b2, b3)
+
+	if err := b1.Put(&physical.Entry{Key: "foo", Value: []byte("bar")}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the leader changes the configuration
+	if err := b2.AddPeer(b3.NodeID(), b3.Address()); err != ErrNotLeader {
+		t.Fatalf("expected not leader error, got: %v", err)
+	}
+	for _, b := range []*RaftBackend{b2, b3} {
+		if err := b1.AddPeer(b.NodeID(), b.Address()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The new peers receive the existing and new entries
+	if err := b1.Put(&physical.Entry{Key: "baz", Value: []byte("qux")}); err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range []*RaftBackend{b2, b3} {
+		waitForEntry(t, b, "foo", "bar")
+		waitForEntry(t, b, "baz", "qux")
+	}
+
+	// The followers can't write
+	if err := b2.Put(&physical.Entry{Key: "foo", Value: []byte("baz")}); err != ErrNotLeader {
+		t.Fatalf("expected not leader error, got: %v", err)
+	}
+
+	peers, err := b1.Peers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(peers) != 3 {
+		t.Fatalf("bad: %#v", peers)
+	}
+	for _, peer := range peers {
+		if peer.Leader != (peer.NodeID == "node1") || !peer.Voter {
+			t.Fatalf("bad: %#v", peer)
+		}
+	}
+
+	if err := b1.RemovePeer("node3"); err != nil {
+		t.Fatal(err)
+	}
+	peers, err = b1.Peers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(peers) != 2 || peers[0].NodeID != "node1" || peers[1].NodeID != "node2" {
+		t.Fatalf("bad: %#v", peers)
+	}
+}
+
+func TestRaft_LockHandover(t *testing.T) {
+	b1, cleanup1 := testBootstrappedRaftBackend(t, "node1")
+	defer cleanup1()
+	b2, cleanup2 := testRaftBackend(t, "node2")
+	defer cleanup2()
+	setTestTLSKey(t, b1, b2)
+
+	if err := b1.AddPeer(b2.NodeID(), b2.Address()); err != nil {
+		t.Fatal(err)
+	}
+
+	lock1, _ := b1.LockWith("core/lock", "node1")
+	leaderLostCh, err := lock1.Lock(nil)
+	if err != nil || leaderLostCh == nil {
+		t.Fatalf("failed to get the lock: %v", err)
+	}
+	waitForEntry(t, b2, "core/lock", "node1")
+
+	// The standby finds the holder of the lock
+	lock2, _ := b2.LockWith("core/lock", "node2")
+	held, value, err := lock2.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !held || value != "node1" {
+		t.Fatalf("bad: %v %q", held, value)
+	}
+
+	lockCh := make(chan (<-chan struct{}), 1)
+	go func() {
+		ch, err := lock2.Lock(nil)
+		if err != nil {
+			t.Error(err)
+		}
+		lockCh <- ch
+	}()
+
+	// Releasing the lock hands the leadership over to the waiting node
+	if err := lock1.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-leaderLostCh:
+	case <-time.After(10 * time.Second):
+		t.Fatal("leader lost channel was not closed")
+	}
+	select {
+	case ch := <-lockCh:
+		if ch == nil {
+			t.Fatal("failed to get the lock")
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("lock was not handed over")
+	}
+	waitForEntry(t, b1, "core/lock", "node2")
+
+	if err := lock2.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRaft_Snapshot(t *testing.T) {
+	b, cleanup := testBootstrappedRaftBackend(t, "node1")
+	defer cleanup()
+
+	if err := b.Put(&physical.Entry{Key: "foo", Value: []byte("bar")}); err != nil {
+		t.Fatal(err)
+	}
+
+	var snapshot bytes.Buffer
+	if err := b.Snapshot(&snapshot); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.Delete("foo"); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Put(&physical.Entry{Key: "baz", Value: []byte("qux")}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The verifier reads the entries of the snapshot and may reject it
+	var verified *physical.Entry
+	verify := func(get func(string) (*physical.Entry, error)) error {
+		var err error
+		verified, err = get("foo")
+		return err
+	}
+	reject := func(get func(string) (*physical.Entry, error)) error {
+		return errors.New("rejected")
+	}
+	data := snapshot.Bytes()
+	if err := b.Restore(bytes.NewReader(data), reject); err == nil {
+		t.Fatal("expected the snapshot to be rejected")
+	}
+	entry, err := b.Get("baz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry == nil {
+		t.Fatal("rejected snapshot should not be restored")
+	}
+
+	if err := b.Restore(bytes.NewReader(data), verify); err != nil {
+		t.Fatal(err)
+	}
+	if verified == nil || string(verified.Value) != "bar" {
+		t.Fatalf("bad: %#v", verified)
+	}
+	entry, err = b.Get("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry == nil || string(entry.Value) != "bar" {
+		t.Fatalf("bad: %#v", entry)
+	}
+	entry, err = b.Get("baz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry != nil {
+		t.Fatalf("bad: %#v", entry)
+	}
+
+	// Writes keep working on the restored state
+	if err := b.Put(&physical.Entry{Key: "baz", Value: []byte("qux")}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.Restore(bytes.NewReader([]byte("not a snapshot")), verify); err == nil {
+		t.Fatal("expected error restoring an invalid snapshot")
+	}
+}
+
+func TestRaft_TLS(t *testing.T) {
+	b1, cleanup1 := testBootstrappedRaftBackend(t, "node1")
+	defer cleanup1()
+	b2, cleanup2 := testRaftBackend(t, "node2")
+	defer cleanup2()
+	b3, cleanup3 := testRaftBackend(t, "node3")
+	defer cleanup3()
+
+	// Only the node with the TLS key of the cluster receives the storage
+	setTestTLSKey(t, b1, b2)
+	setTestTLSKey(t, b3)
+	if err := b1.Put(&physical.Entry{Key: "foo", Value: []byte("bar")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := b1.AddPeer(b2.NodeID(), b2.Address()); err != nil {
+		t.Fatal(err)
+	}
+	if err := b1.AddPeer(b3.NodeID(), b3.Address()); err != nil {
+		t.Fatal(err)
+	}
+	waitForEntry(t, b2, "foo", "bar")
+
+	time.Sleep(time.Second)
+	entry, err := b3.Get("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry != nil {
+		t.Fatalf("node with another TLS key received the storage: %#v", entry)
+	}
+
+	// The connections are mutually authenticated with the TLS key
+	conn, err := b2.streamLayer.Dial(raft.ServerAddress(b1.Address()), time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+	if _, err := b3.streamLayer.Dial(raft.ServerAddress(b1.Address()), time.Second); err == nil {
+		t.Fatal("expected the handshake with another TLS key to fail")
+	}
+}
@@ -0,0 +1,201 @@
+package raft
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"math/big"
+	mathrand "math/rand"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/raft"
+)
+
+var (
+	// ErrTLSNotSetUp is returned when dialing a node of the raft cluster
+	// before the TLS key of the cluster is set
+	ErrTLSNotSetUp = errors.New("raft TLS key is not set up")
+)
+
+// TLSKey is the certificate and private key the nodes of a raft cluster
+// authenticate each other with. Every node uses the same self-signed
+// certificate, which is the only one trusted on the raft connections.
+type TLSKey struct {
+	// CertBytes is the DER encoded certificate
+	CertBytes []byte `json:"cert_bytes"`
+
+	// KeyBytes is the DER encoded EC private key of the certificate
+	KeyBytes []byte `json:"key_bytes"`
+}
+
+// GenerateTLSKey generates the TLS key of a new raft cluster
+func GenerateTLSKey() (*TLSKey, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	host, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, err
+	}
+	host = fmt.Sprintf("raft-%s", host)
+	template := &x509.Certificate{
+		Subject: pkix.Name{
+			CommonName: host,
+		},
+		DNSNames: []string{host},
+		ExtKeyUsage: []x509.ExtKeyUsage{
+			x509.ExtKeyUsageServerAuth,
+			x509.ExtKeyUsageClientAuth,
+		},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment | x509.KeyUsageKeyAgreement | x509.KeyUsageCertSign,
+		SerialNumber:          big.NewInt(mathrand.Int63()),
+		NotBefore:             time.Now().Add(-30 * time.Second),
+		NotAfter:              time.Now().Add(262980 * time.Hour),
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	certBytes, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate the raft certificate: %v", err)
+	}
+	keyBytes, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TLSKey{
+		CertBytes: certBytes,
+		KeyBytes:  keyBytes,
+	}, nil
+}
+
+// tlsConfig returns the TLS configuration of the raft connections, which
+// both ends authenticate with the certificate of the key
+func (k *TLSKey) tlsConfig() (*tls.Config, error) {
+	cert, err := x509.ParseCertificate(k.CertBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the raft certificate: %v", err)
+	}
+	key, err := x509.ParseECPrivateKey(k.KeyBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the raft private key: %v", err)
+	}
+	if len(cert.DNSNames) == 0 {
+		return nil, fmt.Errorf("raft certificate has no DNS name")
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return &tls.Config{
+		Certificates: []tls.Certificate{{
+			Certificate: [][]byte{k.CertBytes},
+			PrivateKey:  key,
+			Leaf:        cert,
+		}},
+		RootCAs:    pool,
+		ClientCAs:  pool,
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ServerName: cert.DNSNames[0],
+		MinVersion: tls.VersionTLS12,
+	}, nil
+}
+
+// tlsStreamLayer is the stream layer of the raft transport. The nodes
+// connect over mutually authenticated TLS; connections are refused until the
+// TLS key of the cluster is set.
+type tlsStreamLayer struct {
+	listener net.Listener
+
+	l      sync.RWMutex
+	config *tls.Config
+}
+
+// newTLSStreamLayer listens for raft connections on the given address,
+// which must be reachable by the other nodes
+func newTLSStreamLayer(address string) (*tlsStreamLayer, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	addr, ok := listener.Addr().(*net.TCPAddr)
+	if !ok || addr.IP.IsUnspecified() {
+		listener.Close()
+		return nil, fmt.Errorf("raft address %q is not advertisable", address)
+	}
+
+	return &tlsStreamLayer{
+		listener: listener,
+	}, nil
+}
+
+// setTLSKey sets the TLS key the connections are authenticated with
+func (s *tlsStreamLayer) setTLSKey(key *TLSKey) error {
+	config, err := key.tlsConfig()
+	if err != nil {
+		return err
+	}
+
+	s.l.Lock()
+	s.config = config
+	s.l.Unlock()
+	return nil
+}
+
+// tlsConfig returns the TLS configuration of the connections, or nil if the
+// TLS key is not set yet
+func (s *tlsStreamLayer) tlsConfig() *tls.Config {
+	s.l.RLock()
+	defer s.l.RUnlock()
+	return s.config
+}
+
+// Accept waits for the next connection of another node
+func (s *tlsStreamLayer) Accept() (net.Conn, error) {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+
+		config := s.tlsConfig()
+		if config == nil {
+			conn.Close()
+			continue
+		}
+		return tls.Server(conn, config), nil
+	}
+}
+
+// Close stops listening for connections
+func (s *tlsStreamLayer) Close() error {
+	return s.listener.Close()
+}
+
+// Addr returns the address the other nodes connect to
+func (s *tlsStreamLayer) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// Dial connects to another node
+func (s *tlsStreamLayer) Dial(address raft.ServerAddress, timeout time.Duration) (net.Conn, error) {
+	config := s.tlsConfig()
+	if config == nil {
+		return nil, ErrTLSNotSetUp
+	}
+
+	dialer := &net.Dialer{
+		Timeout: timeout,
+	}
+	return tls.DialWithDialer(dialer, "tcp", string(address), config)
+}
@@ -2,6 +2,8 @@ package s3
 
 import (
 	"bytes"
+	"crypto/md5"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"net/http"
@@ -9,6 +11,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	log "github.com/mgutz/logxi/v1"
@@ -25,13 +28,39 @@ import (
 	"github.com/hashicorp/vault/physical"
 )
 
+const (
+	// kmsKeyIDS3 is the kms_key_id value requesting the encryption of the
+	// objects with the AWS managed KMS key of S3
+	kmsKeyIDS3 = "aws/s3"
+
+	// maxShardPrefixLength is the maximum length of the shard prefixes, as
+	// the backend lists every shard
+	maxShardPrefixLength = 2
+)
+
+// s3Client is the subset of the S3 API used by the backend
+type s3Client interface {
+	PutObject(*s3.PutObjectInput) (*s3.PutObjectOutput, error)
+	GetObject(*s3.GetObjectInput) (*s3.GetObjectOutput, error)
+	DeleteObject(*s3.DeleteObjectInput) (*s3.DeleteObjectOutput, error)
+	ListObjectsV2(*s3.ListObjectsV2Input) (*s3.ListObjectsV2Output, error)
+}
+
 // S3Backend is a physical backend that stores data
 // within an S3 bucket.
 type S3Backend struct {
 	bucket     string
-	client     *s3.S3
+	client     s3Client
 	logger     log.Logger
 	permitPool *physical.PermitPool
+
+	// kmsKeyID is the KMS key encrypting the objects server-side, if any
+	kmsKeyID string
+
+	// shardPrefixLength is the number of hexadecimal characters of the hash
+	// of each key prefixing its object key, spreading the keys over
+	// 16^shardPrefixLength prefixes of the bucket
+	shardPrefixLength int
 }
 
 // NewS3Backend constructs a S3 backend using a pre-existing
@@ -112,15 +141,54 @@ func NewS3Backend(conf map[string]string, logger log.Logger) (physical.Backend,
 		}
 	}
 
+	var shardPrefixLength int
+	if shardPrefixLengthStr, ok := conf["shard_prefix_length"]; ok {
+		shardPrefixLength, err = strconv.Atoi(shardPrefixLengthStr)
+		if err != nil {
+			return nil, errwrap.Wrapf("failed parsing shard_prefix_length parameter: {{err}}", err)
+		}
+		if shardPrefixLength < 0 || shardPrefixLength > maxShardPrefixLength {
+			return nil, fmt.Errorf("'shard_prefix_length' must be between 0 and %d", maxShardPrefixLength)
+		}
+		if logger.IsDebug() {
+			logger.Debug("s3: shard_prefix_length set", "shard_prefix_length", shardPrefixLength)
+		}
+	}
+
 	s := &S3Backend{
-		client:     s3conn,
-		bucket:     bucket,
-		logger:     logger,
-		permitPool: physical.NewPermitPool(maxParInt),
+		client:            s3conn,
+		bucket:            bucket,
+		logger:            logger,
+		permitPool:        physical.NewPermitPool(maxParInt),
+		kmsKeyID:          conf["kms_key_id"],
+		shardPrefixLength: shardPrefixLength,
 	}
 	return s, nil
 }
 
+// objectKey returns the key of the object storing the entry of the given key
+func (s *S3Backend) objectKey(key string) string {
+	if s.shardPrefixLength == 0 {
+		return key
+	}
+	hash := md5.Sum([]byte(key))
+	return hex.EncodeToString(hash[:])[:s.shardPrefixLength] + "/" + key
+}
+
+// shardPrefixes returns the prefixes of all the shards of the bucket
+func (s *S3Backend) shardPrefixes() []string {
+	if s.shardPrefixLength == 0 {
+		return []string{""}
+	}
+
+	count := 1 << (4 * uint(s.shardPrefixLength))
+	prefixes := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		prefixes = append(prefixes, fmt.Sprintf("%0*x/", s.shardPrefixLength, i))
+	}
+	return prefixes
+}
+
 // Put is used to insert or update an entry
 func (s *S3Backend) Put(entry *physical.Entry) error {
 	defer metrics.MeasureSince([]string{"s3", "put"}, time.Now())
@@ -128,12 +196,19 @@ func (s *S3Backend) Put(entry *physical.Entry) error {
 	s.permitPool.Acquire()
 	defer s.permitPool.Release()
 
-	_, err := s.client.PutObject(&s3.PutObjectInput{
+	input := &s3.PutObjectInput{
 		Bucket: aws.String(s.bucket),
-		Key:    aws.String(entry.Key),
+		Key:    aws.String(s.objectKey(entry.Key)),
 		Body:   bytes.NewReader(entry.Value),
-	})
+	}
+	if s.kmsKeyID != "" {
+		input.ServerSideEncryption = aws.String(s3.ServerSideEncryptionAwsKms)
+		if s.kmsKeyID != kmsKeyIDS3 {
+			input.SSEKMSKeyId = aws.String(s.kmsKeyID)
+		}
+	}
 
+	_, err := s.client.PutObject(input)
 	if err != nil {
 		return err
 	}
@@ -150,7 +225,7 @@ func (s *S3Backend) Get(key string) (*physical.Entry, error) {
 
 	resp, err := s.client.GetObject(&s3.GetObjectInput{
 		Bucket: aws.String(s.bucket),
-		Key:    aws.String(key),
+		Key:    aws.String(s.objectKey(key)),
 	})
 	if awsErr, ok := err.(awserr.RequestFailure); ok {
 		// Return nil on 404s, error on anything else
@@ -189,7 +264,7 @@ func (s *S3Backend) Delete(key string) error {
 
 	_, err := s.client.DeleteObject(&s3.DeleteObjectInput{
 		Bucket: aws.String(s.bucket),
-		Key:    aws.String(key),
+		Key:    aws.String(s.objectKey(key)),
 	})
 
 	if err != nil {
@@ -204,49 +279,89 @@ func (s *S3Backend) Delete(key string) error {
 func (s *S3Backend) List(prefix string) ([]string, error) {
 	defer metrics.MeasureSince([]string{"s3", "list"}, time.Now())
 
+	// The keys under the prefix may be stored in every shard, which are
+	// listed concurrently within the limit of the permit pool
+	shards := s.shardPrefixes()
+	shardKeys := make([][]string, len(shards))
+	shardErrs := make([]error, len(shards))
+	var wg sync.WaitGroup
+	for i, shard := range shards {
+		wg.Add(1)
+		go func(i int, shard string) {
+			defer wg.Done()
+			shardKeys[i], shardErrs[i] = s.listShard(shard, prefix)
+		}(i, shard)
+	}
+	wg.Wait()
+
+	seen := make(map[string]bool)
+	keys := []string{}
+	for i := range shards {
+		if shardErrs[i] != nil {
+			return nil, shardErrs[i]
+		}
+		// A folder may be listed by several shards
+		for _, key := range shardKeys[i] {
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+
+	return keys, nil
+}
+
+// listShard lists the keys under the prefix in a shard, following the
+// continuation tokens of the truncated responses, which hold up to 1000 keys
+func (s *S3Backend) listShard(shard, prefix string) ([]string, error) {
 	s.permitPool.Acquire()
 	defer s.permitPool.Release()
 
 	params := &s3.ListObjectsV2Input{
 		Bucket:    aws.String(s.bucket),
-		Prefix:    aws.String(prefix),
+		Prefix:    aws.String(shard + prefix),
 		Delimiter: aws.String("/"),
 	}
 
 	keys := []string{}
+	for {
+		page, err := s.client.ListObjectsV2(params)
+		if err != nil {
+			return nil, err
+		}
+		if page == nil {
+			return nil, fmt.Errorf("got nil response from S3 but no error")
+		}
 
-	err := s.client.ListObjectsV2Pages(params,
-		func(page *s3.ListObjectsV2Output, lastPage bool) bool {
-			if page != nil {
-				// Add truncated 'folder' paths
-				for _, commonPrefix := range page.CommonPrefixes {
-					// Avoid panic
-					if commonPrefix == nil {
-						continue
-					}
-
-					commonPrefix := strings.TrimPrefix(*commonPrefix.Prefix, prefix)
-					keys = append(keys, commonPrefix)
-				}
-				// Add objects only from the current 'folder'
-				for _, key := range page.Contents {
-					// Avoid panic
-					if key == nil {
-						continue
-					}
-
-					key := strings.TrimPrefix(*key.Key, prefix)
-					keys = append(keys, key)
-				}
+		// Add truncated 'folder' paths
+		for _, commonPrefix := range page.CommonPrefixes {
+			// Avoid panic
+			if commonPrefix == nil || commonPrefix.Prefix == nil {
+				continue
 			}
-			return true
-		})
 
-	if err != nil {
-		return nil, err
-	}
+			keys = append(keys, strings.TrimPrefix(*commonPrefix.Prefix, shard+prefix))
+		}
+		// Add objects only from the current 'folder'
+		for _, object := range page.Contents {
+			// Avoid panic
+			if object == nil || object.Key == nil {
+				continue
+			}
 
-	sort.Strings(keys)
+			keys = append(keys, strings.TrimPrefix(*object.Key, shard+prefix))
+		}
 
-	return keys, nil
+		if !aws.BoolValue(page.IsTruncated) {
+			return keys, nil
+		}
+		if aws.StringValue(page.NextContinuationToken) == "" {
+			return nil, fmt.Errorf("got truncated list of %q from S3 without continuation token", prefix)
+		}
+		params.ContinuationToken = page.NextContinuationToken
+	}
 }
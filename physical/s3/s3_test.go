@@ -1,9 +1,14 @@
 package s3
 
 import (
+	"bytes"
 	"fmt"
+	"io/ioutil"
 	"math/rand"
 	"os"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -13,6 +18,7 @@ import (
 	log "github.com/mgutz/logxi/v1"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
 )
@@ -92,3 +98,189 @@ func TestS3Backend(t *testing.T) {
 	physical.ExerciseBackend(t, b)
 	physical.ExerciseBackend_ListPrefix(t, b)
 }
+
+// fakeS3Client is an in-memory S3 bucket returning the listings in pages of
+// up to 1000 keys
+type fakeS3Client struct {
+	l         sync.Mutex
+	objects   map[string][]byte
+	lastPut   *s3.PutObjectInput
+	listCalls int
+}
+
+func newFakeS3Backend(shardPrefixLength int) (*S3Backend, *fakeS3Client) {
+	client := &fakeS3Client{
+		objects: make(map[string][]byte),
+	}
+	return &S3Backend{
+		bucket:            "vault",
+		client:            client,
+		logger:            logformat.NewVaultLogger(log.LevelTrace),
+		permitPool:        physical.NewPermitPool(physical.DefaultParallelOperations),
+		shardPrefixLength: shardPrefixLength,
+	}, client
+}
+
+func (f *fakeS3Client) PutObject(input *s3.PutObjectInput) (*s3.PutObjectOutput, error) {
+	value, err := ioutil.ReadAll(input.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	f.l.Lock()
+	defer f.l.Unlock()
+	f.objects[*input.Key] = value
+	f.lastPut = input
+	return &s3.PutObjectOutput{}, nil
+}
+
+func (f *fakeS3Client) GetObject(input *s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+	f.l.Lock()
+	defer f.l.Unlock()
+	value, ok := f.objects[*input.Key]
+	if !ok {
+		return nil, awserr.NewRequestFailure(awserr.New(s3.ErrCodeNoSuchKey, "not found", nil), 404, "")
+	}
+	return &s3.GetObjectOutput{
+		Body:          ioutil.NopCloser(bytes.NewReader(value)),
+		ContentLength: aws.Int64(int64(len(value))),
+	}, nil
+}
+
+func (f *fakeS3Client) DeleteObject(input *s3.DeleteObjectInput) (*s3.DeleteObjectOutput, error) {
+	f.l.Lock()
+	defer f.l.Unlock()
+	delete(f.objects, *input.Key)
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+func (f *fakeS3Client) ListObjectsV2(input *s3.ListObjectsV2Input) (*s3.ListObjectsV2Output, error) {
+	f.l.Lock()
+	defer f.l.Unlock()
+	f.listCalls++
+
+	prefix := aws.StringValue(input.Prefix)
+	items := make(map[string]bool)
+	for key := range f.objects {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		if i := strings.Index(key[len(prefix):], "/"); i >= 0 {
+			items[key[:len(prefix)+i+1]] = true
+		} else {
+			items[key] = false
+		}
+	}
+	var sorted []string
+	for item := range items {
+		if item > aws.StringValue(input.ContinuationToken) {
+			sorted = append(sorted, item)
+		}
+	}
+	sort.Strings(sorted)
+
+	output := &s3.ListObjectsV2Output{}
+	if len(sorted) > 1000 {
+		sorted = sorted[:1000]
+		output.IsTruncated = aws.Bool(true)
+		output.NextContinuationToken = aws.String(sorted[len(sorted)-1])
+	}
+	for _, item := range sorted {
+		if items[item] {
+			output.CommonPrefixes = append(output.CommonPrefixes, &s3.CommonPrefix{Prefix: aws.String(item)})
+		} else {
+			output.Contents = append(output.Contents, &s3.Object{Key: aws.String(item)})
+		}
+	}
+	return output, nil
+}
+
+func TestS3Backend_Sharding(t *testing.T) {
+	for _, shardPrefixLength := range []int{0, 1, 2} {
+		b, client := newFakeS3Backend(shardPrefixLength)
+		physical.ExerciseBackend(t, b)
+		physical.ExerciseBackend_ListPrefix(t, b)
+
+		if err := b.Put(&physical.Entry{Key: "foo/bar", Value: []byte("baz")}); err != nil {
+			t.Fatal(err)
+		}
+		for key := range client.objects {
+			if shardPrefixLength > 0 && len(strings.SplitN(key, "/", 2)[0]) != shardPrefixLength {
+				t.Fatalf("bad object key for shard prefix length %d: %q", shardPrefixLength, key)
+			}
+		}
+	}
+}
+
+func TestS3Backend_ListPagination(t *testing.T) {
+	b, client := newFakeS3Backend(1)
+
+	var expected []string
+	for i := 0; i < 2500; i++ {
+		key := fmt.Sprintf("%04d", i)
+		expected = append(expected, key)
+		if err := b.Put(&physical.Entry{Key: "foo/" + key, Value: []byte(key)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Folders are listed once, though they span several shards
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprintf("sub%d/", i)
+		expected = append(expected, key)
+		for j := 0; j < 5; j++ {
+			if err := b.Put(&physical.Entry{Key: fmt.Sprintf("foo/%s%d", key, j)}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	sort.Strings(expected)
+
+	client.listCalls = 0
+	keys, err := b.List("foo/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(keys, ",") != strings.Join(expected, ",") {
+		t.Fatalf("bad: %d keys, expected %d", len(keys), len(expected))
+	}
+	// Each of the 16 shards holds less than 1000 keys of the folder
+	if client.listCalls != 16 {
+		t.Fatalf("bad: %d list calls", client.listCalls)
+	}
+
+	// Without sharding, the keys span several pages
+	b, client = newFakeS3Backend(0)
+	for _, key := range expected {
+		if err := b.Put(&physical.Entry{Key: "foo/" + key}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	keys, err = b.List("foo/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(keys, ",") != strings.Join(expected, ",") {
+		t.Fatalf("bad: %d keys, expected %d", len(keys), len(expected))
+	}
+	if client.listCalls != 3 {
+		t.Fatalf("bad: %d list calls", client.listCalls)
+	}
+}
+
+func TestS3Backend_KMSKeyID(t *testing.T) {
+	for kmsKeyID, expectedKeyID := range map[string]string{"": "", kmsKeyIDS3: "", "my-key": "my-key"} {
+		b, client := newFakeS3Backend(0)
+		b.kmsKeyID = kmsKeyID
+		if err := b.Put(&physical.Entry{Key: "foo", Value: []byte("bar")}); err != nil {
+			t.Fatal(err)
+		}
+
+		sse := aws.StringValue(client.lastPut.ServerSideEncryption)
+		if (kmsKeyID == "" && sse != "") || (kmsKeyID != "" && sse != s3.ServerSideEncryptionAwsKms) {
+			t.Fatalf("bad server-side encryption for %q: %q", kmsKeyID, sse)
+		}
+		if keyID := aws.StringValue(client.lastPut.SSEKMSKeyId); keyID != expectedKeyID {
+			t.Fatalf("bad KMS key ID for %q: %q", kmsKeyID, keyID)
+		}
+	}
+}
@@ -341,6 +341,96 @@ func ExerciseHABackend(t *testing.T, b HABackend, b2 HABackend) {
 	lock2.Unlock()
 }
 
+// ExerciseFencingHABackend checks that writes fenced with a lock are
+// rejected once another holder acquires it, and are no longer fenced once
+// the lock is released. b and b2 must share storage. expire must make a held
+// lock lose its hold without its holder releasing it, as when a session
+// times out.
+func ExerciseFencingHABackend(t *testing.T, b FencingHABackend, b2 FencingHABackend, expire func(Lock)) {
+	lock, err := b.LockWith("fence", "bar")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := lock.Lock(nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	token, err := lock.(FencedLock).FencingToken()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := b.RegisterActiveNodeLock(lock); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	entry := &Entry{Key: "fenced", Value: []byte("bar")}
+	if err := b.(Backend).Put(entry); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Another holder takes over the lock
+	expire(lock)
+	lock2, err := b2.LockWith("fence", "baz")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := lock2.Lock(nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	token2, err := lock2.(FencedLock).FencingToken()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if token2 <= token {
+		t.Fatalf("fencing token did not increase: %d, %d", token, token2)
+	}
+
+	// Writes fenced with the old lock are rejected
+	entry.Value = []byte("stale")
+	if err := b.(Backend).Put(entry); err != ErrFenced {
+		t.Fatalf("expected fenced put, got: %v", err)
+	}
+	if err := b.(Backend).Delete(entry.Key); err != ErrFenced {
+		t.Fatalf("expected fenced delete, got: %v", err)
+	}
+
+	// The new holder can write once it registers its lock
+	if err := b2.RegisterActiveNodeLock(lock2); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	entry.Value = []byte("baz")
+	if err := b2.(Backend).Put(entry); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	out, err := b2.(Backend).Get(entry.Key)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out == nil || string(out.Value) != "baz" {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	// Writes are no longer fenced once the holder steps down, even after
+	// the lock is acquired again
+	lock2.Unlock()
+	lock3, err := b.LockWith("fence", "qux")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := lock3.Lock(nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer lock3.Unlock()
+	entry.Value = []byte("qux")
+	if err := b2.(Backend).Put(entry); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Registering a lock that is not held fails
+	if err := b2.RegisterActiveNodeLock(lock2); err == nil {
+		t.Fatalf("expected error registering a released lock")
+	}
+}
+
 func ExerciseTransactionalBackend(t *testing.T, b Backend) {
 	tb, ok := b.(Transactional)
 	if !ok {
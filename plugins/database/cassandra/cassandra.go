@@ -1,6 +1,7 @@
 package cassandra
 
 import (
+	"fmt"
 	"strings"
 	"time"
 
@@ -18,6 +19,7 @@ import (
 const (
 	defaultUserCreationCQL = `CREATE USER '{{username}}' WITH PASSWORD '{{password}}' NOSUPERUSER;`
 	defaultUserDeletionCQL = `DROP USER '{{username}}';`
+	defaultUserRotationCQL = `ALTER USER '{{username}}' WITH PASSWORD '{{password}}';`
 	cassandraTypeName      = "cassandra"
 )
 
@@ -175,3 +177,66 @@ func (c *Cassandra) RevokeUser(statements dbplugin.Statements, username string)
 
 	return result.ErrorOrNil()
 }
+
+// SetCredentials sets the password of an existing user
+func (c *Cassandra) SetCredentials(statements dbplugin.Statements, username, password string) error {
+	// Grab the lock
+	c.Lock()
+	defer c.Unlock()
+
+	return c.setPassword(statements.RotationStatements, username, password)
+}
+
+// RotateRootCredentials sets a new random password for the user Vault
+// connects as, and returns the updated connection configuration
+func (c *Cassandra) RotateRootCredentials(statements dbplugin.Statements) (config map[string]interface{}, err error) {
+	// Grab the lock
+	c.Lock()
+	defer c.Unlock()
+
+	producer, ok := c.ConnectionProducer.(*cassandraConnectionProducer)
+	if !ok {
+		return nil, fmt.Errorf("root credential rotation is not supported by this connection type")
+	}
+
+	password, err := c.GeneratePassword()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.setPassword(statements.RotationStatements, producer.Username, password); err != nil {
+		return nil, err
+	}
+
+	return producer.setRootPassword(password), nil
+}
+
+// setPassword runs the rotation statements for the user. The caller of this
+// function needs to hold the lock.
+func (c *Cassandra) setPassword(rotationCQL, username, password string) error {
+	session, err := c.getConnection()
+	if err != nil {
+		return err
+	}
+
+	if rotationCQL == "" {
+		rotationCQL = defaultUserRotationCQL
+	}
+
+	for _, query := range strutil.ParseArbitraryStringSlice(rotationCQL, ";") {
+		query = strings.TrimSpace(query)
+		if len(query) == 0 {
+			continue
+		}
+
+		err := session.Query(dbutil.QueryHelper(query, map[string]string{
+			"username": username,
+			"password": password,
+		})).Exec()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -32,6 +32,7 @@ type cassandraConnectionProducer struct {
 	PemBundle         string      `json:"pem_bundle" structs:"pem_bundle" mapstructure:"pem_bundle"`
 	PemJSON           string      `json:"pem_json" structs:"pem_json" mapstructure:"pem_json"`
 
+	rawConfig      map[string]interface{}
 	connectTimeout time.Duration
 	certificate    string
 	privateKey     string
@@ -51,6 +52,7 @@ func (c *cassandraConnectionProducer) Initialize(conf map[string]interface{}, ve
 	if err != nil {
 		return err
 	}
+	c.rawConfig = conf
 
 	if c.ConnectTimeoutRaw == nil {
 		c.ConnectTimeoutRaw = "0s"
@@ -149,6 +151,26 @@ func (c *cassandraConnectionProducer) Close() error {
 	return nil
 }
 
+// setRootPassword makes the producer connect with the new password, closing
+// the current session, and returns the updated configuration. The caller of
+// this function needs to hold the producer's lock.
+func (c *cassandraConnectionProducer) setRootPassword(password string) map[string]interface{} {
+	if c.session != nil {
+		c.session.Close()
+	}
+	c.session = nil
+	c.Password = password
+
+	config := make(map[string]interface{}, len(c.rawConfig))
+	for k, v := range c.rawConfig {
+		config[k] = v
+	}
+	config["password"] = password
+	c.rawConfig = config
+
+	return config
+}
+
 func (c *cassandraConnectionProducer) createSession() (*gocql.Session, error) {
 	hosts := strings.Split(c.Hosts, ",")
 	clusterConfig := gocql.NewCluster(hosts...)
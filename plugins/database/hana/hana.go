@@ -17,7 +17,8 @@ import (
 )
 
 const (
-	hanaTypeName = "hdb"
+	hanaTypeName          = "hdb"
+	defaultHANARotateStmt = `ALTER USER {{name}} PASSWORD "{{password}}"`
 )
 
 // HANA is an implementation of Database interface
@@ -192,6 +193,84 @@ func (h *HANA) RenewUser(statements dbplugin.Statements, username string, expira
 	return nil
 }
 
+// SetCredentials sets the password of an existing user
+func (h *HANA) SetCredentials(statements dbplugin.Statements, username, password string) error {
+	h.Lock()
+	defer h.Unlock()
+
+	return h.setPassword(statements.RotationStatements, username, password)
+}
+
+// RotateRootCredentials sets a new random password for the user Vault
+// connects as, and returns the updated connection configuration
+func (h *HANA) RotateRootCredentials(statements dbplugin.Statements) (config map[string]interface{}, err error) {
+	h.Lock()
+	defer h.Unlock()
+
+	producer, ok := h.ConnectionProducer.(*connutil.SQLConnectionProducer)
+	if !ok {
+		return nil, fmt.Errorf("root credential rotation is not supported by this connection type")
+	}
+	username, err := producer.RootUsername()
+	if err != nil {
+		return nil, err
+	}
+
+	password, err := h.GeneratePassword()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.setPassword(statements.RotationStatements, username, password); err != nil {
+		return nil, err
+	}
+
+	return producer.SetRootPassword(password), nil
+}
+
+// setPassword runs the rotation statements for the user. The caller of this
+// function needs to hold the lock.
+func (h *HANA) setPassword(rotationStmts, username, password string) error {
+	if rotationStmts == "" {
+		rotationStmts = defaultHANARotateStmt
+	}
+
+	// Get the connection
+	db, err := h.getConnection()
+	if err != nil {
+		return err
+	}
+
+	// Start a transaction
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, query := range strutil.ParseArbitraryStringSlice(rotationStmts, ";") {
+		query = strings.TrimSpace(query)
+		if len(query) == 0 {
+			continue
+		}
+
+		stmt, err := tx.Prepare(dbutil.QueryHelper(query, map[string]string{
+			"name":     username,
+			"password": password,
+		}))
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+		if _, err := stmt.Exec(); err != nil {
+			return err
+		}
+	}
+
+	// Commit the transaction
+	return tx.Commit()
+}
+
 // Revoking hana user will deactivate user and try to perform a soft drop
 func (h *HANA) RevokeUser(statements dbplugin.Statements, username string) error {
 	// default revoke will be a soft drop on user
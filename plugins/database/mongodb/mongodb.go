@@ -202,3 +202,67 @@ func (m *MongoDB) RevokeUser(statements dbplugin.Statements, username string) er
 
 	return nil
 }
+
+// SetCredentials sets the password of an existing user. The rotation
+// statement is a JSON blob with an optional db value naming the user's
+// authentication database, which defaults to "admin".
+func (m *MongoDB) SetCredentials(statements dbplugin.Statements, username, password string) error {
+	// Grab the lock
+	m.Lock()
+	defer m.Unlock()
+
+	session, err := m.getConnection()
+	if err != nil {
+		return err
+	}
+
+	// If no rotation statements provided, pass in empty JSON
+	rotationStatement := statements.RotationStatements
+	if rotationStatement == "" {
+		rotationStatement = `{}`
+	}
+
+	var mongoCS mongoDBStatement
+	err = json.Unmarshal([]byte(rotationStatement), &mongoCS)
+	if err != nil {
+		return err
+	}
+
+	db := mongoCS.DB
+	// If db is not specified, use the default authenticationDatabase "admin"
+	if db == "" {
+		db = "admin"
+	}
+
+	updateUserCmd := updateUserCommand{
+		Username: username,
+		Password: password,
+	}
+
+	err = session.DB(db).Run(updateUserCmd, nil)
+	switch {
+	case err == nil:
+	case err == io.EOF, strings.Contains(err.Error(), "EOF"):
+		if err := m.ConnectionProducer.Close(); err != nil {
+			return errwrap.Wrapf("error closing EOF'd mongo connection: {{err}}", err)
+		}
+		session, err := m.getConnection()
+		if err != nil {
+			return err
+		}
+		err = session.DB(db).Run(updateUserCmd, nil)
+		if err != nil {
+			return err
+		}
+	default:
+		return err
+	}
+
+	return nil
+}
+
+// RotateRootCredentials is not supported on MongoDB, as the credentials are
+// part of the connection URL.
+func (m *MongoDB) RotateRootCredentials(statements dbplugin.Statements) (config map[string]interface{}, err error) {
+	return nil, fmt.Errorf("root credential rotation is not supported by the MongoDB plugin")
+}
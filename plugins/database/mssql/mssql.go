@@ -16,7 +16,12 @@ import (
 	"github.com/hashicorp/vault/plugins/helper/database/dbutil"
 )
 
-const msSQLTypeName = "mssql"
+const (
+	msSQLTypeName          = "mssql"
+	defaultMSSQLRotateStmt = `
+ALTER LOGIN [{{name}}] WITH PASSWORD = '{{password}}';
+`
+)
 
 // MSSQL is an implementation of Database interface
 type MSSQL struct {
@@ -143,6 +148,84 @@ func (m *MSSQL) RenewUser(statements dbplugin.Statements, username string, expir
 	return nil
 }
 
+// SetCredentials sets the password of an existing user
+func (m *MSSQL) SetCredentials(statements dbplugin.Statements, username, password string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	return m.setPassword(statements.RotationStatements, username, password)
+}
+
+// RotateRootCredentials sets a new random password for the user Vault
+// connects as, and returns the updated connection configuration
+func (m *MSSQL) RotateRootCredentials(statements dbplugin.Statements) (config map[string]interface{}, err error) {
+	m.Lock()
+	defer m.Unlock()
+
+	producer, ok := m.ConnectionProducer.(*connutil.SQLConnectionProducer)
+	if !ok {
+		return nil, fmt.Errorf("root credential rotation is not supported by this connection type")
+	}
+	username, err := producer.RootUsername()
+	if err != nil {
+		return nil, err
+	}
+
+	password, err := m.GeneratePassword()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.setPassword(statements.RotationStatements, username, password); err != nil {
+		return nil, err
+	}
+
+	return producer.SetRootPassword(password), nil
+}
+
+// setPassword runs the rotation statements for the user. The caller of this
+// function needs to hold the lock.
+func (m *MSSQL) setPassword(rotationStmts, username, password string) error {
+	if rotationStmts == "" {
+		rotationStmts = defaultMSSQLRotateStmt
+	}
+
+	// Get the connection
+	db, err := m.getConnection()
+	if err != nil {
+		return err
+	}
+
+	// Start a transaction
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, query := range strutil.ParseArbitraryStringSlice(rotationStmts, ";") {
+		query = strings.TrimSpace(query)
+		if len(query) == 0 {
+			continue
+		}
+
+		stmt, err := tx.Prepare(dbutil.QueryHelper(query, map[string]string{
+			"name":     username,
+			"password": password,
+		}))
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+		if _, err := stmt.Exec(); err != nil {
+			return err
+		}
+	}
+
+	// Commit the transaction
+	return tx.Commit()
+}
+
 // RevokeUser attempts to drop the specified user. It will first attempt to disable login,
 // then kill pending connections from that user, and finally drop the user and login from the
 // database instance.
@@ -2,6 +2,7 @@ package mysql
 
 import (
 	"database/sql"
+	"fmt"
 	"strings"
 	"time"
 
@@ -20,6 +21,9 @@ const (
 		REVOKE ALL PRIVILEGES, GRANT OPTION FROM '{{name}}'@'%'; 
 		DROP USER '{{name}}'@'%'
 	`
+	defaultMysqlRotationStmts = `
+		ALTER USER '{{name}}'@'%' IDENTIFIED BY '{{password}}';
+	`
 	mySQLTypeName = "mysql"
 )
 
@@ -216,3 +220,78 @@ func (m *MySQL) RevokeUser(statements dbplugin.Statements, username string) erro
 
 	return nil
 }
+
+// SetCredentials sets the password of an existing user
+func (m *MySQL) SetCredentials(statements dbplugin.Statements, username, password string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	return m.setPassword(statements.RotationStatements, username, password)
+}
+
+// RotateRootCredentials sets a new random password for the user Vault
+// connects as, and returns the updated connection configuration
+func (m *MySQL) RotateRootCredentials(statements dbplugin.Statements) (config map[string]interface{}, err error) {
+	m.Lock()
+	defer m.Unlock()
+
+	producer, ok := m.ConnectionProducer.(*connutil.SQLConnectionProducer)
+	if !ok {
+		return nil, fmt.Errorf("root credential rotation is not supported by this connection type")
+	}
+	username, err := producer.RootUsername()
+	if err != nil {
+		return nil, err
+	}
+
+	password, err := m.GeneratePassword()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.setPassword(statements.RotationStatements, username, password); err != nil {
+		return nil, err
+	}
+
+	return producer.SetRootPassword(password), nil
+}
+
+// setPassword runs the rotation statements for the user. The caller of this
+// function needs to hold the lock.
+func (m *MySQL) setPassword(rotationStmts, username, password string) error {
+	if rotationStmts == "" {
+		rotationStmts = defaultMysqlRotationStmts
+	}
+
+	// Get the connection
+	db, err := m.getConnection()
+	if err != nil {
+		return err
+	}
+
+	// Start a transaction
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, query := range strutil.ParseArbitraryStringSlice(rotationStmts, ";") {
+		query = strings.TrimSpace(query)
+		if len(query) == 0 {
+			continue
+		}
+
+		// Not a prepared statement, for the same reason as in RevokeUser
+		query = dbutil.QueryHelper(query, map[string]string{
+			"name":     username,
+			"password": password,
+		})
+		if _, err := tx.Exec(query); err != nil {
+			return err
+		}
+	}
+
+	// Commit the transaction
+	return tx.Commit()
+}
@@ -21,6 +21,9 @@ const (
 	postgreSQLTypeName      string = "postgres"
 	defaultPostgresRenewSQL        = `
 ALTER ROLE "{{name}}" VALID UNTIL '{{expiration}}';
+`
+	defaultPostgresRotationSQL = `
+ALTER ROLE "{{name}}" WITH PASSWORD '{{password}}';
 `
 )
 
@@ -201,6 +204,83 @@ func (p *PostgreSQL) RenewUser(statements dbplugin.Statements, username string,
 	return nil
 }
 
+// SetCredentials sets the password of an existing user
+func (p *PostgreSQL) SetCredentials(statements dbplugin.Statements, username, password string) error {
+	p.Lock()
+	defer p.Unlock()
+
+	return p.setPassword(statements.RotationStatements, username, password)
+}
+
+// RotateRootCredentials sets a new random password for the user Vault
+// connects as, and returns the updated connection configuration
+func (p *PostgreSQL) RotateRootCredentials(statements dbplugin.Statements) (config map[string]interface{}, err error) {
+	p.Lock()
+	defer p.Unlock()
+
+	producer, ok := p.ConnectionProducer.(*connutil.SQLConnectionProducer)
+	if !ok {
+		return nil, fmt.Errorf("root credential rotation is not supported by this connection type")
+	}
+	username, err := producer.RootUsername()
+	if err != nil {
+		return nil, err
+	}
+
+	password, err := p.GeneratePassword()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.setPassword(statements.RotationStatements, username, password); err != nil {
+		return nil, err
+	}
+
+	return producer.SetRootPassword(password), nil
+}
+
+// setPassword runs the rotation statements for the user. The caller of this
+// function needs to hold the lock.
+func (p *PostgreSQL) setPassword(rotationStmts, username, password string) error {
+	if rotationStmts == "" {
+		rotationStmts = defaultPostgresRotationSQL
+	}
+
+	db, err := p.getConnection()
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		tx.Rollback()
+	}()
+
+	for _, query := range strutil.ParseArbitraryStringSlice(rotationStmts, ";") {
+		query = strings.TrimSpace(query)
+		if len(query) == 0 {
+			continue
+		}
+		stmt, err := tx.Prepare(dbutil.QueryHelper(query, map[string]string{
+			"name":     username,
+			"password": password,
+		}))
+		if err != nil {
+			return err
+		}
+
+		defer stmt.Close()
+		if _, err := stmt.Exec(); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
 func (p *PostgreSQL) RevokeUser(statements dbplugin.Statements, username string) error {
 	// Grab the lock
 	p.Lock()
@@ -281,6 +281,109 @@ func TestPostgreSQL_RevokeUser(t *testing.T) {
 	}
 }
 
+func TestPostgreSQL_SetCredentials(t *testing.T) {
+	cleanup, connURL := preparePostgresTestContainer(t)
+	defer cleanup()
+
+	connectionDetails := map[string]interface{}{
+		"connection_url": connURL,
+	}
+
+	dbRaw, _ := New()
+	db := dbRaw.(*PostgreSQL)
+	err := db.Initialize(connectionDetails, true)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	statements := dbplugin.Statements{
+		CreationStatements: testPostgresRole,
+	}
+
+	usernameConfig := dbplugin.UsernameConfig{
+		DisplayName: "test",
+		RoleName:    "test",
+	}
+
+	username, password, err := db.CreateUser(statements, usernameConfig, time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Test default rotation statements
+	newPassword := "A1a-rotated-password"
+	if err := db.SetCredentials(statements, username, newPassword); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if err = testCredsExist(t, connURL, username, password); err == nil {
+		t.Fatal("Credentials were not rotated")
+	}
+	if err = testCredsExist(t, connURL, username, newPassword); err != nil {
+		t.Fatalf("Could not connect with rotated credentials: %s", err)
+	}
+
+	// Test custom rotation statements
+	statements.RotationStatements = defaultPostgresRotationSQL
+	password = "A1a-custom-password"
+	if err := db.SetCredentials(statements, username, password); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if err = testCredsExist(t, connURL, username, password); err != nil {
+		t.Fatalf("Could not connect with rotated credentials: %s", err)
+	}
+}
+
+func TestPostgreSQL_RotateRootCredentials(t *testing.T) {
+	if os.Getenv("PG_URL") != "" {
+		t.Skip("rotating the root credentials of an external database")
+	}
+
+	cleanup, connURL := preparePostgresTestContainer(t)
+	defer cleanup()
+
+	connectionDetails := map[string]interface{}{
+		"connection_url": strings.Replace(connURL, "postgres:secret", "{{username}}:{{password}}", 1),
+		"username":       "postgres",
+		"password":       "secret",
+	}
+
+	dbRaw, _ := New()
+	db := dbRaw.(*PostgreSQL)
+	err := db.Initialize(connectionDetails, true)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer db.Close()
+
+	newConf, err := db.RotateRootCredentials(dbplugin.Statements{})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	password := newConf["password"].(string)
+	if password == "secret" {
+		t.Fatal("expected a new root password")
+	}
+
+	if err = testCredsExist(t, connURL, "postgres", "secret"); err == nil {
+		t.Fatal("Root credentials were not rotated")
+	}
+	if err = testCredsExist(t, connURL, "postgres", password); err != nil {
+		t.Fatalf("Could not connect with rotated root credentials: %s", err)
+	}
+
+	// The plugin keeps working with the new password
+	usernameConfig := dbplugin.UsernameConfig{
+		DisplayName: "test",
+		RoleName:    "test",
+	}
+	_, _, err = db.CreateUser(dbplugin.Statements{CreationStatements: testPostgresRole}, usernameConfig, time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
 func testCredsExist(t testing.TB, connURL, username, password string) error {
 	// Log in with the new creds
 	connURL = strings.Replace(connURL, "postgres:secret", fmt.Sprintf("%s:%s", username, password), 1)
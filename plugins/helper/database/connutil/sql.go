@@ -17,6 +17,11 @@ type SQLConnectionProducer struct {
 	MaxOpenConnections       int         `json:"max_open_connections" structs:"max_open_connections" mapstructure:"max_open_connections"`
 	MaxIdleConnections       int         `json:"max_idle_connections" structs:"max_idle_connections" mapstructure:"max_idle_connections"`
 	MaxConnectionLifetimeRaw interface{} `json:"max_connection_lifetime" structs:"max_connection_lifetime" mapstructure:"max_connection_lifetime"`
+	Username                 string      `json:"username" structs:"username" mapstructure:"username"`
+	Password                 string      `json:"password" structs:"password" mapstructure:"password"`
+
+	// RawConfig is the configuration the producer was initialized with
+	RawConfig map[string]interface{}
 
 	Type                  string
 	maxConnectionLifetime time.Duration
@@ -33,6 +38,7 @@ func (c *SQLConnectionProducer) Initialize(conf map[string]interface{}, verifyCo
 	if err != nil {
 		return err
 	}
+	c.RawConfig = conf
 
 	if len(c.ConnectionURL) == 0 {
 		return fmt.Errorf("connection_url cannot be empty")
@@ -96,7 +102,10 @@ func (c *SQLConnectionProducer) Connection() (interface{}, error) {
 	}
 
 	// Otherwise, attempt to make connection
-	conn := c.ConnectionURL
+	conn := strings.NewReplacer(
+		"{{username}}", c.Username,
+		"{{password}}", c.Password,
+	).Replace(c.ConnectionURL)
 
 	// Ensure timezone is set to UTC for all the conenctions
 	if strings.HasPrefix(conn, "postgres://") || strings.HasPrefix(conn, "postgresql://") {
@@ -136,3 +145,38 @@ func (c *SQLConnectionProducer) Close() error {
 
 	return nil
 }
+
+// RootUsername returns the username of the credentials the producer connects
+// with, if they can be rotated. That requires the connection URL to take the
+// password from the "password" field. The caller of this function needs to
+// hold the producer's lock.
+func (c *SQLConnectionProducer) RootUsername() (string, error) {
+	if c.Username == "" || c.Password == "" {
+		return "", fmt.Errorf("username and password are required to rotate the root credentials")
+	}
+	if !strings.Contains(c.ConnectionURL, "{{password}}") {
+		return "", fmt.Errorf("connection_url must use the {{password}} template to rotate the root credentials")
+	}
+
+	return c.Username, nil
+}
+
+// SetRootPassword makes the producer connect with the new password, closing
+// the current connections, and returns the updated configuration. The caller
+// of this function needs to hold the producer's lock.
+func (c *SQLConnectionProducer) SetRootPassword(password string) map[string]interface{} {
+	if c.db != nil {
+		c.db.Close()
+	}
+	c.db = nil
+	c.Password = password
+
+	config := make(map[string]interface{}, len(c.RawConfig))
+	for k, v := range c.RawConfig {
+		config[k] = v
+	}
+	config["password"] = password
+	c.RawConfig = config
+
+	return config
+}
@@ -1,6 +1,7 @@
 package vault
 
 import (
+	"context"
 	"reflect"
 	"strings"
 
@@ -10,6 +11,10 @@ import (
 	"github.com/hashicorp/vault/logical"
 )
 
+// parameterEntityIDTemplate is replaced in the values of allowed and denied
+// parameters by the entity ID of the requesting token
+const parameterEntityIDTemplate = "{{identity.entity.id}}"
+
 // ACL is used to wrap a set of policies to provide
 // an efficient interface for access control.
 type ACL struct {
@@ -21,15 +26,33 @@ type ACL struct {
 
 	// root is enabled if the "root" named policy is present.
 	root bool
+
+	// exactGrants and globGrants record the policy path rules merged into
+	// each of the rules of the exact and glob trees
+	exactGrants map[string][]*CapabilityGrant
+	globGrants  map[string][]*CapabilityGrant
+}
+
+// CapabilityGrant identifies a path rule of a policy granting capabilities
+type CapabilityGrant struct {
+	// Policy is the name of the policy
+	Policy string `json:"policy" structs:"policy" mapstructure:"policy"`
+
+	// Path is the path of the rule, ending with "*" for globs
+	Path string `json:"path" structs:"path" mapstructure:"path"`
+
+	capabilities uint32
 }
 
 // New is used to construct a policy based ACL from a set of policies.
 func NewACL(policies []*Policy) (*ACL, error) {
 	// Initialize
 	a := &ACL{
-		exactRules: radix.New(),
-		globRules:  radix.New(),
-		root:       false,
+		exactRules:  radix.New(),
+		globRules:   radix.New(),
+		root:        false,
+		exactGrants: make(map[string][]*CapabilityGrant),
+		globGrants:  make(map[string][]*CapabilityGrant),
 	}
 
 	// Inject each policy
@@ -45,10 +68,21 @@ func NewACL(policies []*Policy) (*ACL, error) {
 		for _, pc := range policy.Paths {
 			// Check which tree to use
 			tree := a.exactRules
+			grants := a.exactGrants
+			grantPath := pc.Prefix
 			if pc.Glob {
 				tree = a.globRules
+				grants = a.globGrants
+				grantPath += "*"
 			}
 
+			// Record the rule for the capability lookups
+			grants[pc.Prefix] = append(grants[pc.Prefix], &CapabilityGrant{
+				Policy:       policy.Name,
+				Path:         grantPath,
+				capabilities: pc.Permissions.CapabilitiesBitmap,
+			})
+
 			// Check for an existing policy
 			raw, ok := tree.Get(pc.Prefix)
 			if !ok {
@@ -74,6 +108,8 @@ func NewACL(policies []*Policy) (*ACL, error) {
 				existingPerms.CapabilitiesBitmap = DenyCapabilityInt
 				existingPerms.AllowedParameters = nil
 				existingPerms.DeniedParameters = nil
+				existingPerms.RequiredParameters = nil
+				existingPerms.ControlGroup = nil
 				goto INSERT
 
 			default:
@@ -106,7 +142,7 @@ func NewACL(policies []*Policy) (*ACL, error) {
 
 			if len(pc.Permissions.AllowedParameters) > 0 {
 				if existingPerms.AllowedParameters == nil {
-					existingPerms.AllowedParameters = pc.Permissions.AllowedParameters
+					existingPerms.AllowedParameters = copyParameters(pc.Permissions.AllowedParameters)
 				} else {
 					for key, value := range pc.Permissions.AllowedParameters {
 						pcValue, ok := existingPerms.AllowedParameters[key]
@@ -116,7 +152,7 @@ func NewACL(policies []*Policy) (*ACL, error) {
 							existingPerms.AllowedParameters[key] = []interface{}{}
 						} else {
 							// Merge the two maps, appending values on key conflict.
-							existingPerms.AllowedParameters[key] = append(value, existingPerms.AllowedParameters[key]...)
+							existingPerms.AllowedParameters[key] = append(append([]interface{}{}, value...), existingPerms.AllowedParameters[key]...)
 						}
 					}
 				}
@@ -124,7 +160,7 @@ func NewACL(policies []*Policy) (*ACL, error) {
 
 			if len(pc.Permissions.DeniedParameters) > 0 {
 				if existingPerms.DeniedParameters == nil {
-					existingPerms.DeniedParameters = pc.Permissions.DeniedParameters
+					existingPerms.DeniedParameters = copyParameters(pc.Permissions.DeniedParameters)
 				} else {
 					for key, value := range pc.Permissions.DeniedParameters {
 						pcValue, ok := existingPerms.DeniedParameters[key]
@@ -134,12 +170,26 @@ func NewACL(policies []*Policy) (*ACL, error) {
 							existingPerms.DeniedParameters[key] = []interface{}{}
 						} else {
 							// Merge the two maps, appending values on key conflict.
-							existingPerms.DeniedParameters[key] = append(value, existingPerms.DeniedParameters[key]...)
+							existingPerms.DeniedParameters[key] = append(append([]interface{}{}, value...), existingPerms.DeniedParameters[key]...)
 						}
 					}
 				}
 			}
 
+			// Parameters required by any of the policies are required
+			for _, key := range pc.Permissions.RequiredParameters {
+				existingPerms.RequiredParameters = strutil.AppendIfMissing(existingPerms.RequiredParameters, key)
+			}
+
+			// The control groups of all the policies must be satisfied
+			if pc.Permissions.ControlGroup != nil {
+				if existingPerms.ControlGroup == nil {
+					existingPerms.ControlGroup = pc.Permissions.ControlGroup.Clone()
+				} else {
+					existingPerms.ControlGroup.merge(pc.Permissions.ControlGroup)
+				}
+			}
+
 		INSERT:
 			tree.Insert(pc.Prefix, existingPerms)
 
@@ -186,6 +236,9 @@ CHECK:
 	if capabilities&UpdateCapabilityInt > 0 {
 		pathCapabilities = append(pathCapabilities, UpdateCapability)
 	}
+	if capabilities&PatchCapabilityInt > 0 {
+		pathCapabilities = append(pathCapabilities, PatchCapability)
+	}
 	if capabilities&DeleteCapabilityInt > 0 {
 		pathCapabilities = append(pathCapabilities, DeleteCapability)
 	}
@@ -201,6 +254,60 @@ CHECK:
 	return
 }
 
+// CapabilityGrants returns, for each of the capabilities on the given path,
+// the policy path rules granting it. Explicit denials are reported under
+// "deny", while paths matched by no rule have no grants.
+func (a *ACL) CapabilityGrants(path string) map[string][]*CapabilityGrant {
+	ret := make(map[string][]*CapabilityGrant)
+	if a.root {
+		ret[RootCapability] = []*CapabilityGrant{
+			&CapabilityGrant{
+				Policy: "root",
+				Path:   "*",
+			},
+		}
+		return ret
+	}
+
+	// Use the rules of the same path as Capabilities does
+	grants, ok := a.exactGrants[path]
+	if !ok {
+		prefix, _, ok := a.globRules.LongestPrefix(path)
+		if !ok {
+			return ret
+		}
+		grants = a.globGrants[prefix]
+	}
+
+	for _, capability := range a.Capabilities(path) {
+		bit := cap2Int[capability]
+		for _, grant := range grants {
+			if grant.capabilities&bit > 0 {
+				ret[capability] = append(ret[capability], grant)
+			}
+		}
+	}
+	return ret
+}
+
+// ControlGroup returns the control group governing the given path, if any
+func (a *ACL) ControlGroup(path string) *ControlGroup {
+	// Root is never subject to control groups
+	if a.root {
+		return nil
+	}
+
+	// Find an exact matching rule, look for glob if no match
+	raw, ok := a.exactRules.Get(path)
+	if !ok {
+		_, raw, ok = a.globRules.LongestPrefix(path)
+		if !ok {
+			return nil
+		}
+	}
+	return raw.(*Permissions).ControlGroup
+}
+
 // AllowOperation is used to check if the given operation is permitted. The
 // first bool indicates if an op is allowed, the second whether sudo priviliges
 // exist for that op and path.
@@ -244,12 +351,14 @@ CHECK:
 	sudo := capabilities&SudoCapabilityInt > 0
 	operationAllowed := false
 	switch op {
-	case logical.ReadOperation:
+	case logical.ReadOperation, logical.HeaderOperation:
 		operationAllowed = capabilities&ReadCapabilityInt > 0
 	case logical.ListOperation:
 		operationAllowed = capabilities&ListCapabilityInt > 0
 	case logical.UpdateOperation:
 		operationAllowed = capabilities&UpdateCapabilityInt > 0
+	case logical.PatchOperation:
+		operationAllowed = capabilities&PatchCapabilityInt > 0
 	case logical.DeleteOperation:
 		operationAllowed = capabilities&DeleteCapabilityInt > 0
 	case logical.CreateOperation:
@@ -288,7 +397,14 @@ CHECK:
 
 	// Only check parameter permissions for operations that can modify
 	// parameters.
-	if op == logical.UpdateOperation || op == logical.CreateOperation {
+	if op == logical.UpdateOperation || op == logical.CreateOperation || op == logical.PatchOperation {
+		// Check that all the required parameters are present
+		for _, parameter := range permissions.RequiredParameters {
+			if !dataHasParameter(req.Data, parameter) {
+				return false, sudo
+			}
+		}
+
 		// If there are no data fields, allow
 		if len(req.Data) == 0 {
 			return true, sudo
@@ -307,7 +423,7 @@ CHECK:
 			// Check if parameter has been explictly denied
 			if valueSlice, ok := permissions.DeniedParameters[strings.ToLower(parameter)]; ok {
 				// If the value exists in denied values slice, deny
-				if valueInParameterList(value, valueSlice) {
+				if valueInParameterList(value, valueSlice, req.EntityID) {
 					return false, sudo
 				}
 			}
@@ -333,7 +449,7 @@ CHECK:
 
 			// If the value doesn't exists in the allowed values slice,
 			// deny
-			if ok && !valueInParameterList(value, valueSlice) {
+			if ok && !valueInParameterList(value, valueSlice, req.EntityID) {
 				return false, sudo
 			}
 		}
@@ -342,19 +458,44 @@ CHECK:
 	return true, sudo
 }
 
-func valueInParameterList(v interface{}, list []interface{}) bool {
+// copyParameters returns a copy of the given parameters, so that merging the
+// permissions of an ACL doesn't modify the policies, which are cached and
+// shared between ACLs
+func copyParameters(parameters map[string][]interface{}) map[string][]interface{} {
+	ret := make(map[string][]interface{}, len(parameters))
+	for key, value := range parameters {
+		ret[key] = value
+	}
+	return ret
+}
+
+// dataHasParameter returns whether the request data contains the given
+// parameter, compared case-insensitively
+func dataHasParameter(data map[string]interface{}, parameter string) bool {
+	for key := range data {
+		if strings.ToLower(key) == parameter {
+			return true
+		}
+	}
+	return false
+}
+
+func valueInParameterList(v interface{}, list []interface{}, entityID string) bool {
 	// Empty list is equivalent to the item always existing in the list
 	if len(list) == 0 {
 		return true
 	}
 
-	return valueInSlice(v, list)
+	return valueInSlice(v, list, entityID)
 }
 
-func valueInSlice(v interface{}, list []interface{}) bool {
+func valueInSlice(v interface{}, list []interface{}, entityID string) bool {
 	for _, el := range list {
 		if reflect.TypeOf(el).String() == "string" && reflect.TypeOf(v).String() == "string" {
-			item := el.(string)
+			item, ok := expandParameterTemplate(el.(string), entityID)
+			if !ok {
+				continue
+			}
 			val := v.(string)
 
 			if strutil.GlobbedStringsMatch(item, val) {
@@ -367,3 +508,62 @@ func valueInSlice(v interface{}, list []interface{}) bool {
 
 	return false
 }
+
+// expandParameterTemplate replaces the parameterEntityIDTemplate in a
+// parameter value with the entity ID of the requesting token. Values
+// referencing the template never match requests made without an entity.
+func expandParameterTemplate(value, entityID string) (string, bool) {
+	if !strings.Contains(value, parameterEntityIDTemplate) {
+		return value, true
+	}
+	if entityID == "" {
+		return "", false
+	}
+	return strings.Replace(value, parameterEntityIDTemplate, entityID, -1), true
+}
+
+// requestACLKey is the key of the requestACL in the context of an authorized
+// request
+type requestACLKey struct{}
+
+// requestACL is the ACL of the token of an authorized request. It is attached
+// to the request context so that backends returning other paths on behalf of
+// the token can check them.
+type requestACL struct {
+	core *Core
+	acl  *ACL
+	te   *TokenEntry
+}
+
+// withRequestACL attaches the ACL of the token of the request to its context
+func (c *Core) withRequestACL(req *logical.Request, acl *ACL, te *TokenEntry) {
+	req.SetContext(context.WithValue(req.Context(), requestACLKey{}, &requestACL{
+		core: c,
+		acl:  acl,
+		te:   te,
+	}))
+}
+
+// requestACLFromContext returns the ACL of the token of the request, or nil if
+// the request wasn't authorized by the core
+func requestACLFromContext(req *logical.Request) *requestACL {
+	r, _ := req.Context().Value(requestACLKey{}).(*requestACL)
+	return r
+}
+
+// allowMountOperation reports whether the ACL allows the operation on a path
+// relative to the mount of the routed request
+func (r *requestACL) allowMountOperation(req *logical.Request, op logical.Operation, path string) bool {
+	aclPath, ok := r.core.tokenACLPath(r.te, &logical.Request{
+		Namespace: req.Namespace,
+		Path:      req.MountPoint + path,
+	})
+	if !ok {
+		return false
+	}
+	allowed, _ := r.acl.AllowOperation(&logical.Request{
+		Operation: op,
+		Path:      aclPath,
+	})
+	return allowed
+}
@@ -40,7 +40,7 @@ func TestACL_Capabilities(t *testing.T) {
 	}
 
 	actual = acl.Capabilities("dev/")
-	expected = []string{"sudo", "read", "list", "update", "delete", "create"}
+	expected = []string{"sudo", "read", "list", "update", "patch", "delete", "create"}
 	if !reflect.DeepEqual(actual, expected) {
 		t.Fatalf("bad: path:%s\ngot\n%#v\nexpected\n%#v\n", "dev/", actual, expected)
 	}
@@ -53,6 +53,67 @@ func TestACL_Capabilities(t *testing.T) {
 
 }
 
+func TestACL_CapabilityGrants(t *testing.T) {
+	policy1, err := Parse(`
+name = "ops"
+path "secret/*" {
+	capabilities = ["read", "list"]
+}
+path "secret/prod" {
+	capabilities = ["read"]
+}
+`)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	policy2, err := Parse(`
+name = "dev"
+path "secret/*" {
+	capabilities = ["read", "update"]
+}
+path "secret/prod" {
+	capabilities = ["deny"]
+}
+`)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	acl, err := NewACL([]*Policy{policy1, policy2})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	grants := acl.CapabilityGrants("secret/foo")
+	if len(grants) != 3 || len(grants["read"]) != 2 ||
+		grants["read"][0].Policy != "ops" || grants["read"][0].Path != "secret/*" ||
+		grants["read"][1].Policy != "dev" ||
+		len(grants["update"]) != 1 || grants["update"][0].Policy != "dev" ||
+		len(grants["list"]) != 1 || grants["list"][0].Policy != "ops" {
+		t.Fatalf("bad: %#v", grants)
+	}
+
+	// Explicit denials are reported
+	grants = acl.CapabilityGrants("secret/prod")
+	if len(grants) != 1 || len(grants["deny"]) != 1 ||
+		grants["deny"][0].Policy != "dev" || grants["deny"][0].Path != "secret/prod" {
+		t.Fatalf("bad: %#v", grants)
+	}
+
+	if grants := acl.CapabilityGrants("other"); len(grants) != 0 {
+		t.Fatalf("bad: %#v", grants)
+	}
+
+	acl, err = NewACL([]*Policy{&Policy{Name: "root"}})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	grants = acl.CapabilityGrants("any/path")
+	if len(grants["root"]) != 1 || grants["root"][0].Policy != "root" {
+		t.Fatalf("bad: %#v", grants)
+	}
+}
+
 func TestACL_Root(t *testing.T) {
 	// Create the root policy ACL
 	policy := []*Policy{&Policy{Name: "root"}}
@@ -106,10 +167,13 @@ func TestACL_Single(t *testing.T) {
 
 		{logical.ReadOperation, "dev/foo", true, true},
 		{logical.UpdateOperation, "dev/foo", true, true},
+		{logical.PatchOperation, "dev/foo", true, true},
 
 		{logical.DeleteOperation, "stage/foo", true, false},
+		{logical.PatchOperation, "stage/foo", true, false},
 		{logical.ListOperation, "stage/aws/foo", true, true},
 		{logical.UpdateOperation, "stage/aws/foo", true, true},
+		{logical.PatchOperation, "stage/aws/foo", false, true},
 		{logical.UpdateOperation, "stage/aws/policy/foo", true, true},
 
 		{logical.DeleteOperation, "prod/foo", false, false},
@@ -416,6 +480,61 @@ func TestACL_ValuePermissions(t *testing.T) {
 	}
 }
 
+func TestACL_RequiredAndTemplatedParameters(t *testing.T) {
+	policy, err := Parse(requiredParametersPolicy)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	policy2, err := Parse(requiredParametersPolicy2)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	acl, err := NewACL([]*Policy{policy, policy2})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	type tcase struct {
+		path     string
+		entityID string
+		data     map[string]interface{}
+		allowed  bool
+	}
+
+	tcases := []tcase{
+		{"secret/required", "", nil, false},
+		{"secret/required", "", map[string]interface{}{"owner": "foo"}, false},
+		{"secret/required", "", map[string]interface{}{"OWNER": "foo", "team": "bar"}, true},
+		{"secret/required", "", map[string]interface{}{"owner": "foo", "team": "bar", "other": "baz"}, true},
+		{"secret/templated", "entity1", map[string]interface{}{"owner": "entity1"}, true},
+		{"secret/templated", "entity1", map[string]interface{}{"owner": "entity2"}, false},
+		{"secret/templated", "entity1", map[string]interface{}{"owner": "team-entity1-prod"}, true},
+		{"secret/templated", "", map[string]interface{}{"owner": "{{identity.entity.id}}"}, false},
+		{"secret/templated", "", map[string]interface{}{"owner": ""}, false},
+		{"secret/templated", "entity1", map[string]interface{}{"owner": "entity1", "target": "entity1"}, false},
+		{"secret/templated", "entity1", map[string]interface{}{"owner": "entity1", "target": "entity2"}, true},
+		{"secret/denied", "", map[string]interface{}{"owner": "foo"}, false},
+	}
+
+	for _, tc := range tcases {
+		request := logical.Request{Path: tc.path, Data: tc.data, EntityID: tc.entityID}
+		for _, op := range []logical.Operation{logical.UpdateOperation, logical.CreateOperation} {
+			request.Operation = op
+			allowed, _ := acl.AllowOperation(&request)
+			if allowed != tc.allowed {
+				t.Fatalf("bad: case %#v: %v", tc, allowed)
+			}
+		}
+	}
+
+	// Required parameters don't apply to reads
+	request := logical.Request{Path: "secret/required", Operation: logical.ReadOperation}
+	if allowed, _ := acl.AllowOperation(&request); !allowed {
+		t.Fatalf("expected the read to be allowed")
+	}
+}
+
 // NOTE: this test doesn't catch any races ATM
 func TestACL_CreationRace(t *testing.T) {
 	policy, err := Parse(valuePermissionsPolicy)
@@ -763,3 +882,36 @@ path "test/star" {
 	}
 }
 `
+
+var requiredParametersPolicy = `
+name = "required"
+path "secret/required" {
+	capabilities = ["create", "read", "update"]
+	required_parameters = ["Owner"]
+}
+path "secret/templated" {
+	capabilities = ["create", "update"]
+	allowed_parameters = {
+		"owner" = ["{{identity.entity.id}}", "team-{{identity.entity.id}}-*"]
+		"*" = []
+	}
+	denied_parameters = {
+		"target" = ["{{identity.entity.id}}"]
+	}
+}
+path "secret/denied" {
+	capabilities = ["create", "update"]
+	required_parameters = ["owner"]
+}
+`
+
+var requiredParametersPolicy2 = `
+name = "required2"
+path "secret/required" {
+	capabilities = ["create", "update"]
+	required_parameters = ["team", "owner"]
+}
+path "secret/denied" {
+	capabilities = ["deny"]
+}
+`
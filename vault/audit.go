@@ -14,9 +14,11 @@ import (
 	"github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/go-uuid"
 	"github.com/hashicorp/vault/audit"
+	"github.com/hashicorp/vault/helper/cel"
 	"github.com/hashicorp/vault/helper/jsonutil"
 	"github.com/hashicorp/vault/helper/salt"
 	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/physical"
 )
 
 const (
@@ -36,6 +38,10 @@ const (
 	// auditTableType is the value we expect to find for the audit table and
 	// corresponding entries
 	auditTableType = "audit"
+
+	// auditFilterOption is the audit backend option holding the expression
+	// selecting the requests logged by the backend
+	auditFilterOption = "filter"
 )
 
 var (
@@ -89,6 +95,11 @@ func (c *Core) enableAudit(entry *MountEntry) error {
 	viewPath := auditBarrierPrefix + entry.UUID + "/"
 	view := NewBarrierView(c.barrier, viewPath)
 
+	filter, err := auditFilter(entry)
+	if err != nil {
+		return err
+	}
+
 	// Lookup the new backend
 	backend, err := c.newAuditBackend(entry, view, entry.Options)
 	if err != nil {
@@ -101,13 +112,14 @@ func (c *Core) enableAudit(entry *MountEntry) error {
 	newTable := c.audit.shallowClone()
 	newTable.Entries = append(newTable.Entries, entry)
 	if err := c.persistAudit(newTable, entry.Local); err != nil {
+		closeAuditBackend(c.logger, entry.Path, backend)
 		return errors.New("failed to update audit table")
 	}
 
 	c.audit = newTable
 
 	// Register the backend
-	c.auditBroker.Register(entry.Path, backend, view)
+	c.auditBroker.Register(entry.Path, backend, view, filter)
 	if c.logger.IsInfo() {
 		c.logger.Info("core: enabled audit backend", "path", entry.Path, "type", entry.Type)
 	}
@@ -261,6 +273,7 @@ func (c *Core) persistAudit(table *MountTable, localOnly bool) error {
 		}
 	}
 
+	var txns []*TxnEntry
 	if !localOnly {
 		// Marshal the table
 		compressedBytes, err := jsonutil.EncodeJSONAndCompress(nonLocalAudit, nil)
@@ -275,11 +288,7 @@ func (c *Core) persistAudit(table *MountTable, localOnly bool) error {
 			Value: compressedBytes,
 		}
 
-		// Write to the physical backend
-		if err := c.barrier.Put(entry); err != nil {
-			c.logger.Error("core: failed to persist audit table", "error", err)
-			return err
-		}
+		txns = append(txns, &TxnEntry{Operation: physical.PutOperation, Entry: entry})
 	}
 
 	// Repeat with local audit
@@ -294,8 +303,9 @@ func (c *Core) persistAudit(table *MountTable, localOnly bool) error {
 		Value: compressedBytes,
 	}
 
-	if err := c.barrier.Put(entry); err != nil {
-		c.logger.Error("core: failed to persist local audit table", "error", err)
+	txns = append(txns, &TxnEntry{Operation: physical.PutOperation, Entry: entry})
+	if err := c.barrier.Transaction(txns); err != nil {
+		c.logger.Error("core: failed to persist audit table", "error", err)
 		return err
 	}
 
@@ -306,6 +316,7 @@ func (c *Core) persistAudit(table *MountTable, localOnly bool) error {
 // initialize the audit backends
 func (c *Core) setupAudits() error {
 	broker := NewAuditBroker(c.logger)
+	broker.mountEntry = c.router.MatchingMountEntry
 
 	c.auditLock.Lock()
 	defer c.auditLock.Unlock()
@@ -317,6 +328,12 @@ func (c *Core) setupAudits() error {
 		viewPath := auditBarrierPrefix + entry.UUID + "/"
 		view := NewBarrierView(c.barrier, viewPath)
 
+		filter, err := auditFilter(entry)
+		if err != nil {
+			c.logger.Error("core: failed to compile audit filter", "path", entry.Path, "error", err)
+			continue
+		}
+
 		// Initialize the backend
 		backend, err := c.newAuditBackend(entry, view, entry.Options)
 		if err != nil {
@@ -329,7 +346,7 @@ func (c *Core) setupAudits() error {
 		}
 
 		// Mount the backend
-		broker.Register(entry.Path, backend, view)
+		broker.Register(entry.Path, backend, view, filter)
 
 		successCount += 1
 	}
@@ -354,6 +371,10 @@ func (c *Core) teardownAudits() error {
 		}
 	}
 
+	if c.auditBroker != nil {
+		c.auditBroker.closeBackends()
+	}
+
 	c.audit = nil
 	c.auditBroker = nil
 	return nil
@@ -393,6 +414,7 @@ func (c *Core) newAuditBackend(entry *MountEntry, view logical.Storage, conf map
 		SaltView:   view,
 		SaltConfig: saltConfig,
 		Config:     conf,
+		Logger:     c.logger,
 	})
 	if err != nil {
 		return nil, err
@@ -432,9 +454,26 @@ func defaultAuditTable() *MountTable {
 	return table
 }
 
+// auditFilter compiles the filter option of an audit backend, if any
+func auditFilter(entry *MountEntry) (*cel.Program, error) {
+	expr := entry.Options[auditFilterOption]
+	if strings.TrimSpace(expr) == "" {
+		return nil, nil
+	}
+	filter, err := cel.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid audit filter: %v", err)
+	}
+	return filter, nil
+}
+
 type backendEntry struct {
 	backend audit.Backend
 	view    *BarrierView
+
+	// filter selects the requests logged by the backend; all the requests
+	// are logged if nil
+	filter *cel.Program
 }
 
 // AuditBroker is used to provide a single ingest interface to auditable
@@ -443,6 +482,10 @@ type AuditBroker struct {
 	sync.RWMutex
 	backends map[string]backendEntry
 	logger   log.Logger
+
+	// mountEntry returns the mount of a request path, which is not yet set
+	// on the requests when they are logged
+	mountEntry func(path string) *MountEntry
 }
 
 // NewAuditBroker creates a new audit broker
@@ -455,20 +498,55 @@ func NewAuditBroker(log log.Logger) *AuditBroker {
 }
 
 // Register is used to add new audit backend to the broker
-func (a *AuditBroker) Register(name string, b audit.Backend, v *BarrierView) {
+func (a *AuditBroker) Register(name string, b audit.Backend, v *BarrierView, filter *cel.Program) {
 	a.Lock()
 	defer a.Unlock()
 	a.backends[name] = backendEntry{
 		backend: b,
 		view:    v,
+		filter:  filter,
 	}
 }
 
-// Deregister is used to remove an audit backend from the broker
+// Deregister is used to remove an audit backend from the broker. The backend
+// is closed once removed, without holding the lock, since closing waits for
+// its pending deliveries.
 func (a *AuditBroker) Deregister(name string) {
 	a.Lock()
-	defer a.Unlock()
+	be, ok := a.backends[name]
 	delete(a.backends, name)
+	a.Unlock()
+
+	if ok {
+		closeAuditBackend(a.logger, name, be.backend)
+	}
+}
+
+// closeBackends releases the resources of the registered backends, without
+// holding the lock
+func (a *AuditBroker) closeBackends() {
+	a.RLock()
+	backends := make(map[string]audit.Backend, len(a.backends))
+	for name, be := range a.backends {
+		backends[name] = be.backend
+	}
+	a.RUnlock()
+
+	for name, backend := range backends {
+		closeAuditBackend(a.logger, name, backend)
+	}
+}
+
+// closeAuditBackend closes a backend which is no longer used, if it holds
+// resources
+func closeAuditBackend(logger log.Logger, path string, b audit.Backend) {
+	closer, ok := b.(audit.Closer)
+	if !ok {
+		return
+	}
+	if err := closer.Close(); err != nil {
+		logger.Error("audit: failed to close backend", "path", path, "error", err)
+	}
 }
 
 // IsRegistered is used to check if a given audit backend is registered
@@ -491,6 +569,74 @@ func (a *AuditBroker) GetHash(name string, input string) (string, error) {
 	return be.backend.GetHash(input)
 }
 
+// VerifyChain verifies the chain of the entries logged by the given backend
+func (a *AuditBroker) VerifyChain(name string, path string) (*audit.ChainVerification, error) {
+	a.RLock()
+	be, ok := a.backends[name]
+	a.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown audit backend %s", name)
+	}
+
+	verifier, ok := be.backend.(audit.ChainVerifier)
+	if !ok {
+		return nil, fmt.Errorf("audit backend %s does not support hash chaining", name)
+	}
+	return verifier.VerifyChain(path)
+}
+
+// filterVars returns the variables available to the audit filters
+func (a *AuditBroker) filterVars(auth *logical.Auth, req *logical.Request) map[string]interface{} {
+	var mountType, mountPoint string
+	if a.mountEntry != nil {
+		if me := a.mountEntry(req.Path); me != nil {
+			mountType = me.Type
+			mountPoint = me.Path
+			if me.Table == credentialTableType {
+				mountPoint = credentialRoutePrefix + me.Path
+			}
+		}
+	}
+
+	authVars := map[string]interface{}{
+		"display_name": "",
+		"policies":     []string{},
+		"entity_id":    "",
+	}
+	if auth != nil {
+		authVars["display_name"] = auth.DisplayName
+		authVars["policies"] = append([]string{}, auth.Policies...)
+		authVars["entity_id"] = auth.EntityID
+	}
+
+	return map[string]interface{}{
+		"request": map[string]interface{}{
+			"path":        req.Path,
+			"operation":   string(req.Operation),
+			"mount_type":  mountType,
+			"mount_point": mountPoint,
+		},
+		"auth": authVars,
+	}
+}
+
+// filtered returns whether the filter of the backend excludes the request.
+// Requests are logged when the filter can't be evaluated.
+func (a *AuditBroker) filtered(name string, be backendEntry, vars func() map[string]interface{}) bool {
+	if be.filter == nil {
+		return false
+	}
+	logged, err := be.filter.EvalBool(vars())
+	if err != nil {
+		a.logger.Warn("audit: failed to evaluate filter", "backend", name, "error", err)
+		return false
+	}
+	if !logged {
+		metrics.IncrCounter([]string{"audit", name, "filtered"}, 1)
+	}
+	return !logged
+}
+
 // LogRequest is used to ensure all the audit backends have an opportunity to
 // log the given request and that *at least one* succeeds.
 func (a *AuditBroker) LogRequest(auth *logical.Auth, req *logical.Request, headersConfig *AuditedHeadersConfig, outerErr error) (ret error) {
@@ -502,7 +648,7 @@ func (a *AuditBroker) LogRequest(auth *logical.Auth, req *logical.Request, heade
 
 	defer func() {
 		if r := recover(); r != nil {
-			a.logger.Error("audit: panic during logging", "request_path", req.Path, "error", r)
+			a.logger.Error("audit: panic during logging", "request_path", req.Path, "request_id", req.ID, "error", r)
 			retErr = multierror.Append(retErr, fmt.Errorf("panic generating audit log"))
 		}
 
@@ -525,13 +671,28 @@ func (a *AuditBroker) LogRequest(auth *logical.Auth, req *logical.Request, heade
 		req.Headers = headers
 	}()
 
-	// Ensure at least one backend logs
+	var vars map[string]interface{}
+	filterVars := func() map[string]interface{} {
+		if vars == nil {
+			vars = a.filterVars(auth, req)
+		}
+		return vars
+	}
+
+	// Ensure at least one backend logs, unless the filters of all the
+	// backends exclude the request
 	anyLogged := false
+	anyAttempted := false
 	for name, be := range a.backends {
+		if a.filtered(name, be, filterVars) {
+			continue
+		}
+		anyAttempted = true
+
 		req.Headers = nil
 		transHeaders, thErr := headersConfig.ApplyConfig(headers, be.backend.GetHash)
 		if thErr != nil {
-			a.logger.Error("audit: backend failed to include headers", "backend", name, "error", thErr)
+			a.logger.Error("audit: backend failed to include headers", "backend", name, "request_id", req.ID, "error", thErr)
 			continue
 		}
 		req.Headers = transHeaders
@@ -540,12 +701,12 @@ func (a *AuditBroker) LogRequest(auth *logical.Auth, req *logical.Request, heade
 		lrErr := be.backend.LogRequest(auth, req, outerErr)
 		metrics.MeasureSince([]string{"audit", name, "log_request"}, start)
 		if lrErr != nil {
-			a.logger.Error("audit: backend failed to log request", "backend", name, "error", lrErr)
+			a.logger.Error("audit: backend failed to log request", "backend", name, "request_id", req.ID, "error", lrErr)
 		} else {
 			anyLogged = true
 		}
 	}
-	if !anyLogged && len(a.backends) > 0 {
+	if !anyLogged && anyAttempted {
 		retErr = multierror.Append(retErr, fmt.Errorf("no audit backend succeeded in logging the request"))
 	}
 
@@ -564,7 +725,7 @@ func (a *AuditBroker) LogResponse(auth *logical.Auth, req *logical.Request,
 
 	defer func() {
 		if r := recover(); r != nil {
-			a.logger.Error("audit: panic during logging", "request_path", req.Path, "error", r)
+			a.logger.Error("audit: panic during logging", "request_path", req.Path, "request_id", req.ID, "error", r)
 			retErr = multierror.Append(retErr, fmt.Errorf("panic generating audit log"))
 		}
 
@@ -580,13 +741,28 @@ func (a *AuditBroker) LogResponse(auth *logical.Auth, req *logical.Request,
 		req.Headers = headers
 	}()
 
-	// Ensure at least one backend logs
+	var vars map[string]interface{}
+	filterVars := func() map[string]interface{} {
+		if vars == nil {
+			vars = a.filterVars(auth, req)
+		}
+		return vars
+	}
+
+	// Ensure at least one backend logs, unless the filters of all the
+	// backends exclude the request
 	anyLogged := false
+	anyAttempted := false
 	for name, be := range a.backends {
+		if a.filtered(name, be, filterVars) {
+			continue
+		}
+		anyAttempted = true
+
 		req.Headers = nil
 		transHeaders, thErr := headersConfig.ApplyConfig(headers, be.backend.GetHash)
 		if thErr != nil {
-			a.logger.Error("audit: backend failed to include headers", "backend", name, "error", thErr)
+			a.logger.Error("audit: backend failed to include headers", "backend", name, "request_id", req.ID, "error", thErr)
 			continue
 		}
 		req.Headers = transHeaders
@@ -595,12 +771,12 @@ func (a *AuditBroker) LogResponse(auth *logical.Auth, req *logical.Request,
 		lrErr := be.backend.LogResponse(auth, req, resp, err)
 		metrics.MeasureSince([]string{"audit", name, "log_response"}, start)
 		if lrErr != nil {
-			a.logger.Error("audit: backend failed to log response", "backend", name, "error", lrErr)
+			a.logger.Error("audit: backend failed to log response", "backend", name, "request_id", req.ID, "error", lrErr)
 		} else {
 			anyLogged = true
 		}
 	}
-	if !anyLogged && len(a.backends) > 0 {
+	if !anyLogged && anyAttempted {
 		retErr = multierror.Append(retErr, fmt.Errorf("no audit backend succeeded in logging the response"))
 	}
 
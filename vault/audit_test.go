@@ -150,6 +150,121 @@ func TestCore_EnableAudit(t *testing.T) {
 	}
 }
 
+func TestCore_EnableAudit_Filter(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	backends := make(map[string]*NoopAudit)
+	c.auditBackends["noop"] = func(config *audit.BackendConfig) (audit.Backend, error) {
+		backend := &NoopAudit{
+			Config: config,
+		}
+		backends[config.Config["name"]] = backend
+		return backend, nil
+	}
+
+	me := &MountEntry{
+		Table:   auditTableType,
+		Path:    "bad",
+		Type:    "noop",
+		Options: map[string]string{"filter": "request.path +"},
+	}
+	if err := c.enableAudit(me); err == nil || !strings.Contains(err.Error(), "invalid audit filter") {
+		t.Fatalf("expected an error; err: %v", err)
+	}
+
+	for name, filter := range map[string]string{
+		"all":  "",
+		"siem": `!(request.mount_type == "kv" && request.operation == "read")`,
+	} {
+		me := &MountEntry{
+			Table:   auditTableType,
+			Path:    name,
+			Type:    "noop",
+			Options: map[string]string{"name": name, "filter": filter},
+		}
+		if err := c.enableAudit(me); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "secret/foo")
+	req.Data["zip"] = "zap"
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	req = logical.TestRequest(t, logical.ReadOperation, "secret/foo")
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The reads of the kv mount are excluded from the filtered backend
+	var paths []string
+	for _, req := range backends["siem"].Req {
+		paths = append(paths, string(req.Operation)+" "+req.Path)
+	}
+	if !reflect.DeepEqual(paths, []string{"create secret/foo"}) || len(backends["siem"].Resp) != 1 {
+		t.Fatalf("bad: %#v", paths)
+	}
+	if len(backends["all"].Req) != 2 || len(backends["all"].Resp) != 2 {
+		t.Fatalf("bad: %#v", backends["all"].Req)
+	}
+}
+
+func TestAuditBroker_Filter(t *testing.T) {
+	l := logformat.NewVaultLogger(log.LevelTrace)
+	b := NewAuditBroker(l)
+	b.mountEntry = func(path string) *MountEntry {
+		if strings.HasPrefix(path, "auth/userpass/") {
+			return &MountEntry{Table: credentialTableType, Path: "userpass/", Type: "userpass"}
+		}
+		return nil
+	}
+
+	filter, err := auditFilter(&MountEntry{
+		Options: map[string]string{
+			"filter": `request.mount_point == "auth/userpass/" && auth.display_name.startsWith("userpass-")`,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a1 := &NoopAudit{}
+	b.Register("foo", a1, nil, filter)
+
+	headersConf := &AuditedHeadersConfig{
+		Headers: make(map[string]*auditedHeaderSettings),
+	}
+	auth := &logical.Auth{
+		DisplayName: "userpass-bob",
+	}
+
+	// Requests excluded by the filters of all the backends are not errors
+	a1.ReqErr = fmt.Errorf("failed")
+	req := &logical.Request{Operation: logical.ReadOperation, Path: "secret/foo"}
+	if err := b.LogRequest(auth, req, headersConf, nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := b.LogRequest(nil, &logical.Request{Path: "auth/userpass/login/bob"}, headersConf, nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(a1.Req) != 0 {
+		t.Fatalf("bad: %#v", a1.Req)
+	}
+
+	req = &logical.Request{Operation: logical.ReadOperation, Path: "auth/userpass/users/bob"}
+	if err := b.LogRequest(auth, req, headersConf, nil); !errwrap.Contains(err, "no audit backend succeeded in logging the request") {
+		t.Fatalf("err: %v", err)
+	}
+	a1.ReqErr = nil
+	if err := b.LogRequest(auth, req, headersConf, nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(a1.Req) != 2 {
+		t.Fatalf("bad: %#v", a1.Req)
+	}
+}
+
 func TestCore_EnableAudit_MixedFailures(t *testing.T) {
 	c, _, _ := TestCoreUnsealed(t)
 	c.auditBackends["noop"] = func(config *audit.BackendConfig) (audit.Backend, error) {
@@ -401,8 +516,8 @@ func TestAuditBroker_LogRequest(t *testing.T) {
 	b := NewAuditBroker(l)
 	a1 := &NoopAudit{}
 	a2 := &NoopAudit{}
-	b.Register("foo", a1, nil)
-	b.Register("bar", a2, nil)
+	b.Register("foo", a1, nil, nil)
+	b.Register("bar", a2, nil, nil)
 
 	auth := &logical.Auth{
 		ClientToken: "foo",
@@ -478,8 +593,8 @@ func TestAuditBroker_LogResponse(t *testing.T) {
 	b := NewAuditBroker(l)
 	a1 := &NoopAudit{}
 	a2 := &NoopAudit{}
-	b.Register("foo", a1, nil)
-	b.Register("bar", a2, nil)
+	b.Register("foo", a1, nil, nil)
+	b.Register("bar", a2, nil, nil)
 
 	auth := &logical.Auth{
 		NumUses:     10,
@@ -572,8 +687,8 @@ func TestAuditBroker_AuditHeaders(t *testing.T) {
 	view := NewBarrierView(barrier, "headers/")
 	a1 := &NoopAudit{}
 	a2 := &NoopAudit{}
-	b.Register("foo", a1, nil)
-	b.Register("bar", a2, nil)
+	b.Register("foo", a1, nil, nil)
+	b.Register("bar", a2, nil, nil)
 
 	auth := &logical.Auth{
 		ClientToken: "foo",
@@ -9,6 +9,7 @@ import (
 	"github.com/hashicorp/go-uuid"
 	"github.com/hashicorp/vault/helper/jsonutil"
 	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/physical"
 )
 
 const (
@@ -54,9 +55,17 @@ func (c *Core) enableCredential(entry *MountEntry) error {
 		return fmt.Errorf("backend path must be specified")
 	}
 
+	// The namespaces lock is acquired first, as when creating namespaces
+	c.namespacesLock.RLock()
+	defer c.namespacesLock.RUnlock()
 	c.authLock.Lock()
 	defer c.authLock.Unlock()
 
+	// Verify the mount doesn't overlap another namespace
+	if err := c.checkNamespaceMountLocked(entry, entry.Path); err != nil {
+		return err
+	}
+
 	// Look for matching name
 	for _, ent := range c.auth.Entries {
 		switch {
@@ -100,6 +109,9 @@ func (c *Core) enableCredential(entry *MountEntry) error {
 	if entry.Config.PluginName != "" {
 		conf["plugin_name"] = entry.Config.PluginName
 	}
+	if entry.Config.PluginVersion != "" {
+		conf["plugin_version"] = entry.Config.PluginVersion
+	}
 
 	// Create the new backend
 	backend, err := c.newCredentialBackend(entry.Type, sysView, view, conf)
@@ -116,6 +128,7 @@ func (c *Core) enableCredential(entry *MountEntry) error {
 		return fmt.Errorf("cannot mount '%s' of type '%s' as an auth backend", entry.Config.PluginName, backendType)
 	}
 
+	setupMountSealWrap(view, entry, backend)
 	if err := backend.Initialize(); err != nil {
 		return err
 	}
@@ -354,6 +367,7 @@ func (c *Core) persistAuth(table *MountTable, localOnly bool) error {
 		}
 	}
 
+	var txns []*TxnEntry
 	if !localOnly {
 		// Marshal the table
 		compressedBytes, err := jsonutil.EncodeJSONAndCompress(nonLocalAuth, nil)
@@ -368,11 +382,7 @@ func (c *Core) persistAuth(table *MountTable, localOnly bool) error {
 			Value: compressedBytes,
 		}
 
-		// Write to the physical backend
-		if err := c.barrier.Put(entry); err != nil {
-			c.logger.Error("core: failed to persist auth table", "error", err)
-			return err
-		}
+		txns = append(txns, &TxnEntry{Operation: physical.PutOperation, Entry: entry})
 	}
 
 	// Repeat with local auth
@@ -387,8 +397,9 @@ func (c *Core) persistAuth(table *MountTable, localOnly bool) error {
 		Value: compressedBytes,
 	}
 
-	if err := c.barrier.Put(entry); err != nil {
-		c.logger.Error("core: failed to persist local auth table", "error", err)
+	txns = append(txns, &TxnEntry{Operation: physical.PutOperation, Entry: entry})
+	if err := c.barrier.Transaction(txns); err != nil {
+		c.logger.Error("core: failed to persist auth table", "error", err)
 		return err
 	}
 
@@ -421,6 +432,9 @@ func (c *Core) setupCredentials() error {
 		if entry.Config.PluginName != "" {
 			conf["plugin_name"] = entry.Config.PluginName
 		}
+		if entry.Config.PluginVersion != "" {
+			conf["plugin_version"] = entry.Config.PluginVersion
+		}
 
 		// Initialize the backend
 		backend, err = c.newCredentialBackend(entry.Type, sysView, view, conf)
@@ -443,6 +457,7 @@ func (c *Core) setupCredentials() error {
 			return fmt.Errorf("cannot mount '%s' of type '%s' as an auth backend", entry.Config.PluginName, backend.Type())
 		}
 
+		setupMountSealWrap(view, entry, backend)
 		if err := backend.Initialize(); err != nil {
 			return err
 		}
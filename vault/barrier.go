@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/physical"
 )
 
 var (
@@ -22,6 +23,11 @@ var (
 
 	// ErrBarrierInvalidKey is returned if the Unseal key is invalid
 	ErrBarrierInvalidKey = errors.New("Unseal failed, invalid key")
+
+	// ErrBarrierUndecryptable is returned by Rewrap for an entry encrypted
+	// under a term of the keyring that fails to decrypt, such as a corrupted
+	// entry
+	ErrBarrierUndecryptable = errors.New("entry cannot be decrypted")
 )
 
 const (
@@ -96,6 +102,11 @@ type SecurityBarrier interface {
 	// is present in the leader.
 	ReloadKeyring() error
 
+	// VerifyKeyring checks that an encrypted keyring, such as the one of a
+	// snapshot of the storage, is sealed with the current master key and
+	// matches the current keyring
+	VerifyKeyring(value []byte) error
+
 	// ReloadMasterKey is used to re-read the underlying masterkey.
 	// This is used for HA deployments to ensure the latest master key
 	// is available for keyring reloading.
@@ -121,9 +132,27 @@ type SecurityBarrier interface {
 	// ActiveKeyInfo is used to inform details about the active key
 	ActiveKeyInfo() (*KeyInfo, error)
 
+	// PersistEncryptions persists the number of encryptions with the
+	// active key with the keyring
+	PersistEncryptions() error
+
+	// RotationConfig returns the policy for the automatic rotation of the
+	// active key
+	RotationConfig() (KeyRotationConfig, error)
+
+	// SetRotationConfig updates the policy for the automatic rotation of the
+	// active key
+	SetRotationConfig(KeyRotationConfig) error
+
 	// Rekey is used to change the master key used to protect the keyring
 	Rekey([]byte) error
 
+	// Rewrap re-encrypts an entry with the active key if it was encrypted
+	// with an older one, returning the term it was encrypted under and
+	// whether it was rewritten. ErrBarrierUndecryptable is returned for an
+	// entry that fails to decrypt.
+	Rewrap(key string) (uint32, bool, error)
+
 	// For replication we must send over the keyring, so this must be available
 	Keyring() (*Keyring, error)
 
@@ -148,6 +177,11 @@ type BarrierStorage interface {
 	// List is used ot list all the keys under a given
 	// prefix, up to the next prefix.
 	List(prefix string) ([]string, error)
+
+	// Transaction is used to put and delete several entries atomically if
+	// the physical backend supports transactions. Otherwise the operations
+	// are applied in order, stopping at the first error.
+	Transaction(txns []*TxnEntry) error
 }
 
 // BarrierEncryptor is the in memory only interface that does not actually
@@ -162,13 +196,25 @@ type BarrierEncryptor interface {
 type Entry struct {
 	Key   string
 	Value []byte
+
+	// SealWrap requests that the entry be seal-wrapped, encrypted by the
+	// seal in addition to the barrier
+	SealWrap bool
+}
+
+// TxnEntry is an operation of a barrier transaction. Only the key of the
+// entry is used by a delete operation.
+type TxnEntry struct {
+	Operation physical.Operation
+	Entry     *Entry
 }
 
 // Logical turns the Entry into a logical storage entry.
 func (e *Entry) Logical() *logical.StorageEntry {
 	return &logical.StorageEntry{
-		Key:   e.Key,
-		Value: e.Value,
+		Key:      e.Key,
+		Value:    e.Value,
+		SealWrap: e.SealWrap,
 	}
 }
 
@@ -176,4 +222,7 @@ func (e *Entry) Logical() *logical.StorageEntry {
 type KeyInfo struct {
 	Term        int
 	InstallTime time.Time
+
+	// Encryptions is the number of encryptions with the key
+	Encryptions int64
 }
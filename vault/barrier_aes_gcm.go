@@ -9,10 +9,12 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/armon/go-metrics"
 	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/helper/locksutil"
 	"github.com/hashicorp/vault/physical"
 )
 
@@ -23,6 +25,11 @@ const (
 
 	// termSize the number of bytes used for the key term.
 	termSize = 4
+
+	// aesgcmNonceSize and aesgcmTagSize are the sizes of the nonce and
+	// authentication tag of the barrier's AES-GCM ciphertexts
+	aesgcmNonceSize = 12
+	aesgcmTagSize   = 16
 )
 
 // Versions of the AESGCM storage methodology
@@ -43,6 +50,11 @@ type barrierInit struct {
 // bit. AES-GCM is high performance, and provides both confidentiality
 // and integrity.
 type AESGCMBarrier struct {
+	// unaccountedEncryptions is the number of encryptions with the active
+	// key not yet persisted with the keyring. It is accessed atomically and
+	// kept first for alignment.
+	unaccountedEncryptions int64
+
 	backend physical.Backend
 
 	l      sync.RWMutex
@@ -57,6 +69,10 @@ type AESGCMBarrier struct {
 	cache     map[uint32]cipher.AEAD
 	cacheLock sync.RWMutex
 
+	// rewrapLocks keep entries from being written while Rewrap replaces
+	// them
+	rewrapLocks []*locksutil.LockEntry
+
 	// currentAESGCMVersionByte is prefixed to a message to allow for
 	// future versioning of barrier implementations. It's var instead
 	// of const to allow for testing
@@ -71,6 +87,7 @@ func NewAESGCMBarrier(physical physical.Backend) (*AESGCMBarrier, error) {
 		sealed:  true,
 		cache:   make(map[uint32]cipher.AEAD),
 		currentAESGCMVersionByte: byte(AESGCMVersion2),
+		rewrapLocks:              locksutil.CreateLocks(),
 	}
 	return b, nil
 }
@@ -150,10 +167,12 @@ func (b *AESGCMBarrier) persistKeyring(keyring *Keyring) error {
 	// Encrypt the barrier init value
 	value := b.encrypt(keyringPath, initialKeyTerm, gcm, keyringBuf)
 
-	// Create the keyring physical entry
+	// Create the keyring physical entry, seal-wrapped as it holds the keys
+	// of the barrier
 	pe := &physical.Entry{
-		Key:   keyringPath,
-		Value: value,
+		Key:      keyringPath,
+		Value:    value,
+		SealWrap: true,
 	}
 	if err := b.backend.Put(pe); err != nil {
 		return fmt.Errorf("failed to persist keyring: %v", err)
@@ -181,8 +200,9 @@ func (b *AESGCMBarrier) persistKeyring(keyring *Keyring) error {
 
 	// Update the masterKeyPath for standby instances
 	pe = &physical.Entry{
-		Key:   masterKeyPath,
-		Value: value,
+		Key:      masterKeyPath,
+		Value:    value,
+		SealWrap: true,
 	}
 	if err := b.backend.Put(pe); err != nil {
 		return fmt.Errorf("failed to persist master key: %v", err)
@@ -270,6 +290,43 @@ func (b *AESGCMBarrier) ReloadKeyring() error {
 	return nil
 }
 
+// VerifyKeyring checks that an encrypted keyring, such as the one of a
+// snapshot of the storage, is sealed with the current master key and that
+// the keys it shares with the current keyring are the same
+func (b *AESGCMBarrier) VerifyKeyring(value []byte) error {
+	b.l.RLock()
+	defer b.l.RUnlock()
+	if b.sealed {
+		return ErrBarrierSealed
+	}
+
+	gcm, err := b.aeadFromKey(b.keyring.MasterKey())
+	if err != nil {
+		return err
+	}
+	plain, err := b.decrypt(keyringPath, gcm, value)
+	defer memzero(plain)
+	if err != nil {
+		if strings.Contains(err.Error(), "message authentication failed") {
+			return ErrBarrierInvalidKey
+		}
+		return err
+	}
+	keyring, err := DeserializeKeyring(plain)
+	if err != nil {
+		return fmt.Errorf("keyring deserialization failed: %v", err)
+	}
+	defer keyring.Zeroize(true)
+
+	for term, key := range keyring.keys {
+		current := b.keyring.TermKey(term)
+		if current != nil && subtle.ConstantTimeCompare(key.Value, current.Value) != 1 {
+			return fmt.Errorf("key of term %d does not match the current keyring", term)
+		}
+	}
+	return nil
+}
+
 // ReloadMasterKey is used to re-read the underlying masterkey.
 // This is used for HA deployments to ensure the latest master key
 // is available for keyring reloading.
@@ -421,6 +478,7 @@ func (b *AESGCMBarrier) Seal() error {
 	b.keyring.Zeroize(true)
 	b.keyring = nil
 	b.sealed = true
+	atomic.StoreInt64(&b.unaccountedEncryptions, 0)
 	return nil
 }
 
@@ -443,18 +501,22 @@ func (b *AESGCMBarrier) Rotate() (uint32, error) {
 	term := b.keyring.ActiveTerm()
 	newTerm := term + 1
 
-	// Add a new encryption key
-	newKeyring, err := b.keyring.AddKey(&Key{
+	// Account for the encryptions with the former key, then add a new
+	// encryption key
+	encryptions := atomic.SwapInt64(&b.unaccountedEncryptions, 0)
+	newKeyring, err := b.keyring.AddEncryptions(term, encryptions).AddKey(&Key{
 		Term:    newTerm,
 		Version: 1,
 		Value:   encrypt,
 	})
 	if err != nil {
+		atomic.AddInt64(&b.unaccountedEncryptions, encryptions)
 		return 0, fmt.Errorf("failed to add new encryption key: %v", err)
 	}
 
 	// Persist the new keyring
 	if err := b.persistKeyring(newKeyring); err != nil {
+		atomic.AddInt64(&b.unaccountedEncryptions, encryptions)
 		return 0, err
 	}
 
@@ -566,10 +628,61 @@ func (b *AESGCMBarrier) ActiveKeyInfo() (*KeyInfo, error) {
 	info := &KeyInfo{
 		Term:        int(term),
 		InstallTime: key.InstallTime,
+		Encryptions: key.Encryptions + atomic.LoadInt64(&b.unaccountedEncryptions),
 	}
 	return info, nil
 }
 
+// PersistEncryptions persists the number of encryptions with the active key
+// with the keyring, so that it survives restarts and leadership changes
+func (b *AESGCMBarrier) PersistEncryptions() error {
+	b.l.Lock()
+	defer b.l.Unlock()
+	if b.sealed {
+		return ErrBarrierSealed
+	}
+
+	encryptions := atomic.SwapInt64(&b.unaccountedEncryptions, 0)
+	if encryptions == 0 {
+		return nil
+	}
+	newKeyring := b.keyring.AddEncryptions(b.keyring.ActiveTerm(), encryptions)
+	if err := b.persistKeyring(newKeyring); err != nil {
+		atomic.AddInt64(&b.unaccountedEncryptions, encryptions)
+		return err
+	}
+	b.keyring = newKeyring
+	return nil
+}
+
+// RotationConfig returns the policy for the automatic rotation of the active
+// key
+func (b *AESGCMBarrier) RotationConfig() (KeyRotationConfig, error) {
+	b.l.RLock()
+	defer b.l.RUnlock()
+	if b.sealed {
+		return KeyRotationConfig{}, ErrBarrierSealed
+	}
+	return b.keyring.RotationConfig(), nil
+}
+
+// SetRotationConfig updates the policy for the automatic rotation of the
+// active key and persists it with the keyring
+func (b *AESGCMBarrier) SetRotationConfig(config KeyRotationConfig) error {
+	b.l.Lock()
+	defer b.l.Unlock()
+	if b.sealed {
+		return ErrBarrierSealed
+	}
+
+	newKeyring := b.keyring.SetRotationConfig(config)
+	if err := b.persistKeyring(newKeyring); err != nil {
+		return err
+	}
+	b.keyring = newKeyring
+	return nil
+}
+
 // Rekey is used to change the master key used to protect the keyring
 func (b *AESGCMBarrier) Rekey(key []byte) error {
 	b.l.Lock()
@@ -635,6 +748,10 @@ func (b *AESGCMBarrier) Put(entry *Entry) error {
 		return ErrBarrierSealed
 	}
 
+	lock := locksutil.LockForKey(b.rewrapLocks, entry.Key)
+	lock.RLock()
+	defer lock.RUnlock()
+
 	term := b.keyring.ActiveTerm()
 	primary, err := b.aeadForTerm(term)
 	if err != nil {
@@ -642,12 +759,75 @@ func (b *AESGCMBarrier) Put(entry *Entry) error {
 	}
 
 	pe := &physical.Entry{
-		Key:   entry.Key,
-		Value: b.encrypt(entry.Key, term, primary, entry.Value),
+		Key:      entry.Key,
+		Value:    b.encryptTracked(entry.Key, term, primary, entry.Value),
+		SealWrap: entry.SealWrap,
 	}
 	return b.backend.Put(pe)
 }
 
+// Transaction is used to put and delete several entries atomically on the
+// transactional backends
+func (b *AESGCMBarrier) Transaction(txns []*TxnEntry) error {
+	defer metrics.MeasureSince([]string{"barrier", "transaction"}, time.Now())
+	b.l.RLock()
+	defer b.l.RUnlock()
+	if b.sealed {
+		return ErrBarrierSealed
+	}
+
+	keys := make([]string, 0, len(txns))
+	for _, txn := range txns {
+		keys = append(keys, txn.Entry.Key)
+	}
+	for _, lock := range locksutil.LocksForKeys(b.rewrapLocks, keys) {
+		lock.RLock()
+		defer lock.RUnlock()
+	}
+
+	term := b.keyring.ActiveTerm()
+	primary, err := b.aeadForTerm(term)
+	if err != nil {
+		return err
+	}
+
+	pTxns := make([]physical.TxnEntry, 0, len(txns))
+	for _, txn := range txns {
+		pe := &physical.Entry{
+			Key: txn.Entry.Key,
+		}
+		switch txn.Operation {
+		case physical.PutOperation:
+			pe.Value = b.encryptTracked(txn.Entry.Key, term, primary, txn.Entry.Value)
+			pe.SealWrap = txn.Entry.SealWrap
+		case physical.DeleteOperation:
+		default:
+			return fmt.Errorf("unsupported transaction operation %q", txn.Operation)
+		}
+		pTxns = append(pTxns, physical.TxnEntry{
+			Operation: txn.Operation,
+			Entry:     pe,
+		})
+	}
+
+	if transactional, ok := b.backend.(physical.Transactional); ok {
+		return transactional.Transaction(pTxns)
+	}
+
+	for _, txn := range pTxns {
+		switch txn.Operation {
+		case physical.PutOperation:
+			err = b.backend.Put(txn.Entry)
+		case physical.DeleteOperation:
+			err = b.backend.Delete(txn.Entry.Key)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Get is used to fetch an entry
 func (b *AESGCMBarrier) Get(key string) (*Entry, error) {
 	defer metrics.MeasureSince([]string{"barrier", "get"}, time.Now())
@@ -673,8 +853,9 @@ func (b *AESGCMBarrier) Get(key string) (*Entry, error) {
 
 	// Wrap in a logical entry
 	entry := &Entry{
-		Key:   key,
-		Value: plain,
+		Key:      key,
+		Value:    plain,
+		SealWrap: pe.SealWrap,
 	}
 	return entry, nil
 }
@@ -688,9 +869,74 @@ func (b *AESGCMBarrier) Delete(key string) error {
 		return ErrBarrierSealed
 	}
 
+	lock := locksutil.LockForKey(b.rewrapLocks, key)
+	lock.RLock()
+	defer lock.RUnlock()
+
 	return b.backend.Delete(key)
 }
 
+// Rewrap re-encrypts an entry with the active key if it was encrypted under
+// an older term. It returns the term the entry was encrypted under and
+// whether it was rewritten. Entries that are not encrypted by the barrier's
+// keyring, such as the seal configuration, are left untouched and reported
+// with a term of 0. Entries that claim a term of the keyring but fail to
+// decrypt are reported with ErrBarrierUndecryptable.
+func (b *AESGCMBarrier) Rewrap(key string) (uint32, bool, error) {
+	defer metrics.MeasureSince([]string{"barrier", "rewrap"}, time.Now())
+	b.l.RLock()
+	defer b.l.RUnlock()
+	if b.sealed {
+		return 0, false, ErrBarrierSealed
+	}
+
+	// The keyring and the keys protecting it are encrypted with dedicated
+	// keys, and upgrade keys must stay under their previous term
+	if key == keyringPath || key == masterKeyPath || strings.HasPrefix(key, keyringUpgradePrefix) {
+		return 0, false, nil
+	}
+
+	lock := locksutil.LockForKey(b.rewrapLocks, key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	pe, err := b.backend.Get(key)
+	if err != nil {
+		return 0, false, err
+	}
+	if pe == nil || len(pe.Value) < termSize+1+aesgcmNonceSize+aesgcmTagSize {
+		return 0, false, nil
+	}
+
+	term := binary.BigEndian.Uint32(pe.Value[:termSize])
+	switch {
+	case b.keyring.TermKey(term) == nil:
+		return 0, false, nil
+	case pe.Value[termSize] != AESGCMVersion1 && pe.Value[termSize] != AESGCMVersion2:
+		return 0, false, nil
+	}
+	plain, err := b.decryptKeyring(key, pe.Value)
+	if err != nil {
+		return term, false, ErrBarrierUndecryptable
+	}
+	defer memzero(plain)
+
+	activeTerm := b.keyring.ActiveTerm()
+	if term == activeTerm && pe.Value[termSize] == b.currentAESGCMVersionByte {
+		return term, false, nil
+	}
+
+	primary, err := b.aeadForTerm(activeTerm)
+	if err != nil {
+		return 0, false, err
+	}
+	pe.Value = b.encryptTracked(key, activeTerm, primary, plain)
+	if err := b.backend.Put(pe); err != nil {
+		return 0, false, err
+	}
+	return term, true, nil
+}
+
 // List is used ot list all the keys under a given
 // prefix, up to the next prefix.
 func (b *AESGCMBarrier) List(prefix string) ([]string, error) {
@@ -755,6 +1001,13 @@ func (b *AESGCMBarrier) aeadFromKey(key []byte) (cipher.AEAD, error) {
 	return gcm, nil
 }
 
+// encryptTracked encrypts a value with the active key, counting the
+// encryption for the automatic rotation of the key
+func (b *AESGCMBarrier) encryptTracked(path string, term uint32, gcm cipher.AEAD, plain []byte) []byte {
+	atomic.AddInt64(&b.unaccountedEncryptions, 1)
+	return b.encrypt(path, term, gcm, plain)
+}
+
 // encrypt is used to encrypt a value
 func (b *AESGCMBarrier) encrypt(path string, term uint32, gcm cipher.AEAD, plain []byte) []byte {
 	// Allocate the output buffer with room for tern, version byte,
@@ -812,6 +1065,10 @@ func (b *AESGCMBarrier) decrypt(path string, gcm cipher.AEAD, cipher []byte) ([]
 
 // decryptKeyring is used to decrypt a value using the keyring
 func (b *AESGCMBarrier) decryptKeyring(path string, cipher []byte) ([]byte, error) {
+	if len(cipher) < termSize+1 {
+		return nil, fmt.Errorf("invalid ciphertext length")
+	}
+
 	// Verify the term
 	term := binary.BigEndian.Uint32(cipher[:4])
 
@@ -825,6 +1082,9 @@ func (b *AESGCMBarrier) decryptKeyring(path string, cipher []byte) ([]byte, erro
 	if gcm == nil {
 		return nil, fmt.Errorf("no decryption key available for term %d", term)
 	}
+	if len(cipher) < termSize+1+gcm.NonceSize()+gcm.Overhead() {
+		return nil, fmt.Errorf("invalid ciphertext length")
+	}
 
 	nonce := cipher[5 : 5+gcm.NonceSize()]
 	raw := cipher[5+gcm.NonceSize():]
@@ -855,7 +1115,7 @@ func (b *AESGCMBarrier) Encrypt(key string, plaintext []byte) ([]byte, error) {
 		return nil, err
 	}
 
-	ciphertext := b.encrypt(key, term, primary, plaintext)
+	ciphertext := b.encryptTracked(key, term, primary, plaintext)
 	return ciphertext, nil
 }
 
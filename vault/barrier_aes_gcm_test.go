@@ -2,7 +2,9 @@ package vault
 
 import (
 	"bytes"
+	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"testing"
 
 	"github.com/hashicorp/vault/helper/logformat"
@@ -501,3 +503,183 @@ func TestEncrypt_BarrierEncryptor(t *testing.T) {
 		t.Fatalf("bad: %s", plain)
 	}
 }
+
+// Verify rewrapping moves entries to the active term
+func TestAESGCMBarrier_Rewrap(t *testing.T) {
+	inm, b, _ := mockBarrier(t)
+
+	entry := &Entry{Key: "test", Value: []byte("test")}
+	if err := b.Put(entry); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := inm.Put(&physical.Entry{Key: "plain", Value: []byte("not encrypted by the barrier")}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Entries under the active term are left alone
+	term, rewrapped, err := b.Rewrap("test")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if term != 1 || rewrapped {
+		t.Fatalf("bad: %d %v", term, rewrapped)
+	}
+
+	newTerm, err := b.Rotate()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	term, rewrapped, err = b.Rewrap("test")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if term != 1 || !rewrapped {
+		t.Fatalf("bad: %d %v", term, rewrapped)
+	}
+	pe, err := inm.Get("test")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if actual := binary.BigEndian.Uint32(pe.Value[:termSize]); actual != newTerm {
+		t.Fatalf("bad term: %d", actual)
+	}
+	out, err := b.Get("test")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !bytes.Equal(out.Value, entry.Value) {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	// Entries the barrier did not encrypt, and the keyring, are skipped
+	for _, key := range []string{"plain", keyringPath, "missing"} {
+		term, rewrapped, err = b.Rewrap(key)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if term != 0 || rewrapped {
+			t.Fatalf("%s: bad: %d %v", key, term, rewrapped)
+		}
+	}
+	pe, err = inm.Get("plain")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if string(pe.Value) != "not encrypted by the barrier" {
+		t.Fatalf("bad: %q", pe.Value)
+	}
+	// Entries under a term of the keyring that fail to decrypt are reported
+	pe, err = inm.Get("test")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	pe.Value[len(pe.Value)-1] ^= 0xff
+	if err := inm.Put(pe); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	term, rewrapped, err = b.Rewrap("test")
+	if err != ErrBarrierUndecryptable || term != newTerm || rewrapped {
+		t.Fatalf("bad: %d %v %v", term, rewrapped, err)
+	}
+}
+
+// failingTxnBackend is a transactional backend whose transactions fail once
+// failing is set
+type failingTxnBackend struct {
+	physical.Backend
+	physical.Transactional
+	failing bool
+}
+
+func (f *failingTxnBackend) Transaction(txns []physical.TxnEntry) error {
+	if f.failing {
+		return errors.New("transaction failed")
+	}
+	return f.Transactional.Transaction(txns)
+}
+
+func newFailingTxnBackend(t testing.TB) *failingTxnBackend {
+	inm, err := inmem.NewTransactionalInmem(nil, logger)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	return &failingTxnBackend{
+		Backend:       inm,
+		Transactional: inm.(physical.Transactional),
+	}
+}
+
+func TestAESGCMBarrier_Transaction(t *testing.T) {
+	inm, err := inmem.NewInmem(nil, logger)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	txnBackend := newFailingTxnBackend(t)
+
+	var txnBarrier SecurityBarrier
+	for _, backend := range []physical.Backend{inm, txnBackend} {
+		b, err := NewAESGCMBarrier(backend)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		key, _ := b.GenerateKey()
+		b.Initialize(key)
+		b.Unseal(key)
+
+		if err := b.Put(&Entry{Key: "c", Value: []byte("c")}); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		txns := []*TxnEntry{
+			{Operation: physical.PutOperation, Entry: &Entry{Key: "a", Value: []byte("a")}},
+			{Operation: physical.PutOperation, Entry: &Entry{Key: "b", Value: []byte("b"), SealWrap: true}},
+			{Operation: physical.DeleteOperation, Entry: &Entry{Key: "c"}},
+		}
+		if err := b.Transaction(txns); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		for key, value := range map[string]string{"a": "a", "b": "b", "c": ""} {
+			out, err := b.Get(key)
+			if err != nil {
+				t.Fatalf("err: %v", err)
+			}
+			if value == "" {
+				if out != nil {
+					t.Fatalf("bad: %#v", out)
+				}
+				continue
+			}
+			if out == nil || string(out.Value) != value {
+				t.Fatalf("bad: %#v", out)
+			}
+		}
+
+		// The values are encrypted
+		pe, err := backend.Get("b")
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if bytes.Equal(pe.Value, []byte("b")) || !pe.SealWrap {
+			t.Fatalf("bad: %#v", pe)
+		}
+		txnBarrier = b
+	}
+
+	// Nothing is written by a failed transaction
+	b := txnBarrier
+	txnBackend.failing = true
+	txns := []*TxnEntry{
+		{Operation: physical.PutOperation, Entry: &Entry{Key: "d", Value: []byte("d")}},
+		{Operation: physical.DeleteOperation, Entry: &Entry{Key: "a"}},
+	}
+	if err := b.Transaction(txns); err == nil {
+		t.Fatal("expected error")
+	}
+	if out, err := b.Get("d"); err != nil || out != nil {
+		t.Fatalf("bad: %#v %v", out, err)
+	}
+	if out, err := b.Get("a"); err != nil || out == nil {
+		t.Fatalf("bad: %#v %v", out, err)
+	}
+}
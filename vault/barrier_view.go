@@ -2,9 +2,11 @@ package vault
 
 import (
 	"errors"
+	"fmt"
 	"strings"
 
 	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/physical"
 )
 
 // BarrierView wraps a SecurityBarrier and ensures all access is automatically
@@ -18,6 +20,11 @@ type BarrierView struct {
 	barrier  BarrierStorage
 	prefix   string
 	readonly bool
+
+	// sealWrap seal-wraps every entry of the view, while sealWrapPrefixes
+	// lists the full storage prefixes whose entries are seal-wrapped
+	sealWrap         bool
+	sealWrapPrefixes []string
 }
 
 var (
@@ -66,8 +73,9 @@ func (v *BarrierView) Get(key string) (*logical.StorageEntry, error) {
 	}
 
 	return &logical.StorageEntry{
-		Key:   entry.Key,
-		Value: entry.Value,
+		Key:      entry.Key,
+		Value:    entry.Value,
+		SealWrap: entry.SealWrap,
 	}, nil
 }
 
@@ -84,8 +92,9 @@ func (v *BarrierView) Put(entry *logical.StorageEntry) error {
 	}
 
 	nested := &Entry{
-		Key:   expandedKey,
-		Value: entry.Value,
+		Key:      expandedKey,
+		Value:    entry.Value,
+		SealWrap: entry.SealWrap || v.sealWrapped(expandedKey),
 	}
 	return v.barrier.Put(nested)
 }
@@ -105,10 +114,76 @@ func (v *BarrierView) Delete(key string) error {
 	return v.barrier.Delete(expandedKey)
 }
 
+// logical.TransactionalStorage impl.
+func (v *BarrierView) Transaction(txns []*logical.TxnEntry) error {
+	if v.readonly {
+		return logical.ErrReadOnly
+	}
+
+	nested := make([]*TxnEntry, 0, len(txns))
+	for _, txn := range txns {
+		if err := v.sanityCheck(txn.Entry.Key); err != nil {
+			return err
+		}
+		expandedKey := v.expandKey(txn.Entry.Key)
+
+		switch txn.Operation {
+		case logical.UpdateOperation:
+			nested = append(nested, &TxnEntry{
+				Operation: physical.PutOperation,
+				Entry: &Entry{
+					Key:      expandedKey,
+					Value:    txn.Entry.Value,
+					SealWrap: txn.Entry.SealWrap || v.sealWrapped(expandedKey),
+				},
+			})
+		case logical.DeleteOperation:
+			nested = append(nested, &TxnEntry{
+				Operation: physical.DeleteOperation,
+				Entry:     &Entry{Key: expandedKey},
+			})
+		default:
+			return fmt.Errorf("unsupported transaction operation %q", txn.Operation)
+		}
+	}
+	return v.barrier.Transaction(nested)
+}
+
 // SubView constructs a nested sub-view using the given prefix
 func (v *BarrierView) SubView(prefix string) *BarrierView {
 	sub := v.expandKey(prefix)
-	return &BarrierView{barrier: v.barrier, prefix: sub, readonly: v.readonly}
+	return &BarrierView{
+		barrier:          v.barrier,
+		prefix:           sub,
+		readonly:         v.readonly,
+		sealWrap:         v.sealWrap,
+		sealWrapPrefixes: v.sealWrapPrefixes,
+	}
+}
+
+// setSealWrap configures the entries of the view to seal-wrap: all of them
+// if sealWrap is set, otherwise those under the given prefixes, relative to
+// the view
+func (v *BarrierView) setSealWrap(sealWrap bool, prefixes []string) {
+	v.sealWrap = sealWrap
+	v.sealWrapPrefixes = nil
+	for _, prefix := range prefixes {
+		v.sealWrapPrefixes = append(v.sealWrapPrefixes, v.expandKey(prefix))
+	}
+}
+
+// sealWrapped returns whether the entry at the full storage key is
+// seal-wrapped
+func (v *BarrierView) sealWrapped(key string) bool {
+	if v.sealWrap {
+		return true
+	}
+	for _, prefix := range v.sealWrapPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
 }
 
 // expandKey is used to expand to the full key path with the prefix
@@ -8,45 +8,68 @@ import (
 
 // Capabilities is used to fetch the capabilities of the given token on the given path
 func (c *Core) Capabilities(token, path string) ([]string, error) {
+	capabilities, _, err := c.CapabilitiesAndGrants(token, path)
+	return capabilities, err
+}
+
+// CapabilitiesAndGrants is used to fetch the capabilities of the given token
+// on the given path, along with the policy path rules granting each of them.
+// The path is relative to the root namespace; tokens have no capabilities
+// outside of their namespace.
+func (c *Core) CapabilitiesAndGrants(token, path string) ([]string, map[string][]*CapabilityGrant, error) {
 	if path == "" {
-		return nil, &logical.StatusBadRequest{Err: "missing path"}
+		return nil, nil, &logical.StatusBadRequest{Err: "missing path"}
 	}
 
 	if token == "" {
-		return nil, &logical.StatusBadRequest{Err: "missing token"}
+		return nil, nil, &logical.StatusBadRequest{Err: "missing token"}
 	}
 
 	te, err := c.tokenStore.Lookup(token)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	if te == nil {
-		return nil, &logical.StatusBadRequest{Err: "invalid token"}
+		return nil, nil, &logical.StatusBadRequest{Err: "invalid token"}
 	}
 
 	if te.Policies == nil {
-		return []string{DenyCapability}, nil
+		return []string{DenyCapability}, map[string][]*CapabilityGrant{}, nil
+	}
+
+	// The policies of the token apply to the path relative to its namespace
+	ns := c.tokenNamespace(te)
+	if ns == nil {
+		return []string{DenyCapability}, map[string][]*CapabilityGrant{}, nil
+	}
+	path, ok := namespaceRelativePath(ns, path)
+	if !ok {
+		return []string{DenyCapability}, map[string][]*CapabilityGrant{}, nil
+	}
+	policyStore := c.namespacePolicyStore(ns)
+	if policyStore == nil {
+		return []string{DenyCapability}, map[string][]*CapabilityGrant{}, nil
 	}
 
 	var policies []*Policy
 	for _, tePolicy := range te.Policies {
-		policy, err := c.policyStore.GetPolicy(tePolicy)
+		policy, err := policyStore.GetPolicy(tePolicy)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		policies = append(policies, policy)
 	}
 
 	if len(policies) == 0 {
-		return []string{DenyCapability}, nil
+		return []string{DenyCapability}, map[string][]*CapabilityGrant{}, nil
 	}
 
 	acl, err := NewACL(policies)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	capabilities := acl.Capabilities(path)
 	sort.Strings(capabilities)
-	return capabilities, nil
+	return capabilities, acl.CapabilityGrants(path), nil
 }
@@ -0,0 +1,123 @@
+package vault
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/hashicorp/vault/physical"
+)
+
+// ErrChaosNotHA is returned when dropping the HA lock is requested on a node
+// that is not running in HA mode
+var ErrChaosNotHA = errors.New("vault is not running in HA mode")
+
+// chaosStorage wraps the physical backend so that storage operations can be
+// delayed on request during failover drills
+type chaosStorage struct {
+	backend physical.Backend
+
+	// delay and delayUntil are stored as nanoseconds so that they can be
+	// read without locking on every storage operation
+	delay      int64
+	delayUntil int64
+}
+
+// transactionalChaosStorage is the transactional version of chaosStorage
+type transactionalChaosStorage struct {
+	*chaosStorage
+	physical.Transactional
+}
+
+// setupChaos wraps the physical backend for chaos testing if enabled and
+// returns the backend to use
+func (c *Core) setupChaos(enabled bool, backend physical.Backend) physical.Backend {
+	c.chaosEnabled = enabled
+	if !enabled {
+		return backend
+	}
+
+	c.logger.Warn("core: chaos endpoints are enabled; this should only be used for testing")
+	c.chaosDropLockCh = make(chan struct{}, 1)
+	c.chaosStorage = &chaosStorage{
+		backend: backend,
+	}
+	if txn, ok := backend.(physical.Transactional); ok {
+		return &transactionalChaosStorage{
+			chaosStorage:  c.chaosStorage,
+			Transactional: txn,
+		}
+	}
+	return c.chaosStorage
+}
+
+// SetDelay delays every storage operation by delay until the given
+// duration has passed. A zero duration clears any delay.
+func (s *chaosStorage) SetDelay(delay, duration time.Duration) {
+	if duration <= 0 {
+		atomic.StoreInt64(&s.delayUntil, 0)
+		atomic.StoreInt64(&s.delay, 0)
+		return
+	}
+	atomic.StoreInt64(&s.delay, int64(delay))
+	atomic.StoreInt64(&s.delayUntil, time.Now().Add(duration).UnixNano())
+}
+
+// Delay returns the current delay and until when it applies
+func (s *chaosStorage) Delay() (time.Duration, time.Time) {
+	until := atomic.LoadInt64(&s.delayUntil)
+	if until == 0 || time.Now().UnixNano() >= until {
+		return 0, time.Time{}
+	}
+	return time.Duration(atomic.LoadInt64(&s.delay)), time.Unix(0, until)
+}
+
+func (s *chaosStorage) addDelay() {
+	if delay, _ := s.Delay(); delay > 0 {
+		time.Sleep(delay)
+	}
+}
+
+// Put is a possibly delayed put request
+func (s *chaosStorage) Put(entry *physical.Entry) error {
+	s.addDelay()
+	return s.backend.Put(entry)
+}
+
+// Get is a possibly delayed get request
+func (s *chaosStorage) Get(key string) (*physical.Entry, error) {
+	s.addDelay()
+	return s.backend.Get(key)
+}
+
+// Delete is a possibly delayed delete request
+func (s *chaosStorage) Delete(key string) error {
+	s.addDelay()
+	return s.backend.Delete(key)
+}
+
+// List is a possibly delayed list request
+func (s *chaosStorage) List(prefix string) ([]string, error) {
+	s.addDelay()
+	return s.backend.List(prefix)
+}
+
+// Transaction is a possibly delayed transaction request
+func (s *transactionalChaosStorage) Transaction(txns []physical.TxnEntry) error {
+	s.addDelay()
+	return s.Transactional.Transaction(txns)
+}
+
+// ChaosDropHALock makes the active node release the HA lock immediately,
+// without a graceful step-down, so that a standby can take over
+func (c *Core) ChaosDropHALock() error {
+	if c.ha == nil {
+		return ErrChaosNotHA
+	}
+	select {
+	case c.chaosDropLockCh <- struct{}{}:
+	default:
+		// A drop is already pending
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package vault
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/helper/logformat"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/physical"
+	"github.com/hashicorp/vault/physical/inmem"
+	log "github.com/mgutz/logxi/v1"
+)
+
+func TestChaos_Disabled(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	req := logical.TestRequest(t, logical.ReadOperation, "sys/chaos")
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err == nil || !strings.Contains(err.Error(), logical.ErrUnsupportedPath.Error()) {
+		t.Fatalf("expected unsupported path, got %v", err)
+	}
+}
+
+func TestChaos_StorageDelay(t *testing.T) {
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+	inm, err := inmem.NewTransactionalInmem(nil, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := &Core{logger: logger}
+	backend := c.setupChaos(true, inm)
+	if _, ok := backend.(physical.Transactional); !ok {
+		t.Fatal("expected transactional backend to remain transactional")
+	}
+
+	start := time.Now()
+	if _, err := backend.Get("foo"); err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(start) >= 50*time.Millisecond {
+		t.Fatal("expected no delay by default")
+	}
+
+	c.chaosStorage.SetDelay(50*time.Millisecond, time.Hour)
+	if delay, until := c.chaosStorage.Delay(); delay != 50*time.Millisecond || until.IsZero() {
+		t.Fatalf("bad: %v %v", delay, until)
+	}
+	start = time.Now()
+	if _, err := backend.Get("foo"); err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(start) < 50*time.Millisecond {
+		t.Fatal("expected storage operation to be delayed")
+	}
+
+	c.chaosStorage.SetDelay(0, 0)
+	if delay, _ := c.chaosStorage.Delay(); delay != 0 {
+		t.Fatalf("expected delay to be cleared, got %v", delay)
+	}
+}
+
+func TestChaos_PauseExpiration(t *testing.T) {
+	c, _, _ := TestCoreUnsealed(t)
+	m := c.expiration
+
+	m.PauseExpiration(time.Hour)
+	if m.PausedUntil().IsZero() {
+		t.Fatal("expected expiration to be paused")
+	}
+
+	// An expiring lease is rescheduled rather than revoked
+	m.expireID("sys/fake/lease")
+	m.pendingLock.RLock()
+	timer, ok := m.pending["sys/fake/lease"]
+	m.pendingLock.RUnlock()
+	if !ok {
+		t.Fatal("expected lease to remain pending while paused")
+	}
+	timer.Stop()
+
+	m.PauseExpiration(0)
+	if !m.PausedUntil().IsZero() {
+		t.Fatal("expected expiration to be resumed")
+	}
+}
+
+func TestChaos_Endpoints(t *testing.T) {
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+	inm, err := inmem.NewInmem(nil, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf := testCoreConfig(t, inm, logger)
+	conf.EnableChaos = true
+	c, err := NewCore(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, _, root := testCoreUnsealed(t, c)
+
+	doReq := func(op logical.Operation, path string, data map[string]interface{}) (*logical.Response, error) {
+		req := logical.TestRequest(t, op, path)
+		req.ClientToken = root
+		req.Data = data
+		return c.HandleRequest(req)
+	}
+
+	if _, err := doReq(logical.UpdateOperation, "sys/chaos/pause-expiration", map[string]interface{}{
+		"duration": "1h",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := doReq(logical.ReadOperation, "sys/chaos", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Data["expiration_paused_until"].(string) == "" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if _, err := doReq(logical.DeleteOperation, "sys/chaos/pause-expiration", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err = doReq(logical.UpdateOperation, "sys/chaos/storage-delay", map[string]interface{}{
+		"duration": 0,
+	})
+	if err == nil || !resp.IsError() {
+		t.Fatalf("expected error response, got %v %#v", err, resp)
+	}
+
+	// Not running in HA mode
+	resp, err = doReq(logical.UpdateOperation, "sys/chaos/drop-ha-lock", nil)
+	if err == nil || !resp.IsError() {
+		t.Fatalf("expected error response, got %v %#v", err, resp)
+	}
+}
+
+func TestChaos_DropHALock(t *testing.T) {
+	logger = logformat.NewVaultLogger(log.LevelTrace)
+
+	inm, err := inmem.NewInmemHA(nil, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inmha, err := inmem.NewInmemHA(nil, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newCore := func(redirect string) *Core {
+		core, err := NewCore(&CoreConfig{
+			Physical:     inm,
+			HAPhysical:   inmha.(physical.HABackend),
+			RedirectAddr: redirect,
+			DisableMlock: true,
+			EnableChaos:  true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return core
+	}
+
+	core := newCore("http://127.0.0.1:8200")
+	keys, _ := TestCoreInit(t, core)
+	for _, key := range keys {
+		if _, err := TestCoreUnseal(core, TestKeyCopy(key)); err != nil {
+			t.Fatalf("unseal err: %s", err)
+		}
+	}
+	TestWaitActive(t, core)
+
+	core2 := newCore("http://127.0.0.1:8500")
+	for _, key := range keys {
+		if _, err := TestCoreUnseal(core2, TestKeyCopy(key)); err != nil {
+			t.Fatalf("unseal err: %s", err)
+		}
+	}
+
+	if err := core.ChaosDropHALock(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Give time to switch leaders
+	time.Sleep(5 * time.Second)
+
+	if standby, err := core.Standby(); err != nil || !standby {
+		t.Fatalf("expected core 1 to be standby: %v", err)
+	}
+	if standby, err := core2.Standby(); err != nil || standby {
+		t.Fatalf("expected core 2 to be active: %v", err)
+	}
+}
@@ -356,6 +356,15 @@ func testCluster_ForwardRequests(t *testing.T, c *TestClusterCore, rootToken, re
 		t.Fatalf("err: %v", err)
 	}
 
+	// The request was forwarded over the pooled connections, which are
+	// released once it completes
+	c.requestForwardingConnectionLock.RLock()
+	pool := c.rpcForwardingPool
+	c.requestForwardingConnectionLock.RUnlock()
+	if pool == nil || len(pool.conns) != defaultForwardingPoolSize || len(pool.slots) != 0 {
+		t.Fatalf("bad: pool: %#v", pool)
+	}
+
 	if header == nil {
 		t.Fatal("err: expected at least a content-type header")
 	}
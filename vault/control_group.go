@@ -0,0 +1,353 @@
+package vault
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/helper/parseutil"
+	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/logical"
+)
+
+const (
+	// controlGroupSubPath is the sub-path of the system barrier view where
+	// the requests parked by control groups are stored
+	controlGroupSubPath = "control-group/"
+
+	// controlGroupDefaultTTL is the time given to the approvers of a control
+	// group when the policy doesn't set one
+	controlGroupDefaultTTL = 24 * time.Hour
+)
+
+// ControlGroupHCL is the control_group stanza of a path in a policy
+type ControlGroupHCL struct {
+	TTL     interface{}           `hcl:"ttl"`
+	Factors []*ControlGroupFactor `hcl:"factor"`
+}
+
+// ControlGroup requires the requests on a path to be authorized by members
+// of identity groups before they are served
+type ControlGroup struct {
+	// TTL is the time given to the approvers to authorize a request
+	TTL time.Duration
+
+	// Factors must all be satisfied for a request to be authorized
+	Factors []*ControlGroupFactor
+}
+
+// ControlGroupFactor is satisfied once the given number of members of the
+// given identity groups authorized a request
+type ControlGroupFactor struct {
+	Name       string   `hcl:",key" json:"name" structs:"name" mapstructure:"name"`
+	GroupNames []string `hcl:"group_names" json:"group_names" structs:"group_names" mapstructure:"group_names"`
+	Approvals  int      `hcl:"approvals" json:"approvals" structs:"approvals" mapstructure:"approvals"`
+}
+
+// parseControlGroup validates the control_group stanza of a policy path
+func parseControlGroup(cgHCL *ControlGroupHCL) (*ControlGroup, error) {
+	cg := &ControlGroup{
+		TTL: controlGroupDefaultTTL,
+	}
+
+	if cgHCL.TTL != nil {
+		ttl, err := parseutil.ParseDurationSecond(cgHCL.TTL)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing control group ttl: %v", err)
+		}
+		if ttl <= 0 {
+			return nil, fmt.Errorf("control group ttl must be positive")
+		}
+		cg.TTL = ttl
+	}
+
+	if len(cgHCL.Factors) == 0 {
+		return nil, fmt.Errorf("control group requires at least one factor")
+	}
+	for _, factor := range cgHCL.Factors {
+		if len(factor.GroupNames) == 0 {
+			return nil, fmt.Errorf("control group factor %q requires group_names", factor.Name)
+		}
+		switch {
+		case factor.Approvals == 0:
+			factor.Approvals = 1
+		case factor.Approvals < 0:
+			return nil, fmt.Errorf("control group factor %q requires a positive number of approvals", factor.Name)
+		}
+		cg.Factors = append(cg.Factors, factor)
+	}
+
+	return cg, nil
+}
+
+// Clone returns a deep copy of the control group
+func (cg *ControlGroup) Clone() *ControlGroup {
+	ret := &ControlGroup{
+		TTL:     cg.TTL,
+		Factors: make([]*ControlGroupFactor, 0, len(cg.Factors)),
+	}
+	for _, factor := range cg.Factors {
+		ret.Factors = append(ret.Factors, &ControlGroupFactor{
+			Name:       factor.Name,
+			GroupNames: append([]string(nil), factor.GroupNames...),
+			Approvals:  factor.Approvals,
+		})
+	}
+	return ret
+}
+
+// merge adds the factors of another control group defined on the same path,
+// keeping the shortest TTL
+func (cg *ControlGroup) merge(other *ControlGroup) {
+	if other.TTL < cg.TTL {
+		cg.TTL = other.TTL
+	}
+	cg.Factors = append(cg.Factors, other.Clone().Factors...)
+}
+
+// ControlGroupAuthorization records the authorization of a request by an
+// approver
+type ControlGroupAuthorization struct {
+	EntityID string    `json:"entity_id" structs:"entity_id" mapstructure:"entity_id"`
+	Groups   []string  `json:"groups" structs:"groups" mapstructure:"groups"`
+	Time     time.Time `json:"time" structs:"time" mapstructure:"time"`
+}
+
+// ControlGroupRequest is a request parked until its control group is
+// satisfied
+type ControlGroupRequest struct {
+	ID              string                       `json:"id" structs:"id" mapstructure:"id"`
+	Path            string                       `json:"path" structs:"path" mapstructure:"path"`
+	Operation       logical.Operation            `json:"operation" structs:"operation" mapstructure:"operation"`
+	DataHash        string                       `json:"data_hash" structs:"-" mapstructure:"data_hash"`
+	RequestAccessor string                       `json:"request_accessor" structs:"-" mapstructure:"request_accessor"`
+	RequestEntityID string                       `json:"request_entity_id" structs:"request_entity_id" mapstructure:"request_entity_id"`
+	CreationTime    time.Time                    `json:"creation_time" structs:"creation_time" mapstructure:"creation_time"`
+	ExpireTime      time.Time                    `json:"expire_time" structs:"expire_time" mapstructure:"expire_time"`
+	Factors         []*ControlGroupFactor        `json:"factors" structs:"factors" mapstructure:"factors"`
+	Authorizations  []*ControlGroupAuthorization `json:"authorizations" structs:"authorizations" mapstructure:"authorizations"`
+}
+
+// Approved returns whether all the factors of the control group are
+// satisfied
+func (r *ControlGroupRequest) Approved() bool {
+	for _, factor := range r.Factors {
+		var approvals int
+		for _, authz := range r.Authorizations {
+			for _, group := range authz.Groups {
+				if strutil.StrListContains(factor.GroupNames, group) {
+					approvals++
+					break
+				}
+			}
+		}
+		if approvals < factor.Approvals {
+			return false
+		}
+	}
+	return true
+}
+
+// controlGroupPendingError is returned when checking the token of a request
+// which was parked by a control group
+type controlGroupPendingError struct {
+	request *ControlGroupRequest
+}
+
+func (e *controlGroupPendingError) Error() string {
+	return fmt.Sprintf("request %q requires the authorization of a control group", e.request.ID)
+}
+
+// response returns the response telling the requester how to follow up on
+// the parked request
+func (e *controlGroupPendingError) response() *logical.Response {
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"control_group_id": e.request.ID,
+			"creation_time":    e.request.CreationTime,
+			"expire_time":      e.request.ExpireTime,
+		},
+	}
+	resp.AddWarning("The request requires the authorization of a control group. Once authorized, send the request again with the control group ID in the X-Vault-Control-Group header.")
+	return resp
+}
+
+// controlGroupDataHash binds a parked request to its data, so that the
+// request replayed after the authorization can't be modified
+func controlGroupDataHash(data map[string]interface{}) (string, error) {
+	if len(data) == 0 {
+		return "", nil
+	}
+	encoded, err := jsonutil.EncodeJSON(data)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// checkControlGroup parks a request on a path governed by a control group, or
+// lets through the replay of a request that was authorized
+func (c *Core) checkControlGroup(req *logical.Request, te *TokenEntry, cg *ControlGroup) error {
+	dataHash, err := controlGroupDataHash(req.Data)
+	if err != nil {
+		c.logger.Error("core: failed to hash control group request data", "error", err)
+		return ErrInternalError
+	}
+
+	if req.ControlGroupID == "" {
+		id, err := uuid.GenerateUUID()
+		if err != nil {
+			c.logger.Error("core: failed to generate control group request ID", "error", err)
+			return ErrInternalError
+		}
+
+		now := time.Now()
+		cgReq := &ControlGroupRequest{
+			ID:              id,
+			Path:            req.Path,
+			Operation:       req.Operation,
+			DataHash:        dataHash,
+			RequestAccessor: te.Accessor,
+			RequestEntityID: te.EntityID,
+			CreationTime:    now,
+			ExpireTime:      now.Add(cg.TTL),
+			Factors:         cg.Factors,
+		}
+		if err := c.putControlGroupRequest(cgReq); err != nil {
+			c.logger.Error("core: failed to store control group request", "error", err)
+			return ErrInternalError
+		}
+
+		metrics.IncrCounter([]string{"control_group", "request"}, 1)
+		return &controlGroupPendingError{request: cgReq}
+	}
+
+	c.controlGroupLock.Lock()
+	defer c.controlGroupLock.Unlock()
+
+	cgReq, err := c.ControlGroupRequest(req.ControlGroupID)
+	if err != nil {
+		c.logger.Error("core: failed to read control group request", "error", err)
+		return ErrInternalError
+	}
+	switch {
+	case cgReq == nil:
+		return fmt.Errorf("unknown or expired control group request")
+	case cgReq.Path != req.Path || cgReq.Operation != req.Operation ||
+		cgReq.DataHash != dataHash || cgReq.RequestAccessor != te.Accessor ||
+		cgReq.RequestEntityID != te.EntityID:
+		return logical.ErrPermissionDenied
+	case !cgReq.Approved():
+		return fmt.Errorf("control group request has not been authorized")
+	}
+
+	// Authorized requests are only replayed once
+	view := c.systemBarrierView.SubView(controlGroupSubPath)
+	if err := view.Delete(cgReq.ID); err != nil {
+		c.logger.Error("core: failed to delete control group request", "error", err)
+		return ErrInternalError
+	}
+	return nil
+}
+
+func (c *Core) putControlGroupRequest(cgReq *ControlGroupRequest) error {
+	entry, err := logical.StorageEntryJSON(cgReq.ID, cgReq)
+	if err != nil {
+		return err
+	}
+	view := c.systemBarrierView.SubView(controlGroupSubPath)
+	return view.Put(entry)
+}
+
+// ControlGroupRequest returns the parked request of the given ID, or nil if it
+// doesn't exist or expired
+func (c *Core) ControlGroupRequest(id string) (*ControlGroupRequest, error) {
+	if id == "" || strings.Contains(id, "/") {
+		return nil, nil
+	}
+
+	view := c.systemBarrierView.SubView(controlGroupSubPath)
+	entry, err := view.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var cgReq ControlGroupRequest
+	if err := entry.DecodeJSON(&cgReq); err != nil {
+		return nil, err
+	}
+
+	// Expired requests are cleaned up when they are accessed
+	if time.Now().After(cgReq.ExpireTime) {
+		if err := view.Delete(id); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}
+	return &cgReq, nil
+}
+
+// AuthorizeControlGroupRequest records the authorization of a parked request
+// by the given entity, which must be a member of one of the groups of the
+// control group
+func (c *Core) AuthorizeControlGroupRequest(id, entityID string) (*ControlGroupRequest, error) {
+	if entityID == "" {
+		return nil, fmt.Errorf("authorizing a control group request requires a token with an entity")
+	}
+
+	c.controlGroupLock.Lock()
+	defer c.controlGroupLock.Unlock()
+
+	cgReq, err := c.ControlGroupRequest(id)
+	if err != nil {
+		return nil, err
+	}
+	if cgReq == nil {
+		return nil, fmt.Errorf("unknown or expired control group request")
+	}
+	if cgReq.RequestEntityID == entityID {
+		return nil, fmt.Errorf("requesters cannot authorize their own requests")
+	}
+	for _, authz := range cgReq.Authorizations {
+		if authz.EntityID == entityID {
+			return cgReq, nil
+		}
+	}
+
+	groups, err := c.identityStore.transitiveGroupsByEntityID(entityID)
+	if err != nil {
+		return nil, err
+	}
+	var approverGroups []string
+	for _, group := range groups {
+		for _, factor := range cgReq.Factors {
+			if strutil.StrListContains(factor.GroupNames, group.Name) {
+				approverGroups = strutil.AppendIfMissing(approverGroups, group.Name)
+			}
+		}
+	}
+	if len(approverGroups) == 0 {
+		return nil, logical.ErrPermissionDenied
+	}
+
+	cgReq.Authorizations = append(cgReq.Authorizations, &ControlGroupAuthorization{
+		EntityID: entityID,
+		Groups:   approverGroups,
+		Time:     time.Now(),
+	})
+	if err := c.putControlGroupRequest(cgReq); err != nil {
+		return nil, err
+	}
+
+	metrics.IncrCounter([]string{"control_group", "authorize"}, 1)
+	return cgReq, nil
+}
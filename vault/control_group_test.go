@@ -0,0 +1,239 @@
+package vault
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+var controlGroupPolicy = `
+path "secret/foo" {
+	capabilities = ["create", "read", "update"]
+	control_group = {
+		ttl = "1h"
+		factor "managers" {
+			group_names = ["managers"]
+			approvals = 2
+		}
+	}
+}
+path "secret/bar" {
+	capabilities = ["read"]
+}
+path "sys/control-group/*" {
+	capabilities = ["update"]
+}
+`
+
+func TestControlGroup(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	handle := func(req *logical.Request) *logical.Response {
+		t.Helper()
+		resp, err := c.HandleRequest(req)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+		}
+		return resp
+	}
+	request := func(op logical.Operation, path, token string, data map[string]interface{}) *logical.Request {
+		req := logical.TestRequest(t, op, path)
+		req.ClientToken = token
+		req.Data = data
+		return req
+	}
+
+	policy, err := Parse(controlGroupPolicy)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	policy.Name = "control-group"
+	if err := c.policyStore.SetPolicy(policy); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	handle(request(logical.UpdateOperation, "secret/foo", root, map[string]interface{}{"zip": "zap"}))
+
+	// Create the requester and three approvers, two of which are managers
+	var entityIDs []string
+	for _, name := range []string{"requester", "manager1", "manager2", "other"} {
+		resp := handle(request(logical.UpdateOperation, "identity/entity", root, map[string]interface{}{"name": name}))
+		entityIDs = append(entityIDs, resp.Data["id"].(string))
+	}
+	handle(request(logical.UpdateOperation, "identity/group", root, map[string]interface{}{
+		"name":              "managers",
+		"member_entity_ids": entityIDs[1:3],
+	}))
+
+	var tokens []string
+	for _, entityID := range entityIDs {
+		te := &TokenEntry{
+			Path:     "test",
+			Policies: []string{"control-group"},
+			EntityID: entityID,
+		}
+		if err := c.tokenStore.create(te); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		tokens = append(tokens, te.ID)
+	}
+	requester := tokens[0]
+
+	// Paths without a control group are not affected
+	handle(request(logical.ReadOperation, "secret/bar", requester, nil))
+
+	// The request is parked
+	resp := handle(request(logical.ReadOperation, "secret/foo", requester, nil))
+	if resp.Data["zip"] != nil || resp.Data["control_group_id"] == nil {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	id := resp.Data["control_group_id"].(string)
+
+	// The request can't be replayed until it is authorized
+	replay := request(logical.ReadOperation, "secret/foo", requester, nil)
+	replay.ControlGroupID = id
+	if _, err := c.HandleRequest(replay); err == nil {
+		t.Fatalf("expected an error")
+	}
+
+	// Requesters and non approvers can't authorize the request
+	for _, token := range []string{requester, tokens[3]} {
+		if _, err := c.HandleRequest(request(logical.UpdateOperation, "sys/control-group/authorize", token, map[string]interface{}{"id": id})); err == nil {
+			t.Fatalf("expected an error")
+		}
+	}
+
+	resp = handle(request(logical.UpdateOperation, "sys/control-group/authorize", tokens[1], map[string]interface{}{"id": id}))
+	if resp.Data["approved"] != false {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	// Authorizing twice doesn't count twice
+	resp = handle(request(logical.UpdateOperation, "sys/control-group/authorize", tokens[1], map[string]interface{}{"id": id}))
+	if resp.Data["approved"] != false {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	handle(request(logical.UpdateOperation, "sys/control-group/authorize", tokens[2], map[string]interface{}{"id": id}))
+
+	resp = handle(request(logical.UpdateOperation, "sys/control-group/request", requester, map[string]interface{}{"id": id}))
+	if resp.Data["approved"] != true || resp.Data["path"] != "secret/foo" ||
+		len(resp.Data["authorizations"].([]map[string]interface{})) != 2 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// The replay must match the parked request
+	modified := request(logical.ReadOperation, "secret/foo", tokens[3], nil)
+	modified.ControlGroupID = id
+	if _, err := c.HandleRequest(modified); err == nil {
+		t.Fatalf("expected an error")
+	}
+
+	replay = request(logical.ReadOperation, "secret/foo", requester, nil)
+	replay.ControlGroupID = id
+	resp = handle(replay)
+	if resp.Data["zip"] != "zap" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Authorized requests are only replayed once
+	replay = request(logical.ReadOperation, "secret/foo", requester, nil)
+	replay.ControlGroupID = id
+	if _, err := c.HandleRequest(replay); err == nil {
+		t.Fatalf("expected an error")
+	}
+
+	// Writes are bound to their data
+	resp = handle(request(logical.UpdateOperation, "secret/foo", requester, map[string]interface{}{"zip": "zop"}))
+	id = resp.Data["control_group_id"].(string)
+	handle(request(logical.UpdateOperation, "sys/control-group/authorize", tokens[1], map[string]interface{}{"id": id}))
+	handle(request(logical.UpdateOperation, "sys/control-group/authorize", tokens[2], map[string]interface{}{"id": id}))
+	replay = request(logical.UpdateOperation, "secret/foo", requester, map[string]interface{}{"zip": "zoom"})
+	replay.ControlGroupID = id
+	if _, err := c.HandleRequest(replay); err == nil {
+		t.Fatalf("expected an error")
+	}
+	replay.Data = map[string]interface{}{"zip": "zop"}
+	handle(replay)
+
+	// Root tokens are not subject to control groups
+	resp = handle(request(logical.ReadOperation, "secret/foo", root, nil))
+	if resp.Data["zip"] != "zop" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+}
+
+func TestControlGroup_Expired(t *testing.T) {
+	c, _, _ := TestCoreUnsealed(t)
+
+	cgReq := &ControlGroupRequest{
+		ID:         "expired",
+		Path:       "secret/foo",
+		Operation:  logical.ReadOperation,
+		ExpireTime: time.Now().Add(-time.Minute),
+	}
+	if err := c.putControlGroupRequest(cgReq); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	cgReq, err := c.ControlGroupRequest("expired")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if cgReq != nil {
+		t.Fatalf("expected the request to be expired")
+	}
+	if entry, _ := c.systemBarrierView.SubView(controlGroupSubPath).Get("expired"); entry != nil {
+		t.Fatalf("expected the request to be deleted")
+	}
+}
+
+func TestControlGroup_Merge(t *testing.T) {
+	policy1, err := Parse(controlGroupPolicy)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	policy2, err := Parse(`
+path "secret/foo" {
+	capabilities = ["read"]
+	control_group = {
+		ttl = "30m"
+		factor "security" {
+			group_names = ["security"]
+		}
+	}
+}
+`)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	acl, err := NewACL([]*Policy{policy1, policy2})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	cg := acl.ControlGroup("secret/foo")
+	if cg == nil || cg.TTL != 30*time.Minute || len(cg.Factors) != 2 ||
+		cg.Factors[1].Name != "security" || cg.Factors[1].Approvals != 1 {
+		t.Fatalf("bad: %#v", cg)
+	}
+	if cg := acl.ControlGroup("secret/bar"); cg != nil {
+		t.Fatalf("bad: %#v", cg)
+	}
+
+	// The policies are not modified by the merge
+	if len(policy1.Paths[0].Permissions.ControlGroup.Factors) != 1 {
+		t.Fatalf("bad: %#v", policy1.Paths[0].Permissions.ControlGroup)
+	}
+
+	for raw, expected := range map[string]string{
+		`path "secret/foo" { control_group = { ttl = "1h" } }`:                                                        "requires at least one factor",
+		`path "secret/foo" { control_group = { factor "empty" { approvals = 1 } } }`:                                  `factor "empty" requires group_names`,
+		"path \"secret/foo\" { control_group = { factor \"negative\" {\ngroup_names = [\"a\"]\napprovals = -1\n} } }": "positive number of approvals",
+	} {
+		if _, err := Parse(raw); err == nil || !strings.Contains(err.Error(), expected) {
+			t.Fatalf("expected an error parsing %s; err: %v", raw, err)
+		}
+	}
+}
@@ -11,10 +11,13 @@ import (
 	"net/http"
 	"net/url"
 	"path/filepath"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/armon/go-metrics"
+	"github.com/armon/go-radix"
 	log "github.com/mgutz/logxi/v1"
 
 	"golang.org/x/net/context"
@@ -24,6 +27,7 @@ import (
 	"github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/go-uuid"
 	"github.com/hashicorp/vault/audit"
+	"github.com/hashicorp/vault/helper/cidrutil"
 	"github.com/hashicorp/vault/helper/consts"
 	"github.com/hashicorp/vault/helper/errutil"
 	"github.com/hashicorp/vault/helper/identity"
@@ -34,6 +38,7 @@ import (
 	"github.com/hashicorp/vault/helper/tlsutil"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/physical"
+	physRaft "github.com/hashicorp/vault/physical/raft"
 	"github.com/hashicorp/vault/shamir"
 	cache "github.com/patrickmn/go-cache"
 )
@@ -104,6 +109,7 @@ var (
 	startReplication     = startReplicationImpl
 	stopReplication      = stopReplicationImpl
 	LastRemoteWAL        = lastRemoteWALImpl
+	replicationWALLag    = replicationWALLagImpl
 )
 
 // NonFatalError is an error that can be returned during NewCore that should be
@@ -164,9 +170,20 @@ type Core struct {
 	// physical backend is the un-trusted backend with durable data
 	physical physical.Backend
 
+	// raftBackend is the physical backend if the storage is raft
+	raftBackend *physRaft.RaftBackend
+
 	// Our Seal, for seal configuration information
 	seal Seal
 
+	// unwrapSeal is the seal being migrated away from, still used to
+	// decrypt the entries it seal-wrapped
+	unwrapSeal Seal
+
+	// sealWrapStorage wraps the physical backend to seal-wrap the entries
+	// requesting it
+	sealWrapStorage *sealWrapStorage
+
 	// barrier is the security barrier wrapping the physical backend
 	barrier SecurityBarrier
 
@@ -191,15 +208,32 @@ type Core struct {
 	standbyStopCh    chan struct{}
 	manualStepDownCh chan struct{}
 
+	// unsealTime is when the barrier was last unsealed on this node; it is
+	// compared against any persisted emergency seal marker when taking over
+	// as the active node
+	unsealTime time.Time
+
 	// unlockInfo has the keys provided to Unseal until the threshold number of parts is available, as well as the operation nonce
 	unlockInfo *unlockInformation
 
+	// sealMigration is the pending migration of the seal protecting the
+	// master key, if any
+	sealMigration *sealMigration
+
 	// generateRootProgress holds the shares until we reach enough
 	// to verify the master key
 	generateRootConfig   *GenerateRootConfig
 	generateRootProgress [][]byte
 	generateRootLock     sync.Mutex
 
+	// emergencySealNonce and emergencySealProgress track an in-progress
+	// emergency seal authorization; emergencySealPending is set once the
+	// quorum has been reached so that standbys can be told to seal
+	emergencySealNonce    string
+	emergencySealProgress map[string]struct{}
+	emergencySealLock     sync.Mutex
+	emergencySealPending  uint32
+
 	// These variables holds the config and shares we have until we reach
 	// enough to verify the appropriate master key. Note that the same lock is
 	// used; this isn't time-critical so this shouldn't be a problem.
@@ -209,6 +243,17 @@ type Core struct {
 	recoveryRekeyProgress [][]byte
 	rekeyLock             sync.RWMutex
 
+	// These variables hold the new keys of rekeys requiring verification,
+	// which are only installed once a threshold of their shares is submitted
+	// back, the backups of their PGP-encrypted shares and the shares
+	// submitted so far
+	barrierRekeyVerifyKey       []byte
+	barrierRekeyVerifyBackup    *RekeyBackup
+	barrierRekeyVerifyProgress  [][]byte
+	recoveryRekeyVerifyKey      []byte
+	recoveryRekeyVerifyBackup   *RekeyBackup
+	recoveryRekeyVerifyProgress [][]byte
+
 	// mounts is loaded after unseal since it is a protected
 	// configuration
 	mounts *MountTable
@@ -257,12 +302,28 @@ type Core struct {
 	// policy store is used to manage named ACL policies
 	policyStore *PolicyStore
 
+	// namespaces holds the namespaces by path, including the root
+	// namespace, namespacesByID by ID, and namespacePolicyStores the policy
+	// stores of the namespaces other than root by ID
+	namespaces            *radix.Tree
+	namespacesByID        map[string]*Namespace
+	namespacePolicyStores map[string]*PolicyStore
+
+	// namespacesLock is used to ensure that the namespaces do not change
+	// underneath a calling function. It is acquired before the mounts and
+	// auth locks.
+	namespacesLock sync.RWMutex
+
 	// token store is used to manage authentication tokens
 	tokenStore *TokenStore
 
 	// identityStore is used to manage client entities
 	identityStore *IdentityStore
 
+	// identityLoaded is set once the identity store is set up after
+	// unsealing
+	identityLoaded int32
+
 	// metricsCh is used to stop the metrics streaming
 	metricsCh chan struct{}
 
@@ -270,6 +331,21 @@ type Core struct {
 	// metrics emission and sealing leading to a nil pointer
 	metricsMutex sync.Mutex
 
+	// tokenTidyInterval is the interval at which the token store is tidied
+	// in the background; zero disables it
+	tokenTidyInterval time.Duration
+
+	// tokenTidyCh is used to stop the background tidying of the token store
+	tokenTidyCh chan struct{}
+
+	// keyRotationCh is used to stop the background checks of the automatic
+	// rotation of the active key
+	keyRotationCh chan struct{}
+
+	// leaseRestoreWorkers is the number of workers restoring the leases at
+	// unseal time; zero uses the default
+	leaseRestoreWorkers int
+
 	defaultLeaseTTL time.Duration
 	maxLeaseTTL     time.Duration
 
@@ -284,6 +360,18 @@ type Core struct {
 	// reloadFuncsLock controls access to the funcs
 	reloadFuncsLock sync.RWMutex
 
+	// reloadHook reloads the configuration of the server when requested
+	// through sys/config/reload; may be nil
+	reloadHook func() error
+
+	// configStateHook returns the configuration in effect of the server for
+	// sys/config/state; may be nil
+	configStateHook func() map[string]interface{}
+
+	// metricsSink holds the recent metrics of the server returned by
+	// sys/metrics; may be nil
+	metricsSink *metrics.InmemSink
+
 	// wrappingJWTKey is the key used for generating JWTs containing response
 	// wrapping information
 	wrappingJWTKey *ecdsa.PrivateKey
@@ -336,14 +424,43 @@ type Core struct {
 	rpcClientConnContext context.Context
 	// The function for canceling the client connection
 	rpcClientConnCancelFunc context.CancelFunc
-	// The grpc ClientConn for RPC calls
-	rpcClientConn *grpc.ClientConn
-	// The grpc forwarding client
+	// The pool of grpc connections the requests are forwarded over
+	rpcForwardingPool *forwardingPool
+	// The grpc forwarding client heartbeating the active node
 	rpcForwardingClient *forwardingClient
+	// The configuration of request forwarding, with defaults filled in
+	requestForwardingConfig *RequestForwardingConfig
+
+	// pluginMonitor probes the external plugin processes and restarts the
+	// ones which crashed
+	pluginMonitor *pluginMonitor
 
 	// CORS Information
 	corsConfig *CORSConfig
 
+	// leaseQuotas holds the lease count quotas by name
+	leaseQuotas     map[string]*LeaseCountQuota
+	leaseQuotasLock sync.RWMutex
+
+	// inFlightRequests tracks the requests being handled
+	inFlightRequests inFlightRequests
+
+	// rateLimitQuotas holds the limiters of the rate limit quotas by name
+	rateLimitQuotas     map[string]*rateLimiter
+	rateLimitQuotasLock sync.RWMutex
+
+	// controlGroupLock serializes the authorizations and replays of the
+	// requests parked by control groups
+	controlGroupLock sync.Mutex
+
+	// requestRuleHooks evaluate the requests once their token is authorized,
+	// starting with the endpoint governing policies
+	requestRuleHooks []RequestRuleHook
+
+	// endpointPolicies holds the compiled endpoint governing policies by name
+	endpointPolicies     map[string]*EndpointPolicy
+	endpointPoliciesLock sync.RWMutex
+
 	// replicationState keeps the current replication state cached for quick
 	// lookup
 	replicationState consts.ReplicationState
@@ -354,6 +471,30 @@ type Core struct {
 	// rawEnabled indicates whether the Raw endpoint is enabled
 	rawEnabled bool
 
+	// chaosEnabled indicates whether the chaos endpoints are enabled; if so,
+	// chaosStorage wraps the physical backend and chaosDropLockCh is used
+	// to make the active node drop the HA lock
+	chaosEnabled    bool
+	chaosStorage    *chaosStorage
+	chaosDropLockCh chan struct{}
+
+	// rewrap is the most recent rewrap of storage with the active
+	// encryption key
+	rewrap     *storageRewrap
+	rewrapLock sync.Mutex
+
+	// mountExportKeyLock guards the generation of the key used to sign
+	// exported mount configuration
+	mountExportKeyLock sync.Mutex
+
+	// stateIndex counts the writes applied by the active nodes of the
+	// cluster so that clients can request read-your-writes consistency
+	stateIndex stateIndex
+
+	// loadShedder tracks load signals used to reject low-priority requests;
+	// nil if load shedding is not configured
+	loadShedder *loadShedder
+
 	// pluginDirectory is the location vault will look for plugin binaries
 	pluginDirectory string
 
@@ -385,6 +526,10 @@ type CoreConfig struct {
 
 	Seal Seal `json:"seal" structs:"seal" mapstructure:"seal"`
 
+	// UnwrapSeal is the seal the master key is migrated away from, when
+	// migrating from an auto-unseal seal to Shamir
+	UnwrapSeal Seal `json:"unwrap_seal" structs:"unwrap_seal" mapstructure:"unwrap_seal"`
+
 	Logger log.Logger `json:"logger" structs:"logger" mapstructure:"logger"`
 
 	// Disables the LRU cache on the physical backend
@@ -396,6 +541,10 @@ type CoreConfig struct {
 	// Custom cache size for the LRU cache on the physical backend, or zero for default
 	CacheSize int `json:"cache_size" structs:"cache_size" mapstructure:"cache_size"`
 
+	// Configures the type, TTL and bypassed prefixes of the LRU cache on the
+	// physical backend. Its size overrides CacheSize if set.
+	PhysicalCache *physical.CacheConfig `json:"physical_cache" structs:"physical_cache" mapstructure:"physical_cache"`
+
 	// Set as the leader address for HA
 	RedirectAddr string `json:"redirect_addr" structs:"redirect_addr" mapstructure:"redirect_addr"`
 
@@ -415,8 +564,50 @@ type CoreConfig struct {
 	// Enable the raw endpoint
 	EnableRaw bool `json:"enable_raw" structs:"enable_raw" mapstructure:"enable_raw"`
 
+	// Enable the chaos endpoints used for failover drills
+	EnableChaos bool `json:"enable_chaos" structs:"enable_chaos" mapstructure:"enable_chaos"`
+
 	PluginDirectory string `json:"plugin_directory" structs:"plugin_directory" mapstructure:"plugin_directory"`
 
+	// The interval at which the token store is tidied in the background; zero
+	// disables it
+	TokenTidyInterval time.Duration `json:"token_tidy_interval" structs:"token_tidy_interval" mapstructure:"token_tidy_interval"`
+
+	// The number of workers restoring the leases at unseal time; zero uses
+	// the default
+	LeaseRestoreWorkers int `json:"lease_restore_workers" structs:"lease_restore_workers" mapstructure:"lease_restore_workers"`
+
+	// The maximum size in bytes of the entries written to the physical
+	// backend; zero disables the limit
+	MaxEntrySize int `json:"max_entry_size" structs:"max_entry_size" mapstructure:"max_entry_size"`
+
+	// May be nil, which disables load shedding
+	LoadShedding *LoadSheddingConfig `json:"load_shedding" structs:"load_shedding" mapstructure:"load_shedding"`
+
+	// May be nil, which uses the default request forwarding settings
+	RequestForwarding *RequestForwardingConfig `json:"request_forwarding" structs:"request_forwarding" mapstructure:"request_forwarding"`
+
+	// May be nil, which uses the default restart policy of the plugin
+	// processes
+	PluginRestart *PluginRestartConfig `json:"plugin_restart" structs:"plugin_restart" mapstructure:"plugin_restart"`
+
+	// Additional hooks evaluating the requests after the endpoint governing
+	// policies, any of which can veto a request
+	RequestRuleHooks []RequestRuleHook `json:"-" structs:"-" mapstructure:"-"`
+
+	// Called by sys/config/reload to reload the configuration of the server
+	// the same way as on SIGHUP; may be nil, which only calls the reload
+	// funcs
+	ReloadHook func() error `json:"-" structs:"-" mapstructure:"-"`
+
+	// Called by sys/config/state to return the configuration in effect of
+	// the server, without its secrets; may be nil
+	ConfigStateHook func() map[string]interface{} `json:"-" structs:"-" mapstructure:"-"`
+
+	// The in-memory sink of the metrics of the server, returned by
+	// sys/metrics; may be nil
+	MetricsSink *metrics.InmemSink `json:"-" structs:"-" mapstructure:"-"`
+
 	ReloadFuncs     *map[string][]reload.ReloadFunc
 	ReloadFuncsLock *sync.RWMutex
 }
@@ -476,6 +667,10 @@ func NewCore(conf *CoreConfig) (*Core, error) {
 		clusterPeerClusterAddrsCache:     cache.New(3*heartbeatInterval, time.Second),
 		enableMlock:                      !conf.DisableMlock,
 		rawEnabled:                       conf.EnableRaw,
+		tokenTidyInterval:                conf.TokenTidyInterval,
+		leaseRestoreWorkers:              conf.LeaseRestoreWorkers,
+		requestForwardingConfig:          requestForwardingConfig(conf.RequestForwarding),
+		pluginMonitor:                    newPluginMonitor(conf.PluginRestart, conf.Logger),
 	}
 
 	if conf.ClusterCipherSuites != "" {
@@ -487,15 +682,47 @@ func NewCore(conf *CoreConfig) (*Core, error) {
 	}
 
 	c.corsConfig = &CORSConfig{core: c}
+
+	c.requestRuleHooks = append([]RequestRuleHook{&endpointPolicyHook{core: c}}, conf.RequestRuleHooks...)
 	// Load CORS config and provide a value for the core field.
 
-	_, txnOK := conf.Physical.(physical.Transactional)
+	// Limit the size of the entries right above the backend, as seal
+	// wrapping makes them larger
+	c.physical = c.setupEntrySizeLimit(conf.MaxEntrySize, c.physical)
+
+	// Inject chaos delays below the latency tracker so that load shedding
+	// reacts to them as it would to a real storage slowdown
+	c.physical = c.setupChaos(conf.EnableChaos, c.physical)
+
+	// Track storage latency below the cache layer so that cache hits do not
+	// mask a slow backend
+	c.physical = c.setupLoadShedding(conf.LoadShedding, c.physical)
+
+	// Seal-wrap entries below the cache layer so that cached values don't
+	// need to be decrypted by the seal
+	c.physical = c.setupSealWrap(c.physical)
+
+	_, txnOK := c.physical.(physical.Transactional)
 	// Wrap the physical backend in a cache layer if enabled and not already wrapped
 	if _, isCache := conf.Physical.(*physical.Cache); !conf.DisableCache && !isCache {
+		cacheConf := &physical.CacheConfig{
+			Size: conf.CacheSize,
+		}
+		if conf.PhysicalCache != nil {
+			*cacheConf = *conf.PhysicalCache
+			if cacheConf.Size == 0 {
+				cacheConf.Size = conf.CacheSize
+			}
+		}
+
+		var err error
 		if txnOK {
-			c.physical = physical.NewTransactionalCache(conf.Physical, conf.CacheSize, conf.Logger)
+			c.physical, err = physical.NewTransactionalCacheWithConfig(c.physical, cacheConf, conf.Logger)
 		} else {
-			c.physical = physical.NewCache(conf.Physical, conf.CacheSize, conf.Logger)
+			c.physical, err = physical.NewCacheWithConfig(c.physical, cacheConf, conf.Logger)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to set up the physical cache: %v", err)
 		}
 	}
 
@@ -532,6 +759,9 @@ func NewCore(conf *CoreConfig) (*Core, error) {
 	if conf.HAPhysical != nil && conf.HAPhysical.HAEnabled() {
 		c.ha = conf.HAPhysical
 	}
+	if raftBackend, ok := conf.Physical.(*physRaft.RaftBackend); ok {
+		c.raftBackend = raftBackend
+	}
 
 	// We create the funcs here, then populate the given config with it so that
 	// the caller can share state
@@ -540,6 +770,9 @@ func NewCore(conf *CoreConfig) (*Core, error) {
 	c.reloadFuncs = make(map[string][]reload.ReloadFunc)
 	c.reloadFuncsLock.Unlock()
 	conf.ReloadFuncs = &c.reloadFuncs
+	c.reloadHook = conf.ReloadHook
+	c.configStateHook = conf.ConfigStateHook
+	c.metricsSink = conf.MetricsSink
 
 	// Setup the backends
 	logicalBackends := make(map[string]logical.Factory)
@@ -584,6 +817,19 @@ func NewCore(conf *CoreConfig) (*Core, error) {
 		c.seal = &DefaultSeal{}
 	}
 	c.seal.SetCore(c)
+	if conf.UnwrapSeal != nil {
+		c.unwrapSeal = conf.UnwrapSeal
+		c.unwrapSeal.SetCore(c)
+	}
+
+	// Check for a pending seal migration. As with the stored keys, failing to
+	// read storage is not fatal.
+	if err := c.setupSealMigration(conf.UnwrapSeal); err != nil {
+		if _, ok := err.(*NonFatalError); ok {
+			return c, err
+		}
+		return nil, fmt.Errorf("error checking for seal migration: %v", err)
+	}
 
 	// Attempt unsealing with stored keys; if there are no stored keys this
 	// returns nil, otherwise returns nil or an error
@@ -620,6 +866,31 @@ func (c *Core) CORSConfig() *CORSConfig {
 	return c.corsConfig
 }
 
+// Reload reloads the configuration of the server, such as the TLS
+// certificates of its listeners, without restarting it
+func (c *Core) Reload() error {
+	if c.reloadHook != nil {
+		return c.reloadHook()
+	}
+
+	c.reloadFuncsLock.RLock()
+	defer c.reloadFuncsLock.RUnlock()
+
+	var reloadErrors *multierror.Error
+	for k, relFuncs := range c.reloadFuncs {
+		for _, relFunc := range relFuncs {
+			if relFunc == nil {
+				continue
+			}
+			if err := relFunc(nil); err != nil {
+				reloadErrors = multierror.Append(reloadErrors, fmt.Errorf("error reloading %s: %v", k, err))
+			}
+		}
+	}
+
+	return reloadErrors.ErrorOrNil()
+}
+
 // LookupToken returns the properties of the token from the token store. This
 // is particularly useful to fetch the accessor of the client token and get it
 // populated in the logical request along with the client token. The accessor
@@ -714,8 +985,18 @@ func (c *Core) fetchACLTokenEntryAndEntity(clientToken string) (*ACL, *TokenEntr
 		}
 	}
 
+	// The policies of the token are those of its namespace
+	ns := c.tokenNamespace(te)
+	if ns == nil {
+		return nil, nil, nil, logical.ErrPermissionDenied
+	}
+	policyStore := c.namespacePolicyStore(ns)
+	if policyStore == nil {
+		return nil, nil, nil, logical.ErrPermissionDenied
+	}
+
 	// Construct the corresponding ACL object
-	acl, err := c.policyStore.ACL(tokenPolicies...)
+	acl, err := policyStore.ACL(tokenPolicies...)
 	if err != nil {
 		c.logger.Error("core: failed to construct ACL", "error", err)
 		return nil, nil, nil, ErrInternalError
@@ -732,6 +1013,19 @@ func (c *Core) checkToken(req *logical.Request) (*logical.Auth, *TokenEntry, err
 		return nil, te, err
 	}
 
+	// Ensure that the request originates from one of the CIDR blocks the
+	// token is bound to
+	if te != nil && len(te.BoundCIDRs) > 0 {
+		if req.Connection == nil || req.Connection.RemoteAddr == "" {
+			return nil, te, logical.ErrPermissionDenied
+		}
+		belongs, err := cidrutil.IPBelongsToCIDRBlocksSlice(req.Connection.RemoteAddr, te.BoundCIDRs)
+		if err != nil || !belongs {
+			c.logger.Warn("core: token used from an address outside of its bound CIDR blocks", "remote_addr", req.Connection.RemoteAddr)
+			return nil, te, logical.ErrPermissionDenied
+		}
+	}
+
 	// Check if this is a root protected path
 	rootPath := c.router.RootPath(req.Path)
 
@@ -786,9 +1080,22 @@ func (c *Core) checkToken(req *logical.Request) (*logical.Auth, *TokenEntry, err
 		req.EntityID = te.EntityID
 	}
 
+	// The ACL of the token applies to the path relative to its namespace,
+	// outside of which nothing is allowed
+	aclPath, ok := c.tokenACLPath(te, req)
+	if !ok {
+		return auth, te, logical.ErrPermissionDenied
+	}
+	aclReq := req
+	if aclPath != req.Path {
+		aclReq = new(logical.Request)
+		*aclReq = *req
+		aclReq.Path = aclPath
+	}
+
 	// Check the standard non-root ACLs. Return the token entry if it's not
 	// allowed so we can decrement the use count.
-	allowed, rootPrivs := acl.AllowOperation(req)
+	allowed, rootPrivs := acl.AllowOperation(aclReq)
 	if !allowed {
 		// Return auth for audit logging even if not allowed
 		return auth, te, logical.ErrPermissionDenied
@@ -798,6 +1105,21 @@ func (c *Core) checkToken(req *logical.Request) (*logical.Auth, *TokenEntry, err
 		return auth, te, logical.ErrPermissionDenied
 	}
 
+	// Let the request rule hooks veto the request
+	if err := c.evaluateRequestRules(req, te); err != nil {
+		return auth, te, err
+	}
+
+	// Park the requests on paths governed by a control group until they are
+	// authorized. The control group endpoints themselves are exempt so that
+	// the requests can always be authorized.
+	if cg := acl.ControlGroup(aclPath); cg != nil && !strings.HasPrefix(req.Path, "sys/control-group/") {
+		if err := c.checkControlGroup(req, te, cg); err != nil {
+			return auth, te, err
+		}
+	}
+
+	c.withRequestACL(req, acl, te)
 	return auth, te, nil
 }
 
@@ -947,6 +1269,17 @@ func (c *Core) ResetUnsealProcess() {
 // this method is done with it. If you want to keep the key around, a copy
 // should be made.
 func (c *Core) Unseal(key []byte) (bool, error) {
+	return c.unseal(key, false)
+}
+
+// UnsealMigrate is used to provide one of the key parts to unseal the Vault
+// and complete a pending seal migration. The key parts are the unseal keys
+// when migrating from Shamir, or the recovery keys when migrating to Shamir.
+func (c *Core) UnsealMigrate(key []byte) (bool, error) {
+	return c.unseal(key, true)
+}
+
+func (c *Core) unseal(key []byte, migrate bool) (bool, error) {
 	defer metrics.MeasureSince([]string{"core", "unseal"}, time.Now())
 
 	// Verify the key length
@@ -959,10 +1292,20 @@ func (c *Core) Unseal(key []byte) (bool, error) {
 		return false, &ErrInvalidKey{fmt.Sprintf("key is longer than maximum %d bytes", max)}
 	}
 
-	// Get the seal configuration
-	config, err := c.seal.BarrierConfig()
-	if err != nil {
-		return false, err
+	// Get the seal configuration, or the configuration of the keys of the
+	// pending seal migration
+	config := c.SealMigrationConfig()
+	switch {
+	case config != nil && !migrate:
+		return false, ErrSealMigrationPending
+	case config == nil && migrate:
+		return false, ErrNoSealMigration
+	case config == nil:
+		var err error
+		config, err = c.seal.BarrierConfig()
+		if err != nil {
+			return false, err
+		}
 	}
 
 	// Ensure the barrier is initialized
@@ -983,6 +1326,11 @@ func (c *Core) Unseal(key []byte) (bool, error) {
 		return false, err
 	}
 	if masterKey != nil {
+		if migrate {
+			if masterKey, err = c.migrateSeal(masterKey); err != nil {
+				return false, err
+			}
+		}
 		return c.unsealInternal(masterKey)
 	}
 
@@ -1053,6 +1401,17 @@ func (c *Core) unsealInternal(masterKey []byte) (bool, error) {
 	if c.logger.IsInfo() {
 		c.logger.Info("core: vault is unsealed")
 	}
+	c.unsealTime = time.Now()
+	atomic.StoreUint32(&c.emergencySealPending, 0)
+
+	// The raft node needs the TLS key of its cluster to connect to the other
+	// nodes, whether or not this node becomes active
+	if err := c.raftSetupTLS(false); err != nil {
+		c.logger.Error("core: raft TLS setup failed", "error", err)
+		c.barrier.Seal()
+		c.logger.Warn("core: vault is sealed")
+		return false, err
+	}
 
 	// Do post-unseal setup if HA is not enabled
 	if c.ha == nil {
@@ -1216,8 +1575,9 @@ func (c *Core) sealInitCommon(req *logical.Request) (retErr error) {
 		return retErr
 	}
 
-	// We always require root privileges for this operation
-	if !rootPrivs {
+	// We always require root privileges for this operation, which only the
+	// tokens of the root namespace can have over the whole server
+	if !rootPrivs || te.NamespaceID != "" {
 		retErr = multierror.Append(retErr, logical.ErrPermissionDenied)
 		c.stateLock.RUnlock()
 		return retErr
@@ -1317,8 +1677,9 @@ func (c *Core) StepDown(req *logical.Request) (retErr error) {
 		return retErr
 	}
 
-	// We always require root privileges for this operation
-	if !rootPrivs {
+	// We always require root privileges for this operation, which only the
+	// tokens of the root namespace can have over the whole server
+	if !rootPrivs || te.NamespaceID != "" {
 		retErr = multierror.Append(retErr, logical.ErrPermissionDenied)
 		return retErr
 	}
@@ -1425,6 +1786,12 @@ func (c *Core) postUnseal() (retErr error) {
 	if err := enterprisePostUnseal(c); err != nil {
 		return err
 	}
+	if err := c.raftSetupTLS(true); err != nil {
+		return err
+	}
+	if err := c.stateIndex.reset(c.barrier); err != nil {
+		return err
+	}
 	if err := c.ensureWrappingKey(); err != nil {
 		return err
 	}
@@ -1440,6 +1807,9 @@ func (c *Core) postUnseal() (retErr error) {
 	if err := c.setupPolicyStore(); err != nil {
 		return err
 	}
+	if err := c.setupNamespaces(); err != nil {
+		return err
+	}
 	if err := c.loadCORSConfig(); err != nil {
 		return err
 	}
@@ -1449,12 +1819,25 @@ func (c *Core) postUnseal() (retErr error) {
 	if err := c.setupCredentials(); err != nil {
 		return err
 	}
+	// The identity store is ready once its mount is set up
+	atomic.StoreInt32(&c.identityLoaded, 1)
 	if err := c.startRollback(); err != nil {
 		return err
 	}
 	if err := c.setupExpiration(); err != nil {
 		return err
 	}
+	c.startTokenTidy()
+	c.startKeyRotation()
+	if err := c.loadLeaseCountQuotas(); err != nil {
+		return err
+	}
+	if err := c.loadRateLimitQuotas(); err != nil {
+		return err
+	}
+	if err := c.loadEndpointPolicies(); err != nil {
+		return err
+	}
 	if err := c.loadAudits(); err != nil {
 		return err
 	}
@@ -1487,6 +1870,18 @@ func (c *Core) preSeal() error {
 	c.barrierRekeyProgress = nil
 	c.recoveryRekeyConfig = nil
 	c.recoveryRekeyProgress = nil
+	c.barrierRekeyVerifyKey = nil
+	c.barrierRekeyVerifyBackup = nil
+	c.barrierRekeyVerifyProgress = nil
+	c.recoveryRekeyVerifyKey = nil
+	c.recoveryRekeyVerifyBackup = nil
+	c.recoveryRekeyVerifyProgress = nil
+
+	// Stop rewrapping storage, since the barrier is about to be sealed
+	c.cancelRewrap()
+
+	// State index tokens are only issued while active
+	c.stateIndex.clear()
 
 	if c.metricsCh != nil {
 		close(c.metricsCh)
@@ -1499,12 +1894,19 @@ func (c *Core) preSeal() error {
 	if err := c.teardownAudits(); err != nil {
 		result = multierror.Append(result, errwrap.Wrapf("error tearing down audits: {{err}}", err))
 	}
+	c.unloadLeaseCountQuotas()
+	c.unloadRateLimitQuotas()
+	c.unloadEndpointPolicies()
+	c.stopTokenTidy()
+	c.stopKeyRotation()
+	atomic.StoreInt32(&c.identityLoaded, 0)
 	if err := c.stopExpiration(); err != nil {
 		result = multierror.Append(result, errwrap.Wrapf("error stopping expiration: {{err}}", err))
 	}
 	if err := c.teardownCredentials(); err != nil {
 		result = multierror.Append(result, errwrap.Wrapf("error tearing down credentials: {{err}}", err))
 	}
+	c.teardownNamespaces()
 	if err := c.teardownPolicyStore(); err != nil {
 		result = multierror.Append(result, errwrap.Wrapf("error tearing down policy store: {{err}}", err))
 	}
@@ -1514,6 +1916,7 @@ func (c *Core) preSeal() error {
 	if err := c.unloadMounts(); err != nil {
 		result = multierror.Append(result, errwrap.Wrapf("error unloading mounts: {{err}}", err))
 	}
+	c.pluginMonitor.stopAll()
 	if err := enterprisePreSeal(c); err != nil {
 		result = multierror.Append(result, err)
 	}
@@ -1594,6 +1997,20 @@ func (c *Core) runStandby(doneCh, stopCh, manualStepDownCh chan struct{}) {
 		}
 		c.logger.Info("core: acquired lock, enabling active operation")
 
+		// Fence storage writes with the lock where the backend supports it,
+		// so that writes made after losing the lock are rejected rather than
+		// racing with the new active node
+		if err := c.registerActiveNodeLock(lock); err != nil {
+			c.logger.Error("core: failed to fence storage with the HA lock", "error", err)
+			lock.Unlock()
+			select {
+			case <-time.After(lockRetryInterval):
+			case <-stopCh:
+				return
+			}
+			continue
+		}
+
 		// This is used later to log a metrics event; this can be helpful to
 		// detect flapping
 		activeTime := time.Now()
@@ -1623,6 +2040,22 @@ func (c *Core) runStandby(doneCh, stopCh, manualStepDownCh chan struct{}) {
 				metrics.MeasureSince([]string{"core", "leadership_setup_failed"}, activeTime)
 				return
 			}
+
+			// If an emergency seal was triggered while this node was a
+			// standby and it missed the notification, honor it now rather
+			// than becoming active
+			if sealed, err := c.emergencySealedSinceUnseal(); err != nil || sealed {
+				go c.Shutdown()
+				if err != nil {
+					c.logger.Error("core: error checking emergency seal state", "error", err)
+				} else {
+					c.logger.Warn("core: emergency seal was triggered, sealing")
+				}
+				c.stateLock.Unlock()
+				lock.Unlock()
+				metrics.MeasureSince([]string{"core", "leadership_setup_failed"}, activeTime)
+				return
+			}
 		}
 
 		// Clear previous local cluster cert info so we generate new. Since the
@@ -1666,7 +2099,7 @@ func (c *Core) runStandby(doneCh, stopCh, manualStepDownCh chan struct{}) {
 		}
 
 		// Monitor a loss of leadership
-		var manualStepDown bool
+		var manualStepDown, lockDropped bool
 		select {
 		case <-leaderLostCh:
 			c.logger.Warn("core: leadership lost, stopping active operation")
@@ -1675,6 +2108,13 @@ func (c *Core) runStandby(doneCh, stopCh, manualStepDownCh chan struct{}) {
 		case <-manualStepDownCh:
 			c.logger.Warn("core: stepping down from active operation to standby")
 			manualStepDown = true
+		case <-c.chaosDropLockCh:
+			// Release the lock before any teardown, as if this node had
+			// lost its connection to the HA backend
+			c.logger.Warn("core: dropping HA lock by chaos request, stopping active operation")
+			lock.Unlock()
+			lockDropped = true
+			manualStepDown = true
 		}
 
 		metrics.MeasureSince([]string{"core", "leadership_lost"}, activeTime)
@@ -1696,7 +2136,9 @@ func (c *Core) runStandby(doneCh, stopCh, manualStepDownCh chan struct{}) {
 		c.stateLock.Unlock()
 
 		// Give up leadership
-		lock.Unlock()
+		if !lockDropped {
+			lock.Unlock()
+		}
 
 		// Check for a failure to prepare to seal
 		if preSealErr != nil {
@@ -1855,6 +2297,25 @@ func (c *Core) acquireLock(lock physical.Lock, stopCh <-chan struct{}) <-chan st
 	}
 }
 
+// registerActiveNodeLock registers the lock with the HA backend if it
+// supports fencing writes
+func (c *Core) registerActiveNodeLock(lock physical.Lock) error {
+	fencing, ok := c.ha.(physical.FencingHABackend)
+	if !ok {
+		return nil
+	}
+	if err := fencing.RegisterActiveNodeLock(lock); err != nil {
+		return err
+	}
+
+	if fenced, ok := lock.(physical.FencedLock); ok {
+		if token, err := fenced.FencingToken(); err == nil {
+			c.logger.Info("core: fenced storage writes with the HA lock", "fencing_token", token)
+		}
+	}
+	return nil
+}
+
 // advertiseLeader is used to advertise the current node as leader
 func (c *Core) advertiseLeader(uuid string, leaderLostCh <-chan struct{}) error {
 	go c.cleanLeaderPrefix(uuid, leaderLostCh)
@@ -1964,6 +2425,55 @@ func (c *Core) ReplicationState() consts.ReplicationState {
 	return c.replicationState
 }
 
+// SubsystemStatus reports whether the subsystems of an active node have
+// loaded their state after unsealing, and how far a replication secondary
+// lags behind its primary
+type SubsystemStatus struct {
+	// Ready is set if the leases are restored, the identity store is loaded
+	// and the replication lag is within the accepted maximum
+	Ready bool `json:"ready"`
+
+	LeasesRestored bool               `json:"leases_restored"`
+	IdentityLoaded bool               `json:"identity_loaded"`
+	Replication    *ReplicationStatus `json:"replication"`
+}
+
+// ReplicationStatus describes the replication state of the node
+type ReplicationStatus struct {
+	State         string `json:"state"`
+	LastRemoteWAL uint64 `json:"last_remote_wal"`
+
+	// WALLag is the number of WAL entries of the primary a secondary has not
+	// applied yet
+	WALLag uint64 `json:"wal_lag"`
+}
+
+// SubsystemStatus returns the readiness of the subsystems. A secondary
+// lagging more than maxWALLag entries behind its primary is not ready,
+// unless maxWALLag is zero.
+func (c *Core) SubsystemStatus(maxWALLag uint64) *SubsystemStatus {
+	state := c.ReplicationState()
+	leaseRestore := c.LeaseRestoreStatus()
+	status := &SubsystemStatus{
+		LeasesRestored: leaseRestore != nil && leaseRestore.Complete,
+		IdentityLoaded: atomic.LoadInt32(&c.identityLoaded) == 1,
+		Replication: &ReplicationStatus{
+			State: state.String(),
+		},
+	}
+	secondary := state.HasState(consts.ReplicationPerformanceSecondary | consts.ReplicationDRSecondary)
+	if secondary {
+		status.Replication.LastRemoteWAL = LastRemoteWAL(c)
+		status.Replication.WALLag = replicationWALLag(c)
+	}
+
+	status.Ready = status.LeasesRestored && status.IdentityLoaded
+	if secondary && maxWALLag > 0 && status.Replication.WALLag > maxWALLag {
+		status.Ready = false
+	}
+	return status
+}
+
 func (c *Core) SealAccess() *SealAccess {
 	sa := &SealAccess{}
 	sa.SetSeal(c.seal)
@@ -1987,3 +2497,7 @@ func (c *Core) AuditedHeadersConfig() *AuditedHeadersConfig {
 func lastRemoteWALImpl(c *Core) uint64 {
 	return 0
 }
+
+func replicationWALLagImpl(c *Core) uint64 {
+	return 0
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/hashicorp/go-uuid"
 	"github.com/hashicorp/vault/audit"
 	"github.com/hashicorp/vault/helper/consts"
+	"github.com/hashicorp/vault/helper/identity"
 	"github.com/hashicorp/vault/helper/logformat"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/physical"
@@ -707,6 +708,122 @@ func TestCore_HandleLogin_Token(t *testing.T) {
 	}
 }
 
+func TestCore_HandleLogin_AliasMetadata(t *testing.T) {
+	noop := &NoopBackend{
+		Login: []string{"login"},
+	}
+	c, _, root := TestCoreUnsealed(t)
+	c.credentialBackends["noop"] = func(conf *logical.BackendConfig) (logical.Backend, error) {
+		return noop, nil
+	}
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "sys/auth/foo")
+	req.Data["type"] = "noop"
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	login := func(metadata map[string]string) *identity.Alias {
+		noop.Response = &logical.Response{
+			Auth: &logical.Auth{
+				Policies: []string{"foo"},
+				Alias: &logical.Alias{
+					Name:     "armon",
+					Metadata: metadata,
+				},
+			},
+		}
+		lresp, err := c.HandleRequest(&logical.Request{
+			Path: "auth/foo/login",
+		})
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		entity, err := c.identityStore.memDBEntityByID(lresp.Auth.EntityID, false)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if entity == nil || len(entity.Aliases) != 1 {
+			t.Fatalf("bad: %#v", entity)
+		}
+		return entity.Aliases[0]
+	}
+
+	alias := login(map[string]string{"region": "us-east"})
+	if !reflect.DeepEqual(alias.Metadata, map[string]string{"region": "us-east"}) {
+		t.Fatalf("bad: %#v", alias.Metadata)
+	}
+
+	// Later logins update the alias, keeping keys they don't set
+	alias = login(map[string]string{"region": "eu-west", "cost_center": "42"})
+	expected := map[string]string{"region": "eu-west", "cost_center": "42"}
+	if !reflect.DeepEqual(alias.Metadata, expected) {
+		t.Fatalf("bad: %#v", alias.Metadata)
+	}
+	alias = login(nil)
+	if !reflect.DeepEqual(alias.Metadata, expected) {
+		t.Fatalf("bad: %#v", alias.Metadata)
+	}
+}
+
+func TestCore_HandleLogin_BoundCIDRs(t *testing.T) {
+	noop := &NoopBackend{
+		Login: []string{"login"},
+		Response: &logical.Response{
+			Auth: &logical.Auth{
+				Policies:   []string{"foo"},
+				BoundCIDRs: []string{"127.0.0.1/32"},
+			},
+		},
+	}
+	c, _, root := TestCoreUnsealed(t)
+	c.credentialBackends["noop"] = func(conf *logical.BackendConfig) (logical.Backend, error) {
+		return noop, nil
+	}
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "sys/auth/foo")
+	req.Data["type"] = "noop"
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	lresp, err := c.HandleRequest(&logical.Request{
+		Path: "auth/foo/login",
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	lookupSelf := func(remoteAddr string) (*logical.Response, error) {
+		req := logical.TestRequest(t, logical.ReadOperation, "auth/token/lookup-self")
+		req.ClientToken = lresp.Auth.ClientToken
+		if remoteAddr != "" {
+			req.Connection = &logical.Connection{
+				RemoteAddr: remoteAddr,
+			}
+		}
+		return c.HandleRequest(req)
+	}
+
+	resp, err := lookupSelf("127.0.0.1")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(resp.Data["bound_cidrs"], []string{"127.0.0.1/32"}) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// The token can't be used from other addresses
+	for _, remoteAddr := range []string{"10.0.0.1", ""} {
+		if _, err := lookupSelf(remoteAddr); err == nil || !errwrap.Contains(err, logical.ErrPermissionDenied.Error()) {
+			t.Fatalf("expected permission denied from %q; err: %v", remoteAddr, err)
+		}
+	}
+}
+
 func TestCore_HandleRequest_AuditTrail(t *testing.T) {
 	// Create a noop audit backend
 	noop := &NoopAudit{}
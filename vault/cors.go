@@ -3,6 +3,8 @@ package vault
 import (
 	"errors"
 	"fmt"
+	"net/http"
+	"strings"
 	"sync"
 	"sync/atomic"
 
@@ -19,6 +21,7 @@ var StdAllowedHeaders = []string{
 	"Content-Type",
 	"X-Requested-With",
 	"X-Vault-AWS-IAM-Server-ID",
+	"X-Vault-Index",
 	"X-Vault-MFA",
 	"X-Vault-No-Request-Forwarding",
 	"X-Vault-Token",
@@ -58,7 +61,7 @@ func (c *Core) saveCORSConfig() error {
 	return nil
 }
 
-// This should only be called with the core state lock held for writing
+// This should only be called with the core state lock held
 func (c *Core) loadCORSConfig() error {
 	view := c.systemBarrierView.SubView("config/")
 
@@ -76,9 +79,14 @@ func (c *Core) loadCORSConfig() error {
 	if err != nil {
 		return err
 	}
-	newConfig.core = c
 
-	c.corsConfig = newConfig
+	// Update the config in place, as the HTTP handlers hold on to it
+	c.corsConfig.Lock()
+	c.corsConfig.AllowedOrigins = newConfig.AllowedOrigins
+	c.corsConfig.AllowedHeaders = newConfig.AllowedHeaders
+	c.corsConfig.Unlock()
+
+	atomic.StoreUint32(&c.corsConfig.Enabled, newConfig.Enabled)
 
 	return nil
 }
@@ -97,13 +105,17 @@ func (c *CORSConfig) Enable(urls []string, headers []string) error {
 	c.Lock()
 	c.AllowedOrigins = urls
 
-	// Start with the standard headers to Vault accepts.
-	c.AllowedHeaders = append(c.AllowedHeaders, StdAllowedHeaders...)
+	// Start with the standard headers to Vault accepts. The headers given
+	// replace the ones of any previous configuration.
+	c.AllowedHeaders = append([]string(nil), StdAllowedHeaders...)
 
 	// Allow the user to add additional headers to the list of
 	// headers allowed on cross-origin requests.
-	if len(headers) > 0 {
-		c.AllowedHeaders = append(c.AllowedHeaders, headers...)
+	for _, header := range headers {
+		header = http.CanonicalHeaderKey(strings.TrimSpace(header))
+		if header != "" && !strutil.StrListContains(c.AllowedHeaders, header) {
+			c.AllowedHeaders = append(c.AllowedHeaders, header)
+		}
 	}
 	c.Unlock()
 
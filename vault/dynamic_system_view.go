@@ -5,8 +5,10 @@ import (
 	"time"
 
 	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/go-uuid"
 
 	"github.com/hashicorp/vault/helper/consts"
+	"github.com/hashicorp/vault/helper/passwordpolicy"
 	"github.com/hashicorp/vault/helper/pluginutil"
 	"github.com/hashicorp/vault/helper/wrapping"
 	"github.com/hashicorp/vault/logical"
@@ -17,6 +19,31 @@ type dynamicSystemView struct {
 	mountEntry *MountEntry
 }
 
+// internalAuditor is implemented by system views that can record operations
+// a backend performs on its own, outside of any client request, in the audit
+// log
+type internalAuditor interface {
+	auditInternalRequest(req *logical.Request, resp *logical.Response) error
+}
+
+// auditInternalRequest logs the request, relative to the mount, and its
+// response to the audit backends
+func (d dynamicSystemView) auditInternalRequest(req *logical.Request, resp *logical.Response) error {
+	if req.ID == "" {
+		id, err := uuid.GenerateUUID()
+		if err != nil {
+			return err
+		}
+		req.ID = id
+	}
+	req.Path = d.mountEntry.Path + req.Path
+
+	if err := d.core.auditBroker.LogRequest(nil, req, d.core.auditedHeaders, nil); err != nil {
+		return err
+	}
+	return d.core.auditBroker.LogResponse(nil, req, resp, d.core.auditedHeaders, nil)
+}
+
 func (d dynamicSystemView) DefaultLeaseTTL() time.Duration {
 	def, _ := d.fetchTTLs()
 	return def
@@ -41,8 +68,23 @@ func (d dynamicSystemView) SudoPrivilege(path string, token string) bool {
 		return false
 	}
 
+	// The path is relative to the root namespace, and the ACL of the token
+	// applies to the path relative to its namespace
+	ns := d.core.tokenNamespace(te)
+	if ns == nil {
+		return false
+	}
+	path, ok := namespaceRelativePath(ns, path)
+	if !ok {
+		return false
+	}
+	policyStore := d.core.namespacePolicyStore(ns)
+	if policyStore == nil {
+		return false
+	}
+
 	// Construct the corresponding ACL object
-	acl, err := d.core.policyStore.ACL(te.Policies...)
+	acl, err := policyStore.ACL(te.Policies...)
 	if err != nil {
 		d.core.logger.Error("failed to retrieve ACL for token's policies", "token_policies", te.Policies, "error", err)
 		return false
@@ -137,7 +179,68 @@ func (d dynamicSystemView) LookupPlugin(name string) (*pluginutil.PluginRunner,
 	return r, nil
 }
 
+// LookupPluginVersion looks for the given version of a plugin in the plugin
+// catalog, or for the plugin returned by LookupPlugin if the version is empty.
+func (d dynamicSystemView) LookupPluginVersion(name, version string) (*pluginutil.PluginRunner, error) {
+	if version == "" {
+		return d.LookupPlugin(name)
+	}
+	if d.core == nil {
+		return nil, fmt.Errorf("system view core is nil")
+	}
+	if d.core.pluginCatalog == nil {
+		return nil, fmt.Errorf("system view core plugin catalog is nil")
+	}
+	r, err := d.core.pluginCatalog.GetVersion(name, version)
+	if err != nil {
+		return nil, err
+	}
+	if r == nil {
+		return nil, errwrap.Wrapf(fmt.Sprintf("{{err}}: %s version %s", name, version), ErrPluginNotFound)
+	}
+
+	return r, nil
+}
+
+// PasswordPolicy returns the password policy of the given name
+func (d dynamicSystemView) PasswordPolicy(name string) (*passwordpolicy.Policy, error) {
+	return d.core.PasswordPolicy(name)
+}
+
 // MlockEnabled returns the configuration setting for enabling mlock on plugins.
 func (d dynamicSystemView) MlockEnabled() bool {
 	return d.core.enableMlock
 }
+
+// EntityInfo returns the identity entity of the given ID, or nil if it
+// doesn't exist
+func (d dynamicSystemView) EntityInfo(entityID string) (*logical.Entity, error) {
+	if entityID == "" || d.core.identityStore == nil {
+		return nil, nil
+	}
+
+	// The entity is cloned so that backends can't modify the entities of the
+	// identity store through the maps they are given
+	entity, err := d.core.identityStore.memDBEntityByID(entityID, true)
+	if err != nil {
+		return nil, err
+	}
+	if entity == nil {
+		return nil, nil
+	}
+
+	info := &logical.Entity{
+		ID:       entity.ID,
+		Name:     entity.Name,
+		Metadata: entity.Metadata,
+	}
+	for _, alias := range entity.Aliases {
+		info.Aliases = append(info.Aliases, &logical.Alias{
+			MountType:     alias.MountType,
+			MountAccessor: alias.MountAccessor,
+			Name:          alias.Name,
+			Metadata:      alias.Metadata,
+		})
+	}
+	return info, nil
+}
@@ -0,0 +1,255 @@
+package vault
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/helper/consts"
+	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/logical"
+)
+
+const (
+	// coreEmergencySealPath is the path used to persist the fact that an
+	// emergency seal was triggered, so that nodes that missed the cluster
+	// notification refuse to become active.
+	coreEmergencySealPath = "core/emergency-seal"
+
+	// emergencySealConfigPath is the path in the system barrier view's
+	// config area where the authorization settings are stored
+	emergencySealConfigPath = "emergency-seal"
+)
+
+// emergencySealPropagationDelay is how long the active node keeps answering
+// heartbeats after an emergency seal has been authorized, so that every
+// standby has a chance to learn about it before the active node goes away.
+var emergencySealPropagationDelay = heartbeatInterval + 5*time.Second
+
+// EmergencySealConfig holds the set of identity entities that may
+// authorize an emergency seal and how many of them are required.
+type EmergencySealConfig struct {
+	AuthorizedEntities []string `json:"authorized_entities" structs:"authorized_entities" mapstructure:"authorized_entities"`
+	Required           int      `json:"required" structs:"required" mapstructure:"required"`
+}
+
+// EmergencySealStatus reports the progress of an emergency seal attempt
+type EmergencySealStatus struct {
+	Started  bool
+	Nonce    string
+	Progress int
+	Required int
+}
+
+// emergencySealMarker is persisted when an emergency seal is triggered
+type emergencySealMarker struct {
+	Nonce    string    `json:"nonce"`
+	SealedAt time.Time `json:"sealed_at"`
+}
+
+// EmergencySealConfiguration returns the emergency seal configuration, or
+// nil if none has been set
+func (c *Core) EmergencySealConfiguration() (*EmergencySealConfig, error) {
+	view := c.systemBarrierView.SubView("config/")
+	entry, err := view.Get(emergencySealConfigPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read emergency seal config: %v", err)
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var conf EmergencySealConfig
+	if err := entry.DecodeJSON(&conf); err != nil {
+		return nil, fmt.Errorf("failed to decode emergency seal config: %v", err)
+	}
+	return &conf, nil
+}
+
+// SetEmergencySealConfig validates and stores the emergency seal
+// configuration. Any in-progress attempt is cancelled since the set of
+// authorized entities may have changed.
+func (c *Core) SetEmergencySealConfig(conf *EmergencySealConfig) error {
+	conf.AuthorizedEntities = strutil.RemoveDuplicates(conf.AuthorizedEntities, false)
+	switch {
+	case len(conf.AuthorizedEntities) == 0:
+		return fmt.Errorf("at least one authorized entity must be provided")
+	case conf.Required < 1:
+		return fmt.Errorf("required must be at least 1")
+	case conf.Required > len(conf.AuthorizedEntities):
+		return fmt.Errorf("required cannot be greater than the number of authorized entities")
+	}
+
+	entry, err := logical.StorageEntryJSON(emergencySealConfigPath, conf)
+	if err != nil {
+		return fmt.Errorf("failed to create emergency seal config entry: %v", err)
+	}
+
+	view := c.systemBarrierView.SubView("config/")
+	if err := view.Put(entry); err != nil {
+		return fmt.Errorf("failed to save emergency seal config: %v", err)
+	}
+
+	c.EmergencySealCancel()
+	return nil
+}
+
+// EmergencySealProgress returns the status of the current emergency seal
+// attempt
+func (c *Core) EmergencySealProgress() (*EmergencySealStatus, error) {
+	conf, err := c.EmergencySealConfiguration()
+	if err != nil {
+		return nil, err
+	}
+
+	status := &EmergencySealStatus{}
+	if conf != nil {
+		status.Required = conf.Required
+	}
+
+	c.emergencySealLock.Lock()
+	defer c.emergencySealLock.Unlock()
+
+	if c.emergencySealNonce != "" {
+		status.Started = true
+		status.Nonce = c.emergencySealNonce
+		status.Progress = len(c.emergencySealProgress)
+	}
+	return status, nil
+}
+
+// EmergencySealAuthorize records an authorization from the given entity.
+// The first authorization starts a new attempt and generates its nonce;
+// subsequent authorizations must supply that nonce. Once the required
+// number of distinct entities have authorized, the cluster is sealed.
+func (c *Core) EmergencySealAuthorize(entityID, nonce string) (*EmergencySealStatus, error) {
+	if entityID == "" {
+		return nil, fmt.Errorf("emergency seal authorizations must be made by an identity entity")
+	}
+
+	conf, err := c.EmergencySealConfiguration()
+	if err != nil {
+		return nil, err
+	}
+	if conf == nil {
+		return nil, fmt.Errorf("emergency seal is not configured")
+	}
+	if !strutil.StrListContains(conf.AuthorizedEntities, entityID) {
+		return nil, logical.ErrPermissionDenied
+	}
+
+	c.emergencySealLock.Lock()
+	defer c.emergencySealLock.Unlock()
+
+	switch {
+	case c.emergencySealNonce == "":
+		if nonce != "" {
+			return nil, fmt.Errorf("no emergency seal attempt in progress")
+		}
+		c.emergencySealNonce, err = uuid.GenerateUUID()
+		if err != nil {
+			c.emergencySealNonce = ""
+			return nil, err
+		}
+		c.emergencySealProgress = make(map[string]struct{})
+	case nonce != c.emergencySealNonce:
+		return nil, fmt.Errorf("incorrect nonce supplied; nonce for this emergency seal attempt is %s", c.emergencySealNonce)
+	}
+
+	if _, ok := c.emergencySealProgress[entityID]; ok {
+		return nil, fmt.Errorf("entity has already authorized this emergency seal attempt")
+	}
+	c.emergencySealProgress[entityID] = struct{}{}
+
+	status := &EmergencySealStatus{
+		Started:  true,
+		Nonce:    c.emergencySealNonce,
+		Progress: len(c.emergencySealProgress),
+		Required: conf.Required,
+	}
+
+	if status.Progress < conf.Required {
+		if c.logger.IsDebug() {
+			c.logger.Debug("core: emergency seal authorization recorded", "progress", status.Progress, "required", conf.Required)
+		}
+		return status, nil
+	}
+
+	if err := c.triggerEmergencySeal(c.emergencySealNonce); err != nil {
+		return nil, err
+	}
+
+	c.emergencySealNonce = ""
+	c.emergencySealProgress = nil
+
+	return status, nil
+}
+
+// EmergencySealCancel discards any in-progress emergency seal attempt
+func (c *Core) EmergencySealCancel() {
+	c.emergencySealLock.Lock()
+	c.emergencySealNonce = ""
+	c.emergencySealProgress = nil
+	c.emergencySealLock.Unlock()
+}
+
+// triggerEmergencySeal persists the seal marker and schedules this node to
+// seal. Until then, heartbeat replies tell standbys to seal themselves.
+func (c *Core) triggerEmergencySeal(nonce string) error {
+	value, err := json.Marshal(&emergencySealMarker{
+		Nonce:    nonce,
+		SealedAt: time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode emergency seal marker: %v", err)
+	}
+	if err := c.barrier.Put(&Entry{
+		Key:   coreEmergencySealPath,
+		Value: value,
+	}); err != nil {
+		return fmt.Errorf("failed to persist emergency seal marker: %v", err)
+	}
+
+	atomic.StoreUint32(&c.emergencySealPending, 1)
+	c.logger.Warn("core: emergency seal authorized, sealing cluster", "propagation_delay", emergencySealPropagationDelay)
+
+	// This is invoked while the request holds the state lock, so sealing
+	// has to happen asynchronously
+	go func() {
+		time.Sleep(emergencySealPropagationDelay)
+		if err := c.Shutdown(); err != nil {
+			c.logger.Error("core: error performing emergency seal", "error", err)
+		}
+	}()
+
+	return nil
+}
+
+// emergencySealPendingErr returns an error if an emergency seal has been
+// authorized and this node is only waiting to propagate it
+func (c *Core) emergencySealPendingErr() error {
+	if atomic.LoadUint32(&c.emergencySealPending) == 1 {
+		return consts.ErrSealed
+	}
+	return nil
+}
+
+// emergencySealedSinceUnseal checks whether an emergency seal was triggered
+// after this node was last unsealed. The state lock must be held.
+func (c *Core) emergencySealedSinceUnseal() (bool, error) {
+	entry, err := c.barrier.Get(coreEmergencySealPath)
+	if err != nil {
+		return false, err
+	}
+	if entry == nil {
+		return false, nil
+	}
+
+	var marker emergencySealMarker
+	if err := json.Unmarshal(entry.Value, &marker); err != nil {
+		return false, fmt.Errorf("failed to decode emergency seal marker: %v", err)
+	}
+	return marker.SealedAt.After(c.unsealTime), nil
+}
@@ -0,0 +1,248 @@
+package vault
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/helper/consts"
+	"github.com/hashicorp/vault/logical"
+	"golang.org/x/net/context"
+)
+
+func TestEmergencySeal_Config(t *testing.T) {
+	c, _, _ := TestCoreUnsealed(t)
+
+	conf, err := c.EmergencySealConfiguration()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf != nil {
+		t.Fatalf("expected no config, got %#v", conf)
+	}
+
+	bad := []*EmergencySealConfig{
+		&EmergencySealConfig{Required: 1},
+		&EmergencySealConfig{AuthorizedEntities: []string{"a"}},
+		&EmergencySealConfig{AuthorizedEntities: []string{"a", "a"}, Required: 2},
+	}
+	for _, conf := range bad {
+		if err := c.SetEmergencySealConfig(conf); err == nil {
+			t.Fatalf("expected error for %#v", conf)
+		}
+	}
+
+	if err := c.SetEmergencySealConfig(&EmergencySealConfig{
+		AuthorizedEntities: []string{"a", "b", "c"},
+		Required:           2,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	conf, err = c.EmergencySealConfiguration()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf == nil || conf.Required != 2 || len(conf.AuthorizedEntities) != 3 {
+		t.Fatalf("bad: %#v", conf)
+	}
+}
+
+func TestEmergencySeal_Authorize(t *testing.T) {
+	c, _, _ := TestCoreUnsealed(t)
+
+	oldDelay := emergencySealPropagationDelay
+	emergencySealPropagationDelay = 0
+	defer func() {
+		emergencySealPropagationDelay = oldDelay
+	}()
+
+	if _, err := c.EmergencySealAuthorize("a", ""); err == nil {
+		t.Fatal("expected error when not configured")
+	}
+
+	if err := c.SetEmergencySealConfig(&EmergencySealConfig{
+		AuthorizedEntities: []string{"a", "b", "c"},
+		Required:           2,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.EmergencySealAuthorize("", ""); err == nil {
+		t.Fatal("expected error without an entity")
+	}
+	if _, err := c.EmergencySealAuthorize("d", ""); err != logical.ErrPermissionDenied {
+		t.Fatalf("expected permission denied, got %v", err)
+	}
+
+	status, err := c.EmergencySealAuthorize("a", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.Started || status.Nonce == "" || status.Progress != 1 || status.Required != 2 {
+		t.Fatalf("bad: %#v", status)
+	}
+	nonce := status.Nonce
+
+	if _, err := c.EmergencySealAuthorize("a", nonce); err == nil {
+		t.Fatal("expected error on duplicate authorization")
+	}
+	if _, err := c.EmergencySealAuthorize("b", "bogus"); err == nil {
+		t.Fatal("expected error on bad nonce")
+	}
+
+	// Cancelling discards progress
+	c.EmergencySealCancel()
+	status, err = c.EmergencySealProgress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Started || status.Progress != 0 {
+		t.Fatalf("bad: %#v", status)
+	}
+
+	status, err = c.EmergencySealAuthorize("a", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	status, err = c.EmergencySealAuthorize("c", status.Nonce)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Progress != 2 {
+		t.Fatalf("bad: %#v", status)
+	}
+
+	// Requests are rejected while the seal propagates
+	req := logical.TestRequest(t, logical.ReadOperation, "sys/mounts")
+	if _, err := c.HandleRequest(req); err != consts.ErrSealed {
+		t.Fatalf("expected sealed error, got %v", err)
+	}
+
+	for i := 0; i < 50; i++ {
+		if sealed, _ := c.Sealed(); sealed {
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	t.Fatal("expected core to be sealed")
+}
+
+func TestEmergencySeal_Marker(t *testing.T) {
+	c, keys, _ := TestCoreUnsealed(t)
+
+	c.stateLock.RLock()
+	sealed, err := c.emergencySealedSinceUnseal()
+	c.stateLock.RUnlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sealed {
+		t.Fatal("expected no emergency seal")
+	}
+
+	if err := c.triggerEmergencySeal("nonce"); err != nil {
+		t.Fatal(err)
+	}
+
+	c.stateLock.RLock()
+	sealed, err = c.emergencySealedSinceUnseal()
+	c.stateLock.RUnlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sealed {
+		t.Fatal("expected emergency seal to be detected")
+	}
+
+	// Standbys learn about the seal through heartbeat replies
+	srv := &forwardedRequestRPCServer{core: c}
+	reply, err := srv.Echo(context.Background(), &EchoRequest{Message: "ping"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reply.EmergencySeal {
+		t.Fatal("expected echo reply to request an emergency seal")
+	}
+
+	// Once resealed and unsealed again the marker no longer applies
+	if err := c.Shutdown(); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range keys {
+		if _, err := TestCoreUnseal(c, TestKeyCopy(key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c.stateLock.RLock()
+	sealed, err = c.emergencySealedSinceUnseal()
+	c.stateLock.RUnlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sealed {
+		t.Fatal("expected marker to predate unseal")
+	}
+	if err := c.emergencySealPendingErr(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSystemBackend_emergencySeal(t *testing.T) {
+	b := testSystemBackend(t)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "emergency-seal/config")
+	req.Data["authorized_entities"] = "a,b"
+	req.Data["required"] = 2
+	resp, err := b.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %#v", err, resp)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "emergency-seal/config")
+	resp, err = b.HandleRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Data["required"].(int) != 2 || len(resp.Data["authorized_entities"].([]string)) != 2 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "emergency-seal")
+	req.EntityID = "c"
+	if _, err := b.HandleRequest(req); err != logical.ErrPermissionDenied {
+		t.Fatalf("expected permission denied, got %v", err)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "emergency-seal")
+	req.EntityID = "a"
+	resp, err = b.HandleRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Data["progress"].(int) != 1 || resp.Data["complete"].(bool) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "emergency-seal")
+	resp, err = b.HandleRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Data["started"].(bool) || resp.Data["nonce"].(string) == "" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	req = logical.TestRequest(t, logical.DeleteOperation, "emergency-seal")
+	if _, err := b.HandleRequest(req); err != nil {
+		t.Fatal(err)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "emergency-seal")
+	resp, err = b.HandleRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Data["started"].(bool) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+}
@@ -0,0 +1,31 @@
+package vault
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/vault/physical"
+)
+
+// setupEntrySizeLimit wraps the physical backend to reject the entries
+// larger than maxSize bytes, if set
+func (c *Core) setupEntrySizeLimit(maxSize int, backend physical.Backend) physical.Backend {
+	if maxSize <= 0 {
+		return backend
+	}
+
+	if _, txnOK := backend.(physical.Transactional); txnOK {
+		return physical.NewTransactionalEntrySizeLimiter(backend, maxSize, c.describeStorageKey)
+	}
+	return physical.NewEntrySizeLimiter(backend, maxSize, c.describeStorageKey)
+}
+
+// describeStorageKey identifies the mount and path a storage key was written
+// for, if it belongs to a mount
+func (c *Core) describeStorageKey(key string) string {
+	mountPath, prefix, ok := c.router.MatchingStoragePrefix(key)
+	if !ok {
+		return fmt.Sprintf("storage key %q", key)
+	}
+	return fmt.Sprintf("path %q of mount %q", strings.TrimPrefix(key, prefix), mountPath)
+}
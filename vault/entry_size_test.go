@@ -0,0 +1,57 @@
+package vault
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/helper/logformat"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/physical/inmem"
+	log "github.com/mgutz/logxi/v1"
+)
+
+func TestCore_MaxEntrySize(t *testing.T) {
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+	inm, err := inmem.NewInmem(nil, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf := testCoreConfig(t, inm, logger)
+	conf.MaxEntrySize = 4096
+	c, err := NewCore(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, _, root := testCoreUnsealed(t, c)
+
+	write := func(value string) error {
+		req := logical.TestRequest(t, logical.UpdateOperation, "secret/foo/bar")
+		req.Data["value"] = value
+		req.ClientToken = root
+		_, err := c.HandleRequest(req)
+		return err
+	}
+	if err := write("baz"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The error identifies the mount and path of the entry
+	err = write(strings.Repeat("a", 8192))
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if !strings.Contains(err.Error(), `for path "foo/bar" of mount "secret/" exceeds the maximum entry size of 4096 bytes`) {
+		t.Fatalf("bad error: %v", err)
+	}
+
+	// The entry is left untouched
+	req := logical.TestRequest(t, logical.ReadOperation, "secret/foo/bar")
+	req.ClientToken = root
+	resp, err := c.HandleRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp == nil || resp.Data["value"] != "baz" {
+		t.Fatalf("bad: %#v", resp)
+	}
+}
@@ -5,12 +5,14 @@ import (
 	"errors"
 	"fmt"
 	"path"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/armon/go-metrics"
+	"github.com/armon/go-radix"
 	log "github.com/mgutz/logxi/v1"
 
 	"github.com/hashicorp/errwrap"
@@ -33,6 +35,10 @@ const (
 	// tokenViewPrefix is the prefix used for the token based lookup of leases.
 	tokenViewPrefix = "token/"
 
+	// artifactViewPrefix is the prefix used for the artifact based lookup of
+	// leases.
+	artifactViewPrefix = "artifact/"
+
 	// maxRevokeAttempts limits how many revoke attempts are made
 	maxRevokeAttempts = 6
 
@@ -44,6 +50,18 @@ const (
 
 	// defaultLeaseDuration is the default lease duration used when no lease is specified
 	defaultLeaseTTL = maxLeaseTTL
+
+	// leaseRenewEndpoint is the path advertised to consumers for renewing
+	// leases
+	leaseRenewEndpoint = "sys/leases/renew"
+
+	// leaseContractRotationFraction is the fraction of a lease's duration
+	// after which consumers are advised to renew or rotate the secret
+	leaseContractRotationFraction = 2.0 / 3.0
+
+	// expirationPauseRecheckInterval is how often a lease that expired while
+	// expiration was paused is checked again
+	expirationPauseRecheckInterval = time.Second
 )
 
 // ExpirationManager is used by the Core to manage leases. Secrets
@@ -51,15 +69,31 @@ const (
 // If a secret is not renewed in timely manner, it may be expired, and
 // the ExpirationManager will handle doing automatic revocation.
 type ExpirationManager struct {
-	router     *Router
-	idView     *BarrierView
-	tokenView  *BarrierView
-	tokenStore *TokenStore
-	logger     log.Logger
+	router       *Router
+	idView       *BarrierView
+	tokenView    *BarrierView
+	artifactView *BarrierView
+	tokenStore   *TokenStore
+	logger       log.Logger
 
 	pending     map[string]*time.Timer
 	pendingLock sync.RWMutex
 
+	// leaseEntities holds the IDs of the leases loaded in memory, along with
+	// the ID of the entity they were issued to; used to enforce the lease
+	// count quotas
+	leaseEntities     *radix.Tree
+	leaseEntitiesLock sync.RWMutex
+
+	// countLeaseFunc is called with a delta of 1 or -1 as leases are tracked
+	// and untracked, to update the counters of the lease count quotas. It is
+	// called with leaseEntitiesLock held.
+	countLeaseFunc func(leaseID, entityID string, delta int)
+
+	// irrevocable holds the leases which could not be revoked after the
+	// maximum number of attempts, by lease ID
+	irrevocable sync.Map
+
 	tidyLock int32
 
 	restoreMode        int32
@@ -68,6 +102,19 @@ type ExpirationManager struct {
 	restoreLocks       []*locksutil.LockEntry
 	restoreLoaded      sync.Map
 	quitCh             chan struct{}
+
+	// restoreWorkers is the number of workers loading the leases at restore
+	// time; zero uses the default
+	restoreWorkers int
+
+	// restoreTotal and restoreProcessed track the progress of the restore;
+	// restoreTotal is only known once the leases are collected
+	restoreTotal     int64
+	restoreProcessed int64
+
+	// pausedUntil holds the time, in Unix nanoseconds, until which leases
+	// are not revoked when they expire; used by the chaos endpoints
+	pausedUntil int64
 }
 
 // NewExpirationManager creates a new ExpirationManager that is backed
@@ -78,12 +125,15 @@ func NewExpirationManager(router *Router, view *BarrierView, ts *TokenStore, log
 	}
 
 	exp := &ExpirationManager{
-		router:     router,
-		idView:     view.SubView(leaseViewPrefix),
-		tokenView:  view.SubView(tokenViewPrefix),
-		tokenStore: ts,
-		logger:     logger,
-		pending:    make(map[string]*time.Timer),
+		router:       router,
+		idView:       view.SubView(leaseViewPrefix),
+		tokenView:    view.SubView(tokenViewPrefix),
+		artifactView: view.SubView(artifactViewPrefix),
+		tokenStore:   ts,
+		logger:       logger,
+		pending:      make(map[string]*time.Timer),
+
+		leaseEntities: radix.New(),
 
 		// new instances of the expiration manager will go immediately into
 		// restore mode
@@ -104,6 +154,8 @@ func (c *Core) setupExpiration() error {
 
 	// Create the manager
 	mgr := NewExpirationManager(c.router, view, c.tokenStore, c.logger)
+	mgr.restoreWorkers = c.leaseRestoreWorkers
+	mgr.countLeaseFunc = c.countLease
 	c.expiration = mgr
 
 	// Link the token store to this
@@ -136,6 +188,39 @@ func (c *Core) stopExpiration() error {
 	return nil
 }
 
+// LeaseRestoreStatus describes the progress of the restore of the leases by
+// the expiration manager
+type LeaseRestoreStatus struct {
+	// Complete is set once all the leases are loaded; until then, leases are
+	// loaded on demand as they are used
+	Complete bool `json:"complete"`
+
+	// Loaded is the number of leases loaded so far, out of Total. Total is
+	// zero until the leases have been listed.
+	Loaded int64 `json:"loaded"`
+	Total  int64 `json:"total"`
+}
+
+// LeaseRestoreStatus returns the progress of the restore of the leases, or
+// nil if the expiration manager is not running
+func (c *Core) LeaseRestoreStatus() *LeaseRestoreStatus {
+	c.metricsMutex.Lock()
+	defer c.metricsMutex.Unlock()
+
+	if c.expiration == nil {
+		return nil
+	}
+	return c.expiration.restoreStatus()
+}
+
+func (m *ExpirationManager) restoreStatus() *LeaseRestoreStatus {
+	return &LeaseRestoreStatus{
+		Complete: !m.inRestoreMode(),
+		Loaded:   atomic.LoadInt64(&m.restoreProcessed),
+		Total:    atomic.LoadInt64(&m.restoreTotal),
+	}
+}
+
 // lockLease takes out a lock for a given lease ID
 func (m *ExpirationManager) lockLease(leaseID string) {
 	locksutil.LockForKey(m.restoreLocks, leaseID).Lock()
@@ -297,6 +382,7 @@ func (m *ExpirationManager) Restore(errorFunc func()) (retErr error) {
 		return errwrap.Wrapf("failed to scan for leases: {{err}}", err)
 	}
 	m.logger.Debug("expiration: leases collected", "num_existing", len(existing))
+	atomic.StoreInt64(&m.restoreTotal, int64(len(existing)))
 
 	// Make the channels used for the worker pool
 	broker := make(chan string)
@@ -308,8 +394,13 @@ func (m *ExpirationManager) Restore(errorFunc func()) (retErr error) {
 	// Use a wait group
 	wg := &sync.WaitGroup{}
 
-	// Create 64 workers to distribute work to
-	for i := 0; i < consts.ExpirationRestoreWorkerCount; i++ {
+	// Create the workers to distribute work to
+	workers := m.restoreWorkers
+	if workers <= 0 {
+		workers = consts.ExpirationRestoreWorkerCount
+	}
+	m.logger.Debug("expiration: starting restore workers", "count", workers)
+	for i := 0; i < workers; i++ {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
@@ -329,6 +420,7 @@ func (m *ExpirationManager) Restore(errorFunc func()) (retErr error) {
 					}
 
 					// Send message that lease is done
+					atomic.AddInt64(&m.restoreProcessed, 1)
 					result <- struct{}{}
 
 				// quit early
@@ -348,7 +440,7 @@ func (m *ExpirationManager) Restore(errorFunc func()) (retErr error) {
 		defer wg.Done()
 		for i, leaseID := range existing {
 			if i > 0 && i%500 == 0 {
-				m.logger.Trace("expiration: leases loading", "progress", i)
+				m.logger.Trace("expiration: leases loading", "progress", i, "total", len(existing))
 			}
 
 			select {
@@ -486,25 +578,37 @@ func (m *ExpirationManager) revokeCommon(leaseID string, force, skipToken bool)
 		}
 	}
 
+	return m.removeEntry(le)
+}
+
+// removeEntry deletes a lease entry along with its indexes and expiration
+// handler, once it was revoked
+func (m *ExpirationManager) removeEntry(le *leaseEntry) error {
 	// Delete the entry
-	if err := m.deleteEntry(leaseID); err != nil {
+	if err := m.deleteEntry(le.LeaseID); err != nil {
 		return err
 	}
 
-	// Delete the secondary index, but only if it's a leased secret (not auth)
+	// Delete the secondary indexes, but only if it's a leased secret (not auth)
 	if le.Secret != nil {
 		if err := m.removeIndexByToken(le.ClientToken, le.LeaseID); err != nil {
 			return err
 		}
+		if err := m.removeIndexByArtifacts(le.Secret.Artifacts, le.LeaseID); err != nil {
+			return err
+		}
 	}
 
 	// Clear the expiration handler
 	m.pendingLock.Lock()
-	if timer, ok := m.pending[leaseID]; ok {
+	if timer, ok := m.pending[le.LeaseID]; ok {
 		timer.Stop()
-		delete(m.pending, leaseID)
+		delete(m.pending, le.LeaseID)
 	}
 	m.pendingLock.Unlock()
+
+	m.irrevocable.Delete(le.LeaseID)
+	m.untrackLease(le.LeaseID)
 	return nil
 }
 
@@ -637,6 +741,12 @@ func (m *ExpirationManager) Renew(leaseID string, increment time.Duration) (*log
 	// Attach the LeaseID
 	resp.Secret.LeaseID = leaseID
 
+	// Keep the artifacts indexed for the lease if the backend did not
+	// return them again
+	if resp.Secret.Artifacts == nil {
+		resp.Secret.Artifacts = le.Secret.Artifacts
+	}
+
 	// Update the lease entry
 	le.Data = resp.Data
 	le.Secret = resp.Secret
@@ -806,6 +916,10 @@ func (m *ExpirationManager) Register(req *logical.Request, resp *logical.Respons
 			if err := m.removeIndexByToken(req.ClientToken, leaseID); err != nil {
 				retErr = multierror.Append(retErr, errwrap.Wrapf("an additional error was encountered removing lease indexes associated with the newly-generated secret: {{err}}", err))
 			}
+
+			if err := m.removeIndexByArtifacts(resp.Secret.Artifacts, leaseID); err != nil {
+				retErr = multierror.Append(retErr, errwrap.Wrapf("an additional error was encountered removing lease indexes associated with the newly-generated secret: {{err}}", err))
+			}
 		}
 	}()
 
@@ -815,6 +929,7 @@ func (m *ExpirationManager) Register(req *logical.Request, resp *logical.Respons
 		Path:        req.Path,
 		Data:        resp.Data,
 		Secret:      resp.Secret,
+		EntityID:    req.EntityID,
 		IssueTime:   time.Now(),
 		ExpireTime:  resp.Secret.ExpirationTime(),
 	}
@@ -829,8 +944,14 @@ func (m *ExpirationManager) Register(req *logical.Request, resp *logical.Respons
 		return "", err
 	}
 
+	// Maintain secondary index by artifact
+	if err := m.createIndexByArtifacts(le.Secret.Artifacts, le.LeaseID); err != nil {
+		return "", err
+	}
+
 	// Setup revocation timer if there is a lease
 	m.updatePending(&le, resp.Secret.LeaseTotal())
+	m.trackLease(&le)
 
 	// Done
 	return le.LeaseID, nil
@@ -872,9 +993,55 @@ func (m *ExpirationManager) RegisterAuth(source string, auth *logical.Auth) erro
 
 	// Setup revocation timer
 	m.updatePending(&le, auth.LeaseTotal())
+	m.trackLease(&le)
 	return nil
 }
 
+// leaseContract returns the rotation hints of a registered lease, issued or
+// renewed at the given time. The lease is revoked along with the token it
+// belongs to, so its validity isn't guaranteed past the expiry of the token.
+func (m *ExpirationManager) leaseContract(secret *logical.Secret, issued time.Time) (*logical.LeaseContract, error) {
+	contract := logical.NewLeaseContract(issued, &secret.LeaseOptions, leaseRenewEndpoint, leaseContractRotationFraction)
+	if contract == nil {
+		return nil, nil
+	}
+
+	le, err := m.loadEntry(secret.LeaseID)
+	if err != nil {
+		return nil, err
+	}
+	if le == nil || le.ClientToken == "" {
+		return contract, nil
+	}
+	te, err := m.tokenStore.Lookup(le.ClientToken)
+	if err != nil {
+		return nil, err
+	}
+	if te == nil {
+		// The token is being revoked, and the lease with it
+		return nil, nil
+	}
+
+	// Tokens without a TTL, such as root tokens, don't expire. Renewals move
+	// the expiry of the others, which is that of their own lease, except for
+	// batch tokens which can't be renewed.
+	if te.TTL == 0 {
+		return contract, nil
+	}
+	expireTime := time.Unix(te.CreationTime, 0).Add(te.TTL)
+	if te.tokenType() != tokenTypeBatch {
+		tokenLease, err := m.FetchLeaseTimesByToken(te.Path, te.ID)
+		if err != nil {
+			return nil, err
+		}
+		if tokenLease != nil {
+			expireTime = tokenLease.ExpireTime
+		}
+	}
+	contract.Cap(issued, expireTime, leaseContractRotationFraction)
+	return contract, nil
+}
+
 // FetchLeaseTimesByToken is a helper function to use token values to compute
 // the leaseID, rather than pushing that logic back into the token store.
 func (m *ExpirationManager) FetchLeaseTimesByToken(source, token string) (*leaseEntry, error) {
@@ -908,6 +1075,7 @@ func (m *ExpirationManager) FetchLeaseTimes(leaseID string) (*leaseEntry, error)
 		IssueTime:       le.IssueTime,
 		ExpireTime:      le.ExpireTime,
 		LastRenewalTime: le.LastRenewalTime,
+		RevokeErr:       le.RevokeErr,
 	}
 	if le.Secret != nil {
 		ret.Secret = &logical.Secret{}
@@ -955,13 +1123,50 @@ func (m *ExpirationManager) updatePending(le *leaseEntry, leaseTotal time.Durati
 	timer.Reset(leaseTotal)
 }
 
+// PauseExpiration stops expired leases from being revoked for the given
+// duration. Leases that expire in the meantime are revoked shortly after the
+// pause ends. A zero duration resumes expiration immediately.
+func (m *ExpirationManager) PauseExpiration(d time.Duration) {
+	if d <= 0 {
+		atomic.StoreInt64(&m.pausedUntil, 0)
+		return
+	}
+	atomic.StoreInt64(&m.pausedUntil, time.Now().Add(d).UnixNano())
+}
+
+// PausedUntil returns the time until which expiration is paused, or the
+// zero time if it is not paused
+func (m *ExpirationManager) PausedUntil() time.Time {
+	until := atomic.LoadInt64(&m.pausedUntil)
+	if until == 0 || time.Now().UnixNano() >= until {
+		return time.Time{}
+	}
+	return time.Unix(0, until)
+}
+
 // expireID is invoked when a given ID is expired
 func (m *ExpirationManager) expireID(leaseID string) {
+	// If expiration is paused, check back later rather than revoking
+	if !m.PausedUntil().IsZero() {
+		select {
+		case <-m.quitCh:
+			return
+		default:
+		}
+		m.pendingLock.Lock()
+		m.pending[leaseID] = time.AfterFunc(expirationPauseRecheckInterval, func() {
+			m.expireID(leaseID)
+		})
+		m.pendingLock.Unlock()
+		return
+	}
+
 	// Clear from the pending expiration
 	m.pendingLock.Lock()
 	delete(m.pending, leaseID)
 	m.pendingLock.Unlock()
 
+	var lastErr error
 	for attempt := uint(0); attempt < maxRevokeAttempts; attempt++ {
 		select {
 		case <-m.quitCh:
@@ -977,9 +1182,13 @@ func (m *ExpirationManager) expireID(leaseID string) {
 			return
 		}
 		m.logger.Error("expiration: failed to revoke lease", "lease_id", leaseID, "error", err)
+		lastErr = err
 		time.Sleep((1 << attempt) * revokeRetryBase)
 	}
-	m.logger.Error("expiration: maximum revoke attempts reached", "lease_id", leaseID)
+	m.logger.Error("expiration: maximum revoke attempts reached, marking lease irrevocable", "lease_id", leaseID)
+	if err := m.markIrrevocable(leaseID, lastErr); err != nil {
+		m.logger.Error("expiration: failed to mark lease irrevocable", "lease_id", leaseID, "error", err)
+	}
 }
 
 // revokeEntry is used to attempt revocation of an internal entry
@@ -1085,8 +1294,14 @@ func (m *ExpirationManager) loadEntryInternal(leaseID string, restoreMode bool,
 		// the lazy loaded restore process
 		m.restoreLoaded.Store(le.LeaseID, struct{}{})
 
-		// Setup revocation timer
-		m.updatePending(le, le.ExpireTime.Sub(time.Now()))
+		// Irrevocable leases are not retried until an operator reviews them
+		if le.RevokeErr != "" {
+			m.irrevocable.Store(le.LeaseID, le.irrevocableInfo())
+		} else {
+			// Setup revocation timer
+			m.updatePending(le, le.ExpireTime.Sub(time.Now()))
+		}
+		m.trackLease(le)
 	}
 	return le, nil
 }
@@ -1208,6 +1423,89 @@ func (m *ExpirationManager) lookupByToken(token string) ([]string, error) {
 	return leaseIDs, nil
 }
 
+// artifactIndexKey returns the key of the secondary index from an artifact
+// to a lease entry. The artifact value is salted like tokens are, since it
+// may hold characters that are not valid in keys.
+func (m *ExpirationManager) artifactIndexKey(artifactType, artifactID, leaseID string) (string, error) {
+	saltedID, err := m.tokenStore.SaltID(artifactID)
+	if err != nil {
+		return "", err
+	}
+
+	if leaseID == "" {
+		return artifactType + "/" + saltedID + "/", nil
+	}
+
+	leaseSaltedID, err := m.tokenStore.SaltID(leaseID)
+	if err != nil {
+		return "", err
+	}
+	return artifactType + "/" + saltedID + "/" + leaseSaltedID, nil
+}
+
+// createIndexByArtifacts creates a secondary index from each artifact to a
+// lease entry
+func (m *ExpirationManager) createIndexByArtifacts(artifacts map[string]string, leaseID string) error {
+	for artifactType, artifactID := range artifacts {
+		key, err := m.artifactIndexKey(artifactType, artifactID, leaseID)
+		if err != nil {
+			return err
+		}
+
+		ent := logical.StorageEntry{
+			Key:   key,
+			Value: []byte(leaseID),
+		}
+		if err := m.artifactView.Put(&ent); err != nil {
+			return fmt.Errorf("failed to persist lease artifact index entry: %v", err)
+		}
+	}
+	return nil
+}
+
+// removeIndexByArtifacts removes the secondary index from each artifact to a
+// lease entry
+func (m *ExpirationManager) removeIndexByArtifacts(artifacts map[string]string, leaseID string) error {
+	for artifactType, artifactID := range artifacts {
+		key, err := m.artifactIndexKey(artifactType, artifactID, leaseID)
+		if err != nil {
+			return err
+		}
+
+		if err := m.artifactView.Delete(key); err != nil {
+			return fmt.Errorf("failed to delete lease artifact index entry: %v", err)
+		}
+	}
+	return nil
+}
+
+// LookupByArtifact returns the IDs of the leases the artifact was issued with
+func (m *ExpirationManager) LookupByArtifact(artifactType, artifactID string) ([]string, error) {
+	prefix, err := m.artifactIndexKey(artifactType, artifactID, "")
+	if err != nil {
+		return nil, err
+	}
+
+	subKeys, err := m.artifactView.List(prefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list leases: %v", err)
+	}
+
+	leaseIDs := make([]string, 0, len(subKeys))
+	for _, sub := range subKeys {
+		out, err := m.artifactView.Get(prefix + sub)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read lease artifact index: %v", err)
+		}
+		if out == nil {
+			continue
+		}
+		leaseIDs = append(leaseIDs, string(out.Value))
+	}
+	sort.Strings(leaseIDs)
+	return leaseIDs, nil
+}
+
 // emitMetrics is invoked periodically to emit statistics
 func (m *ExpirationManager) emitMetrics() {
 	m.pendingLock.RLock()
@@ -1225,9 +1523,14 @@ type leaseEntry struct {
 	Data            map[string]interface{} `json:"data"`
 	Secret          *logical.Secret        `json:"secret"`
 	Auth            *logical.Auth          `json:"auth"`
+	EntityID        string                 `json:"entity_id,omitempty"`
 	IssueTime       time.Time              `json:"issue_time"`
 	ExpireTime      time.Time              `json:"expire_time"`
 	LastRenewalTime time.Time              `json:"last_renewal_time"`
+
+	// RevokeErr is the last error encountered when revoking the lease, set
+	// once the lease is marked irrevocable
+	RevokeErr string `json:"revoke_err,omitempty"`
 }
 
 // encode is used to JSON encode the lease entry
@@ -1249,6 +1552,8 @@ func (le *leaseEntry) renewable() (bool, error) {
 		err = fmt.Errorf("lease is not renewable")
 	case le.Auth != nil && !le.Auth.Renewable:
 		err = fmt.Errorf("lease is not renewable")
+	case le.RevokeErr != "":
+		err = fmt.Errorf("lease is irrevocable")
 	}
 
 	if err != nil {
@@ -0,0 +1,94 @@
+package vault
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/armon/go-metrics"
+)
+
+// IrrevocableLease describes a lease which could not be revoked after the
+// maximum number of attempts
+type IrrevocableLease struct {
+	LeaseID    string    `json:"lease_id" structs:"lease_id" mapstructure:"lease_id"`
+	Path       string    `json:"path" structs:"path" mapstructure:"path"`
+	IssueTime  time.Time `json:"issue_time" structs:"issue_time" mapstructure:"issue_time"`
+	ExpireTime time.Time `json:"expire_time" structs:"expire_time" mapstructure:"expire_time"`
+	RevokeErr  string    `json:"revoke_error" structs:"revoke_error" mapstructure:"revoke_error"`
+}
+
+// irrevocableInfo returns the description of an irrevocable lease
+func (le *leaseEntry) irrevocableInfo() *IrrevocableLease {
+	return &IrrevocableLease{
+		LeaseID:    le.LeaseID,
+		Path:       le.Path,
+		IssueTime:  le.IssueTime,
+		ExpireTime: le.ExpireTime,
+		RevokeErr:  le.RevokeErr,
+	}
+}
+
+// markIrrevocable records the revocation error of a lease which could not be
+// revoked, so that it is no longer retried on restore and is listed for an
+// operator to review
+func (m *ExpirationManager) markIrrevocable(leaseID string, revokeErr error) error {
+	m.lockLease(leaseID)
+	defer m.unlockLease(leaseID)
+
+	le, err := m.loadEntryInternal(leaseID, false, false)
+	if err != nil {
+		return err
+	}
+	if le == nil {
+		return nil
+	}
+
+	le.RevokeErr = "unknown error"
+	if revokeErr != nil {
+		le.RevokeErr = revokeErr.Error()
+	}
+	if err := m.persistEntry(le); err != nil {
+		return err
+	}
+
+	metrics.IncrCounter([]string{"expire", "irrevocable"}, 1)
+	m.irrevocable.Store(leaseID, le.irrevocableInfo())
+	return nil
+}
+
+// IrrevocableLeases returns the leases which could not be revoked, sorted by
+// lease ID
+func (m *ExpirationManager) IrrevocableLeases() []*IrrevocableLease {
+	var leases []*IrrevocableLease
+	m.irrevocable.Range(func(k, v interface{}) bool {
+		leases = append(leases, v.(*IrrevocableLease))
+		return true
+	})
+	sort.Slice(leases, func(i, j int) bool {
+		return leases[i].LeaseID < leases[j].LeaseID
+	})
+	return leases
+}
+
+// RemoveIrrevocable deletes an irrevocable lease without revoking it from
+// its backend, once an operator has cleaned up what it refers to
+func (m *ExpirationManager) RemoveIrrevocable(leaseID string) error {
+	defer metrics.MeasureSince([]string{"expire", "remove-irrevocable"}, time.Now())
+
+	le, err := m.loadEntry(leaseID)
+	if err != nil {
+		return err
+	}
+	if le == nil || le.RevokeErr == "" {
+		return fmt.Errorf("lease %q is not irrevocable", leaseID)
+	}
+
+	if err := m.removeEntry(le); err != nil {
+		return err
+	}
+	if m.logger.IsInfo() {
+		m.logger.Info("expiration: removed irrevocable lease", "lease_id", leaseID)
+	}
+	return nil
+}
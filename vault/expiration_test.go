@@ -566,6 +566,91 @@ func TestExpiration_Revoke(t *testing.T) {
 	}
 }
 
+func TestExpiration_LookupByArtifact(t *testing.T) {
+	exp := mockExpiration(t)
+	noop := &NoopBackend{}
+	_, barrier, _ := mockBarrier(t)
+	view := NewBarrierView(barrier, "logical/")
+	meUUID, err := uuid.GenerateUUID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = exp.router.Mount(noop, "prod/pki/", &MountEntry{Path: "prod/pki/", Type: "noop", UUID: meUUID, Accessor: "noop-accessor"}, view)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	register := func(serial string) string {
+		id, err := exp.Register(&logical.Request{
+			Operation:   logical.UpdateOperation,
+			Path:        "prod/pki/issue/web",
+			ClientToken: "foobar",
+		}, &logical.Response{
+			Secret: &logical.Secret{
+				LeaseOptions: logical.LeaseOptions{
+					TTL: time.Hour,
+				},
+				Artifacts: map[string]string{
+					"serial_number": serial,
+				},
+			},
+		})
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		return id
+	}
+
+	first := register("1a:2b:3c")
+	second := register("4d:5e:6f")
+
+	leaseIDs, err := exp.LookupByArtifact("serial_number", "1a:2b:3c")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(leaseIDs, []string{first}) {
+		t.Fatalf("bad: %v", leaseIDs)
+	}
+
+	leaseIDs, err = exp.LookupByArtifact("username", "1a:2b:3c")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(leaseIDs) != 0 {
+		t.Fatalf("bad: %v", leaseIDs)
+	}
+
+	if err := exp.Revoke(second); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	leaseIDs, err = exp.LookupByArtifact("serial_number", "4d:5e:6f")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(leaseIDs) != 0 {
+		t.Fatalf("expected the index to be removed on revocation, got %v", leaseIDs)
+	}
+
+	// Artifact types can't contain a slash
+	_, err = exp.Register(&logical.Request{
+		Operation:   logical.UpdateOperation,
+		Path:        "prod/pki/issue/web",
+		ClientToken: "foobar",
+	}, &logical.Response{
+		Secret: &logical.Secret{
+			LeaseOptions: logical.LeaseOptions{
+				TTL: time.Hour,
+			},
+			Artifacts: map[string]string{
+				"serial/number": "1a",
+			},
+		},
+	})
+	if err == nil {
+		t.Fatalf("expected an error for an invalid artifact type")
+	}
+}
+
 func TestExpiration_RevokeOnExpire(t *testing.T) {
 	exp := mockExpiration(t)
 	noop := &NoopBackend{}
@@ -1478,3 +1563,150 @@ func badRenewFactory(conf *logical.BackendConfig) (logical.Backend, error) {
 
 	return be, nil
 }
+
+func TestExpiration_RestoreStatus(t *testing.T) {
+	c, ts, _, _ := TestCoreWithTokenStore(t)
+	exp := ts.expiration
+	noop := &NoopBackend{}
+	_, barrier, _ := mockBarrier(t)
+	view := NewBarrierView(barrier, "logical/")
+	meUUID, err := uuid.GenerateUUID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = exp.router.Mount(noop, "prod/aws/", &MountEntry{Path: "prod/aws/", Type: "noop", UUID: meUUID, Accessor: "noop-accessor"}, view)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 10; i++ {
+		req := &logical.Request{
+			Operation:   logical.ReadOperation,
+			Path:        fmt.Sprintf("prod/aws/%d", i),
+			ClientToken: "foobar",
+		}
+		resp := &logical.Response{
+			Secret: &logical.Secret{
+				LeaseOptions: logical.LeaseOptions{
+					TTL: time.Hour,
+				},
+			},
+		}
+		if _, err := exp.Register(req, resp); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	if err := exp.Stop(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Restore with a new manager, as done at unseal time
+	exp = NewExpirationManager(c.router, c.systemBarrierView.SubView(expirationSubPath), ts, c.logger)
+	exp.restoreWorkers = 2
+	if status := exp.restoreStatus(); status.Complete || status.Total != 0 {
+		t.Fatalf("bad: %#v", status)
+	}
+	if err := exp.Restore(nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer exp.Stop()
+
+	expected := &LeaseRestoreStatus{
+		Complete: true,
+		Loaded:   10,
+		Total:    10,
+	}
+	if status := exp.restoreStatus(); !reflect.DeepEqual(status, expected) {
+		t.Fatalf("bad: expected: %#v, got: %#v", expected, status)
+	}
+}
+
+func TestExpiration_Irrevocable(t *testing.T) {
+	c, ts, _, _ := TestCoreWithTokenStore(t)
+	exp := ts.expiration
+	noop := &NoopBackend{}
+	_, barrier, _ := mockBarrier(t)
+	view := NewBarrierView(barrier, "logical/")
+	meUUID, err := uuid.GenerateUUID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = exp.router.Mount(noop, "prod/aws/", &MountEntry{Path: "prod/aws/", Type: "noop", UUID: meUUID, Accessor: "noop-accessor"}, view)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var leaseIDs []string
+	for i := 0; i < 2; i++ {
+		req := &logical.Request{
+			Operation:   logical.ReadOperation,
+			Path:        fmt.Sprintf("prod/aws/%d", i),
+			ClientToken: "foobar",
+		}
+		resp := &logical.Response{
+			Secret: &logical.Secret{
+				LeaseOptions: logical.LeaseOptions{
+					TTL:       time.Hour,
+					Renewable: true,
+				},
+			},
+		}
+		leaseID, err := exp.Register(req, resp)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		leaseIDs = append(leaseIDs, leaseID)
+	}
+
+	if err := exp.markIrrevocable(leaseIDs[0], fmt.Errorf("connection refused")); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	leases := exp.IrrevocableLeases()
+	if len(leases) != 1 || leases[0].LeaseID != leaseIDs[0] || leases[0].Path != "prod/aws/0" || leases[0].RevokeErr != "connection refused" {
+		t.Fatalf("bad: %#v", leases)
+	}
+	if _, err := exp.Renew(leaseIDs[0], 0); err == nil {
+		t.Fatalf("expected irrevocable leases not to be renewable")
+	}
+
+	if err := exp.Stop(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Irrevocable leases are not retried after a restore
+	exp = NewExpirationManager(c.router, c.systemBarrierView.SubView(expirationSubPath), ts, c.logger)
+	if err := exp.Restore(nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer exp.Stop()
+
+	exp.pendingLock.RLock()
+	_, pending0 := exp.pending[leaseIDs[0]]
+	_, pending1 := exp.pending[leaseIDs[1]]
+	exp.pendingLock.RUnlock()
+	if pending0 || !pending1 {
+		t.Fatalf("bad: pending: %v, %v", pending0, pending1)
+	}
+	if leases := exp.IrrevocableLeases(); len(leases) != 1 || leases[0].LeaseID != leaseIDs[0] {
+		t.Fatalf("bad: %#v", leases)
+	}
+
+	// Only irrevocable leases can be removed, and they are removed without
+	// contacting their backend
+	if err := exp.RemoveIrrevocable(leaseIDs[1]); err == nil {
+		t.Fatalf("expected an error")
+	}
+	if err := exp.RemoveIrrevocable(leaseIDs[0]); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(noop.Requests) != 0 {
+		t.Fatalf("bad: %#v", noop.Requests)
+	}
+	if le, err := exp.loadEntry(leaseIDs[0]); err != nil || le != nil {
+		t.Fatalf("bad: %#v, err: %v", le, err)
+	}
+	if leases := exp.IrrevocableLeases(); len(leases) != 0 {
+		t.Fatalf("bad: %#v", leases)
+	}
+}
@@ -42,6 +42,11 @@ func (i *IdentityStore) pathLookupGroupUpdate(req *logical.Request, d *framework
 		return logical.ErrorResponse("empty type"), nil
 	}
 
+	namespaceID, err := i.requestNamespaceID(req)
+	if err != nil {
+		return nil, err
+	}
+
 	switch lookupType {
 	case "by_id":
 		groupID := d.Get("group_id").(string)
@@ -52,13 +57,16 @@ func (i *IdentityStore) pathLookupGroupUpdate(req *logical.Request, d *framework
 		if err != nil {
 			return nil, err
 		}
+		if group != nil && group.NamespaceID != namespaceID {
+			group = nil
+		}
 		return i.handleGroupReadCommon(group)
 	case "by_name":
 		groupName := d.Get("group_name").(string)
 		if groupName == "" {
 			return logical.ErrorResponse("empty group_name"), nil
 		}
-		group, err := i.memDBGroupByName(groupName, false)
+		group, err := i.memDBGroupByName(namespaceID, groupName, false)
 		if err != nil {
 			return nil, err
 		}
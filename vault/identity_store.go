@@ -3,6 +3,7 @@ package vault
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/golang/protobuf/ptypes"
 	memdb "github.com/hashicorp/go-memdb"
@@ -12,6 +13,7 @@ import (
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
 	"github.com/hashicorp/vault/version"
+	cache "github.com/patrickmn/go-cache"
 )
 
 const (
@@ -29,11 +31,14 @@ func NewIdentityStore(core *Core, config *logical.BackendConfig) (*IdentityStore
 	}
 
 	iStore := &IdentityStore{
-		view:        config.StorageView,
-		db:          db,
-		entityLocks: locksutil.CreateLocks(),
-		logger:      core.logger,
+		view:                      config.StorageView,
+		db:                        db,
+		entityLocks:               locksutil.CreateLocks(),
+		logger:                    core.logger,
 		validateMountAccessorFunc: core.router.validateMountByAccessor,
+		namespaceByPathFunc:       core.namespaceByPath,
+		mfaUsedCodes:              cache.New(0, 30*time.Second),
+		mfaFailedValidations:      cache.New(0, 30*time.Second),
 	}
 
 	iStore.entityPacker, err = storagepacker.NewStoragePacker(iStore.view, iStore.logger, "")
@@ -48,12 +53,20 @@ func NewIdentityStore(core *Core, config *logical.BackendConfig) (*IdentityStore
 
 	iStore.Backend = &framework.Backend{
 		BackendType: logical.TypeLogical,
+		PathsSpecial: &logical.Paths{
+			SealWrapStorage: []string{
+				storagepacker.StoragePackerBucketsPrefix,
+				groupBucketsPrefix,
+			},
+		},
 		Paths: framework.PathAppend(
 			entityPaths(iStore),
 			aliasPaths(iStore),
 			groupPaths(iStore),
+			groupAliasPaths(iStore),
 			lookupPaths(iStore),
 			upgradePaths(iStore),
+			mfaPaths(iStore),
 		),
 		Invalidate: iStore.Invalidate,
 	}
@@ -298,7 +311,10 @@ func (i *IdentityStore) CreateEntity(alias *logical.Alias) (*identity.Entity, er
 		return nil, fmt.Errorf("alias already belongs to a different entity")
 	}
 
-	entity = &identity.Entity{}
+	// The entity belongs to the namespace of the mount
+	entity = &identity.Entity{
+		NamespaceID: mountValidationResp.NamespaceID,
+	}
 
 	err = i.sanitizeEntity(entity)
 	if err != nil {
@@ -312,6 +328,7 @@ func (i *IdentityStore) CreateEntity(alias *logical.Alias) (*identity.Entity, er
 		MountAccessor: alias.MountAccessor,
 		MountPath:     mountValidationResp.MountPath,
 		MountType:     mountValidationResp.MountType,
+		Metadata:      alias.Metadata,
 	}
 
 	err = i.sanitizeAlias(newAlias)
@@ -332,3 +349,57 @@ func (i *IdentityStore) CreateEntity(alias *logical.Alias) (*identity.Entity, er
 
 	return entity, nil
 }
+
+// UpdateAliasMetadata merges the metadata of the given alias into the
+// matching alias of the entity. This is used by core to store metadata
+// computed at login time; the entity is only persisted if the metadata
+// changed.
+func (i *IdentityStore) UpdateAliasMetadata(entityID string, alias *logical.Alias) error {
+	if alias == nil || len(alias.Metadata) == 0 {
+		return nil
+	}
+
+	lock := locksutil.LockForKey(i.entityLocks, entityID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	entity, err := i.memDBEntityByID(entityID, true)
+	if err != nil {
+		return err
+	}
+	if entity == nil {
+		return fmt.Errorf("entity %q not found", entityID)
+	}
+
+	changed := false
+	for _, entityAlias := range entity.Aliases {
+		if entityAlias.MountAccessor != alias.MountAccessor || entityAlias.Name != alias.Name {
+			continue
+		}
+
+		aliasChanged := false
+		for k, v := range alias.Metadata {
+			if current, ok := entityAlias.Metadata[k]; ok && current == v {
+				continue
+			}
+			if entityAlias.Metadata == nil {
+				entityAlias.Metadata = make(map[string]string, len(alias.Metadata))
+			}
+			entityAlias.Metadata[k] = v
+			aliasChanged = true
+		}
+		if !aliasChanged {
+			continue
+		}
+
+		if err := i.sanitizeAlias(entityAlias); err != nil {
+			return err
+		}
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+
+	return i.upsertEntityNonLocked(entity, nil, true)
+}
@@ -0,0 +1,192 @@
+package vault
+
+import (
+	"sort"
+	"time"
+
+	"github.com/hashicorp/vault/helper/locksutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+const (
+	// entityActivityPrefix is the storage prefix of the login history of
+	// each entity
+	entityActivityPrefix = "activity/"
+
+	// entityActivityMaxRecords bounds the login history kept per entity;
+	// older logins are dropped first
+	entityActivityMaxRecords = 100
+)
+
+// entityLoginRecord describes a single login of an entity
+type entityLoginRecord struct {
+	Time          time.Time `json:"time"`
+	MountAccessor string    `json:"mount_accessor"`
+	MountPath     string    `json:"mount_path"`
+	MountType     string    `json:"mount_type"`
+	AliasName     string    `json:"alias_name"`
+	RemoteAddr    string    `json:"remote_addr"`
+	TokenAccessor string    `json:"token_accessor"`
+	Policies      []string  `json:"policies"`
+	TTL           int64     `json:"ttl"`
+}
+
+// entityActivity returns the login history of the entity, oldest first
+func (i *IdentityStore) entityActivity(entityID string) ([]*entityLoginRecord, error) {
+	entry, err := i.view.Get(entityActivityPrefix + entityID)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var records []*entityLoginRecord
+	if err := entry.DecodeJSON(&records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// putEntityActivity stores the login history of the entity, keeping only the
+// most recent records. The caller must hold the entity's lock.
+func (i *IdentityStore) putEntityActivity(entityID string, records []*entityLoginRecord) error {
+	sort.SliceStable(records, func(a, b int) bool {
+		return records[a].Time.Before(records[b].Time)
+	})
+	if len(records) > entityActivityMaxRecords {
+		records = records[len(records)-entityActivityMaxRecords:]
+	}
+
+	entry, err := logical.StorageEntryJSON(entityActivityPrefix+entityID, records)
+	if err != nil {
+		return err
+	}
+	return i.view.Put(entry)
+}
+
+// recordEntityLogin appends a login to the history of the entity
+func (i *IdentityStore) recordEntityLogin(entityID string, record *entityLoginRecord) error {
+	lock := locksutil.LockForKey(i.entityLocks, entityID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	records, err := i.entityActivity(entityID)
+	if err != nil {
+		return err
+	}
+	return i.putEntityActivity(entityID, append(records, record))
+}
+
+// mergeEntityActivity moves the login history of the merged entity into the
+// entity it was merged into. The caller must hold the locks of both entities.
+func (i *IdentityStore) mergeEntityActivity(toEntityID, fromEntityID string) error {
+	fromRecords, err := i.entityActivity(fromEntityID)
+	if err != nil {
+		return err
+	}
+	if len(fromRecords) == 0 {
+		return nil
+	}
+
+	toRecords, err := i.entityActivity(toEntityID)
+	if err != nil {
+		return err
+	}
+	if err := i.putEntityActivity(toEntityID, append(toRecords, fromRecords...)); err != nil {
+		return err
+	}
+	return i.view.Delete(entityActivityPrefix + fromEntityID)
+}
+
+// pathEntityIDActivityRead returns the recent logins of an entity along with
+// aggregates per mount and per source address
+func (i *IdentityStore) pathEntityIDActivityRead(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	entityID := d.Get("id").(string)
+	if entityID == "" {
+		return logical.ErrorResponse("missing entity id"), nil
+	}
+
+	namespaceID, err := i.requestNamespaceID(req)
+	if err != nil {
+		return nil, err
+	}
+
+	entity, err := i.memDBEntityByID(entityID, false)
+	if err != nil {
+		return nil, err
+	}
+	if entity == nil || entity.NamespaceID != namespaceID {
+		return nil, nil
+	}
+
+	records, err := i.entityActivity(entity.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	mounts := map[string]interface{}{}
+	sourceIPs := map[string]interface{}{}
+	logins := make([]interface{}, 0, len(records))
+	tokenAccessors := make([]string, 0, len(records))
+
+	// Records are walked most recent first, so the first record seen for a
+	// mount or address is its last login
+	count := func(summaries map[string]interface{}, key string, record *entityLoginRecord, init func() map[string]interface{}) {
+		summary, ok := summaries[key].(map[string]interface{})
+		if !ok {
+			summary = init()
+			summary["last_login"] = record.Time.Format(time.RFC3339)
+			summary["login_count"] = 0
+			summaries[key] = summary
+		}
+		summary["login_count"] = summary["login_count"].(int) + 1
+		summary["first_login"] = record.Time.Format(time.RFC3339)
+	}
+
+	for idx := len(records) - 1; idx >= 0; idx-- {
+		record := records[idx]
+		count(mounts, record.MountPath, record, func() map[string]interface{} {
+			return map[string]interface{}{
+				"mount_accessor": record.MountAccessor,
+				"mount_type":     record.MountType,
+			}
+		})
+		if record.RemoteAddr != "" {
+			count(sourceIPs, record.RemoteAddr, record, func() map[string]interface{} {
+				return map[string]interface{}{}
+			})
+		}
+
+		logins = append(logins, map[string]interface{}{
+			"time":           record.Time.Format(time.RFC3339),
+			"mount_accessor": record.MountAccessor,
+			"mount_path":     record.MountPath,
+			"mount_type":     record.MountType,
+			"alias_name":     record.AliasName,
+			"remote_addr":    record.RemoteAddr,
+			"token_accessor": record.TokenAccessor,
+			"policies":       record.Policies,
+			"ttl":            record.TTL,
+		})
+		tokenAccessors = append(tokenAccessors, record.TokenAccessor)
+	}
+
+	respData := map[string]interface{}{
+		"entity_id":       entity.ID,
+		"login_count":     len(records),
+		"mounts":          mounts,
+		"source_ips":      sourceIPs,
+		"token_accessors": tokenAccessors,
+		"logins":          logins,
+	}
+	if len(records) > 0 {
+		respData["first_login"] = records[0].Time.Format(time.RFC3339)
+		respData["last_login"] = records[len(records)-1].Time.Format(time.RFC3339)
+	}
+
+	return &logical.Response{
+		Data: respData,
+	}, nil
+}
@@ -120,6 +120,13 @@ func (i *IdentityStore) pathAliasIDUpdate(req *logical.Request, d *framework.Fie
 	if alias == nil {
 		return logical.ErrorResponse("invalid alias id"), nil
 	}
+	ok, err := i.aliasInRequestNamespace(req, alias)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return logical.ErrorResponse("invalid alias id"), nil
+	}
 
 	return i.handleAliasUpdateCommon(req, d, alias)
 }
@@ -138,6 +145,11 @@ func (i *IdentityStore) handleAliasUpdateCommon(req *logical.Request, d *framewo
 		newAlias = true
 	}
 
+	namespaceID, err := i.requestNamespaceID(req)
+	if err != nil {
+		return nil, err
+	}
+
 	// Get entity id
 	entityID := d.Get("entity_id").(string)
 	if entityID != "" {
@@ -145,7 +157,7 @@ func (i *IdentityStore) handleAliasUpdateCommon(req *logical.Request, d *framewo
 		if err != nil {
 			return nil, err
 		}
-		if entity == nil {
+		if entity == nil || entity.NamespaceID != namespaceID {
 			return logical.ErrorResponse("invalid entity ID"), nil
 		}
 	}
@@ -162,7 +174,7 @@ func (i *IdentityStore) handleAliasUpdateCommon(req *logical.Request, d *framewo
 	}
 
 	mountValidationResp := i.validateMountAccessorFunc(mountAccessor)
-	if mountValidationResp == nil {
+	if mountValidationResp == nil || mountValidationResp.NamespaceID != namespaceID {
 		return logical.ErrorResponse(fmt.Sprintf("invalid mount accessor %q", mountAccessor)), nil
 	}
 
@@ -195,6 +207,7 @@ func (i *IdentityStore) handleAliasUpdateCommon(req *logical.Request, d *framewo
 		// a new entity for it.
 		if entity == nil {
 			entity = &identity.Entity{
+				NamespaceID: namespaceID,
 				Aliases: []*identity.Alias{
 					alias,
 				},
@@ -298,6 +311,14 @@ func (i *IdentityStore) pathAliasIDRead(req *logical.Request, d *framework.Field
 		return nil, nil
 	}
 
+	ok, err := i.aliasInRequestNamespace(req, alias)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, nil
+	}
+
 	respData := map[string]interface{}{}
 	respData["id"] = alias.ID
 	respData["entity_id"] = alias.EntityID
@@ -324,6 +345,19 @@ func (i *IdentityStore) pathAliasIDDelete(req *logical.Request, d *framework.Fie
 		return logical.ErrorResponse("missing alias ID"), nil
 	}
 
+	// Aliases of other namespaces are left untouched
+	alias, err := i.memDBAliasByID(aliasID, false)
+	if err != nil {
+		return nil, err
+	}
+	if alias == nil {
+		return nil, nil
+	}
+	ok, err := i.aliasInRequestNamespace(req, alias)
+	if err != nil || !ok {
+		return nil, err
+	}
+
 	return nil, i.deleteAlias(aliasID)
 }
 
@@ -342,12 +376,34 @@ func (i *IdentityStore) pathAliasIDList(req *logical.Request, d *framework.Field
 		if raw == nil {
 			break
 		}
-		aliasIDs = append(aliasIDs, raw.(*identity.Alias).ID)
+		alias := raw.(*identity.Alias)
+		ok, err := i.aliasInRequestNamespace(req, alias)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		aliasIDs = append(aliasIDs, alias.ID)
 	}
 
 	return logical.ListResponse(aliasIDs), nil
 }
 
+// aliasInRequestNamespace returns whether an alias belongs to the namespace
+// of a request, which is the namespace of its entity
+func (i *IdentityStore) aliasInRequestNamespace(req *logical.Request, alias *identity.Alias) (bool, error) {
+	namespaceID, err := i.requestNamespaceID(req)
+	if err != nil {
+		return false, err
+	}
+	entity, err := i.memDBEntityByID(alias.EntityID, false)
+	if err != nil {
+		return false, err
+	}
+	return entity != nil && entity.NamespaceID == namespaceID, nil
+}
+
 var aliasHelp = map[string][2]string{
 	"alias": {
 		"Create a new alias",
@@ -17,6 +17,7 @@ import (
 // Following are the paths supported:
 // entity - To register a new entity
 // entity/id - To lookup, modify, delete and list entities based on ID
+// entity/id/<id>/activity - To review the recent logins of an entity
 // entity/merge - To merge entities based on ID
 func entityPaths(i *IdentityStore) []*framework.Path {
 	return []*framework.Path{
@@ -76,6 +77,21 @@ func entityPaths(i *IdentityStore) []*framework.Path {
 			HelpSynopsis:    strings.TrimSpace(entityHelp["entity-id"][0]),
 			HelpDescription: strings.TrimSpace(entityHelp["entity-id"][1]),
 		},
+		{
+			Pattern: "entity/id/" + framework.GenericNameRegex("id") + "/activity$",
+			Fields: map[string]*framework.FieldSchema{
+				"id": {
+					Type:        framework.TypeString,
+					Description: "ID of the entity",
+				},
+			},
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation: i.checkPremiumVersion(i.pathEntityIDActivityRead),
+			},
+
+			HelpSynopsis:    strings.TrimSpace(entityHelp["entity-id-activity"][0]),
+			HelpDescription: strings.TrimSpace(entityHelp["entity-id-activity"][1]),
+		},
 		{
 			Pattern: "entity/id/?$",
 			Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -125,12 +141,17 @@ func (i *IdentityStore) pathEntityMergeID(req *logical.Request, d *framework.Fie
 
 	force := d.Get("force").(bool)
 
+	namespaceID, err := i.requestNamespaceID(req)
+	if err != nil {
+		return nil, err
+	}
+
 	toEntityForLocking, err := i.memDBEntityByID(toEntityID, false)
 	if err != nil {
 		return nil, err
 	}
 
-	if toEntityForLocking == nil {
+	if toEntityForLocking == nil || toEntityForLocking.NamespaceID != namespaceID {
 		return logical.ErrorResponse("entity id to merge to is invalid"), nil
 	}
 
@@ -168,7 +189,7 @@ func (i *IdentityStore) pathEntityMergeID(req *logical.Request, d *framework.Fie
 			return nil, err
 		}
 
-		if lockFromEntity == nil {
+		if lockFromEntity == nil || lockFromEntity.NamespaceID != namespaceID {
 			return logical.ErrorResponse("entity id to merge from is invalid"), nil
 		}
 
@@ -254,6 +275,24 @@ func (i *IdentityStore) pathEntityMergeID(req *logical.Request, d *framework.Fie
 			return nil, err
 		}
 
+		// Carry over the login history of the entity we are merging from
+		err = i.mergeEntityActivity(toEntity.ID, fromEntity.ID)
+		if err != nil {
+			if fromLockHeld {
+				fromEntityLock.Unlock()
+			}
+			return nil, err
+		}
+
+		// Carry over the MFA enrollments of the entity we are merging from
+		err = i.mergeEntityMFASecrets(toEntity.ID, fromEntity.ID)
+		if err != nil {
+			if fromLockHeld {
+				fromEntityLock.Unlock()
+			}
+			return nil, err
+		}
+
 		if fromLockHeld {
 			fromEntityLock.Unlock()
 		}
@@ -310,11 +349,16 @@ func (i *IdentityStore) pathEntityIDUpdate(req *logical.Request, d *framework.Fi
 		return logical.ErrorResponse("missing entity id"), nil
 	}
 
+	namespaceID, err := i.requestNamespaceID(req)
+	if err != nil {
+		return nil, err
+	}
+
 	entity, err := i.memDBEntityByID(entityID, true)
 	if err != nil {
 		return nil, err
 	}
-	if entity == nil {
+	if entity == nil || entity.NamespaceID != namespaceID {
 		return nil, fmt.Errorf("invalid entity id")
 	}
 
@@ -329,7 +373,13 @@ func (i *IdentityStore) handleEntityUpdateCommon(req *logical.Request, d *framew
 	// Entity will be nil when a new entity is being registered; create a new
 	// struct in that case.
 	if entity == nil {
-		entity = &identity.Entity{}
+		namespaceID, err := i.requestNamespaceID(req)
+		if err != nil {
+			return nil, err
+		}
+		entity = &identity.Entity{
+			NamespaceID: namespaceID,
+		}
 		newEntity = true
 	}
 
@@ -342,7 +392,7 @@ func (i *IdentityStore) handleEntityUpdateCommon(req *logical.Request, d *framew
 	// Get the name
 	entityName := d.Get("name").(string)
 	if entityName != "" {
-		entityByName, err := i.memDBEntityByName(entityName, false)
+		entityByName, err := i.memDBEntityByName(entity.NamespaceID, entityName, false)
 		if err != nil {
 			return nil, err
 		}
@@ -403,11 +453,16 @@ func (i *IdentityStore) pathEntityIDRead(req *logical.Request, d *framework.Fiel
 		return logical.ErrorResponse("missing entity id"), nil
 	}
 
+	namespaceID, err := i.requestNamespaceID(req)
+	if err != nil {
+		return nil, err
+	}
+
 	entity, err := i.memDBEntityByID(entityID, false)
 	if err != nil {
 		return nil, err
 	}
-	if entity == nil {
+	if entity == nil || entity.NamespaceID != namespaceID {
 		return nil, nil
 	}
 
@@ -457,12 +512,31 @@ func (i *IdentityStore) pathEntityIDDelete(req *logical.Request, d *framework.Fi
 		return logical.ErrorResponse("missing entity id"), nil
 	}
 
+	namespaceID, err := i.requestNamespaceID(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Entities of other namespaces are left untouched
+	entity, err := i.memDBEntityByID(entityID, false)
+	if err != nil {
+		return nil, err
+	}
+	if entity == nil || entity.NamespaceID != namespaceID {
+		return nil, nil
+	}
+
 	return nil, i.deleteEntity(entityID)
 }
 
 // pathEntityIDList lists the IDs of all the valid entities in the identity
 // store
 func (i *IdentityStore) pathEntityIDList(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	namespaceID, err := i.requestNamespaceID(req)
+	if err != nil {
+		return nil, err
+	}
+
 	ws := memdb.NewWatchSet()
 	iter, err := i.memDBEntities(ws)
 	if err != nil {
@@ -475,7 +549,11 @@ func (i *IdentityStore) pathEntityIDList(req *logical.Request, d *framework.Fiel
 		if raw == nil {
 			break
 		}
-		entityIDs = append(entityIDs, raw.(*identity.Entity).ID)
+		entity := raw.(*identity.Entity)
+		if entity.NamespaceID != namespaceID {
+			continue
+		}
+		entityIDs = append(entityIDs, entity.ID)
 	}
 
 	return logical.ListResponse(entityIDs), nil
@@ -490,6 +568,12 @@ var entityHelp = map[string][2]string{
 		"Update, read or delete an entity using entity ID",
 		"",
 	},
+	"entity-id-activity": {
+		"Read the recent logins of an entity",
+		`Returns the most recent logins of the entity, along with the number of
+logins per auth mount and per source address and the accessors of the tokens
+issued. Only the last 100 logins are kept.`,
+	},
 	"entity-id-list": {
 		"List all the entity IDs",
 		"",
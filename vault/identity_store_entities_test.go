@@ -5,6 +5,7 @@ import (
 	"reflect"
 	"sort"
 	"testing"
+	"time"
 
 	uuid "github.com/hashicorp/go-uuid"
 	credGithub "github.com/hashicorp/vault/builtin/credential/github"
@@ -413,7 +414,7 @@ func TestIdentityStore_MemDBEntityIndexes(t *testing.T) {
 	}
 
 	// Fetch the entity using its name
-	entityFetched, err = is.memDBEntityByName(entity.Name, false)
+	entityFetched, err = is.memDBEntityByName("", entity.Name, false)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -461,7 +462,7 @@ func TestIdentityStore_MemDBEntityIndexes(t *testing.T) {
 		t.Fatalf("bad: entity; expected: nil, actual: %#v\n", entityFetched)
 	}
 
-	entityFetched, err = is.memDBEntityByName(entity.Name, false)
+	entityFetched, err = is.memDBEntityByName("", entity.Name, false)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -781,3 +782,104 @@ func TestIdentityStore_MergeEntitiesByID(t *testing.T) {
 		}
 	}
 }
+
+func TestIdentityStore_EntityActivity(t *testing.T) {
+	noop := &NoopBackend{
+		Login: []string{"login"},
+	}
+	c, _, root := TestCoreUnsealed(t)
+	c.credentialBackends["noop"] = func(conf *logical.BackendConfig) (logical.Backend, error) {
+		return noop, nil
+	}
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "sys/auth/foo")
+	req.Data["type"] = "noop"
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	noop.Response = &logical.Response{
+		Auth: &logical.Auth{
+			Policies: []string{"foo"},
+			Alias: &logical.Alias{
+				Name: "armon",
+			},
+		},
+	}
+	var entityID string
+	var accessors []string
+	for _, addr := range []string{"10.0.0.1", "10.0.0.2", "10.0.0.1"} {
+		lresp, err := c.HandleRequest(&logical.Request{
+			Path: "auth/foo/login",
+			Connection: &logical.Connection{
+				RemoteAddr: addr,
+			},
+		})
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		entityID = lresp.Auth.EntityID
+		accessors = append([]string{lresp.Auth.Accessor}, accessors...)
+	}
+
+	resp, err := c.HandleRequest(&logical.Request{
+		Operation:   logical.ReadOperation,
+		Path:        "identity/entity/id/" + entityID + "/activity",
+		ClientToken: root,
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: err: %v resp: %#v", err, resp)
+	}
+
+	if resp.Data["login_count"] != 3 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if !reflect.DeepEqual(resp.Data["token_accessors"], accessors) {
+		t.Fatalf("bad: token accessors; expected: %v actual: %v", accessors, resp.Data["token_accessors"])
+	}
+	mount := resp.Data["mounts"].(map[string]interface{})["auth/foo/"].(map[string]interface{})
+	if mount["login_count"] != 3 || mount["mount_type"] != "noop" {
+		t.Fatalf("bad: %#v", mount)
+	}
+	sourceIPs := resp.Data["source_ips"].(map[string]interface{})
+	if len(sourceIPs) != 2 || sourceIPs["10.0.0.1"].(map[string]interface{})["login_count"] != 2 {
+		t.Fatalf("bad: %#v", sourceIPs)
+	}
+	logins := resp.Data["logins"].([]interface{})
+	if len(logins) != 3 || logins[0].(map[string]interface{})["remote_addr"] != "10.0.0.1" {
+		t.Fatalf("bad: %#v", logins)
+	}
+
+	// The history is bounded, dropping the oldest logins
+	for i := 0; i < entityActivityMaxRecords; i++ {
+		err := c.identityStore.recordEntityLogin(entityID, &entityLoginRecord{
+			Time:      time.Now().UTC(),
+			MountPath: "auth/bar/",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	records, err := c.identityStore.entityActivity(entityID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != entityActivityMaxRecords {
+		t.Fatalf("bad: %d records", len(records))
+	}
+	for _, record := range records {
+		if record.MountPath != "auth/bar/" {
+			t.Fatalf("bad: %#v", record)
+		}
+	}
+
+	// Deleting the entity removes its history
+	if err := c.identityStore.deleteEntity(entityID); err != nil {
+		t.Fatal(err)
+	}
+	records, err = c.identityStore.entityActivity(entityID)
+	if err != nil || records != nil {
+		t.Fatalf("bad: err: %v records: %#v", err, records)
+	}
+}
@@ -0,0 +1,402 @@
+package vault
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/golang/protobuf/ptypes"
+	memdb "github.com/hashicorp/go-memdb"
+	"github.com/hashicorp/vault/helper/identity"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// groupAliasPaths returns the API endpoints to operate on group aliases.
+// Following are the paths supported:
+// group-alias - To register/modify a group alias
+// group-alias/id - To lookup, delete and list group aliases based on ID
+func groupAliasPaths(i *IdentityStore) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "group-alias$",
+			Fields: map[string]*framework.FieldSchema{
+				"id": {
+					Type:        framework.TypeString,
+					Description: "ID of the group alias.",
+				},
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Alias of the group.",
+				},
+				"mount_accessor": {
+					Type:        framework.TypeString,
+					Description: "Mount accessor to which this alias belongs to.",
+				},
+				"canonical_id": {
+					Type:        framework.TypeString,
+					Description: "ID of the external group to which this alias belongs to. If not set, a new external group is created.",
+				},
+			},
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: i.checkPremiumVersion(i.pathGroupAliasRegister),
+			},
+
+			HelpSynopsis:    strings.TrimSpace(groupAliasHelp["group-alias"][0]),
+			HelpDescription: strings.TrimSpace(groupAliasHelp["group-alias"][1]),
+		},
+		{
+			Pattern: "group-alias/id/" + framework.GenericNameRegex("id"),
+			Fields: map[string]*framework.FieldSchema{
+				"id": {
+					Type:        framework.TypeString,
+					Description: "ID of the group alias.",
+				},
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Alias of the group.",
+				},
+				"mount_accessor": {
+					Type:        framework.TypeString,
+					Description: "Mount accessor to which this alias belongs to.",
+				},
+				"canonical_id": {
+					Type:        framework.TypeString,
+					Description: "ID of the external group to which this alias belongs to.",
+				},
+			},
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: i.checkPremiumVersion(i.pathGroupAliasIDUpdate),
+				logical.ReadOperation:   i.checkPremiumVersion(i.pathGroupAliasIDRead),
+				logical.DeleteOperation: i.checkPremiumVersion(i.pathGroupAliasIDDelete),
+			},
+
+			HelpSynopsis:    strings.TrimSpace(groupAliasHelp["group-alias-by-id"][0]),
+			HelpDescription: strings.TrimSpace(groupAliasHelp["group-alias-by-id"][1]),
+		},
+		{
+			Pattern: "group-alias/id/?$",
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ListOperation: i.checkPremiumVersion(i.pathGroupAliasIDList),
+			},
+
+			HelpSynopsis:    strings.TrimSpace(groupAliasHelp["group-alias-id-list"][0]),
+			HelpDescription: strings.TrimSpace(groupAliasHelp["group-alias-id-list"][1]),
+		},
+	}
+}
+
+// pathGroupAliasRegister is used to register a new group alias
+func (i *IdentityStore) pathGroupAliasRegister(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	_, ok := d.GetOk("id")
+	if ok {
+		return i.pathGroupAliasIDUpdate(req, d)
+	}
+
+	i.groupLock.Lock()
+	defer i.groupLock.Unlock()
+
+	return i.handleGroupAliasUpdateCommon(req, d, nil)
+}
+
+// pathGroupAliasIDUpdate is used to update a group alias based on the given
+// group alias ID
+func (i *IdentityStore) pathGroupAliasIDUpdate(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	aliasID := d.Get("id").(string)
+	if aliasID == "" {
+		return logical.ErrorResponse("empty group alias ID"), nil
+	}
+
+	i.groupLock.Lock()
+	defer i.groupLock.Unlock()
+
+	alias, err := i.memDBGroupAliasByID(aliasID, true)
+	if err != nil {
+		return nil, err
+	}
+	if alias == nil {
+		return logical.ErrorResponse("invalid group alias ID"), nil
+	}
+	ok, err := i.groupAliasInRequestNamespace(req, alias)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return logical.ErrorResponse("invalid group alias ID"), nil
+	}
+
+	return i.handleGroupAliasUpdateCommon(req, d, alias)
+}
+
+// handleGroupAliasUpdateCommon is used to register or update a group alias.
+// The caller should hold the group lock.
+func (i *IdentityStore) handleGroupAliasUpdateCommon(req *logical.Request, d *framework.FieldData, alias *identity.Alias) (*logical.Response, error) {
+	aliasName := d.Get("name").(string)
+	if aliasName == "" {
+		return logical.ErrorResponse("missing alias name"), nil
+	}
+
+	mountAccessor := d.Get("mount_accessor").(string)
+	if mountAccessor == "" {
+		return logical.ErrorResponse("missing mount_accessor"), nil
+	}
+
+	namespaceID, err := i.requestNamespaceID(req)
+	if err != nil {
+		return nil, err
+	}
+
+	mountValidationResp := i.validateMountAccessorFunc(mountAccessor)
+	if mountValidationResp == nil || mountValidationResp.NamespaceID != namespaceID {
+		return logical.ErrorResponse(fmt.Sprintf("invalid mount accessor %q", mountAccessor)), nil
+	}
+
+	txn := i.db.Txn(true)
+	defer txn.Abort()
+
+	aliasByFactors, err := i.memDBGroupAliasByFactorsInTxn(txn, mountValidationResp.MountAccessor, aliasName, false)
+	if err != nil {
+		return nil, err
+	}
+	if aliasByFactors != nil && (alias == nil || aliasByFactors.ID != alias.ID) {
+		return logical.ErrorResponse("combination of mount and group alias name is already in use"), nil
+	}
+
+	// The group to which the alias currently belongs, if it is being moved
+	var previousGroup *identity.Group
+
+	var group *identity.Group
+	canonicalID := d.Get("canonical_id").(string)
+	switch {
+	case canonicalID != "":
+		group, err = i.memDBGroupByIDInTxn(txn, canonicalID, true)
+		if err != nil {
+			return nil, err
+		}
+		if group == nil || group.NamespaceID != namespaceID {
+			return logical.ErrorResponse("invalid canonical ID"), nil
+		}
+		if group.Type != groupTypeExternal {
+			return logical.ErrorResponse("alias can't be set on an internal group"), nil
+		}
+		if group.Alias != nil && (alias == nil || group.Alias.ID != alias.ID) {
+			return logical.ErrorResponse("group already has an alias"), nil
+		}
+
+	case alias != nil:
+		canonicalID = alias.CanonicalID
+		group, err = i.memDBGroupByIDInTxn(txn, canonicalID, true)
+		if err != nil {
+			return nil, err
+		}
+		if group == nil {
+			return nil, fmt.Errorf("group alias is not associated with a group")
+		}
+
+	default:
+		group, err = i.newExternalGroup(txn, mountValidationResp, aliasName)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if alias == nil {
+		if group.Alias == nil {
+			group.Alias, err = i.newGroupAlias(mountValidationResp, aliasName, group.ID)
+			if err != nil {
+				return nil, err
+			}
+		}
+		alias = group.Alias
+	} else if alias.CanonicalID != group.ID {
+		previousGroup, err = i.memDBGroupByIDInTxn(txn, alias.CanonicalID, true)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	alias.Name = aliasName
+	alias.CanonicalID = group.ID
+	alias.MountType = mountValidationResp.MountType
+	alias.MountAccessor = mountValidationResp.MountAccessor
+	alias.MountPath = mountValidationResp.MountPath
+	alias.LastUpdateTime = ptypes.TimestampNow()
+	group.Alias = alias
+
+	groups := []*identity.Group{group}
+	if previousGroup != nil {
+		previousGroup.Alias = nil
+		err = i.upsertGroupInTxn(txn, previousGroup, false)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, previousGroup)
+	}
+
+	err = i.upsertGroupInTxn(txn, group, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// Moving the alias updates both groups at once
+	err = i.persistGroups(groups)
+	if err != nil {
+		return nil, err
+	}
+
+	txn.Commit()
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"id":           alias.ID,
+			"canonical_id": group.ID,
+		},
+	}, nil
+}
+
+// pathGroupAliasIDRead returns the properties of a group alias for a given
+// group alias ID
+func (i *IdentityStore) pathGroupAliasIDRead(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	aliasID := d.Get("id").(string)
+	if aliasID == "" {
+		return logical.ErrorResponse("empty group alias ID"), nil
+	}
+
+	alias, err := i.memDBGroupAliasByID(aliasID, false)
+	if err != nil {
+		return nil, err
+	}
+	if alias == nil {
+		return nil, nil
+	}
+	ok, err := i.groupAliasInRequestNamespace(req, alias)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: groupAliasResponseData(alias),
+	}, nil
+}
+
+// pathGroupAliasIDDelete deletes the group alias for a given group alias ID
+func (i *IdentityStore) pathGroupAliasIDDelete(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	aliasID := d.Get("id").(string)
+	if aliasID == "" {
+		return logical.ErrorResponse("empty group alias ID"), nil
+	}
+
+	i.groupLock.Lock()
+	defer i.groupLock.Unlock()
+
+	txn := i.db.Txn(true)
+	defer txn.Abort()
+
+	alias, err := i.memDBGroupAliasByIDInTxn(txn, aliasID, false)
+	if err != nil {
+		return nil, err
+	}
+	if alias == nil {
+		return nil, nil
+	}
+
+	group, err := i.memDBGroupByIDInTxn(txn, alias.CanonicalID, true)
+	if err != nil {
+		return nil, err
+	}
+	if group == nil {
+		return nil, fmt.Errorf("group alias is not associated with a group")
+	}
+
+	// Aliases of other namespaces are left untouched
+	namespaceID, err := i.requestNamespaceID(req)
+	if err != nil {
+		return nil, err
+	}
+	if group.NamespaceID != namespaceID {
+		return nil, nil
+	}
+
+	// Removing the alias from the group also removes it from MemDB
+	group.Alias = nil
+	err = i.upsertGroupInTxn(txn, group, true)
+	if err != nil {
+		return nil, err
+	}
+
+	txn.Commit()
+
+	return nil, nil
+}
+
+// pathGroupAliasIDList lists the IDs of all the group aliases in the
+// identity store
+func (i *IdentityStore) pathGroupAliasIDList(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	ws := memdb.NewWatchSet()
+	iter, err := i.memDBGroupAliases(ws)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch iterator for group aliases in memdb: %v", err)
+	}
+
+	var aliasIDs []string
+	for {
+		raw := iter.Next()
+		if raw == nil {
+			break
+		}
+		alias := raw.(*identity.Alias)
+		ok, err := i.groupAliasInRequestNamespace(req, alias)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		aliasIDs = append(aliasIDs, alias.ID)
+	}
+
+	return logical.ListResponse(aliasIDs), nil
+}
+
+// groupAliasInRequestNamespace returns whether a group alias belongs to the
+// namespace of a request, which is the namespace of its group
+func (i *IdentityStore) groupAliasInRequestNamespace(req *logical.Request, alias *identity.Alias) (bool, error) {
+	namespaceID, err := i.requestNamespaceID(req)
+	if err != nil {
+		return false, err
+	}
+	group, err := i.memDBGroupByID(alias.CanonicalID, false)
+	if err != nil {
+		return false, err
+	}
+	return group != nil && group.NamespaceID == namespaceID, nil
+}
+
+// groupAliasResponseData returns the response data of a group alias, as
+// returned on reads of the alias and of its group
+func groupAliasResponseData(alias *identity.Alias) map[string]interface{} {
+	return map[string]interface{}{
+		"id":               alias.ID,
+		"canonical_id":     alias.CanonicalID,
+		"name":             alias.Name,
+		"mount_type":       alias.MountType,
+		"mount_accessor":   alias.MountAccessor,
+		"mount_path":       alias.MountPath,
+		"creation_time":    ptypes.TimestampString(alias.CreationTime),
+		"last_update_time": ptypes.TimestampString(alias.LastUpdateTime),
+	}
+}
+
+var groupAliasHelp = map[string][2]string{
+	"group-alias": {
+		"Creates a new group alias, or updates an existing one.",
+		"",
+	},
+	"group-alias-by-id": {
+		"Update, read or delete a group alias using ID.",
+		"",
+	},
+	"group-alias-id-list": {
+		"List all the group alias IDs.",
+		"",
+	},
+}
@@ -0,0 +1,280 @@
+package vault
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestIdentityStore_GroupAliases_CRUD(t *testing.T) {
+	var resp *logical.Response
+	var err error
+	is, ghAccessor, _ := testIdentityStoreWithGithubAuth(t)
+
+	// Registering an alias without a group creates an external group
+	groupAliasReq := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "group-alias",
+		Data: map[string]interface{}{
+			"name":           "testgroupalias",
+			"mount_accessor": ghAccessor,
+		},
+	}
+	resp, err = is.HandleRequest(groupAliasReq)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	aliasID := resp.Data["id"].(string)
+	groupID := resp.Data["canonical_id"].(string)
+
+	resp, err = is.HandleRequest(&logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "group/id/" + groupID,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	if resp.Data["type"] != groupTypeExternal || resp.Data["name"] != "testgroupalias" {
+		t.Fatalf("bad: group data: %#v", resp.Data)
+	}
+	if resp.Data["alias"].(map[string]interface{})["id"] != aliasID {
+		t.Fatalf("bad: group alias: %#v", resp.Data["alias"])
+	}
+
+	// The same combination of mount and name can't be registered twice
+	resp, err = is.HandleRequest(groupAliasReq)
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error; resp: %#v, err: %v", resp, err)
+	}
+
+	aliasReq := &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "group-alias/id/" + aliasID,
+	}
+	resp, err = is.HandleRequest(aliasReq)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+
+	expectedData := map[string]interface{}{
+		"id":               aliasID,
+		"canonical_id":     groupID,
+		"name":             "testgroupalias",
+		"mount_type":       "github",
+		"mount_accessor":   ghAccessor,
+		"mount_path":       "github/",
+		"creation_time":    resp.Data["creation_time"],
+		"last_update_time": resp.Data["last_update_time"],
+	}
+	if !reflect.DeepEqual(expectedData, resp.Data) {
+		t.Fatalf("bad: group alias data;\nexpected: %#v\n actual: %#v\n", expectedData, resp.Data)
+	}
+
+	// Aliases can't be set on internal groups
+	resp, err = is.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "group",
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	internalGroupID := resp.Data["id"].(string)
+
+	aliasReq.Operation = logical.UpdateOperation
+	aliasReq.Data = map[string]interface{}{
+		"name":           "testgroupalias",
+		"mount_accessor": ghAccessor,
+		"canonical_id":   internalGroupID,
+	}
+	resp, err = is.HandleRequest(aliasReq)
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error; resp: %#v, err: %v", resp, err)
+	}
+
+	// Move the alias to another external group
+	resp, err = is.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "group",
+		Data: map[string]interface{}{
+			"type": groupTypeExternal,
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	externalGroupID := resp.Data["id"].(string)
+
+	aliasReq.Data["name"] = "updatedgroupalias"
+	aliasReq.Data["canonical_id"] = externalGroupID
+	resp, err = is.HandleRequest(aliasReq)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+
+	group, err := is.memDBGroupByID(groupID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if group.Alias != nil {
+		t.Fatalf("expected the alias to be removed from the previous group")
+	}
+
+	alias, err := is.memDBGroupAliasByFactors(ghAccessor, "updatedgroupalias", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if alias == nil || alias.ID != aliasID || alias.CanonicalID != externalGroupID {
+		t.Fatalf("bad: group alias: %#v", alias)
+	}
+
+	// Delete the alias
+	aliasReq.Operation = logical.DeleteOperation
+	aliasReq.Data = nil
+	resp, err = is.HandleRequest(aliasReq)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+
+	group, err = is.memDBGroupByID(externalGroupID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if group == nil || group.Alias != nil {
+		t.Fatalf("expected the alias to be removed from the group; group: %#v", group)
+	}
+
+	resp, err = is.HandleRequest(&logical.Request{
+		Operation: logical.ListOperation,
+		Path:      "group-alias/id/",
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	if len(resp.Data) != 0 {
+		t.Fatalf("expected no group aliases; resp: %#v", resp)
+	}
+}
+
+func TestIdentityStore_ExternalGroups_Members(t *testing.T) {
+	is, ghAccessor, _ := testIdentityStoreWithGithubAuth(t)
+
+	// Member entities of external groups are managed on login
+	resp, err := is.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "group",
+		Data: map[string]interface{}{
+			"type":              groupTypeExternal,
+			"member_entity_ids": "testentityid",
+		},
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error; resp: %#v, err: %v", resp, err)
+	}
+
+	entity, err := is.CreateEntity(&logical.Alias{
+		MountAccessor: ghAccessor,
+		MountType:     "github",
+		Name:          "githubuser",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Register one of the group aliases beforehand
+	resp, err = is.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "group-alias",
+		Data: map[string]interface{}{
+			"name":           "existing",
+			"mount_accessor": ghAccessor,
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	existingGroupID := resp.Data["canonical_id"].(string)
+
+	groupAliases := []*logical.Alias{
+		{Name: "existing"},
+		{Name: "unknown"},
+	}
+
+	// Without auto-creation, only the registered group aliases are linked
+	err = is.refreshExternalGroupMembershipsByEntityID(entity.ID, ghAccessor, groupAliases, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	groups, err := is.memDBGroupsByMemberEntityID(entity.ID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 1 || groups[0].ID != existingGroupID {
+		t.Fatalf("bad: groups: %#v", groups)
+	}
+
+	alias, err := is.memDBGroupAliasByFactors(ghAccessor, "unknown", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if alias != nil {
+		t.Fatalf("expected no group alias to be created")
+	}
+
+	// With auto-creation, the missing group is created
+	err = is.refreshExternalGroupMembershipsByEntityID(entity.ID, ghAccessor, groupAliases, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	alias, err = is.memDBGroupAliasByFactors(ghAccessor, "unknown", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if alias == nil {
+		t.Fatalf("expected a group alias to be created")
+	}
+
+	groups, err = is.memDBGroupsByMemberEntityID(entity.ID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 2 {
+		t.Fatalf("bad: groups: %#v", groups)
+	}
+
+	// The entity is removed from the groups that are no longer returned
+	err = is.refreshExternalGroupMembershipsByEntityID(entity.ID, ghAccessor, groupAliases[1:], true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	groups, err = is.memDBGroupsByMemberEntityID(entity.ID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 1 || groups[0].ID != alias.CanonicalID || groups[0].Type != groupTypeExternal {
+		t.Fatalf("bad: groups: %#v", groups)
+	}
+
+	// Policies of the external groups apply to the entity
+	_, err = is.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "group/id/" + alias.CanonicalID,
+		Data: map[string]interface{}{
+			"policies": "externalpolicy",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	policies, err := is.groupPoliciesByEntityID(entity.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(policies, []string{"externalpolicy"}) {
+		t.Fatalf("bad: policies: %#v", policies)
+	}
+}
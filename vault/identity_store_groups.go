@@ -40,6 +40,10 @@ func groupPaths(i *IdentityStore) []*framework.Path {
 					Type:        framework.TypeCommaStringSlice,
 					Description: "Entity IDs to be assigned as group members.",
 				},
+				"type": {
+					Type:        framework.TypeString,
+					Description: "Type of the group, 'internal' or 'external'. Defaults to 'internal'.",
+				},
 			},
 			Callbacks: map[logical.Operation]framework.OperationFunc{
 				logical.UpdateOperation: i.checkPremiumVersion(i.pathGroupRegister),
@@ -75,6 +79,10 @@ func groupPaths(i *IdentityStore) []*framework.Path {
 					Type:        framework.TypeCommaStringSlice,
 					Description: "Entity IDs to be assigned as group members.",
 				},
+				"type": {
+					Type:        framework.TypeString,
+					Description: "Type of the group, 'internal' or 'external'. Defaults to 'internal'.",
+				},
 			},
 			Callbacks: map[logical.Operation]framework.OperationFunc{
 				logical.UpdateOperation: i.checkPremiumVersion(i.pathGroupIDUpdate),
@@ -118,11 +126,16 @@ func (i *IdentityStore) pathGroupIDUpdate(req *logical.Request, d *framework.Fie
 	i.groupLock.Lock()
 	defer i.groupLock.Unlock()
 
+	namespaceID, err := i.requestNamespaceID(req)
+	if err != nil {
+		return nil, err
+	}
+
 	group, err := i.memDBGroupByID(groupID, true)
 	if err != nil {
 		return nil, err
 	}
-	if group == nil {
+	if group == nil || group.NamespaceID != namespaceID {
 		return logical.ErrorResponse("invalid group ID"), nil
 	}
 
@@ -133,10 +146,27 @@ func (i *IdentityStore) handleGroupUpdateCommon(req *logical.Request, d *framewo
 	var err error
 	var newGroup bool
 	if group == nil {
-		group = &identity.Group{}
+		namespaceID, err := i.requestNamespaceID(req)
+		if err != nil {
+			return nil, err
+		}
+		group = &identity.Group{
+			NamespaceID: namespaceID,
+		}
 		newGroup = true
 	}
 
+	groupType := d.Get("type").(string)
+	switch {
+	case groupType == "":
+	case groupType != groupTypeInternal && groupType != groupTypeExternal:
+		return logical.ErrorResponse(fmt.Sprintf("invalid group type %q", groupType)), nil
+	case newGroup:
+		group.Type = groupType
+	case groupType != group.Type && !(groupType == groupTypeInternal && group.Type == ""):
+		return logical.ErrorResponse("group type cannot be changed"), nil
+	}
+
 	// Update the policies if supplied
 	policiesRaw, ok := d.GetOk("policies")
 	if ok {
@@ -147,7 +177,7 @@ func (i *IdentityStore) handleGroupUpdateCommon(req *logical.Request, d *framewo
 	groupName := d.Get("name").(string)
 	if groupName != "" {
 		// Check if there is a group already existing for the given name
-		groupByName, err := i.memDBGroupByName(groupName, false)
+		groupByName, err := i.memDBGroupByName(group.NamespaceID, groupName, false)
 		if err != nil {
 			return nil, err
 		}
@@ -173,6 +203,9 @@ func (i *IdentityStore) handleGroupUpdateCommon(req *logical.Request, d *framewo
 
 	memberEntityIDsRaw, ok := d.GetOk("member_entity_ids")
 	if ok {
+		if group.Type == groupTypeExternal {
+			return logical.ErrorResponse("member entities of external groups are managed on login and cannot be set"), nil
+		}
 		group.MemberEntityIDs = memberEntityIDsRaw.([]string)
 		if len(group.MemberEntityIDs) > 512 {
 			return logical.ErrorResponse("member entity IDs exceeding the limit of 512"), nil
@@ -182,6 +215,9 @@ func (i *IdentityStore) handleGroupUpdateCommon(req *logical.Request, d *framewo
 	memberGroupIDsRaw, ok := d.GetOk("member_group_ids")
 	var memberGroupIDs []string
 	if ok {
+		if group.Type == groupTypeExternal {
+			return logical.ErrorResponse("external groups cannot have member groups"), nil
+		}
 		memberGroupIDs = memberGroupIDsRaw.([]string)
 	}
 
@@ -205,11 +241,16 @@ func (i *IdentityStore) pathGroupIDRead(req *logical.Request, d *framework.Field
 		return logical.ErrorResponse("empty group id"), nil
 	}
 
+	namespaceID, err := i.requestNamespaceID(req)
+	if err != nil {
+		return nil, err
+	}
+
 	group, err := i.memDBGroupByID(groupID, false)
 	if err != nil {
 		return nil, err
 	}
-	if group == nil {
+	if group == nil || group.NamespaceID != namespaceID {
 		return nil, nil
 	}
 
@@ -230,6 +271,15 @@ func (i *IdentityStore) handleGroupReadCommon(group *identity.Group) (*logical.R
 	respData["creation_time"] = ptypes.TimestampString(group.CreationTime)
 	respData["last_update_time"] = ptypes.TimestampString(group.LastUpdateTime)
 	respData["modify_index"] = group.ModifyIndex
+	respData["type"] = group.Type
+	if respData["type"] == "" {
+		respData["type"] = groupTypeInternal
+	}
+
+	respData["alias"] = map[string]interface{}{}
+	if group.Alias != nil {
+		respData["alias"] = groupAliasResponseData(group.Alias)
+	}
 
 	memberGroupIDs, err := i.memberGroupIDsByID(group.ID)
 	if err != nil {
@@ -247,11 +297,31 @@ func (i *IdentityStore) pathGroupIDDelete(req *logical.Request, d *framework.Fie
 	if groupID == "" {
 		return logical.ErrorResponse("empty group ID"), nil
 	}
+
+	namespaceID, err := i.requestNamespaceID(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Groups of other namespaces are left untouched
+	group, err := i.memDBGroupByID(groupID, false)
+	if err != nil {
+		return nil, err
+	}
+	if group == nil || group.NamespaceID != namespaceID {
+		return nil, nil
+	}
+
 	return nil, i.deleteGroupByID(groupID)
 }
 
 // pathGroupIDList lists the IDs of all the groups in the identity store
 func (i *IdentityStore) pathGroupIDList(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	namespaceID, err := i.requestNamespaceID(req)
+	if err != nil {
+		return nil, err
+	}
+
 	ws := memdb.NewWatchSet()
 	iter, err := i.memDBGroupIterator(ws)
 	if err != nil {
@@ -264,7 +334,11 @@ func (i *IdentityStore) pathGroupIDList(req *logical.Request, d *framework.Field
 		if raw == nil {
 			break
 		}
-		groupIDs = append(groupIDs, raw.(*identity.Group).ID)
+		group := raw.(*identity.Group)
+		if group.NamespaceID != namespaceID {
+			continue
+		}
+		groupIDs = append(groupIDs, group.ID)
 	}
 
 	return logical.ListResponse(groupIDs), nil
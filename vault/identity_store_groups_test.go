@@ -56,7 +56,7 @@ func TestIdentityStore_MemDBGroupIndexes(t *testing.T) {
 	var fetchedGroup *identity.Group
 
 	// Fetch group given the name
-	fetchedGroup, err = i.memDBGroupByName("testgroupname", false)
+	fetchedGroup, err = i.memDBGroupByName("", "testgroupname", false)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -209,6 +209,8 @@ func TestIdentityStore_GroupsCreateUpdate(t *testing.T) {
 	expectedData["creation_time"] = resp.Data["creation_time"]
 	expectedData["last_update_time"] = resp.Data["last_update_time"]
 	expectedData["modify_index"] = resp.Data["modify_index"]
+	expectedData["type"] = "internal"
+	expectedData["alias"] = map[string]interface{}{}
 
 	if !reflect.DeepEqual(expectedData, resp.Data) {
 		t.Fatalf("bad: group data;\nexpected: %#v\n actual: %#v\n", expectedData, resp.Data)
@@ -327,6 +329,8 @@ func TestIdentityStore_GroupsCRUD_ByID(t *testing.T) {
 	expectedData["creation_time"] = resp.Data["creation_time"]
 	expectedData["last_update_time"] = resp.Data["last_update_time"]
 	expectedData["modify_index"] = resp.Data["modify_index"]
+	expectedData["type"] = "internal"
+	expectedData["alias"] = map[string]interface{}{}
 
 	if !reflect.DeepEqual(expectedData, resp.Data) {
 		t.Fatalf("bad: group data;\nexpected: %#v\n actual: %#v\n", expectedData, resp.Data)
@@ -0,0 +1,1190 @@
+package vault
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"image/png"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/duosecurity/duo_api_golang"
+	"github.com/duosecurity/duo_api_golang/authapi"
+	"github.com/hashicorp/go-cleanhttp"
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/vault/helper/identity"
+	"github.com/hashicorp/vault/helper/locksutil"
+	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+	"github.com/pquerna/otp"
+	totplib "github.com/pquerna/otp/totp"
+)
+
+const (
+	// MFAHeaderName is the name of the header carrying the MFA credentials of
+	// a login request. Each value is of the form "<method name>[:<passcode>]".
+	MFAHeaderName = "X-Vault-MFA"
+
+	// Storage prefixes of the MFA methods, the MFA enforcements and the TOTP
+	// secrets of the entities, the latter being keyed by method name and
+	// entity ID
+	mfaMethodPrefix      = "mfa/method/"
+	mfaEnforcementPrefix = "mfa/enforcement/"
+	mfaTOTPSecretPrefix  = "mfa/totp-secret/"
+
+	mfaMethodTypeTOTP    = "totp"
+	mfaMethodTypeDuo     = "duo"
+	mfaMethodTypeWebhook = "webhook"
+
+	// mfaTOTPMaxFailedValidations is the number of invalid TOTP passcodes an
+	// entity may give for a method before it is locked out of the method
+	// until the passcodes it tried have expired
+	mfaTOTPMaxFailedValidations = 5
+)
+
+// mfaMethod is the configuration of an MFA method. Only the fields of the
+// method's type are set.
+type mfaMethod struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+
+	// TOTP
+	Issuer    string `json:"issuer,omitempty"`
+	Period    int    `json:"period,omitempty"`
+	Digits    int    `json:"digits,omitempty"`
+	Algorithm string `json:"algorithm,omitempty"`
+	KeySize   int    `json:"key_size,omitempty"`
+	Skew      int    `json:"skew,omitempty"`
+	QRSize    int    `json:"qr_size,omitempty"`
+
+	// Duo
+	IntegrationKey string `json:"integration_key,omitempty"`
+	SecretKey      string `json:"secret_key,omitempty"`
+	APIHostname    string `json:"api_hostname,omitempty"`
+	UsernameFormat string `json:"username_format,omitempty"`
+	PushInfo       string `json:"push_info,omitempty"`
+
+	// Webhook
+	URL     string `json:"url,omitempty"`
+	Secret  string `json:"secret,omitempty"`
+	Timeout int    `json:"timeout,omitempty"`
+}
+
+// mfaEnforcement requires logins matching any of its targets to be verified
+// by one of its methods
+type mfaEnforcement struct {
+	Name                string   `json:"name"`
+	MethodNames         []string `json:"mfa_method_names"`
+	AuthMethodAccessors []string `json:"auth_method_accessors"`
+	IdentityEntityIDs   []string `json:"identity_entity_ids"`
+	IdentityGroupIDs    []string `json:"identity_group_ids"`
+}
+
+// mfaTOTPSecret is the TOTP secret an entity was enrolled with
+type mfaTOTPSecret struct {
+	Secret string `json:"secret"`
+}
+
+func mfaPaths(i *IdentityStore) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "mfa/method/totp/" + framework.GenericNameRegex("name") + "$",
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of the MFA method.",
+				},
+				"issuer": {
+					Type:        framework.TypeString,
+					Description: "Name of the issuer shown in the authenticator apps. Defaults to 'Vault'.",
+				},
+				"period": {
+					Type:        framework.TypeDurationSecond,
+					Default:     30,
+					Description: "Length of time, in seconds, a passcode is valid for. Defaults to 30 seconds.",
+				},
+				"digits": {
+					Type:        framework.TypeInt,
+					Default:     6,
+					Description: "Number of digits of the passcodes, 6 or 8. Defaults to 6.",
+				},
+				"algorithm": {
+					Type:        framework.TypeString,
+					Default:     "SHA1",
+					Description: "Hashing algorithm of the passcodes, 'SHA1', 'SHA256' or 'SHA512'. Defaults to 'SHA1'.",
+				},
+				"key_size": {
+					Type:        framework.TypeInt,
+					Default:     20,
+					Description: "Size, in bytes, of the generated secrets. Defaults to 20.",
+				},
+				"skew": {
+					Type:        framework.TypeInt,
+					Default:     1,
+					Description: "Number of periods before and after the current one during which passcodes are accepted, 0 or 1. Defaults to 1.",
+				},
+				"qr_size": {
+					Type:        framework.TypeInt,
+					Default:     200,
+					Description: "Pixel size of the QR code returned on enrollment; 0 disables it. Defaults to 200.",
+				},
+			},
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: i.checkPremiumVersion(i.pathMFAMethodWrite(mfaMethodTypeTOTP)),
+				logical.ReadOperation:   i.checkPremiumVersion(i.pathMFAMethodRead(mfaMethodTypeTOTP)),
+				logical.DeleteOperation: i.checkPremiumVersion(i.pathMFAMethodDelete(mfaMethodTypeTOTP)),
+			},
+
+			HelpSynopsis:    strings.TrimSpace(mfaHelp["totp"][0]),
+			HelpDescription: strings.TrimSpace(mfaHelp["totp"][1]),
+		},
+		{
+			Pattern: "mfa/method/totp/" + framework.GenericNameRegex("name") + "/admin-generate$",
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of the MFA method.",
+				},
+				"entity_id": {
+					Type:        framework.TypeString,
+					Description: "ID of the entity to generate a TOTP secret for.",
+				},
+			},
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: i.checkPremiumVersion(i.pathMFATOTPAdminGenerate),
+			},
+
+			HelpSynopsis:    strings.TrimSpace(mfaHelp["totp-admin-generate"][0]),
+			HelpDescription: strings.TrimSpace(mfaHelp["totp-admin-generate"][1]),
+		},
+		{
+			Pattern: "mfa/method/totp/" + framework.GenericNameRegex("name") + "/admin-destroy$",
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of the MFA method.",
+				},
+				"entity_id": {
+					Type:        framework.TypeString,
+					Description: "ID of the entity to remove the TOTP secret of.",
+				},
+			},
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: i.checkPremiumVersion(i.pathMFATOTPAdminDestroy),
+			},
+
+			HelpSynopsis:    strings.TrimSpace(mfaHelp["totp-admin-destroy"][0]),
+			HelpDescription: strings.TrimSpace(mfaHelp["totp-admin-destroy"][1]),
+		},
+		{
+			Pattern: "mfa/method/duo/" + framework.GenericNameRegex("name") + "$",
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of the MFA method.",
+				},
+				"integration_key": {
+					Type:        framework.TypeString,
+					Description: "Integration key of the Duo application.",
+				},
+				"secret_key": {
+					Type:        framework.TypeString,
+					Description: "Secret key of the Duo application.",
+				},
+				"api_hostname": {
+					Type:        framework.TypeString,
+					Description: "API hostname of the Duo application.",
+				},
+				"username_format": {
+					Type:        framework.TypeString,
+					Default:     "{{alias.name}}",
+					Description: "Format of the Duo username; '{{alias.name}}' and '{{entity.name}}' are replaced by the name of the login's alias and entity. Defaults to '{{alias.name}}'.",
+				},
+				"push_info": {
+					Type:        framework.TypeString,
+					Description: "URL-encoded key/value pairs shown in the Duo push notifications.",
+				},
+			},
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: i.checkPremiumVersion(i.pathMFAMethodWrite(mfaMethodTypeDuo)),
+				logical.ReadOperation:   i.checkPremiumVersion(i.pathMFAMethodRead(mfaMethodTypeDuo)),
+				logical.DeleteOperation: i.checkPremiumVersion(i.pathMFAMethodDelete(mfaMethodTypeDuo)),
+			},
+
+			HelpSynopsis:    strings.TrimSpace(mfaHelp["duo"][0]),
+			HelpDescription: strings.TrimSpace(mfaHelp["duo"][1]),
+		},
+		{
+			Pattern: "mfa/method/webhook/" + framework.GenericNameRegex("name") + "$",
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of the MFA method.",
+				},
+				"url": {
+					Type:        framework.TypeString,
+					Description: "URL the login details are posted to.",
+				},
+				"secret": {
+					Type:        framework.TypeString,
+					Description: "Secret used to sign the posted login details; the hex-encoded HMAC-SHA256 of the body is sent in the X-Vault-MFA-Signature header.",
+				},
+				"timeout": {
+					Type:        framework.TypeDurationSecond,
+					Default:     10,
+					Description: "Timeout of the request to the URL. Defaults to 10 seconds.",
+				},
+			},
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: i.checkPremiumVersion(i.pathMFAMethodWrite(mfaMethodTypeWebhook)),
+				logical.ReadOperation:   i.checkPremiumVersion(i.pathMFAMethodRead(mfaMethodTypeWebhook)),
+				logical.DeleteOperation: i.checkPremiumVersion(i.pathMFAMethodDelete(mfaMethodTypeWebhook)),
+			},
+
+			HelpSynopsis:    strings.TrimSpace(mfaHelp["webhook"][0]),
+			HelpDescription: strings.TrimSpace(mfaHelp["webhook"][1]),
+		},
+		{
+			Pattern: "mfa/method/?$",
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ListOperation: i.checkPremiumVersion(i.pathMFAMethodList),
+			},
+
+			HelpSynopsis:    strings.TrimSpace(mfaHelp["method-list"][0]),
+			HelpDescription: strings.TrimSpace(mfaHelp["method-list"][1]),
+		},
+		{
+			Pattern: "mfa/enforcement/" + framework.GenericNameRegex("name") + "$",
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of the MFA enforcement.",
+				},
+				"mfa_method_names": {
+					Type:        framework.TypeCommaStringSlice,
+					Description: "Names of the MFA methods; logins must be verified by one of them.",
+				},
+				"auth_method_accessors": {
+					Type:        framework.TypeCommaStringSlice,
+					Description: "Accessors of the auth mounts whose logins require MFA.",
+				},
+				"identity_entity_ids": {
+					Type:        framework.TypeCommaStringSlice,
+					Description: "IDs of the entities whose logins require MFA.",
+				},
+				"identity_group_ids": {
+					Type:        framework.TypeCommaStringSlice,
+					Description: "IDs of the groups whose member entities require MFA on login.",
+				},
+			},
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: i.checkPremiumVersion(i.pathMFAEnforcementWrite),
+				logical.ReadOperation:   i.checkPremiumVersion(i.pathMFAEnforcementRead),
+				logical.DeleteOperation: i.checkPremiumVersion(i.pathMFAEnforcementDelete),
+			},
+
+			HelpSynopsis:    strings.TrimSpace(mfaHelp["enforcement"][0]),
+			HelpDescription: strings.TrimSpace(mfaHelp["enforcement"][1]),
+		},
+		{
+			Pattern: "mfa/enforcement/?$",
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ListOperation: i.checkPremiumVersion(i.pathMFAEnforcementList),
+			},
+
+			HelpSynopsis:    strings.TrimSpace(mfaHelp["enforcement-list"][0]),
+			HelpDescription: strings.TrimSpace(mfaHelp["enforcement-list"][1]),
+		},
+	}
+}
+
+func (i *IdentityStore) mfaMethodByName(name string) (*mfaMethod, error) {
+	entry, err := i.view.Get(mfaMethodPrefix + name)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var method mfaMethod
+	if err := entry.DecodeJSON(&method); err != nil {
+		return nil, err
+	}
+	return &method, nil
+}
+
+func (i *IdentityStore) mfaEnforcementByName(name string) (*mfaEnforcement, error) {
+	entry, err := i.view.Get(mfaEnforcementPrefix + name)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var enforcement mfaEnforcement
+	if err := entry.DecodeJSON(&enforcement); err != nil {
+		return nil, err
+	}
+	return &enforcement, nil
+}
+
+func (i *IdentityStore) mfaEnforcements() ([]*mfaEnforcement, error) {
+	names, err := i.view.List(mfaEnforcementPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var enforcements []*mfaEnforcement
+	for _, name := range names {
+		enforcement, err := i.mfaEnforcementByName(name)
+		if err != nil {
+			return nil, err
+		}
+		if enforcement != nil {
+			enforcements = append(enforcements, enforcement)
+		}
+	}
+	return enforcements, nil
+}
+
+func (i *IdentityStore) mfaTOTPSecret(methodName, entityID string) (*mfaTOTPSecret, error) {
+	entry, err := i.view.Get(mfaTOTPSecretPrefix + methodName + "/" + entityID)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var secret mfaTOTPSecret
+	if err := entry.DecodeJSON(&secret); err != nil {
+		return nil, err
+	}
+	return &secret, nil
+}
+
+func (i *IdentityStore) pathMFAMethodWrite(methodType string) framework.OperationFunc {
+	return func(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		name := d.Get("name").(string)
+		if name == "" {
+			return logical.ErrorResponse("missing method name"), nil
+		}
+
+		method, err := i.mfaMethodByName(name)
+		if err != nil {
+			return nil, err
+		}
+		newMethod := method == nil
+		if newMethod {
+			method = &mfaMethod{
+				Name: name,
+				Type: methodType,
+			}
+		}
+		if method.Type != methodType {
+			return logical.ErrorResponse(fmt.Sprintf("method %q is of type %q", name, method.Type)), nil
+		}
+
+		// Fields which are not given keep their current value, or take their
+		// default value if the method is being created
+		getField := func(key string) interface{} {
+			if raw, ok := d.GetOk(key); ok {
+				return raw
+			}
+			if newMethod {
+				return d.Get(key)
+			}
+			return nil
+		}
+
+		switch methodType {
+		case mfaMethodTypeTOTP:
+			if v := getField("issuer"); v != nil {
+				method.Issuer = v.(string)
+			}
+			if method.Issuer == "" {
+				method.Issuer = "Vault"
+			}
+			if v := getField("period"); v != nil {
+				method.Period = v.(int)
+			}
+			if v := getField("digits"); v != nil {
+				method.Digits = v.(int)
+			}
+			if v := getField("algorithm"); v != nil {
+				method.Algorithm = strings.ToUpper(v.(string))
+			}
+			if v := getField("key_size"); v != nil {
+				method.KeySize = v.(int)
+			}
+			if v := getField("skew"); v != nil {
+				method.Skew = v.(int)
+			}
+			if v := getField("qr_size"); v != nil {
+				method.QRSize = v.(int)
+			}
+
+			switch {
+			case method.Period <= 0:
+				return logical.ErrorResponse("period must be positive"), nil
+			case method.Digits != 6 && method.Digits != 8:
+				return logical.ErrorResponse("digits must be 6 or 8"), nil
+			case method.KeySize <= 0:
+				return logical.ErrorResponse("key_size must be positive"), nil
+			case method.Skew != 0 && method.Skew != 1:
+				return logical.ErrorResponse("skew must be 0 or 1"), nil
+			case method.QRSize < 0:
+				return logical.ErrorResponse("qr_size cannot be negative"), nil
+			}
+			if _, err := totpAlgorithm(method.Algorithm); err != nil {
+				return logical.ErrorResponse(err.Error()), nil
+			}
+
+		case mfaMethodTypeDuo:
+			for key, field := range map[string]*string{
+				"integration_key": &method.IntegrationKey,
+				"secret_key":      &method.SecretKey,
+				"api_hostname":    &method.APIHostname,
+				"username_format": &method.UsernameFormat,
+				"push_info":       &method.PushInfo,
+			} {
+				if v := getField(key); v != nil {
+					*field = v.(string)
+				}
+			}
+
+			switch {
+			case method.IntegrationKey == "":
+				return logical.ErrorResponse("missing integration_key"), nil
+			case method.SecretKey == "":
+				return logical.ErrorResponse("missing secret_key"), nil
+			case method.APIHostname == "":
+				return logical.ErrorResponse("missing api_hostname"), nil
+			case method.UsernameFormat == "":
+				return logical.ErrorResponse("missing username_format"), nil
+			}
+
+		case mfaMethodTypeWebhook:
+			if v := getField("url"); v != nil {
+				method.URL = v.(string)
+			}
+			if v := getField("secret"); v != nil {
+				method.Secret = v.(string)
+			}
+			if v := getField("timeout"); v != nil {
+				method.Timeout = v.(int)
+			}
+
+			u, err := url.Parse(method.URL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return logical.ErrorResponse("url must be an absolute http or https URL"), nil
+			}
+			if method.Timeout <= 0 {
+				return logical.ErrorResponse("timeout must be positive"), nil
+			}
+		}
+
+		entry, err := logical.StorageEntryJSON(mfaMethodPrefix+name, method)
+		if err != nil {
+			return nil, err
+		}
+		return nil, i.view.Put(entry)
+	}
+}
+
+func (i *IdentityStore) pathMFAMethodRead(methodType string) framework.OperationFunc {
+	return func(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		method, err := i.mfaMethodByName(d.Get("name").(string))
+		if err != nil {
+			return nil, err
+		}
+		if method == nil || method.Type != methodType {
+			return nil, nil
+		}
+
+		respData := map[string]interface{}{
+			"name": method.Name,
+			"type": method.Type,
+		}
+		switch method.Type {
+		case mfaMethodTypeTOTP:
+			respData["issuer"] = method.Issuer
+			respData["period"] = method.Period
+			respData["digits"] = method.Digits
+			respData["algorithm"] = method.Algorithm
+			respData["key_size"] = method.KeySize
+			respData["skew"] = method.Skew
+			respData["qr_size"] = method.QRSize
+		case mfaMethodTypeDuo:
+			// The secret key is not returned
+			respData["integration_key"] = method.IntegrationKey
+			respData["api_hostname"] = method.APIHostname
+			respData["username_format"] = method.UsernameFormat
+			respData["push_info"] = method.PushInfo
+		case mfaMethodTypeWebhook:
+			// The secret is not returned
+			respData["url"] = method.URL
+			respData["timeout"] = method.Timeout
+		}
+
+		return &logical.Response{
+			Data: respData,
+		}, nil
+	}
+}
+
+func (i *IdentityStore) pathMFAMethodDelete(methodType string) framework.OperationFunc {
+	return func(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		name := d.Get("name").(string)
+
+		method, err := i.mfaMethodByName(name)
+		if err != nil {
+			return nil, err
+		}
+		if method == nil {
+			return nil, nil
+		}
+		if method.Type != methodType {
+			return logical.ErrorResponse(fmt.Sprintf("method %q is of type %q", name, method.Type)), nil
+		}
+
+		// Methods can't be deleted while enforcements refer to them, as that
+		// would silently loosen the enforcements
+		enforcements, err := i.mfaEnforcements()
+		if err != nil {
+			return nil, err
+		}
+		for _, enforcement := range enforcements {
+			if strutil.StrListContains(enforcement.MethodNames, name) {
+				return logical.ErrorResponse(fmt.Sprintf("method %q is used by the enforcement %q", name, enforcement.Name)), nil
+			}
+		}
+
+		// Delete the secrets the entities were enrolled with
+		entityIDs, err := i.view.List(mfaTOTPSecretPrefix + name + "/")
+		if err != nil {
+			return nil, err
+		}
+		for _, entityID := range entityIDs {
+			if err := i.view.Delete(mfaTOTPSecretPrefix + name + "/" + entityID); err != nil {
+				return nil, err
+			}
+		}
+
+		return nil, i.view.Delete(mfaMethodPrefix + name)
+	}
+}
+
+func (i *IdentityStore) pathMFAMethodList(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	names, err := i.view.List(mfaMethodPrefix)
+	if err != nil {
+		return nil, err
+	}
+	return logical.ListResponse(names), nil
+}
+
+// pathMFATOTPAdminGenerate enrolls an entity in a TOTP method, returning the
+// key to load into an authenticator app
+func (i *IdentityStore) pathMFATOTPAdminGenerate(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	method, err := i.mfaMethodByName(d.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if method == nil || method.Type != mfaMethodTypeTOTP {
+		return logical.ErrorResponse("invalid TOTP method name"), nil
+	}
+
+	entityID := d.Get("entity_id").(string)
+	if entityID == "" {
+		return logical.ErrorResponse("missing entity_id"), nil
+	}
+
+	lock := locksutil.LockForKey(i.entityLocks, entityID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	entity, err := i.memDBEntityByID(entityID, false)
+	if err != nil {
+		return nil, err
+	}
+	if entity == nil {
+		return logical.ErrorResponse("invalid entity_id"), nil
+	}
+
+	existing, err := i.mfaTOTPSecret(method.Name, entity.ID)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return logical.ErrorResponse("the entity is already enrolled; destroy its secret before generating a new one"), nil
+	}
+
+	algorithm, err := totpAlgorithm(method.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	accountName := entity.Name
+	if accountName == "" {
+		accountName = entity.ID
+	}
+
+	key, err := totplib.Generate(totplib.GenerateOpts{
+		Issuer:      method.Issuer,
+		AccountName: accountName,
+		Period:      uint(method.Period),
+		SecretSize:  uint(method.KeySize),
+		Digits:      otp.Digits(method.Digits),
+		Algorithm:   algorithm,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := logical.StorageEntryJSON(mfaTOTPSecretPrefix+method.Name+"/"+entity.ID, &mfaTOTPSecret{
+		Secret: key.Secret(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := i.view.Put(entry); err != nil {
+		return nil, err
+	}
+
+	respData := map[string]interface{}{
+		"url": key.String(),
+	}
+	if method.QRSize > 0 {
+		barcode, err := key.Image(method.QRSize, method.QRSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate the QR code: %v", err)
+		}
+
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, barcode); err != nil {
+			return nil, err
+		}
+		respData["barcode"] = base64.StdEncoding.EncodeToString(buf.Bytes())
+	}
+
+	return &logical.Response{
+		Data: respData,
+	}, nil
+}
+
+func (i *IdentityStore) pathMFATOTPAdminDestroy(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	method, err := i.mfaMethodByName(d.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if method == nil || method.Type != mfaMethodTypeTOTP {
+		return logical.ErrorResponse("invalid TOTP method name"), nil
+	}
+
+	entityID := d.Get("entity_id").(string)
+	if entityID == "" {
+		return logical.ErrorResponse("missing entity_id"), nil
+	}
+
+	return nil, i.view.Delete(mfaTOTPSecretPrefix + method.Name + "/" + entityID)
+}
+
+// deleteEntityMFASecrets deletes the TOTP secrets of an entity. The caller
+// must hold the entity's lock.
+func (i *IdentityStore) deleteEntityMFASecrets(entityID string) error {
+	// The listed method names carry a trailing slash
+	methodNames, err := i.view.List(mfaTOTPSecretPrefix)
+	if err != nil {
+		return err
+	}
+	for _, methodName := range methodNames {
+		if err := i.view.Delete(mfaTOTPSecretPrefix + methodName + entityID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeEntityMFASecrets moves the TOTP secrets of the merged entity into the
+// entity it was merged into, unless the latter is already enrolled in the
+// same method. The caller must hold the locks of both entities.
+func (i *IdentityStore) mergeEntityMFASecrets(toEntityID, fromEntityID string) error {
+	methodNames, err := i.view.List(mfaTOTPSecretPrefix)
+	if err != nil {
+		return err
+	}
+	for _, methodName := range methodNames {
+		fromKey := mfaTOTPSecretPrefix + methodName + fromEntityID
+		toKey := mfaTOTPSecretPrefix + methodName + toEntityID
+
+		fromEntry, err := i.view.Get(fromKey)
+		if err != nil {
+			return err
+		}
+		if fromEntry == nil {
+			continue
+		}
+
+		toEntry, err := i.view.Get(toKey)
+		if err != nil {
+			return err
+		}
+		if toEntry == nil {
+			fromEntry.Key = toKey
+			if err := i.view.Put(fromEntry); err != nil {
+				return err
+			}
+		}
+
+		if err := i.view.Delete(fromKey); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (i *IdentityStore) pathMFAEnforcementWrite(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+	if name == "" {
+		return logical.ErrorResponse("missing enforcement name"), nil
+	}
+
+	enforcement := &mfaEnforcement{
+		Name:                name,
+		MethodNames:         strutil.RemoveDuplicates(d.Get("mfa_method_names").([]string), false),
+		AuthMethodAccessors: strutil.RemoveDuplicates(d.Get("auth_method_accessors").([]string), false),
+		IdentityEntityIDs:   strutil.RemoveDuplicates(d.Get("identity_entity_ids").([]string), false),
+		IdentityGroupIDs:    strutil.RemoveDuplicates(d.Get("identity_group_ids").([]string), false),
+	}
+
+	if len(enforcement.MethodNames) == 0 {
+		return logical.ErrorResponse("missing mfa_method_names"), nil
+	}
+	for _, methodName := range enforcement.MethodNames {
+		method, err := i.mfaMethodByName(methodName)
+		if err != nil {
+			return nil, err
+		}
+		if method == nil {
+			return logical.ErrorResponse(fmt.Sprintf("invalid MFA method %q", methodName)), nil
+		}
+	}
+
+	if len(enforcement.AuthMethodAccessors) == 0 && len(enforcement.IdentityEntityIDs) == 0 && len(enforcement.IdentityGroupIDs) == 0 {
+		return logical.ErrorResponse("at least one of auth_method_accessors, identity_entity_ids or identity_group_ids must be set"), nil
+	}
+	for _, accessor := range enforcement.AuthMethodAccessors {
+		if i.validateMountAccessorFunc(accessor) == nil {
+			return logical.ErrorResponse(fmt.Sprintf("invalid mount accessor %q", accessor)), nil
+		}
+	}
+	for _, entityID := range enforcement.IdentityEntityIDs {
+		if err := i.validateEntityID(entityID); err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+	}
+	for _, groupID := range enforcement.IdentityGroupIDs {
+		if err := i.validateGroupID(groupID); err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+	}
+
+	entry, err := logical.StorageEntryJSON(mfaEnforcementPrefix+name, enforcement)
+	if err != nil {
+		return nil, err
+	}
+	return nil, i.view.Put(entry)
+}
+
+func (i *IdentityStore) pathMFAEnforcementRead(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	enforcement, err := i.mfaEnforcementByName(d.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if enforcement == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"name":                  enforcement.Name,
+			"mfa_method_names":      enforcement.MethodNames,
+			"auth_method_accessors": enforcement.AuthMethodAccessors,
+			"identity_entity_ids":   enforcement.IdentityEntityIDs,
+			"identity_group_ids":    enforcement.IdentityGroupIDs,
+		},
+	}, nil
+}
+
+func (i *IdentityStore) pathMFAEnforcementDelete(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	return nil, i.view.Delete(mfaEnforcementPrefix + d.Get("name").(string))
+}
+
+func (i *IdentityStore) pathMFAEnforcementList(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	names, err := i.view.List(mfaEnforcementPrefix)
+	if err != nil {
+		return nil, err
+	}
+	return logical.ListResponse(names), nil
+}
+
+// enforceLoginMFA checks that a login satisfies the MFA enforcements matching
+// its mount, entity or the groups of its entity. Every matching enforcement
+// must be satisfied by one of its methods, using the credentials given in the
+// MFA header of the request. The methods validated are returned, or an error
+// response if the login is to be rejected.
+func (i *IdentityStore) enforceLoginMFA(req *logical.Request, auth *logical.Auth) ([]string, *logical.Response, error) {
+	enforcements, err := i.mfaEnforcements()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(enforcements) == 0 {
+		return nil, nil, nil
+	}
+
+	var entity *identity.Entity
+	var groupIDs []string
+	if auth.EntityID != "" {
+		entity, err = i.memDBEntityByID(auth.EntityID, false)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		groups, err := i.transitiveGroupsByEntityID(auth.EntityID)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, group := range groups {
+			groupIDs = append(groupIDs, group.ID)
+		}
+	}
+
+	// Credentials are given as "<method name>[:<passcode>]"
+	credentials := make(map[string]string)
+	for _, value := range http.Header(req.Headers)[http.CanonicalHeaderKey(MFAHeaderName)] {
+		methodName, passcode := value, ""
+		if idx := strings.Index(value, ":"); idx >= 0 {
+			methodName, passcode = value[:idx], value[idx+1:]
+		}
+		credentials[strings.TrimSpace(methodName)] = strings.TrimSpace(passcode)
+	}
+
+	// Methods shared by several enforcements are only validated once, so
+	// that users are not prompted more than once
+	validated := make(map[string]error)
+	validate := func(methodName string) error {
+		if err, ok := validated[methodName]; ok {
+			return err
+		}
+
+		passcode, ok := credentials[methodName]
+		if !ok {
+			return fmt.Errorf("no credentials given for method %q", methodName)
+		}
+
+		method, err := i.mfaMethodByName(methodName)
+		switch {
+		case err != nil:
+			return err
+		case method == nil:
+			err = fmt.Errorf("method %q does not exist", methodName)
+		default:
+			err = i.validateMFA(method, req, auth, entity, passcode)
+		}
+		validated[methodName] = err
+		return err
+	}
+
+	for _, enforcement := range enforcements {
+		if !mfaEnforcementMatches(enforcement, req.MountAccessor, auth.EntityID, groupIDs) {
+			continue
+		}
+
+		var retErr *multierror.Error
+		satisfied := false
+		for _, methodName := range enforcement.MethodNames {
+			err := validate(methodName)
+			if err == nil {
+				satisfied = true
+				break
+			}
+			retErr = multierror.Append(retErr, err)
+		}
+
+		if !satisfied {
+			i.logger.Debug("identity: login rejected by MFA enforcement", "enforcement", enforcement.Name, "entity_id", auth.EntityID, "error", retErr)
+			return nil, logical.ErrorResponse(fmt.Sprintf("MFA required by %q with one of the methods %q: %v", enforcement.Name, enforcement.MethodNames, retErr)), nil
+		}
+	}
+
+	var methods []string
+	for methodName, err := range validated {
+		if err == nil {
+			methods = append(methods, methodName)
+		}
+	}
+	sort.Strings(methods)
+	return methods, nil, nil
+}
+
+func mfaEnforcementMatches(enforcement *mfaEnforcement, mountAccessor, entityID string, groupIDs []string) bool {
+	if strutil.StrListContains(enforcement.AuthMethodAccessors, mountAccessor) {
+		return true
+	}
+	if entityID != "" && strutil.StrListContains(enforcement.IdentityEntityIDs, entityID) {
+		return true
+	}
+	for _, groupID := range groupIDs {
+		if strutil.StrListContains(enforcement.IdentityGroupIDs, groupID) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateMFA verifies the passcode of a login using the method
+func (i *IdentityStore) validateMFA(method *mfaMethod, req *logical.Request, auth *logical.Auth, entity *identity.Entity, passcode string) error {
+	switch method.Type {
+	case mfaMethodTypeTOTP:
+		return i.validateTOTP(method, entity, passcode)
+	case mfaMethodTypeDuo:
+		return validateDuo(method, req, auth, entity, passcode)
+	case mfaMethodTypeWebhook:
+		return validateWebhook(method, req, auth, passcode)
+	default:
+		return fmt.Errorf("unsupported method type %q", method.Type)
+	}
+}
+
+// validateTOTP verifies a TOTP passcode of the entity. Passcodes can't be
+// used twice, and entities giving too many invalid passcodes are rejected
+// until the passcodes could have expired.
+func (i *IdentityStore) validateTOTP(method *mfaMethod, entity *identity.Entity, passcode string) error {
+	if entity == nil {
+		return fmt.Errorf("TOTP method %q requires the login to have an entity", method.Name)
+	}
+	if passcode == "" {
+		return fmt.Errorf("missing passcode for method %q", method.Name)
+	}
+
+	secret, err := i.mfaTOTPSecret(method.Name, entity.ID)
+	if err != nil {
+		return err
+	}
+	if secret == nil {
+		return fmt.Errorf("entity is not enrolled in method %q", method.Name)
+	}
+
+	algorithm, err := totpAlgorithm(method.Algorithm)
+	if err != nil {
+		return err
+	}
+
+	key := method.Name + "/" + entity.ID
+	if failures, ok := i.mfaFailedValidations.Get(key); ok && failures.(int) >= mfaTOTPMaxFailedValidations {
+		return fmt.Errorf("maximum validation attempts exceeded for method %q; wait until the next time period", method.Name)
+	}
+
+	// Passcodes are valid for the period, as well as the skew on both sides
+	validity := time.Duration(method.Period*(2+2*method.Skew)) * time.Second
+
+	valid, err := totplib.ValidateCustom(passcode, secret.Secret, time.Now().UTC(), totplib.ValidateOpts{
+		Period:    uint(method.Period),
+		Skew:      uint(method.Skew),
+		Digits:    otp.Digits(method.Digits),
+		Algorithm: algorithm,
+	})
+	if err != nil || !valid {
+		if err := i.mfaFailedValidations.Add(key, 1, validity); err != nil {
+			i.mfaFailedValidations.IncrementInt(key, 1)
+		}
+		return fmt.Errorf("invalid passcode for method %q", method.Name)
+	}
+
+	// Adding fails if the passcode is already cached, which also rejects
+	// concurrent logins with the same passcode
+	if err := i.mfaUsedCodes.Add(key+"/"+passcode, nil, validity); err != nil {
+		return fmt.Errorf("passcode already used for method %q; wait until the next time period", method.Name)
+	}
+	i.mfaFailedValidations.Delete(key)
+	return nil
+}
+
+// validateDuo sends a push to the Duo user of the login, or verifies the
+// passcode if one is given
+func validateDuo(method *mfaMethod, req *logical.Request, auth *logical.Auth, entity *identity.Entity, passcode string) error {
+	username := method.UsernameFormat
+	if auth.Alias != nil {
+		username = strings.Replace(username, "{{alias.name}}", auth.Alias.Name, -1)
+	}
+	if entity != nil {
+		username = strings.Replace(username, "{{entity.name}}", entity.Name, -1)
+	}
+	if username == "" || strings.Contains(username, "{{") {
+		return fmt.Errorf("unable to determine the Duo username for method %q", method.Name)
+	}
+
+	var remoteAddr string
+	if req.Connection != nil {
+		remoteAddr = req.Connection.RemoteAddr
+	}
+
+	duoClient := duoapi.NewDuoApi(method.IntegrationKey, method.SecretKey, method.APIHostname, "vault")
+	authClient := authapi.NewAuthApi(*duoClient)
+
+	preauth, err := authClient.Preauth(authapi.PreauthUsername(username), authapi.PreauthIpAddr(remoteAddr))
+	if err != nil || preauth == nil {
+		return fmt.Errorf("failed to call Duo preauth for method %q", method.Name)
+	}
+	if preauth.StatResult.Stat != "OK" {
+		return fmt.Errorf("failed to look up the Duo user for method %q", method.Name)
+	}
+
+	switch preauth.Response.Result {
+	case "allow":
+		return nil
+	case "auth":
+	default:
+		return fmt.Errorf("Duo denied the login for method %q: %s", method.Name, preauth.Response.Status_Msg)
+	}
+
+	factor := "push"
+	options := []func(*url.Values){authapi.AuthUsername(username)}
+	if passcode != "" {
+		factor = "passcode"
+		options = append(options, authapi.AuthPasscode(passcode))
+	} else {
+		options = append(options, authapi.AuthDevice("auto"))
+		if method.PushInfo != "" {
+			options = append(options, authapi.AuthPushinfo(method.PushInfo))
+		}
+	}
+
+	result, err := authClient.Auth(factor, options...)
+	if err != nil || result == nil {
+		return fmt.Errorf("failed to call Duo auth for method %q", method.Name)
+	}
+	if result.StatResult.Stat != "OK" || result.Response.Result != "allow" {
+		return fmt.Errorf("Duo denied the login for method %q: %s", method.Name, result.Response.Status_Msg)
+	}
+	return nil
+}
+
+// validateWebhook posts the details of the login to the URL of the method,
+// which accepts the login by responding with a 2xx status code
+func validateWebhook(method *mfaMethod, req *logical.Request, auth *logical.Auth, passcode string) error {
+	payload := map[string]interface{}{
+		"method_name":    method.Name,
+		"path":           req.Path,
+		"mount_accessor": req.MountAccessor,
+		"mount_type":     req.MountType,
+		"entity_id":      auth.EntityID,
+		"passcode":       passcode,
+	}
+	if auth.Alias != nil {
+		payload["alias_name"] = auth.Alias.Name
+	}
+	if req.Connection != nil {
+		payload["remote_addr"] = req.Connection.RemoteAddr
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	httpReq, err := http.NewRequest("POST", method.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if method.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(method.Secret))
+		mac.Write(body)
+		httpReq.Header.Set("X-Vault-MFA-Signature", hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	client := cleanhttp.DefaultClient()
+	client.Timeout = time.Duration(method.Timeout) * time.Second
+
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to call the webhook of method %q: %v", method.Name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook of method %q denied the login with status %d", method.Name, resp.StatusCode)
+	}
+	return nil
+}
+
+func totpAlgorithm(name string) (otp.Algorithm, error) {
+	switch name {
+	case "SHA1":
+		return otp.AlgorithmSHA1, nil
+	case "SHA256":
+		return otp.AlgorithmSHA256, nil
+	case "SHA512":
+		return otp.AlgorithmSHA512, nil
+	default:
+		return 0, fmt.Errorf("invalid algorithm %q", name)
+	}
+}
+
+var mfaHelp = map[string][2]string{
+	"totp": {
+		"Create, read, update or delete a TOTP MFA method.",
+		`TOTP methods verify logins with time-based one-time passcodes. Entities
+are enrolled in the method with the admin-generate endpoint; the returned key
+is loaded into an authenticator app generating the passcodes.`,
+	},
+	"totp-admin-generate": {
+		"Enroll an entity in a TOTP MFA method.",
+		`Generates the TOTP secret of the entity and returns its otpauth URL along
+with a base64-encoded PNG QR code of it. An entity that is already enrolled
+must have its secret destroyed before a new one can be generated.`,
+	},
+	"totp-admin-destroy": {
+		"Remove the TOTP secret of an entity.",
+		`The entity can't satisfy the method anymore until it is enrolled again.`,
+	},
+	"duo": {
+		"Create, read, update or delete a Duo MFA method.",
+		`Duo methods send a push notification to the Duo user of the login, or
+verify the Duo passcode given on login. The secret key is not returned on read.`,
+	},
+	"webhook": {
+		"Create, read, update or delete a webhook MFA method.",
+		`Webhook methods post the details of the login, including the passcode
+given on login, as JSON to the configured URL. The login is accepted if the URL
+responds with a 2xx status code. If a secret is set, the hex-encoded
+HMAC-SHA256 of the body is sent in the X-Vault-MFA-Signature header.`,
+	},
+	"method-list": {
+		"List the MFA methods.",
+		"",
+	},
+	"enforcement": {
+		"Create, read, update or delete an MFA enforcement.",
+		`Logins to the given auth mounts, of the given entities, or of the members
+of the given groups must be verified by one of the methods of the enforcement.
+Credentials are given on login in the X-Vault-MFA header, as
+"<method name>[:<passcode>]"; the header can be repeated to satisfy several
+enforcements.`,
+	},
+	"enforcement-list": {
+		"List the MFA enforcements.",
+		"",
+	},
+}
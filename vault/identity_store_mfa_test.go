@@ -0,0 +1,292 @@
+package vault
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/logical"
+	"github.com/pquerna/otp"
+	totplib "github.com/pquerna/otp/totp"
+)
+
+func TestIdentityStore_MFA_LoginEnforcement(t *testing.T) {
+	noop := &NoopBackend{
+		Login: []string{"login"},
+		Response: &logical.Response{
+			Auth: &logical.Auth{
+				Policies: []string{"foo"},
+				Alias: &logical.Alias{
+					Name: "mfauser",
+				},
+			},
+		},
+	}
+	c, _, root := TestCoreUnsealed(t)
+	c.credentialBackends["noop"] = func(conf *logical.BackendConfig) (logical.Backend, error) {
+		return noop, nil
+	}
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "sys/auth/foo")
+	req.Data["type"] = "noop"
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	accessor := c.router.MatchingMountEntry("auth/foo/").Accessor
+
+	login := func(credentials ...string) (*logical.Response, error) {
+		req := &logical.Request{
+			Path: "auth/foo/login",
+		}
+		if len(credentials) > 0 {
+			req.Headers = map[string][]string{
+				http.CanonicalHeaderKey(MFAHeaderName): credentials,
+			}
+		}
+		return c.HandleRequest(req)
+	}
+
+	write := func(path string, data map[string]interface{}) *logical.Response {
+		req := logical.TestRequest(t, logical.UpdateOperation, path)
+		req.Data = data
+		req.ClientToken = root
+		resp, err := c.HandleRequest(req)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: path: %q, resp: %#v, err: %v", path, resp, err)
+		}
+		return resp
+	}
+
+	// Without enforcements, logins don't require MFA
+	resp, err := login()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	entityID := resp.Auth.EntityID
+	if entityID == "" {
+		t.Fatalf("expected an entity to be created")
+	}
+
+	// Enroll the entity in a TOTP method
+	write("identity/mfa/method/totp/mytotp", map[string]interface{}{
+		"issuer":  "test",
+		"qr_size": 0,
+	})
+	resp = write("identity/mfa/method/totp/mytotp/admin-generate", map[string]interface{}{
+		"entity_id": entityID,
+	})
+	key, err := otp.NewKeyFromURL(resp.Data["url"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := resp.Data["barcode"]; ok {
+		t.Fatalf("expected no barcode")
+	}
+
+	// The entity can't be enrolled twice
+	req = logical.TestRequest(t, logical.UpdateOperation, "identity/mfa/method/totp/mytotp/admin-generate")
+	req.Data["entity_id"] = entityID
+	req.ClientToken = root
+	resp, err = c.HandleRequest(req)
+	if err == nil && (resp == nil || !resp.IsError()) {
+		t.Fatalf("expected an error; resp: %#v", resp)
+	}
+
+	write("identity/mfa/enforcement/entity", map[string]interface{}{
+		"mfa_method_names":    "mytotp",
+		"identity_entity_ids": entityID,
+	})
+
+	// Logins without a valid passcode are rejected
+	for _, credentials := range [][]string{nil, {"mytotp"}, {"mytotp:000000"}, {"other:123456"}} {
+		if _, err := login(credentials...); err == nil || !errwrap.Contains(err, logical.ErrPermissionDenied.Error()) {
+			t.Fatalf("expected permission denied with credentials %q; err: %v", credentials, err)
+		}
+	}
+
+	passcode, err := totplib.GenerateCode(key.Secret(), time.Now().UTC())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err = login("mytotp:" + passcode)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Auth == nil || resp.Auth.ClientToken == "" {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// Passcodes can't be replayed
+	if _, err := login("mytotp:" + passcode); err == nil || !errwrap.Contains(err, logical.ErrPermissionDenied.Error()) {
+		t.Fatalf("expected permission denied; err: %v", err)
+	}
+
+	// Forget the used passcode, so that it can be given again below
+	c.identityStore.mfaUsedCodes.Flush()
+
+	// Require a webhook on all the logins of the mount
+	var payload map[string]interface{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload = nil
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if payload["passcode"] != "approved" {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer ts.Close()
+
+	write("identity/mfa/method/webhook/myhook", map[string]interface{}{
+		"url": ts.URL,
+	})
+	write("identity/mfa/enforcement/mount", map[string]interface{}{
+		"mfa_method_names":      "myhook",
+		"auth_method_accessors": accessor,
+	})
+
+	// Both enforcements must be satisfied
+	if _, err := login("mytotp:" + passcode); err == nil || !errwrap.Contains(err, logical.ErrPermissionDenied.Error()) {
+		t.Fatalf("expected permission denied; err: %v", err)
+	}
+	if _, err := login("mytotp:"+passcode, "myhook:denied"); err == nil || !errwrap.Contains(err, logical.ErrPermissionDenied.Error()) {
+		t.Fatalf("expected permission denied; err: %v", err)
+	}
+	c.identityStore.mfaUsedCodes.Flush()
+	resp, err = login("mytotp:"+passcode, "myhook:approved")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Auth == nil || resp.Auth.ClientToken == "" {
+		t.Fatalf("bad: %#v", resp)
+	}
+	if payload["entity_id"] != entityID || payload["mount_accessor"] != accessor || payload["alias_name"] != "mfauser" {
+		t.Fatalf("bad: webhook payload: %#v", payload)
+	}
+	c.identityStore.mfaUsedCodes.Flush()
+
+	// The token records the methods validated at login
+	te, err := c.tokenStore.Lookup(resp.Auth.ClientToken)
+	if err != nil || te == nil || !reflect.DeepEqual(te.MFAMethods, []string{"myhook", "mytotp"}) {
+		t.Fatalf("bad: token entry: %#v, err: %v", te, err)
+	}
+
+	// Methods can't be deleted while enforcements refer to them
+	req = logical.TestRequest(t, logical.DeleteOperation, "identity/mfa/method/totp/mytotp")
+	req.ClientToken = root
+	resp, err = c.HandleRequest(req)
+	if err == nil && (resp == nil || !resp.IsError()) {
+		t.Fatalf("expected an error; resp: %#v", resp)
+	}
+
+	// Too many invalid passcodes lock the entity out of the method, even
+	// with a valid passcode
+	c.identityStore.mfaUsedCodes.Flush()
+	for i := 0; i < mfaTOTPMaxFailedValidations; i++ {
+		if _, err := login("mytotp:000000", "myhook:approved"); err == nil {
+			t.Fatal("expected an error")
+		}
+	}
+	if _, err := login("mytotp:"+passcode, "myhook:approved"); err == nil || !errwrap.Contains(err, logical.ErrPermissionDenied.Error()) {
+		t.Fatalf("expected permission denied; err: %v", err)
+	}
+	c.identityStore.mfaFailedValidations.Flush()
+
+	// Once the entity's secret is destroyed, it can't satisfy the method
+	write("identity/mfa/method/totp/mytotp/admin-destroy", map[string]interface{}{
+		"entity_id": entityID,
+	})
+	if _, err := login("mytotp:"+passcode, "myhook:approved"); err == nil || !errwrap.Contains(err, logical.ErrPermissionDenied.Error()) {
+		t.Fatalf("expected permission denied; err: %v", err)
+	}
+}
+
+func TestIdentityStore_MFA_Config(t *testing.T) {
+	is, ghAccessor, _ := testIdentityStoreWithGithubAuth(t)
+
+	resp, err := is.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "mfa/method/duo/myduo",
+		Data: map[string]interface{}{
+			"integration_key": "ikey",
+			"secret_key":      "skey",
+			"api_hostname":    "api-test.duosecurity.com",
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+
+	// The secret key is not returned
+	resp, err = is.HandleRequest(&logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "mfa/method/duo/myduo",
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	if resp.Data["username_format"] != "{{alias.name}}" || resp.Data["integration_key"] != "ikey" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if _, ok := resp.Data["secret_key"]; ok {
+		t.Fatalf("expected the secret key not to be returned")
+	}
+
+	// Method names are shared by all the method types
+	resp, err = is.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "mfa/method/totp/myduo",
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error; resp: %#v, err: %v", resp, err)
+	}
+
+	for _, data := range []map[string]interface{}{
+		// Unknown method
+		{"mfa_method_names": "unknown", "auth_method_accessors": ghAccessor},
+		// No target
+		{"mfa_method_names": "myduo"},
+		// Unknown targets
+		{"mfa_method_names": "myduo", "auth_method_accessors": "unknown"},
+		{"mfa_method_names": "myduo", "identity_entity_ids": "unknown"},
+		{"mfa_method_names": "myduo", "identity_group_ids": "unknown"},
+	} {
+		resp, err = is.HandleRequest(&logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "mfa/enforcement/test",
+			Data:      data,
+		})
+		if err != nil || resp == nil || !resp.IsError() {
+			t.Fatalf("expected an error for %#v; resp: %#v, err: %v", data, resp, err)
+		}
+	}
+
+	resp, err = is.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "mfa/enforcement/test",
+		Data: map[string]interface{}{
+			"mfa_method_names":      "myduo",
+			"auth_method_accessors": ghAccessor,
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+
+	resp, err = is.HandleRequest(&logical.Request{
+		Operation: logical.ListOperation,
+		Path:      "mfa/enforcement/",
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	if keys := resp.Data["keys"].([]string); len(keys) != 1 || keys[0] != "test" {
+		t.Fatalf("bad: keys: %#v", keys)
+	}
+}
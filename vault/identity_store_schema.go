@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	memdb "github.com/hashicorp/go-memdb"
+	"github.com/hashicorp/vault/helper/identity"
 )
 
 func identityStoreSchema() *memdb.DBSchema {
@@ -15,6 +16,7 @@ func identityStoreSchema() *memdb.DBSchema {
 		entityTableSchema,
 		aliasesTableSchema,
 		groupTableSchema,
+		groupAliasesTableSchema,
 	}
 
 	for _, schemaFunc := range schemas {
@@ -91,11 +93,9 @@ func entityTableSchema() *memdb.TableSchema {
 				},
 			},
 			"name": &memdb.IndexSchema{
-				Name:   "name",
-				Unique: true,
-				Indexer: &memdb.StringFieldIndex{
-					Field: "Name",
-				},
+				Name:    "name",
+				Unique:  true,
+				Indexer: namespaceNameIndex{},
 			},
 			"metadata": &memdb.IndexSchema{
 				Name:         "metadata",
@@ -137,11 +137,9 @@ func groupTableSchema() *memdb.TableSchema {
 				},
 			},
 			"name": {
-				Name:   "name",
-				Unique: true,
-				Indexer: &memdb.StringFieldIndex{
-					Field: "Name",
-				},
+				Name:    "name",
+				Unique:  true,
+				Indexer: namespaceNameIndex{},
 			},
 			"member_entity_ids": {
 				Name:         "member_entity_ids",
@@ -178,3 +176,79 @@ func groupTableSchema() *memdb.TableSchema {
 		},
 	}
 }
+
+func groupAliasesTableSchema() *memdb.TableSchema {
+	return &memdb.TableSchema{
+		Name: "group_aliases",
+		Indexes: map[string]*memdb.IndexSchema{
+			"id": {
+				Name:   "id",
+				Unique: true,
+				Indexer: &memdb.StringFieldIndex{
+					Field: "ID",
+				},
+			},
+			"canonical_id": {
+				Name:   "canonical_id",
+				Unique: true,
+				Indexer: &memdb.StringFieldIndex{
+					Field: "CanonicalID",
+				},
+			},
+			"factors": {
+				Name:   "factors",
+				Unique: true,
+				Indexer: &memdb.CompoundIndex{
+					Indexes: []memdb.Indexer{
+						&memdb.StringFieldIndex{
+							Field: "MountAccessor",
+						},
+						&memdb.StringFieldIndex{
+							Field: "Name",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// namespaceNameIndex indexes the entities and groups by name within their
+// namespace, since names are only unique within a namespace. Its arguments
+// are the namespace ID and the name.
+type namespaceNameIndex struct{}
+
+func (namespaceNameIndex) FromObject(obj interface{}) (bool, []byte, error) {
+	var namespaceID, name string
+	switch o := obj.(type) {
+	case *identity.Entity:
+		namespaceID, name = o.NamespaceID, o.Name
+	case *identity.Group:
+		namespaceID, name = o.NamespaceID, o.Name
+	default:
+		return false, nil, fmt.Errorf("unsupported type %T", obj)
+	}
+	if name == "" {
+		return false, nil, nil
+	}
+	return true, namespaceNameIndexKey(namespaceID, name), nil
+}
+
+func (namespaceNameIndex) FromArgs(args ...interface{}) ([]byte, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("must provide the namespace ID and the name")
+	}
+	namespaceID, ok := args[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("namespace ID must be a string: %#v", args[0])
+	}
+	name, ok := args[1].(string)
+	if !ok {
+		return nil, fmt.Errorf("name must be a string: %#v", args[1])
+	}
+	return namespaceNameIndexKey(namespaceID, name), nil
+}
+
+func namespaceNameIndexKey(namespaceID, name string) []byte {
+	return []byte(namespaceID + "\x00" + name + "\x00")
+}
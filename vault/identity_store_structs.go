@@ -10,6 +10,7 @@ import (
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
 	log "github.com/mgutz/logxi/v1"
+	cache "github.com/patrickmn/go-cache"
 )
 
 const (
@@ -17,6 +18,15 @@ const (
 	entityPrefix = "entity/"
 )
 
+const (
+	// Members of internal groups are managed explicitly
+	groupTypeInternal = "internal"
+
+	// Entity members of external groups are managed on login, based on the
+	// group aliases returned by the auth backend of the group alias
+	groupTypeExternal = "external"
+)
+
 var (
 	// metaKeyFormatRegEx checks if a metadata key string is valid
 	metaKeyFormatRegEx = regexp.MustCompile(`^[a-zA-Z0-9=/+_-]+$`).MatchString
@@ -55,6 +65,10 @@ type IdentityStore struct {
 	// properties of the mount given the mount accessor.
 	validateMountAccessorFunc func(string) *validateMountResponse
 
+	// namespaceByPathFunc returns the namespace at the given path, which
+	// scopes the entities and groups of a request
+	namespaceByPathFunc func(string) *Namespace
+
 	// entityLocks are a set of 256 locks to which all the entities will be
 	// categorized to while performing storage modifications.
 	entityLocks []*locksutil.LockEntry
@@ -72,4 +86,10 @@ type IdentityStore struct {
 	// groupPacker is used to pack multiple group storage entries into 256
 	// buckets
 	groupPacker *storagepacker.StoragePacker
+
+	// mfaUsedCodes holds the TOTP passcodes used by the entities until they
+	// expire, and mfaFailedValidations the number of invalid passcodes given
+	// by the entities, both keyed by method name and entity ID
+	mfaUsedCodes         *cache.Cache
+	mfaFailedValidations *cache.Cache
 }
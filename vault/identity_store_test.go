@@ -171,6 +171,20 @@ func TestIdentityStore_WrapInfoInheritance(t *testing.T) {
 	if resp.WrapInfo.WrappedEntityID != entityID {
 		t.Fatalf("bad: WrapInfo in response not having proper entity ID set; expected: %q, actual:%q", entityID, resp.WrapInfo.WrappedEntityID)
 	}
+
+	// The creator entity is recorded with the wrapping token
+	lookupReq := &logical.Request{
+		Path:        "sys/wrapping/lookup",
+		ClientToken: resp.WrapInfo.Token,
+		Operation:   logical.ReadOperation,
+	}
+	resp, err = core.HandleRequest(lookupReq)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	if resp.Data["creator_entity_id"] != entityID {
+		t.Fatalf("bad: creator entity ID; expected: %q, actual: %q", entityID, resp.Data["creator_entity_id"])
+	}
 }
 
 func TestIdentityStore_TokenEntityInheritance(t *testing.T) {
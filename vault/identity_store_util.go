@@ -13,6 +13,7 @@ import (
 	"github.com/hashicorp/vault/helper/locksutil"
 	"github.com/hashicorp/vault/helper/storagepacker"
 	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/logical"
 )
 
 // parseMetadata takes in a slice of string and parses each item as a key value pair separated by an '=' sign.
@@ -414,6 +415,18 @@ func (i *IdentityStore) deleteEntity(entityID string) error {
 		return err
 	}
 
+	// Delete the login history of the entity
+	err = i.view.Delete(entityActivityPrefix + entity.ID)
+	if err != nil {
+		return err
+	}
+
+	// Delete the MFA secrets the entity was enrolled with
+	err = i.deleteEntityMFASecrets(entity.ID)
+	if err != nil {
+		return err
+	}
+
 	// Committing the transaction *after* successfully deleting entity
 	txn.Commit()
 
@@ -867,7 +880,7 @@ func (i *IdentityStore) memDBEntityByID(entityID string, clone bool) (*identity.
 	return i.memDBEntityByIDInTxn(txn, entityID, clone)
 }
 
-func (i *IdentityStore) memDBEntityByNameInTxn(txn *memdb.Txn, entityName string, clone bool) (*identity.Entity, error) {
+func (i *IdentityStore) memDBEntityByNameInTxn(txn *memdb.Txn, namespaceID, entityName string, clone bool) (*identity.Entity, error) {
 	if entityName == "" {
 		return nil, fmt.Errorf("missing entity name")
 	}
@@ -876,7 +889,7 @@ func (i *IdentityStore) memDBEntityByNameInTxn(txn *memdb.Txn, entityName string
 		return nil, fmt.Errorf("txn is nil")
 	}
 
-	entityRaw, err := txn.First("entities", "name", entityName)
+	entityRaw, err := txn.First("entities", "name", namespaceID, entityName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch entity from memdb using entity name: %v", err)
 	}
@@ -897,14 +910,14 @@ func (i *IdentityStore) memDBEntityByNameInTxn(txn *memdb.Txn, entityName string
 	return entity, nil
 }
 
-func (i *IdentityStore) memDBEntityByName(entityName string, clone bool) (*identity.Entity, error) {
+func (i *IdentityStore) memDBEntityByName(namespaceID, entityName string, clone bool) (*identity.Entity, error) {
 	if entityName == "" {
 		return nil, fmt.Errorf("missing entity name")
 	}
 
 	txn := i.db.Txn(false)
 
-	return i.memDBEntityByNameInTxn(txn, entityName, clone)
+	return i.memDBEntityByNameInTxn(txn, namespaceID, entityName, clone)
 }
 
 func (i *IdentityStore) memDBEntitiesByMetadata(filters map[string]string, clone bool) ([]*identity.Entity, error) {
@@ -1165,7 +1178,7 @@ func (i *IdentityStore) sanitizeEntity(entity *identity.Entity) error {
 
 	// Create a name if there isn't one already
 	if entity.Name == "" {
-		entity.Name, err = i.generateName("entity")
+		entity.Name, err = i.generateName("entity", entity.NamespaceID)
 		if err != nil {
 			return fmt.Errorf("failed to generate entity name")
 		}
@@ -1189,57 +1202,29 @@ func (i *IdentityStore) sanitizeEntity(entity *identity.Entity) error {
 }
 
 func (i *IdentityStore) sanitizeAndUpsertGroup(group *identity.Group, memberGroupIDs []string) error {
-	var err error
-
-	if group == nil {
-		return fmt.Errorf("group is nil")
-	}
-
-	// Create an ID if there isn't one already
-	if group.ID == "" {
-		group.ID, err = uuid.GenerateUUID()
-		if err != nil {
-			return fmt.Errorf("failed to generate group id")
-		}
-
-		// Set the hash value of the storage bucket key in group
-		group.BucketKeyHash = i.groupPacker.BucketKeyHashByItemID(group.ID)
-	}
-
-	// Create a name if there isn't one already
-	if group.Name == "" {
-		group.Name, err = i.generateName("group")
-		if err != nil {
-			return fmt.Errorf("failed to generate group name")
-		}
-	}
-
-	// Entity metadata should always be map[string]string
-	err = validateMetadata(group.Metadata)
+	err := i.sanitizeGroup(group)
 	if err != nil {
-		return fmt.Errorf("invalid group metadata: %v", err)
-	}
-
-	// Set the creation and last update times
-	if group.CreationTime == nil {
-		group.CreationTime = ptypes.TimestampNow()
-		group.LastUpdateTime = group.CreationTime
-	} else {
-		group.LastUpdateTime = ptypes.TimestampNow()
+		return err
 	}
 
 	// Remove duplicate entity IDs and check if all IDs are valid
 	group.MemberEntityIDs = strutil.RemoveDuplicates(group.MemberEntityIDs, false)
 	for _, entityID := range group.MemberEntityIDs {
-		err = i.validateEntityID(entityID)
+		entity, err := i.memDBEntityByID(entityID, false)
 		if err != nil {
-			return err
+			return fmt.Errorf("failed to validate entity ID %q: %v", entityID, err)
+		}
+		if entity == nil || entity.NamespaceID != group.NamespaceID {
+			return fmt.Errorf("invalid entity ID %q", entityID)
 		}
 	}
 
 	txn := i.db.Txn(true)
 	defer txn.Abort()
 
+	// The member groups are persisted along with the group
+	var groups []*identity.Group
+
 	memberGroupIDs = strutil.RemoveDuplicates(memberGroupIDs, false)
 	// After the group lock is held, make membership updates to all the
 	// relevant groups
@@ -1248,7 +1233,7 @@ func (i *IdentityStore) sanitizeAndUpsertGroup(group *identity.Group, memberGrou
 		if err != nil {
 			return err
 		}
-		if memberGroup == nil {
+		if memberGroup == nil || memberGroup.NamespaceID != group.NamespaceID {
 			return fmt.Errorf("invalid member group ID %q", memberGroupID)
 		}
 
@@ -1267,17 +1252,22 @@ func (i *IdentityStore) sanitizeAndUpsertGroup(group *identity.Group, memberGrou
 		memberGroup.ParentGroupIDs = append(memberGroup.ParentGroupIDs, group.ID)
 
 		// This technically is not upsert. It is only update, only the method name is upsert here.
-		err = i.upsertGroupInTxn(txn, memberGroup, true)
+		err = i.upsertGroupInTxn(txn, memberGroup, false)
 		if err != nil {
-			// Ideally we would want to revert the whole operation in case of
-			// errors while persisting in member groups. But there is no
-			// storage transaction support yet. When we do have it, this will need
-			// an update.
 			return err
 		}
+		groups = append(groups, memberGroup)
 	}
 
-	err = i.upsertGroupInTxn(txn, group, true)
+	err = i.upsertGroupInTxn(txn, group, false)
+	if err != nil {
+		return err
+	}
+	groups = append(groups, group)
+
+	// Persist all the groups at once so that a failure doesn't leave the
+	// membership of the group and its member groups inconsistent
+	err = i.persistGroups(groups)
 	if err != nil {
 		return err
 	}
@@ -1287,6 +1277,56 @@ func (i *IdentityStore) sanitizeAndUpsertGroup(group *identity.Group, memberGrou
 	return nil
 }
 
+// sanitizeGroup sets the ID, name, type and timestamps of a group if needed
+// and validates its metadata
+func (i *IdentityStore) sanitizeGroup(group *identity.Group) error {
+	var err error
+
+	if group == nil {
+		return fmt.Errorf("group is nil")
+	}
+
+	// Create an ID if there isn't one already
+	if group.ID == "" {
+		group.ID, err = uuid.GenerateUUID()
+		if err != nil {
+			return fmt.Errorf("failed to generate group id")
+		}
+
+		// Set the hash value of the storage bucket key in group
+		group.BucketKeyHash = i.groupPacker.BucketKeyHashByItemID(group.ID)
+	}
+
+	// Create a name if there isn't one already
+	if group.Name == "" {
+		group.Name, err = i.generateName("group", group.NamespaceID)
+		if err != nil {
+			return fmt.Errorf("failed to generate group name")
+		}
+	}
+
+	// Entity metadata should always be map[string]string
+	err = validateMetadata(group.Metadata)
+	if err != nil {
+		return fmt.Errorf("invalid group metadata: %v", err)
+	}
+
+	// Groups created before group types were introduced are internal
+	if group.Type == "" {
+		group.Type = groupTypeInternal
+	}
+
+	// Set the creation and last update times
+	if group.CreationTime == nil {
+		group.CreationTime = ptypes.TimestampNow()
+		group.LastUpdateTime = group.CreationTime
+	} else {
+		group.LastUpdateTime = ptypes.TimestampNow()
+	}
+
+	return nil
+}
+
 func (i *IdentityStore) validateMemberGroupID(groupID string, memberGroupID string) error {
 	group, err := i.memDBGroupByID(groupID, true)
 	if err != nil {
@@ -1321,6 +1361,16 @@ func (i *IdentityStore) validateMemberGroupID(groupID string, memberGroupID stri
 	return nil
 }
 
+// requestNamespaceID returns the ID of the namespace of a request, which
+// scopes the entities and groups it can access
+func (i *IdentityStore) requestNamespaceID(req *logical.Request) (string, error) {
+	ns := i.namespaceByPathFunc(req.Namespace)
+	if ns == nil {
+		return "", errNamespaceNotFound(req.Namespace)
+	}
+	return ns.ID, nil
+}
+
 func (i *IdentityStore) validateEntityID(entityID string) error {
 	entity, err := i.memDBEntityByID(entityID, false)
 	if err != nil {
@@ -1475,7 +1525,7 @@ func satisfiesMetadataFilters(meta map[string]string, filters map[string]string)
 	return true
 }
 
-func (i *IdentityStore) memDBGroupByNameInTxn(txn *memdb.Txn, groupName string, clone bool) (*identity.Group, error) {
+func (i *IdentityStore) memDBGroupByNameInTxn(txn *memdb.Txn, namespaceID, groupName string, clone bool) (*identity.Group, error) {
 	if groupName == "" {
 		return nil, fmt.Errorf("missing group name")
 	}
@@ -1484,7 +1534,7 @@ func (i *IdentityStore) memDBGroupByNameInTxn(txn *memdb.Txn, groupName string,
 		return nil, fmt.Errorf("txn is nil")
 	}
 
-	groupRaw, err := txn.First("groups", "name", groupName)
+	groupRaw, err := txn.First("groups", "name", namespaceID, groupName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch group from memdb using group name: %v", err)
 	}
@@ -1505,14 +1555,14 @@ func (i *IdentityStore) memDBGroupByNameInTxn(txn *memdb.Txn, groupName string,
 	return group, nil
 }
 
-func (i *IdentityStore) memDBGroupByName(groupName string, clone bool) (*identity.Group, error) {
+func (i *IdentityStore) memDBGroupByName(namespaceID, groupName string, clone bool) (*identity.Group, error) {
 	if groupName == "" {
 		return nil, fmt.Errorf("missing group name")
 	}
 
 	txn := i.db.Txn(false)
 
-	return i.memDBGroupByNameInTxn(txn, groupName, clone)
+	return i.memDBGroupByNameInTxn(txn, namespaceID, groupName, clone)
 }
 
 func (i *IdentityStore) upsertGroupInTxn(txn *memdb.Txn, group *identity.Group, persist bool) error {
@@ -1536,23 +1586,32 @@ func (i *IdentityStore) upsertGroupInTxn(txn *memdb.Txn, group *identity.Group,
 	}
 
 	if persist {
-		groupAsAny, err := ptypes.MarshalAny(group)
+		err = i.persistGroups([]*identity.Group{group})
 		if err != nil {
 			return err
 		}
+	}
 
-		item := &storagepacker.Item{
-			ID:      group.ID,
-			Message: groupAsAny,
-		}
+	return nil
+}
 
-		err = i.groupPacker.PutItem(item)
+// persistGroups writes the groups to storage. Their buckets are written in a
+// single transaction if the storage supports it.
+func (i *IdentityStore) persistGroups(groups []*identity.Group) error {
+	items := make([]*storagepacker.Item, 0, len(groups))
+	for _, group := range groups {
+		groupAsAny, err := ptypes.MarshalAny(group)
 		if err != nil {
 			return err
 		}
+
+		items = append(items, &storagepacker.Item{
+			ID:      group.ID,
+			Message: groupAsAny,
+		})
 	}
 
-	return nil
+	return i.groupPacker.PutItems(items)
 }
 
 func (i *IdentityStore) memDBUpsertGroup(group *identity.Group) error {
@@ -1594,6 +1653,18 @@ func (i *IdentityStore) memDBUpsertGroupInTxn(txn *memdb.Txn, group *identity.Gr
 		return fmt.Errorf("failed to update group into memdb: %v", err)
 	}
 
+	// Replace the indexed alias of the group, which may have been removed
+	err = i.memDBDeleteGroupAliasByCanonicalIDInTxn(txn, group.ID)
+	if err != nil {
+		return err
+	}
+	if group.Alias != nil {
+		err = i.memDBUpsertGroupAliasInTxn(txn, group.Alias)
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -1641,6 +1712,44 @@ func (i *IdentityStore) deleteGroupByID(groupID string) error {
 	return nil
 }
 
+// deleteNamespaceIdentities deletes the entities and the groups of a
+// namespace, along with their aliases
+func (i *IdentityStore) deleteNamespaceIdentities(namespaceID string) error {
+	var entityIDs, groupIDs []string
+
+	ws := memdb.NewWatchSet()
+	entities, err := i.memDBEntities(ws)
+	if err != nil {
+		return fmt.Errorf("failed to fetch iterator for entities in memdb: %v", err)
+	}
+	for raw := entities.Next(); raw != nil; raw = entities.Next() {
+		if entity := raw.(*identity.Entity); entity.NamespaceID == namespaceID {
+			entityIDs = append(entityIDs, entity.ID)
+		}
+	}
+	groups, err := i.memDBGroupIterator(ws)
+	if err != nil {
+		return fmt.Errorf("failed to fetch iterator for groups in memdb: %v", err)
+	}
+	for raw := groups.Next(); raw != nil; raw = groups.Next() {
+		if group := raw.(*identity.Group); group.NamespaceID == namespaceID {
+			groupIDs = append(groupIDs, group.ID)
+		}
+	}
+
+	for _, entityID := range entityIDs {
+		if err := i.deleteEntity(entityID); err != nil {
+			return err
+		}
+	}
+	for _, groupID := range groupIDs {
+		if err := i.deleteGroupByID(groupID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (i *IdentityStore) memDBDeleteGroupByIDInTxn(txn *memdb.Txn, groupID string) error {
 	if groupID == "" {
 		return nil
@@ -1664,10 +1773,10 @@ func (i *IdentityStore) memDBDeleteGroupByIDInTxn(txn *memdb.Txn, groupID string
 		return fmt.Errorf("failed to delete group from memdb: %v", err)
 	}
 
-	return nil
+	return i.memDBDeleteGroupAliasByCanonicalIDInTxn(txn, group.ID)
 }
 
-func (i *IdentityStore) deleteGroupByName(groupName string) error {
+func (i *IdentityStore) deleteGroupByName(namespaceID, groupName string) error {
 	var err error
 	var group *identity.Group
 
@@ -1684,7 +1793,7 @@ func (i *IdentityStore) deleteGroupByName(groupName string) error {
 	defer txn.Abort()
 
 	// Fetch the group using its ID
-	group, err = i.memDBGroupByNameInTxn(txn, groupName, false)
+	group, err = i.memDBGroupByNameInTxn(txn, namespaceID, groupName, false)
 	if err != nil {
 		return err
 	}
@@ -1695,7 +1804,7 @@ func (i *IdentityStore) deleteGroupByName(groupName string) error {
 	}
 
 	// Delete the group using the same transaction
-	err = i.memDBDeleteGroupByNameInTxn(txn, group.Name)
+	err = i.memDBDeleteGroupByNameInTxn(txn, namespaceID, group.Name)
 	if err != nil {
 		return err
 	}
@@ -1712,7 +1821,7 @@ func (i *IdentityStore) deleteGroupByName(groupName string) error {
 	return nil
 }
 
-func (i *IdentityStore) memDBDeleteGroupByNameInTxn(txn *memdb.Txn, groupName string) error {
+func (i *IdentityStore) memDBDeleteGroupByNameInTxn(txn *memdb.Txn, namespaceID, groupName string) error {
 	if groupName == "" {
 		return nil
 	}
@@ -1721,7 +1830,7 @@ func (i *IdentityStore) memDBDeleteGroupByNameInTxn(txn *memdb.Txn, groupName st
 		return fmt.Errorf("txn is nil")
 	}
 
-	group, err := i.memDBGroupByNameInTxn(txn, groupName, false)
+	group, err := i.memDBGroupByNameInTxn(txn, namespaceID, groupName, false)
 	if err != nil {
 		return err
 	}
@@ -1735,7 +1844,7 @@ func (i *IdentityStore) memDBDeleteGroupByNameInTxn(txn *memdb.Txn, groupName st
 		return fmt.Errorf("failed to delete group from memdb: %v", err)
 	}
 
-	return nil
+	return i.memDBDeleteGroupAliasByCanonicalIDInTxn(txn, group.ID)
 }
 
 func (i *IdentityStore) memDBGroupByIDInTxn(txn *memdb.Txn, groupID string, clone bool) (*identity.Group, error) {
@@ -1856,6 +1965,18 @@ func (i *IdentityStore) memDBGroupsByMemberEntityID(entityID string, clone bool)
 	txn := i.db.Txn(false)
 	defer txn.Abort()
 
+	return i.memDBGroupsByMemberEntityIDInTxn(txn, entityID, clone)
+}
+
+func (i *IdentityStore) memDBGroupsByMemberEntityIDInTxn(txn *memdb.Txn, entityID string, clone bool) ([]*identity.Group, error) {
+	if entityID == "" {
+		return nil, fmt.Errorf("missing entity ID")
+	}
+
+	if txn == nil {
+		return nil, fmt.Errorf("txn is nil")
+	}
+
 	groupsIter, err := txn.Get("groups", "member_entity_ids", entityID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to lookup groups using entity ID: %v", err)
@@ -1889,7 +2010,7 @@ func (i *IdentityStore) groupPoliciesByEntityID(entityID string) ([]string, erro
 	visited := make(map[string]bool)
 	var policies []string
 	for _, group := range groups {
-		policies, err = i.collectPoliciesReverseDFS(group, visited, nil)
+		policies, err = i.collectPoliciesReverseDFS(group, visited, policies)
 		if err != nil {
 			return nil, err
 		}
@@ -1911,7 +2032,7 @@ func (i *IdentityStore) transitiveGroupsByEntityID(entityID string) ([]*identity
 	visited := make(map[string]bool)
 	var tGroups []*identity.Group
 	for _, group := range groups {
-		tGroups, err = i.collectGroupsReverseDFS(group, visited, nil)
+		tGroups, err = i.collectGroupsReverseDFS(group, visited, tGroups)
 		if err != nil {
 			return nil, err
 		}
@@ -2053,7 +2174,7 @@ func (i *IdentityStore) memDBGroupIterator(ws memdb.WatchSet) (memdb.ResultItera
 	return iter, nil
 }
 
-func (i *IdentityStore) generateName(entryType string) (string, error) {
+func (i *IdentityStore) generateName(entryType, namespaceID string) (string, error) {
 	var name string
 OUTER:
 	for {
@@ -2065,7 +2186,7 @@ OUTER:
 
 		switch entryType {
 		case "entity":
-			entity, err := i.memDBEntityByName(name, false)
+			entity, err := i.memDBEntityByName(namespaceID, name, false)
 			if err != nil {
 				return "", err
 			}
@@ -2073,7 +2194,7 @@ OUTER:
 				break OUTER
 			}
 		case "group":
-			group, err := i.memDBGroupByName(name, false)
+			group, err := i.memDBGroupByName(namespaceID, name, false)
 			if err != nil {
 				return "", err
 			}
@@ -2120,3 +2241,299 @@ func (i *IdentityStore) memDBGroupsByBucketEntryKeyHashInTxn(txn *memdb.Txn, has
 
 	return groups, nil
 }
+
+func (i *IdentityStore) memDBUpsertGroupAliasInTxn(txn *memdb.Txn, alias *identity.Alias) error {
+	if txn == nil {
+		return fmt.Errorf("nil txn")
+	}
+
+	if alias == nil {
+		return fmt.Errorf("group alias is nil")
+	}
+
+	aliasRaw, err := txn.First("group_aliases", "id", alias.ID)
+	if err != nil {
+		return fmt.Errorf("failed to lookup group alias from memdb using group alias ID: %v", err)
+	}
+
+	if aliasRaw != nil {
+		err = txn.Delete("group_aliases", aliasRaw)
+		if err != nil {
+			return fmt.Errorf("failed to delete group alias from memdb: %v", err)
+		}
+	}
+
+	if err := txn.Insert("group_aliases", alias); err != nil {
+		return fmt.Errorf("failed to update group alias into memdb: %v", err)
+	}
+
+	return nil
+}
+
+func (i *IdentityStore) memDBDeleteGroupAliasByCanonicalIDInTxn(txn *memdb.Txn, groupID string) error {
+	if txn == nil {
+		return fmt.Errorf("nil txn")
+	}
+
+	aliasRaw, err := txn.First("group_aliases", "canonical_id", groupID)
+	if err != nil {
+		return fmt.Errorf("failed to lookup group alias from memdb using group ID: %v", err)
+	}
+
+	if aliasRaw == nil {
+		return nil
+	}
+
+	err = txn.Delete("group_aliases", aliasRaw)
+	if err != nil {
+		return fmt.Errorf("failed to delete group alias from memdb: %v", err)
+	}
+
+	return nil
+}
+
+func (i *IdentityStore) memDBGroupAliasByIDInTxn(txn *memdb.Txn, aliasID string, clone bool) (*identity.Alias, error) {
+	if aliasID == "" {
+		return nil, fmt.Errorf("missing group alias ID")
+	}
+
+	if txn == nil {
+		return nil, fmt.Errorf("txn is nil")
+	}
+
+	aliasRaw, err := txn.First("group_aliases", "id", aliasID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch group alias from memdb using group alias ID: %v", err)
+	}
+
+	if aliasRaw == nil {
+		return nil, nil
+	}
+
+	alias, ok := aliasRaw.(*identity.Alias)
+	if !ok {
+		return nil, fmt.Errorf("failed to declare the type of fetched group alias")
+	}
+
+	if clone {
+		return alias.Clone()
+	}
+
+	return alias, nil
+}
+
+func (i *IdentityStore) memDBGroupAliasByID(aliasID string, clone bool) (*identity.Alias, error) {
+	if aliasID == "" {
+		return nil, fmt.Errorf("missing group alias ID")
+	}
+
+	txn := i.db.Txn(false)
+
+	return i.memDBGroupAliasByIDInTxn(txn, aliasID, clone)
+}
+
+func (i *IdentityStore) memDBGroupAliasByFactorsInTxn(txn *memdb.Txn, mountAccessor, aliasName string, clone bool) (*identity.Alias, error) {
+	if aliasName == "" {
+		return nil, fmt.Errorf("missing group alias name")
+	}
+
+	if mountAccessor == "" {
+		return nil, fmt.Errorf("missing mount accessor")
+	}
+
+	if txn == nil {
+		return nil, fmt.Errorf("txn is nil")
+	}
+
+	aliasRaw, err := txn.First("group_aliases", "factors", mountAccessor, aliasName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch group alias from memdb using factors: %v", err)
+	}
+
+	if aliasRaw == nil {
+		return nil, nil
+	}
+
+	alias, ok := aliasRaw.(*identity.Alias)
+	if !ok {
+		return nil, fmt.Errorf("failed to declare the type of fetched group alias")
+	}
+
+	if clone {
+		return alias.Clone()
+	}
+
+	return alias, nil
+}
+
+func (i *IdentityStore) memDBGroupAliasByFactors(mountAccessor, aliasName string, clone bool) (*identity.Alias, error) {
+	txn := i.db.Txn(false)
+
+	return i.memDBGroupAliasByFactorsInTxn(txn, mountAccessor, aliasName, clone)
+}
+
+func (i *IdentityStore) memDBGroupAliases(ws memdb.WatchSet) (memdb.ResultIterator, error) {
+	txn := i.db.Txn(false)
+
+	iter, err := txn.Get("group_aliases", "id")
+	if err != nil {
+		return nil, err
+	}
+
+	ws.Add(iter.WatchCh())
+
+	return iter, nil
+}
+
+// newGroupAlias returns a group alias with the given name on a mount, tied to
+// the given group
+func (i *IdentityStore) newGroupAlias(mountValidationResp *validateMountResponse, aliasName, groupID string) (*identity.Alias, error) {
+	aliasID, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate group alias ID")
+	}
+
+	now := ptypes.TimestampNow()
+	return &identity.Alias{
+		ID:             aliasID,
+		CanonicalID:    groupID,
+		Name:           aliasName,
+		MountType:      mountValidationResp.MountType,
+		MountAccessor:  mountValidationResp.MountAccessor,
+		MountPath:      mountValidationResp.MountPath,
+		CreationTime:   now,
+		LastUpdateTime: now,
+	}, nil
+}
+
+// newExternalGroup returns an external group tied to a new group alias with
+// the given name. The group is named after the alias if the name is free.
+func (i *IdentityStore) newExternalGroup(txn *memdb.Txn, mountValidationResp *validateMountResponse, aliasName string) (*identity.Group, error) {
+	group := &identity.Group{
+		Type:        groupTypeExternal,
+		NamespaceID: mountValidationResp.NamespaceID,
+	}
+
+	groupByName, err := i.memDBGroupByNameInTxn(txn, group.NamespaceID, aliasName, false)
+	if err != nil {
+		return nil, err
+	}
+	if groupByName == nil {
+		group.Name = aliasName
+	}
+
+	err = i.sanitizeGroup(group)
+	if err != nil {
+		return nil, err
+	}
+
+	group.Alias, err = i.newGroupAlias(mountValidationResp, aliasName, group.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return group, nil
+}
+
+// refreshExternalGroupMembershipsByEntityID makes the entity a member of the
+// external groups tied to the given group aliases of a mount, and removes it
+// from the other external groups of the mount. If autoCreate is set, external
+// groups are created for the group aliases that are not registered yet.
+func (i *IdentityStore) refreshExternalGroupMembershipsByEntityID(entityID, mountAccessor string, groupAliases []*logical.Alias, autoCreate bool) error {
+	if entityID == "" {
+		return fmt.Errorf("empty entity ID")
+	}
+
+	mountValidationResp := i.validateMountAccessorFunc(mountAccessor)
+	if mountValidationResp == nil {
+		return fmt.Errorf("invalid mount accessor %q", mountAccessor)
+	}
+
+	i.groupLock.Lock()
+	defer i.groupLock.Unlock()
+
+	txn := i.db.Txn(true)
+	defer txn.Abort()
+
+	// The updated groups are persisted together after the memberships are
+	// refreshed
+	var groups []*identity.Group
+	memberGroupIDs := make(map[string]bool)
+	for _, groupAlias := range groupAliases {
+		if groupAlias == nil || groupAlias.Name == "" {
+			continue
+		}
+
+		var group *identity.Group
+		alias, err := i.memDBGroupAliasByFactorsInTxn(txn, mountValidationResp.MountAccessor, groupAlias.Name, false)
+		if err != nil {
+			return err
+		}
+		switch {
+		case alias != nil:
+			group, err = i.memDBGroupByIDInTxn(txn, alias.CanonicalID, true)
+			if err != nil {
+				return err
+			}
+			if group == nil {
+				continue
+			}
+		case autoCreate:
+			group, err = i.newExternalGroup(txn, mountValidationResp, groupAlias.Name)
+			if err != nil {
+				return err
+			}
+			if i.logger.IsDebug() {
+				i.logger.Debug("identity: creating external group for group alias", "group_id", group.ID, "alias", groupAlias.Name, "mount_accessor", mountValidationResp.MountAccessor)
+			}
+		default:
+			continue
+		}
+
+		memberGroupIDs[group.ID] = true
+		if strutil.StrListContains(group.MemberEntityIDs, entityID) {
+			continue
+		}
+
+		group.MemberEntityIDs = append(group.MemberEntityIDs, entityID)
+		group.LastUpdateTime = ptypes.TimestampNow()
+		err = i.upsertGroupInTxn(txn, group, false)
+		if err != nil {
+			return err
+		}
+		groups = append(groups, group)
+	}
+
+	// Remove the entity from the external groups of the mount whose group
+	// aliases were not returned on this login
+	groups, err := i.memDBGroupsByMemberEntityIDInTxn(txn, entityID, true)
+	if err != nil {
+		return err
+	}
+	for _, group := range groups {
+		if group.Type != groupTypeExternal || group.Alias == nil ||
+			group.Alias.MountAccessor != mountValidationResp.MountAccessor ||
+			memberGroupIDs[group.ID] {
+			continue
+		}
+
+		group.MemberEntityIDs = strutil.StrListDelete(group.MemberEntityIDs, entityID)
+		group.LastUpdateTime = ptypes.TimestampNow()
+		err = i.upsertGroupInTxn(txn, group, false)
+		if err != nil {
+			return err
+		}
+		groups = append(groups, group)
+	}
+
+	if len(groups) > 0 {
+		err = i.persistGroups(groups)
+		if err != nil {
+			return err
+		}
+	}
+
+	txn.Commit()
+
+	return nil
+}
@@ -0,0 +1,105 @@
+package vault
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+// InFlightRequest describes a request being handled by the core
+type InFlightRequest struct {
+	ID               string    `json:"id" structs:"id" mapstructure:"id"`
+	Path             string    `json:"path" structs:"path" mapstructure:"path"`
+	Operation        string    `json:"operation" structs:"operation" mapstructure:"operation"`
+	ClientRemoteAddr string    `json:"client_remote_addr" structs:"client_remote_addr" mapstructure:"client_remote_addr"`
+	StartTime        time.Time `json:"start_time" structs:"start_time" mapstructure:"start_time"`
+
+	cancel context.CancelFunc
+}
+
+// inFlightRequests tracks the requests being handled, so that operators can
+// list them and cancel those which are stuck
+type inFlightRequests struct {
+	l        sync.Mutex
+	requests map[string]*InFlightRequest
+}
+
+// trackRequest records a request until the returned function is called. The
+// context of the request is replaced by one canceled along with it, until
+// the function restores the original context, so that the request can be
+// handled again.
+func (c *Core) trackRequest(req *logical.Request) func() {
+	parent := req.Context()
+	ctx, cancel := context.WithCancel(parent)
+	req.SetContext(ctx)
+
+	entry := &InFlightRequest{
+		ID:        req.ID,
+		Path:      req.Path,
+		Operation: string(req.Operation),
+		StartTime: time.Now(),
+		cancel:    cancel,
+	}
+	if req.Connection != nil {
+		entry.ClientRemoteAddr = req.Connection.RemoteAddr
+	}
+
+	ifr := &c.inFlightRequests
+	ifr.l.Lock()
+	if ifr.requests == nil {
+		ifr.requests = make(map[string]*InFlightRequest)
+	}
+	ifr.requests[entry.ID] = entry
+	ifr.l.Unlock()
+
+	return func() {
+		cancel()
+		req.SetContext(parent)
+
+		ifr.l.Lock()
+		// Requests replayed with the same identifier replace each other
+		if ifr.requests[entry.ID] == entry {
+			delete(ifr.requests, entry.ID)
+		}
+		ifr.l.Unlock()
+	}
+}
+
+// InFlightRequests returns the requests being handled, oldest first
+func (c *Core) InFlightRequests() []*InFlightRequest {
+	ifr := &c.inFlightRequests
+	ifr.l.Lock()
+	requests := make([]*InFlightRequest, 0, len(ifr.requests))
+	for _, entry := range ifr.requests {
+		copied := *entry
+		copied.cancel = nil
+		requests = append(requests, &copied)
+	}
+	ifr.l.Unlock()
+
+	sort.Slice(requests, func(i, j int) bool {
+		return requests[i].StartTime.Before(requests[j].StartTime)
+	})
+	return requests
+}
+
+// CancelInFlightRequest cancels the context of the request with the given
+// identifier, and returns false if no such request is being handled. A
+// request is failed before its backend callback runs; a callback which is
+// already running is only interrupted if it checks the request context.
+func (c *Core) CancelInFlightRequest(id string) bool {
+	ifr := &c.inFlightRequests
+	ifr.l.Lock()
+	entry, ok := ifr.requests[id]
+	ifr.l.Unlock()
+	if !ok {
+		return false
+	}
+
+	c.logger.Warn("core: canceling in-flight request", "request_id", id, "path", entry.Path)
+	entry.cancel()
+	return true
+}
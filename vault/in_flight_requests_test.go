@@ -0,0 +1,118 @@
+package vault
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+func TestCore_InFlightRequests(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	// The backend blocks until its request is canceled
+	started := make(chan struct{})
+	c.logicalBackends["blocking"] = func(conf *logical.BackendConfig) (logical.Backend, error) {
+		be := &framework.Backend{
+			Paths: []*framework.Path{
+				&framework.Path{
+					Pattern: "stuck",
+					Callbacks: map[logical.Operation]framework.OperationFunc{
+						logical.ReadOperation: func(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+							close(started)
+							<-req.Context().Done()
+							return nil, req.Context().Err()
+						},
+					},
+				},
+			},
+		}
+		if err := be.Setup(conf); err != nil {
+			return nil, err
+		}
+		return be, nil
+	}
+	meUUID, _ := uuid.GenerateUUID()
+	if err := c.mount(&MountEntry{
+		Table: mountTableType,
+		UUID:  meUUID,
+		Path:  "blocking",
+		Type:  "blocking",
+	}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		req := logical.TestRequest(t, logical.ReadOperation, "blocking/stuck")
+		req.ID = "stuck-request"
+		req.ClientToken = root
+		req.Connection = &logical.Connection{RemoteAddr: "10.0.0.1"}
+		_, err := c.HandleRequest(req)
+		errCh <- err
+	}()
+	<-started
+
+	req := logical.TestRequest(t, logical.ReadOperation, "sys/in-flight-req")
+	req.ClientToken = root
+	resp, err := c.HandleRequest(req)
+	if err != nil || resp == nil {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	info, ok := resp.Data["stuck-request"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected the stuck request: %#v", resp.Data)
+	}
+	if info["path"] != "blocking/stuck" || info["operation"] != "read" || info["client_remote_addr"] != "10.0.0.1" {
+		t.Fatalf("bad: %#v", info)
+	}
+	// The listing request is in flight too
+	if _, ok := resp.Data[req.ID]; !ok {
+		t.Fatalf("expected the listing request: %#v", resp.Data)
+	}
+
+	// Unknown requests can't be canceled
+	req = logical.TestRequest(t, logical.DeleteOperation, "sys/in-flight-req/unknown")
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); !errwrap.Contains(err, logical.ErrInvalidRequest.Error()) {
+		t.Fatalf("bad: err: %v", err)
+	}
+
+	req = logical.TestRequest(t, logical.DeleteOperation, "sys/in-flight-req/stuck-request")
+	req.ClientToken = root
+	if resp, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+
+	select {
+	case err := <-errCh:
+		if !errwrap.Contains(err, context.Canceled.Error()) {
+			t.Fatalf("bad: err: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the request was not canceled")
+	}
+
+	requests := c.InFlightRequests()
+	if len(requests) != 0 {
+		t.Fatalf("bad: %#v", requests)
+	}
+}
+
+func TestCore_InFlightRequests_canceledBeforeHandling(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	req := logical.TestRequest(t, logical.ReadOperation, "sys/mounts")
+	req.ClientToken = root
+	req.SetContext(ctx)
+	if _, err := c.HandleRequest(req); !errwrap.Contains(err, logical.ErrRequestCanceled.Error()) {
+		t.Fatalf("bad: err: %v", err)
+	}
+}
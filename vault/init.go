@@ -127,6 +127,11 @@ func (c *Core) Initialize(initParams *InitParams) (*InitResult, error) {
 		return nil, ErrAlreadyInit
 	}
 
+	// With raft storage, only the raft leader can write the initial data
+	if err := c.raftInitialize(); err != nil {
+		return nil, err
+	}
+
 	err = c.seal.Init()
 	if err != nil {
 		c.logger.Error("core: failed to initialize seal", "error", err)
@@ -265,6 +270,11 @@ func (c *Core) UnsealWithStoredKeys() error {
 		return nil
 	}
 
+	if c.SealMigrationConfig() != nil {
+		c.logger.Info("core: seal migration pending, not unsealing with stored keys")
+		return nil
+	}
+
 	c.logger.Info("core: stored unseal keys supported, attempting fetch")
 	keys, err := c.seal.GetStoredKeys()
 	if err != nil {
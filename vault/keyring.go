@@ -18,15 +18,17 @@ import (
 // when a new key is added to the keyring, we can encrypt with the master key
 // and write out the new keyring.
 type Keyring struct {
-	masterKey  []byte
-	keys       map[uint32]*Key
-	activeTerm uint32
+	masterKey      []byte
+	keys           map[uint32]*Key
+	activeTerm     uint32
+	rotationConfig KeyRotationConfig
 }
 
 // EncodedKeyring is used for serialization of the keyring
 type EncodedKeyring struct {
-	MasterKey []byte
-	Keys      []*Key
+	MasterKey      []byte
+	Keys           []*Key
+	RotationConfig KeyRotationConfig
 }
 
 // Key represents a single term, along with the key used.
@@ -35,6 +37,25 @@ type Key struct {
 	Version     int
 	Value       []byte
 	InstallTime time.Time
+
+	// Encryptions is the number of encryptions with the key accounted for
+	// when the keyring was last persisted
+	Encryptions int64
+}
+
+// KeyRotationConfig is the policy for the automatic rotation of the active
+// key, persisted with the keyring
+type KeyRotationConfig struct {
+	// Disabled turns the automatic rotation off
+	Disabled bool `json:"disabled"`
+
+	// MaxOperations is the number of encryptions after which the key is
+	// rotated. The absolute maximum for AES-GCM applies when zero.
+	MaxOperations int64 `json:"max_operations"`
+
+	// Interval is the age after which the key is rotated. Keys are not
+	// rotated on age when zero.
+	Interval time.Duration `json:"interval"`
 }
 
 // Serialize is used to create a byte encoded key
@@ -63,9 +84,10 @@ func NewKeyring() *Keyring {
 // Clone returns a new copy of the keyring
 func (k *Keyring) Clone() *Keyring {
 	clone := &Keyring{
-		masterKey:  k.masterKey,
-		keys:       make(map[uint32]*Key, len(k.keys)),
-		activeTerm: k.activeTerm,
+		masterKey:      k.masterKey,
+		keys:           make(map[uint32]*Key, len(k.keys)),
+		activeTerm:     k.activeTerm,
+		rotationConfig: k.rotationConfig,
 	}
 	for idx, key := range k.keys {
 		clone.keys[idx] = key
@@ -134,6 +156,31 @@ func (k *Keyring) TermKey(term uint32) *Key {
 	return k.keys[term]
 }
 
+// AddEncryptions accounts for encryptions with the key of the given term
+func (k *Keyring) AddEncryptions(term uint32, encryptions int64) *Keyring {
+	clone := k.Clone()
+	if key, ok := clone.keys[term]; ok {
+		updated := *key
+		updated.Encryptions += encryptions
+		clone.keys[term] = &updated
+	}
+	return clone
+}
+
+// RotationConfig returns the policy for the automatic rotation of the
+// active key
+func (k *Keyring) RotationConfig() KeyRotationConfig {
+	return k.rotationConfig
+}
+
+// SetRotationConfig is used to update the policy for the automatic rotation
+// of the active key
+func (k *Keyring) SetRotationConfig(config KeyRotationConfig) *Keyring {
+	clone := k.Clone()
+	clone.rotationConfig = config
+	return clone
+}
+
 // SetMasterKey is used to update the master key
 func (k *Keyring) SetMasterKey(val []byte) *Keyring {
 	valCopy := make([]byte, len(val))
@@ -152,7 +199,8 @@ func (k *Keyring) MasterKey() []byte {
 func (k *Keyring) Serialize() ([]byte, error) {
 	// Create the encoded entry
 	enc := EncodedKeyring{
-		MasterKey: k.masterKey,
+		MasterKey:      k.masterKey,
+		RotationConfig: k.rotationConfig,
 	}
 	for _, key := range k.keys {
 		enc.Keys = append(enc.Keys, key)
@@ -174,6 +222,7 @@ func DeserializeKeyring(buf []byte) (*Keyring, error) {
 	// Create a new keyring
 	k := NewKeyring()
 	k.masterKey = enc.MasterKey
+	k.rotationConfig = enc.RotationConfig
 	for _, key := range enc.Keys {
 		k.keys[key.Term] = key
 		if key.Term > k.activeTerm {
@@ -0,0 +1,159 @@
+package vault
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault/helper/consts"
+)
+
+const (
+	// absoluteOperationMaximum is the number of encryptions after which the
+	// active key is rotated at the latest. AES-GCM keys with random nonces
+	// must not encrypt more than 2^32 values; this leaves a margin of 10%.
+	absoluteOperationMaximum = int64(3865470566)
+
+	// absoluteOperationMinimum is the lowest number of encryptions after
+	// which the active key can be configured to rotate
+	absoluteOperationMinimum = int64(1000000)
+
+	// minimumRotationInterval is the shortest age after which the active key
+	// can be configured to rotate
+	minimumRotationInterval = 24 * time.Hour
+
+	// keyRotationCheckInterval is how often the active node persists the
+	// number of encryptions with the active key and checks whether it must
+	// be rotated
+	keyRotationCheckInterval = 10 * time.Minute
+)
+
+// validateRotationConfig checks the bounds of a key rotation policy
+func validateRotationConfig(config KeyRotationConfig) error {
+	if config.MaxOperations != 0 && (config.MaxOperations < absoluteOperationMinimum || config.MaxOperations > absoluteOperationMaximum) {
+		return fmt.Errorf("max_operations must be between %d and %d", absoluteOperationMinimum, absoluteOperationMaximum)
+	}
+	if config.Interval != 0 && config.Interval < minimumRotationInterval {
+		return fmt.Errorf("interval must be at least %s", minimumRotationInterval)
+	}
+	return nil
+}
+
+// startKeyRotation starts checking in the background whether the active key
+// must be rotated
+func (c *Core) startKeyRotation() {
+	c.keyRotationCh = make(chan struct{})
+	go c.runKeyRotation(c.keyRotationCh)
+}
+
+// stopKeyRotation stops the background checks, persisting the number of
+// encryptions with the active key
+func (c *Core) stopKeyRotation() {
+	if c.keyRotationCh == nil {
+		return
+	}
+	close(c.keyRotationCh)
+	c.keyRotationCh = nil
+
+	if err := c.barrier.PersistEncryptions(); err != nil {
+		c.logger.Error("core: failed to persist the number of encryptions with the active key", "error", err)
+	}
+}
+
+func (c *Core) runKeyRotation(stopCh chan struct{}) {
+	tick := time.NewTicker(keyRotationCheckInterval)
+	defer tick.Stop()
+
+	for {
+		select {
+		case <-tick.C:
+			if err := c.checkKeyRotation(); err != nil {
+				c.logger.Error("core: failed to check the automatic rotation of the active key", "error", err)
+			}
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+// checkKeyRotation persists the number of encryptions with the active key,
+// and rotates it if required by the rotation policy
+func (c *Core) checkKeyRotation() error {
+	if err := c.barrier.PersistEncryptions(); err != nil {
+		return fmt.Errorf("failed to persist the number of encryptions: %v", err)
+	}
+
+	// As with manual rotations, the keyring of a performance secondary
+	// is managed by its primary
+	if c.replicationState.HasState(consts.ReplicationPerformanceSecondary) {
+		return nil
+	}
+
+	config, err := c.barrier.RotationConfig()
+	if err != nil {
+		return err
+	}
+	if config.Disabled {
+		return nil
+	}
+	info, err := c.barrier.ActiveKeyInfo()
+	if err != nil {
+		return err
+	}
+
+	maxOperations := config.MaxOperations
+	if maxOperations == 0 {
+		maxOperations = absoluteOperationMaximum
+	}
+	var reason string
+	switch {
+	case info.Encryptions >= maxOperations:
+		reason = "max_operations"
+	case config.Interval > 0 && time.Since(info.InstallTime) >= config.Interval:
+		reason = "interval"
+	default:
+		return nil
+	}
+
+	c.logger.Info("core: rotating the active key automatically", "term", info.Term, "reason", reason, "encryptions", info.Encryptions)
+	_, err = c.rotateBarrierKey()
+	return err
+}
+
+// rotateBarrierKey installs a new active key, giving the standby instances
+// an upgrade path to it
+func (c *Core) rotateBarrierKey() (uint32, error) {
+	// Rotate to the new term
+	newTerm, err := c.barrier.Rotate()
+	if err != nil {
+		c.logger.Error("core: failed to create new encryption key", "error", err)
+		return 0, err
+	}
+	c.logger.Info("core: installed new encryption key", "term", newTerm)
+
+	// In HA mode, we need to an upgrade path for the standby instances
+	if c.ha != nil {
+		// Create the upgrade path to the new term
+		if err := c.barrier.CreateUpgrade(newTerm); err != nil {
+			c.logger.Error("core: failed to create new upgrade", "term", newTerm, "error", err)
+		}
+
+		// Schedule the destroy of the upgrade path
+		time.AfterFunc(keyRotateGracePeriod, func() {
+			if err := c.barrier.DestroyUpgrade(newTerm); err != nil {
+				c.logger.Error("core: failed to destroy upgrade", "term", newTerm, "error", err)
+			}
+		})
+	}
+
+	// Write to the canary path, which will force a synchronous truing during
+	// replication
+	if err := c.barrier.Put(&Entry{
+		Key:   coreKeyringCanaryPath,
+		Value: []byte(fmt.Sprintf("new-rotation-term-%d", newTerm)),
+	}); err != nil {
+		c.logger.Error("core: error saving keyring canary", "error", err)
+		return 0, fmt.Errorf("failed to save keyring canary: %v", err)
+	}
+
+	return newTerm, nil
+}
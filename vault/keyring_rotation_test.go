@@ -0,0 +1,138 @@
+package vault
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestCore_KeyRotation_maxOperations(t *testing.T) {
+	c, keys, root := TestCoreUnsealed(t)
+
+	if err := c.barrier.SetRotationConfig(KeyRotationConfig{MaxOperations: 10}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	put := func(n int) {
+		for i := 0; i < n; i++ {
+			if err := c.barrier.Put(&Entry{Key: fmt.Sprintf("test/%d", i), Value: []byte("foo")}); err != nil {
+				t.Fatalf("err: %v", err)
+			}
+		}
+	}
+	check := func(term int) {
+		if err := c.checkKeyRotation(); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		info, err := c.barrier.ActiveKeyInfo()
+		if err != nil || info.Term != term {
+			t.Fatalf("bad: info: %#v, err: %v", info, err)
+		}
+	}
+
+	// Initializing and unsealing encrypted more than 10 values
+	check(2)
+
+	// Only the keyring canary was encrypted with the new key
+	put(8)
+	check(2)
+	put(1)
+	check(3)
+
+	// The number of encryptions is persisted with the keyring
+	if err := c.Seal(root); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for _, key := range keys {
+		if _, err := TestCoreUnseal(c, TestKeyCopy(key)); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+	keyring, err := c.barrier.Keyring()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if key := keyring.TermKey(1); key.Encryptions < 10 {
+		t.Fatalf("bad: %#v", key)
+	}
+	if key := keyring.TermKey(2); key.Encryptions != 10 {
+		t.Fatalf("bad: %#v", key)
+	}
+	if config := keyring.RotationConfig(); config.MaxOperations != 10 {
+		t.Fatalf("bad: %#v", config)
+	}
+}
+
+func TestCore_KeyRotation_interval(t *testing.T) {
+	c, _, _ := TestCoreUnsealed(t)
+
+	config := KeyRotationConfig{Disabled: true, Interval: time.Nanosecond}
+	if err := c.barrier.SetRotationConfig(config); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := c.checkKeyRotation(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	info, err := c.barrier.ActiveKeyInfo()
+	if err != nil || info.Term != 1 {
+		t.Fatalf("bad: info: %#v, err: %v", info, err)
+	}
+
+	config.Disabled = false
+	if err := c.barrier.SetRotationConfig(config); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := c.checkKeyRotation(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	info, err = c.barrier.ActiveKeyInfo()
+	if err != nil || info.Term != 2 {
+		t.Fatalf("bad: info: %#v, err: %v", info, err)
+	}
+}
+
+func TestSystemBackend_rotateConfig(t *testing.T) {
+	b := testSystemBackend(t)
+
+	req := logical.TestRequest(t, logical.ReadOperation, "rotate/config")
+	resp, err := b.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Data["enabled"] != true || resp.Data["max_operations"] != absoluteOperationMaximum || resp.Data["interval"] != int64(0) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	for _, data := range []map[string]interface{}{
+		{"max_operations": 10},
+		{"max_operations": absoluteOperationMaximum + 1},
+		{"interval": "1h"},
+	} {
+		req = logical.TestRequest(t, logical.UpdateOperation, "rotate/config")
+		req.Data = data
+		resp, err = b.HandleRequest(req)
+		if err != logical.ErrInvalidRequest || resp == nil || !resp.IsError() {
+			t.Fatalf("bad: %v: resp: %#v, err: %v", data, resp, err)
+		}
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "rotate/config")
+	req.Data = map[string]interface{}{
+		"enabled":        false,
+		"max_operations": 2000000,
+		"interval":       "48h",
+	}
+	if resp, err := b.HandleRequest(req); err != nil || resp != nil {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "rotate/config")
+	resp, err = b.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Data["enabled"] != false || resp.Data["max_operations"] != int64(2000000) || resp.Data["interval"] != int64(48*3600) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+}
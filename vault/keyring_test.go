@@ -143,6 +143,9 @@ func TestKeyring_Serialize(t *testing.T) {
 	testSecond := []byte("second")
 	k, _ = k.AddKey(&Key{Term: 1, Version: 1, Value: testKey, InstallTime: now})
 	k, _ = k.AddKey(&Key{Term: 2, Version: 1, Value: testSecond, InstallTime: now})
+	k = k.AddEncryptions(1, 10)
+	config := KeyRotationConfig{MaxOperations: 1000000, Interval: 24 * time.Hour}
+	k = k.SetRotationConfig(config)
 
 	buf, err := k.Serialize()
 	if err != nil {
@@ -162,6 +165,12 @@ func TestKeyring_Serialize(t *testing.T) {
 	if k2.ActiveTerm() != k.ActiveTerm() {
 		t.Fatalf("Term mismatch")
 	}
+	if k2.RotationConfig() != config {
+		t.Fatalf("bad: %#v", k2.RotationConfig())
+	}
+	if k2.TermKey(1).Encryptions != 10 {
+		t.Fatalf("bad: %#v", k2.TermKey(1))
+	}
 
 	var i uint32
 	for i = 1; i < k.ActiveTerm(); i++ {
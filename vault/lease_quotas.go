@@ -0,0 +1,346 @@
+package vault
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/logical"
+)
+
+const (
+	// leaseCountQuotaSubPath is the sub-path of the system barrier view where
+	// the lease count quotas are stored
+	leaseCountQuotaSubPath = "quotas/lease-count/"
+)
+
+// LeaseCountQuota caps the number of active leases issued under a path,
+// either in total or to each entity
+type LeaseCountQuota struct {
+	Name string `json:"name" structs:"name" mapstructure:"name"`
+
+	// Path is the mount, or the path under a mount such as the credentials
+	// endpoint of a role, whose leases are counted. An empty path counts all
+	// the leases.
+	Path string `json:"path" structs:"path" mapstructure:"path"`
+
+	// MaxLeases is the maximum number of active leases
+	MaxLeases int `json:"max_leases" structs:"max_leases" mapstructure:"max_leases"`
+
+	// PerEntity applies the maximum to the leases of each entity rather than
+	// to all the leases. Leases issued to tokens without an entity are not
+	// counted.
+	PerEntity bool `json:"per_entity" structs:"per_entity" mapstructure:"per_entity"`
+
+	// counter holds the number of leases counted by the quota
+	counter *leaseCountQuotaCounter
+}
+
+// leaseCountQuotaCounter counts the leases of a quota, by entity ID for the
+// per-entity quotas and under the empty ID otherwise. A slot is reserved
+// before a lease is issued and released once the lease is registered, so
+// that concurrent requests can't exceed the quota.
+type leaseCountQuotaCounter struct {
+	l        sync.Mutex
+	counts   map[string]int
+	reserved map[string]int
+}
+
+func newLeaseCountQuotaCounter() *leaseCountQuotaCounter {
+	return &leaseCountQuotaCounter{
+		counts:   make(map[string]int),
+		reserved: make(map[string]int),
+	}
+}
+
+// reserve reserves a slot for a new lease, unless the leases and the slots
+// already reserved reach the maximum
+func (c *leaseCountQuotaCounter) reserve(key string, max int) bool {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	if c.counts[key]+c.reserved[key] >= max {
+		return false
+	}
+	c.reserved[key]++
+	return true
+}
+
+// release releases a slot reserved for a lease
+func (c *leaseCountQuotaCounter) release(key string) {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	c.reserved[key]--
+	if c.reserved[key] <= 0 {
+		delete(c.reserved, key)
+	}
+}
+
+// add adds the delta to the number of leases
+func (c *leaseCountQuotaCounter) add(key string, delta int) {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	c.counts[key] += delta
+	if c.counts[key] <= 0 {
+		delete(c.counts, key)
+	}
+}
+
+// count returns the number of leases
+func (c *leaseCountQuotaCounter) count(key string) int {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	return c.counts[key]
+}
+
+// prefix returns the prefix of the IDs of the leases counted by the quota
+func (q *LeaseCountQuota) prefix() string {
+	if q.Path == "" {
+		return ""
+	}
+	return strings.TrimSuffix(q.Path, "/") + "/"
+}
+
+// counterKey returns the key under which the leases of the given entity are
+// counted, and false if the quota doesn't count them
+func (q *LeaseCountQuota) counterKey(entityID string) (string, bool) {
+	if !q.PerEntity {
+		return "", true
+	}
+	return entityID, entityID != ""
+}
+
+// leaseCount returns the number of leases counted by a quota which is not
+// per entity
+func (q *LeaseCountQuota) leaseCount() int {
+	return q.counter.count("")
+}
+
+// loadLeaseCountQuotas loads the lease count quotas from storage
+func (c *Core) loadLeaseCountQuotas() error {
+	view := c.systemBarrierView.SubView(leaseCountQuotaSubPath)
+	names, err := view.List("")
+	if err != nil {
+		return fmt.Errorf("failed to list lease count quotas: %v", err)
+	}
+
+	quotas := make(map[string]*LeaseCountQuota, len(names))
+	for _, name := range names {
+		entry, err := view.Get(name)
+		if err != nil {
+			return fmt.Errorf("failed to read lease count quota: %v", err)
+		}
+		if entry == nil {
+			continue
+		}
+
+		var quota LeaseCountQuota
+		if err := entry.DecodeJSON(&quota); err != nil {
+			return fmt.Errorf("failed to decode lease count quota: %v", err)
+		}
+		quotas[name] = &quota
+	}
+
+	// Hold the leases while counting them, so that no lease is tracked
+	// before the quotas are in place
+	c.expiration.leaseEntitiesLock.RLock()
+	defer c.expiration.leaseEntitiesLock.RUnlock()
+
+	for _, quota := range quotas {
+		quota.counter = c.expiration.countQuotaLeases(quota)
+	}
+
+	c.leaseQuotasLock.Lock()
+	c.leaseQuotas = quotas
+	c.leaseQuotasLock.Unlock()
+	return nil
+}
+
+// unloadLeaseCountQuotas clears the lease count quotas from memory
+func (c *Core) unloadLeaseCountQuotas() {
+	c.leaseQuotasLock.Lock()
+	c.leaseQuotas = nil
+	c.leaseQuotasLock.Unlock()
+}
+
+// LeaseCountQuota returns the lease count quota of the given name, or nil if
+// it doesn't exist
+func (c *Core) LeaseCountQuota(name string) *LeaseCountQuota {
+	c.leaseQuotasLock.RLock()
+	defer c.leaseQuotasLock.RUnlock()
+
+	quota, ok := c.leaseQuotas[name]
+	if !ok {
+		return nil
+	}
+	copied := *quota
+	return &copied
+}
+
+// SetLeaseCountQuota validates and stores a lease count quota
+func (c *Core) SetLeaseCountQuota(quota *LeaseCountQuota) error {
+	switch {
+	case quota.Name == "":
+		return fmt.Errorf("missing quota name")
+	case quota.MaxLeases <= 0:
+		return fmt.Errorf("max_leases must be positive")
+	case strings.Contains(quota.Path, ".."):
+		return fmt.Errorf("invalid path %q", quota.Path)
+	}
+
+	quota.Path = strings.TrimPrefix(quota.Path, "/")
+	if quota.Path != "" && c.router.MatchingMount(quota.Path) == "" &&
+		c.router.MatchingMount(quota.prefix()) == "" {
+		return fmt.Errorf("path %q does not match a mount", quota.Path)
+	}
+
+	entry, err := logical.StorageEntryJSON(quota.Name, quota)
+	if err != nil {
+		return fmt.Errorf("failed to create lease count quota entry: %v", err)
+	}
+
+	c.expiration.leaseEntitiesLock.RLock()
+	defer c.expiration.leaseEntitiesLock.RUnlock()
+	c.leaseQuotasLock.Lock()
+	defer c.leaseQuotasLock.Unlock()
+
+	view := c.systemBarrierView.SubView(leaseCountQuotaSubPath)
+	if err := view.Put(entry); err != nil {
+		return fmt.Errorf("failed to save lease count quota: %v", err)
+	}
+
+	quota.counter = c.expiration.countQuotaLeases(quota)
+	if c.leaseQuotas == nil {
+		c.leaseQuotas = make(map[string]*LeaseCountQuota)
+	}
+	c.leaseQuotas[quota.Name] = quota
+	return nil
+}
+
+// DeleteLeaseCountQuota deletes the lease count quota of the given name
+func (c *Core) DeleteLeaseCountQuota(name string) error {
+	c.leaseQuotasLock.Lock()
+	defer c.leaseQuotasLock.Unlock()
+
+	view := c.systemBarrierView.SubView(leaseCountQuotaSubPath)
+	if err := view.Delete(name); err != nil {
+		return fmt.Errorf("failed to delete lease count quota: %v", err)
+	}
+	delete(c.leaseQuotas, name)
+	return nil
+}
+
+// ListLeaseCountQuotas returns the names of the lease count quotas
+func (c *Core) ListLeaseCountQuotas() ([]string, error) {
+	view := c.systemBarrierView.SubView(leaseCountQuotaSubPath)
+	names, err := view.List("")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list lease count quotas: %v", err)
+	}
+	return names, nil
+}
+
+// reserveLeaseCountQuotas reserves a slot for a new lease issued on the given
+// path to the given entity in each of the lease count quotas counting it. It
+// returns logical.ErrLeaseCountQuotaExceeded if one of the quotas is full;
+// otherwise the returned function must be called to release the slots once
+// the lease is registered or failed to be.
+//
+// Leases are only counted once they are loaded by the expiration manager, so
+// the quotas are loosely enforced until the leases are restored.
+func (c *Core) reserveLeaseCountQuotas(leasePath, entityID string) (func(), error) {
+	c.leaseQuotasLock.RLock()
+	defer c.leaseQuotasLock.RUnlock()
+
+	type reservation struct {
+		counter *leaseCountQuotaCounter
+		key     string
+	}
+	var reservations []reservation
+	release := func() {
+		for _, r := range reservations {
+			r.counter.release(r.key)
+		}
+	}
+
+	for _, quota := range c.leaseQuotas {
+		if !strings.HasPrefix(leasePath+"/", quota.prefix()) {
+			continue
+		}
+		key, ok := quota.counterKey(entityID)
+		if !ok {
+			continue
+		}
+
+		if !quota.counter.reserve(key, quota.MaxLeases) {
+			release()
+			metrics.IncrCounter([]string{"quota", "lease-count", "violation"}, 1)
+			if c.logger.IsDebug() {
+				c.logger.Debug("core: lease count quota exceeded", "quota", quota.Name, "path", leasePath, "entity_id", entityID)
+			}
+			return nil, errwrap.Wrapf(fmt.Sprintf("quota %q allows %d leases: {{err}}", quota.Name, quota.MaxLeases), logical.ErrLeaseCountQuotaExceeded)
+		}
+		reservations = append(reservations, reservation{counter: quota.counter, key: key})
+	}
+	return release, nil
+}
+
+// countLease updates the counters of the lease count quotas counting a lease
+// which was tracked or untracked by the expiration manager
+func (c *Core) countLease(leaseID, entityID string, delta int) {
+	c.leaseQuotasLock.RLock()
+	defer c.leaseQuotasLock.RUnlock()
+
+	for _, quota := range c.leaseQuotas {
+		if !strings.HasPrefix(leaseID, quota.prefix()) {
+			continue
+		}
+		if key, ok := quota.counterKey(entityID); ok {
+			quota.counter.add(key, delta)
+		}
+	}
+}
+
+// trackLease records a lease loaded in memory, so that it is counted by the
+// lease count quotas
+func (m *ExpirationManager) trackLease(le *leaseEntry) {
+	entityID := le.EntityID
+	if le.Auth != nil {
+		entityID = le.Auth.EntityID
+	}
+
+	m.leaseEntitiesLock.Lock()
+	defer m.leaseEntitiesLock.Unlock()
+
+	if _, updated := m.leaseEntities.Insert(le.LeaseID, entityID); !updated && m.countLeaseFunc != nil {
+		m.countLeaseFunc(le.LeaseID, entityID, 1)
+	}
+}
+
+// untrackLease forgets a lease that was revoked
+func (m *ExpirationManager) untrackLease(leaseID string) {
+	m.leaseEntitiesLock.Lock()
+	defer m.leaseEntitiesLock.Unlock()
+
+	if entityID, deleted := m.leaseEntities.Delete(leaseID); deleted && m.countLeaseFunc != nil {
+		m.countLeaseFunc(leaseID, entityID.(string), -1)
+	}
+}
+
+// countQuotaLeases returns a counter holding the leases counted by the quota.
+// The caller must hold leaseEntitiesLock.
+func (m *ExpirationManager) countQuotaLeases(quota *LeaseCountQuota) *leaseCountQuotaCounter {
+	counter := newLeaseCountQuotaCounter()
+	m.leaseEntities.WalkPrefix(quota.prefix(), func(leaseID string, v interface{}) bool {
+		if key, ok := quota.counterKey(v.(string)); ok {
+			counter.counts[key]++
+		}
+		return false
+	})
+	return counter
+}
@@ -0,0 +1,240 @@
+package vault
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestLeaseCountQuotas(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	c.logicalBackends["kv"] = LeasedPassthroughBackendFactory
+	meUUID, _ := uuid.GenerateUUID()
+	err := c.mount(&MountEntry{
+		Table: mountTableType,
+		UUID:  meUUID,
+		Path:  "leasetest",
+		Type:  "kv",
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	write := func(path string, data map[string]interface{}) (*logical.Response, error) {
+		req := logical.TestRequest(t, logical.UpdateOperation, path)
+		req.Data = data
+		req.ClientToken = root
+		return c.HandleRequest(req)
+	}
+	read := func(path string) (*logical.Response, error) {
+		req := logical.TestRequest(t, logical.ReadOperation, path)
+		req.ClientToken = root
+		return c.HandleRequest(req)
+	}
+
+	if resp, err := write("leasetest/foo", map[string]interface{}{"zip": "zap", "ttl": "1h"}); err != nil {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+
+	// Invalid quotas are rejected
+	for _, data := range []map[string]interface{}{
+		{"path": "leasetest/", "max_leases": 0},
+		{"path": "unknown/", "max_leases": 1},
+		{"path": "leasetest/../sys", "max_leases": 1},
+	} {
+		resp, err := write("sys/quotas/lease-count/bad", data)
+		if err == nil || resp == nil || !resp.IsError() {
+			t.Fatalf("expected an error for %#v; resp: %#v, err: %v", data, resp, err)
+		}
+	}
+
+	if resp, err := write("sys/quotas/lease-count/leasetest", map[string]interface{}{
+		"path":       "leasetest",
+		"max_leases": 2,
+	}); err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+
+	var leaseIDs []string
+	for i := 0; i < 2; i++ {
+		resp, err := read("leasetest/foo")
+		if err != nil || resp == nil || resp.Secret == nil || resp.Secret.LeaseID == "" {
+			t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+		}
+		leaseIDs = append(leaseIDs, resp.Secret.LeaseID)
+	}
+
+	// The third lease exceeds the quota
+	_, err = read("leasetest/foo")
+	if err == nil || !errwrap.Contains(err, logical.ErrLeaseCountQuotaExceeded.Error()) {
+		t.Fatalf("expected the quota to be exceeded; err: %v", err)
+	}
+	if code, _ := logical.RespondErrorCommon(nil, nil, err); code != 429 {
+		t.Fatalf("bad: status code: %d", code)
+	}
+
+	// Requests which don't issue leases are not affected
+	if resp, err := write("leasetest/bar", map[string]interface{}{"zip": "zap"}); err != nil {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+
+	resp, err := read("sys/quotas/lease-count/leasetest")
+	if err != nil || resp == nil {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	if resp.Data["lease_count"] != 2 || resp.Data["max_leases"] != 2 || resp.Data["path"] != "leasetest" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Revoking a lease frees room for a new one
+	if err := c.expiration.Revoke(leaseIDs[0]); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp, err := read("leasetest/foo"); err != nil || resp == nil || resp.Secret == nil {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+
+	// Quotas also apply to the tokens
+	if resp, err := write("sys/quotas/lease-count/tokens", map[string]interface{}{
+		"path":       "auth/token/create",
+		"max_leases": 1,
+	}); err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	if resp, err := write("auth/token/create", nil); err != nil || resp == nil || resp.Auth == nil {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	if _, err := write("auth/token/create", nil); err == nil || !errwrap.Contains(err, logical.ErrLeaseCountQuotaExceeded.Error()) {
+		t.Fatalf("expected the quota to be exceeded; err: %v", err)
+	}
+
+	req := logical.TestRequest(t, logical.ListOperation, "sys/quotas/lease-count/")
+	req.ClientToken = root
+	resp, err = c.HandleRequest(req)
+	if err != nil || resp == nil {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	if keys := resp.Data["keys"].([]string); len(keys) != 2 {
+		t.Fatalf("bad: keys: %#v", keys)
+	}
+
+	// Deleted quotas are no longer enforced
+	req = logical.TestRequest(t, logical.DeleteOperation, "sys/quotas/lease-count/leasetest")
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if resp, err := read("leasetest/foo"); err != nil || resp == nil || resp.Secret == nil {
+			t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+		}
+	}
+
+	// Quotas are loaded back after unsealing
+	if quota := c.LeaseCountQuota("tokens"); quota == nil {
+		t.Fatalf("expected the quota to exist")
+	}
+	c.unloadLeaseCountQuotas()
+	if err := c.loadLeaseCountQuotas(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if quota := c.LeaseCountQuota("tokens"); quota == nil || quota.MaxLeases != 1 {
+		t.Fatalf("bad: %#v", quota)
+	}
+}
+
+func TestLeaseCountQuotas_PerEntity(t *testing.T) {
+	c, _, _ := TestCoreUnsealed(t)
+
+	err := c.SetLeaseCountQuota(&LeaseCountQuota{
+		Name:      "entities",
+		Path:      "secret/",
+		MaxLeases: 1,
+		PerEntity: true,
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	c.expiration.trackLease(&leaseEntry{LeaseID: "secret/foo/lease1", EntityID: "entity1"})
+	c.expiration.trackLease(&leaseEntry{LeaseID: "secret/foo/lease2", EntityID: "entity2"})
+	c.expiration.trackLease(&leaseEntry{LeaseID: "secretive/foo/lease3", EntityID: "entity3"})
+
+	if _, err := c.reserveLeaseCountQuotas("secret/foo", "entity1"); err == nil || !errwrap.Contains(err, logical.ErrLeaseCountQuotaExceeded.Error()) {
+		t.Fatalf("expected the quota to be exceeded; err: %v", err)
+	}
+	for _, entityID := range []string{"entity3", ""} {
+		release, err := c.reserveLeaseCountQuotas("secret/foo", entityID)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		release()
+	}
+
+	c.expiration.untrackLease("secret/foo/lease1")
+	release, err := c.reserveLeaseCountQuotas("secret/foo", "entity1")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The reserved slot counts until it is released
+	if _, err := c.reserveLeaseCountQuotas("secret/foo", "entity1"); err == nil {
+		t.Fatalf("expected the quota to be exceeded")
+	}
+	release()
+
+	// Quotas count the leases tracked before they were created
+	if err := c.SetLeaseCountQuota(&LeaseCountQuota{
+		Name:      "all",
+		Path:      "secret/",
+		MaxLeases: 10,
+	}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if count := c.LeaseCountQuota("all").leaseCount(); count != 1 {
+		t.Fatalf("bad: lease count: %d", count)
+	}
+}
+
+func TestLeaseCountQuotas_Concurrent(t *testing.T) {
+	c, _, _ := TestCoreUnsealed(t)
+
+	if err := c.SetLeaseCountQuota(&LeaseCountQuota{
+		Name:      "secret",
+		Path:      "secret/",
+		MaxLeases: 5,
+	}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Concurrent requests can't issue more leases than the quota allows
+	var issued int32
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			release, err := c.reserveLeaseCountQuotas("secret/foo", "")
+			if err != nil {
+				return
+			}
+			defer release()
+			atomic.AddInt32(&issued, 1)
+			c.expiration.trackLease(&leaseEntry{LeaseID: fmt.Sprintf("secret/foo/lease%d", i)})
+		}(i)
+	}
+	wg.Wait()
+
+	if issued != 5 {
+		t.Fatalf("bad: issued %d leases", issued)
+	}
+	if count := c.LeaseCountQuota("secret").leaseCount(); count != 5 {
+		t.Fatalf("bad: lease count: %d", count)
+	}
+}
@@ -0,0 +1,175 @@
+package vault
+
+import (
+	"errors"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/physical"
+)
+
+const (
+	// defaultLoadSheddingRetryAfter is the value advertised to clients in
+	// the Retry-After header when no explicit value has been configured
+	defaultLoadSheddingRetryAfter = 5 * time.Second
+)
+
+var (
+	// ErrLoadShed is returned when a request has been rejected because the
+	// node is currently overloaded
+	ErrLoadShed = errors.New("request rejected due to server load; retry later")
+
+	// lowPriorityReadPaths are read endpoints that are informational and
+	// can be expensive to serve, making them the first to be shed
+	lowPriorityReadPaths = []string{
+		"sys/audit",
+		"sys/auth",
+		"sys/config/auditing/",
+		"sys/key-status",
+		"sys/mounts",
+		"sys/policy",
+	}
+
+	// renewalPaths are the paths used to renew tokens and leases, which are
+	// never shed so that existing clients can keep their credentials alive
+	renewalPaths = []string{
+		"auth/token/renew",
+		"auth/token/renew-self",
+		"sys/leases/renew",
+		"sys/renew",
+	}
+)
+
+// LoadSheddingConfig configures the thresholds at which the core begins to
+// reject low-priority requests
+type LoadSheddingConfig struct {
+	// MaxInFlightRequests is the number of concurrently executing requests
+	// above which low-priority requests are rejected. Zero disables the
+	// check.
+	MaxInFlightRequests int64 `json:"max_in_flight_requests" structs:"max_in_flight_requests" mapstructure:"max_in_flight_requests"`
+
+	// MaxStorageLatency is the moving average storage latency above which
+	// low-priority requests are rejected. Zero disables the check.
+	MaxStorageLatency time.Duration `json:"max_storage_latency" structs:"max_storage_latency" mapstructure:"max_storage_latency"`
+
+	// RetryAfter is the value returned to clients in the Retry-After header
+	// of rejected requests
+	RetryAfter time.Duration `json:"retry_after" structs:"retry_after" mapstructure:"retry_after"`
+}
+
+// latencyReporter is implemented by physical backends that track the
+// latency of their requests
+type latencyReporter interface {
+	Latency() time.Duration
+}
+
+// loadShedder tracks the load signals used to decide whether a request
+// should be rejected
+type loadShedder struct {
+	config   *LoadSheddingConfig
+	inFlight int64
+	storage  latencyReporter
+}
+
+// setupLoadShedding wraps the given physical backend in a latency tracker,
+// if needed, and returns the backend that should be used by the core
+func (c *Core) setupLoadShedding(conf *LoadSheddingConfig, backend physical.Backend) physical.Backend {
+	if conf == nil {
+		return backend
+	}
+	if conf.RetryAfter == 0 {
+		conf.RetryAfter = defaultLoadSheddingRetryAfter
+	}
+
+	c.loadShedder = &loadShedder{
+		config: conf,
+	}
+
+	if conf.MaxStorageLatency == 0 {
+		return backend
+	}
+
+	if _, txnOK := backend.(physical.Transactional); txnOK {
+		tracker := physical.NewTransactionalLatencyTracker(backend)
+		c.loadShedder.storage = tracker
+		return tracker
+	}
+
+	tracker := physical.NewLatencyTracker(backend)
+	c.loadShedder.storage = tracker
+	return tracker
+}
+
+// trackInFlight records the start of a request and returns a function that
+// must be called when the request has finished
+func (c *Core) trackInFlight() func() {
+	if c.loadShedder == nil {
+		return func() {}
+	}
+
+	atomic.AddInt64(&c.loadShedder.inFlight, 1)
+	return func() {
+		atomic.AddInt64(&c.loadShedder.inFlight, -1)
+	}
+}
+
+// ShouldShedLoad returns whether the given request should be rejected due to
+// server load, along with the duration clients should wait before retrying.
+// Only low-priority requests are ever shed; logins and renewals are always
+// served.
+func (c *Core) ShouldShedLoad(req *logical.Request) (bool, time.Duration) {
+	ls := c.loadShedder
+	if ls == nil || req == nil {
+		return false, 0
+	}
+
+	if !c.isLowPriorityRequest(req) {
+		return false, 0
+	}
+
+	overloaded := false
+	if ls.config.MaxInFlightRequests > 0 &&
+		atomic.LoadInt64(&ls.inFlight) >= ls.config.MaxInFlightRequests {
+		overloaded = true
+	}
+	if ls.storage != nil && ls.config.MaxStorageLatency > 0 &&
+		ls.storage.Latency() > ls.config.MaxStorageLatency {
+		overloaded = true
+	}
+
+	if !overloaded {
+		return false, 0
+	}
+
+	metrics.IncrCounter([]string{"core", "load_shed"}, 1)
+	return true, ls.config.RetryAfter
+}
+
+// isLowPriorityRequest classifies the request for the purposes of load
+// shedding
+func (c *Core) isLowPriorityRequest(req *logical.Request) bool {
+	if c.router.LoginPath(req.Path) {
+		return false
+	}
+	for _, path := range renewalPaths {
+		if req.Path == path || strings.HasPrefix(req.Path, path+"/") {
+			return false
+		}
+	}
+
+	switch req.Operation {
+	case logical.ListOperation:
+		return true
+	case logical.ReadOperation:
+		for _, path := range lowPriorityReadPaths {
+			if strings.HasPrefix(req.Path, path) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,74 @@
+package vault
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+type testLatencyReporter time.Duration
+
+func (t testLatencyReporter) Latency() time.Duration {
+	return time.Duration(t)
+}
+
+func TestCore_ShouldShedLoad(t *testing.T) {
+	c, _, _ := TestCoreUnsealed(t)
+
+	// Not configured, nothing is shed
+	req := &logical.Request{Operation: logical.ListOperation, Path: "secret/"}
+	if shed, _ := c.ShouldShedLoad(req); shed {
+		t.Fatal("expected request not to be shed")
+	}
+
+	c.loadShedder = &loadShedder{
+		config: &LoadSheddingConfig{
+			MaxInFlightRequests: 2,
+			MaxStorageLatency:   100 * time.Millisecond,
+			RetryAfter:          7 * time.Second,
+		},
+		storage: testLatencyReporter(10 * time.Millisecond),
+	}
+
+	// Under the thresholds
+	if shed, _ := c.ShouldShedLoad(req); shed {
+		t.Fatal("expected request not to be shed")
+	}
+
+	// Over the queue depth threshold
+	done1 := c.trackInFlight()
+	done2 := c.trackInFlight()
+	shed, retryAfter := c.ShouldShedLoad(req)
+	if !shed {
+		t.Fatal("expected request to be shed")
+	}
+	if retryAfter != 7*time.Second {
+		t.Fatalf("bad: %v", retryAfter)
+	}
+
+	highPriority := []*logical.Request{
+		&logical.Request{Operation: logical.UpdateOperation, Path: "auth/token/renew-self"},
+		&logical.Request{Operation: logical.UpdateOperation, Path: "sys/leases/renew"},
+		&logical.Request{Operation: logical.UpdateOperation, Path: "sys/renew/secret/foo"},
+		&logical.Request{Operation: logical.ReadOperation, Path: "secret/foo"},
+	}
+	for _, r := range highPriority {
+		if shed, _ := c.ShouldShedLoad(r); shed {
+			t.Fatalf("expected %q not to be shed", r.Path)
+		}
+	}
+
+	done1()
+	done2()
+	if shed, _ := c.ShouldShedLoad(req); shed {
+		t.Fatal("expected request not to be shed")
+	}
+
+	// Over the storage latency threshold
+	c.loadShedder.storage = testLatencyReporter(time.Second)
+	req = &logical.Request{Operation: logical.ReadOperation, Path: "sys/mounts"}
+	if shed, _ := c.ShouldShedLoad(req); !shed {
+		t.Fatal("expected request to be shed")
+	}
+}
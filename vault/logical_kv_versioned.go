@@ -0,0 +1,1223 @@
+package vault
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/helper/locksutil"
+	"github.com/hashicorp/vault/helper/parseutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+const (
+	kvConfigPath      = "config"
+	kvMetadataPrefix  = "metadata/"
+	kvVersionsPrefix  = "versions/"
+	kvExpiryPrefix    = "expiry/"
+	kvTombstonePrefix = "tombstones/"
+	kvMaxVersionsUsed = 10
+
+	// kvTreeDefaultLimit is the number of keys a tree read returns unless
+	// told otherwise
+	kvTreeDefaultLimit = 1000
+)
+
+// kvConfig holds the mount-wide settings of a versioned KV backend
+type kvConfig struct {
+	MaxVersions int  `json:"max_versions"`
+	CASRequired bool `json:"cas_required"`
+}
+
+// kvKeyMetadata tracks the versions of a single key
+type kvKeyMetadata struct {
+	Key            string                        `json:"key"`
+	Versions       map[uint64]*kvVersionMetadata `json:"versions"`
+	CurrentVersion uint64                        `json:"current_version"`
+	OldestVersion  uint64                        `json:"oldest_version"`
+	MaxVersions    int                           `json:"max_versions"`
+	CASRequired    bool                          `json:"cas_required"`
+	CreatedTime    time.Time                     `json:"created_time"`
+	UpdatedTime    time.Time                     `json:"updated_time"`
+
+	// DeleteAfter is how long after its latest write the key and all of its
+	// versions are removed, and ExpireTime when that will happen
+	DeleteAfter time.Duration `json:"delete_after"`
+	ExpireTime  time.Time     `json:"expire_time"`
+}
+
+// kvExpiry is the entry in the expiry index pointing to a key with a
+// delete_after setting
+type kvExpiry struct {
+	Key        string    `json:"key"`
+	ExpireTime time.Time `json:"expire_time"`
+}
+
+// kvTombstone records the last version of a removed key, so that the
+// versions of a key written anew continue from it and a version number never
+// refers to two different values
+type kvTombstone struct {
+	CurrentVersion uint64 `json:"current_version"`
+}
+
+// kvVersionMetadata describes a single version of a key
+type kvVersionMetadata struct {
+	CreatedTime  time.Time `json:"created_time"`
+	DeletionTime time.Time `json:"deletion_time"`
+	Destroyed    bool      `json:"destroyed"`
+}
+
+// kvVersion is the stored data of a single version of a key
+type kvVersion struct {
+	Data        map[string]interface{} `json:"data"`
+	CreatedTime time.Time              `json:"created_time"`
+}
+
+// VersionedKVBackend is the KV backend mounted with the "version" option set
+// to "2". It keeps a configurable number of versions of each key, which can
+// be soft deleted, undeleted and destroyed.
+type VersionedKVBackend struct {
+	*framework.Backend
+	locks []*locksutil.LockEntry
+
+	// auditor records the removal of expired keys in the audit log, if
+	// the system view supports it
+	auditor internalAuditor
+}
+
+// newVersionedKVBackend returns a VersionedKVBackend
+func newVersionedKVBackend(conf *logical.BackendConfig) (logical.Backend, error) {
+	b := &VersionedKVBackend{
+		locks: locksutil.CreateLocks(),
+	}
+	if conf != nil {
+		b.auditor, _ = conf.System.(internalAuditor)
+	}
+	b.Backend = &framework.Backend{
+		Help: strings.TrimSpace(versionedKVHelp),
+
+		PeriodicFunc: b.sweepExpired,
+
+		Paths: []*framework.Path{
+			&framework.Path{
+				Pattern: "config$",
+
+				Fields: map[string]*framework.FieldSchema{
+					"max_versions": &framework.FieldSchema{
+						Type:        framework.TypeInt,
+						Description: "The number of versions to keep for each key. Defaults to 10.",
+					},
+					"cas_required": &framework.FieldSchema{
+						Type:        framework.TypeBool,
+						Description: "If true, all keys require the cas parameter to be set on writes.",
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation:   b.handleConfigRead,
+					logical.UpdateOperation: b.handleConfigWrite,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(versionedKVConfigHelpSynopsis),
+				HelpDescription: strings.TrimSpace(versionedKVConfigHelpDescription),
+			},
+
+			&framework.Path{
+				Pattern: "data/(?P<path>.+)",
+
+				Fields: map[string]*framework.FieldSchema{
+					"path": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: "Location of the key.",
+					},
+					"version": &framework.FieldSchema{
+						Type:        framework.TypeInt,
+						Description: "The version to read. Defaults to the current version.",
+					},
+					"known_version": &framework.FieldSchema{
+						Type: framework.TypeInt,
+						Description: `The version of the key the client last read. If it is
+still the version to be read, the read returns no data and
+is answered with a 304.`,
+					},
+					"data": &framework.FieldSchema{
+						Type:        framework.TypeMap,
+						Description: "The data to write as a new version of the key.",
+					},
+					"options": &framework.FieldSchema{
+						Type: framework.TypeMap,
+						Description: `Write options. "cas" must match the current
+version of the key for the write to succeed; a value of 0
+only allows the write if the key does not exist.
+"delete_after" sets the duration after which the key and
+all of its versions are removed, counted from each write.`,
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation:   b.handleDataRead,
+					logical.CreateOperation: b.handleDataWrite,
+					logical.UpdateOperation: b.handleDataWrite,
+					logical.PatchOperation:  b.handleDataPatch,
+					logical.DeleteOperation: b.handleDataDelete,
+				},
+
+				ExistenceCheck: b.handleExistenceCheck,
+
+				HelpSynopsis:    strings.TrimSpace(versionedKVDataHelpSynopsis),
+				HelpDescription: strings.TrimSpace(versionedKVDataHelpDescription),
+			},
+
+			b.versionsPath("delete", b.handleDeleteVersions, versionedKVDeleteHelpSynopsis, versionedKVDeleteHelpDescription),
+			b.versionsPath("undelete", b.handleUndeleteVersions, versionedKVUndeleteHelpSynopsis, versionedKVUndeleteHelpDescription),
+			b.versionsPath("destroy", b.handleDestroyVersions, versionedKVDestroyHelpSynopsis, versionedKVDestroyHelpDescription),
+
+			&framework.Path{
+				Pattern: "tree/?(?P<path>.*)",
+
+				Fields: map[string]*framework.FieldSchema{
+					"path": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: "The prefix to list keys under.",
+					},
+					"depth": &framework.FieldSchema{
+						Type:        framework.TypeInt,
+						Description: "The number of levels to descend. Folders below it are returned without their contents. Defaults to 0, which is unlimited.",
+					},
+					"after": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: `Only keys sorting after this one are returned. Set to the "next_after" of the previous page to continue a listing.`,
+					},
+					"limit": &framework.FieldSchema{
+						Type:        framework.TypeInt,
+						Default:     kvTreeDefaultLimit,
+						Description: "The maximum number of keys to return.",
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation: b.handleTreeRead,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(versionedKVTreeHelpSynopsis),
+				HelpDescription: strings.TrimSpace(versionedKVTreeHelpDescription),
+			},
+
+			&framework.Path{
+				Pattern: "metadata/?(?P<path>.*)",
+
+				Fields: map[string]*framework.FieldSchema{
+					"path": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: "Location of the key.",
+					},
+					"max_versions": &framework.FieldSchema{
+						Type:        framework.TypeInt,
+						Description: "The number of versions to keep for this key. If unset, the mount's setting is used.",
+					},
+					"cas_required": &framework.FieldSchema{
+						Type:        framework.TypeBool,
+						Description: "If true, writes to this key require the cas parameter to be set.",
+					},
+					"delete_after": &framework.FieldSchema{
+						Type:        framework.TypeDurationSecond,
+						Description: "The duration after the latest write at which the key and all of its versions are removed. If 0, the key does not expire.",
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation:   b.handleMetadataRead,
+					logical.UpdateOperation: b.handleMetadataWrite,
+					logical.DeleteOperation: b.handleMetadataDelete,
+					logical.ListOperation:   b.handleMetadataList,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(versionedKVMetadataHelpSynopsis),
+				HelpDescription: strings.TrimSpace(versionedKVMetadataHelpDescription),
+			},
+		},
+	}
+
+	b.Backend.Setup(conf)
+
+	return b, nil
+}
+
+func (b *VersionedKVBackend) versionsPath(prefix string, callback framework.OperationFunc, synopsis, description string) *framework.Path {
+	return &framework.Path{
+		Pattern: prefix + "/(?P<path>.+)",
+
+		Fields: map[string]*framework.FieldSchema{
+			"path": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Location of the key.",
+			},
+			"versions": &framework.FieldSchema{
+				Type:        framework.TypeCommaStringSlice,
+				Description: "The versions to operate on.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: callback,
+		},
+
+		HelpSynopsis:    strings.TrimSpace(synopsis),
+		HelpDescription: strings.TrimSpace(description),
+	}
+}
+
+// config returns the mount's configuration
+func (b *VersionedKVBackend) config(s logical.Storage) (*kvConfig, error) {
+	entry, err := s.Get(kvConfigPath)
+	if err != nil {
+		return nil, err
+	}
+	var config kvConfig
+	if entry == nil {
+		return &config, nil
+	}
+	if err := entry.DecodeJSON(&config); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// keyMetadata returns the metadata of the key, or nil if it does not exist
+func (b *VersionedKVBackend) keyMetadata(s logical.Storage, key string) (*kvKeyMetadata, error) {
+	entry, err := s.Get(kvMetadataPrefix + key)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+	var meta kvKeyMetadata
+	if err := entry.DecodeJSON(&meta); err != nil {
+		return nil, err
+	}
+	return &meta, nil
+}
+
+func (b *VersionedKVBackend) writeKeyMetadata(s logical.Storage, meta *kvKeyMetadata) error {
+	entry, err := logical.StorageEntryJSON(kvMetadataPrefix+meta.Key, meta)
+	if err != nil {
+		return err
+	}
+	return s.Put(entry)
+}
+
+// versionPath returns the storage path of a version of the key. Keys are
+// hashed so that versions of a key don't collide with keys nested below it.
+func versionPath(key string, version uint64) string {
+	return kvVersionsPrefix + keyHash(key) + "/" + strconv.FormatUint(version, 10)
+}
+
+// tombstonePath returns the storage path of the key's tombstone
+func tombstonePath(key string) string {
+	return kvTombstonePrefix + keyHash(key)
+}
+
+// expiryPath returns the storage path of the key's entry in the expiry index
+func expiryPath(key string) string {
+	return kvExpiryPrefix + keyHash(key)
+}
+
+func keyHash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// expired returns whether the key's delete_after has elapsed
+func (meta *kvKeyMetadata) expired(now time.Time) bool {
+	return !meta.ExpireTime.IsZero() && !now.Before(meta.ExpireTime)
+}
+
+// updateExpiry sets when the key expires from its delete_after setting and
+// its latest write, and updates the expiry index to match
+func (b *VersionedKVBackend) updateExpiry(s logical.Storage, meta *kvKeyMetadata) error {
+	vm, ok := meta.Versions[meta.CurrentVersion]
+	if meta.DeleteAfter <= 0 || !ok {
+		if meta.ExpireTime.IsZero() {
+			return nil
+		}
+		meta.ExpireTime = time.Time{}
+		return s.Delete(expiryPath(meta.Key))
+	}
+
+	meta.ExpireTime = vm.CreatedTime.Add(meta.DeleteAfter)
+	entry, err := logical.StorageEntryJSON(expiryPath(meta.Key), &kvExpiry{
+		Key:        meta.Key,
+		ExpireTime: meta.ExpireTime,
+	})
+	if err != nil {
+		return err
+	}
+	return s.Put(entry)
+}
+
+// sweepExpired removes the keys whose delete_after has elapsed. It is run
+// periodically by the rollback manager.
+func (b *VersionedKVBackend) sweepExpired(req *logical.Request) error {
+	hashes, err := req.Storage.List(kvExpiryPrefix)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, hash := range hashes {
+		entry, err := req.Storage.Get(kvExpiryPrefix + hash)
+		if err != nil {
+			return err
+		}
+		if entry == nil {
+			continue
+		}
+		var expiry kvExpiry
+		if err := entry.DecodeJSON(&expiry); err != nil {
+			return err
+		}
+		if now.Before(expiry.ExpireTime) {
+			continue
+		}
+
+		removed, err := b.removeExpired(req.Storage, expiry.Key, now)
+		if err != nil {
+			return err
+		}
+		if removed {
+			b.auditExpired(expiry.Key)
+		}
+	}
+	return nil
+}
+
+// removeExpired removes the key if it is still expired once locked, since it
+// may have been written again after the index was read
+func (b *VersionedKVBackend) removeExpired(s logical.Storage, key string, now time.Time) (bool, error) {
+	lock := locksutil.LockForKey(b.locks, key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	meta, err := b.keyMetadata(s, key)
+	if err != nil {
+		return false, err
+	}
+	if meta == nil || meta.ExpireTime.IsZero() {
+		return false, s.Delete(expiryPath(key))
+	}
+	if !meta.expired(now) {
+		return false, nil
+	}
+
+	if err := b.deleteKey(s, meta); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// auditExpired records the removal of an expired key in the audit log as a
+// delete of its metadata
+func (b *VersionedKVBackend) auditExpired(key string) {
+	if b.Logger().IsDebug() {
+		b.Logger().Debug("kv: removed expired key", "key", key)
+	}
+	if b.auditor == nil {
+		return
+	}
+	req := &logical.Request{
+		Operation: logical.DeleteOperation,
+		Path:      kvMetadataPrefix + key,
+	}
+	if err := b.auditor.auditInternalRequest(req, nil); err != nil {
+		b.Logger().Error("kv: failed to audit removal of expired key", "error", err)
+	}
+}
+
+// maxVersions returns how many versions of the key are kept
+func (meta *kvKeyMetadata) maxVersions(config *kvConfig) int {
+	switch {
+	case meta.MaxVersions > 0:
+		return meta.MaxVersions
+	case config.MaxVersions > 0:
+		return config.MaxVersions
+	default:
+		return kvMaxVersionsUsed
+	}
+}
+
+// trimVersions removes the oldest versions of the key until no more than the
+// maximum number of versions are kept
+func (b *VersionedKVBackend) trimVersions(s logical.Storage, meta *kvKeyMetadata, config *kvConfig) error {
+	max := meta.maxVersions(config)
+	for len(meta.Versions) > max {
+		if err := s.Delete(versionPath(meta.Key, meta.OldestVersion)); err != nil {
+			return err
+		}
+		delete(meta.Versions, meta.OldestVersion)
+		meta.OldestVersion++
+	}
+	return nil
+}
+
+func (b *VersionedKVBackend) handleExistenceCheck(
+	req *logical.Request, data *framework.FieldData) (bool, error) {
+	meta, err := b.keyMetadata(req.Storage, data.Get("path").(string))
+	if err != nil {
+		return false, fmt.Errorf("existence check failed: %v", err)
+	}
+	return meta != nil, nil
+}
+
+func (b *VersionedKVBackend) handleConfigRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	config, err := b.config(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	maxVersions := config.MaxVersions
+	if maxVersions == 0 {
+		maxVersions = kvMaxVersionsUsed
+	}
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"max_versions": maxVersions,
+			"cas_required": config.CASRequired,
+		},
+	}, nil
+}
+
+func (b *VersionedKVBackend) handleConfigWrite(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	config, err := b.config(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	if maxVersionsRaw, ok := data.GetOk("max_versions"); ok {
+		config.MaxVersions = maxVersionsRaw.(int)
+		if config.MaxVersions < 0 {
+			return logical.ErrorResponse("max_versions cannot be negative"), logical.ErrInvalidRequest
+		}
+	}
+	if casRequiredRaw, ok := data.GetOk("cas_required"); ok {
+		config.CASRequired = casRequiredRaw.(bool)
+	}
+
+	entry, err := logical.StorageEntryJSON(kvConfigPath, config)
+	if err != nil {
+		return nil, err
+	}
+	return nil, req.Storage.Put(entry)
+}
+
+func (b *VersionedKVBackend) handleDataRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	key := data.Get("path").(string)
+
+	lock := locksutil.LockForKey(b.locks, key)
+	lock.RLock()
+	defer lock.RUnlock()
+
+	meta, err := b.keyMetadata(req.Storage, key)
+	if err != nil {
+		return nil, err
+	}
+	if meta == nil || meta.expired(time.Now()) {
+		return nil, nil
+	}
+
+	version := meta.CurrentVersion
+	if versionRaw := data.Get("version").(int); versionRaw > 0 {
+		version = uint64(versionRaw)
+	}
+
+	vm, ok := meta.Versions[version]
+	if !ok || vm.Destroyed || !vm.DeletionTime.IsZero() {
+		return nil, nil
+	}
+
+	// Version numbers continue when a key is removed and written anew, so
+	// a version identifies a single value of the key
+	cacheControl := &logical.CacheControl{
+		ETag: fmt.Sprintf("%d-%d", version, vm.CreatedTime.UnixNano()),
+	}
+	if knownVersion := data.Get("known_version").(int); knownVersion > 0 && uint64(knownVersion) == version {
+		cacheControl.NotModified = true
+		return &logical.Response{
+			CacheControl: cacheControl,
+		}, nil
+	}
+
+	entry, err := req.Storage.Get(versionPath(key, version))
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+	var v kvVersion
+	if err := jsonutil.DecodeJSON(entry.Value, &v); err != nil {
+		return nil, fmt.Errorf("json decoding failed: %v", err)
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"data":     v.Data,
+			"metadata": versionMetadataResponse(version, vm),
+		},
+		CacheControl: cacheControl,
+	}, nil
+}
+
+func (b *VersionedKVBackend) handleDataWrite(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	value, ok := data.GetOk("data")
+	if !ok {
+		return logical.ErrorResponse("no data provided"), logical.ErrInvalidRequest
+	}
+
+	return b.writeVersion(req, data, func(*kvKeyMetadata) (map[string]interface{}, error) {
+		return value.(map[string]interface{}), nil
+	})
+}
+
+// handleDataPatch writes a new version of the key with the data of the
+// current version patched with the request data as a JSON merge patch
+func (b *VersionedKVBackend) handleDataPatch(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	patch, ok := data.GetOk("data")
+	if !ok || patch == nil {
+		return logical.ErrorResponse("no data provided"), logical.ErrInvalidRequest
+	}
+
+	return b.writeVersion(req, data, func(meta *kvKeyMetadata) (map[string]interface{}, error) {
+		vm, ok := meta.Versions[meta.CurrentVersion]
+		if !ok || vm.Destroyed || !vm.DeletionTime.IsZero() || meta.expired(time.Now()) {
+			return nil, logical.CodedError(http.StatusNotFound, fmt.Sprintf("no current version of %q to patch", meta.Key))
+		}
+
+		entry, err := req.Storage.Get(versionPath(meta.Key, meta.CurrentVersion))
+		if err != nil {
+			return nil, err
+		}
+		if entry == nil {
+			return nil, logical.CodedError(http.StatusNotFound, fmt.Sprintf("no current version of %q to patch", meta.Key))
+		}
+		var v kvVersion
+		if err := jsonutil.DecodeJSON(entry.Value, &v); err != nil {
+			return nil, fmt.Errorf("json decoding failed: %v", err)
+		}
+
+		return framework.MergePatch(v.Data, patch.(map[string]interface{})), nil
+	})
+}
+
+// writeVersion writes a new version of the key with the data returned by the
+// value function, which is called with the metadata of the key once it is
+// locked and the check-and-set option verified
+func (b *VersionedKVBackend) writeVersion(req *logical.Request, data *framework.FieldData, value func(meta *kvKeyMetadata) (map[string]interface{}, error)) (*logical.Response, error) {
+	key := data.Get("path").(string)
+	options := data.Get("options").(map[string]interface{})
+
+	var cas *uint64
+	if casRaw, ok := options["cas"]; ok {
+		casInt, err := strconv.ParseUint(fmt.Sprintf("%v", casRaw), 10, 64)
+		if err != nil {
+			return logical.ErrorResponse("cas must be a non-negative integer"), logical.ErrInvalidRequest
+		}
+		cas = &casInt
+	}
+
+	var deleteAfter *time.Duration
+	if deleteAfterRaw, ok := options["delete_after"]; ok {
+		dur, err := parseutil.ParseDurationSecond(deleteAfterRaw)
+		if err != nil || dur < 0 {
+			return logical.ErrorResponse("delete_after must be a non-negative duration"), logical.ErrInvalidRequest
+		}
+		deleteAfter = &dur
+	}
+
+	config, err := b.config(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	lock := locksutil.LockForKey(b.locks, key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	meta, err := b.keyMetadata(req.Storage, key)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	if meta == nil {
+		meta, err = b.newKeyMetadata(req.Storage, key, now)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// A check-and-set of 0 only writes a key without versions, even if its
+	// numbering continues from a removed key
+	current := meta.CurrentVersion
+	if len(meta.Versions) == 0 {
+		current = 0
+	}
+	switch {
+	case cas == nil && (config.CASRequired || meta.CASRequired):
+		return logical.ErrorResponse("check-and-set parameter required for this call"), logical.ErrInvalidRequest
+	case cas != nil && *cas != current:
+		return logical.ErrorResponse("check-and-set parameter did not match the current version"), logical.ErrInvalidRequest
+	}
+
+	versionData, err := value(meta)
+	if err != nil {
+		return nil, err
+	}
+
+	version := meta.CurrentVersion + 1
+	entry, err := logical.StorageEntryJSON(versionPath(key, version), &kvVersion{
+		Data:        versionData,
+		CreatedTime: now,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(entry); err != nil {
+		return nil, err
+	}
+
+	vm := &kvVersionMetadata{
+		CreatedTime: now,
+	}
+	meta.Versions[version] = vm
+	meta.CurrentVersion = version
+	meta.UpdatedTime = now
+	if deleteAfter != nil {
+		meta.DeleteAfter = *deleteAfter
+	}
+	if err := b.trimVersions(req.Storage, meta, config); err != nil {
+		return nil, err
+	}
+	if err := b.updateExpiry(req.Storage, meta); err != nil {
+		return nil, err
+	}
+	if err := b.writeKeyMetadata(req.Storage, meta); err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: versionMetadataResponse(version, vm),
+	}, nil
+}
+
+// newKeyMetadata returns the metadata of a key being written for the first
+// time, continuing the version numbers of a previously removed key
+func (b *VersionedKVBackend) newKeyMetadata(s logical.Storage, key string, now time.Time) (*kvKeyMetadata, error) {
+	meta := &kvKeyMetadata{
+		Key:         key,
+		Versions:    make(map[uint64]*kvVersionMetadata),
+		CreatedTime: now,
+	}
+
+	entry, err := s.Get(tombstonePath(key))
+	if err != nil {
+		return nil, err
+	}
+	if entry != nil {
+		var tombstone kvTombstone
+		if err := entry.DecodeJSON(&tombstone); err != nil {
+			return nil, err
+		}
+		meta.CurrentVersion = tombstone.CurrentVersion
+	}
+	meta.OldestVersion = meta.CurrentVersion + 1
+	return meta, nil
+}
+
+func (b *VersionedKVBackend) handleDataDelete(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	key := data.Get("path").(string)
+
+	lock := locksutil.LockForKey(b.locks, key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	meta, err := b.keyMetadata(req.Storage, key)
+	if err != nil {
+		return nil, err
+	}
+	if meta == nil {
+		return nil, nil
+	}
+
+	vm, ok := meta.Versions[meta.CurrentVersion]
+	if !ok || vm.Destroyed || !vm.DeletionTime.IsZero() {
+		return nil, nil
+	}
+	vm.DeletionTime = time.Now().UTC()
+	return nil, b.writeKeyMetadata(req.Storage, meta)
+}
+
+// updateVersions applies the update function to each of the requested
+// versions of the key that are still tracked, then saves the metadata
+func (b *VersionedKVBackend) updateVersions(req *logical.Request, data *framework.FieldData, update func(key string, version uint64, vm *kvVersionMetadata) error) (*logical.Response, error) {
+	key := data.Get("path").(string)
+
+	var versions []uint64
+	for _, versionStr := range data.Get("versions").([]string) {
+		version, err := strconv.ParseUint(versionStr, 10, 64)
+		if err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("invalid version %q", versionStr)), logical.ErrInvalidRequest
+		}
+		versions = append(versions, version)
+	}
+	if len(versions) == 0 {
+		return logical.ErrorResponse("no versions provided"), logical.ErrInvalidRequest
+	}
+
+	lock := locksutil.LockForKey(b.locks, key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	meta, err := b.keyMetadata(req.Storage, key)
+	if err != nil {
+		return nil, err
+	}
+	if meta == nil {
+		return nil, nil
+	}
+
+	for _, version := range versions {
+		vm, ok := meta.Versions[version]
+		if !ok {
+			continue
+		}
+		if err := update(key, version, vm); err != nil {
+			return nil, err
+		}
+	}
+	return nil, b.writeKeyMetadata(req.Storage, meta)
+}
+
+func (b *VersionedKVBackend) handleDeleteVersions(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	now := time.Now().UTC()
+	return b.updateVersions(req, data, func(key string, version uint64, vm *kvVersionMetadata) error {
+		if vm.DeletionTime.IsZero() {
+			vm.DeletionTime = now
+		}
+		return nil
+	})
+}
+
+func (b *VersionedKVBackend) handleUndeleteVersions(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	return b.updateVersions(req, data, func(key string, version uint64, vm *kvVersionMetadata) error {
+		if !vm.Destroyed {
+			vm.DeletionTime = time.Time{}
+		}
+		return nil
+	})
+}
+
+func (b *VersionedKVBackend) handleDestroyVersions(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	return b.updateVersions(req, data, func(key string, version uint64, vm *kvVersionMetadata) error {
+		if vm.Destroyed {
+			return nil
+		}
+		if err := req.Storage.Delete(versionPath(key, version)); err != nil {
+			return err
+		}
+		vm.Destroyed = true
+		return nil
+	})
+}
+
+func (b *VersionedKVBackend) handleMetadataRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	key := data.Get("path").(string)
+	if key == "" {
+		return logical.ErrorResponse("missing path"), logical.ErrInvalidRequest
+	}
+
+	lock := locksutil.LockForKey(b.locks, key)
+	lock.RLock()
+	defer lock.RUnlock()
+
+	meta, err := b.keyMetadata(req.Storage, key)
+	if err != nil {
+		return nil, err
+	}
+	if meta == nil || meta.expired(time.Now()) {
+		return nil, nil
+	}
+
+	versions := make(map[string]interface{}, len(meta.Versions))
+	for version, vm := range meta.Versions {
+		versions[strconv.FormatUint(version, 10)] = versionMetadataResponse(version, vm)
+	}
+	expireTime := ""
+	if !meta.ExpireTime.IsZero() {
+		expireTime = meta.ExpireTime.Format(time.RFC3339Nano)
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"versions":        versions,
+			"current_version": meta.CurrentVersion,
+			"oldest_version":  meta.OldestVersion,
+			"max_versions":    meta.MaxVersions,
+			"cas_required":    meta.CASRequired,
+			"created_time":    meta.CreatedTime.Format(time.RFC3339Nano),
+			"updated_time":    meta.UpdatedTime.Format(time.RFC3339Nano),
+			"delete_after":    int64(meta.DeleteAfter.Seconds()),
+			"expire_time":     expireTime,
+		},
+		CacheControl: &logical.CacheControl{},
+	}, nil
+}
+
+func (b *VersionedKVBackend) handleMetadataWrite(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	key := data.Get("path").(string)
+	if key == "" {
+		return logical.ErrorResponse("missing path"), logical.ErrInvalidRequest
+	}
+
+	config, err := b.config(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	lock := locksutil.LockForKey(b.locks, key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	meta, err := b.keyMetadata(req.Storage, key)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	if meta == nil {
+		meta, err = b.newKeyMetadata(req.Storage, key, now)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if maxVersionsRaw, ok := data.GetOk("max_versions"); ok {
+		meta.MaxVersions = maxVersionsRaw.(int)
+		if meta.MaxVersions < 0 {
+			return logical.ErrorResponse("max_versions cannot be negative"), logical.ErrInvalidRequest
+		}
+	}
+	if casRequiredRaw, ok := data.GetOk("cas_required"); ok {
+		meta.CASRequired = casRequiredRaw.(bool)
+	}
+	if deleteAfterRaw, ok := data.GetOk("delete_after"); ok {
+		meta.DeleteAfter = time.Duration(deleteAfterRaw.(int)) * time.Second
+		if meta.DeleteAfter < 0 {
+			return logical.ErrorResponse("delete_after cannot be negative"), logical.ErrInvalidRequest
+		}
+	}
+	meta.UpdatedTime = now
+
+	if err := b.trimVersions(req.Storage, meta, config); err != nil {
+		return nil, err
+	}
+	if err := b.updateExpiry(req.Storage, meta); err != nil {
+		return nil, err
+	}
+	return nil, b.writeKeyMetadata(req.Storage, meta)
+}
+
+func (b *VersionedKVBackend) handleMetadataDelete(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	key := data.Get("path").(string)
+	if key == "" {
+		return logical.ErrorResponse("missing path"), logical.ErrInvalidRequest
+	}
+
+	lock := locksutil.LockForKey(b.locks, key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	meta, err := b.keyMetadata(req.Storage, key)
+	if err != nil {
+		return nil, err
+	}
+	if meta == nil {
+		return nil, nil
+	}
+	return nil, b.deleteKey(req.Storage, meta)
+}
+
+// deleteKey removes all versions of the key, its expiry and its metadata,
+// leaving a tombstone with its last version
+func (b *VersionedKVBackend) deleteKey(s logical.Storage, meta *kvKeyMetadata) error {
+	entry, err := logical.StorageEntryJSON(tombstonePath(meta.Key), &kvTombstone{
+		CurrentVersion: meta.CurrentVersion,
+	})
+	if err != nil {
+		return err
+	}
+	if err := s.Put(entry); err != nil {
+		return err
+	}
+
+	for version := range meta.Versions {
+		if err := s.Delete(versionPath(meta.Key, version)); err != nil {
+			return err
+		}
+	}
+	if !meta.ExpireTime.IsZero() {
+		if err := s.Delete(expiryPath(meta.Key)); err != nil {
+			return err
+		}
+	}
+	return s.Delete(kvMetadataPrefix + meta.Key)
+}
+
+func (b *VersionedKVBackend) handleMetadataList(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	keys, err := req.Storage.List(kvMetadataPrefix + data.Get("path").(string))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(keys)
+	return logical.ListResponse(keys), nil
+}
+
+func (b *VersionedKVBackend) handleTreeRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	prefix := data.Get("path").(string)
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	depth := data.Get("depth").(int)
+	limit := data.Get("limit").(int)
+	if depth < 0 || limit <= 0 {
+		return logical.ErrorResponse("depth cannot be negative and limit must be positive"), logical.ErrInvalidRequest
+	}
+
+	w := &kvTreeWalker{
+		storage: req.Storage,
+		prefix:  kvMetadataPrefix + prefix,
+		depth:   depth,
+		after:   data.Get("after").(string),
+		limit:   limit,
+		keys:    []string{},
+	}
+
+	// Only the keys of folders the token could list are returned
+	if acl := requestACLFromContext(req); acl != nil {
+		w.listable = func(folder string) bool {
+			return acl.allowMountOperation(req, logical.ListOperation, kvMetadataPrefix+prefix+folder)
+		}
+	}
+
+	if err := w.walk("", 1); err != nil {
+		return nil, err
+	}
+
+	// Keys are nested under their folders. Keys map to nothing, as do
+	// folders beyond the requested depth.
+	tree := make(map[string]interface{})
+	for _, key := range w.keys {
+		parts := strings.SplitAfter(key, "/")
+		if parts[len(parts)-1] == "" {
+			parts = parts[:len(parts)-1]
+		}
+
+		node := tree
+		for _, part := range parts[:len(parts)-1] {
+			child, ok := node[part].(map[string]interface{})
+			if !ok {
+				child = make(map[string]interface{})
+				node[part] = child
+			}
+			node = child
+		}
+		node[parts[len(parts)-1]] = nil
+	}
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"keys": w.keys,
+			"tree": tree,
+		},
+	}
+	if w.truncated {
+		resp.Data["next_after"] = w.keys[len(w.keys)-1]
+	}
+	return resp, nil
+}
+
+// kvTreeWalker lists the keys under a prefix depth-first in sorted order
+type kvTreeWalker struct {
+	storage logical.Storage
+	prefix  string
+	depth   int
+	after   string
+	limit   int
+
+	// listable reports whether the keys directly in a folder can be
+	// returned. Folders that can't be listed are still descended into.
+	listable func(folder string) bool
+
+	keys      []string
+	truncated bool
+}
+
+// walk adds the keys under the folder, relative to the walker's prefix, until
+// the limit is reached
+func (w *kvTreeWalker) walk(folder string, level int) error {
+	children, err := w.storage.List(w.prefix + folder)
+	if err != nil {
+		return err
+	}
+	sort.Strings(children)
+	listable := w.listable == nil || w.listable(folder)
+
+	for _, child := range children {
+		key := folder + child
+		isFolder := strings.HasSuffix(child, "/")
+		descend := isFolder && (w.depth == 0 || level < w.depth)
+
+		// Skip what has already been returned. Folders that contain the
+		// last returned key are resumed.
+		switch {
+		case descend && strings.HasPrefix(w.after, key):
+		case key <= w.after:
+			continue
+		}
+
+		if descend {
+			if err := w.walk(key, level+1); err != nil {
+				return err
+			}
+		} else if listable {
+			if len(w.keys) == w.limit {
+				w.truncated = true
+			} else {
+				w.keys = append(w.keys, key)
+			}
+		}
+		if w.truncated {
+			return nil
+		}
+	}
+	return nil
+}
+
+func versionMetadataResponse(version uint64, vm *kvVersionMetadata) map[string]interface{} {
+	deletionTime := ""
+	if !vm.DeletionTime.IsZero() {
+		deletionTime = vm.DeletionTime.Format(time.RFC3339Nano)
+	}
+	return map[string]interface{}{
+		"version":       version,
+		"created_time":  vm.CreatedTime.Format(time.RFC3339Nano),
+		"deletion_time": deletionTime,
+		"destroyed":     vm.Destroyed,
+	}
+}
+
+const versionedKVHelp = `
+The versioned key/value backend stores a number of versions of each
+key. Data is read and written under "data/", while "metadata/" lists
+keys and shows their versions. Versions can be soft deleted through
+"delete/", restored through "undelete/", and permanently removed
+through "destroy/".
+`
+
+const versionedKVConfigHelpSynopsis = `
+Configure the versioned key/value backend.
+`
+
+const versionedKVConfigHelpDescription = `
+This path sets how many versions of each key are kept and whether writes
+must use check-and-set. Settings on a key's metadata take precedence.
+`
+
+const versionedKVDataHelpSynopsis = `
+Read, write, or delete the versions of a key.
+`
+
+const versionedKVDataHelpDescription = `
+Writing to this path stores a new version of the key. If "cas" is
+provided in "options", the write only succeeds if it matches the key's
+current version. Once more versions exist than are configured to be
+kept, the oldest are removed.
+
+Reading returns the current version, or the version given by "version".
+Deleted and destroyed versions cannot be read. Clients that poll a key
+can pass the version they last read as "known_version"; if it is still
+the version to be read, no data is returned and the read is answered
+with a 304. Deleting this path soft
+deletes the current version; it can be restored with "undelete/".
+`
+
+const versionedKVDeleteHelpSynopsis = `
+Soft delete versions of a key.
+`
+
+const versionedKVDeleteHelpDescription = `
+This path marks the given versions of the key as deleted. Their data
+is kept and can be restored with "undelete/".
+`
+
+const versionedKVUndeleteHelpSynopsis = `
+Restore soft deleted versions of a key.
+`
+
+const versionedKVUndeleteHelpDescription = `
+This path restores the given versions of the key if they were deleted.
+Destroyed versions cannot be restored.
+`
+
+const versionedKVDestroyHelpSynopsis = `
+Permanently remove versions of a key.
+`
+
+const versionedKVDestroyHelpDescription = `
+This path permanently removes the data of the given versions of the
+key. The versions remain in the key's metadata marked as destroyed.
+`
+
+const versionedKVTreeHelpSynopsis = `
+List all keys under a prefix.
+`
+
+const versionedKVTreeHelpDescription = `
+This path returns every key under the prefix in a single request, both as
+a sorted list and nested by folder. "depth" limits how many levels of
+folders are descended into. At most "limit" keys are returned; if more
+remain, "next_after" is set and can be passed as "after" to read the next
+page. Only the keys of folders whose metadata path the token can list
+are returned.
+`
+
+const versionedKVMetadataHelpSynopsis = `
+Read, configure, list, or remove keys and their versions.
+`
+
+const versionedKVMetadataHelpDescription = `
+Reading this path returns the versions of the key and its settings,
+and listing it returns the keys below it. Writing sets how many
+versions of the key are kept and whether writes to it must use
+check-and-set. Deleting this path removes the key and all of its
+versions permanently.
+`
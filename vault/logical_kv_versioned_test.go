@@ -0,0 +1,720 @@
+package vault
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/audit"
+	"github.com/hashicorp/vault/logical"
+)
+
+func testVersionedKVBackend(t *testing.T) (logical.Backend, logical.Storage) {
+	b, err := PassthroughBackendFactory(&logical.BackendConfig{
+		Logger: nil,
+		System: logical.StaticSystemView{
+			DefaultLeaseTTLVal: time.Hour * 24,
+			MaxLeaseTTLVal:     time.Hour * 24 * 32,
+		},
+		Config: map[string]string{
+			"version": "2",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := b.(*VersionedKVBackend); !ok {
+		t.Fatalf("expected a versioned backend, got %T", b)
+	}
+	return b, &logical.InmemStorage{}
+}
+
+func testVersionedKVRequest(t *testing.T, b logical.Backend, s logical.Storage, op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: op,
+		Path:      path,
+		Data:      data,
+		Storage:   s,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("%s %s: resp: %#v err: %v", op, path, resp, err)
+	}
+	return resp
+}
+
+func testVersionedKVWrite(t *testing.T, b logical.Backend, s logical.Storage, path, value string) {
+	testVersionedKVRequest(t, b, s, logical.UpdateOperation, "data/"+path, map[string]interface{}{
+		"data": map[string]interface{}{
+			"value": value,
+		},
+	})
+}
+
+// testVersionedKVRead returns the value of the version of the key, or "" if
+// it can't be read
+func testVersionedKVRead(t *testing.T, b logical.Backend, s logical.Storage, path string, version int) string {
+	data := map[string]interface{}{}
+	if version != 0 {
+		data["version"] = version
+	}
+	resp := testVersionedKVRequest(t, b, s, logical.ReadOperation, "data/"+path, data)
+	if resp == nil {
+		return ""
+	}
+	return resp.Data["data"].(map[string]interface{})["value"].(string)
+}
+
+func TestVersionedKV_UnsupportedVersion(t *testing.T) {
+	_, err := PassthroughBackendFactory(&logical.BackendConfig{
+		Config: map[string]string{
+			"version": "3",
+		},
+	})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestVersionedKV_Versions(t *testing.T) {
+	b, s := testVersionedKVBackend(t)
+
+	for _, value := range []string{"one", "two", "three"} {
+		testVersionedKVWrite(t, b, s, "foo", value)
+	}
+
+	if value := testVersionedKVRead(t, b, s, "foo", 0); value != "three" {
+		t.Fatalf("bad: %q", value)
+	}
+	if value := testVersionedKVRead(t, b, s, "foo", 1); value != "one" {
+		t.Fatalf("bad: %q", value)
+	}
+
+	resp := testVersionedKVRequest(t, b, s, logical.ReadOperation, "data/foo", nil)
+	if resp.Data["metadata"].(map[string]interface{})["version"] != uint64(3) {
+		t.Fatalf("bad: %#v", resp.Data["metadata"])
+	}
+	if resp.CacheControl == nil || resp.CacheControl.ETag == "" {
+		t.Fatalf("expected the read to be cacheable: %#v", resp.CacheControl)
+	}
+
+	// Reading the version the client already has returns no data
+	resp = testVersionedKVRequest(t, b, s, logical.ReadOperation, "data/foo", map[string]interface{}{
+		"known_version": 3,
+	})
+	if resp.Data != nil || !resp.CacheControl.NotModified {
+		t.Fatalf("bad: %#v", resp)
+	}
+	resp = testVersionedKVRequest(t, b, s, logical.ReadOperation, "data/foo", map[string]interface{}{
+		"known_version": 2,
+	})
+	if resp.Data == nil || resp.CacheControl.NotModified {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	resp = testVersionedKVRequest(t, b, s, logical.ReadOperation, "metadata/foo", nil)
+	if resp.Data["current_version"] != uint64(3) || resp.Data["oldest_version"] != uint64(1) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if len(resp.Data["versions"].(map[string]interface{})) != 3 {
+		t.Fatalf("bad: %#v", resp.Data["versions"])
+	}
+
+	resp = testVersionedKVRequest(t, b, s, logical.ListOperation, "metadata/", nil)
+	if !reflect.DeepEqual(resp.Data["keys"], []string{"foo"}) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+}
+
+func TestVersionedKV_Recreate(t *testing.T) {
+	b, s := testVersionedKVBackend(t)
+
+	testVersionedKVWrite(t, b, s, "foo", "one")
+	testVersionedKVRequest(t, b, s, logical.DeleteOperation, "metadata/foo", nil)
+
+	// A key written anew continues the version numbers of the removed one,
+	// so the version a client knows from before doesn't match
+	resp := testVersionedKVRequest(t, b, s, logical.UpdateOperation, "data/foo", map[string]interface{}{
+		"data":    map[string]interface{}{"value": "two"},
+		"options": map[string]interface{}{"cas": 0},
+	})
+	if resp.Data["version"] != uint64(2) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	resp = testVersionedKVRequest(t, b, s, logical.ReadOperation, "data/foo", map[string]interface{}{
+		"known_version": 1,
+	})
+	if resp.Data == nil || resp.CacheControl.NotModified {
+		t.Fatalf("bad: %#v", resp)
+	}
+	if value := testVersionedKVRead(t, b, s, "foo", 1); value != "" {
+		t.Fatalf("expected the removed version to be gone: %q", value)
+	}
+
+	resp = testVersionedKVRequest(t, b, s, logical.ReadOperation, "metadata/foo", nil)
+	if resp.Data["current_version"] != uint64(2) || resp.Data["oldest_version"] != uint64(2) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+}
+
+func TestVersionedKV_MaxVersions(t *testing.T) {
+	b, s := testVersionedKVBackend(t)
+
+	testVersionedKVRequest(t, b, s, logical.UpdateOperation, "config", map[string]interface{}{
+		"max_versions": 3,
+	})
+	for _, value := range []string{"one", "two", "three", "four"} {
+		testVersionedKVWrite(t, b, s, "foo", value)
+	}
+
+	if value := testVersionedKVRead(t, b, s, "foo", 1); value != "" {
+		t.Fatalf("expected version 1 to be removed, got %q", value)
+	}
+	if value := testVersionedKVRead(t, b, s, "foo", 2); value != "two" {
+		t.Fatalf("bad: %q", value)
+	}
+
+	// The key's own setting takes precedence over the mount's
+	testVersionedKVRequest(t, b, s, logical.UpdateOperation, "metadata/foo", map[string]interface{}{
+		"max_versions": 1,
+	})
+	resp := testVersionedKVRequest(t, b, s, logical.ReadOperation, "metadata/foo", nil)
+	if resp.Data["oldest_version"] != uint64(4) || len(resp.Data["versions"].(map[string]interface{})) != 1 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	keys, err := s.List(kvVersionsPrefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	versions, err := s.List(kvVersionsPrefix + keys[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(versions, []string{"4"}) {
+		t.Fatalf("bad: %#v", versions)
+	}
+}
+
+func TestVersionedKV_DeleteUndeleteDestroy(t *testing.T) {
+	b, s := testVersionedKVBackend(t)
+
+	testVersionedKVWrite(t, b, s, "foo", "one")
+	testVersionedKVWrite(t, b, s, "foo", "two")
+
+	// Deleting the key soft deletes the current version only
+	testVersionedKVRequest(t, b, s, logical.DeleteOperation, "data/foo", nil)
+	if value := testVersionedKVRead(t, b, s, "foo", 0); value != "" {
+		t.Fatalf("expected the current version to be deleted, got %q", value)
+	}
+	if value := testVersionedKVRead(t, b, s, "foo", 1); value != "one" {
+		t.Fatalf("bad: %q", value)
+	}
+
+	testVersionedKVRequest(t, b, s, logical.UpdateOperation, "undelete/foo", map[string]interface{}{
+		"versions": "2",
+	})
+	if value := testVersionedKVRead(t, b, s, "foo", 0); value != "two" {
+		t.Fatalf("bad: %q", value)
+	}
+
+	testVersionedKVRequest(t, b, s, logical.UpdateOperation, "delete/foo", map[string]interface{}{
+		"versions": "1,2",
+	})
+	if value := testVersionedKVRead(t, b, s, "foo", 1); value != "" {
+		t.Fatalf("expected version 1 to be deleted, got %q", value)
+	}
+
+	// Destroyed versions can't be restored
+	testVersionedKVRequest(t, b, s, logical.UpdateOperation, "destroy/foo", map[string]interface{}{
+		"versions": "1",
+	})
+	testVersionedKVRequest(t, b, s, logical.UpdateOperation, "undelete/foo", map[string]interface{}{
+		"versions": "1,2",
+	})
+	if value := testVersionedKVRead(t, b, s, "foo", 1); value != "" {
+		t.Fatalf("expected version 1 to be destroyed, got %q", value)
+	}
+	if value := testVersionedKVRead(t, b, s, "foo", 2); value != "two" {
+		t.Fatalf("bad: %q", value)
+	}
+	entry, err := s.Get(versionPath("foo", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry != nil {
+		t.Fatal("expected the data of version 1 to be removed")
+	}
+
+	// Deleting the metadata removes everything but the last version number
+	testVersionedKVRequest(t, b, s, logical.DeleteOperation, "metadata/foo", nil)
+	keys, err := logical.CollectKeys(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 || keys[0] != tombstonePath("foo") {
+		t.Fatalf("bad: %#v", keys)
+	}
+}
+
+func TestVersionedKV_CheckAndSet(t *testing.T) {
+	b, s := testVersionedKVBackend(t)
+
+	write := func(cas int) (*logical.Response, error) {
+		return b.HandleRequest(&logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "data/foo",
+			Data: map[string]interface{}{
+				"data": map[string]interface{}{
+					"value": "bar",
+				},
+				"options": map[string]interface{}{
+					"cas": cas,
+				},
+			},
+			Storage: s,
+		})
+	}
+
+	if resp, err := write(0); err != nil || resp.IsError() {
+		t.Fatalf("resp: %#v err: %v", resp, err)
+	}
+	if resp, err := write(0); err == nil || !resp.IsError() {
+		t.Fatal("expected a cas mismatch")
+	}
+	if resp, err := write(1); err != nil || resp.IsError() {
+		t.Fatalf("resp: %#v err: %v", resp, err)
+	}
+
+	testVersionedKVRequest(t, b, s, logical.UpdateOperation, "metadata/foo", map[string]interface{}{
+		"cas_required": true,
+	})
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "data/foo",
+		Data: map[string]interface{}{
+			"data": map[string]interface{}{
+				"value": "baz",
+			},
+		},
+		Storage: s,
+	})
+	if err == nil || !resp.IsError() {
+		t.Fatal("expected the write without cas to fail")
+	}
+}
+
+func TestVersionedKV_Patch(t *testing.T) {
+	b, s := testVersionedKVBackend(t)
+
+	patch := func(data map[string]interface{}) (*logical.Response, error) {
+		return b.HandleRequest(&logical.Request{
+			Operation: logical.PatchOperation,
+			Path:      "data/foo",
+			Data:      data,
+			Storage:   s,
+		})
+	}
+
+	// A key which doesn't exist can't be patched
+	_, err := patch(map[string]interface{}{
+		"data": map[string]interface{}{"value": "bar"},
+	})
+	if coded, ok := err.(logical.HTTPCodedError); !ok || coded.Code() != 404 {
+		t.Fatalf("bad: %#v", err)
+	}
+
+	testVersionedKVRequest(t, b, s, logical.UpdateOperation, "data/foo", map[string]interface{}{
+		"data": map[string]interface{}{
+			"value":   "bar",
+			"removed": "baz",
+		},
+	})
+
+	// The patch is written as a new version
+	resp, err := patch(map[string]interface{}{
+		"data": map[string]interface{}{
+			"added":   "qux",
+			"removed": nil,
+		},
+		"options": map[string]interface{}{
+			"cas": 1,
+		},
+	})
+	if err != nil || resp.IsError() {
+		t.Fatalf("resp: %#v err: %v", resp, err)
+	}
+	if resp.Data["version"] != uint64(2) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	resp = testVersionedKVRequest(t, b, s, logical.ReadOperation, "data/foo", nil)
+	expected := map[string]interface{}{
+		"value": "bar",
+		"added": "qux",
+	}
+	if !reflect.DeepEqual(resp.Data["data"], expected) {
+		t.Fatalf("bad: %#v", resp.Data["data"])
+	}
+
+	// Patches are subject to check-and-set
+	resp, err = patch(map[string]interface{}{
+		"data": map[string]interface{}{"value": "new"},
+		"options": map[string]interface{}{
+			"cas": 1,
+		},
+	})
+	if err == nil || !resp.IsError() {
+		t.Fatal("expected a cas mismatch")
+	}
+
+	// The current version can't be patched once deleted
+	testVersionedKVRequest(t, b, s, logical.DeleteOperation, "data/foo", nil)
+	_, err = patch(map[string]interface{}{
+		"data": map[string]interface{}{"value": "new"},
+	})
+	if coded, ok := err.(logical.HTTPCodedError); !ok || coded.Code() != 404 {
+		t.Fatalf("bad: %#v", err)
+	}
+}
+
+func TestVersionedKV_Mount(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "sys/mounts/versioned")
+	req.ClientToken = root
+	req.Data["type"] = "kv"
+	req.Data["options"] = map[string]interface{}{
+		"version": "2",
+	}
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatal(err)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "versioned/data/foo")
+	req.ClientToken = root
+	req.Data["data"] = map[string]interface{}{
+		"value": "bar",
+	}
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatal(err)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "versioned/data/foo")
+	req.ClientToken = root
+	resp, err := c.HandleRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp == nil || resp.Data["data"].(map[string]interface{})["value"] != "bar" {
+		t.Fatalf("bad: %#v", resp)
+	}
+	if resp.Secret != nil {
+		t.Fatalf("versioned reads should not be leased: %#v", resp.Secret)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "sys/mounts")
+	req.ClientToken = root
+	resp, err = c.HandleRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := resp.Data["versioned/"].(map[string]interface{})["options"]
+	if !reflect.DeepEqual(options, map[string]string{"version": "2"}) {
+		t.Fatalf("bad: %#v", options)
+	}
+}
+
+func TestVersionedKV_Tree(t *testing.T) {
+	b, s := testVersionedKVBackend(t)
+
+	for _, key := range []string{"a", "b/c", "b/d/e", "b/d/f", "g"} {
+		testVersionedKVWrite(t, b, s, key, "value")
+	}
+
+	resp := testVersionedKVRequest(t, b, s, logical.ReadOperation, "tree/", nil)
+	if !reflect.DeepEqual(resp.Data["keys"], []string{"a", "b/c", "b/d/e", "b/d/f", "g"}) {
+		t.Fatalf("bad: %#v", resp.Data["keys"])
+	}
+	expected := map[string]interface{}{
+		"a": nil,
+		"b/": map[string]interface{}{
+			"c": nil,
+			"d/": map[string]interface{}{
+				"e": nil,
+				"f": nil,
+			},
+		},
+		"g": nil,
+	}
+	if !reflect.DeepEqual(resp.Data["tree"], expected) {
+		t.Fatalf("bad: %#v", resp.Data["tree"])
+	}
+	if _, ok := resp.Data["next_after"]; ok {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Folders beyond the depth are returned without their contents
+	resp = testVersionedKVRequest(t, b, s, logical.ReadOperation, "tree/b", map[string]interface{}{
+		"depth": 1,
+	})
+	if !reflect.DeepEqual(resp.Data["keys"], []string{"c", "d/"}) {
+		t.Fatalf("bad: %#v", resp.Data["keys"])
+	}
+	expected = map[string]interface{}{
+		"c":  nil,
+		"d/": nil,
+	}
+	if !reflect.DeepEqual(resp.Data["tree"], expected) {
+		t.Fatalf("bad: %#v", resp.Data["tree"])
+	}
+
+	// Pages continue where the previous one left off
+	var keys []string
+	after := ""
+	for i := 0; ; i++ {
+		if i > 5 {
+			t.Fatal("too many pages")
+		}
+		resp = testVersionedKVRequest(t, b, s, logical.ReadOperation, "tree/", map[string]interface{}{
+			"limit": 2,
+			"after": after,
+		})
+		keys = append(keys, resp.Data["keys"].([]string)...)
+		next, ok := resp.Data["next_after"]
+		if !ok {
+			break
+		}
+		after = next.(string)
+	}
+	if !reflect.DeepEqual(keys, []string{"a", "b/c", "b/d/e", "b/d/f", "g"}) {
+		t.Fatalf("bad: %#v", keys)
+	}
+}
+
+const versionedKVTreePolicy = `
+path "versioned/tree/*" {
+	capabilities = ["read"]
+}
+path "versioned/metadata/*" {
+	capabilities = ["list"]
+}
+path "versioned/metadata/b/secret/*" {
+	capabilities = ["deny"]
+}
+`
+
+func TestVersionedKV_Tree_acl(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "sys/mounts/versioned")
+	req.ClientToken = root
+	req.Data["type"] = "kv"
+	req.Data["options"] = map[string]interface{}{
+		"version": "2",
+	}
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{"a", "b/c", "b/secret/d", "b/secret/e/f"} {
+		req = logical.TestRequest(t, logical.UpdateOperation, "versioned/data/"+key)
+		req.ClientToken = root
+		req.Data["data"] = map[string]interface{}{
+			"value": "bar",
+		}
+		if _, err := c.HandleRequest(req); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	policy, err := Parse(versionedKVTreePolicy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy.Name = "tree"
+	if err := c.policyStore.SetPolicy(policy); err != nil {
+		t.Fatal(err)
+	}
+	testCoreMakeToken(t, c, root, "client", "", []string{"tree"})
+
+	// The keys of folders that can't be listed are left out
+	req = logical.TestRequest(t, logical.ReadOperation, "versioned/tree/")
+	req.ClientToken = "client"
+	resp, err := c.HandleRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(resp.Data["keys"], []string{"a", "b/c"}) {
+		t.Fatalf("bad: %#v", resp.Data["keys"])
+	}
+
+	// Folders beyond the depth are listed by their parent
+	req = logical.TestRequest(t, logical.ReadOperation, "versioned/tree/b")
+	req.ClientToken = "client"
+	req.Data["depth"] = 1
+	resp, err = c.HandleRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(resp.Data["keys"], []string{"c", "secret/"}) {
+		t.Fatalf("bad: %#v", resp.Data["keys"])
+	}
+
+	// The root token sees everything
+	req = logical.TestRequest(t, logical.ReadOperation, "versioned/tree/")
+	req.ClientToken = root
+	resp, err = c.HandleRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(resp.Data["keys"], []string{"a", "b/c", "b/secret/d", "b/secret/e/f"}) {
+		t.Fatalf("bad: %#v", resp.Data["keys"])
+	}
+}
+
+func TestVersionedKV_DeleteAfter(t *testing.T) {
+	b, s := testVersionedKVBackend(t)
+
+	testVersionedKVRequest(t, b, s, logical.UpdateOperation, "data/scratch", map[string]interface{}{
+		"options": map[string]interface{}{
+			"delete_after": "50ms",
+		},
+		"data": map[string]interface{}{
+			"value": "one",
+		},
+	})
+	testVersionedKVWrite(t, b, s, "kept", "value")
+
+	resp := testVersionedKVRequest(t, b, s, logical.ReadOperation, "metadata/scratch", nil)
+	if resp.Data["expire_time"] == "" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Later writes keep the setting
+	testVersionedKVWrite(t, b, s, "scratch", "two")
+	if value := testVersionedKVRead(t, b, s, "scratch", 0); value != "two" {
+		t.Fatalf("bad: %q", value)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	// Expired keys can't be read even before they are swept
+	if value := testVersionedKVRead(t, b, s, "scratch", 0); value != "" {
+		t.Fatalf("expected the key to have expired, got %q", value)
+	}
+	if resp := testVersionedKVRequest(t, b, s, logical.ReadOperation, "metadata/scratch", nil); resp != nil {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// The sweep runs with the rollback of the mount
+	if _, err := b.HandleRequest(&logical.Request{
+		Operation: logical.RollbackOperation,
+		Storage:   s,
+	}); err != nil && err != logical.ErrUnsupportedOperation {
+		t.Fatal(err)
+	}
+
+	for _, prefix := range []string{kvExpiryPrefix, kvVersionsPrefix + keyHash("scratch") + "/"} {
+		keys, err := s.List(prefix)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(keys) != 0 {
+			t.Fatalf("%s: expected no keys, got %v", prefix, keys)
+		}
+	}
+	resp = testVersionedKVRequest(t, b, s, logical.ListOperation, "metadata/", nil)
+	if !reflect.DeepEqual(resp.Data["keys"], []string{"kept"}) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Clearing the setting removes the expiry
+	testVersionedKVRequest(t, b, s, logical.UpdateOperation, "data/scratch", map[string]interface{}{
+		"options": map[string]interface{}{
+			"delete_after": "1h",
+		},
+		"data": map[string]interface{}{
+			"value": "three",
+		},
+	})
+	testVersionedKVRequest(t, b, s, logical.UpdateOperation, "metadata/scratch", map[string]interface{}{
+		"delete_after": 0,
+	})
+	resp = testVersionedKVRequest(t, b, s, logical.ReadOperation, "metadata/scratch", nil)
+	if resp.Data["delete_after"] != int64(0) || resp.Data["expire_time"] != "" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if keys, _ := s.List(kvExpiryPrefix); len(keys) != 0 {
+		t.Fatalf("expected no expiry, got %v", keys)
+	}
+
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "data/scratch",
+		Data: map[string]interface{}{
+			"options": map[string]interface{}{
+				"delete_after": "-1s",
+			},
+			"data": map[string]interface{}{},
+		},
+		Storage: s,
+	})
+	if err == nil || !resp.IsError() {
+		t.Fatal("expected a negative delete_after to fail")
+	}
+}
+
+func TestVersionedKV_DeleteAfterAudited(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	var noop *NoopAudit
+	c.auditBackends["noop"] = func(config *audit.BackendConfig) (audit.Backend, error) {
+		noop = &NoopAudit{
+			Config: config,
+		}
+		return noop, nil
+	}
+	req := logical.TestRequest(t, logical.UpdateOperation, "sys/audit/noop")
+	req.ClientToken = root
+	req.Data["type"] = "noop"
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatal(err)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "sys/mounts/scratch")
+	req.ClientToken = root
+	req.Data["type"] = "kv"
+	req.Data["options"] = map[string]interface{}{
+		"version": "2",
+	}
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatal(err)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "scratch/data/foo")
+	req.ClientToken = root
+	req.Data["options"] = map[string]interface{}{
+		"delete_after": "10ms",
+	}
+	req.Data["data"] = map[string]interface{}{
+		"value": "bar",
+	}
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if err := c.rollback.Rollback("scratch/"); err != nil {
+		t.Fatal(err)
+	}
+
+	var found bool
+	for _, r := range noop.Req {
+		if r.Path == "scratch/metadata/foo" && r.Operation == logical.DeleteOperation {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("expected the removal to be audited")
+	}
+}
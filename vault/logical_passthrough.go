@@ -1,11 +1,18 @@
 package vault
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"strings"
 
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/go-uuid"
 	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/helper/locksutil"
 	"github.com/hashicorp/vault/helper/parseutil"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
@@ -26,8 +33,25 @@ func LeasedPassthroughBackendFactory(conf *logical.BackendConfig) (logical.Backe
 // LeaseSwitchedPassthroughBackend returns a PassthroughBackend
 // with leases switched on or off
 func LeaseSwitchedPassthroughBackend(conf *logical.BackendConfig, leases bool) (logical.Backend, error) {
+	if conf != nil {
+		switch conf.Config["version"] {
+		case "", "1":
+		case "2":
+			return newVersionedKVBackend(conf)
+		default:
+			return nil, fmt.Errorf("unsupported kv version %q", conf.Config["version"])
+		}
+	}
+
+	etagKey, err := uuid.GenerateRandomBytes(32)
+	if err != nil {
+		return nil, err
+	}
+
 	var b PassthroughBackend
 	b.generateLeases = leases
+	b.locks = locksutil.CreateLocks()
+	b.etagKey = etagKey
 	b.Backend = &framework.Backend{
 		Help: strings.TrimSpace(passthroughHelp),
 
@@ -39,6 +63,7 @@ func LeaseSwitchedPassthroughBackend(conf *logical.BackendConfig, leases bool) (
 					logical.ReadOperation:   b.handleRead,
 					logical.CreateOperation: b.handleWrite,
 					logical.UpdateOperation: b.handleWrite,
+					logical.PatchOperation:  b.handlePatch,
 					logical.DeleteOperation: b.handleDelete,
 					logical.ListOperation:   b.handleList,
 				},
@@ -75,6 +100,14 @@ func LeaseSwitchedPassthroughBackend(conf *logical.BackendConfig, leases bool) (
 type PassthroughBackend struct {
 	*framework.Backend
 	generateLeases bool
+
+	// locks serialize the writes of a key with its patches
+	locks []*locksutil.LockEntry
+
+	// etagKey keys the HMAC used as the ETag of reads, so that ETags don't
+	// reveal anything about the values. It is generated when the backend is
+	// created; clients simply fetch the values again when it changes.
+	etagKey []byte
 }
 
 func (b *PassthroughBackend) handleRevoke(
@@ -144,9 +177,34 @@ func (b *PassthroughBackend) handleRead(
 
 	resp.Secret.TTL = ttlDuration
 
+	// Clients that read the same values repeatedly can revalidate them. This
+	// has no effect when a lease is issued.
+	resp.CacheControl = &logical.CacheControl{
+		ETag: b.etag(out.Value),
+	}
+
 	return resp, nil
 }
 
+// etag returns an HMAC of the stored value. A plain hash of the data would let
+// anyone who sees the ETag, such as an intermediate cache, test guesses of
+// the secret values.
+func (b *PassthroughBackend) etag(value []byte) string {
+	mac := hmac.New(sha256.New, b.etagKey)
+	mac.Write(value)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// rejectDeleteAfter returns an error response if the data sets delete_after,
+// which only versioned mounts implement, rather than storing it as a value
+// that would never take effect
+func rejectDeleteAfter(data map[string]interface{}) *logical.Response {
+	if _, ok := data["delete_after"]; !ok {
+		return nil
+	}
+	return logical.ErrorResponse(`"delete_after" is only supported by kv mounts with version 2`)
+}
+
 func (b *PassthroughBackend) GeneratesLeases() bool {
 	return b.generateLeases
 }
@@ -157,11 +215,53 @@ func (b *PassthroughBackend) handleWrite(
 	if len(req.Data) == 0 {
 		return logical.ErrorResponse("missing data fields"), nil
 	}
+	if resp := rejectDeleteAfter(req.Data); resp != nil {
+		return resp, nil
+	}
 
+	lock := locksutil.LockForKey(b.locks, req.Path)
+	lock.Lock()
+	defer lock.Unlock()
+
+	return nil, b.write(req, req.Data)
+}
+
+// handlePatch applies the request data to the secret as a JSON merge patch
+func (b *PassthroughBackend) handlePatch(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	// Check that some fields are given
+	if len(req.Data) == 0 {
+		return logical.ErrorResponse("missing data fields"), nil
+	}
+	if resp := rejectDeleteAfter(req.Data); resp != nil {
+		return resp, nil
+	}
+
+	lock := locksutil.LockForKey(b.locks, req.Path)
+	lock.Lock()
+	defer lock.Unlock()
+
+	out, err := req.Storage.Get(req.Path)
+	if err != nil {
+		return nil, fmt.Errorf("read failed: %v", err)
+	}
+	if out == nil {
+		return nil, logical.CodedError(http.StatusNotFound, fmt.Sprintf("no secret found at %q", req.Path))
+	}
+
+	var rawData map[string]interface{}
+	if err := jsonutil.DecodeJSON(out.Value, &rawData); err != nil {
+		return nil, fmt.Errorf("json decoding failed: %v", err)
+	}
+
+	return nil, b.write(req, framework.MergePatch(rawData, req.Data))
+}
+
+func (b *PassthroughBackend) write(req *logical.Request, data map[string]interface{}) error {
 	// JSON encode the data
-	buf, err := json.Marshal(req.Data)
+	buf, err := json.Marshal(data)
 	if err != nil {
-		return nil, fmt.Errorf("json encoding failed: %v", err)
+		return fmt.Errorf("json encoding failed: %v", err)
 	}
 
 	// Write out a new key
@@ -170,10 +270,10 @@ func (b *PassthroughBackend) handleWrite(
 		Value: buf,
 	}
 	if err := req.Storage.Put(entry); err != nil {
-		return nil, fmt.Errorf("failed to write: %v", err)
+		return errwrap.Wrapf("failed to write: {{err}}", err)
 	}
 
-	return nil, nil
+	return nil
 }
 
 func (b *PassthroughBackend) handleDelete(
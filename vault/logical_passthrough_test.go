@@ -43,6 +43,15 @@ func TestPassthroughBackend_Write(t *testing.T) {
 		if out == nil {
 			t.Fatalf("failed to write to view")
 		}
+
+		// Only versioned mounts expire keys
+		req = logical.TestRequest(t, logical.UpdateOperation, "bar")
+		req.Data["raw"] = "test"
+		req.Data["delete_after"] = "1h"
+		resp, err = b.HandleRequest(req)
+		if err != nil || resp == nil || !resp.IsError() {
+			t.Fatalf("expected delete_after to be rejected: resp: %#v err: %v", resp, err)
+		}
 	}
 	b := testPassthroughBackend()
 	test(b)
@@ -107,11 +116,16 @@ func TestPassthroughBackend_Read(t *testing.T) {
 				"raw":   "test",
 				ttlType: reqTTL,
 			},
+			CacheControl: &logical.CacheControl{},
 		}
 
 		if !leased {
 			expected.Secret.Renewable = false
 		}
+		if resp.CacheControl == nil || resp.CacheControl.ETag == "" {
+			t.Fatalf("expected the read to carry an ETag: %#v", resp.CacheControl)
+		}
+		expected.CacheControl.ETag = resp.CacheControl.ETag
 		resp.Secret.InternalData = nil
 		resp.Secret.LeaseID = ""
 		if !reflect.DeepEqual(resp, expected) {
@@ -162,6 +176,60 @@ func TestPassthroughBackend_Delete(t *testing.T) {
 	test(b)
 }
 
+func TestPassthroughBackend_Patch(t *testing.T) {
+	test := func(b logical.Backend) {
+		req := logical.TestRequest(t, logical.PatchOperation, "foo")
+		req.Data["raw"] = "test"
+		storage := req.Storage
+
+		// A secret which doesn't exist can't be patched
+		_, err := b.HandleRequest(req)
+		if coded, ok := err.(logical.HTTPCodedError); !ok || coded.Code() != 404 {
+			t.Fatalf("bad: %#v", err)
+		}
+
+		req = logical.TestRequest(t, logical.UpdateOperation, "foo")
+		req.Storage = storage
+		req.Data["raw"] = "test"
+		req.Data["removed"] = "value"
+		req.Data["nested"] = map[string]interface{}{"a": "b"}
+		if _, err := b.HandleRequest(req); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		req = logical.TestRequest(t, logical.PatchOperation, "foo")
+		req.Storage = storage
+		req.Data["raw"] = "patched"
+		req.Data["removed"] = nil
+		req.Data["nested"] = map[string]interface{}{"c": "d"}
+		resp, err := b.HandleRequest(req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if resp != nil {
+			t.Fatalf("bad: %v", resp)
+		}
+
+		req = logical.TestRequest(t, logical.ReadOperation, "foo")
+		req.Storage = storage
+		resp, err = b.HandleRequest(req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		expected := map[string]interface{}{
+			"raw":    "patched",
+			"nested": map[string]interface{}{"a": "b", "c": "d"},
+		}
+		if !reflect.DeepEqual(resp.Data, expected) {
+			t.Fatalf("bad: %#v", resp.Data)
+		}
+	}
+	b := testPassthroughBackend()
+	test(b)
+	b = testPassthroughLeasedBackend()
+	test(b)
+}
+
 func TestPassthroughBackend_List(t *testing.T) {
 	test := func(b logical.Backend) {
 		req := logical.TestRequest(t, logical.UpdateOperation, "foo")
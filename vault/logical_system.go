@@ -4,14 +4,22 @@ import (
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/fatih/structs"
 	"github.com/hashicorp/vault/helper/consts"
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/helper/locksutil"
 	"github.com/hashicorp/vault/helper/parseutil"
+	"github.com/hashicorp/vault/helper/passwordpolicy"
 	"github.com/hashicorp/vault/helper/wrapping"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
@@ -47,7 +55,8 @@ var (
 
 func NewSystemBackend(core *Core) *SystemBackend {
 	b := &SystemBackend{
-		Core: core,
+		Core:              core,
+		wrappedEntryLocks: locksutil.CreateLocks(),
 	}
 
 	b.Backend = &framework.Backend{
@@ -59,12 +68,25 @@ func NewSystemBackend(core *Core) *SystemBackend {
 				"remount",
 				"audit",
 				"audit/*",
+				"audit-verify/*",
 				"raw",
 				"raw/*",
+				"chaos",
+				"chaos/*",
+				"storage/raft/*",
 				"replication/primary/secondary-token",
 				"replication/reindex",
 				"rotate",
+				"rotate/config",
+				"rewrap",
+				"sealwrap/rewrap",
+				"emergency-seal/config",
 				"config/cors",
+				"config/mount-import",
+				"config/reload",
+				"config/state",
+				"metrics",
+				"host-info",
 				"config/auditing/*",
 				"plugins/catalog/*",
 				"revoke-prefix/*",
@@ -72,6 +94,11 @@ func NewSystemBackend(core *Core) *SystemBackend {
 				"leases/revoke-prefix/*",
 				"leases/revoke-force/*",
 				"leases/lookup/*",
+				"leases/lookup-artifact",
+				"leases/irrevocable",
+				"leases/irrevocable/*",
+				"in-flight-req",
+				"in-flight-req/*",
 			},
 
 			Unauthenticated: []string{
@@ -128,8 +155,72 @@ func NewSystemBackend(core *Core) *SystemBackend {
 					logical.DeleteOperation: b.handleCORSDelete,
 				},
 
-				HelpDescription: strings.TrimSpace(sysHelp["config/cors"][0]),
-				HelpSynopsis:    strings.TrimSpace(sysHelp["config/cors"][1]),
+				HelpSynopsis:    strings.TrimSpace(sysHelp["config/cors"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["config/cors"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "config/mount-import$",
+
+				Fields: map[string]*framework.FieldSchema{
+					"trusted_keys": &framework.FieldSchema{
+						Type:        framework.TypeCommaStringSlice,
+						Description: strings.TrimSpace(sysHelp["mount_import_trusted_keys"][0]),
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation:   b.handleMountImportConfigRead,
+					logical.UpdateOperation: b.handleMountImportConfigUpdate,
+					logical.DeleteOperation: b.handleMountImportConfigDelete,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["config/mount-import"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["config/mount-import"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "config/reload$",
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.UpdateOperation: b.handleConfigReload,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["config/reload"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["config/reload"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "config/state$",
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation: b.handleConfigStateRead,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["config/state"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["config/state"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "metrics$",
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation: b.handleMetricsRead,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["metrics"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["metrics"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "host-info$",
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation: b.handleHostInfoRead,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["host-info"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["host-info"][1]),
 			},
 
 			&framework.Path{
@@ -235,6 +326,14 @@ func NewSystemBackend(core *Core) *SystemBackend {
 						Type:        framework.TypeString,
 						Description: strings.TrimSpace(sysHelp["auth_desc"][0]),
 					},
+					"auto_create_group_aliases": &framework.FieldSchema{
+						Type:        framework.TypeBool,
+						Description: strings.TrimSpace(sysHelp["auto_create_group_aliases"][0]),
+					},
+					"token_type": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["token_type"][0]),
+					},
 				},
 				Callbacks: map[logical.Operation]framework.OperationFunc{
 					logical.ReadOperation:   b.handleAuthTuneRead,
@@ -275,6 +374,58 @@ func NewSystemBackend(core *Core) *SystemBackend {
 				HelpDescription: strings.TrimSpace(sysHelp["mount_tune"][1]),
 			},
 
+			&framework.Path{
+				Pattern: "mounts/(?P<path>.+?)/export-config$",
+
+				Fields: map[string]*framework.FieldSchema{
+					"path": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["mount_path"][0]),
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation: b.handleMountExportConfig,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["mount_export_config"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["mount_export_config"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "mounts/(?P<path>.+?)/import-config$",
+
+				Fields: map[string]*framework.FieldSchema{
+					"path": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["mount_path"][0]),
+					},
+					"document": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["mount_import_document"][0]),
+					},
+					"signature": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["mount_import_signature"][0]),
+					},
+					"apply": &framework.FieldSchema{
+						Type:        framework.TypeBool,
+						Description: strings.TrimSpace(sysHelp["mount_import_apply"][0]),
+					},
+					"prune": &framework.FieldSchema{
+						Type:        framework.TypeBool,
+						Description: strings.TrimSpace(sysHelp["mount_import_prune"][0]),
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.UpdateOperation: b.handleMountImportConfig,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["mount_import_config"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["mount_import_config"][1]),
+			},
+
 			&framework.Path{
 				Pattern: "mounts/(?P<path>.+?)",
 
@@ -300,10 +451,19 @@ func NewSystemBackend(core *Core) *SystemBackend {
 						Default:     false,
 						Description: strings.TrimSpace(sysHelp["mount_local"][0]),
 					},
+					"seal_wrap": &framework.FieldSchema{
+						Type:        framework.TypeBool,
+						Default:     false,
+						Description: strings.TrimSpace(sysHelp["seal_wrap"][0]),
+					},
 					"plugin_name": &framework.FieldSchema{
 						Type:        framework.TypeString,
 						Description: strings.TrimSpace(sysHelp["mount_plugin_name"][0]),
 					},
+					"options": &framework.FieldSchema{
+						Type:        framework.TypeMap,
+						Description: strings.TrimSpace(sysHelp["mount_options"][0]),
+					},
 				},
 
 				Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -356,6 +516,18 @@ func NewSystemBackend(core *Core) *SystemBackend {
 						Type:        framework.TypeString,
 						Description: strings.TrimSpace(sysHelp["leases-list-prefix"][0]),
 					},
+					"entity_id": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["leases-list-entity-id"][0]),
+					},
+					"expiring_within": &framework.FieldSchema{
+						Type:        framework.TypeDurationSecond,
+						Description: strings.TrimSpace(sysHelp["leases-list-expiring-within"][0]),
+					},
+					"recursive": &framework.FieldSchema{
+						Type:        framework.TypeBool,
+						Description: strings.TrimSpace(sysHelp["leases-list-recursive"][0]),
+					},
 				},
 
 				Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -384,6 +556,28 @@ func NewSystemBackend(core *Core) *SystemBackend {
 				HelpDescription: strings.TrimSpace(sysHelp["leases"][1]),
 			},
 
+			&framework.Path{
+				Pattern: "leases/lookup-artifact",
+
+				Fields: map[string]*framework.FieldSchema{
+					"type": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["leases-artifact-type"][0]),
+					},
+					"id": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["leases-artifact-id"][0]),
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.UpdateOperation: b.handleLeaseLookupArtifact,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["leases-lookup-artifact"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["leases-lookup-artifact"][1]),
+			},
+
 			&framework.Path{
 				Pattern: "(leases/)?renew" + framework.OptionalParamRegex("url_lease_id"),
 
@@ -468,6 +662,35 @@ func NewSystemBackend(core *Core) *SystemBackend {
 				HelpDescription: strings.TrimSpace(sysHelp["revoke-prefix"][1]),
 			},
 
+			&framework.Path{
+				Pattern: "leases/irrevocable/?$",
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation: b.handleLeasesIrrevocable,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["leases-irrevocable"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["leases-irrevocable"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "leases/irrevocable/remove$",
+
+				Fields: map[string]*framework.FieldSchema{
+					"lease_id": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["lease_id"][0]),
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.UpdateOperation: b.handleLeasesIrrevocableRemove,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["leases-irrevocable-remove"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["leases-irrevocable-remove"][1]),
+			},
+
 			&framework.Path{
 				Pattern: "leases/tidy$",
 
@@ -479,6 +702,25 @@ func NewSystemBackend(core *Core) *SystemBackend {
 				HelpDescription: strings.TrimSpace(sysHelp["tidy_leases"][1]),
 			},
 
+			&framework.Path{
+				Pattern: "tokens/tidy$",
+
+				Fields: map[string]*framework.FieldSchema{
+					"dry_run": &framework.FieldSchema{
+						Type:        framework.TypeBool,
+						Default:     false,
+						Description: strings.TrimSpace(sysHelp["tidy_tokens_dry_run"][0]),
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.UpdateOperation: b.handleTidyTokens,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["tidy_tokens"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["tidy_tokens"][1]),
+			},
+
 			&framework.Path{
 				Pattern: "auth$",
 
@@ -515,6 +757,11 @@ func NewSystemBackend(core *Core) *SystemBackend {
 						Default:     false,
 						Description: strings.TrimSpace(sysHelp["mount_local"][0]),
 					},
+					"seal_wrap": &framework.FieldSchema{
+						Type:        framework.TypeBool,
+						Default:     false,
+						Description: strings.TrimSpace(sysHelp["seal_wrap"][0]),
+					},
 					"plugin_name": &framework.FieldSchema{
 						Type:        framework.TypeString,
 						Description: strings.TrimSpace(sysHelp["auth_plugin"][0]),
@@ -567,357 +814,991 @@ func NewSystemBackend(core *Core) *SystemBackend {
 			},
 
 			&framework.Path{
-				Pattern:         "seal-status$",
-				HelpSynopsis:    strings.TrimSpace(sysHelp["seal-status"][0]),
-				HelpDescription: strings.TrimSpace(sysHelp["seal-status"][1]),
-			},
+				Pattern: "namespaces/?$",
 
-			&framework.Path{
-				Pattern:         "seal$",
-				HelpSynopsis:    strings.TrimSpace(sysHelp["seal"][0]),
-				HelpDescription: strings.TrimSpace(sysHelp["seal"][1]),
-			},
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ListOperation: b.handleNamespaceList,
+				},
 
-			&framework.Path{
-				Pattern:         "unseal$",
-				HelpSynopsis:    strings.TrimSpace(sysHelp["unseal"][0]),
-				HelpDescription: strings.TrimSpace(sysHelp["unseal"][1]),
+				HelpSynopsis:    strings.TrimSpace(sysHelp["namespace-list"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["namespace-list"][1]),
 			},
 
 			&framework.Path{
-				Pattern: "audit-hash/(?P<path>.+)",
+				Pattern: "namespaces/" + framework.GenericNameRegex("name") + "$",
 
 				Fields: map[string]*framework.FieldSchema{
-					"path": &framework.FieldSchema{
+					"name": &framework.FieldSchema{
 						Type:        framework.TypeString,
-						Description: strings.TrimSpace(sysHelp["audit_path"][0]),
-					},
-
-					"input": &framework.FieldSchema{
-						Type: framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["namespace-name"][0]),
 					},
 				},
 
 				Callbacks: map[logical.Operation]framework.OperationFunc{
-					logical.UpdateOperation: b.handleAuditHash,
+					logical.ReadOperation:   b.handleNamespaceRead,
+					logical.UpdateOperation: b.handleNamespaceSet,
+					logical.DeleteOperation: b.handleNamespaceDelete,
 				},
 
-				HelpSynopsis:    strings.TrimSpace(sysHelp["audit-hash"][0]),
-				HelpDescription: strings.TrimSpace(sysHelp["audit-hash"][1]),
+				HelpSynopsis:    strings.TrimSpace(sysHelp["namespace"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["namespace"][1]),
 			},
 
 			&framework.Path{
-				Pattern: "audit$",
+				Pattern: "policies/password/?$",
 
 				Callbacks: map[logical.Operation]framework.OperationFunc{
-					logical.ReadOperation: b.handleAuditTable,
+					logical.ListOperation: b.handlePasswordPolicyList,
 				},
 
-				HelpSynopsis:    strings.TrimSpace(sysHelp["audit-table"][0]),
-				HelpDescription: strings.TrimSpace(sysHelp["audit-table"][1]),
+				HelpSynopsis:    strings.TrimSpace(sysHelp["password-policy-list"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["password-policy-list"][1]),
 			},
 
 			&framework.Path{
-				Pattern: "audit/(?P<path>.+)",
+				Pattern: "policies/password/" + framework.GenericNameRegex("name") + "/generate$",
 
 				Fields: map[string]*framework.FieldSchema{
-					"path": &framework.FieldSchema{
+					"name": &framework.FieldSchema{
 						Type:        framework.TypeString,
-						Description: strings.TrimSpace(sysHelp["audit_path"][0]),
+						Description: strings.TrimSpace(sysHelp["password-policy-name"][0]),
 					},
-					"type": &framework.FieldSchema{
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation: b.handlePasswordPolicyGenerate,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["password-policy-generate"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["password-policy-generate"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "policies/password/" + framework.GenericNameRegex("name") + "$",
+
+				Fields: map[string]*framework.FieldSchema{
+					"name": &framework.FieldSchema{
 						Type:        framework.TypeString,
-						Description: strings.TrimSpace(sysHelp["audit_type"][0]),
+						Description: strings.TrimSpace(sysHelp["password-policy-name"][0]),
 					},
-					"description": &framework.FieldSchema{
+					"length": &framework.FieldSchema{
+						Type:        framework.TypeInt,
+						Default:     20,
+						Description: "Length of the generated passwords, and minimum length of the validated ones. Defaults to 20.",
+					},
+					"min_lowercase": &framework.FieldSchema{
+						Type:        framework.TypeInt,
+						Description: "Minimum number of lowercase letters.",
+					},
+					"min_uppercase": &framework.FieldSchema{
+						Type:        framework.TypeInt,
+						Description: "Minimum number of uppercase letters.",
+					},
+					"min_digits": &framework.FieldSchema{
+						Type:        framework.TypeInt,
+						Description: "Minimum number of digits.",
+					},
+					"min_symbols": &framework.FieldSchema{
+						Type:        framework.TypeInt,
+						Description: "Minimum number of symbols.",
+					},
+					"symbols": &framework.FieldSchema{
 						Type:        framework.TypeString,
-						Description: strings.TrimSpace(sysHelp["audit_desc"][0]),
+						Description: "Characters of the symbol class. Defaults to " + passwordpolicy.DefaultSymbols,
 					},
-					"options": &framework.FieldSchema{
-						Type:        framework.TypeMap,
-						Description: strings.TrimSpace(sysHelp["audit_opts"][0]),
+					"banned_passwords": &framework.FieldSchema{
+						Type:        framework.TypeCommaStringSlice,
+						Description: "Passwords that are rejected regardless of case.",
 					},
-					"local": &framework.FieldSchema{
-						Type:        framework.TypeBool,
-						Default:     false,
-						Description: strings.TrimSpace(sysHelp["mount_local"][0]),
+					"history": &framework.FieldSchema{
+						Type:        framework.TypeInt,
+						Description: "Number of previous passwords of a user that can't be reused.",
 					},
 				},
 
 				Callbacks: map[logical.Operation]framework.OperationFunc{
-					logical.UpdateOperation: b.handleEnableAudit,
-					logical.DeleteOperation: b.handleDisableAudit,
+					logical.ReadOperation:   b.handlePasswordPolicyRead,
+					logical.UpdateOperation: b.handlePasswordPolicySet,
+					logical.DeleteOperation: b.handlePasswordPolicyDelete,
 				},
 
-				HelpSynopsis:    strings.TrimSpace(sysHelp["audit"][0]),
-				HelpDescription: strings.TrimSpace(sysHelp["audit"][1]),
+				HelpSynopsis:    strings.TrimSpace(sysHelp["password-policy"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["password-policy"][1]),
 			},
 
 			&framework.Path{
-				Pattern: "key-status$",
+				Pattern: "policies/egp/?$",
 
 				Callbacks: map[logical.Operation]framework.OperationFunc{
-					logical.ReadOperation: b.handleKeyStatus,
+					logical.ListOperation: b.handleEndpointPolicyList,
 				},
 
-				HelpSynopsis:    strings.TrimSpace(sysHelp["key-status"][0]),
-				HelpDescription: strings.TrimSpace(sysHelp["key-status"][1]),
+				HelpSynopsis:    strings.TrimSpace(sysHelp["egp-list"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["egp-list"][1]),
 			},
 
 			&framework.Path{
-				Pattern: "rotate$",
+				Pattern: "policies/egp/" + framework.GenericNameRegex("name") + "$",
+
+				Fields: map[string]*framework.FieldSchema{
+					"name": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: "The name of the endpoint governing policy.",
+					},
+					"policy": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: "CEL expression which must evaluate to true for a request to be allowed.",
+					},
+					"paths": &framework.FieldSchema{
+						Type:        framework.TypeCommaStringSlice,
+						Description: `Request paths governed by the policy. A trailing "*" matches any path with the given prefix.`,
+					},
+					"enforcement_level": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Default:     EnforcementHardMandatory,
+						Description: `Either "advisory", which only logs the requests failing the policy, or "hard-mandatory", which rejects them.`,
+					},
+				},
 
 				Callbacks: map[logical.Operation]framework.OperationFunc{
-					logical.UpdateOperation: b.handleRotate,
+					logical.ReadOperation:   b.handleEndpointPolicyRead,
+					logical.UpdateOperation: b.handleEndpointPolicySet,
+					logical.DeleteOperation: b.handleEndpointPolicyDelete,
 				},
 
-				HelpSynopsis:    strings.TrimSpace(sysHelp["rotate"][0]),
-				HelpDescription: strings.TrimSpace(sysHelp["rotate"][1]),
+				HelpSynopsis:    strings.TrimSpace(sysHelp["egp"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["egp"][1]),
 			},
 
-			/*
-				// Disabled for the moment as we don't support this externally
-				&framework.Path{
-					Pattern: "wrapping/pubkey$",
-
-					Callbacks: map[logical.Operation]framework.OperationFunc{
-						logical.ReadOperation: b.handleWrappingPubkey,
-					},
-
-					HelpSynopsis:    strings.TrimSpace(sysHelp["wrappubkey"][0]),
-					HelpDescription: strings.TrimSpace(sysHelp["wrappubkey"][1]),
-				},
-			*/
-
 			&framework.Path{
-				Pattern: "wrapping/wrap$",
+				Pattern: "quotas/lease-count/?$",
 
 				Callbacks: map[logical.Operation]framework.OperationFunc{
-					logical.UpdateOperation: b.handleWrappingWrap,
+					logical.ListOperation: b.handleLeaseCountQuotaList,
 				},
 
-				HelpSynopsis:    strings.TrimSpace(sysHelp["wrap"][0]),
-				HelpDescription: strings.TrimSpace(sysHelp["wrap"][1]),
+				HelpSynopsis:    strings.TrimSpace(sysHelp["lease-count-quota-list"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["lease-count-quota-list"][1]),
 			},
 
 			&framework.Path{
-				Pattern: "wrapping/unwrap$",
+				Pattern: "quotas/lease-count/" + framework.GenericNameRegex("name") + "$",
 
 				Fields: map[string]*framework.FieldSchema{
-					"token": &framework.FieldSchema{
-						Type: framework.TypeString,
+					"name": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: "The name of the quota.",
+					},
+					"path": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: "Mount, or path under a mount such as the credentials endpoint of a role, whose leases are counted. Counts all the leases if empty.",
+					},
+					"max_leases": &framework.FieldSchema{
+						Type:        framework.TypeInt,
+						Description: "Maximum number of active leases.",
+					},
+					"per_entity": &framework.FieldSchema{
+						Type:        framework.TypeBool,
+						Description: "If set, the maximum applies to the leases of each entity rather than to all the leases.",
 					},
 				},
 
-				Callbacks: map[logical.Operation]framework.OperationFunc{
-					logical.UpdateOperation: b.handleWrappingUnwrap,
-				},
-
-				HelpSynopsis:    strings.TrimSpace(sysHelp["unwrap"][0]),
-				HelpDescription: strings.TrimSpace(sysHelp["unwrap"][1]),
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation:   b.handleLeaseCountQuotaRead,
+					logical.UpdateOperation: b.handleLeaseCountQuotaSet,
+					logical.DeleteOperation: b.handleLeaseCountQuotaDelete,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["lease-count-quota"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["lease-count-quota"][1]),
 			},
 
 			&framework.Path{
-				Pattern: "wrapping/lookup$",
-
-				Fields: map[string]*framework.FieldSchema{
-					"token": &framework.FieldSchema{
-						Type: framework.TypeString,
-					},
-				},
+				Pattern: "in-flight-req$",
 
 				Callbacks: map[logical.Operation]framework.OperationFunc{
-					logical.UpdateOperation: b.handleWrappingLookup,
-					logical.ReadOperation:   b.handleWrappingLookup,
+					logical.ReadOperation: b.handleInFlightRequests,
 				},
 
-				HelpSynopsis:    strings.TrimSpace(sysHelp["wraplookup"][0]),
-				HelpDescription: strings.TrimSpace(sysHelp["wraplookup"][1]),
+				HelpSynopsis:    strings.TrimSpace(sysHelp["in-flight-req"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["in-flight-req"][1]),
 			},
 
 			&framework.Path{
-				Pattern: "wrapping/rewrap$",
+				Pattern: "in-flight-req/(?P<id>.+)",
 
 				Fields: map[string]*framework.FieldSchema{
-					"token": &framework.FieldSchema{
-						Type: framework.TypeString,
+					"id": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: "The identifier of the request.",
 					},
 				},
 
 				Callbacks: map[logical.Operation]framework.OperationFunc{
-					logical.UpdateOperation: b.handleWrappingRewrap,
+					logical.DeleteOperation: b.handleInFlightRequestCancel,
 				},
 
-				HelpSynopsis:    strings.TrimSpace(sysHelp["rewrap"][0]),
-				HelpDescription: strings.TrimSpace(sysHelp["rewrap"][1]),
+				HelpSynopsis:    strings.TrimSpace(sysHelp["in-flight-req-cancel"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["in-flight-req-cancel"][1]),
 			},
 
 			&framework.Path{
-				Pattern: "config/auditing/request-headers/(?P<header>.+)",
+				Pattern: "quotas/rate-limit/?$",
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ListOperation: b.handleRateLimitQuotaList,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["rate-limit-quota-list"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["rate-limit-quota-list"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "quotas/rate-limit/" + framework.GenericNameRegex("name") + "$",
 
 				Fields: map[string]*framework.FieldSchema{
-					"header": &framework.FieldSchema{
-						Type: framework.TypeString,
+					"name": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: "The name of the quota.",
 					},
-					"hmac": &framework.FieldSchema{
-						Type: framework.TypeBool,
+					"path": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: "Mount, or path prefix under a mount, whose requests are limited. Limits all the requests if empty.",
+					},
+					"rate": &framework.FieldSchema{
+						Type:        framework.TypeInt,
+						Description: "Number of requests allowed per interval.",
+					},
+					"interval": &framework.FieldSchema{
+						Type:        framework.TypeDurationSecond,
+						Default:     1,
+						Description: "Interval over which the rate applies. Defaults to one second.",
+					},
+					"burst": &framework.FieldSchema{
+						Type:        framework.TypeInt,
+						Description: "Number of requests allowed at once after an idle period. Defaults to the rate.",
+					},
+					"per_client_ip": &framework.FieldSchema{
+						Type:        framework.TypeBool,
+						Description: "If set, the limit applies to the requests of each client IP address rather than to all the requests.",
 					},
 				},
 
 				Callbacks: map[logical.Operation]framework.OperationFunc{
-					logical.UpdateOperation: b.handleAuditedHeaderUpdate,
-					logical.DeleteOperation: b.handleAuditedHeaderDelete,
-					logical.ReadOperation:   b.handleAuditedHeaderRead,
+					logical.ReadOperation:   b.handleRateLimitQuotaRead,
+					logical.UpdateOperation: b.handleRateLimitQuotaSet,
+					logical.DeleteOperation: b.handleRateLimitQuotaDelete,
 				},
 
-				HelpSynopsis:    strings.TrimSpace(sysHelp["audited-headers-name"][0]),
-				HelpDescription: strings.TrimSpace(sysHelp["audited-headers-name"][1]),
+				HelpSynopsis:    strings.TrimSpace(sysHelp["rate-limit-quota"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["rate-limit-quota"][1]),
 			},
 
 			&framework.Path{
-				Pattern: "config/auditing/request-headers$",
+				Pattern: "control-group/request$",
+
+				Fields: map[string]*framework.FieldSchema{
+					"id": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["control-group-id"][0]),
+					},
+				},
 
 				Callbacks: map[logical.Operation]framework.OperationFunc{
-					logical.ReadOperation: b.handleAuditedHeadersRead,
+					logical.UpdateOperation: b.handleControlGroupRequest,
 				},
 
-				HelpSynopsis:    strings.TrimSpace(sysHelp["audited-headers"][0]),
-				HelpDescription: strings.TrimSpace(sysHelp["audited-headers"][1]),
+				HelpSynopsis:    strings.TrimSpace(sysHelp["control-group-request"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["control-group-request"][1]),
 			},
+
 			&framework.Path{
-				Pattern: "plugins/catalog/?$",
+				Pattern: "control-group/authorize$",
 
-				Fields: map[string]*framework.FieldSchema{},
+				Fields: map[string]*framework.FieldSchema{
+					"id": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["control-group-id"][0]),
+					},
+				},
 
 				Callbacks: map[logical.Operation]framework.OperationFunc{
-					logical.ListOperation: b.handlePluginCatalogList,
+					logical.UpdateOperation: b.handleControlGroupAuthorize,
 				},
 
-				HelpSynopsis:    strings.TrimSpace(sysHelp["plugin-catalog"][0]),
-				HelpDescription: strings.TrimSpace(sysHelp["plugin-catalog"][1]),
+				HelpSynopsis:    strings.TrimSpace(sysHelp["control-group-authorize"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["control-group-authorize"][1]),
 			},
+
 			&framework.Path{
-				Pattern: "plugins/catalog/(?P<name>.+)",
+				Pattern:         "seal-status$",
+				HelpSynopsis:    strings.TrimSpace(sysHelp["seal-status"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["seal-status"][1]),
+			},
+
+			&framework.Path{
+				Pattern:         "seal$",
+				HelpSynopsis:    strings.TrimSpace(sysHelp["seal"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["seal"][1]),
+			},
+
+			&framework.Path{
+				Pattern:         "unseal$",
+				HelpSynopsis:    strings.TrimSpace(sysHelp["unseal"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["unseal"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "audit-hash/(?P<path>.+)",
 
 				Fields: map[string]*framework.FieldSchema{
-					"name": &framework.FieldSchema{
-						Type:        framework.TypeString,
-						Description: strings.TrimSpace(sysHelp["plugin-catalog_name"][0]),
-					},
-					"sha256": &framework.FieldSchema{
-						Type:        framework.TypeString,
-						Description: strings.TrimSpace(sysHelp["plugin-catalog_sha-256"][0]),
-					},
-					"sha_256": &framework.FieldSchema{
+					"path": &framework.FieldSchema{
 						Type:        framework.TypeString,
-						Description: strings.TrimSpace(sysHelp["plugin-catalog_sha-256"][0]),
+						Description: strings.TrimSpace(sysHelp["audit_path"][0]),
 					},
-					"command": &framework.FieldSchema{
-						Type:        framework.TypeString,
-						Description: strings.TrimSpace(sysHelp["plugin-catalog_command"][0]),
+
+					"input": &framework.FieldSchema{
+						Type: framework.TypeString,
 					},
 				},
 
 				Callbacks: map[logical.Operation]framework.OperationFunc{
-					logical.UpdateOperation: b.handlePluginCatalogUpdate,
-					logical.DeleteOperation: b.handlePluginCatalogDelete,
-					logical.ReadOperation:   b.handlePluginCatalogRead,
+					logical.UpdateOperation: b.handleAuditHash,
 				},
 
-				HelpSynopsis:    strings.TrimSpace(sysHelp["plugin-catalog"][0]),
-				HelpDescription: strings.TrimSpace(sysHelp["plugin-catalog"][1]),
+				HelpSynopsis:    strings.TrimSpace(sysHelp["audit-hash"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["audit-hash"][1]),
 			},
+
 			&framework.Path{
-				Pattern: "plugins/reload/backend$",
+				Pattern: "audit-verify/(?P<path>.+)",
 
 				Fields: map[string]*framework.FieldSchema{
-					"plugin": &framework.FieldSchema{
+					"path": &framework.FieldSchema{
 						Type:        framework.TypeString,
-						Description: strings.TrimSpace(sysHelp["plugin-backend-reload-plugin"][0]),
+						Description: strings.TrimSpace(sysHelp["audit_path"][0]),
 					},
-					"mounts": &framework.FieldSchema{
-						Type:        framework.TypeCommaStringSlice,
-						Description: strings.TrimSpace(sysHelp["plugin-backend-reload-mounts"][0]),
+
+					"file_path": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["audit_verify_file_path"][0]),
 					},
 				},
 
 				Callbacks: map[logical.Operation]framework.OperationFunc{
-					logical.UpdateOperation: b.handlePluginReloadUpdate,
+					logical.UpdateOperation: b.handleAuditVerify,
 				},
 
-				HelpSynopsis:    strings.TrimSpace(sysHelp["plugin-reload"][0]),
-				HelpDescription: strings.TrimSpace(sysHelp["plugin-reload"][1]),
+				HelpSynopsis:    strings.TrimSpace(sysHelp["audit-verify"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["audit-verify"][1]),
 			},
-		},
-	}
 
-	b.Backend.Paths = append(b.Backend.Paths, replicationPaths(b)...)
+			&framework.Path{
+				Pattern: "audit$",
 
-	if core.rawEnabled {
-		b.Backend.Paths = append(b.Backend.Paths, &framework.Path{
-			Pattern: "(raw/?$|raw/(?P<path>.+))",
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation: b.handleAuditTable,
+				},
 
-			Fields: map[string]*framework.FieldSchema{
-				"path": &framework.FieldSchema{
-					Type: framework.TypeString,
+				HelpSynopsis:    strings.TrimSpace(sysHelp["audit-table"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["audit-table"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "audit/(?P<path>.+)",
+
+				Fields: map[string]*framework.FieldSchema{
+					"path": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["audit_path"][0]),
+					},
+					"type": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["audit_type"][0]),
+					},
+					"description": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["audit_desc"][0]),
+					},
+					"options": &framework.FieldSchema{
+						Type:        framework.TypeMap,
+						Description: strings.TrimSpace(sysHelp["audit_opts"][0]),
+					},
+					"local": &framework.FieldSchema{
+						Type:        framework.TypeBool,
+						Default:     false,
+						Description: strings.TrimSpace(sysHelp["mount_local"][0]),
+					},
 				},
-				"value": &framework.FieldSchema{
-					Type: framework.TypeString,
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.UpdateOperation: b.handleEnableAudit,
+					logical.DeleteOperation: b.handleDisableAudit,
 				},
-			},
 
-			Callbacks: map[logical.Operation]framework.OperationFunc{
-				logical.ReadOperation:   b.handleRawRead,
-				logical.UpdateOperation: b.handleRawWrite,
-				logical.DeleteOperation: b.handleRawDelete,
-				logical.ListOperation:   b.handleRawList,
+				HelpSynopsis:    strings.TrimSpace(sysHelp["audit"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["audit"][1]),
 			},
-		})
-	}
 
-	b.Backend.Invalidate = b.invalidate
+			&framework.Path{
+				Pattern: "key-status$",
 
-	return b
-}
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation: b.handleKeyStatus,
+				},
 
-// SystemBackend implements logical.Backend and is used to interact with
-// the core of the system. This backend is hardcoded to exist at the "sys"
-// prefix. Conceptually it is similar to procfs on Linux.
-type SystemBackend struct {
-	*framework.Backend
-	Core *Core
-}
+				HelpSynopsis:    strings.TrimSpace(sysHelp["key-status"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["key-status"][1]),
+			},
 
-// handleCORSRead returns the current CORS configuration
-func (b *SystemBackend) handleCORSRead(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
-	corsConf := b.Core.corsConfig
+			&framework.Path{
+				Pattern: "rotate$",
 
-	enabled := corsConf.IsEnabled()
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.UpdateOperation: b.handleRotate,
+				},
 
-	resp := &logical.Response{
-		Data: map[string]interface{}{
-			"enabled": enabled,
-		},
-	}
+				HelpSynopsis:    strings.TrimSpace(sysHelp["rotate"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["rotate"][1]),
+			},
 
-	if enabled {
-		corsConf.RLock()
-		resp.Data["allowed_origins"] = corsConf.AllowedOrigins
-		resp.Data["allowed_headers"] = corsConf.AllowedHeaders
-		corsConf.RUnlock()
-	}
+			&framework.Path{
+				Pattern: "rotate/config$",
 
-	return resp, nil
-}
+				Fields: map[string]*framework.FieldSchema{
+					"enabled": &framework.FieldSchema{
+						Type:        framework.TypeBool,
+						Description: strings.TrimSpace(sysHelp["rotate-config-enabled"][0]),
+					},
+					"max_operations": &framework.FieldSchema{
+						Type:        framework.TypeInt,
+						Description: strings.TrimSpace(sysHelp["rotate-config-max-operations"][0]),
+					},
+					"interval": &framework.FieldSchema{
+						Type:        framework.TypeDurationSecond,
+						Description: strings.TrimSpace(sysHelp["rotate-config-interval"][0]),
+					},
+				},
 
-// handleCORSUpdate sets the list of origins that are allowed to make
-// cross-origin requests and sets the CORS enabled flag to true
-func (b *SystemBackend) handleCORSUpdate(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
-	origins := d.Get("allowed_origins").([]string)
-	headers := d.Get("allowed_headers").([]string)
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation:   b.handleRotateConfigRead,
+					logical.UpdateOperation: b.handleRotateConfigUpdate,
+				},
 
-	return nil, b.Core.corsConfig.Enable(origins, headers)
-}
+				HelpSynopsis:    strings.TrimSpace(sysHelp["rotate-config"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["rotate-config"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "rewrap$",
+
+				Fields: map[string]*framework.FieldSchema{
+					"prefix": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["storage-rewrap_prefix"][0]),
+					},
+					"rate": &framework.FieldSchema{
+						Type:        framework.TypeInt,
+						Description: strings.TrimSpace(sysHelp["storage-rewrap_rate"][0]),
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation:   b.handleRewrapStatus,
+					logical.UpdateOperation: b.handleRewrapStart,
+					logical.DeleteOperation: b.handleRewrapCancel,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["storage-rewrap"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["storage-rewrap"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "sealwrap/rewrap$",
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation:   b.handleSealRewrapStatus,
+					logical.UpdateOperation: b.handleSealRewrap,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["sealwrap-rewrap"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["sealwrap-rewrap"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "emergency-seal/config$",
+
+				Fields: map[string]*framework.FieldSchema{
+					"authorized_entities": &framework.FieldSchema{
+						Type:        framework.TypeCommaStringSlice,
+						Description: "Identity entity IDs that may authorize an emergency seal.",
+					},
+					"required": &framework.FieldSchema{
+						Type:        framework.TypeInt,
+						Description: "Number of distinct authorized entities required to trigger an emergency seal.",
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation:   b.handleEmergencySealConfigRead,
+					logical.UpdateOperation: b.handleEmergencySealConfigUpdate,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["emergency-seal-config"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["emergency-seal-config"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "emergency-seal$",
+
+				Fields: map[string]*framework.FieldSchema{
+					"nonce": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: "Nonce of the emergency seal attempt being authorized. Omit to start a new attempt.",
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation:   b.handleEmergencySealStatus,
+					logical.UpdateOperation: b.handleEmergencySealAuthorize,
+					logical.DeleteOperation: b.handleEmergencySealCancel,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["emergency-seal"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["emergency-seal"][1]),
+			},
+
+			/*
+				// Disabled for the moment as we don't support this externally
+				&framework.Path{
+					Pattern: "wrapping/pubkey$",
+
+					Callbacks: map[logical.Operation]framework.OperationFunc{
+						logical.ReadOperation: b.handleWrappingPubkey,
+					},
+
+					HelpSynopsis:    strings.TrimSpace(sysHelp["wrappubkey"][0]),
+					HelpDescription: strings.TrimSpace(sysHelp["wrappubkey"][1]),
+				},
+			*/
+
+			&framework.Path{
+				Pattern: "wrapping/wrap$",
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.UpdateOperation: b.handleWrappingWrap,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["wrap"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["wrap"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "wrapping/wrap-entries$",
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.UpdateOperation: b.handleWrappingWrapEntries,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["wrap-entries"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["wrap-entries"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "wrapping/unwrap$",
+
+				Fields: map[string]*framework.FieldSchema{
+					"token": &framework.FieldSchema{
+						Type: framework.TypeString,
+					},
+					"entry": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["unwrap-entry"][0]),
+					},
+					"creation_path": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["unwrap-creation-path"][0]),
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.UpdateOperation: b.handleWrappingUnwrap,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["unwrap"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["unwrap"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "wrapping/lookup$",
+
+				Fields: map[string]*framework.FieldSchema{
+					"token": &framework.FieldSchema{
+						Type: framework.TypeString,
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.UpdateOperation: b.handleWrappingLookup,
+					logical.ReadOperation:   b.handleWrappingLookup,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["wraplookup"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["wraplookup"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "wrapping/rewrap$",
+
+				Fields: map[string]*framework.FieldSchema{
+					"token": &framework.FieldSchema{
+						Type: framework.TypeString,
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.UpdateOperation: b.handleWrappingRewrap,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["rewrap"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["rewrap"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "config/auditing/request-headers/(?P<header>.+)",
+
+				Fields: map[string]*framework.FieldSchema{
+					"header": &framework.FieldSchema{
+						Type: framework.TypeString,
+					},
+					"hmac": &framework.FieldSchema{
+						Type: framework.TypeBool,
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.UpdateOperation: b.handleAuditedHeaderUpdate,
+					logical.DeleteOperation: b.handleAuditedHeaderDelete,
+					logical.ReadOperation:   b.handleAuditedHeaderRead,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["audited-headers-name"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["audited-headers-name"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "config/auditing/request-headers$",
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation: b.handleAuditedHeadersRead,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["audited-headers"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["audited-headers"][1]),
+			},
+			&framework.Path{
+				Pattern: "plugins/catalog/?$",
+
+				Fields: map[string]*framework.FieldSchema{},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ListOperation: b.handlePluginCatalogList,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["plugin-catalog"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["plugin-catalog"][1]),
+			},
+			&framework.Path{
+				Pattern: "plugins/catalog/(?P<name>.+)",
+
+				Fields: map[string]*framework.FieldSchema{
+					"name": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["plugin-catalog_name"][0]),
+					},
+					"sha256": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["plugin-catalog_sha-256"][0]),
+					},
+					"sha_256": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["plugin-catalog_sha-256"][0]),
+					},
+					"command": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["plugin-catalog_command"][0]),
+					},
+					"version": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["plugin-catalog_version"][0]),
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.UpdateOperation: b.handlePluginCatalogUpdate,
+					logical.DeleteOperation: b.handlePluginCatalogDelete,
+					logical.ReadOperation:   b.handlePluginCatalogRead,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["plugin-catalog"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["plugin-catalog"][1]),
+			},
+			&framework.Path{
+				Pattern: "plugins/reload/backend$",
+
+				Fields: map[string]*framework.FieldSchema{
+					"plugin": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["plugin-backend-reload-plugin"][0]),
+					},
+					"mounts": &framework.FieldSchema{
+						Type:        framework.TypeCommaStringSlice,
+						Description: strings.TrimSpace(sysHelp["plugin-backend-reload-mounts"][0]),
+					},
+					"version": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["plugin-backend-reload-version"][0]),
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.UpdateOperation: b.handlePluginReloadUpdate,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["plugin-reload"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["plugin-reload"][1]),
+			},
+			&framework.Path{
+				Pattern: "plugins/status$",
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation: b.handlePluginStatusRead,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["plugin-status"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["plugin-status"][1]),
+			},
+			&framework.Path{
+				Pattern: "internal/specs/openapi$",
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation: b.handleInternalOpenAPI,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["internal-specs-openapi"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["internal-specs-openapi"][1]),
+			},
+		},
+	}
+
+	b.Backend.Paths = append(b.Backend.Paths, replicationPaths(b)...)
+
+	if core.rawEnabled {
+		b.Backend.Paths = append(b.Backend.Paths, &framework.Path{
+			Pattern: "(raw/?$|raw/(?P<path>.+))",
+
+			Fields: map[string]*framework.FieldSchema{
+				"path": &framework.FieldSchema{
+					Type: framework.TypeString,
+				},
+				"value": &framework.FieldSchema{
+					Type: framework.TypeString,
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation:   b.handleRawRead,
+				logical.UpdateOperation: b.handleRawWrite,
+				logical.DeleteOperation: b.handleRawDelete,
+				logical.ListOperation:   b.handleRawList,
+			},
+		})
+	}
+
+	if core.chaosEnabled {
+		b.Backend.Paths = append(b.Backend.Paths, chaosPaths(b)...)
+	}
+
+	if core.raftBackend != nil {
+		b.Backend.Paths = append(b.Backend.Paths, raftStoragePaths(b)...)
+	}
+
+	b.Backend.Invalidate = b.invalidate
+
+	return b
+}
+
+func chaosPaths(b *SystemBackend) []*framework.Path {
+	return []*framework.Path{
+		&framework.Path{
+			Pattern: "chaos$",
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation: b.handleChaosStatus,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["chaos"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["chaos"][1]),
+		},
+
+		&framework.Path{
+			Pattern: "chaos/drop-ha-lock$",
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: b.handleChaosDropHALock,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["chaos/drop-ha-lock"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["chaos/drop-ha-lock"][1]),
+		},
+
+		&framework.Path{
+			Pattern: "chaos/storage-delay$",
+
+			Fields: map[string]*framework.FieldSchema{
+				"delay": &framework.FieldSchema{
+					Type:        framework.TypeDurationSecond,
+					Default:     1,
+					Description: "How long to delay each storage operation.",
+				},
+				"duration": &framework.FieldSchema{
+					Type:        framework.TypeDurationSecond,
+					Description: "How long storage operations should be delayed for.",
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: b.handleChaosStorageDelay,
+				logical.DeleteOperation: b.handleChaosStorageDelayDelete,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["chaos/storage-delay"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["chaos/storage-delay"][1]),
+		},
+
+		&framework.Path{
+			Pattern: "chaos/pause-expiration$",
+
+			Fields: map[string]*framework.FieldSchema{
+				"duration": &framework.FieldSchema{
+					Type:        framework.TypeDurationSecond,
+					Description: "How long lease expiration should be paused for.",
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: b.handleChaosPauseExpiration,
+				logical.DeleteOperation: b.handleChaosPauseExpirationDelete,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["chaos/pause-expiration"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["chaos/pause-expiration"][1]),
+		},
+	}
+}
+
+func raftStoragePaths(b *SystemBackend) []*framework.Path {
+	return []*framework.Path{
+		&framework.Path{
+			Pattern: "storage/raft/configuration$",
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation: b.handleRaftConfigurationRead,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["storage/raft/configuration"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["storage/raft/configuration"][1]),
+		},
+
+		&framework.Path{
+			Pattern: "storage/raft/add-peer$",
+
+			Fields: map[string]*framework.FieldSchema{
+				"server_id": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "The node ID of the server to add.",
+				},
+				"address": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "The raft address of the server to add.",
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: b.handleRaftAddPeer,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["storage/raft/add-peer"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["storage/raft/add-peer"][1]),
+		},
+
+		&framework.Path{
+			Pattern: "storage/raft/remove-peer$",
+
+			Fields: map[string]*framework.FieldSchema{
+				"server_id": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "The node ID of the server to remove.",
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: b.handleRaftRemovePeer,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["storage/raft/remove-peer"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["storage/raft/remove-peer"][1]),
+		},
+	}
+}
+
+// SystemBackend implements logical.Backend and is used to interact with
+// the core of the system. This backend is hardcoded to exist at the "sys"
+// prefix. Conceptually it is similar to procfs on Linux.
+type SystemBackend struct {
+	*framework.Backend
+	Core *Core
+
+	// wrappedEntryLocks serialize unwrapping the entries of a token wrapping
+	// multiple named entries so that each is only unwrapped once
+	wrappedEntryLocks []*locksutil.LockEntry
+}
+
+// handleCORSRead returns the current CORS configuration
+func (b *SystemBackend) handleCORSRead(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	corsConf := b.Core.corsConfig
+
+	enabled := corsConf.IsEnabled()
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"enabled": enabled,
+		},
+	}
+
+	if enabled {
+		corsConf.RLock()
+		resp.Data["allowed_origins"] = corsConf.AllowedOrigins
+		resp.Data["allowed_headers"] = corsConf.AllowedHeaders
+		corsConf.RUnlock()
+	}
+
+	return resp, nil
+}
+
+// handleCORSUpdate sets the list of origins that are allowed to make
+// cross-origin requests and sets the CORS enabled flag to true, unless
+// enable is set to false
+func (b *SystemBackend) handleCORSUpdate(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	if enable, ok := d.GetOk("enable"); ok && !enable.(bool) {
+		return nil, b.Core.corsConfig.Disable()
+	}
+
+	origins := d.Get("allowed_origins").([]string)
+	headers := d.Get("allowed_headers").([]string)
+
+	return nil, b.Core.corsConfig.Enable(origins, headers)
+}
 
 // handleCORSDelete sets the CORS enabled flag to false and clears the list of
 // allowed origins & headers.
@@ -925,343 +1806,1541 @@ func (b *SystemBackend) handleCORSDelete(req *logical.Request, d *framework.Fiel
 	return nil, b.Core.corsConfig.Disable()
 }
 
-func (b *SystemBackend) handleTidyLeases(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
-	err := b.Core.expiration.Tidy()
-	if err != nil {
-		b.Backend.Logger().Error("sys: failed to tidy leases", "error", err)
+// handleConfigReload reloads the configuration of the server, as SIGHUP does
+func (b *SystemBackend) handleConfigReload(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	if err := b.Core.Reload(); err != nil {
+		b.Backend.Logger().Error("sys: failed to reload the configuration", "error", err)
+		return handleError(err)
+	}
+	return nil, nil
+}
+
+// handleConfigStateRead returns the configuration in effect of the server,
+// which reflects the changes applied on reload
+func (b *SystemBackend) handleConfigStateRead(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	if b.Core.configStateHook == nil {
+		return logical.ErrorResponse("the configuration of the server is not available"), logical.ErrUnsupportedPath
+	}
+
+	return &logical.Response{
+		Data: b.Core.configStateHook(),
+	}, nil
+}
+
+// handleMetricsRead returns the metrics of the last complete interval of the
+// in-memory sink of the server
+func (b *SystemBackend) handleMetricsRead(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	if b.Core.metricsSink == nil {
+		return logical.ErrorResponse("the metrics of the server are not available"), logical.ErrUnsupportedPath
+	}
+
+	summary, err := b.Core.metricsSink.DisplayMetrics(nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// The summary is returned with the field names of its JSON encoding
+	raw, err := json.Marshal(summary)
+	if err != nil {
+		return nil, err
+	}
+	var data map[string]interface{}
+	if err := jsonutil.DecodeJSON(raw, &data); err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: data,
+	}, nil
+}
+
+// handleHostInfoRead returns information about the host and the process of
+// the server
+func (b *SystemBackend) handleHostInfoRead(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"timestamp":     time.Now().UTC().Format(time.RFC3339Nano),
+			"hostname":      hostname,
+			"os":            runtime.GOOS,
+			"arch":          runtime.GOARCH,
+			"go_version":    runtime.Version(),
+			"num_cpu":       runtime.NumCPU(),
+			"num_goroutine": runtime.NumGoroutine(),
+			"pid":           os.Getpid(),
+			"memory": map[string]interface{}{
+				"alloc":          mem.Alloc,
+				"total_alloc":    mem.TotalAlloc,
+				"sys":            mem.Sys,
+				"heap_alloc":     mem.HeapAlloc,
+				"heap_inuse":     mem.HeapInuse,
+				"heap_objects":   mem.HeapObjects,
+				"num_gc":         mem.NumGC,
+				"pause_total_ns": mem.PauseTotalNs,
+			},
+		},
+	}, nil
+}
+
+func (b *SystemBackend) handleTidyLeases(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	err := b.Core.expiration.Tidy()
+	if err != nil {
+		b.Backend.Logger().Error("sys: failed to tidy leases", "error", err)
+		return handleError(err)
+	}
+	return nil, err
+}
+
+func (b *SystemBackend) handleTidyTokens(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	report, err := b.Core.tokenStore.tidy(d.Get("dry_run").(bool))
+	if err != nil {
+		b.Backend.Logger().Error("sys: failed to tidy tokens", "error", err)
+		return handleError(err)
+	}
+	return &logical.Response{
+		Data: structs.New(report).Map(),
+	}, nil
+}
+
+func (b *SystemBackend) invalidate(key string) {
+	if b.Core.logger.IsTrace() {
+		b.Core.logger.Trace("sys: invalidating key", "key", key)
+	}
+	switch {
+	case strings.HasPrefix(key, policySubPath):
+		b.Core.stateLock.RLock()
+		defer b.Core.stateLock.RUnlock()
+		if b.Core.policyStore != nil {
+			b.Core.policyStore.invalidate(strings.TrimPrefix(key, policySubPath))
+		}
+	case key == "config/cors":
+		b.Core.stateLock.RLock()
+		defer b.Core.stateLock.RUnlock()
+		if err := b.Core.loadCORSConfig(); err != nil {
+			b.Core.logger.Error("sys: failed to reload CORS config", "error", err)
+		}
+	}
+}
+
+func (b *SystemBackend) handlePluginCatalogList(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	plugins, err := b.Core.pluginCatalog.List()
+	if err != nil {
+		return nil, err
+	}
+
+	return logical.ListResponse(plugins), nil
+}
+
+func (b *SystemBackend) handlePluginCatalogUpdate(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	pluginName := d.Get("name").(string)
+	if pluginName == "" {
+		return logical.ErrorResponse("missing plugin name"), nil
+	}
+
+	sha256 := d.Get("sha256").(string)
+	if sha256 == "" {
+		sha256 = d.Get("sha_256").(string)
+		if sha256 == "" {
+			return logical.ErrorResponse("missing SHA-256 value"), nil
+		}
+	}
+
+	command := d.Get("command").(string)
+	if command == "" {
+		return logical.ErrorResponse("missing command value"), nil
+	}
+
+	sha256Bytes, err := hex.DecodeString(sha256)
+	if err != nil {
+		return logical.ErrorResponse("Could not decode SHA-256 value from Hex"), err
+	}
+
+	version := d.Get("version").(string)
+	err = b.Core.pluginCatalog.SetVersion(pluginName, version, command, sha256Bytes)
+	switch err {
+	case nil:
+	case ErrInvalidPluginVersion:
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	default:
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func (b *SystemBackend) handlePluginCatalogRead(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	pluginName := d.Get("name").(string)
+	if pluginName == "" {
+		return logical.ErrorResponse("missing plugin name"), nil
+	}
+	version := d.Get("version").(string)
+	plugin, err := b.Core.pluginCatalog.GetVersion(pluginName, version)
+	if err != nil {
+		return nil, err
+	}
+	if plugin == nil {
+		return nil, nil
+	}
+
+	// Create a map of data to be returned and remove sensitive information from it
+	data := structs.New(plugin).Map()
+
+	// List the versions of the plugin along with its unversioned entry
+	if version == "" {
+		versions, err := b.Core.pluginCatalog.ListVersions(pluginName)
+		if err != nil {
+			return nil, err
+		}
+		if len(versions) > 0 {
+			data["versions"] = versions
+		}
+	}
+
+	return &logical.Response{
+		Data: data,
+	}, nil
+}
+
+func (b *SystemBackend) handlePluginCatalogDelete(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	pluginName := d.Get("name").(string)
+	if pluginName == "" {
+		return logical.ErrorResponse("missing plugin name"), nil
+	}
+	err := b.Core.pluginCatalog.DeleteVersion(pluginName, d.Get("version").(string))
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func (b *SystemBackend) handlePluginReloadUpdate(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	pluginName := d.Get("plugin").(string)
+	pluginMounts := d.Get("mounts").([]string)
+
+	if pluginName != "" && len(pluginMounts) > 0 {
+		return logical.ErrorResponse("plugin and mounts cannot be set at the same time"), nil
+	}
+	if pluginName == "" && len(pluginMounts) == 0 {
+		return logical.ErrorResponse("plugin or mounts must be provided"), nil
+	}
+
+	// Upgrade the mounts to the given version of their plugin, rolling them
+	// back if any of them fails to upgrade
+	if version := d.Get("version").(string); version != "" {
+		if err := b.Core.upgradePluginMounts(pluginName, pluginMounts, version); err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		return nil, nil
+	}
+
+	if pluginName != "" {
+		err := b.Core.reloadMatchingPlugin(pluginName)
+		if err != nil {
+			return nil, err
+		}
+	} else if len(pluginMounts) > 0 {
+		err := b.Core.reloadMatchingPluginMounts(pluginMounts)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, nil
+}
+
+// handleInternalOpenAPI merges the OpenAPI documents of the backends of the
+// mounts of the namespace, which they return with their root help
+func (b *SystemBackend) handleInternalOpenAPI(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	ns := b.Core.requestNamespace(req)
+	if ns == nil {
+		return handleError(errNamespaceNotFound(req.Namespace))
+	}
+
+	type documentedMount struct {
+		path      string
+		routePath string
+		tag       string
+	}
+	var mounts []documentedMount
+
+	b.Core.mountsLock.RLock()
+	for _, entry := range b.Core.mounts.Entries {
+		if entry.NamespaceID != ns.ID {
+			continue
+		}
+		tag := "secrets"
+		if entry.Type == "system" {
+			tag = "system"
+		}
+		mounts = append(mounts, documentedMount{
+			path:      strings.TrimPrefix(entry.Path, ns.Path),
+			routePath: entry.Path,
+			tag:       tag,
+		})
+	}
+	b.Core.mountsLock.RUnlock()
+
+	b.Core.authLock.RLock()
+	for _, entry := range b.Core.auth.Entries {
+		if entry.NamespaceID != ns.ID {
+			continue
+		}
+		mounts = append(mounts, documentedMount{
+			path:      credentialRoutePrefix + strings.TrimPrefix(entry.Path, ns.Path),
+			routePath: credentialRoutePrefix + entry.Path,
+			tag:       "auth",
+		})
+	}
+	b.Core.authLock.RUnlock()
+
+	doc := framework.NewOASDocument()
+	for _, mount := range mounts {
+		backend := b.Core.router.MatchingBackend(mount.routePath)
+		if backend == nil {
+			continue
+		}
+
+		resp, err := backend.HandleRequest(&logical.Request{
+			Operation: logical.HelpOperation,
+			Path:      "",
+		})
+		if err != nil || resp == nil || resp.Data["openapi"] == nil {
+			b.Backend.Logger().Warn("sys: failed to document mount", "path", mount.path, "error", err)
+			continue
+		}
+		mountDoc, err := framework.DecodeOASDocument(resp.Data["openapi"])
+		if err != nil {
+			b.Backend.Logger().Warn("sys: failed to document mount", "path", mount.path, "error", err)
+			continue
+		}
+
+		doc.AddPaths(mount.path, mount.tag, mountDoc)
+	}
+
+	data, err := doc.Map()
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: data,
+	}, nil
+}
+
+// handlePluginStatusRead returns the health of the external plugin processes
+// run by the mounts
+func (b *SystemBackend) handlePluginStatusRead(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	statuses := b.Core.pluginMonitor.statuses()
+
+	plugins := make([]map[string]interface{}, 0, len(statuses))
+	for _, status := range statuses {
+		plugins = append(plugins, map[string]interface{}{
+			"name":            status.Name,
+			"path":            status.Path,
+			"instance":        status.Instance,
+			"state":           status.State,
+			"restarts":        status.Restarts,
+			"last_error":      status.LastError,
+			"last_check_time": status.LastCheckTime.Format(time.RFC3339Nano),
+		})
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"plugins": plugins,
+		},
+	}, nil
+}
+
+// handleAuditedHeaderUpdate creates or overwrites a header entry
+func (b *SystemBackend) handleAuditedHeaderUpdate(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	header := d.Get("header").(string)
+	hmac := d.Get("hmac").(bool)
+	if header == "" {
+		return logical.ErrorResponse("missing header name"), nil
+	}
+
+	headerConfig := b.Core.AuditedHeadersConfig()
+	err := headerConfig.add(header, hmac)
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// handleAudtedHeaderDelete deletes the header with the given name
+func (b *SystemBackend) handleAuditedHeaderDelete(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	header := d.Get("header").(string)
+	if header == "" {
+		return logical.ErrorResponse("missing header name"), nil
+	}
+
+	headerConfig := b.Core.AuditedHeadersConfig()
+	err := headerConfig.remove(header)
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// handleAuditedHeaderRead returns the header configuration for the given header name
+func (b *SystemBackend) handleAuditedHeaderRead(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	header := d.Get("header").(string)
+	if header == "" {
+		return logical.ErrorResponse("missing header name"), nil
+	}
+
+	headerConfig := b.Core.AuditedHeadersConfig()
+	settings, ok := headerConfig.Headers[header]
+	if !ok {
+		return logical.ErrorResponse("Could not find header in config"), nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			header: settings,
+		},
+	}, nil
+}
+
+// handleAuditedHeadersRead returns the whole audited headers config
+func (b *SystemBackend) handleAuditedHeadersRead(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	headerConfig := b.Core.AuditedHeadersConfig()
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"headers": headerConfig.Headers,
+		},
+	}, nil
+}
+
+// handleCapabilities returns the ACL capabilities of the token for a given path
+func (b *SystemBackend) handleCapabilities(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	token := d.Get("token").(string)
+	if token == "" {
+		token = req.ClientToken
+	}
+	capabilities, grants, err := b.Core.CapabilitiesAndGrants(token, namespaceCapabilitiesPath(req, d))
+	if err != nil {
+		return nil, err
+	}
+
+	return capabilitiesResponse(capabilities, grants), nil
+}
+
+// namespaceCapabilitiesPath returns the path whose capabilities are
+// requested, which is relative to the namespace of the request, relative to
+// the root namespace instead
+func namespaceCapabilitiesPath(req *logical.Request, d *framework.FieldData) string {
+	path := d.Get("path").(string)
+	if path == "" {
+		return ""
+	}
+	return req.Namespace + path
+}
+
+// handleCapabilitiesAccessor returns the ACL capabilities of the
+// token associted with the given accessor for a given path.
+func (b *SystemBackend) handleCapabilitiesAccessor(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	accessor := d.Get("accessor").(string)
+	if accessor == "" {
+		return logical.ErrorResponse("missing accessor"), nil
+	}
+
+	aEntry, err := b.Core.tokenStore.lookupByAccessor(accessor, false)
+	if err != nil {
+		return nil, err
+	}
+
+	capabilities, grants, err := b.Core.CapabilitiesAndGrants(aEntry.TokenID, namespaceCapabilitiesPath(req, d))
+	if err != nil {
+		return nil, err
+	}
+
+	return capabilitiesResponse(capabilities, grants), nil
+}
+
+// capabilitiesResponse returns the capabilities of a token along with the
+// policy path rules granting each of them
+func capabilitiesResponse(capabilities []string, grants map[string][]*CapabilityGrant) *logical.Response {
+	capabilityGrants := make(map[string]interface{}, len(grants))
+	for capability, capGrants := range grants {
+		list := make([]map[string]interface{}, 0, len(capGrants))
+		for _, grant := range capGrants {
+			list = append(list, structs.New(grant).Map())
+		}
+		capabilityGrants[capability] = list
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"capabilities":      capabilities,
+			"capability_grants": capabilityGrants,
+		},
+	}
+}
+
+// handleRekeyRetrieve returns backed-up, PGP-encrypted unseal keys from a
+// rekey operation
+func (b *SystemBackend) handleRekeyRetrieve(
+	req *logical.Request,
+	data *framework.FieldData,
+	recovery bool) (*logical.Response, error) {
+	backup, err := b.Core.RekeyRetrieveBackup(recovery)
+	if err != nil {
+		return nil, fmt.Errorf("unable to look up backed-up keys: %v", err)
+	}
+	if backup == nil {
+		return logical.ErrorResponse("no backed-up keys found"), nil
+	}
+
+	keysB64 := map[string][]string{}
+	for k, v := range backup.Keys {
+		for _, j := range v {
+			currB64Keys := keysB64[k]
+			if currB64Keys == nil {
+				currB64Keys = []string{}
+			}
+			key, err := hex.DecodeString(j)
+			if err != nil {
+				return nil, fmt.Errorf("error decoding hex-encoded backup key: %v", err)
+			}
+			currB64Keys = append(currB64Keys, base64.StdEncoding.EncodeToString(key))
+			keysB64[k] = currB64Keys
+		}
+	}
+
+	// Format the status
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"nonce":       backup.Nonce,
+			"keys":        backup.Keys,
+			"keys_base64": keysB64,
+		},
+	}
+
+	return resp, nil
+}
+
+func (b *SystemBackend) handleRekeyRetrieveBarrier(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	return b.handleRekeyRetrieve(req, data, false)
+}
+
+func (b *SystemBackend) handleRekeyRetrieveRecovery(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	return b.handleRekeyRetrieve(req, data, true)
+}
+
+// handleRekeyDelete deletes backed-up, PGP-encrypted unseal keys from a rekey
+// operation
+func (b *SystemBackend) handleRekeyDelete(
+	req *logical.Request,
+	data *framework.FieldData,
+	recovery bool) (*logical.Response, error) {
+	err := b.Core.RekeyDeleteBackup(recovery)
+	if err != nil {
+		return nil, fmt.Errorf("error during deletion of backed-up keys: %v", err)
+	}
+
+	return nil, nil
+}
+
+func (b *SystemBackend) handleRekeyDeleteBarrier(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	return b.handleRekeyDelete(req, data, false)
+}
+
+func (b *SystemBackend) handleRekeyDeleteRecovery(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	return b.handleRekeyDelete(req, data, true)
+}
+
+// handleMountTable handles the "mounts" endpoint to provide the mount table
+func (b *SystemBackend) handleMountTable(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	ns := b.Core.requestNamespace(req)
+	if ns == nil {
+		return handleError(errNamespaceNotFound(req.Namespace))
+	}
+
+	b.Core.mountsLock.RLock()
+	defer b.Core.mountsLock.RUnlock()
+
+	resp := &logical.Response{
+		Data: make(map[string]interface{}),
+	}
+
+	for _, entry := range b.Core.mounts.Entries {
+		// Only the mounts of the namespace are listed, relative to it
+		if entry.NamespaceID != ns.ID {
+			continue
+		}
+
+		// Populate mount info
+		structConfig := structs.New(entry.Config).Map()
+		structConfig["default_lease_ttl"] = int64(structConfig["default_lease_ttl"].(time.Duration).Seconds())
+		structConfig["max_lease_ttl"] = int64(structConfig["max_lease_ttl"].(time.Duration).Seconds())
+		info := map[string]interface{}{
+			"type":        entry.Type,
+			"description": entry.Description,
+			"accessor":    entry.Accessor,
+			"config":      structConfig,
+			"local":       entry.Local,
+			"seal_wrap":   entry.SealWrap,
+		}
+		if len(entry.Options) > 0 {
+			info["options"] = entry.Options
+		}
+		resp.Data[strings.TrimPrefix(entry.Path, ns.Path)] = info
+	}
+
+	return resp, nil
+}
+
+// handleMount is used to mount a new path
+func (b *SystemBackend) handleMount(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	repState := b.Core.replicationState
+
+	local := data.Get("local").(bool)
+	if !local && repState.HasState(consts.ReplicationPerformanceSecondary) {
+		return logical.ErrorResponse("cannot add a non-local mount to a replication secondary"), nil
+	}
+
+	// Get all the options
+	path := data.Get("path").(string)
+	logicalType := data.Get("type").(string)
+	description := data.Get("description").(string)
+	pluginName := data.Get("plugin_name").(string)
+	options := data.Get("options").(map[string]interface{})
+
+	path = sanitizeMountPath(path)
+
+	var optionMap map[string]string
+	if len(options) > 0 {
+		optionMap = make(map[string]string, len(options))
+		for k, v := range options {
+			vStr, ok := v.(string)
+			if !ok {
+				return logical.ErrorResponse("options must be string valued"),
+					logical.ErrInvalidRequest
+			}
+			optionMap[k] = vStr
+		}
+	}
+
+	var config MountConfig
+	var apiConfig APIMountConfig
+
+	configMap := data.Get("config").(map[string]interface{})
+	if configMap != nil && len(configMap) != 0 {
+		err := mapstructure.Decode(configMap, &apiConfig)
+		if err != nil {
+			return logical.ErrorResponse(
+					"unable to convert given mount config information"),
+				logical.ErrInvalidRequest
+		}
+	}
+
+	switch apiConfig.DefaultLeaseTTL {
+	case "":
+	case "system":
+	default:
+		tmpDef, err := parseutil.ParseDurationSecond(apiConfig.DefaultLeaseTTL)
+		if err != nil {
+			return logical.ErrorResponse(fmt.Sprintf(
+					"unable to parse default TTL of %s: %s", apiConfig.DefaultLeaseTTL, err)),
+				logical.ErrInvalidRequest
+		}
+		config.DefaultLeaseTTL = tmpDef
+	}
+
+	switch apiConfig.MaxLeaseTTL {
+	case "":
+	case "system":
+	default:
+		tmpMax, err := parseutil.ParseDurationSecond(apiConfig.MaxLeaseTTL)
+		if err != nil {
+			return logical.ErrorResponse(fmt.Sprintf(
+					"unable to parse max TTL of %s: %s", apiConfig.MaxLeaseTTL, err)),
+				logical.ErrInvalidRequest
+		}
+		config.MaxLeaseTTL = tmpMax
+	}
+
+	if config.MaxLeaseTTL != 0 && config.DefaultLeaseTTL > config.MaxLeaseTTL {
+		return logical.ErrorResponse(
+				"given default lease TTL greater than given max lease TTL"),
+			logical.ErrInvalidRequest
+	}
+
+	if config.DefaultLeaseTTL > b.Core.maxLeaseTTL {
+		return logical.ErrorResponse(fmt.Sprintf(
+				"given default lease TTL greater than system max lease TTL of %d", int(b.Core.maxLeaseTTL.Seconds()))),
+			logical.ErrInvalidRequest
+	}
+
+	// Only set plugin-name if mount is of type plugin, with apiConfig.PluginName
+	// option taking precedence.
+	if logicalType == "plugin" {
+		switch {
+		case apiConfig.PluginName != "":
+			config.PluginName = apiConfig.PluginName
+		case pluginName != "":
+			config.PluginName = pluginName
+		default:
+			return logical.ErrorResponse(
+					"plugin_name must be provided for plugin backend"),
+				logical.ErrInvalidRequest
+		}
+	}
+
+	// Pin plugin mounts to the version of the plugin given, if any
+	if apiConfig.PluginVersion != "" {
+		if logicalType != "plugin" {
+			return logical.ErrorResponse(
+					"plugin_version can only be set for plugin backends"),
+				logical.ErrInvalidRequest
+		}
+		config.PluginVersion = apiConfig.PluginVersion
+	}
+
+	// Copy over the force no cache if set
+	if apiConfig.ForceNoCache {
+		config.ForceNoCache = true
+	}
+
+	if logicalType == "" {
+		return logical.ErrorResponse(
+				"backend type must be specified as a string"),
+			logical.ErrInvalidRequest
+	}
+
+	// The mount belongs to the namespace of the request
+	ns := b.Core.requestNamespace(req)
+	if ns == nil {
+		return handleError(errNamespaceNotFound(req.Namespace))
+	}
+
+	// Create the mount entry
+	me := &MountEntry{
+		Table:       mountTableType,
+		Path:        ns.Path + path,
+		Type:        logicalType,
+		Description: description,
+		Config:      config,
+		Options:     optionMap,
+		Local:       local,
+		SealWrap:    data.Get("seal_wrap").(bool),
+		NamespaceID: ns.ID,
+	}
+
+	// Attempt mount
+	if err := b.Core.mount(me); err != nil {
+		b.Backend.Logger().Error("sys: mount failed", "path", me.Path, "error", err)
+		return handleError(err)
+	}
+
+	return nil, nil
+}
+
+// used to intercept an HTTPCodedError so it goes back to callee
+func handleError(
+	err error) (*logical.Response, error) {
+	switch err.(type) {
+	case logical.HTTPCodedError:
+		return logical.ErrorResponse(err.Error()), err
+	default:
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+}
+
+// handleUnmount is used to unmount a path
+func (b *SystemBackend) handleUnmount(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	path := data.Get("path").(string)
+	path = sanitizeMountPath(path)
+	path, ok := namespaceMountPath(req.Namespace, path)
+	if !ok {
+		return handleError(fmt.Errorf("cannot unmount '%s'", path))
+	}
+
+	repState := b.Core.replicationState
+	entry := b.Core.router.MatchingMountEntry(path)
+	if entry != nil && !entry.Local && repState.HasState(consts.ReplicationPerformanceSecondary) {
+		return logical.ErrorResponse("cannot unmount a non-local mount on a replication secondary"), nil
+	}
+
+	// We return success when the mount does not exists to not expose if the
+	// mount existed or not
+	match := b.Core.router.MatchingMount(path)
+	if match == "" || path != match {
+		return nil, nil
+	}
+
+	// Attempt unmount
+	if err := b.Core.unmount(path); err != nil {
+		b.Backend.Logger().Error("sys: unmount failed", "path", path, "error", err)
+		return handleError(err)
+	}
+
+	return nil, nil
+}
+
+// handleRemount is used to remount a path
+func (b *SystemBackend) handleRemount(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	repState := b.Core.replicationState
+
+	// Get the paths
+	fromPath := data.Get("from").(string)
+	toPath := data.Get("to").(string)
+	if fromPath == "" || toPath == "" {
+		return logical.ErrorResponse(
+				"both 'from' and 'to' path must be specified as a string"),
+			logical.ErrInvalidRequest
+	}
+
+	fromPath = sanitizeMountPath(fromPath)
+	toPath = sanitizeMountPath(toPath)
+	fromPath, fromOK := namespaceMountPath(req.Namespace, fromPath)
+	toPath, toOK := namespaceMountPath(req.Namespace, toPath)
+	if !fromOK || !toOK {
+		return handleError(fmt.Errorf("cannot remount '%s' to '%s'", fromPath, toPath))
+	}
+
+	entry := b.Core.router.MatchingMountEntry(fromPath)
+	if entry != nil && !entry.Local && repState.HasState(consts.ReplicationPerformanceSecondary) {
+		return logical.ErrorResponse("cannot remount a non-local mount on a replication secondary"), nil
+	}
+
+	// Attempt remount
+	if err := b.Core.remount(fromPath, toPath); err != nil {
+		b.Backend.Logger().Error("sys: remount failed", "from_path", fromPath, "to_path", toPath, "error", err)
 		return handleError(err)
 	}
-	return nil, err
+
+	return nil, nil
 }
 
-func (b *SystemBackend) invalidate(key string) {
-	if b.Core.logger.IsTrace() {
-		b.Core.logger.Trace("sys: invalidating key", "key", key)
+// handleAuthTuneRead is used to get config settings on a auth path
+func (b *SystemBackend) handleAuthTuneRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	path := data.Get("path").(string)
+	if path == "" {
+		return logical.ErrorResponse(
+				"path must be specified as a string"),
+			logical.ErrInvalidRequest
+	}
+	return b.handleTuneReadCommon(req, "auth/"+path)
+}
+
+// handleMountTuneRead is used to get config settings on a backend
+func (b *SystemBackend) handleMountTuneRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	path := data.Get("path").(string)
+	if path == "" {
+		return logical.ErrorResponse(
+				"path must be specified as a string"),
+			logical.ErrInvalidRequest
+	}
+
+	// This call will read both logical backend's configuration as well as auth backends'.
+	// Retaining this behavior for backward compatibility. If this behavior is not desired,
+	// an error can be returned if path has a prefix of "auth/".
+	return b.handleTuneReadCommon(req, path)
+}
+
+// handleTuneReadCommon returns the config settings of a path, relative to
+// the namespace of the request
+func (b *SystemBackend) handleTuneReadCommon(req *logical.Request, path string) (*logical.Response, error) {
+	path = sanitizeMountPath(path)
+	path = namespaceRouterPath(req.Namespace, path)
+
+	sysView := b.Core.router.MatchingSystemView(path)
+	if sysView == nil {
+		b.Backend.Logger().Error("sys: cannot fetch sysview", "path", path)
+		return handleError(fmt.Errorf("sys: cannot fetch sysview for path %s", path))
+	}
+
+	mountEntry := b.Core.router.MatchingMountEntry(path)
+	if mountEntry == nil {
+		b.Backend.Logger().Error("sys: cannot fetch mount entry", "path", path)
+		return handleError(fmt.Errorf("sys: cannot fetch mount entry for path %s", path))
+	}
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"default_lease_ttl": int(sysView.DefaultLeaseTTL().Seconds()),
+			"max_lease_ttl":     int(sysView.MaxLeaseTTL().Seconds()),
+			"force_no_cache":    mountEntry.Config.ForceNoCache,
+		},
+	}
+	if mountEntry.Table == credentialTableType {
+		resp.Data["auto_create_group_aliases"] = mountEntry.Config.AutoCreateGroupAliases
+		resp.Data["token_type"] = mountTokenType(mountEntry)
+	}
+
+	return resp, nil
+}
+
+// handleAuthTuneWrite is used to set config settings on an auth path
+func (b *SystemBackend) handleAuthTuneWrite(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	path := data.Get("path").(string)
+	if path == "" {
+		return logical.ErrorResponse("path must be specified as a string"),
+			logical.ErrInvalidRequest
+	}
+	return b.handleTuneWriteCommon(req, "auth/"+path, data)
+}
+
+// handleMountTuneWrite is used to set config settings on a backend
+func (b *SystemBackend) handleMountTuneWrite(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	path := data.Get("path").(string)
+	if path == "" {
+		return logical.ErrorResponse("path must be specified as a string"),
+			logical.ErrInvalidRequest
+	}
+	// This call will write both logical backend's configuration as well as auth backends'.
+	// Retaining this behavior for backward compatibility. If this behavior is not desired,
+	// an error can be returned if path has a prefix of "auth/".
+	return b.handleTuneWriteCommon(req, path, data)
+}
+
+// handleTuneWriteCommon is used to set config settings on a path, relative
+// to the namespace of the request
+func (b *SystemBackend) handleTuneWriteCommon(
+	req *logical.Request, path string, data *framework.FieldData) (*logical.Response, error) {
+	repState := b.Core.replicationState
+
+	path = sanitizeMountPath(path)
+	path, ok := namespaceMountPath(req.Namespace, path)
+	if !ok {
+		return handleError(fmt.Errorf("sys: cannot tune '%s'", path))
+	}
+
+	// Prevent protected paths from being changed
+	for _, p := range untunableMounts {
+		if strings.HasPrefix(path, p) {
+			b.Backend.Logger().Error("sys: cannot tune this mount", "path", path)
+			return handleError(fmt.Errorf("sys: cannot tune '%s'", path))
+		}
+	}
+
+	mountEntry := b.Core.router.MatchingMountEntry(path)
+	if mountEntry == nil {
+		b.Backend.Logger().Error("sys: tune failed: no mount entry found", "path", path)
+		return handleError(fmt.Errorf("sys: tune of path '%s' failed: no mount entry found", path))
 	}
+	if mountEntry != nil && !mountEntry.Local && repState.HasState(consts.ReplicationPerformanceSecondary) {
+		return logical.ErrorResponse("cannot tune a non-local mount on a replication secondary"), nil
+	}
+
+	var lock *sync.RWMutex
 	switch {
-	case strings.HasPrefix(key, policySubPath):
-		b.Core.stateLock.RLock()
-		defer b.Core.stateLock.RUnlock()
-		if b.Core.policyStore != nil {
-			b.Core.policyStore.invalidate(strings.TrimPrefix(key, policySubPath))
+	case strings.HasPrefix(path, "auth/"):
+		lock = &b.Core.authLock
+	default:
+		lock = &b.Core.mountsLock
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	// Check again after grabbing the lock
+	mountEntry = b.Core.router.MatchingMountEntry(path)
+	if mountEntry == nil {
+		b.Backend.Logger().Error("sys: tune failed: no mount entry found", "path", path)
+		return handleError(fmt.Errorf("sys: tune of path '%s' failed: no mount entry found", path))
+	}
+	if mountEntry != nil && !mountEntry.Local && repState.HasState(consts.ReplicationPerformanceSecondary) {
+		return logical.ErrorResponse("cannot tune a non-local mount on a replication secondary"), nil
+	}
+
+	// Timing configuration parameters
+	{
+		var newDefault, newMax time.Duration
+		defTTL := data.Get("default_lease_ttl").(string)
+		switch defTTL {
+		case "":
+			newDefault = mountEntry.Config.DefaultLeaseTTL
+		case "system":
+			newDefault = time.Duration(0)
+		default:
+			tmpDef, err := parseutil.ParseDurationSecond(defTTL)
+			if err != nil {
+				return handleError(err)
+			}
+			newDefault = tmpDef
+		}
+
+		maxTTL := data.Get("max_lease_ttl").(string)
+		switch maxTTL {
+		case "":
+			newMax = mountEntry.Config.MaxLeaseTTL
+		case "system":
+			newMax = time.Duration(0)
+		default:
+			tmpMax, err := parseutil.ParseDurationSecond(maxTTL)
+			if err != nil {
+				return handleError(err)
+			}
+			newMax = tmpMax
+		}
+
+		if newDefault != mountEntry.Config.DefaultLeaseTTL ||
+			newMax != mountEntry.Config.MaxLeaseTTL {
+
+			if err := b.tuneMountTTLs(path, mountEntry, newDefault, newMax); err != nil {
+				b.Backend.Logger().Error("sys: tuning failed", "path", path, "error", err)
+				return handleError(err)
+			}
+		}
+	}
+
+	description := data.Get("description").(string)
+	if description != "" {
+		oldDesc := mountEntry.Description
+		mountEntry.Description = description
+
+		// Update the mount table
+		var err error
+		switch {
+		case strings.HasPrefix(path, "auth/"):
+			err = b.Core.persistAuth(b.Core.auth, mountEntry.Local)
+		default:
+			err = b.Core.persistMounts(b.Core.mounts, mountEntry.Local)
+		}
+		if err != nil {
+			mountEntry.Description = oldDesc
+			return handleError(err)
+		}
+		if b.Core.logger.IsInfo() {
+			b.Core.logger.Info("core: mount tuning of description successful", "path", path)
+		}
+	}
+
+	if autoCreateRaw, ok := data.GetOk("auto_create_group_aliases"); ok {
+		autoCreate := autoCreateRaw.(bool)
+		if autoCreate != mountEntry.Config.AutoCreateGroupAliases {
+			mountEntry.Config.AutoCreateGroupAliases = autoCreate
+
+			// The field is only part of the schema of auth tuning, so this is
+			// always an auth mount
+			if err := b.Core.persistAuth(b.Core.auth, mountEntry.Local); err != nil {
+				mountEntry.Config.AutoCreateGroupAliases = !autoCreate
+				return handleError(err)
+			}
+			if b.Core.logger.IsInfo() {
+				b.Core.logger.Info("core: mount tuning of auto_create_group_aliases successful", "path", path, "auto_create_group_aliases", autoCreate)
+			}
 		}
 	}
-}
 
-func (b *SystemBackend) handlePluginCatalogList(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
-	plugins, err := b.Core.pluginCatalog.List()
+	if tokenTypeRaw, ok := data.GetOk("token_type"); ok {
+		tokenType := tokenTypeRaw.(string)
+		if !validTokenType(tokenType) {
+			return logical.ErrorResponse(fmt.Sprintf("invalid token type %q", tokenType)), logical.ErrInvalidRequest
+		}
+		if tokenType != mountTokenType(mountEntry) {
+			oldTokenType := mountEntry.Config.TokenType
+			mountEntry.Config.TokenType = tokenType
+
+			// Like auto_create_group_aliases, the field is only part of the
+			// schema of auth tuning
+			if err := b.Core.persistAuth(b.Core.auth, mountEntry.Local); err != nil {
+				mountEntry.Config.TokenType = oldTokenType
+				return handleError(err)
+			}
+			if b.Core.logger.IsInfo() {
+				b.Core.logger.Info("core: mount tuning of token_type successful", "path", path, "token_type", tokenType)
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// handleMountExportConfig returns the signed configuration of a mount
+func (b *SystemBackend) handleMountExportConfig(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	path := data.Get("path").(string)
+	if path == "" {
+		return logical.ErrorResponse("path must be specified as a string"),
+			logical.ErrInvalidRequest
+	}
+	path = sanitizeMountPath(path)
+	path = namespaceRouterPath(req.Namespace, path)
+
+	doc, signature, publicKey, err := b.Core.exportMountConfig(path)
 	if err != nil {
-		return nil, err
+		return handleError(err)
 	}
 
-	return logical.ListResponse(plugins), nil
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"document":   base64.StdEncoding.EncodeToString(doc),
+			"signature":  base64.StdEncoding.EncodeToString(signature),
+			"public_key": base64.StdEncoding.EncodeToString(publicKey),
+		},
+	}, nil
 }
 
-func (b *SystemBackend) handlePluginCatalogUpdate(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
-	pluginName := d.Get("name").(string)
-	if pluginName == "" {
-		return logical.ErrorResponse("missing plugin name"), nil
+// handleMountImportConfig compares a signed configuration document with a
+// mount, and applies it if requested
+func (b *SystemBackend) handleMountImportConfig(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	path := data.Get("path").(string)
+	if path == "" {
+		return logical.ErrorResponse("path must be specified as a string"),
+			logical.ErrInvalidRequest
+	}
+	path = sanitizeMountPath(path)
+	routerPath, ok := namespaceMountPath(req.Namespace, path)
+	if !ok {
+		return handleError(fmt.Errorf("cannot import the configuration of '%s'", path))
 	}
 
-	sha256 := d.Get("sha256").(string)
-	if sha256 == "" {
-		sha256 = d.Get("sha_256").(string)
-		if sha256 == "" {
-			return logical.ErrorResponse("missing SHA-256 value"), nil
+	decoded := make(map[string][]byte, 2)
+	for _, field := range []string{"document", "signature"} {
+		value := data.Get(field).(string)
+		if value == "" {
+			return logical.ErrorResponse(fmt.Sprintf("%s must be specified", field)), logical.ErrInvalidRequest
+		}
+		raw, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("%s must be base64-encoded", field)), logical.ErrInvalidRequest
 		}
+		decoded[field] = raw
 	}
 
-	command := d.Get("command").(string)
-	if command == "" {
-		return logical.ErrorResponse("missing command value"), nil
+	keys, err := b.Core.mountImportKeys()
+	if err != nil {
+		return handleError(err)
 	}
-
-	sha256Bytes, err := hex.DecodeString(sha256)
+	doc, err := verifyMountConfig(decoded["document"], decoded["signature"], keys)
 	if err != nil {
-		return logical.ErrorResponse("Could not decode SHA-256 value from Hex"), err
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
 	}
 
-	err = b.Core.pluginCatalog.Set(pluginName, command, sha256Bytes)
+	diff, err := b.Core.diffMountConfig(routerPath, doc)
 	if err != nil {
-		return nil, err
+		return handleError(err)
 	}
 
-	return nil, nil
-}
+	resp := &logical.Response{
+		Data: diff.responseData(),
+	}
+	resp.Data["applied"] = false
+	if !data.Get("apply").(bool) {
+		return resp, nil
+	}
 
-func (b *SystemBackend) handlePluginCatalogRead(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
-	pluginName := d.Get("name").(string)
-	if pluginName == "" {
-		return logical.ErrorResponse("missing plugin name"), nil
+	if len(diff.Tune) > 0 {
+		tune := &framework.FieldData{
+			Raw: map[string]interface{}{
+				"default_lease_ttl": strconv.Itoa(doc.DefaultLeaseTTL),
+				"max_lease_ttl":     strconv.Itoa(doc.MaxLeaseTTL),
+				"description":       doc.Description,
+			},
+			Schema: map[string]*framework.FieldSchema{
+				"default_lease_ttl": &framework.FieldSchema{Type: framework.TypeString},
+				"max_lease_ttl":     &framework.FieldSchema{Type: framework.TypeString},
+				"description":       &framework.FieldSchema{Type: framework.TypeString},
+			},
+		}
+		if tuneResp, err := b.handleTuneWriteCommon(req, path, tune); err != nil || tuneResp.IsError() {
+			return tuneResp, err
+		}
 	}
-	plugin, err := b.Core.pluginCatalog.Get(pluginName)
-	if err != nil {
-		return nil, err
+	if err := b.Core.applyMountConfigEntries(routerPath, doc, diff, data.Get("prune").(bool)); err != nil {
+		return handleError(err)
 	}
-	if plugin == nil {
-		return nil, nil
+	if !data.Get("prune").(bool) {
+		resp.Data["removed"] = []string{}
 	}
+	resp.Data["applied"] = true
 
-	// Create a map of data to be returned and remove sensitive information from it
-	data := structs.New(plugin).Map()
+	return resp, nil
+}
+
+// handleMountImportConfigRead returns the keys of the clusters whose exported
+// mount configuration can be imported
+func (b *SystemBackend) handleMountImportConfigRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	config, err := b.Core.mountImportConfig()
+	if err != nil {
+		return handleError(err)
+	}
+	trustedKeys := config.TrustedKeys
+	if trustedKeys == nil {
+		trustedKeys = []string{}
+	}
 
 	return &logical.Response{
-		Data: data,
+		Data: map[string]interface{}{
+			"trusted_keys": trustedKeys,
+		},
 	}, nil
 }
 
-func (b *SystemBackend) handlePluginCatalogDelete(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
-	pluginName := d.Get("name").(string)
-	if pluginName == "" {
-		return logical.ErrorResponse("missing plugin name"), nil
+// handleMountImportConfigUpdate replaces the keys of the clusters whose
+// exported mount configuration can be imported
+func (b *SystemBackend) handleMountImportConfigUpdate(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	config := &mountImportConfig{
+		TrustedKeys: data.Get("trusted_keys").([]string),
 	}
-	err := b.Core.pluginCatalog.Delete(pluginName)
-	if err != nil {
-		return nil, err
+	if err := b.Core.setMountImportConfig(config); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
 	}
-
 	return nil, nil
 }
 
-func (b *SystemBackend) handlePluginReloadUpdate(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
-	pluginName := d.Get("plugin").(string)
-	pluginMounts := d.Get("mounts").([]string)
+// handleMountImportConfigDelete removes the trusted keys, so that only the
+// exports of this cluster can be imported
+func (b *SystemBackend) handleMountImportConfigDelete(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	return nil, b.Core.barrier.Delete(coreMountImportConfigPath)
+}
 
-	if pluginName != "" && len(pluginMounts) > 0 {
-		return logical.ErrorResponse("plugin and mounts cannot be set at the same time"), nil
+// handleLease is use to view the metadata for a given LeaseID
+func (b *SystemBackend) handleLeaseLookup(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	leaseID := data.Get("lease_id").(string)
+	if leaseID == "" {
+		return logical.ErrorResponse("lease_id must be specified"),
+			logical.ErrInvalidRequest
 	}
-	if pluginName == "" && len(pluginMounts) == 0 {
-		return logical.ErrorResponse("plugin or mounts must be provided"), nil
+	if !namespaceLeaseAllowed(req.Namespace, leaseID) {
+		return logical.ErrorResponse("invalid lease"), logical.ErrInvalidRequest
 	}
 
-	if pluginName != "" {
-		err := b.Core.reloadMatchingPlugin(pluginName)
-		if err != nil {
-			return nil, err
-		}
-	} else if len(pluginMounts) > 0 {
-		err := b.Core.reloadMatchingPluginMounts(pluginMounts)
-		if err != nil {
-			return nil, err
-		}
+	leaseTimes, err := b.Core.expiration.FetchLeaseTimes(leaseID)
+	if err != nil {
+		b.Backend.Logger().Error("sys: error retrieving lease", "lease_id", leaseID, "error", err)
+		return handleError(err)
+	}
+	if leaseTimes == nil {
+		return logical.ErrorResponse("invalid lease"), logical.ErrInvalidRequest
 	}
 
-	return nil, nil
-}
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"id":           leaseID,
+			"issue_time":   leaseTimes.IssueTime,
+			"expire_time":  nil,
+			"last_renewal": nil,
+			"ttl":          int64(0),
+		},
+	}
+	renewable, _ := leaseTimes.renewable()
+	resp.Data["renewable"] = renewable
 
-// handleAuditedHeaderUpdate creates or overwrites a header entry
-func (b *SystemBackend) handleAuditedHeaderUpdate(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
-	header := d.Get("header").(string)
-	hmac := d.Get("hmac").(bool)
-	if header == "" {
-		return logical.ErrorResponse("missing header name"), nil
+	if !leaseTimes.LastRenewalTime.IsZero() {
+		resp.Data["last_renewal"] = leaseTimes.LastRenewalTime
+	}
+	if !leaseTimes.ExpireTime.IsZero() {
+		resp.Data["expire_time"] = leaseTimes.ExpireTime
+		resp.Data["ttl"] = leaseTimes.ttl()
 	}
+	return resp, nil
+}
 
-	headerConfig := b.Core.AuditedHeadersConfig()
-	err := headerConfig.add(header, hmac)
-	if err != nil {
-		return nil, err
+func (b *SystemBackend) handleLeaseLookupList(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	prefix := data.Get("prefix").(string)
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix = prefix + "/"
+	}
+	if !namespaceLeaseAllowed(req.Namespace, prefix) {
+		return logical.ErrorResponse("prefix is not within the namespace"), logical.ErrInvalidRequest
 	}
 
-	return nil, nil
-}
+	var filters []func(*leaseEntry) bool
+	if entityID, ok := data.GetOk("entity_id"); ok {
+		filters = append(filters, func(le *leaseEntry) bool {
+			if le.Auth != nil {
+				return le.Auth.EntityID == entityID.(string)
+			}
+			return le.EntityID == entityID.(string)
+		})
+	}
+	if expiringWithin, ok := data.GetOk("expiring_within"); ok {
+		before := time.Now().Add(time.Duration(expiringWithin.(int)) * time.Second)
+		filters = append(filters, func(le *leaseEntry) bool {
+			return !le.ExpireTime.IsZero() && le.ExpireTime.Before(before)
+		})
+	}
 
-// handleAudtedHeaderDelete deletes the header with the given name
-func (b *SystemBackend) handleAuditedHeaderDelete(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
-	header := d.Get("header").(string)
-	if header == "" {
-		return logical.ErrorResponse("missing header name"), nil
+	// Without filters, this stays a plain listing of the next level of the
+	// prefix
+	if len(filters) == 0 && !data.Get("recursive").(bool) {
+		keys, err := b.Core.expiration.idView.List(prefix)
+		if err != nil {
+			b.Backend.Logger().Error("sys: error listing leases", "prefix", prefix, "error", err)
+			return handleError(err)
+		}
+		return logical.ListResponse(keys), nil
 	}
 
-	headerConfig := b.Core.AuditedHeadersConfig()
-	err := headerConfig.remove(header)
+	suffixes, err := logical.CollectKeys(b.Core.expiration.idView.SubView(prefix))
 	if err != nil {
-		return nil, err
+		b.Backend.Logger().Error("sys: error listing leases", "prefix", prefix, "error", err)
+		return handleError(err)
 	}
+	sort.Strings(suffixes)
 
-	return nil, nil
-}
+	keys := []string{}
+	keyInfo := map[string]interface{}{}
+	for _, suffix := range suffixes {
+		leaseID := prefix + suffix
+		le, err := b.Core.expiration.loadEntry(leaseID)
+		if err != nil {
+			b.Backend.Logger().Error("sys: error retrieving lease", "lease_id", leaseID, "error", err)
+			return handleError(err)
+		}
+		if le == nil {
+			continue
+		}
 
-// handleAuditedHeaderRead returns the header configuration for the given header name
-func (b *SystemBackend) handleAuditedHeaderRead(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
-	header := d.Get("header").(string)
-	if header == "" {
-		return logical.ErrorResponse("missing header name"), nil
+		matches := true
+		for _, filter := range filters {
+			if !filter(le) {
+				matches = false
+				break
+			}
+		}
+		if !matches {
+			continue
+		}
+
+		info := map[string]interface{}{
+			"issue_time":  le.IssueTime,
+			"expire_time": nil,
+			"ttl":         int64(0),
+			"entity_id":   le.EntityID,
+		}
+		if le.Auth != nil {
+			info["entity_id"] = le.Auth.EntityID
+		}
+		if !le.ExpireTime.IsZero() {
+			info["expire_time"] = le.ExpireTime
+			info["ttl"] = le.ttl()
+		}
+		keys = append(keys, leaseID)
+		keyInfo[leaseID] = info
 	}
 
-	headerConfig := b.Core.AuditedHeadersConfig()
-	settings, ok := headerConfig.Headers[header]
-	if !ok {
-		return logical.ErrorResponse("Could not find header in config"), nil
+	resp := logical.ListResponse(keys)
+	resp.Data["key_info"] = keyInfo
+	return resp, nil
+}
+
+// handleLeasesIrrevocable lists the leases which could not be revoked
+func (b *SystemBackend) handleLeasesIrrevocable(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	leases := []map[string]interface{}{}
+	for _, lease := range b.Core.expiration.IrrevocableLeases() {
+		leases = append(leases, structs.New(lease).Map())
 	}
 
 	return &logical.Response{
 		Data: map[string]interface{}{
-			header: settings,
+			"leases": leases,
 		},
 	}, nil
 }
 
-// handleAuditedHeadersRead returns the whole audited headers config
-func (b *SystemBackend) handleAuditedHeadersRead(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
-	headerConfig := b.Core.AuditedHeadersConfig()
+// handleLeasesIrrevocableRemove deletes an irrevocable lease without revoking
+// it from its backend
+func (b *SystemBackend) handleLeasesIrrevocableRemove(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	leaseID := data.Get("lease_id").(string)
+	if leaseID == "" {
+		return logical.ErrorResponse("lease_id must be specified"),
+			logical.ErrInvalidRequest
+	}
 
-	return &logical.Response{
-		Data: map[string]interface{}{
-			"headers": headerConfig.Headers,
-		},
-	}, nil
+	if err := b.Core.expiration.RemoveIrrevocable(leaseID); err != nil {
+		b.Backend.Logger().Error("sys: error removing irrevocable lease", "lease_id", leaseID, "error", err)
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	return nil, nil
 }
 
-// handleCapabilities returns the ACL capabilities of the token for a given path
-func (b *SystemBackend) handleCapabilities(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
-	token := d.Get("token").(string)
-	if token == "" {
-		token = req.ClientToken
+// handleLeaseLookupArtifact is used to find the leases an artifact, such as a
+// certificate serial number, was issued with
+func (b *SystemBackend) handleLeaseLookupArtifact(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	artifactType := data.Get("type").(string)
+	artifactID := data.Get("id").(string)
+	if artifactType == "" || artifactID == "" {
+		return logical.ErrorResponse("type and id must be specified"),
+			logical.ErrInvalidRequest
+	}
+	if strings.Contains(artifactType, "/") {
+		return logical.ErrorResponse("invalid artifact type"),
+			logical.ErrInvalidRequest
 	}
-	capabilities, err := b.Core.Capabilities(token, d.Get("path").(string))
+
+	leaseIDs, err := b.Core.expiration.LookupByArtifact(artifactType, artifactID)
 	if err != nil {
-		return nil, err
+		b.Backend.Logger().Error("sys: error looking up leases by artifact", "type", artifactType, "error", err)
+		return handleError(err)
 	}
 
 	return &logical.Response{
 		Data: map[string]interface{}{
-			"capabilities": capabilities,
+			"lease_ids": leaseIDs,
 		},
 	}, nil
 }
 
-// handleCapabilitiesAccessor returns the ACL capabilities of the
-// token associted with the given accessor for a given path.
-func (b *SystemBackend) handleCapabilitiesAccessor(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
-	accessor := d.Get("accessor").(string)
-	if accessor == "" {
-		return logical.ErrorResponse("missing accessor"), nil
+// handleRenew is used to renew a lease with a given LeaseID
+func (b *SystemBackend) handleRenew(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	// Get all the options
+	leaseID := data.Get("lease_id").(string)
+	if leaseID == "" {
+		leaseID = data.Get("url_lease_id").(string)
 	}
-
-	aEntry, err := b.Core.tokenStore.lookupByAccessor(accessor, false)
-	if err != nil {
-		return nil, err
+	if leaseID == "" {
+		return logical.ErrorResponse("lease_id must be specified"),
+			logical.ErrInvalidRequest
+	}
+	incrementRaw := data.Get("increment").(int)
+	if !namespaceLeaseAllowed(req.Namespace, leaseID) {
+		return logical.ErrorResponse("invalid lease"), logical.ErrInvalidRequest
 	}
 
-	capabilities, err := b.Core.Capabilities(aEntry.TokenID, d.Get("path").(string))
+	// Convert the increment
+	increment := time.Duration(incrementRaw) * time.Second
+
+	// Invoke the expiration manager directly
+	resp, err := b.Core.expiration.Renew(leaseID, increment)
 	if err != nil {
-		return nil, err
+		b.Backend.Logger().Error("sys: lease renewal failed", "lease_id", leaseID, "error", err)
+		return handleError(err)
 	}
-
-	return &logical.Response{
-		Data: map[string]interface{}{
-			"capabilities": capabilities,
-		},
-	}, nil
+	return resp, err
 }
 
-// handleRekeyRetrieve returns backed-up, PGP-encrypted unseal keys from a
-// rekey operation
-func (b *SystemBackend) handleRekeyRetrieve(
-	req *logical.Request,
-	data *framework.FieldData,
-	recovery bool) (*logical.Response, error) {
-	backup, err := b.Core.RekeyRetrieveBackup(recovery)
-	if err != nil {
-		return nil, fmt.Errorf("unable to look up backed-up keys: %v", err)
+// handleRevoke is used to revoke a given LeaseID
+func (b *SystemBackend) handleRevoke(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	// Get all the options
+	leaseID := data.Get("lease_id").(string)
+	if leaseID == "" {
+		leaseID = data.Get("url_lease_id").(string)
 	}
-	if backup == nil {
-		return logical.ErrorResponse("no backed-up keys found"), nil
+	if leaseID == "" {
+		return logical.ErrorResponse("lease_id must be specified"),
+			logical.ErrInvalidRequest
 	}
-
-	keysB64 := map[string][]string{}
-	for k, v := range backup.Keys {
-		for _, j := range v {
-			currB64Keys := keysB64[k]
-			if currB64Keys == nil {
-				currB64Keys = []string{}
-			}
-			key, err := hex.DecodeString(j)
-			if err != nil {
-				return nil, fmt.Errorf("error decoding hex-encoded backup key: %v", err)
-			}
-			currB64Keys = append(currB64Keys, base64.StdEncoding.EncodeToString(key))
-			keysB64[k] = currB64Keys
-		}
+	if !namespaceLeaseAllowed(req.Namespace, leaseID) {
+		return logical.ErrorResponse("invalid lease"), logical.ErrInvalidRequest
 	}
 
-	// Format the status
-	resp := &logical.Response{
-		Data: map[string]interface{}{
-			"nonce":       backup.Nonce,
-			"keys":        backup.Keys,
-			"keys_base64": keysB64,
-		},
+	// Invoke the expiration manager directly
+	if err := b.Core.expiration.Revoke(leaseID); err != nil {
+		b.Backend.Logger().Error("sys: lease revocation failed", "lease_id", leaseID, "error", err)
+		return handleError(err)
 	}
-
-	return resp, nil
+	return nil, nil
 }
 
-func (b *SystemBackend) handleRekeyRetrieveBarrier(
+// handleRevokePrefix is used to revoke a prefix with many LeaseIDs
+func (b *SystemBackend) handleRevokePrefix(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	return b.handleRekeyRetrieve(req, data, false)
+	return b.handleRevokePrefixCommon(req, data, false)
 }
 
-func (b *SystemBackend) handleRekeyRetrieveRecovery(
+// handleRevokeForce is used to revoke a prefix with many LeaseIDs, ignoring errors
+func (b *SystemBackend) handleRevokeForce(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	return b.handleRekeyRetrieve(req, data, true)
+	return b.handleRevokePrefixCommon(req, data, true)
 }
 
-// handleRekeyDelete deletes backed-up, PGP-encrypted unseal keys from a rekey
-// operation
-func (b *SystemBackend) handleRekeyDelete(
-	req *logical.Request,
-	data *framework.FieldData,
-	recovery bool) (*logical.Response, error) {
-	err := b.Core.RekeyDeleteBackup(recovery)
-	if err != nil {
-		return nil, fmt.Errorf("error during deletion of backed-up keys: %v", err)
+// handleRevokePrefixCommon is used to revoke a prefix with many LeaseIDs
+func (b *SystemBackend) handleRevokePrefixCommon(
+	req *logical.Request, data *framework.FieldData, force bool) (*logical.Response, error) {
+	// Get all the options
+	prefix := data.Get("prefix").(string)
+	if !namespaceLeaseAllowed(req.Namespace, prefix) {
+		return logical.ErrorResponse("prefix is not within the namespace"), logical.ErrInvalidRequest
 	}
 
+	// Invoke the expiration manager directly
+	var err error
+	if force {
+		err = b.Core.expiration.RevokeForce(prefix)
+	} else {
+		err = b.Core.expiration.RevokePrefix(prefix)
+	}
+	if err != nil {
+		b.Backend.Logger().Error("sys: revoke prefix failed", "prefix", prefix, "error", err)
+		return handleError(err)
+	}
 	return nil, nil
 }
 
-func (b *SystemBackend) handleRekeyDeleteBarrier(
-	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	return b.handleRekeyDelete(req, data, false)
-}
-
-func (b *SystemBackend) handleRekeyDeleteRecovery(
+// handleAuthTable handles the "auth" endpoint to provide the auth table
+func (b *SystemBackend) handleAuthTable(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	return b.handleRekeyDelete(req, data, true)
-}
+	ns := b.Core.requestNamespace(req)
+	if ns == nil {
+		return handleError(errNamespaceNotFound(req.Namespace))
+	}
 
-// handleMountTable handles the "mounts" endpoint to provide the mount table
-func (b *SystemBackend) handleMountTable(
-	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	b.Core.mountsLock.RLock()
-	defer b.Core.mountsLock.RUnlock()
+	b.Core.authLock.RLock()
+	defer b.Core.authLock.RUnlock()
 
 	resp := &logical.Response{
 		Data: make(map[string]interface{}),
 	}
+	for _, entry := range b.Core.auth.Entries {
+		// Only the auth mounts of the namespace are listed, relative to it
+		if entry.NamespaceID != ns.ID {
+			continue
+		}
 
-	for _, entry := range b.Core.mounts.Entries {
-		// Populate mount info
-		structConfig := structs.New(entry.Config).Map()
-		structConfig["default_lease_ttl"] = int64(structConfig["default_lease_ttl"].(time.Duration).Seconds())
-		structConfig["max_lease_ttl"] = int64(structConfig["max_lease_ttl"].(time.Duration).Seconds())
 		info := map[string]interface{}{
 			"type":        entry.Type,
 			"description": entry.Description,
 			"accessor":    entry.Accessor,
-			"config":      structConfig,
-			"local":       entry.Local,
+			"config": map[string]interface{}{
+				"default_lease_ttl":         int64(entry.Config.DefaultLeaseTTL.Seconds()),
+				"max_lease_ttl":             int64(entry.Config.MaxLeaseTTL.Seconds()),
+				"auto_create_group_aliases": entry.Config.AutoCreateGroupAliases,
+				"token_type":                mountTokenType(entry),
+			},
+			"local":     entry.Local,
+			"seal_wrap": entry.SealWrap,
 		}
-		resp.Data[entry.Path] = info
+		if entry.Config.PluginVersion != "" {
+			info["config"].(map[string]interface{})["plugin_version"] = entry.Config.PluginVersion
+		}
+		resp.Data[strings.TrimPrefix(entry.Path, ns.Path)] = info
 	}
-
 	return resp, nil
 }
 
-// handleMount is used to mount a new path
-func (b *SystemBackend) handleMount(
+// handleEnableAuth is used to enable a new credential backend
+func (b *SystemBackend) handleEnableAuth(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 	repState := b.Core.replicationState
 
@@ -1276,8 +3355,6 @@ func (b *SystemBackend) handleMount(
 	description := data.Get("description").(string)
 	pluginName := data.Get("plugin_name").(string)
 
-	path = sanitizeMountPath(path)
-
 	var config MountConfig
 	var apiConfig APIMountConfig
 
@@ -1286,50 +3363,12 @@ func (b *SystemBackend) handleMount(
 		err := mapstructure.Decode(configMap, &apiConfig)
 		if err != nil {
 			return logical.ErrorResponse(
-					"unable to convert given mount config information"),
-				logical.ErrInvalidRequest
-		}
-	}
-
-	switch apiConfig.DefaultLeaseTTL {
-	case "":
-	case "system":
-	default:
-		tmpDef, err := parseutil.ParseDurationSecond(apiConfig.DefaultLeaseTTL)
-		if err != nil {
-			return logical.ErrorResponse(fmt.Sprintf(
-					"unable to parse default TTL of %s: %s", apiConfig.DefaultLeaseTTL, err)),
-				logical.ErrInvalidRequest
-		}
-		config.DefaultLeaseTTL = tmpDef
-	}
-
-	switch apiConfig.MaxLeaseTTL {
-	case "":
-	case "system":
-	default:
-		tmpMax, err := parseutil.ParseDurationSecond(apiConfig.MaxLeaseTTL)
-		if err != nil {
-			return logical.ErrorResponse(fmt.Sprintf(
-					"unable to parse max TTL of %s: %s", apiConfig.MaxLeaseTTL, err)),
+					"unable to convert given auth config information"),
 				logical.ErrInvalidRequest
 		}
-		config.MaxLeaseTTL = tmpMax
-	}
-
-	if config.MaxLeaseTTL != 0 && config.DefaultLeaseTTL > config.MaxLeaseTTL {
-		return logical.ErrorResponse(
-				"given default lease TTL greater than given max lease TTL"),
-			logical.ErrInvalidRequest
-	}
-
-	if config.DefaultLeaseTTL > b.Core.maxLeaseTTL {
-		return logical.ErrorResponse(fmt.Sprintf(
-				"given default lease TTL greater than system max lease TTL of %d", int(b.Core.maxLeaseTTL.Seconds()))),
-			logical.ErrInvalidRequest
 	}
 
-	// Only set plugin-name if mount is of type plugin, with apiConfig.PluginName
+	// Only set plugin name if mount is of type plugin, with apiConfig.PluginName
 	// option taking precedence.
 	if logicalType == "plugin" {
 		switch {
@@ -1344,9 +3383,23 @@ func (b *SystemBackend) handleMount(
 		}
 	}
 
-	// Copy over the force no cache if set
-	if apiConfig.ForceNoCache {
-		config.ForceNoCache = true
+	// Pin plugin mounts to the version of the plugin given, if any
+	if apiConfig.PluginVersion != "" {
+		if logicalType != "plugin" {
+			return logical.ErrorResponse(
+					"plugin_version can only be set for plugin backends"),
+				logical.ErrInvalidRequest
+		}
+		config.PluginVersion = apiConfig.PluginVersion
+	}
+
+	config.AutoCreateGroupAliases = apiConfig.AutoCreateGroupAliases
+
+	if apiConfig.TokenType != "" {
+		if !validTokenType(apiConfig.TokenType) {
+			return logical.ErrorResponse(fmt.Sprintf("invalid token type %q", apiConfig.TokenType)), logical.ErrInvalidRequest
+		}
+		config.TokenType = apiConfig.TokenType
 	}
 
 	if logicalType == "" {
@@ -1355,447 +3408,641 @@ func (b *SystemBackend) handleMount(
 			logical.ErrInvalidRequest
 	}
 
+	path = sanitizeMountPath(path)
+
+	// The mount belongs to the namespace of the request
+	ns := b.Core.requestNamespace(req)
+	if ns == nil {
+		return handleError(errNamespaceNotFound(req.Namespace))
+	}
+
 	// Create the mount entry
 	me := &MountEntry{
-		Table:       mountTableType,
-		Path:        path,
+		Table:       credentialTableType,
+		Path:        ns.Path + path,
 		Type:        logicalType,
 		Description: description,
 		Config:      config,
 		Local:       local,
+		SealWrap:    data.Get("seal_wrap").(bool),
+		NamespaceID: ns.ID,
 	}
 
-	// Attempt mount
-	if err := b.Core.mount(me); err != nil {
-		b.Backend.Logger().Error("sys: mount failed", "path", me.Path, "error", err)
+	// Attempt enabling
+	if err := b.Core.enableCredential(me); err != nil {
+		b.Backend.Logger().Error("sys: enable auth mount failed", "path", me.Path, "error", err)
 		return handleError(err)
 	}
-
 	return nil, nil
 }
 
-// used to intercept an HTTPCodedError so it goes back to callee
-func handleError(
-	err error) (*logical.Response, error) {
-	switch err.(type) {
-	case logical.HTTPCodedError:
-		return logical.ErrorResponse(err.Error()), err
-	default:
-		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
-	}
-}
-
-// handleUnmount is used to unmount a path
-func (b *SystemBackend) handleUnmount(
+// handleDisableAuth is used to disable a credential backend
+func (b *SystemBackend) handleDisableAuth(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 	path := data.Get("path").(string)
 	path = sanitizeMountPath(path)
+	fullPath, ok := namespaceMountPath(req.Namespace, credentialRoutePrefix+path)
+	if !ok {
+		return handleError(fmt.Errorf("cannot disable '%s'", fullPath))
+	}
+	path = strings.TrimPrefix(fullPath, credentialRoutePrefix)
 
 	repState := b.Core.replicationState
-	entry := b.Core.router.MatchingMountEntry(path)
+	entry := b.Core.router.MatchingMountEntry(fullPath)
 	if entry != nil && !entry.Local && repState.HasState(consts.ReplicationPerformanceSecondary) {
 		return logical.ErrorResponse("cannot unmount a non-local mount on a replication secondary"), nil
 	}
 
-	// We return success when the mount does not exists to not expose if the
-	// mount existed or not
-	match := b.Core.router.MatchingMount(path)
-	if match == "" || path != match {
-		return nil, nil
+	// We return success when the mount does not exists to not expose if the
+	// mount existed or not
+	match := b.Core.router.MatchingMount(fullPath)
+	if match == "" || fullPath != match {
+		return nil, nil
+	}
+
+	// Attempt disable
+	if err := b.Core.disableCredential(path); err != nil {
+		b.Backend.Logger().Error("sys: disable auth mount failed", "path", path, "error", err)
+		return handleError(err)
+	}
+	return nil, nil
+}
+
+// handlePolicyList handles the "policy" endpoint to provide the enabled policies
+func (b *SystemBackend) handlePolicyList(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	policyStore := b.Core.requestPolicyStore(req)
+	if policyStore == nil {
+		return handleError(errNamespaceNotFound(req.Namespace))
+	}
+
+	// Get all the configured policies
+	policies, err := policyStore.ListPolicies()
+
+	// Add the special "root" policy
+	policies = append(policies, "root")
+	resp := logical.ListResponse(policies)
+
+	// Backwords compatibility
+	resp.Data["policies"] = resp.Data["keys"]
+
+	return resp, err
+}
+
+// handlePolicyRead handles the "policy/<name>" endpoint to read a policy
+func (b *SystemBackend) handlePolicyRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+
+	policyStore := b.Core.requestPolicyStore(req)
+	if policyStore == nil {
+		return handleError(errNamespaceNotFound(req.Namespace))
 	}
 
-	// Attempt unmount
-	if err := b.Core.unmount(path); err != nil {
-		b.Backend.Logger().Error("sys: unmount failed", "path", path, "error", err)
+	policy, err := policyStore.GetPolicy(name)
+	if err != nil {
 		return handleError(err)
 	}
 
-	return nil, nil
+	if policy == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"name":  policy.Name,
+			"rules": policy.Raw,
+		},
+	}, nil
 }
 
-// handleRemount is used to remount a path
-func (b *SystemBackend) handleRemount(
+// handlePolicySet handles the "policy/<name>" endpoint to set a policy
+func (b *SystemBackend) handlePolicySet(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	repState := b.Core.replicationState
+	name := data.Get("name").(string)
 
-	// Get the paths
-	fromPath := data.Get("from").(string)
-	toPath := data.Get("to").(string)
-	if fromPath == "" || toPath == "" {
-		return logical.ErrorResponse(
-				"both 'from' and 'to' path must be specified as a string"),
-			logical.ErrInvalidRequest
+	rulesRaw, ok := data.GetOk("rules")
+	if !ok {
+		return logical.ErrorResponse("'rules' parameter not supplied"), nil
 	}
 
-	fromPath = sanitizeMountPath(fromPath)
-	toPath = sanitizeMountPath(toPath)
-
-	entry := b.Core.router.MatchingMountEntry(fromPath)
-	if entry != nil && !entry.Local && repState.HasState(consts.ReplicationPerformanceSecondary) {
-		return logical.ErrorResponse("cannot remount a non-local mount on a replication secondary"), nil
+	rules := rulesRaw.(string)
+	if rules == "" {
+		return logical.ErrorResponse("'rules' parameter empty"), nil
 	}
 
-	// Attempt remount
-	if err := b.Core.remount(fromPath, toPath); err != nil {
-		b.Backend.Logger().Error("sys: remount failed", "from_path", fromPath, "to_path", toPath, "error", err)
+	// Validate the rules parse
+	parse, err := Parse(rules)
+	if err != nil {
 		return handleError(err)
 	}
 
-	return nil, nil
-}
-
-// handleAuthTuneRead is used to get config settings on a auth path
-func (b *SystemBackend) handleAuthTuneRead(
-	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	path := data.Get("path").(string)
-	if path == "" {
-		return logical.ErrorResponse(
-				"path must be specified as a string"),
-			logical.ErrInvalidRequest
+	if name != "" {
+		parse.Name = name
 	}
-	return b.handleTuneReadCommon("auth/" + path)
-}
 
-// handleMountTuneRead is used to get config settings on a backend
-func (b *SystemBackend) handleMountTuneRead(
-	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	path := data.Get("path").(string)
-	if path == "" {
-		return logical.ErrorResponse(
-				"path must be specified as a string"),
-			logical.ErrInvalidRequest
+	policyStore := b.Core.requestPolicyStore(req)
+	if policyStore == nil {
+		return handleError(errNamespaceNotFound(req.Namespace))
 	}
 
-	// This call will read both logical backend's configuration as well as auth backends'.
-	// Retaining this behavior for backward compatibility. If this behavior is not desired,
-	// an error can be returned if path has a prefix of "auth/".
-	return b.handleTuneReadCommon(path)
+	// Update the policy
+	if err := policyStore.SetPolicy(parse); err != nil {
+		return handleError(err)
+	}
+	return nil, nil
 }
 
-// handleTuneReadCommon returns the config settings of a path
-func (b *SystemBackend) handleTuneReadCommon(path string) (*logical.Response, error) {
-	path = sanitizeMountPath(path)
+// handlePolicyDelete handles the "policy/<name>" endpoint to delete a policy
+func (b *SystemBackend) handlePolicyDelete(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
 
-	sysView := b.Core.router.MatchingSystemView(path)
-	if sysView == nil {
-		b.Backend.Logger().Error("sys: cannot fetch sysview", "path", path)
-		return handleError(fmt.Errorf("sys: cannot fetch sysview for path %s", path))
+	policyStore := b.Core.requestPolicyStore(req)
+	if policyStore == nil {
+		return handleError(errNamespaceNotFound(req.Namespace))
 	}
 
-	mountEntry := b.Core.router.MatchingMountEntry(path)
-	if mountEntry == nil {
-		b.Backend.Logger().Error("sys: cannot fetch mount entry", "path", path)
-		return handleError(fmt.Errorf("sys: cannot fetch mount entry for path %s", path))
+	if err := policyStore.DeletePolicy(name); err != nil {
+		return handleError(err)
+	}
+	return nil, nil
+}
+
+// handleNamespaceList lists the child namespaces of the namespace of the
+// request
+func (b *SystemBackend) handleNamespaceList(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	ns := b.Core.requestNamespace(req)
+	if ns == nil {
+		return handleError(errNamespaceNotFound(req.Namespace))
 	}
 
-	resp := &logical.Response{
-		Data: map[string]interface{}{
-			"default_lease_ttl": int(sysView.DefaultLeaseTTL().Seconds()),
-			"max_lease_ttl":     int(sysView.MaxLeaseTTL().Seconds()),
-			"force_no_cache":    mountEntry.Config.ForceNoCache,
-		},
+	keys := []string{}
+	keyInfo := map[string]interface{}{}
+	for _, child := range b.Core.ListNamespaces(ns) {
+		key := strings.TrimPrefix(child.Path, ns.Path)
+		keys = append(keys, key)
+		keyInfo[key] = namespaceResponseData(child)
 	}
+	sort.Strings(keys)
 
+	resp := logical.ListResponse(keys)
+	resp.Data["key_info"] = keyInfo
 	return resp, nil
 }
 
-// handleAuthTuneWrite is used to set config settings on an auth path
-func (b *SystemBackend) handleAuthTuneWrite(
+// handleNamespaceRead returns a child namespace of the namespace of the
+// request
+func (b *SystemBackend) handleNamespaceRead(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	path := data.Get("path").(string)
-	if path == "" {
-		return logical.ErrorResponse("path must be specified as a string"),
-			logical.ErrInvalidRequest
+	child := b.Core.namespaceByPath(req.Namespace + data.Get("name").(string))
+	if child == nil || child.isRoot() {
+		return nil, nil
 	}
-	return b.handleTuneWriteCommon("auth/"+path, data)
+
+	return &logical.Response{
+		Data: namespaceResponseData(child),
+	}, nil
 }
 
-// handleMountTuneWrite is used to set config settings on a backend
-func (b *SystemBackend) handleMountTuneWrite(
+// handleNamespaceSet creates a child namespace of the namespace of the
+// request
+func (b *SystemBackend) handleNamespaceSet(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	path := data.Get("path").(string)
-	if path == "" {
-		return logical.ErrorResponse("path must be specified as a string"),
-			logical.ErrInvalidRequest
+	ns := b.Core.requestNamespace(req)
+	if ns == nil {
+		return handleError(errNamespaceNotFound(req.Namespace))
 	}
-	// This call will write both logical backend's configuration as well as auth backends'.
-	// Retaining this behavior for backward compatibility. If this behavior is not desired,
-	// an error can be returned if path has a prefix of "auth/".
-	return b.handleTuneWriteCommon(path, data)
-}
 
-// handleTuneWriteCommon is used to set config settings on a path
-func (b *SystemBackend) handleTuneWriteCommon(
-	path string, data *framework.FieldData) (*logical.Response, error) {
-	repState := b.Core.replicationState
+	child, err := b.Core.CreateNamespace(ns, data.Get("name").(string))
+	if err != nil {
+		return handleError(err)
+	}
 
-	path = sanitizeMountPath(path)
+	return &logical.Response{
+		Data: namespaceResponseData(child),
+	}, nil
+}
 
-	// Prevent protected paths from being changed
-	for _, p := range untunableMounts {
-		if strings.HasPrefix(path, p) {
-			b.Backend.Logger().Error("sys: cannot tune this mount", "path", path)
-			return handleError(fmt.Errorf("sys: cannot tune '%s'", path))
-		}
+// handleNamespaceDelete deletes a child namespace of the namespace of the
+// request
+func (b *SystemBackend) handleNamespaceDelete(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	child := b.Core.namespaceByPath(req.Namespace + data.Get("name").(string))
+	if child == nil || child.isRoot() {
+		return nil, nil
 	}
 
-	mountEntry := b.Core.router.MatchingMountEntry(path)
-	if mountEntry == nil {
-		b.Backend.Logger().Error("sys: tune failed: no mount entry found", "path", path)
-		return handleError(fmt.Errorf("sys: tune of path '%s' failed: no mount entry found", path))
-	}
-	if mountEntry != nil && !mountEntry.Local && repState.HasState(consts.ReplicationPerformanceSecondary) {
-		return logical.ErrorResponse("cannot tune a non-local mount on a replication secondary"), nil
+	if err := b.Core.DeleteNamespace(child); err != nil {
+		return handleError(err)
 	}
+	return nil, nil
+}
 
-	var lock *sync.RWMutex
-	switch {
-	case strings.HasPrefix(path, "auth/"):
-		lock = &b.Core.authLock
-	default:
-		lock = &b.Core.mountsLock
+// namespaceResponseData returns the data of the responses describing a
+// namespace
+func namespaceResponseData(ns *Namespace) map[string]interface{} {
+	return map[string]interface{}{
+		"id":   ns.ID,
+		"path": ns.Path,
 	}
+}
 
-	lock.Lock()
-	defer lock.Unlock()
-
-	// Check again after grabbing the lock
-	mountEntry = b.Core.router.MatchingMountEntry(path)
-	if mountEntry == nil {
-		b.Backend.Logger().Error("sys: tune failed: no mount entry found", "path", path)
-		return handleError(fmt.Errorf("sys: tune of path '%s' failed: no mount entry found", path))
-	}
-	if mountEntry != nil && !mountEntry.Local && repState.HasState(consts.ReplicationPerformanceSecondary) {
-		return logical.ErrorResponse("cannot tune a non-local mount on a replication secondary"), nil
+// handlePasswordPolicyList lists the password policies
+func (b *SystemBackend) handlePasswordPolicyList(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	names, err := b.Core.ListPasswordPolicies()
+	if err != nil {
+		return nil, err
 	}
+	return logical.ListResponse(names), nil
+}
 
-	// Timing configuration parameters
-	{
-		var newDefault, newMax time.Duration
-		defTTL := data.Get("default_lease_ttl").(string)
-		switch defTTL {
-		case "":
-			newDefault = mountEntry.Config.DefaultLeaseTTL
-		case "system":
-			newDefault = time.Duration(0)
-		default:
-			tmpDef, err := parseutil.ParseDurationSecond(defTTL)
-			if err != nil {
-				return handleError(err)
-			}
-			newDefault = tmpDef
-		}
-
-		maxTTL := data.Get("max_lease_ttl").(string)
-		switch maxTTL {
-		case "":
-			newMax = mountEntry.Config.MaxLeaseTTL
-		case "system":
-			newMax = time.Duration(0)
-		default:
-			tmpMax, err := parseutil.ParseDurationSecond(maxTTL)
-			if err != nil {
-				return handleError(err)
-			}
-			newMax = tmpMax
-		}
-
-		if newDefault != mountEntry.Config.DefaultLeaseTTL ||
-			newMax != mountEntry.Config.MaxLeaseTTL {
-
-			if err := b.tuneMountTTLs(path, mountEntry, newDefault, newMax); err != nil {
-				b.Backend.Logger().Error("sys: tuning failed", "path", path, "error", err)
-				return handleError(err)
-			}
-		}
+// handlePasswordPolicyRead returns a password policy
+func (b *SystemBackend) handlePasswordPolicyRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	policy, err := b.Core.PasswordPolicy(data.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if policy == nil {
+		return nil, nil
 	}
 
-	description := data.Get("description").(string)
-	if description != "" {
-		oldDesc := mountEntry.Description
-		mountEntry.Description = description
+	return &logical.Response{
+		Data: structs.New(policy).Map(),
+	}, nil
+}
 
-		// Update the mount table
-		var err error
-		switch {
-		case strings.HasPrefix(path, "auth/"):
-			err = b.Core.persistAuth(b.Core.auth, mountEntry.Local)
-		default:
-			err = b.Core.persistMounts(b.Core.mounts, mountEntry.Local)
-		}
-		if err != nil {
-			mountEntry.Description = oldDesc
-			return handleError(err)
-		}
-		if b.Core.logger.IsInfo() {
-			b.Core.logger.Info("core: mount tuning of description successful", "path", path)
-		}
+// handlePasswordPolicySet creates or updates a password policy
+func (b *SystemBackend) handlePasswordPolicySet(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	policy := &passwordpolicy.Policy{
+		Name:            data.Get("name").(string),
+		Length:          data.Get("length").(int),
+		MinLowercase:    data.Get("min_lowercase").(int),
+		MinUppercase:    data.Get("min_uppercase").(int),
+		MinDigits:       data.Get("min_digits").(int),
+		MinSymbols:      data.Get("min_symbols").(int),
+		Symbols:         data.Get("symbols").(string),
+		BannedPasswords: data.Get("banned_passwords").([]string),
+		History:         data.Get("history").(int),
+	}
+	if err := b.Core.SetPasswordPolicy(policy); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
 	}
 
 	return nil, nil
 }
 
-// handleLease is use to view the metadata for a given LeaseID
-func (b *SystemBackend) handleLeaseLookup(
+// handlePasswordPolicyDelete deletes a password policy
+func (b *SystemBackend) handlePasswordPolicyDelete(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	leaseID := data.Get("lease_id").(string)
-	if leaseID == "" {
-		return logical.ErrorResponse("lease_id must be specified"),
-			logical.ErrInvalidRequest
+	return nil, b.Core.DeletePasswordPolicy(data.Get("name").(string))
+}
+
+// handlePasswordPolicyGenerate generates a password from a password policy
+func (b *SystemBackend) handlePasswordPolicyGenerate(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+	policy, err := b.Core.PasswordPolicy(name)
+	if err != nil {
+		return nil, err
+	}
+	if policy == nil {
+		return logical.ErrorResponse(fmt.Sprintf("password policy %q does not exist", name)), logical.ErrInvalidRequest
 	}
 
-	leaseTimes, err := b.Core.expiration.FetchLeaseTimes(leaseID)
+	password, err := policy.Generate()
 	if err != nil {
-		b.Backend.Logger().Error("sys: error retrieving lease", "lease_id", leaseID, "error", err)
-		return handleError(err)
-	}
-	if leaseTimes == nil {
-		return logical.ErrorResponse("invalid lease"), logical.ErrInvalidRequest
+		return nil, err
 	}
 
-	resp := &logical.Response{
+	return &logical.Response{
 		Data: map[string]interface{}{
-			"id":           leaseID,
-			"issue_time":   leaseTimes.IssueTime,
-			"expire_time":  nil,
-			"last_renewal": nil,
-			"ttl":          int64(0),
+			"password": password,
 		},
+	}, nil
+}
+
+// handleLeaseCountQuotaList lists the lease count quotas
+func (b *SystemBackend) handleLeaseCountQuotaList(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	names, err := b.Core.ListLeaseCountQuotas()
+	if err != nil {
+		return nil, err
 	}
-	renewable, _ := leaseTimes.renewable()
-	resp.Data["renewable"] = renewable
+	return logical.ListResponse(names), nil
+}
 
-	if !leaseTimes.LastRenewalTime.IsZero() {
-		resp.Data["last_renewal"] = leaseTimes.LastRenewalTime
+// handleLeaseCountQuotaRead returns a lease count quota along with the number
+// of leases it currently counts
+func (b *SystemBackend) handleLeaseCountQuotaRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	quota := b.Core.LeaseCountQuota(data.Get("name").(string))
+	if quota == nil {
+		return nil, nil
 	}
-	if !leaseTimes.ExpireTime.IsZero() {
-		resp.Data["expire_time"] = leaseTimes.ExpireTime
-		resp.Data["ttl"] = leaseTimes.ttl()
+
+	resp := &logical.Response{
+		Data: structs.New(quota).Map(),
+	}
+	if !quota.PerEntity {
+		resp.Data["lease_count"] = quota.leaseCount()
 	}
 	return resp, nil
 }
 
-func (b *SystemBackend) handleLeaseLookupList(
+// handleControlGroupRequest returns the status of a request parked by a
+// control group
+func (b *SystemBackend) handleControlGroupRequest(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	prefix := data.Get("prefix").(string)
-	if prefix != "" && !strings.HasSuffix(prefix, "/") {
-		prefix = prefix + "/"
+	id := data.Get("id").(string)
+	if id == "" {
+		return logical.ErrorResponse("id must be specified"), logical.ErrInvalidRequest
 	}
 
-	keys, err := b.Core.expiration.idView.List(prefix)
+	cgReq, err := b.Core.ControlGroupRequest(id)
 	if err != nil {
-		b.Backend.Logger().Error("sys: error listing leases", "prefix", prefix, "error", err)
-		return handleError(err)
+		return nil, err
 	}
-	return logical.ListResponse(keys), nil
+	if cgReq == nil {
+		return logical.ErrorResponse("unknown or expired control group request"), logical.ErrInvalidRequest
+	}
+	return controlGroupRequestResponse(cgReq), nil
 }
 
-// handleRenew is used to renew a lease with a given LeaseID
-func (b *SystemBackend) handleRenew(
+// handleControlGroupAuthorize records the authorization of a request parked
+// by a control group by the entity of the calling token
+func (b *SystemBackend) handleControlGroupAuthorize(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	// Get all the options
-	leaseID := data.Get("lease_id").(string)
-	if leaseID == "" {
-		leaseID = data.Get("url_lease_id").(string)
+	id := data.Get("id").(string)
+	if id == "" {
+		return logical.ErrorResponse("id must be specified"), logical.ErrInvalidRequest
 	}
-	if leaseID == "" {
-		return logical.ErrorResponse("lease_id must be specified"),
-			logical.ErrInvalidRequest
+
+	cgReq, err := b.Core.AuthorizeControlGroupRequest(id, req.EntityID)
+	switch {
+	case err == logical.ErrPermissionDenied:
+		return logical.ErrorResponse("the calling entity is not an approver of the control group"), logical.ErrPermissionDenied
+	case err != nil:
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
 	}
-	incrementRaw := data.Get("increment").(int)
+	return controlGroupRequestResponse(cgReq), nil
+}
 
-	// Convert the increment
-	increment := time.Duration(incrementRaw) * time.Second
+func controlGroupRequestResponse(cgReq *ControlGroupRequest) *logical.Response {
+	authorizations := make([]map[string]interface{}, 0, len(cgReq.Authorizations))
+	for _, authz := range cgReq.Authorizations {
+		authorizations = append(authorizations, map[string]interface{}{
+			"entity_id": authz.EntityID,
+			"groups":    authz.Groups,
+			"time":      authz.Time,
+		})
+	}
+	factors := make([]map[string]interface{}, 0, len(cgReq.Factors))
+	for _, factor := range cgReq.Factors {
+		factors = append(factors, structs.New(factor).Map())
+	}
 
-	// Invoke the expiration manager directly
-	resp, err := b.Core.expiration.Renew(leaseID, increment)
-	if err != nil {
-		b.Backend.Logger().Error("sys: lease renewal failed", "lease_id", leaseID, "error", err)
-		return handleError(err)
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"id":                cgReq.ID,
+			"path":              cgReq.Path,
+			"operation":         cgReq.Operation,
+			"request_entity_id": cgReq.RequestEntityID,
+			"creation_time":     cgReq.CreationTime,
+			"expire_time":       cgReq.ExpireTime,
+			"factors":           factors,
+			"authorizations":    authorizations,
+			"approved":          cgReq.Approved(),
+		},
 	}
-	return resp, err
 }
 
-// handleRevoke is used to revoke a given LeaseID
-func (b *SystemBackend) handleRevoke(
+// handleEndpointPolicyList lists the endpoint governing policies
+func (b *SystemBackend) handleEndpointPolicyList(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	// Get all the options
-	leaseID := data.Get("lease_id").(string)
-	if leaseID == "" {
-		leaseID = data.Get("url_lease_id").(string)
+	names, err := b.Core.ListEndpointPolicies()
+	if err != nil {
+		return nil, err
 	}
-	if leaseID == "" {
-		return logical.ErrorResponse("lease_id must be specified"),
-			logical.ErrInvalidRequest
+	return logical.ListResponse(names), nil
+}
+
+// handleEndpointPolicyRead returns an endpoint governing policy
+func (b *SystemBackend) handleEndpointPolicyRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	policy := b.Core.EndpointPolicy(data.Get("name").(string))
+	if policy == nil {
+		return nil, nil
 	}
+	return &logical.Response{
+		Data: structs.New(policy).Map(),
+	}, nil
+}
 
-	// Invoke the expiration manager directly
-	if err := b.Core.expiration.Revoke(leaseID); err != nil {
-		b.Backend.Logger().Error("sys: lease revocation failed", "lease_id", leaseID, "error", err)
-		return handleError(err)
+// handleEndpointPolicySet creates or updates an endpoint governing policy
+func (b *SystemBackend) handleEndpointPolicySet(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	policy := &EndpointPolicy{
+		Name:             data.Get("name").(string),
+		Policy:           data.Get("policy").(string),
+		Paths:            data.Get("paths").([]string),
+		EnforcementLevel: data.Get("enforcement_level").(string),
 	}
+	if err := b.Core.SetEndpointPolicy(policy); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
 	return nil, nil
 }
 
-// handleRevokePrefix is used to revoke a prefix with many LeaseIDs
-func (b *SystemBackend) handleRevokePrefix(
+// handleEndpointPolicyDelete deletes an endpoint governing policy
+func (b *SystemBackend) handleEndpointPolicyDelete(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	return b.handleRevokePrefixCommon(req, data, false)
+	return nil, b.Core.DeleteEndpointPolicy(data.Get("name").(string))
 }
 
-// handleRevokeForce is used to revoke a prefix with many LeaseIDs, ignoring errors
-func (b *SystemBackend) handleRevokeForce(
+// handleLeaseCountQuotaSet creates or updates a lease count quota
+func (b *SystemBackend) handleLeaseCountQuotaSet(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	return b.handleRevokePrefixCommon(req, data, true)
+	quota := &LeaseCountQuota{
+		Name:      data.Get("name").(string),
+		Path:      data.Get("path").(string),
+		MaxLeases: data.Get("max_leases").(int),
+		PerEntity: data.Get("per_entity").(bool),
+	}
+	if err := b.Core.SetLeaseCountQuota(quota); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	return nil, nil
 }
 
-// handleRevokePrefixCommon is used to revoke a prefix with many LeaseIDs
-func (b *SystemBackend) handleRevokePrefixCommon(
-	req *logical.Request, data *framework.FieldData, force bool) (*logical.Response, error) {
-	// Get all the options
-	prefix := data.Get("prefix").(string)
+// handleLeaseCountQuotaDelete deletes a lease count quota
+func (b *SystemBackend) handleLeaseCountQuotaDelete(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	return nil, b.Core.DeleteLeaseCountQuota(data.Get("name").(string))
+}
 
-	// Invoke the expiration manager directly
-	var err error
-	if force {
-		err = b.Core.expiration.RevokeForce(prefix)
-	} else {
-		err = b.Core.expiration.RevokePrefix(prefix)
+// handleInFlightRequests returns the requests being handled by the node,
+// keyed by their identifier
+func (b *SystemBackend) handleInFlightRequests(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	now := time.Now()
+	requests := make(map[string]interface{})
+	for _, ifr := range b.Core.InFlightRequests() {
+		info := structs.New(ifr).Map()
+		info["start_time"] = ifr.StartTime.Format(time.RFC3339Nano)
+		info["duration"] = now.Sub(ifr.StartTime).String()
+		requests[ifr.ID] = info
+	}
+
+	return &logical.Response{
+		Data: requests,
+	}, nil
+}
+
+// handleInFlightRequestCancel cancels a request being handled by the node
+func (b *SystemBackend) handleInFlightRequestCancel(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	id := data.Get("id").(string)
+	if id == req.ID {
+		return logical.ErrorResponse("a request cannot cancel itself"), logical.ErrInvalidRequest
+	}
+	if !b.Core.CancelInFlightRequest(id) {
+		return logical.ErrorResponse(fmt.Sprintf("no in-flight request with identifier %q", id)), logical.ErrInvalidRequest
 	}
+	return nil, nil
+}
+
+// handleRateLimitQuotaList lists the rate limit quotas
+func (b *SystemBackend) handleRateLimitQuotaList(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	names, err := b.Core.ListRateLimitQuotas()
 	if err != nil {
-		b.Backend.Logger().Error("sys: revoke prefix failed", "prefix", prefix, "error", err)
-		return handleError(err)
+		return nil, err
+	}
+	return logical.ListResponse(names), nil
+}
+
+// handleRateLimitQuotaRead returns a rate limit quota
+func (b *SystemBackend) handleRateLimitQuotaRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	quota := b.Core.RateLimitQuota(data.Get("name").(string))
+	if quota == nil {
+		return nil, nil
 	}
+
+	resp := &logical.Response{
+		Data: structs.New(quota).Map(),
+	}
+	resp.Data["interval"] = int64(quota.Interval.Seconds())
+	return resp, nil
+}
+
+// handleRateLimitQuotaSet creates or updates a rate limit quota
+func (b *SystemBackend) handleRateLimitQuotaSet(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	quota := &RateLimitQuota{
+		Name:        data.Get("name").(string),
+		Path:        data.Get("path").(string),
+		Rate:        data.Get("rate").(int),
+		Interval:    time.Duration(data.Get("interval").(int)) * time.Second,
+		Burst:       data.Get("burst").(int),
+		PerClientIP: data.Get("per_client_ip").(bool),
+	}
+	if quota.Interval <= 0 {
+		return logical.ErrorResponse("interval must be positive"), logical.ErrInvalidRequest
+	}
+	if err := b.Core.SetRateLimitQuota(quota); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
 	return nil, nil
 }
 
-// handleAuthTable handles the "auth" endpoint to provide the auth table
-func (b *SystemBackend) handleAuthTable(
+// handleRateLimitQuotaDelete deletes a rate limit quota
+func (b *SystemBackend) handleRateLimitQuotaDelete(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	b.Core.authLock.RLock()
-	defer b.Core.authLock.RUnlock()
+	return nil, b.Core.DeleteRateLimitQuota(data.Get("name").(string))
+}
+
+// handleAuditTable handles the "audit" endpoint to provide the audit table
+func (b *SystemBackend) handleAuditTable(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	b.Core.auditLock.RLock()
+	defer b.Core.auditLock.RUnlock()
 
 	resp := &logical.Response{
 		Data: make(map[string]interface{}),
 	}
-	for _, entry := range b.Core.auth.Entries {
+	for _, entry := range b.Core.audit.Entries {
 		info := map[string]interface{}{
+			"path":        entry.Path,
 			"type":        entry.Type,
 			"description": entry.Description,
-			"accessor":    entry.Accessor,
-			"config": map[string]interface{}{
-				"default_lease_ttl": int64(entry.Config.DefaultLeaseTTL.Seconds()),
-				"max_lease_ttl":     int64(entry.Config.MaxLeaseTTL.Seconds()),
-			},
-			"local": entry.Local,
+			"options":     entry.Options,
+			"local":       entry.Local,
 		}
 		resp.Data[entry.Path] = info
 	}
 	return resp, nil
 }
 
-// handleEnableAuth is used to enable a new credential backend
-func (b *SystemBackend) handleEnableAuth(
+// handleAuditHash is used to fetch the hash of the given input data with the
+// specified audit backend's salt
+func (b *SystemBackend) handleAuditHash(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	path := data.Get("path").(string)
+	input := data.Get("input").(string)
+	if input == "" {
+		return logical.ErrorResponse("the \"input\" parameter is empty"), nil
+	}
+
+	path = sanitizeMountPath(path)
+
+	hash, err := b.Core.auditBroker.GetHash(path, input)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"hash": hash,
+		},
+	}, nil
+}
+
+// handleAuditVerify is used to verify the chain of the entries logged by an
+// audit backend
+func (b *SystemBackend) handleAuditVerify(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	path := sanitizeMountPath(data.Get("path").(string))
+
+	result, err := b.Core.auditBroker.VerifyChain(path, data.Get("file_path").(string))
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"valid":             result.Valid,
+			"error":             result.Error,
+			"line":              result.Line,
+			"entries":           result.Entries,
+			"unchained_entries": result.UnchainedEntries,
+			"first_prev_hash":   result.FirstPrevHash,
+			"chain_start":       result.ChainStart,
+			"last_hash":         result.LastHash,
+		},
+	}, nil
+}
+
+// handleEnableAudit is used to enable a new audit backend
+func (b *SystemBackend) handleEnableAudit(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 	repState := b.Core.replicationState
 
@@ -1806,432 +4053,548 @@ func (b *SystemBackend) handleEnableAuth(
 
 	// Get all the options
 	path := data.Get("path").(string)
-	logicalType := data.Get("type").(string)
+	backendType := data.Get("type").(string)
 	description := data.Get("description").(string)
-	pluginName := data.Get("plugin_name").(string)
-
-	var config MountConfig
-	var apiConfig APIMountConfig
-
-	configMap := data.Get("config").(map[string]interface{})
-	if configMap != nil && len(configMap) != 0 {
-		err := mapstructure.Decode(configMap, &apiConfig)
-		if err != nil {
-			return logical.ErrorResponse(
-					"unable to convert given auth config information"),
-				logical.ErrInvalidRequest
-		}
-	}
+	options := data.Get("options").(map[string]interface{})
 
-	// Only set plugin name if mount is of type plugin, with apiConfig.PluginName
-	// option taking precedence.
-	if logicalType == "plugin" {
-		switch {
-		case apiConfig.PluginName != "":
-			config.PluginName = apiConfig.PluginName
-		case pluginName != "":
-			config.PluginName = pluginName
-		default:
-			return logical.ErrorResponse(
-					"plugin_name must be provided for plugin backend"),
+	optionMap := make(map[string]string)
+	for k, v := range options {
+		vStr, ok := v.(string)
+		if !ok {
+			return logical.ErrorResponse("options must be string valued"),
 				logical.ErrInvalidRequest
 		}
+		optionMap[k] = vStr
 	}
 
-	if logicalType == "" {
-		return logical.ErrorResponse(
-				"backend type must be specified as a string"),
-			logical.ErrInvalidRequest
-	}
-
-	path = sanitizeMountPath(path)
-
 	// Create the mount entry
 	me := &MountEntry{
-		Table:       credentialTableType,
+		Table:       auditTableType,
 		Path:        path,
-		Type:        logicalType,
+		Type:        backendType,
 		Description: description,
-		Config:      config,
+		Options:     optionMap,
 		Local:       local,
 	}
 
 	// Attempt enabling
-	if err := b.Core.enableCredential(me); err != nil {
-		b.Backend.Logger().Error("sys: enable auth mount failed", "path", me.Path, "error", err)
+	if err := b.Core.enableAudit(me); err != nil {
+		b.Backend.Logger().Error("sys: enable audit mount failed", "path", me.Path, "error", err)
 		return handleError(err)
 	}
 	return nil, nil
 }
 
-// handleDisableAuth is used to disable a credential backend
-func (b *SystemBackend) handleDisableAuth(
+// handleDisableAudit is used to disable an audit backend
+func (b *SystemBackend) handleDisableAudit(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 	path := data.Get("path").(string)
-	path = sanitizeMountPath(path)
-	fullPath := credentialRoutePrefix + path
 
-	repState := b.Core.replicationState
-	entry := b.Core.router.MatchingMountEntry(fullPath)
-	if entry != nil && !entry.Local && repState.HasState(consts.ReplicationPerformanceSecondary) {
-		return logical.ErrorResponse("cannot unmount a non-local mount on a replication secondary"), nil
+	// Attempt disable
+	if existed, err := b.Core.disableAudit(path); existed && err != nil {
+		b.Backend.Logger().Error("sys: disable audit mount failed", "path", path, "error", err)
+		return handleError(err)
 	}
+	return nil, nil
+}
 
-	// We return success when the mount does not exists to not expose if the
-	// mount existed or not
-	match := b.Core.router.MatchingMount(fullPath)
-	if match == "" || fullPath != match {
-		return nil, nil
+// handleRawRead is used to read directly from the barrier
+func (b *SystemBackend) handleRawRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	path := data.Get("path").(string)
+
+	// Prevent access of protected paths
+	for _, p := range protectedPaths {
+		if strings.HasPrefix(path, p) {
+			err := fmt.Sprintf("cannot read '%s'", path)
+			return logical.ErrorResponse(err), logical.ErrInvalidRequest
+		}
 	}
 
-	// Attempt disable
-	if err := b.Core.disableCredential(path); err != nil {
-		b.Backend.Logger().Error("sys: disable auth mount failed", "path", path, "error", err)
+	entry, err := b.Core.barrier.Get(path)
+	if err != nil {
 		return handleError(err)
 	}
-	return nil, nil
+	if entry == nil {
+		return nil, nil
+	}
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"value": string(entry.Value),
+		},
+	}
+	return resp, nil
 }
 
-// handlePolicyList handles the "policy" endpoint to provide the enabled policies
-func (b *SystemBackend) handlePolicyList(
+// handleRawWrite is used to write directly to the barrier
+func (b *SystemBackend) handleRawWrite(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	// Get all the configured policies
-	policies, err := b.Core.policyStore.ListPolicies()
+	path := data.Get("path").(string)
 
-	// Add the special "root" policy
-	policies = append(policies, "root")
-	resp := logical.ListResponse(policies)
+	// Prevent access of protected paths
+	for _, p := range protectedPaths {
+		if strings.HasPrefix(path, p) {
+			err := fmt.Sprintf("cannot write '%s'", path)
+			return logical.ErrorResponse(err), logical.ErrInvalidRequest
+		}
+	}
 
-	// Backwords compatibility
-	resp.Data["policies"] = resp.Data["keys"]
+	value := data.Get("value").(string)
+	entry := &Entry{
+		Key:   path,
+		Value: []byte(value),
+	}
+	if err := b.Core.barrier.Put(entry); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	return nil, nil
+}
 
-	return resp, err
+// handleRawDelete is used to delete directly from the barrier
+func (b *SystemBackend) handleRawDelete(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	path := data.Get("path").(string)
+
+	// Prevent access of protected paths
+	for _, p := range protectedPaths {
+		if strings.HasPrefix(path, p) {
+			err := fmt.Sprintf("cannot delete '%s'", path)
+			return logical.ErrorResponse(err), logical.ErrInvalidRequest
+		}
+	}
+
+	if err := b.Core.barrier.Delete(path); err != nil {
+		return handleError(err)
+	}
+	return nil, nil
 }
 
-// handlePolicyRead handles the "policy/<name>" endpoint to read a policy
-func (b *SystemBackend) handlePolicyRead(
+// handleRawList is used to list directly from the barrier
+func (b *SystemBackend) handleRawList(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	name := data.Get("name").(string)
+	path := data.Get("path").(string)
+	if path != "" && !strings.HasSuffix(path, "/") {
+		path = path + "/"
+	}
+
+	// Prevent access of protected paths
+	for _, p := range protectedPaths {
+		if strings.HasPrefix(path, p) {
+			err := fmt.Sprintf("cannot list '%s'", path)
+			return logical.ErrorResponse(err), logical.ErrInvalidRequest
+		}
+	}
 
-	policy, err := b.Core.policyStore.GetPolicy(name)
+	keys, err := b.Core.barrier.List(path)
 	if err != nil {
 		return handleError(err)
 	}
+	return logical.ListResponse(keys), nil
+}
 
-	if policy == nil {
-		return nil, nil
+// handleKeyStatus returns status information about the backend key
+func (b *SystemBackend) handleKeyStatus(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	// Get the key info
+	info, err := b.Core.barrier.ActiveKeyInfo()
+	if err != nil {
+		return nil, err
 	}
 
-	return &logical.Response{
+	resp := &logical.Response{
 		Data: map[string]interface{}{
-			"name":  policy.Name,
-			"rules": policy.Raw,
+			"term":         info.Term,
+			"install_time": info.InstallTime.Format(time.RFC3339Nano),
+			"encryptions":  info.Encryptions,
 		},
-	}, nil
+	}
+	return resp, nil
 }
 
-// handlePolicySet handles the "policy/<name>" endpoint to set a policy
-func (b *SystemBackend) handlePolicySet(
+// handleRewrapStart starts rewrapping storage with the active encryption key
+func (b *SystemBackend) handleRewrapStart(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	name := data.Get("name").(string)
+	repState := b.Core.replicationState
+	if repState.HasState(consts.ReplicationPerformanceSecondary) {
+		return logical.ErrorResponse("cannot rewrap on a replication secondary"), nil
+	}
 
-	rulesRaw, ok := data.GetOk("rules")
-	if !ok {
-		return logical.ErrorResponse("'rules' parameter not supplied"), nil
+	rate := data.Get("rate").(int)
+	if rate < 0 {
+		return logical.ErrorResponse("rate cannot be negative"), logical.ErrInvalidRequest
 	}
 
-	rules := rulesRaw.(string)
-	if rules == "" {
-		return logical.ErrorResponse("'rules' parameter empty"), nil
+	if err := b.Core.startRewrap(data.Get("prefix").(string), rate); err != nil {
+		if err == ErrRewrapInProgress {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		return nil, err
 	}
+	return b.handleRewrapStatus(req, data)
+}
 
-	// Validate the rules parse
-	parse, err := Parse(rules)
-	if err != nil {
-		return handleError(err)
+// handleRewrapStatus returns the progress of the most recent storage rewrap
+func (b *SystemBackend) handleRewrapStatus(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	b.Core.rewrapLock.Lock()
+	job := b.Core.rewrap
+	b.Core.rewrapLock.Unlock()
+	if job == nil {
+		return nil, nil
 	}
 
-	if name != "" {
-		parse.Name = name
+	info, err := b.Core.barrier.ActiveKeyInfo()
+	if err != nil {
+		return nil, err
 	}
 
-	// Update the policy
-	if err := b.Core.policyStore.SetPolicy(parse); err != nil {
-		return handleError(err)
+	resp := &logical.Response{
+		Data: job.statusData(),
 	}
-	return nil, nil
+	resp.Data["active_term"] = info.Term
+	return resp, nil
 }
 
-// handlePolicyDelete handles the "policy/<name>" endpoint to delete a policy
-func (b *SystemBackend) handlePolicyDelete(
+// handleRewrapCancel cancels a running storage rewrap
+func (b *SystemBackend) handleRewrapCancel(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	name := data.Get("name").(string)
-
-	if err := b.Core.policyStore.DeletePolicy(name); err != nil {
-		return handleError(err)
-	}
+	b.Core.cancelRewrap()
 	return nil, nil
 }
 
-// handleAuditTable handles the "audit" endpoint to provide the audit table
-func (b *SystemBackend) handleAuditTable(
+// handleSealRewrapStatus returns the status of the values encrypted by the
+// auto-unseal seal
+func (b *SystemBackend) handleSealRewrapStatus(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	b.Core.auditLock.RLock()
-	defer b.Core.auditLock.RUnlock()
-
-	resp := &logical.Response{
-		Data: make(map[string]interface{}),
+	status, err := b.Core.SealRewrapStatus()
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
 	}
-	for _, entry := range b.Core.audit.Entries {
-		info := map[string]interface{}{
-			"path":        entry.Path,
-			"type":        entry.Type,
-			"description": entry.Description,
-			"options":     entry.Options,
-			"local":       entry.Local,
-		}
-		resp.Data[entry.Path] = info
+	return sealRewrapResponse(status), nil
+}
+
+// handleSealRewrap re-encrypts the values encrypted by the auto-unseal seal
+// with its current key
+func (b *SystemBackend) handleSealRewrap(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	status, err := b.Core.SealRewrap()
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
 	}
+	resp := sealRewrapResponse(status)
+	resp.Data["rewrapped"] = status.Rewrapped
 	return resp, nil
 }
 
-// handleAuditHash is used to fetch the hash of the given input data with the
-// specified audit backend's salt
-func (b *SystemBackend) handleAuditHash(
+func sealRewrapResponse(status *SealRewrapStatus) *logical.Response {
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"seal_type":     status.SealType,
+			"key_id":        status.KeyID,
+			"entries":       status.Entries,
+			"stale_entries": status.StaleEntries,
+		},
+	}
+}
+
+// handleRotate is used to trigger a key rotation
+func (b *SystemBackend) handleRotate(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	path := data.Get("path").(string)
-	input := data.Get("input").(string)
-	if input == "" {
-		return logical.ErrorResponse("the \"input\" parameter is empty"), nil
+	repState := b.Core.replicationState
+	if repState.HasState(consts.ReplicationPerformanceSecondary) {
+		return logical.ErrorResponse("cannot rotate on a replication secondary"), nil
 	}
 
-	path = sanitizeMountPath(path)
+	if _, err := b.Core.rotateBarrierKey(); err != nil {
+		return handleError(err)
+	}
+	return nil, nil
+}
 
-	hash, err := b.Core.auditBroker.GetHash(path, input)
+// handleRotateConfigRead returns the policy for the automatic rotation of
+// the active key
+func (b *SystemBackend) handleRotateConfigRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	config, err := b.Core.barrier.RotationConfig()
 	if err != nil {
-		return logical.ErrorResponse(err.Error()), nil
+		return nil, err
 	}
 
+	maxOperations := config.MaxOperations
+	if maxOperations == 0 {
+		maxOperations = absoluteOperationMaximum
+	}
 	return &logical.Response{
 		Data: map[string]interface{}{
-			"hash": hash,
+			"enabled":        !config.Disabled,
+			"max_operations": maxOperations,
+			"interval":       int64(config.Interval.Seconds()),
 		},
 	}, nil
 }
 
-// handleEnableAudit is used to enable a new audit backend
-func (b *SystemBackend) handleEnableAudit(
+// handleRotateConfigUpdate updates the policy for the automatic rotation of
+// the active key
+func (b *SystemBackend) handleRotateConfigUpdate(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 	repState := b.Core.replicationState
+	if repState.HasState(consts.ReplicationPerformanceSecondary) {
+		return logical.ErrorResponse("cannot configure rotation on a replication secondary"), nil
+	}
 
-	local := data.Get("local").(bool)
-	if !local && repState.HasState(consts.ReplicationPerformanceSecondary) {
-		return logical.ErrorResponse("cannot add a non-local mount to a replication secondary"), nil
+	config, err := b.Core.barrier.RotationConfig()
+	if err != nil {
+		return nil, err
+	}
+	if enabledRaw, ok := data.GetOk("enabled"); ok {
+		config.Disabled = !enabledRaw.(bool)
+	}
+	if maxOperationsRaw, ok := data.GetOk("max_operations"); ok {
+		config.MaxOperations = int64(maxOperationsRaw.(int))
+	}
+	if intervalRaw, ok := data.GetOk("interval"); ok {
+		config.Interval = time.Duration(intervalRaw.(int)) * time.Second
+	}
+	if err := validateRotationConfig(config); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
 	}
 
-	// Get all the options
-	path := data.Get("path").(string)
-	backendType := data.Get("type").(string)
-	description := data.Get("description").(string)
-	options := data.Get("options").(map[string]interface{})
+	if err := b.Core.barrier.SetRotationConfig(config); err != nil {
+		b.Backend.Logger().Error("sys: failed to save the key rotation configuration", "error", err)
+		return handleError(err)
+	}
+	return nil, nil
+}
+
+// handleChaosStatus returns the state of any injected faults
+func (b *SystemBackend) handleChaosStatus(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"storage_delay":           0,
+			"storage_delay_until":     "",
+			"expiration_paused_until": "",
+		},
+	}
 
-	optionMap := make(map[string]string)
-	for k, v := range options {
-		vStr, ok := v.(string)
-		if !ok {
-			return logical.ErrorResponse("options must be string valued"),
-				logical.ErrInvalidRequest
-		}
-		optionMap[k] = vStr
+	if delay, until := b.Core.chaosStorage.Delay(); delay > 0 {
+		resp.Data["storage_delay"] = int64(delay.Seconds())
+		resp.Data["storage_delay_until"] = until.Format(time.RFC3339)
+	}
+	if until := b.Core.expiration.PausedUntil(); !until.IsZero() {
+		resp.Data["expiration_paused_until"] = until.Format(time.RFC3339)
 	}
 
-	// Create the mount entry
-	me := &MountEntry{
-		Table:       auditTableType,
-		Path:        path,
-		Type:        backendType,
-		Description: description,
-		Options:     optionMap,
-		Local:       local,
+	return resp, nil
+}
+
+// handleChaosDropHALock makes the active node drop the HA lock
+func (b *SystemBackend) handleChaosDropHALock(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	if err := b.Core.ChaosDropHALock(); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
 	}
+	b.Backend.Logger().Warn("sys: HA lock drop requested")
+	return nil, nil
+}
 
-	// Attempt enabling
-	if err := b.Core.enableAudit(me); err != nil {
-		b.Backend.Logger().Error("sys: enable audit mount failed", "path", me.Path, "error", err)
-		return handleError(err)
+// handleChaosStorageDelay delays storage operations for a period of time
+func (b *SystemBackend) handleChaosStorageDelay(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	delay := time.Duration(data.Get("delay").(int)) * time.Second
+	duration := time.Duration(data.Get("duration").(int)) * time.Second
+	if delay <= 0 || duration <= 0 {
+		return logical.ErrorResponse("delay and duration must be positive"), logical.ErrInvalidRequest
 	}
+
+	b.Core.chaosStorage.SetDelay(delay, duration)
+	b.Backend.Logger().Warn("sys: delaying storage operations", "delay", delay, "duration", duration)
 	return nil, nil
 }
 
-// handleDisableAudit is used to disable an audit backend
-func (b *SystemBackend) handleDisableAudit(
+// handleChaosStorageDelayDelete clears any storage delay
+func (b *SystemBackend) handleChaosStorageDelayDelete(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	path := data.Get("path").(string)
+	b.Core.chaosStorage.SetDelay(0, 0)
+	return nil, nil
+}
 
-	// Attempt disable
-	if existed, err := b.Core.disableAudit(path); existed && err != nil {
-		b.Backend.Logger().Error("sys: disable audit mount failed", "path", path, "error", err)
-		return handleError(err)
+// handleChaosPauseExpiration pauses lease expiration for a period of time
+func (b *SystemBackend) handleChaosPauseExpiration(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	duration := time.Duration(data.Get("duration").(int)) * time.Second
+	if duration <= 0 {
+		return logical.ErrorResponse("duration must be positive"), logical.ErrInvalidRequest
 	}
+
+	b.Core.expiration.PauseExpiration(duration)
+	b.Backend.Logger().Warn("sys: pausing lease expiration", "duration", duration)
 	return nil, nil
 }
 
-// handleRawRead is used to read directly from the barrier
-func (b *SystemBackend) handleRawRead(
+// handleChaosPauseExpirationDelete resumes lease expiration
+func (b *SystemBackend) handleChaosPauseExpirationDelete(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	path := data.Get("path").(string)
+	b.Core.expiration.PauseExpiration(0)
+	return nil, nil
+}
 
-	// Prevent access of protected paths
-	for _, p := range protectedPaths {
-		if strings.HasPrefix(path, p) {
-			err := fmt.Sprintf("cannot read '%s'", path)
-			return logical.ErrorResponse(err), logical.ErrInvalidRequest
-		}
+// errRaftRootNamespace is returned for the raft storage requests made
+// outside of the root namespace
+var errRaftRootNamespace = errors.New("raft storage can only be managed from the root namespace")
+
+// handleRaftConfigurationRead returns the servers of the raft cluster
+func (b *SystemBackend) handleRaftConfigurationRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	if req.Namespace != "" {
+		return logical.ErrorResponse(errRaftRootNamespace.Error()), logical.ErrInvalidRequest
 	}
 
-	entry, err := b.Core.barrier.Get(path)
+	peers, err := b.Core.raftBackend.Peers()
 	if err != nil {
-		return handleError(err)
+		return nil, err
 	}
-	if entry == nil {
-		return nil, nil
+
+	servers := make([]map[string]interface{}, 0, len(peers))
+	for _, peer := range peers {
+		servers = append(servers, structs.New(peer).Map())
 	}
-	resp := &logical.Response{
+	return &logical.Response{
 		Data: map[string]interface{}{
-			"value": string(entry.Value),
+			"servers": servers,
 		},
-	}
-	return resp, nil
+	}, nil
 }
 
-// handleRawWrite is used to write directly to the barrier
-func (b *SystemBackend) handleRawWrite(
+// handleRaftAddPeer adds a voting server to the raft cluster
+func (b *SystemBackend) handleRaftAddPeer(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	path := data.Get("path").(string)
-
-	// Prevent access of protected paths
-	for _, p := range protectedPaths {
-		if strings.HasPrefix(path, p) {
-			err := fmt.Sprintf("cannot write '%s'", path)
-			return logical.ErrorResponse(err), logical.ErrInvalidRequest
-		}
+	if req.Namespace != "" {
+		return logical.ErrorResponse(errRaftRootNamespace.Error()), logical.ErrInvalidRequest
 	}
 
-	value := data.Get("value").(string)
-	entry := &Entry{
-		Key:   path,
-		Value: []byte(value),
-	}
-	if err := b.Core.barrier.Put(entry); err != nil {
-		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	serverID := data.Get("server_id").(string)
+	address := data.Get("address").(string)
+	if serverID == "" || address == "" {
+		return logical.ErrorResponse("server_id and address are required"), logical.ErrInvalidRequest
 	}
-	return nil, nil
-}
-
-// handleRawDelete is used to delete directly from the barrier
-func (b *SystemBackend) handleRawDelete(
-	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	path := data.Get("path").(string)
 
-	// Prevent access of protected paths
-	for _, p := range protectedPaths {
-		if strings.HasPrefix(path, p) {
-			err := fmt.Sprintf("cannot delete '%s'", path)
-			return logical.ErrorResponse(err), logical.ErrInvalidRequest
-		}
+	// The new peer needs the TLS key of the cluster to connect to it
+	key, err := b.Core.raftTLSKey()
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, fmt.Errorf("raft TLS key is not set up")
 	}
 
-	if err := b.Core.barrier.Delete(path); err != nil {
+	if err := b.Core.raftBackend.AddPeer(serverID, address); err != nil {
 		return handleError(err)
 	}
-	return nil, nil
+	b.Backend.Logger().Info("sys: added raft peer", "server_id", serverID, "address", address)
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"tls_cert": base64.StdEncoding.EncodeToString(key.CertBytes),
+			"tls_key":  base64.StdEncoding.EncodeToString(key.KeyBytes),
+		},
+	}, nil
 }
 
-// handleRawList is used to list directly from the barrier
-func (b *SystemBackend) handleRawList(
+// handleRaftRemovePeer removes a server from the raft cluster
+func (b *SystemBackend) handleRaftRemovePeer(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	path := data.Get("path").(string)
-	if path != "" && !strings.HasSuffix(path, "/") {
-		path = path + "/"
+	if req.Namespace != "" {
+		return logical.ErrorResponse(errRaftRootNamespace.Error()), logical.ErrInvalidRequest
 	}
 
-	// Prevent access of protected paths
-	for _, p := range protectedPaths {
-		if strings.HasPrefix(path, p) {
-			err := fmt.Sprintf("cannot list '%s'", path)
-			return logical.ErrorResponse(err), logical.ErrInvalidRequest
-		}
+	serverID := data.Get("server_id").(string)
+	if serverID == "" {
+		return logical.ErrorResponse("server_id is required"), logical.ErrInvalidRequest
 	}
 
-	keys, err := b.Core.barrier.List(path)
-	if err != nil {
+	if err := b.Core.raftBackend.RemovePeer(serverID); err != nil {
 		return handleError(err)
 	}
-	return logical.ListResponse(keys), nil
+	b.Backend.Logger().Info("sys: removed raft peer", "server_id", serverID)
+	return nil, nil
 }
 
-// handleKeyStatus returns status information about the backend key
-func (b *SystemBackend) handleKeyStatus(
+// handleEmergencySealConfigRead returns the emergency seal configuration
+func (b *SystemBackend) handleEmergencySealConfigRead(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	// Get the key info
-	info, err := b.Core.barrier.ActiveKeyInfo()
+	conf, err := b.Core.EmergencySealConfiguration()
 	if err != nil {
 		return nil, err
 	}
-
-	resp := &logical.Response{
-		Data: map[string]interface{}{
-			"term":         info.Term,
-			"install_time": info.InstallTime.Format(time.RFC3339Nano),
-		},
+	if conf == nil {
+		return nil, nil
 	}
-	return resp, nil
+
+	return &logical.Response{
+		Data: structs.New(conf).Map(),
+	}, nil
 }
 
-// handleRotate is used to trigger a key rotation
-func (b *SystemBackend) handleRotate(
+// handleEmergencySealConfigUpdate sets the emergency seal configuration
+func (b *SystemBackend) handleEmergencySealConfigUpdate(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	repState := b.Core.replicationState
-	if repState.HasState(consts.ReplicationPerformanceSecondary) {
-		return logical.ErrorResponse("cannot rotate on a replication secondary"), nil
+	conf := &EmergencySealConfig{
+		AuthorizedEntities: data.Get("authorized_entities").([]string),
+		Required:           data.Get("required").(int),
+	}
+	if err := b.Core.SetEmergencySealConfig(conf); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
 	}
 
-	// Rotate to the new term
-	newTerm, err := b.Core.barrier.Rotate()
+	return nil, nil
+}
+
+// handleEmergencySealStatus returns the progress of the current emergency
+// seal attempt
+func (b *SystemBackend) handleEmergencySealStatus(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	status, err := b.Core.EmergencySealProgress()
 	if err != nil {
-		b.Backend.Logger().Error("sys: failed to create new encryption key", "error", err)
-		return handleError(err)
+		return nil, err
 	}
-	b.Backend.Logger().Info("sys: installed new encryption key")
 
-	// In HA mode, we need to an upgrade path for the standby instances
-	if b.Core.ha != nil {
-		// Create the upgrade path to the new term
-		if err := b.Core.barrier.CreateUpgrade(newTerm); err != nil {
-			b.Backend.Logger().Error("sys: failed to create new upgrade", "term", newTerm, "error", err)
-		}
+	return emergencySealStatusResponse(status), nil
+}
 
-		// Schedule the destroy of the upgrade path
-		time.AfterFunc(keyRotateGracePeriod, func() {
-			if err := b.Core.barrier.DestroyUpgrade(newTerm); err != nil {
-				b.Backend.Logger().Error("sys: failed to destroy upgrade", "term", newTerm, "error", err)
-			}
-		})
+// handleEmergencySealAuthorize records the calling entity's authorization
+// for an emergency seal
+func (b *SystemBackend) handleEmergencySealAuthorize(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	status, err := b.Core.EmergencySealAuthorize(req.EntityID, data.Get("nonce").(string))
+	switch {
+	case err == logical.ErrPermissionDenied:
+		return nil, err
+	case err != nil:
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
 	}
 
-	// Write to the canary path, which will force a synchronous truing during
-	// replication
-	if err := b.Core.barrier.Put(&Entry{
-		Key:   coreKeyringCanaryPath,
-		Value: []byte(fmt.Sprintf("new-rotation-term-%d", newTerm)),
-	}); err != nil {
-		b.Core.logger.Error("core: error saving keyring canary", "error", err)
-		return nil, fmt.Errorf("failed to save keyring canary: %v", err)
-	}
+	return emergencySealStatusResponse(status), nil
+}
 
+// handleEmergencySealCancel cancels the current emergency seal attempt
+func (b *SystemBackend) handleEmergencySealCancel(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	b.Core.EmergencySealCancel()
 	return nil, nil
 }
 
+func emergencySealStatusResponse(status *EmergencySealStatus) *logical.Response {
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"started":  status.Started,
+			"nonce":    status.Nonce,
+			"progress": status.Progress,
+			"required": status.Required,
+			"complete": status.Required > 0 && status.Progress >= status.Required,
+		},
+	}
+}
+
 func (b *SystemBackend) handleWrappingPubkey(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 	x, _ := b.Core.wrappingJWTKey.X.MarshalText()
@@ -2263,6 +4626,33 @@ func (b *SystemBackend) handleWrappingWrap(
 	}, nil
 }
 
+func (b *SystemBackend) handleWrappingWrapEntries(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	if req.WrapInfo == nil || req.WrapInfo.TTL == 0 {
+		return logical.ErrorResponse("endpoint requires response wrapping to be used"), logical.ErrInvalidRequest
+	}
+
+	if len(data.Raw) == 0 {
+		return logical.ErrorResponse("at least one entry must be given"), logical.ErrInvalidRequest
+	}
+	for name, payload := range data.Raw {
+		if name == "" || strings.Contains(name, "/") {
+			return logical.ErrorResponse(fmt.Sprintf("invalid entry name %q", name)), logical.ErrInvalidRequest
+		}
+		if _, ok := payload.(map[string]interface{}); !ok {
+			return logical.ErrorResponse(fmt.Sprintf("entry %q must be an object", name)), logical.ErrInvalidRequest
+		}
+	}
+
+	// As with sys/wrapping/wrap, JWT wrapping tokens can't be created through
+	// this endpoint
+	req.WrapInfo.Format = "uuid"
+
+	return &logical.Response{
+		Data: data.Raw,
+	}, nil
+}
+
 func (b *SystemBackend) handleWrappingUnwrap(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 	// If a third party is unwrapping (rather than the calling token being the
@@ -2277,6 +4667,33 @@ func (b *SystemBackend) handleWrappingUnwrap(
 		token = req.ClientToken
 	}
 
+	// The creation path is asserted before a token given in the request is
+	// used, so that a mismatch doesn't consume it
+	if creationPath := data.Get("creation_path").(string); creationPath != "" {
+		resp, err := b.checkWrappingCreationPath(token, creationPath)
+		if resp != nil || err != nil {
+			return resp, err
+		}
+	}
+
+	// Tokens wrapping multiple named entries are only revoked once all of
+	// them have been unwrapped, so they are checked for before the token is
+	// used
+	entry := data.Get("entry").(string)
+	entries, err := b.wrappedEntries(token)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case len(entries) > 0 && entry == "":
+		return logical.ErrorResponse(fmt.Sprintf(
+			"token wraps multiple entries, one of which must be given in \"entry\": %s", strings.Join(entries, ", "))), logical.ErrInvalidRequest
+	case len(entries) > 0:
+		return b.handleWrappingUnwrapEntry(token, entry)
+	case entry != "":
+		return logical.ErrorResponse("token does not wrap multiple entries"), logical.ErrInvalidRequest
+	}
+
 	if thirdParty {
 		// Use the token to decrement the use count to avoid a second operation on the token.
 		_, err := b.Core.tokenStore.UseTokenByID(token)
@@ -2287,26 +4704,145 @@ func (b *SystemBackend) handleWrappingUnwrap(
 		defer b.Core.tokenStore.Revoke(token)
 	}
 
-	cubbyReq := &logical.Request{
+	cubbyReq := &logical.Request{
+		Operation:   logical.ReadOperation,
+		Path:        "cubbyhole/response",
+		ClientToken: token,
+	}
+	cubbyResp, err := b.Core.router.Route(cubbyReq)
+	if err != nil {
+		return nil, fmt.Errorf("error looking up wrapping information: %v", err)
+	}
+	if cubbyResp == nil {
+		return logical.ErrorResponse("no information found; wrapping token may be from a previous Vault version"), nil
+	}
+	if cubbyResp != nil && cubbyResp.IsError() {
+		return cubbyResp, nil
+	}
+	if cubbyResp.Data == nil {
+		return logical.ErrorResponse("wrapping information was nil; wrapping token may be from a previous Vault version"), nil
+	}
+
+	return unwrappedResponse(cubbyResp.Data["response"])
+}
+
+// handleWrappingUnwrapEntry unwraps a single entry of a token wrapping
+// multiple named entries, revoking the token once no entries remain
+func (b *SystemBackend) handleWrappingUnwrapEntry(token, entry string) (*logical.Response, error) {
+	lock := locksutil.LockForKey(b.wrappedEntryLocks, token)
+	lock.Lock()
+	defer lock.Unlock()
+
+	cubbyResp, err := b.Core.router.Route(&logical.Request{
+		Operation:   logical.ReadOperation,
+		Path:        "cubbyhole/" + wrappedEntryPrefix + entry,
+		ClientToken: token,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error looking up wrapped entry: %v", err)
+	}
+	if cubbyResp != nil && cubbyResp.IsError() {
+		return cubbyResp, nil
+	}
+	if cubbyResp == nil || cubbyResp.Data == nil {
+		return logical.ErrorResponse(fmt.Sprintf("entry %q not found; it may have already been unwrapped", entry)), logical.ErrInvalidRequest
+	}
+	response := cubbyResp.Data["response"]
+
+	cubbyResp, err = b.Core.router.Route(&logical.Request{
+		Operation:   logical.DeleteOperation,
+		Path:        "cubbyhole/" + wrappedEntryPrefix + entry,
+		ClientToken: token,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error removing wrapped entry: %v", err)
+	}
+	if cubbyResp != nil && cubbyResp.IsError() {
+		return cubbyResp, nil
+	}
+
+	remaining, err := b.remainingWrappedEntries(token)
+	if err != nil {
+		return nil, err
+	}
+	if len(remaining) == 0 {
+		defer b.Core.tokenStore.Revoke(token)
+	}
+
+	return unwrappedResponse(response)
+}
+
+// checkWrappingCreationPath returns an error response unless the token was
+// created by a request to the expected path. A mismatch may reveal that the
+// wrapped response was intercepted and replaced.
+func (b *SystemBackend) checkWrappingCreationPath(token, expected string) (*logical.Response, error) {
+	cubbyResp, err := b.Core.router.Route(&logical.Request{
+		Operation:   logical.ReadOperation,
+		Path:        "cubbyhole/wrapinfo",
+		ClientToken: token,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error looking up wrapping information: %v", err)
+	}
+	if cubbyResp != nil && cubbyResp.IsError() {
+		return cubbyResp, nil
+	}
+	if cubbyResp == nil || cubbyResp.Data == nil {
+		return logical.ErrorResponse("no wrapping information found to verify the creation path"), logical.ErrInvalidRequest
+	}
+
+	creationPath, _ := cubbyResp.Data["creation_path"].(string)
+	if strings.TrimPrefix(expected, "/") != creationPath {
+		b.Backend.Logger().Warn("sys: wrapping token creation path mismatch, the wrapped response may have been intercepted",
+			"expected", expected, "actual", creationPath)
+		return logical.ErrorResponse(fmt.Sprintf(
+			"wrapping token was created by a request to %q rather than the expected path %q", creationPath, expected)), logical.ErrPermissionDenied
+	}
+	return nil, nil
+}
+
+// wrappedEntries returns the names of the entries a token was created to
+// wrap, which is empty unless it wraps multiple named entries
+func (b *SystemBackend) wrappedEntries(token string) ([]string, error) {
+	cubbyResp, err := b.Core.router.Route(&logical.Request{
 		Operation:   logical.ReadOperation,
-		Path:        "cubbyhole/response",
+		Path:        "cubbyhole/wrapinfo",
 		ClientToken: token,
-	}
-	cubbyResp, err := b.Core.router.Route(cubbyReq)
+	})
 	if err != nil {
 		return nil, fmt.Errorf("error looking up wrapping information: %v", err)
 	}
-	if cubbyResp == nil {
-		return logical.ErrorResponse("no information found; wrapping token may be from a previous Vault version"), nil
+	if cubbyResp == nil || cubbyResp.IsError() || cubbyResp.Data == nil {
+		return nil, nil
 	}
-	if cubbyResp != nil && cubbyResp.IsError() {
-		return cubbyResp, nil
+
+	var entries []string
+	if err := mapstructure.Decode(cubbyResp.Data["entries"], &entries); err != nil {
+		return nil, fmt.Errorf("error reading entries from wrapping information: %v", err)
 	}
-	if cubbyResp.Data == nil {
-		return logical.ErrorResponse("wrapping information was nil; wrapping token may be from a previous Vault version"), nil
+	return entries, nil
+}
+
+// remainingWrappedEntries returns the names of the entries of a token
+// wrapping multiple named entries that haven't been unwrapped yet
+func (b *SystemBackend) remainingWrappedEntries(token string) ([]string, error) {
+	cubbyResp, err := b.Core.router.Route(&logical.Request{
+		Operation:   logical.ListOperation,
+		Path:        "cubbyhole/" + wrappedEntryPrefix,
+		ClientToken: token,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing wrapped entries: %v", err)
+	}
+	if cubbyResp == nil || cubbyResp.Data == nil || cubbyResp.Data["keys"] == nil {
+		return []string{}, nil
 	}
+	return cubbyResp.Data["keys"].([]string), nil
+}
 
-	responseRaw := cubbyResp.Data["response"]
+// unwrappedResponse returns the raw HTTP response of a wrapped response
+// stored in a cubbyhole
+func unwrappedResponse(responseRaw interface{}) (*logical.Response, error) {
 	if responseRaw == nil {
 		return nil, fmt.Errorf("no response found inside the cubbyhole")
 	}
@@ -2339,6 +4875,16 @@ func (b *SystemBackend) handleWrappingLookup(
 		if token == "" {
 			return logical.ErrorResponse("missing \"token\" value in input"), logical.ErrInvalidRequest
 		}
+	} else {
+		// The cubbyhole of any other token could hold forged wrapping
+		// information
+		valid, err := b.Core.isWrappingToken(token)
+		if err != nil {
+			return nil, err
+		}
+		if !valid {
+			return logical.ErrorResponse("wrapping token is not valid or does not exist"), logical.ErrInvalidRequest
+		}
 	}
 
 	cubbyReq := &logical.Request{
@@ -2381,6 +4927,16 @@ func (b *SystemBackend) handleWrappingLookup(
 	if creationPath != nil {
 		resp.Data["creation_path"] = cubbyResp.Data["creation_path"]
 	}
+	if creatorEntityID := cubbyResp.Data["creator_entity_id"]; creatorEntityID != nil {
+		resp.Data["creator_entity_id"] = creatorEntityID
+	}
+	if cubbyResp.Data["entries"] != nil {
+		entries, err := b.remainingWrappedEntries(token)
+		if err != nil {
+			return nil, err
+		}
+		resp.Data["entries"] = entries
+	}
 
 	return resp, nil
 }
@@ -2401,6 +4957,14 @@ func (b *SystemBackend) handleWrappingRewrap(
 		token = req.ClientToken
 	}
 
+	entries, err := b.wrappedEntries(token)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) > 0 {
+		return logical.ErrorResponse("tokens wrapping multiple entries cannot be rewrapped"), logical.ErrInvalidRequest
+	}
+
 	if thirdParty {
 		// Use the token to decrement the use count to avoid a second operation on the token.
 		_, err := b.Core.tokenStore.UseTokenByID(token)
@@ -2446,6 +5010,7 @@ func (b *SystemBackend) handleWrappingRewrap(
 		return nil, fmt.Errorf("creation_path value in wrapping information was nil")
 	}
 	creationPath := creationPathRaw.(string)
+	creatorEntityID, _ := cubbyResp.Data["creator_entity_id"].(string)
 
 	// Fetch the original response and return it as the data for the new response
 	cubbyReq = &logical.Request{
@@ -2479,8 +5044,9 @@ func (b *SystemBackend) handleWrappingRewrap(
 			"response": response,
 		},
 		WrapInfo: &wrapping.ResponseWrapInfo{
-			TTL:          time.Duration(creationTTL),
-			CreationPath: creationPath,
+			TTL:             time.Duration(creationTTL),
+			CreationPath:    creationPath,
+			WrappedEntityID: creatorEntityID,
 		},
 	}, nil
 }
@@ -2505,6 +5071,47 @@ as well as perform core operations.
 
 // sysHelp is all the help text for the sys backend.
 var sysHelp = map[string][2]string{
+	"config/reload": {
+		"Reloads the configuration of the server.",
+		`
+Reloads the configuration of the server without restarting it, the same
+way as sending SIGHUP to the server process does. The listeners load their
+TLS certificates, client CA bundles and cipher settings again from the
+configuration files. The connections already established are not affected.
+The log level and the telemetry sinks are changed to the ones of the
+configuration, and the file audit devices reopen their files.
+		`,
+	},
+
+	"config/state": {
+		"Returns the configuration in effect of the server.",
+		`
+Returns the configuration of the server as it is in effect, including the
+changes applied on reload such as the log level and the telemetry
+settings. The settings which may hold secrets, such as the configuration
+of the storage and seal backends, are left out.
+		`,
+	},
+
+	"metrics": {
+		"Returns the recent metrics of the server.",
+		`
+Returns the metrics of the last complete ten seconds interval of the
+in-memory telemetry sink of the server: the gauges, counters, samples and
+points, with their labels. The metrics are also sent to the telemetry sinks
+of the configuration.
+		`,
+	},
+
+	"host-info": {
+		"Returns information about the host and the process of the server.",
+		`
+Returns the hostname, operating system and architecture of the host, and
+the Go version, process ID, number of goroutines and memory statistics of
+the server process.
+		`,
+	},
+
 	"config/cors": {
 		"Configures or returns the current configuration of CORS settings.",
 		`
@@ -2638,6 +5245,16 @@ and max_lease_ttl.`,
 and is unaffected by replication.`,
 	},
 
+	"seal_wrap": {
+		`Seal-wrap the storage entries of the backend, encrypting them
+with the seal in addition to the barrier.`,
+	},
+
+	"mount_options": {
+		`Backend-specific options for the mount, such as "version"
+for the kv backend.`,
+	},
+
 	"mount_plugin_name": {
 		`Name of the plugin to mount based from the name registered 
 in the plugin catalog.`,
@@ -2667,6 +5284,59 @@ This path responds to the following HTTP methods.
 the auth path.`,
 	},
 
+	"mount_export_config": {
+		"Export the configuration of a mount.",
+		`
+Returns a signed document holding the tune settings of the mount and the
+non-secret configuration its backend stores, such as roles. The document can
+be imported into a mount of the same type on another cluster with the
+import-config endpoint, for instance to promote configuration from a staging
+cluster to production. Secrets and credentials are never exported.
+		`,
+	},
+
+	"mount_import_config": {
+		"Import the configuration of a mount exported by another cluster.",
+		`
+Verifies that a document returned by the export-config endpoint was signed by
+this cluster or a cluster trusted in "sys/config/mount-import", and returns
+how importing it would change the mount: the tune settings that
+differ, and the configuration entries that would be added, changed, or
+removed. The changes are only made if "apply" is set, and entries missing from
+the document are only removed if "prune" is also set.
+		`,
+	},
+
+	"mount_import_document": {
+		`The base64-encoded document returned by the export-config endpoint.`,
+	},
+
+	"mount_import_signature": {
+		`The base64-encoded signature returned with the document.`,
+	},
+
+	"config/mount-import": {
+		"Configures the clusters whose exported mount configuration can be imported.",
+		`
+Documents returned by the export-config endpoint of a mount are signed with
+a key of the exporting cluster, which is returned along with them. The
+import-config endpoint only accepts documents signed by this cluster or by a
+cluster whose public key is listed in "trusted_keys".
+		`,
+	},
+
+	"mount_import_trusted_keys": {
+		`The base64-encoded public keys of the clusters whose exported mount configuration can be imported, as returned by their export-config endpoint.`,
+	},
+
+	"mount_import_apply": {
+		`If true, the changes are made. Otherwise only the differences are returned.`,
+	},
+
+	"mount_import_prune": {
+		`If true, configuration entries of the mount that are missing from the document are removed when applying.`,
+	},
+
 	"mount_tune": {
 		"Tune backend configuration parameters for this mount.",
 		`Read and write the 'default-lease-ttl' and 'max-lease-ttl' values of
@@ -2783,6 +5453,15 @@ Example: you might have an OAuth backend for GitHub, and one for Google Apps.
 		`Configuration for this mount, such as plugin_name.`,
 	},
 
+	"auto_create_group_aliases": {
+		`Whether logins create the external groups and group aliases of the
+groups reported by the auth backend when they do not exist yet.`,
+	},
+
+	"token_type": {
+		`The type of the tokens issued by logins, either "service" or "batch".`,
+	},
+
 	"auth_plugin": {
 		`Name of the auth plugin to use based from the name in the plugin catalog.`,
 		"",
@@ -2815,6 +5494,120 @@ or delete a policy.
 		`,
 	},
 
+	"namespace-list": {
+		`List the child namespaces of the namespace.`,
+		"",
+	},
+
+	"namespace": {
+		`Read, Create, or Delete a child namespace.`,
+		`
+Namespaces isolate the mounts, policies, identities and tokens of tenants. The
+requests to a namespace are sent with its path in the X-Vault-Namespace header
+or as a prefix of the request path. The tokens of a namespace only access it
+and its child namespaces, whose administrators can't reach their parents.
+Namespaces can only be deleted once their mounts and child namespaces are.
+		`,
+	},
+
+	"namespace-name": {
+		`The name of the namespace, which is a single path segment.`,
+		"",
+	},
+
+	"password-policy-list": {
+		`List the password policies.`,
+		"",
+	},
+
+	"password-policy": {
+		`Read, Modify, or Delete a password policy.`,
+		`
+Password policies set the length, the minimum number of characters of each
+class and the banned passwords of the passwords given by users to the backends
+enforcing them, such as userpass, along with the number of previous passwords
+that can't be reused. Backends generating passwords use them too.
+		`,
+	},
+
+	"egp-list": {
+		`List the endpoint governing policies.`,
+		"",
+	},
+
+	"egp": {
+		`Read, Modify, or Delete an endpoint governing policy.`,
+		`
+Endpoint governing policies are CEL rules evaluated against the metadata of the
+requests on the paths they govern once their token is authorized: the request
+path, operation and source address, the time of day, the policies, metadata and
+entity of the token, and the MFA methods validated at login. Requests failing a
+hard-mandatory policy are rejected, while those failing an advisory policy are
+only logged. Requests of root tokens are not evaluated.
+		`,
+	},
+
+	"lease-count-quota-list": {
+		`List the lease count quotas.`,
+		"",
+	},
+
+	"lease-count-quota": {
+		`Read, Modify, or Delete a lease count quota.`,
+		`
+Lease count quotas cap the number of active leases issued under a mount, or a
+path under a mount such as the credentials endpoint of a role, either in total
+or to each entity. Requests which would issue a lease exceeding a quota are
+rejected. Leases are counted as they are loaded, so quotas are loosely enforced
+until the leases are restored after unsealing.
+		`,
+	},
+
+	"in-flight-req": {
+		`List the requests being handled by the node.`,
+		`
+Returns the requests currently being handled by this node, keyed by their
+identifier, with their path, operation, client address, start time and
+duration. Requests forwarded by the standby nodes are listed on the active
+node.
+		`,
+	},
+
+	"in-flight-req-cancel": {
+		`Cancel a request being handled by the node.`,
+		`
+Cancels the context of the request, which the backends handling it use to
+abort their work. A backend blocked in a call that doesn't honor the context
+only notices the cancellation once the call returns.
+		`,
+	},
+
+	"rate-limit-quota-list": {
+		`List the rate limit quotas.`,
+		"",
+	},
+
+	"rate-limit-quota": {
+		`Read, Modify, or Delete a rate limit quota.`,
+		`
+Rate limit quotas limit the rate of the requests to a mount, or a path prefix
+under a mount, either in total or from each client IP address. Requests
+exceeding a quota are rejected with a 429 status code and a Retry-After header,
+before any work is done on their behalf. The requests to sys/quotas are never
+limited.
+		`,
+	},
+
+	"password-policy-generate": {
+		`Generate a password from a password policy.`,
+		"",
+	},
+
+	"password-policy-name": {
+		`The name of the password policy.`,
+		"",
+	},
+
 	"policy-name": {
 		`The name of the policy. Example: "ops"`,
 		"",
@@ -2830,6 +5623,21 @@ or delete a policy.
 		"",
 	},
 
+	"audit-verify": {
+		"Verify the chain of the entries logged by the given audit backend",
+		`
+Verifies that each entry of the log of an audit backend with hash chaining
+enabled links to the previous one, detecting modified, inserted and removed
+entries, and that the current log file was not truncated.
+		`,
+	},
+
+	"audit_verify_file_path": {
+		`The log file to verify, which must be in the directory of the log of the
+backend. Defaults to the current log file.`,
+		"",
+	},
+
 	"audit-table": {
 		"List the currently enabled audit backends.",
 		`
@@ -2880,6 +5688,32 @@ Enable a new audit backend or disable an existing backend.
 		`,
 	},
 
+	"rotate-config": {
+		"Configures the automatic rotation of the backend encryption key.",
+		`
+		The backend encryption key is rotated automatically once it has
+		encrypted a number of values, and optionally once it reaches an age,
+		so that rotations don't depend on operators calling sys/rotate. The
+		number of encryptions is counted by the active node and persisted with
+		the keyring. Reading this endpoint returns the rotation policy, while
+		writing to it updates it.
+		`,
+	},
+
+	"rotate-config-enabled": {
+		"Whether the key is rotated automatically. Defaults to true.",
+	},
+
+	"rotate-config-max-operations": {
+		`The number of encryptions after which the key is rotated, between
+1000000 and 3865470566, the default.`,
+	},
+
+	"rotate-config-interval": {
+		`The age after which the key is rotated, at least 24 hours. Keys are
+not rotated on age if zero, the default.`,
+	},
+
 	"rotate": {
 		"Rotates the backend encryption key used to persist data.",
 		`
@@ -2889,6 +5723,122 @@ Enable a new audit backend or disable an existing backend.
 		`,
 	},
 
+	"sealwrap-rewrap": {
+		"Re-encrypts the values encrypted by the auto-unseal seal with its current key.",
+		`
+		Auto-unseal seals store the barrier key and the recovery key encrypted
+		by their key management service. Reading this endpoint returns how many
+		of these values were encrypted with a key other than the current one,
+		such as before a key rotation. Writing to it decrypts and re-encrypts
+		them with the current key, so that former keys can be retired.
+		`,
+	},
+
+	"storage-rewrap": {
+		"Re-encrypts storage entries with the active encryption key.",
+		`
+		After the encryption key has been rotated, existing entries remain
+		encrypted with the key they were written with. Writing to this endpoint
+		starts re-encrypting the entries under a storage prefix with the active
+		key in the background, so that no data depends on older keys. Reading
+		it returns the progress of the most recent rewrap, including how many
+		entries were found under each key term; deleting it cancels a running
+		rewrap.
+		`,
+	},
+
+	"storage-rewrap_prefix": {
+		`The storage prefix to rewrap, such as "logical/". Defaults to all of storage.`,
+	},
+
+	"storage-rewrap_rate": {
+		`The maximum number of entries to rewrap per second. Defaults to 0, which is unlimited.`,
+	},
+
+	"chaos": {
+		"Returns the faults currently injected by the chaos endpoints.",
+		`
+		The chaos endpoints are only available when the server is started
+		with "enable_chaos_endpoints" set. They allow failover drills to be
+		driven through the API and must not be enabled in production.
+		`,
+	},
+
+	"chaos/drop-ha-lock": {
+		"Makes the active node drop the HA lock.",
+		`
+		The active node releases its HA lock without first stepping down
+		gracefully, as if it had lost its connection to the HA backend, so
+		that a standby can take over.
+		`,
+	},
+
+	"chaos/storage-delay": {
+		"Delays storage operations on this node.",
+		`
+		Writing delays each storage operation by "delay" for the next
+		"duration". Deleting removes the delay.
+		`,
+	},
+
+	"storage/raft/configuration": {
+		"Returns the servers of the raft cluster.",
+		`
+		Returns the node ID and raft address of each server of the raft
+		storage cluster, whether it is the leader and whether it votes.
+		`,
+	},
+
+	"storage/raft/add-peer": {
+		"Adds a server to the raft cluster.",
+		`
+		Adds a voting server to the raft storage cluster. The server must not
+		have any raft state yet; it receives the storage of the cluster and
+		can then be unsealed with its keys. Nodes usually join through
+		sys/storage/raft/join, which calls this endpoint.
+		`,
+	},
+
+	"storage/raft/remove-peer": {
+		"Removes a server from the raft cluster.",
+		`
+		Removes a server from the raft storage cluster, such as a node that
+		was permanently lost.
+		`,
+	},
+
+	"chaos/pause-expiration": {
+		"Pauses lease expiration on this node.",
+		`
+		Writing stops expired leases from being revoked for "duration".
+		Leases that expire in the meantime are revoked once expiration
+		resumes. Deleting resumes expiration immediately.
+		`,
+	},
+
+	"emergency-seal-config": {
+		"Configures which identity entities may authorize an emergency seal.",
+		`
+		Sets the identity entity IDs that are allowed to authorize an
+		emergency seal, and how many distinct entities must do so before
+		every node in the cluster is sealed. Updating the configuration
+		cancels any in-progress emergency seal attempt.
+		`,
+	},
+
+	"emergency-seal": {
+		"Authorizes, inspects or cancels an emergency seal of the cluster.",
+		`
+		Each update records an authorization from the calling entity, which
+		must be listed in the emergency seal configuration. The first
+		authorization starts an attempt and returns its nonce, which later
+		authorizations must provide. When the required number of entities
+		have authorized, the active node tells all standbys over the cluster
+		connection to seal, then seals itself. Reading returns the progress
+		of the current attempt and deleting cancels it.
+		`,
+	},
+
 	"rekey_backup": {
 		"Allows fetching or deleting the backup of the rotated unseal keys.",
 		"",
@@ -2897,19 +5847,64 @@ Enable a new audit backend or disable an existing backend.
 	"capabilities": {
 		"Fetches the capabilities of the given token on the given path.",
 		`Returns the capabilities of the given token on the path.
-		The path will be searched for a path match in all the policies associated with the token.`,
+		The path will be searched for a path match in all the policies associated with the token.
+		The policies and the path rules granting each capability are returned as capability_grants.`,
 	},
 
 	"capabilities_self": {
 		"Fetches the capabilities of the given token on the given path.",
 		`Returns the capabilities of the client token on the path.
-		The path will be searched for a path match in all the policies associated with the client token.`,
+		The path will be searched for a path match in all the policies associated with the client token.
+		The policies and the path rules granting each capability are returned as capability_grants.`,
 	},
 
 	"capabilities_accessor": {
 		"Fetches the capabilities of the token associated with the given token, on the given path.",
 		`When there is no access to the token, token accessor can be used to fetch the token's capabilities
-		on a given path.`,
+		on a given path, along with the policies and the path rules granting each of them.`,
+	},
+
+	"leases-irrevocable": {
+		`List the leases which could not be revoked.`,
+		`
+Leases whose revocation still fails after the maximum number of attempts are
+marked irrevocable, along with the last revocation error. They are no longer
+retried, including after unsealing, until they are revoked explicitly or
+removed once an operator has cleaned up what they refer to.
+		`,
+	},
+
+	"control-group-id": {
+		`The ID of the request parked by a control group.`,
+		"",
+	},
+
+	"control-group-request": {
+		`Look up a request parked by a control group.`,
+		`
+This returns the path and operation of a request parked by a control group,
+the factors of the control group and the authorizations given so far.
+		`,
+	},
+
+	"control-group-authorize": {
+		`Authorize a request parked by a control group.`,
+		`
+This records the authorization of a request parked by a control group by the
+entity of the calling token, which must be a member of one of the identity
+groups of the control group. Once all the factors are satisfied, the requester
+can send the request again with the control group ID in the
+X-Vault-Control-Group header.
+		`,
+	},
+
+	"leases-irrevocable-remove": {
+		`Remove an irrevocable lease without revoking it.`,
+		`
+This deletes an irrevocable lease without contacting its backend. It should
+only be used once the secret it refers to has been cleaned up, or is known
+to be gone.
+		`,
 	},
 
 	"tidy_leases": {
@@ -2921,11 +5916,44 @@ necessary, and is only required if upgrade notes or support personnel suggest
 it.`,
 	},
 
+	"tidy_tokens": {
+		`This endpoint repairs the token store after certain error conditions.`,
+		`This endpoint revokes the tokens whose parent no longer exists, rebuilds
+the missing index entries of existing tokens, and deletes the index entries of
+tokens which no longer exist along with the leases of the tokens. A report of
+the inconsistencies found is returned. The token store can also be tidied in
+the background with the "token_tidy_interval" server option.`,
+	},
+
+	"tidy_tokens_dry_run": {
+		"If set, the inconsistencies found are reported but not repaired.",
+		"",
+	},
+
 	"wrap": {
 		"Response-wraps an arbitrary JSON object.",
 		`Round trips the given input data into a response-wrapped token.`,
 	},
 
+	"wrap-entries": {
+		"Response-wraps multiple named JSON objects in a single token.",
+		`Wraps each top-level key of the input data as a separate entry of one
+		response-wrapped token. Each entry can be unwrapped once by giving its name
+		to sys/wrapping/unwrap; the token is revoked once all of them have been
+		unwrapped or its TTL expires.`,
+	},
+
+	"unwrap-entry": {
+		`The name of the entry to unwrap from a token wrapping multiple entries.`,
+		"",
+	},
+
+	"unwrap-creation-path": {
+		`If set, the token is only unwrapped if it was created by a request to this
+path, which detects a wrapped response that was intercepted and replaced.`,
+		"",
+	},
+
 	"wrappubkey": {
 		"Returns pubkeys used in some wrapping formats.",
 		"Returns pubkeys used in some wrapping formats.",
@@ -2940,7 +5968,8 @@ it.`,
 
 	"wraplookup": {
 		"Looks up the properties of a response-wrapped token.",
-		`Returns the creation TTL and creation time of a response-wrapped token.`,
+		`Returns the creation TTL and creation time of a response-wrapped token, along
+		with the entries that have yet to be unwrapped if it wraps multiple entries.`,
 	},
 
 	"rewrap": {
@@ -2999,6 +6028,13 @@ executable defined in this command must exist in vault's
 plugin directory.`,
 		"",
 	},
+	"plugin-catalog_version": {
+		`The version of the plugin, of the form MAJOR.MINOR.PATCH.
+If set, the entry is registered as this version of the plugin,
+which mounts can be pinned to, rather than as its unversioned
+entry.`,
+		"",
+	},
 	"leases": {
 		`View or list lease metadata.`,
 		`
@@ -3008,14 +6044,53 @@ This path responds to the following HTTP methods.
         Retrieve the metadata for the provided lease id.
 
     LIST /<prefix>
-        Lists the leases for the named prefix.
+        Lists the leases for the named prefix. With the "entity_id" or
+        "expiring_within" filters, or "recursive" set, the IDs of all the
+        matching leases under the prefix are listed along with their
+        metadata in "key_info". For instance, "expiring_within=1h" lists
+        the leases expiring in the next hour.
+		`,
+	},
+
+	"leases-lookup-artifact": {
+		`Find the leases an artifact was issued with.`,
+		`
+Backends attach artifacts, such as the serial number of a certificate or a
+database username, to the leases of the secrets they issue. This path returns
+the IDs of the leases that a given artifact was issued with, so that they can
+be looked up or revoked.
 		`,
 	},
 
+	"leases-artifact-type": {
+		`The type of the artifact. Example: "serial_number"`,
+		"",
+	},
+
+	"leases-artifact-id": {
+		`The artifact to look up, such as a certificate serial number.`,
+		"",
+	},
+
 	"leases-list-prefix": {
 		`The path to list leases under. Example: "aws/creds/deploy"`,
 		"",
 	},
+
+	"leases-list-entity-id": {
+		`Only list the leases issued to the given entity.`,
+		"",
+	},
+
+	"leases-list-expiring-within": {
+		`Only list the leases expiring within the given duration. Example: "1h"`,
+		"",
+	},
+
+	"leases-list-recursive": {
+		`List the IDs of all the leases under the prefix rather than the next level of the prefix.`,
+		"",
+	},
 	"plugin-reload": {
 		"Reload mounts that use a particular backend plugin.",
 		`Reload mounts that use a particular backend plugin. Either the plugin name
@@ -3031,4 +6106,27 @@ This path responds to the following HTTP methods.
 		`The mount paths of the plugin backends to reload.`,
 		"",
 	},
+	"plugin-status": {
+		"Report the health of the external plugin processes.",
+		`Lists the external plugin processes run by the mounts, as probed by
+the plugin health monitor. Each entry reports the state of the process,
+which is "running", "restarting" or "failed" once the restarts have been
+given up on, along with the number of automatic restarts and the last
+error seen.`,
+	},
+	"internal-specs-openapi": {
+		"Generate an OpenAPI document of the API of the mounts.",
+		`Returns an OpenAPI 3 document describing the paths of the secret and
+auth mounts of the namespace and of the system backend, as generated
+from the definitions of the paths of their backends. It can be used to
+generate API clients. Mounts whose backends can't describe their paths,
+such as plugins which are not running, are left out.`,
+	},
+	"plugin-backend-reload-version": {
+		`The version of the plugin to upgrade the mounts to. The
+mounts are pinned to this version and reloaded; if any of them
+fails to reload, all of them are rolled back to their previous
+version.`,
+		"",
+	},
 }
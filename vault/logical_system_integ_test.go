@@ -1,6 +1,7 @@
 package vault_test
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"testing"
@@ -121,6 +122,206 @@ func TestSystemBackend_Plugin_MismatchType(t *testing.T) {
 	time.Sleep(1 * time.Second)
 }
 
+func TestSystemBackend_Plugin_netRPC(t *testing.T) {
+	cluster := testSystemBackendMock(t, 1, 1, logical.TypeLogical)
+	defer cluster.Cleanup()
+
+	core := cluster.Cores[0]
+
+	// Replace the plugin with one which only serves over net/rpc
+	vault.TestAddTestPlugin(t, core.Core, "mock-plugin", "TestBackend_PluginMainNetRPC")
+
+	// Reload the backend so that the host falls back to net/rpc
+	resp, err := core.Client.Logical().Write("sys/plugins/reload/backend", map[string]interface{}{
+		"plugin": "mock-plugin",
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp != nil {
+		t.Fatalf("bad: %v", resp)
+	}
+
+	req := logical.TestRequest(t, logical.ReadOperation, "mock-0/internal")
+	req.ClientToken = core.Client.Token()
+	resp2, err := core.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp2 == nil {
+		t.Fatalf("bad: response should not be nil")
+	}
+}
+
+func TestSystemBackend_Plugin_multiplexing(t *testing.T) {
+	cluster := testSystemBackendMock(t, 1, 2, logical.TypeLogical)
+	defer cluster.Cleanup()
+
+	core := cluster.Cores[0]
+
+	pids := func() []string {
+		var pids []string
+		for i := 0; i < 2; i++ {
+			req := logical.TestRequest(t, logical.ReadOperation, fmt.Sprintf("mock-%d/pid", i))
+			req.ClientToken = core.Client.Token()
+			resp, err := core.HandleRequest(req)
+			if err != nil {
+				t.Fatalf("err: %v", err)
+			}
+			if resp == nil {
+				t.Fatalf("bad: response should not be nil")
+			}
+			pids = append(pids, fmt.Sprint(resp.Data["pid"]))
+		}
+		return pids
+	}
+
+	// Each mount runs a process of its own
+	if p := pids(); p[0] == p[1] {
+		t.Fatalf("bad: mounts served by the same process: %v", p)
+	}
+
+	// Replace the plugin with one which supports multiplexing
+	vault.TestAddTestPlugin(t, core.Core, "mock-plugin", "TestBackend_PluginMainMultiplexed")
+
+	resp, err := core.Client.Logical().Write("sys/plugins/reload/backend", map[string]interface{}{
+		"plugin": "mock-plugin",
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp != nil {
+		t.Fatalf("bad: %v", resp)
+	}
+
+	// Both mounts are now served by the same process
+	if p := pids(); p[0] != p[1] {
+		t.Fatalf("bad: mounts served by different processes: %v", p)
+	}
+
+	// The data of the mounts is still kept apart
+	req := logical.TestRequest(t, logical.UpdateOperation, "mock-0/internal")
+	req.ClientToken = core.Client.Token()
+	req.Data["value"] = "foo"
+	if _, err := core.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	req = logical.TestRequest(t, logical.ReadOperation, "mock-1/internal")
+	req.ClientToken = core.Client.Token()
+	resp2, err := core.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp2.Data["value"] != "bar" {
+		t.Fatalf("bad: %#v", resp2)
+	}
+}
+
+func TestSystemBackend_Plugin_upgrade(t *testing.T) {
+	cluster := testSystemBackendMock(t, 1, 1, logical.TypeLogical)
+	defer cluster.Cleanup()
+
+	core := cluster.Cores[0]
+	client := core.Client
+
+	vault.TestAddTestPlugin(t, core.Core, "mock-cred-plugin", "TestBackend_PluginMainCredentials")
+	_, err := client.Logical().Write("sys/auth/mock-cred", map[string]interface{}{
+		"type":        "plugin",
+		"plugin_name": "mock-cred-plugin",
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	vault.TestAddTestPluginVersion(t, core.Core, "mock-plugin", "1.0.0", "TestBackend_PluginMainLogical")
+
+	pluginVersion := func() string {
+		resp, err := client.Logical().Read("sys/mounts")
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		config := resp.Data["mock-0/"].(map[string]interface{})["config"].(map[string]interface{})
+		version, _ := config["plugin_version"].(string)
+		return version
+	}
+
+	testRead := func() {
+		req := logical.TestRequest(t, logical.ReadOperation, "mock-0/internal")
+		req.ClientToken = client.Token()
+		resp, err := core.HandleRequest(req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if resp == nil {
+			t.Fatalf("bad: response should not be nil")
+		}
+	}
+
+	// The auth mount has no version 1.0.0 of its plugin, so the upgrade of
+	// the secret mount is rolled back
+	_, err = client.Logical().Write("sys/plugins/reload/backend", map[string]interface{}{
+		"mounts":  "mock-0/,auth/mock-cred/",
+		"version": "1.0.0",
+	})
+	if err == nil {
+		t.Fatal("expected an error upgrading a mount to an unknown version")
+	}
+	if v := pluginVersion(); v != "" {
+		t.Fatalf("bad: upgrade was not rolled back, mount pinned to %q", v)
+	}
+	testRead()
+
+	// Upgrade the mounts of the plugin
+	_, err = client.Logical().Write("sys/plugins/reload/backend", map[string]interface{}{
+		"plugin":  "mock-plugin",
+		"version": "1.0.0",
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if v := pluginVersion(); v != "1.0.0" {
+		t.Fatalf("bad: mount pinned to %q", v)
+	}
+	testRead()
+
+	// Version 2.0.0 is a credential backend, which cannot serve the mount
+	vault.TestAddTestPluginVersion(t, core.Core, "mock-plugin", "2.0.0", "TestBackend_PluginMainCredentials")
+	_, err = client.Logical().Write("sys/plugins/reload/backend", map[string]interface{}{
+		"plugin":  "mock-plugin",
+		"version": "2.0.0",
+	})
+	if err == nil {
+		t.Fatal("expected an error upgrading a mount to a credential backend")
+	}
+	if v := pluginVersion(); v != "1.0.0" {
+		t.Fatalf("bad: mount pinned to %q", v)
+	}
+	testRead()
+
+	// A mount listed twice is upgraded once, and rolled back to its
+	// previous version
+	vault.TestAddTestPluginVersion(t, core.Core, "mock-plugin", "3.0.0", "TestBackend_PluginMainLogical")
+	_, err = client.Logical().Write("sys/plugins/reload/backend", map[string]interface{}{
+		"mounts":  "mock-0/,mock-0/,auth/mock-cred/",
+		"version": "3.0.0",
+	})
+	if err == nil {
+		t.Fatal("expected an error upgrading a mount to an unknown version")
+	}
+	if v := pluginVersion(); v != "1.0.0" {
+		t.Fatalf("bad: mount pinned to %q", v)
+	}
+	testRead()
+
+	resp, err := client.Logical().Read("sys/plugins/catalog/mock-plugin")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if versions := fmt.Sprint(resp.Data["versions"]); versions != "[1.0.0 2.0.0 3.0.0]" {
+		t.Fatalf("bad: %s", versions)
+	}
+}
+
 func TestSystemBackend_Plugin_CatalogRemoved(t *testing.T) {
 	t.Run("secret", func(t *testing.T) {
 		testPlugin_CatalogRemoved(t, logical.TypeLogical, false)
@@ -245,6 +446,89 @@ func TestSystemBackend_Plugin_autoReload(t *testing.T) {
 	}
 }
 
+func TestSystemBackend_Plugin_autoRestart(t *testing.T) {
+	cluster := testSystemBackendMockConfig(t, &vault.CoreConfig{
+		PluginRestart: &vault.PluginRestartConfig{
+			CheckInterval:  100 * time.Millisecond,
+			InitialBackoff: 10 * time.Millisecond,
+		},
+	}, 1, 1, logical.TypeLogical)
+	defer cluster.Cleanup()
+
+	core := cluster.Cores[0]
+	client := core.Client
+
+	pid := func() int {
+		resp, err := client.Logical().Read("mock-0/pid")
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if resp == nil {
+			t.Fatalf("bad: response should not be nil")
+		}
+		pid, err := resp.Data["pid"].(json.Number).Int64()
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		return int(pid)
+	}
+
+	status := func() map[string]interface{} {
+		resp, err := client.Logical().Read("sys/plugins/status")
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		plugins := resp.Data["plugins"].([]interface{})
+		if len(plugins) != 1 {
+			t.Fatalf("bad: %#v", resp.Data)
+		}
+		return plugins[0].(map[string]interface{})
+	}
+
+	// The plugin process is monitored once the backend is loaded
+	oldPid := pid()
+	if s := status(); s["path"] != "mock-0/" || s["name"] != "mock-plugin" || s["state"] != "running" {
+		t.Fatalf("bad: %#v", s)
+	}
+
+	// Crash the plugin process, the monitor restarts it
+	proc, err := os.FindProcess(oldPid)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := proc.Kill(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	var s map[string]interface{}
+	for i := 0; i < 100; i++ {
+		s = status()
+		if s["restarts"] == json.Number("1") && s["state"] == "running" {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if s["restarts"] != json.Number("1") || s["state"] != "running" || s["last_error"] == "" {
+		t.Fatalf("bad: %#v", s)
+	}
+
+	if newPid := pid(); newPid == oldPid {
+		t.Fatalf("bad: plugin process %d was not restarted", newPid)
+	}
+
+	// Unmounting the backend stops the monitoring of its process
+	if _, err := client.Logical().Delete("sys/mounts/mock-0"); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	resp, err := client.Logical().Read("sys/plugins/status")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if plugins := resp.Data["plugins"].([]interface{}); len(plugins) != 0 {
+		t.Fatalf("bad: %#v", plugins)
+	}
+}
+
 func TestSystemBackend_Plugin_SealUnseal(t *testing.T) {
 	cluster := testSystemBackendMock(t, 1, 1, logical.TypeLogical)
 	defer cluster.Cleanup()
@@ -334,13 +618,17 @@ func testSystemBackend_PluginReload(t *testing.T, reqData map[string]interface{}
 // testSystemBackendMock returns a systemBackend with the desired number
 // of mounted mock plugin backends
 func testSystemBackendMock(t *testing.T, numCores, numMounts int, backendType logical.BackendType) *vault.TestCluster {
-	coreConfig := &vault.CoreConfig{
-		LogicalBackends: map[string]logical.Factory{
-			"plugin": plugin.Factory,
-		},
-		CredentialBackends: map[string]logical.Factory{
-			"plugin": plugin.Factory,
-		},
+	return testSystemBackendMockConfig(t, &vault.CoreConfig{}, numCores, numMounts, backendType)
+}
+
+// testSystemBackendMockConfig is like testSystemBackendMock, with the given
+// core configuration
+func testSystemBackendMockConfig(t *testing.T, coreConfig *vault.CoreConfig, numCores, numMounts int, backendType logical.BackendType) *vault.TestCluster {
+	coreConfig.LogicalBackends = map[string]logical.Factory{
+		"plugin": plugin.Factory,
+	}
+	coreConfig.CredentialBackends = map[string]logical.Factory{
+		"plugin": plugin.Factory,
 	}
 
 	cluster := vault.NewTestCluster(t, coreConfig, &vault.TestClusterOptions{
@@ -465,3 +753,65 @@ func TestBackend_PluginMainCredentials(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestBackend_PluginMainNetRPC(t *testing.T) {
+	args := []string{}
+	if os.Getenv(pluginutil.PluginUnwrapTokenEnv) == "" && os.Getenv(pluginutil.PluginMetadaModeEnv) != "true" {
+		return
+	}
+
+	// Behave like a plugin built before the gRPC protocol, which ignores
+	// the protocol versions advertised by Vault
+	os.Unsetenv(lplugin.PluginProtocolVersionsEnv)
+
+	caPEM := os.Getenv(pluginutil.PluginCACertPEMEnv)
+	if caPEM == "" {
+		t.Fatal("CA cert not passed in")
+	}
+	args = append(args, fmt.Sprintf("--ca-cert=%s", caPEM))
+
+	apiClientMeta := &pluginutil.APIClientMeta{}
+	flags := apiClientMeta.FlagSet()
+	flags.Parse(args)
+	tlsConfig := apiClientMeta.GetTLSConfig()
+	tlsProviderFunc := pluginutil.VaultPluginTLSProvider(tlsConfig)
+
+	factoryFunc := mock.FactoryType(logical.TypeLogical)
+
+	err := lplugin.Serve(&lplugin.ServeOpts{
+		BackendFactoryFunc: factoryFunc,
+		TLSProviderFunc:    tlsProviderFunc,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBackend_PluginMainMultiplexed(t *testing.T) {
+	args := []string{}
+	if os.Getenv(pluginutil.PluginUnwrapTokenEnv) == "" && os.Getenv(pluginutil.PluginMetadaModeEnv) != "true" {
+		return
+	}
+
+	caPEM := os.Getenv(pluginutil.PluginCACertPEMEnv)
+	if caPEM == "" {
+		t.Fatal("CA cert not passed in")
+	}
+	args = append(args, fmt.Sprintf("--ca-cert=%s", caPEM))
+
+	apiClientMeta := &pluginutil.APIClientMeta{}
+	flags := apiClientMeta.FlagSet()
+	flags.Parse(args)
+	tlsConfig := apiClientMeta.GetTLSConfig()
+	tlsProviderFunc := pluginutil.VaultPluginTLSProvider(tlsConfig)
+
+	factoryFunc := mock.FactoryType(logical.TypeLogical)
+
+	err := lplugin.ServeMultiplex(&lplugin.ServeOpts{
+		BackendFactoryFunc: factoryFunc,
+		TLSProviderFunc:    tlsProviderFunc,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
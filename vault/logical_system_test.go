@@ -2,22 +2,28 @@ package vault
 
 import (
 	"crypto/sha256"
+	"encoding/base64"
 	"encoding/hex"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"reflect"
+	"runtime"
+	"sort"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/armon/go-metrics"
 	"github.com/fatih/structs"
 	"github.com/hashicorp/vault/audit"
 	"github.com/hashicorp/vault/helper/builtinplugins"
 	"github.com/hashicorp/vault/helper/pluginutil"
+	"github.com/hashicorp/vault/helper/reload"
 	"github.com/hashicorp/vault/helper/salt"
 	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
 	"github.com/mitchellh/mapstructure"
 )
 
@@ -27,12 +33,25 @@ func TestSystemBackend_RootPaths(t *testing.T) {
 		"remount",
 		"audit",
 		"audit/*",
+		"audit-verify/*",
 		"raw",
 		"raw/*",
+		"chaos",
+		"chaos/*",
+		"storage/raft/*",
 		"replication/primary/secondary-token",
 		"replication/reindex",
 		"rotate",
+		"rotate/config",
+		"rewrap",
+		"sealwrap/rewrap",
+		"emergency-seal/config",
 		"config/cors",
+		"config/mount-import",
+		"config/reload",
+		"config/state",
+		"metrics",
+		"host-info",
 		"config/auditing/*",
 		"plugins/catalog/*",
 		"revoke-prefix/*",
@@ -40,6 +59,11 @@ func TestSystemBackend_RootPaths(t *testing.T) {
 		"leases/revoke-prefix/*",
 		"leases/revoke-force/*",
 		"leases/lookup/*",
+		"leases/lookup-artifact",
+		"leases/irrevocable",
+		"leases/irrevocable/*",
+		"in-flight-req",
+		"in-flight-req/*",
 	}
 
 	b := testSystemBackend(t)
@@ -125,7 +149,8 @@ func TestSystemBackend_mounts(t *testing.T) {
 				"max_lease_ttl":     resp.Data["secret/"].(map[string]interface{})["config"].(map[string]interface{})["max_lease_ttl"].(int64),
 				"force_no_cache":    false,
 			},
-			"local": false,
+			"local":     false,
+			"seal_wrap": false,
 		},
 		"sys/": map[string]interface{}{
 			"type":        "system",
@@ -136,7 +161,8 @@ func TestSystemBackend_mounts(t *testing.T) {
 				"max_lease_ttl":     resp.Data["sys/"].(map[string]interface{})["config"].(map[string]interface{})["max_lease_ttl"].(int64),
 				"force_no_cache":    false,
 			},
-			"local": false,
+			"local":     false,
+			"seal_wrap": false,
 		},
 		"cubbyhole/": map[string]interface{}{
 			"description": "per-token private secret storage",
@@ -147,7 +173,8 @@ func TestSystemBackend_mounts(t *testing.T) {
 				"max_lease_ttl":     resp.Data["cubbyhole/"].(map[string]interface{})["config"].(map[string]interface{})["max_lease_ttl"].(int64),
 				"force_no_cache":    false,
 			},
-			"local": true,
+			"local":     true,
+			"seal_wrap": false,
 		},
 		"identity/": map[string]interface{}{
 			"description": "identity store",
@@ -158,7 +185,8 @@ func TestSystemBackend_mounts(t *testing.T) {
 				"max_lease_ttl":     resp.Data["identity/"].(map[string]interface{})["config"].(map[string]interface{})["max_lease_ttl"].(int64),
 				"force_no_cache":    false,
 			},
-			"local": false,
+			"local":     false,
+			"seal_wrap": false,
 		},
 	}
 	if !reflect.DeepEqual(resp.Data, exp) {
@@ -293,6 +321,12 @@ func testCapabilities(t *testing.T, endpoint string) {
 	if !reflect.DeepEqual(actual, expected) {
 		t.Fatalf("bad: got\n%#v\nexpected\n%#v\n", actual, expected)
 	}
+
+	grants := resp.Data["capability_grants"].(map[string]interface{})
+	expectedGrant := []map[string]interface{}{{"policy": "test", "path": "foo/bar*"}}
+	if len(grants) != 3 || !reflect.DeepEqual(grants["sudo"], expectedGrant) {
+		t.Fatalf("bad: %#v", grants)
+	}
 }
 
 func TestSystemBackend_CapabilitiesAccessor(t *testing.T) {
@@ -590,6 +624,93 @@ func TestSystemBackend_leases_list(t *testing.T) {
 	}
 }
 
+func TestSystemBackend_leases_list_filters(t *testing.T) {
+	core, b, root := testCoreSystemBackend(t)
+
+	leases := map[string]string{}
+	for path, ttl := range map[string]string{"secret/foo": "1h", "secret/bar/baz": "3h"} {
+		req := logical.TestRequest(t, logical.UpdateOperation, path)
+		req.Data["foo"] = "bar"
+		req.Data["ttl"] = ttl
+		req.ClientToken = root
+		if _, err := core.HandleRequest(req); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		req = logical.TestRequest(t, logical.ReadOperation, path)
+		req.ClientToken = root
+		resp, err := core.HandleRequest(req)
+		if err != nil || resp == nil || resp.Secret == nil {
+			t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+		}
+		leases[path] = resp.Secret.LeaseID
+	}
+
+	// Register a lease issued to an entity
+	leaseID, err := core.expiration.Register(&logical.Request{
+		Operation:   logical.ReadOperation,
+		Path:        "secret/qux",
+		ClientToken: root,
+		EntityID:    "entity1",
+	}, &logical.Response{
+		Secret: &logical.Secret{
+			LeaseOptions: logical.LeaseOptions{
+				TTL: 2 * time.Hour,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	leases["secret/qux"] = leaseID
+
+	list := func(path string, data map[string]interface{}) ([]string, map[string]interface{}) {
+		req := logical.TestRequest(t, logical.ListOperation, path)
+		req.Data = data
+		resp, err := b.HandleRequest(req)
+		if err != nil || resp == nil {
+			t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+		}
+		keys, _ := resp.Data["keys"].([]string)
+		keyInfo, _ := resp.Data["key_info"].(map[string]interface{})
+		return keys, keyInfo
+	}
+
+	// Recursive listings return the IDs of all the leases under the prefix
+	keys, keyInfo := list("leases/lookup/secret", map[string]interface{}{"recursive": true})
+	if len(keys) != 3 || len(keyInfo) != 3 {
+		t.Fatalf("bad: keys: %#v, key_info: %#v", keys, keyInfo)
+	}
+	keys, _ = list("leases/lookup/secret/bar", map[string]interface{}{"recursive": true})
+	if len(keys) != 1 || keys[0] != leases["secret/bar/baz"] {
+		t.Fatalf("bad: keys: %#v", keys)
+	}
+
+	keys, keyInfo = list("leases/lookup/", map[string]interface{}{"expiring_within": "150m"})
+	sort.Strings(keys)
+	expected := []string{leases["secret/foo"], leases["secret/qux"]}
+	sort.Strings(expected)
+	if !reflect.DeepEqual(keys, expected) {
+		t.Fatalf("bad: expected: %#v, got: %#v", expected, keys)
+	}
+	info := keyInfo[leases["secret/foo"]].(map[string]interface{})
+	if ttl := info["ttl"].(int64); ttl <= 0 || ttl > 3600 {
+		t.Fatalf("bad: %#v", info)
+	}
+
+	keys, keyInfo = list("leases/lookup/secret/", map[string]interface{}{"entity_id": "entity1", "expiring_within": "150m"})
+	if len(keys) != 1 || keys[0] != leases["secret/qux"] {
+		t.Fatalf("bad: keys: %#v", keys)
+	}
+	if info := keyInfo[keys[0]].(map[string]interface{}); info["entity_id"] != "entity1" {
+		t.Fatalf("bad: %#v", info)
+	}
+
+	if keys, _ = list("leases/lookup/secret/", map[string]interface{}{"expiring_within": "30m"}); len(keys) != 0 {
+		t.Fatalf("bad: keys: %#v", keys)
+	}
+}
+
 func TestSystemBackend_renew(t *testing.T) {
 	core, b, root := testCoreSystemBackend(t)
 
@@ -1133,10 +1254,13 @@ func TestSystemBackend_authTable(t *testing.T) {
 			"description": "token based credentials",
 			"accessor":    resp.Data["token/"].(map[string]interface{})["accessor"],
 			"config": map[string]interface{}{
-				"default_lease_ttl": int64(0),
-				"max_lease_ttl":     int64(0),
+				"default_lease_ttl":         int64(0),
+				"max_lease_ttl":             int64(0),
+				"auto_create_group_aliases": false,
+				"token_type":                "service",
 			},
-			"local": false,
+			"local":     false,
+			"seal_wrap": false,
 		},
 	}
 	if !reflect.DeepEqual(resp.Data, exp) {
@@ -1197,6 +1321,44 @@ func TestSystemBackend_disableAuth(t *testing.T) {
 	}
 }
 
+func TestSystemBackend_tuneAuth(t *testing.T) {
+	c, b, _ := testCoreSystemBackend(t)
+	c.credentialBackends["noop"] = func(*logical.BackendConfig) (logical.Backend, error) {
+		return &NoopBackend{}, nil
+	}
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "auth/foo")
+	req.Data["type"] = "noop"
+	resp, err := b.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "auth/foo/tune")
+	req.Data["auto_create_group_aliases"] = true
+	resp, err = b.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp != nil && resp.IsError() {
+		t.Fatalf("bad: %v", resp)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "auth/foo/tune")
+	resp, err = b.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Data["auto_create_group_aliases"] != true {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	me := c.router.MatchingMountEntry("auth/foo/")
+	if me == nil || !me.Config.AutoCreateGroupAliases {
+		t.Fatalf("bad: mount entry: %#v", me)
+	}
+}
+
 func TestSystemBackend_policyList(t *testing.T) {
 	b := testSystemBackend(t)
 	req := logical.TestRequest(t, logical.ReadOperation, "policy")
@@ -1214,6 +1376,86 @@ func TestSystemBackend_policyList(t *testing.T) {
 	}
 }
 
+func TestSystemBackend_passwordPolicyCRUD(t *testing.T) {
+	c, b, _ := testCoreSystemBackend(t)
+
+	// Invalid policies are rejected
+	req := logical.TestRequest(t, logical.UpdateOperation, "policies/password/foo")
+	req.Data["length"] = 4
+	req.Data["min_digits"] = 5
+	resp, err := b.HandleRequest(req)
+	if err != logical.ErrInvalidRequest || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error; resp: %#v, err: %v", resp, err)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "policies/password/foo")
+	req.Data["length"] = 16
+	req.Data["min_digits"] = 4
+	req.Data["min_symbols"] = 2
+	req.Data["symbols"] = "!?"
+	req.Data["banned_passwords"] = "hunter2"
+	req.Data["history"] = 5
+	resp, err = b.HandleRequest(req)
+	if err != nil || resp != nil {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "policies/password/foo")
+	resp, err = b.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	exp := map[string]interface{}{
+		"name":             "foo",
+		"length":           16,
+		"min_lowercase":    0,
+		"min_uppercase":    0,
+		"min_digits":       4,
+		"min_symbols":      2,
+		"symbols":          "!?",
+		"banned_passwords": []string{"hunter2"},
+		"history":          5,
+	}
+	if !reflect.DeepEqual(resp.Data, exp) {
+		t.Fatalf("got: %#v expect: %#v", resp.Data, exp)
+	}
+
+	// Generated passwords satisfy the policy
+	policy, err := c.PasswordPolicy("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = logical.TestRequest(t, logical.ReadOperation, "policies/password/foo/generate")
+	resp, err = b.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	password := resp.Data["password"].(string)
+	if err := policy.Check(password); err != nil {
+		t.Fatalf("bad: password %q: %v", password, err)
+	}
+
+	req = logical.TestRequest(t, logical.ListOperation, "policies/password/")
+	resp, err = b.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(resp.Data["keys"], []string{"foo"}) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	req = logical.TestRequest(t, logical.DeleteOperation, "policies/password/foo")
+	if _, err := b.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "policies/password/foo/generate")
+	resp, err = b.HandleRequest(req)
+	if err != logical.ErrInvalidRequest || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error; resp: %#v, err: %v", resp, err)
+	}
+}
+
 func TestSystemBackend_policyCRUD(t *testing.T) {
 	b := testSystemBackend(t)
 
@@ -1567,6 +1809,7 @@ func TestSystemBackend_keyStatus(t *testing.T) {
 		"term": 1,
 	}
 	delete(resp.Data, "install_time")
+	delete(resp.Data, "encryptions")
 	if !reflect.DeepEqual(resp.Data, exp) {
 		t.Fatalf("got: %#v expect: %#v", resp.Data, exp)
 	}
@@ -1594,11 +1837,399 @@ func TestSystemBackend_rotate(t *testing.T) {
 		"term": 2,
 	}
 	delete(resp.Data, "install_time")
+	delete(resp.Data, "encryptions")
 	if !reflect.DeepEqual(resp.Data, exp) {
 		t.Fatalf("got: %#v expect: %#v", resp.Data, exp)
 	}
 }
 
+func TestSystemBackend_rewrap(t *testing.T) {
+	c, _, _ := TestCoreUnsealed(t)
+	b := testSystemBackendInternal(t, c)
+
+	req := logical.TestRequest(t, logical.ReadOperation, "rewrap")
+	resp, err := b.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp != nil {
+		t.Fatalf("expected no status before a rewrap, got %#v", resp)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "rotate")
+	if _, err := b.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// A corrupted entry is counted without stopping the rewrap
+	if err := c.barrier.Put(&Entry{Key: "core/corrupted", Value: []byte("test")}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	pe, err := c.physical.Get("core/corrupted")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	pe.Value[len(pe.Value)-1] ^= 0xff
+	if err := c.physical.Put(pe); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "rewrap")
+	req.Data["prefix"] = "core/"
+	resp, err = b.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Data["prefix"] != "core/" || resp.Data["active_term"] != 2 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	for i := 0; ; i++ {
+		if i > 100 {
+			t.Fatal("rewrap did not complete")
+		}
+		req = logical.TestRequest(t, logical.ReadOperation, "rewrap")
+		resp, err = b.HandleRequest(req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if resp.Data["status"] != storageRewrapStatusRunning {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if resp.Data["status"] != storageRewrapStatusComplete || resp.Data["rewrapped"].(int) == 0 || resp.Data["failed"].(int) != 1 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if _, ok := resp.Data["terms"].(map[string]interface{})["1"]; !ok {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Nothing under the prefix is left under the old term
+	keys, err := logical.CollectKeys(c.barrier)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for _, key := range keys {
+		if !strings.HasPrefix(key, "core/") || key == "core/corrupted" {
+			continue
+		}
+		term, rewrapped, err := c.barrier.Rewrap(key)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if rewrapped || (term != 0 && term != 2) {
+			t.Fatalf("%s was not rewrapped: %d", key, term)
+		}
+	}
+}
+
+func TestSystemBackend_mountConfigExportImport(t *testing.T) {
+	setup := func(description string, entries map[string]string) (*Core, logical.Backend, *NoopBackend) {
+		c, _, root := TestCoreUnsealed(t)
+		noop := &NoopBackend{
+			Exportable: []string{"role/"},
+		}
+		c.logicalBackends["noop"] = func(*logical.BackendConfig) (logical.Backend, error) {
+			return noop, nil
+		}
+		req := logical.TestRequest(t, logical.UpdateOperation, "sys/mounts/app")
+		req.ClientToken = root
+		req.Data["type"] = "noop"
+		req.Data["description"] = description
+		if _, err := c.HandleRequest(req); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		view := c.router.MatchingStorageView("app/")
+		for key, value := range entries {
+			if err := view.Put(&logical.StorageEntry{Key: key, Value: []byte(value)}); err != nil {
+				t.Fatalf("err: %v", err)
+			}
+		}
+		return c, testSystemBackendInternal(t, c), noop
+	}
+
+	_, stagingSys, _ := setup("roles", map[string]string{
+		"role/web":       "v2",
+		"role/nested/db": "v1",
+		"config/creds":   "staging secret",
+	})
+	prod, prodSys, noop := setup("", map[string]string{
+		"role/web":     "v1",
+		"role/old":     "v1",
+		"config/creds": "prod secret",
+	})
+
+	req := logical.TestRequest(t, logical.ReadOperation, "mounts/app/export-config")
+	resp, err := stagingSys.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	exported := resp.Data
+	raw, _ := base64.StdEncoding.DecodeString(exported["document"].(string))
+	if strings.Contains(string(raw), "config/creds") {
+		t.Fatalf("non-exportable entry was exported: %s", raw)
+	}
+
+	importReq := func(data map[string]interface{}) (*logical.Response, error) {
+		req := logical.TestRequest(t, logical.UpdateOperation, "mounts/app/import-config")
+		for k, v := range exported {
+			req.Data[k] = v
+		}
+		for k, v := range data {
+			req.Data[k] = v
+		}
+		return prodSys.HandleRequest(req)
+	}
+
+	// Documents are only accepted from trusted clusters, whatever key the
+	// request carries
+	resp, err = importReq(map[string]interface{}{
+		"public_key": exported["public_key"],
+	})
+	if err == nil || !resp.IsError() {
+		t.Fatalf("expected a signature error, got %#v", resp)
+	}
+	req = logical.TestRequest(t, logical.UpdateOperation, "config/mount-import")
+	req.Data["trusted_keys"] = "not-a-key"
+	resp, err = prodSys.HandleRequest(req)
+	if err == nil || !resp.IsError() {
+		t.Fatalf("expected an invalid key error, got %#v", resp)
+	}
+	req.Data["trusted_keys"] = exported["public_key"]
+	if _, err := prodSys.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	req = logical.TestRequest(t, logical.ReadOperation, "config/mount-import")
+	resp, err = prodSys.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(resp.Data["trusted_keys"], []string{exported["public_key"].(string)}) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// The diff is returned without changing anything
+	resp, err = importReq(nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(resp.Data["added"], []string{"role/nested/db"}) ||
+		!reflect.DeepEqual(resp.Data["changed"], []string{"role/web"}) ||
+		!reflect.DeepEqual(resp.Data["removed"], []string{"role/old"}) ||
+		resp.Data["applied"] != false {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if _, ok := resp.Data["tune"].(map[string]interface{})["description"]; !ok {
+		t.Fatalf("bad: %#v", resp.Data["tune"])
+	}
+	view := prod.router.MatchingStorageView("app/")
+	if out, _ := view.Get("role/web"); string(out.Value) != "v1" {
+		t.Fatalf("dry run changed storage: %q", out.Value)
+	}
+
+	// Tampered documents are rejected
+	resp, err = importReq(map[string]interface{}{
+		"document": base64.StdEncoding.EncodeToString([]byte(strings.Replace(string(raw), `"roles"`, `"other"`, 1))),
+	})
+	if err == nil || !resp.IsError() {
+		t.Fatalf("expected a signature error, got %#v", resp)
+	}
+
+	resp, err = importReq(map[string]interface{}{
+		"apply": true,
+		"prune": true,
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Data["applied"] != true {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	expected := map[string]string{
+		"role/web":       "v2",
+		"role/nested/db": "v1",
+		"config/creds":   "prod secret",
+	}
+	for key, value := range expected {
+		out, err := view.Get(key)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if out == nil || string(out.Value) != value {
+			t.Fatalf("%s: bad: %#v", key, out)
+		}
+	}
+	if out, _ := view.Get("role/old"); out != nil {
+		t.Fatalf("expected role/old to be pruned")
+	}
+	sort.Strings(noop.Invalidations)
+	if !reflect.DeepEqual(noop.Invalidations, []string{"role/nested/db", "role/old", "role/web"}) {
+		t.Fatalf("bad: %#v", noop.Invalidations)
+	}
+	if desc := prod.router.MatchingMountEntry("app/").Description; desc != "roles" {
+		t.Fatalf("bad: %q", desc)
+	}
+
+	// Importing again makes no changes
+	resp, err = importReq(nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(resp.Data["added"].([]string))+len(resp.Data["changed"].([]string))+len(resp.Data["removed"].([]string)) != 0 ||
+		len(resp.Data["tune"].(map[string]interface{})) != 0 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+}
+
+func TestSystemBackend_configReload(t *testing.T) {
+	c, _, _ := TestCoreUnsealed(t)
+	b := testSystemBackendInternal(t, c)
+
+	var reloads int
+	c.reloadFuncsLock.Lock()
+	c.reloadFuncs["listener|tcp"] = []reload.ReloadFunc{
+		func(map[string]interface{}) error {
+			reloads++
+			return nil
+		},
+	}
+	c.reloadFuncsLock.Unlock()
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "config/reload")
+	if _, err := b.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if reloads != 1 {
+		t.Fatalf("bad: %d", reloads)
+	}
+
+	// The server reloads its configuration through the hook
+	var hookCalls int
+	c.reloadHook = func() error {
+		hookCalls++
+		return fmt.Errorf("bad config")
+	}
+	resp, err := b.HandleRequest(req)
+	if err != logical.ErrInvalidRequest || !resp.IsError() {
+		t.Fatalf("bad: %#v, %v", resp, err)
+	}
+	if hookCalls != 1 || reloads != 1 {
+		t.Fatalf("bad: %d, %d", hookCalls, reloads)
+	}
+}
+
+func TestSystemBackend_configState(t *testing.T) {
+	c, _, _ := TestCoreUnsealed(t)
+	b := testSystemBackendInternal(t, c)
+
+	req := logical.TestRequest(t, logical.ReadOperation, "config/state")
+	resp, err := b.HandleRequest(req)
+	if err != logical.ErrUnsupportedPath || !resp.IsError() {
+		t.Fatalf("bad: %#v, %v", resp, err)
+	}
+
+	// The server returns the configuration in effect through the hook
+	c.configStateHook = func() map[string]interface{} {
+		return map[string]interface{}{
+			"log_level": "debug",
+		}
+	}
+	resp, err = b.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Data["log_level"] != "debug" {
+		t.Fatalf("bad: %#v", resp)
+	}
+}
+
+func TestSystemBackend_metrics(t *testing.T) {
+	c, _, _ := TestCoreUnsealed(t)
+	b := testSystemBackendInternal(t, c)
+
+	req := logical.TestRequest(t, logical.ReadOperation, "metrics")
+	resp, err := b.HandleRequest(req)
+	if err != logical.ErrUnsupportedPath || !resp.IsError() {
+		t.Fatalf("bad: %#v, %v", resp, err)
+	}
+
+	c.metricsSink = metrics.NewInmemSink(10*time.Second, time.Minute)
+	c.metricsSink.IncrCounter([]string{"foo"}, 1)
+	resp, err = b.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	counters, ok := resp.Data["Counters"].([]interface{})
+	if !ok || len(counters) != 1 || counters[0].(map[string]interface{})["Name"] != "foo" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+}
+
+func TestSystemBackend_hostInfo(t *testing.T) {
+	b := testSystemBackend(t)
+
+	req := logical.TestRequest(t, logical.ReadOperation, "host-info")
+	resp, err := b.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Data["os"] != runtime.GOOS || resp.Data["pid"] != os.Getpid() {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+}
+
+func TestSystemBackend_OpenAPI(t *testing.T) {
+	b := testSystemBackend(t)
+
+	req := logical.TestRequest(t, logical.ReadOperation, "internal/specs/openapi")
+	resp, err := b.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	doc, err := framework.DecodeOASDocument(resp.Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Version != framework.OASVersion {
+		t.Fatalf("bad: %#v", doc)
+	}
+
+	// The paths of the system backend, the secret and the auth mounts are
+	// documented under the paths they are mounted at
+	cases := map[string]string{
+		"/sys/mounts/{path}": "system",
+		"/secret/{path}":     "secrets",
+		"/auth/token/create": "auth",
+		"/auth/token/roles/": "auth",
+		"/sys/policy/{name}": "system",
+		"/cubbyhole/{path}":  "secrets",
+	}
+	for path, tag := range cases {
+		item, ok := doc.Paths[path]
+		if !ok {
+			t.Fatalf("missing path %q", path)
+		}
+		op := item.Get
+		if op == nil {
+			op = item.Post
+		}
+		if op == nil || !reflect.DeepEqual(op.Tags, []string{tag}) {
+			t.Fatalf("bad: %s: %#v", path, item)
+		}
+	}
+
+	if item := doc.Paths["/sys/raw/{path}"]; item != nil {
+		t.Fatalf("bad: raw paths are not enabled: %#v", item)
+	}
+	if !doc.Paths["/sys/audit/{path}"].Sudo {
+		t.Fatalf("bad: %#v", doc.Paths["/sys/audit/{path}"])
+	}
+}
+
 func testSystemBackend(t *testing.T) logical.Backend {
 	c, _, _ := TestCoreUnsealed(t)
 	return testSystemBackendInternal(t, c)
@@ -15,6 +15,7 @@ import (
 	"github.com/hashicorp/vault/helper/jsonutil"
 	"github.com/hashicorp/vault/helper/strutil"
 	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/physical"
 )
 
 const (
@@ -158,16 +159,18 @@ func (t *MountTable) sortEntriesByPath() *MountTable {
 
 // MountEntry is used to represent a mount table entry
 type MountEntry struct {
-	Table       string            `json:"table"`             // The table it belongs to
-	Path        string            `json:"path"`              // Mount Path
-	Type        string            `json:"type"`              // Logical backend Type
-	Description string            `json:"description"`       // User-provided description
-	UUID        string            `json:"uuid"`              // Barrier view UUID
-	Accessor    string            `json:"accessor"`          // Unique but more human-friendly ID. Does not change, not used for any sensitive things (like as a salt, which the UUID sometimes is).
-	Config      MountConfig       `json:"config"`            // Configuration related to this mount (but not backend-derived)
-	Options     map[string]string `json:"options"`           // Backend options
-	Local       bool              `json:"local"`             // Local mounts are not replicated or affected by replication
-	Tainted     bool              `json:"tainted,omitempty"` // Set as a Write-Ahead flag for unmount/remount
+	Table       string            `json:"table"`                  // The table it belongs to
+	Path        string            `json:"path"`                   // Mount Path
+	Type        string            `json:"type"`                   // Logical backend Type
+	Description string            `json:"description"`            // User-provided description
+	UUID        string            `json:"uuid"`                   // Barrier view UUID
+	Accessor    string            `json:"accessor"`               // Unique but more human-friendly ID. Does not change, not used for any sensitive things (like as a salt, which the UUID sometimes is).
+	Config      MountConfig       `json:"config"`                 // Configuration related to this mount (but not backend-derived)
+	Options     map[string]string `json:"options"`                // Backend options
+	Local       bool              `json:"local"`                  // Local mounts are not replicated or affected by replication
+	SealWrap    bool              `json:"seal_wrap"`              // Whether the entries of the backend are seal-wrapped
+	Tainted     bool              `json:"tainted,omitempty"`      // Set as a Write-Ahead flag for unmount/remount
+	NamespaceID string            `json:"namespace_id,omitempty"` // Namespace the mount belongs to, empty for the root namespace
 }
 
 // MountConfig is used to hold settable options
@@ -176,6 +179,18 @@ type MountConfig struct {
 	MaxLeaseTTL     time.Duration `json:"max_lease_ttl" structs:"max_lease_ttl" mapstructure:"max_lease_ttl"`             // Override for global default
 	ForceNoCache    bool          `json:"force_no_cache" structs:"force_no_cache" mapstructure:"force_no_cache"`          // Override for global default
 	PluginName      string        `json:"plugin_name,omitempty" structs:"plugin_name,omitempty" mapstructure:"plugin_name"`
+
+	// PluginVersion pins a plugin mount to a version of its plugin in the
+	// plugin catalog; the unversioned entry of the plugin is used when empty
+	PluginVersion string `json:"plugin_version,omitempty" structs:"plugin_version,omitempty" mapstructure:"plugin_version"`
+
+	// AutoCreateGroupAliases makes logins through an auth mount create the
+	// external groups and group aliases of unknown group aliases
+	AutoCreateGroupAliases bool `json:"auto_create_group_aliases,omitempty" structs:"auto_create_group_aliases,omitempty" mapstructure:"auto_create_group_aliases"`
+
+	// TokenType is the type of the tokens issued by logins through an auth
+	// mount; service tokens are issued when empty
+	TokenType string `json:"token_type,omitempty" structs:"token_type,omitempty" mapstructure:"token_type"`
 }
 
 // APIMountConfig is an embedded struct of api.MountConfigInput
@@ -184,6 +199,19 @@ type APIMountConfig struct {
 	MaxLeaseTTL     string `json:"max_lease_ttl" structs:"max_lease_ttl" mapstructure:"max_lease_ttl"`
 	ForceNoCache    bool   `json:"force_no_cache" structs:"force_no_cache" mapstructure:"force_no_cache"`
 	PluginName      string `json:"plugin_name,omitempty" structs:"plugin_name,omitempty" mapstructure:"plugin_name"`
+	PluginVersion   string `json:"plugin_version,omitempty" structs:"plugin_version,omitempty" mapstructure:"plugin_version"`
+
+	AutoCreateGroupAliases bool   `json:"auto_create_group_aliases,omitempty" structs:"auto_create_group_aliases,omitempty" mapstructure:"auto_create_group_aliases"`
+	TokenType              string `json:"token_type,omitempty" structs:"token_type,omitempty" mapstructure:"token_type"`
+}
+
+// mountTokenType returns the type of the tokens issued by logins through the
+// mount
+func mountTokenType(entry *MountEntry) string {
+	if entry.Config.TokenType == "" {
+		return tokenTypeService
+	}
+	return entry.Config.TokenType
 }
 
 // Mount is used to mount a new backend to the mount table.
@@ -207,6 +235,9 @@ func (c *Core) mount(entry *MountEntry) error {
 		}
 	}
 
+	// The namespaces lock is acquired first, as when creating namespaces
+	c.namespacesLock.RLock()
+	defer c.namespacesLock.RUnlock()
 	c.mountsLock.Lock()
 	defer c.mountsLock.Unlock()
 
@@ -215,6 +246,11 @@ func (c *Core) mount(entry *MountEntry) error {
 		return logical.CodedError(409, fmt.Sprintf("existing mount at %s", match))
 	}
 
+	// Verify the mount doesn't overlap another namespace
+	if err := c.checkNamespaceMountLocked(entry, entry.Path); err != nil {
+		return err
+	}
+
 	// Generate a new UUID and view
 	if entry.UUID == "" {
 		entryUUID, err := uuid.GenerateUUID()
@@ -234,9 +270,15 @@ func (c *Core) mount(entry *MountEntry) error {
 	view := NewBarrierView(c.barrier, viewPath)
 	sysView := c.mountEntrySysView(entry)
 	conf := make(map[string]string)
+	for k, v := range entry.Options {
+		conf[k] = v
+	}
 	if entry.Config.PluginName != "" {
 		conf["plugin_name"] = entry.Config.PluginName
 	}
+	if entry.Config.PluginVersion != "" {
+		conf["plugin_version"] = entry.Config.PluginVersion
+	}
 
 	backend, err := c.newLogicalBackend(entry.Type, sysView, view, conf)
 	if err != nil {
@@ -252,6 +294,8 @@ func (c *Core) mount(entry *MountEntry) error {
 		return fmt.Errorf("cannot mount '%s' of type '%s' as a logical backend", entry.Config.PluginName, backendType)
 	}
 
+	setupMountSealWrap(view, entry, backend)
+
 	// Call initialize; this takes care of init tasks that must be run after
 	// the ignore paths are collected.
 	if err := backend.Initialize(); err != nil {
@@ -427,6 +471,18 @@ func (c *Core) remount(src, dst string) error {
 		return fmt.Errorf("existing mount at '%s'", match)
 	}
 
+	// Mounts cannot be moved to another namespace
+	entry := c.router.MatchingMountEntry(src)
+	if entry == nil {
+		return fmt.Errorf("no matching mount at '%s'", src)
+	}
+	c.namespacesLock.RLock()
+	err := c.checkNamespaceMountLocked(entry, dst)
+	c.namespacesLock.RUnlock()
+	if err != nil {
+		return err
+	}
+
 	// Mark the entry as tainted
 	if err := c.taintMountEntry(src); err != nil {
 		return err
@@ -626,6 +682,9 @@ func (c *Core) persistMounts(table *MountTable, localOnly bool) error {
 		}
 	}
 
+	// Write the tables in a single transaction so that a failure doesn't
+	// leave the local table out of sync with the other one
+	var txns []*TxnEntry
 	if !localOnly {
 		// Encode the mount table into JSON and compress it (lzw).
 		compressedBytes, err := jsonutil.EncodeJSONAndCompress(nonLocalMounts, nil)
@@ -640,11 +699,7 @@ func (c *Core) persistMounts(table *MountTable, localOnly bool) error {
 			Value: compressedBytes,
 		}
 
-		// Write to the physical backend
-		if err := c.barrier.Put(entry); err != nil {
-			c.logger.Error("core: failed to persist mount table", "error", err)
-			return err
-		}
+		txns = append(txns, &TxnEntry{Operation: physical.PutOperation, Entry: entry})
 	}
 
 	// Repeat with local mounts
@@ -659,8 +714,9 @@ func (c *Core) persistMounts(table *MountTable, localOnly bool) error {
 		Value: compressedBytes,
 	}
 
-	if err := c.barrier.Put(entry); err != nil {
-		c.logger.Error("core: failed to persist local mount table", "error", err)
+	txns = append(txns, &TxnEntry{Operation: physical.PutOperation, Entry: entry})
+	if err := c.barrier.Transaction(txns); err != nil {
+		c.logger.Error("core: failed to persist mount table", "error", err)
 		return err
 	}
 
@@ -690,9 +746,15 @@ func (c *Core) setupMounts() error {
 		sysView := c.mountEntrySysView(entry)
 		// Set up conf to pass in plugin_name
 		conf := make(map[string]string)
+		for k, v := range entry.Options {
+			conf[k] = v
+		}
 		if entry.Config.PluginName != "" {
 			conf["plugin_name"] = entry.Config.PluginName
 		}
+		if entry.Config.PluginVersion != "" {
+			conf["plugin_version"] = entry.Config.PluginVersion
+		}
 		// Create the new backend
 		backend, err = c.newLogicalBackend(entry.Type, sysView, view, conf)
 		if err != nil {
@@ -714,6 +776,7 @@ func (c *Core) setupMounts() error {
 			return fmt.Errorf("cannot mount '%s' of type '%s' as a logical backend", entry.Config.PluginName, backend.Type())
 		}
 
+		setupMountSealWrap(view, entry, backend)
 		if err := backend.Initialize(); err != nil {
 			return err
 		}
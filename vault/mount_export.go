@@ -0,0 +1,384 @@
+package vault
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+	"golang.org/x/crypto/ed25519"
+)
+
+const (
+	// coreMountExportKeyPath is the storage path of the key used to sign
+	// exported mount configuration
+	coreMountExportKeyPath = "core/mount-export-key"
+
+	// coreMountImportConfigPath is the storage path of the keys of the
+	// clusters whose exported mount configuration can be imported
+	coreMountImportConfigPath = "core/mount-import-config"
+
+	// mountConfigDocumentVersion is the format version of exported mount
+	// configuration documents
+	mountConfigDocumentVersion = 1
+)
+
+// mountConfigDocument is the exported configuration of a mount: its tune
+// settings and the storage entries its backend declares exportable, such as
+// roles
+type mountConfigDocument struct {
+	Version         int               `json:"version"`
+	Path            string            `json:"path"`
+	Type            string            `json:"type"`
+	PluginName      string            `json:"plugin_name,omitempty"`
+	PluginVersion   string            `json:"plugin_version,omitempty"`
+	Description     string            `json:"description"`
+	DefaultLeaseTTL int               `json:"default_lease_ttl"`
+	MaxLeaseTTL     int               `json:"max_lease_ttl"`
+	Options         map[string]string `json:"options,omitempty"`
+	Entries         map[string][]byte `json:"entries"`
+	ExportTime      time.Time         `json:"export_time"`
+}
+
+// mountImportConfig holds the base64-encoded public keys of the clusters whose
+// exported mount configuration this cluster accepts
+type mountImportConfig struct {
+	TrustedKeys []string `json:"trusted_keys"`
+}
+
+// mountConfigDiff describes the changes importing a document makes to a
+// mount
+type mountConfigDiff struct {
+	Tune    map[string]interface{}
+	Added   []string
+	Changed []string
+	Removed []string
+}
+
+func (d *mountConfigDiff) responseData() map[string]interface{} {
+	return map[string]interface{}{
+		"tune":    d.Tune,
+		"added":   d.Added,
+		"changed": d.Changed,
+		"removed": d.Removed,
+	}
+}
+
+// mountExportKey returns the key used to sign exported mount configuration,
+// generating it on first use
+func (c *Core) mountExportKey() (ed25519.PrivateKey, error) {
+	c.mountExportKeyLock.Lock()
+	defer c.mountExportKeyLock.Unlock()
+
+	entry, err := c.barrier.Get(coreMountExportKeyPath)
+	if err != nil {
+		return nil, err
+	}
+	if entry != nil {
+		if len(entry.Value) != ed25519.PrivateKeySize {
+			return nil, fmt.Errorf("invalid mount export key")
+		}
+		return ed25519.PrivateKey(entry.Value), nil
+	}
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.barrier.Put(&Entry{
+		Key:   coreMountExportKeyPath,
+		Value: key,
+	}); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// mountImportConfig returns the keys of the clusters whose exports can be
+// imported
+func (c *Core) mountImportConfig() (*mountImportConfig, error) {
+	entry, err := c.barrier.Get(coreMountImportConfigPath)
+	if err != nil {
+		return nil, err
+	}
+	var config mountImportConfig
+	if entry != nil {
+		if err := json.Unmarshal(entry.Value, &config); err != nil {
+			return nil, fmt.Errorf("failed to decode mount import config: %v", err)
+		}
+	}
+	return &config, nil
+}
+
+// setMountImportConfig validates and stores the keys of the clusters whose
+// exports can be imported
+func (c *Core) setMountImportConfig(config *mountImportConfig) error {
+	for _, key := range config.TrustedKeys {
+		if _, err := parseMountImportKey(key); err != nil {
+			return err
+		}
+	}
+	raw, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+	return c.barrier.Put(&Entry{
+		Key:   coreMountImportConfigPath,
+		Value: raw,
+	})
+}
+
+// mountImportKeys returns the keys an imported document may be signed with:
+// the export key of this cluster and the configured trusted keys. The key
+// that signed a document is never taken from the request importing it, which
+// would let anyone able to import sign their own documents.
+func (c *Core) mountImportKeys() ([]ed25519.PublicKey, error) {
+	key, err := c.mountExportKey()
+	if err != nil {
+		return nil, err
+	}
+	config, err := c.mountImportConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []ed25519.PublicKey{key.Public().(ed25519.PublicKey)}
+	for _, encoded := range config.TrustedKeys {
+		trusted, err := parseMountImportKey(encoded)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, trusted)
+	}
+	return keys, nil
+}
+
+func parseMountImportKey(encoded string) (ed25519.PublicKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(raw) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid trusted key %q: must be a base64-encoded Ed25519 public key", encoded)
+	}
+	return ed25519.PublicKey(raw), nil
+}
+
+// exportableStorage returns the storage prefixes the backend mounted at the
+// path declares exportable
+func (c *Core) exportableStorage(path string) ([]string, error) {
+	backend := c.router.MatchingBackend(path)
+	if backend == nil {
+		return nil, fmt.Errorf("no backend mounted at %q", path)
+	}
+	special := backend.SpecialPaths()
+	if special == nil {
+		return nil, nil
+	}
+	return special.ExportableStorage, nil
+}
+
+// exportMountConfig returns the signed configuration document of the mount
+// at the path, along with the signature and the public key to verify it
+func (c *Core) exportMountConfig(path string) ([]byte, []byte, ed25519.PublicKey, error) {
+	entry := c.router.MatchingMountEntry(path)
+	if entry == nil {
+		return nil, nil, nil, fmt.Errorf("no mount at %q", path)
+	}
+	prefixes, err := c.exportableStorage(path)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	entries, err := c.mountConfigEntries(path, prefixes)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	doc := &mountConfigDocument{
+		Version:         mountConfigDocumentVersion,
+		Path:            entry.Path,
+		Type:            entry.Type,
+		PluginName:      entry.Config.PluginName,
+		PluginVersion:   entry.Config.PluginVersion,
+		Description:     entry.Description,
+		DefaultLeaseTTL: int(entry.Config.DefaultLeaseTTL.Seconds()),
+		MaxLeaseTTL:     int(entry.Config.MaxLeaseTTL.Seconds()),
+		Options:         entry.Options,
+		Entries:         entries,
+		ExportTime:      time.Now().UTC(),
+	}
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	key, err := c.mountExportKey()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return raw, ed25519.Sign(key, raw), key.Public().(ed25519.PublicKey), nil
+}
+
+// mountConfigEntries returns the storage entries of the mount under the
+// prefixes
+func (c *Core) mountConfigEntries(path string, prefixes []string) (map[string][]byte, error) {
+	view := c.router.MatchingStorageView(path)
+	if view == nil {
+		return nil, fmt.Errorf("cannot fetch storage view for path %q", path)
+	}
+
+	entries := make(map[string][]byte)
+	for _, prefix := range prefixes {
+		keys, err := logical.CollectKeys(view.SubView(prefix))
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range keys {
+			out, err := view.Get(prefix + key)
+			if err != nil {
+				return nil, err
+			}
+			if out != nil {
+				entries[prefix+key] = out.Value
+			}
+		}
+	}
+	return entries, nil
+}
+
+// verifyMountConfig checks that a document was signed with one of the keys
+// and decodes it
+func verifyMountConfig(raw, signature []byte, keys []ed25519.PublicKey) (*mountConfigDocument, error) {
+	verified := false
+	for _, key := range keys {
+		if ed25519.Verify(key, raw, signature) {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, errors.New("document is not signed by this cluster or a trusted cluster")
+	}
+
+	var doc mountConfigDocument
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("failed to decode document: %v", err)
+	}
+	if doc.Version != mountConfigDocumentVersion {
+		return nil, fmt.Errorf("unsupported document version %d", doc.Version)
+	}
+	return &doc, nil
+}
+
+// diffMountConfig compares the document with the mount at the path. Entries
+// of the mount missing from the document are listed as removed.
+func (c *Core) diffMountConfig(path string, doc *mountConfigDocument) (*mountConfigDiff, error) {
+	entry := c.router.MatchingMountEntry(path)
+	if entry == nil {
+		return nil, fmt.Errorf("no mount at %q", path)
+	}
+	if entry.Type != doc.Type || entry.Config.PluginName != doc.PluginName {
+		return nil, fmt.Errorf("document is for a %q mount but %q is a %q mount", doc.Type, path, entry.Type)
+	}
+	if len(entry.Options) != 0 || len(doc.Options) != 0 {
+		if !reflect.DeepEqual(entry.Options, doc.Options) {
+			return nil, fmt.Errorf("mount options of %q differ from the document's", path)
+		}
+	}
+
+	prefixes, err := c.exportableStorage(path)
+	if err != nil {
+		return nil, err
+	}
+	for key := range doc.Entries {
+		if !hasAnyPrefix(key, prefixes) {
+			return nil, fmt.Errorf("entry %q is not exportable by the backend at %q", key, path)
+		}
+	}
+	current, err := c.mountConfigEntries(path, prefixes)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &mountConfigDiff{
+		Tune:    make(map[string]interface{}),
+		Added:   []string{},
+		Changed: []string{},
+		Removed: []string{},
+	}
+	tuneChange := func(name string, current, imported interface{}) {
+		if current != imported {
+			diff.Tune[name] = map[string]interface{}{
+				"current":  current,
+				"imported": imported,
+			}
+		}
+	}
+	tuneChange("description", entry.Description, doc.Description)
+	tuneChange("default_lease_ttl", int(entry.Config.DefaultLeaseTTL.Seconds()), doc.DefaultLeaseTTL)
+	tuneChange("max_lease_ttl", int(entry.Config.MaxLeaseTTL.Seconds()), doc.MaxLeaseTTL)
+
+	for key, value := range doc.Entries {
+		old, ok := current[key]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, key)
+		case !bytes.Equal(old, value):
+			diff.Changed = append(diff.Changed, key)
+		}
+	}
+	for key := range current {
+		if _, ok := doc.Entries[key]; !ok {
+			diff.Removed = append(diff.Removed, key)
+		}
+	}
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Changed)
+	sort.Strings(diff.Removed)
+	return diff, nil
+}
+
+// applyMountConfigEntries writes the added and changed entries of the diff
+// to the mount's storage, and deletes the removed entries if prune is set.
+// The backend is told of each change so it can drop any cached state.
+func (c *Core) applyMountConfigEntries(path string, doc *mountConfigDocument, diff *mountConfigDiff, prune bool) error {
+	view := c.router.MatchingStorageView(path)
+	backend := c.router.MatchingBackend(path)
+	if view == nil || backend == nil {
+		return fmt.Errorf("no backend mounted at %q", path)
+	}
+
+	for _, keys := range [][]string{diff.Added, diff.Changed} {
+		for _, key := range keys {
+			if err := view.Put(&logical.StorageEntry{
+				Key:   key,
+				Value: doc.Entries[key],
+			}); err != nil {
+				return err
+			}
+			backend.InvalidateKey(key)
+		}
+	}
+	if prune {
+		for _, key := range diff.Removed {
+			if err := view.Delete(key); err != nil {
+				return err
+			}
+			backend.InvalidateKey(key)
+		}
+	}
+	return nil
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
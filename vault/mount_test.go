@@ -86,6 +86,43 @@ func TestCore_Mount(t *testing.T) {
 // Test that the local table actually gets populated as expected with local
 // entries, and that upon reading the entries from both are recombined
 // correctly
+func TestCore_Mount_Transaction(t *testing.T) {
+	backend := newFailingTxnBackend(t)
+	c, _, _ := TestCoreUnsealedBackend(t, backend)
+
+	raw := make(map[string][]byte)
+	for _, path := range []string{coreMountConfigPath, coreLocalMountConfigPath} {
+		entry, err := c.barrier.Get(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		raw[path] = entry.Value
+	}
+
+	// A failed write leaves both tables untouched
+	backend.failing = true
+	me := &MountEntry{
+		Table: mountTableType,
+		Path:  "foo",
+		Type:  "kv",
+	}
+	if err := c.mount(me); err == nil {
+		t.Fatal("expected error")
+	}
+	for path, value := range raw {
+		entry, err := c.barrier.Get(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(entry.Value, value) {
+			t.Fatalf("table %q was modified", path)
+		}
+	}
+	if match := c.router.MatchingMount("foo/bar"); match != "" {
+		t.Fatalf("unexpected mount: %q", match)
+	}
+}
+
 func TestCore_Mount_Local(t *testing.T) {
 	c, _, _ := TestCoreUnsealed(t)
 
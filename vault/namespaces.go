@@ -0,0 +1,602 @@
+package vault
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/armon/go-radix"
+	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/logical"
+)
+
+const (
+	// namespaceSubPath is the sub-path of the system barrier view where the
+	// namespaces are stored
+	namespaceSubPath = "namespaces/"
+
+	// namespacePolicySubPath is the sub-path of the system barrier view
+	// under which the policies of each namespace other than root are stored
+	namespacePolicySubPath = "namespace-policies/"
+)
+
+var (
+	// validNamespaceName checks the name of a namespace, which is a single
+	// path segment
+	validNamespaceName = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`).MatchString
+
+	// reservedNamespaceNames cannot be used as namespace names since they
+	// would shadow the singleton mounts
+	reservedNamespaceNames = []string{
+		"audit",
+		"auth",
+		"cubbyhole",
+		"identity",
+		"sys",
+	}
+
+	// namespaceSharedPrefixes are the paths of the singleton mounts shared by
+	// all the namespaces. Their requests are routed to the same backends in
+	// every namespace, which scope them to the namespace of the request.
+	namespaceSharedPrefixes = []string{
+		"sys/",
+		"auth/token/",
+		"cubbyhole/",
+		"identity/",
+	}
+
+	// namespaceSharedPaths are the paths of the singleton mounts available in
+	// namespaces other than root, matched exactly or as the parent of the
+	// requested path. The other paths manage the whole server.
+	namespaceSharedPaths = []string{
+		"sys/mounts",
+		"sys/auth",
+		"sys/policy",
+		"sys/namespaces",
+		"sys/capabilities",
+		"sys/capabilities-self",
+		"sys/capabilities-accessor",
+		"sys/wrapping",
+		"sys/renew",
+		"sys/revoke",
+		"sys/revoke-prefix",
+		"sys/revoke-force",
+		"sys/leases/lookup",
+		"sys/leases/renew",
+		"sys/leases/revoke",
+		"sys/leases/revoke-prefix",
+		"sys/leases/revoke-force",
+		"auth/token/create-orphan",
+		"auth/token/lookup",
+		"auth/token/lookup-self",
+		"auth/token/lookup-accessor",
+		"auth/token/renew",
+		"auth/token/renew-self",
+		"auth/token/revoke",
+		"auth/token/revoke-self",
+		"auth/token/revoke-accessor",
+		"auth/token/revoke-orphan",
+		"cubbyhole",
+		"identity/entity",
+		"identity/entity-alias",
+		"identity/alias",
+		"identity/group",
+		"identity/group-alias",
+		"identity/lookup",
+	}
+
+	// namespaceSharedExactPaths are the paths of the singleton mounts
+	// available in namespaces other than root only when matched exactly
+	namespaceSharedExactPaths = []string{
+		"auth/token/create",
+	}
+)
+
+// Namespace isolates the mounts, policies, identities and tokens of a
+// tenant under a path. Namespaces are nested: the path of a namespace starts
+// with the path of its parent, and the root namespace has an empty path.
+type Namespace struct {
+	ID   string `json:"id" structs:"id" mapstructure:"id"`
+	Path string `json:"path" structs:"path" mapstructure:"path"`
+}
+
+// rootNamespace is the namespace of the requests which don't target
+// another one. Its ID is empty so that the mounts, tokens and identities
+// created before namespaces were introduced belong to it.
+var rootNamespace = &Namespace{}
+
+// isRoot returns whether this is the root namespace
+func (ns *Namespace) isRoot() bool {
+	return ns.ID == ""
+}
+
+// setupNamespaces loads the namespaces along with their policy stores. This
+// must be called after the policy store of the root namespace is set up.
+func (c *Core) setupNamespaces() error {
+	view := c.systemBarrierView.SubView(namespaceSubPath)
+	ids, err := view.List("")
+	if err != nil {
+		return fmt.Errorf("failed to list namespaces: %v", err)
+	}
+
+	tree := radix.New()
+	tree.Insert(rootNamespace.Path, rootNamespace)
+	byID := map[string]*Namespace{
+		rootNamespace.ID: rootNamespace,
+	}
+	policyStores := make(map[string]*PolicyStore, len(ids))
+	for _, id := range ids {
+		entry, err := view.Get(id)
+		if err != nil {
+			return fmt.Errorf("failed to read namespace: %v", err)
+		}
+		if entry == nil {
+			continue
+		}
+
+		var ns Namespace
+		if err := entry.DecodeJSON(&ns); err != nil {
+			return fmt.Errorf("failed to decode namespace: %v", err)
+		}
+		tree.Insert(ns.Path, &ns)
+		byID[ns.ID] = &ns
+		policyStores[ns.ID] = c.newNamespacePolicyStore(&ns)
+	}
+
+	c.namespacesLock.Lock()
+	c.namespaces = tree
+	c.namespacesByID = byID
+	c.namespacePolicyStores = policyStores
+	c.namespacesLock.Unlock()
+	return nil
+}
+
+// teardownNamespaces is used to reverse setupNamespaces when the vault is
+// being sealed
+func (c *Core) teardownNamespaces() {
+	c.namespacesLock.Lock()
+	c.namespaces = nil
+	c.namespacesByID = nil
+	c.namespacePolicyStores = nil
+	c.namespacesLock.Unlock()
+}
+
+// newNamespacePolicyStore returns the policy store of a namespace other
+// than root
+func (c *Core) newNamespacePolicyStore(ns *Namespace) *PolicyStore {
+	view := c.systemBarrierView.SubView(namespacePolicySubPath + ns.ID + "/")
+	return NewPolicyStore(view, &dynamicSystemView{core: c})
+}
+
+// namespaceByID returns the namespace of the given ID, or nil if it doesn't
+// exist. The empty ID is the ID of the root namespace.
+func (c *Core) namespaceByID(id string) *Namespace {
+	if id == "" {
+		return rootNamespace
+	}
+
+	c.namespacesLock.RLock()
+	defer c.namespacesLock.RUnlock()
+	return c.namespacesByID[id]
+}
+
+// namespaceByPath returns the namespace at the given path, or nil if it
+// doesn't exist. The empty path is the path of the root namespace.
+func (c *Core) namespaceByPath(path string) *Namespace {
+	path = namespacePath(path)
+	if path == "" {
+		return rootNamespace
+	}
+
+	c.namespacesLock.RLock()
+	defer c.namespacesLock.RUnlock()
+	if c.namespaces == nil {
+		return nil
+	}
+	raw, ok := c.namespaces.Get(path)
+	if !ok {
+		return nil
+	}
+	return raw.(*Namespace)
+}
+
+// namespacePolicyStore returns the policy store of a namespace, or nil if
+// the namespace doesn't exist anymore
+func (c *Core) namespacePolicyStore(ns *Namespace) *PolicyStore {
+	if ns.isRoot() {
+		return c.policyStore
+	}
+
+	c.namespacesLock.RLock()
+	defer c.namespacesLock.RUnlock()
+	return c.namespacePolicyStores[ns.ID]
+}
+
+// requestNamespace returns the namespace of a routed request, or nil if it
+// was deleted since
+func (c *Core) requestNamespace(req *logical.Request) *Namespace {
+	return c.namespaceByPath(req.Namespace)
+}
+
+// requestPolicyStore returns the policy store of the namespace of a routed
+// request, or nil if the namespace was deleted since
+func (c *Core) requestPolicyStore(req *logical.Request) *PolicyStore {
+	ns := c.requestNamespace(req)
+	if ns == nil {
+		return nil
+	}
+	return c.namespacePolicyStore(ns)
+}
+
+// errNamespaceNotFound returns the error of the requests to a namespace
+// which doesn't exist
+func errNamespaceNotFound(path string) error {
+	return fmt.Errorf("namespace %q not found", path)
+}
+
+// namespacePath normalizes the path of a namespace
+func namespacePath(path string) string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return ""
+	}
+	return path + "/"
+}
+
+// resolveNamespaceLocked returns the deepest namespace whose path prefixes
+// the given path, along with the path relative to it. This must be called
+// with the namespaces lock held.
+func (c *Core) resolveNamespaceLocked(path string) (*Namespace, string) {
+	if c.namespaces == nil {
+		return rootNamespace, path
+	}
+	prefix, raw, ok := c.namespaces.LongestPrefix(path)
+	if !ok {
+		return rootNamespace, path
+	}
+	return raw.(*Namespace), strings.TrimPrefix(path, prefix)
+}
+
+// routeNamespace resolves the namespace of a request, from the namespace
+// it was sent to and its path, and translates its path into the path routed
+// to. The mounts of a namespace are routed under its path, its auth mounts
+// under the auth prefix followed by its path, and the singleton mounts are
+// shared by all the namespaces.
+func (c *Core) routeNamespace(req *logical.Request) error {
+	c.namespacesLock.RLock()
+	defer c.namespacesLock.RUnlock()
+
+	prefix := namespacePath(req.Namespace)
+	if prefix != "" {
+		if c.namespaces == nil {
+			return errNamespaceNotFound(prefix)
+		}
+		if _, ok := c.namespaces.Get(prefix); !ok {
+			return errNamespaceNotFound(prefix)
+		}
+	}
+
+	ns, path := c.resolveNamespaceLocked(prefix + req.Path)
+	if !ns.isRoot() && !namespaceSharedPathAvailable(path) {
+		return fmt.Errorf("path %q is not available in a namespace", path)
+	}
+
+	req.Namespace = ns.Path
+	req.Path = namespaceRouterPath(ns.Path, path)
+	return nil
+}
+
+// namespaceSharedPathAvailable returns whether a path relative to a
+// namespace other than root is available. All the paths are, except those
+// of the singleton mounts which manage the whole server.
+func namespaceSharedPathAvailable(path string) bool {
+	shared := false
+	for _, prefix := range namespaceSharedPrefixes {
+		if strings.HasPrefix(path+"/", prefix) {
+			shared = true
+			break
+		}
+	}
+	if !shared {
+		return true
+	}
+
+	path = strings.TrimSuffix(path, "/")
+	if strutil.StrListContains(namespaceSharedExactPaths, path) {
+		return true
+	}
+	for _, p := range namespaceSharedPaths {
+		if path == p || strings.HasPrefix(path, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// namespaceRouterPath returns the path routed to for a path relative to
+// the namespace at the given path
+func namespaceRouterPath(nsPath, path string) string {
+	if nsPath == "" {
+		return path
+	}
+	for _, prefix := range namespaceSharedPrefixes {
+		if strings.HasPrefix(path+"/", prefix) {
+			return path
+		}
+	}
+	if strings.HasPrefix(path, credentialRoutePrefix) {
+		return credentialRoutePrefix + nsPath + strings.TrimPrefix(path, credentialRoutePrefix)
+	}
+	return nsPath + path
+}
+
+// namespaceMountPath returns the router path of a mount given by its path
+// relative to the namespace at the given path, and whether the mount can be
+// managed from the namespace. The singleton mounts shared by the namespaces
+// can only be managed from the root namespace.
+func namespaceMountPath(nsPath, path string) (string, bool) {
+	routerPath := namespaceRouterPath(nsPath, path)
+	return routerPath, nsPath == "" || routerPath != path
+}
+
+// namespaceLeaseAllowed returns whether a lease ID or prefix can be managed
+// from the namespace at the given path: those of the mounts and auth mounts
+// of the namespace and of its children are
+func namespaceLeaseAllowed(nsPath, leaseID string) bool {
+	return nsPath == "" || strings.HasPrefix(leaseID, nsPath) || strings.HasPrefix(leaseID, credentialRoutePrefix+nsPath)
+}
+
+// namespaceFullPath returns the path relative to the root namespace of a
+// request routed to the given path in the namespace at the given path. This
+// is the path which the ACLs of the tokens of the parent namespaces apply to.
+func namespaceFullPath(nsPath, routerPath string) string {
+	switch {
+	case nsPath == "":
+		return routerPath
+	case strings.HasPrefix(routerPath, credentialRoutePrefix+nsPath):
+		return nsPath + credentialRoutePrefix + strings.TrimPrefix(routerPath, credentialRoutePrefix+nsPath)
+	case strings.HasPrefix(routerPath, nsPath):
+		return routerPath
+	default:
+		return nsPath + routerPath
+	}
+}
+
+// namespaceRelativePath returns a path relative to the root namespace
+// relative to the given namespace instead, and whether the path is within
+// the namespace
+func namespaceRelativePath(ns *Namespace, fullPath string) (string, bool) {
+	if !strings.HasPrefix(fullPath, ns.Path) {
+		return "", false
+	}
+	return strings.TrimPrefix(fullPath, ns.Path), true
+}
+
+// checkNamespaceMountLocked returns an error if the given mount entry can't
+// be mounted at the given path of its table, which excludes the auth prefix
+// for auth mounts: the path must be within the namespace of the entry, not
+// overlap another namespace, and not shadow the singleton mounts shared by
+// the namespaces. This must be called with the namespaces lock held.
+func (c *Core) checkNamespaceMountLocked(entry *MountEntry, path string) error {
+	if c.namespaces == nil {
+		return nil
+	}
+	ns, rel := c.resolveNamespaceLocked(path)
+	if ns.ID != entry.NamespaceID {
+		return logical.CodedError(409, fmt.Sprintf("path is within namespace %q", ns.Path))
+	}
+
+	if !ns.isRoot() {
+		reserved := protectedMounts
+		if entry.Table == credentialTableType {
+			reserved = []string{"token/"}
+		}
+		for _, p := range reserved {
+			if strings.HasPrefix(rel, p) {
+				return logical.CodedError(403, fmt.Sprintf("cannot mount '%s'", rel))
+			}
+		}
+	}
+
+	var conflict string
+	c.namespaces.WalkPrefix(path, func(nsPath string, raw interface{}) bool {
+		conflict = nsPath
+		return true
+	})
+	if conflict != "" {
+		return logical.CodedError(409, fmt.Sprintf("existing namespace at %s", conflict))
+	}
+	return nil
+}
+
+// checkNamespaceMountsLocked returns an error if a namespace at the given
+// path would overlap a mount. This must be called with the namespaces lock
+// held.
+func (c *Core) checkNamespaceMountsLocked(path string) error {
+	c.mountsLock.RLock()
+	defer c.mountsLock.RUnlock()
+	c.authLock.RLock()
+	defer c.authLock.RUnlock()
+
+	overlaps := func(entry *MountEntry) bool {
+		return strings.HasPrefix(entry.Path, path) || strings.HasPrefix(path, entry.Path)
+	}
+	if c.mounts != nil {
+		for _, entry := range c.mounts.Entries {
+			if overlaps(entry) {
+				return logical.CodedError(409, fmt.Sprintf("existing mount at %s", entry.Path))
+			}
+		}
+	}
+	if c.auth != nil {
+		for _, entry := range c.auth.Entries {
+			if overlaps(entry) {
+				return logical.CodedError(409, fmt.Sprintf("existing auth mount at %s", entry.Path))
+			}
+		}
+	}
+	return nil
+}
+
+// CreateNamespace creates a namespace of the given name under its parent,
+// returning the existing namespace if there is one
+func (c *Core) CreateNamespace(parent *Namespace, name string) (*Namespace, error) {
+	switch {
+	case !validNamespaceName(name):
+		return nil, fmt.Errorf("invalid namespace name %q", name)
+	case strutil.StrListContains(reservedNamespaceNames, strings.ToLower(name)):
+		return nil, fmt.Errorf("namespace name %q is reserved", name)
+	}
+	path := parent.Path + name + "/"
+
+	c.namespacesLock.Lock()
+	defer c.namespacesLock.Unlock()
+
+	if c.namespaces == nil {
+		return nil, fmt.Errorf("namespaces are not set up")
+	}
+	if _, ok := c.namespacesByID[parent.ID]; !ok {
+		return nil, errNamespaceNotFound(parent.Path)
+	}
+	if raw, ok := c.namespaces.Get(path); ok {
+		return raw.(*Namespace), nil
+	}
+	if err := c.checkNamespaceMountsLocked(path); err != nil {
+		return nil, err
+	}
+
+	id, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, err
+	}
+	ns := &Namespace{
+		ID:   id,
+		Path: path,
+	}
+
+	// The policies are created first, so that a namespace is never loaded
+	// without its default policies
+	policyStore := c.newNamespacePolicyStore(ns)
+	if err := policyStore.createDefaultPolicy(); err != nil {
+		return nil, err
+	}
+	if err := policyStore.createResponseWrappingPolicy(); err != nil {
+		return nil, err
+	}
+
+	entry, err := logical.StorageEntryJSON(ns.ID, ns)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create namespace entry: %v", err)
+	}
+	if err := c.systemBarrierView.SubView(namespaceSubPath).Put(entry); err != nil {
+		return nil, fmt.Errorf("failed to save namespace: %v", err)
+	}
+
+	c.namespaces.Insert(ns.Path, ns)
+	c.namespacesByID[ns.ID] = ns
+	c.namespacePolicyStores[ns.ID] = policyStore
+
+	c.logger.Info("core: created namespace", "path", ns.Path, "id", ns.ID)
+	return ns, nil
+}
+
+// DeleteNamespace deletes a namespace, along with its policies and
+// identities. The namespace must have no child namespaces and no mounts.
+// The tokens of the namespace are no longer valid once it is deleted.
+func (c *Core) DeleteNamespace(ns *Namespace) error {
+	if ns.isRoot() {
+		return fmt.Errorf("cannot delete the root namespace")
+	}
+
+	c.namespacesLock.Lock()
+	defer c.namespacesLock.Unlock()
+
+	if c.namespaces == nil || c.namespacesByID[ns.ID] == nil {
+		return nil
+	}
+
+	var child string
+	c.namespaces.WalkPrefix(ns.Path, func(nsPath string, raw interface{}) bool {
+		if nsPath != ns.Path {
+			child = nsPath
+			return true
+		}
+		return false
+	})
+	if child != "" {
+		return fmt.Errorf("namespace has child namespace %q", child)
+	}
+	if err := c.checkNamespaceMountsLocked(ns.Path); err != nil {
+		return fmt.Errorf("namespace has mounts, disable them first: %v", err)
+	}
+
+	if c.identityStore != nil {
+		if err := c.identityStore.deleteNamespaceIdentities(ns.ID); err != nil {
+			return fmt.Errorf("failed to delete the identities of the namespace: %v", err)
+		}
+	}
+	if err := logical.ClearView(c.systemBarrierView.SubView(namespacePolicySubPath + ns.ID + "/")); err != nil {
+		return fmt.Errorf("failed to delete the policies of the namespace: %v", err)
+	}
+	if err := c.systemBarrierView.SubView(namespaceSubPath).Delete(ns.ID); err != nil {
+		return fmt.Errorf("failed to delete namespace: %v", err)
+	}
+
+	c.namespaces.Delete(ns.Path)
+	delete(c.namespacesByID, ns.ID)
+	delete(c.namespacePolicyStores, ns.ID)
+
+	c.logger.Info("core: deleted namespace", "path", ns.Path, "id", ns.ID)
+	return nil
+}
+
+// ListNamespaces returns the child namespaces of a namespace
+func (c *Core) ListNamespaces(parent *Namespace) []*Namespace {
+	c.namespacesLock.RLock()
+	defer c.namespacesLock.RUnlock()
+
+	var children []*Namespace
+	if c.namespaces == nil {
+		return children
+	}
+	c.namespaces.WalkPrefix(parent.Path, func(nsPath string, raw interface{}) bool {
+		rest := strings.TrimPrefix(nsPath, parent.Path)
+		if rest != "" && strings.Count(rest, "/") == 1 {
+			children = append(children, raw.(*Namespace))
+		}
+		return false
+	})
+	return children
+}
+
+// tokenNamespace returns the namespace of a token, or nil if it was deleted
+func (c *Core) tokenNamespace(te *TokenEntry) *Namespace {
+	return c.namespaceByID(te.NamespaceID)
+}
+
+// tokenACLPath returns the path of a request checked by the ACL of a token,
+// which is relative to the namespace of the token, and whether the request
+// is allowed at all: only the namespace of the token and its children can be
+// accessed.
+func (c *Core) tokenACLPath(te *TokenEntry, req *logical.Request) (string, bool) {
+	ns := c.tokenNamespace(te)
+	if ns == nil {
+		return "", false
+	}
+	return namespaceRelativePath(ns, namespaceFullPath(namespacePath(req.Namespace), req.Path))
+}
+
+// namespacePolicy returns the policy of the given name in the namespace of
+// the given ID, or nil if either doesn't exist
+func (c *Core) namespacePolicy(nsID, name string) (*Policy, error) {
+	ns := c.namespaceByID(nsID)
+	if ns == nil {
+		return nil, nil
+	}
+	policyStore := c.namespacePolicyStore(ns)
+	if policyStore == nil {
+		return nil, nil
+	}
+	return policyStore.GetPolicy(name)
+}
@@ -0,0 +1,304 @@
+package vault
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/logical"
+)
+
+// testNamespaceRequest handles a request in a namespace, returning the
+// response and the error
+func testNamespaceRequest(c *Core, token, ns string, op logical.Operation, path string, data map[string]interface{}) (*logical.Response, error) {
+	req := &logical.Request{
+		Operation:   op,
+		Path:        path,
+		Namespace:   ns,
+		ClientToken: token,
+		Data:        data,
+	}
+	return c.HandleRequest(req)
+}
+
+// testNamespaceSuccess handles a request in a namespace, failing on errors
+func testNamespaceSuccess(t *testing.T, c *Core, token, ns string, op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+	resp, err := testNamespaceRequest(c, token, ns, op, path, data)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: %s%s: resp: %#v, err: %v", ns, path, resp, err)
+	}
+	return resp
+}
+
+// testNamespaceToken creates a token in a namespace with the given policy,
+// which is also created in the namespace
+func testNamespaceToken(t *testing.T, c *Core, root, ns, policy string) string {
+	testNamespaceSuccess(t, c, root, ns, logical.UpdateOperation, "sys/policy/test", map[string]interface{}{
+		"rules": policy,
+	})
+	resp := testNamespaceSuccess(t, c, root, ns, logical.UpdateOperation, "auth/token/create", map[string]interface{}{
+		"policies": []string{"test"},
+	})
+	return resp.Auth.ClientToken
+}
+
+func TestNamespaces_mounts(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	resp := testNamespaceSuccess(t, c, root, "", logical.UpdateOperation, "sys/namespaces/ns1", nil)
+	if resp.Data["path"] != "ns1/" || resp.Data["id"] == "" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// The mount table of the namespace is isolated from the root one
+	testNamespaceSuccess(t, c, root, "ns1", logical.UpdateOperation, "sys/mounts/secret", map[string]interface{}{
+		"type": "kv",
+	})
+	testNamespaceSuccess(t, c, root, "ns1", logical.UpdateOperation, "secret/foo", map[string]interface{}{
+		"value": "ns1",
+	})
+	testNamespaceSuccess(t, c, root, "", logical.UpdateOperation, "secret/foo", map[string]interface{}{
+		"value": "root",
+	})
+	resp = testNamespaceSuccess(t, c, root, "ns1", logical.ReadOperation, "secret/foo", nil)
+	if resp == nil || resp.Data["value"] != "ns1" {
+		t.Fatalf("bad: %#v", resp)
+	}
+	resp = testNamespaceSuccess(t, c, root, "", logical.ReadOperation, "ns1/secret/foo", nil)
+	if resp == nil || resp.Data["value"] != "ns1" {
+		t.Fatalf("bad: %#v", resp)
+	}
+	resp = testNamespaceSuccess(t, c, root, "", logical.ReadOperation, "secret/foo", nil)
+	if resp == nil || resp.Data["value"] != "root" {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	resp = testNamespaceSuccess(t, c, root, "ns1", logical.ReadOperation, "sys/mounts", nil)
+	if len(resp.Data) != 1 || resp.Data["secret/"] == nil {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	resp = testNamespaceSuccess(t, c, root, "", logical.ReadOperation, "sys/mounts", nil)
+	if resp.Data["ns1/secret/"] != nil {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// The singleton mounts can't be shadowed, nor the namespace overlapped
+	resp, err := testNamespaceRequest(c, root, "ns1", logical.UpdateOperation, "sys/mounts/sys", map[string]interface{}{
+		"type": "kv",
+	})
+	if err == nil {
+		t.Fatalf("expected an error, got %#v", resp)
+	}
+	resp, err = testNamespaceRequest(c, root, "", logical.UpdateOperation, "sys/mounts/ns1/other", map[string]interface{}{
+		"type": "kv",
+	})
+	if err == nil {
+		t.Fatalf("expected an error, got %#v", resp)
+	}
+
+	// The server-wide sys endpoints are not available in a namespace
+	resp, err = testNamespaceRequest(c, root, "ns1", logical.UpdateOperation, "sys/seal", nil)
+	if err != logical.ErrInvalidRequest {
+		t.Fatalf("expected an invalid request, got resp: %#v, err: %v", resp, err)
+	}
+	resp, err = testNamespaceRequest(c, root, "nope", logical.ReadOperation, "sys/mounts", nil)
+	if err != logical.ErrInvalidRequest || !strings.Contains(resp.Data["error"].(string), "not found") {
+		t.Fatalf("expected a missing namespace, got resp: %#v, err: %v", resp, err)
+	}
+
+	// Namespaces with mounts can't be deleted
+	resp, err = testNamespaceRequest(c, root, "", logical.DeleteOperation, "sys/namespaces/ns1", nil)
+	if err == nil {
+		t.Fatalf("expected an error, got %#v", resp)
+	}
+	testNamespaceSuccess(t, c, root, "ns1", logical.DeleteOperation, "sys/mounts/secret", nil)
+	testNamespaceSuccess(t, c, root, "", logical.DeleteOperation, "sys/namespaces/ns1", nil)
+	resp, err = testNamespaceRequest(c, root, "ns1", logical.ReadOperation, "sys/mounts", nil)
+	if err != logical.ErrInvalidRequest {
+		t.Fatalf("expected a missing namespace, got resp: %#v, err: %v", resp, err)
+	}
+}
+
+func TestNamespaces_policiesAndTokens(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	testNamespaceSuccess(t, c, root, "", logical.UpdateOperation, "sys/namespaces/ns1", nil)
+	testNamespaceSuccess(t, c, root, "ns1", logical.UpdateOperation, "sys/mounts/secret", map[string]interface{}{
+		"type": "kv",
+	})
+
+	token := testNamespaceToken(t, c, root, "ns1", `path "secret/*" { capabilities = ["create", "read", "update"] }`)
+
+	// The policies of the namespace are isolated from the root ones
+	resp := testNamespaceSuccess(t, c, root, "ns1", logical.ListOperation, "sys/policy", nil)
+	if !reflect.DeepEqual(resp.Data["keys"], []string{"default", "test", "root"}) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	resp = testNamespaceSuccess(t, c, root, "", logical.ReadOperation, "sys/policy/test", nil)
+	if resp != nil {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// The policy of the token applies to the paths of its namespace, with or
+	// without the header
+	testNamespaceSuccess(t, c, token, "ns1", logical.UpdateOperation, "secret/foo", map[string]interface{}{
+		"value": "bar",
+	})
+	resp = testNamespaceSuccess(t, c, token, "", logical.ReadOperation, "ns1/secret/foo", nil)
+	if resp == nil || resp.Data["value"] != "bar" {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// The token can't access the root namespace
+	resp, err := testNamespaceRequest(c, token, "", logical.ReadOperation, "secret/foo", nil)
+	if err == nil || !errwrap.Contains(err, logical.ErrPermissionDenied.Error()) {
+		t.Fatalf("expected permission denied, got resp: %#v, err: %v", resp, err)
+	}
+	resp, err = testNamespaceRequest(c, token, "", logical.ReadOperation, "sys/policy/default", nil)
+	if err == nil || !errwrap.Contains(err, logical.ErrPermissionDenied.Error()) {
+		t.Fatalf("expected permission denied, got resp: %#v, err: %v", resp, err)
+	}
+
+	// Nor look up the tokens of the root namespace
+	resp, err = testNamespaceRequest(c, token, "ns1", logical.UpdateOperation, "auth/token/lookup", map[string]interface{}{
+		"token": root,
+	})
+	if err == nil && (resp == nil || !resp.IsError()) {
+		t.Fatalf("expected an error, got %#v", resp)
+	}
+
+	// The capabilities are those of the namespace of the token
+	resp = testNamespaceSuccess(t, c, root, "", logical.UpdateOperation, "sys/capabilities", map[string]interface{}{
+		"token": token,
+		"path":  "ns1/secret/foo",
+	})
+	if !reflect.DeepEqual(resp.Data["capabilities"], []string{"create", "read", "update"}) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	resp = testNamespaceSuccess(t, c, root, "", logical.UpdateOperation, "sys/capabilities", map[string]interface{}{
+		"token": token,
+		"path":  "secret/foo",
+	})
+	if !reflect.DeepEqual(resp.Data["capabilities"], []string{"deny"}) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// The tokens of the namespace are no longer valid once it is deleted
+	testNamespaceSuccess(t, c, root, "ns1", logical.DeleteOperation, "sys/mounts/secret", nil)
+	testNamespaceSuccess(t, c, root, "", logical.DeleteOperation, "sys/namespaces/ns1", nil)
+	testNamespaceSuccess(t, c, root, "", logical.UpdateOperation, "sys/namespaces/ns1", nil)
+	resp, err = testNamespaceRequest(c, token, "ns1", logical.ReadOperation, "auth/token/lookup-self", nil)
+	if err == nil || !errwrap.Contains(err, logical.ErrPermissionDenied.Error()) {
+		t.Fatalf("expected permission denied, got resp: %#v, err: %v", resp, err)
+	}
+}
+
+func TestNamespaces_hierarchy(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	testNamespaceSuccess(t, c, root, "", logical.UpdateOperation, "sys/namespaces/ns1", nil)
+
+	// An administrator of a namespace manages its child namespaces
+	admin := testNamespaceToken(t, c, root, "ns1", `
+path "sys/namespaces/*" { capabilities = ["create", "read", "update", "delete", "list"] }
+path "ns2/sys/mounts/*" { capabilities = ["create", "read", "update", "delete"] }
+path "ns2/secret/*" { capabilities = ["create", "read", "update"] }
+`)
+	resp := testNamespaceSuccess(t, c, admin, "ns1", logical.UpdateOperation, "sys/namespaces/ns2", nil)
+	if resp.Data["path"] != "ns1/ns2/" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	resp, err := testNamespaceRequest(c, admin, "", logical.UpdateOperation, "sys/namespaces/other", nil)
+	if err == nil || !errwrap.Contains(err, logical.ErrPermissionDenied.Error()) {
+		t.Fatalf("expected permission denied, got resp: %#v, err: %v", resp, err)
+	}
+
+	resp = testNamespaceSuccess(t, c, root, "", logical.ListOperation, "sys/namespaces/", nil)
+	if !reflect.DeepEqual(resp.Data["keys"], []string{"ns1/"}) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	resp = testNamespaceSuccess(t, c, admin, "ns1", logical.ListOperation, "sys/namespaces/", nil)
+	if !reflect.DeepEqual(resp.Data["keys"], []string{"ns2/"}) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// The policies of the parent namespace apply to the child namespaces,
+	// at paths relative to the parent
+	testNamespaceSuccess(t, c, admin, "ns1/ns2", logical.UpdateOperation, "sys/mounts/secret", map[string]interface{}{
+		"type": "kv",
+	})
+	testNamespaceSuccess(t, c, admin, "ns1/ns2", logical.UpdateOperation, "secret/foo", map[string]interface{}{
+		"value": "bar",
+	})
+	resp = testNamespaceSuccess(t, c, admin, "ns1", logical.ReadOperation, "ns2/secret/foo", nil)
+	if resp == nil || resp.Data["value"] != "bar" {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// The tokens of the child namespaces can't access their parents
+	child := testNamespaceToken(t, c, root, "ns1/ns2", `path "*" { capabilities = ["sudo", "create", "read", "update", "delete", "list"] }`)
+	resp, err = testNamespaceRequest(c, child, "ns1", logical.ReadOperation, "sys/mounts", nil)
+	if err == nil || !errwrap.Contains(err, logical.ErrPermissionDenied.Error()) {
+		t.Fatalf("expected permission denied, got resp: %#v, err: %v", resp, err)
+	}
+	testNamespaceSuccess(t, c, child, "ns1/ns2", logical.ReadOperation, "secret/foo", nil)
+
+	// Namespaces with children can't be deleted
+	resp, err = testNamespaceRequest(c, root, "", logical.DeleteOperation, "sys/namespaces/ns1", nil)
+	if err == nil {
+		t.Fatalf("expected an error, got %#v", resp)
+	}
+}
+
+func TestNamespaces_identity(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	testNamespaceSuccess(t, c, root, "", logical.UpdateOperation, "sys/namespaces/ns1", nil)
+
+	// Entity and group names are unique per namespace
+	rootEntity := testNamespaceSuccess(t, c, root, "", logical.UpdateOperation, "identity/entity", map[string]interface{}{
+		"name": "alice",
+	}).Data["id"].(string)
+	nsEntity := testNamespaceSuccess(t, c, root, "ns1", logical.UpdateOperation, "identity/entity", map[string]interface{}{
+		"name": "alice",
+	}).Data["id"].(string)
+	testNamespaceSuccess(t, c, root, "ns1", logical.UpdateOperation, "identity/group", map[string]interface{}{
+		"name":              "admins",
+		"member_entity_ids": []string{nsEntity},
+	})
+
+	// Entities of other namespaces are neither listed nor readable
+	resp := testNamespaceSuccess(t, c, root, "ns1", logical.ListOperation, "identity/entity/id", nil)
+	if !reflect.DeepEqual(resp.Data["keys"], []string{nsEntity}) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	resp = testNamespaceSuccess(t, c, root, "ns1", logical.ReadOperation, "identity/entity/id/"+rootEntity, nil)
+	if resp != nil {
+		t.Fatalf("bad: %#v", resp)
+	}
+	resp, err := testNamespaceRequest(c, root, "", logical.UpdateOperation, "identity/lookup/group", map[string]interface{}{
+		"type":       "by_name",
+		"group_name": "admins",
+	})
+	if err == nil {
+		t.Fatalf("expected an error, got %#v", resp)
+	}
+
+	// Nor can they be members of the groups of the namespace
+	resp, err = testNamespaceRequest(c, root, "ns1", logical.UpdateOperation, "identity/group", map[string]interface{}{
+		"name":              "others",
+		"member_entity_ids": []string{rootEntity},
+	})
+	if err == nil {
+		t.Fatalf("expected an error, got %#v", resp)
+	}
+
+	// The identities of the namespace are deleted along with it
+	testNamespaceSuccess(t, c, root, "", logical.DeleteOperation, "sys/namespaces/ns1", nil)
+	entity, err := c.identityStore.memDBEntityByID(nsEntity, false)
+	if err != nil || entity != nil {
+		t.Fatalf("bad: entity: %#v, err: %v", entity, err)
+	}
+	entity, err = c.identityStore.memDBEntityByID(rootEntity, false)
+	if err != nil || entity == nil {
+		t.Fatalf("bad: entity: %#v, err: %v", entity, err)
+	}
+}
@@ -0,0 +1,70 @@
+package vault
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/vault/helper/passwordpolicy"
+	"github.com/hashicorp/vault/logical"
+)
+
+const (
+	// passwordPolicySubPath is the sub-path of the system barrier view where
+	// the password policies are stored
+	passwordPolicySubPath = "password-policy/"
+)
+
+// PasswordPolicy returns the password policy of the given name, or nil if it
+// doesn't exist
+func (c *Core) PasswordPolicy(name string) (*passwordpolicy.Policy, error) {
+	view := c.systemBarrierView.SubView(passwordPolicySubPath)
+	entry, err := view.Get(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read password policy: %v", err)
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var policy passwordpolicy.Policy
+	if err := entry.DecodeJSON(&policy); err != nil {
+		return nil, fmt.Errorf("failed to decode password policy: %v", err)
+	}
+	return &policy, nil
+}
+
+// SetPasswordPolicy validates and stores a password policy
+func (c *Core) SetPasswordPolicy(policy *passwordpolicy.Policy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+
+	entry, err := logical.StorageEntryJSON(policy.Name, policy)
+	if err != nil {
+		return fmt.Errorf("failed to create password policy entry: %v", err)
+	}
+
+	view := c.systemBarrierView.SubView(passwordPolicySubPath)
+	if err := view.Put(entry); err != nil {
+		return fmt.Errorf("failed to save password policy: %v", err)
+	}
+	return nil
+}
+
+// DeletePasswordPolicy deletes the password policy of the given name
+func (c *Core) DeletePasswordPolicy(name string) error {
+	view := c.systemBarrierView.SubView(passwordPolicySubPath)
+	if err := view.Delete(name); err != nil {
+		return fmt.Errorf("failed to delete password policy: %v", err)
+	}
+	return nil
+}
+
+// ListPasswordPolicies returns the names of the password policies
+func (c *Core) ListPasswordPolicies() ([]string, error) {
+	view := c.systemBarrierView.SubView(passwordPolicySubPath)
+	names, err := view.List("")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list password policies: %v", err)
+	}
+	return names, nil
+}
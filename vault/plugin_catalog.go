@@ -5,7 +5,9 @@ import (
 	"errors"
 	"fmt"
 	"path/filepath"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 
@@ -18,24 +20,35 @@ import (
 
 var (
 	pluginCatalogPath         = "core/plugin-catalog/"
+	pluginCatalogVersionsPath = "core/plugin-catalog-versions/"
 	ErrDirectoryNotConfigured = errors.New("could not set plugin, plugin directory is not configured")
 	ErrPluginNotFound         = errors.New("plugin not found in the catalog")
+	ErrInvalidPluginVersion   = errors.New("plugin versions must be of the form MAJOR.MINOR.PATCH, optionally prefixed with 'v' and followed by a pre-release suffix")
 )
 
+// pluginVersionRegex matches the semantic versions the plugins can be
+// registered with
+var pluginVersionRegex = regexp.MustCompile(`^v?(\d+)\.(\d+)\.(\d+)(-[0-9A-Za-z.-]+)?$`)
+
 // PluginCatalog keeps a record of plugins known to vault. External plugins need
 // to be registered to the catalog before they can be used in backends. Builtin
-// plugins are automatically detected and included in the catalog.
+// plugins are automatically detected and included in the catalog. External
+// plugins can also be registered under several versions, which mounts can be
+// pinned to; these are stored apart from the unversioned entries, keyed by the
+// name of the plugin and its version.
 type PluginCatalog struct {
-	catalogView *BarrierView
-	directory   string
+	catalogView  *BarrierView
+	versionsView *BarrierView
+	directory    string
 
 	lock sync.RWMutex
 }
 
 func (c *Core) setupPluginCatalog() error {
 	c.pluginCatalog = &PluginCatalog{
-		catalogView: NewBarrierView(c.barrier, pluginCatalogPath),
-		directory:   c.pluginDirectory,
+		catalogView:  NewBarrierView(c.barrier, pluginCatalogPath),
+		versionsView: NewBarrierView(c.barrier, pluginCatalogVersionsPath),
+		directory:    c.pluginDirectory,
 	}
 
 	if c.logger.IsInfo() {
@@ -83,9 +96,50 @@ func (c *PluginCatalog) Get(name string) (*pluginutil.PluginRunner, error) {
 	return nil, nil
 }
 
+// GetVersion retrieves the given version of a plugin from the catalog, or the
+// plugin returned by Get if the version is empty. Builtin plugins have no
+// versions. It returns nil if the version was not registered.
+func (c *PluginCatalog) GetVersion(name, version string) (*pluginutil.PluginRunner, error) {
+	if version == "" {
+		return c.Get(name)
+	}
+
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	if c.directory == "" {
+		return nil, nil
+	}
+
+	out, err := c.versionsView.Get(pluginVersionKey(name, version))
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve plugin \"%s\" version \"%s\": %v", name, version, err)
+	}
+	if out == nil {
+		return nil, nil
+	}
+
+	entry := new(pluginutil.PluginRunner)
+	if err := jsonutil.DecodeJSON(out.Value, entry); err != nil {
+		return nil, fmt.Errorf("failed to decode plugin entry: %v", err)
+	}
+
+	// prepend the plugin directory to the command
+	entry.Command = filepath.Join(c.directory, entry.Command)
+
+	return entry, nil
+}
+
 // Set registers a new external plugin with the catalog, or updates an existing
 // external plugin. It takes the name, command and SHA256 of the plugin.
 func (c *PluginCatalog) Set(name, command string, sha256 []byte) error {
+	return c.SetVersion(name, "", command, sha256)
+}
+
+// SetVersion registers the given version of an external plugin with the
+// catalog, or updates it. An empty version registers the unversioned entry of
+// the plugin, like Set.
+func (c *PluginCatalog) SetVersion(name, version, command string, sha256 []byte) error {
 	if c.directory == "" {
 		return ErrDirectoryNotConfigured
 	}
@@ -97,6 +151,10 @@ func (c *PluginCatalog) Set(name, command string, sha256 []byte) error {
 		return consts.ErrPathContainsParentReferences
 	}
 
+	if version != "" && !pluginVersionRegex.MatchString(version) {
+		return ErrInvalidPluginVersion
+	}
+
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
@@ -124,6 +182,7 @@ func (c *PluginCatalog) Set(name, command string, sha256 []byte) error {
 		Args:    parts[1:],
 		Sha256:  sha256,
 		Builtin: false,
+		Version: version,
 	}
 
 	buf, err := json.Marshal(entry)
@@ -131,11 +190,16 @@ func (c *PluginCatalog) Set(name, command string, sha256 []byte) error {
 		return fmt.Errorf("failed to encode plugin entry: %v", err)
 	}
 
+	view := c.catalogView
 	logicalEntry := logical.StorageEntry{
 		Key:   name,
 		Value: buf,
 	}
-	if err := c.catalogView.Put(&logicalEntry); err != nil {
+	if version != "" {
+		view = c.versionsView
+		logicalEntry.Key = pluginVersionKey(name, version)
+	}
+	if err := view.Put(&logicalEntry); err != nil {
 		return fmt.Errorf("failed to persist plugin entry: %v", err)
 	}
 	return nil
@@ -144,10 +208,81 @@ func (c *PluginCatalog) Set(name, command string, sha256 []byte) error {
 // Delete is used to remove an external plugin from the catalog. Builtin plugins
 // can not be deleted.
 func (c *PluginCatalog) Delete(name string) error {
+	return c.DeleteVersion(name, "")
+}
+
+// DeleteVersion removes the given version of an external plugin from the
+// catalog, or its unversioned entry if the version is empty.
+func (c *PluginCatalog) DeleteVersion(name, version string) error {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
-	return c.catalogView.Delete(name)
+	if version == "" {
+		return c.catalogView.Delete(name)
+	}
+	return c.versionsView.Delete(pluginVersionKey(name, version))
+}
+
+// ListVersions returns the versions registered for the given plugin, from the
+// oldest to the newest.
+func (c *PluginCatalog) ListVersions(name string) ([]string, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	keys, err := c.versionsView.List(name + "/")
+	if err != nil {
+		return nil, err
+	}
+
+	// Skip the versions of the plugins whose names are nested under this
+	// one
+	versions := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if !strings.HasSuffix(key, "/") {
+			versions = append(versions, key)
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return comparePluginVersions(versions[i], versions[j]) < 0
+	})
+
+	return versions, nil
+}
+
+func pluginVersionKey(name, version string) string {
+	return name + "/" + version
+}
+
+// comparePluginVersions compares two plugin versions by their numeric
+// components first; versions with a pre-release suffix come before the
+// release they precede.
+func comparePluginVersions(a, b string) int {
+	ma := pluginVersionRegex.FindStringSubmatch(a)
+	mb := pluginVersionRegex.FindStringSubmatch(b)
+	if ma == nil || mb == nil {
+		return strings.Compare(a, b)
+	}
+
+	for i := 1; i <= 3; i++ {
+		na, _ := strconv.Atoi(ma[i])
+		nb, _ := strconv.Atoi(mb[i])
+		if na != nb {
+			if na < nb {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	switch {
+	case ma[4] == mb[4]:
+		return 0
+	case ma[4] == "":
+		return 1
+	case mb[4] == "":
+		return -1
+	}
+	return strings.Compare(ma[4], mb[4])
 }
 
 // List returns a list of all the known plugin names. If an external and builtin
@@ -174,3 +174,84 @@ func TestPluginCatalog_List(t *testing.T) {
 	}
 
 }
+
+func TestPluginCatalog_Versions(t *testing.T) {
+	core, _, _ := TestCoreUnsealed(t)
+
+	sym, err := filepath.EvalSymlinks(os.TempDir())
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	core.pluginCatalog.directory = sym
+
+	file, err := ioutil.TempFile(os.TempDir(), "temp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	command := fmt.Sprintf("%s --test", filepath.Base(file.Name()))
+
+	err = core.pluginCatalog.SetVersion("my-plugin", "latest", command, []byte{'1'})
+	if err != ErrInvalidPluginVersion {
+		t.Fatalf("expected an invalid version error, got %v", err)
+	}
+
+	for _, version := range []string{"1.10.0", "v1.2.0", "1.2.0-beta1"} {
+		err = core.pluginCatalog.SetVersion("my-plugin", version, command, []byte(version))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Versions of plugins nested under the name are not listed
+	err = core.pluginCatalog.SetVersion("my-plugin/nested", "1.0.0", command, []byte{'1'})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	versions, err := core.pluginCatalog.ListVersions("my-plugin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedVersions := []string{"1.2.0-beta1", "v1.2.0", "1.10.0"}
+	if !reflect.DeepEqual(versions, expectedVersions) {
+		t.Fatalf("expected did not match actual, got %#v\n expected %#v\n", versions, expectedVersions)
+	}
+
+	p, err := core.pluginCatalog.GetVersion("my-plugin", "v1.2.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &pluginutil.PluginRunner{
+		Name:    "my-plugin",
+		Command: filepath.Join(sym, filepath.Base(file.Name())),
+		Args:    []string{"--test"},
+		Sha256:  []byte("v1.2.0"),
+		Version: "v1.2.0",
+	}
+	if !reflect.DeepEqual(p, expected) {
+		t.Fatalf("expected did not match actual, got %#v\n expected %#v\n", p, expected)
+	}
+
+	// The plugin has no unversioned entry
+	p, err = core.pluginCatalog.Get("my-plugin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p != nil {
+		t.Fatalf("expected no unversioned entry, got %#v", p)
+	}
+
+	err = core.pluginCatalog.DeleteVersion("my-plugin", "v1.2.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err = core.pluginCatalog.GetVersion("my-plugin", "v1.2.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p != nil {
+		t.Fatalf("expected the version to be deleted, got %#v", p)
+	}
+}
@@ -0,0 +1,305 @@
+package vault
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	uuid "github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/helper/pluginutil"
+	log "github.com/mgutz/logxi/v1"
+)
+
+const (
+	// defaultPluginCheckInterval is how often the plugin processes are
+	// probed
+	defaultPluginCheckInterval = 10 * time.Second
+
+	// defaultPluginInitialBackoff is the delay before retrying the first
+	// failed restart of a plugin process
+	defaultPluginInitialBackoff = time.Second
+
+	// defaultPluginMaxBackoff caps the delay between the restarts of a plugin
+	// process
+	defaultPluginMaxBackoff = time.Minute
+
+	// defaultPluginMaxRestartAttempts is the number of consecutive failed
+	// restarts after which a plugin process is given up on
+	defaultPluginMaxRestartAttempts = 10
+)
+
+const (
+	pluginStateRunning    = "running"
+	pluginStateRestarting = "restarting"
+	pluginStateFailed     = "failed"
+)
+
+// PluginRestartConfig configures the probing of the external plugin processes
+// and the restart of the ones which crashed or hang
+type PluginRestartConfig struct {
+	// CheckInterval is how often the processes are probed. A process which
+	// doesn't answer within the interval is restarted.
+	CheckInterval time.Duration `json:"check_interval" structs:"check_interval" mapstructure:"check_interval"`
+
+	// InitialBackoff is the delay before retrying a failed restart. The
+	// delay doubles with every failed attempt up to MaxBackoff.
+	InitialBackoff time.Duration `json:"initial_backoff" structs:"initial_backoff" mapstructure:"initial_backoff"`
+
+	// MaxBackoff caps the delay between two restart attempts
+	MaxBackoff time.Duration `json:"max_backoff" structs:"max_backoff" mapstructure:"max_backoff"`
+
+	// MaxAttempts is the number of consecutive failed restarts after which
+	// the process is marked as failed. It is no longer restarted until it
+	// is reloaded.
+	MaxAttempts int `json:"max_attempts" structs:"max_attempts" mapstructure:"max_attempts"`
+}
+
+// pluginRestartConfig returns the restart policy of a core configuration,
+// with defaults filled in
+func pluginRestartConfig(conf *PluginRestartConfig) *PluginRestartConfig {
+	result := &PluginRestartConfig{}
+	if conf != nil {
+		*result = *conf
+	}
+	if result.CheckInterval <= 0 {
+		result.CheckInterval = defaultPluginCheckInterval
+	}
+	if result.InitialBackoff <= 0 {
+		result.InitialBackoff = defaultPluginInitialBackoff
+	}
+	if result.MaxBackoff <= 0 {
+		result.MaxBackoff = defaultPluginMaxBackoff
+	}
+	if result.MaxBackoff < result.InitialBackoff {
+		result.MaxBackoff = result.InitialBackoff
+	}
+	if result.MaxAttempts <= 0 {
+		result.MaxAttempts = defaultPluginMaxRestartAttempts
+	}
+	return result
+}
+
+// PluginStatus is the health of a monitored plugin process
+type PluginStatus struct {
+	Name          string    `json:"name" structs:"name" mapstructure:"name"`
+	Path          string    `json:"path" structs:"path" mapstructure:"path"`
+	Instance      string    `json:"instance" structs:"instance" mapstructure:"instance"`
+	State         string    `json:"state" structs:"state" mapstructure:"state"`
+	Restarts      int       `json:"restarts" structs:"restarts" mapstructure:"restarts"`
+	LastError     string    `json:"last_error" structs:"last_error" mapstructure:"last_error"`
+	LastCheckTime time.Time `json:"last_check_time" structs:"last_check_time" mapstructure:"last_check_time"`
+}
+
+// pluginMonitor probes the plugin processes registered by the backends and
+// restarts the ones which stop answering
+type pluginMonitor struct {
+	config *PluginRestartConfig
+	logger log.Logger
+
+	l       sync.RWMutex
+	plugins map[string]*monitoredPlugin
+}
+
+// monitoredPlugin is a plugin process registered with the monitor
+type monitoredPlugin struct {
+	*pluginutil.MonitoredPlugin
+	path   string
+	stopCh chan struct{}
+
+	// stopOnce guards stopCh, which is closed either when the backend stops
+	// running the process or when the monitor stops
+	stopOnce sync.Once
+
+	l      sync.RWMutex
+	status PluginStatus
+}
+
+// stop stops probing the plugin process
+func (p *monitoredPlugin) stop() {
+	p.stopOnce.Do(func() {
+		close(p.stopCh)
+	})
+}
+
+func newPluginMonitor(conf *PluginRestartConfig, logger log.Logger) *pluginMonitor {
+	return &pluginMonitor{
+		config:  pluginRestartConfig(conf),
+		logger:  logger,
+		plugins: make(map[string]*monitoredPlugin),
+	}
+}
+
+// monitor starts probing a plugin process run by the backend mounted at the
+// given path
+func (m *pluginMonitor) monitor(path string, p *pluginutil.MonitoredPlugin) func() {
+	id, err := uuid.GenerateUUID()
+	if err != nil {
+		// Not monitoring the process only disables the automatic restarts
+		m.logger.Error("core: failed to monitor plugin process", "plugin", p.Name, "path", path, "error", err)
+		return func() {}
+	}
+
+	mp := &monitoredPlugin{
+		MonitoredPlugin: p,
+		path:            path,
+		stopCh:          make(chan struct{}),
+		status: PluginStatus{
+			Name:          p.Name,
+			Path:          path,
+			Instance:      p.Instance,
+			State:         pluginStateRunning,
+			LastCheckTime: time.Now(),
+		},
+	}
+
+	m.l.Lock()
+	m.plugins[id] = mp
+	m.l.Unlock()
+
+	go m.run(mp)
+
+	return func() {
+		m.l.Lock()
+		delete(m.plugins, id)
+		m.l.Unlock()
+		mp.stop()
+	}
+}
+
+// stopAll stops monitoring all the processes
+func (m *pluginMonitor) stopAll() {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	for id, p := range m.plugins {
+		p.stop()
+		delete(m.plugins, id)
+	}
+}
+
+// statuses returns the health of the monitored processes, sorted by path
+// and instance
+func (m *pluginMonitor) statuses() []PluginStatus {
+	m.l.RLock()
+	result := make([]PluginStatus, 0, len(m.plugins))
+	for _, p := range m.plugins {
+		p.l.RLock()
+		result = append(result, p.status)
+		p.l.RUnlock()
+	}
+	m.l.RUnlock()
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Path != result[j].Path {
+			return result[i].Path < result[j].Path
+		}
+		return result[i].Instance < result[j].Instance
+	})
+	return result
+}
+
+func (m *pluginMonitor) run(p *monitoredPlugin) {
+	ticker := time.NewTicker(m.config.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stopCh:
+			return
+		case <-ticker.C:
+		}
+
+		err := m.ping(p)
+
+		p.l.Lock()
+		p.status.LastCheckTime = time.Now()
+		failed := p.status.State == pluginStateFailed
+		switch {
+		case err == nil:
+			// A process given up on answers again once it is reloaded
+			p.status.State = pluginStateRunning
+		case failed:
+			p.status.LastError = err.Error()
+		}
+		p.l.Unlock()
+
+		if err == nil || failed {
+			continue
+		}
+
+		m.logger.Warn("core: plugin process is not answering, restarting it", "plugin", p.Name, "path", p.path, "instance", p.Instance, "error", err)
+		m.restart(p, err)
+	}
+}
+
+// ping probes the process, giving up once the check interval elapsed so
+// that a process which hangs is restarted too
+func (m *pluginMonitor) ping(p *monitoredPlugin) error {
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- p.Ping()
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-time.After(m.config.CheckInterval):
+		return fmt.Errorf("plugin process did not answer within %s", m.config.CheckInterval)
+	}
+}
+
+// restart replaces the process, backing off exponentially between the failed
+// attempts
+func (m *pluginMonitor) restart(p *monitoredPlugin, err error) {
+	backoff := m.config.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		p.l.Lock()
+		p.status.State = pluginStateRestarting
+		p.status.LastError = err.Error()
+		p.l.Unlock()
+
+		err = p.Restart()
+		if err == nil {
+			p.l.Lock()
+			p.status.State = pluginStateRunning
+			p.status.Restarts++
+			p.l.Unlock()
+
+			m.logger.Info("core: restarted plugin process", "plugin", p.Name, "path", p.path, "instance", p.Instance)
+			return
+		}
+
+		m.logger.Error("core: failed to restart plugin process", "plugin", p.Name, "path", p.path, "instance", p.Instance, "attempt", attempt, "error", err)
+
+		if attempt >= m.config.MaxAttempts {
+			p.l.Lock()
+			p.status.State = pluginStateFailed
+			p.status.LastError = err.Error()
+			p.l.Unlock()
+
+			m.logger.Error("core: giving up on plugin process, reload it to restart it", "plugin", p.Name, "path", p.path, "instance", p.Instance)
+			return
+		}
+
+		select {
+		case <-p.stopCh:
+			return
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > m.config.MaxBackoff {
+			backoff = m.config.MaxBackoff
+		}
+	}
+}
+
+// MonitorPlugin implements pluginutil.HealthMonitor
+func (d dynamicSystemView) MonitorPlugin(p *pluginutil.MonitoredPlugin) func() {
+	path := d.mountEntry.Path
+	if d.mountEntry.Table == credentialTableType {
+		path = credentialRoutePrefix + path
+	}
+	return d.core.pluginMonitor.monitor(path, p)
+}
@@ -0,0 +1,159 @@
+package vault
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/helper/logformat"
+	"github.com/hashicorp/vault/helper/pluginutil"
+	log "github.com/mgutz/logxi/v1"
+)
+
+func TestPluginRestartConfig_defaults(t *testing.T) {
+	conf := pluginRestartConfig(nil)
+	if conf.CheckInterval != defaultPluginCheckInterval ||
+		conf.InitialBackoff != defaultPluginInitialBackoff ||
+		conf.MaxBackoff != defaultPluginMaxBackoff ||
+		conf.MaxAttempts != defaultPluginMaxRestartAttempts {
+		t.Fatalf("bad: %#v", conf)
+	}
+
+	conf = pluginRestartConfig(&PluginRestartConfig{
+		InitialBackoff: 2 * time.Minute,
+	})
+	if conf.MaxBackoff != 2*time.Minute {
+		t.Fatalf("bad: max backoff %s below the initial backoff", conf.MaxBackoff)
+	}
+}
+
+// testMonitoredPlugin is a plugin process whose liveness is controlled by
+// the test
+type testMonitoredPlugin struct {
+	sync.Mutex
+	alive        bool
+	restarts     int
+	restartFails int
+}
+
+func (p *testMonitoredPlugin) ping() error {
+	p.Lock()
+	defer p.Unlock()
+	if !p.alive {
+		return pluginutil.ErrPluginExited
+	}
+	return nil
+}
+
+func (p *testMonitoredPlugin) restart() error {
+	p.Lock()
+	defer p.Unlock()
+	p.restarts++
+	if p.restartFails > 0 {
+		p.restartFails--
+		return errors.New("restart failed")
+	}
+	p.alive = true
+	return nil
+}
+
+func (p *testMonitoredPlugin) kill(restartFails int) {
+	p.Lock()
+	defer p.Unlock()
+	p.alive = false
+	p.restartFails = restartFails
+}
+
+func testPluginMonitor(t *testing.T, maxAttempts int) (*pluginMonitor, *testMonitoredPlugin, func()) {
+	m := newPluginMonitor(&PluginRestartConfig{
+		CheckInterval:  10 * time.Millisecond,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     5 * time.Millisecond,
+		MaxAttempts:    maxAttempts,
+	}, logformat.NewVaultLogger(log.LevelTrace))
+
+	p := &testMonitoredPlugin{alive: true}
+	stop := m.monitor("foo/", &pluginutil.MonitoredPlugin{
+		Name:     "mock-plugin",
+		Instance: "conn",
+		Ping:     p.ping,
+		Restart:  p.restart,
+	})
+	return m, p, stop
+}
+
+func waitPluginState(t *testing.T, m *pluginMonitor, state string, restarts int) PluginStatus {
+	var statuses []PluginStatus
+	for i := 0; i < 200; i++ {
+		statuses = m.statuses()
+		if len(statuses) == 1 && statuses[0].State == state && statuses[0].Restarts == restarts {
+			return statuses[0]
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("bad: %#v, expected state %q after %d restarts", statuses, state, restarts)
+	return PluginStatus{}
+}
+
+func TestPluginMonitor_restart(t *testing.T) {
+	m, p, stop := testPluginMonitor(t, 5)
+	defer stop()
+
+	status := waitPluginState(t, m, pluginStateRunning, 0)
+	if status.Name != "mock-plugin" || status.Path != "foo/" || status.Instance != "conn" {
+		t.Fatalf("bad: %#v", status)
+	}
+
+	// The process crashes and its first two restarts fail
+	p.kill(2)
+	status = waitPluginState(t, m, pluginStateRunning, 1)
+	if status.LastError != "restart failed" {
+		t.Fatalf("bad: %#v", status)
+	}
+
+	p.Lock()
+	defer p.Unlock()
+	if p.restarts != 3 {
+		t.Fatalf("bad: %d restart attempts", p.restarts)
+	}
+}
+
+func TestPluginMonitor_failed(t *testing.T) {
+	m, p, stop := testPluginMonitor(t, 2)
+	defer stop()
+
+	// The restarts are given up on after the maximum number of attempts
+	p.kill(10)
+	waitPluginState(t, m, pluginStateFailed, 0)
+
+	time.Sleep(50 * time.Millisecond)
+	p.Lock()
+	if p.restarts != 2 {
+		t.Fatalf("bad: %d restart attempts", p.restarts)
+	}
+	p.Unlock()
+
+	// The process answers again once it is reloaded
+	p.Lock()
+	p.alive = true
+	p.Unlock()
+	waitPluginState(t, m, pluginStateRunning, 0)
+
+	stop()
+	if statuses := m.statuses(); len(statuses) != 0 {
+		t.Fatalf("bad: %#v", statuses)
+	}
+}
+
+func TestPluginMonitor_stopAll(t *testing.T) {
+	m, _, stop := testPluginMonitor(t, 1)
+	waitPluginState(t, m, pluginStateRunning, 0)
+
+	// The backend may stop running the process after the monitor stopped
+	m.stopAll()
+	stop()
+	if statuses := m.statuses(); len(statuses) != 0 {
+		t.Fatalf("bad: %#v", statuses)
+	}
+}
@@ -1,6 +1,7 @@
 package vault
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 
@@ -77,25 +78,56 @@ func (c *Core) reloadMatchingPlugin(pluginName string) error {
 // MountEntry. entry.Type should be checked by the caller to ensure that
 // it's a "plugin" type.
 func (c *Core) reloadPluginCommon(entry *MountEntry, isAuth bool) error {
-	path := entry.Path
+	re := c.pluginRouteEntry(entry, isAuth)
 
 	// Fast-path out if the backend doesn't exist
-	raw, ok := c.router.root.Get(path)
-	if !ok {
+	if re == nil {
 		return nil
 	}
 
 	// Call backend's Cleanup routine
-	re := raw.(*routeEntry)
 	re.backend.Cleanup()
 
-	view := re.storageView
+	backend, err := c.newPluginBackend(entry, isAuth, re.storageView)
+	if err != nil {
+		return err
+	}
+
+	// Set the backend back
+	re.backend = backend
+
+	return nil
+}
+
+// pluginRouteEntry returns the route entry of a mount, or nil if it is not
+// mounted
+func (c *Core) pluginRouteEntry(entry *MountEntry, isAuth bool) *routeEntry {
+	path := entry.Path
+	if isAuth {
+		path = credentialRoutePrefix + path
+	}
+
+	raw, ok := c.router.root.Get(path)
+	if !ok {
+		return nil
+	}
+	return raw.(*routeEntry)
+}
 
+// newPluginBackend creates and initializes the backend of a plugin mount,
+// running the version of the plugin the mount is pinned to
+func (c *Core) newPluginBackend(entry *MountEntry, isAuth bool, view *BarrierView) (logical.Backend, error) {
 	sysView := c.mountEntrySysView(entry)
 	conf := make(map[string]string)
+	for k, v := range entry.Options {
+		conf[k] = v
+	}
 	if entry.Config.PluginName != "" {
 		conf["plugin_name"] = entry.Config.PluginName
 	}
+	if entry.Config.PluginVersion != "" {
+		conf["plugin_version"] = entry.Config.PluginVersion
+	}
 
 	var backend logical.Backend
 	var err error
@@ -106,19 +138,145 @@ func (c *Core) reloadPluginCommon(entry *MountEntry, isAuth bool) error {
 		backend, err = c.newCredentialBackend(entry.Type, sysView, view, conf)
 	}
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if backend == nil {
-		return fmt.Errorf("nil backend of type %q returned from creation function", entry.Type)
+		return nil, fmt.Errorf("nil backend of type %q returned from creation function", entry.Type)
+	}
+
+	// Check for the correct backend type, as the plugin may have changed
+	backendType := backend.Type()
+	switch {
+	case !isAuth && backendType != logical.TypeLogical:
+		backend.Cleanup()
+		return nil, fmt.Errorf("cannot mount '%s' of type '%s' as a logical backend", entry.Config.PluginName, backendType)
+	case isAuth && backendType != logical.TypeCredential:
+		backend.Cleanup()
+		return nil, fmt.Errorf("cannot mount '%s' of type '%s' as an auth backend", entry.Config.PluginName, backendType)
 	}
 
 	// Call initialize; this takes care of init tasks that must be run after
 	// the ignore paths are collected.
 	if err := backend.Initialize(); err != nil {
+		backend.Cleanup()
+		return nil, err
+	}
+
+	return backend, nil
+}
+
+// pluginUpgrade is a plugin mount pinned to a new version of its plugin
+type pluginUpgrade struct {
+	entry           *MountEntry
+	isAuth          bool
+	previousVersion string
+}
+
+// upgradePluginMounts pins the mounts of the plugin pluginName, or the given
+// mounts, to a version of their plugin and reloads them. The backend of a
+// mount is only replaced once the new version has been set up, and if any of
+// the mounts fails to upgrade, the mounts already upgraded are rolled back to
+// their previous version and the mount tables are left untouched.
+func (c *Core) upgradePluginMounts(pluginName string, mounts []string, version string) error {
+	c.mountsLock.Lock()
+	defer c.mountsLock.Unlock()
+	c.authLock.Lock()
+	defer c.authLock.Unlock()
+
+	var upgrades []*pluginUpgrade
+	if pluginName != "" {
+		for _, entry := range c.mounts.Entries {
+			if entry.Config.PluginName == pluginName && entry.Type == "plugin" {
+				upgrades = append(upgrades, &pluginUpgrade{entry: entry})
+			}
+		}
+		for _, entry := range c.auth.Entries {
+			if entry.Config.PluginName == pluginName && entry.Type == "plugin" {
+				upgrades = append(upgrades, &pluginUpgrade{entry: entry, isAuth: true})
+			}
+		}
+		if len(upgrades) == 0 {
+			return fmt.Errorf("no mounts of plugin %s", pluginName)
+		}
+	}
+	for _, mount := range mounts {
+		entry := c.router.MatchingMountEntry(mount)
+		if entry == nil {
+			return fmt.Errorf("cannot fetch mount entry on %s", mount)
+		}
+		if entry.Type != "plugin" {
+			return fmt.Errorf("%s is not a plugin mount", mount)
+		}
+		isAuth := strings.HasPrefix(c.router.MatchingMount(mount), credentialRoutePrefix)
+		upgrades = append(upgrades, &pluginUpgrade{entry: entry, isAuth: isAuth})
+	}
+
+	// A mount listed more than once is upgraded only once, so that its
+	// previous version is kept for the rollback
+	seen := make(map[*MountEntry]bool, len(upgrades))
+	var upgraded []*pluginUpgrade
+	for _, upgrade := range upgrades {
+		if seen[upgrade.entry] {
+			continue
+		}
+		seen[upgrade.entry] = true
+
+		upgrade.previousVersion = upgrade.entry.Config.PluginVersion
+		if err := c.replacePluginBackend(upgrade.entry, upgrade.isAuth, version); err != nil {
+			c.rollbackPluginUpgrades(upgraded)
+			return fmt.Errorf("cannot upgrade plugin on %s to version %s: %v", upgrade.entry.Path, version, err)
+		}
+		upgraded = append(upgraded, upgrade)
+		c.logger.Info("core: successfully upgraded plugin", "plugin", upgrade.entry.Config.PluginName, "path", upgrade.entry.Path, "version", version)
+	}
+
+	// Persist the versions the mounts are now pinned to
+	if err := c.persistMounts(c.mounts, false); err != nil {
+		c.rollbackPluginUpgrades(upgraded)
+		return errors.New("failed to update mount table")
+	}
+	if err := c.persistAuth(c.auth, false); err != nil {
+		c.rollbackPluginUpgrades(upgraded)
+		if err := c.persistMounts(c.mounts, false); err != nil {
+			c.logger.Error("core: failed to restore mount table", "error", err)
+		}
+		return errors.New("failed to update auth table")
+	}
+
+	return nil
+}
+
+// rollbackPluginUpgrades pins the upgraded mounts back to their previous
+// version
+func (c *Core) rollbackPluginUpgrades(upgraded []*pluginUpgrade) {
+	for _, upgrade := range upgraded {
+		if err := c.replacePluginBackend(upgrade.entry, upgrade.isAuth, upgrade.previousVersion); err != nil {
+			c.logger.Error("core: failed to roll plugin back", "path", upgrade.entry.Path, "version", upgrade.previousVersion, "error", err)
+			continue
+		}
+		c.logger.Warn("core: rolled plugin back", "path", upgrade.entry.Path, "version", upgrade.previousVersion)
+	}
+}
+
+// replacePluginBackend pins a mount to a version of its plugin and replaces
+// its backend, keeping the current backend and version if the new backend
+// cannot be set up
+func (c *Core) replacePluginBackend(entry *MountEntry, isAuth bool, version string) error {
+	re := c.pluginRouteEntry(entry, isAuth)
+	if re == nil {
+		return fmt.Errorf("no backend mounted at %s", entry.Path)
+	}
+
+	previousVersion := entry.Config.PluginVersion
+	entry.Config.PluginVersion = version
+
+	backend, err := c.newPluginBackend(entry, isAuth, re.storageView)
+	if err != nil {
+		entry.Config.PluginVersion = previousVersion
 		return err
 	}
 
-	// Set the backend back
+	re.backend.Cleanup()
 	re.backend = backend
 
 	return nil
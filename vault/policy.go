@@ -11,6 +11,7 @@ import (
 	"github.com/hashicorp/hcl"
 	"github.com/hashicorp/hcl/hcl/ast"
 	"github.com/hashicorp/vault/helper/parseutil"
+	"github.com/hashicorp/vault/helper/strutil"
 	"github.com/mitchellh/copystructure"
 )
 
@@ -19,6 +20,7 @@ const (
 	CreateCapability = "create"
 	ReadCapability   = "read"
 	UpdateCapability = "update"
+	PatchCapability  = "patch"
 	DeleteCapability = "delete"
 	ListCapability   = "list"
 	SudoCapability   = "sudo"
@@ -39,6 +41,7 @@ const (
 	DeleteCapabilityInt
 	ListCapabilityInt
 	SudoCapabilityInt
+	PatchCapabilityInt
 )
 
 var (
@@ -47,6 +50,7 @@ var (
 		CreateCapability: CreateCapabilityInt,
 		ReadCapability:   ReadCapabilityInt,
 		UpdateCapability: UpdateCapabilityInt,
+		PatchCapability:  PatchCapabilityInt,
 		DeleteCapability: DeleteCapabilityInt,
 		ListCapability:   ListCapabilityInt,
 		SudoCapability:   SudoCapabilityInt,
@@ -71,10 +75,12 @@ type PathCapabilities struct {
 
 	// These keys are used at the top level to make the HCL nicer; we store in
 	// the Permissions object though
-	MinWrappingTTLHCL    interface{}              `hcl:"min_wrapping_ttl"`
-	MaxWrappingTTLHCL    interface{}              `hcl:"max_wrapping_ttl"`
-	AllowedParametersHCL map[string][]interface{} `hcl:"allowed_parameters"`
-	DeniedParametersHCL  map[string][]interface{} `hcl:"denied_parameters"`
+	MinWrappingTTLHCL     interface{}              `hcl:"min_wrapping_ttl"`
+	MaxWrappingTTLHCL     interface{}              `hcl:"max_wrapping_ttl"`
+	AllowedParametersHCL  map[string][]interface{} `hcl:"allowed_parameters"`
+	DeniedParametersHCL   map[string][]interface{} `hcl:"denied_parameters"`
+	RequiredParametersHCL []string                 `hcl:"required_parameters"`
+	ControlGroupHCL       *ControlGroupHCL         `hcl:"control_group"`
 }
 
 type Permissions struct {
@@ -83,6 +89,8 @@ type Permissions struct {
 	MaxWrappingTTL     time.Duration
 	AllowedParameters  map[string][]interface{}
 	DeniedParameters   map[string][]interface{}
+	RequiredParameters []string
+	ControlGroup       *ControlGroup
 }
 
 func (p *Permissions) Clone() (*Permissions, error) {
@@ -116,6 +124,15 @@ func (p *Permissions) Clone() (*Permissions, error) {
 		ret.DeniedParameters = clonedDenied.(map[string][]interface{})
 	}
 
+	if p.RequiredParameters != nil {
+		ret.RequiredParameters = make([]string, len(p.RequiredParameters))
+		copy(ret.RequiredParameters, p.RequiredParameters)
+	}
+
+	if p.ControlGroup != nil {
+		ret.ControlGroup = p.ControlGroup.Clone()
+	}
+
 	return ret, nil
 }
 
@@ -172,6 +189,8 @@ func parsePaths(result *Policy, list *ast.ObjectList) error {
 			"capabilities",
 			"allowed_parameters",
 			"denied_parameters",
+			"required_parameters",
+			"control_group",
 			"min_wrapping_ttl",
 			"max_wrapping_ttl",
 		}
@@ -208,9 +227,9 @@ func parsePaths(result *Policy, list *ast.ObjectList) error {
 			case OldReadPathPolicy:
 				pc.Capabilities = append(pc.Capabilities, []string{ReadCapability, ListCapability}...)
 			case OldWritePathPolicy:
-				pc.Capabilities = append(pc.Capabilities, []string{CreateCapability, ReadCapability, UpdateCapability, DeleteCapability, ListCapability}...)
+				pc.Capabilities = append(pc.Capabilities, []string{CreateCapability, ReadCapability, UpdateCapability, PatchCapability, DeleteCapability, ListCapability}...)
 			case OldSudoPathPolicy:
-				pc.Capabilities = append(pc.Capabilities, []string{CreateCapability, ReadCapability, UpdateCapability, DeleteCapability, ListCapability, SudoCapability}...)
+				pc.Capabilities = append(pc.Capabilities, []string{CreateCapability, ReadCapability, UpdateCapability, PatchCapability, DeleteCapability, ListCapability, SudoCapability}...)
 			default:
 				return fmt.Errorf("path %q: invalid policy '%s'", key, pc.Policy)
 			}
@@ -225,7 +244,7 @@ func parsePaths(result *Policy, list *ast.ObjectList) error {
 				pc.Capabilities = []string{DenyCapability}
 				pc.Permissions.CapabilitiesBitmap = DenyCapabilityInt
 				goto PathFinished
-			case CreateCapability, ReadCapability, UpdateCapability, DeleteCapability, ListCapability, SudoCapability:
+			case CreateCapability, ReadCapability, UpdateCapability, PatchCapability, DeleteCapability, ListCapability, SudoCapability:
 				pc.Permissions.CapabilitiesBitmap |= cap2Int[cap]
 			default:
 				return fmt.Errorf("path %q: invalid capability '%s'", key, cap)
@@ -245,6 +264,15 @@ func parsePaths(result *Policy, list *ast.ObjectList) error {
 				pc.Permissions.DeniedParameters[strings.ToLower(key)] = val
 			}
 		}
+		if pc.RequiredParametersHCL != nil {
+			pc.Permissions.RequiredParameters = make([]string, 0, len(pc.RequiredParametersHCL))
+			for _, param := range pc.RequiredParametersHCL {
+				if param == "" || param == "*" {
+					return fmt.Errorf("path %q: invalid required parameter %q", key, param)
+				}
+				pc.Permissions.RequiredParameters = strutil.AppendIfMissing(pc.Permissions.RequiredParameters, strings.ToLower(param))
+			}
+		}
 		if pc.MinWrappingTTLHCL != nil {
 			dur, err := parseutil.ParseDurationSecond(pc.MinWrappingTTLHCL)
 			if err != nil {
@@ -264,6 +292,13 @@ func parsePaths(result *Policy, list *ast.ObjectList) error {
 			pc.Permissions.MaxWrappingTTL < pc.Permissions.MinWrappingTTL {
 			return errors.New("max_wrapping_ttl cannot be less than min_wrapping_ttl")
 		}
+		if pc.ControlGroupHCL != nil {
+			cg, err := parseControlGroup(pc.ControlGroupHCL)
+			if err != nil {
+				return multierror.Prefix(err, fmt.Sprintf("path %q:", key))
+			}
+			pc.Permissions.ControlGroup = cg
+		}
 
 	PathFinished:
 		paths = append(paths, &pc)
@@ -1,8 +1,11 @@
 package vault
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/armon/go-metrics"
@@ -21,6 +24,10 @@ const (
 	// policyCacheSize is the number of policies that are kept cached
 	policyCacheSize = 1024
 
+	// aclCacheSize is the number of ACLs, one per distinct set of policies,
+	// that are kept cached
+	aclCacheSize = 1024
+
 	// responseWrappingPolicyName is the name of the fixed policy
 	responseWrappingPolicyName = "response-wrapping"
 
@@ -83,6 +90,11 @@ path "sys/wrapping/wrap" {
     capabilities = ["update"]
 }
 
+# Allow a token to wrap multiple named values in a response-wrapping token
+path "sys/wrapping/wrap-entries" {
+    capabilities = ["update"]
+}
+
 # Allow a token to look up the creation time and TTL of a given
 # response-wrapping token
 path "sys/wrapping/lookup" {
@@ -113,6 +125,14 @@ var (
 type PolicyStore struct {
 	view *BarrierView
 	lru  *lru.TwoQueueCache
+
+	// aclLRU caches the ACLs compiled from sets of policies, keyed by the
+	// hash of the policy names. It is purged whenever a policy changes, and
+	// aclGeneration is incremented so that ACLs compiled concurrently from
+	// the previous policies are not cached.
+	aclLRU        *lru.TwoQueueCache
+	aclGeneration uint64
+	aclLock       sync.RWMutex
 }
 
 // PolicyEntry is used to store a policy by name
@@ -130,6 +150,8 @@ func NewPolicyStore(view *BarrierView, system logical.SystemView) *PolicyStore {
 	if !system.CachingDisabled() {
 		cache, _ := lru.New2Q(policyCacheSize)
 		p.lru = cache
+		aclCache, _ := lru.New2Q(aclCacheSize)
+		p.aclLRU = aclCache
 	}
 
 	return p
@@ -192,6 +214,26 @@ func (ps *PolicyStore) invalidate(name string) {
 
 	// This may come with a prefixed "/" due to joining the file path
 	ps.lru.Remove(strings.TrimPrefix(name, "/"))
+	ps.purgeACLs()
+}
+
+// purgeACLs clears the compiled ACLs after a policy has changed
+func (ps *PolicyStore) purgeACLs() {
+	if ps.aclLRU == nil {
+		return
+	}
+	ps.aclLock.Lock()
+	ps.aclGeneration++
+	ps.aclLRU.Purge()
+	ps.aclLock.Unlock()
+}
+
+// policySetHash returns the key of the ACL cache for the given policy names,
+// which doesn't depend on their order, case or duplicates
+func policySetHash(names []string) string {
+	names = strutil.RemoveDuplicates(names, true)
+	sum := sha256.Sum256([]byte(strings.Join(names, "\n")))
+	return hex.EncodeToString(sum[:])
 }
 
 // SetPolicy is used to create or update the given policy
@@ -226,6 +268,7 @@ func (ps *PolicyStore) setPolicyInternal(p *Policy) error {
 		// Update the LRU cache
 		ps.lru.Add(p.Name, p)
 	}
+	ps.purgeACLs()
 	return nil
 }
 
@@ -343,12 +386,29 @@ func (ps *PolicyStore) DeletePolicy(name string) error {
 		// Clear the cache
 		ps.lru.Remove(name)
 	}
+	ps.purgeACLs()
 	return nil
 }
 
 // ACL is used to return an ACL which is built using the
 // named policies.
 func (ps *PolicyStore) ACL(names ...string) (*ACL, error) {
+	// Check for an ACL compiled from the same policies. ACLs are not modified
+	// once constructed, so they can be shared between requests.
+	var key string
+	var generation uint64
+	if ps.aclLRU != nil {
+		key = policySetHash(names)
+		if raw, ok := ps.aclLRU.Get(key); ok {
+			metrics.IncrCounter([]string{"policy", "acl_cache", "hit"}, 1)
+			return raw.(*ACL), nil
+		}
+		metrics.IncrCounter([]string{"policy", "acl_cache", "miss"}, 1)
+		ps.aclLock.RLock()
+		generation = ps.aclGeneration
+		ps.aclLock.RUnlock()
+	}
+
 	// Fetch the policies
 	var policy []*Policy
 	for _, name := range names {
@@ -364,6 +424,15 @@ func (ps *PolicyStore) ACL(names ...string) (*ACL, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to construct ACL: %v", err)
 	}
+
+	// Only cache the ACL if no policy changed while it was compiled
+	if ps.aclLRU != nil {
+		ps.aclLock.RLock()
+		if ps.aclGeneration == generation {
+			ps.aclLRU.Add(key, acl)
+		}
+		ps.aclLock.RUnlock()
+	}
 	return acl, nil
 }
 
@@ -1,20 +1,21 @@
 package vault
 
 import (
+	"fmt"
 	"reflect"
 	"testing"
 
 	"github.com/hashicorp/vault/logical"
 )
 
-func mockPolicyStore(t *testing.T) *PolicyStore {
+func mockPolicyStore(t testing.TB) *PolicyStore {
 	_, barrier, _ := mockBarrier(t)
 	view := NewBarrierView(barrier, "foo/")
 	p := NewPolicyStore(view, logical.TestSystemView())
 	return p
 }
 
-func mockPolicyStoreNoCache(t *testing.T) *PolicyStore {
+func mockPolicyStoreNoCache(t testing.TB) *PolicyStore {
 	sysView := logical.TestSystemView()
 	sysView.CachingDisabledVal = true
 	_, barrier, _ := mockBarrier(t)
@@ -194,6 +195,81 @@ func TestPolicyStore_ACL(t *testing.T) {
 	testLayeredACL(t, acl)
 }
 
+func TestPolicyStore_ACLCache(t *testing.T) {
+	ps := mockPolicyStore(t)
+
+	policy, _ := Parse(aclPolicy)
+	if err := ps.SetPolicy(policy); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	policy, _ = Parse(aclPolicy2)
+	if err := ps.SetPolicy(policy); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	acl, err := ps.ACL("dev", "ops")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The order, case and duplicates of the names don't matter
+	cached, err := ps.ACL("OPS", "dev", "ops")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if cached != acl {
+		t.Fatalf("expected the ACL to be cached")
+	}
+	testLayeredACL(t, cached)
+
+	other, err := ps.ACL("dev")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if other == acl {
+		t.Fatalf("expected a different ACL")
+	}
+
+	// Writing a policy invalidates the ACLs
+	policy, _ = Parse(tokenCreationPolicy)
+	policy.Name = "ops"
+	if err := ps.SetPolicy(policy); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	updated, err := ps.ACL("dev", "ops")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if updated == acl {
+		t.Fatalf("expected the ACL to be compiled again")
+	}
+	if allowed, _ := updated.AllowOperation(&logical.Request{Operation: logical.UpdateOperation, Path: "auth/token/create"}); !allowed {
+		t.Fatalf("expected the updated policy to be used")
+	}
+
+	// Deleting a policy invalidates the ACLs
+	if err := ps.DeletePolicy("ops"); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	deleted, err := ps.ACL("dev", "ops")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if allowed, _ := deleted.AllowOperation(&logical.Request{Operation: logical.UpdateOperation, Path: "auth/token/create"}); allowed {
+		t.Fatalf("expected the deleted policy to be ignored")
+	}
+
+	// ACLs are not cached when caching is disabled
+	ps = mockPolicyStoreNoCache(t)
+	acl, err = ps.ACL("default")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if cached, _ := ps.ACL("default"); cached == acl {
+		t.Fatalf("expected the ACL not to be cached")
+	}
+}
+
 func TestPolicyStore_v1Upgrade(t *testing.T) {
 	ps := mockPolicyStore(t)
 
@@ -216,3 +292,43 @@ func TestPolicyStore_v1Upgrade(t *testing.T) {
 		t.Fatalf("should enable glob")
 	}
 }
+
+func BenchmarkPolicyStore_ACL(b *testing.B) {
+	benchmarkPolicyStoreACL(b, mockPolicyStore(b))
+}
+
+func BenchmarkPolicyStore_ACL_NoCache(b *testing.B) {
+	benchmarkPolicyStoreACL(b, mockPolicyStoreNoCache(b))
+}
+
+// benchmarkPolicyStoreACL measures the token checks of a request, compiling
+// the ACL of a set of policies and evaluating the request against it
+func benchmarkPolicyStoreACL(b *testing.B, ps *PolicyStore) {
+	var names []string
+	for i, raw := range []string{aclPolicy, aclPolicy2, mergingPolicies, permissionsPolicy, valuePermissionsPolicy} {
+		policy, err := Parse(raw)
+		if err != nil {
+			b.Fatalf("err: %v", err)
+		}
+		policy.Name = fmt.Sprintf("policy%d", i)
+		if err := ps.SetPolicy(policy); err != nil {
+			b.Fatalf("err: %v", err)
+		}
+		names = append(names, policy.Name)
+	}
+
+	req := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "foo/bar",
+		Data:      map[string]interface{}{"zip": "zap"},
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		acl, err := ps.ACL(names...)
+		if err != nil {
+			b.Fatalf("err: %v", err)
+		}
+		acl.AllowOperation(req)
+	}
+}
@@ -85,6 +85,10 @@ path "test/types" {
 		"bool" = [false]
 	}
 }
+path "test/required" {
+	capabilities = ["create", "update"]
+	required_parameters = ["Owner", "team", "owner"]
+}
 `)
 
 func TestPolicy_Parse(t *testing.T) {
@@ -114,12 +118,13 @@ func TestPolicy_Parse(t *testing.T) {
 				"create",
 				"read",
 				"update",
+				"patch",
 				"delete",
 				"list",
 				"sudo",
 			},
 			Permissions: &Permissions{
-				CapabilitiesBitmap: (CreateCapabilityInt | ReadCapabilityInt | UpdateCapabilityInt | DeleteCapabilityInt | ListCapabilityInt | SudoCapabilityInt),
+				CapabilitiesBitmap: (CreateCapabilityInt | ReadCapabilityInt | UpdateCapabilityInt | PatchCapabilityInt | DeleteCapabilityInt | ListCapabilityInt | SudoCapabilityInt),
 			},
 			Glob: true,
 		},
@@ -225,6 +230,20 @@ func TestPolicy_Parse(t *testing.T) {
 			},
 			Glob: false,
 		},
+		&PathCapabilities{
+			Prefix: "test/required",
+			Policy: "",
+			Capabilities: []string{
+				"create",
+				"update",
+			},
+			RequiredParametersHCL: []string{"Owner", "team", "owner"},
+			Permissions: &Permissions{
+				CapabilitiesBitmap: (CreateCapabilityInt | UpdateCapabilityInt),
+				RequiredParameters: []string{"owner", "team"},
+			},
+			Glob: false,
+		},
 	}
 	if !reflect.DeepEqual(p.Paths, expect) {
 		t.Errorf("expected \n\n%#v\n\n to be \n\n%#v\n\n", p.Paths, expect)
@@ -298,6 +317,22 @@ path "/" {
 	}
 }
 
+func TestPolicy_ParseBadRequiredParameters(t *testing.T) {
+	_, err := Parse(strings.TrimSpace(`
+path "/" {
+	capabilities = ["create"]
+	required_parameters = ["*"]
+}
+`))
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+
+	if !strings.Contains(err.Error(), `path "/": invalid required parameter "*"`) {
+		t.Errorf("bad error: %s", err)
+	}
+}
+
 func TestPolicy_ParseBadCapabilities(t *testing.T) {
 	_, err := Parse(strings.TrimSpace(`
 path "/" {
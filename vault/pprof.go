@@ -0,0 +1,70 @@
+package vault
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/pprof"
+	"runtime/trace"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/vault/logical"
+)
+
+const (
+	// maxPprofSeconds is the longest duration of the CPU profiles and the
+	// execution traces returned by sys/pprof
+	maxPprofSeconds = 300
+)
+
+// Pprof returns the named runtime profile of the server in the format of the
+// pprof tool. The CPU profile, named "profile", and the execution trace, named
+// "trace", are sampled for the given number of seconds; debug is passed to
+// the other profiles. The request is authorized before the profile is
+// sampled, which doesn't hold the state lock so that sealing and stepping
+// down aren't blocked by it.
+func (c *Core) Pprof(req *logical.Request, name string, seconds, debug int) ([]byte, error) {
+	defer metrics.MeasureSince([]string{"core", "pprof"}, time.Now())
+
+	if req == nil {
+		return nil, errors.New("nil request to pprof")
+	}
+	if err := c.authorizeRootRequest(req); err != nil {
+		return nil, err
+	}
+
+	if seconds <= 0 || seconds > maxPprofSeconds {
+		return nil, logical.CodedError(http.StatusBadRequest, fmt.Sprintf("seconds must be between 1 and %d", maxPprofSeconds))
+	}
+	duration := time.Duration(seconds) * time.Second
+
+	var buf bytes.Buffer
+	switch name {
+	case "profile":
+		if err := pprof.StartCPUProfile(&buf); err != nil {
+			return nil, logical.CodedError(http.StatusBadRequest, fmt.Sprintf("could not start the CPU profile: %s", err))
+		}
+		time.Sleep(duration)
+		pprof.StopCPUProfile()
+
+	case "trace":
+		if err := trace.Start(&buf); err != nil {
+			return nil, logical.CodedError(http.StatusBadRequest, fmt.Sprintf("could not start the execution trace: %s", err))
+		}
+		time.Sleep(duration)
+		trace.Stop()
+
+	default:
+		profile := pprof.Lookup(name)
+		if profile == nil {
+			return nil, logical.CodedError(http.StatusBadRequest, fmt.Sprintf("unknown profile %q", name))
+		}
+		if err := profile.WriteTo(&buf, debug); err != nil {
+			return nil, err
+		}
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,259 @@
+package vault
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/physical"
+	physRaft "github.com/hashicorp/vault/physical/raft"
+)
+
+const (
+	// raftLeaderWaitTimeout is how long initialization waits for a
+	// bootstrapped node to become the raft leader
+	raftLeaderWaitTimeout = 30 * time.Second
+
+	// raftTLSPath is the barrier path of the TLS key the nodes of the raft
+	// cluster authenticate each other with
+	raftTLSPath = "core/raft/tls"
+)
+
+var (
+	// ErrRaftNotInUse is returned for raft operations when the storage is
+	// not raft
+	ErrRaftNotInUse = errors.New("raft storage is not in use")
+)
+
+// RaftJoinRequest describes how a node joins a raft cluster
+type RaftJoinRequest struct {
+	// LeaderAPIAddr is the API address of a node of the cluster, to which
+	// the request to add this node is sent
+	LeaderAPIAddr string
+
+	// LeaderCACert is the PEM encoded CA certificate used to verify the
+	// TLS certificate of the leader
+	LeaderCACert string
+
+	// Token is a token of the cluster allowed to add peers
+	Token string
+}
+
+// raftInitialize bootstraps a new raft cluster of this node on
+// initialization, unless it already is part of one, and waits for the node
+// to become the raft leader so that it can write the initial data
+func (c *Core) raftInitialize() error {
+	if c.raftBackend == nil {
+		return nil
+	}
+
+	err := c.raftBackend.Bootstrap()
+	switch err {
+	case nil:
+		c.logger.Info("core: bootstrapped a new raft cluster")
+	case physRaft.ErrAlreadyBootstrapped:
+	default:
+		c.logger.Error("core: failed to bootstrap the raft cluster", "error", err)
+		return fmt.Errorf("failed to bootstrap the raft cluster: %v", err)
+	}
+
+	if err := c.raftBackend.WaitForLeader(raftLeaderWaitTimeout); err != nil {
+		return fmt.Errorf("node must be the raft leader to initialize: %v", err)
+	}
+	return nil
+}
+
+// raftTLSKey returns the TLS key of the raft cluster stored in the barrier,
+// or nil if there is none yet
+func (c *Core) raftTLSKey() (*physRaft.TLSKey, error) {
+	entry, err := c.barrier.Get(raftTLSPath)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var key physRaft.TLSKey
+	if err := jsonutil.DecodeJSON(entry.Value, &key); err != nil {
+		return nil, fmt.Errorf("failed to decode the raft TLS key: %v", err)
+	}
+	return &key, nil
+}
+
+// raftSetupTLS sets the TLS key of the raft cluster stored in the barrier up
+// on the raft node once unsealed. If the cluster has none yet, the active
+// node generates it.
+func (c *Core) raftSetupTLS(active bool) error {
+	if c.raftBackend == nil {
+		return nil
+	}
+
+	key, err := c.raftTLSKey()
+	if err != nil {
+		c.logger.Error("core: failed to read the raft TLS key", "error", err)
+		return err
+	}
+	if key == nil {
+		if !active {
+			return nil
+		}
+		if key, err = physRaft.GenerateTLSKey(); err != nil {
+			c.logger.Error("core: failed to generate the raft TLS key", "error", err)
+			return err
+		}
+		value, err := jsonutil.EncodeJSON(key)
+		if err != nil {
+			return err
+		}
+		if err := c.barrier.Put(&Entry{
+			Key:   raftTLSPath,
+			Value: value,
+		}); err != nil {
+			c.logger.Error("core: failed to store the raft TLS key", "error", err)
+			return err
+		}
+	}
+
+	return c.raftBackend.SetTLSKey(key)
+}
+
+// RaftBootstrap makes this node the single server of a new raft cluster
+func (c *Core) RaftBootstrap() error {
+	if c.raftBackend == nil {
+		return ErrRaftNotInUse
+	}
+	return c.raftBackend.Bootstrap()
+}
+
+// JoinRaftCluster asks a node of an existing raft cluster to add this node,
+// which must not have any raft state yet. The storage is then replicated to
+// this node, which can be unsealed with the keys of the cluster.
+func (c *Core) JoinRaftCluster(joinReq *RaftJoinRequest) error {
+	if c.raftBackend == nil {
+		return ErrRaftNotInUse
+	}
+	if joinReq.LeaderAPIAddr == "" {
+		return fmt.Errorf("leader API address is required")
+	}
+
+	hasState, err := c.raftBackend.HasState()
+	if err != nil {
+		return err
+	}
+	if hasState {
+		return physRaft.ErrAlreadyBootstrapped
+	}
+
+	config := api.DefaultConfig()
+	config.Address = joinReq.LeaderAPIAddr
+	if joinReq.LeaderCACert != "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(joinReq.LeaderCACert)) {
+			return fmt.Errorf("failed to parse the leader CA certificate")
+		}
+		config.HttpClient.Transport.(*http.Transport).TLSClientConfig.RootCAs = pool
+	}
+	client, err := api.NewClient(config)
+	if err != nil {
+		return err
+	}
+	client.SetToken(joinReq.Token)
+	client.ClearNamespace()
+
+	// The leader returns the TLS key of the cluster, without which this node
+	// can't receive the storage
+	resp, err := client.Sys().RaftAddPeer(c.raftBackend.NodeID(), c.raftBackend.Address())
+	if err != nil {
+		return fmt.Errorf("failed to join the raft cluster: %v", err)
+	}
+	err = c.raftBackend.SetTLSKey(&physRaft.TLSKey{
+		CertBytes: resp.TLSCert,
+		KeyBytes:  resp.TLSKey,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set the raft TLS key: %v", err)
+	}
+	c.logger.Info("core: joined the raft cluster", "leader_api_addr", joinReq.LeaderAPIAddr)
+	return nil
+}
+
+// RaftSnapshot writes a snapshot of the storage of the raft cluster to w
+func (c *Core) RaftSnapshot(req *logical.Request, w io.Writer) error {
+	defer metrics.MeasureSince([]string{"core", "raft_snapshot"}, time.Now())
+
+	if err := c.authorizeRaftSnapshotRequest(req); err != nil {
+		return err
+	}
+	return c.raftBackend.Snapshot(w)
+}
+
+// RestoreRaftSnapshot replaces the storage of the raft cluster with a
+// snapshot written by RaftSnapshot. Snapshots whose keyring isn't sealed with
+// the current master key, such as the ones of another cluster or taken
+// before a rekey, are rejected since the restored storage couldn't be
+// unsealed. The node steps down afterwards so that the next active node
+// loads the restored data.
+func (c *Core) RestoreRaftSnapshot(req *logical.Request, r io.Reader) error {
+	defer metrics.MeasureSince([]string{"core", "raft_snapshot_restore"}, time.Now())
+
+	if err := c.authorizeRaftSnapshotRequest(req); err != nil {
+		return err
+	}
+	if err := c.raftBackend.Restore(r, c.verifyRaftSnapshot); err != nil {
+		return err
+	}
+	c.logger.Info("core: restored a raft snapshot, stepping down")
+
+	// Don't serve cached entries of the replaced storage
+	if purgable, ok := c.physical.(physical.Purgable); ok {
+		purgable.Purge()
+	}
+
+	c.stateLock.RLock()
+	defer c.stateLock.RUnlock()
+	if !c.sealed && !c.standby {
+		select {
+		case c.manualStepDownCh <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// verifyRaftSnapshot checks that the keyring of a snapshot is sealed with
+// the current master key and matches the current keyring
+func (c *Core) verifyRaftSnapshot(get func(key string) (*physical.Entry, error)) error {
+	entry, err := get(keyringPath)
+	if err != nil {
+		return fmt.Errorf("failed to read the keyring of the snapshot: %v", err)
+	}
+	if entry == nil {
+		return fmt.Errorf("snapshot has no keyring")
+	}
+	if err := c.barrier.VerifyKeyring(entry.Value); err != nil {
+		c.logger.Error("core: rejected a raft snapshot not sealed with the current keyring", "error", err)
+		return fmt.Errorf("snapshot is not sealed with the current keyring: %v", err)
+	}
+	return nil
+}
+
+// authorizeRaftSnapshotRequest checks that the request is allowed to save
+// or restore raft snapshots, which requires root privileges in the root
+// namespace, and audits it
+func (c *Core) authorizeRaftSnapshotRequest(req *logical.Request) error {
+	if req == nil {
+		return errors.New("nil request to raft snapshot")
+	}
+	if c.raftBackend == nil {
+		return ErrRaftNotInUse
+	}
+	return c.authorizeRootRequest(req)
+}
@@ -0,0 +1,174 @@
+package vault
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/helper/logformat"
+	"github.com/hashicorp/vault/logical"
+	physRaft "github.com/hashicorp/vault/physical/raft"
+	log "github.com/mgutz/logxi/v1"
+)
+
+func testRaftBackend(t *testing.T) (*physRaft.RaftBackend, func()) {
+	dir, err := ioutil.TempDir("", "vault-raft")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+	b, err := physRaft.NewRaftBackend(map[string]string{
+		"path":    dir,
+		"address": "127.0.0.1:0",
+	}, logger)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+
+	backend := b.(*physRaft.RaftBackend)
+	return backend, func() {
+		backend.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestRaft_initBootstraps(t *testing.T) {
+	backend, cleanup := testRaftBackend(t)
+	defer cleanup()
+
+	c, _, root := TestCoreUnsealedBackend(t, backend)
+
+	hasState, err := backend.HasState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hasState {
+		t.Fatal("initialization should have bootstrapped the raft cluster")
+	}
+	if err := c.RaftBootstrap(); err != physRaft.ErrAlreadyBootstrapped {
+		t.Fatalf("expected already bootstrapped error, got: %v", err)
+	}
+
+	req := logical.TestRequest(t, logical.ReadOperation, "sys/storage/raft/configuration")
+	req.ClientToken = root
+	resp, err := c.HandleRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	servers := resp.Data["servers"].([]map[string]interface{})
+	if len(servers) != 1 || servers[0]["node_id"] != backend.NodeID() || servers[0]["leader"] != true {
+		t.Fatalf("bad: %#v", servers)
+	}
+
+	// The active node generates the TLS key of the cluster
+	key, err := c.raftTLSKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key == nil || len(key.CertBytes) == 0 || len(key.KeyBytes) == 0 {
+		t.Fatalf("bad: %#v", key)
+	}
+}
+
+func TestRaft_snapshot(t *testing.T) {
+	backend, cleanup := testRaftBackend(t)
+	defer cleanup()
+
+	c, _, root := TestCoreUnsealedBackend(t, backend)
+
+	write := func(value string) {
+		req := logical.TestRequest(t, logical.UpdateOperation, "secret/foo")
+		req.Data["value"] = value
+		req.ClientToken = root
+		if _, err := c.HandleRequest(req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("bar")
+
+	snapshotReq := &logical.Request{
+		Operation:   logical.ReadOperation,
+		Path:        "sys/storage/raft/snapshot",
+		ClientToken: root,
+	}
+	var snapshot bytes.Buffer
+	if err := c.RaftSnapshot(snapshotReq, &snapshot); err != nil {
+		t.Fatal(err)
+	}
+
+	write("baz")
+
+	restoreReq := &logical.Request{
+		Operation:   logical.UpdateOperation,
+		Path:        "sys/storage/raft/snapshot",
+		ClientToken: root,
+	}
+	if err := c.RestoreRaftSnapshot(restoreReq, &snapshot); err != nil {
+		t.Fatal(err)
+	}
+
+	req := logical.TestRequest(t, logical.ReadOperation, "secret/foo")
+	req.ClientToken = root
+	resp, err := c.HandleRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp == nil || resp.Data["value"] != "bar" {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// Snapshots of another cluster are rejected
+	otherBackend, otherCleanup := testRaftBackend(t)
+	defer otherCleanup()
+	otherCore, _, otherRoot := TestCoreUnsealedBackend(t, otherBackend)
+	snapshotReq.ClientToken = otherRoot
+	var otherSnapshot bytes.Buffer
+	if err := otherCore.RaftSnapshot(snapshotReq, &otherSnapshot); err != nil {
+		t.Fatal(err)
+	}
+	snapshotReq.ClientToken = root
+	if err := c.RestoreRaftSnapshot(restoreReq, &otherSnapshot); err == nil {
+		t.Fatal("expected a snapshot of another cluster to be rejected")
+	}
+
+	// Snapshots require root privileges
+	req = logical.TestRequest(t, logical.UpdateOperation, "auth/token/create")
+	req.Data["policies"] = []string{"default"}
+	req.ClientToken = root
+	resp, err = c.HandleRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshotReq.ClientToken = resp.Auth.ClientToken
+	err = c.RaftSnapshot(snapshotReq, ioutil.Discard)
+	if err == nil || !errwrap.Contains(err, logical.ErrPermissionDenied.Error()) {
+		t.Fatalf("expected permission denied, got: %v", err)
+	}
+}
+
+func TestRaft_notInUse(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	req := &logical.Request{
+		Operation:   logical.ReadOperation,
+		Path:        "sys/storage/raft/snapshot",
+		ClientToken: root,
+	}
+	if err := c.RaftSnapshot(req, ioutil.Discard); err != ErrRaftNotInUse {
+		t.Fatalf("expected raft not in use error, got: %v", err)
+	}
+	if err := c.RaftBootstrap(); err != ErrRaftNotInUse {
+		t.Fatalf("expected raft not in use error, got: %v", err)
+	}
+
+	// The raft endpoints only exist with raft storage
+	req = logical.TestRequest(t, logical.ReadOperation, "sys/storage/raft/configuration")
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -0,0 +1,360 @@
+package vault
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/vault/logical"
+)
+
+const (
+	// rateLimitQuotaSubPath is the sub-path of the system barrier view where
+	// the rate limit quotas are stored
+	rateLimitQuotaSubPath = "quotas/rate-limit/"
+
+	// rateLimitPurgeInterval is the minimum interval between two purges of
+	// the idle buckets of a quota
+	rateLimitPurgeInterval = time.Minute
+)
+
+// RateLimitQuota limits the rate of the requests to a path, either in total
+// or from each client IP address. The requests are limited with a token
+// bucket: each request takes a token from a bucket holding up to Burst
+// tokens, refilled with Rate tokens every Interval.
+type RateLimitQuota struct {
+	Name string `json:"name" structs:"name" mapstructure:"name"`
+
+	// Path is the mount, or the path prefix under a mount, whose requests
+	// are limited. An empty path limits all the requests.
+	Path string `json:"path" structs:"path" mapstructure:"path"`
+
+	// Rate is the number of requests allowed per Interval
+	Rate     int           `json:"rate" structs:"rate" mapstructure:"rate"`
+	Interval time.Duration `json:"interval" structs:"interval" mapstructure:"interval"`
+
+	// Burst is the number of requests allowed at once, after the path was
+	// idle long enough. It defaults to Rate.
+	Burst int `json:"burst" structs:"burst" mapstructure:"burst"`
+
+	// PerClientIP applies the limit to the requests of each client IP
+	// address rather than to all the requests. Requests without a client
+	// address are not limited.
+	PerClientIP bool `json:"per_client_ip" structs:"per_client_ip" mapstructure:"per_client_ip"`
+}
+
+// prefix returns the prefix of the paths limited by the quota
+func (q *RateLimitQuota) prefix() string {
+	if q.Path == "" {
+		return ""
+	}
+	return strings.TrimSuffix(q.Path, "/") + "/"
+}
+
+// tokenBucket holds the tokens available to the requests of a client
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimiter enforces a rate limit quota, holding a token bucket for all
+// the requests or for each client IP address
+type rateLimiter struct {
+	quota *RateLimitQuota
+
+	// perSecond is the number of tokens added to the buckets per second
+	perSecond float64
+
+	l         sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastPurge time.Time
+}
+
+func newRateLimiter(quota *RateLimitQuota) *rateLimiter {
+	return &rateLimiter{
+		quota:     quota,
+		perSecond: float64(quota.Rate) / quota.Interval.Seconds(),
+		buckets:   make(map[string]*tokenBucket),
+		lastPurge: time.Now(),
+	}
+}
+
+// allow takes a token from the bucket of the given key. If the bucket is
+// empty, it returns false along with the time until a token is available.
+func (r *rateLimiter) allow(key string, now time.Time) (bool, time.Duration) {
+	r.l.Lock()
+	defer r.l.Unlock()
+
+	bucket := r.refill(key, now)
+	if bucket.tokens < 1 {
+		return false, r.retryAfter(bucket)
+	}
+	bucket.tokens--
+	return true, 0
+}
+
+// refill returns the bucket of the given key, refilled with the tokens added
+// since its last request. The limiter must be locked.
+func (r *rateLimiter) refill(key string, now time.Time) *tokenBucket {
+	if now.Sub(r.lastPurge) >= rateLimitPurgeInterval {
+		r.purge(now)
+	}
+
+	burst := float64(r.quota.Burst)
+	bucket, ok := r.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: burst, last: now}
+		r.buckets[key] = bucket
+	}
+
+	bucket.tokens += now.Sub(bucket.last).Seconds() * r.perSecond
+	if bucket.tokens > burst {
+		bucket.tokens = burst
+	}
+	bucket.last = now
+	return bucket
+}
+
+// retryAfter returns the time until a token is available in an empty bucket
+func (r *rateLimiter) retryAfter(bucket *tokenBucket) time.Duration {
+	wait := (1 - bucket.tokens) / r.perSecond
+	return time.Duration(wait * float64(time.Second))
+}
+
+// allowAll takes a token from the bucket of the given key of every limiter,
+// or from none of them if one of the buckets is empty. In that case it
+// returns the limiter of the empty bucket along with the time until a token
+// is available.
+func allowAll(limiters []*rateLimiter, keys []string, now time.Time) (*rateLimiter, time.Duration) {
+	// The limiters are locked in the order of their quotas to avoid
+	// deadlocking with concurrent requests
+	sort.Sort(byQuotaName{limiters: limiters, keys: keys})
+	for _, limiter := range limiters {
+		limiter.l.Lock()
+		defer limiter.l.Unlock()
+	}
+
+	buckets := make([]*tokenBucket, len(limiters))
+	for i, limiter := range limiters {
+		buckets[i] = limiter.refill(keys[i], now)
+		if buckets[i].tokens < 1 {
+			return limiter, limiter.retryAfter(buckets[i])
+		}
+	}
+	for _, bucket := range buckets {
+		bucket.tokens--
+	}
+	return nil, 0
+}
+
+// byQuotaName sorts limiters, along with their bucket keys, by the name of
+// their quota
+type byQuotaName struct {
+	limiters []*rateLimiter
+	keys     []string
+}
+
+func (b byQuotaName) Len() int {
+	return len(b.limiters)
+}
+
+func (b byQuotaName) Less(i, j int) bool {
+	return b.limiters[i].quota.Name < b.limiters[j].quota.Name
+}
+
+func (b byQuotaName) Swap(i, j int) {
+	b.limiters[i], b.limiters[j] = b.limiters[j], b.limiters[i]
+	b.keys[i], b.keys[j] = b.keys[j], b.keys[i]
+}
+
+// purge drops the buckets which have been refilled since their last request,
+// as they are identical to new buckets. This bounds the memory held by the
+// per client IP quotas to the clients seen recently.
+func (r *rateLimiter) purge(now time.Time) {
+	burst := float64(r.quota.Burst)
+	for key, bucket := range r.buckets {
+		if bucket.tokens+now.Sub(bucket.last).Seconds()*r.perSecond >= burst {
+			delete(r.buckets, key)
+		}
+	}
+	r.lastPurge = now
+}
+
+// loadRateLimitQuotas loads the rate limit quotas from storage
+func (c *Core) loadRateLimitQuotas() error {
+	view := c.systemBarrierView.SubView(rateLimitQuotaSubPath)
+	names, err := view.List("")
+	if err != nil {
+		return fmt.Errorf("failed to list rate limit quotas: %v", err)
+	}
+
+	limiters := make(map[string]*rateLimiter, len(names))
+	for _, name := range names {
+		entry, err := view.Get(name)
+		if err != nil {
+			return fmt.Errorf("failed to read rate limit quota: %v", err)
+		}
+		if entry == nil {
+			continue
+		}
+
+		var quota RateLimitQuota
+		if err := entry.DecodeJSON(&quota); err != nil {
+			return fmt.Errorf("failed to decode rate limit quota: %v", err)
+		}
+		limiters[name] = newRateLimiter(&quota)
+	}
+
+	c.rateLimitQuotasLock.Lock()
+	c.rateLimitQuotas = limiters
+	c.rateLimitQuotasLock.Unlock()
+	return nil
+}
+
+// unloadRateLimitQuotas clears the rate limit quotas from memory
+func (c *Core) unloadRateLimitQuotas() {
+	c.rateLimitQuotasLock.Lock()
+	c.rateLimitQuotas = nil
+	c.rateLimitQuotasLock.Unlock()
+}
+
+// RateLimitQuota returns the rate limit quota of the given name, or nil if it
+// doesn't exist
+func (c *Core) RateLimitQuota(name string) *RateLimitQuota {
+	c.rateLimitQuotasLock.RLock()
+	defer c.rateLimitQuotasLock.RUnlock()
+
+	limiter, ok := c.rateLimitQuotas[name]
+	if !ok {
+		return nil
+	}
+	copied := *limiter.quota
+	return &copied
+}
+
+// SetRateLimitQuota validates and stores a rate limit quota. Updating a quota
+// resets its buckets.
+func (c *Core) SetRateLimitQuota(quota *RateLimitQuota) error {
+	if quota.Interval == 0 {
+		quota.Interval = time.Second
+	}
+	if quota.Burst == 0 {
+		quota.Burst = quota.Rate
+	}
+
+	switch {
+	case quota.Name == "":
+		return fmt.Errorf("missing quota name")
+	case quota.Rate <= 0:
+		return fmt.Errorf("rate must be positive")
+	case quota.Interval < 0:
+		return fmt.Errorf("interval must be positive")
+	case quota.Burst < 0:
+		return fmt.Errorf("burst must be positive")
+	case strings.Contains(quota.Path, ".."):
+		return fmt.Errorf("invalid path %q", quota.Path)
+	}
+
+	quota.Path = strings.TrimPrefix(quota.Path, "/")
+	if quota.Path != "" && c.router.MatchingMount(quota.Path) == "" &&
+		c.router.MatchingMount(quota.prefix()) == "" {
+		return fmt.Errorf("path %q does not match a mount", quota.Path)
+	}
+
+	entry, err := logical.StorageEntryJSON(quota.Name, quota)
+	if err != nil {
+		return fmt.Errorf("failed to create rate limit quota entry: %v", err)
+	}
+
+	c.rateLimitQuotasLock.Lock()
+	defer c.rateLimitQuotasLock.Unlock()
+
+	view := c.systemBarrierView.SubView(rateLimitQuotaSubPath)
+	if err := view.Put(entry); err != nil {
+		return fmt.Errorf("failed to save rate limit quota: %v", err)
+	}
+
+	if c.rateLimitQuotas == nil {
+		c.rateLimitQuotas = make(map[string]*rateLimiter)
+	}
+	c.rateLimitQuotas[quota.Name] = newRateLimiter(quota)
+	return nil
+}
+
+// DeleteRateLimitQuota deletes the rate limit quota of the given name
+func (c *Core) DeleteRateLimitQuota(name string) error {
+	c.rateLimitQuotasLock.Lock()
+	defer c.rateLimitQuotasLock.Unlock()
+
+	view := c.systemBarrierView.SubView(rateLimitQuotaSubPath)
+	if err := view.Delete(name); err != nil {
+		return fmt.Errorf("failed to delete rate limit quota: %v", err)
+	}
+	delete(c.rateLimitQuotas, name)
+	return nil
+}
+
+// ListRateLimitQuotas returns the names of the rate limit quotas
+func (c *Core) ListRateLimitQuotas() ([]string, error) {
+	view := c.systemBarrierView.SubView(rateLimitQuotaSubPath)
+	names, err := view.List("")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list rate limit quotas: %v", err)
+	}
+	return names, nil
+}
+
+// checkRateLimitQuotas returns a *logical.RateLimitQuotaError if the request
+// exceeds one of the rate limit quotas. The requests managing the quotas are
+// never limited, so that a quota which is too strict can always be fixed.
+func (c *Core) checkRateLimitQuotas(req *logical.Request) error {
+	c.rateLimitQuotasLock.RLock()
+	defer c.rateLimitQuotasLock.RUnlock()
+
+	if len(c.rateLimitQuotas) == 0 || strings.HasPrefix(req.Path, "sys/quotas/") {
+		return nil
+	}
+
+	var clientIP string
+	if req.Connection != nil {
+		clientIP = req.Connection.RemoteAddr
+	}
+
+	var limiters []*rateLimiter
+	var keys []string
+	for _, limiter := range c.rateLimitQuotas {
+		quota := limiter.quota
+		if !strings.HasPrefix(req.Path+"/", quota.prefix()) {
+			continue
+		}
+
+		var key string
+		if quota.PerClientIP {
+			if clientIP == "" {
+				continue
+			}
+			key = clientIP
+		}
+		limiters = append(limiters, limiter)
+		keys = append(keys, key)
+	}
+
+	// The request takes a token from every matching quota only if none of
+	// them is exceeded
+	limiter, retryAfter := allowAll(limiters, keys, time.Now())
+	if limiter == nil {
+		return nil
+	}
+
+	metrics.IncrCounter([]string{"quota", "rate-limit", "violation"}, 1)
+	if c.logger.IsDebug() {
+		c.logger.Debug("core: rate limit quota exceeded", "quota", limiter.quota.Name, "path", req.Path, "client_ip", clientIP)
+	}
+	return &logical.RateLimitQuotaError{
+		Quota:      limiter.quota.Name,
+		RetryAfter: retryAfter,
+	}
+}
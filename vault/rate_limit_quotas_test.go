@@ -0,0 +1,196 @@
+package vault
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestRateLimiter_allow(t *testing.T) {
+	limiter := newRateLimiter(&RateLimitQuota{
+		Rate:     2,
+		Interval: time.Second,
+		Burst:    3,
+	})
+
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		if ok, _ := limiter.allow("", now); !ok {
+			t.Fatalf("request %d should be allowed by the burst", i)
+		}
+	}
+	ok, retryAfter := limiter.allow("", now)
+	if ok {
+		t.Fatal("request should exceed the burst")
+	}
+	if retryAfter != 500*time.Millisecond {
+		t.Fatalf("bad: retry after: %s", retryAfter)
+	}
+
+	// The other keys have their own bucket
+	if ok, _ := limiter.allow("10.0.0.1", now); !ok {
+		t.Fatal("request of another key should be allowed")
+	}
+
+	// The bucket is refilled at the rate
+	if ok, _ := limiter.allow("", now.Add(500*time.Millisecond)); !ok {
+		t.Fatal("request should be allowed after the refill")
+	}
+	if ok, _ := limiter.allow("", now.Add(500*time.Millisecond)); ok {
+		t.Fatal("request should exceed the rate")
+	}
+
+	// The full buckets are purged
+	limiter.purge(now.Add(time.Minute))
+	if len(limiter.buckets) != 0 {
+		t.Fatalf("bad: buckets: %#v", limiter.buckets)
+	}
+}
+
+func TestRateLimiter_allowAll(t *testing.T) {
+	wide := newRateLimiter(&RateLimitQuota{
+		Name:     "wide",
+		Rate:     2,
+		Interval: time.Second,
+		Burst:    2,
+	})
+	narrow := newRateLimiter(&RateLimitQuota{
+		Name:     "narrow",
+		Rate:     1,
+		Interval: time.Second,
+		Burst:    1,
+	})
+
+	now := time.Now()
+	if limiter, _ := allowAll([]*rateLimiter{wide, narrow}, []string{"", "10.0.0.1"}, now); limiter != nil {
+		t.Fatalf("request should be allowed; exceeded quota: %q", limiter.quota.Name)
+	}
+	limiter, retryAfter := allowAll([]*rateLimiter{wide, narrow}, []string{"", "10.0.0.1"}, now)
+	if limiter != narrow || retryAfter != time.Second {
+		t.Fatalf("bad: limiter: %#v, retry after: %s", limiter, retryAfter)
+	}
+
+	// The rejected request doesn't take a token from the other quotas
+	if ok, _ := wide.allow("", now); !ok {
+		t.Fatal("request should be allowed by the wide quota")
+	}
+	if ok, _ := wide.allow("", now); ok {
+		t.Fatal("request should exceed the wide quota")
+	}
+}
+
+func TestRateLimitQuotas(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	write := func(path string, data map[string]interface{}) (*logical.Response, error) {
+		req := logical.TestRequest(t, logical.UpdateOperation, path)
+		req.Data = data
+		req.ClientToken = root
+		return c.HandleRequest(req)
+	}
+	read := func(path, clientIP string) (*logical.Response, error) {
+		req := logical.TestRequest(t, logical.ReadOperation, path)
+		req.ClientToken = root
+		req.Connection = &logical.Connection{RemoteAddr: clientIP}
+		return c.HandleRequest(req)
+	}
+
+	// Invalid quotas are rejected
+	for _, data := range []map[string]interface{}{
+		{"path": "secret/", "rate": 0},
+		{"path": "secret/", "rate": 1, "burst": -1},
+		{"path": "secret/", "rate": 1, "interval": -1},
+		{"path": "unknown/", "rate": 1},
+		{"path": "secret/../sys", "rate": 1},
+	} {
+		resp, err := write("sys/quotas/rate-limit/bad", data)
+		if err == nil || resp == nil || !resp.IsError() {
+			t.Fatalf("expected an error for %#v; resp: %#v, err: %v", data, resp, err)
+		}
+	}
+
+	if resp, err := write("sys/quotas/rate-limit/secret", map[string]interface{}{
+		"path":          "secret/",
+		"rate":          2,
+		"interval":      "1h",
+		"per_client_ip": true,
+	}); err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+
+	req := logical.TestRequest(t, logical.ReadOperation, "sys/quotas/rate-limit/secret")
+	req.ClientToken = root
+	resp, err := c.HandleRequest(req)
+	if err != nil || resp == nil {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	if resp.Data["interval"].(int64) != 3600 || resp.Data["burst"].(int) != 2 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := read("secret/foo", "10.0.0.1"); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	// The third request of the client exceeds the quota
+	_, err = read("secret/foo", "10.0.0.1")
+	rlErr, ok := err.(*logical.RateLimitQuotaError)
+	if !ok {
+		t.Fatalf("expected the quota to be exceeded; err: %v", err)
+	}
+	if rlErr.Quota != "secret" || rlErr.RetryAfter <= 0 {
+		t.Fatalf("bad: %#v", rlErr)
+	}
+	if code, _ := logical.RespondErrorCommon(nil, nil, err); code != 429 {
+		t.Fatalf("bad: status code: %d", code)
+	}
+
+	// Other clients and paths are not affected, nor are the quotas
+	if _, err := read("secret/foo", "10.0.0.2"); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := read("sys/mounts", "10.0.0.1"); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := write("sys/quotas/rate-limit/global", map[string]interface{}{"rate": 1}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The global quota limits all the requests
+	if _, err := read("sys/mounts", "10.0.0.3"); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := read("cubbyhole/foo", "10.0.0.4"); err == nil {
+		t.Fatal("expected the global quota to be exceeded")
+	}
+
+	// The quotas are restored after unsealing
+	quotas := make(map[string]*RateLimitQuota)
+	for name, limiter := range c.rateLimitQuotas {
+		quotas[name] = limiter.quota
+	}
+	c.unloadRateLimitQuotas()
+	if err := c.loadRateLimitQuotas(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(c.rateLimitQuotas) != len(quotas) {
+		t.Fatalf("bad: %#v", c.rateLimitQuotas)
+	}
+	for name, quota := range quotas {
+		if loaded := c.RateLimitQuota(name); loaded == nil || *loaded != *quota {
+			t.Fatalf("bad: quota %q: %#v", name, loaded)
+		}
+	}
+
+	req = logical.TestRequest(t, logical.DeleteOperation, "sys/quotas/rate-limit/global")
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := read("cubbyhole/foo", "10.0.0.4"); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+}
@@ -2,6 +2,7 @@ package vault
 
 import (
 	"bytes"
+	"crypto/subtle"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
@@ -33,6 +34,17 @@ type RekeyResult struct {
 	PGPFingerprints []string
 	Backup          bool
 	RecoveryKey     bool
+
+	// VerificationRequired is set when the new key shares must be submitted
+	// back, with the verification nonce, before the new key is installed
+	VerificationRequired bool
+	VerificationNonce    string
+}
+
+// RekeyVerifyResult is returned once the new key shares of a rekey are
+// verified and the new key is installed
+type RekeyVerifyResult struct {
+	Nonce string
 }
 
 // RekeyBackup stores the backup copy of PGP-encrypted keys
@@ -136,6 +148,9 @@ func (c *Core) BarrierRekeyInit(config *SealConfig) error {
 		if config.Backup {
 			return fmt.Errorf("key backup not supported when using stored keys")
 		}
+		if config.VerificationRequired {
+			return fmt.Errorf("requiring verification not supported when using stored keys")
+		}
 	}
 
 	// Check if the seal configuration is valid
@@ -280,6 +295,11 @@ func (c *Core) BarrierRekeyUpdate(key []byte, nonce string) (*RekeyResult, error
 		return nil, fmt.Errorf("incorrect nonce supplied; nonce for this rekey operation is %s", c.barrierRekeyConfig.Nonce)
 	}
 
+	// Once the new key shares are generated, only their verification remains
+	if c.barrierRekeyVerifyKey != nil {
+		return nil, fmt.Errorf("rekey operation already finished; verification must be performed; nonce for the verification operation is %s", c.barrierRekeyConfig.VerificationNonce)
+	}
+
 	// Check if we already have this piece
 	for _, existing := range c.barrierRekeyProgress {
 		if bytes.Equal(existing, key) {
@@ -350,6 +370,9 @@ func (c *Core) BarrierRekeyUpdate(key []byte, nonce string) (*RekeyResult, error
 		}
 	}
 
+	// The backup of the PGP-encrypted shares is only stored once the new key
+	// is installed
+	var backup *RekeyBackup
 	if len(c.barrierRekeyConfig.PGPKeys) > 0 {
 		hexEncodedShares := make([][]byte, len(results.SecretShares))
 		for i, _ := range results.SecretShares {
@@ -371,44 +394,67 @@ func (c *Core) BarrierRekeyUpdate(key []byte, nonce string) (*RekeyResult, error
 				}
 			}
 
-			backupVals := &RekeyBackup{
+			backup = &RekeyBackup{
 				Nonce: c.barrierRekeyConfig.Nonce,
 				Keys:  backupInfo,
 			}
-			buf, err := json.Marshal(backupVals)
-			if err != nil {
-				c.logger.Error("core: failed to marshal unseal key backup", "error", err)
-				return nil, fmt.Errorf("failed to marshal unseal key backup: %v", err)
-			}
-			pe := &physical.Entry{
-				Key:   coreBarrierUnsealKeysBackupPath,
-				Value: buf,
-			}
-			if err = c.physical.Put(pe); err != nil {
-				c.logger.Error("core: failed to save unseal key backup", "error", err)
-				return nil, fmt.Errorf("failed to save unseal key backup: %v", err)
-			}
+		}
+	}
+
+	// If verification is required, the new master key is only installed once
+	// a threshold of the new key shares is submitted back
+	if c.barrierRekeyConfig.VerificationRequired {
+		nonce, err := uuid.GenerateUUID()
+		if err != nil {
+			return nil, err
+		}
+		c.barrierRekeyConfig.VerificationNonce = nonce
+		c.barrierRekeyVerifyKey = newMasterKey
+		c.barrierRekeyVerifyBackup = backup
+		results.VerificationRequired = true
+		results.VerificationNonce = nonce
+		return results, nil
+	}
+
+	if err := c.performBarrierRekey(newMasterKey, keysToStore, backup); err != nil {
+		return nil, err
+	}
+
+	// Done!
+	c.barrierRekeyProgress = nil
+	c.barrierRekeyConfig = nil
+	return results, nil
+}
+
+// performBarrierRekey installs the new master key of a barrier rekey, storing
+// the given shares of it with the seal and the given backup of its
+// PGP-encrypted shares, if any
+func (c *Core) performBarrierRekey(newMasterKey []byte, keysToStore [][]byte, backup *RekeyBackup) error {
+	if backup != nil {
+		if err := c.storeRekeyBackup(coreBarrierUnsealKeysBackupPath, backup); err != nil {
+			c.logger.Error("core: failed to save unseal key backup", "error", err)
+			return fmt.Errorf("failed to save unseal key backup: %v", err)
 		}
 	}
 
 	if keysToStore != nil {
 		if err := c.seal.SetStoredKeys(keysToStore); err != nil {
 			c.logger.Error("core: failed to store keys", "error", err)
-			return nil, fmt.Errorf("failed to store keys: %v", err)
+			return fmt.Errorf("failed to store keys: %v", err)
 		}
 	}
 
 	// Rekey the barrier
 	if err := c.barrier.Rekey(newMasterKey); err != nil {
 		c.logger.Error("core: failed to rekey barrier", "error", err)
-		return nil, fmt.Errorf("failed to rekey barrier: %v", err)
+		return fmt.Errorf("failed to rekey barrier: %v", err)
 	}
 	if c.logger.IsInfo() {
 		c.logger.Info("core: security barrier rekeyed", "shares", c.barrierRekeyConfig.SecretShares, "threshold", c.barrierRekeyConfig.SecretThreshold)
 	}
-	if err := c.seal.SetBarrierConfig(c.barrierRekeyConfig); err != nil {
+	if err := c.seal.SetBarrierConfig(installedRekeyConfig(c.barrierRekeyConfig)); err != nil {
 		c.logger.Error("core: error saving rekey seal configuration", "error", err)
-		return nil, fmt.Errorf("failed to save rekey seal configuration: %v", err)
+		return fmt.Errorf("failed to save rekey seal configuration: %v", err)
 	}
 
 	// Write to the canary path, which will force a synchronous truing during
@@ -418,13 +464,10 @@ func (c *Core) BarrierRekeyUpdate(key []byte, nonce string) (*RekeyResult, error
 		Value: []byte(c.barrierRekeyConfig.Nonce),
 	}); err != nil {
 		c.logger.Error("core: error saving keyring canary", "error", err)
-		return nil, fmt.Errorf("failed to save keyring canary: %v", err)
+		return fmt.Errorf("failed to save keyring canary: %v", err)
 	}
 
-	// Done!
-	c.barrierRekeyProgress = nil
-	c.barrierRekeyConfig = nil
-	return results, nil
+	return nil
 }
 
 // RecoveryRekeyUpdate is used to provide a new key part
@@ -477,6 +520,11 @@ func (c *Core) RecoveryRekeyUpdate(key []byte, nonce string) (*RekeyResult, erro
 		return nil, fmt.Errorf("incorrect nonce supplied; nonce for this rekey operation is %s", c.recoveryRekeyConfig.Nonce)
 	}
 
+	// Once the new key shares are generated, only their verification remains
+	if c.recoveryRekeyVerifyKey != nil {
+		return nil, fmt.Errorf("rekey operation already finished; verification must be performed; nonce for the verification operation is %s", c.recoveryRekeyConfig.VerificationNonce)
+	}
+
 	// Check if we already have this piece
 	for _, existing := range c.recoveryRekeyProgress {
 		if bytes.Equal(existing, key) {
@@ -538,6 +586,7 @@ func (c *Core) RecoveryRekeyUpdate(key []byte, nonce string) (*RekeyResult, erro
 		results.SecretShares = shares
 	}
 
+	var backup *RekeyBackup
 	if len(c.recoveryRekeyConfig.PGPKeys) > 0 {
 		hexEncodedShares := make([][]byte, len(results.SecretShares))
 		for i, _ := range results.SecretShares {
@@ -559,34 +608,56 @@ func (c *Core) RecoveryRekeyUpdate(key []byte, nonce string) (*RekeyResult, erro
 				}
 			}
 
-			backupVals := &RekeyBackup{
+			backup = &RekeyBackup{
 				Nonce: c.recoveryRekeyConfig.Nonce,
 				Keys:  backupInfo,
 			}
-			buf, err := json.Marshal(backupVals)
-			if err != nil {
-				c.logger.Error("core: failed to marshal recovery key backup", "error", err)
-				return nil, fmt.Errorf("failed to marshal recovery key backup: %v", err)
-			}
-			pe := &physical.Entry{
-				Key:   coreRecoveryUnsealKeysBackupPath,
-				Value: buf,
-			}
-			if err = c.physical.Put(pe); err != nil {
-				c.logger.Error("core: failed to save unseal key backup", "error", err)
-				return nil, fmt.Errorf("failed to save unseal key backup: %v", err)
-			}
+		}
+	}
+
+	// If verification is required, the new recovery key is only installed
+	// once a threshold of the new key shares is submitted back
+	if c.recoveryRekeyConfig.VerificationRequired {
+		nonce, err := uuid.GenerateUUID()
+		if err != nil {
+			return nil, err
+		}
+		c.recoveryRekeyConfig.VerificationNonce = nonce
+		c.recoveryRekeyVerifyKey = newMasterKey
+		c.recoveryRekeyVerifyBackup = backup
+		results.VerificationRequired = true
+		results.VerificationNonce = nonce
+		return results, nil
+	}
+
+	if err := c.performRecoveryRekey(newMasterKey, backup); err != nil {
+		return nil, err
+	}
+
+	// Done!
+	c.recoveryRekeyProgress = nil
+	c.recoveryRekeyConfig = nil
+	return results, nil
+}
+
+// performRecoveryRekey installs the new recovery key of a recovery rekey,
+// storing the given backup of its PGP-encrypted shares, if any
+func (c *Core) performRecoveryRekey(newMasterKey []byte, backup *RekeyBackup) error {
+	if backup != nil {
+		if err := c.storeRekeyBackup(coreRecoveryUnsealKeysBackupPath, backup); err != nil {
+			c.logger.Error("core: failed to save recovery key backup", "error", err)
+			return fmt.Errorf("failed to save recovery key backup: %v", err)
 		}
 	}
 
 	if err := c.seal.SetRecoveryKey(newMasterKey); err != nil {
 		c.logger.Error("core: failed to set recovery key", "error", err)
-		return nil, fmt.Errorf("failed to set recovery key: %v", err)
+		return fmt.Errorf("failed to set recovery key: %v", err)
 	}
 
-	if err := c.seal.SetRecoveryConfig(c.recoveryRekeyConfig); err != nil {
+	if err := c.seal.SetRecoveryConfig(installedRekeyConfig(c.recoveryRekeyConfig)); err != nil {
 		c.logger.Error("core: error saving rekey seal configuration", "error", err)
-		return nil, fmt.Errorf("failed to save rekey seal configuration: %v", err)
+		return fmt.Errorf("failed to save rekey seal configuration: %v", err)
 	}
 
 	// Write to the canary path, which will force a synchronous truing during
@@ -596,13 +667,198 @@ func (c *Core) RecoveryRekeyUpdate(key []byte, nonce string) (*RekeyResult, erro
 		Value: []byte(c.recoveryRekeyConfig.Nonce),
 	}); err != nil {
 		c.logger.Error("core: error saving keyring canary", "error", err)
-		return nil, fmt.Errorf("failed to save keyring canary: %v", err)
+		return fmt.Errorf("failed to save keyring canary: %v", err)
+	}
+
+	return nil
+}
+
+// storeRekeyBackup stores the backup of the PGP-encrypted shares of a rekey
+func (c *Core) storeRekeyBackup(path string, backup *RekeyBackup) error {
+	buf, err := json.Marshal(backup)
+	if err != nil {
+		return err
+	}
+	return c.physical.Put(&physical.Entry{
+		Key:   path,
+		Value: buf,
+	})
+}
+
+// installedRekeyConfig returns the seal configuration to save once the new
+// key of a rekey is installed, without the state of its verification
+func installedRekeyConfig(config *SealConfig) *SealConfig {
+	ret := config.Clone()
+	ret.VerificationRequired = false
+	ret.VerificationNonce = ""
+	return ret
+}
+
+// RekeyVerificationProgress is used to return the number of new key shares
+// submitted to verify a rekey
+func (c *Core) RekeyVerificationProgress(recovery bool) (int, error) {
+	c.stateLock.RLock()
+	defer c.stateLock.RUnlock()
+	if c.sealed {
+		return 0, consts.ErrSealed
+	}
+	if c.standby {
+		return 0, consts.ErrStandby
+	}
+
+	c.rekeyLock.RLock()
+	defer c.rekeyLock.RUnlock()
+
+	if recovery {
+		return len(c.recoveryRekeyVerifyProgress), nil
+	}
+	return len(c.barrierRekeyVerifyProgress), nil
+}
+
+// RekeyVerify is used to submit a share of the new key generated by a rekey
+// requiring verification. Once a threshold of shares reconstructing the new
+// key is submitted, the new key is installed and the rekey completes.
+func (c *Core) RekeyVerify(key []byte, nonce string, recovery bool) (*RekeyVerifyResult, error) {
+	// Ensure we are already unsealed
+	c.stateLock.RLock()
+	defer c.stateLock.RUnlock()
+	if c.sealed {
+		return nil, consts.ErrSealed
+	}
+	if c.standby {
+		return nil, consts.ErrStandby
+	}
+
+	// Verify the key length
+	min, max := c.barrier.KeyLength()
+	max += shamir.ShareOverhead
+	if len(key) < min {
+		return nil, &ErrInvalidKey{fmt.Sprintf("key is shorter than minimum %d bytes", min)}
+	}
+	if len(key) > max {
+		return nil, &ErrInvalidKey{fmt.Sprintf("key is longer than maximum %d bytes", max)}
+	}
+
+	c.rekeyLock.Lock()
+	defer c.rekeyLock.Unlock()
+
+	config, newKey, backup, progress := c.barrierRekeyConfig, c.barrierRekeyVerifyKey, c.barrierRekeyVerifyBackup, &c.barrierRekeyVerifyProgress
+	if recovery {
+		config, newKey, backup, progress = c.recoveryRekeyConfig, c.recoveryRekeyVerifyKey, c.recoveryRekeyVerifyBackup, &c.recoveryRekeyVerifyProgress
+	}
+
+	// Ensure a verification is in progress
+	if config == nil {
+		return nil, fmt.Errorf("no rekey in progress")
+	}
+	if newKey == nil {
+		return nil, fmt.Errorf("no rekey verification in progress")
+	}
+
+	if nonce != config.VerificationNonce {
+		return nil, fmt.Errorf("incorrect nonce supplied; nonce for this verify operation is %s", config.VerificationNonce)
+	}
+
+	// Check if we already have this piece
+	for _, existing := range *progress {
+		if bytes.Equal(existing, key) {
+			return nil, fmt.Errorf("given key has already been provided during this verify operation")
+		}
+	}
+
+	// Store this key
+	*progress = append(*progress, key)
+
+	// Check if we don't have enough keys to verify
+	if len(*progress) < config.SecretThreshold {
+		if c.logger.IsDebug() {
+			c.logger.Debug("core: cannot verify yet, not enough keys", "keys", len(*progress), "threshold", config.SecretThreshold)
+		}
+		return nil, nil
+	}
+
+	// Recover the new key
+	var recoveredKey []byte
+	var err error
+	if config.SecretThreshold == 1 {
+		recoveredKey = (*progress)[0]
+	} else {
+		recoveredKey, err = shamir.Combine(*progress)
+	}
+	*progress = nil
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute new key: %v", err)
+	}
+
+	if subtle.ConstantTimeCompare(recoveredKey, newKey) != 1 {
+		c.logger.Error("core: rekey verification failed, the submitted keys do not match the new key")
+		return nil, fmt.Errorf("rekey verification failed; incorrect key shares supplied")
+	}
+
+	if recovery {
+		err = c.performRecoveryRekey(newKey, backup)
+	} else {
+		err = c.performBarrierRekey(newKey, nil, backup)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result := &RekeyVerifyResult{
+		Nonce: config.VerificationNonce,
 	}
 
 	// Done!
-	c.recoveryRekeyProgress = nil
-	c.recoveryRekeyConfig = nil
-	return results, nil
+	if recovery {
+		c.recoveryRekeyConfig = nil
+		c.recoveryRekeyVerifyKey = nil
+		c.recoveryRekeyVerifyBackup = nil
+	} else {
+		c.barrierRekeyConfig = nil
+		c.barrierRekeyVerifyKey = nil
+		c.barrierRekeyVerifyBackup = nil
+	}
+	return result, nil
+}
+
+// RekeyVerifyRestart is used to discard the new key shares submitted to
+// verify a rekey, starting the verification over with a new nonce. Unlike
+// RekeyCancel, the new key shares remain valid.
+func (c *Core) RekeyVerifyRestart(recovery bool) error {
+	c.stateLock.RLock()
+	defer c.stateLock.RUnlock()
+	if c.sealed {
+		return consts.ErrSealed
+	}
+	if c.standby {
+		return consts.ErrStandby
+	}
+
+	c.rekeyLock.Lock()
+	defer c.rekeyLock.Unlock()
+
+	config, newKey := c.barrierRekeyConfig, c.barrierRekeyVerifyKey
+	if recovery {
+		config, newKey = c.recoveryRekeyConfig, c.recoveryRekeyVerifyKey
+	}
+	if config == nil {
+		return fmt.Errorf("no rekey in progress")
+	}
+	if newKey == nil {
+		return fmt.Errorf("no rekey verification in progress")
+	}
+
+	nonce, err := uuid.GenerateUUID()
+	if err != nil {
+		return err
+	}
+	config.VerificationNonce = nonce
+	if recovery {
+		c.recoveryRekeyVerifyProgress = nil
+	} else {
+		c.barrierRekeyVerifyProgress = nil
+	}
+	return nil
 }
 
 // RekeyCancel is used to cancel an inprogress rekey
@@ -623,9 +879,15 @@ func (c *Core) RekeyCancel(recovery bool) error {
 	if recovery {
 		c.recoveryRekeyConfig = nil
 		c.recoveryRekeyProgress = nil
+		c.recoveryRekeyVerifyKey = nil
+		c.recoveryRekeyVerifyBackup = nil
+		c.recoveryRekeyVerifyProgress = nil
 	} else {
 		c.barrierRekeyConfig = nil
 		c.barrierRekeyProgress = nil
+		c.barrierRekeyVerifyKey = nil
+		c.barrierRekeyVerifyBackup = nil
+		c.barrierRekeyVerifyProgress = nil
 	}
 	return nil
 }
@@ -1,6 +1,8 @@
 package vault
 
 import (
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"reflect"
 	"testing"
@@ -8,6 +10,7 @@ import (
 	log "github.com/mgutz/logxi/v1"
 
 	"github.com/hashicorp/vault/helper/logformat"
+	"github.com/hashicorp/vault/helper/pgpkeys"
 	"github.com/hashicorp/vault/physical"
 	"github.com/hashicorp/vault/physical/inmem"
 )
@@ -369,6 +372,201 @@ func testCore_Rekey_Invalid_Common(t *testing.T, c *Core, keys [][]byte, recover
 	}
 }
 
+func TestCore_Rekey_Verify(t *testing.T) {
+	bc, rc := TestSealDefConfigs()
+	bc.StoredShares = 0
+	c, masterKeys, recoveryKeys, root := TestCoreUnsealedWithConfigs(t, bc, rc)
+	testCore_Rekey_Verify_Common(t, c, masterKeys, root, false)
+	testCore_Rekey_Verify_Common(t, c, recoveryKeys, root, true)
+}
+
+func testCore_Rekey_Verify_Common(t *testing.T, c *Core, keys [][]byte, root string, recovery bool) {
+	sealConfig := func() *SealConfig {
+		var conf *SealConfig
+		var err error
+		if recovery {
+			conf, err = c.seal.RecoveryConfig()
+		} else {
+			conf, err = c.seal.BarrierConfig()
+		}
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		return conf
+	}
+	oldConf := sealConfig()
+
+	// Start a rekey requiring verification
+	err := c.RekeyInit(&SealConfig{
+		SecretThreshold:      2,
+		SecretShares:         4,
+		VerificationRequired: true,
+	}, recovery)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	rkconf, err := c.RekeyConfig(recovery)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if rkconf == nil || !rkconf.VerificationRequired || rkconf.VerificationNonce != "" {
+		t.Fatalf("bad: %#v", rkconf)
+	}
+
+	var result *RekeyResult
+	for _, key := range keys {
+		result, err = c.RekeyUpdate(key, rkconf.Nonce, recovery)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if result != nil {
+			break
+		}
+	}
+	if result == nil || len(result.SecretShares) != 4 || !result.VerificationRequired || result.VerificationNonce == "" {
+		t.Fatalf("bad: %#v", result)
+	}
+
+	// The new key is not installed until verified
+	if !reflect.DeepEqual(sealConfig(), oldConf) {
+		t.Fatalf("seal configuration should not change yet: %#v", sealConfig())
+	}
+	if _, err := c.RekeyUpdate(keys[0], rkconf.Nonce, recovery); err == nil {
+		t.Fatal("expected an error while awaiting verification")
+	}
+
+	// Shares of another key fail the verification and reset its progress
+	if ret, err := c.RekeyVerify(TestKeyCopy(result.SecretShares[0]), result.VerificationNonce, recovery); err != nil || ret != nil {
+		t.Fatalf("bad: ret: %#v, err: %v", ret, err)
+	}
+	num, err := c.RekeyVerificationProgress(recovery)
+	if err != nil || num != 1 {
+		t.Fatalf("bad: num: %d, err: %v", num, err)
+	}
+	if _, err := c.RekeyVerify(TestKeyCopy(keys[0]), result.VerificationNonce, recovery); err == nil {
+		t.Fatal("expected an error")
+	}
+	num, err = c.RekeyVerificationProgress(recovery)
+	if err != nil || num != 0 {
+		t.Fatalf("bad: num: %d, err: %v", num, err)
+	}
+
+	// Restarting the verification changes its nonce
+	if err := c.RekeyVerifyRestart(recovery); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	rkconf, err = c.RekeyConfig(recovery)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if rkconf.VerificationNonce == "" || rkconf.VerificationNonce == result.VerificationNonce {
+		t.Fatalf("bad: %#v", rkconf)
+	}
+	if _, err := c.RekeyVerify(TestKeyCopy(result.SecretShares[0]), result.VerificationNonce, recovery); err == nil {
+		t.Fatal("expected an error with the former nonce")
+	}
+
+	// A threshold of the new shares installs the new key
+	var verifyResult *RekeyVerifyResult
+	for _, key := range result.SecretShares[2:] {
+		verifyResult, err = c.RekeyVerify(TestKeyCopy(key), rkconf.VerificationNonce, recovery)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+	if verifyResult == nil || verifyResult.Nonce != rkconf.VerificationNonce {
+		t.Fatalf("bad: %#v", verifyResult)
+	}
+	if conf, err := c.RekeyConfig(recovery); err != nil || conf != nil {
+		t.Fatalf("bad: conf: %#v, err: %v", conf, err)
+	}
+	conf := sealConfig()
+	if conf.SecretShares != 4 || conf.SecretThreshold != 2 || conf.VerificationRequired || conf.VerificationNonce != "" {
+		t.Fatalf("bad: %#v", conf)
+	}
+
+	if recovery {
+		return
+	}
+	if err := c.Seal(root); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for _, key := range result.SecretShares[:2] {
+		if _, err := TestCoreUnseal(c, TestKeyCopy(key)); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+	if sealed, _ := c.Sealed(); sealed {
+		t.Fatal("should be unsealed")
+	}
+}
+
+func TestCore_Rekey_Verify_Backup(t *testing.T) {
+	bc, rc := TestSealDefConfigs()
+	bc.StoredShares = 0
+	c, masterKeys, _, _ := TestCoreUnsealedWithConfigs(t, bc, rc)
+
+	// Start a rekey requiring verification with a backup of the shares
+	err := c.RekeyInit(&SealConfig{
+		SecretThreshold:      1,
+		SecretShares:         1,
+		PGPKeys:              []string{pgpkeys.TestPubKey1},
+		Backup:               true,
+		VerificationRequired: true,
+	}, false)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	rkconf, err := c.RekeyConfig(false)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	var result *RekeyResult
+	for _, key := range masterKeys {
+		result, err = c.RekeyUpdate(key, rkconf.Nonce, false)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if result != nil {
+			break
+		}
+	}
+	if result == nil || len(result.SecretShares) != 1 || !result.VerificationRequired {
+		t.Fatalf("bad: %#v", result)
+	}
+
+	// The backup is not stored until the new key is verified
+	backup, err := c.RekeyRetrieveBackup(false)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if backup != nil {
+		t.Fatalf("bad: %#v", backup)
+	}
+
+	ptBuf, err := pgpkeys.DecryptBytes(base64.StdEncoding.EncodeToString(result.SecretShares[0]), pgpkeys.TestPrivKey1)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	share, err := hex.DecodeString(ptBuf.String())
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	verifyResult, err := c.RekeyVerify(share, result.VerificationNonce, false)
+	if err != nil || verifyResult == nil {
+		t.Fatalf("bad: result: %#v, err: %v", verifyResult, err)
+	}
+
+	backup, err = c.RekeyRetrieveBackup(false)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if backup == nil || backup.Nonce != rkconf.Nonce || len(backup.Keys[result.PGPFingerprints[0]]) != 1 {
+		t.Fatalf("bad: %#v", backup)
+	}
+}
+
 func TestCore_Standby_Rekey(t *testing.T) {
 	// Create the first core and initialize it
 	logger := logformat.NewVaultLogger(log.LevelTrace)
@@ -12,6 +12,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/armon/go-metrics"
 	"github.com/hashicorp/vault/helper/forwarding"
 	"golang.org/x/net/context"
 	"golang.org/x/net/http2"
@@ -56,10 +57,13 @@ func (c *Core) startForwarding() error {
 		return nil
 	}
 
+	// Bounding the concurrent streams of each connection applies
+	// backpressure to the standbys through HTTP/2 flow control
 	c.rpcServer = grpc.NewServer(
 		grpc.KeepaliveParams(keepalive.ServerParameters{
 			Time: 2 * heartbeatInterval,
 		}),
+		grpc.MaxConcurrentStreams(uint32(c.requestForwardingConfig.MaxInFlight)),
 	)
 
 	if ha && c.clusterHandler != nil {
@@ -222,12 +226,12 @@ func (c *Core) refreshRequestForwardingConnection(clusterAddr string) error {
 		return err
 	}
 
-	// Set up grpc forwarding handling
-	// It's not really insecure, but we have to dial manually to get the
-	// ALPN header right. It's just "insecure" because GRPC isn't managing
-	// the TLS state.
+	// Set up grpc forwarding handling over a pool of connections, each
+	// authenticated with the cluster certificates. It's not really insecure,
+	// but we have to dial manually to get the ALPN header right. It's just
+	// "insecure" because GRPC isn't managing the TLS state.
 	ctx, cancelFunc := context.WithCancel(context.Background())
-	c.rpcClientConn, err = grpc.DialContext(ctx, clusterURL.Host,
+	c.rpcForwardingPool, err = newForwardingPool(ctx, clusterURL.Host, clusterURL.Host, c.requestForwardingConfig,
 		grpc.WithDialer(c.getGRPCDialer(requestForwardingALPN, "", nil)),
 		grpc.WithInsecure(), // it's not, we handle it in the dialer
 		grpc.WithKeepaliveParams(keepalive.ClientParameters{
@@ -241,7 +245,7 @@ func (c *Core) refreshRequestForwardingConnection(clusterAddr string) error {
 	c.rpcClientConnContext = ctx
 	c.rpcClientConnCancelFunc = cancelFunc
 	c.rpcForwardingClient = &forwardingClient{
-		RequestForwardingClient: NewRequestForwardingClient(c.rpcClientConn),
+		RequestForwardingClient: c.rpcForwardingPool.conns[0].client,
 		core:        c,
 		echoTicker:  time.NewTicker(heartbeatInterval),
 		echoContext: ctx,
@@ -259,9 +263,9 @@ func (c *Core) clearForwardingClients() {
 		c.rpcClientConnCancelFunc()
 		c.rpcClientConnCancelFunc = nil
 	}
-	if c.rpcClientConn != nil {
-		c.rpcClientConn.Close()
-		c.rpcClientConn = nil
+	if c.rpcForwardingPool != nil {
+		c.rpcForwardingPool.close()
+		c.rpcForwardingPool = nil
 	}
 
 	c.rpcClientConnContext = nil
@@ -271,10 +275,15 @@ func (c *Core) clearForwardingClients() {
 // ForwardRequest forwards a given request to the active node and returns the
 // response.
 func (c *Core) ForwardRequest(req *http.Request) (int, http.Header, []byte, error) {
+	// The connection lock is only held to read the pool, since waiting for a
+	// forwarding slot would otherwise block clearing the forwarding clients.
+	// Clearing them cancels the context, aborting any request still waiting
+	// or in flight.
 	c.requestForwardingConnectionLock.RLock()
-	defer c.requestForwardingConnectionLock.RUnlock()
+	pool, ctx := c.rpcForwardingPool, c.rpcClientConnContext
+	c.requestForwardingConnectionLock.RUnlock()
 
-	if c.rpcForwardingClient == nil {
+	if pool == nil {
 		return 0, nil, nil, ErrCannotForward
 	}
 
@@ -287,8 +296,22 @@ func (c *Core) ForwardRequest(req *http.Request) (int, http.Header, []byte, erro
 		c.logger.Error("core: got nil forwarding RPC request")
 		return 0, nil, nil, fmt.Errorf("got nil forwarding RPC request")
 	}
-	resp, err := c.rpcForwardingClient.ForwardRequest(c.rpcClientConnContext, freq)
+
+	fc, err := pool.acquire(ctx)
 	if err != nil {
+		return 0, nil, nil, err
+	}
+	defer pool.release(fc)
+
+	c.stateLock.RLock()
+	clusterAddr := c.clusterAddr
+	c.stateLock.RUnlock()
+
+	start := time.Now()
+	resp, err := fc.client.ForwardRequest(withForwardingNode(ctx, clusterAddr), freq)
+	metrics.MeasureSinceWithLabels([]string{"ha", "rpc", "client", "forward"}, start, pool.labels())
+	if err != nil {
+		metrics.IncrCounterWithLabels([]string{"ha", "rpc", "client", "forward", "errors"}, 1, pool.labels())
 		c.logger.Error("core: error during forwarded RPC request", "error", err)
 		return 0, nil, nil, fmt.Errorf("error during forwarding RPC request")
 	}
@@ -342,10 +365,13 @@ type forwardedRequestRPCServer struct {
 
 func (s *forwardedRequestRPCServer) ForwardRequest(ctx context.Context, freq *forwarding.Request) (*forwarding.Response, error) {
 	//s.core.logger.Trace("forwarding: serving rpc forwarded request")
+	labels := []metrics.Label{{Name: "node", Value: forwardingNode(ctx)}}
+	defer metrics.MeasureSinceWithLabels([]string{"ha", "rpc", "server", "forward"}, time.Now(), labels)
 
 	// Parse an http.Request out of it
 	req, err := forwarding.ParseForwardedRequest(freq)
 	if err != nil {
+		metrics.IncrCounterWithLabels([]string{"ha", "rpc", "server", "forward", "errors"}, 1, labels)
 		return nil, err
 	}
 
@@ -390,7 +416,8 @@ func (s *forwardedRequestRPCServer) Echo(ctx context.Context, in *EchoRequest) (
 		s.core.clusterPeerClusterAddrsCache.Set(in.ClusterAddr, nil, 0)
 	}
 	return &EchoReply{
-		Message: "pong",
+		Message:       "pong",
+		EmergencySeal: atomic.LoadUint32(&s.core.emergencySealPending) == 1,
 	}, nil
 }
 
@@ -430,6 +457,11 @@ func (c *forwardingClient) startHeartbeat() {
 				c.core.logger.Debug("forwarding: unexpected echo response from active node", "message", resp.Message)
 				return
 			}
+			if resp.EmergencySeal {
+				c.core.logger.Warn("forwarding: active node reported an emergency seal, sealing")
+				go c.core.Shutdown()
+				return
+			}
 			c.core.logger.Trace("forwarding: successful heartbeat")
 		}
 
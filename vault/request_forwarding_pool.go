@@ -0,0 +1,195 @@
+package vault
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// defaultForwardingPoolSize is the number of connections a standby opens
+	// to the active node to forward requests
+	defaultForwardingPoolSize = 4
+
+	// defaultForwardingMaxInFlight is the number of requests a standby
+	// forwards concurrently, and the number of concurrent forwarded requests
+	// the active node serves per connection
+	defaultForwardingMaxInFlight = 256
+
+	// defaultForwardingQueueTimeout is how long a request waits for a
+	// forwarding slot before it is rejected
+	defaultForwardingQueueTimeout = 5 * time.Second
+
+	// forwardingNodeMetadataKey is the gRPC metadata key carrying the cluster
+	// address of the standby forwarding a request
+	forwardingNodeMetadataKey = "vault-forwarding-node"
+)
+
+var (
+	// ErrForwardingBackpressure is returned when a request can't be
+	// forwarded because too many requests are already being forwarded to the
+	// active node
+	ErrForwardingBackpressure = errors.New("too many requests are being forwarded to the active node; retry later")
+)
+
+// RequestForwardingConfig configures the forwarding of requests from the
+// standby nodes to the active node
+type RequestForwardingConfig struct {
+	// PoolSize is the number of connections opened to the active node. The
+	// forwarded requests are multiplexed over them.
+	PoolSize int `json:"pool_size" structs:"pool_size" mapstructure:"pool_size"`
+
+	// MaxInFlight is the number of requests forwarded concurrently. Further
+	// requests wait for a slot up to QueueTimeout.
+	MaxInFlight int `json:"max_in_flight" structs:"max_in_flight" mapstructure:"max_in_flight"`
+
+	// QueueTimeout is how long a request waits for a forwarding slot before
+	// it is rejected
+	QueueTimeout time.Duration `json:"queue_timeout" structs:"queue_timeout" mapstructure:"queue_timeout"`
+}
+
+// requestForwardingConfig returns the forwarding configuration of a core
+// configuration, with defaults filled in
+func requestForwardingConfig(conf *RequestForwardingConfig) *RequestForwardingConfig {
+	result := &RequestForwardingConfig{}
+	if conf != nil {
+		*result = *conf
+	}
+	if result.PoolSize <= 0 {
+		result.PoolSize = defaultForwardingPoolSize
+	}
+	if result.MaxInFlight <= 0 {
+		result.MaxInFlight = defaultForwardingMaxInFlight
+	}
+	if result.QueueTimeout <= 0 {
+		result.QueueTimeout = defaultForwardingQueueTimeout
+	}
+	return result
+}
+
+// forwardingConn is a connection of the forwarding pool
+type forwardingConn struct {
+	conn   *grpc.ClientConn
+	client RequestForwardingClient
+
+	// inFlight is the number of requests being forwarded over the
+	// connection
+	inFlight int64
+}
+
+// forwardingPool multiplexes the requests forwarded to the active node over
+// a pool of gRPC connections, bounding the number of requests in flight
+type forwardingPool struct {
+	conns []*forwardingConn
+
+	// slots holds a token per request in flight; requests wait for a free
+	// slot up to queueTimeout
+	slots        chan struct{}
+	queueTimeout time.Duration
+
+	// node is the cluster address of the active node, labelling the metrics
+	node string
+
+	closeOnce sync.Once
+}
+
+// newForwardingPool dials the connections of a forwarding pool to the active
+// node at the given address
+func newForwardingPool(ctx context.Context, addr, node string, config *RequestForwardingConfig, opts ...grpc.DialOption) (*forwardingPool, error) {
+	p := &forwardingPool{
+		slots:        make(chan struct{}, config.MaxInFlight),
+		queueTimeout: config.QueueTimeout,
+		node:         node,
+	}
+	for i := 0; i < config.PoolSize; i++ {
+		conn, err := grpc.DialContext(ctx, addr, opts...)
+		if err != nil {
+			p.close()
+			return nil, err
+		}
+		p.conns = append(p.conns, &forwardingConn{
+			conn:   conn,
+			client: NewRequestForwardingClient(conn),
+		})
+	}
+	return p, nil
+}
+
+// acquire waits for a forwarding slot and returns the connection with the
+// fewest requests in flight, which must be released once the request
+// completes. It returns ErrForwardingBackpressure if no slot frees up in
+// time.
+func (p *forwardingPool) acquire(ctx context.Context) (*forwardingConn, error) {
+	select {
+	case p.slots <- struct{}{}:
+	default:
+		timer := time.NewTimer(p.queueTimeout)
+		defer timer.Stop()
+		select {
+		case p.slots <- struct{}{}:
+		case <-timer.C:
+			metrics.IncrCounterWithLabels([]string{"ha", "rpc", "client", "forward", "rejected"}, 1, p.labels())
+			return nil, ErrForwardingBackpressure
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	var best *forwardingConn
+	for _, fc := range p.conns {
+		if best == nil || atomic.LoadInt64(&fc.inFlight) < atomic.LoadInt64(&best.inFlight) {
+			best = fc
+		}
+	}
+	atomic.AddInt64(&best.inFlight, 1)
+	metrics.SetGaugeWithLabels([]string{"ha", "rpc", "client", "forward", "in_flight"}, float32(len(p.slots)), p.labels())
+	return best, nil
+}
+
+// release frees the forwarding slot of a request forwarded over the given
+// connection
+func (p *forwardingPool) release(fc *forwardingConn) {
+	atomic.AddInt64(&fc.inFlight, -1)
+	<-p.slots
+	metrics.SetGaugeWithLabels([]string{"ha", "rpc", "client", "forward", "in_flight"}, float32(len(p.slots)), p.labels())
+}
+
+// labels returns the labels of the metrics of the pool
+func (p *forwardingPool) labels() []metrics.Label {
+	return []metrics.Label{{Name: "node", Value: p.node}}
+}
+
+// close closes the connections of the pool
+func (p *forwardingPool) close() {
+	p.closeOnce.Do(func() {
+		for _, fc := range p.conns {
+			fc.conn.Close()
+		}
+	})
+}
+
+// withForwardingNode adds the cluster address of the standby to the
+// metadata of a forwarded request, labelling the metrics of the active node
+func withForwardingNode(ctx context.Context, clusterAddr string) context.Context {
+	if clusterAddr == "" {
+		return ctx
+	}
+	return metadata.NewOutgoingContext(ctx, metadata.Pairs(forwardingNodeMetadataKey, clusterAddr))
+}
+
+// forwardingNode returns the cluster address of the standby which forwarded
+// a request, or "unknown" if it didn't send it
+func forwardingNode(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md[forwardingNodeMetadataKey]; len(values) > 0 && values[0] != "" {
+			return values[0]
+		}
+	}
+	return "unknown"
+}
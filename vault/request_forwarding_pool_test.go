@@ -0,0 +1,165 @@
+package vault
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/metadata"
+)
+
+func testForwardingPool(size, maxInFlight int, queueTimeout time.Duration) *forwardingPool {
+	p := &forwardingPool{
+		slots:        make(chan struct{}, maxInFlight),
+		queueTimeout: queueTimeout,
+		node:         "https://127.0.0.1:8201",
+	}
+	for i := 0; i < size; i++ {
+		p.conns = append(p.conns, &forwardingConn{})
+	}
+	return p
+}
+
+func TestForwardingPool_leastLoaded(t *testing.T) {
+	p := testForwardingPool(3, 10, time.Second)
+
+	// The requests are spread over the connections
+	seen := map[*forwardingConn]bool{}
+	var acquired []*forwardingConn
+	for i := 0; i < 3; i++ {
+		fc, err := p.acquire(context.Background())
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		seen[fc] = true
+		acquired = append(acquired, fc)
+	}
+	if len(seen) != 3 {
+		t.Fatalf("expected the requests on 3 connections, got %d", len(seen))
+	}
+
+	// The connection with the fewest requests in flight is picked
+	p.release(acquired[1])
+	fc, err := p.acquire(context.Background())
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if fc != acquired[1] {
+		t.Fatal("expected the least loaded connection")
+	}
+}
+
+func TestForwardingPool_backpressure(t *testing.T) {
+	p := testForwardingPool(2, 2, 50*time.Millisecond)
+
+	first, err := p.acquire(context.Background())
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := p.acquire(context.Background()); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Further requests are rejected once they waited for the queue timeout
+	start := time.Now()
+	if _, err := p.acquire(context.Background()); err != ErrForwardingBackpressure {
+		t.Fatalf("expected backpressure, got %v", err)
+	}
+	if time.Since(start) < 50*time.Millisecond {
+		t.Fatal("expected the request to wait for a slot")
+	}
+
+	// Or as soon as their context is canceled
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := p.acquire(ctx); err != context.Canceled {
+		t.Fatalf("expected a canceled context, got %v", err)
+	}
+
+	// Waiting requests get the slots of the completed ones
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		p.release(first)
+	}()
+	if _, err := p.acquire(context.Background()); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestForwardingNode(t *testing.T) {
+	if node := forwardingNode(context.Background()); node != "unknown" {
+		t.Fatalf("bad: %s", node)
+	}
+
+	ctx := withForwardingNode(context.Background(), "https://127.0.0.2:8201")
+	md, _ := metadata.FromOutgoingContext(ctx)
+	ctx = metadata.NewIncomingContext(context.Background(), md)
+	if node := forwardingNode(ctx); node != "https://127.0.0.2:8201" {
+		t.Fatalf("bad: %s", node)
+	}
+}
+
+func TestRequestForwardingConfig(t *testing.T) {
+	conf := requestForwardingConfig(nil)
+	if conf.PoolSize != defaultForwardingPoolSize || conf.MaxInFlight != defaultForwardingMaxInFlight || conf.QueueTimeout != defaultForwardingQueueTimeout {
+		t.Fatalf("bad: %#v", conf)
+	}
+
+	conf = requestForwardingConfig(&RequestForwardingConfig{PoolSize: 1})
+	if conf.PoolSize != 1 || conf.MaxInFlight != defaultForwardingMaxInFlight {
+		t.Fatalf("bad: %#v", conf)
+	}
+}
+
+func TestCore_ForwardRequest_clearWhileQueued(t *testing.T) {
+	c, _, _ := TestCoreUnsealed(t)
+
+	// Take the only slot of a pool, leaving no connection to close
+	pool := testForwardingPool(1, 1, time.Minute)
+	if _, err := pool.acquire(context.Background()); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	pool.conns = nil
+	ctx, cancel := context.WithCancel(context.Background())
+	c.requestForwardingConnectionLock.Lock()
+	c.rpcForwardingPool = pool
+	c.rpcClientConnContext = ctx
+	c.rpcClientConnCancelFunc = cancel
+	c.requestForwardingConnectionLock.Unlock()
+
+	req, err := http.NewRequest("GET", "https://127.0.0.1:8200/v1/sys/health", strings.NewReader(""))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		_, _, _, err := c.ForwardRequest(req)
+		errCh <- err
+	}()
+
+	// Clearing the forwarding clients doesn't wait for the queued request,
+	// which is aborted
+	time.Sleep(50 * time.Millisecond)
+	cleared := make(chan struct{})
+	go func() {
+		c.requestForwardingConnectionLock.Lock()
+		c.clearForwardingClients()
+		c.requestForwardingConnectionLock.Unlock()
+		close(cleared)
+	}()
+	select {
+	case <-cleared:
+	case <-time.After(5 * time.Second):
+		t.Fatal("clearing the forwarding clients blocked on a queued request")
+	}
+	select {
+	case err := <-errCh:
+		if err != context.Canceled {
+			t.Fatalf("expected a canceled context, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the queued request was not aborted")
+	}
+}
@@ -59,8 +59,9 @@ func (m *EchoRequest) GetClusterAddr() string {
 }
 
 type EchoReply struct {
-	Message      string   `protobuf:"bytes,1,opt,name=message" json:"message,omitempty"`
-	ClusterAddrs []string `protobuf:"bytes,2,rep,name=cluster_addrs,json=clusterAddrs" json:"cluster_addrs,omitempty"`
+	Message       string   `protobuf:"bytes,1,opt,name=message" json:"message,omitempty"`
+	ClusterAddrs  []string `protobuf:"bytes,2,rep,name=cluster_addrs,json=clusterAddrs" json:"cluster_addrs,omitempty"`
+	EmergencySeal bool     `protobuf:"varint,3,opt,name=emergency_seal,json=emergencySeal" json:"emergency_seal,omitempty"`
 }
 
 func (m *EchoReply) Reset()                    { *m = EchoReply{} }
@@ -82,6 +83,13 @@ func (m *EchoReply) GetClusterAddrs() []string {
 	return nil
 }
 
+func (m *EchoReply) GetEmergencySeal() bool {
+	if m != nil {
+		return m.EmergencySeal
+	}
+	return false
+}
+
 func init() {
 	proto.RegisterType((*EchoRequest)(nil), "vault.EchoRequest")
 	proto.RegisterType((*EchoReply)(nil), "vault.EchoReply")
@@ -195,21 +203,23 @@ var _RequestForwarding_serviceDesc = grpc.ServiceDesc{
 func init() { proto.RegisterFile("request_forwarding_service.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 254 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x7c, 0x90, 0x3d, 0x4f, 0xc3, 0x30,
-	0x10, 0x86, 0xdb, 0xf2, 0xa5, 0xb8, 0x05, 0x81, 0x61, 0x88, 0x32, 0x85, 0xb0, 0x74, 0x72, 0x24,
-	0x58, 0x58, 0x18, 0x18, 0x60, 0xe8, 0x98, 0x3f, 0x10, 0xb9, 0xf6, 0x11, 0x47, 0x72, 0x6b, 0x73,
-	0xe7, 0x14, 0x65, 0xe5, 0x97, 0x23, 0x92, 0x94, 0xa6, 0x0b, 0xe3, 0xbd, 0x27, 0x3d, 0xf7, 0xdc,
-	0xcb, 0x52, 0x84, 0xcf, 0x06, 0x28, 0x94, 0x1f, 0x0e, 0xbf, 0x24, 0xea, 0x7a, 0x5b, 0x95, 0x04,
-	0xb8, 0xab, 0x15, 0x08, 0x8f, 0x2e, 0x38, 0x7e, 0xb6, 0x93, 0x8d, 0x0d, 0xc9, 0x73, 0x55, 0x07,
-	0xd3, 0xac, 0x85, 0x72, 0x9b, 0xdc, 0x48, 0x32, 0xb5, 0x72, 0xe8, 0xf3, 0x6e, 0x97, 0x1b, 0xb0,
-	0x1e, 0x30, 0x3f, 0x20, 0xf2, 0xd0, 0x7a, 0xa0, 0x1e, 0x90, 0xad, 0xd8, 0xfc, 0x4d, 0x19, 0x57,
-	0xf4, 0x87, 0x78, 0xcc, 0x2e, 0x36, 0x40, 0x24, 0x2b, 0x88, 0xa7, 0xe9, 0x74, 0x19, 0x15, 0xfb,
-	0x91, 0xdf, 0xb3, 0x85, 0xb2, 0x0d, 0x05, 0xc0, 0x52, 0x6a, 0x8d, 0xf1, 0xac, 0x5b, 0xcf, 0x87,
-	0xec, 0x55, 0x6b, 0xcc, 0x56, 0x2c, 0xea, 0x59, 0xde, 0xb6, 0xff, 0x90, 0x1e, 0xd8, 0xe5, 0x98,
-	0x44, 0xf1, 0x2c, 0x3d, 0x59, 0x46, 0xc5, 0x62, 0x84, 0xa2, 0xc7, 0xef, 0x29, 0xbb, 0x19, 0xa4,
-	0xde, 0xff, 0xcc, 0xf9, 0x0b, 0xbb, 0x1a, 0xa6, 0xbd, 0xf0, 0xad, 0x38, 0x3c, 0x26, 0x86, 0x30,
-	0xb9, 0x3b, 0x0e, 0xc9, 0xbb, 0x2d, 0x41, 0x36, 0xe1, 0x82, 0x9d, 0xfe, 0x0a, 0x72, 0x2e, 0xba,
-	0x6a, 0xc4, 0xe8, 0xf3, 0xe4, 0xfa, 0x28, 0xf3, 0xb6, 0xcd, 0x26, 0xeb, 0xf3, 0xae, 0xa3, 0xa7,
-	0x9f, 0x00, 0x00, 0x00, 0xff, 0xff, 0x6a, 0x13, 0x7f, 0xc2, 0x88, 0x01, 0x00, 0x00,
+	// 275 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x7c, 0x90, 0xbd, 0x4e, 0xc3, 0x30,
+	0x14, 0x85, 0x9b, 0x96, 0xbf, 0xb8, 0x3f, 0x02, 0xc3, 0x10, 0x65, 0x0a, 0x41, 0x48, 0x99, 0x1c,
+	0x09, 0x16, 0x16, 0x06, 0x06, 0x18, 0x18, 0xc3, 0x03, 0x44, 0xae, 0x73, 0x49, 0x22, 0x39, 0xb1,
+	0xeb, 0xeb, 0x14, 0x65, 0xe5, 0xc9, 0x11, 0x49, 0xda, 0xa6, 0x4b, 0xc7, 0xfb, 0x1d, 0xe9, 0xb3,
+	0xcf, 0x21, 0x81, 0x81, 0x4d, 0x03, 0x68, 0xd3, 0x6f, 0x65, 0x7e, 0xb8, 0xc9, 0xca, 0x3a, 0x4f,
+	0x11, 0xcc, 0xb6, 0x14, 0xc0, 0xb4, 0x51, 0x56, 0xd1, 0xf3, 0x2d, 0x6f, 0xa4, 0xf5, 0x5f, 0xf2,
+	0xd2, 0x16, 0xcd, 0x9a, 0x09, 0x55, 0xc5, 0x05, 0xc7, 0xa2, 0x14, 0xca, 0xe8, 0xb8, 0xcb, 0xe2,
+	0x02, 0xa4, 0x06, 0x13, 0x1f, 0x14, 0xb1, 0x6d, 0x35, 0x60, 0x2f, 0x08, 0x3f, 0xc9, 0xfc, 0x5d,
+	0x14, 0x2a, 0xe9, 0x1f, 0xa2, 0x1e, 0xb9, 0xac, 0x00, 0x91, 0xe7, 0xe0, 0x39, 0x81, 0x13, 0xb9,
+	0xc9, 0xee, 0xa4, 0xf7, 0x64, 0x21, 0x64, 0x83, 0x16, 0x4c, 0xca, 0xb3, 0xcc, 0x78, 0xd3, 0x2e,
+	0x9e, 0x0f, 0xec, 0x2d, 0xcb, 0x4c, 0xb8, 0x21, 0x6e, 0xef, 0xd2, 0xb2, 0x3d, 0x61, 0x7a, 0x20,
+	0xcb, 0xb1, 0x09, 0xbd, 0x69, 0x30, 0x8b, 0xdc, 0x64, 0x31, 0x52, 0x21, 0x7d, 0x24, 0x2b, 0xa8,
+	0xc0, 0xe4, 0x50, 0x8b, 0x36, 0x45, 0xe0, 0xd2, 0x9b, 0x05, 0x4e, 0x74, 0x95, 0x2c, 0xf7, 0xf4,
+	0x0b, 0xb8, 0x7c, 0xfa, 0x75, 0xc8, 0xcd, 0xf0, 0xf7, 0x8f, 0x7d, 0x41, 0xfa, 0x4a, 0x56, 0xc3,
+	0xb5, 0xeb, 0x75, 0xcb, 0x0e, 0xfd, 0xd9, 0x00, 0xfd, 0xbb, 0x63, 0x88, 0x5a, 0xd5, 0x08, 0xe1,
+	0x84, 0x32, 0x72, 0xf6, 0xdf, 0x83, 0x52, 0xd6, 0x2d, 0xc8, 0x46, 0x03, 0xf9, 0xd7, 0x47, 0x4c,
+	0xcb, 0x36, 0x9c, 0xac, 0x2f, 0xba, 0x29, 0x9f, 0xff, 0x06, 0x00, 0x9e, 0x30, 0x02, 0x99, 0xaf,
+	0x01, 0x00, 0x00,
 }
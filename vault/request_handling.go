@@ -1,12 +1,14 @@
 package vault
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 
 	"github.com/armon/go-metrics"
 	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/go-uuid"
 	"github.com/hashicorp/vault/helper/consts"
 	"github.com/hashicorp/vault/helper/identity"
 	"github.com/hashicorp/vault/helper/jsonutil"
@@ -18,6 +20,8 @@ import (
 
 // HandleRequest is used to handle a new incoming request
 func (c *Core) HandleRequest(req *logical.Request) (resp *logical.Response, err error) {
+	defer c.trackInFlight()()
+
 	c.stateLock.RLock()
 	defer c.stateLock.RUnlock()
 	if c.sealed {
@@ -26,6 +30,20 @@ func (c *Core) HandleRequest(req *logical.Request) (resp *logical.Response, err
 	if c.standby {
 		return nil, consts.ErrStandby
 	}
+	if err := c.emergencySealPendingErr(); err != nil {
+		return nil, err
+	}
+
+	// Requests which didn't come through the HTTP API are assigned an
+	// identifier here, correlating their audit entries and log lines
+	if req.ID == "" {
+		id, err := uuid.GenerateUUID()
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate identifier for the request: %v", err)
+		}
+		req.ID = id
+	}
+	defer c.trackRequest(req)()
 
 	// Allowing writing to a path ending in / makes it extremely difficult to
 	// understand user intent for the filesystem-like backends (kv,
@@ -35,10 +53,22 @@ func (c *Core) HandleRequest(req *logical.Request) (resp *logical.Response, err
 	// backends. Basically, it's all just terrible, so don't allow it.
 	if strings.HasSuffix(req.Path, "/") &&
 		(req.Operation == logical.UpdateOperation ||
-			req.Operation == logical.CreateOperation) {
+			req.Operation == logical.CreateOperation ||
+			req.Operation == logical.PatchOperation) {
 		return logical.ErrorResponse("cannot write to a path ending in '/'"), nil
 	}
 
+	// Resolve the namespace of the request and the path it is routed to
+	if err := c.routeNamespace(req); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	// Reject the requests exceeding a rate limit quota before doing any work
+	// on their behalf
+	if err := c.checkRateLimitQuotas(req); err != nil {
+		return nil, err
+	}
+
 	var auth *logical.Auth
 	if c.router.LoginPath(req.Path) {
 		resp, auth, err = c.handleLoginRequest(req)
@@ -46,10 +76,24 @@ func (c *Core) HandleRequest(req *logical.Request) (resp *logical.Response, err
 		resp, auth, err = c.handleRequest(req)
 	}
 
+	if err == nil && (resp == nil || !resp.IsError()) && isStateChangingOperation(req.Operation) {
+		c.advanceStateIndex()
+	}
+
 	// Ensure we don't leak internal data
 	if resp != nil {
 		if resp.Secret != nil {
 			resp.Secret.InternalData = nil
+
+			// Attach rotation hints for registered leases
+			resp.Secret.Contract = nil
+			if resp.Secret.LeaseID != "" {
+				contract, err := c.expiration.leaseContract(resp.Secret, time.Now())
+				if err != nil {
+					c.logger.Error("core: failed to compute lease contract", "lease_id", resp.Secret.LeaseID, "request_id", req.ID, "error", err)
+				}
+				resp.Secret.Contract = contract
+			}
 		}
 		if resp.Auth != nil {
 			resp.Auth.InternalData = nil
@@ -97,7 +141,7 @@ func (c *Core) HandleRequest(req *logical.Request) (resp *logical.Response, err
 		httpResp := &logical.HTTPResponse{}
 		err := jsonutil.DecodeJSON(resp.Data[logical.HTTPRawBody].([]byte), httpResp)
 		if err != nil {
-			c.logger.Error("core: failed to unmarshal wrapped HTTP response for audit logging", "error", err)
+			c.logger.Error("core: failed to unmarshal wrapped HTTP response for audit logging", "request_id", req.ID, "error", err)
 			return nil, ErrInternalError
 		}
 
@@ -106,7 +150,7 @@ func (c *Core) HandleRequest(req *logical.Request) (resp *logical.Response, err
 
 	// Create an audit trail of the response
 	if auditErr := c.auditBroker.LogResponse(auth, req, auditResp, c.auditedHeaders, err); auditErr != nil {
-		c.logger.Error("core: failed to audit response", "request_path", req.Path, "error", auditErr)
+		c.logger.Error("core: failed to audit response", "request_path", req.Path, "request_id", req.ID, "error", auditErr)
 		return nil, ErrInternalError
 	}
 
@@ -124,7 +168,7 @@ func (c *Core) handleRequest(req *logical.Request) (retResp *logical.Response, r
 		var err error
 		te, err = c.tokenStore.UseToken(te)
 		if err != nil {
-			c.logger.Error("core: failed to use token", "error", err)
+			c.logger.Error("core: failed to use token", "request_id", req.ID, "error", err)
 			retErr = multierror.Append(retErr, ErrInternalError)
 			return nil, nil, retErr
 		}
@@ -140,7 +184,7 @@ func (c *Core) handleRequest(req *logical.Request) (retResp *logical.Response, r
 			defer func(id string) {
 				err = c.tokenStore.Revoke(id)
 				if err != nil {
-					c.logger.Error("core: failed to revoke token", "error", err)
+					c.logger.Error("core: failed to revoke token", "request_id", req.ID, "error", err)
 					retResp = nil
 					retAuth = nil
 					retErr = multierror.Append(retErr, ErrInternalError)
@@ -154,19 +198,34 @@ func (c *Core) handleRequest(req *logical.Request) (retResp *logical.Response, r
 			}(te.ID)
 		}
 	}
+	if pending, ok := ctErr.(*controlGroupPendingError); ok {
+		// The request was parked by a control group, tell the requester how
+		// to follow up on it
+		if err := c.auditBroker.LogRequest(auth, req, c.auditedHeaders, nil); err != nil {
+			c.logger.Error("core: failed to audit request", "path", req.Path, "request_id", req.ID, "error", err)
+			retErr = multierror.Append(retErr, ErrInternalError)
+			return nil, auth, retErr
+		}
+		return pending.response(), auth, nil
+	}
 	if ctErr != nil {
 		// If it is an internal error we return that, otherwise we
 		// return invalid request so that the status codes can be correct
 		var errType error
-		switch ctErr {
-		case ErrInternalError, logical.ErrPermissionDenied:
-			errType = ctErr
+		switch ctErr.(type) {
+		case *requestRuleError:
+			errType = logical.ErrPermissionDenied
 		default:
-			errType = logical.ErrInvalidRequest
+			switch ctErr {
+			case ErrInternalError, logical.ErrPermissionDenied:
+				errType = ctErr
+			default:
+				errType = logical.ErrInvalidRequest
+			}
 		}
 
 		if err := c.auditBroker.LogRequest(auth, req, c.auditedHeaders, ctErr); err != nil {
-			c.logger.Error("core: failed to audit request", "path", req.Path, "error", err)
+			c.logger.Error("core: failed to audit request", "path", req.Path, "request_id", req.ID, "error", err)
 		}
 
 		if errType != nil {
@@ -178,12 +237,19 @@ func (c *Core) handleRequest(req *logical.Request) (retResp *logical.Response, r
 		return logical.ErrorResponse(ctErr.Error()), auth, retErr
 	}
 
+	// Batch tokens are not stored, so their cubbyhole could never be
+	// destroyed along with them
+	if te != nil && te.tokenType() == tokenTypeBatch && strings.HasPrefix(req.Path, "cubbyhole/") {
+		retErr = multierror.Append(retErr, logical.ErrInvalidRequest)
+		return logical.ErrorResponse("batch tokens cannot use the cubbyhole"), auth, retErr
+	}
+
 	// Attach the display name
 	req.DisplayName = auth.DisplayName
 
 	// Create an audit trail of the request
 	if err := c.auditBroker.LogRequest(auth, req, c.auditedHeaders, nil); err != nil {
-		c.logger.Error("core: failed to audit request", "path", req.Path, "error", err)
+		c.logger.Error("core: failed to audit request", "path", req.Path, "request_id", req.ID, "error", err)
 		retErr = multierror.Append(retErr, ErrInternalError)
 		return nil, auth, retErr
 	}
@@ -270,9 +336,33 @@ func (c *Core) handleRequest(req *logical.Request) (retResp *logical.Response, r
 		}
 
 		if registerLease {
-			leaseID, err := c.expiration.Register(req, resp)
+			// Batch tokens can't be revoked, so the leases they create are
+			// tied to their parent instead, if any
+			registerReq := req
+			if te != nil && te.tokenType() == tokenTypeBatch && te.Parent != "" {
+				parentReq := *req
+				parentReq.ClientToken = te.Parent
+				registerReq = &parentReq
+			}
+
+			// Reject the secret if it would exceed a lease count quota
+			releaseQuotas, err := c.reserveLeaseCountQuotas(req.Path, req.EntityID)
+			if err != nil {
+				revResp, revErr := c.router.Route(logical.RevokeRequest(req.Path, resp.Secret, resp.Data))
+				if revErr == nil && revResp != nil && revResp.IsError() {
+					revErr = revResp.Error()
+				}
+				if revErr != nil {
+					c.logger.Error("core: failed to revoke secret exceeding a lease count quota", "request_path", req.Path, "request_id", req.ID, "error", revErr)
+				}
+				retErr = multierror.Append(retErr, err)
+				return nil, auth, retErr
+			}
+
+			leaseID, err := c.expiration.Register(registerReq, resp)
+			releaseQuotas()
 			if err != nil {
-				c.logger.Error("core: failed to register lease", "request_path", req.Path, "error", err)
+				c.logger.Error("core: failed to register lease", "request_path", req.Path, "request_id", req.ID, "error", err)
 				retErr = multierror.Append(retErr, ErrInternalError)
 				return nil, auth, retErr
 			}
@@ -285,7 +375,7 @@ func (c *Core) handleRequest(req *logical.Request) (retResp *logical.Response, r
 	// since it does not need to be re-registered
 	if resp != nil && resp.Auth != nil && !strings.HasPrefix(req.Path, "auth/token/renew") {
 		if !strings.HasPrefix(req.Path, "auth/token/") {
-			c.logger.Error("core: unexpected Auth response for non-token backend", "request_path", req.Path)
+			c.logger.Error("core: unexpected Auth response for non-token backend", "request_path", req.Path, "request_id", req.ID)
 			retErr = multierror.Append(retErr, ErrInternalError)
 			return nil, auth, retErr
 		}
@@ -294,17 +384,34 @@ func (c *Core) handleRequest(req *logical.Request) (retResp *logical.Response, r
 		// here because roles allow suffixes.
 		te, err := c.tokenStore.Lookup(resp.Auth.ClientToken)
 		if err != nil {
-			c.logger.Error("core: failed to look up token", "error", err)
+			c.logger.Error("core: failed to look up token", "request_id", req.ID, "error", err)
 			retErr = multierror.Append(retErr, ErrInternalError)
 			return nil, auth, retErr
 		}
 
-		if err := c.expiration.RegisterAuth(te.Path, resp.Auth); err != nil {
-			c.tokenStore.Revoke(te.ID)
-			c.logger.Error("core: failed to register token lease", "request_path", req.Path, "error", err)
+		if te == nil {
+			c.logger.Error("core: created token not found", "request_path", req.Path, "request_id", req.ID)
 			retErr = multierror.Append(retErr, ErrInternalError)
 			return nil, auth, retErr
 		}
+
+		// Batch tokens have no lease
+		if te.tokenType() != tokenTypeBatch {
+			releaseQuotas, err := c.reserveLeaseCountQuotas(te.Path, te.EntityID)
+			if err != nil {
+				c.tokenStore.Revoke(te.ID)
+				retErr = multierror.Append(retErr, err)
+				return nil, auth, retErr
+			}
+			err = c.expiration.RegisterAuth(te.Path, resp.Auth)
+			releaseQuotas()
+			if err != nil {
+				c.tokenStore.Revoke(te.ID)
+				c.logger.Error("core: failed to register token lease", "request_path", req.Path, "request_id", req.ID, "error", err)
+				retErr = multierror.Append(retErr, ErrInternalError)
+				return nil, auth, retErr
+			}
+		}
 	}
 
 	if resp != nil &&
@@ -328,17 +435,22 @@ func (c *Core) handleLoginRequest(req *logical.Request) (*logical.Response, *log
 
 	// Create an audit trail of the request, auth is not available on login requests
 	if err := c.auditBroker.LogRequest(nil, req, c.auditedHeaders, nil); err != nil {
-		c.logger.Error("core: failed to audit request", "path", req.Path, "error", err)
+		c.logger.Error("core: failed to audit request", "path", req.Path, "request_id", req.ID, "error", err)
 		return nil, nil, ErrInternalError
 	}
 
 	// The token store uses authentication even when creating a new token,
 	// so it's handled in handleRequest. It should not be reached here.
 	if strings.HasPrefix(req.Path, "auth/token/") {
-		c.logger.Error("core: unexpected login request for token backend", "request_path", req.Path)
+		c.logger.Error("core: unexpected login request for token backend", "request_path", req.Path, "request_id", req.ID)
 		return nil, nil, ErrInternalError
 	}
 
+	// Let the request rule hooks veto the login, with no token to evaluate
+	if err := c.evaluateRequestRules(req, nil); err != nil {
+		return logical.ErrorResponse(err.Error()), nil, logical.ErrPermissionDenied
+	}
+
 	// Route the request
 	resp, routeErr := c.router.Route(req)
 	if resp != nil {
@@ -381,7 +493,7 @@ func (c *Core) handleLoginRequest(req *logical.Request) (*logical.Response, *log
 
 	// A login request should never return a secret!
 	if resp != nil && resp.Secret != nil {
-		c.logger.Error("core: unexpected Secret response for login path", "request_path", req.Path)
+		c.logger.Error("core: unexpected Secret response for login path", "request_path", req.Path, "request_id", req.ID)
 		return nil, nil, ErrInternalError
 	}
 
@@ -419,15 +531,39 @@ func (c *Core) handleLoginRequest(req *logical.Request) (*logical.Response, *log
 				if entity == nil {
 					return nil, nil, fmt.Errorf("failed to create an entity for the authenticated alias")
 				}
+			} else if err := c.identityStore.UpdateAliasMetadata(entity.ID, auth.Alias); err != nil {
+				return nil, nil, err
 			}
 
 			auth.EntityID = entity.ID
+
+			// Refresh the memberships of the entity in the external groups
+			// of the mount, creating the groups if the mount is tuned to
+			var autoCreate bool
+			if me := c.router.MatchingMountByAccessor(auth.Alias.MountAccessor); me != nil {
+				autoCreate = me.Config.AutoCreateGroupAliases
+			}
+			err = c.identityStore.refreshExternalGroupMembershipsByEntityID(entity.ID, auth.Alias.MountAccessor, auth.GroupAliases, autoCreate)
+			if err != nil {
+				return nil, nil, err
+			}
 		}
 
 		if strutil.StrListSubset(auth.Policies, []string{"root"}) {
 			return logical.ErrorResponse("authentication backends cannot create root tokens"), nil, logical.ErrInvalidRequest
 		}
 
+		// Reject the login if it doesn't satisfy the MFA enforcements
+		// matching its mount, entity or groups
+		mfaMethods, mfaResp, err := c.identityStore.enforceLoginMFA(req, auth)
+		if err != nil {
+			c.logger.Error("core: failed to enforce MFA on login", "request_path", req.Path, "request_id", req.ID, "error", err)
+			return nil, nil, ErrInternalError
+		}
+		if mfaResp != nil {
+			return mfaResp, nil, logical.ErrPermissionDenied
+		}
+
 		// Determine the source of the login
 		source := c.router.MatchingMount(req.Path)
 		source = strings.TrimPrefix(source, credentialRoutePrefix)
@@ -438,7 +574,7 @@ func (c *Core) handleLoginRequest(req *logical.Request) (*logical.Response, *log
 
 		sysView := c.router.MatchingSystemView(req.Path)
 		if sysView == nil {
-			c.logger.Error("core: unable to look up sys view for login path", "request_path", req.Path)
+			c.logger.Error("core: unable to look up sys view for login path", "request_path", req.Path, "request_id", req.ID)
 			return nil, nil, ErrInternalError
 		}
 
@@ -452,8 +588,16 @@ func (c *Core) handleLoginRequest(req *logical.Request) (*logical.Response, *log
 			auth.TTL = sysView.MaxLeaseTTL()
 		}
 
+		// The token belongs to the namespace of the auth mount
+		me := c.router.MatchingMountEntry(req.Path)
+		if me == nil {
+			c.logger.Error("core: unable to look up mount entry for login path", "request_path", req.Path, "request_id", req.ID)
+			return nil, nil, ErrInternalError
+		}
+
 		// Generate a token
 		te := TokenEntry{
+			NamespaceID:  me.NamespaceID,
 			Path:         req.Path,
 			Policies:     auth.Policies,
 			Meta:         auth.Metadata,
@@ -461,6 +605,8 @@ func (c *Core) handleLoginRequest(req *logical.Request) (*logical.Response, *log
 			CreationTime: time.Now().Unix(),
 			TTL:          auth.TTL,
 			NumUses:      auth.NumUses,
+			BoundCIDRs:   auth.BoundCIDRs,
+			MFAMethods:   mfaMethods,
 		}
 
 		te.Policies = policyutil.SanitizePolicies(te.Policies, true)
@@ -472,9 +618,33 @@ func (c *Core) handleLoginRequest(req *logical.Request) (*logical.Response, *log
 			}
 		}
 
-		if err := c.tokenStore.create(&te); err != nil {
-			c.logger.Error("core: failed to create token", "error", err)
-			return nil, auth, ErrInternalError
+		// Issue batch tokens if the mount is tuned to
+		switch me.Config.TokenType {
+		case tokenTypeBatch:
+			if te.NumUses != 0 {
+				return logical.ErrorResponse("batch tokens cannot have a limited number of uses"), nil, logical.ErrInvalidRequest
+			}
+
+			// Batch tokens carry the entity inline and have no lease, so
+			// they can't be renewed
+			te.EntityID = auth.EntityID
+			auth.Renewable = false
+			if err := c.tokenStore.createBatch(&te); err != nil {
+				c.logger.Error("core: failed to create batch token", "request_id", req.ID, "error", err)
+				return nil, auth, ErrInternalError
+			}
+
+		default:
+			// Reject the login if its token would exceed a lease count quota
+			releaseQuotas, err := c.reserveLeaseCountQuotas(te.Path, auth.EntityID)
+			if err != nil {
+				return nil, nil, err
+			}
+			defer releaseQuotas()
+			if err := c.tokenStore.create(&te); err != nil {
+				c.logger.Error("core: failed to create token", "request_id", req.ID, "error", err)
+				return nil, auth, ErrInternalError
+			}
 		}
 
 		// Populate the client token and accessor
@@ -483,10 +653,33 @@ func (c *Core) handleLoginRequest(req *logical.Request) (*logical.Response, *log
 		auth.Policies = te.Policies
 
 		// Register with the expiration manager
-		if err := c.expiration.RegisterAuth(te.Path, auth); err != nil {
-			c.tokenStore.Revoke(te.ID)
-			c.logger.Error("core: failed to register token lease", "request_path", req.Path, "error", err)
-			return nil, auth, ErrInternalError
+		if te.tokenType() != tokenTypeBatch {
+			if err := c.expiration.RegisterAuth(te.Path, auth); err != nil {
+				c.tokenStore.Revoke(te.ID)
+				c.logger.Error("core: failed to register token lease", "request_path", req.Path, "request_id", req.ID, "error", err)
+				return nil, auth, ErrInternalError
+			}
+		}
+
+		// Record the login in the entity's activity timeline. The login has
+		// already succeeded at this point, so failures are only logged.
+		if auth.EntityID != "" {
+			record := &entityLoginRecord{
+				Time:          time.Now().UTC(),
+				MountAccessor: req.MountAccessor,
+				MountPath:     c.router.MatchingMount(req.Path),
+				MountType:     req.MountType,
+				AliasName:     auth.Alias.Name,
+				TokenAccessor: te.Accessor,
+				Policies:      te.Policies,
+				TTL:           int64(te.TTL.Seconds()),
+			}
+			if req.Connection != nil {
+				record.RemoteAddr = req.Connection.RemoteAddr
+			}
+			if err := c.identityStore.recordEntityLogin(auth.EntityID, record); err != nil {
+				c.logger.Error("core: failed to record entity login", "entity_id", auth.EntityID, "error", err)
+			}
 		}
 
 		// Attach the display name, might be used by audit backends
@@ -495,3 +688,60 @@ func (c *Core) handleLoginRequest(req *logical.Request) (*logical.Response, *log
 
 	return resp, auth, routeErr
 }
+
+// authorizeRootRequest checks that a request served outside of the logical
+// backends has root privileges in the root namespace, and audits it. The
+// state lock is released once the request is authorized.
+func (c *Core) authorizeRootRequest(req *logical.Request) (retErr error) {
+	c.stateLock.RLock()
+	defer c.stateLock.RUnlock()
+	if c.sealed {
+		return consts.ErrSealed
+	}
+	if c.standby {
+		return consts.ErrStandby
+	}
+
+	acl, te, _, err := c.fetchACLTokenEntryAndEntity(req.ClientToken)
+	if err != nil {
+		return err
+	}
+
+	// Audit-log the request before going any further
+	auth := &logical.Auth{
+		ClientToken: req.ClientToken,
+		Policies:    te.Policies,
+		Metadata:    te.Meta,
+		DisplayName: te.DisplayName,
+	}
+	if err := c.auditBroker.LogRequest(auth, req, c.auditedHeaders, nil); err != nil {
+		c.logger.Error("core: failed to audit request", "request_path", req.Path, "error", err)
+		return errors.New("failed to audit request, cannot continue")
+	}
+
+	// Attempt to use the token (decrement num_uses)
+	te, err = c.tokenStore.UseToken(te)
+	if err != nil {
+		c.logger.Error("core: failed to use token", "error", err)
+		return ErrInternalError
+	}
+	if te == nil {
+		// Token has been revoked
+		return logical.ErrPermissionDenied
+	}
+	if te.NumUses == -1 {
+		// Token needs to be revoked
+		defer func(id string) {
+			if err := c.tokenStore.Revoke(id); err != nil {
+				c.logger.Error("core: token needed revocation after request but failed to revoke", "error", err)
+				retErr = multierror.Append(retErr, ErrInternalError)
+			}
+		}(te.ID)
+	}
+
+	allowed, rootPrivs := acl.AllowOperation(req)
+	if !allowed || !rootPrivs || te.NamespaceID != "" {
+		return logical.ErrPermissionDenied
+	}
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/audit"
 	credUserpass "github.com/hashicorp/vault/builtin/credential/userpass"
 	"github.com/hashicorp/vault/logical"
 )
@@ -140,3 +141,242 @@ func TestRequestHandling_LoginWrapping(t *testing.T) {
 		t.Fatalf("bad: %#v", resp)
 	}
 }
+
+func TestRequestHandling_LeaseContract(t *testing.T) {
+	core, _, root := TestCoreUnsealed(t)
+
+	core.logicalBackends["kv"] = LeasedPassthroughBackendFactory
+
+	meUUID, _ := uuid.GenerateUUID()
+	err := core.mount(&MountEntry{
+		Table: mountTableType,
+		UUID:  meUUID,
+		Path:  "leasetest",
+		Type:  "kv",
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req := &logical.Request{
+		Path:        "leasetest/foo",
+		ClientToken: root,
+		Operation:   logical.UpdateOperation,
+		Data: map[string]interface{}{
+			"zip": "zap",
+			"ttl": "3h",
+		},
+	}
+	if _, err := core.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	start := time.Now()
+	req = &logical.Request{
+		Path:        "leasetest/foo",
+		ClientToken: root,
+		Operation:   logical.ReadOperation,
+	}
+	resp, err := core.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp == nil || resp.Secret == nil || resp.Secret.LeaseID == "" {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	contract := resp.Secret.Contract
+	if contract == nil {
+		t.Fatal("expected lease contract")
+	}
+	if contract.RenewEndpoint != "sys/leases/renew" {
+		t.Fatalf("bad: %#v", contract)
+	}
+	if contract.EarliestRotation.Before(start.Add(2*time.Hour)) ||
+		contract.EarliestRotation.After(time.Now().Add(2*time.Hour)) {
+		t.Fatalf("bad earliest rotation: %s", contract.EarliestRotation)
+	}
+	if contract.GuaranteedValidUntil.Before(start.Add(3*time.Hour)) ||
+		contract.GuaranteedValidUntil.After(time.Now().Add(3*time.Hour)) {
+		t.Fatalf("bad guaranteed valid until: %s", contract.GuaranteedValidUntil)
+	}
+}
+
+func TestRequestHandling_LeaseContract_tokenTTL(t *testing.T) {
+	core, _, root := TestCoreUnsealed(t)
+
+	core.logicalBackends["kv"] = LeasedPassthroughBackendFactory
+
+	meUUID, _ := uuid.GenerateUUID()
+	err := core.mount(&MountEntry{
+		Table: mountTableType,
+		UUID:  meUUID,
+		Path:  "leasetest",
+		Type:  "kv",
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req := &logical.Request{
+		Path:        "leasetest/foo",
+		ClientToken: root,
+		Operation:   logical.UpdateOperation,
+		Data: map[string]interface{}{
+			"zip": "zap",
+			"ttl": "3h",
+		},
+	}
+	if _, err := core.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The secret is read with a token that expires before its lease
+	req = &logical.Request{
+		Path:        "auth/token/create",
+		ClientToken: root,
+		Operation:   logical.UpdateOperation,
+		Data: map[string]interface{}{
+			"ttl": "30m",
+		},
+	}
+	resp, err := core.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp == nil || resp.Auth == nil {
+		t.Fatalf("bad: %#v", resp)
+	}
+	token := resp.Auth.ClientToken
+
+	start := time.Now()
+	req = &logical.Request{
+		Path:        "leasetest/foo",
+		ClientToken: token,
+		Operation:   logical.ReadOperation,
+	}
+	resp, err = core.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp == nil || resp.Secret == nil || resp.Secret.LeaseID == "" {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	contract := resp.Secret.Contract
+	if contract == nil {
+		t.Fatal("expected lease contract")
+	}
+	if contract.GuaranteedValidUntil.Before(start.Add(29*time.Minute)) ||
+		contract.GuaranteedValidUntil.After(time.Now().Add(30*time.Minute)) {
+		t.Fatalf("bad guaranteed valid until: %s", contract.GuaranteedValidUntil)
+	}
+	if contract.EarliestRotation.Before(start.Add(19*time.Minute)) ||
+		contract.EarliestRotation.After(time.Now().Add(20*time.Minute)) {
+		t.Fatalf("bad earliest rotation: %s", contract.EarliestRotation)
+	}
+}
+
+func TestRequestHandling_LoginBatchToken(t *testing.T) {
+	core, _, root := TestCoreUnsealed(t)
+	core.credentialBackends["userpass"] = credUserpass.Factory
+
+	for _, req := range []*logical.Request{
+		{
+			Path: "sys/auth/userpass",
+			Data: map[string]interface{}{
+				"type": "userpass",
+			},
+		},
+		{
+			Path: "sys/auth/userpass/tune",
+			Data: map[string]interface{}{
+				"token_type": "batch",
+			},
+		},
+		{
+			Path: "auth/userpass/users/test",
+			Data: map[string]interface{}{
+				"password": "foo",
+				"policies": "default",
+			},
+		},
+	} {
+		req.ClientToken = root
+		req.Operation = logical.UpdateOperation
+		resp, err := core.HandleRequest(req)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: path: %q, resp: %#v, err: %v", req.Path, resp, err)
+		}
+	}
+
+	resp, err := core.HandleRequest(&logical.Request{
+		Path:      "auth/userpass/login/test",
+		Operation: logical.UpdateOperation,
+		Data: map[string]interface{}{
+			"password": "foo",
+		},
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp == nil || resp.Auth == nil || resp.Auth.Renewable || resp.Auth.Accessor != "" {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	te, err := core.tokenStore.Lookup(resp.Auth.ClientToken)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if te == nil || te.Type != tokenTypeBatch || te.Parent != "" || te.EntityID != resp.Auth.EntityID {
+		t.Fatalf("bad: %#v", te)
+	}
+
+	// The token is not tracked by the expiration manager
+	leaseTimes, err := core.expiration.FetchLeaseTimesByToken(te.Path, te.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if leaseTimes != nil {
+		t.Fatalf("bad: %#v", leaseTimes)
+	}
+}
+
+func TestRequestHandling_RequestID(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	noop := &NoopAudit{}
+	c.auditBackends["noop"] = func(config *audit.BackendConfig) (audit.Backend, error) {
+		noop.Config = config
+		return noop, nil
+	}
+	if err := c.enableAudit(&MountEntry{
+		Table: auditTableType,
+		Path:  "noop",
+		Type:  "noop",
+	}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Requests without an identifier are assigned one
+	req := logical.TestRequest(t, logical.ReadOperation, "sys/mounts")
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := uuid.ParseUUID(req.ID); err != nil {
+		t.Fatalf("bad: %q", req.ID)
+	}
+
+	// The identifier of the request is kept, and used by the audit entries
+	req = logical.TestRequest(t, logical.ReadOperation, "secret/missing")
+	req.ID = "test-request-id"
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(noop.Req) != 2 || noop.Req[1].ID != "test-request-id" ||
+		len(noop.RespReq) != 2 || noop.RespReq[1].ID != "test-request-id" {
+		t.Fatalf("bad: %#v %#v", noop.Req, noop.RespReq)
+	}
+}
@@ -0,0 +1,356 @@
+package vault
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/vault/helper/cel"
+	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/logical"
+)
+
+const (
+	// endpointPolicySubPath is the sub-path of the system barrier view where
+	// the endpoint governing policies are stored
+	endpointPolicySubPath = "policies/egp/"
+
+	// EnforcementAdvisory only logs the requests failing a policy
+	EnforcementAdvisory = "advisory"
+
+	// EnforcementHardMandatory rejects the requests failing a policy
+	EnforcementHardMandatory = "hard-mandatory"
+)
+
+// RequestRuleInput is the metadata of a request evaluated by the request rule
+// hooks. The token fields are empty on login requests.
+type RequestRuleInput struct {
+	Path       string
+	Operation  logical.Operation
+	RemoteAddr string
+	Time       time.Time
+
+	EntityID    string
+	DisplayName string
+	Policies    []string
+	TokenMeta   map[string]string
+	TokenType   string
+	MFAMethods  []string
+}
+
+func newRequestRuleInput(req *logical.Request, te *TokenEntry) *RequestRuleInput {
+	input := &RequestRuleInput{
+		Path:      req.Path,
+		Operation: req.Operation,
+		Time:      time.Now().UTC(),
+	}
+	if req.Connection != nil {
+		input.RemoteAddr = req.Connection.RemoteAddr
+	}
+	if te != nil {
+		input.EntityID = te.EntityID
+		input.DisplayName = te.DisplayName
+		input.Policies = te.Policies
+		input.TokenMeta = te.Meta
+		input.TokenType = te.tokenType()
+		input.MFAMethods = te.MFAMethods
+	}
+	return input
+}
+
+// vars returns the variables available to the rules of the endpoint
+// governing policies
+func (i *RequestRuleInput) vars() map[string]interface{} {
+	meta := i.TokenMeta
+	if meta == nil {
+		meta = map[string]string{}
+	}
+	return map[string]interface{}{
+		"request": map[string]interface{}{
+			"path":        i.Path,
+			"operation":   string(i.Operation),
+			"remote_addr": i.RemoteAddr,
+		},
+		"token": map[string]interface{}{
+			"entity_id":    i.EntityID,
+			"display_name": i.DisplayName,
+			"policies":     append([]string{}, i.Policies...),
+			"meta":         meta,
+			"type":         i.TokenType,
+		},
+		"time": map[string]interface{}{
+			"hour":    i.Time.Hour(),
+			"minute":  i.Time.Minute(),
+			"weekday": int(i.Time.Weekday()),
+			"unix":    i.Time.Unix(),
+		},
+		"mfa": map[string]interface{}{
+			"methods":   append([]string{}, i.MFAMethods...),
+			"validated": len(i.MFAMethods) > 0,
+		},
+	}
+}
+
+// RequestRuleHook is a policy module evaluating the metadata of the requests
+// once their token is authorized. Returning an error vetoes the request.
+type RequestRuleHook interface {
+	// Name identifies the hook in the logs and the errors
+	Name() string
+
+	// EvaluateRequest returns an error if the request must be rejected
+	EvaluateRequest(input *RequestRuleInput) error
+}
+
+// requestRuleError is returned when a request is vetoed by a hook
+type requestRuleError struct {
+	hook string
+	err  error
+}
+
+func (e *requestRuleError) Error() string {
+	return fmt.Sprintf("permission denied by %s: %v", e.hook, e.err)
+}
+
+// evaluateRequestRules runs the request rule hooks in order, stopping at the
+// first veto. Requests of root tokens are not evaluated.
+func (c *Core) evaluateRequestRules(req *logical.Request, te *TokenEntry) error {
+	if len(c.requestRuleHooks) == 0 {
+		return nil
+	}
+	if te != nil && strutil.StrListContains(te.Policies, "root") {
+		return nil
+	}
+
+	input := newRequestRuleInput(req, te)
+	for _, hook := range c.requestRuleHooks {
+		if err := hook.EvaluateRequest(input); err != nil {
+			metrics.IncrCounter([]string{"request_rule", "veto"}, 1)
+			if c.logger.IsDebug() {
+				c.logger.Debug("core: request vetoed", "hook", hook.Name(), "path", req.Path, "request_id", req.ID, "error", err)
+			}
+			return &requestRuleError{hook: hook.Name(), err: err}
+		}
+	}
+	return nil
+}
+
+// EndpointPolicy is an endpoint governing policy: a rule evaluated against
+// the metadata of the requests on the paths it governs
+type EndpointPolicy struct {
+	Name string `json:"name" structs:"name" mapstructure:"name"`
+
+	// Policy is the CEL expression which must evaluate to true for a request
+	// to be allowed
+	Policy string `json:"policy" structs:"policy" mapstructure:"policy"`
+
+	// Paths are the request paths governed by the policy. A trailing "*"
+	// matches any path with the given prefix.
+	Paths []string `json:"paths" structs:"paths" mapstructure:"paths"`
+
+	// EnforcementLevel is either advisory or hard-mandatory
+	EnforcementLevel string `json:"enforcement_level" structs:"enforcement_level" mapstructure:"enforcement_level"`
+
+	program *cel.Program
+}
+
+// compile validates the policy and compiles its rule
+func (p *EndpointPolicy) compile() error {
+	switch {
+	case p.Name == "":
+		return fmt.Errorf("missing policy name")
+	case strings.TrimSpace(p.Policy) == "":
+		return fmt.Errorf("missing policy rule")
+	case len(p.Paths) == 0:
+		return fmt.Errorf("at least one path is required")
+	}
+
+	switch p.EnforcementLevel {
+	case "":
+		p.EnforcementLevel = EnforcementHardMandatory
+	case EnforcementAdvisory, EnforcementHardMandatory:
+	default:
+		return fmt.Errorf("invalid enforcement level %q", p.EnforcementLevel)
+	}
+
+	for i, path := range p.Paths {
+		p.Paths[i] = strings.TrimPrefix(path, "/")
+	}
+
+	program, err := cel.Compile(p.Policy)
+	if err != nil {
+		return fmt.Errorf("failed to compile policy rule: %v", err)
+	}
+	p.program = program
+	return nil
+}
+
+// governs returns whether the policy applies to the given request path
+func (p *EndpointPolicy) governs(path string) bool {
+	for _, pattern := range p.Paths {
+		if strings.HasSuffix(pattern, "*") {
+			if strings.HasPrefix(path, strings.TrimSuffix(pattern, "*")) {
+				return true
+			}
+		} else if path == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// endpointPolicyHook is the built-in hook enforcing the endpoint governing
+// policies
+type endpointPolicyHook struct {
+	core *Core
+}
+
+func (h *endpointPolicyHook) Name() string {
+	return "endpoint governing policy"
+}
+
+func (h *endpointPolicyHook) EvaluateRequest(input *RequestRuleInput) error {
+	c := h.core
+	c.endpointPoliciesLock.RLock()
+	defer c.endpointPoliciesLock.RUnlock()
+
+	if len(c.endpointPolicies) == 0 {
+		return nil
+	}
+
+	var vars map[string]interface{}
+	for _, policy := range c.endpointPolicies {
+		if !policy.governs(input.Path) {
+			continue
+		}
+		if vars == nil {
+			vars = input.vars()
+		}
+
+		allowed, err := policy.program.EvalBool(vars)
+		if err != nil {
+			c.logger.Warn("core: failed to evaluate endpoint governing policy", "policy", policy.Name, "path", input.Path, "error", err)
+		}
+		if allowed {
+			continue
+		}
+
+		if policy.EnforcementLevel == EnforcementAdvisory {
+			metrics.IncrCounter([]string{"policy", "egp", "advisory"}, 1)
+			c.logger.Warn("core: request failed advisory endpoint governing policy", "policy", policy.Name, "path", input.Path)
+			continue
+		}
+
+		// Hard mandatory policies fail closed when the rule can't be
+		// evaluated
+		metrics.IncrCounter([]string{"policy", "egp", "violation"}, 1)
+		return fmt.Errorf("policy %q rejected the request", policy.Name)
+	}
+	return nil
+}
+
+// loadEndpointPolicies loads and compiles the endpoint governing policies
+// from storage
+func (c *Core) loadEndpointPolicies() error {
+	view := c.systemBarrierView.SubView(endpointPolicySubPath)
+	names, err := view.List("")
+	if err != nil {
+		return fmt.Errorf("failed to list endpoint governing policies: %v", err)
+	}
+
+	policies := make(map[string]*EndpointPolicy, len(names))
+	for _, name := range names {
+		entry, err := view.Get(name)
+		if err != nil {
+			return fmt.Errorf("failed to read endpoint governing policy: %v", err)
+		}
+		if entry == nil {
+			continue
+		}
+
+		var policy EndpointPolicy
+		if err := entry.DecodeJSON(&policy); err != nil {
+			return fmt.Errorf("failed to decode endpoint governing policy: %v", err)
+		}
+		if err := policy.compile(); err != nil {
+			return fmt.Errorf("failed to load endpoint governing policy %q: %v", name, err)
+		}
+		policies[name] = &policy
+	}
+
+	c.endpointPoliciesLock.Lock()
+	c.endpointPolicies = policies
+	c.endpointPoliciesLock.Unlock()
+	return nil
+}
+
+// unloadEndpointPolicies clears the endpoint governing policies from memory
+func (c *Core) unloadEndpointPolicies() {
+	c.endpointPoliciesLock.Lock()
+	c.endpointPolicies = nil
+	c.endpointPoliciesLock.Unlock()
+}
+
+// EndpointPolicy returns the endpoint governing policy of the given name, or
+// nil if it doesn't exist
+func (c *Core) EndpointPolicy(name string) *EndpointPolicy {
+	c.endpointPoliciesLock.RLock()
+	defer c.endpointPoliciesLock.RUnlock()
+
+	policy, ok := c.endpointPolicies[name]
+	if !ok {
+		return nil
+	}
+	copied := *policy
+	copied.Paths = append([]string(nil), policy.Paths...)
+	return &copied
+}
+
+// SetEndpointPolicy validates and stores an endpoint governing policy
+func (c *Core) SetEndpointPolicy(policy *EndpointPolicy) error {
+	if err := policy.compile(); err != nil {
+		return err
+	}
+
+	entry, err := logical.StorageEntryJSON(policy.Name, policy)
+	if err != nil {
+		return fmt.Errorf("failed to create endpoint governing policy entry: %v", err)
+	}
+
+	c.endpointPoliciesLock.Lock()
+	defer c.endpointPoliciesLock.Unlock()
+
+	view := c.systemBarrierView.SubView(endpointPolicySubPath)
+	if err := view.Put(entry); err != nil {
+		return fmt.Errorf("failed to save endpoint governing policy: %v", err)
+	}
+
+	if c.endpointPolicies == nil {
+		c.endpointPolicies = make(map[string]*EndpointPolicy)
+	}
+	c.endpointPolicies[policy.Name] = policy
+	return nil
+}
+
+// DeleteEndpointPolicy deletes the endpoint governing policy of the given name
+func (c *Core) DeleteEndpointPolicy(name string) error {
+	c.endpointPoliciesLock.Lock()
+	defer c.endpointPoliciesLock.Unlock()
+
+	view := c.systemBarrierView.SubView(endpointPolicySubPath)
+	if err := view.Delete(name); err != nil {
+		return fmt.Errorf("failed to delete endpoint governing policy: %v", err)
+	}
+	delete(c.endpointPolicies, name)
+	return nil
+}
+
+// ListEndpointPolicies returns the names of the endpoint governing policies
+func (c *Core) ListEndpointPolicies() ([]string, error) {
+	view := c.systemBarrierView.SubView(endpointPolicySubPath)
+	names, err := view.List("")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list endpoint governing policies: %v", err)
+	}
+	return names, nil
+}
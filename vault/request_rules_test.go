@@ -0,0 +1,263 @@
+package vault
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/logical"
+)
+
+type testRequestRuleHook struct {
+	inputs []*RequestRuleInput
+	veto   string
+}
+
+func (h *testRequestRuleHook) Name() string {
+	return "test hook"
+}
+
+func (h *testRequestRuleHook) EvaluateRequest(input *RequestRuleInput) error {
+	h.inputs = append(h.inputs, input)
+	if input.Path == h.veto {
+		return fmt.Errorf("vetoed")
+	}
+	return nil
+}
+
+func TestEndpointPolicies(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	write := func(path string, data map[string]interface{}) (*logical.Response, error) {
+		req := logical.TestRequest(t, logical.UpdateOperation, path)
+		req.Data = data
+		req.ClientToken = root
+		return c.HandleRequest(req)
+	}
+	read := func(path, token, remoteAddr string) (*logical.Response, error) {
+		req := logical.TestRequest(t, logical.ReadOperation, path)
+		req.ClientToken = token
+		req.Connection = &logical.Connection{RemoteAddr: remoteAddr}
+		return c.HandleRequest(req)
+	}
+
+	if resp, err := write("secret/foo", map[string]interface{}{"zip": "zap"}); err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+
+	policy, err := Parse(`path "secret/*" { capabilities = ["read"] }`)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	policy.Name = "secret"
+	if err := c.policyStore.SetPolicy(policy); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	te := &TokenEntry{
+		Path:     "test",
+		Policies: []string{"secret"},
+		Meta:     map[string]string{"team": "ops"},
+	}
+	if err := c.tokenStore.create(te); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Invalid policies are rejected
+	for _, data := range []map[string]interface{}{
+		{"policy": "", "paths": "secret/*"},
+		{"policy": "true", "paths": ""},
+		{"policy": "1 +", "paths": "secret/*"},
+		{"policy": "true", "paths": "secret/*", "enforcement_level": "soft"},
+	} {
+		resp, err := write("sys/policies/egp/bad", data)
+		if err == nil || resp == nil || !resp.IsError() {
+			t.Fatalf("expected an error for %#v; resp: %#v, err: %v", data, resp, err)
+		}
+	}
+
+	if resp, err := write("sys/policies/egp/network", map[string]interface{}{
+		"policy": `cidr("10.0.0.0/8").containsIP(request.remote_addr) && token.meta.team == "ops"`,
+		"paths":  "secret/*",
+	}); err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+
+	req := logical.TestRequest(t, logical.ReadOperation, "sys/policies/egp/network")
+	req.ClientToken = root
+	resp, err := c.HandleRequest(req)
+	if err != nil || resp == nil || resp.Data["enforcement_level"] != EnforcementHardMandatory ||
+		!reflect.DeepEqual(resp.Data["paths"], []string{"secret/*"}) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+
+	if resp, err := read("secret/foo", te.ID, "10.1.2.3"); err != nil || resp.Data["zip"] != "zap" {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+
+	// Requests failing the policy are rejected
+	_, err = read("secret/foo", te.ID, "192.168.1.1")
+	if err == nil || !errwrap.Contains(err, logical.ErrPermissionDenied.Error()) {
+		t.Fatalf("expected permission denied; err: %v", err)
+	}
+	if code, _ := logical.RespondErrorCommon(nil, nil, err); code != 403 {
+		t.Fatalf("bad: status code: %d", code)
+	}
+
+	// Root tokens are not evaluated
+	if resp, err := read("secret/foo", root, "192.168.1.1"); err != nil || resp.Data["zip"] != "zap" {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+
+	// Advisory policies only log the failures
+	if resp, err := write("sys/policies/egp/network", map[string]interface{}{
+		"policy":            `cidr("10.0.0.0/8").containsIP(request.remote_addr)`,
+		"paths":             "secret/*",
+		"enforcement_level": EnforcementAdvisory,
+	}); err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	if resp, err := read("secret/foo", te.ID, "192.168.1.1"); err != nil || resp.Data["zip"] != "zap" {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+
+	// Hard mandatory policies which can't be evaluated fail closed
+	if resp, err := write("sys/policies/egp/mfa", map[string]interface{}{
+		"policy": `mfa.validated || token.meta.missing == "x"`,
+		"paths":  "secret/foo",
+	}); err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	if _, err := read("secret/foo", te.ID, "10.1.2.3"); err == nil || !errwrap.Contains(err, logical.ErrPermissionDenied.Error()) {
+		t.Fatalf("expected permission denied; err: %v", err)
+	}
+
+	// The policies are restored after unsealing
+	c.unloadEndpointPolicies()
+	if err := c.loadEndpointPolicies(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if policy := c.EndpointPolicy("mfa"); policy == nil || policy.program == nil {
+		t.Fatalf("bad: %#v", policy)
+	}
+
+	req = logical.TestRequest(t, logical.ListOperation, "sys/policies/egp/")
+	req.ClientToken = root
+	resp, err = c.HandleRequest(req)
+	if err != nil || !reflect.DeepEqual(resp.Data["keys"], []string{"mfa", "network"}) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+
+	req = logical.TestRequest(t, logical.DeleteOperation, "sys/policies/egp/mfa")
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp, err := read("secret/foo", te.ID, "10.1.2.3"); err != nil || resp.Data["zip"] != "zap" {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+}
+
+func TestRequestRuleHooks(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	hook := &testRequestRuleHook{veto: "secret/vetoed"}
+	c.requestRuleHooks = append(c.requestRuleHooks, hook)
+
+	policy, err := Parse(`path "secret/*" { capabilities = ["read"] }`)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	policy.Name = "secret"
+	if err := c.policyStore.SetPolicy(policy); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	te := &TokenEntry{
+		Path:        "test",
+		Policies:    []string{"secret"},
+		DisplayName: "test-token",
+		MFAMethods:  []string{"mytotp"},
+	}
+	if err := c.tokenStore.create(te); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req := logical.TestRequest(t, logical.ReadOperation, "secret/foo")
+	req.ClientToken = te.ID
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(hook.inputs) != 1 {
+		t.Fatalf("bad: %#v", hook.inputs)
+	}
+	input := hook.inputs[0]
+	if input.Path != "secret/foo" || input.Operation != logical.ReadOperation ||
+		input.DisplayName != "test-token" || !reflect.DeepEqual(input.MFAMethods, []string{"mytotp"}) {
+		t.Fatalf("bad: %#v", input)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "secret/vetoed")
+	req.ClientToken = te.ID
+	resp, err := c.HandleRequest(req)
+	if err == nil || !errwrap.Contains(err, logical.ErrPermissionDenied.Error()) {
+		t.Fatalf("expected permission denied; err: %v", err)
+	}
+	if resp == nil || !strings.Contains(resp.Data["error"].(string), "permission denied by test hook: vetoed") {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// Requests denied by the ACLs never reach the hooks
+	req = logical.TestRequest(t, logical.ReadOperation, "sys/mounts")
+	req.ClientToken = te.ID
+	if _, err := c.HandleRequest(req); err == nil {
+		t.Fatalf("expected an error")
+	}
+	if len(hook.inputs) != 2 {
+		t.Fatalf("bad: %#v", hook.inputs)
+	}
+
+	// Root tokens are not evaluated
+	req = logical.TestRequest(t, logical.ReadOperation, "secret/vetoed")
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(hook.inputs) != 2 {
+		t.Fatalf("bad: %#v", hook.inputs)
+	}
+}
+
+func TestRequestRuleInput_Vars(t *testing.T) {
+	c, _, _ := TestCoreUnsealed(t)
+
+	policy := &EndpointPolicy{
+		Name:   "business-hours",
+		Policy: `time.hour >= 9 && time.hour < 17 && time.weekday in [1, 2, 3, 4, 5] && "ops" in token.policies`,
+		Paths:  []string{"*"},
+	}
+	if err := c.SetEndpointPolicy(policy); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	hook := &endpointPolicyHook{core: c}
+
+	input := &RequestRuleInput{
+		Path:     "secret/foo",
+		Policies: []string{"ops"},
+	}
+	for _, tc := range []struct {
+		time    string
+		allowed bool
+	}{
+		{"2018-01-03T10:00:00Z", true},
+		{"2018-01-03T18:00:00Z", false},
+		{"2018-01-06T10:00:00Z", false},
+	} {
+		if err := input.Time.UnmarshalText([]byte(tc.time)); err != nil {
+			t.Fatal(err)
+		}
+		if err := hook.EvaluateRequest(input); (err == nil) != tc.allowed {
+			t.Fatalf("bad: %s: err: %v", tc.time, err)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package vault
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	storageRewrapStatusRunning   = "running"
+	storageRewrapStatusComplete  = "complete"
+	storageRewrapStatusCancelled = "cancelled"
+	storageRewrapStatusFailed    = "failed"
+)
+
+// ErrRewrapInProgress is returned when a storage rewrap is requested while
+// another one is running
+var ErrRewrapInProgress = errors.New("a storage rewrap is already in progress")
+
+var errRewrapCancelled = errors.New("storage rewrap cancelled")
+
+// storageRewrap tracks a background rewrap of the entries under a storage
+// prefix with the active encryption key
+type storageRewrap struct {
+	sync.RWMutex
+
+	prefix    string
+	rate      int
+	status    string
+	err       string
+	startTime time.Time
+	endTime   time.Time
+
+	// scanned counts every entry visited, rewrapped those that were
+	// re-encrypted, skipped those that are not encrypted by the keyring, and
+	// failed those that could not be decrypted. terms counts the entries
+	// found under each key term.
+	scanned   int
+	rewrapped int
+	skipped   int
+	failed    int
+	terms     map[uint32]int
+
+	cancelOnce sync.Once
+	cancelCh   chan struct{}
+}
+
+// startRewrap starts rewrapping the entries under the prefix in the
+// background, at no more than rate entries per second if rate is positive
+func (c *Core) startRewrap(prefix string, rate int) error {
+	c.rewrapLock.Lock()
+	defer c.rewrapLock.Unlock()
+
+	if c.rewrap != nil {
+		c.rewrap.RLock()
+		running := c.rewrap.status == storageRewrapStatusRunning
+		c.rewrap.RUnlock()
+		if running {
+			return ErrRewrapInProgress
+		}
+	}
+
+	job := &storageRewrap{
+		prefix:    prefix,
+		rate:      rate,
+		status:    storageRewrapStatusRunning,
+		startTime: time.Now(),
+		terms:     make(map[uint32]int),
+		cancelCh:  make(chan struct{}),
+	}
+	c.rewrap = job
+
+	go c.runRewrap(job)
+	return nil
+}
+
+// cancelRewrap stops a running storage rewrap, if any
+func (c *Core) cancelRewrap() {
+	c.rewrapLock.Lock()
+	defer c.rewrapLock.Unlock()
+
+	if c.rewrap != nil {
+		c.rewrap.cancel()
+	}
+}
+
+func (c *Core) runRewrap(job *storageRewrap) {
+	c.logger.Info("core: starting storage rewrap", "prefix", job.prefix)
+
+	var interval time.Duration
+	if job.rate > 0 {
+		interval = time.Second / time.Duration(job.rate)
+	}
+
+	err := c.rewrapPrefix(job, job.prefix, interval)
+	switch err {
+	case nil:
+		job.finish(storageRewrapStatusComplete, "")
+		c.logger.Info("core: storage rewrap complete", "prefix", job.prefix)
+	case errRewrapCancelled:
+		job.finish(storageRewrapStatusCancelled, "")
+		c.logger.Info("core: storage rewrap cancelled", "prefix", job.prefix)
+	default:
+		job.finish(storageRewrapStatusFailed, err.Error())
+		c.logger.Error("core: storage rewrap failed", "prefix", job.prefix, "error", err)
+	}
+}
+
+// rewrapPrefix rewraps the entries under the prefix depth-first in sorted
+// order, waiting interval between entries
+func (c *Core) rewrapPrefix(job *storageRewrap, prefix string, interval time.Duration) error {
+	keys, err := c.barrier.List(prefix)
+	if err != nil {
+		return err
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if strings.HasSuffix(key, "/") {
+			if err := c.rewrapPrefix(job, prefix+key, interval); err != nil {
+				return err
+			}
+			continue
+		}
+
+		select {
+		case <-job.cancelCh:
+			return errRewrapCancelled
+		default:
+		}
+
+		// Entries that fail to decrypt are counted and left for an operator
+		// to inspect, while other errors stop the rewrap
+		term, rewrapped, err := c.barrier.Rewrap(prefix + key)
+		failed := err == ErrBarrierUndecryptable
+		if failed {
+			c.logger.Error("core: storage rewrap failed to decrypt entry", "key", prefix+key, "term", term)
+		} else if err != nil {
+			return err
+		}
+
+		job.Lock()
+		job.scanned++
+		switch {
+		case failed:
+			job.failed++
+		case term == 0:
+			job.skipped++
+		case rewrapped:
+			job.rewrapped++
+		}
+		if term != 0 {
+			job.terms[term]++
+		}
+		job.Unlock()
+
+		if interval > 0 {
+			select {
+			case <-job.cancelCh:
+				return errRewrapCancelled
+			case <-time.After(interval):
+			}
+		}
+	}
+	return nil
+}
+
+func (job *storageRewrap) finish(status, err string) {
+	job.Lock()
+	defer job.Unlock()
+
+	job.status = status
+	job.err = err
+	job.endTime = time.Now()
+}
+
+func (job *storageRewrap) cancel() {
+	job.cancelOnce.Do(func() {
+		close(job.cancelCh)
+	})
+}
+
+// statusData returns the progress of the rewrap for the status endpoint
+func (job *storageRewrap) statusData() map[string]interface{} {
+	job.RLock()
+	defer job.RUnlock()
+
+	terms := make(map[string]interface{}, len(job.terms))
+	for term, count := range job.terms {
+		terms[strconv.FormatUint(uint64(term), 10)] = count
+	}
+
+	data := map[string]interface{}{
+		"prefix":     job.prefix,
+		"rate":       job.rate,
+		"status":     job.status,
+		"start_time": job.startTime.Format(time.RFC3339Nano),
+		"end_time":   "",
+		"scanned":    job.scanned,
+		"rewrapped":  job.rewrapped,
+		"skipped":    job.skipped,
+		"failed":     job.failed,
+		"terms":      terms,
+	}
+	if job.status != storageRewrapStatusRunning {
+		data["end_time"] = job.endTime.Format(time.RFC3339Nano)
+	}
+	if job.err != "" {
+		data["error"] = job.err
+	}
+	return data
+}
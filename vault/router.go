@@ -51,6 +51,7 @@ type validateMountResponse struct {
 	MountType     string `json:"mount_type" structs:"mount_type" mapstructure:"mount_type"`
 	MountAccessor string `json:"mount_accessor" structs:"mount_accessor" mapstructure:"mount_accessor"`
 	MountPath     string `json:"mount_path" structs:"mount_path" mapstructure:"mount_path"`
+	NamespaceID   string `json:"namespace_id" structs:"namespace_id" mapstructure:"namespace_id"`
 }
 
 // validateMountByAccessor returns the mount type and ID for a given mount
@@ -69,6 +70,7 @@ func (r *Router) validateMountByAccessor(accessor string) *validateMountResponse
 		MountAccessor: mountEntry.Accessor,
 		MountType:     mountEntry.Type,
 		MountPath:     mountEntry.Path,
+		NamespaceID:   mountEntry.NamespaceID,
 	}
 }
 
@@ -357,15 +359,6 @@ func (r *Router) routeCommon(req *logical.Request, existenceCheck bool) (*logica
 
 	originalEntityID := req.EntityID
 
-	// Allow EntityID to passthrough to the system backend. This is required to
-	// allow clients to generate MFA credentials in respective entity objects
-	// in identity store via the system backend.
-	switch {
-	case strings.HasPrefix(originalPath, "sys/"):
-	default:
-		req.EntityID = ""
-	}
-
 	// Hash the request token unless this is the token backend
 	clientToken := req.ClientToken
 	switch {
@@ -20,6 +20,7 @@ type NoopBackend struct {
 
 	Root          []string
 	Login         []string
+	Exportable    []string
 	Paths         []string
 	Requests      []*logical.Request
 	Response      *logical.Response
@@ -46,8 +47,9 @@ func (n *NoopBackend) HandleExistenceCheck(req *logical.Request) (bool, bool, er
 
 func (n *NoopBackend) SpecialPaths() *logical.Paths {
 	return &logical.Paths{
-		Root:            n.Root,
-		Unauthenticated: n.Login,
+		Root:              n.Root,
+		Unauthenticated:   n.Login,
+		ExportableStorage: n.Exportable,
 	}
 }
 
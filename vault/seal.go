@@ -114,16 +114,51 @@ func (d *DefaultSeal) BarrierConfig() (*SealConfig, error) {
 		return nil, err
 	}
 
+	conf, err := loadBarrierConfig(d.core, d.BarrierType())
+	if err != nil || conf == nil {
+		return nil, err
+	}
+
+	d.config = conf
+	return d.config.Clone(), nil
+}
+
+func (d *DefaultSeal) SetBarrierConfig(config *SealConfig) error {
+	if err := d.checkCore(); err != nil {
+		return err
+	}
+
+	// Provide a way to wipe out the cached value (also prevents actually
+	// saving a nil config)
+	if config == nil {
+		d.config = nil
+		return nil
+	}
+
+	config.Type = d.BarrierType()
+	if err := storeBarrierConfig(d.core, config); err != nil {
+		return err
+	}
+
+	d.config = config.Clone()
+
+	return nil
+}
+
+// loadBarrierConfig reads the barrier seal configuration from storage,
+// checking that it was written by a seal of the given type. A nil config is
+// returned if Vault is not initialized.
+func loadBarrierConfig(c *Core, sealType string) (*SealConfig, error) {
 	// Fetch the core configuration
-	pe, err := d.core.physical.Get(barrierSealConfigPath)
+	pe, err := c.physical.Get(barrierSealConfigPath)
 	if err != nil {
-		d.core.logger.Error("core: failed to read seal configuration", "error", err)
+		c.logger.Error("core: failed to read seal configuration", "error", err)
 		return nil, fmt.Errorf("failed to check seal configuration: %v", err)
 	}
 
 	// If the seal configuration is missing, we are not initialized
 	if pe == nil {
-		d.core.logger.Info("core: seal configuration missing, not initialized")
+		c.logger.Info("core: seal configuration missing, not initialized")
 		return nil, nil
 	}
 
@@ -131,44 +166,31 @@ func (d *DefaultSeal) BarrierConfig() (*SealConfig, error) {
 
 	// Decode the barrier entry
 	if err := jsonutil.DecodeJSON(pe.Value, &conf); err != nil {
-		d.core.logger.Error("core: failed to decode seal configuration", "error", err)
+		c.logger.Error("core: failed to decode seal configuration", "error", err)
 		return nil, fmt.Errorf("failed to decode seal configuration: %v", err)
 	}
 
-	switch conf.Type {
+	switch {
 	// This case should not be valid for other types as only this is the default
-	case "":
-		conf.Type = d.BarrierType()
-	case d.BarrierType():
+	case conf.Type == "" && sealType == "shamir":
+		conf.Type = sealType
+	case conf.Type == sealType:
 	default:
-		d.core.logger.Error("core: barrier seal type does not match loaded type", "barrier_seal_type", conf.Type, "loaded_seal_type", d.BarrierType())
-		return nil, fmt.Errorf("barrier seal type of %s does not match loaded type of %s", conf.Type, d.BarrierType())
+		c.logger.Error("core: barrier seal type does not match loaded type", "barrier_seal_type", conf.Type, "loaded_seal_type", sealType)
+		return nil, fmt.Errorf("barrier seal type of %s does not match loaded type of %s", conf.Type, sealType)
 	}
 
 	// Check for a valid seal configuration
 	if err := conf.Validate(); err != nil {
-		d.core.logger.Error("core: invalid seal configuration", "error", err)
+		c.logger.Error("core: invalid seal configuration", "error", err)
 		return nil, fmt.Errorf("seal validation failed: %v", err)
 	}
 
-	d.config = &conf
-	return d.config.Clone(), nil
+	return &conf, nil
 }
 
-func (d *DefaultSeal) SetBarrierConfig(config *SealConfig) error {
-	if err := d.checkCore(); err != nil {
-		return err
-	}
-
-	// Provide a way to wipe out the cached value (also prevents actually
-	// saving a nil config)
-	if config == nil {
-		d.config = nil
-		return nil
-	}
-
-	config.Type = d.BarrierType()
-
+// storeBarrierConfig writes the barrier seal configuration to storage
+func storeBarrierConfig(c *Core, config *SealConfig) error {
 	// Encode the seal configuration
 	buf, err := json.Marshal(config)
 	if err != nil {
@@ -181,13 +203,11 @@ func (d *DefaultSeal) SetBarrierConfig(config *SealConfig) error {
 		Value: buf,
 	}
 
-	if err := d.core.physical.Put(pe); err != nil {
-		d.core.logger.Error("core: failed to write seal configuration", "error", err)
+	if err := c.physical.Put(pe); err != nil {
+		c.logger.Error("core: failed to write seal configuration", "error", err)
 		return fmt.Errorf("failed to write seal configuration: %v", err)
 	}
 
-	d.config = config.Clone()
-
 	return nil
 }
 
@@ -241,6 +261,16 @@ type SealConfig struct {
 
 	// How many keys to store, for seals that support storage.
 	StoredShares int `json:"stored_shares"`
+
+	// VerificationRequired indicates that the new key shares generated by a
+	// rekey must be submitted back before the new key is installed, so that
+	// losing them can't lock the operators out. It is only used while
+	// rekeying and is not persisted.
+	VerificationRequired bool `json:"-"`
+
+	// VerificationNonce is the nonce of the verification of the new key
+	// shares, which changes when the verification is restarted
+	VerificationNonce string `json:"-"`
 }
 
 // Validate is used to sanity check the seal configuration
@@ -292,6 +322,9 @@ func (s *SealConfig) Clone() *SealConfig {
 		Nonce:           s.Nonce,
 		Backup:          s.Backup,
 		StoredShares:    s.StoredShares,
+
+		VerificationRequired: s.VerificationRequired,
+		VerificationNonce:    s.VerificationNonce,
 	}
 	if len(s.PGPKeys) > 0 {
 		ret.PGPKeys = make([]string, len(s.PGPKeys))
@@ -0,0 +1,185 @@
+package awskms
+
+import (
+	"fmt"
+	"os"
+	"sync/atomic"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/hashicorp/vault/helper/awsutil"
+	"github.com/hashicorp/vault/vault/seal"
+	log "github.com/mgutz/logxi/v1"
+)
+
+const (
+	// EnvAWSKMSSealKeyID overrides the kms_key_id option
+	EnvAWSKMSSealKeyID = "VAULT_AWSKMS_SEAL_KEY_ID"
+)
+
+// AWSKMSSeal is a seal encrypting its values with a key of the AWS Key
+// Management Service. The values are envelope encrypted, only their data key
+// is encrypted by KMS.
+type AWSKMSSeal struct {
+	logger log.Logger
+	client *kms.KMS
+
+	keyID        string
+	currentKeyID *atomic.Value
+}
+
+var _ seal.Access = (*AWSKMSSeal)(nil)
+
+// NewSeal creates an unconfigured AWS KMS seal
+func NewSeal(logger log.Logger) *AWSKMSSeal {
+	k := &AWSKMSSeal{
+		logger:       logger,
+		currentKeyID: new(atomic.Value),
+	}
+	k.currentKeyID.Store("")
+	return k
+}
+
+// SetConfig configures the seal and checks that the key can be used,
+// returning information about the configuration to display
+func (k *AWSKMSSeal) SetConfig(config map[string]string) (map[string]string, error) {
+	if config == nil {
+		config = map[string]string{}
+	}
+
+	k.keyID = os.Getenv(EnvAWSKMSSealKeyID)
+	if k.keyID == "" {
+		k.keyID = config["kms_key_id"]
+	}
+	if k.keyID == "" {
+		return nil, fmt.Errorf("'kms_key_id' not found for AWS KMS seal configuration")
+	}
+
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if region == "" {
+		region = config["region"]
+	}
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	endpoint := os.Getenv("AWS_KMS_ENDPOINT")
+	if endpoint == "" {
+		endpoint = config["endpoint"]
+	}
+
+	credsConfig := &awsutil.CredentialsConfig{
+		AccessKey:    config["access_key"],
+		SecretKey:    config["secret_key"],
+		SessionToken: config["session_token"],
+		Region:       region,
+	}
+	credsConfig.HTTPClient = cleanhttp.DefaultClient()
+	creds, err := credsConfig.GenerateCredentialChain()
+	if err != nil {
+		return nil, err
+	}
+
+	awsConfig := &aws.Config{
+		Credentials: creds,
+		Region:      aws.String(region),
+		HTTPClient:  cleanhttp.DefaultClient(),
+	}
+	if endpoint != "" {
+		awsConfig.Endpoint = aws.String(endpoint)
+	}
+	k.client = kms.New(session.New(awsConfig))
+
+	// Check that the key can be used, which also resolves its ARN
+	if _, err := k.Encrypt([]byte("vault")); err != nil {
+		return nil, fmt.Errorf("error checking the AWS KMS key: %v", err)
+	}
+
+	info := map[string]string{
+		"AWS KMS region": region,
+		"AWS KMS key ID": k.KeyID(),
+	}
+	if endpoint != "" {
+		info["AWS KMS endpoint"] = endpoint
+	}
+	return info, nil
+}
+
+func (k *AWSKMSSeal) SealType() string {
+	return seal.AWSKMS
+}
+
+// KeyID returns the ARN of the key used by the last encryption
+func (k *AWSKMSSeal) KeyID() string {
+	return k.currentKeyID.Load().(string)
+}
+
+func (k *AWSKMSSeal) Init() error {
+	return nil
+}
+
+func (k *AWSKMSSeal) Finalize() error {
+	return nil
+}
+
+func (k *AWSKMSSeal) Encrypt(plaintext []byte) (*seal.EncryptedBlobInfo, error) {
+	if k.client == nil {
+		return nil, fmt.Errorf("seal is not configured")
+	}
+
+	env, err := seal.EnvelopeEncrypt(plaintext)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := k.client.Encrypt(&kms.EncryptInput{
+		KeyId:     aws.String(k.keyID),
+		Plaintext: env.Key,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error encrypting data key: %v", err)
+	}
+
+	// The configured key may be an alias, record the key actually used
+	keyID := aws.StringValue(output.KeyId)
+	k.currentKeyID.Store(keyID)
+
+	return &seal.EncryptedBlobInfo{
+		Ciphertext: env.Ciphertext,
+		IV:         env.IV,
+		KeyInfo: &seal.KeyInfo{
+			KeyID:      keyID,
+			WrappedKey: output.CiphertextBlob,
+		},
+	}, nil
+}
+
+func (k *AWSKMSSeal) Decrypt(in *seal.EncryptedBlobInfo) ([]byte, error) {
+	if k.client == nil {
+		return nil, fmt.Errorf("seal is not configured")
+	}
+	if in == nil || in.KeyInfo == nil {
+		return nil, fmt.Errorf("missing key info")
+	}
+
+	// The ciphertext blob identifies the key it was encrypted with, so the
+	// values encrypted with a former key can be decrypted as long as it can
+	// be used
+	output, err := k.client.Decrypt(&kms.DecryptInput{
+		CiphertextBlob: in.KeyInfo.WrappedKey,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting data key: %v", err)
+	}
+
+	return seal.EnvelopeDecrypt(&seal.EnvelopeInfo{
+		Ciphertext: in.Ciphertext,
+		Key:        output.Plaintext,
+		IV:         in.IV,
+	})
+}
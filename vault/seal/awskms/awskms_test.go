@@ -0,0 +1,118 @@
+package awskms
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/helper/logformat"
+	log "github.com/mgutz/logxi/v1"
+)
+
+// testKMSServer mocks the Encrypt and Decrypt operations of AWS KMS,
+// "wrapping" the data keys by prefixing them with the key ARN
+func testKMSServer(t *testing.T, keyARN *string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256") {
+			t.Errorf("request is not signed")
+		}
+
+		var body map[string][]byte
+		var resp interface{}
+		switch r.Header.Get("X-Amz-Target") {
+		case "TrentService.Encrypt":
+			var input struct {
+				KeyId     string
+				Plaintext []byte
+			}
+			if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+				t.Error(err)
+				return
+			}
+			if input.KeyId != "alias/vault" {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"__type": "NotFoundException", "message": "key not found"}`))
+				return
+			}
+			resp = map[string]interface{}{
+				"KeyId":          *keyARN,
+				"CiphertextBlob": append([]byte(*keyARN+"|"), input.Plaintext...),
+			}
+		case "TrentService.Decrypt":
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Error(err)
+				return
+			}
+			parts := bytes.SplitN(body["CiphertextBlob"], []byte("|"), 2)
+			resp = map[string]interface{}{
+				"KeyId":     string(parts[0]),
+				"Plaintext": parts[1],
+			}
+		default:
+			t.Errorf("unexpected operation %q", r.Header.Get("X-Amz-Target"))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		json.NewEncoder(w).Encode(resp)
+	}))
+}
+
+func TestAWSKMSSeal(t *testing.T) {
+	os.Unsetenv(EnvAWSKMSSealKeyID)
+
+	keyARN := "arn:aws:kms:us-east-1:123456789012:key/1"
+	server := testKMSServer(t, &keyARN)
+	defer server.Close()
+
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+	s := NewSeal(logger)
+	config := map[string]string{
+		"access_key": "AKIAEXAMPLE",
+		"secret_key": "secret",
+		"region":     "us-east-1",
+		"endpoint":   server.URL,
+	}
+	if _, err := s.SetConfig(config); err == nil {
+		t.Fatalf("expected an error without a key ID")
+	}
+
+	config["kms_key_id"] = "alias/vault"
+	info, err := s.SetConfig(config)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if info["AWS KMS key ID"] != keyARN || s.KeyID() != keyARN {
+		t.Fatalf("bad: %#v", info)
+	}
+
+	blob, err := s.Encrypt([]byte("foo"))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if blob.KeyInfo.KeyID != keyARN || bytes.Contains(blob.Ciphertext, []byte("foo")) {
+		t.Fatalf("bad: %#v", blob)
+	}
+
+	// Values encrypted with the former key can still be decrypted
+	keyARN = "arn:aws:kms:us-east-1:123456789012:key/2"
+	if _, err := s.Encrypt([]byte("bar")); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if s.KeyID() != keyARN {
+		t.Fatalf("bad: key ID: %s", s.KeyID())
+	}
+	plaintext, err := s.Decrypt(blob)
+	if err != nil || string(plaintext) != "foo" {
+		t.Fatalf("bad: plaintext: %q, err: %v", plaintext, err)
+	}
+
+	config["kms_key_id"] = "alias/missing"
+	if _, err := NewSeal(logger).SetConfig(config); err == nil || !strings.Contains(err.Error(), "key not found") {
+		t.Fatalf("expected a key not found error, got %v", err)
+	}
+}
@@ -0,0 +1,256 @@
+package azurekeyvault
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"github.com/Azure/azure-sdk-for-go/dataplane/keyvault"
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/adal"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/hashicorp/vault/vault/seal"
+	log "github.com/mgutz/logxi/v1"
+)
+
+const (
+	// EnvAzureKeyVaultSealVaultName overrides the vault_name option
+	EnvAzureKeyVaultSealVaultName = "VAULT_AZUREKEYVAULT_VAULT_NAME"
+
+	// EnvAzureKeyVaultSealKeyName overrides the key_name option
+	EnvAzureKeyVaultSealKeyName = "VAULT_AZUREKEYVAULT_KEY_NAME"
+
+	// wrapAlgorithm is the algorithm the data keys are wrapped with
+	wrapAlgorithm keyvault.JSONWebKeyEncryptionAlgorithm = "RSA-OAEP-256"
+)
+
+// AzureKeyVaultSeal is a seal wrapping the data keys of its values with a
+// key of Azure Key Vault. The values are envelope encrypted, only their data
+// key is wrapped by Key Vault.
+type AzureKeyVaultSeal struct {
+	logger log.Logger
+	client *keyvault.ManagementClient
+
+	// vaultURL is the base URL of the key vault
+	vaultURL string
+	keyName  string
+
+	currentKeyID *atomic.Value
+}
+
+var _ seal.Access = (*AzureKeyVaultSeal)(nil)
+
+// NewSeal creates an unconfigured Azure Key Vault seal
+func NewSeal(logger log.Logger) *AzureKeyVaultSeal {
+	v := &AzureKeyVaultSeal{
+		logger:       logger,
+		currentKeyID: new(atomic.Value),
+	}
+	v.currentKeyID.Store("")
+	return v
+}
+
+// SetConfig configures the seal and checks that the key can be used,
+// returning information about the configuration to display. Without client
+// credentials, the managed service identity of the VM is used.
+func (v *AzureKeyVaultSeal) SetConfig(config map[string]string) (map[string]string, error) {
+	if config == nil {
+		config = map[string]string{}
+	}
+
+	option := func(env, name string) string {
+		if value := os.Getenv(env); value != "" {
+			return value
+		}
+		return config[name]
+	}
+	tenantID := option("AZURE_TENANT_ID", "tenant_id")
+	clientID := option("AZURE_CLIENT_ID", "client_id")
+	clientSecret := option("AZURE_CLIENT_SECRET", "client_secret")
+	envName := option("AZURE_ENVIRONMENT", "environment")
+	vaultName := option(EnvAzureKeyVaultSealVaultName, "vault_name")
+	v.keyName = option(EnvAzureKeyVaultSealKeyName, "key_name")
+
+	switch {
+	case vaultName == "":
+		return nil, fmt.Errorf("'vault_name' not found for Azure Key Vault seal configuration")
+	case v.keyName == "":
+		return nil, fmt.Errorf("'key_name' not found for Azure Key Vault seal configuration")
+	case (clientID == "") != (clientSecret == ""):
+		return nil, fmt.Errorf("both 'client_id' and 'client_secret' must be set, or neither to use the managed service identity")
+	case clientID != "" && tenantID == "":
+		return nil, fmt.Errorf("'tenant_id' not found for Azure Key Vault seal configuration")
+	}
+
+	if envName == "" {
+		envName = azure.PublicCloud.Name
+	}
+	env, err := azure.EnvironmentFromName(envName)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Azure environment: %v", err)
+	}
+	v.vaultURL = fmt.Sprintf("https://%s.%s", vaultName, env.KeyVaultDNSSuffix)
+
+	if err := v.setupToken(env, tenantID, clientID, clientSecret); err != nil {
+		return nil, err
+	}
+
+	// Check that the key can be used, which also resolves its current version
+	if _, err := v.Encrypt([]byte("vault")); err != nil {
+		return nil, fmt.Errorf("error checking the Azure Key Vault key: %v", err)
+	}
+
+	return map[string]string{
+		"Azure environment": env.Name,
+		"Azure vault name":  vaultName,
+		"Azure key name":    v.keyName,
+		"Azure key ID":      v.KeyID(),
+	}, nil
+}
+
+// setupToken creates the Key Vault client, authenticating with the client
+// credentials if any, or the managed service identity
+func (v *AzureKeyVaultSeal) setupToken(env azure.Environment, tenantID, clientID, clientSecret string) error {
+	resource := strings.TrimSuffix(env.KeyVaultEndpoint, "/")
+
+	var token *adal.ServicePrincipalToken
+	if clientID == "" {
+		msiEndpoint, err := adal.GetMSIVMEndpoint()
+		if err != nil {
+			return fmt.Errorf("error getting the managed service identity endpoint: %v", err)
+		}
+		token, err = adal.NewServicePrincipalTokenFromMSI(msiEndpoint, resource)
+		if err != nil {
+			return fmt.Errorf("error creating managed service identity token: %v", err)
+		}
+	} else {
+		oauthConfig, err := adal.NewOAuthConfig(env.ActiveDirectoryEndpoint, tenantID)
+		if err != nil {
+			return fmt.Errorf("error creating Azure OAuth configuration: %v", err)
+		}
+		token, err = adal.NewServicePrincipalToken(*oauthConfig, clientID, clientSecret, resource)
+		if err != nil {
+			return fmt.Errorf("error creating Azure service principal token: %v", err)
+		}
+	}
+
+	client := keyvault.New()
+	client.Authorizer = autorest.NewBearerAuthorizer(token)
+	client.Sender = cleanhttp.DefaultClient()
+	v.client = &client
+	return nil
+}
+
+func (v *AzureKeyVaultSeal) SealType() string {
+	return seal.AzureKeyVault
+}
+
+// KeyID returns the identifier of the key version used by the last
+// encryption
+func (v *AzureKeyVaultSeal) KeyID() string {
+	return v.currentKeyID.Load().(string)
+}
+
+func (v *AzureKeyVaultSeal) Init() error {
+	return nil
+}
+
+func (v *AzureKeyVaultSeal) Finalize() error {
+	return nil
+}
+
+func (v *AzureKeyVaultSeal) Encrypt(plaintext []byte) (*seal.EncryptedBlobInfo, error) {
+	if v.client == nil {
+		return nil, fmt.Errorf("seal is not configured")
+	}
+
+	env, err := seal.EnvelopeEncrypt(plaintext)
+	if err != nil {
+		return nil, err
+	}
+
+	// Wrap with the current version of the key, so that the rotations of the
+	// key are picked up
+	key, err := v.client.GetKey(v.vaultURL, v.keyName, "")
+	if err != nil {
+		return nil, fmt.Errorf("error reading key: %v", err)
+	}
+	if key.Key == nil {
+		return nil, fmt.Errorf("error reading key: no key returned")
+	}
+	version, err := v.keyVersion(to.String(key.Key.Kid))
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := v.client.WrapKey(v.vaultURL, v.keyName, version, keyvault.KeyOperationsParameters{
+		Algorithm: wrapAlgorithm,
+		Value:     to.StringPtr(base64.RawURLEncoding.EncodeToString(env.Key)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error wrapping data key: %v", err)
+	}
+	wrapped, err := base64.RawURLEncoding.DecodeString(to.String(resp.Result))
+	if err != nil {
+		return nil, fmt.Errorf("error decoding wrapped data key: %v", err)
+	}
+	keyID := to.String(resp.Kid)
+	v.currentKeyID.Store(keyID)
+
+	return &seal.EncryptedBlobInfo{
+		Ciphertext: env.Ciphertext,
+		IV:         env.IV,
+		KeyInfo: &seal.KeyInfo{
+			KeyID:      keyID,
+			WrappedKey: wrapped,
+		},
+	}, nil
+}
+
+func (v *AzureKeyVaultSeal) Decrypt(in *seal.EncryptedBlobInfo) ([]byte, error) {
+	if v.client == nil {
+		return nil, fmt.Errorf("seal is not configured")
+	}
+	if in == nil || in.KeyInfo == nil {
+		return nil, fmt.Errorf("missing key info")
+	}
+
+	// Unwrap with the key version the data key was wrapped with
+	version, err := v.keyVersion(in.KeyInfo.KeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := v.client.UnwrapKey(v.vaultURL, v.keyName, version, keyvault.KeyOperationsParameters{
+		Algorithm: wrapAlgorithm,
+		Value:     to.StringPtr(base64.RawURLEncoding.EncodeToString(in.KeyInfo.WrappedKey)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error unwrapping data key: %v", err)
+	}
+	key, err := base64.RawURLEncoding.DecodeString(to.String(resp.Result))
+	if err != nil {
+		return nil, fmt.Errorf("error decoding data key: %v", err)
+	}
+
+	return seal.EnvelopeDecrypt(&seal.EnvelopeInfo{
+		Ciphertext: in.Ciphertext,
+		Key:        key,
+		IV:         in.IV,
+	})
+}
+
+// keyVersion returns the version of a key identifier, checking that it
+// belongs to the configured key
+func (v *AzureKeyVaultSeal) keyVersion(kid string) (string, error) {
+	prefix := fmt.Sprintf("%s/keys/%s/", v.vaultURL, v.keyName)
+	version := strings.TrimPrefix(kid, prefix)
+	if version == kid || version == "" || strings.Contains(version, "/") {
+		return "", fmt.Errorf("key identifier %q does not belong to key %q", kid, v.keyName)
+	}
+	return version, nil
+}
@@ -0,0 +1,148 @@
+package azurekeyvault
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/hashicorp/vault/helper/logformat"
+	log "github.com/mgutz/logxi/v1"
+)
+
+// testKeyVaultServer mocks the Azure AD token endpoint and the key
+// operations of Key Vault, "wrapping" the data keys by prefixing them with
+// the key version
+func testKeyVaultServer(t *testing.T, version *string) *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.URL.Path == "/tenant/oauth2/token" {
+			if r.FormValue("client_id") != "client" || r.FormValue("client_secret") != "secret" {
+				t.Errorf("bad token request: %v", r.Form)
+			}
+			w.Write([]byte(`{"access_token": "test-token", "token_type": "Bearer", "expires_on": "4102444800"}`))
+			return
+		}
+
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			t.Errorf("bad authorization: %q", r.Header.Get("Authorization"))
+		}
+		if r.URL.Query().Get("api-version") != "2016-10-01" {
+			t.Errorf("bad API version: %q", r.URL.RawQuery)
+		}
+
+		kid := fmt.Sprintf("%s/keys/unseal/%s", server.URL, *version)
+		if r.Method == "GET" && r.URL.Path == "/keys/unseal/" {
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"key": map[string]string{"kid": kid},
+			})
+			return
+		}
+
+		var params struct {
+			Algorithm string `json:"alg"`
+			Value     string `json:"value"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			t.Error(err)
+			return
+		}
+		if params.Algorithm != string(wrapAlgorithm) {
+			t.Errorf("bad algorithm: %q", params.Algorithm)
+		}
+
+		switch {
+		case r.URL.Path == "/keys/unseal/"+*version+"/wrapkey":
+			json.NewEncoder(w).Encode(map[string]string{
+				"kid":   kid,
+				"value": base64.RawURLEncoding.EncodeToString([]byte(*version + "." + params.Value)),
+			})
+		case strings.HasSuffix(r.URL.Path, "/unwrapkey"):
+			wrapped, err := base64.RawURLEncoding.DecodeString(params.Value)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			parts := strings.SplitN(string(wrapped), ".", 2)
+			if !strings.HasSuffix(r.URL.Path, "/"+parts[0]+"/unwrapkey") {
+				t.Errorf("unwrapping with the wrong version: %s", r.URL.Path)
+			}
+			json.NewEncoder(w).Encode(map[string]string{
+				"kid":   kid,
+				"value": parts[1],
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": {"code": "KeyNotFound", "message": "key not found"}}`))
+		}
+	}))
+	return server
+}
+
+func TestAzureKeyVaultSeal(t *testing.T) {
+	for _, env := range []string{"AZURE_TENANT_ID", "AZURE_CLIENT_ID", "AZURE_CLIENT_SECRET", "AZURE_ENVIRONMENT", EnvAzureKeyVaultSealVaultName, EnvAzureKeyVaultSealKeyName} {
+		os.Unsetenv(env)
+	}
+
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+	for _, config := range []map[string]string{
+		{"key_name": "unseal"},
+		{"vault_name": "vault"},
+		{"vault_name": "vault", "key_name": "unseal", "client_id": "client"},
+		{"vault_name": "vault", "key_name": "unseal", "client_id": "client", "client_secret": "secret"},
+		{"vault_name": "vault", "key_name": "unseal", "environment": "nowhere"},
+	} {
+		if _, err := NewSeal(logger).SetConfig(config); err == nil {
+			t.Fatalf("expected an error for %#v", config)
+		}
+	}
+
+	version := "1"
+	server := testKeyVaultServer(t, &version)
+	defer server.Close()
+
+	// Point the seal to the mock server
+	s := NewSeal(logger)
+	s.vaultURL = server.URL
+	s.keyName = "unseal"
+	env := azure.PublicCloud
+	env.ActiveDirectoryEndpoint = server.URL + "/"
+	if err := s.setupToken(env, "tenant", "client", "secret"); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	blob, err := s.Encrypt([]byte("foo"))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if blob.KeyInfo.KeyID != server.URL+"/keys/unseal/1" || s.KeyID() != blob.KeyInfo.KeyID || bytes.Contains(blob.Ciphertext, []byte("foo")) {
+		t.Fatalf("bad: %#v", blob)
+	}
+
+	// Values wrapped with a former version can still be unwrapped
+	version = "2"
+	if _, err := s.Encrypt([]byte("bar")); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if s.KeyID() != server.URL+"/keys/unseal/2" {
+		t.Fatalf("bad: key ID: %s", s.KeyID())
+	}
+	plaintext, err := s.Decrypt(blob)
+	if err != nil || string(plaintext) != "foo" {
+		t.Fatalf("bad: plaintext: %q, err: %v", plaintext, err)
+	}
+
+	// Key identifiers of other keys are rejected
+	blob.KeyInfo.KeyID = "https://evil.example.com/keys/unseal/1"
+	if _, err := s.Decrypt(blob); err == nil || !strings.Contains(err.Error(), "does not belong") {
+		t.Fatalf("expected an error, got %v", err)
+	}
+}
@@ -0,0 +1,209 @@
+package gcpckms
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/hashicorp/vault/vault/seal"
+	log "github.com/mgutz/logxi/v1"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	cloudkms "google.golang.org/api/cloudkms/v1"
+)
+
+const (
+	// EnvGCPCKMSSealKeyRing overrides the key_ring option
+	EnvGCPCKMSSealKeyRing = "VAULT_GCPCKMS_SEAL_KEY_RING"
+
+	// EnvGCPCKMSSealCryptoKey overrides the crypto_key option
+	EnvGCPCKMSSealCryptoKey = "VAULT_GCPCKMS_SEAL_CRYPTO_KEY"
+
+	defaultEndpoint = "https://cloudkms.googleapis.com"
+
+	cloudKMSScope = "https://www.googleapis.com/auth/cloudkms"
+)
+
+// GCPCKMSSeal is a seal encrypting its values with a crypto key of the Google
+// Cloud Key Management Service. The values are envelope encrypted, only their
+// data key is encrypted by Cloud KMS.
+type GCPCKMSSeal struct {
+	logger log.Logger
+	client *cloudkms.Service
+
+	// keyName is the resource name of the crypto key
+	keyName string
+
+	currentKeyID *atomic.Value
+}
+
+var _ seal.Access = (*GCPCKMSSeal)(nil)
+
+// NewSeal creates an unconfigured GCP Cloud KMS seal
+func NewSeal(logger log.Logger) *GCPCKMSSeal {
+	s := &GCPCKMSSeal{
+		logger:       logger,
+		currentKeyID: new(atomic.Value),
+	}
+	s.currentKeyID.Store("")
+	return s
+}
+
+// SetConfig configures the seal and checks that the crypto key can be used,
+// returning information about the configuration to display
+func (s *GCPCKMSSeal) SetConfig(config map[string]string) (map[string]string, error) {
+	if config == nil {
+		config = map[string]string{}
+	}
+
+	option := func(env, name, def string) string {
+		if value := os.Getenv(env); value != "" {
+			return value
+		}
+		if value := config[name]; value != "" {
+			return value
+		}
+		return def
+	}
+	project := option("GOOGLE_PROJECT", "project", "")
+	region := option("GOOGLE_REGION", "region", "global")
+	keyRing := option(EnvGCPCKMSSealKeyRing, "key_ring", "")
+	cryptoKey := option(EnvGCPCKMSSealCryptoKey, "crypto_key", "")
+	credentials := option("GOOGLE_CREDENTIALS", "credentials", "")
+	endpoint := option("GOOGLE_CKMS_ENDPOINT", "endpoint", defaultEndpoint)
+
+	switch {
+	case project == "":
+		return nil, fmt.Errorf("'project' not found for GCP Cloud KMS seal configuration")
+	case keyRing == "":
+		return nil, fmt.Errorf("'key_ring' not found for GCP Cloud KMS seal configuration")
+	case cryptoKey == "":
+		return nil, fmt.Errorf("'crypto_key' not found for GCP Cloud KMS seal configuration")
+	}
+	s.keyName = fmt.Sprintf("projects/%s/locations/%s/keyRings/%s/cryptoKeys/%s", project, region, keyRing, cryptoKey)
+
+	// Use the service account credentials file if one is given, or the
+	// application default credentials
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, cleanhttp.DefaultClient())
+	var client *http.Client
+	if credentials != "" {
+		data, err := ioutil.ReadFile(credentials)
+		if err != nil {
+			return nil, fmt.Errorf("error reading GCP credentials file: %v", err)
+		}
+		jwtConfig, err := google.JWTConfigFromJSON(data, cloudKMSScope)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing GCP credentials file: %v", err)
+		}
+		client = jwtConfig.Client(ctx)
+	} else {
+		var err error
+		client, err = google.DefaultClient(ctx, cloudKMSScope)
+		if err != nil {
+			return nil, fmt.Errorf("error loading GCP default credentials: %v", err)
+		}
+	}
+
+	service, err := cloudkms.New(client)
+	if err != nil {
+		return nil, err
+	}
+	service.BasePath = strings.TrimSuffix(endpoint, "/") + "/"
+	s.client = service
+
+	// Check that the crypto key can be used, which also resolves its primary
+	// version
+	if _, err := s.Encrypt([]byte("vault")); err != nil {
+		return nil, fmt.Errorf("error checking the GCP Cloud KMS crypto key: %v", err)
+	}
+
+	return map[string]string{
+		"GCP KMS project":    project,
+		"GCP KMS region":     region,
+		"GCP KMS key ring":   keyRing,
+		"GCP KMS crypto key": cryptoKey,
+	}, nil
+}
+
+func (s *GCPCKMSSeal) SealType() string {
+	return seal.GCPCKMS
+}
+
+// KeyID returns the crypto key version used by the last encryption
+func (s *GCPCKMSSeal) KeyID() string {
+	return s.currentKeyID.Load().(string)
+}
+
+func (s *GCPCKMSSeal) Init() error {
+	return nil
+}
+
+func (s *GCPCKMSSeal) Finalize() error {
+	return nil
+}
+
+func (s *GCPCKMSSeal) Encrypt(plaintext []byte) (*seal.EncryptedBlobInfo, error) {
+	if s.client == nil {
+		return nil, fmt.Errorf("seal is not configured")
+	}
+
+	env, err := seal.EnvelopeEncrypt(plaintext)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.client.Projects.Locations.KeyRings.CryptoKeys.Encrypt(s.keyName, &cloudkms.EncryptRequest{
+		Plaintext: base64.StdEncoding.EncodeToString(env.Key),
+	}).Do()
+	if err != nil {
+		return nil, fmt.Errorf("error encrypting data key: %v", err)
+	}
+	wrappedKey, err := base64.StdEncoding.DecodeString(resp.Ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding encrypted data key: %v", err)
+	}
+	s.currentKeyID.Store(resp.Name)
+
+	return &seal.EncryptedBlobInfo{
+		Ciphertext: env.Ciphertext,
+		IV:         env.IV,
+		KeyInfo: &seal.KeyInfo{
+			KeyID:      resp.Name,
+			WrappedKey: wrappedKey,
+		},
+	}, nil
+}
+
+func (s *GCPCKMSSeal) Decrypt(in *seal.EncryptedBlobInfo) ([]byte, error) {
+	if s.client == nil {
+		return nil, fmt.Errorf("seal is not configured")
+	}
+	if in == nil || in.KeyInfo == nil {
+		return nil, fmt.Errorf("missing key info")
+	}
+
+	// Cloud KMS finds the version of the crypto key the data key was
+	// encrypted with, as long as it is enabled
+	resp, err := s.client.Projects.Locations.KeyRings.CryptoKeys.Decrypt(s.keyName, &cloudkms.DecryptRequest{
+		Ciphertext: base64.StdEncoding.EncodeToString(in.KeyInfo.WrappedKey),
+	}).Do()
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting data key: %v", err)
+	}
+	key, err := base64.StdEncoding.DecodeString(resp.Plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding data key: %v", err)
+	}
+
+	return seal.EnvelopeDecrypt(&seal.EnvelopeInfo{
+		Ciphertext: in.Ciphertext,
+		Key:        key,
+		IV:         in.IV,
+	})
+}
@@ -0,0 +1,156 @@
+package gcpckms
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/helper/logformat"
+	log "github.com/mgutz/logxi/v1"
+)
+
+const testKeyName = "projects/vault/locations/global/keyRings/vault/cryptoKeys/unseal"
+
+// testCKMSServer mocks the OAuth token endpoint and the encrypt and decrypt
+// methods of Cloud KMS, "wrapping" the data keys by prefixing them with the
+// crypto key version
+func testCKMSServer(t *testing.T, version *string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"access_token": "test-token", "token_type": "Bearer", "expires_in": 3600}`))
+			return
+		}
+
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			t.Errorf("bad authorization: %q", r.Header.Get("Authorization"))
+		}
+
+		var body map[string][]byte
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+			return
+		}
+
+		var resp interface{}
+		switch r.URL.Path {
+		case "/v1/" + testKeyName + ":encrypt":
+			resp = map[string]interface{}{
+				"name":       *version,
+				"ciphertext": append([]byte(*version+"|"), body["plaintext"]...),
+			}
+		case "/v1/" + testKeyName + ":decrypt":
+			parts := bytes.SplitN(body["ciphertext"], []byte("|"), 2)
+			resp = map[string]interface{}{
+				"plaintext": parts[1],
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": {"code": 404, "message": "CryptoKey not found.", "status": "NOT_FOUND"}}`))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}))
+}
+
+func testCredentials(t *testing.T, dir, tokenURL string) string {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	})
+
+	buf, err := json.Marshal(map[string]string{
+		"type":         "service_account",
+		"client_email": "vault@vault.iam.gserviceaccount.com",
+		"private_key":  string(keyPEM),
+		"token_uri":    tokenURL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "credentials.json")
+	if err := ioutil.WriteFile(path, buf, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestGCPCKMSSeal(t *testing.T) {
+	for _, env := range []string{"GOOGLE_PROJECT", "GOOGLE_REGION", "GOOGLE_CREDENTIALS", "GOOGLE_CKMS_ENDPOINT", EnvGCPCKMSSealKeyRing, EnvGCPCKMSSealCryptoKey} {
+		os.Unsetenv(env)
+	}
+
+	dir, err := ioutil.TempDir("", "vault-gcpckms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	version := testKeyName + "/cryptoKeyVersions/1"
+	server := testCKMSServer(t, &version)
+	defer server.Close()
+
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+	s := NewSeal(logger)
+	config := map[string]string{
+		"credentials": testCredentials(t, dir, server.URL+"/token"),
+		"project":     "vault",
+		"key_ring":    "vault",
+		"endpoint":    server.URL,
+	}
+	if _, err := s.SetConfig(config); err == nil {
+		t.Fatalf("expected an error without a crypto key")
+	}
+
+	config["crypto_key"] = "unseal"
+	info, err := s.SetConfig(config)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if info["GCP KMS region"] != "global" || s.KeyID() != version {
+		t.Fatalf("bad: info: %#v, key ID: %s", info, s.KeyID())
+	}
+
+	blob, err := s.Encrypt([]byte("foo"))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if blob.KeyInfo.KeyID != version || bytes.Contains(blob.Ciphertext, []byte("foo")) {
+		t.Fatalf("bad: %#v", blob)
+	}
+
+	// Values encrypted with a former version can still be decrypted
+	version = testKeyName + "/cryptoKeyVersions/2"
+	if _, err := s.Encrypt([]byte("bar")); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if s.KeyID() != version {
+		t.Fatalf("bad: key ID: %s", s.KeyID())
+	}
+	plaintext, err := s.Decrypt(blob)
+	if err != nil || string(plaintext) != "foo" {
+		t.Fatalf("bad: plaintext: %q, err: %v", plaintext, err)
+	}
+
+	config["crypto_key"] = "missing"
+	if _, err := NewSeal(logger).SetConfig(config); err == nil || !strings.Contains(err.Error(), "CryptoKey not found") {
+		t.Fatalf("expected a not found error, got %v", err)
+	}
+}
@@ -0,0 +1,110 @@
+package seal
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"fmt"
+)
+
+const (
+	Shamir        = "shamir"
+	AWSKMS        = "awskms"
+	GCPCKMS       = "gcpckms"
+	AzureKeyVault = "azurekeyvault"
+	Test          = "test-auto"
+)
+
+// Access is the low level interface of a key management service used by an
+// auto-unseal seal to encrypt the values it stores, such as the barrier key
+type Access interface {
+	// SealType returns the type of the seal
+	SealType() string
+
+	// KeyID returns the identifier of the key currently used for encryption
+	KeyID() string
+
+	// Init is called when the seal is set up, before any encryption
+	Init() error
+
+	// Finalize is called when the seal is no longer used
+	Finalize() error
+
+	Encrypt(plaintext []byte) (*EncryptedBlobInfo, error)
+	Decrypt(in *EncryptedBlobInfo) ([]byte, error)
+}
+
+// EncryptedBlobInfo holds a value encrypted by an Access, along with the
+// information required to decrypt it
+type EncryptedBlobInfo struct {
+	Ciphertext []byte `json:"ciphertext"`
+	IV         []byte `json:"iv"`
+
+	// KeyInfo describes the key the value was encrypted with
+	KeyInfo *KeyInfo `json:"key_info"`
+}
+
+// KeyInfo describes the key a value was encrypted with. Envelope encrypted
+// values also hold their data key, wrapped by the key management service.
+type KeyInfo struct {
+	KeyID      string `json:"key_id"`
+	WrappedKey []byte `json:"wrapped_key"`
+}
+
+// EnvelopeInfo is the result of an envelope encryption: the ciphertext, the
+// data key it was encrypted with and the nonce
+type EnvelopeInfo struct {
+	Ciphertext []byte
+	Key        []byte
+	IV         []byte
+}
+
+// EnvelopeEncrypt encrypts a value with a newly generated AES-256 data key in
+// GCM mode. The data key is returned so that it can be wrapped by a key
+// management service, which may not support encrypting large values.
+func EnvelopeEncrypt(plaintext []byte) (*EnvelopeInfo, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate data key: %v", err)
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	iv := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(iv); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %v", err)
+	}
+
+	return &EnvelopeInfo{
+		Ciphertext: gcm.Seal(nil, iv, plaintext, nil),
+		Key:        key,
+		IV:         iv,
+	}, nil
+}
+
+// EnvelopeDecrypt decrypts a value encrypted by EnvelopeEncrypt
+func EnvelopeDecrypt(info *EnvelopeInfo) ([]byte, error) {
+	gcm, err := newGCM(info.Key)
+	if err != nil {
+		return nil, err
+	}
+	if len(info.IV) != gcm.NonceSize() {
+		return nil, fmt.Errorf("invalid nonce size")
+	}
+
+	plaintext, err := gcm.Open(nil, info.IV, info.Ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt envelope: %v", err)
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %v", err)
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,115 @@
+package seal
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sync"
+)
+
+// TestSeal is an Access for tests, wrapping the data keys with keys derived
+// from its key ID. Changing the key ID simulates a key rotation: the values
+// encrypted with former keys can still be decrypted.
+type TestSeal struct {
+	l     sync.RWMutex
+	keyID string
+}
+
+var _ Access = (*TestSeal)(nil)
+
+// NewTestSeal creates a test seal with the given key ID
+func NewTestSeal(keyID string) *TestSeal {
+	if keyID == "" {
+		keyID = "static-key"
+	}
+	return &TestSeal{keyID: keyID}
+}
+
+func (t *TestSeal) SealType() string {
+	return Test
+}
+
+func (t *TestSeal) KeyID() string {
+	t.l.RLock()
+	defer t.l.RUnlock()
+	return t.keyID
+}
+
+// SetKeyID changes the key used for encryption
+func (t *TestSeal) SetKeyID(keyID string) {
+	t.l.Lock()
+	defer t.l.Unlock()
+	t.keyID = keyID
+}
+
+func (t *TestSeal) Init() error {
+	return nil
+}
+
+func (t *TestSeal) Finalize() error {
+	return nil
+}
+
+func (t *TestSeal) Encrypt(plaintext []byte) (*EncryptedBlobInfo, error) {
+	env, err := EnvelopeEncrypt(plaintext)
+	if err != nil {
+		return nil, err
+	}
+
+	keyID := t.KeyID()
+	wrapped, err := testWrap(keyID, env.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &EncryptedBlobInfo{
+		Ciphertext: env.Ciphertext,
+		IV:         env.IV,
+		KeyInfo: &KeyInfo{
+			KeyID:      keyID,
+			WrappedKey: wrapped,
+		},
+	}, nil
+}
+
+func (t *TestSeal) Decrypt(in *EncryptedBlobInfo) ([]byte, error) {
+	if in == nil || in.KeyInfo == nil {
+		return nil, fmt.Errorf("missing key info")
+	}
+
+	key, err := testUnwrap(in.KeyInfo.KeyID, in.KeyInfo.WrappedKey)
+	if err != nil {
+		return nil, err
+	}
+	return EnvelopeDecrypt(&EnvelopeInfo{
+		Ciphertext: in.Ciphertext,
+		Key:        key,
+		IV:         in.IV,
+	})
+}
+
+// testWrap encrypts a data key with the key derived from the key ID, using
+// a fixed nonce as each data key is only wrapped once
+func testWrap(keyID string, key []byte) ([]byte, error) {
+	gcm, err := newGCM(testKey(keyID))
+	if err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nil, make([]byte, gcm.NonceSize()), key, nil), nil
+}
+
+func testUnwrap(keyID string, wrapped []byte) ([]byte, error) {
+	gcm, err := newGCM(testKey(keyID))
+	if err != nil {
+		return nil, err
+	}
+	key, err := gcm.Open(nil, make([]byte, gcm.NonceSize()), wrapped, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %v", err)
+	}
+	return key, nil
+}
+
+func testKey(keyID string) []byte {
+	sum := sha256.Sum256([]byte("test-seal/" + keyID))
+	return sum[:]
+}
@@ -0,0 +1,461 @@
+package vault
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/physical"
+	"github.com/hashicorp/vault/vault/seal"
+)
+
+const (
+	// storedBarrierKeysPath is the path used to store the barrier keys,
+	// encrypted by the key management service of an auto-unseal seal. This
+	// value is stored outside of the barrier, since it is required to unseal.
+	storedBarrierKeysPath = "core/hsm/barrier-unseal-keys"
+)
+
+// autoSeal is a Seal storing the barrier keys encrypted by a key management
+// service, so that Vault unseals itself on startup. Recovery keys replace
+// the unseal keys for the operations requiring a quorum of key holders, such
+// as rekeying or generating a root token.
+type autoSeal struct {
+	seal.Access
+
+	core *Core
+
+	// l protects the cached configurations and serializes the updates of the
+	// encrypted values
+	l              sync.Mutex
+	barrierConfig  *SealConfig
+	recoveryConfig *SealConfig
+}
+
+// NewAutoSeal creates a seal backed by the given key management service
+func NewAutoSeal(access seal.Access) Seal {
+	return &autoSeal{
+		Access: access,
+	}
+}
+
+func (d *autoSeal) checkCore() error {
+	if d.core == nil {
+		return fmt.Errorf("seal does not have a core set")
+	}
+	return nil
+}
+
+func (d *autoSeal) SetCore(core *Core) {
+	d.core = core
+}
+
+func (d *autoSeal) Init() error {
+	return d.Access.Init()
+}
+
+func (d *autoSeal) Finalize() error {
+	return d.Access.Finalize()
+}
+
+func (d *autoSeal) BarrierType() string {
+	return d.SealType()
+}
+
+func (d *autoSeal) StoredKeysSupported() bool {
+	return true
+}
+
+func (d *autoSeal) RecoveryKeySupported() bool {
+	return true
+}
+
+func (d *autoSeal) SetStoredKeys(keys [][]byte) error {
+	if err := d.checkCore(); err != nil {
+		return err
+	}
+
+	buf, err := json.Marshal(keys)
+	if err != nil {
+		return fmt.Errorf("failed to encode stored keys: %v", err)
+	}
+
+	d.l.Lock()
+	defer d.l.Unlock()
+	return d.putEncrypted(d.core.physical.Put, storedBarrierKeysPath, buf)
+}
+
+func (d *autoSeal) GetStoredKeys() ([][]byte, error) {
+	if err := d.checkCore(); err != nil {
+		return nil, err
+	}
+
+	buf, err := d.getEncrypted(d.core.physical.Get, storedBarrierKeysPath)
+	if err != nil || buf == nil {
+		return nil, err
+	}
+
+	var keys [][]byte
+	if err := jsonutil.DecodeJSON(buf, &keys); err != nil {
+		return nil, fmt.Errorf("failed to decode stored keys: %v", err)
+	}
+	return keys, nil
+}
+
+func (d *autoSeal) BarrierConfig() (*SealConfig, error) {
+	d.l.Lock()
+	defer d.l.Unlock()
+
+	if d.barrierConfig != nil {
+		return d.barrierConfig.Clone(), nil
+	}
+
+	if err := d.checkCore(); err != nil {
+		return nil, err
+	}
+
+	conf, err := loadBarrierConfig(d.core, d.BarrierType())
+	if err != nil || conf == nil {
+		return nil, err
+	}
+
+	d.barrierConfig = conf
+	return d.barrierConfig.Clone(), nil
+}
+
+func (d *autoSeal) SetBarrierConfig(config *SealConfig) error {
+	if err := d.checkCore(); err != nil {
+		return err
+	}
+
+	d.l.Lock()
+	defer d.l.Unlock()
+
+	if config == nil {
+		d.barrierConfig = nil
+		return nil
+	}
+
+	config.Type = d.BarrierType()
+	if err := storeBarrierConfig(d.core, config); err != nil {
+		return err
+	}
+
+	d.barrierConfig = config.Clone()
+	return nil
+}
+
+func (d *autoSeal) RecoveryType() string {
+	return seal.Shamir
+}
+
+// RecoveryConfig returns the recovery key configuration, which is stored
+// inside the barrier
+func (d *autoSeal) RecoveryConfig() (*SealConfig, error) {
+	d.l.Lock()
+	defer d.l.Unlock()
+
+	if d.recoveryConfig != nil {
+		return d.recoveryConfig.Clone(), nil
+	}
+
+	if err := d.checkCore(); err != nil {
+		return nil, err
+	}
+
+	entry, err := d.core.barrier.Get(recoverySealConfigPath)
+	if err != nil {
+		d.core.logger.Error("core: failed to read recovery configuration", "error", err)
+		return nil, fmt.Errorf("failed to read recovery configuration: %v", err)
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var conf SealConfig
+	if err := jsonutil.DecodeJSON(entry.Value, &conf); err != nil {
+		d.core.logger.Error("core: failed to decode recovery configuration", "error", err)
+		return nil, fmt.Errorf("failed to decode recovery configuration: %v", err)
+	}
+	if conf.Type != d.RecoveryType() {
+		return nil, fmt.Errorf("recovery seal type of %s does not match loaded type of %s", conf.Type, d.RecoveryType())
+	}
+
+	d.recoveryConfig = &conf
+	return d.recoveryConfig.Clone(), nil
+}
+
+func (d *autoSeal) SetRecoveryConfig(config *SealConfig) error {
+	if err := d.checkCore(); err != nil {
+		return err
+	}
+
+	d.l.Lock()
+	defer d.l.Unlock()
+
+	// Provide a way to wipe out the cached value
+	if config == nil {
+		d.recoveryConfig = nil
+		return nil
+	}
+
+	config.Type = d.RecoveryType()
+	buf, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("failed to encode recovery configuration: %v", err)
+	}
+
+	if err := d.core.barrier.Put(&Entry{
+		Key:   recoverySealConfigPath,
+		Value: buf,
+	}); err != nil {
+		d.core.logger.Error("core: failed to write recovery configuration", "error", err)
+		return fmt.Errorf("failed to write recovery configuration: %v", err)
+	}
+
+	d.recoveryConfig = config.Clone()
+	return nil
+}
+
+// SetRecoveryKey stores the recovery key inside the barrier, additionally
+// encrypted by the key management service
+func (d *autoSeal) SetRecoveryKey(key []byte) error {
+	if err := d.checkCore(); err != nil {
+		return err
+	}
+	if len(key) == 0 {
+		return fmt.Errorf("recovery key to store is empty")
+	}
+
+	d.l.Lock()
+	defer d.l.Unlock()
+	return d.putEncrypted(d.barrierPut, recoveryKeyPath, key)
+}
+
+func (d *autoSeal) VerifyRecoveryKey(key []byte) error {
+	if err := d.checkCore(); err != nil {
+		return err
+	}
+	if len(key) == 0 {
+		return fmt.Errorf("recovery key to verify is empty")
+	}
+
+	stored, err := d.getEncrypted(d.barrierGet, recoveryKeyPath)
+	if err != nil {
+		return err
+	}
+	if stored == nil {
+		return fmt.Errorf("no recovery key found")
+	}
+
+	if subtle.ConstantTimeCompare(stored, key) != 1 {
+		return fmt.Errorf("recovery key does not match submitted values")
+	}
+	return nil
+}
+
+// SealRewrapStatus describes the values encrypted by the key management
+// service of an auto-unseal seal: the values protecting the keys of the
+// barrier and the seal-wrapped entries
+type SealRewrapStatus struct {
+	// SealType is the type of the seal
+	SealType string
+
+	// KeyID identifies the key currently used for encryption
+	KeyID string
+
+	// Entries is the number of encrypted values
+	Entries int
+
+	// StaleEntries is the number of values encrypted with another key, or
+	// by the seal being migrated away from
+	StaleEntries int
+
+	// Rewrapped is the number of values re-encrypted by a rewrap
+	Rewrapped int
+}
+
+// SealRewrapStatus returns the status of the values encrypted by the seal
+func (c *Core) SealRewrapStatus() (*SealRewrapStatus, error) {
+	d, ok := c.seal.(*autoSeal)
+	if !ok && c.unwrapSeal == nil {
+		return nil, fmt.Errorf("seal of type %s does not support rewrapping", c.seal.BarrierType())
+	}
+
+	status := &SealRewrapStatus{
+		SealType: c.seal.BarrierType(),
+	}
+	if ok {
+		d.l.Lock()
+		defer d.l.Unlock()
+
+		status.KeyID = d.KeyID()
+		for _, path := range d.rewrapPaths() {
+			blob, err := d.getBlob(path.get, path.key)
+			if err != nil {
+				return nil, err
+			}
+			if blob == nil {
+				continue
+			}
+			status.Entries++
+			if blob.KeyInfo == nil || blob.KeyInfo.KeyID != status.KeyID {
+				status.StaleEntries++
+			}
+		}
+	}
+
+	if err := c.sealWrapStorage.walkSealWrapped(func(key string, wrapped *sealWrappedValue) error {
+		status.Entries++
+		if c.sealWrapStorage.stale(wrapped) {
+			status.StaleEntries++
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// SealRewrap re-encrypts the values encrypted by the seal with its current
+// key, so that the keys formerly used can be retired. After a migration to
+// Shamir, the entries seal-wrapped by the former seal are rewritten only
+// encrypted by the barrier, so that the former seal can be removed.
+func (c *Core) SealRewrap() (*SealRewrapStatus, error) {
+	d, ok := c.seal.(*autoSeal)
+	if !ok && c.unwrapSeal == nil {
+		return nil, fmt.Errorf("seal of type %s does not support rewrapping", c.seal.BarrierType())
+	}
+
+	status := &SealRewrapStatus{
+		SealType: c.seal.BarrierType(),
+	}
+	if ok {
+		d.l.Lock()
+		defer d.l.Unlock()
+
+		for _, path := range d.rewrapPaths() {
+			plaintext, err := d.getEncrypted(path.get, path.key)
+			if err != nil {
+				return nil, err
+			}
+			if plaintext == nil {
+				continue
+			}
+			if err := d.putEncrypted(path.put, path.key, plaintext); err != nil {
+				return nil, err
+			}
+			status.Entries++
+			status.Rewrapped++
+		}
+		status.KeyID = d.KeyID()
+	}
+
+	if err := c.sealWrapStorage.walkSealWrapped(func(key string, wrapped *sealWrappedValue) error {
+		rewrapped, err := c.sealWrapStorage.rewrap(key)
+		if err != nil {
+			return err
+		}
+		// Under Shamir, the rewritten entries are no longer seal-wrapped
+		if ok {
+			status.Entries++
+		}
+		if rewrapped {
+			status.Rewrapped++
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	c.logger.Info("core: rewrapped values encrypted by the seal", "seal_type", status.SealType, "key_id", status.KeyID, "entries", status.Rewrapped)
+	return status, nil
+}
+
+// rewrapPath is a value encrypted by the seal, along with the storage it is
+// held in
+type rewrapPath struct {
+	key string
+	get func(string) (*physical.Entry, error)
+	put func(*physical.Entry) error
+}
+
+func (d *autoSeal) rewrapPaths() []rewrapPath {
+	return []rewrapPath{
+		{storedBarrierKeysPath, d.core.physical.Get, d.core.physical.Put},
+		{recoveryKeyPath, d.barrierGet, d.barrierPut},
+	}
+}
+
+func (d *autoSeal) barrierGet(key string) (*physical.Entry, error) {
+	entry, err := d.core.barrier.Get(key)
+	if err != nil || entry == nil {
+		return nil, err
+	}
+	return &physical.Entry{
+		Key:   entry.Key,
+		Value: entry.Value,
+	}, nil
+}
+
+func (d *autoSeal) barrierPut(entry *physical.Entry) error {
+	return d.core.barrier.Put(&Entry{
+		Key:   entry.Key,
+		Value: entry.Value,
+	})
+}
+
+// getBlob reads an encrypted value without decrypting it
+func (d *autoSeal) getBlob(get func(string) (*physical.Entry, error), key string) (*seal.EncryptedBlobInfo, error) {
+	entry, err := get(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", key, err)
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var blob seal.EncryptedBlobInfo
+	if err := jsonutil.DecodeJSON(entry.Value, &blob); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %v", key, err)
+	}
+	return &blob, nil
+}
+
+// getEncrypted reads and decrypts a value encrypted by the seal. A nil value
+// is returned if it doesn't exist.
+func (d *autoSeal) getEncrypted(get func(string) (*physical.Entry, error), key string) ([]byte, error) {
+	blob, err := d.getBlob(get, key)
+	if err != nil || blob == nil {
+		return nil, err
+	}
+
+	plaintext, err := d.Decrypt(blob)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt %s: %v", key, err)
+	}
+	return plaintext, nil
+}
+
+// putEncrypted encrypts a value with the seal and stores it
+func (d *autoSeal) putEncrypted(put func(*physical.Entry) error, key string, plaintext []byte) error {
+	blob, err := d.Encrypt(plaintext)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt %s: %v", key, err)
+	}
+
+	buf, err := json.Marshal(blob)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %v", key, err)
+	}
+
+	if err := put(&physical.Entry{
+		Key:   key,
+		Value: buf,
+	}); err != nil {
+		return fmt.Errorf("failed to write %s: %v", key, err)
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package vault
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/shamir"
+	"github.com/hashicorp/vault/vault/seal"
+)
+
+func testCoreAutoSeal(t *testing.T, access seal.Access) (*Core, [][]byte, string) {
+	core := TestCoreWithSeal(t, NewAutoSeal(access), false)
+	result, err := core.Initialize(&InitParams{
+		BarrierConfig: &SealConfig{
+			SecretShares:    1,
+			SecretThreshold: 1,
+			StoredShares:    1,
+		},
+		RecoveryConfig: &SealConfig{
+			SecretShares:    3,
+			SecretThreshold: 2,
+		},
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(result.SecretShares) != 0 || len(result.RecoveryShares) != 3 {
+		t.Fatalf("bad: %#v", result)
+	}
+
+	if err := core.UnsealWithStoredKeys(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if sealed, _ := core.Sealed(); sealed {
+		t.Fatal("should not be sealed")
+	}
+	return core, result.RecoveryShares, result.RootToken
+}
+
+func TestAutoSeal(t *testing.T) {
+	access := seal.NewTestSeal("key-1")
+	core, recoveryShares, root := testCoreAutoSeal(t, access)
+
+	conf, err := core.SealAccess().BarrierConfig()
+	if err != nil || conf.Type != seal.Test {
+		t.Fatalf("bad: conf: %#v, err: %v", conf, err)
+	}
+	conf, err = core.SealAccess().RecoveryConfig()
+	if err != nil || conf.Type != seal.Shamir || conf.SecretShares != 3 {
+		t.Fatalf("bad: conf: %#v, err: %v", conf, err)
+	}
+
+	// The barrier key is not stored in plaintext
+	keys, err := core.seal.GetStoredKeys()
+	if err != nil || len(keys) != 1 {
+		t.Fatalf("bad: keys: %v, err: %v", keys, err)
+	}
+	entry, err := core.physical.Get(storedBarrierKeysPath)
+	if err != nil || entry == nil {
+		t.Fatalf("bad: entry: %#v, err: %v", entry, err)
+	}
+	if bytes.Contains(entry.Value, keys[0]) {
+		t.Fatal("barrier key is stored in plaintext")
+	}
+
+	// The recovery shares reconstruct the recovery key
+	recoveryKey, err := shamir.Combine(recoveryShares[:2])
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := core.seal.VerifyRecoveryKey(recoveryKey); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := core.seal.VerifyRecoveryKey([]byte("wrong")); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	// Vault unseals itself after being sealed
+	if err := core.Seal(root); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := core.UnsealWithStoredKeys(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if sealed, _ := core.Sealed(); sealed {
+		t.Fatal("should not be sealed")
+	}
+
+	// A seal of another type can't use the barrier configuration
+	core.seal = &DefaultSeal{core: core}
+	if _, err := core.seal.BarrierConfig(); err == nil || !strings.Contains(err.Error(), "does not match loaded type") {
+		t.Fatalf("expected a type mismatch, got %v", err)
+	}
+}
+
+func TestCore_SealRewrap(t *testing.T) {
+	access := seal.NewTestSeal("key-1")
+	core, recoveryShares, root := testCoreAutoSeal(t, access)
+
+	request := func(op logical.Operation) map[string]interface{} {
+		req := logical.TestRequest(t, op, "sys/sealwrap/rewrap")
+		req.ClientToken = root
+		resp, err := core.HandleRequest(req)
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+		}
+		return resp.Data
+	}
+
+	data := request(logical.ReadOperation)
+	if data["seal_type"] != seal.Test || data["key_id"] != "key-1" || data["entries"] != 4 || data["stale_entries"] != 0 {
+		t.Fatalf("bad: %#v", data)
+	}
+
+	// Rotate the key of the seal
+	access.SetKeyID("key-2")
+	status, err := core.SealRewrapStatus()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if status.KeyID != "key-2" || status.Entries != 4 || status.StaleEntries != 4 {
+		t.Fatalf("bad: %#v", status)
+	}
+
+	data = request(logical.UpdateOperation)
+	if data["key_id"] != "key-2" || data["rewrapped"] != 4 || data["stale_entries"] != 0 {
+		t.Fatalf("bad: %#v", data)
+	}
+	data = request(logical.ReadOperation)
+	if data["entries"] != 4 || data["stale_entries"] != 0 {
+		t.Fatalf("bad: %#v", data)
+	}
+
+	// The rewrapped values can still be used
+	recoveryKey, err := shamir.Combine(recoveryShares[1:])
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := core.seal.VerifyRecoveryKey(recoveryKey); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := core.Seal(root); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := core.UnsealWithStoredKeys(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if sealed, _ := core.Sealed(); sealed {
+		t.Fatal("should not be sealed")
+	}
+}
+
+func TestCore_SealRewrap_shamir(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "sys/sealwrap/rewrap")
+	req.ClientToken = root
+	resp, err := c.HandleRequest(req)
+	if err != nil || resp == nil || !resp.IsError() || !strings.Contains(resp.Data["error"].(string), "does not support rewrapping") {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+}
@@ -0,0 +1,557 @@
+package vault
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/physical"
+	"github.com/hashicorp/vault/shamir"
+	"github.com/hashicorp/vault/vault/seal"
+)
+
+const (
+	// sealMigrationPath is the path of the record of a seal migration in
+	// progress. This value is stored outside of the barrier, since it is
+	// required to unseal.
+	sealMigrationPath = "core/seal-migration"
+)
+
+var (
+	// ErrSealMigrationPending is returned when unsealing without the migrate
+	// flag while the seal protecting the master key is being migrated
+	ErrSealMigrationPending = errors.New("seal migration is pending, unseal with the migrate flag to complete it")
+
+	// ErrNoSealMigration is returned when unsealing with the migrate flag
+	// while no seal migration is pending
+	ErrNoSealMigration = errors.New("no seal migration is pending")
+)
+
+// sealMigration describes a pending migration of the protection of the
+// master key, from the seal the stored barrier configuration was written by
+// to the configured seal
+type sealMigration struct {
+	// from is the seal the master key is migrated from
+	from Seal
+
+	// config is the configuration of the keys submitted to migrate: the
+	// unseal keys when migrating from Shamir, or the recovery keys when
+	// migrating to Shamir
+	config *SealConfig
+}
+
+// sealMigrationRecord is persisted before the barrier is rekeyed, so that a
+// migration interrupted before the barrier configuration is written can be
+// resumed. It holds the new master key sealed by both seals: encrypted by an
+// auto-unseal seal, or with the master key reconstructed by the Shamir key
+// holders.
+type sealMigrationRecord struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+
+	// FromKey and ToKey are the new master key sealed by the seal migrated
+	// from and by the seal migrated to
+	FromKey []byte `json:"from_key"`
+	ToKey   []byte `json:"to_key"`
+}
+
+// setupSealMigration checks whether the stored barrier configuration was
+// written by another seal than the configured one, in which case a migration
+// is pending. Migrating from Shamir to an auto-unseal seal only requires the
+// auto-unseal seal to be configured; migrating back requires the auto-unseal
+// seal to be given as the unwrap seal, so that the master key can be
+// decrypted.
+func (c *Core) setupSealMigration(unwrapSeal Seal) error {
+	// Without an auto-unseal seal, there is nothing to migrate
+	if unwrapSeal == nil && !c.seal.StoredKeysSupported() {
+		return nil
+	}
+
+	pe, err := c.physical.Get(barrierSealConfigPath)
+	if err != nil {
+		return &NonFatalError{Err: fmt.Errorf("failed to check seal configuration: %v", err)}
+	}
+	if pe == nil {
+		return nil
+	}
+
+	var conf SealConfig
+	if err := jsonutil.DecodeJSON(pe.Value, &conf); err != nil {
+		return fmt.Errorf("failed to decode seal configuration: %v", err)
+	}
+	storedType := conf.Type
+	if storedType == "" {
+		storedType = seal.Shamir
+	}
+
+	to := c.seal.BarrierType()
+	if storedType == to {
+		if unwrapSeal != nil {
+			c.logger.Warn("core: seal migration configured but the master key is already protected by the seal, ignoring the unwrap seal", "seal_type", to)
+		}
+
+		// The record of a migration interrupted once complete is stale
+		if err := c.physical.Delete(sealMigrationPath); err != nil {
+			return &NonFatalError{Err: fmt.Errorf("failed to delete seal migration record: %v", err)}
+		}
+		return nil
+	}
+
+	var from Seal
+	switch {
+	case storedType == seal.Shamir:
+		from = &DefaultSeal{}
+	case to == seal.Shamir && unwrapSeal.BarrierType() == storedType:
+		from = unwrapSeal
+	case to == seal.Shamir:
+		return fmt.Errorf("master key is protected by a seal of type %s, not by the disabled seal of type %s", storedType, unwrapSeal.BarrierType())
+	default:
+		return fmt.Errorf("migrating from a seal of type %s to a seal of type %s is not supported, migrate to shamir first", storedType, to)
+	}
+	from.SetCore(c)
+
+	var config *SealConfig
+	if from.BarrierType() == seal.Shamir {
+		config, err = from.BarrierConfig()
+	} else {
+		config, err = c.migrationRecoveryConfig(from)
+	}
+	if err != nil {
+		return err
+	}
+
+	c.sealMigration = &sealMigration{
+		from:   from,
+		config: config,
+	}
+	c.logger.Warn("core: seal migration pending, unseal with the migrate flag to complete it", "from", from.BarrierType(), "to", to)
+	return nil
+}
+
+// migrationRecoveryConfig reads the recovery configuration of the seal
+// migrated from, which is stored inside the barrier. The barrier is unsealed
+// with the stored key, or with the key of an interrupted migration.
+func (c *Core) migrationRecoveryConfig(from Seal) (*SealConfig, error) {
+	masterKey, err := storedMasterKey(from)
+	if err != nil {
+		return nil, err
+	}
+	defer memzero(masterKey)
+
+	if err := c.barrier.Unseal(masterKey); err != nil {
+		record, recordErr := c.sealMigrationRecord(from)
+		if recordErr != nil || record == nil {
+			return nil, fmt.Errorf("failed to unseal barrier with the stored key: %v", err)
+		}
+		newMasterKey, err := unwrapSealMigrationKey(from, nil, record.FromKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt the recorded master key: %v", err)
+		}
+		defer memzero(newMasterKey)
+		if err := c.barrier.Unseal(newMasterKey); err != nil {
+			return nil, fmt.Errorf("failed to unseal barrier with the stored key: %v", err)
+		}
+	}
+	defer c.barrier.Seal()
+
+	config, err := from.RecoveryConfig()
+	if err != nil {
+		return nil, err
+	}
+	if config == nil || config.SecretShares == 0 {
+		return nil, fmt.Errorf("recovery keys are required to migrate from a seal of type %s, but none are configured", from.BarrierType())
+	}
+	return config, nil
+}
+
+// storedMasterKey returns the master key stored by an auto-unseal seal
+func storedMasterKey(s Seal) ([]byte, error) {
+	keys, err := s.GetStoredKeys()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch stored keys: %v", err)
+	}
+	switch len(keys) {
+	case 0:
+		return nil, fmt.Errorf("no stored keys found")
+	case 1:
+		return keys[0], nil
+	default:
+		masterKey, err := shamir.Combine(keys)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute master key: %v", err)
+		}
+		return masterKey, nil
+	}
+}
+
+// SealMigrationConfig returns the configuration of the keys to submit to
+// complete a pending seal migration, or nil if none is pending
+func (c *Core) SealMigrationConfig() *SealConfig {
+	c.stateLock.RLock()
+	defer c.stateLock.RUnlock()
+	if c.sealMigration == nil {
+		return nil
+	}
+	return c.sealMigration.config.Clone()
+}
+
+// migrateSeal moves the protection of the master key to the configured seal,
+// given the key reconstructed from the submitted key shares, and returns the
+// master key to unseal with. The barrier is left unsealed on success.
+//
+// This must be called with the state write lock held
+func (c *Core) migrateSeal(key []byte) ([]byte, error) {
+	m := c.sealMigration
+	if m == nil {
+		return nil, ErrNoSealMigration
+	}
+	c.logger.Info("core: seal migration starting", "from", m.from.BarrierType(), "to", c.seal.BarrierType())
+
+	var masterKey []byte
+	var err error
+	if m.from.BarrierType() == seal.Shamir {
+		masterKey, err = c.migrateFromShamir(m, key)
+	} else {
+		masterKey, err = c.migrateToShamir(m, key)
+	}
+	if err != nil {
+		c.logger.Error("core: seal migration failed", "error", err)
+		return nil, err
+	}
+
+	c.sealMigration = nil
+	c.logger.Info("core: seal migration complete", "seal_type", c.seal.BarrierType())
+	return masterKey, nil
+}
+
+// migrateFromShamir migrates from Shamir to an auto-unseal seal. The barrier
+// is rekeyed with a new master key stored by the seal, and the former master
+// key becomes the recovery key, so that the unseal key holders become the
+// recovery key holders. The new master key is recorded before the barrier is
+// rekeyed and the barrier configuration is written last: until then, the
+// migration can be resumed with the unseal keys, even if it was interrupted
+// after the barrier was rekeyed.
+func (c *Core) migrateFromShamir(m *sealMigration, oldMasterKey []byte) ([]byte, error) {
+	defer memzero(oldMasterKey)
+
+	record, err := c.sealMigrationRecord(m.from)
+	if err != nil {
+		return nil, err
+	}
+
+	// Resume an interrupted migration with the recorded master key, with
+	// which the barrier may already be rekeyed
+	var newMasterKey []byte
+	rekeyed := false
+	if record != nil {
+		if newMasterKey, err = unwrapSealMigrationKey(m.from, oldMasterKey, record.FromKey); err != nil {
+			return nil, &ErrInvalidKey{fmt.Sprintf("failed to decrypt the recorded master key: %v", err)}
+		}
+		rekeyed = c.barrier.Unseal(newMasterKey) == nil
+		c.logger.Info("core: seal migration: resuming interrupted migration", "rekeyed", rekeyed)
+	}
+	if !rekeyed {
+		if err := c.barrier.Unseal(oldMasterKey); err != nil {
+			return nil, err
+		}
+	}
+	migrated := false
+	defer func() {
+		if !migrated {
+			c.barrier.Seal()
+		}
+	}()
+
+	if record == nil {
+		newMasterKey, err = c.barrier.GenerateKey()
+		if err != nil {
+			return nil, fmt.Errorf("master key generation failed: %v", err)
+		}
+		if err := c.recordSealMigration(m, oldMasterKey, newMasterKey); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := c.seal.SetStoredKeys([][]byte{newMasterKey}); err != nil {
+		return nil, fmt.Errorf("failed to store keys: %v", err)
+	}
+	c.logger.Info("core: seal migration: stored the new master key", "seal_type", c.seal.BarrierType())
+
+	recoveryConfig := m.config.Clone()
+	recoveryConfig.StoredShares = 0
+	if err := c.seal.SetRecoveryConfig(recoveryConfig); err != nil {
+		c.rollbackSealMigration(nil)
+		return nil, fmt.Errorf("recovery configuration saving failed: %v", err)
+	}
+	if err := c.seal.SetRecoveryKey(oldMasterKey); err != nil {
+		c.rollbackSealMigration(nil)
+		return nil, fmt.Errorf("failed to save recovery key: %v", err)
+	}
+	c.logger.Info("core: seal migration: unseal keys converted to recovery keys", "shares", recoveryConfig.SecretShares, "threshold", recoveryConfig.SecretThreshold)
+
+	if !rekeyed {
+		if err := c.barrier.Rekey(newMasterKey); err != nil {
+			c.rollbackSealMigration(nil)
+			return nil, fmt.Errorf("failed to rekey barrier: %v", err)
+		}
+		c.logger.Info("core: seal migration: security barrier rekeyed")
+	}
+
+	if err := c.seal.SetBarrierConfig(&SealConfig{
+		SecretShares:    1,
+		SecretThreshold: 1,
+		StoredShares:    1,
+	}); err != nil {
+		c.rollbackSealMigration(oldMasterKey)
+		return nil, fmt.Errorf("failed to save seal configuration: %v", err)
+	}
+	c.deleteSealMigrationRecord()
+
+	migrated = true
+	return newMasterKey, nil
+}
+
+// migrateToShamir migrates from an auto-unseal seal to Shamir. The barrier is
+// rekeyed with the recovery key as the master key, so that the recovery key
+// holders become the unseal key holders. The new master key is recorded
+// before the barrier is rekeyed and the barrier configuration is written
+// last: until then, the migration can be resumed with the recovery keys, even
+// if it was interrupted after the barrier was rekeyed.
+func (c *Core) migrateToShamir(m *sealMigration, recoveryKey []byte) ([]byte, error) {
+	oldMasterKey, err := storedMasterKey(m.from)
+	if err != nil {
+		return nil, err
+	}
+	defer memzero(oldMasterKey)
+
+	record, err := c.sealMigrationRecord(m.from)
+	if err != nil {
+		return nil, err
+	}
+
+	// Resume an interrupted migration, in which the barrier may already be
+	// rekeyed with the recorded recovery key
+	rekeyed := false
+	if record != nil {
+		newMasterKey, err := unwrapSealMigrationKey(m.from, nil, record.FromKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt the recorded master key: %v", err)
+		}
+		if subtle.ConstantTimeCompare(newMasterKey, recoveryKey) != 1 {
+			return nil, &ErrInvalidKey{"recovery key does not match the key of the interrupted migration"}
+		}
+		rekeyed = c.barrier.Unseal(recoveryKey) == nil
+		c.logger.Info("core: seal migration: resuming interrupted migration", "rekeyed", rekeyed)
+	}
+	if !rekeyed {
+		if err := c.barrier.Unseal(oldMasterKey); err != nil {
+			return nil, fmt.Errorf("failed to unseal barrier with the stored key: %v", err)
+		}
+	}
+	migrated := false
+	defer func() {
+		if !migrated {
+			c.barrier.Seal()
+		}
+	}()
+
+	if err := m.from.VerifyRecoveryKey(recoveryKey); err != nil {
+		return nil, &ErrInvalidKey{err.Error()}
+	}
+
+	if !rekeyed {
+		if record == nil {
+			if err := c.recordSealMigration(m, nil, recoveryKey); err != nil {
+				return nil, err
+			}
+		}
+		if err := c.barrier.Rekey(recoveryKey); err != nil {
+			return nil, fmt.Errorf("failed to rekey barrier: %v", err)
+		}
+		c.logger.Info("core: seal migration: security barrier rekeyed with the recovery key")
+	}
+
+	config := m.config.Clone()
+	config.StoredShares = 0
+	if err := c.seal.SetBarrierConfig(config); err != nil {
+		c.logger.Warn("core: rolling back seal migration")
+		if err := c.barrier.Rekey(oldMasterKey); err != nil {
+			c.logger.Error("core: seal migration rollback: failed to rekey barrier with the former master key", "error", err)
+		} else {
+			c.deleteSealMigrationRecord()
+		}
+		return nil, fmt.Errorf("failed to save seal configuration: %v", err)
+	}
+	c.deleteSealMigrationRecord()
+	c.logger.Info("core: seal migration: recovery keys converted to unseal keys", "shares", config.SecretShares, "threshold", config.SecretThreshold)
+
+	// The values of the former seal are no longer used
+	if err := c.physical.Delete(storedBarrierKeysPath); err != nil {
+		c.logger.Warn("core: seal migration: failed to delete the stored keys of the former seal", "error", err)
+	}
+	for _, path := range []string{recoverySealConfigPath, recoveryKeyPath} {
+		if err := c.barrier.Delete(path); err != nil {
+			c.logger.Warn("core: seal migration: failed to delete the recovery values of the former seal", "path", path, "error", err)
+		}
+	}
+
+	migrated = true
+	return recoveryKey, nil
+}
+
+// rollbackSealMigration reverts the changes of a failed migration from
+// Shamir, rekeying the barrier back with the former master key if given.
+// The barrier configuration has not been written, so the migration can be
+// retried with the unseal keys. The record of the migration is kept if the
+// barrier could not be rekeyed back.
+func (c *Core) rollbackSealMigration(oldMasterKey []byte) {
+	c.logger.Warn("core: rolling back seal migration")
+
+	if oldMasterKey != nil {
+		if err := c.barrier.Rekey(oldMasterKey); err != nil {
+			c.logger.Error("core: seal migration rollback: failed to rekey barrier with the former master key", "error", err)
+			return
+		}
+	}
+	c.deleteSealMigrationRecord()
+
+	if err := c.physical.Delete(storedBarrierKeysPath); err != nil {
+		c.logger.Warn("core: seal migration rollback: failed to delete stored keys", "error", err)
+	}
+	for _, path := range []string{recoverySealConfigPath, recoveryKeyPath} {
+		if err := c.barrier.Delete(path); err != nil {
+			c.logger.Warn("core: seal migration rollback: failed to delete recovery values", "path", path, "error", err)
+		}
+	}
+	if c.seal.RecoveryKeySupported() {
+		c.seal.SetRecoveryConfig(nil)
+	}
+}
+
+// sealMigrationRecord reads the record of an interrupted migration, or
+// returns nil if there is none
+func (c *Core) sealMigrationRecord(from Seal) (*sealMigrationRecord, error) {
+	entry, err := c.physical.Get(sealMigrationPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read seal migration record: %v", err)
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var record sealMigrationRecord
+	if err := jsonutil.DecodeJSON(entry.Value, &record); err != nil {
+		return nil, fmt.Errorf("failed to decode seal migration record: %v", err)
+	}
+	if record.From != from.BarrierType() || record.To != c.seal.BarrierType() {
+		return nil, fmt.Errorf("an interrupted migration from a seal of type %s to a seal of type %s must be completed first", record.From, record.To)
+	}
+	return &record, nil
+}
+
+// recordSealMigration persists the new master key sealed by both seals before
+// the barrier is rekeyed. The master key reconstructed by the Shamir key
+// holders is the former one when migrating from Shamir, and the new one when
+// migrating to Shamir.
+func (c *Core) recordSealMigration(m *sealMigration, oldMasterKey, newMasterKey []byte) error {
+	shamirKey := oldMasterKey
+	if shamirKey == nil {
+		shamirKey = newMasterKey
+	}
+
+	fromKey, err := wrapSealMigrationKey(m.from, shamirKey, newMasterKey)
+	if err != nil {
+		return fmt.Errorf("failed to seal the new master key: %v", err)
+	}
+	toKey, err := wrapSealMigrationKey(c.seal, shamirKey, newMasterKey)
+	if err != nil {
+		return fmt.Errorf("failed to seal the new master key: %v", err)
+	}
+
+	buf, err := json.Marshal(&sealMigrationRecord{
+		From:    m.from.BarrierType(),
+		To:      c.seal.BarrierType(),
+		FromKey: fromKey,
+		ToKey:   toKey,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode seal migration record: %v", err)
+	}
+	if err := c.physical.Put(&physical.Entry{
+		Key:   sealMigrationPath,
+		Value: buf,
+	}); err != nil {
+		return fmt.Errorf("failed to save seal migration record: %v", err)
+	}
+	return nil
+}
+
+func (c *Core) deleteSealMigrationRecord() {
+	if err := c.physical.Delete(sealMigrationPath); err != nil {
+		c.logger.Warn("core: seal migration: failed to delete the migration record", "error", err)
+	}
+}
+
+// wrapSealMigrationKey seals a master key with a seal: it is encrypted by an
+// auto-unseal seal, or with the given master key of a Shamir seal
+func wrapSealMigrationKey(s Seal, shamirKey, key []byte) ([]byte, error) {
+	if s.BarrierType() == seal.Shamir {
+		gcm, err := sealMigrationAEAD(shamirKey)
+		if err != nil {
+			return nil, err
+		}
+		nonce := make([]byte, gcm.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return nil, err
+		}
+		return gcm.Seal(nonce, nonce, key, []byte(sealMigrationPath)), nil
+	}
+
+	access, ok := s.(seal.Access)
+	if !ok {
+		return nil, fmt.Errorf("seal of type %s cannot encrypt values", s.BarrierType())
+	}
+	blob, err := access.Encrypt(key)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(blob)
+}
+
+// unwrapSealMigrationKey reverses wrapSealMigrationKey
+func unwrapSealMigrationKey(s Seal, shamirKey, wrapped []byte) ([]byte, error) {
+	if s.BarrierType() == seal.Shamir {
+		gcm, err := sealMigrationAEAD(shamirKey)
+		if err != nil {
+			return nil, err
+		}
+		if len(wrapped) < gcm.NonceSize() {
+			return nil, fmt.Errorf("invalid sealed key")
+		}
+		nonce := wrapped[:gcm.NonceSize()]
+		return gcm.Open(nil, nonce, wrapped[gcm.NonceSize():], []byte(sealMigrationPath))
+	}
+
+	access, ok := s.(seal.Access)
+	if !ok {
+		return nil, fmt.Errorf("seal of type %s cannot decrypt values", s.BarrierType())
+	}
+	var blob seal.EncryptedBlobInfo
+	if err := jsonutil.DecodeJSON(wrapped, &blob); err != nil {
+		return nil, err
+	}
+	return access.Decrypt(&blob)
+}
+
+func sealMigrationAEAD(key []byte) (cipher.AEAD, error) {
+	aesCipher, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(aesCipher)
+}
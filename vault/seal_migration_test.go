@@ -0,0 +1,341 @@
+package vault
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/helper/logformat"
+	"github.com/hashicorp/vault/physical"
+	"github.com/hashicorp/vault/physical/inmem"
+	"github.com/hashicorp/vault/shamir"
+	"github.com/hashicorp/vault/vault/seal"
+	log "github.com/mgutz/logxi/v1"
+)
+
+// failingSealConfigBackend fails the writes of the barrier seal
+// configuration while fail is set
+type failingSealConfigBackend struct {
+	physical.Backend
+	fail bool
+}
+
+func (b *failingSealConfigBackend) Put(entry *physical.Entry) error {
+	if b.fail && entry.Key == barrierSealConfigPath {
+		return errors.New("injected failure")
+	}
+	return b.Backend.Put(entry)
+}
+
+// crashingBackend fails every write once the keyring is written while crash
+// is set, as if Vault stopped right after the barrier was rekeyed
+type crashingBackend struct {
+	physical.Backend
+	crash   bool
+	crashed bool
+}
+
+func (b *crashingBackend) Put(entry *physical.Entry) error {
+	if b.crashed {
+		return errors.New("crashed")
+	}
+	if err := b.Backend.Put(entry); err != nil {
+		return err
+	}
+	b.crashed = b.crash && entry.Key == keyringPath
+	return nil
+}
+
+func (b *crashingBackend) Delete(key string) error {
+	if b.crashed {
+		return errors.New("crashed")
+	}
+	return b.Backend.Delete(key)
+}
+
+func testSealMigrationCore(t *testing.T, backend physical.Backend, s, unwrapSeal Seal) (*Core, error) {
+	conf := testCoreConfig(t, backend, logformat.NewVaultLogger(log.LevelTrace))
+	conf.Seal = s
+	conf.UnwrapSeal = unwrapSeal
+	return NewCore(conf)
+}
+
+// testSealMigrationShamirCore initializes a core with 3 unseal keys and a
+// threshold of 2, writing a value inside the barrier
+func testSealMigrationShamirCore(t *testing.T, backend physical.Backend) ([][]byte, string) {
+	core, err := testSealMigrationCore(t, backend, nil, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	result, err := core.Initialize(&InitParams{
+		BarrierConfig: &SealConfig{
+			SecretShares:    3,
+			SecretThreshold: 2,
+		},
+		RecoveryConfig: &SealConfig{},
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for _, key := range result.SecretShares[:2] {
+		if _, err := core.Unseal(TestKeyCopy(key)); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+	if err := core.barrier.Put(&Entry{Key: "migration/test", Value: []byte("foo")}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := core.Seal(result.RootToken); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	return result.SecretShares, result.RootToken
+}
+
+func testSealMigrationUnseal(t *testing.T, core *Core, keys [][]byte) {
+	for i, key := range keys {
+		unsealed, err := core.UnsealMigrate(TestKeyCopy(key))
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if unsealed != (i == len(keys)-1) {
+			t.Fatalf("bad: unsealed %v after %d keys", unsealed, i+1)
+		}
+	}
+
+	entry, err := core.barrier.Get("migration/test")
+	if err != nil || entry == nil || string(entry.Value) != "foo" {
+		t.Fatalf("bad: entry: %#v, err: %v", entry, err)
+	}
+}
+
+func TestCore_SealMigration(t *testing.T) {
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+	backend, err := inmem.NewInmem(nil, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, root := testSealMigrationShamirCore(t, backend)
+
+	// Migrate from Shamir to the auto-unseal seal
+	access := seal.NewTestSeal("key-1")
+	core, err := testSealMigrationCore(t, backend, NewAutoSeal(access), nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if sealed, _ := core.Sealed(); !sealed {
+		t.Fatal("should be sealed")
+	}
+	config := core.SealMigrationConfig()
+	if config == nil || config.Type != seal.Shamir || config.SecretShares != 3 || config.SecretThreshold != 2 {
+		t.Fatalf("bad: %#v", config)
+	}
+	if _, err := core.Unseal(TestKeyCopy(keys[0])); err != ErrSealMigrationPending {
+		t.Fatalf("expected a pending migration, got %v", err)
+	}
+	testSealMigrationUnseal(t, core, keys[:2])
+
+	if core.SealMigrationConfig() != nil {
+		t.Fatal("migration should be complete")
+	}
+	conf, err := core.SealAccess().BarrierConfig()
+	if err != nil || conf.Type != seal.Test || conf.StoredShares != 1 {
+		t.Fatalf("bad: conf: %#v, err: %v", conf, err)
+	}
+	conf, err = core.SealAccess().RecoveryConfig()
+	if err != nil || conf.SecretShares != 3 || conf.SecretThreshold != 2 {
+		t.Fatalf("bad: conf: %#v, err: %v", conf, err)
+	}
+	if _, err := core.UnsealMigrate(TestKeyCopy(keys[0])); err != ErrNoSealMigration {
+		t.Fatalf("expected no pending migration, got %v", err)
+	}
+
+	// The former unseal keys are the recovery keys
+	recoveryKey, err := shamir.Combine([][]byte{TestKeyCopy(keys[1]), TestKeyCopy(keys[2])})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := core.seal.VerifyRecoveryKey(recoveryKey); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The former unseal keys no longer unseal the barrier, which is rekeyed
+	storedKeys, err := core.seal.GetStoredKeys()
+	if err != nil || len(storedKeys) != 1 || bytes.Equal(storedKeys[0], recoveryKey) {
+		t.Fatalf("bad: stored keys: %v, err: %v", storedKeys, err)
+	}
+	if err := core.Seal(root); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := core.UnsealWithStoredKeys(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if sealed, _ := core.Sealed(); sealed {
+		t.Fatal("should not be sealed")
+	}
+	if err := core.Seal(root); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Migrating back requires the auto-unseal seal
+	core, err = testSealMigrationCore(t, backend, nil, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := core.Unseal(TestKeyCopy(keys[0])); err == nil || !strings.Contains(err.Error(), "does not match loaded type") {
+		t.Fatalf("expected a type mismatch without the unwrap seal, got %v", err)
+	}
+
+	// Migrate from the auto-unseal seal to Shamir, with the recovery keys
+	core, err = testSealMigrationCore(t, backend, nil, NewAutoSeal(access))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if sealed, _ := core.Sealed(); !sealed {
+		t.Fatal("should be sealed")
+	}
+	config = core.SealMigrationConfig()
+	if config == nil || config.SecretShares != 3 || config.SecretThreshold != 2 {
+		t.Fatalf("bad: %#v", config)
+	}
+	testSealMigrationUnseal(t, core, keys[1:])
+
+	conf, err = core.SealAccess().BarrierConfig()
+	if err != nil || conf.Type != seal.Shamir || conf.SecretShares != 3 || conf.SecretThreshold != 2 || conf.StoredShares != 0 {
+		t.Fatalf("bad: conf: %#v, err: %v", conf, err)
+	}
+	if entry, err := core.physical.Get(storedBarrierKeysPath); err != nil || entry != nil {
+		t.Fatalf("bad: entry: %#v, err: %v", entry, err)
+	}
+	if err := core.Seal(root); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The recovery keys are the unseal keys
+	core, err = testSealMigrationCore(t, backend, nil, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for _, key := range [][]byte{keys[0], keys[2]} {
+		if _, err := core.Unseal(TestKeyCopy(key)); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+	if sealed, _ := core.Sealed(); sealed {
+		t.Fatal("should not be sealed")
+	}
+}
+
+func TestCore_SealMigration_rollback(t *testing.T) {
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+	inm, err := inmem.NewInmem(nil, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend := &failingSealConfigBackend{Backend: inm}
+	keys, _ := testSealMigrationShamirCore(t, backend)
+
+	core, err := testSealMigrationCore(t, backend, NewAutoSeal(seal.NewTestSeal("key-1")), nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The migration fails when writing the barrier configuration
+	backend.fail = true
+	if _, err := core.UnsealMigrate(TestKeyCopy(keys[0])); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := core.UnsealMigrate(TestKeyCopy(keys[1])); err == nil || !strings.Contains(err.Error(), "injected failure") {
+		t.Fatalf("expected an injected failure, got %v", err)
+	}
+	if sealed, _ := core.Sealed(); !sealed {
+		t.Fatal("should be sealed")
+	}
+	if core.SealMigrationConfig() == nil {
+		t.Fatal("migration should still be pending")
+	}
+	if entry, err := core.physical.Get(storedBarrierKeysPath); err != nil || entry != nil {
+		t.Fatalf("stored keys should be removed: entry: %#v, err: %v", entry, err)
+	}
+
+	// The migration is retried with the same unseal keys
+	backend.fail = false
+	testSealMigrationUnseal(t, core, keys[1:])
+	conf, err := core.SealAccess().BarrierConfig()
+	if err != nil || conf.Type != seal.Test {
+		t.Fatalf("bad: conf: %#v, err: %v", conf, err)
+	}
+}
+
+func TestCore_SealMigration_interrupted(t *testing.T) {
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+	inm, err := inmem.NewInmem(nil, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend := &crashingBackend{Backend: inm}
+	keys, root := testSealMigrationShamirCore(t, backend)
+	access := seal.NewTestSeal("key-1")
+
+	// The migration from Shamir stops once the barrier is rekeyed
+	core, err := testSealMigrationCore(t, backend, NewAutoSeal(access), nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	backend.crash = true
+	if _, err := core.UnsealMigrate(TestKeyCopy(keys[0])); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := core.UnsealMigrate(TestKeyCopy(keys[1])); err == nil {
+		t.Fatal("expected an error")
+	}
+	if entry, err := inm.Get(sealMigrationPath); err != nil || entry == nil {
+		t.Fatalf("bad: entry: %#v, err: %v", entry, err)
+	}
+
+	// It is resumed with the unseal keys after a restart
+	backend.crash, backend.crashed = false, false
+	core, err = testSealMigrationCore(t, backend, NewAutoSeal(access), nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	testSealMigrationUnseal(t, core, keys[1:])
+	conf, err := core.SealAccess().BarrierConfig()
+	if err != nil || conf.Type != seal.Test {
+		t.Fatalf("bad: conf: %#v, err: %v", conf, err)
+	}
+	if entry, err := inm.Get(sealMigrationPath); err != nil || entry != nil {
+		t.Fatalf("bad: entry: %#v, err: %v", entry, err)
+	}
+	if err := core.Seal(root); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The migration back to Shamir stops once the barrier is rekeyed
+	core, err = testSealMigrationCore(t, backend, nil, NewAutoSeal(access))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	backend.crash = true
+	if _, err := core.UnsealMigrate(TestKeyCopy(keys[0])); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := core.UnsealMigrate(TestKeyCopy(keys[2])); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	// It is resumed with the same recovery key only
+	backend.crash, backend.crashed = false, false
+	core, err = testSealMigrationCore(t, backend, nil, NewAutoSeal(access))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	testSealMigrationUnseal(t, core, [][]byte{keys[0], keys[2]})
+	conf, err = core.SealAccess().BarrierConfig()
+	if err != nil || conf.Type != seal.Shamir {
+		t.Fatalf("bad: conf: %#v, err: %v", conf, err)
+	}
+	if entry, err := inm.Get(sealMigrationPath); err != nil || entry != nil {
+		t.Fatalf("bad: entry: %#v, err: %v", entry, err)
+	}
+}
@@ -0,0 +1,321 @@
+package vault
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/helper/locksutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/physical"
+	"github.com/hashicorp/vault/vault/seal"
+)
+
+// sealWrapMagic prefixes the seal-wrapped values in storage. Values
+// encrypted by the barrier start with their key term, which never reaches
+// it, and the other values are JSON documents.
+var sealWrapMagic = []byte{0xff, 's', 'w', 0x01}
+
+// sealWrappedValue is a storage value encrypted by the key management service
+// of an auto-unseal seal
+type sealWrappedValue struct {
+	SealType string                  `json:"seal_type"`
+	Blob     *seal.EncryptedBlobInfo `json:"blob"`
+}
+
+// sealWrapStorage wraps the physical backend to seal-wrap the entries
+// requesting it: they are encrypted by the seal in addition to the barrier,
+// so that critical values are protected by the key management service
+// itself. Seal wrapping requires an auto-unseal seal; with Shamir, entries
+// are only encrypted by the barrier.
+type sealWrapStorage struct {
+	backend physical.Backend
+	core    *Core
+
+	// locks serializes the rewraps of an entry with its updates
+	locks []*locksutil.LockEntry
+}
+
+// transactionalSealWrapStorage is the transactional version of
+// sealWrapStorage
+type transactionalSealWrapStorage struct {
+	*sealWrapStorage
+	physical.Transactional
+}
+
+// setupSealWrap wraps the physical backend to seal-wrap entries and returns
+// the backend to use
+func (c *Core) setupSealWrap(backend physical.Backend) physical.Backend {
+	c.sealWrapStorage = &sealWrapStorage{
+		backend: backend,
+		core:    c,
+		locks:   locksutil.CreateLocks(),
+	}
+	if txn, ok := backend.(physical.Transactional); ok {
+		return &transactionalSealWrapStorage{
+			sealWrapStorage: c.sealWrapStorage,
+			Transactional:   txn,
+		}
+	}
+	return c.sealWrapStorage
+}
+
+// setupMountSealWrap configures the storage view of a mount to seal-wrap the
+// entries of its backend: all of them if the mount is seal-wrapped,
+// otherwise those under the paths the backend declares critical
+func setupMountSealWrap(view *BarrierView, entry *MountEntry, backend logical.Backend) {
+	var prefixes []string
+	if special := backend.SpecialPaths(); special != nil {
+		prefixes = special.SealWrapStorage
+	}
+	view.setSealWrap(entry.SealWrap, prefixes)
+}
+
+// sealWrapAccess returns the key management service entries are seal-wrapped
+// with, or nil if the seal doesn't seal-wrap
+func (c *Core) sealWrapAccess() seal.Access {
+	if d, ok := c.seal.(*autoSeal); ok {
+		return d.Access
+	}
+	return nil
+}
+
+// sealUnwrapAccess returns the key management service to decrypt the entries
+// seal-wrapped by a seal of the given type: the configured seal, or the seal
+// being migrated away from
+func (c *Core) sealUnwrapAccess(sealType string) (seal.Access, error) {
+	for _, s := range []Seal{c.seal, c.unwrapSeal} {
+		if d, ok := s.(*autoSeal); ok && d.SealType() == sealType {
+			return d.Access, nil
+		}
+	}
+	return nil, fmt.Errorf("entry is seal-wrapped by a seal of type %s, which is not configured", sealType)
+}
+
+// Put seal-wraps the entry if requested
+func (s *sealWrapStorage) Put(entry *physical.Entry) error {
+	lock := locksutil.LockForKey(s.locks, entry.Key)
+	lock.RLock()
+	defer lock.RUnlock()
+
+	pe, err := s.wrap(entry)
+	if err != nil {
+		return err
+	}
+	return s.backend.Put(pe)
+}
+
+// Get unwraps the entry if it is seal-wrapped
+func (s *sealWrapStorage) Get(key string) (*physical.Entry, error) {
+	pe, err := s.backend.Get(key)
+	if err != nil || pe == nil {
+		return pe, err
+	}
+
+	wrapped, err := decodeSealWrapped(pe)
+	if err != nil || wrapped == nil {
+		return pe, err
+	}
+	return s.unwrap(pe.Key, wrapped)
+}
+
+func (s *sealWrapStorage) Delete(key string) error {
+	lock := locksutil.LockForKey(s.locks, key)
+	lock.RLock()
+	defer lock.RUnlock()
+
+	return s.backend.Delete(key)
+}
+
+func (s *sealWrapStorage) List(prefix string) ([]string, error) {
+	return s.backend.List(prefix)
+}
+
+// Transaction seal-wraps the entries of the transaction requesting it
+func (s *transactionalSealWrapStorage) Transaction(txns []physical.TxnEntry) error {
+	wrapped := make([]physical.TxnEntry, len(txns))
+	for i, txn := range txns {
+		wrapped[i] = txn
+		if txn.Operation != physical.PutOperation {
+			continue
+		}
+		pe, err := s.wrap(txn.Entry)
+		if err != nil {
+			return err
+		}
+		wrapped[i].Entry = pe
+	}
+	return s.Transactional.Transaction(wrapped)
+}
+
+// wrap returns the entry to store for an entry, encrypted by the seal if it
+// requests seal wrapping
+func (s *sealWrapStorage) wrap(entry *physical.Entry) (*physical.Entry, error) {
+	if !entry.SealWrap {
+		return entry, nil
+	}
+	access := s.core.sealWrapAccess()
+	if access == nil {
+		return entry, nil
+	}
+
+	blob, err := access.Encrypt(entry.Value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to seal-wrap %s: %v", entry.Key, err)
+	}
+	buf, err := json.Marshal(&sealWrappedValue{
+		SealType: access.SealType(),
+		Blob:     blob,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode seal-wrapped %s: %v", entry.Key, err)
+	}
+
+	return &physical.Entry{
+		Key:      entry.Key,
+		Value:    append(append([]byte{}, sealWrapMagic...), buf...),
+		SealWrap: true,
+	}, nil
+}
+
+// unwrap decrypts a seal-wrapped value
+func (s *sealWrapStorage) unwrap(key string, wrapped *sealWrappedValue) (*physical.Entry, error) {
+	access, err := s.core.sealUnwrapAccess(wrapped.SealType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap %s: %v", key, err)
+	}
+	plaintext, err := access.Decrypt(wrapped.Blob)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap %s: %v", key, err)
+	}
+
+	return &physical.Entry{
+		Key:      key,
+		Value:    plaintext,
+		SealWrap: true,
+	}, nil
+}
+
+// decodeSealWrapped decodes a seal-wrapped value, returning nil if the value
+// is not seal-wrapped
+func decodeSealWrapped(pe *physical.Entry) (*sealWrappedValue, error) {
+	if !bytes.HasPrefix(pe.Value, sealWrapMagic) {
+		return nil, nil
+	}
+
+	var wrapped sealWrappedValue
+	if err := jsonutil.DecodeJSON(pe.Value[len(sealWrapMagic):], &wrapped); err != nil {
+		return nil, fmt.Errorf("failed to decode seal-wrapped %s: %v", pe.Key, err)
+	}
+	if wrapped.Blob == nil {
+		return nil, fmt.Errorf("seal-wrapped %s has no value", pe.Key)
+	}
+	return &wrapped, nil
+}
+
+// stale returns whether a seal-wrapped value must be rewrapped: it was
+// encrypted by another seal or with another key than the current one
+func (s *sealWrapStorage) stale(wrapped *sealWrappedValue) bool {
+	access := s.core.sealWrapAccess()
+	if access == nil || access.SealType() != wrapped.SealType {
+		return true
+	}
+	return wrapped.Blob.KeyInfo == nil || wrapped.Blob.KeyInfo.KeyID != access.KeyID()
+}
+
+// sealWrapped reads the seal-wrapped value at the key without decrypting it,
+// returning nil if the entry doesn't exist or isn't seal-wrapped
+func (s *sealWrapStorage) sealWrapped(key string) (*sealWrappedValue, error) {
+	pe, err := s.backend.Get(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", key, err)
+	}
+	if pe == nil {
+		return nil, nil
+	}
+	return decodeSealWrapped(pe)
+}
+
+// rewrap re-encrypts the seal-wrapped entry at the key with the current key
+// of the seal if it is stale, or stores it only encrypted by the barrier if
+// the seal no longer seal-wraps. It returns whether the entry was rewritten.
+func (s *sealWrapStorage) rewrap(key string) (bool, error) {
+	lock := locksutil.LockForKey(s.locks, key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	wrapped, err := s.sealWrapped(key)
+	if err != nil || wrapped == nil || !s.stale(wrapped) {
+		return false, err
+	}
+
+	entry, err := s.unwrap(key, wrapped)
+	if err != nil {
+		return false, err
+	}
+	pe, err := s.wrap(entry)
+	if err != nil {
+		return false, err
+	}
+	if err := s.backend.Put(pe); err != nil {
+		return false, fmt.Errorf("failed to write %s: %v", key, err)
+	}
+	return true, nil
+}
+
+// sealWrapLocations returns the storage keys, and the prefixes ending with a
+// slash, that may hold seal-wrapped entries: the keyring and the storage of
+// the mounts seal-wrapping entries
+func (c *Core) sealWrapLocations() []string {
+	locations := []string{keyringPath, masterKeyPath}
+
+	c.router.l.RLock()
+	defer c.router.l.RUnlock()
+	c.router.root.Walk(func(path string, raw interface{}) bool {
+		view := raw.(*routeEntry).storageView
+		switch {
+		case view == nil:
+		case view.sealWrap:
+			locations = append(locations, view.prefix)
+		default:
+			locations = append(locations, view.sealWrapPrefixes...)
+		}
+		return false
+	})
+	return locations
+}
+
+// walkSealWrapped calls the function with the key of each seal-wrapped entry
+// in the locations that may hold them
+func (s *sealWrapStorage) walkSealWrapped(fn func(key string, wrapped *sealWrappedValue) error) error {
+	var walk func(location string) error
+	walk = func(location string) error {
+		if !strings.HasSuffix(location, "/") {
+			wrapped, err := s.sealWrapped(location)
+			if err != nil || wrapped == nil {
+				return err
+			}
+			return fn(location, wrapped)
+		}
+
+		keys, err := s.backend.List(location)
+		if err != nil {
+			return fmt.Errorf("failed to list %s: %v", location, err)
+		}
+		for _, key := range keys {
+			if err := walk(location + key); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, location := range s.core.sealWrapLocations() {
+		if err := walk(location); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,259 @@
+package vault
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/helper/logformat"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/physical/inmem"
+	"github.com/hashicorp/vault/vault/seal"
+	log "github.com/mgutz/logxi/v1"
+)
+
+// testSealWrapRequest handles a request, failing on errors
+func testSealWrapRequest(t *testing.T, c *Core, root string, op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+	req := logical.TestRequest(t, op, path)
+	req.ClientToken = root
+	req.Data = data
+	resp, err := c.HandleRequest(req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: %s: resp: %#v, err: %v", path, resp, err)
+	}
+	return resp
+}
+
+// testSealWrapMount mounts a kv backend seal-wrapping its entries, returning
+// the storage prefix of the mount
+func testSealWrapMount(t *testing.T, c *Core, root string) string {
+	testSealWrapRequest(t, c, root, logical.UpdateOperation, "sys/mounts/wrapped", map[string]interface{}{
+		"type":      "kv",
+		"seal_wrap": true,
+	})
+	return c.router.MatchingStorageView("wrapped/").prefix
+}
+
+// testSealWrapped returns whether the value stored at the key is
+// seal-wrapped
+func testSealWrapped(t *testing.T, c *Core, key string) bool {
+	pe, err := c.sealWrapStorage.backend.Get(key)
+	if err != nil || pe == nil {
+		t.Fatalf("bad: %s: entry: %#v, err: %v", key, pe, err)
+	}
+	return bytes.HasPrefix(pe.Value, sealWrapMagic)
+}
+
+func TestSealWrap(t *testing.T) {
+	c, _, root := testCoreAutoSeal(t, seal.NewTestSeal("key-1"))
+
+	// The keyring is seal-wrapped
+	for _, key := range []string{keyringPath, masterKeyPath} {
+		if !testSealWrapped(t, c, key) {
+			t.Fatalf("%s should be seal-wrapped", key)
+		}
+	}
+
+	// The entries of a seal-wrapped mount are seal-wrapped
+	prefix := testSealWrapMount(t, c, root)
+	testSealWrapRequest(t, c, root, logical.UpdateOperation, "wrapped/foo", map[string]interface{}{
+		"value": "bar",
+	})
+	if !testSealWrapped(t, c, prefix+"foo") {
+		t.Fatal("entry should be seal-wrapped")
+	}
+	resp := testSealWrapRequest(t, c, root, logical.ReadOperation, "wrapped/foo", nil)
+	if resp == nil || resp.Data["value"] != "bar" {
+		t.Fatalf("bad: %#v", resp)
+	}
+	entry, err := c.barrier.Get(prefix + "foo")
+	if err != nil || entry == nil || !entry.SealWrap {
+		t.Fatalf("bad: entry: %#v, err: %v", entry, err)
+	}
+
+	resp = testSealWrapRequest(t, c, root, logical.ReadOperation, "sys/mounts", nil)
+	if resp.Data["wrapped/"].(map[string]interface{})["seal_wrap"] != true || resp.Data["secret/"].(map[string]interface{})["seal_wrap"] != false {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// The entries of other mounts are not
+	testSealWrapRequest(t, c, root, logical.UpdateOperation, "secret/foo", map[string]interface{}{
+		"value": "bar",
+	})
+	if testSealWrapped(t, c, c.router.MatchingStorageView("secret/").prefix+"foo") {
+		t.Fatal("entry should not be seal-wrapped")
+	}
+
+	// The identity buckets are seal-wrapped
+	testSealWrapRequest(t, c, root, logical.UpdateOperation, "identity/entity", map[string]interface{}{
+		"name": "test",
+	})
+	view := c.router.MatchingStorageView("identity/")
+	keys, err := logical.CollectKeys(view.SubView("packer/buckets/"))
+	if err != nil || len(keys) == 0 {
+		t.Fatalf("bad: keys: %v, err: %v", keys, err)
+	}
+	for _, key := range keys {
+		if !testSealWrapped(t, c, view.prefix+"packer/buckets/"+key) {
+			t.Fatalf("bucket %s should be seal-wrapped", key)
+		}
+	}
+
+	// The seal-wrapped values are read when unsealing again
+	if err := c.Seal(root); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := c.UnsealWithStoredKeys(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	resp = testSealWrapRequest(t, c, root, logical.ReadOperation, "wrapped/foo", nil)
+	if resp == nil || resp.Data["value"] != "bar" {
+		t.Fatalf("bad: %#v", resp)
+	}
+}
+
+func TestSealWrap_shamir(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	// Without an auto-unseal seal, entries are only encrypted by the barrier
+	prefix := testSealWrapMount(t, c, root)
+	testSealWrapRequest(t, c, root, logical.UpdateOperation, "wrapped/foo", map[string]interface{}{
+		"value": "bar",
+	})
+	for _, key := range []string{keyringPath, prefix + "foo"} {
+		if testSealWrapped(t, c, key) {
+			t.Fatalf("%s should not be seal-wrapped", key)
+		}
+	}
+}
+
+func TestSealWrap_rewrap(t *testing.T) {
+	access := seal.NewTestSeal("key-1")
+	c, _, root := testCoreAutoSeal(t, access)
+	prefix := testSealWrapMount(t, c, root)
+	testSealWrapRequest(t, c, root, logical.UpdateOperation, "wrapped/foo", map[string]interface{}{
+		"value": "bar",
+	})
+
+	// The stored keys, the recovery key, the keyring and the entry
+	status, err := c.SealRewrapStatus()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if status.Entries != 5 || status.StaleEntries != 0 {
+		t.Fatalf("bad: %#v", status)
+	}
+
+	access.SetKeyID("key-2")
+	status, err = c.SealRewrap()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if status.Entries != 5 || status.Rewrapped != 5 {
+		t.Fatalf("bad: %#v", status)
+	}
+	pe, err := c.sealWrapStorage.backend.Get(prefix + "foo")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	wrapped, err := decodeSealWrapped(pe)
+	if err != nil || wrapped == nil || wrapped.Blob.KeyInfo.KeyID != "key-2" {
+		t.Fatalf("bad: wrapped: %#v, err: %v", wrapped, err)
+	}
+
+	// Up to date entries are not rewritten
+	status, err = c.SealRewrap()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if status.Entries != 5 || status.Rewrapped != 2 {
+		t.Fatalf("bad: %#v", status)
+	}
+
+	resp := testSealWrapRequest(t, c, root, logical.ReadOperation, "wrapped/foo", nil)
+	if resp == nil || resp.Data["value"] != "bar" {
+		t.Fatalf("bad: %#v", resp)
+	}
+}
+
+func TestSealWrap_migrateToShamir(t *testing.T) {
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+	backend, err := inmem.NewInmem(nil, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	access := seal.NewTestSeal("key-1")
+
+	c, err := testSealMigrationCore(t, backend, NewAutoSeal(access), nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	result, err := c.Initialize(&InitParams{
+		BarrierConfig: &SealConfig{
+			SecretShares:    1,
+			SecretThreshold: 1,
+			StoredShares:    1,
+		},
+		RecoveryConfig: &SealConfig{
+			SecretShares:    1,
+			SecretThreshold: 1,
+		},
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := c.UnsealWithStoredKeys(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	root := result.RootToken
+	prefix := testSealWrapMount(t, c, root)
+	testSealWrapRequest(t, c, root, logical.UpdateOperation, "wrapped/foo", map[string]interface{}{
+		"value": "bar",
+	})
+	if err := c.barrier.Put(&Entry{Key: "migration/test", Value: []byte("foo")}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := c.Seal(root); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The entries seal-wrapped by the former seal are still read
+	c, err = testSealMigrationCore(t, backend, nil, NewAutoSeal(access))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	testSealMigrationUnseal(t, c, [][]byte{result.RecoveryShares[0]})
+	resp := testSealWrapRequest(t, c, root, logical.ReadOperation, "wrapped/foo", nil)
+	if resp == nil || resp.Data["value"] != "bar" {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// Rewrapping stores them only encrypted by the barrier
+	status, err := c.SealRewrapStatus()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if status.SealType != seal.Shamir || status.Entries != 1 || status.StaleEntries != 1 {
+		t.Fatalf("bad: %#v", status)
+	}
+	status, err = c.SealRewrap()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if status.Entries != 0 || status.Rewrapped != 1 {
+		t.Fatalf("bad: %#v", status)
+	}
+	if testSealWrapped(t, c, prefix+"foo") {
+		t.Fatal("entry should not be seal-wrapped")
+	}
+
+	// The former seal is no longer needed
+	c.unwrapSeal = nil
+	resp = testSealWrapRequest(t, c, root, logical.ReadOperation, "wrapped/foo", nil)
+	if resp == nil || resp.Data["value"] != "bar" {
+		t.Fatalf("bad: %#v", resp)
+	}
+	if _, err := c.SealRewrap(); err == nil || !strings.Contains(err.Error(), "does not support rewrapping") {
+		t.Fatalf("expected an error, got %v", err)
+	}
+}
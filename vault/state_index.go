@@ -0,0 +1,186 @@
+package vault
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/logical"
+)
+
+const (
+	// coreStateIndexPath is the storage path of the highest state index
+	// reserved by an active node
+	coreStateIndexPath = "core/state-index"
+
+	// stateIndexReservation is how many indexes an active node reserves at
+	// once, so that the reservation is only persisted every that many writes
+	stateIndexReservation = 1000
+
+	// stateIndexPollInterval is how often WaitForStateIndex rechecks the local
+	// index while waiting for it to catch up
+	stateIndexPollInterval = 10 * time.Millisecond
+)
+
+// ErrMalformedStateIndex is returned when a client supplies a state index
+// token that this version of Vault cannot parse
+var ErrMalformedStateIndex = errors.New("malformed state index")
+
+// ErrStateIndexInactive is returned by WaitForStateIndex on a node that is not
+// active, which has applied no writes; the request is served by the active
+// node, which waits for the index instead
+var ErrStateIndexInactive = errors.New("state index is only tracked by the active node")
+
+// stateIndex tracks the writes the active nodes of the cluster have applied.
+// The index is monotonic across leadership changes: each active node
+// persists a reservation of the indexes it may issue, and starts from the
+// reservation of the previous active node, so its index is greater than any
+// index issued before it became active.
+type stateIndex struct {
+	// index is the number of the last applied write, and zero while the
+	// node isn't active
+	index uint64
+
+	// lock protects reserved, the highest index persisted in storage
+	lock     sync.Mutex
+	barrier  SecurityBarrier
+	reserved uint64
+}
+
+// stateIndexEntry is the persisted reservation of the state index
+type stateIndexEntry struct {
+	Reserved uint64 `json:"reserved"`
+}
+
+// reset starts tracking the index when this node becomes active, from the
+// reservation of the previous active node
+func (s *stateIndex) reset(barrier SecurityBarrier) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.barrier = barrier
+	raw, err := barrier.Get(coreStateIndexPath)
+	if err != nil {
+		return fmt.Errorf("failed to read the state index: %v", err)
+	}
+	var entry stateIndexEntry
+	if raw != nil {
+		if err := jsonutil.DecodeJSON(raw.Value, &entry); err != nil {
+			return fmt.Errorf("failed to decode the state index: %v", err)
+		}
+	}
+
+	s.reserved = entry.Reserved
+	if err := s.reserveLocked(entry.Reserved + 1); err != nil {
+		return err
+	}
+	atomic.StoreUint64(&s.index, entry.Reserved+1)
+	return nil
+}
+
+// clear stops tracking the index when this node stops being active
+func (s *stateIndex) clear() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	atomic.StoreUint64(&s.index, 0)
+	s.barrier = nil
+	s.reserved = 0
+}
+
+// advance records a completed write, persisting a new reservation when the
+// current one is used up
+func (s *stateIndex) advance() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.barrier == nil {
+		return nil
+	}
+	index := atomic.LoadUint64(&s.index) + 1
+	if index > s.reserved {
+		if err := s.reserveLocked(index); err != nil {
+			return err
+		}
+	}
+	atomic.StoreUint64(&s.index, index)
+	return nil
+}
+
+// reserveLocked persists a reservation of the indexes following index
+func (s *stateIndex) reserveLocked(index uint64) error {
+	value, err := jsonutil.EncodeJSON(&stateIndexEntry{
+		Reserved: index + stateIndexReservation,
+	})
+	if err != nil {
+		return err
+	}
+	if err := s.barrier.Put(&Entry{
+		Key:   coreStateIndexPath,
+		Value: value,
+	}); err != nil {
+		return fmt.Errorf("failed to persist the state index: %v", err)
+	}
+	s.reserved = index + stateIndexReservation
+	return nil
+}
+
+// isStateChangingOperation returns whether a successful request with the
+// given operation should advance the state index
+func isStateChangingOperation(op logical.Operation) bool {
+	switch op {
+	case logical.ReadOperation, logical.HeaderOperation, logical.ListOperation, logical.HelpOperation:
+		return false
+	}
+	return true
+}
+
+// advanceStateIndex records a completed write
+func (c *Core) advanceStateIndex() {
+	if err := c.stateIndex.advance(); err != nil {
+		c.logger.Error("core: failed to advance the state index", "error", err)
+	}
+}
+
+// StateIndex returns a token identifying the writes this node has applied.
+// Clients can send it back on later requests to ensure they observe their
+// own writes. An empty string is returned if the node is not active.
+func (c *Core) StateIndex() string {
+	index := atomic.LoadUint64(&c.stateIndex.index)
+	if index == 0 {
+		return ""
+	}
+	return strconv.FormatUint(index, 10)
+}
+
+// WaitForStateIndex blocks until this node has applied at least the writes
+// described by the given token, returning false if that does not happen
+// within the timeout. Since the index is monotonic across leadership
+// changes, tokens issued by previous active nodes are below the index of
+// the current one, whose state was loaded from storage when it became
+// active.
+func (c *Core) WaitForStateIndex(token string, timeout time.Duration) (bool, error) {
+	required, err := strconv.ParseUint(token, 10, 64)
+	if err != nil {
+		return false, ErrMalformedStateIndex
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		index := atomic.LoadUint64(&c.stateIndex.index)
+		if index == 0 {
+			return false, ErrStateIndexInactive
+		}
+		if index >= required {
+			return true, nil
+		}
+		if !time.Now().Before(deadline) {
+			return false, nil
+		}
+		time.Sleep(stateIndexPollInterval)
+	}
+}
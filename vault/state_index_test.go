@@ -0,0 +1,106 @@
+package vault
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestCore_StateIndex(t *testing.T) {
+	c, keys, root := TestCoreUnsealed(t)
+
+	start := c.StateIndex()
+	if start == "" {
+		t.Fatal("expected a state index on an active node")
+	}
+
+	req := &logical.Request{
+		Operation:   logical.ReadOperation,
+		Path:        "sys/mounts",
+		ClientToken: root,
+	}
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatal(err)
+	}
+	if c.StateIndex() != start {
+		t.Fatalf("read should not advance the index: %q vs %q", c.StateIndex(), start)
+	}
+
+	write := func() {
+		req := &logical.Request{
+			Operation:   logical.UpdateOperation,
+			Path:        "secret/foo",
+			Data:        map[string]interface{}{"foo": "bar"},
+			ClientToken: root,
+		}
+		if _, err := c.HandleRequest(req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write()
+	written := c.StateIndex()
+	if written == start {
+		t.Fatal("write should advance the index")
+	}
+
+	ok, err := c.WaitForStateIndex(written, 0)
+	if err != nil || !ok {
+		t.Fatalf("expected index to be satisfied: %v %v", ok, err)
+	}
+	index, _ := strconv.ParseUint(written, 10, 64)
+	next := strconv.FormatUint(index+1, 10)
+	ok, err = c.WaitForStateIndex(next, stateIndexPollInterval)
+	if err != nil || ok {
+		t.Fatalf("expected index not to be satisfied: %v %v", ok, err)
+	}
+	if _, err := c.WaitForStateIndex("bogus", 0); err != ErrMalformedStateIndex {
+		t.Fatalf("expected malformed error, got %v", err)
+	}
+
+	// Waiting blocks until the index is reached
+	doneCh := make(chan bool)
+	go func() {
+		ok, _ := c.WaitForStateIndex(next, 5*time.Second)
+		doneCh <- ok
+	}()
+	time.Sleep(5 * stateIndexPollInterval)
+	write()
+	if !<-doneCh {
+		t.Fatal("expected the wait to be satisfied by the write")
+	}
+
+	// The writes past the reservation persist a new one
+	for i := 0; i < stateIndexReservation; i++ {
+		if err := c.stateIndex.advance(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	last := c.StateIndex()
+
+	// The index of a new term is greater than any index issued before
+	if err := c.Seal(root); err != nil {
+		t.Fatal(err)
+	}
+	if c.StateIndex() != "" {
+		t.Fatal("expected no state index while sealed")
+	}
+	if _, err := c.WaitForStateIndex(last, 0); err != ErrStateIndexInactive {
+		t.Fatalf("expected inactive error, got %v", err)
+	}
+	for _, key := range keys {
+		if _, err := TestCoreUnseal(c, TestKeyCopy(key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	lastIndex, _ := strconv.ParseUint(last, 10, 64)
+	newIndex, _ := strconv.ParseUint(c.StateIndex(), 10, 64)
+	if newIndex <= lastIndex {
+		t.Fatalf("expected the index to increase across terms: %d then %d", lastIndex, newIndex)
+	}
+	ok, err = c.WaitForStateIndex(last, 0)
+	if err != nil || !ok {
+		t.Fatalf("expected index from a previous term to be satisfied: %v %v", ok, err)
+	}
+}
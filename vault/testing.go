@@ -350,6 +350,12 @@ func TestDynamicSystemView(c *Core) *dynamicSystemView {
 }
 
 func TestAddTestPlugin(t testing.T, c *Core, name, testFunc string) {
+	TestAddTestPluginVersion(t, c, name, "", testFunc)
+}
+
+// TestAddTestPluginVersion registers the test function as the given version
+// of the plugin
+func TestAddTestPluginVersion(t testing.T, c *Core, name, version, testFunc string) {
 	file, err := os.Open(os.Args[0])
 	if err != nil {
 		t.Fatal(err)
@@ -377,7 +383,7 @@ func TestAddTestPlugin(t testing.T, c *Core, name, testFunc string) {
 	c.pluginCatalog.directory = directoryPath
 
 	command := fmt.Sprintf("%s --test.run=%s", filepath.Base(os.Args[0]), testFunc)
-	err = c.pluginCatalog.Set(name, command, sum)
+	err = c.pluginCatalog.SetVersion(name, version, command, sum)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -563,6 +569,9 @@ func (n *rawHTTP) HandleRequest(req *logical.Request) (*logical.Response, error)
 			logical.HTTPStatusCode:  200,
 			logical.HTTPContentType: "plain/text",
 			logical.HTTPRawBody:     []byte("hello world"),
+			logical.HTTPRawHeaders: map[string][]string{
+				"Link": []string{"<hello>", "<world>"},
+			},
 		},
 	}, nil
 }
@@ -1008,6 +1017,7 @@ func NewTestCluster(t testing.T, base *CoreConfig, opts *TestClusterOptions) *Te
 		coreConfig.PluginDirectory = base.PluginDirectory
 		coreConfig.Seal = base.Seal
 		coreConfig.DevToken = base.DevToken
+		coreConfig.PluginRestart = base.PluginRestart
 
 		if !coreConfig.DisableMlock {
 			base.DisableMlock = false
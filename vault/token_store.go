@@ -4,7 +4,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"sync"
-	"sync/atomic"
 
 	"regexp"
 	"strings"
@@ -13,8 +12,8 @@ import (
 	log "github.com/mgutz/logxi/v1"
 
 	"github.com/armon/go-metrics"
-	"github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/helper/cidrutil"
 	"github.com/hashicorp/vault/helper/consts"
 	"github.com/hashicorp/vault/helper/jsonutil"
 	"github.com/hashicorp/vault/helper/locksutil"
@@ -60,6 +59,10 @@ const (
 	// again (or when the revocation function is run again), but all other uses
 	// will report the token invalid
 	tokenRevocationFailed = -3
+
+	// tokenEntityAliasTemplate is replaced in the path suffix of a role by
+	// the entity alias the token is bound to
+	tokenEntityAliasTemplate = "{{entity_alias}}"
 )
 
 var (
@@ -90,7 +93,22 @@ type TokenStore struct {
 
 	cubbyholeBackend *CubbyholeBackend
 
-	policyLookupFunc func(string) (*Policy, error)
+	// policyLookupFunc returns the policy of the given name in the namespace
+	// of the given ID
+	policyLookupFunc func(string, string) (*Policy, error)
+
+	// entityIDByAliasFunc returns the ID of the entity of an alias of the
+	// token store mount, creating the entity if needed
+	entityIDByAliasFunc func(string) (string, error)
+
+	// namespaceByPathFunc and namespaceByIDFunc return the namespace at the
+	// given path or of the given ID, or nil if it doesn't exist
+	namespaceByPathFunc func(string) *Namespace
+	namespaceByIDFunc   func(string) *Namespace
+
+	// batchTokenEncryptor encrypts the content of batch tokens with the
+	// barrier keyring
+	batchTokenEncryptor BarrierEncryptor
 
 	tokenLocks []*locksutil.LockEntry
 
@@ -121,8 +139,12 @@ func NewTokenStore(c *Core, config *logical.BackendConfig) (*TokenStore, error)
 	}
 
 	if c.policyStore != nil {
-		t.policyLookupFunc = c.policyStore.GetPolicy
+		t.policyLookupFunc = c.namespacePolicy
 	}
+	t.entityIDByAliasFunc = c.tokenEntityIDByAlias
+	t.namespaceByPathFunc = c.namespaceByPath
+	t.namespaceByIDFunc = c.namespaceByID
+	t.batchTokenEncryptor = c.barrier
 
 	// Setup the framework endpoints
 	t.Backend = &framework.Backend{
@@ -216,6 +238,28 @@ func NewTokenStore(c *Core, config *logical.BackendConfig) (*TokenStore, error)
 						Default:     true,
 						Description: tokenRenewableHelp,
 					},
+
+					"token_type": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Default:     "",
+						Description: tokenTypeHelp,
+					},
+
+					"bound_cidrs": &framework.FieldSchema{
+						Type:        framework.TypeCommaStringSlice,
+						Description: tokenBoundCIDRsHelp,
+					},
+
+					"num_uses": &framework.FieldSchema{
+						Type:        framework.TypeInt,
+						Default:     0,
+						Description: tokenNumUsesHelp,
+					},
+
+					"allowed_entity_aliases": &framework.FieldSchema{
+						Type:        framework.TypeCommaStringSlice,
+						Description: tokenAllowedEntityAliasesHelp,
+					},
 				},
 
 				Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -465,6 +509,14 @@ func NewTokenStore(c *Core, config *logical.BackendConfig) (*TokenStore, error)
 			&framework.Path{
 				Pattern: "tidy$",
 
+				Fields: map[string]*framework.FieldSchema{
+					"dry_run": &framework.FieldSchema{
+						Type:        framework.TypeBool,
+						Default:     false,
+						Description: "If set, the inconsistencies found are reported but not repaired.",
+					},
+				},
+
 				Callbacks: map[logical.Operation]framework.OperationFunc{
 					logical.UpdateOperation: t.handleTidy,
 				},
@@ -583,6 +635,20 @@ type TokenEntry struct {
 	ExplicitMaxTTLDeprecated time.Duration `json:"ExplicitMaxTTL" mapstructure:"ExplicitMaxTTL" structs:"ExplicitMaxTTL"`
 
 	EntityID string `json:"entity_id" mapstructure:"entity_id" structs:"entity_id"`
+
+	// The CIDR blocks from which the token can be used. An empty list means
+	// that the token is not bound to any source address.
+	BoundCIDRs []string `json:"bound_cidrs" mapstructure:"bound_cidrs" structs:"bound_cidrs"`
+
+	// The type of the token, either service or batch. Batch tokens are never
+	// persisted, so this is empty for all the stored entries.
+	Type string `json:"type,omitempty" mapstructure:"type" structs:"type"`
+	// The MFA methods validated by the login which issued the token
+	MFAMethods []string `json:"mfa_methods,omitempty" mapstructure:"mfa_methods" structs:"mfa_methods"`
+
+	// The ID of the namespace of the token, whose policies apply to it. It
+	// is empty for the root namespace.
+	NamespaceID string `json:"namespace_id,omitempty" mapstructure:"namespace_id" structs:"namespace_id"`
 }
 
 // tsRoleEntry contains token store role information
@@ -614,6 +680,22 @@ type tsRoleEntry struct {
 	// If set, the token entry will have an explicit maximum TTL set, rather
 	// than deferring to role/mount values
 	ExplicitMaxTTL time.Duration `json:"explicit_max_ttl" mapstructure:"explicit_max_ttl" structs:"explicit_max_ttl"`
+
+	// If set, the type of the tokens created using this role
+	TokenType string `json:"token_type" mapstructure:"token_type" structs:"token_type"`
+
+	// If set, tokens created using this role can only be used from these
+	// CIDR blocks
+	BoundCIDRs []string `json:"bound_cidrs" mapstructure:"bound_cidrs" structs:"bound_cidrs"`
+
+	// If non-zero, tokens created using this role are limited to this number
+	// of uses
+	NumUses int `json:"num_uses" mapstructure:"num_uses" structs:"num_uses"`
+
+	// If set, tokens created using this role can be bound to the entity of
+	// one of these aliases of the token store mount. Entries can start or end
+	// with a "*" glob.
+	AllowedEntityAliases []string `json:"allowed_entity_aliases" mapstructure:"allowed_entity_aliases" structs:"allowed_entity_aliases"`
 }
 
 type accessorEntry struct {
@@ -681,22 +763,23 @@ func (ts *TokenStore) tokenStoreAccessorList(
 	return resp, nil
 }
 
-// createAccessor is used to create an identifier for the token ID.
-// A storage index, mapping the accessor to the token ID is also created.
-func (ts *TokenStore) createAccessor(entry *TokenEntry) error {
+// createAccessor is used to create an identifier for the token ID. It
+// returns the storage index entry mapping the accessor to the token ID, which
+// is written along with the token.
+func (ts *TokenStore) createAccessor(entry *TokenEntry) (*logical.StorageEntry, error) {
 	defer metrics.MeasureSince([]string{"token", "createAccessor"}, time.Now())
 
 	// Create a random accessor
 	accessorUUID, err := uuid.GenerateUUID()
 	if err != nil {
-		return err
+		return nil, err
 	}
 	entry.Accessor = accessorUUID
 
 	// Create index entry, mapping the accessor to the token ID
 	saltID, err := ts.SaltID(entry.Accessor)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	path := accessorPrefix + saltID
 
@@ -706,14 +789,10 @@ func (ts *TokenStore) createAccessor(entry *TokenEntry) error {
 	}
 	aEntryBytes, err := jsonutil.EncodeJSON(aEntry)
 	if err != nil {
-		return fmt.Errorf("failed to marshal accessor index entry: %v", err)
+		return nil, fmt.Errorf("failed to marshal accessor index entry: %v", err)
 	}
 
-	le := &logical.StorageEntry{Key: path, Value: aEntryBytes}
-	if err := ts.view.Put(le); err != nil {
-		return fmt.Errorf("failed to persist accessor index entry: %v", err)
-	}
-	return nil
+	return &logical.StorageEntry{Key: path, Value: aEntryBytes}, nil
 }
 
 // Create is used to create a new token entry. The entry is assigned
@@ -740,11 +819,6 @@ func (ts *TokenStore) create(entry *TokenEntry) error {
 
 	entry.Policies = policyutil.SanitizePolicies(entry.Policies, policyutil.DoNotAddDefaultPolicy)
 
-	err = ts.createAccessor(entry)
-	if err != nil {
-		return err
-	}
-
 	return ts.storeCommon(entry, true)
 }
 
@@ -755,25 +829,30 @@ func (ts *TokenStore) store(entry *TokenEntry) error {
 	return ts.storeCommon(entry, false)
 }
 
-// storeCommon handles the actual storage of an entry, possibly generating
-// secondary indexes
+// storeCommon handles the actual storage of an entry, possibly creating its
+// accessor and generating secondary indexes. The entry and its indexes are
+// written in a single transaction if the storage supports it.
 func (ts *TokenStore) storeCommon(entry *TokenEntry, writeSecondary bool) error {
 	saltedId, err := ts.SaltID(entry.ID)
 	if err != nil {
 		return err
 	}
 
-	// Marshal the entry
-	enc, err := json.Marshal(entry)
-	if err != nil {
-		return fmt.Errorf("failed to encode entry: %v", err)
-	}
-
+	var txns []*logical.TxnEntry
 	if writeSecondary {
+		accessorIndex, err := ts.createAccessor(entry)
+		if err != nil {
+			return err
+		}
+		txns = append(txns, &logical.TxnEntry{
+			Operation: logical.UpdateOperation,
+			Entry:     accessorIndex,
+		})
+
 		// Write the secondary index if necessary. This is done before the
-		// primary index because we'd rather have a dangling pointer with
-		// a missing primary instead of missing the parent index and potentially
-		// escaping the revocation chain.
+		// primary index because, without transactions, we'd rather have a
+		// dangling pointer with a missing primary instead of missing the
+		// parent index and potentially escaping the revocation chain.
 		if entry.Parent != "" {
 			// Ensure the parent exists
 			parent, err := ts.Lookup(entry.Parent)
@@ -790,17 +869,26 @@ func (ts *TokenStore) storeCommon(entry *TokenEntry, writeSecondary bool) error
 				return err
 			}
 			path := parentPrefix + parentSaltedID + "/" + saltedId
-			le := &logical.StorageEntry{Key: path}
-			if err := ts.view.Put(le); err != nil {
-				return fmt.Errorf("failed to persist entry: %v", err)
-			}
+			txns = append(txns, &logical.TxnEntry{
+				Operation: logical.UpdateOperation,
+				Entry:     &logical.StorageEntry{Key: path},
+			})
 		}
 	}
 
+	// Marshal the entry
+	enc, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode entry: %v", err)
+	}
+
 	// Write the primary ID
 	path := lookupPrefix + saltedId
-	le := &logical.StorageEntry{Key: path, Value: enc}
-	if err := ts.view.Put(le); err != nil {
+	txns = append(txns, &logical.TxnEntry{
+		Operation: logical.UpdateOperation,
+		Entry:     &logical.StorageEntry{Key: path, Value: enc},
+	})
+	if err := logical.StorageTransaction(ts.view, txns); err != nil {
 		return fmt.Errorf("failed to persist entry: %v", err)
 	}
 	return nil
@@ -882,6 +970,11 @@ func (ts *TokenStore) Lookup(id string) (*TokenEntry, error) {
 		return nil, fmt.Errorf("cannot lookup blank token")
 	}
 
+	// Batch tokens carry their entry, so there is nothing to lock
+	if strings.HasPrefix(id, batchTokenPrefix) {
+		return ts.lookupBatch(id)
+	}
+
 	lock := locksutil.LockForKey(ts.tokenLocks, id)
 	lock.RLock()
 	defer lock.RUnlock()
@@ -985,6 +1078,9 @@ func (ts *TokenStore) Revoke(id string) error {
 	if id == "" {
 		return fmt.Errorf("cannot revoke blank token")
 	}
+	if strings.HasPrefix(id, batchTokenPrefix) {
+		return fmt.Errorf("batch tokens cannot be revoked")
+	}
 
 	saltedID, err := ts.SaltID(id)
 	if err != nil {
@@ -1104,6 +1200,23 @@ func (ts *TokenStore) revokeSalted(saltedId string) (ret error) {
 		}
 	}
 
+	// Orphan the children of the token, so that they don't refer to a parent
+	// which no longer exists. When revoking a tree, the children have already
+	// been revoked.
+	childrenPath := parentPrefix + saltedId + "/"
+	children, err := ts.view.List(childrenPath)
+	if err != nil {
+		return fmt.Errorf("failed to scan for children: %v", err)
+	}
+	for _, child := range children {
+		if err := ts.orphanSalted(child); err != nil {
+			return err
+		}
+		if err = ts.view.Delete(childrenPath + child); err != nil {
+			return fmt.Errorf("failed to delete entry: %v", err)
+		}
+	}
+
 	// Now that the entry is not usable for any revocation tasks, nuke it
 	path := lookupPrefix + saltedId
 	if err = ts.view.Delete(path); err != nil {
@@ -1113,6 +1226,35 @@ func (ts *TokenStore) revokeSalted(saltedId string) (ret error) {
 	return nil
 }
 
+// orphanSalted clears the parent of the given salted token
+func (ts *TokenStore) orphanSalted(saltedId string) error {
+	entry, err := ts.lookupSalted(saltedId, true)
+	if err != nil {
+		return err
+	}
+	if entry == nil {
+		return nil
+	}
+
+	lock := locksutil.LockForKey(ts.tokenLocks, entry.ID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	entry, err = ts.lookupSalted(saltedId, true)
+	if err != nil {
+		return err
+	}
+	if entry == nil {
+		return nil
+	}
+
+	entry.Parent = ""
+	if err := ts.storeCommon(entry, false); err != nil {
+		return fmt.Errorf("failed to orphan entry: %v", err)
+	}
+	return nil
+}
+
 // RevokeTree is used to invalide a given token and all
 // child tokens.
 func (ts *TokenStore) RevokeTree(id string) error {
@@ -1121,6 +1263,9 @@ func (ts *TokenStore) RevokeTree(id string) error {
 	if id == "" {
 		return fmt.Errorf("cannot tree-revoke blank token")
 	}
+	if strings.HasPrefix(id, batchTokenPrefix) {
+		return fmt.Errorf("batch tokens cannot be revoked")
+	}
 
 	// Get the salted ID
 	saltedId, err := ts.SaltID(id)
@@ -1221,168 +1366,6 @@ func (ts *TokenStore) lookupBySaltedAccessor(saltedAccessor string, tainted bool
 	return aEntry, nil
 }
 
-// handleTidy handles the cleaning up of leaked accessor storage entries and
-// cleaning up of leases that are associated to tokens that are expired.
-func (ts *TokenStore) handleTidy(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	var tidyErrors *multierror.Error
-
-	if !atomic.CompareAndSwapInt64(&ts.tidyLock, 0, 1) {
-		ts.logger.Warn("token: tidy operation on tokens is already in progress")
-		return nil, fmt.Errorf("tidy operation on tokens is already in progress")
-	}
-
-	defer atomic.CompareAndSwapInt64(&ts.tidyLock, 1, 0)
-
-	ts.logger.Info("token: beginning tidy operation on tokens")
-	defer ts.logger.Info("token: finished tidy operation on tokens")
-
-	// List out all the accessors
-	saltedAccessorList, err := ts.view.List(accessorPrefix)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch accessor index entries: %v", err)
-	}
-
-	// First, clean up secondary index entries that are no longer valid
-	parentList, err := ts.view.List(parentPrefix)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch secondary index entries: %v", err)
-	}
-
-	var countParentList, deletedCountParentList int64
-
-	// Scan through the secondary index entries; if there is an entry
-	// with the token's salt ID at the end, remove it
-	for _, parent := range parentList {
-		children, err := ts.view.List(parentPrefix + parent)
-		if err != nil {
-			tidyErrors = multierror.Append(tidyErrors, fmt.Errorf("failed to read secondary index: %v", err))
-			continue
-		}
-
-		for _, child := range children {
-			countParentList++
-			if countParentList%500 == 0 {
-				ts.logger.Info("token: checking validity of tokens in secondary index list", "progress", countParentList)
-			}
-
-			// Look up tainted entries so we can be sure that if this isn't
-			// found, it doesn't exist. Doing the following without locking
-			// since appropriate locks cannot be held with salted token IDs.
-			te, _ := ts.lookupSalted(child, true)
-			if te == nil {
-				index := parentPrefix + parent + child
-				ts.logger.Trace("token: deleting invalid secondary index", "index", index)
-				err = ts.view.Delete(index)
-				if err != nil {
-					tidyErrors = multierror.Append(tidyErrors, fmt.Errorf("failed to delete secondary index: %v", err))
-				}
-				deletedCountParentList++
-			}
-		}
-	}
-
-	var countAccessorList,
-		deletedCountAccessorEmptyToken,
-		deletedCountAccessorInvalidToken,
-		deletedCountInvalidTokenInAccessor int64
-
-	// For each of the accessor, see if the token ID associated with it is
-	// a valid one. If not, delete the leases associated with that token
-	// and delete the accessor as well.
-	for _, saltedAccessor := range saltedAccessorList {
-		countAccessorList++
-		if countAccessorList%500 == 0 {
-			ts.logger.Info("token: checking if accessors contain valid tokens", "progress", countAccessorList)
-		}
-
-		accessorEntry, err := ts.lookupBySaltedAccessor(saltedAccessor, true)
-		if err != nil {
-			tidyErrors = multierror.Append(tidyErrors, fmt.Errorf("failed to read the accessor index: %v", err))
-			continue
-		}
-
-		// A valid accessor storage entry should always have a token ID
-		// in it. If not, it is an invalid accessor entry and needs to
-		// be deleted.
-		if accessorEntry.TokenID == "" {
-			index := accessorPrefix + saltedAccessor
-			// If deletion of accessor fails, move on to the next
-			// item since this is just a best-effort operation
-			err = ts.view.Delete(index)
-			if err != nil {
-				tidyErrors = multierror.Append(tidyErrors, fmt.Errorf("failed to delete the accessor index: %v", err))
-				continue
-			}
-			deletedCountAccessorEmptyToken++
-		}
-
-		lock := locksutil.LockForKey(ts.tokenLocks, accessorEntry.TokenID)
-		lock.RLock()
-
-		// Look up tainted variants so we only find entries that truly don't
-		// exist
-		saltedId, err := ts.SaltID(accessorEntry.TokenID)
-		if err != nil {
-			tidyErrors = multierror.Append(tidyErrors, fmt.Errorf("failed to read salt id: %v", err))
-			lock.RUnlock()
-			continue
-		}
-		te, err := ts.lookupSalted(saltedId, true)
-		if err != nil {
-			tidyErrors = multierror.Append(tidyErrors, fmt.Errorf("failed to lookup tainted ID: %v", err))
-			lock.RUnlock()
-			continue
-		}
-
-		lock.RUnlock()
-
-		// If token entry is not found assume that the token is not valid any
-		// more and conclude that accessor, leases, and secondary index entries
-		// for this token should not exist as well.
-		if te == nil {
-			ts.logger.Info("token: deleting token with nil entry", "salted_token", saltedId)
-
-			// RevokeByToken expects a '*TokenEntry'. For the
-			// purposes of tidying, it is sufficient if the token
-			// entry only has ID set.
-			tokenEntry := &TokenEntry{
-				ID: accessorEntry.TokenID,
-			}
-
-			// Attempt to revoke the token. This will also revoke
-			// the leases associated with the token.
-			err := ts.expiration.RevokeByToken(tokenEntry)
-			if err != nil {
-				tidyErrors = multierror.Append(tidyErrors, fmt.Errorf("failed to revoke leases of expired token: %v", err))
-				continue
-			}
-			deletedCountInvalidTokenInAccessor++
-
-			index := accessorPrefix + saltedAccessor
-
-			// If deletion of accessor fails, move on to the next item since
-			// this is just a best-effort operation. We do this last so that on
-			// next run if something above failed we still have the accessor
-			// entry to try again.
-			err = ts.view.Delete(index)
-			if err != nil {
-				tidyErrors = multierror.Append(tidyErrors, fmt.Errorf("failed to delete accessor entry: %v", err))
-				continue
-			}
-			deletedCountAccessorInvalidToken++
-		}
-	}
-
-	ts.logger.Debug("token: number of tokens scanned in parent index list", "count", countParentList)
-	ts.logger.Debug("token: number of tokens revoked in parent index list", "count", deletedCountParentList)
-	ts.logger.Debug("token: number of accessors scanned", "count", countAccessorList)
-	ts.logger.Debug("token: number of deleted accessors which had empty tokens", "count", deletedCountAccessorEmptyToken)
-	ts.logger.Debug("token: number of revoked tokens which were invalid but present in accessors", "count", deletedCountInvalidTokenInAccessor)
-	ts.logger.Debug("token: number of deleted accessors which had invalid tokens", "count", deletedCountAccessorInvalidToken)
-
-	return nil, tidyErrors.ErrorOrNil()
-}
-
 // handleUpdateLookupAccessor handles the auth/token/lookup-accessor path for returning
 // the properties of the token associated with the accessor
 func (ts *TokenStore) handleUpdateLookupAccessor(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
@@ -1454,6 +1437,9 @@ func (ts *TokenStore) handleUpdateRevokeAccessor(req *logical.Request, data *fra
 	if err != nil {
 		return nil, err
 	}
+	if resp, err := ts.checkRequestNamespace(req, aEntry.TokenID); resp != nil || err != nil {
+		return resp, err
+	}
 
 	// Revoke the token and its children
 	if err := ts.RevokeTree(aEntry.TokenID); err != nil {
@@ -1499,8 +1485,14 @@ func (ts *TokenStore) handleCreateCommon(
 			logical.ErrInvalidRequest
 	}
 
+	// Batch tokens are not stored, so they can't keep track of children
+	if parent.tokenType() == tokenTypeBatch {
+		return logical.ErrorResponse("batch tokens cannot create child tokens"),
+			logical.ErrInvalidRequest
+	}
+
 	// Check if the client token has sudo/root privileges for the requested path
-	isSudo := ts.System().SudoPrivilege(req.MountPoint+req.Path, req.ClientToken)
+	isSudo := ts.System().SudoPrivilege(namespaceFullPath(req.Namespace, req.MountPoint+req.Path), req.ClientToken)
 
 	// Read and parse the fields
 	var data struct {
@@ -1516,6 +1508,9 @@ func (ts *TokenStore) handleCreateCommon(
 		DisplayName     string `mapstructure:"display_name"`
 		NumUses         int    `mapstructure:"num_uses"`
 		Period          string
+		Type            string
+		BoundCIDRs      []string `mapstructure:"bound_cidrs"`
+		EntityAlias     string   `mapstructure:"entity_alias"`
 	}
 	if err := mapstructure.WeakDecode(req.Data, &data); err != nil {
 		return logical.ErrorResponse(fmt.Sprintf(
@@ -1528,9 +1523,33 @@ func (ts *TokenStore) handleCreateCommon(
 			logical.ErrInvalidRequest
 	}
 
+	// The type set on the role, if any, takes precedence
+	tokenType := data.Type
+	if role != nil && role.TokenType != "" {
+		if tokenType != "" && tokenType != role.TokenType {
+			return logical.ErrorResponse(fmt.Sprintf("role only allows creating %s tokens", role.TokenType)),
+				logical.ErrInvalidRequest
+		}
+		tokenType = role.TokenType
+	}
+	if tokenType == "" {
+		tokenType = tokenTypeService
+	}
+	if !validTokenType(tokenType) {
+		return logical.ErrorResponse(fmt.Sprintf("invalid token type %q", tokenType)),
+			logical.ErrInvalidRequest
+	}
+
+	// The token belongs to the namespace of the request
+	ns := ts.namespaceByPathFunc(req.Namespace)
+	if ns == nil {
+		return logical.ErrorResponse(errNamespaceNotFound(req.Namespace).Error()), logical.ErrInvalidRequest
+	}
+
 	// Setup the token entry
 	te := TokenEntry{
-		Parent: req.ClientToken,
+		Parent:      req.ClientToken,
+		NamespaceID: ns.ID,
 
 		// The mount point is always the same since we have only one token
 		// store; using req.MountPoint causes trouble in tests since they don't
@@ -1553,6 +1572,8 @@ func (ts *TokenStore) handleCreateCommon(
 	// by a role (using revoke-prefix). Users can further specify a PathSuffix
 	// in the role; that way they can use something like "v1", "v2" to indicate
 	// role revisions, and revoke only tokens issued with a previous revision.
+	// The path suffix can also contain the entity alias the token is bound
+	// to, so that the tokens of an alias can be revoked together.
 	if role != nil {
 		te.Role = role.Name
 
@@ -1562,9 +1583,29 @@ func (ts *TokenStore) handleCreateCommon(
 			renewable = false
 		}
 
-		if role.PathSuffix != "" {
-			te.Path = fmt.Sprintf("%s/%s", te.Path, role.PathSuffix)
+		if data.EntityAlias != "" && !role.allowsEntityAlias(data.EntityAlias) {
+			return logical.ErrorResponse(fmt.Sprintf("entity alias %q is not allowed by the role", data.EntityAlias)),
+				logical.ErrInvalidRequest
+		}
+
+		pathSuffix := role.PathSuffix
+		if strings.Contains(pathSuffix, tokenEntityAliasTemplate) {
+			if data.EntityAlias == "" {
+				return logical.ErrorResponse("the role requires an entity_alias"), logical.ErrInvalidRequest
+			}
+			pathSuffix = strings.Replace(pathSuffix, tokenEntityAliasTemplate, data.EntityAlias, -1)
+			if strings.Contains(pathSuffix, "..") {
+				return logical.ErrorResponse(fmt.Sprintf("invalid entity_alias: %s", consts.ErrPathContainsParentReferences)),
+					logical.ErrInvalidRequest
+			}
+		}
+
+		if pathSuffix != "" {
+			te.Path = fmt.Sprintf("%s/%s", te.Path, pathSuffix)
 		}
+	} else if data.EntityAlias != "" {
+		return logical.ErrorResponse("entity_alias is only allowed when creating tokens with a role"),
+			logical.ErrInvalidRequest
 	}
 
 	// Attach the given display name if any
@@ -1581,6 +1622,10 @@ func (ts *TokenStore) handleCreateCommon(
 			return logical.ErrorResponse("root or sudo privileges required to specify token id"),
 				logical.ErrInvalidRequest
 		}
+		if strings.HasPrefix(data.ID, batchTokenPrefix) {
+			return logical.ErrorResponse(fmt.Sprintf("token id cannot begin with %q", batchTokenPrefix)),
+				logical.ErrInvalidRequest
+		}
 		te.ID = data.ID
 	}
 
@@ -1739,6 +1784,19 @@ func (ts *TokenStore) handleCreateCommon(
 		te.EntityID = parent.EntityID
 	}
 
+	// Tokens bound to an entity alias resolve to the alias' entity instead,
+	// which is created if it doesn't exist yet
+	if data.EntityAlias != "" {
+		if ts.entityIDByAliasFunc == nil {
+			return nil, fmt.Errorf("entity aliases are not supported")
+		}
+		entityID, err := ts.entityIDByAliasFunc(data.EntityAlias)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve the entity of alias %q: %v", data.EntityAlias, err)
+		}
+		te.EntityID = entityID
+	}
+
 	if data.ExplicitMaxTTL != "" {
 		dur, err := parseutil.ParseDurationSecond(data.ExplicitMaxTTL)
 		if err != nil {
@@ -1813,6 +1871,55 @@ func (ts *TokenStore) handleCreateCommon(
 				resp.AddWarning(fmt.Sprintf("Period specified both during creation call and in role; using the lesser value of %d seconds", int64(periodToUse.Seconds())))
 			}
 		}
+		if role.NumUses != 0 {
+			switch {
+			case te.NumUses == 0:
+				te.NumUses = role.NumUses
+			default:
+				if role.NumUses < te.NumUses {
+					te.NumUses = role.NumUses
+				}
+				resp.AddWarning(fmt.Sprintf("Number of uses specified both during creation call and in role; using the lesser value of %d", te.NumUses))
+			}
+		}
+	}
+
+	// The CIDR blocks can be given as a list or as a comma-separated string
+	boundCIDRs := strutil.RemoveDuplicates(strings.Split(strings.Join(data.BoundCIDRs, ","), ","), false)
+	if len(boundCIDRs) > 0 {
+		if valid, err := cidrutil.ValidateCIDRListSlice(boundCIDRs); !valid {
+			return logical.ErrorResponse(fmt.Sprintf("invalid bound_cidrs: %v", err)), logical.ErrInvalidRequest
+		}
+	}
+
+	// Tokens can't be usable from outside of the CIDR blocks of the role, or
+	// of the parent token, as the latter could otherwise escape its own
+	// binding by creating tokens
+	var roleBoundCIDRs []string
+	if role != nil {
+		roleBoundCIDRs = role.BoundCIDRs
+	}
+	for _, bound := range []struct {
+		source string
+		cidrs  []string
+	}{
+		{"role", roleBoundCIDRs},
+		{"parent token", parent.BoundCIDRs},
+	} {
+		if len(bound.cidrs) == 0 {
+			continue
+		}
+		if len(boundCIDRs) == 0 {
+			boundCIDRs = bound.cidrs
+			continue
+		}
+		if subset, err := cidrutil.SubsetBlocks(bound.cidrs, boundCIDRs); err != nil || !subset {
+			return logical.ErrorResponse(fmt.Sprintf("token CIDR blocks must be a subset of those of the %s (%v)", bound.source, bound.cidrs)),
+				logical.ErrInvalidRequest
+		}
+	}
+	if len(boundCIDRs) > 0 {
+		te.BoundCIDRs = boundCIDRs
 	}
 
 	sysView := ts.System()
@@ -1865,8 +1972,25 @@ func (ts *TokenStore) handleCreateCommon(
 	}
 
 	// Create the token
-	if err := ts.create(&te); err != nil {
-		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	switch tokenType {
+	case tokenTypeBatch:
+		if strutil.StrListContains(te.Policies, "root") {
+			return logical.ErrorResponse("batch tokens cannot be root tokens"), logical.ErrInvalidRequest
+		}
+		if periodToUse > 0 {
+			return logical.ErrorResponse("batch tokens cannot be periodic"), logical.ErrInvalidRequest
+		}
+
+		// Batch tokens have no lease, so they can't be renewed
+		renewable = false
+		if err := ts.createBatch(&te); err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+
+	default:
+		if err := ts.create(&te); err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
 	}
 
 	// Generate the response
@@ -1886,7 +2010,7 @@ func (ts *TokenStore) handleCreateCommon(
 
 	if ts.policyLookupFunc != nil {
 		for _, p := range te.Policies {
-			policy, err := ts.policyLookupFunc(p)
+			policy, err := ts.policyLookupFunc(te.NamespaceID, p)
 			if err != nil {
 				return logical.ErrorResponse(fmt.Sprintf("could not look up policy %s", p)), nil
 			}
@@ -1925,6 +2049,9 @@ func (ts *TokenStore) handleRevokeTree(
 		}
 		urltoken = true
 	}
+	if resp, err := ts.checkRequestNamespace(req, id); resp != nil || err != nil {
+		return resp, err
+	}
 
 	// Revoke the token and its children
 	if err := ts.RevokeTree(id); err != nil {
@@ -1965,13 +2092,17 @@ func (ts *TokenStore) handleRevokeOrphan(
 	}
 
 	// Check if the client token has sudo/root privileges for the requested path
-	isSudo := ts.System().SudoPrivilege(req.MountPoint+req.Path, req.ClientToken)
+	isSudo := ts.System().SudoPrivilege(namespaceFullPath(req.Namespace, req.MountPoint+req.Path), req.ClientToken)
 
 	if !isSudo {
 		return logical.ErrorResponse("root or sudo privileges required to revoke and orphan"),
 			logical.ErrInvalidRequest
 	}
 
+	if resp, err := ts.checkRequestNamespace(req, id); resp != nil || err != nil {
+		return resp, err
+	}
+
 	// Revoke and orphan
 	if err := ts.Revoke(id); err != nil {
 		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
@@ -2011,22 +2142,28 @@ func (ts *TokenStore) handleLookup(
 		return logical.ErrorResponse("missing token ID"), logical.ErrInvalidRequest
 	}
 
-	lock := locksutil.LockForKey(ts.tokenLocks, id)
-	lock.RLock()
-	defer lock.RUnlock()
-
 	// Lookup the token
-	saltedId, err := ts.SaltID(id)
-	if err != nil {
-		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	var out *TokenEntry
+	var err error
+	if strings.HasPrefix(id, batchTokenPrefix) {
+		out, err = ts.lookupBatch(id)
+	} else {
+		lock := locksutil.LockForKey(ts.tokenLocks, id)
+		lock.RLock()
+		defer lock.RUnlock()
+
+		saltedId, err := ts.SaltID(id)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		out, err = ts.lookupSalted(saltedId, true)
 	}
-	out, err := ts.lookupSalted(saltedId, true)
 
 	if err != nil {
 		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
 	}
 
-	if out == nil {
+	if out == nil || (out.ID != req.ClientToken && !ts.tokenInRequestNamespace(req, out)) {
 		return logical.ErrorResponse("bad token"), logical.ErrPermissionDenied
 	}
 
@@ -2048,6 +2185,7 @@ func (ts *TokenStore) handleLookup(
 			"ttl":              int64(0),
 			"explicit_max_ttl": int64(out.ExplicitMaxTTL.Seconds()),
 			"entity_id":        out.EntityID,
+			"type":             out.tokenType(),
 		},
 	}
 
@@ -2061,6 +2199,23 @@ func (ts *TokenStore) handleLookup(
 	if out.Period != 0 {
 		resp.Data["period"] = int64(out.Period.Seconds())
 	}
+	if len(out.BoundCIDRs) > 0 {
+		resp.Data["bound_cidrs"] = out.BoundCIDRs
+	}
+
+	// Batch tokens have no lease, their expiration is fixed at creation
+	if out.tokenType() == tokenTypeBatch {
+		expireTime := out.expireTime()
+		resp.Data["expire_time"] = expireTime
+		resp.Data["ttl"] = int64(expireTime.Sub(time.Now().Round(time.Second)).Seconds())
+		resp.Data["renewable"] = false
+		resp.Data["issue_time"] = time.Unix(out.CreationTime, 0)
+
+		if urltoken {
+			resp.AddWarning(`Using a token in the path is unsafe as the token can be logged in many places. Please use POST or PUT with the token passed in via the "token" parameter.`)
+		}
+		return resp, nil
+	}
 
 	// Fetch the last renewal time
 	leaseTimes, err := ts.expiration.FetchLeaseTimesByToken(out.Path, out.ID)
@@ -2119,10 +2274,14 @@ func (ts *TokenStore) handleRenew(
 	}
 
 	// Verify the token exists
-	if te == nil {
+	if te == nil || (te.ID != req.ClientToken && !ts.tokenInRequestNamespace(req, te)) {
 		return logical.ErrorResponse("token not found"), logical.ErrInvalidRequest
 	}
 
+	if te.tokenType() == tokenTypeBatch {
+		return logical.ErrorResponse("batch tokens cannot be renewed"), logical.ErrInvalidRequest
+	}
+
 	// Renew the token and its children
 	resp, err := ts.expiration.RenewToken(req, te.Path, te.ID, increment)
 
@@ -2133,6 +2292,31 @@ func (ts *TokenStore) handleRenew(
 	return resp, err
 }
 
+// tokenInRequestNamespace returns whether a token can be managed through a
+// request, which is the case if it belongs to the namespace of the request
+// or to one of its children
+func (ts *TokenStore) tokenInRequestNamespace(req *logical.Request, te *TokenEntry) bool {
+	reqNS := ts.namespaceByPathFunc(req.Namespace)
+	tokenNS := ts.namespaceByIDFunc(te.NamespaceID)
+	if reqNS == nil || tokenNS == nil {
+		return false
+	}
+	return strings.HasPrefix(tokenNS.Path, reqNS.Path)
+}
+
+// checkRequestNamespace returns an error response if the token of the given
+// ID exists and can't be managed through the request
+func (ts *TokenStore) checkRequestNamespace(req *logical.Request, id string) (*logical.Response, error) {
+	te, err := ts.Lookup(id)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	if te != nil && !ts.tokenInRequestNamespace(req, te) {
+		return logical.ErrorResponse("token not found"), logical.ErrInvalidRequest
+	}
+	return nil, nil
+}
+
 func (ts *TokenStore) destroyCubbyhole(saltedID string) error {
 	if ts.cubbyholeBackend == nil {
 		// Should only ever happen in testing
@@ -2281,12 +2465,56 @@ func (ts *TokenStore) tokenStoreRoleRead(
 			"orphan":              role.Orphan,
 			"path_suffix":         role.PathSuffix,
 			"renewable":           role.Renewable,
+			"token_type":          role.TokenType,
+			"bound_cidrs":         role.BoundCIDRs,
+			"num_uses":            role.NumUses,
+
+			"allowed_entity_aliases": role.AllowedEntityAliases,
 		},
 	}
 
 	return resp, nil
 }
 
+// allowsEntityAlias returns whether tokens created with the role can be
+// bound to the given entity alias
+func (role *tsRoleEntry) allowsEntityAlias(alias string) bool {
+	for _, allowed := range role.AllowedEntityAliases {
+		if strutil.GlobbedStringsMatch(allowed, alias) {
+			return true
+		}
+	}
+	return false
+}
+
+// tokenEntityIDByAlias returns the ID of the entity of the given alias of the
+// token store mount, creating the entity if the alias doesn't exist
+func (c *Core) tokenEntityIDByAlias(aliasName string) (string, error) {
+	if c.identityStore == nil {
+		return "", fmt.Errorf("identity store is not available")
+	}
+	me := c.router.MatchingMountEntry("auth/token/")
+	if me == nil {
+		return "", fmt.Errorf("token store mount not found")
+	}
+
+	entity, err := c.identityStore.EntityByAliasFactors(me.Accessor, aliasName, false)
+	if err != nil {
+		return "", err
+	}
+	if entity == nil {
+		entity, err = c.identityStore.CreateEntity(&logical.Alias{
+			Name:          aliasName,
+			MountAccessor: me.Accessor,
+			MountType:     me.Type,
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+	return entity.ID, nil
+}
+
 func (ts *TokenStore) tokenStoreRoleExistenceCheck(req *logical.Request, data *framework.FieldData) (bool, error) {
 	name := data.Get("role_name").(string)
 	if name == "" {
@@ -2344,6 +2572,47 @@ func (ts *TokenStore) tokenStoreRoleCreateUpdate(
 		entry.Renewable = data.Get("renewable").(bool)
 	}
 
+	tokenTypeStr, ok := data.GetOk("token_type")
+	if ok {
+		entry.TokenType = tokenTypeStr.(string)
+	} else if req.Operation == logical.CreateOperation {
+		entry.TokenType = data.Get("token_type").(string)
+	}
+	if entry.TokenType != "" && !validTokenType(entry.TokenType) {
+		return logical.ErrorResponse(fmt.Sprintf("invalid token type %q", entry.TokenType)), nil
+	}
+	if entry.TokenType == tokenTypeBatch && entry.Period != 0 {
+		return logical.ErrorResponse("batch tokens cannot be periodic"), nil
+	}
+
+	boundCIDRsRaw, ok := data.GetOk("bound_cidrs")
+	if ok {
+		entry.BoundCIDRs = strutil.RemoveDuplicates(boundCIDRsRaw.([]string), false)
+		if len(entry.BoundCIDRs) > 0 {
+			if valid, err := cidrutil.ValidateCIDRListSlice(entry.BoundCIDRs); !valid {
+				return logical.ErrorResponse(fmt.Sprintf("invalid bound_cidrs: %v", err)), nil
+			}
+		}
+	}
+
+	numUsesRaw, ok := data.GetOk("num_uses")
+	if ok {
+		entry.NumUses = numUsesRaw.(int)
+	} else if req.Operation == logical.CreateOperation {
+		entry.NumUses = data.Get("num_uses").(int)
+	}
+	if entry.NumUses < 0 {
+		return logical.ErrorResponse("number of uses cannot be negative"), nil
+	}
+	if entry.TokenType == tokenTypeBatch && entry.NumUses != 0 {
+		return logical.ErrorResponse("batch tokens cannot have a limited number of uses"), nil
+	}
+
+	allowedEntityAliasesRaw, ok := data.GetOk("allowed_entity_aliases")
+	if ok {
+		entry.AllowedEntityAliases = strutil.RemoveDuplicates(allowedEntityAliasesRaw.([]string), false)
+	}
+
 	var resp *logical.Response
 
 	explicitMaxTTLInt, ok := data.GetOk("explicit_max_ttl")
@@ -2384,6 +2653,9 @@ func (ts *TokenStore) tokenStoreRoleCreateUpdate(
 	if strings.Contains(entry.PathSuffix, "..") {
 		return logical.ErrorResponse(fmt.Sprintf("error registering path suffix: %s", consts.ErrPathContainsParentReferences)), nil
 	}
+	if strings.Contains(entry.PathSuffix, tokenEntityAliasTemplate) && len(entry.AllowedEntityAliases) == 0 {
+		return logical.ErrorResponse(fmt.Sprintf("path suffixes using %s require allowed_entity_aliases", tokenEntityAliasTemplate)), nil
+	}
 
 	allowedPoliciesStr, ok := data.GetOk("allowed_policies")
 	if ok {
@@ -2418,9 +2690,10 @@ conditions have occurred.
 `
 	tokenTidyDesc = `
 This endpoint performs cleanup tasks that can be run to clean up token and
-lease entries after certain error conditions. Usually running this is not
-necessary, and is only required if upgrade notes or support personnel suggest
-it.
+lease entries after certain error conditions. Tokens whose parent no longer
+exists are revoked, the index entries of existing tokens are rebuilt, and the
+index entries of tokens which no longer exist are deleted along with the leases
+of the tokens. With "dry_run", the inconsistencies are only reported.
 `
 	tokenBackendHelp = `The token credential backend is always enabled and builtin to Vault.
 Client tokens are used to identify a client and to allow Vault to associate policies and ACLs
@@ -2467,6 +2740,27 @@ no effect on the token being renewed.`
 	tokenRenewableHelp = `Tokens created via this role will be
 renewable or not according to this value.
 Defaults to "true".`
+	tokenBoundCIDRsHelp = `If set, tokens created via this role
+can only be used from these CIDR blocks. The
+parameter is a comma-delimited list of CIDR
+blocks.`
+	tokenNumUsesHelp = `If set, tokens created via this role
+are limited to this number of uses, and are
+revoked after their last use. Defaults to 0,
+which means unlimited.`
+	tokenAllowedEntityAliasesHelp = `If set, tokens created via this role
+can be bound to the entity of one of these
+aliases of the token store mount, given as
+"entity_alias" at creation; entries can
+start or end with a "*" glob. The entity is
+created if the alias doesn't exist. The
+path suffix can contain {{entity_alias}} to
+revoke the tokens of an alias together.`
+	tokenTypeHelp = `If set, tokens created via this role
+will be of this type, either "service" or
+"batch". Batch tokens are not persisted and
+cannot be renewed, revoked, or create child
+tokens.`
 	tokenListAccessorsHelp = `List token accessors, which can then be
 be used to iterate and discover their properities
 or revoke them. Because this can be used to
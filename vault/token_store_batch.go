@@ -0,0 +1,213 @@
+package vault
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/helper/policyutil"
+	"github.com/hashicorp/vault/logical"
+)
+
+const (
+	// tokenTypeService is the type of the tokens persisted in the token
+	// store. Tokens stored before token types were introduced have no type
+	// and are service tokens.
+	tokenTypeService = "service"
+
+	// tokenTypeBatch is the type of the tokens which are not persisted but
+	// carry their entry in their ID, encrypted with the barrier keyring
+	tokenTypeBatch = "batch"
+
+	// batchTokenPrefix is the prefix of the IDs of batch tokens
+	batchTokenPrefix = "b."
+
+	// batchTokenEncryptionPath is authenticated along with the content of
+	// batch tokens, so that other barrier ciphertexts are not valid tokens
+	batchTokenEncryptionPath = "token/batch"
+)
+
+// batchTokenEntry is the content of a batch token. Since batch tokens are
+// not stored, it holds everything needed to authorize their requests. The
+// parent is recorded by its accessor, so that its ID can't be recovered from
+// the batch token even if the barrier keyring is compromised later.
+type batchTokenEntry struct {
+	ParentAccessor string            `json:"parent_accessor,omitempty"`
+	Policies       []string          `json:"policies,omitempty"`
+	Path           string            `json:"path"`
+	Meta           map[string]string `json:"meta,omitempty"`
+	DisplayName    string            `json:"display_name,omitempty"`
+	CreationTime   int64             `json:"creation_time"`
+	TTL            int64             `json:"ttl"`
+	Role           string            `json:"role,omitempty"`
+	EntityID       string            `json:"entity_id,omitempty"`
+	BoundCIDRs     []string          `json:"bound_cidrs,omitempty"`
+	MFAMethods     []string          `json:"mfa_methods,omitempty"`
+	NamespaceID    string            `json:"namespace_id,omitempty"`
+
+	// Nonce makes the IDs of batch tokens with the same content unique
+	Nonce string `json:"nonce"`
+}
+
+// validTokenType returns whether the given type is a known token type
+func validTokenType(tokenType string) bool {
+	switch tokenType {
+	case tokenTypeService, tokenTypeBatch:
+		return true
+	}
+	return false
+}
+
+// tokenType returns the type of the token, defaulting to service tokens
+func (te *TokenEntry) tokenType() string {
+	if te.Type == "" {
+		return tokenTypeService
+	}
+	return te.Type
+}
+
+// createBatch is used to create a new batch token. The ID of the entry is
+// generated from its content, and nothing is persisted.
+func (ts *TokenStore) createBatch(entry *TokenEntry) error {
+	defer metrics.MeasureSince([]string{"token", "create_batch"}, time.Now())
+
+	switch {
+	case entry.ID != "":
+		return fmt.Errorf("the ID of batch tokens cannot be specified")
+	case entry.TTL <= 0:
+		return fmt.Errorf("batch tokens must have a TTL")
+	case entry.NumUses != 0:
+		return fmt.Errorf("batch tokens cannot have a limited number of uses")
+	case entry.Period != 0:
+		return fmt.Errorf("batch tokens cannot be periodic")
+	}
+
+	entry.Policies = policyutil.SanitizePolicies(entry.Policies, policyutil.DoNotAddDefaultPolicy)
+
+	// Ensure the parent exists, since batch tokens are only valid as long as
+	// their parent is
+	var parentAccessor string
+	if entry.Parent != "" {
+		parent, err := ts.Lookup(entry.Parent)
+		if err != nil {
+			return fmt.Errorf("failed to lookup parent: %v", err)
+		}
+		if parent == nil {
+			return fmt.Errorf("parent token not found")
+		}
+		if parent.Accessor == "" {
+			return fmt.Errorf("parent token has no accessor")
+		}
+		parentAccessor = parent.Accessor
+	}
+
+	nonce, err := uuid.GenerateUUID()
+	if err != nil {
+		return err
+	}
+
+	enc, err := json.Marshal(&batchTokenEntry{
+		ParentAccessor: parentAccessor,
+		Policies:       entry.Policies,
+		Path:           entry.Path,
+		Meta:           entry.Meta,
+		DisplayName:    entry.DisplayName,
+		CreationTime:   entry.CreationTime,
+		TTL:            int64(entry.TTL.Seconds()),
+		Role:           entry.Role,
+		EntityID:       entry.EntityID,
+		BoundCIDRs:     entry.BoundCIDRs,
+		MFAMethods:     entry.MFAMethods,
+		NamespaceID:    entry.NamespaceID,
+		Nonce:          nonce,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode entry: %v", err)
+	}
+
+	ciphertext, err := ts.batchTokenEncryptor.Encrypt(batchTokenEncryptionPath, enc)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt entry: %v", err)
+	}
+
+	entry.ID = batchTokenPrefix + base64.RawURLEncoding.EncodeToString(ciphertext)
+	entry.Type = tokenTypeBatch
+	entry.Accessor = ""
+	return nil
+}
+
+// lookupBatch is used to decrypt and decode a batch token. It returns nil if
+// the token is not authentic, has expired, or its parent was revoked.
+func (ts *TokenStore) lookupBatch(id string) (*TokenEntry, error) {
+	if !strings.HasPrefix(id, batchTokenPrefix) {
+		return nil, nil
+	}
+
+	ciphertext, err := base64.RawURLEncoding.DecodeString(id[len(batchTokenPrefix):])
+	if err != nil {
+		return nil, nil
+	}
+	enc, err := ts.batchTokenEncryptor.Decrypt(batchTokenEncryptionPath, ciphertext)
+	if err != nil {
+		return nil, nil
+	}
+	var bte batchTokenEntry
+	if err := jsonutil.DecodeJSON(enc, &bte); err != nil {
+		return nil, fmt.Errorf("failed to decode entry: %v", err)
+	}
+
+	entry := &TokenEntry{
+		ID:           id,
+		Type:         tokenTypeBatch,
+		Policies:     bte.Policies,
+		Path:         bte.Path,
+		Meta:         bte.Meta,
+		DisplayName:  bte.DisplayName,
+		CreationTime: bte.CreationTime,
+		TTL:          time.Duration(bte.TTL) * time.Second,
+		Role:         bte.Role,
+		EntityID:     bte.EntityID,
+		BoundCIDRs:   bte.BoundCIDRs,
+		MFAMethods:   bte.MFAMethods,
+		NamespaceID:  bte.NamespaceID,
+	}
+
+	if time.Now().After(entry.expireTime()) {
+		return nil, nil
+	}
+
+	// Batch tokens can't be revoked themselves, but they are revoked along
+	// with their parent
+	if bte.ParentAccessor != "" {
+		aEntry, err := ts.lookupByAccessor(bte.ParentAccessor, false)
+		if err != nil {
+			if _, ok := err.(*logical.StatusBadRequest); ok {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("failed to lookup parent: %v", err)
+		}
+		if aEntry.TokenID == "" {
+			return nil, nil
+		}
+		parent, err := ts.Lookup(aEntry.TokenID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to lookup parent: %v", err)
+		}
+		if parent == nil {
+			return nil, nil
+		}
+		entry.Parent = parent.ID
+	}
+
+	return entry, nil
+}
+
+// expireTime returns the time at which a batch token expires
+func (te *TokenEntry) expireTime() time.Time {
+	return time.Unix(te.CreationTime, 0).Add(te.TTL)
+}
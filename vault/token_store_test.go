@@ -1,6 +1,8 @@
 package vault
 
 import (
+	"bytes"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"path"
@@ -11,6 +13,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/hashicorp/errwrap"
 	"github.com/hashicorp/go-uuid"
 	"github.com/hashicorp/vault/helper/locksutil"
 	"github.com/hashicorp/vault/logical"
@@ -466,6 +469,54 @@ func TestTokenStore_CreateLookup(t *testing.T) {
 	}
 }
 
+func TestTokenStore_CreateTransaction(t *testing.T) {
+	backend := newFailingTxnBackend(t)
+	c, _, root := TestCoreUnsealedBackend(t, backend)
+	ts := c.tokenStore
+
+	accessors, err := ts.view.List(accessorPrefix)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Neither the token nor its indexes are written by a failed transaction
+	backend.failing = true
+	ent := &TokenEntry{Path: "test", Policies: []string{"dev"}, Parent: root}
+	if err := ts.create(ent); err == nil {
+		t.Fatal("expected error")
+	}
+	out, err := ts.view.List(accessorPrefix)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(out) != len(accessors) {
+		t.Fatalf("bad: %v", out)
+	}
+	parentSaltedID, err := ts.SaltID(root)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	out, err = ts.view.List(parentPrefix + parentSaltedID + "/")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(out) != 0 {
+		t.Fatalf("bad: %v", out)
+	}
+
+	backend.failing = false
+	if err := ts.create(ent); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	te, err := ts.lookupByAccessor(ent.Accessor, false)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if te.TokenID != ent.ID {
+		t.Fatalf("bad: %#v", te)
+	}
+}
+
 func TestTokenStore_CreateLookup_ProvidedID(t *testing.T) {
 	c, ts, _, _ := TestCoreWithTokenStore(t)
 
@@ -761,6 +812,9 @@ func TestTokenStore_Revoke_Orphan(t *testing.T) {
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
+
+	// The child is now an orphan
+	ent2.Parent = ""
 	if !reflect.DeepEqual(out, ent2) {
 		t.Fatalf("bad: expected:%#v\nactual:%#v", ent2, out)
 	}
@@ -1379,14 +1433,25 @@ func TestTokenStore_HandleRequest_RevokeOrphan(t *testing.T) {
 		t.Fatalf("bad: %v", out)
 	}
 
-	// Sub-child should exist!
+	// Sub-child should exist, as an orphan!
 	out, err = ts.Lookup("sub-child")
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
-	if out == nil {
+	if out == nil || out.Parent != "" {
 		t.Fatalf("bad: %v", out)
 	}
+	saltedChild, err := ts.SaltID("child")
+	if err != nil {
+		t.Fatal(err)
+	}
+	children, err := ts.view.List(parentPrefix + saltedChild + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(children) != 0 {
+		t.Fatalf("bad: children: %#v", children)
+	}
 }
 
 func TestTokenStore_HandleRequest_RevokeOrphan_NonRoot(t *testing.T) {
@@ -1449,6 +1514,7 @@ func TestTokenStore_HandleRequest_Lookup(t *testing.T) {
 		"explicit_max_ttl": int64(0),
 		"expire_time":      nil,
 		"entity_id":        "",
+		"type":             "service",
 	}
 
 	if resp.Data["creation_time"].(int64) == 0 {
@@ -1489,6 +1555,7 @@ func TestTokenStore_HandleRequest_Lookup(t *testing.T) {
 		"explicit_max_ttl": int64(0),
 		"renewable":        true,
 		"entity_id":        "",
+		"type":             "service",
 	}
 
 	if resp.Data["creation_time"].(int64) == 0 {
@@ -1540,6 +1607,7 @@ func TestTokenStore_HandleRequest_Lookup(t *testing.T) {
 		"explicit_max_ttl": int64(0),
 		"renewable":        true,
 		"entity_id":        "",
+		"type":             "service",
 	}
 
 	if resp.Data["creation_time"].(int64) == 0 {
@@ -1622,6 +1690,7 @@ func TestTokenStore_HandleRequest_LookupSelf(t *testing.T) {
 		"ttl":              int64(3600),
 		"explicit_max_ttl": int64(0),
 		"entity_id":        "",
+		"type":             "service",
 	}
 
 	if resp.Data["creation_time"].(int64) == 0 {
@@ -1792,6 +1861,11 @@ func TestTokenStore_RoleCRUD(t *testing.T) {
 		"path_suffix":         "happenin",
 		"explicit_max_ttl":    int64(0),
 		"renewable":           true,
+		"token_type":          "",
+		"bound_cidrs":         []string(nil),
+		"num_uses":            0,
+
+		"allowed_entity_aliases": []string(nil),
 	}
 
 	if !reflect.DeepEqual(expected, resp.Data) {
@@ -1836,6 +1910,11 @@ func TestTokenStore_RoleCRUD(t *testing.T) {
 		"path_suffix":         "happenin",
 		"explicit_max_ttl":    int64(0),
 		"renewable":           false,
+		"token_type":          "",
+		"bound_cidrs":         []string(nil),
+		"num_uses":            0,
+
+		"allowed_entity_aliases": []string(nil),
 	}
 
 	if !reflect.DeepEqual(expected, resp.Data) {
@@ -1873,6 +1952,11 @@ func TestTokenStore_RoleCRUD(t *testing.T) {
 		"path_suffix":         "happenin",
 		"period":              int64(0),
 		"renewable":           false,
+		"token_type":          "",
+		"bound_cidrs":         []string(nil),
+		"num_uses":            0,
+
+		"allowed_entity_aliases": []string(nil),
 	}
 
 	if !reflect.DeepEqual(expected, resp.Data) {
@@ -3572,7 +3656,9 @@ func TestTokenStore_TidyLeaseRevocation(t *testing.T) {
 	}
 
 	// Call tidy
-	ts.handleTidy(nil, nil)
+	if _, err := ts.tidy(false); err != nil {
+		t.Fatal(err)
+	}
 
 	// Verify leases are gone
 	storedLeases, err = exp.lookupByToken(tut)
@@ -3583,3 +3669,536 @@ func TestTokenStore_TidyLeaseRevocation(t *testing.T) {
 		t.Fatal("found leases")
 	}
 }
+
+func TestTokenStore_BatchTokens(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	ts := c.tokenStore
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "sys/policy/foo")
+	req.ClientToken = root
+	req.Data["rules"] = `path "auth/token/create" { capabilities = ["update"] }`
+	if resp, err := c.HandleRequest(req); err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	testCoreMakeToken(t, c, root, "parent", "1h", []string{"foo"})
+
+	storedTokens := func() int {
+		keys, err := ts.view.List(lookupPrefix)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		return len(keys)
+	}
+	stored := storedTokens()
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "auth/token/create")
+	req.ClientToken = "parent"
+	req.Data["type"] = "batch"
+	req.Data["ttl"] = "30m"
+	resp, err := c.HandleRequest(req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	batch := resp.Auth.ClientToken
+	if !strings.HasPrefix(batch, batchTokenPrefix) || resp.Auth.Accessor != "" || resp.Auth.Renewable {
+		t.Fatalf("bad: %#v", resp.Auth)
+	}
+
+	// Batch tokens are not persisted
+	if n := storedTokens(); n != stored {
+		t.Fatalf("bad: expected %d stored tokens, got %d", stored, n)
+	}
+
+	te, err := ts.Lookup(batch)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if te == nil || te.Type != tokenTypeBatch || te.Parent != "parent" || te.TTL != 30*time.Minute ||
+		!reflect.DeepEqual(te.Policies, []string{"default", "foo"}) {
+		t.Fatalf("bad: %#v", te)
+	}
+
+	// The content of batch tokens is encrypted, and doesn't reveal the ID
+	// of the parent
+	payload, err := base64.RawURLEncoding.DecodeString(batch[len(batchTokenPrefix):])
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if bytes.Contains(payload, []byte("parent")) || bytes.Contains(payload, []byte("policies")) {
+		t.Fatalf("bad: batch token is not encrypted: %q", payload)
+	}
+
+	// Tampered tokens are not valid
+	for _, tampered := range []string{batchTokenPrefix, batchTokenPrefix + "AAAA", batchTokenPrefix + "!"} {
+		if te, err := ts.Lookup(tampered); err != nil || te != nil {
+			t.Fatalf("bad: te: %#v, err: %v", te, err)
+		}
+	}
+	tampered := batch[:len(batch)-1] + "0"
+	if tampered == batch {
+		tampered = batch[:len(batch)-1] + "1"
+	}
+	if te, err := ts.Lookup(tampered); err != nil || te != nil {
+		t.Fatalf("bad: te: %#v, err: %v", te, err)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "auth/token/lookup-self")
+	req.ClientToken = batch
+	resp, err = c.HandleRequest(req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	if resp.Data["type"] != tokenTypeBatch || resp.Data["renewable"] != false || resp.Data["ttl"].(int64) <= 0 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Batch tokens can't be renewed or revoked, create children, or use the
+	// cubbyhole
+	for _, path := range []string{"auth/token/renew-self", "auth/token/revoke-self", "auth/token/create", "cubbyhole/foo"} {
+		req = logical.TestRequest(t, logical.UpdateOperation, path)
+		req.ClientToken = batch
+		req.Data["foo"] = "bar"
+		resp, err = c.HandleRequest(req)
+		if err == nil || resp == nil || !resp.IsError() {
+			t.Fatalf("expected an error on %q; resp: %#v, err: %v", path, resp, err)
+		}
+	}
+
+	for _, data := range []map[string]interface{}{
+		{"num_uses": 1},
+		{"period": "1h"},
+		{"id": "foo"},
+	} {
+		req = logical.TestRequest(t, logical.UpdateOperation, "auth/token/create")
+		req.ClientToken = root
+		req.Data = data
+		req.Data["type"] = "batch"
+		req.Data["policies"] = "foo"
+		resp, err = c.HandleRequest(req)
+		if err == nil || resp == nil || !resp.IsError() {
+			t.Fatalf("expected an error for %#v; resp: %#v, err: %v", data, resp, err)
+		}
+	}
+
+	// Batch tokens expire on their own
+	expired := &TokenEntry{
+		Path:         "auth/token/create",
+		Policies:     []string{"foo"},
+		CreationTime: time.Now().Add(-time.Hour).Unix(),
+		TTL:          time.Minute,
+	}
+	if err := ts.createBatch(expired); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if te, err := ts.Lookup(expired.ID); err != nil || te != nil {
+		t.Fatalf("bad: te: %#v, err: %v", te, err)
+	}
+
+	// Batch tokens are revoked along with their parent
+	if err := ts.RevokeTree("parent"); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if te, err := ts.Lookup(batch); err != nil || te != nil {
+		t.Fatalf("bad: te: %#v, err: %v", te, err)
+	}
+}
+
+func TestTokenStore_RoleTokenType(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "auth/token/roles/test")
+	req.ClientToken = root
+	req.Data = map[string]interface{}{
+		"token_type": "invalid",
+	}
+	resp, err := c.HandleRequest(req)
+	if err == nil && (resp == nil || !resp.IsError()) {
+		t.Fatalf("expected an error; resp: %#v", resp)
+	}
+
+	req.Data["token_type"] = "batch"
+	resp, err = c.HandleRequest(req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "auth/token/create/test")
+	req.ClientToken = root
+	req.Data["type"] = "service"
+	resp, err = c.HandleRequest(req)
+	if err == nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error; resp: %#v, err: %v", resp, err)
+	}
+
+	delete(req.Data, "type")
+	req.Data["policies"] = "foo"
+	resp, err = c.HandleRequest(req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	te, err := c.tokenStore.Lookup(resp.Auth.ClientToken)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if te == nil || te.Type != tokenTypeBatch || te.Role != "test" {
+		t.Fatalf("bad: %#v", te)
+	}
+}
+
+func TestTokenStore_BoundCIDRs(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	lookupSelf := func(token, remoteAddr string) (*logical.Response, error) {
+		req := logical.TestRequest(t, logical.ReadOperation, "auth/token/lookup-self")
+		req.ClientToken = token
+		req.Connection = &logical.Connection{RemoteAddr: remoteAddr}
+		return c.HandleRequest(req)
+	}
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "auth/token/create")
+	req.ClientToken = root
+	req.Data["bound_cidrs"] = "not-a-cidr"
+	resp, err := c.HandleRequest(req)
+	if err == nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error; resp: %#v, err: %v", resp, err)
+	}
+
+	req.Data["bound_cidrs"] = []string{"127.0.0.1/32", "10.0.0.0/8,10.0.0.0/8"}
+	resp, err = c.HandleRequest(req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	token := resp.Auth.ClientToken
+
+	resp, err = lookupSelf(token, "10.1.2.3")
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	if !reflect.DeepEqual(resp.Data["bound_cidrs"], []string{"10.0.0.0/8", "127.0.0.1/32"}) {
+		t.Fatalf("bad: bound_cidrs: %#v", resp.Data["bound_cidrs"])
+	}
+	for _, remoteAddr := range []string{"127.0.0.2", "192.168.0.1", ""} {
+		if _, err := lookupSelf(token, remoteAddr); err == nil || !errwrap.Contains(err, logical.ErrPermissionDenied.Error()) {
+			t.Fatalf("expected permission denied from %q; err: %v", remoteAddr, err)
+		}
+	}
+
+	// Children inherit the blocks of their parent, and can't widen them
+	req = logical.TestRequest(t, logical.UpdateOperation, "auth/token/create")
+	req.ClientToken = token
+	req.Connection = &logical.Connection{RemoteAddr: "10.1.2.3"}
+	req.Data["bound_cidrs"] = "0.0.0.0/0"
+	resp, err = c.HandleRequest(req)
+	if err == nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error; resp: %#v, err: %v", resp, err)
+	}
+
+	delete(req.Data, "bound_cidrs")
+	resp, err = c.HandleRequest(req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	te, err := c.tokenStore.Lookup(resp.Auth.ClientToken)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if te == nil || !reflect.DeepEqual(te.BoundCIDRs, []string{"10.0.0.0/8", "127.0.0.1/32"}) {
+		t.Fatalf("bad: %#v", te)
+	}
+
+	// Roles bind the tokens created against them
+	req = logical.TestRequest(t, logical.UpdateOperation, "auth/token/roles/test")
+	req.ClientToken = root
+	req.Data["bound_cidrs"] = "192.168.0.0/16"
+	resp, err = c.HandleRequest(req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "auth/token/create/test")
+	req.ClientToken = root
+	req.Data["bound_cidrs"] = "10.0.0.0/8"
+	resp, err = c.HandleRequest(req)
+	if err == nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error; resp: %#v, err: %v", resp, err)
+	}
+
+	req.Data["bound_cidrs"] = "192.168.1.0/24"
+	resp, err = c.HandleRequest(req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	if _, err := lookupSelf(resp.Auth.ClientToken, "192.168.2.1"); err == nil || !errwrap.Contains(err, logical.ErrPermissionDenied.Error()) {
+		t.Fatalf("expected permission denied; err: %v", err)
+	}
+	if resp, err := lookupSelf(resp.Auth.ClientToken, "192.168.1.1"); err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+}
+
+func TestTokenStore_RoleNumUses(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "auth/token/roles/test")
+	req.ClientToken = root
+	req.Data["num_uses"] = -1
+	resp, err := c.HandleRequest(req)
+	if err == nil && (resp == nil || !resp.IsError()) {
+		t.Fatalf("expected an error; resp: %#v", resp)
+	}
+
+	req.Data["num_uses"] = 2
+	req.Data["token_type"] = "batch"
+	resp, err = c.HandleRequest(req)
+	if err == nil && (resp == nil || !resp.IsError()) {
+		t.Fatalf("expected an error; resp: %#v", resp)
+	}
+
+	delete(req.Data, "token_type")
+	resp, err = c.HandleRequest(req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "auth/token/create/test")
+	req.ClientToken = root
+	resp, err = c.HandleRequest(req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	token := resp.Auth.ClientToken
+
+	// The lesser of the role's and of the requested number of uses is used
+	req.Data["num_uses"] = 5
+	resp, err = c.HandleRequest(req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	if len(resp.Warnings) == 0 {
+		t.Fatalf("expected a warning")
+	}
+	te, err := c.tokenStore.Lookup(resp.Auth.ClientToken)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if te == nil || te.NumUses != 2 {
+		t.Fatalf("bad: %#v", te)
+	}
+
+	// The token is revoked after its last use
+	for i := 0; i < 2; i++ {
+		req := logical.TestRequest(t, logical.ReadOperation, "auth/token/lookup-self")
+		req.ClientToken = token
+		resp, err := c.HandleRequest(req)
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("bad: use %d: resp: %#v, err: %v", i, resp, err)
+		}
+	}
+	req = logical.TestRequest(t, logical.ReadOperation, "auth/token/lookup-self")
+	req.ClientToken = token
+	if _, err := c.HandleRequest(req); err == nil || !errwrap.Contains(err, logical.ErrPermissionDenied.Error()) {
+		t.Fatalf("expected permission denied; err: %v", err)
+	}
+}
+
+func TestTokenStore_TidyRepairs(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	ts := c.tokenStore
+
+	testMakeToken(t, ts, root, "parent", "", []string{"root"})
+	testMakeToken(t, ts, "parent", "child", "", []string{"root"})
+	testMakeToken(t, ts, "child", "grandchild", "", []string{"foo"})
+	testMakeToken(t, ts, root, "other", "", []string{"root"})
+	testMakeToken(t, ts, "other", "other-child", "", []string{"foo"})
+
+	saltID := func(id string) string {
+		salted, err := ts.SaltID(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return salted
+	}
+	exists := func(key string) bool {
+		raw, err := ts.view.Get(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return raw != nil
+	}
+
+	// Simulate a crash while revoking a tree, which left the children of the
+	// parent behind, and the loss of index entries of another tree
+	if err := ts.view.Delete(lookupPrefix + saltID("parent")); err != nil {
+		t.Fatal(err)
+	}
+	other, err := ts.Lookup("other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherAccessor := accessorPrefix + saltID(other.Accessor)
+	otherChildIndex := parentPrefix + saltID("other") + "/" + saltID("other-child")
+	for _, key := range []string{otherAccessor, otherChildIndex} {
+		if err := ts.view.Delete(key); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "sys/tokens/tidy")
+	req.ClientToken = root
+	req.Data["dry_run"] = true
+	resp, err := c.HandleRequest(req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	for key, expected := range map[string]int64{
+		"dry_run":                        1,
+		"tokens_with_missing_parent":     1,
+		"parent_index_entries_rebuilt":   1,
+		"accessor_index_entries_rebuilt": 1,
+		"invalid_parent_index_entries":   1,
+		"accessors_with_invalid_token":   1,
+	} {
+		actual, ok := resp.Data[key].(int64)
+		if b, isBool := resp.Data[key].(bool); isBool && b {
+			actual, ok = 1, true
+		}
+		if !ok || actual != expected {
+			t.Fatalf("bad: %s: expected %d, got %#v", key, expected, resp.Data[key])
+		}
+	}
+
+	// Nothing was repaired
+	if out, err := ts.Lookup("grandchild"); err != nil || out == nil {
+		t.Fatalf("bad: %#v, err: %v", out, err)
+	}
+	if exists(otherAccessor) || exists(otherChildIndex) {
+		t.Fatalf("expected index entries not to be rebuilt")
+	}
+
+	req.Data["dry_run"] = false
+	resp, err = c.HandleRequest(req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+
+	// The tree of the missing parent is revoked
+	for _, id := range []string{"child", "grandchild"} {
+		if out, err := ts.Lookup(id); err != nil || out != nil {
+			t.Fatalf("bad: %s: %#v, err: %v", id, out, err)
+		}
+	}
+	if !exists(otherAccessor) || !exists(otherChildIndex) {
+		t.Fatalf("expected index entries to be rebuilt")
+	}
+	out, err := ts.lookupByAccessor(other.Accessor, false)
+	if err != nil || out.TokenID != "other" {
+		t.Fatalf("bad: %#v, err: %v", out, err)
+	}
+
+	// The other child is revoked along with its parent again
+	if err := ts.RevokeTree("other"); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := ts.Lookup("other-child"); err != nil || out != nil {
+		t.Fatalf("bad: %#v, err: %v", out, err)
+	}
+
+	// Nothing is left to repair
+	resp, err = c.HandleRequest(req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	for _, key := range []string{"tokens_with_missing_parent", "parent_index_entries_rebuilt", "accessor_index_entries_rebuilt", "invalid_parent_index_entries", "accessors_without_token", "accessors_with_invalid_token"} {
+		if resp.Data[key].(int64) != 0 {
+			t.Fatalf("bad: %s: %#v", key, resp.Data)
+		}
+	}
+}
+
+func TestTokenStore_RoleEntityAliases(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	write := func(path string, data map[string]interface{}) (*logical.Response, error) {
+		req := logical.TestRequest(t, logical.UpdateOperation, path)
+		req.ClientToken = root
+		req.Data = data
+		return c.HandleRequest(req)
+	}
+
+	// Templated path suffixes require allowed entity aliases
+	resp, err := write("auth/token/roles/test", map[string]interface{}{
+		"path_suffix": "{{entity_alias}}",
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error; resp: %#v, err: %v", resp, err)
+	}
+
+	resp, err = write("auth/token/roles/test", map[string]interface{}{
+		"path_suffix":            "{{entity_alias}}",
+		"allowed_entity_aliases": "worker-*,builder",
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+
+	// Entity aliases require a role which allows them
+	for _, tc := range []struct {
+		path  string
+		alias string
+	}{
+		{"auth/token/create", "worker-1"},
+		{"auth/token/create-orphan", "builder"},
+		{"auth/token/create/test", "deployer"},
+		{"auth/token/create/test", ""},
+		{"auth/token/create/test", "worker-../x"},
+	} {
+		data := map[string]interface{}{}
+		if tc.alias != "" {
+			data["entity_alias"] = tc.alias
+		}
+		if resp, err := write(tc.path, data); err == nil || resp == nil || !resp.IsError() {
+			t.Fatalf("expected an error for %q with alias %q; resp: %#v, err: %v", tc.path, tc.alias, resp, err)
+		}
+	}
+
+	resp, err = write("auth/token/create/test", map[string]interface{}{
+		"entity_alias": "worker-1",
+	})
+	if err != nil || resp == nil || resp.Auth == nil {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	entityID := resp.Auth.EntityID
+	if entityID == "" {
+		t.Fatalf("expected the token to be bound to an entity")
+	}
+
+	te, err := c.tokenStore.Lookup(resp.Auth.ClientToken)
+	if err != nil || te == nil {
+		t.Fatalf("bad: %#v, err: %v", te, err)
+	}
+	if te.EntityID != entityID || te.Path != "auth/token/create/test/worker-1" {
+		t.Fatalf("bad: %#v", te)
+	}
+
+	// The entity was created with an alias of the token store mount
+	accessor := c.router.MatchingMountEntry("auth/token/").Accessor
+	entity, err := c.identityStore.EntityByAliasFactors(accessor, "worker-1", false)
+	if err != nil || entity == nil || entity.ID != entityID {
+		t.Fatalf("bad: %#v, err: %v", entity, err)
+	}
+
+	// Tokens bound to the same alias resolve to the same entity
+	resp, err = write("auth/token/create/test", map[string]interface{}{
+		"entity_alias": "worker-1",
+	})
+	if err != nil || resp == nil || resp.Auth == nil || resp.Auth.EntityID != entityID {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+
+	resp, err = write("auth/token/create/test", map[string]interface{}{
+		"entity_alias": "builder",
+	})
+	if err != nil || resp == nil || resp.Auth == nil || resp.Auth.EntityID == entityID {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+}
@@ -0,0 +1,407 @@
+package vault
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/fatih/structs"
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/helper/locksutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// tokenTidyReport describes the inconsistencies found in the token store by
+// a tidy operation. Unless the operation is a dry run, they are repaired.
+type tokenTidyReport struct {
+	DryRun bool `json:"dry_run" structs:"dry_run" mapstructure:"dry_run"`
+
+	// Tokens whose parent no longer exists are revoked, along with their
+	// children, and tokens whose revocation failed are revoked again
+	TokensScanned           int64 `json:"tokens_scanned" structs:"tokens_scanned" mapstructure:"tokens_scanned"`
+	TokensWithMissingParent int64 `json:"tokens_with_missing_parent" structs:"tokens_with_missing_parent" mapstructure:"tokens_with_missing_parent"`
+	FailedRevocations       int64 `json:"failed_revocations" structs:"failed_revocations" mapstructure:"failed_revocations"`
+
+	// Index entries missing for existing tokens are rebuilt
+	ParentIndexEntriesRebuilt   int64 `json:"parent_index_entries_rebuilt" structs:"parent_index_entries_rebuilt" mapstructure:"parent_index_entries_rebuilt"`
+	AccessorIndexEntriesRebuilt int64 `json:"accessor_index_entries_rebuilt" structs:"accessor_index_entries_rebuilt" mapstructure:"accessor_index_entries_rebuilt"`
+
+	// Parent index entries of tokens which don't exist, or are orphans, are
+	// deleted
+	ParentIndexEntriesScanned int64 `json:"parent_index_entries_scanned" structs:"parent_index_entries_scanned" mapstructure:"parent_index_entries_scanned"`
+	InvalidParentIndexEntries int64 `json:"invalid_parent_index_entries" structs:"invalid_parent_index_entries" mapstructure:"invalid_parent_index_entries"`
+
+	// Accessors of tokens which don't exist are deleted, along with the
+	// leases of the tokens
+	AccessorsScanned          int64 `json:"accessors_scanned" structs:"accessors_scanned" mapstructure:"accessors_scanned"`
+	AccessorsWithoutToken     int64 `json:"accessors_without_token" structs:"accessors_without_token" mapstructure:"accessors_without_token"`
+	AccessorsWithInvalidToken int64 `json:"accessors_with_invalid_token" structs:"accessors_with_invalid_token" mapstructure:"accessors_with_invalid_token"`
+}
+
+// handleTidy handles the auth/token/tidy path, which cleans up the token
+// store after certain error conditions
+func (ts *TokenStore) handleTidy(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	report, err := ts.tidy(data.Get("dry_run").(bool))
+	if err != nil {
+		return nil, err
+	}
+	return &logical.Response{
+		Data: structs.New(report).Map(),
+	}, nil
+}
+
+// tidy cleans up the token store: it revokes the tokens whose parent is gone,
+// retries failed revocations, rebuilds the missing index entries of the
+// existing tokens, and deletes the index entries of tokens which no longer
+// exist, revoking their leases. If dryRun is set, nothing is modified.
+func (ts *TokenStore) tidy(dryRun bool) (*tokenTidyReport, error) {
+	defer metrics.MeasureSince([]string{"token", "tidy"}, time.Now())
+
+	// Tokens are not found until the expiration manager is set up, which
+	// would make all their index entries look invalid
+	if ts.expiration == nil {
+		return nil, fmt.Errorf("cannot run tidy before the expiration manager is set up")
+	}
+
+	if !atomic.CompareAndSwapInt64(&ts.tidyLock, 0, 1) {
+		ts.logger.Warn("token: tidy operation on tokens is already in progress")
+		return nil, fmt.Errorf("tidy operation on tokens is already in progress")
+	}
+
+	defer atomic.CompareAndSwapInt64(&ts.tidyLock, 1, 0)
+
+	ts.logger.Info("token: beginning tidy operation on tokens", "dry_run", dryRun)
+	defer ts.logger.Info("token: finished tidy operation on tokens", "dry_run", dryRun)
+
+	report := &tokenTidyReport{
+		DryRun: dryRun,
+	}
+	var tidyErrors *multierror.Error
+
+	// First, go through the tokens, since revoking those whose parent is gone
+	// also cleans up their index entries
+	saltedIDs, err := ts.view.List(lookupPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch token entries: %v", err)
+	}
+	for _, saltedID := range saltedIDs {
+		report.TokensScanned++
+		if report.TokensScanned%500 == 0 {
+			ts.logger.Info("token: checking validity of tokens", "progress", report.TokensScanned)
+		}
+		if err := ts.tidyToken(saltedID, report); err != nil {
+			tidyErrors = multierror.Append(tidyErrors, err)
+		}
+	}
+
+	// Then clean up secondary index entries that are no longer valid
+	parentList, err := ts.view.List(parentPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch secondary index entries: %v", err)
+	}
+
+	// Scan through the secondary index entries; if there is an entry
+	// with the token's salt ID at the end, remove it
+	for _, parent := range parentList {
+		children, err := ts.view.List(parentPrefix + parent)
+		if err != nil {
+			tidyErrors = multierror.Append(tidyErrors, fmt.Errorf("failed to read secondary index: %v", err))
+			continue
+		}
+
+		for _, child := range children {
+			report.ParentIndexEntriesScanned++
+			if report.ParentIndexEntriesScanned%500 == 0 {
+				ts.logger.Info("token: checking validity of tokens in secondary index list", "progress", report.ParentIndexEntriesScanned)
+			}
+
+			// Look up tainted entries so we can be sure that if this isn't
+			// found, it doesn't exist. Doing the following without locking
+			// since appropriate locks cannot be held with salted token IDs.
+			te, err := ts.lookupSalted(child, true)
+			if err != nil {
+				tidyErrors = multierror.Append(tidyErrors, fmt.Errorf("failed to lookup token: %v", err))
+				continue
+			}
+
+			// Tokens which were orphaned don't belong to the index of their
+			// former parent
+			if te != nil && te.Parent != "" {
+				parentSaltedID, err := ts.SaltID(te.Parent)
+				if err != nil {
+					tidyErrors = multierror.Append(tidyErrors, fmt.Errorf("failed to read salt id: %v", err))
+					continue
+				}
+				if parentSaltedID+"/" == parent {
+					continue
+				}
+			}
+
+			report.InvalidParentIndexEntries++
+			if dryRun {
+				continue
+			}
+			index := parentPrefix + parent + child
+			ts.logger.Trace("token: deleting invalid secondary index", "index", index)
+			if err := ts.view.Delete(index); err != nil {
+				tidyErrors = multierror.Append(tidyErrors, fmt.Errorf("failed to delete secondary index: %v", err))
+			}
+		}
+	}
+
+	// List out all the accessors
+	saltedAccessorList, err := ts.view.List(accessorPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch accessor index entries: %v", err)
+	}
+
+	// For each of the accessor, see if the token ID associated with it is
+	// a valid one. If not, delete the leases associated with that token
+	// and delete the accessor as well.
+	for _, saltedAccessor := range saltedAccessorList {
+		report.AccessorsScanned++
+		if report.AccessorsScanned%500 == 0 {
+			ts.logger.Info("token: checking if accessors contain valid tokens", "progress", report.AccessorsScanned)
+		}
+
+		accessorEntry, err := ts.lookupBySaltedAccessor(saltedAccessor, true)
+		if err != nil {
+			tidyErrors = multierror.Append(tidyErrors, fmt.Errorf("failed to read the accessor index: %v", err))
+			continue
+		}
+
+		index := accessorPrefix + saltedAccessor
+
+		// A valid accessor storage entry should always have a token ID
+		// in it. If not, it is an invalid accessor entry and needs to
+		// be deleted.
+		if accessorEntry.TokenID == "" {
+			report.AccessorsWithoutToken++
+			if dryRun {
+				continue
+			}
+			// If deletion of accessor fails, move on to the next
+			// item since this is just a best-effort operation
+			if err := ts.view.Delete(index); err != nil {
+				tidyErrors = multierror.Append(tidyErrors, fmt.Errorf("failed to delete the accessor index: %v", err))
+			}
+			continue
+		}
+
+		lock := locksutil.LockForKey(ts.tokenLocks, accessorEntry.TokenID)
+		lock.RLock()
+
+		// Look up tainted variants so we only find entries that truly don't
+		// exist
+		saltedId, err := ts.SaltID(accessorEntry.TokenID)
+		if err != nil {
+			tidyErrors = multierror.Append(tidyErrors, fmt.Errorf("failed to read salt id: %v", err))
+			lock.RUnlock()
+			continue
+		}
+		te, err := ts.lookupSalted(saltedId, true)
+		if err != nil {
+			tidyErrors = multierror.Append(tidyErrors, fmt.Errorf("failed to lookup tainted ID: %v", err))
+			lock.RUnlock()
+			continue
+		}
+
+		lock.RUnlock()
+
+		// If token entry is not found assume that the token is not valid any
+		// more and conclude that accessor, leases, and secondary index entries
+		// for this token should not exist as well.
+		if te != nil {
+			continue
+		}
+		report.AccessorsWithInvalidToken++
+		if dryRun {
+			continue
+		}
+
+		ts.logger.Info("token: deleting token with nil entry", "salted_token", saltedId)
+
+		// RevokeByToken expects a '*TokenEntry'. For the purposes of
+		// tidying, it is sufficient if the token entry only has ID set.
+		tokenEntry := &TokenEntry{
+			ID: accessorEntry.TokenID,
+		}
+
+		// Attempt to revoke the token. This will also revoke the leases
+		// associated with the token.
+		if err := ts.expiration.RevokeByToken(tokenEntry); err != nil {
+			tidyErrors = multierror.Append(tidyErrors, fmt.Errorf("failed to revoke leases of expired token: %v", err))
+			continue
+		}
+
+		// If deletion of accessor fails, move on to the next item since this
+		// is just a best-effort operation. We do this last so that on next run
+		// if something above failed we still have the accessor entry to try
+		// again.
+		if err := ts.view.Delete(index); err != nil {
+			tidyErrors = multierror.Append(tidyErrors, fmt.Errorf("failed to delete accessor entry: %v", err))
+		}
+	}
+
+	ts.logger.Debug("token: tidy report", "report", fmt.Sprintf("%+v", *report))
+
+	return report, tidyErrors.ErrorOrNil()
+}
+
+// tidyToken checks the given token entry against its parent and its index
+// entries, and repairs it unless the report is for a dry run
+func (ts *TokenStore) tidyToken(saltedID string, report *tokenTidyReport) error {
+	te, err := ts.lookupSalted(saltedID, true)
+	if err != nil {
+		return fmt.Errorf("failed to lookup token: %v", err)
+	}
+	if te == nil || te.NumUses == tokenRevocationInProgress {
+		return nil
+	}
+
+	// Retry the revocation of tokens whose revocation failed
+	if te.NumUses == tokenRevocationFailed {
+		report.FailedRevocations++
+		if report.DryRun {
+			return nil
+		}
+		if err := ts.revokeTreeSalted(saltedID); err != nil {
+			return fmt.Errorf("failed to revoke token: %v", err)
+		}
+		return nil
+	}
+
+	// Tokens whose parent is gone were missed by the revocation of their
+	// parent, since orphaning tokens clears their parent
+	var parentSaltedID string
+	if te.Parent != "" {
+		parentSaltedID, err = ts.SaltID(te.Parent)
+		if err != nil {
+			return err
+		}
+		parent, err := ts.lookupSalted(parentSaltedID, true)
+		if err != nil {
+			return fmt.Errorf("failed to lookup parent: %v", err)
+		}
+		if parent == nil {
+			report.TokensWithMissingParent++
+			if report.DryRun {
+				return nil
+			}
+			ts.logger.Info("token: revoking token with missing parent", "salted_token", saltedID)
+			if err := ts.revokeTreeSalted(saltedID); err != nil {
+				return fmt.Errorf("failed to revoke token with missing parent: %v", err)
+			}
+			return nil
+		}
+	}
+
+	lock := locksutil.LockForKey(ts.tokenLocks, te.ID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	// Look the token up again under the lock, so that index entries are not
+	// rebuilt for a token being revoked
+	te, err = ts.lookupSalted(saltedID, true)
+	if err != nil {
+		return fmt.Errorf("failed to lookup token: %v", err)
+	}
+	if te == nil || te.NumUses < 0 {
+		return nil
+	}
+
+	if te.Parent != "" {
+		index := parentPrefix + parentSaltedID + "/" + saltedID
+		raw, err := ts.view.Get(index)
+		if err != nil {
+			return fmt.Errorf("failed to read secondary index: %v", err)
+		}
+		if raw == nil {
+			report.ParentIndexEntriesRebuilt++
+			if !report.DryRun {
+				if err := ts.view.Put(&logical.StorageEntry{Key: index}); err != nil {
+					return fmt.Errorf("failed to persist secondary index: %v", err)
+				}
+			}
+		}
+	}
+
+	if te.Accessor != "" {
+		saltedAccessor, err := ts.SaltID(te.Accessor)
+		if err != nil {
+			return err
+		}
+		index := accessorPrefix + saltedAccessor
+		raw, err := ts.view.Get(index)
+		if err != nil {
+			return fmt.Errorf("failed to read accessor index: %v", err)
+		}
+		var aEntry accessorEntry
+		if raw != nil {
+			if aEntry, err = ts.lookupBySaltedAccessor(saltedAccessor, true); err != nil {
+				return err
+			}
+		}
+		if aEntry.TokenID != te.ID {
+			report.AccessorIndexEntriesRebuilt++
+			if !report.DryRun {
+				enc, err := jsonutil.EncodeJSON(&accessorEntry{
+					TokenID:    te.ID,
+					AccessorID: te.Accessor,
+				})
+				if err != nil {
+					return fmt.Errorf("failed to marshal accessor index entry: %v", err)
+				}
+				if err := ts.view.Put(&logical.StorageEntry{Key: index, Value: enc}); err != nil {
+					return fmt.Errorf("failed to persist accessor index entry: %v", err)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// startTokenTidy starts tidying the token store in the background, if
+// enabled
+func (c *Core) startTokenTidy() {
+	if c.tokenTidyInterval <= 0 {
+		return
+	}
+	c.tokenTidyCh = make(chan struct{})
+	go c.runTokenTidy(c.tokenStore, c.tokenTidyCh)
+}
+
+// stopTokenTidy stops tidying the token store in the background
+func (c *Core) stopTokenTidy() {
+	if c.tokenTidyCh != nil {
+		close(c.tokenTidyCh)
+		c.tokenTidyCh = nil
+	}
+}
+
+func (c *Core) runTokenTidy(ts *TokenStore, stopCh chan struct{}) {
+	tick := time.NewTicker(c.tokenTidyInterval)
+	defer tick.Stop()
+
+	for {
+		select {
+		case <-tick.C:
+			report, err := ts.tidy(false)
+			if err != nil {
+				c.logger.Error("core: failed to tidy tokens", "error", err)
+				continue
+			}
+			if report.TokensWithMissingParent+report.FailedRevocations+report.ParentIndexEntriesRebuilt+
+				report.AccessorIndexEntriesRebuilt+report.InvalidParentIndexEntries+
+				report.AccessorsWithoutToken+report.AccessorsWithInvalidToken > 0 {
+				c.logger.Warn("core: repaired inconsistencies in the token store", "report", fmt.Sprintf("%+v", *report))
+			}
+		case <-stopCh:
+			return
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"crypto/rand"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
@@ -21,6 +22,10 @@ import (
 const (
 	// The location of the key used to generate response-wrapping JWTs
 	coreWrappingJWTKeyPath = "core/wrapping/jwtkey"
+
+	// The cubbyhole prefix of the entries of a token wrapping multiple
+	// named entries
+	wrappedEntryPrefix = "entries/"
 )
 
 func (c *Core) ensureWrappingKey() error {
@@ -94,12 +99,22 @@ func (c *Core) wrapInCubbyhole(req *logical.Request, resp *logical.Response, aut
 
 	var err error
 
+	// Tokens wrapping multiple named entries aren't limited in uses; each
+	// entry can be unwrapped once and the token is revoked along with the
+	// last one
+	wrapEntries := req.Path == "sys/wrapping/wrap-entries"
+
 	// If we are wrapping, the first part (performed in this functions) happens
 	// before auditing so that resp.WrapInfo.Token can contain the HMAC'd
 	// wrapping token ID in the audit logs, so that it can be determined from
 	// the audit logs whether the token was ever actually used.
 	creationTime := time.Now()
+	ns := c.requestNamespace(req)
+	if ns == nil {
+		return nil, errNamespaceNotFound(req.Namespace)
+	}
 	te := TokenEntry{
+		NamespaceID:    ns.ID,
 		Path:           req.Path,
 		Policies:       []string{"response-wrapping"},
 		CreationTime:   creationTime.Unix(),
@@ -107,6 +122,9 @@ func (c *Core) wrapInCubbyhole(req *logical.Request, resp *logical.Response, aut
 		NumUses:        1,
 		ExplicitMaxTTL: resp.WrapInfo.TTL,
 	}
+	if wrapEntries {
+		te.NumUses = 0
+	}
 
 	if err := c.tokenStore.create(&te); err != nil {
 		c.logger.Error("core: failed to create wrapping token", "error", err)
@@ -120,7 +138,8 @@ func (c *Core) wrapInCubbyhole(req *logical.Request, resp *logical.Response, aut
 		resp.WrapInfo.CreationPath = req.Path
 	}
 
-	if auth != nil && auth.EntityID != "" {
+	// A rewrap keeps the entity that created the original token
+	if req.Path != "sys/wrapping/rewrap" && auth != nil && auth.EntityID != "" {
 		resp.WrapInfo.WrappedEntityID = auth.EntityID
 	}
 
@@ -165,7 +184,7 @@ func (c *Core) wrapInCubbyhole(req *logical.Request, resp *logical.Response, aut
 		cubbyReq.Data = map[string]interface{}{
 			"response": resp.Data["response"],
 		}
-	} else {
+	} else if !wrapEntries {
 		httpResponse := logical.LogicalResponseToHTTPResponse(resp)
 
 		// Add the unique identifier of the original request to the response
@@ -189,17 +208,30 @@ func (c *Core) wrapInCubbyhole(req *logical.Request, resp *logical.Response, aut
 		}
 	}
 
-	cubbyResp, err := c.router.Route(cubbyReq)
-	if err != nil {
-		// Revoke since it's not yet being tracked for expiration
-		c.tokenStore.Revoke(te.ID)
-		c.logger.Error("core: failed to store wrapped response information", "error", err)
-		return nil, ErrInternalError
-	}
-	if cubbyResp != nil && cubbyResp.IsError() {
-		c.tokenStore.Revoke(te.ID)
-		c.logger.Error("core: failed to store wrapped response information", "error", cubbyResp.Data["error"])
-		return cubbyResp, nil
+	// Each entry is stored on its own rather than in a single response;
+	// their names are kept with the wrapping information
+	var entries []string
+	if wrapEntries {
+		entries, err = c.wrapEntriesInCubbyhole(req, resp, te.ID)
+		if err != nil {
+			// Revoke since it's not yet being tracked for expiration
+			c.tokenStore.Revoke(te.ID)
+			c.logger.Error("core: failed to store wrapped entries", "error", err)
+			return nil, ErrInternalError
+		}
+	} else {
+		cubbyResp, err := c.router.Route(cubbyReq)
+		if err != nil {
+			// Revoke since it's not yet being tracked for expiration
+			c.tokenStore.Revoke(te.ID)
+			c.logger.Error("core: failed to store wrapped response information", "error", err)
+			return nil, ErrInternalError
+		}
+		if cubbyResp != nil && cubbyResp.IsError() {
+			c.tokenStore.Revoke(te.ID)
+			c.logger.Error("core: failed to store wrapped response information", "error", cubbyResp.Data["error"])
+			return cubbyResp, nil
+		}
 	}
 
 	// Store info for lookup
@@ -208,13 +240,19 @@ func (c *Core) wrapInCubbyhole(req *logical.Request, resp *logical.Response, aut
 		"creation_ttl":  resp.WrapInfo.TTL,
 		"creation_time": creationTime,
 	}
+	if wrapEntries {
+		cubbyReq.Data["entries"] = entries
+	}
 	// Store creation_path if not a rewrap
 	if req.Path != "sys/wrapping/rewrap" {
 		cubbyReq.Data["creation_path"] = req.Path
 	} else {
 		cubbyReq.Data["creation_path"] = resp.WrapInfo.CreationPath
 	}
-	cubbyResp, err = c.router.Route(cubbyReq)
+	if resp.WrapInfo.WrappedEntityID != "" {
+		cubbyReq.Data["creator_entity_id"] = resp.WrapInfo.WrappedEntityID
+	}
+	cubbyResp, err := c.router.Route(cubbyReq)
 	if err != nil {
 		// Revoke since it's not yet being tracked for expiration
 		c.tokenStore.Revoke(te.ID)
@@ -247,14 +285,56 @@ func (c *Core) wrapInCubbyhole(req *logical.Request, resp *logical.Response, aut
 	return nil, nil
 }
 
+// wrapEntriesInCubbyhole stores each payload of the response as a separately
+// wrapped entry in the cubbyhole of the wrapping token, returning the sorted
+// names of the entries
+func (c *Core) wrapEntriesInCubbyhole(req *logical.Request, resp *logical.Response, token string) ([]string, error) {
+	entries := make([]string, 0, len(resp.Data))
+	for name := range resp.Data {
+		entries = append(entries, name)
+	}
+	sort.Strings(entries)
+
+	for _, name := range entries {
+		payload, ok := resp.Data[name].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("entry %q is not a map", name)
+		}
+
+		httpResponse := &logical.HTTPResponse{
+			RequestID: req.ID,
+			Data:      payload,
+		}
+		marshaledResponse, err := json.Marshal(httpResponse)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal entry %q: %v", name, err)
+		}
+
+		cubbyResp, err := c.router.Route(&logical.Request{
+			Operation:   logical.CreateOperation,
+			Path:        "cubbyhole/" + wrappedEntryPrefix + name,
+			ClientToken: token,
+			Data: map[string]interface{}{
+				"response": string(marshaledResponse),
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if cubbyResp != nil && cubbyResp.IsError() {
+			return nil, cubbyResp.Error()
+		}
+	}
+
+	return entries, nil
+}
+
 // ValidateWrappingToken checks whether a token is a wrapping token.
 func (c *Core) ValidateWrappingToken(req *logical.Request) (bool, error) {
 	if req == nil {
 		return false, fmt.Errorf("invalid request")
 	}
 
-	var err error
-
 	var token string
 	var thirdParty bool
 	if req.Data != nil && req.Data["token"] != nil {
@@ -306,6 +386,13 @@ func (c *Core) ValidateWrappingToken(req *logical.Request) (bool, error) {
 		return false, consts.ErrStandby
 	}
 
+	return c.isWrappingToken(token)
+}
+
+// isWrappingToken checks whether a token is a wrapping token, whose
+// cubbyhole can be trusted to hold the wrapping information. The state
+// lock must be held.
+func (c *Core) isWrappingToken(token string) (bool, error) {
 	te, err := c.tokenStore.Lookup(token)
 	if err != nil {
 		return false, err